@@ -28,6 +28,7 @@ interface RuntimeOptions {
   poll_wait: string;
   heartbeat_interval: string;
   max_response_body_bytes: number;
+  max_bytes_per_second?: number;
   proxy_url?: string;
   no_proxy?: string;
   tls_skip_verify: boolean;
@@ -42,6 +43,8 @@ interface AgentProfile {
   agent_id: string;
   mode: "connector" | "legacy_tunnels" | string;
   connector_id?: string;
+  secret_backend?: "keychain" | "file" | string;
+  update_channel?: "stable" | "beta" | string;
   runtime: RuntimeOptions;
   legacy_tunnels?: TunnelConfig[];
   created_at?: string;
@@ -57,13 +60,31 @@ interface NativeStatusSnapshot {
   agent_id?: string;
   session_id?: string;
   mode?: string;
+  routes?: TunnelRoute[];
   updated_at?: string;
 }
 
+interface TunnelRoute {
+  id: string;
+  public_url: string;
+  expires_at?: string;
+}
+
 interface UpdateCheckResult {
   current_version: string;
+  channel?: string;
   latest_version?: string;
+  update_available: boolean;
+  required?: boolean;
+  min_version?: string;
   download_url?: string;
+  sha256?: string;
+  message: string;
+}
+
+interface UpdateApplyResult {
+  path?: string;
+  installed: boolean;
   message: string;
 }
 
@@ -75,11 +96,14 @@ interface ProfileFormState {
   connector_id: string;
   connector_secret: string;
   agent_token: string;
+  secret_backend: "keychain" | "file";
+  update_channel: "stable" | "beta";
   legacy_tunnels: string;
   request_timeout: string;
   poll_wait: string;
   heartbeat_interval: string;
   max_response_body_bytes: string;
+  max_bytes_per_second: string;
   proxy_url: string;
   no_proxy: string;
   tls_skip_verify: boolean;
@@ -87,6 +111,21 @@ interface ProfileFormState {
   log_level: string;
 }
 
+interface DiscoveredPort {
+  port: number;
+  target_url: string;
+  status: number;
+  server?: string;
+  title?: string;
+  tunnel_id: string;
+}
+
+interface DiscoveryResult {
+  discovered: DiscoveredPort[];
+  added?: TunnelConfig[];
+  profile?: AgentProfile;
+}
+
 interface ApiErrorPayload {
   error?: string;
 }
@@ -99,11 +138,14 @@ const defaultForm = (): ProfileFormState => ({
   connector_id: "",
   connector_secret: "",
   agent_token: "",
+  secret_backend: "keychain",
+  update_channel: "stable",
   legacy_tunnels: "",
   request_timeout: "45s",
   poll_wait: "25s",
   heartbeat_interval: "10s",
   max_response_body_bytes: String(20 << 20),
+  max_bytes_per_second: "",
   proxy_url: "",
   no_proxy: "",
   tls_skip_verify: false,
@@ -120,11 +162,14 @@ function profileToForm(profile: AgentProfile): ProfileFormState {
     connector_id: profile.connector_id ?? "",
     connector_secret: "",
     agent_token: "",
+    secret_backend: profile.secret_backend === "file" ? "file" : "keychain",
+    update_channel: profile.update_channel === "beta" ? "beta" : "stable",
     legacy_tunnels: tunnelsToString(profile.legacy_tunnels),
     request_timeout: profile.runtime?.request_timeout ?? "45s",
     poll_wait: profile.runtime?.poll_wait ?? "25s",
     heartbeat_interval: profile.runtime?.heartbeat_interval ?? "10s",
     max_response_body_bytes: String(profile.runtime?.max_response_body_bytes ?? 20 << 20),
+    max_bytes_per_second: profile.runtime?.max_bytes_per_second ? String(profile.runtime.max_bytes_per_second) : "",
     proxy_url: profile.runtime?.proxy_url ?? "",
     no_proxy: profile.runtime?.no_proxy ?? "",
     tls_skip_verify: Boolean(profile.runtime?.tls_skip_verify),
@@ -216,6 +261,9 @@ export function App() {
   const [runtimeProfile, setRuntimeProfile] = useState<string>("");
   const [pairProfile, setPairProfile] = useState<string>("");
   const [pairToken, setPairToken] = useState<string>("");
+  const [discoverPorts, setDiscoverPorts] = useState<string>("");
+  const [discoverProfile, setDiscoverProfile] = useState<string>("");
+  const [discovered, setDiscovered] = useState<DiscoveredPort[]>([]);
   const [updateResult, setUpdateResult] = useState<UpdateCheckResult | null>(null);
   const [errorMessage, setErrorMessage] = useState<string>("");
   const [successMessage, setSuccessMessage] = useState<string>("");
@@ -325,6 +373,12 @@ export function App() {
       setError("Max response body bytes must be a positive integer");
       return;
     }
+    // An empty bandwidth limit is sent as -1, which clears a saved limit.
+    const bandwidth = form.max_bytes_per_second.trim() === "" ? -1 : Number.parseInt(form.max_bytes_per_second, 10);
+    if (!Number.isFinite(bandwidth) || (bandwidth !== -1 && bandwidth < 1024)) {
+      setError("Bandwidth limit must be empty or at least 1024 bytes per second");
+      return;
+    }
 
     const payload = {
       name: form.name.trim(),
@@ -334,12 +388,15 @@ export function App() {
       connector_id: form.connector_id.trim(),
       connector_secret: form.connector_secret.trim(),
       agent_token: form.agent_token.trim(),
+      secret_backend: form.secret_backend,
+      update_channel: form.update_channel,
       legacy_tunnels: form.legacy_tunnels.trim(),
       runtime: {
         request_timeout: form.request_timeout.trim(),
         poll_wait: form.poll_wait.trim(),
         heartbeat_interval: form.heartbeat_interval.trim(),
         max_response_body_bytes: maxBytes,
+        max_bytes_per_second: bandwidth,
         proxy_url: form.proxy_url.trim(),
         no_proxy: form.no_proxy.trim(),
         tls_skip_verify: form.tls_skip_verify,
@@ -407,6 +464,23 @@ export function App() {
     setSuccessMessage("");
   };
 
+  const handleCopyURL = async (publicURL: string) => {
+    try {
+      await navigator.clipboard.writeText(publicURL);
+      setSuccess(`Copied ${publicURL}`);
+    } catch {
+      setError("Could not copy to the clipboard");
+    }
+  };
+
+  const handlePastePairToken = async () => {
+    try {
+      setPairToken((await navigator.clipboard.readText()).trim());
+    } catch {
+      setError("Could not read the clipboard; paste the token into the field instead");
+    }
+  };
+
   const handlePair = async (event: FormEvent) => {
     event.preventDefault();
     if (!pairProfile.trim()) {
@@ -433,6 +507,39 @@ export function App() {
     }
   };
 
+  const handleDiscover = async (event: FormEvent) => {
+    event.preventDefault();
+    try {
+      const query = discoverPorts.trim() ? `?ports=${encodeURIComponent(discoverPorts.trim())}` : "";
+      const result = await apiRequest<DiscoveryResult>(`/api/discover${query}`);
+      setDiscovered(result.discovered);
+      setSuccess(`Found ${result.discovered.length} local HTTP server(s)`);
+    } catch (error) {
+      setError(error instanceof Error ? error.message : "Port discovery failed");
+    }
+  };
+
+  const handleAddDiscovered = async () => {
+    if (!discoverProfile.trim()) {
+      setError("Profile is required to add discovered tunnels");
+      return;
+    }
+    try {
+      const result = await apiRequest<DiscoveryResult>(
+        `/api/profiles/${encodeURIComponent(discoverProfile.trim())}/discover`,
+        {
+          method: "POST",
+          body: JSON.stringify({ ports: discoverPorts.trim() }),
+        }
+      );
+      setDiscovered(result.discovered);
+      setSuccess(`Added ${result.added?.length ?? 0} tunnel(s) to profile ${result.profile?.name ?? discoverProfile}`);
+      await refreshSettingsAndProfiles();
+    } catch (error) {
+      setError(error instanceof Error ? error.message : "Adding discovered tunnels failed");
+    }
+  };
+
   const handleStart = async () => {
     try {
       await apiRequest<NativeStatusSnapshot>("/api/runtime/start", {
@@ -488,6 +595,15 @@ export function App() {
     }
   };
 
+  const handleApplyUpdate = async () => {
+    try {
+      const data = await apiRequest<UpdateApplyResult>("/api/update/apply", { method: "POST" });
+      setSuccess(data.message);
+    } catch (error) {
+      setError(error instanceof Error ? error.message : "Update failed");
+    }
+  };
+
   return (
     <main className="layout">
       <header className="hero">
@@ -548,6 +664,22 @@ export function App() {
           </button>
         </div>
 
+        {status.routes && status.routes.length > 0 && (
+          <ul className="runtime-urls">
+            {status.routes.map((route) => (
+              <li key={route.id}>
+                <span>{route.id}</span>
+                <a href={route.public_url} target="_blank" rel="noreferrer">
+                  {route.public_url}
+                </a>
+                <button className="btn secondary" type="button" onClick={() => void handleCopyURL(route.public_url)}>
+                  Copy
+                </button>
+              </li>
+            ))}
+          </ul>
+        )}
+
         {(status.message || status.error) && (
           <div className="runtime-extra">
             {status.message && <p>{status.message}</p>}
@@ -622,6 +754,36 @@ export function App() {
                 onChange={(event) => setForm((prev) => ({ ...prev, agent_token: event.target.value }))}
               />
             </label>
+            <label>
+              Secret Storage
+              <select
+                value={form.secret_backend}
+                onChange={(event) =>
+                  setForm((prev) => ({
+                    ...prev,
+                    secret_backend: event.target.value === "file" ? "file" : "keychain",
+                  }))
+                }
+              >
+                <option value="keychain">system keychain</option>
+                <option value="file">encrypted file (passphrase)</option>
+              </select>
+            </label>
+            <label>
+              Update Channel
+              <select
+                value={form.update_channel}
+                onChange={(event) =>
+                  setForm((prev) => ({
+                    ...prev,
+                    update_channel: event.target.value === "beta" ? "beta" : "stable",
+                  }))
+                }
+              >
+                <option value="stable">stable</option>
+                <option value="beta">beta (pre-releases)</option>
+              </select>
+            </label>
             <label className="full-width">
               Legacy Tunnels (id=url,id2@token=url)
               <textarea
@@ -660,6 +822,14 @@ export function App() {
                 }
               />
             </label>
+            <label>
+              Bandwidth Limit (bytes/s)
+              <input
+                value={form.max_bytes_per_second}
+                placeholder="unlimited"
+                onChange={(event) => setForm((prev) => ({ ...prev, max_bytes_per_second: event.target.value }))}
+              />
+            </label>
             <label>
               Proxy URL
               <input
@@ -733,6 +903,9 @@ export function App() {
                 />
               </label>
               <div className="actions full-width">
+                <button className="btn secondary" type="button" onClick={() => void handlePastePairToken()}>
+                  Paste Token
+                </button>
                 <button className="btn" type="submit">
                   Pair Profile
                 </button>
@@ -740,6 +913,46 @@ export function App() {
             </form>
           </section>
 
+          <section>
+            <h2>Discover Local Ports</h2>
+            <form className="form-grid" onSubmit={handleDiscover}>
+              <label>
+                Ports
+                <input
+                  value={discoverPorts}
+                  placeholder="3000-3010,4200,5000,5173,8000,8080,8888"
+                  onChange={(event) => setDiscoverPorts(event.target.value)}
+                />
+              </label>
+              <label>
+                Legacy Tunnels Profile
+                <input
+                  value={discoverProfile}
+                  onChange={(event) => setDiscoverProfile(event.target.value)}
+                />
+              </label>
+              <div className="actions full-width">
+                <button className="btn" type="submit">
+                  Scan
+                </button>
+                <button className="btn secondary" type="button" onClick={handleAddDiscovered}>
+                  Add as Tunnels
+                </button>
+              </div>
+            </form>
+            {discovered.length > 0 && (
+              <ul>
+                {discovered.map((port) => (
+                  <li key={port.port}>
+                    <code>{`${port.tunnel_id}=${port.target_url}`}</code> ({port.status}
+                    {port.title ? `, ${port.title}` : ""}
+                    {port.server ? `, ${port.server}` : ""})
+                  </li>
+                ))}
+              </ul>
+            )}
+          </section>
+
           <section>
             <h2>App Settings</h2>
             <form className="form-grid" onSubmit={handleSaveSettings}>
@@ -786,17 +999,25 @@ export function App() {
               <button className="btn secondary" onClick={handleCheckUpdates}>
                 Check for Updates
               </button>
+              {updateResult?.update_available && updateResult.sha256 && (
+                <button className="btn" onClick={handleApplyUpdate}>
+                  Download and Verify {updateResult.latest_version}
+                </button>
+              )}
             </div>
             {updateResult && (
               <div className="info-block">
                 <p>{updateResult.message}</p>
                 <p>Current: {updateResult.current_version}</p>
+                {updateResult.channel && <p>Channel: {updateResult.channel}</p>}
                 {updateResult.latest_version && <p>Latest: {updateResult.latest_version}</p>}
+                {updateResult.required && <p>This gateway requires agent {updateResult.min_version} or newer.</p>}
                 {updateResult.download_url && (
                   <p>
                     Download: <a href={updateResult.download_url}>{updateResult.download_url}</a>
                   </p>
                 )}
+                {updateResult.sha256 && <p>SHA-256: {updateResult.sha256}</p>}
               </div>
             )}
           </section>
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/szaher/try/proxer/internal/agent"
+	"github.com/szaher/try/proxer/internal/nativeagent"
+)
+
+func handleDiscoverCommand(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("discover", flag.ExitOnError)
+	ports := fs.String("ports", agent.DefaultDiscoveryPorts, "ports and ranges to scan, e.g. 3000-3010,5173")
+	host := fs.String("host", "127.0.0.1", "host to scan")
+	asJSON := fs.Bool("json", false, "output json")
+	apply := fs.Bool("apply", false, "add discovered ports as tunnels to the profile")
+	profile := fs.String("profile", "", "profile id or name for --apply (defaults to the active profile)")
+	_ = fs.Parse(args)
+
+	portList, err := agent.ParsePortList(*ports)
+	if err != nil {
+		log.Fatalf("parse --ports: %v", err)
+	}
+	discovered := agent.DiscoverLocalPorts(ctx, *host, portList)
+	result := nativeagent.DiscoveryResult{Discovered: discovered}
+
+	if *apply {
+		service, err := nativeagent.NewService()
+		if err != nil {
+			log.Fatalf("initialize native agent service: %v", err)
+		}
+		target, err := service.ResolveProfile(*profile)
+		if err != nil {
+			log.Fatalf("resolve profile: %v", err)
+		}
+		updated, added, err := service.AddDiscoveredTunnels(target.ID, discovered)
+		if err != nil {
+			log.Fatalf("add discovered tunnels: %v", err)
+		}
+		result.Profile = &updated
+		result.Added = added
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(result)
+		return
+	}
+	if len(discovered) == 0 {
+		fmt.Printf("no HTTP servers found on %s ports %s\n", *host, *ports)
+		return
+	}
+	for _, port := range discovered {
+		details := []string{fmt.Sprintf("status %d", port.Status)}
+		if port.Server != "" {
+			details = append(details, "server "+port.Server)
+		}
+		if port.Title != "" {
+			details = append(details, fmt.Sprintf("title %q", port.Title))
+		}
+		fmt.Printf("%s (%s) -> %s=%s\n", port.TargetURL, strings.Join(details, ", "), port.TunnelID, port.TargetURL)
+	}
+	if !*apply {
+		fmt.Println("run with --apply to add these tunnels to the active legacy_tunnels profile")
+		return
+	}
+	if len(result.Added) == 0 {
+		fmt.Printf("profile %s already forwards every discovered port\n", result.Profile.Name)
+		return
+	}
+	for _, tunnel := range result.Added {
+		fmt.Printf("added tunnel %s=%s to profile %s\n", tunnel.ID, tunnel.Target, result.Profile.Name)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/szaher/try/proxer/internal/agent"
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func handleExposeCommand(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("expose", flag.ExitOnError)
+	dir := fs.String("dir", "", "directory to serve read-only")
+	id := fs.String("id", "", "tunnel id (defaults to the directory name)")
+	token := fs.String("token", "", "optional tunnel access token")
+	listing := fs.Bool("listing", false, "list directories that have no index.html")
+	_ = fs.Parse(args)
+
+	if strings.TrimSpace(*dir) == "" {
+		log.Fatalf("expose requires --dir")
+	}
+	target, err := agent.FileTunnelTarget(*dir, *listing)
+	if err != nil {
+		log.Fatalf("expose %s: %v", *dir, err)
+	}
+	tunnelID := strings.TrimSpace(*id)
+	if tunnelID == "" {
+		abs, _ := filepath.Abs(*dir)
+		tunnelID = filepath.Base(abs)
+	}
+
+	cfg, err := agent.LoadConfigFromEnv()
+	if err != nil {
+		log.Fatalf("load agent config from env: %v", err)
+	}
+	if strings.TrimSpace(cfg.PairToken) != "" || strings.TrimSpace(cfg.ConnectorID) != "" {
+		log.Fatalf("expose publishes a tunnel with PROXER_AGENT_TOKEN; unset the connector pairing variables")
+	}
+	cfg.Tunnels = []protocol.TunnelConfig{{ID: tunnelID, Target: target, Token: strings.TrimSpace(*token)}}
+
+	logger := log.New(os.Stdout, "[agent] ", log.LstdFlags|log.Lmicroseconds)
+	logger.Printf("serving %s as tunnel %q via %s", target, tunnelID, cfg.GatewayBaseURL)
+	if err := agent.New(cfg, logger).Run(ctx); err != nil {
+		log.Fatalf("agent stopped with error: %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
@@ -18,6 +19,7 @@ import (
 
 	"github.com/szaher/try/proxer/internal/agent"
 	"github.com/szaher/try/proxer/internal/nativeagent"
+	"github.com/szaher/try/proxer/internal/protocol"
 )
 
 func main() {
@@ -34,6 +36,13 @@ func main() {
 		return
 	}
 
+	// The system opens proxer-agent://pair links with the link as the only
+	// argument.
+	if strings.HasPrefix(strings.ToLower(args[0]), protocol.PairLinkScheme+"://") {
+		handlePairCommand([]string{"--link", args[0]})
+		return
+	}
+
 	switch args[0] {
 	case "gui":
 		if err := nativeagent.RunGUI(ctx); err != nil {
@@ -41,6 +50,12 @@ func main() {
 		}
 	case "run":
 		handleRunCommand(ctx, args[1:])
+	case "headless":
+		handleHeadlessCommand(ctx, args[1:])
+	case "expose":
+		handleExposeCommand(ctx, args[1:])
+	case "discover":
+		handleDiscoverCommand(ctx, args[1:])
 	case "status":
 		handleStatusCommand(args[1:])
 	case "logs":
@@ -53,6 +68,8 @@ func main() {
 		handleConfigCommand(args[1:])
 	case "update":
 		handleUpdateCommand(args[1:])
+	case "routes":
+		handleRoutesCommand(args[1:])
 	case "help", "--help", "-h":
 		printUsage()
 	default:
@@ -67,6 +84,7 @@ func runLegacyEnvMode(ctx context.Context) {
 	if err != nil {
 		log.Fatalf("load agent config from env: %v", err)
 	}
+	cfg.Version = nativeagent.BuildVersion()
 	logger := log.New(os.Stdout, "[agent] ", log.LstdFlags|log.Lmicroseconds)
 	client := agent.New(cfg, logger)
 	logger.Printf("starting proxer agent in legacy env mode (id=%s, tunnels=%d)", cfg.AgentID, len(cfg.Tunnels))
@@ -103,11 +121,57 @@ func runManagedRun(ctx context.Context, profile string) {
 	}
 }
 
+func runManagedRunAll(ctx context.Context, profiles []string) {
+	service, err := nativeagent.NewService()
+	if err != nil {
+		log.Fatalf("initialize native agent service: %v", err)
+	}
+	started, err := service.StartProfiles(profiles)
+	if err != nil {
+		if len(started) == 0 {
+			log.Fatalf("start managed runtimes: %v", err)
+		}
+		log.Printf("some profiles failed to start: %v", err)
+	}
+	for _, profile := range started {
+		fmt.Printf("started profile %s (%s); log: %s\n", profile.Name, profile.ID, service.ProfileLogPath(profile.ID))
+	}
+	fmt.Println("managed runtimes started; press Ctrl+C to stop")
+
+	waitErrCh := make(chan error, 1)
+	go func() {
+		waitErrCh <- service.WaitProfiles(ctx)
+	}()
+
+	select {
+	case <-ctx.Done():
+		if err := service.StopProfiles(); err != nil {
+			log.Fatalf("stop runtimes: %v", err)
+		}
+		fmt.Println("runtimes stopped")
+	case err := <-waitErrCh:
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Fatalf("runtimes exited with error: %v", err)
+		}
+	}
+}
+
 func handleRunCommand(ctx context.Context, args []string) {
 	fs := flag.NewFlagSet("run", flag.ExitOnError)
-	profile := fs.String("profile", "", "profile id or name")
+	profile := fs.String("profile", "", "profile id or name; with --all, a comma-separated list")
+	all := fs.Bool("all", false, "run several profiles at once, each with its own session, status and log")
 	_ = fs.Parse(args)
 
+	if *all {
+		var profiles []string
+		for _, ref := range strings.Split(*profile, ",") {
+			if ref = strings.TrimSpace(ref); ref != "" {
+				profiles = append(profiles, ref)
+			}
+		}
+		runManagedRunAll(ctx, profiles)
+		return
+	}
 	if strings.TrimSpace(*profile) == "" && hasLegacyEnvConfig() {
 		runLegacyEnvMode(ctx)
 		return
@@ -115,15 +179,70 @@ func handleRunCommand(ctx context.Context, args []string) {
 	runManagedRun(ctx, *profile)
 }
 
+func handleHeadlessCommand(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("headless", flag.ExitOnError)
+	configPath := fs.String("config", "", "ProxerAgent config file (default $PROXER_AGENT_CONFIG or "+agent.DefaultHeadlessConfigPath+")")
+	_ = fs.Parse(args)
+
+	path := strings.TrimSpace(*configPath)
+	if path == "" {
+		path = strings.TrimSpace(os.Getenv("PROXER_AGENT_CONFIG"))
+	}
+	if path == "" {
+		path = agent.DefaultHeadlessConfigPath
+	}
+	cfg, options, err := agent.LoadHeadlessConfig(path)
+	if err != nil {
+		log.Fatalf("load headless config: %v", err)
+	}
+	cfg.Version = nativeagent.BuildVersion()
+	logger := log.New(os.Stdout, "[agent] ", log.LstdFlags|log.Lmicroseconds)
+
+	probes := agent.NewProbes()
+	cfg.EventHook = probes.Observe
+	probeServer := &http.Server{Addr: options.ProbeListenAddr, Handler: probes.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := probeServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("serve probes: %v", err)
+		}
+	}()
+	defer probeServer.Close()
+
+	logger.Printf("starting proxer agent in headless mode (id=%s, tunnels=%d, probes=%s)", cfg.AgentID, len(cfg.Tunnels), options.ProbeListenAddr)
+	if err := agent.New(cfg, logger).Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("agent stopped with error: %v", err)
+	}
+}
+
 func handleStatusCommand(args []string) {
 	fs := flag.NewFlagSet("status", flag.ExitOnError)
 	asJSON := fs.Bool("json", false, "output json")
+	all := fs.Bool("all", false, "show the status of every profile started with run --all")
 	_ = fs.Parse(args)
 
 	service, err := nativeagent.NewService()
 	if err != nil {
 		log.Fatalf("initialize native agent service: %v", err)
 	}
+	if *all {
+		statuses, err := service.ProfileStatuses()
+		if err != nil {
+			log.Fatalf("read profile statuses: %v", err)
+		}
+		if *asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			_ = encoder.Encode(statuses)
+			return
+		}
+		for i, status := range statuses {
+			if i > 0 {
+				fmt.Println()
+			}
+			printStatus(status)
+		}
+		return
+	}
 	status, err := service.Status()
 	if err != nil {
 		log.Fatalf("read status: %v", err)
@@ -134,6 +253,10 @@ func handleStatusCommand(args []string) {
 		_ = encoder.Encode(status)
 		return
 	}
+	printStatus(status)
+}
+
+func printStatus(status nativeagent.NativeStatusSnapshot) {
 	fmt.Printf("state: %s\n", status.State)
 	fmt.Printf("profile: %s (%s)\n", status.ProfileName, status.ProfileID)
 	fmt.Printf("agent_id: %s\n", status.AgentID)
@@ -143,12 +266,41 @@ func handleStatusCommand(args []string) {
 	if strings.TrimSpace(status.Error) != "" {
 		fmt.Printf("error: %s\n", status.Error)
 	}
+	if conn := status.Connection; conn != nil {
+		lastRegistered, lastHeartbeat := "never", "never"
+		if conn.LastRegisteredAt != nil {
+			lastRegistered = conn.LastRegisteredAt.Format(time.RFC3339)
+		}
+		if conn.LastHeartbeatAt != nil {
+			lastHeartbeat = fmt.Sprintf("%s (rtt %dms)", conn.LastHeartbeatAt.Format(time.RFC3339), conn.HeartbeatRTTMs)
+		}
+		fmt.Printf("last_registered_at: %s\n", lastRegistered)
+		fmt.Printf("reconnects: %d (%d resumed)\n", conn.Reconnects, conn.Resumes)
+		fmt.Printf("last_heartbeat: %s\n", lastHeartbeat)
+		if conn.BackoffMs > 0 {
+			fmt.Printf("retrying in: %s\n", time.Duration(conn.BackoffMs)*time.Millisecond)
+		}
+		fmt.Printf("requests: %d served, %d errored\n", conn.RequestsServed, conn.RequestsErrored)
+	}
+	for _, route := range status.Routes {
+		ttl := "no expiry"
+		if route.ExpiresAt != nil {
+			remaining := time.Until(*route.ExpiresAt).Round(time.Second)
+			if remaining <= 0 {
+				ttl = "expired"
+			} else {
+				ttl = "expires in " + remaining.String()
+			}
+		}
+		fmt.Printf("route: %s %s (%s)\n", route.ID, route.PublicURL, ttl)
+	}
 }
 
 func handleLogsCommand(ctx context.Context, args []string) {
 	fs := flag.NewFlagSet("logs", flag.ExitOnError)
 	follow := fs.Bool("follow", false, "follow log output")
 	tailLines := fs.Int("tail", 200, "tail lines to print")
+	profile := fs.String("profile", "", "show the log of a profile started with run --all")
 	_ = fs.Parse(args)
 
 	service, err := nativeagent.NewService()
@@ -156,6 +308,13 @@ func handleLogsCommand(ctx context.Context, args []string) {
 		log.Fatalf("initialize native agent service: %v", err)
 	}
 	logPath := service.LogFilePath()
+	if strings.TrimSpace(*profile) != "" {
+		resolved, err := service.ResolveProfile(*profile)
+		if err != nil {
+			log.Fatalf("resolve profile: %v", err)
+		}
+		logPath = service.ProfileLogPath(resolved.ID)
+	}
 	if err := printTail(logPath, *tailLines, os.Stdout); err != nil {
 		log.Fatalf("read logs: %v", err)
 	}
@@ -168,7 +327,7 @@ func handleLogsCommand(ctx context.Context, args []string) {
 
 func handleProfileCommand(args []string) {
 	if len(args) == 0 {
-		fmt.Fprintln(os.Stderr, "profile command requires a subcommand: list|add|edit|remove|use")
+		fmt.Fprintln(os.Stderr, "profile command requires a subcommand: list|add|edit|remove|use|export|import")
 		os.Exit(1)
 	}
 	service, err := nativeagent.NewService()
@@ -228,6 +387,10 @@ func handleProfileCommand(args []string) {
 			log.Fatalf("set active profile: %v", err)
 		}
 		fmt.Printf("active profile is now %s (%s)\n", active.Name, active.ID)
+	case "export":
+		handleProfileExport(service, args[1:])
+	case "import":
+		handleProfileImport(service, args[1:])
 	default:
 		log.Fatalf("unknown profile subcommand %q", args[0])
 	}
@@ -260,14 +423,17 @@ func parseProfileInput(args []string, create bool) nativeagent.ProfileInput {
 	agentID := fs.String("agent-id", agentIDDefault, "agent ID")
 	mode := fs.String("mode", modeDefault, "connector or legacy_tunnels")
 	connectorID := fs.String("connector-id", "", "connector ID")
-	connectorSecret := fs.String("connector-secret", "", "connector secret (stored in keychain)")
-	agentToken := fs.String("agent-token", "", "legacy agent token (stored in keychain)")
+	connectorSecret := fs.String("connector-secret", "", "connector secret (stored in the secret backend)")
+	agentToken := fs.String("agent-token", "", "legacy agent token (stored in the secret backend)")
+	secretBackend := fs.String("secret-backend", "", "where secrets are stored: keychain or file")
+	updateChannel := fs.String("update-channel", "", "release channel update checks follow: stable or beta")
 	legacyTunnels := fs.String("legacy-tunnels", "", "legacy tunnel mappings: id=url,id2@token=url")
 
 	requestTimeout := fs.String("request-timeout", requestTimeoutDefault, "upstream request timeout")
 	pollWait := fs.String("poll-wait", pollWaitDefault, "gateway pull wait")
 	heartbeat := fs.String("heartbeat-interval", heartbeatDefault, "heartbeat interval")
 	maxRespBytes := fs.Int64("max-response-body-bytes", maxRespDefault, "max response body bytes")
+	maxBytesPerSecond := fs.Int64("max-bytes-per-second", 0, "bandwidth limit to local targets in bytes per second (-1 removes it)")
 	proxyURL := fs.String("proxy-url", "", "outbound proxy URL")
 	noProxy := fs.String("no-proxy", "", "NO_PROXY value")
 	tlsSkipVerify := fs.String("tls-skip-verify", "", "set true or false")
@@ -289,11 +455,14 @@ func parseProfileInput(args []string, create bool) nativeagent.ProfileInput {
 		ConnectorSecret: strings.TrimSpace(*connectorSecret),
 		AgentToken:      strings.TrimSpace(*agentToken),
 		LegacyTunnels:   strings.TrimSpace(*legacyTunnels),
+		SecretBackend:   strings.TrimSpace(*secretBackend),
+		UpdateChannel:   strings.TrimSpace(*updateChannel),
 		Runtime: nativeagent.RuntimeOptions{
 			RequestTimeout:       strings.TrimSpace(*requestTimeout),
 			PollWait:             strings.TrimSpace(*pollWait),
 			HeartbeatInterval:    strings.TrimSpace(*heartbeat),
 			MaxResponseBodyBytes: *maxRespBytes,
+			MaxBytesPerSecond:    *maxBytesPerSecond,
 			ProxyURL:             strings.TrimSpace(*proxyURL),
 			NoProxy:              strings.TrimSpace(*noProxy),
 			CAFile:               strings.TrimSpace(*caFile),
@@ -314,17 +483,23 @@ func parseProfileInput(args []string, create bool) nativeagent.ProfileInput {
 func handlePairCommand(args []string) {
 	fs := flag.NewFlagSet("pair", flag.ExitOnError)
 	token := fs.String("token", "", "pair token")
+	link := fs.String("link", "", "proxer-agent://pair link")
 	profile := fs.String("profile", "", "profile id or name")
 	_ = fs.Parse(args)
 
-	if strings.TrimSpace(*token) == "" {
-		log.Fatalf("--token is required")
+	if strings.TrimSpace(*token) == "" && strings.TrimSpace(*link) == "" {
+		log.Fatalf("--token or --link is required")
 	}
 	service, err := nativeagent.NewService()
 	if err != nil {
 		log.Fatalf("initialize native agent service: %v", err)
 	}
-	updated, err := service.PairProfile(*profile, *token)
+	var updated nativeagent.AgentProfile
+	if strings.TrimSpace(*link) != "" {
+		updated, err = service.PairFromLink(*link, *profile)
+	} else {
+		updated, err = service.PairProfile(*profile, *token)
+	}
 	if err != nil {
 		log.Fatalf("pair profile: %v", err)
 	}
@@ -393,24 +568,45 @@ func handleConfigCommand(args []string) {
 }
 
 func handleUpdateCommand(args []string) {
-	if len(args) == 0 || args[0] != "check" {
-		log.Fatalf("usage: proxer-agent update check")
+	if len(args) == 0 || (args[0] != "check" && args[0] != "apply") {
+		log.Fatalf("usage: proxer-agent update check|apply [--profile <name-or-id>]")
 	}
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	profile := fs.String("profile", "", "profile name or ID (defaults to the active profile)")
+	_ = fs.Parse(args[1:])
+
 	service, err := nativeagent.NewService()
 	if err != nil {
 		log.Fatalf("initialize native agent service: %v", err)
 	}
-	result, err := service.CheckForUpdates()
+	if args[0] == "apply" {
+		result, err := service.ApplyUpdate(strings.TrimSpace(*profile))
+		if err != nil {
+			log.Fatalf("apply update: %v", err)
+		}
+		if result.Path != "" {
+			fmt.Printf("path: %s\n", result.Path)
+		}
+		fmt.Println(result.Message)
+		return
+	}
+	result, err := service.CheckForUpdates(strings.TrimSpace(*profile))
 	if err != nil {
 		log.Fatalf("check updates: %v", err)
 	}
 	fmt.Printf("current version: %s\n", result.CurrentVersion)
+	if strings.TrimSpace(result.Channel) != "" {
+		fmt.Printf("channel: %s\n", result.Channel)
+	}
 	if strings.TrimSpace(result.LatestVersion) != "" {
 		fmt.Printf("latest version: %s\n", result.LatestVersion)
 	}
 	if strings.TrimSpace(result.DownloadURL) != "" {
 		fmt.Printf("download: %s\n", result.DownloadURL)
 	}
+	if strings.TrimSpace(result.SHA256) != "" {
+		fmt.Printf("sha256: %s\n", result.SHA256)
+	}
 	fmt.Println(result.Message)
 }
 
@@ -420,17 +616,27 @@ func printUsage() {
 Commands:
   proxer-agent gui
   proxer-agent run [--profile <name-or-id>]
-  proxer-agent status [--json]
-  proxer-agent logs [--follow] [--tail 200]
+  proxer-agent run --all [--profile <name-or-id>,<name-or-id>]
+  proxer-agent headless [--config /etc/proxer/agent.yaml]
+  proxer-agent expose --dir ./build [--id site] [--token <token>] [--listing]
+  proxer-agent discover [--ports 3000-3010,5173] [--json] [--apply] [--profile <name-or-id>]
+  proxer-agent status [--json] [--all]
+  proxer-agent logs [--follow] [--tail 200] [--profile <name-or-id>]
   proxer-agent profile list
-  proxer-agent profile add --name <name> [--gateway URL] [--mode connector|legacy_tunnels]
+  proxer-agent profile add --name <name> [--gateway URL] [--mode connector|legacy_tunnels] [--update-channel stable|beta]
   proxer-agent profile edit <name-or-id> [flags]
   proxer-agent profile remove <name-or-id>
   proxer-agent profile use <name-or-id>
+  proxer-agent profile export <name-or-id> [--out profile.json] [--with-secrets] [--passphrase-file path]
+  proxer-agent profile import <file> [--name <name>] [--secret-backend keychain|file] [--passphrase-file path]
   proxer-agent pair --token <pair_token> [--profile <name-or-id>]
+  proxer-agent pair --link <proxer-agent://pair link> [--profile <name-or-id>]
   proxer-agent config get <key>
   proxer-agent config set <key> <value>
-  proxer-agent update check
+  proxer-agent update check [--profile <name-or-id>]
+  proxer-agent update apply [--profile <name-or-id>]
+  proxer-agent routes export --tenant <id> [--format yaml|json] [--output file] [--include-secrets]
+  proxer-agent routes import --tenant <id> --file <path> [--dry-run] [--on-conflict fail|skip|overwrite]
 
 Compatibility mode:
   If PROXER_* env vars are present and no managed profile is specified,
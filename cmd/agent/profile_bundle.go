@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/szaher/try/proxer/internal/nativeagent"
+)
+
+func bundlePassphrase(path string) string {
+	if strings.TrimSpace(path) != "" {
+		data, err := os.ReadFile(strings.TrimSpace(path))
+		if err != nil {
+			log.Fatalf("read --passphrase-file: %v", err)
+		}
+		return strings.TrimSpace(string(data))
+	}
+	return os.Getenv("PROXER_AGENT_BUNDLE_PASSPHRASE")
+}
+
+func handleProfileExport(service *nativeagent.Service, args []string) {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		log.Fatalf("usage: proxer-agent profile export <profile> [--out file] [--with-secrets] [--passphrase-file path]")
+	}
+	fs := flag.NewFlagSet("profile export", flag.ExitOnError)
+	out := fs.String("out", "", "write the bundle to this file instead of stdout")
+	withSecrets := fs.Bool("with-secrets", false, "include secrets, encrypted under a passphrase")
+	passphraseFile := fs.String("passphrase-file", "", "file holding the bundle passphrase (default: PROXER_AGENT_BUNDLE_PASSPHRASE)")
+	_ = fs.Parse(args[1:])
+
+	passphrase := ""
+	if *withSecrets {
+		passphrase = bundlePassphrase(*passphraseFile)
+		if passphrase == "" {
+			log.Fatalf("--with-secrets needs --passphrase-file or PROXER_AGENT_BUNDLE_PASSPHRASE")
+		}
+	}
+	bundle, err := service.ExportProfile(args[0], passphrase)
+	if err != nil {
+		log.Fatalf("export profile: %v", err)
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		log.Fatalf("encode profile bundle: %v", err)
+	}
+	data = append(data, '\n')
+	if strings.TrimSpace(*out) == "" {
+		_, _ = os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*out, data, 0o600); err != nil {
+		log.Fatalf("write profile bundle: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "exported profile %s to %s\n", bundle.Profile.Name, *out)
+}
+
+func handleProfileImport(service *nativeagent.Service, args []string) {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		log.Fatalf("usage: proxer-agent profile import <file> [--name <name>] [--secret-backend keychain|file] [--passphrase-file path]")
+	}
+	fs := flag.NewFlagSet("profile import", flag.ExitOnError)
+	name := fs.String("name", "", "import under this profile name")
+	secretBackend := fs.String("secret-backend", "", "where imported secrets are stored: keychain or file")
+	passphraseFile := fs.String("passphrase-file", "", "file holding the bundle passphrase (default: PROXER_AGENT_BUNDLE_PASSPHRASE)")
+	_ = fs.Parse(args[1:])
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		log.Fatalf("read profile bundle: %v", err)
+	}
+	var bundle nativeagent.ProfileBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		log.Fatalf("parse profile bundle: %v", err)
+	}
+	imported, err := service.ImportProfile(bundle, nativeagent.ProfileImportOptions{
+		Name:          *name,
+		Passphrase:    bundlePassphrase(*passphraseFile),
+		SecretBackend: *secretBackend,
+	})
+	if err != nil {
+		log.Fatalf("import profile: %v", err)
+	}
+	fmt.Printf("imported profile %s (%s)\n", imported.Name, imported.ID)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/nativeagent"
+)
+
+type gatewayAPIFlags struct {
+	gateway  *string
+	username *string
+	password *string
+	tenant   *string
+}
+
+func registerGatewayAPIFlags(fs *flag.FlagSet) gatewayAPIFlags {
+	return gatewayAPIFlags{
+		gateway:  fs.String("gateway", "", "gateway base URL (defaults to the active profile)"),
+		username: fs.String("username", os.Getenv("PROXER_USERNAME"), "gateway console username (or PROXER_USERNAME)"),
+		password: fs.String("password", os.Getenv("PROXER_PASSWORD"), "gateway console password (or PROXER_PASSWORD)"),
+		tenant:   fs.String("tenant", "", "tenant id"),
+	}
+}
+
+func handleRoutesCommand(args []string) {
+	if len(args) == 0 {
+		log.Fatalf("routes command requires a subcommand: export|import")
+	}
+	switch args[0] {
+	case "export":
+		handleRoutesExport(args[1:])
+	case "import":
+		handleRoutesImport(args[1:])
+	default:
+		log.Fatalf("unknown routes subcommand %q", args[0])
+	}
+}
+
+func handleRoutesExport(args []string) {
+	fs := flag.NewFlagSet("routes export", flag.ExitOnError)
+	api := registerGatewayAPIFlags(fs)
+	format := fs.String("format", "yaml", "json or yaml")
+	output := fs.String("output", "", "write to file instead of stdout")
+	includeSecrets := fs.Bool("include-secrets", false, "include route access tokens")
+	_ = fs.Parse(args)
+
+	client, baseURL, tenantID := api.login()
+	query := url.Values{"format": {*format}}
+	if *includeSecrets {
+		query.Set("include_secrets", "true")
+	}
+	resp, err := client.Get(baseURL + "/api/tenants/" + url.PathEscape(tenantID) + "/routes:export?" + query.Encode())
+	if err != nil {
+		log.Fatalf("export routes: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("export routes: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if strings.TrimSpace(*output) == "" {
+		_, _ = os.Stdout.Write(body)
+		return
+	}
+	if err := os.WriteFile(*output, body, 0o644); err != nil {
+		log.Fatalf("write %s: %v", *output, err)
+	}
+	fmt.Printf("exported routes for tenant %s to %s\n", tenantID, *output)
+}
+
+func handleRoutesImport(args []string) {
+	fs := flag.NewFlagSet("routes import", flag.ExitOnError)
+	api := registerGatewayAPIFlags(fs)
+	file := fs.String("file", "", "routes document to import (json or yaml, - for stdin)")
+	dryRun := fs.Bool("dry-run", false, "validate and report without applying")
+	onConflict := fs.String("on-conflict", "fail", "fail, skip or overwrite")
+	_ = fs.Parse(args)
+
+	if strings.TrimSpace(*file) == "" {
+		log.Fatalf("--file is required")
+	}
+	var document []byte
+	var err error
+	if *file == "-" {
+		document, err = io.ReadAll(os.Stdin)
+	} else {
+		document, err = os.ReadFile(*file)
+	}
+	if err != nil {
+		log.Fatalf("read %s: %v", *file, err)
+	}
+	format := "yaml"
+	if strings.HasSuffix(strings.ToLower(*file), ".json") {
+		format = "json"
+	}
+
+	client, baseURL, tenantID := api.login()
+	query := url.Values{
+		"format":      {format},
+		"on_conflict": {*onConflict},
+		"dry_run":     {strconv.FormatBool(*dryRun)},
+	}
+	resp, err := client.Post(baseURL+"/api/tenants/"+url.PathEscape(tenantID)+"/routes:import?"+query.Encode(), "application/"+format, bytes.NewReader(document))
+	if err != nil {
+		log.Fatalf("import routes: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var report struct {
+		Applied bool           `json:"applied"`
+		Summary map[string]int `json:"summary"`
+		Results []struct {
+			ID     string `json:"id"`
+			Action string `json:"action"`
+			Error  string `json:"error"`
+		} `json:"results"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &report); err != nil {
+		log.Fatalf("import routes: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	for _, result := range report.Results {
+		if result.Error != "" {
+			fmt.Printf("%-10s %s: %s\n", result.Action, result.ID, result.Error)
+			continue
+		}
+		fmt.Printf("%-10s %s\n", result.Action, result.ID)
+	}
+	switch {
+	case report.Applied:
+		fmt.Println("import applied")
+	case *dryRun:
+		fmt.Println("dry run: no changes applied")
+		if report.Summary["invalid"] > 0 || report.Summary["conflict"] > 0 {
+			os.Exit(1)
+		}
+	default:
+		fmt.Println("import rejected: no changes applied")
+		os.Exit(1)
+	}
+}
+
+func (f gatewayAPIFlags) login() (*http.Client, string, string) {
+	tenantID := strings.TrimSpace(*f.tenant)
+	if tenantID == "" {
+		log.Fatalf("--tenant is required")
+	}
+	if strings.TrimSpace(*f.username) == "" || *f.password == "" {
+		log.Fatalf("--username and --password (or PROXER_USERNAME/PROXER_PASSWORD) are required")
+	}
+	baseURL := strings.TrimRight(strings.TrimSpace(*f.gateway), "/")
+	if baseURL == "" {
+		baseURL = activeProfileGateway()
+	}
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar, Timeout: 60 * time.Second}
+	payload, _ := json.Marshal(map[string]string{"username": strings.TrimSpace(*f.username), "password": *f.password})
+	resp, err := client.Post(baseURL+"/api/auth/login", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		log.Fatalf("login to %s: %v", baseURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Fatalf("login to %s: %s: %s", baseURL, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return client, baseURL, tenantID
+}
+
+func activeProfileGateway() string {
+	if value := strings.TrimSpace(os.Getenv("PROXER_GATEWAY_BASE_URL")); value != "" {
+		return strings.TrimRight(value, "/")
+	}
+	service, err := nativeagent.NewService()
+	if err == nil {
+		if profile, err := service.ActiveProfile(); err == nil && strings.TrimSpace(profile.GatewayBaseURL) != "" {
+			return strings.TrimRight(profile.GatewayBaseURL, "/")
+		}
+	}
+	return "http://127.0.0.1:18080"
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/echoserver"
+)
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:3000", "listen address")
+	name := flag.String("name", "echo", "name echoed as \"service\"")
+	status := flag.Int("status", http.StatusOK, "default response status")
+	latency := flag.Duration("latency", 0, "default delay before each response")
+	flag.Parse()
+
+	if *status < 100 || *status > 999 {
+		log.Fatalf("invalid -status %d", *status)
+	}
+	if *latency < 0 || *latency > echoserver.MaxDelay {
+		log.Fatalf("-latency must be between 0 and %s", echoserver.MaxDelay)
+	}
+
+	logger := log.New(os.Stdout, "[echo] ", log.LstdFlags|log.Lmicroseconds)
+	handler := echoserver.Handler(echoserver.Options{Name: *name, Status: *status, Latency: *latency})
+	server := &http.Server{
+		Addr:              *listen,
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger.Printf("%s %s", r.Method, r.URL.RequestURI())
+			handler.ServeHTTP(w, r)
+		}),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Printf("echoing requests on http://%s", *listen)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatalf("echo server stopped: %v", err)
+	}
+}
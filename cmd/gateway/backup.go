@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/gateway"
+	storepkg "github.com/szaher/try/proxer/internal/store"
+)
+
+func registerStorageFlags(fs *flag.FlagSet) func() storepkg.SnapshotStore {
+	configPath := registerConfigFlag(fs)
+	driver := fs.String("driver", os.Getenv("PROXER_STORAGE_DRIVER"), "storage driver (memory or sqlite; defaults to PROXER_STORAGE_DRIVER or sqlite)")
+	sqlitePath := fs.String("sqlite-path", os.Getenv("PROXER_SQLITE_PATH"), "sqlite database path (defaults to PROXER_SQLITE_PATH or /data/proxer.db)")
+	return func() storepkg.SnapshotStore {
+		selectedDriver := strings.TrimSpace(*driver)
+		path := strings.TrimSpace(*sqlitePath)
+		if strings.TrimSpace(*configPath) != "" {
+			cfg, err := gateway.LoadConfig(*configPath)
+			if err != nil {
+				log.Fatalf("load gateway config: %v", err)
+			}
+			if selectedDriver == "" {
+				selectedDriver = cfg.StorageDriver
+			}
+			if path == "" {
+				path = cfg.SQLitePath
+			}
+		}
+		if selectedDriver == "" {
+			selectedDriver = "sqlite"
+		}
+		if path == "" {
+			path = "/data/proxer.db"
+		}
+		persistence, err := storepkg.NewSnapshotStore(selectedDriver, path)
+		if err != nil {
+			log.Fatalf("open %s storage: %v", selectedDriver, err)
+		}
+		return persistence
+	}
+}
+
+func handleBackupCommand(args []string) {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := fs.String("out", "", "backup archive path (.tar.gz)")
+	open := registerStorageFlags(fs)
+	_ = fs.Parse(args)
+	if strings.TrimSpace(*out) == "" {
+		log.Fatalf("--out is required")
+	}
+
+	persistence := open()
+	payload, err := persistence.Load()
+	if err != nil {
+		log.Fatalf("load persisted state: %v", err)
+	}
+	if len(payload) == 0 {
+		log.Fatalf("%s storage has no persisted state to back up", persistence.Driver())
+	}
+
+	file, err := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		log.Fatalf("create %s: %v", *out, err)
+	}
+	manifest, err := gateway.WriteBackupArchive(file, payload, persistence.Driver())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(*out)
+		log.Fatalf("write backup: %v", err)
+	}
+	fmt.Printf("backup written to %s (driver=%s, saved_at=%s, sha256=%s)\n", *out, manifest.SourceDriver, manifest.SnapshotSavedAt.Format(time.RFC3339), manifest.SnapshotSHA256)
+}
+
+func handleRestoreCommand(args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	in := fs.String("in", "", "backup archive path (.tar.gz)")
+	force := fs.Bool("force", false, "overwrite existing persisted state")
+	open := registerStorageFlags(fs)
+	_ = fs.Parse(args)
+	if strings.TrimSpace(*in) == "" {
+		log.Fatalf("--in is required")
+	}
+
+	file, err := os.Open(*in)
+	if err != nil {
+		log.Fatalf("open %s: %v", *in, err)
+	}
+	payload, manifest, err := gateway.ReadBackupArchive(file)
+	_ = file.Close()
+	if err != nil {
+		log.Fatalf("read backup: %v", err)
+	}
+
+	persistence := open()
+	if persistence.Driver() == "memory" {
+		log.Fatalf("restoring into memory storage has no effect; use --driver sqlite")
+	}
+	existing, err := persistence.Load()
+	if err != nil {
+		log.Fatalf("load persisted state: %v", err)
+	}
+	if len(existing) > 0 && !*force {
+		log.Fatalf("%s storage already has persisted state; pass --force to overwrite", persistence.Driver())
+	}
+	if err := persistence.Save(payload); err != nil {
+		log.Fatalf("save restored state: %v", err)
+	}
+	fmt.Printf("restored backup from %s (source_driver=%s, saved_at=%s) into %s storage\n", *in, manifest.SourceDriver, manifest.SnapshotSavedAt.Format(time.RFC3339), persistence.Driver())
+}
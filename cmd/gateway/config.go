@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/szaher/try/proxer/internal/gateway"
+)
+
+func registerConfigFlag(fs *flag.FlagSet) *string {
+	return fs.String("config", os.Getenv("PROXER_CONFIG"), "gateway config file (.yaml, .yml, .json or .toml; defaults to PROXER_CONFIG)")
+}
+
+func handleConfigCommand(args []string) {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "usage: proxer-gateway config validate [--config gateway.yaml]")
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+	configPath := registerConfigFlag(fs)
+	_ = fs.Parse(args[1:])
+
+	cfg, err := gateway.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
+	source := "environment"
+	if path := strings.TrimSpace(*configPath); path != "" {
+		source = path + " + environment"
+	}
+	fmt.Printf("configuration OK (%s)\n", source)
+	fmt.Printf("  listen_addr:     %s\n", cfg.ListenAddr)
+	if cfg.TLSListenAddr != "" {
+		fmt.Printf("  tls_listen_addr: %s\n", cfg.TLSListenAddr)
+	}
+	fmt.Printf("  public_base_url: %s\n", cfg.PublicBaseURL)
+	fmt.Printf("  storage_driver:  %s\n", cfg.StorageDriver)
+	if cfg.StorageDriver == "sqlite" {
+		fmt.Printf("  sqlite_path:     %s\n", cfg.SQLitePath)
+	}
+	fmt.Printf("  dev_mode:        %t\n", cfg.DevMode)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/gateway"
+)
+
+const devRateLimitRPM = 1000
+
+func handleDevCommand(args []string) {
+	fs := flag.NewFlagSet("dev", flag.ExitOnError)
+	configPath := registerConfigFlag(fs)
+	listen := fs.String("listen", "", "listen address (defaults to PROXER_LISTEN_ADDR or :8080)")
+	appPort := fs.Int("app-port", 3000, "local port the seeded app route forwards to on the agent's machine")
+	_ = fs.Parse(args)
+
+	cfg, err := gateway.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("load gateway config: %v", err)
+	}
+	if addr := strings.TrimSpace(*listen); addr != "" {
+		cfg.ListenAddr = addr
+		if strings.TrimSpace(os.Getenv("PROXER_PUBLIC_BASE_URL")) == "" {
+			cfg.PublicBaseURL = devBaseURL(addr)
+		}
+	}
+	cfg.StorageDriver = "memory"
+	cfg.DevMode = true
+	cfg.PublicSignupEnabled = true
+	cfg.PublicSignupRPM = devRateLimitRPM
+	cfg.AuthRateLimitRPM = devRateLimitRPM
+	cfg.PairTokenTTL = 24 * time.Hour
+
+	logger := log.New(os.Stdout, "[gateway] ", log.LstdFlags|log.Lmicroseconds)
+	server := gateway.NewServer(cfg, logger)
+	seed, err := server.SeedDevData(*appPort)
+	if err != nil {
+		log.Fatalf("seed dev data: %v", err)
+	}
+	printDevSummary(cfg, seed)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := server.Start(ctx); err != nil {
+		logger.Fatalf("gateway stopped with error: %v", err)
+	}
+}
+
+func devBaseURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://" + addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+func printDevSummary(cfg gateway.Config, seed gateway.DevSeed) {
+	fmt.Printf(`
+Proxer dev gateway (in-memory; everything is lost when it stops)
+
+  Console:    %s/
+  Login:      %s / %s
+  Demo route: %s
+              answers right away, no agent needed
+  App route:  %s
+              forwards to 127.0.0.1:%d on the machine running the agent
+              (go run ./cmd/echo-server -listen 127.0.0.1:%d serves one to try)
+
+Pair an agent with connector %q (token valid until %s):
+
+  %s
+
+or open %s
+
+`, strings.TrimRight(cfg.PublicBaseURL, "/"), cfg.SuperAdminUsername, cfg.SuperAdminPassword,
+		seed.DemoRouteURL, seed.AppRouteURL, seed.AppLocalPort, seed.AppLocalPort,
+		seed.ConnectorID, seed.PairToken.ExpiresAt.Local().Format("Jan 2 15:04"), seed.PairCommand, seed.PairDeepLink)
+}
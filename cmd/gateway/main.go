@@ -2,29 +2,102 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/szaher/try/proxer/internal/gateway"
 )
 
 func main() {
-	cfg, err := gateway.LoadConfigFromEnv()
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		switch os.Args[1] {
+		case "backup":
+			handleBackupCommand(os.Args[2:])
+			return
+		case "restore":
+			handleRestoreCommand(os.Args[2:])
+			return
+		case "config":
+			handleConfigCommand(os.Args[2:])
+			return
+		case "dev":
+			handleDevCommand(os.Args[2:])
+			return
+		case "help":
+			printUsage()
+			return
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+			printUsage()
+			os.Exit(1)
+		}
+	}
+
+	fs := flag.NewFlagSet("proxer-gateway", flag.ExitOnError)
+	fs.Usage = printUsage
+	configPath := registerConfigFlag(fs)
+	_ = fs.Parse(os.Args[1:])
+
+	cfg, err := gateway.LoadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("load gateway config: %v", err)
 	}
 
 	logger := log.New(os.Stdout, "[gateway] ", log.LstdFlags|log.Lmicroseconds)
 	server := gateway.NewServer(cfg, logger)
+	server.SetConfigLoader(func() (gateway.Config, error) {
+		return gateway.LoadConfig(*configPath)
+	})
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+	go func() {
+		for range reload {
+			if _, err := server.ReloadConfig("signal:SIGHUP"); err != nil {
+				logger.Printf("config reload failed, keeping current settings: %v", err)
+			}
+		}
+	}()
+
 	logger.Printf("starting proxer gateway on %s", cfg.ListenAddr)
+	if cfg.AdminListenAddr != "" {
+		logger.Printf("serving console and admin API on %s", cfg.AdminListenAddr)
+	}
+	if cfg.AgentListenAddr != "" {
+		logger.Printf("serving agent API on %s", cfg.AgentListenAddr)
+	}
 	if err := server.Start(ctx); err != nil {
 		logger.Fatalf("gateway stopped with error: %v", err)
 	}
 	logger.Printf("gateway shutdown complete")
 }
+
+func printUsage() {
+	fmt.Print(`Proxer Gateway
+
+Commands:
+  proxer-gateway [--config gateway.yaml]           run the gateway
+  proxer-gateway config validate [--config gateway.yaml]
+  proxer-gateway dev [--listen :8080] [--app-port 3000]    in-memory gateway with a demo route and a connector to pair
+  proxer-gateway backup --out state.tar.gz [--config gateway.yaml] [--driver sqlite] [--sqlite-path /data/proxer.db]
+  proxer-gateway restore --in state.tar.gz [--config gateway.yaml] [--driver sqlite] [--sqlite-path /data/proxer.db] [--force]
+
+The config file may be YAML (.yaml, .yml, .json) or TOML (.toml); its keys
+are the PROXER_* variable names in lower case without the prefix, and
+non-empty PROXER_* env vars override it. --config defaults to $PROXER_CONFIG.
+Send SIGHUP (or POST /api/admin/config/reload) to re-read it; limits and
+timeouts apply live, listener and storage changes still need a restart.
+
+backup and restore work on the storage driver directly; stop the gateway
+before restoring so its persistence loop does not overwrite the restored state.
+`)
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/agent"
+	"github.com/szaher/try/proxer/internal/gateway"
+	"github.com/szaher/try/proxer/internal/protocol"
+	"github.com/szaher/try/proxer/pkg/client"
+)
+
+const (
+	loadAgentToken = "loadgen-token"
+	loadTunnelID   = "loadgen"
+)
+
+type options struct {
+	requests     int
+	duration     time.Duration
+	concurrency  int
+	requestBytes int
+	bodyBytes    int
+	warmup       int
+	jsonOutput   bool
+	minRPS       float64
+	maxP99       time.Duration
+}
+
+type report struct {
+	Requests      int64   `json:"requests"`
+	Errors        int64   `json:"errors"`
+	Concurrency   int     `json:"concurrency"`
+	DurationMs    float64 `json:"duration_ms"`
+	RequestsPerS  float64 `json:"requests_per_second"`
+	BytesIn       int64   `json:"bytes_in"`
+	BytesOut      int64   `json:"bytes_out"`
+	P50Ms         float64 `json:"p50_ms"`
+	P90Ms         float64 `json:"p90_ms"`
+	P99Ms         float64 `json:"p99_ms"`
+	MaxMs         float64 `json:"max_ms"`
+	AllocsPerReq  float64 `json:"allocs_per_request"`
+	BytesPerReq   float64 `json:"alloc_bytes_per_request"`
+	GCCycles      uint32  `json:"gc_cycles"`
+	FirstErrorMsg string  `json:"first_error,omitempty"`
+}
+
+func main() {
+	fs := flag.NewFlagSet("proxer-loadgen", flag.ExitOnError)
+	opts := options{}
+	fs.IntVar(&opts.requests, "requests", 5000, "requests to send (ignored when -duration is set)")
+	fs.DurationVar(&opts.duration, "duration", 0, "run for this long instead of a fixed request count")
+	fs.IntVar(&opts.concurrency, "concurrency", 16, "concurrent clients")
+	fs.IntVar(&opts.requestBytes, "request-bytes", 0, "request body size; non-zero sends POSTs")
+	fs.IntVar(&opts.bodyBytes, "body-bytes", 1024, "upstream response body size")
+	fs.IntVar(&opts.warmup, "warmup", 100, "requests sent before measuring")
+	fs.BoolVar(&opts.jsonOutput, "json", false, "print the report as JSON")
+	fs.Float64Var(&opts.minRPS, "min-rps", 0, "fail when throughput is below this")
+	fs.DurationVar(&opts.maxP99, "max-p99", 0, "fail when the p99 latency is above this")
+	_ = fs.Parse(os.Args[1:])
+	if opts.concurrency <= 0 || (opts.requests <= 0 && opts.duration <= 0) {
+		log.Fatalf("-concurrency and -requests or -duration must be > 0")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	proxyURL, err := startHarness(ctx, opts.bodyBytes)
+	if err != nil {
+		log.Fatalf("start harness: %v", err)
+	}
+
+	result := run(proxyURL, opts)
+	if opts.jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(result)
+	} else {
+		printReport(result)
+	}
+
+	var failures []string
+	if result.Errors > 0 {
+		failures = append(failures, fmt.Sprintf("%d requests failed", result.Errors))
+	}
+	if opts.minRPS > 0 && result.RequestsPerS < opts.minRPS {
+		failures = append(failures, fmt.Sprintf("throughput %.0f req/s is below %.0f", result.RequestsPerS, opts.minRPS))
+	}
+	if opts.maxP99 > 0 && result.P99Ms > float64(opts.maxP99)/float64(time.Millisecond) {
+		failures = append(failures, fmt.Sprintf("p99 %.2fms is above %s", result.P99Ms, opts.maxP99))
+	}
+	if len(failures) > 0 {
+		fmt.Fprintln(os.Stderr, "loadgen: "+strings.Join(failures, "; "))
+		os.Exit(1)
+	}
+}
+
+func startHarness(ctx context.Context, bodyBytes int) (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("listen for upstream: %w", err)
+	}
+	payload := bytes.Repeat([]byte("x"), bodyBytes)
+	upstream := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(payload)
+	})}
+	go func() { _ = upstream.Serve(listener) }()
+	go func() {
+		<-ctx.Done()
+		_ = upstream.Close()
+	}()
+
+	server := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		AgentToken:     loadAgentToken,
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 10 * time.Second,
+		StorageDriver:  "memory",
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = server.Start(ctx) }()
+	gatewayAddr := ""
+	if err := waitFor(5*time.Second, func() bool {
+		gatewayAddr = server.Addr()
+		return !strings.HasSuffix(gatewayAddr, ":0")
+	}); err != nil {
+		return "", fmt.Errorf("gateway did not start listening: %w", err)
+	}
+	if err := liftTenantLimits(ctx, "http://"+gatewayAddr); err != nil {
+		return "", fmt.Errorf("lift tenant limits: %w", err)
+	}
+
+	tunnelAgent := agent.New(agent.Config{
+		GatewayBaseURL:    "http://" + gatewayAddr,
+		AgentToken:        loadAgentToken,
+		AgentID:           "loadgen-agent",
+		HeartbeatInterval: time.Second,
+		RequestTimeout:    10 * time.Second,
+		PollWait:          time.Second,
+		Tunnels:           []protocol.TunnelConfig{{ID: loadTunnelID, Target: "http://" + listener.Addr().String()}},
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = tunnelAgent.Run(ctx) }()
+
+	proxyURL := fmt.Sprintf("http://%s/t/%s/", gatewayAddr, loadTunnelID)
+	if err := waitFor(10*time.Second, func() bool {
+		resp, err := http.Get(proxyURL)
+		if err != nil {
+			return false
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}); err != nil {
+		return "", fmt.Errorf("tunnel never served traffic: %w", err)
+	}
+	return proxyURL, nil
+}
+
+// liftTenantLimits moves the default tenant onto a plan whose request rate and
+// traffic cap the run cannot reach, so it measures the gateway, not its limits.
+func liftTenantLimits(ctx context.Context, baseURL string) error {
+	sdk, err := client.New(client.Config{BaseURL: baseURL})
+	if err != nil {
+		return err
+	}
+	if _, err := sdk.Login(ctx, "admin", "admin123"); err != nil {
+		return err
+	}
+	if _, err := sdk.UpsertPlan(ctx, client.Plan{
+		ID:            "loadgen",
+		Name:          "Load test",
+		MaxRoutes:     10,
+		MaxConnectors: 10,
+		MaxRPS:        1e9,
+		MaxMonthlyGB:  1e6,
+	}); err != nil {
+		return err
+	}
+	_, err = sdk.AssignPlan(ctx, gateway.DefaultTenantID, "loadgen")
+	return err
+}
+
+func waitFor(timeout time.Duration, ready func() bool) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if ready() {
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return errors.New("timed out")
+}
+
+func run(proxyURL string, opts options) report {
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:        opts.concurrency,
+			MaxIdleConnsPerHost: opts.concurrency,
+		},
+	}
+	requestBody := bytes.Repeat([]byte("y"), opts.requestBytes)
+	send := func() (int64, error) {
+		method := http.MethodGet
+		var body io.Reader
+		if len(requestBody) > 0 {
+			method = http.MethodPost
+			body = bytes.NewReader(requestBody)
+		}
+		req, err := http.NewRequest(method, proxyURL, body)
+		if err != nil {
+			return 0, err
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		read, err := io.Copy(io.Discard, resp.Body)
+		if err != nil {
+			return read, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return read, fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return read, nil
+	}
+
+	for range opts.warmup {
+		_, _ = send()
+	}
+
+	var (
+		issued   atomic.Int64
+		errCount atomic.Int64
+		bytesIn  atomic.Int64
+		mu       sync.Mutex
+		latency  = make([]time.Duration, 0, max(opts.requests, 1024))
+		firstErr string
+		wg       sync.WaitGroup
+	)
+	deadline := time.Time{}
+	if opts.duration > 0 {
+		deadline = time.Now().Add(opts.duration)
+	}
+	next := func() bool {
+		if !deadline.IsZero() {
+			return time.Now().Before(deadline)
+		}
+		return issued.Add(1) <= int64(opts.requests)
+	}
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	started := time.Now()
+	for range opts.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := make([]time.Duration, 0, 256)
+			for next() {
+				begin := time.Now()
+				read, err := send()
+				local = append(local, time.Since(begin))
+				bytesIn.Add(read)
+				if err != nil && errCount.Add(1) == 1 {
+					mu.Lock()
+					firstErr = err.Error()
+					mu.Unlock()
+				}
+			}
+			mu.Lock()
+			latency = append(latency, local...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(started)
+	runtime.ReadMemStats(&after)
+
+	total := int64(len(latency))
+	result := report{
+		Requests:      total,
+		Errors:        errCount.Load(),
+		Concurrency:   opts.concurrency,
+		DurationMs:    float64(elapsed) / float64(time.Millisecond),
+		BytesIn:       bytesIn.Load(),
+		BytesOut:      total * int64(len(requestBody)),
+		GCCycles:      after.NumGC - before.NumGC,
+		FirstErrorMsg: firstErr,
+	}
+	if total == 0 {
+		return result
+	}
+	result.RequestsPerS = float64(total) / elapsed.Seconds()
+	result.AllocsPerReq = float64(after.Mallocs-before.Mallocs) / float64(total)
+	result.BytesPerReq = float64(after.TotalAlloc-before.TotalAlloc) / float64(total)
+	sort.Slice(latency, func(i, j int) bool { return latency[i] < latency[j] })
+	result.P50Ms = percentileMs(latency, 0.50)
+	result.P90Ms = percentileMs(latency, 0.90)
+	result.P99Ms = percentileMs(latency, 0.99)
+	result.MaxMs = percentileMs(latency, 1)
+	return result
+}
+
+func percentileMs(sorted []time.Duration, p float64) float64 {
+	index := int(float64(len(sorted))*p+0.5) - 1
+	index = min(max(index, 0), len(sorted)-1)
+	return float64(sorted[index]) / float64(time.Millisecond)
+}
+
+func printReport(result report) {
+	fmt.Printf("requests:     %d (%d errors) with %d clients in %.0fms\n", result.Requests, result.Errors, result.Concurrency, result.DurationMs)
+	fmt.Printf("throughput:   %.0f req/s, %d bytes in, %d bytes out\n", result.RequestsPerS, result.BytesIn, result.BytesOut)
+	fmt.Printf("latency:      p50 %.2fms  p90 %.2fms  p99 %.2fms  max %.2fms\n", result.P50Ms, result.P90Ms, result.P99Ms, result.MaxMs)
+	fmt.Printf("allocations:  %.0f allocs/req, %.0f bytes/req, %d GC cycles\n", result.AllocsPerReq, result.BytesPerReq, result.GCCycles)
+	if result.FirstErrorMsg != "" {
+		fmt.Printf("first error:  %s\n", result.FirstErrorMsg)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/szaher/try/proxer/pkg/client"
+)
+
+type connectionFlags struct {
+	gateway  *string
+	token    *string
+	username *string
+	password *string
+}
+
+func registerConnectionFlags(fs *flag.FlagSet) connectionFlags {
+	gatewayDefault := strings.TrimSpace(os.Getenv("PROXER_GATEWAY_BASE_URL"))
+	if gatewayDefault == "" {
+		gatewayDefault = "http://127.0.0.1:18080"
+	}
+	return connectionFlags{
+		gateway:  fs.String("gateway", gatewayDefault, "gateway base URL (or PROXER_GATEWAY_BASE_URL)"),
+		token:    fs.String("token", os.Getenv("PROXER_TOKEN"), "session token from proxerctl login (or PROXER_TOKEN)"),
+		username: fs.String("username", os.Getenv("PROXER_USERNAME"), "console username when no token is set (or PROXER_USERNAME)"),
+		password: fs.String("password", os.Getenv("PROXER_PASSWORD"), "console password when no token is set (or PROXER_PASSWORD)"),
+	}
+}
+
+func (f connectionFlags) connect(ctx context.Context) (*client.Client, error) {
+	c, err := client.New(client.Config{BaseURL: *f.gateway, Token: *f.token})
+	if err != nil {
+		return nil, err
+	}
+	if c.Token() != "" {
+		return c, nil
+	}
+	if strings.TrimSpace(*f.username) == "" || *f.password == "" {
+		return nil, fmt.Errorf("set --token (or PROXER_TOKEN), or --username and --password (or PROXER_USERNAME/PROXER_PASSWORD)")
+	}
+	if _, err := c.Login(ctx, strings.TrimSpace(*f.username), *f.password); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/szaher/try/proxer/pkg/client"
+)
+
+const defaultTenant = "default"
+
+func main() {
+	log.SetFlags(0)
+	args := os.Args[1:]
+	if len(args) == 0 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "login":
+		handleLogin(args[1:])
+	case "get":
+		handleGet(args[1:])
+	case "create":
+		handleCreate(args[1:])
+	case "update":
+		handleUpdate(args[1:])
+	case "delete":
+		handleDelete(args[1:])
+	case "help", "-h", "--help":
+		printUsage()
+	default:
+		printUsage()
+		log.Fatalf("unknown command %q", args[0])
+	}
+}
+
+func handleLogin(args []string) {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	conn := registerConnectionFlags(fs)
+	_ = parseArgs(fs, args)
+
+	*conn.token = ""
+	c, err := conn.connect(context.Background())
+	if err != nil {
+		log.Fatalf("login: %v", err)
+	}
+	fmt.Printf("export PROXER_TOKEN=%s\n", c.Token())
+}
+
+func handleGet(args []string) {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	conn := registerConnectionFlags(fs)
+	tenant := fs.String("tenant", defaultTenant, "tenant of routes")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	positional := parseArgs(fs, args)
+	if len(positional) < 1 || len(positional) > 2 {
+		log.Fatalf("usage: proxerctl get <resource> [id] [--tenant id] [--json]")
+	}
+	res := mustResource(positional[0])
+	c := mustConnect(conn)
+	ctx := context.Background()
+
+	if len(positional) == 2 {
+		item, err := res.get(ctx, c, *tenant, positional[1])
+		if err != nil {
+			log.Fatalf("get %s: %v", res.name, err)
+		}
+		if *asJSON {
+			printJSON(item)
+			return
+		}
+		printTable(res.columns, []map[string]any{item})
+		return
+	}
+
+	items, err := res.list(ctx, c, *tenant)
+	if err != nil {
+		log.Fatalf("get %s: %v", res.name, err)
+	}
+	if *asJSON {
+		printJSON(items)
+		return
+	}
+	printTable(res.columns, items)
+}
+
+func handleCreate(args []string) {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	conn := registerConnectionFlags(fs)
+	tenant := fs.String("tenant", defaultTenant, "tenant of routes")
+	file := fs.String("f", "", "JSON object to create, or - for stdin")
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 || *file == "" {
+		log.Fatalf("usage: proxerctl create <resource> -f <file|-> [--tenant id]")
+	}
+	res := mustResource(positional[0])
+	body := readObject(*file)
+	item, err := res.create(context.Background(), mustConnect(conn), *tenant, body)
+	if err != nil {
+		log.Fatalf("create %s: %v", res.name, err)
+	}
+	printJSON(item)
+}
+
+func handleUpdate(args []string) {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	conn := registerConnectionFlags(fs)
+	tenant := fs.String("tenant", defaultTenant, "tenant of routes")
+	file := fs.String("f", "", "JSON fields to update, or - for stdin")
+	positional := parseArgs(fs, args)
+	if len(positional) != 2 || *file == "" {
+		log.Fatalf("usage: proxerctl update <resource> <id> -f <file|-> [--tenant id]")
+	}
+	res := mustResource(positional[0])
+	body := readObject(*file)
+	item, err := res.update(context.Background(), mustConnect(conn), *tenant, positional[1], body)
+	if err != nil {
+		log.Fatalf("update %s %s: %v", res.name, positional[1], err)
+	}
+	printJSON(item)
+}
+
+func handleDelete(args []string) {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	conn := registerConnectionFlags(fs)
+	tenant := fs.String("tenant", defaultTenant, "tenant of routes")
+	positional := parseArgs(fs, args)
+	if len(positional) != 2 {
+		log.Fatalf("usage: proxerctl delete <resource> <id> [--tenant id]")
+	}
+	res := mustResource(positional[0])
+	if err := res.remove(context.Background(), mustConnect(conn), *tenant, positional[1]); err != nil {
+		log.Fatalf("delete %s %s: %v", res.name, positional[1], err)
+	}
+	fmt.Printf("deleted %s %s\n", strings.TrimSuffix(res.name, "s"), positional[1])
+}
+
+// parseArgs parses flags wherever they appear and returns the positional
+// arguments, so "get routes --json" and "get --json routes" both work.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		_ = fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func mustResource(name string) resource {
+	res, err := lookupResource(name)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	return res
+}
+
+func mustConnect(conn connectionFlags) *client.Client {
+	c, err := conn.connect(context.Background())
+	if err != nil {
+		log.Fatalf("connect: %v", err)
+	}
+	return c
+}
+
+func readObject(path string) map[string]any {
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		log.Fatalf("read %s: %v", path, err)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err != nil {
+		log.Fatalf("parse %s: expected a JSON object: %v", path, err)
+	}
+	if body == nil {
+		body = map[string]any{}
+	}
+	return body
+}
+
+func printJSON(value any) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		log.Fatalf("encode output: %v", err)
+	}
+	fmt.Println(string(data))
+}
+
+func printTable(columns []string, items []map[string]any) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, item := range items {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = formatCell(item[column])
+		}
+		fmt.Fprintln(writer, strings.Join(cells, "\t"))
+	}
+	_ = writer.Flush()
+}
+
+func formatCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "-"
+	case string:
+		if v == "" {
+			return "-"
+		}
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func printUsage() {
+	fmt.Print(`Proxer gateway admin CLI
+
+Commands:
+  proxerctl login [--gateway URL] --username <user> --password <password>
+  proxerctl get <resource> [id] [--tenant id] [--json]
+  proxerctl create <resource> -f <file|-> [--tenant id]
+  proxerctl update <resource> <id> -f <file|-> [--tenant id]
+  proxerctl delete <resource> <id> [--tenant id]
+
+Resources: tenants, routes, connectors, users, plans
+
+Authentication:
+  Every command accepts --gateway, --token, --username and --password, with
+  defaults from PROXER_GATEWAY_BASE_URL, PROXER_TOKEN, PROXER_USERNAME and
+  PROXER_PASSWORD. "proxerctl login" prints a session token to export as
+  PROXER_TOKEN so later commands skip the password login.
+`)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/szaher/try/proxer/pkg/client"
+)
+
+type resource struct {
+	name         string
+	aliases      []string
+	listKey      string
+	itemKey      string
+	idField      string
+	columns      []string
+	tenantScoped bool
+	upsert       bool
+	deletable    bool
+	basePath     string
+	deleteMsg    string
+}
+
+var resources = []resource{
+	{
+		name: "tenants", aliases: []string{"tenant"},
+		listKey: "tenants", itemKey: "tenant", idField: "id",
+		columns:  []string{"id", "name", "created_at"},
+		upsert:   true,
+		basePath: "/api/tenants", deletable: true,
+	},
+	{
+		name: "routes", aliases: []string{"route"},
+		listKey: "routes", itemKey: "route", idField: "id",
+		columns:      []string{"id", "target", "connector_id", "public_url"},
+		tenantScoped: true, upsert: true, deletable: true,
+	},
+	{
+		name: "connectors", aliases: []string{"connector"},
+		listKey: "connectors", itemKey: "connector", idField: "id",
+		columns:  []string{"id", "tenant_id", "name", "connected", "agent_id"},
+		basePath: "/api/connectors", deletable: true,
+	},
+	{
+		name: "users", aliases: []string{"user"},
+		listKey: "users", itemKey: "user", idField: "username",
+		columns:   []string{"username", "role", "tenant_id", "status"},
+		basePath:  "/api/admin/users",
+		deleteMsg: `users cannot be deleted; disable them with: proxerctl update users <username> -f - <<< '{"status":"disabled"}'`,
+	},
+	{
+		name: "plans", aliases: []string{"plan"},
+		listKey: "plans", itemKey: "plan", idField: "id",
+		columns:   []string{"id", "name", "max_routes", "max_connectors", "max_rps"},
+		basePath:  "/api/admin/plans",
+		deleteMsg: "plans cannot be deleted; assign tenants another plan instead",
+	},
+}
+
+func lookupResource(name string) (resource, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, res := range resources {
+		if res.name == name {
+			return res, nil
+		}
+		for _, alias := range res.aliases {
+			if alias == name {
+				return res, nil
+			}
+		}
+	}
+	names := make([]string, 0, len(resources))
+	for _, res := range resources {
+		names = append(names, res.name)
+	}
+	return resource{}, fmt.Errorf("unknown resource %q; expected one of %s", name, strings.Join(names, ", "))
+}
+
+func (r resource) collectionPath(tenant string) string {
+	if r.tenantScoped {
+		return "/api/tenants/" + url.PathEscape(tenant) + "/routes"
+	}
+	return r.basePath
+}
+
+func (r resource) itemPath(tenant, id string) string {
+	return r.collectionPath(tenant) + "/" + url.PathEscape(id)
+}
+
+func (r resource) list(ctx context.Context, c *client.Client, tenant string) ([]map[string]any, error) {
+	var payload map[string]any
+	if err := c.Do(ctx, http.MethodGet, r.collectionPath(tenant), nil, &payload); err != nil {
+		return nil, err
+	}
+	raw, _ := payload[r.listKey].([]any)
+	items := make([]map[string]any, 0, len(raw))
+	for _, entry := range raw {
+		if item, ok := entry.(map[string]any); ok {
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return fmt.Sprint(items[i][r.idField]) < fmt.Sprint(items[j][r.idField])
+	})
+	return items, nil
+}
+
+func (r resource) get(ctx context.Context, c *client.Client, tenant, id string) (map[string]any, error) {
+	items, err := r.list(ctx, c, tenant)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if strings.EqualFold(fmt.Sprint(item[r.idField]), id) {
+			return item, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %q not found", strings.TrimSuffix(r.name, "s"), id)
+}
+
+func (r resource) create(ctx context.Context, c *client.Client, tenant string, body map[string]any) (map[string]any, error) {
+	return r.send(ctx, c, http.MethodPost, r.collectionPath(tenant), body)
+}
+
+func (r resource) update(ctx context.Context, c *client.Client, tenant, id string, body map[string]any) (map[string]any, error) {
+	if r.upsert {
+		// Upserts replace the whole object, so apply the fields on top of the
+		// current one to keep update a partial change like PATCH.
+		current, err := r.get(ctx, c, tenant, id)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range body {
+			current[key] = value
+		}
+		current[r.idField] = id
+		return r.send(ctx, c, http.MethodPost, r.collectionPath(tenant), current)
+	}
+	return r.send(ctx, c, http.MethodPatch, r.itemPath(tenant, id), body)
+}
+
+func (r resource) remove(ctx context.Context, c *client.Client, tenant, id string) error {
+	if !r.deletable {
+		return errors.New(r.deleteMsg)
+	}
+	return c.Do(ctx, http.MethodDelete, r.itemPath(tenant, id), nil, nil)
+}
+
+func (r resource) send(ctx context.Context, c *client.Client, method, path string, body map[string]any) (map[string]any, error) {
+	var payload map[string]any
+	if err := c.Do(ctx, method, path, body, &payload); err != nil {
+		return nil, err
+	}
+	if item, ok := payload[r.itemKey].(map[string]any); ok {
+		return item, nil
+	}
+	return payload, nil
+}
@@ -13,6 +13,8 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,23 +24,71 @@ import (
 	"github.com/szaher/try/proxer/internal/protocol"
 )
 
-var errSessionExpired = errors.New("agent session expired")
+var (
+	errSessionExpired      = errors.New("agent session expired")
+	errGatewayShuttingDown = errors.New("gateway is shutting down")
+	errNotResumable        = errors.New("no session to resume")
+)
 
-type Agent struct {
-	cfg        Config
-	logger     *log.Logger
-	httpClient *http.Client
-	tunnels    map[string]protocol.TunnelConfig
-	eventHook  RuntimeEventHook
+const (
+	UpstreamHTTP2Auto = "auto"
+	UpstreamHTTP2H2C  = "h2c"
+	UpstreamHTTP2Off  = "off"
+)
 
-	sessionMu sync.RWMutex
-	sessionID string
+const (
+	TransportAuto = "auto"
+	TransportJSON = "json"
+)
+
+type Agent struct {
+	cfg            Config
+	logger         *log.Logger
+	httpClient     *http.Client
+	upstreamClient *http.Client
+	eventHook      RuntimeEventHook
+
+	tunnelsMu      sync.RWMutex
+	tunnels        map[string]protocol.TunnelConfig
+	tunnelList     []protocol.TunnelConfig
+	tunnelsChanged chan struct{}
+
+	sessionMu       sync.RWMutex
+	sessionID       string
+	resumeSessionID string
+	routes          []protocol.TunnelRoute
+	encoding        string
+	capabilities    []string
+	secretRotateAt  time.Time
+	pullCancel      context.CancelFunc
+
+	health    healthState
+	local     localClients
+	conn      connStats
+	offline   offlineQueue
+	forwards  forwardState
+	bandwidth *httpx.BandwidthLimiter
 }
 
 func New(cfg Config, logger *log.Logger) *Agent {
 	if logger == nil {
 		logger = log.New(io.Discard, "", 0)
 	}
+	if cfg.HealthCheckInterval <= 0 {
+		cfg.HealthCheckInterval = 10 * time.Second
+	}
+	if cfg.HealthCheckThreshold <= 0 {
+		cfg.HealthCheckThreshold = 3
+	}
+	if strings.TrimSpace(cfg.OfflineQueueDir) == "" {
+		cfg.OfflineQueueDir = defaultOfflineQueueDir()
+	}
+	if cfg.OfflineQueueMax <= 0 {
+		cfg.OfflineQueueMax = defaultOfflineQueueMax
+	}
+	if cfg.OfflineReplayInterval <= 0 {
+		cfg.OfflineReplayInterval = defaultOfflineReplayPeriod
+	}
 	tunnelMap := make(map[string]protocol.TunnelConfig, len(cfg.Tunnels))
 	for _, tunnel := range cfg.Tunnels {
 		tunnelMap[tunnel.ID] = tunnel
@@ -76,14 +126,28 @@ func New(cfg Config, logger *log.Logger) *Agent {
 		transport.TLSClientConfig = tlsConfig
 	}
 
+	upstreamTransport := transport.Clone()
+	configureUpstreamProtocols(upstreamTransport, cfg.UpstreamHTTP2)
+
+	var bandwidth *httpx.BandwidthLimiter
+	if cfg.MaxBytesPerSecond > 0 {
+		bandwidth = httpx.NewBandwidthLimiter(cfg.MaxBytesPerSecond)
+	}
 	return &Agent{
 		cfg:    cfg,
 		logger: logger,
 		httpClient: &http.Client{
 			Transport: transport,
 		},
-		tunnels:   tunnelMap,
-		eventHook: cfg.EventHook,
+		upstreamClient: &http.Client{
+			Transport: upstreamTransport,
+		},
+		tunnels:        tunnelMap,
+		tunnelList:     append([]protocol.TunnelConfig(nil), cfg.Tunnels...),
+		tunnelsChanged: make(chan struct{}, 1),
+		eventHook:      cfg.EventHook,
+		health:         healthState{reports: make(map[string]protocol.TargetHealth)},
+		bandwidth:      bandwidth,
 	}
 }
 
@@ -92,6 +156,18 @@ func (a *Agent) Run(ctx context.Context) error {
 	heartbeatDone := make(chan struct{})
 	defer close(heartbeatDone)
 	go a.heartbeatLoop(ctx, heartbeatDone)
+	if len(a.cfg.HealthChecks) > 0 {
+		go a.healthLoop(ctx, heartbeatDone)
+	}
+	if len(a.cfg.OfflineQueueTargets) > 0 {
+		go a.offlineReplayLoop(ctx, heartbeatDone)
+	}
+	if a.cfg.Docker != nil {
+		go a.dockerLoop(ctx, heartbeatDone)
+	}
+	if len(a.cfg.Forwards) > 0 {
+		go a.forwardLoop(ctx, heartbeatDone)
+	}
 
 	backoff := time.Second
 	for {
@@ -101,16 +177,42 @@ func (a *Agent) Run(ctx context.Context) error {
 			return nil
 		}
 
+		select {
+		case <-a.tunnelsChanged:
+			// The gateway resumes a live session with its old tunnels, so a
+			// new tunnel set takes a fresh registration.
+			a.sessionMu.Lock()
+			a.sessionID = ""
+			a.resumeSessionID = ""
+			a.sessionMu.Unlock()
+		default:
+		}
+		if a.getSessionID() == "" && a.cfg.Docker != nil && len(a.currentTunnels()) == 0 {
+			a.emit(RuntimeStateStarting, "waiting for tunnels", nil)
+			select {
+			case <-ctx.Done():
+			case <-a.tunnelsChanged:
+			}
+			continue
+		}
+
 		if a.getSessionID() == "" {
-			if err := a.register(ctx); err != nil {
+			err := a.resume(ctx)
+			if errors.Is(err, errNotResumable) {
+				err = a.register(ctx)
+			}
+			if err != nil {
 				a.logger.Printf("agent registration failed: %v", err)
+				a.conn.setBackoff(backoff)
 				a.emit(RuntimeStateDegraded, "registration failed", err)
 				if err := waitWithContext(ctx, backoff); err != nil {
 					a.emit(RuntimeStateStopping, "agent stopping", nil)
 					a.emit(RuntimeStateStopped, "agent stopped", nil)
 					return nil
 				}
-				if backoff < 10*time.Second {
+				// Keep probing a restarting gateway often enough to resume
+				// soon after it is back.
+				if backoff < a.maxRegisterBackoff() {
 					backoff *= 2
 				}
 				continue
@@ -119,6 +221,7 @@ func (a *Agent) Run(ctx context.Context) error {
 			a.emit(RuntimeStateRunning, "agent registered", nil)
 		}
 
+		a.rotateSecretIfDue(ctx)
 		err := a.pullAndProcess(ctx)
 		if err == nil {
 			backoff = time.Second
@@ -128,11 +231,17 @@ func (a *Agent) Run(ctx context.Context) error {
 		if errors.Is(err, errSessionExpired) {
 			a.logger.Printf("session expired; re-registering")
 			a.emit(RuntimeStateDegraded, "session expired", err)
-			a.setSessionID("")
+			a.dropSession()
 			continue
 		}
 
+		if errors.Is(err, errGatewayShuttingDown) {
+			// The gateway forgets sessions on restart; resume once it is
+			// back.
+			a.dropSession()
+		}
 		a.logger.Printf("agent poll loop error: %v", err)
+		a.conn.setBackoff(backoff)
 		a.emit(RuntimeStateDegraded, "poll loop error", err)
 		if err := waitWithContext(ctx, backoff); err != nil {
 			a.emit(RuntimeStateStopping, "agent stopping", nil)
@@ -142,6 +251,7 @@ func (a *Agent) Run(ctx context.Context) error {
 		if backoff < 10*time.Second {
 			backoff *= 2
 		}
+		a.conn.setBackoff(0)
 	}
 }
 
@@ -150,18 +260,7 @@ func (a *Agent) register(ctx context.Context) error {
 		return err
 	}
 
-	registerReq := protocol.RegisterRequest{
-		AgentID: a.cfg.AgentID,
-	}
-	if a.isConnectorMode() {
-		registerReq.ConnectorID = a.cfg.ConnectorID
-		registerReq.ConnectorSecret = a.cfg.ConnectorSecret
-	} else {
-		registerReq.Token = a.cfg.AgentToken
-		registerReq.Tunnels = a.cfg.Tunnels
-	}
-
-	requestBody, err := json.Marshal(registerReq)
+	requestBody, err := json.Marshal(a.registerRequest())
 	if err != nil {
 		return fmt.Errorf("encode register payload: %w", err)
 	}
@@ -181,13 +280,48 @@ func (a *Agent) register(ctx context.Context) error {
 	}
 	defer response.Body.Close()
 
+	if response.StatusCode == http.StatusUpgradeRequired {
+		var rejection struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(&rejection)
+		return fmt.Errorf("gateway requires a newer agent: %s; update with `proxer-agent update apply`", strings.TrimSpace(rejection.Message))
+	}
 	if response.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
 		return fmt.Errorf("register rejected (status %d): %s", response.StatusCode, strings.TrimSpace(string(body)))
 	}
 
+	if err := a.acceptSession(response.Body, "registered with gateway"); err != nil {
+		return err
+	}
+	a.conn.recordSession(false)
+	return nil
+}
+
+func (a *Agent) registerRequest() protocol.RegisterRequest {
+	registerReq := protocol.RegisterRequest{
+		AgentID:         a.cfg.AgentID,
+		ProtocolVersion: protocol.ProtocolVersion,
+		Capabilities:    protocol.Capabilities,
+		AgentVersion:    a.cfg.Version,
+	}
+	if a.isConnectorMode() {
+		registerReq.ConnectorID = a.cfg.ConnectorID
+		registerReq.ConnectorSecret = a.cfg.ConnectorSecret
+	} else {
+		registerReq.Token = a.cfg.AgentToken
+		registerReq.Tunnels = a.currentTunnels()
+	}
+	if a.cfg.Transport != TransportJSON {
+		registerReq.Encodings = []string{protocol.EncodingFrame}
+	}
+	return registerReq
+}
+
+func (a *Agent) acceptSession(body io.Reader, logPrefix string) error {
 	var payload protocol.RegisterResponse
-	if err := json.NewDecoder(response.Body).Decode(&payload); err != nil {
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
 		return fmt.Errorf("decode register response: %w", err)
 	}
 	if !payload.Accepted || strings.TrimSpace(payload.SessionID) == "" {
@@ -195,10 +329,79 @@ func (a *Agent) register(ctx context.Context) error {
 	}
 
 	a.setSessionID(payload.SessionID)
-	a.logger.Printf("registered with gateway: session=%s tunnels=%d", payload.SessionID, len(payload.Tunnels))
+	a.setRoutes(payload.Tunnels)
+	a.setEncoding(payload.Encoding)
+	a.setCapabilities(payload.Capabilities)
+	if a.isConnectorMode() {
+		a.scheduleSecretRotation(payload.SecretRotateAfter, payload.SecretExpiresAt)
+	}
+	a.logger.Printf("%s: session=%s tunnels=%d", logPrefix, payload.SessionID, len(payload.Tunnels))
+	if payload.ProtocolVersion > 0 {
+		a.logger.Printf("gateway protocol v%d, capabilities: %s", payload.ProtocolVersion, strings.Join(payload.Capabilities, ","))
+	}
+	for _, route := range payload.Tunnels {
+		if route.ExpiresAt != nil {
+			a.logger.Printf("route %s expires in %s (%s)", route.ID, time.Until(*route.ExpiresAt).Round(time.Second), route.ExpiresAt.Format(time.RFC3339))
+		}
+	}
 	return nil
 }
 
+func (a *Agent) resume(ctx context.Context) error {
+	a.sessionMu.RLock()
+	previous := a.resumeSessionID
+	a.sessionMu.RUnlock()
+	if previous == "" {
+		return errNotResumable
+	}
+
+	requestBody, err := json.Marshal(protocol.ResumeRequest{SessionID: previous, RegisterRequest: a.registerRequest()})
+	if err != nil {
+		return fmt.Errorf("encode resume payload: %w", err)
+	}
+	requestCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(requestCtx, http.MethodPost, strings.TrimRight(a.cfg.GatewayBaseURL, "/")+"/api/agent/resume", bytes.NewReader(requestBody))
+	if err != nil {
+		return fmt.Errorf("build resume request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := a.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("post resume request: %w", err)
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusOK:
+		if err := a.acceptSession(response.Body, "resumed gateway session"); err != nil {
+			return err
+		}
+		a.conn.recordSession(true)
+		return nil
+	case response.StatusCode >= http.StatusBadRequest && response.StatusCode < http.StatusInternalServerError:
+		// Includes 409 session_not_resumable and gateways without resume.
+		a.sessionMu.Lock()
+		a.resumeSessionID = ""
+		a.sessionMu.Unlock()
+		return errNotResumable
+	default:
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+		return fmt.Errorf("resume rejected (status %d): %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+}
+
+func (a *Agent) maxRegisterBackoff() time.Duration {
+	a.sessionMu.RLock()
+	defer a.sessionMu.RUnlock()
+	if a.resumeSessionID != "" {
+		return 2 * time.Second
+	}
+	return 10 * time.Second
+}
+
 func (a *Agent) ensureConnectorCredentials(ctx context.Context) error {
 	if strings.TrimSpace(a.cfg.ConnectorID) != "" && strings.TrimSpace(a.cfg.ConnectorSecret) != "" {
 		return nil
@@ -275,12 +478,21 @@ func (a *Agent) pullAndProcess(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("build pull request: %w", err)
 	}
+	framed := a.getEncoding() == protocol.EncodingFrame
+	if framed {
+		request.Header.Set("Accept", protocol.FrameContentType)
+	}
 
+	a.setPullCancel(cancel)
 	response, err := a.httpClient.Do(request)
+	a.setPullCancel(nil)
 	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			return nil
 		}
+		// The gateway may be restarting. Resuming confirms the session, or
+		// restores it, in one round trip once the gateway answers again.
+		a.dropSession()
 		return fmt.Errorf("pull request failed: %w", err)
 	}
 	defer response.Body.Close()
@@ -288,15 +500,29 @@ func (a *Agent) pullAndProcess(ctx context.Context) error {
 	switch response.StatusCode {
 	case http.StatusOK:
 		var payload protocol.PullResponse
-		if err := json.NewDecoder(response.Body).Decode(&payload); err != nil {
+		if strings.HasPrefix(response.Header.Get("Content-Type"), protocol.FrameContentType) {
+			payload, err = protocol.ReadPullFrame(response.Body)
+		} else {
+			err = json.NewDecoder(response.Body).Decode(&payload)
+		}
+		if err != nil {
 			return fmt.Errorf("decode pull response: %w", err)
 		}
 		if payload.Request == nil {
 			return nil
 		}
-		proxyResp := a.handleProxyRequest(payload.Request)
+		if payload.Request.Stream == protocol.StreamTCP {
+			return a.handleStream(ctx, sessionID, payload.Request)
+		}
+		proxyResp, reason := a.handleProxyRequest(ctx, sessionID, payload.Request)
+		if reason != "" {
+			a.logger.Printf("proxy request_id=%s route=%s cancelled by gateway: %s", payload.Request.RequestID, payload.Request.TunnelID, reason)
+			return nil
+		}
+		a.logProxyRequest(payload.Request, proxyResp)
+		a.conn.recordRequest(proxyResp.Error != "")
 		if err := a.submitResponse(ctx, sessionID, proxyResp); err != nil {
-			return err
+			return fmt.Errorf("request_id=%s: %w", proxyResp.RequestID, err)
 		}
 		return nil
 	case http.StatusNoContent:
@@ -305,17 +531,36 @@ func (a *Agent) pullAndProcess(ctx context.Context) error {
 		return errSessionExpired
 	default:
 		body, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+		var apiErr struct {
+			Code string `json:"code"`
+		}
+		if response.StatusCode == http.StatusServiceUnavailable && json.Unmarshal(body, &apiErr) == nil && apiErr.Code == "gateway_shutting_down" {
+			return errGatewayShuttingDown
+		}
 		return fmt.Errorf("pull rejected (status %d): %s", response.StatusCode, strings.TrimSpace(string(body)))
 	}
 }
 
 func (a *Agent) submitResponse(ctx context.Context, sessionID string, proxyResp *protocol.ProxyResponse) error {
-	requestBody, err := json.Marshal(protocol.SubmitResponseRequest{
+	payload := protocol.SubmitResponseRequest{
 		SessionID: sessionID,
 		Response:  proxyResp,
-	})
-	if err != nil {
-		return fmt.Errorf("encode submit response payload: %w", err)
+	}
+	contentType := "application/json"
+	var requestBody []byte
+	if a.getEncoding() == protocol.EncodingFrame {
+		var buffer bytes.Buffer
+		if err := protocol.WriteSubmitResponseFrame(&buffer, payload); err != nil {
+			return fmt.Errorf("encode submit response payload: %w", err)
+		}
+		requestBody = buffer.Bytes()
+		contentType = protocol.FrameContentType
+	} else {
+		var err error
+		requestBody, err = json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("encode submit response payload: %w", err)
+		}
 	}
 
 	requestCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
@@ -325,7 +570,7 @@ func (a *Agent) submitResponse(ctx context.Context, sessionID string, proxyResp
 	if err != nil {
 		return fmt.Errorf("build submit response request: %w", err)
 	}
-	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Content-Type", contentType)
 
 	response, err := a.httpClient.Do(request)
 	if err != nil {
@@ -358,13 +603,19 @@ func (a *Agent) heartbeatLoop(ctx context.Context, done <-chan struct{}) {
 			if sessionID == "" {
 				continue
 			}
+			start := time.Now()
 			if err := a.sendHeartbeat(ctx, sessionID); err != nil {
 				if errors.Is(err, errSessionExpired) {
-					a.setSessionID("")
+					a.dropSession()
 					continue
 				}
 				a.logger.Printf("heartbeat error: %v", err)
+				continue
 			}
+			a.conn.recordHeartbeat(time.Since(start))
+			// Publishes the request counts and round trip at most once per
+			// heartbeat rather than on every request.
+			a.refreshStats()
 		}
 	}
 }
@@ -373,6 +624,7 @@ func (a *Agent) sendHeartbeat(ctx context.Context, sessionID string) error {
 	requestBody, err := json.Marshal(protocol.HeartbeatRequest{
 		SessionID: sessionID,
 		AgentID:   a.cfg.AgentID,
+		Health:    a.healthReports(),
 	})
 	if err != nil {
 		return fmt.Errorf("encode heartbeat payload: %w", err)
@@ -403,7 +655,19 @@ func (a *Agent) sendHeartbeat(ctx context.Context, sessionID string) error {
 	return nil
 }
 
-func (a *Agent) handleProxyRequest(proxyReq *protocol.ProxyRequest) *protocol.ProxyResponse {
+func (a *Agent) handleProxyRequest(ctx context.Context, sessionID string, proxyReq *protocol.ProxyRequest) (*protocol.ProxyResponse, string) {
+	if !a.gatewaySupports(protocol.CapabilityCancel) {
+		response, _ := a.forwardLocal(context.Background(), proxyReq, true)
+		return response, ""
+	}
+	requestCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelled := a.watchCancel(ctx, sessionID, proxyReq.RequestID, cancel)
+	response, _ := a.forwardLocal(requestCtx, proxyReq, true)
+	return response, cancelled.stop()
+}
+
+func (a *Agent) forwardLocal(ctx context.Context, proxyReq *protocol.ProxyRequest, capture bool) (*protocol.ProxyResponse, error) {
 	start := time.Now()
 	response := &protocol.ProxyResponse{
 		RequestID: proxyReq.RequestID,
@@ -413,41 +677,80 @@ func (a *Agent) handleProxyRequest(proxyReq *protocol.ProxyRequest) *protocol.Pr
 	}
 
 	var err error
-	targetBase := ""
+	targetBase, socketPath := "", ""
+	var targetTLS *protocol.LocalTLS
 	if proxyReq.LocalTarget != nil {
 		targetBase, err = buildLocalTargetBaseURL(proxyReq.LocalTarget)
 		if err != nil {
 			response.Status = http.StatusBadRequest
 			response.Error = fmt.Sprintf("invalid local target: %v", err)
 			response.LatencyMs = time.Since(start).Milliseconds()
-			return response
+			return response, nil
 		}
+		socketPath = strings.TrimSpace(proxyReq.LocalTarget.Socket)
+		targetTLS = proxyReq.LocalTarget.TLS
 	} else {
-		tunnel, ok := a.tunnels[proxyReq.TunnelID]
+		tunnel, ok := a.tunnel(proxyReq.TunnelID)
 		if !ok {
 			response.Status = http.StatusNotFound
 			response.Error = fmt.Sprintf("unknown tunnel id %q", proxyReq.TunnelID)
 			response.LatencyMs = time.Since(start).Milliseconds()
-			return response
+			return response, nil
+		}
+		if dir, listing, ok := parseFileTunnelTarget(tunnel.Target); ok {
+			return a.serveFileTunnel(proxyReq, dir, listing), nil
+		}
+		targetBase, targetTLS = tunnel.Target, tunnel.TLS
+		if path, ok := parseUnixSocketTarget(tunnel.Target); ok {
+			targetBase, socketPath = "http://"+unixSocketHost, path
 		}
-		targetBase = tunnel.Target
+	}
+	client, err := a.localClient(socketPath, targetTLS)
+	if err != nil {
+		response.Error = fmt.Sprintf("invalid local TLS settings: %v", err)
+		response.LatencyMs = time.Since(start).Milliseconds()
+		return response, nil
 	}
 
 	targetURL, err := buildTargetURL(targetBase, proxyReq.Path, proxyReq.Query)
 	if err != nil {
 		response.Error = fmt.Sprintf("build target URL: %v", err)
 		response.LatencyMs = time.Since(start).Milliseconds()
-		return response
+		return response, nil
 	}
 
-	requestCtx, cancel := context.WithTimeout(context.Background(), a.cfg.RequestTimeout)
+	requestTimeout := a.cfg.RequestTimeout
+	if proxyReq.TimeoutMs > 0 {
+		requestTimeout = time.Duration(proxyReq.TimeoutMs) * time.Millisecond
+	}
+	deadlineCtx, cancel := context.WithTimeout(ctx, requestTimeout)
 	defer cancel()
-
-	outboundReq, err := http.NewRequestWithContext(requestCtx, proxyReq.Method, targetURL, bytes.NewReader(proxyReq.Body))
+	requestCtx, watchdog, cancelIdle := httpx.WithIdleTimeout(deadlineCtx, time.Duration(proxyReq.IdleTimeoutMs)*time.Millisecond)
+	defer cancelIdle()
+	requestCtx, headerTimer, cancelHeaderTimer := httpx.WithResponseHeaderTimeout(requestCtx, time.Duration(proxyReq.HeaderTimeoutMs)*time.Millisecond)
+	defer cancelHeaderTimer()
+
+	var body io.Reader = bytes.NewReader(proxyReq.Body)
+	var upload *countingReader
+	if proxyReq.Upload {
+		uploadBody, err := a.openUpload(requestCtx, a.getSessionID(), proxyReq.RequestID)
+		if err != nil {
+			response.Error = err.Error()
+			response.LatencyMs = time.Since(start).Milliseconds()
+			return response, nil
+		}
+		defer uploadBody.Close()
+		upload = &countingReader{r: watchdog.Reader(uploadBody)}
+		body = headerTimer.Body(httpx.ThrottleReader(requestCtx, upload, a.bandwidth))
+	}
+	outboundReq, err := http.NewRequestWithContext(requestCtx, proxyReq.Method, targetURL, body)
 	if err != nil {
 		response.Error = fmt.Sprintf("construct outbound request: %v", err)
 		response.LatencyMs = time.Since(start).Milliseconds()
-		return response
+		return response, nil
+	}
+	if upload != nil {
+		outboundReq.ContentLength = proxyReq.UploadBytes
 	}
 
 	for header, values := range proxyReq.Headers {
@@ -460,38 +763,114 @@ func (a *Agent) handleProxyRequest(proxyReq *protocol.ProxyRequest) *protocol.Pr
 	}
 	outboundReq.Header.Set("X-Proxer-Tunnel-ID", proxyReq.TunnelID)
 	outboundReq.Header.Set("X-Proxer-Agent-ID", a.cfg.AgentID)
+	if host := strings.TrimSpace(proxyReq.Host); host != "" {
+		outboundReq.Host = host
+	}
 	if requestID := strings.TrimSpace(proxyReq.RequestID); requestID != "" {
 		outboundReq.Header.Set("X-Proxer-Request-ID", requestID)
 	}
+	httpx.ForwardTrailers(outboundReq, proxyReq.Headers, proxyReq.Trailers)
 
-	outboundResp, err := a.httpClient.Do(outboundReq)
+	if err := a.bandwidth.WaitN(requestCtx, len(proxyReq.Body)); err != nil {
+		response.Status = http.StatusGatewayTimeout
+		response.Error = fmt.Sprintf("wait for bandwidth: %v", err)
+		response.LatencyMs = time.Since(start).Milliseconds()
+		return response, nil
+	}
+	if upload == nil {
+		headerTimer.Start()
+	}
+	outboundResp, retries, err := httpx.DoWithRetry(requestCtx, proxyReq.Retry, proxyReq.Method, func() (*http.Response, error) {
+		watchdog.Touch()
+		return client.Do(httpx.CloneForRetry(outboundReq))
+	})
+	headerTimer.Received()
+	response.Retries = retries
+	if upload != nil {
+		response.BytesIn = upload.n.Load()
+	}
 	if err != nil {
+		if capture && ctx.Err() == nil && !proxyReq.Upload && a.capturesOffline(proxyReq, err) {
+			if queued := a.captureOffline(proxyReq, start); queued != nil {
+				return queued, err
+			}
+		}
 		response.Error = fmt.Sprintf("forward request to local target: %v", err)
+		if httpx.IsResponseHeaderTimeout(requestCtx) {
+			response.Error = fmt.Sprintf("local target sent no response headers within %dms", proxyReq.HeaderTimeoutMs)
+		}
+		if isLocalTimeout(requestCtx) {
+			response.Status = http.StatusGatewayTimeout
+		}
 		response.LatencyMs = time.Since(start).Milliseconds()
-		return response
+		return response, err
 	}
 	defer outboundResp.Body.Close()
+	watchdog.Touch()
 
-	respBody, err := readAllWithLimit(outboundResp.Body, a.cfg.MaxResponseBodyBytes)
+	limit, source := a.responseLimit(proxyReq)
+	respBody, exceeded, err := httpx.ReadLimitedBody(httpx.ThrottleReader(requestCtx, watchdog.Reader(outboundResp.Body), a.bandwidth), outboundResp.ContentLength, limit, source)
+	if exceeded != nil {
+		response.Status = http.StatusBadGateway
+		response.Error = fmt.Sprintf("local target response exceeded the %d byte limit (%d bytes read)", exceeded.LimitBytes, exceeded.ReadBytes)
+		response.LimitExceeded = exceeded
+		response.LatencyMs = time.Since(start).Milliseconds()
+		return response, nil
+	}
 	if err != nil {
-		if errors.Is(err, errBodyTooLarge) {
-			response.Status = http.StatusRequestEntityTooLarge
-			response.Error = "local target response exceeded configured size limit"
-			response.LatencyMs = time.Since(start).Milliseconds()
-			return response
-		}
 		response.Error = fmt.Sprintf("read local target response: %v", err)
 		response.Status = http.StatusBadGateway
+		if isLocalTimeout(requestCtx) {
+			response.Status = http.StatusGatewayTimeout
+		}
 		response.LatencyMs = time.Since(start).Milliseconds()
-		return response
+		return response, nil
 	}
 
 	response.Status = outboundResp.StatusCode
 	response.Headers = httpx.CloneHTTPHeader(outboundResp.Header)
+	if len(outboundResp.Trailer) > 0 {
+		response.Trailers = httpx.CloneHTTPHeader(outboundResp.Trailer)
+	}
 	response.Body = respBody
 	response.BytesOut = int64(len(respBody))
 	response.LatencyMs = time.Since(start).Milliseconds()
-	return response
+	return response, nil
+}
+
+func (a *Agent) logProxyRequest(proxyReq *protocol.ProxyRequest, response *protocol.ProxyResponse) {
+	if response.Error == "" && !strings.EqualFold(strings.TrimSpace(a.cfg.LogLevel), "debug") {
+		return
+	}
+	line := fmt.Sprintf("proxy request_id=%s route=%s method=%s path=%q status=%d latency_ms=%d",
+		proxyReq.RequestID, proxyReq.TunnelID, proxyReq.Method, proxyReq.Path, response.Status, response.LatencyMs)
+	if traceparent := http.Header(proxyReq.Headers).Get("Traceparent"); traceparent != "" {
+		line += " traceparent=" + traceparent
+	}
+	if response.Error != "" {
+		line += fmt.Sprintf(" error=%q", response.Error)
+	}
+	a.logger.Print(line)
+}
+
+func configureUpstreamProtocols(transport *http.Transport, mode string) {
+	protocols := new(http.Protocols)
+	switch mode {
+	case UpstreamHTTP2Off:
+		protocols.SetHTTP1(true)
+	case UpstreamHTTP2H2C:
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		transport.ForceAttemptHTTP2 = true
+	}
+	transport.Protocols = protocols
+}
+
+func isLocalTimeout(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded) || httpx.IsIdleTimeout(ctx) || httpx.IsResponseHeaderTimeout(ctx)
 }
 
 func (a *Agent) getSessionID() string {
@@ -504,6 +883,101 @@ func (a *Agent) setSessionID(sessionID string) {
 	a.sessionMu.Lock()
 	defer a.sessionMu.Unlock()
 	a.sessionID = sessionID
+	if sessionID != "" {
+		a.resumeSessionID = ""
+	}
+}
+
+func (a *Agent) dropSession() {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+	if a.sessionID != "" {
+		a.resumeSessionID = a.sessionID
+	}
+	a.sessionID = ""
+}
+
+func (a *Agent) setPullCancel(cancel context.CancelFunc) {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+	a.pullCancel = cancel
+}
+
+func (a *Agent) SetTunnels(tunnels []protocol.TunnelConfig) {
+	list := append([]protocol.TunnelConfig(nil), tunnels...)
+	slices.SortFunc(list, func(x, y protocol.TunnelConfig) int { return strings.Compare(x.ID, y.ID) })
+	byID := make(map[string]protocol.TunnelConfig, len(list))
+	for _, tunnel := range list {
+		byID[tunnel.ID] = tunnel
+	}
+
+	a.tunnelsMu.Lock()
+	a.tunnels = byID
+	a.tunnelList = list
+	a.tunnelsMu.Unlock()
+
+	select {
+	case a.tunnelsChanged <- struct{}{}:
+	default:
+	}
+	a.sessionMu.RLock()
+	cancel := a.pullCancel
+	a.sessionMu.RUnlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+func (a *Agent) currentTunnels() []protocol.TunnelConfig {
+	a.tunnelsMu.RLock()
+	defer a.tunnelsMu.RUnlock()
+	return append([]protocol.TunnelConfig(nil), a.tunnelList...)
+}
+
+func (a *Agent) tunnel(id string) (protocol.TunnelConfig, bool) {
+	a.tunnelsMu.RLock()
+	defer a.tunnelsMu.RUnlock()
+	tunnel, ok := a.tunnels[id]
+	return tunnel, ok
+}
+
+func (a *Agent) getRoutes() []protocol.TunnelRoute {
+	a.sessionMu.RLock()
+	defer a.sessionMu.RUnlock()
+	if len(a.routes) == 0 {
+		return nil
+	}
+	return append([]protocol.TunnelRoute(nil), a.routes...)
+}
+
+func (a *Agent) setRoutes(routes []protocol.TunnelRoute) {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+	a.routes = append([]protocol.TunnelRoute(nil), routes...)
+}
+
+func (a *Agent) getEncoding() string {
+	a.sessionMu.RLock()
+	defer a.sessionMu.RUnlock()
+	return a.encoding
+}
+
+func (a *Agent) setEncoding(encoding string) {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+	a.encoding = encoding
+}
+
+func (a *Agent) gatewaySupports(capability string) bool {
+	a.sessionMu.RLock()
+	defer a.sessionMu.RUnlock()
+	return slices.Contains(a.capabilities, capability)
+}
+
+func (a *Agent) setCapabilities(capabilities []string) {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+	a.capabilities = slices.Clone(capabilities)
 }
 
 func buildTargetURL(base, path, query string) (string, error) {
@@ -531,6 +1005,15 @@ func buildLocalTargetBaseURL(target *protocol.LocalTarget) (string, error) {
 		return "", fmt.Errorf("scheme must be http or https")
 	}
 	host := strings.TrimSpace(target.Host)
+	if socketPath := strings.TrimSpace(target.Socket); socketPath != "" {
+		if !filepath.IsAbs(socketPath) {
+			return "", fmt.Errorf("socket must be an absolute path")
+		}
+		if host == "" {
+			host = unixSocketHost
+		}
+		return fmt.Sprintf("%s://%s", scheme, host), nil
+	}
 	if host == "" {
 		host = "127.0.0.1"
 	}
@@ -540,19 +1023,12 @@ func buildLocalTargetBaseURL(target *protocol.LocalTarget) (string, error) {
 	return fmt.Sprintf("%s://%s:%d", scheme, host, target.Port), nil
 }
 
-func readAllWithLimit(reader io.Reader, maxBytes int64) ([]byte, error) {
-	if maxBytes <= 0 {
-		return io.ReadAll(reader)
+func (a *Agent) responseLimit(proxyReq *protocol.ProxyRequest) (int64, string) {
+	limit := a.cfg.MaxResponseBodyBytes
+	if requested := proxyReq.MaxResponseBodyBytes; requested > 0 && (limit <= 0 || requested < limit) {
+		return requested, protocol.ResponseLimitRequest
 	}
-	limited := &io.LimitedReader{R: reader, N: maxBytes + 1}
-	body, err := io.ReadAll(limited)
-	if err != nil {
-		return nil, err
-	}
-	if int64(len(body)) > maxBytes {
-		return nil, errBodyTooLarge
-	}
-	return body, nil
+	return limit, protocol.ResponseLimitAgent
 }
 
 func (a *Agent) isConnectorMode() bool {
@@ -560,20 +1036,34 @@ func (a *Agent) isConnectorMode() bool {
 }
 
 func (a *Agent) emit(state, message string, err error) {
-	if a.eventHook == nil {
-		return
+	errText := ""
+	if err != nil {
+		errText = err.Error()
 	}
-	event := RuntimeEvent{
-		State:     state,
-		Message:   strings.TrimSpace(message),
-		AgentID:   strings.TrimSpace(a.cfg.AgentID),
-		SessionID: strings.TrimSpace(a.getSessionID()),
-		At:        time.Now().UTC(),
+	a.conn.setLastEvent(state, message, errText)
+	a.emitEvent(state, message, errText)
+}
+
+func (a *Agent) refreshStats() {
+	if state, message, errText := a.conn.lastEvent(); state != "" {
+		a.emitEvent(state, message, errText)
 	}
-	if err != nil {
-		event.Error = err.Error()
+}
+
+func (a *Agent) emitEvent(state, message, errText string) {
+	if a.eventHook == nil {
+		return
 	}
-	a.eventHook(event)
+	a.eventHook(RuntimeEvent{
+		State:      state,
+		Message:    strings.TrimSpace(message),
+		Error:      errText,
+		AgentID:    strings.TrimSpace(a.cfg.AgentID),
+		SessionID:  strings.TrimSpace(a.getSessionID()),
+		Routes:     a.getRoutes(),
+		Connection: a.conn.snapshot(),
+		At:         time.Now().UTC(),
+	})
 }
 
 var errBodyTooLarge = errors.New("body too large")
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+type cancelWatch struct {
+	stopWatch context.CancelFunc
+	done      chan struct{}
+	mu        sync.Mutex
+	reason    string
+}
+
+// watchCancel calls cancel if the gateway reports that the caller of
+// requestID gave up. Watching is best effort: errors end it quietly and the
+// request runs to completion as before.
+func (a *Agent) watchCancel(ctx context.Context, sessionID, requestID string, cancel context.CancelFunc) *cancelWatch {
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	watch := &cancelWatch{stopWatch: stopWatch, done: make(chan struct{})}
+	go func() {
+		defer close(watch.done)
+		for watchCtx.Err() == nil {
+			reason, err := a.pollCancel(watchCtx, sessionID, requestID)
+			if err != nil {
+				return
+			}
+			if reason != "" {
+				watch.mu.Lock()
+				watch.reason = reason
+				watch.mu.Unlock()
+				cancel()
+				return
+			}
+		}
+	}()
+	return watch
+}
+
+func (w *cancelWatch) stop() string {
+	w.stopWatch()
+	<-w.done
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.reason
+}
+
+func (a *Agent) pollCancel(ctx context.Context, sessionID, requestID string) (string, error) {
+	query := url.Values{}
+	query.Set("session_id", sessionID)
+	query.Set("request_id", requestID)
+	query.Set("wait", strconv.Itoa(max(1, int(a.cfg.PollWait.Seconds()))))
+	requestCtx, cancel := context.WithTimeout(ctx, a.cfg.PollWait+5*time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(requestCtx, http.MethodGet, strings.TrimRight(a.cfg.GatewayBaseURL, "/")+"/api/agent/cancel?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	response, err := a.httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		var notice protocol.CancelNotice
+		if err := json.NewDecoder(response.Body).Decode(&notice); err != nil {
+			return "", err
+		}
+		if notice.Reason == "" {
+			notice.Reason = "cancelled"
+		}
+		return notice.Reason, nil
+	case http.StatusNoContent:
+		return "", nil
+	default:
+		return "", fmt.Errorf("cancel watch rejected (status %d)", response.StatusCode)
+	}
+}
@@ -13,23 +13,38 @@ import (
 )
 
 type Config struct {
-	GatewayBaseURL       string
-	AgentToken           string
-	AgentID              string
-	HeartbeatInterval    time.Duration
-	RequestTimeout       time.Duration
-	PollWait             time.Duration
-	Tunnels              []protocol.TunnelConfig
-	PairToken            string
-	ConnectorID          string
-	ConnectorSecret      string
-	MaxResponseBodyBytes int64
-	ProxyURL             string
-	NoProxy              string
-	TLSSkipVerify        bool
-	CAFile               string
-	LogLevel             string
-	EventHook            RuntimeEventHook
+	GatewayBaseURL        string
+	AgentToken            string
+	AgentID               string
+	HeartbeatInterval     time.Duration
+	RequestTimeout        time.Duration
+	PollWait              time.Duration
+	Tunnels               []protocol.TunnelConfig
+	PairToken             string
+	ConnectorID           string
+	ConnectorSecret       string
+	MaxResponseBodyBytes  int64
+	MaxBytesPerSecond     int64
+	ProxyURL              string
+	NoProxy               string
+	TLSSkipVerify         bool
+	CAFile                string
+	UpstreamHTTP2         string
+	Transport             string
+	HealthChecks          []HealthCheck
+	HealthCheckInterval   time.Duration
+	HealthCheckThreshold  int
+	OfflineQueueTargets   []string
+	OfflineQueueDir       string
+	OfflineQueueMax       int
+	OfflineReplayInterval time.Duration
+	LogLevel              string
+	Version               string
+	EventHook             RuntimeEventHook
+	ConnectorSecretFile   string
+	SecretHook            func(connectorID, secret string)
+	Docker                *DockerWatch
+	Forwards              []ReverseForward
 }
 
 func LoadConfigFromEnv() (Config, error) {
@@ -48,11 +63,17 @@ func LoadConfigFromEnv() (Config, error) {
 		PairToken:            readEnv("PROXER_AGENT_PAIR_TOKEN", ""),
 		ConnectorID:          readEnv("PROXER_AGENT_CONNECTOR_ID", ""),
 		ConnectorSecret:      readEnv("PROXER_AGENT_CONNECTOR_SECRET", ""),
+		ConnectorSecretFile:  readEnv("PROXER_AGENT_CONNECTOR_SECRET_FILE", ""),
 		MaxResponseBodyBytes: 20 << 20,
 		ProxyURL:             readEnv("PROXER_AGENT_PROXY_URL", ""),
 		NoProxy:              readEnv("PROXER_AGENT_NO_PROXY", ""),
 		TLSSkipVerify:        false,
 		CAFile:               readEnv("PROXER_AGENT_CA_FILE", ""),
+		UpstreamHTTP2:        strings.ToLower(readEnv("PROXER_AGENT_UPSTREAM_HTTP2", UpstreamHTTP2Auto)),
+		Transport:            strings.ToLower(readEnv("PROXER_AGENT_TRANSPORT", TransportAuto)),
+		HealthCheckInterval:  10 * time.Second,
+		HealthCheckThreshold: 3,
+		OfflineQueueDir:      readEnv("PROXER_AGENT_OFFLINE_QUEUE_DIR", ""),
 		LogLevel:             readEnv("PROXER_AGENT_LOG_LEVEL", "info"),
 	}
 	if tlsSkipVerifyRaw := strings.TrimSpace(os.Getenv("PROXER_AGENT_TLS_SKIP_VERIFY")); tlsSkipVerifyRaw != "" {
@@ -116,6 +137,79 @@ func LoadConfigFromEnv() (Config, error) {
 		}
 	}
 
+	if intervalStr := strings.TrimSpace(os.Getenv("PROXER_AGENT_HEALTH_INTERVAL")); intervalStr != "" {
+		interval, err := time.ParseDuration(intervalStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse PROXER_AGENT_HEALTH_INTERVAL: %w", err)
+		}
+		if interval < time.Second {
+			return Config{}, fmt.Errorf("PROXER_AGENT_HEALTH_INTERVAL must be at least 1s")
+		}
+		cfg.HealthCheckInterval = interval
+	}
+	if thresholdStr := strings.TrimSpace(os.Getenv("PROXER_AGENT_HEALTH_THRESHOLD")); thresholdStr != "" {
+		threshold, err := strconv.Atoi(thresholdStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse PROXER_AGENT_HEALTH_THRESHOLD: %w", err)
+		}
+		if threshold < 1 {
+			return Config{}, fmt.Errorf("PROXER_AGENT_HEALTH_THRESHOLD must be at least 1")
+		}
+		cfg.HealthCheckThreshold = threshold
+	}
+
+	if rateStr := strings.TrimSpace(os.Getenv("PROXER_AGENT_MAX_BYTES_PER_SECOND")); rateStr != "" {
+		value, err := strconv.ParseInt(rateStr, 10, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse PROXER_AGENT_MAX_BYTES_PER_SECOND: %w", err)
+		}
+		if err := ValidateBandwidthLimit(value); err != nil {
+			return Config{}, fmt.Errorf("PROXER_AGENT_MAX_BYTES_PER_SECOND %w", err)
+		}
+		cfg.MaxBytesPerSecond = value
+	}
+
+	if maxStr := strings.TrimSpace(os.Getenv("PROXER_AGENT_OFFLINE_QUEUE_MAX")); maxStr != "" {
+		value, err := strconv.Atoi(maxStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse PROXER_AGENT_OFFLINE_QUEUE_MAX: %w", err)
+		}
+		if value < 1 {
+			return Config{}, fmt.Errorf("PROXER_AGENT_OFFLINE_QUEUE_MAX must be at least 1")
+		}
+		cfg.OfflineQueueMax = value
+	}
+
+	switch cfg.UpstreamHTTP2 {
+	case UpstreamHTTP2Auto, UpstreamHTTP2H2C, UpstreamHTTP2Off:
+	default:
+		return Config{}, fmt.Errorf("PROXER_AGENT_UPSTREAM_HTTP2 must be auto, h2c or off")
+	}
+	switch cfg.Transport {
+	case TransportAuto, TransportJSON:
+	default:
+		return Config{}, fmt.Errorf("PROXER_AGENT_TRANSPORT must be auto or json")
+	}
+
+	if strings.TrimSpace(cfg.ConnectorSecret) == "" && strings.TrimSpace(cfg.ConnectorSecretFile) != "" {
+		secret, err := os.ReadFile(cfg.ConnectorSecretFile)
+		if err != nil && !os.IsNotExist(err) {
+			return Config{}, fmt.Errorf("read PROXER_AGENT_CONNECTOR_SECRET_FILE: %w", err)
+		}
+		cfg.ConnectorSecret = strings.TrimSpace(string(secret))
+	}
+
+	docker, err := parseDockerWatch(os.Getenv("PROXER_AGENT_DOCKER"), os.Getenv("PROXER_AGENT_DOCKER_SOCKET"), os.Getenv("PROXER_AGENT_DOCKER_ADDRESS"))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Docker = docker
+	forwards, err := parseForwards(os.Getenv("PROXER_AGENT_FORWARDS"))
+	if err != nil {
+		return Config{}, fmt.Errorf("parse PROXER_AGENT_FORWARDS: %w", err)
+	}
+	cfg.Forwards = forwards
+
 	isConnectorMode := strings.TrimSpace(cfg.PairToken) != "" ||
 		(strings.TrimSpace(cfg.ConnectorID) != "" && strings.TrimSpace(cfg.ConnectorSecret) != "")
 
@@ -125,6 +219,9 @@ func LoadConfigFromEnv() (Config, error) {
 				return Config{}, fmt.Errorf("connector mode requires PROXER_AGENT_PAIR_TOKEN or both PROXER_AGENT_CONNECTOR_ID and PROXER_AGENT_CONNECTOR_SECRET")
 			}
 		}
+		if cfg.Docker != nil {
+			return Config{}, fmt.Errorf("PROXER_AGENT_DOCKER creates tunnels and cannot be used in connector mode")
+		}
 		if tunnelsRaw := strings.TrimSpace(os.Getenv("PROXER_AGENT_TUNNELS")); tunnelsRaw != "" {
 			tunnels, err := parseTunnels(tunnelsRaw)
 			if err != nil {
@@ -132,14 +229,46 @@ func LoadConfigFromEnv() (Config, error) {
 			}
 			cfg.Tunnels = tunnels
 		}
+		if err := parseTunnelTLS(os.Getenv("PROXER_AGENT_TUNNEL_TLS"), cfg.Tunnels); err != nil {
+			return Config{}, fmt.Errorf("parse PROXER_AGENT_TUNNEL_TLS: %w", err)
+		}
+		healthChecks, err := parseHealthChecks(os.Getenv("PROXER_AGENT_HEALTH_CHECKS"), cfg.Tunnels)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse PROXER_AGENT_HEALTH_CHECKS: %w", err)
+		}
+		cfg.HealthChecks = healthChecks
+		offlineTargets, err := parseOfflineQueueTargets(os.Getenv("PROXER_AGENT_OFFLINE_QUEUE"), cfg.Tunnels)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse PROXER_AGENT_OFFLINE_QUEUE: %w", err)
+		}
+		cfg.OfflineQueueTargets = offlineTargets
 		return cfg, nil
 	}
 
-	tunnels, err := parseTunnels(readEnv("PROXER_AGENT_TUNNELS", "app3000=http://host.docker.internal:3000"))
+	if len(cfg.Forwards) > 0 {
+		return Config{}, fmt.Errorf("PROXER_AGENT_FORWARDS needs connector mode")
+	}
+
+	if tunnelsRaw := strings.TrimSpace(os.Getenv("PROXER_AGENT_TUNNELS")); tunnelsRaw != "" || cfg.Docker == nil {
+		tunnels, err := parseTunnels(readEnv("PROXER_AGENT_TUNNELS", "app3000=http://host.docker.internal:3000"))
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.Tunnels = tunnels
+	}
+	if err := parseTunnelTLS(os.Getenv("PROXER_AGENT_TUNNEL_TLS"), cfg.Tunnels); err != nil {
+		return Config{}, fmt.Errorf("parse PROXER_AGENT_TUNNEL_TLS: %w", err)
+	}
+	healthChecks, err := parseHealthChecks(os.Getenv("PROXER_AGENT_HEALTH_CHECKS"), cfg.Tunnels)
 	if err != nil {
-		return Config{}, err
+		return Config{}, fmt.Errorf("parse PROXER_AGENT_HEALTH_CHECKS: %w", err)
 	}
-	cfg.Tunnels = tunnels
+	cfg.HealthChecks = healthChecks
+	offlineTargets, err := parseOfflineQueueTargets(os.Getenv("PROXER_AGENT_OFFLINE_QUEUE"), cfg.Tunnels)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse PROXER_AGENT_OFFLINE_QUEUE: %w", err)
+	}
+	cfg.OfflineQueueTargets = offlineTargets
 
 	if strings.TrimSpace(cfg.AgentToken) == "" {
 		return Config{}, fmt.Errorf("PROXER_AGENT_TOKEN cannot be empty")
@@ -181,8 +310,8 @@ func parseTunnels(raw string) ([]protocol.TunnelConfig, error) {
 		}
 		seen[id] = struct{}{}
 
-		if _, err := url.ParseRequestURI(rhs); err != nil {
-			return nil, fmt.Errorf("invalid tunnel target for %q: %w", id, err)
+		if err := checkTunnelTarget(id, rhs); err != nil {
+			return nil, err
 		}
 
 		tunnels = append(tunnels, protocol.TunnelConfig{
@@ -202,6 +331,27 @@ func parseTunnels(raw string) ([]protocol.TunnelConfig, error) {
 	return tunnels, nil
 }
 
+func checkTunnelTarget(id, target string) error {
+	if _, err := url.ParseRequestURI(target); err != nil {
+		return fmt.Errorf("invalid tunnel target for %q: %w", id, err)
+	}
+	if err := CheckFileTunnelTarget(target); err != nil {
+		return fmt.Errorf("invalid tunnel target for %q: %w", id, err)
+	}
+	return nil
+}
+
+// MinBytesPerSecond is the lowest bandwidth limit accepted, so a limit cannot
+// stall the tunnel outright.
+const MinBytesPerSecond = 1024
+
+func ValidateBandwidthLimit(bytesPerSecond int64) error {
+	if bytesPerSecond != 0 && bytesPerSecond < MinBytesPerSecond {
+		return fmt.Errorf("must be 0 (unlimited) or at least %d bytes per second", MinBytesPerSecond)
+	}
+	return nil
+}
+
 func readEnv(key, fallback string) string {
 	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
 		return value
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"sync"
+	"time"
+)
+
+type ConnectionStats struct {
+	Reconnects       int        `json:"reconnects"`
+	Resumes          int        `json:"resumes"`
+	LastRegisteredAt *time.Time `json:"last_registered_at,omitempty"`
+	BackoffMs        int64      `json:"backoff_ms"`
+	HeartbeatRTTMs   int64      `json:"heartbeat_rtt_ms"`
+	LastHeartbeatAt  *time.Time `json:"last_heartbeat_at,omitempty"`
+	RequestsServed   int64      `json:"requests_served"`
+	RequestsErrored  int64      `json:"requests_errored"`
+}
+
+type connStats struct {
+	mu       sync.Mutex
+	stats    ConnectionStats
+	sessions int
+
+	state, message, err string
+}
+
+func (c *connStats) recordSession(resumed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now().UTC()
+	c.sessions++
+	if c.sessions > 1 {
+		c.stats.Reconnects++
+	}
+	if resumed {
+		c.stats.Resumes++
+	}
+	c.stats.LastRegisteredAt = &now
+	c.stats.BackoffMs = 0
+}
+
+func (c *connStats) setBackoff(backoff time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.BackoffMs = backoff.Milliseconds()
+}
+
+func (c *connStats) recordHeartbeat(rtt time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now().UTC()
+	c.stats.HeartbeatRTTMs = rtt.Milliseconds()
+	c.stats.LastHeartbeatAt = &now
+}
+
+func (c *connStats) recordRequest(failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.RequestsServed++
+	if failed {
+		c.stats.RequestsErrored++
+	}
+}
+
+func (c *connStats) snapshot() *ConnectionStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	if stats.LastRegisteredAt != nil {
+		at := *stats.LastRegisteredAt
+		stats.LastRegisteredAt = &at
+	}
+	if stats.LastHeartbeatAt != nil {
+		at := *stats.LastHeartbeatAt
+		stats.LastHeartbeatAt = &at
+	}
+	return &stats
+}
+
+func (c *connStats) setLastEvent(state, message, err string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state, c.message, c.err = state, message, err
+}
+
+func (c *connStats) lastEvent() (string, string, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state, c.message, c.err
+}
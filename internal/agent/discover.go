@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultDiscoveryPorts = "3000-3010,4200,5000,5173,8000,8080,8888"
+
+const (
+	maxDiscoveryPorts       = 1024
+	discoveryDialTimeout    = 300 * time.Millisecond
+	discoveryRequestTimeout = time.Second
+	discoveryConcurrency    = 32
+	discoveryBodyLimit      = 64 << 10
+)
+
+var htmlTitlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+type DiscoveredPort struct {
+	Port      int    `json:"port"`
+	TargetURL string `json:"target_url"`
+	Status    int    `json:"status"`
+	Server    string `json:"server,omitempty"`
+	Title     string `json:"title,omitempty"`
+	TunnelID  string `json:"tunnel_id"`
+}
+
+func ParsePortList(raw string) ([]int, error) {
+	seen := make(map[int]struct{})
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		startRaw, endRaw, isRange := strings.Cut(entry, "-")
+		start, err := parsePort(startRaw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q: %w", entry, err)
+		}
+		end := start
+		if isRange {
+			if end, err = parsePort(endRaw); err != nil {
+				return nil, fmt.Errorf("invalid port range %q: %w", entry, err)
+			}
+			if end < start {
+				return nil, fmt.Errorf("invalid port range %q: end is before start", entry)
+			}
+		}
+		if end-start+1+len(seen) > maxDiscoveryPorts {
+			return nil, fmt.Errorf("at most %d ports can be scanned", maxDiscoveryPorts)
+		}
+		for port := start; port <= end; port++ {
+			seen[port] = struct{}{}
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("no ports to scan")
+	}
+	ports := make([]int, 0, len(seen))
+	for port := range seen {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	return ports, nil
+}
+
+func parsePort(raw string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, err
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("port must be between 1 and 65535")
+	}
+	return port, nil
+}
+
+func SuggestedTunnelID(port int) string {
+	return "app" + strconv.Itoa(port)
+}
+
+// DiscoverLocalPorts probes ports on host and returns the ones serving HTTP,
+// ordered by port. Ports that accept connections but do not speak HTTP are
+// left out because they cannot back a tunnel.
+func DiscoverLocalPorts(ctx context.Context, host string, ports []int) []DiscoveredPort {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	client := &http.Client{
+		Timeout: discoveryRequestTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		found = make([]DiscoveredPort, 0)
+	)
+	slots := make(chan struct{}, discoveryConcurrency)
+	for _, port := range ports {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(port int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if result, ok := probeHTTPPort(ctx, client, host, port); ok {
+				mu.Lock()
+				found = append(found, result)
+				mu.Unlock()
+			}
+		}(port)
+	}
+	wg.Wait()
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Port < found[j].Port })
+	return found
+}
+
+func probeHTTPPort(ctx context.Context, client *http.Client, host string, port int) (DiscoveredPort, bool) {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	dialCtx, cancel := context.WithTimeout(ctx, discoveryDialTimeout)
+	conn, err := (&net.Dialer{}).DialContext(dialCtx, "tcp", address)
+	cancel()
+	if err != nil {
+		return DiscoveredPort{}, false
+	}
+	_ = conn.Close()
+
+	targetURL := "http://" + address
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL+"/", nil)
+	if err != nil {
+		return DiscoveredPort{}, false
+	}
+	request.Header.Set("User-Agent", "proxer-agent-discover")
+	response, err := client.Do(request)
+	if err != nil {
+		return DiscoveredPort{}, false
+	}
+	defer response.Body.Close()
+
+	result := DiscoveredPort{
+		Port:      port,
+		TargetURL: targetURL,
+		Status:    response.StatusCode,
+		Server:    response.Header.Get("Server"),
+		TunnelID:  SuggestedTunnelID(port),
+	}
+	if strings.Contains(strings.ToLower(response.Header.Get("Content-Type")), "html") {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, discoveryBodyLimit))
+		if match := htmlTitlePattern.FindSubmatch(body); match != nil {
+			result.Title = strings.Join(strings.Fields(html.UnescapeString(string(match[1]))), " ")
+		}
+	}
+	return result, true
+}
@@ -0,0 +1,292 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const (
+	DockerLabelRoute  = "proxer.route"
+	DockerLabelPort   = "proxer.port"
+	DockerLabelScheme = "proxer.scheme"
+)
+
+const (
+	DockerAddressAuto      = "auto"
+	DockerAddressContainer = "container"
+	DockerAddressPublished = "published"
+)
+
+const (
+	defaultDockerSocket = "/var/run/docker.sock"
+	dockerRetryInterval = 5 * time.Second
+)
+
+type DockerWatch struct {
+	Socket  string
+	Address string
+}
+
+type dockerContainer struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Labels map[string]string `json:"Labels"`
+	Ports  []struct {
+		IP          string `json:"IP"`
+		PrivatePort int    `json:"PrivatePort"`
+		PublicPort  int    `json:"PublicPort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress string `json:"IPAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+func (c dockerContainer) name() string {
+	if len(c.Names) > 0 {
+		return strings.TrimPrefix(c.Names[0], "/")
+	}
+	if len(c.ID) > 12 {
+		return c.ID[:12]
+	}
+	return c.ID
+}
+
+func parseDockerWatch(enabled, socket, address string) (*DockerWatch, error) {
+	if strings.TrimSpace(enabled) == "" {
+		return nil, nil
+	}
+	on, err := strconv.ParseBool(strings.TrimSpace(enabled))
+	if err != nil {
+		return nil, fmt.Errorf("parse PROXER_AGENT_DOCKER: %w", err)
+	}
+	if !on {
+		return nil, nil
+	}
+	watch := &DockerWatch{Socket: strings.TrimSpace(socket), Address: strings.ToLower(strings.TrimSpace(address))}
+	if watch.Socket == "" {
+		if host, ok := strings.CutPrefix(strings.TrimSpace(os.Getenv("DOCKER_HOST")), "unix://"); ok {
+			watch.Socket = host
+		}
+	}
+	if watch.Socket == "" {
+		watch.Socket = defaultDockerSocket
+	}
+	if watch.Address == "" {
+		watch.Address = DockerAddressAuto
+	}
+	switch watch.Address {
+	case DockerAddressAuto, DockerAddressContainer, DockerAddressPublished:
+	default:
+		return nil, fmt.Errorf("PROXER_AGENT_DOCKER_ADDRESS must be auto, container or published")
+	}
+	return watch, nil
+}
+
+func (a *Agent) dockerLoop(ctx context.Context, done <-chan struct{}) {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-watchCtx.Done():
+		}
+	}()
+
+	static := a.currentTunnels()
+	client := &http.Client{Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", a.cfg.Docker.Socket)
+		},
+	}}
+	_, err := os.Stat("/.dockerenv")
+	inContainer := err == nil
+
+	for {
+		err := a.syncDockerTunnels(watchCtx, client, static, inContainer)
+		if err == nil {
+			err = a.followDockerEvents(watchCtx, client, func() error {
+				return a.syncDockerTunnels(watchCtx, client, static, inContainer)
+			})
+		}
+		if watchCtx.Err() != nil {
+			return
+		}
+		a.logger.Printf("docker watch error: %v; retrying in %s", err, dockerRetryInterval)
+		if err := waitWithContext(watchCtx, dockerRetryInterval); err != nil {
+			return
+		}
+	}
+}
+
+// syncDockerTunnels lists the running labelled containers and replaces the
+// agent's tunnels when the set differs. Configured tunnels always stay and
+// win over a container claiming the same ID.
+func (a *Agent) syncDockerTunnels(ctx context.Context, client *http.Client, static []protocol.TunnelConfig, inContainer bool) error {
+	filters, _ := json.Marshal(map[string][]string{"label": {DockerLabelRoute}, "status": {"running"}})
+	var containers []dockerContainer
+	if err := dockerGet(ctx, client, "/containers/json?filters="+url.QueryEscape(string(filters)), &containers); err != nil {
+		return err
+	}
+
+	tunnels := append([]protocol.TunnelConfig(nil), static...)
+	taken := make(map[string]string, len(tunnels))
+	for _, tunnel := range static {
+		taken[tunnel.ID] = "configured tunnel"
+	}
+	slices.SortFunc(containers, func(x, y dockerContainer) int { return strings.Compare(x.name(), y.name()) })
+	for _, container := range containers {
+		tunnel, err := dockerTunnel(container, a.cfg.Docker.Address, inContainer)
+		if err != nil {
+			a.logger.Printf("docker container %s skipped: %v", container.name(), err)
+			continue
+		}
+		if owner, ok := taken[tunnel.ID]; ok {
+			a.logger.Printf("docker container %s skipped: tunnel %q is already used by %s", container.name(), tunnel.ID, owner)
+			continue
+		}
+		taken[tunnel.ID] = "container " + container.name()
+		tunnels = append(tunnels, tunnel)
+	}
+
+	slices.SortFunc(tunnels, func(x, y protocol.TunnelConfig) int { return strings.Compare(x.ID, y.ID) })
+	if slices.Equal(tunnels, a.currentTunnels()) {
+		return nil
+	}
+	ids := make([]string, 0, len(tunnels))
+	for _, tunnel := range tunnels {
+		ids = append(ids, tunnel.ID+"="+tunnel.Target)
+	}
+	a.logger.Printf("docker tunnels changed: %s", strings.Join(ids, ", "))
+	a.SetTunnels(tunnels)
+	return nil
+}
+
+func dockerTunnel(container dockerContainer, addressMode string, inContainer bool) (protocol.TunnelConfig, error) {
+	id := strings.TrimSpace(container.Labels[DockerLabelRoute])
+	if id == "" {
+		return protocol.TunnelConfig{}, fmt.Errorf("empty %s label", DockerLabelRoute)
+	}
+	port, err := strconv.Atoi(strings.TrimSpace(container.Labels[DockerLabelPort]))
+	if err != nil || port < 1 || port > 65535 {
+		return protocol.TunnelConfig{}, fmt.Errorf("%s label must be a port between 1 and 65535", DockerLabelPort)
+	}
+	scheme := strings.ToLower(strings.TrimSpace(container.Labels[DockerLabelScheme]))
+	if scheme == "" {
+		scheme = "http"
+	}
+	if scheme != "http" && scheme != "https" {
+		return protocol.TunnelConfig{}, fmt.Errorf("%s label must be http or https", DockerLabelScheme)
+	}
+
+	networkHost := ""
+	networks := make([]string, 0, len(container.NetworkSettings.Networks))
+	for network := range container.NetworkSettings.Networks {
+		networks = append(networks, network)
+	}
+	slices.Sort(networks)
+	for _, network := range networks {
+		if ip := strings.TrimSpace(container.NetworkSettings.Networks[network].IPAddress); ip != "" {
+			networkHost = net.JoinHostPort(ip, strconv.Itoa(port))
+			break
+		}
+	}
+	publishedHost := ""
+	for _, mapping := range container.Ports {
+		if mapping.PrivatePort != port || mapping.PublicPort == 0 || (mapping.Type != "" && mapping.Type != "tcp") {
+			continue
+		}
+		ip := strings.TrimSpace(mapping.IP)
+		if ip == "" || ip == "0.0.0.0" || ip == "::" {
+			ip = "127.0.0.1"
+		}
+		publishedHost = net.JoinHostPort(ip, strconv.Itoa(mapping.PublicPort))
+		break
+	}
+
+	host := ""
+	switch addressMode {
+	case DockerAddressContainer:
+		host = networkHost
+	case DockerAddressPublished:
+		host = publishedHost
+	default:
+		host = publishedHost
+		if inContainer || host == "" {
+			host = networkHost
+		}
+	}
+	if host == "" {
+		return protocol.TunnelConfig{}, fmt.Errorf("no %s address for port %d", addressMode, port)
+	}
+	target := scheme + "://" + host
+	if err := checkTunnelTarget(id, target); err != nil {
+		return protocol.TunnelConfig{}, err
+	}
+	return protocol.TunnelConfig{ID: id, Target: target}, nil
+}
+
+func (a *Agent) followDockerEvents(ctx context.Context, client *http.Client, sync func() error) error {
+	filters, _ := json.Marshal(map[string][]string{
+		"type":  {"container"},
+		"event": {"start", "die"},
+		"label": {DockerLabelRoute},
+	})
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker/events?filters="+url.QueryEscape(string(filters)), nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("follow docker events: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 2048))
+		return fmt.Errorf("follow docker events: status %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	decoder := json.NewDecoder(response.Body)
+	for {
+		var event json.RawMessage
+		if err := decoder.Decode(&event); err != nil {
+			return fmt.Errorf("docker event stream ended: %w", err)
+		}
+		if err := sync(); err != nil {
+			return err
+		}
+	}
+}
+
+func dockerGet(ctx context.Context, client *http.Client, path string, out any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker"+path, nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("query docker: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 2048))
+		return fmt.Errorf("query docker: status %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(response.Body).Decode(out)
+}
@@ -1,6 +1,10 @@
 package agent
 
-import "time"
+import (
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
 
 const (
 	RuntimeStateStopped  = "stopped"
@@ -13,13 +17,14 @@ const (
 )
 
 type RuntimeEvent struct {
-	State     string    `json:"state"`
-	Message   string    `json:"message,omitempty"`
-	Error     string    `json:"error,omitempty"`
-	AgentID   string    `json:"agent_id,omitempty"`
-	SessionID string    `json:"session_id,omitempty"`
-	At        time.Time `json:"at"`
+	State      string                 `json:"state"`
+	Message    string                 `json:"message,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	AgentID    string                 `json:"agent_id,omitempty"`
+	SessionID  string                 `json:"session_id,omitempty"`
+	Routes     []protocol.TunnelRoute `json:"routes,omitempty"`
+	Connection *ConnectionStats       `json:"connection,omitempty"`
+	At         time.Time              `json:"at"`
 }
 
 type RuntimeEventHook func(RuntimeEvent)
-
@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const fileTunnelScheme = "file"
+
+func FileTunnelTarget(dir string, listing bool) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolve directory: %w", err)
+	}
+	target := &url.URL{Scheme: fileTunnelScheme, Path: filepath.ToSlash(abs)}
+	if !strings.HasPrefix(target.Path, "/") {
+		target.Path = "/" + target.Path
+	}
+	if listing {
+		target.RawQuery = "listing=1"
+	}
+	if err := CheckFileTunnelTarget(target.String()); err != nil {
+		return "", err
+	}
+	return target.String(), nil
+}
+
+func CheckFileTunnelTarget(target string) error {
+	dir, _, ok := parseFileTunnelTarget(target)
+	if !ok {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("file tunnel directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("file tunnel target %s is not a directory", dir)
+	}
+	return nil
+}
+
+func parseFileTunnelTarget(target string) (dir string, listing bool, ok bool) {
+	parsed, err := url.Parse(strings.TrimSpace(target))
+	if err != nil || parsed.Scheme != fileTunnelScheme {
+		return "", false, false
+	}
+	dir = filepath.FromSlash(parsed.Path)
+	// file:///C:/site on Windows.
+	if len(dir) > 2 && dir[0] == filepath.Separator && dir[2] == ':' {
+		dir = dir[1:]
+	}
+	listing = parsed.Query().Get("listing") == "1" || parsed.Query().Get("listing") == "true"
+	return dir, listing, true
+}
+
+func (a *Agent) serveFileTunnel(proxyReq *protocol.ProxyRequest, dir string, listing bool) *protocol.ProxyResponse {
+	start := time.Now()
+	response := &protocol.ProxyResponse{
+		RequestID: proxyReq.RequestID,
+		TunnelID:  proxyReq.TunnelID,
+		BytesIn:   int64(len(proxyReq.Body)),
+	}
+	finish := func(status int, message string) *protocol.ProxyResponse {
+		response.Status = status
+		response.Headers = map[string][]string{"Content-Type": {"text/plain; charset=utf-8"}}
+		response.Body = []byte(message + "\n")
+		response.BytesOut = int64(len(response.Body))
+		response.LatencyMs = time.Since(start).Milliseconds()
+		return response
+	}
+
+	if proxyReq.Method != http.MethodGet && proxyReq.Method != http.MethodHead {
+		return finish(http.StatusMethodNotAllowed, "file tunnels are read-only")
+	}
+	requestPath := path.Clean("/" + proxyReq.Path)
+	for _, segment := range strings.Split(requestPath, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return finish(http.StatusNotFound, "404 page not found")
+		}
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		response.Error = fmt.Sprintf("open file tunnel directory: %v", err)
+		return finish(http.StatusBadGateway, "file tunnel directory is unavailable")
+	}
+	defer root.Close()
+
+	relative := strings.TrimPrefix(requestPath, "/")
+	if relative == "" {
+		relative = "."
+	}
+	if info, err := root.Stat(relative); err == nil && info.IsDir() && !listing {
+		if _, err := root.Stat(path.Join(relative, "index.html")); err != nil {
+			return finish(http.StatusNotFound, "404 page not found")
+		}
+	}
+
+	target := &url.URL{Path: proxyReq.Path, RawQuery: proxyReq.Query}
+	request, err := http.NewRequest(proxyReq.Method, target.String(), nil)
+	if err != nil {
+		return finish(http.StatusBadRequest, "invalid request path")
+	}
+	for header, values := range proxyReq.Headers {
+		for _, value := range values {
+			request.Header.Add(header, value)
+		}
+	}
+
+	limit, source := a.responseLimit(proxyReq)
+	recorder := &fileResponseRecorder{header: make(http.Header), limit: limit}
+	http.FileServerFS(root.FS()).ServeHTTP(recorder, request)
+	if recorder.overflow {
+		contentLength := int64(-1)
+		if declared, err := strconv.ParseInt(recorder.header.Get("Content-Length"), 10, 64); err == nil {
+			contentLength = declared
+		}
+		response.Error = "local file exceeded configured size limit"
+		response.LimitExceeded = &protocol.ResponseLimit{Source: source, LimitBytes: limit, ReadBytes: int64(recorder.body.Len()), ContentLength: contentLength}
+		return finish(http.StatusBadGateway, "file exceeds the response size limit")
+	}
+
+	response.Status = recorder.status
+	if response.Status == 0 {
+		response.Status = http.StatusOK
+	}
+	response.Headers = map[string][]string(recorder.header)
+	response.Body = recorder.body.Bytes()
+	response.BytesOut = int64(len(response.Body))
+	response.LatencyMs = time.Since(start).Milliseconds()
+	return response
+}
+
+type fileResponseRecorder struct {
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	limit    int64
+	overflow bool
+}
+
+func (r *fileResponseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *fileResponseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *fileResponseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if r.limit > 0 && int64(r.body.Len()+len(p)) > r.limit {
+		r.overflow = true
+		return 0, errBodyTooLarge
+	}
+	return r.body.Write(p)
+}
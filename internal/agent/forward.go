@@ -0,0 +1,219 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+type ReverseForward struct {
+	Listen string
+	Target string
+}
+
+type forwardState struct {
+	mu       sync.Mutex
+	status   map[string]protocol.ForwardStatus
+	lastErr  string
+	announce map[string]string
+}
+
+func (s *forwardState) get(target string) (protocol.ForwardStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.status[target]
+	return status, ok
+}
+
+func (s *forwardState) update(forwards []protocol.ForwardStatus) []protocol.ForwardStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status == nil {
+		s.status = make(map[string]protocol.ForwardStatus)
+		s.announce = make(map[string]string)
+	}
+	changed := make([]protocol.ForwardStatus, 0)
+	for _, forward := range forwards {
+		s.status[forward.Target] = forward
+		if s.announce[forward.Target] != forward.Status {
+			s.announce[forward.Target] = forward.Status
+			changed = append(changed, forward)
+		}
+	}
+	s.lastErr = ""
+	return changed
+}
+
+// failed records a sync error and reports whether it differs from the last
+// one, so a gateway without reverse forwards is not logged on every beat.
+func (s *forwardState) failed(err error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastErr == err.Error() {
+		return false
+	}
+	s.lastErr = err.Error()
+	return true
+}
+
+func parseForwards(raw string) ([]ReverseForward, error) {
+	forwards := make([]ReverseForward, 0)
+	seen := make(map[string]struct{})
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) == 3 {
+			parts = append([]string{"127.0.0.1"}, parts...)
+		}
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("invalid forward %q; expected [bind_address:]port:host:hostport", entry)
+		}
+		for _, port := range []string{parts[1], parts[3]} {
+			if value, err := strconv.Atoi(port); err != nil || value < 1 || value > 65535 {
+				return nil, fmt.Errorf("invalid forward %q: ports must be between 1 and 65535", entry)
+			}
+		}
+		if strings.TrimSpace(parts[2]) == "" {
+			return nil, fmt.Errorf("invalid forward %q: missing host", entry)
+		}
+		forward := ReverseForward{
+			Listen: net.JoinHostPort(parts[0], parts[1]),
+			Target: net.JoinHostPort(strings.ToLower(parts[2]), parts[3]),
+		}
+		if _, ok := seen[forward.Listen]; ok {
+			return nil, fmt.Errorf("duplicate forward listen address %q", forward.Listen)
+		}
+		seen[forward.Listen] = struct{}{}
+		forwards = append(forwards, forward)
+	}
+	return forwards, nil
+}
+
+func (a *Agent) forwardLoop(ctx context.Context, done <-chan struct{}) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for _, forward := range a.cfg.Forwards {
+		listener, err := net.Listen("tcp", forward.Listen)
+		if err != nil {
+			a.logger.Printf("reverse forward %s -> %s: %v", forward.Listen, forward.Target, err)
+			continue
+		}
+		defer listener.Close()
+		go a.acceptForward(ctx, forward, listener)
+	}
+
+	targets := make([]string, 0, len(a.cfg.Forwards))
+	for _, forward := range a.cfg.Forwards {
+		targets = append(targets, forward.Target)
+	}
+	ticker := time.NewTicker(a.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		if sessionID := a.getSessionID(); sessionID != "" && a.isConnectorMode() {
+			if err := a.syncForwards(ctx, sessionID, targets); err != nil && ctx.Err() == nil && a.forwards.failed(err) {
+				a.logger.Printf("reverse forwards: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Agent) syncForwards(ctx context.Context, sessionID string, targets []string) error {
+	var response protocol.ForwardsResponse
+	if err := a.postForwardAPI(ctx, "/api/agent/forwards", protocol.ForwardsRequest{SessionID: sessionID, Targets: targets}, &response); err != nil {
+		return err
+	}
+	for _, forward := range a.forwards.update(response.Forwards) {
+		switch forward.Status {
+		case protocol.ForwardPending:
+			a.logger.Printf("reverse forward to %s is waiting for a tenant admin to approve it (id=%s)", forward.Target, forward.ID)
+		default:
+			a.logger.Printf("reverse forward to %s is %s", forward.Target, forward.Status)
+		}
+	}
+	return nil
+}
+
+func (a *Agent) acceptForward(ctx context.Context, forward ReverseForward, listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				a.logger.Printf("reverse forward %s: accept: %v", forward.Listen, err)
+			}
+			return
+		}
+		go func() {
+			if err := a.serveForward(ctx, forward, conn); err != nil {
+				a.logger.Printf("reverse forward %s -> %s: %v", forward.Listen, forward.Target, err)
+			}
+		}()
+	}
+}
+
+func (a *Agent) serveForward(ctx context.Context, forward ReverseForward, conn net.Conn) error {
+	status, ok := a.forwards.get(forward.Target)
+	if !ok || status.Status != protocol.ForwardApproved {
+		_ = conn.Close()
+		if !ok {
+			return errors.New("not filed with the gateway yet")
+		}
+		return fmt.Errorf("forward is %s", status.Status)
+	}
+	sessionID := a.getSessionID()
+	if sessionID == "" {
+		_ = conn.Close()
+		return errors.New("no gateway session")
+	}
+	var opened protocol.ForwardOpenResponse
+	if err := a.postForwardAPI(ctx, "/api/agent/forwards/open", protocol.ForwardOpenRequest{SessionID: sessionID, ForwardID: status.ID}, &opened); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	return a.pipeStream(ctx, a.streamURL(sessionID, opened.StreamID), conn)
+}
+
+func (a *Agent) postForwardAPI(ctx context.Context, path string, payload, out any) error {
+	requestBody, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode forward payload: %w", err)
+	}
+	requestCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	request, err := http.NewRequestWithContext(requestCtx, http.MethodPost, strings.TrimRight(a.cfg.GatewayBaseURL, "/")+path, bytes.NewReader(requestBody))
+	if err != nil {
+		return fmt.Errorf("build forward request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := a.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("post %s: %w", path, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+		return fmt.Errorf("%s rejected (status %d): %s", path, response.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(response.Body).Decode(out)
+}
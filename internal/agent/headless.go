@@ -0,0 +1,249 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+// Headless config document identity, in the style of a Kubernetes custom
+// resource so the file can live in a ConfigMap next to the Deployment.
+const (
+	HeadlessAPIVersion = "proxer.dev/v1alpha1"
+	HeadlessKind       = "ProxerAgent"
+)
+
+const DefaultHeadlessConfigPath = "/etc/proxer/agent.yaml"
+
+const DefaultProbeListenAddr = ":8081"
+
+type HeadlessDocument struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec HeadlessSpec `yaml:"spec"`
+}
+
+type HeadlessSpec struct {
+	GatewayBaseURL string `yaml:"gatewayBaseURL"`
+	AgentID        string `yaml:"agentID"`
+	Connector      *struct {
+		ID         string `yaml:"id"`
+		SecretFile string `yaml:"secretFile"`
+	} `yaml:"connector"`
+	AgentTokenFile string           `yaml:"agentTokenFile"`
+	Tunnels        []HeadlessTunnel `yaml:"tunnels"`
+	HealthChecks   []struct {
+		Target string `yaml:"target"`
+		Kind   string `yaml:"kind"`
+		Path   string `yaml:"path"`
+	} `yaml:"healthChecks"`
+	Runtime struct {
+		RequestTimeout       string `yaml:"requestTimeout"`
+		PollWait             string `yaml:"pollWait"`
+		HeartbeatInterval    string `yaml:"heartbeatInterval"`
+		MaxResponseBodyBytes int64  `yaml:"maxResponseBodyBytes"`
+		MaxBytesPerSecond    int64  `yaml:"maxBytesPerSecond"`
+		ProxyURL             string `yaml:"proxyURL"`
+		NoProxy              string `yaml:"noProxy"`
+		CAFile               string `yaml:"caFile"`
+		TLSSkipVerify        bool   `yaml:"tlsSkipVerify"`
+		LogLevel             string `yaml:"logLevel"`
+	} `yaml:"runtime"`
+	Probes struct {
+		ListenAddr string `yaml:"listenAddr"`
+	} `yaml:"probes"`
+}
+
+type HeadlessTunnel struct {
+	ID        string `yaml:"id"`
+	Target    string `yaml:"target"`
+	TokenFile string `yaml:"tokenFile"`
+}
+
+type HeadlessOptions struct {
+	ProbeListenAddr string
+}
+
+func LoadHeadlessConfig(path string) (Config, HeadlessOptions, error) {
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, HeadlessOptions{}, fmt.Errorf("read agent config: %w", err)
+	}
+	cfg, options, err := ParseHeadlessConfig(payload)
+	if err != nil {
+		return Config{}, HeadlessOptions{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, options, nil
+}
+
+// ParseHeadlessConfig validates a headless config document. Unknown fields
+// are rejected so a typo does not silently fall back to a default.
+func ParseHeadlessConfig(payload []byte) (Config, HeadlessOptions, error) {
+	var document HeadlessDocument
+	decoder := yaml.NewDecoder(bytes.NewReader(payload))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&document); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, HeadlessOptions{}, err
+	}
+	if document.APIVersion != HeadlessAPIVersion || document.Kind != HeadlessKind {
+		return Config{}, HeadlessOptions{}, fmt.Errorf("expected apiVersion %s and kind %s", HeadlessAPIVersion, HeadlessKind)
+	}
+	spec := document.Spec
+
+	agentID := strings.TrimSpace(spec.AgentID)
+	if agentID == "" {
+		agentID = strings.TrimSpace(document.Metadata.Name)
+	}
+	if agentID == "" {
+		if host, err := os.Hostname(); err == nil {
+			agentID = strings.TrimSpace(host)
+		}
+	}
+	cfg := Config{
+		GatewayBaseURL:       strings.TrimSpace(spec.GatewayBaseURL),
+		AgentID:              agentID,
+		HeartbeatInterval:    10 * time.Second,
+		RequestTimeout:       45 * time.Second,
+		PollWait:             25 * time.Second,
+		MaxResponseBodyBytes: 20 << 20,
+		MaxBytesPerSecond:    spec.Runtime.MaxBytesPerSecond,
+		ProxyURL:             strings.TrimSpace(spec.Runtime.ProxyURL),
+		NoProxy:              strings.TrimSpace(spec.Runtime.NoProxy),
+		CAFile:               strings.TrimSpace(spec.Runtime.CAFile),
+		TLSSkipVerify:        spec.Runtime.TLSSkipVerify,
+		UpstreamHTTP2:        UpstreamHTTP2Auto,
+		Transport:            TransportAuto,
+		HealthCheckInterval:  10 * time.Second,
+		HealthCheckThreshold: 3,
+		LogLevel:             strings.TrimSpace(spec.Runtime.LogLevel),
+	}
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = "info"
+	}
+
+	parsedURL, err := url.Parse(cfg.GatewayBaseURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") {
+		return Config{}, HeadlessOptions{}, fmt.Errorf("spec.gatewayBaseURL must be an http or https URL")
+	}
+	for _, duration := range []struct {
+		field string
+		raw   string
+		out   *time.Duration
+	}{
+		{"requestTimeout", spec.Runtime.RequestTimeout, &cfg.RequestTimeout},
+		{"pollWait", spec.Runtime.PollWait, &cfg.PollWait},
+		{"heartbeatInterval", spec.Runtime.HeartbeatInterval, &cfg.HeartbeatInterval},
+	} {
+		raw := strings.TrimSpace(duration.raw)
+		if raw == "" {
+			continue
+		}
+		value, err := time.ParseDuration(raw)
+		if err != nil || value <= 0 {
+			return Config{}, HeadlessOptions{}, fmt.Errorf("spec.runtime.%s must be a positive duration, got %q", duration.field, raw)
+		}
+		*duration.out = value
+	}
+	if spec.Runtime.MaxResponseBodyBytes < 0 {
+		return Config{}, HeadlessOptions{}, fmt.Errorf("spec.runtime.maxResponseBodyBytes must be > 0")
+	}
+	if spec.Runtime.MaxResponseBodyBytes > 0 {
+		cfg.MaxResponseBodyBytes = spec.Runtime.MaxResponseBodyBytes
+	}
+	if err := ValidateBandwidthLimit(cfg.MaxBytesPerSecond); err != nil {
+		return Config{}, HeadlessOptions{}, fmt.Errorf("spec.runtime.maxBytesPerSecond %w", err)
+	}
+	if cfg.CAFile != "" {
+		if _, err := os.Stat(cfg.CAFile); err != nil {
+			return Config{}, HeadlessOptions{}, fmt.Errorf("check spec.runtime.caFile: %w", err)
+		}
+	}
+
+	if spec.Connector != nil {
+		cfg.ConnectorID = strings.TrimSpace(spec.Connector.ID)
+		cfg.ConnectorSecretFile = strings.TrimSpace(spec.Connector.SecretFile)
+		if cfg.ConnectorID == "" || cfg.ConnectorSecretFile == "" {
+			return Config{}, HeadlessOptions{}, fmt.Errorf("spec.connector needs both id and secretFile")
+		}
+		if cfg.ConnectorSecret, err = readSecretFile("spec.connector.secretFile", cfg.ConnectorSecretFile); err != nil {
+			return Config{}, HeadlessOptions{}, err
+		}
+	} else {
+		if strings.TrimSpace(spec.AgentTokenFile) == "" {
+			return Config{}, HeadlessOptions{}, fmt.Errorf("spec.connector or spec.agentTokenFile is required")
+		}
+		if cfg.AgentToken, err = readSecretFile("spec.agentTokenFile", spec.AgentTokenFile); err != nil {
+			return Config{}, HeadlessOptions{}, err
+		}
+		if len(spec.Tunnels) == 0 {
+			return Config{}, HeadlessOptions{}, fmt.Errorf("spec.tunnels must list at least one tunnel without spec.connector")
+		}
+	}
+
+	seen := make(map[string]struct{}, len(spec.Tunnels))
+	for index, tunnel := range spec.Tunnels {
+		id := strings.TrimSpace(tunnel.ID)
+		if id == "" {
+			return Config{}, HeadlessOptions{}, fmt.Errorf("spec.tunnels[%d].id cannot be empty", index)
+		}
+		if _, ok := seen[id]; ok {
+			return Config{}, HeadlessOptions{}, fmt.Errorf("duplicate tunnel id %q", id)
+		}
+		seen[id] = struct{}{}
+		target := strings.TrimSpace(tunnel.Target)
+		if err := checkTunnelTarget(id, target); err != nil {
+			return Config{}, HeadlessOptions{}, err
+		}
+		config := protocol.TunnelConfig{ID: id, Target: target}
+		if strings.TrimSpace(tunnel.TokenFile) != "" {
+			if config.Token, err = readSecretFile(fmt.Sprintf("spec.tunnels[%d].tokenFile", index), tunnel.TokenFile); err != nil {
+				return Config{}, HeadlessOptions{}, err
+			}
+		}
+		cfg.Tunnels = append(cfg.Tunnels, config)
+	}
+	sort.Slice(cfg.Tunnels, func(i, j int) bool { return cfg.Tunnels[i].ID < cfg.Tunnels[j].ID })
+
+	checks := make([]string, 0, len(spec.HealthChecks))
+	for _, check := range spec.HealthChecks {
+		entry := strings.TrimSpace(check.Target) + "=" + strings.TrimSpace(check.Kind)
+		if path := strings.TrimSpace(check.Path); path != "" {
+			entry += ":" + path
+		}
+		checks = append(checks, entry)
+	}
+	if cfg.HealthChecks, err = parseHealthChecks(strings.Join(checks, ","), cfg.Tunnels); err != nil {
+		return Config{}, HeadlessOptions{}, fmt.Errorf("spec.healthChecks: %w", err)
+	}
+
+	options := HeadlessOptions{ProbeListenAddr: strings.TrimSpace(spec.Probes.ListenAddr)}
+	if options.ProbeListenAddr == "" {
+		options.ProbeListenAddr = DefaultProbeListenAddr
+	}
+	return cfg, options, nil
+}
+
+func readSecretFile(field, path string) (string, error) {
+	payload, err := os.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", field, err)
+	}
+	secret := strings.TrimSpace(string(payload))
+	if secret == "" {
+		return "", fmt.Errorf("%s %s is empty", field, path)
+	}
+	return secret, nil
+}
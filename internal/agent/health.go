@@ -0,0 +1,234 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const (
+	HealthCheckTCP   = "tcp"
+	HealthCheckHTTP  = "http"
+	HealthCheckHTTPS = "https"
+)
+
+const maxHealthCheckTimeout = 5 * time.Second
+
+type HealthCheck struct {
+	Target string
+	Kind   string
+	Path   string
+}
+
+type healthState struct {
+	mu      sync.Mutex
+	reports map[string]protocol.TargetHealth
+}
+
+func parseHealthChecks(raw string, tunnels []protocol.TunnelConfig) ([]HealthCheck, error) {
+	known := make(map[string]struct{}, len(tunnels))
+	for _, tunnel := range tunnels {
+		known[tunnel.ID] = struct{}{}
+	}
+
+	checks := make([]HealthCheck, 0)
+	seen := make(map[string]struct{})
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, spec, ok := strings.Cut(entry, "=")
+		target = strings.TrimSpace(target)
+		if !ok || target == "" {
+			return nil, fmt.Errorf("invalid health check %q; expected target=tcp or target=http:/path", entry)
+		}
+		if _, ok := seen[target]; ok {
+			return nil, fmt.Errorf("duplicate health check for %q", target)
+		}
+		seen[target] = struct{}{}
+		if _, _, err := net.SplitHostPort(target); err != nil && !filepath.IsAbs(target) {
+			if _, ok := known[target]; !ok {
+				return nil, fmt.Errorf("health check target %q is neither a tunnel id, host:port nor a socket path", target)
+			}
+		}
+
+		kind, path, _ := strings.Cut(strings.TrimSpace(spec), ":")
+		check := HealthCheck{Target: target, Kind: strings.ToLower(kind), Path: strings.TrimSpace(path)}
+		switch check.Kind {
+		case HealthCheckTCP:
+			if check.Path != "" {
+				return nil, fmt.Errorf("tcp health check for %q cannot have a path", target)
+			}
+		case HealthCheckHTTP, HealthCheckHTTPS:
+			if check.Path == "" {
+				check.Path = "/"
+			}
+			if !strings.HasPrefix(check.Path, "/") {
+				return nil, fmt.Errorf("health check path for %q must start with /", target)
+			}
+		default:
+			return nil, fmt.Errorf("health check kind for %q must be tcp, http or https", target)
+		}
+		checks = append(checks, check)
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Target < checks[j].Target })
+	return checks, nil
+}
+
+func (a *Agent) healthLoop(ctx context.Context, done <-chan struct{}) {
+	ticker := time.NewTicker(a.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if a.runHealthChecks(ctx) {
+			if sessionID := a.getSessionID(); sessionID != "" {
+				if err := a.sendHeartbeat(ctx, sessionID); err != nil {
+					a.logger.Printf("health report error: %v", err)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Agent) runHealthChecks(ctx context.Context) bool {
+	changed := false
+	for _, check := range a.cfg.HealthChecks {
+		err := a.probeHealth(ctx, check)
+
+		a.health.mu.Lock()
+		previous, existed := a.health.reports[check.Target]
+		report := protocol.TargetHealth{
+			Target:    check.Target,
+			Status:    protocol.HealthHealthy,
+			CheckedAt: time.Now().UTC(),
+		}
+		if err != nil {
+			report.ConsecutiveFailures = previous.ConsecutiveFailures + 1
+			report.Error = err.Error()
+			report.Status = protocol.HealthDegraded
+			if report.ConsecutiveFailures >= a.cfg.HealthCheckThreshold {
+				report.Status = protocol.HealthUnhealthy
+			}
+		}
+		a.health.reports[check.Target] = report
+		a.health.mu.Unlock()
+
+		if !existed || previous.Status != report.Status {
+			changed = true
+			if report.Status != protocol.HealthHealthy {
+				a.logger.Printf("local target %s is %s: %s", check.Target, report.Status, report.Error)
+			} else if existed {
+				a.logger.Printf("local target %s recovered", check.Target)
+			}
+		}
+	}
+	return changed
+}
+
+func (a *Agent) healthReports() []protocol.TargetHealth {
+	a.health.mu.Lock()
+	defer a.health.mu.Unlock()
+	if len(a.health.reports) == 0 {
+		return nil
+	}
+	reports := make([]protocol.TargetHealth, 0, len(a.health.reports))
+	for _, report := range a.health.reports {
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Target < reports[j].Target })
+	return reports
+}
+
+func (a *Agent) probeHealth(ctx context.Context, check HealthCheck) error {
+	timeout := min(a.cfg.HealthCheckInterval, maxHealthCheckTimeout)
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	scheme := "http"
+	if check.Kind == HealthCheckHTTPS {
+		scheme = "https"
+	}
+	base, socketPath := "", ""
+	var targetTLS *protocol.LocalTLS
+	if tunnel, ok := a.tunnel(check.Target); ok {
+		if _, _, isFile := parseFileTunnelTarget(tunnel.Target); isFile {
+			return CheckFileTunnelTarget(tunnel.Target)
+		}
+		base, targetTLS = tunnel.Target, tunnel.TLS
+		if path, ok := parseUnixSocketTarget(tunnel.Target); ok {
+			base, socketPath = "http://"+unixSocketHost, path
+		}
+	} else if filepath.IsAbs(check.Target) {
+		base, socketPath = scheme+"://"+unixSocketHost, check.Target
+	} else {
+		base = scheme + "://" + check.Target
+	}
+
+	if socketPath != "" && check.Kind == HealthCheckTCP {
+		conn, err := (&net.Dialer{}).DialContext(probeCtx, "unix", socketPath)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	client, err := a.localClient(socketPath, targetTLS)
+	if err != nil {
+		return err
+	}
+
+	if check.Kind == HealthCheckTCP {
+		parsed, err := url.Parse(base)
+		if err != nil {
+			return fmt.Errorf("parse target: %w", err)
+		}
+		address := parsed.Host
+		if parsed.Port() == "" {
+			port := "80"
+			if parsed.Scheme == "https" {
+				port = "443"
+			}
+			address = net.JoinHostPort(parsed.Hostname(), port)
+		}
+		conn, err := (&net.Dialer{}).DialContext(probeCtx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	targetURL, err := buildTargetURL(base, check.Path, "")
+	if err != nil {
+		return fmt.Errorf("build health check URL: %w", err)
+	}
+	request, err := http.NewRequestWithContext(probeCtx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return fmt.Errorf("build health check request: %w", err)
+	}
+	request.Header.Set("User-Agent", "proxer-agent-health")
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 400 {
+		return fmt.Errorf("health check returned status %d", response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+type localClientKey struct {
+	socket string
+	tls    protocol.LocalTLS
+}
+
+// localClients caches one HTTP client per variant so keep-alive connections
+// are reused across requests.
+type localClients struct {
+	mu      sync.Mutex
+	clients map[localClientKey]*http.Client
+}
+
+// localClient returns the client for a local target. Targets without a
+// socket or TLS overrides share upstreamClient; the others get a clone of its
+// transport, so protocol settings still apply. Proxies never apply to
+// sockets.
+func (a *Agent) localClient(socketPath string, opts *protocol.LocalTLS) (*http.Client, error) {
+	key := localClientKey{socket: socketPath}
+	if opts != nil {
+		key.tls = *opts
+	}
+	if key == (localClientKey{}) {
+		return a.upstreamClient, nil
+	}
+
+	a.local.mu.Lock()
+	defer a.local.mu.Unlock()
+	if client, ok := a.local.clients[key]; ok {
+		return client, nil
+	}
+	transport := a.upstreamClient.Transport.(*http.Transport).Clone()
+	if socketPath != "" {
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		}
+	}
+	if opts != nil {
+		tlsConfig, err := localTLSConfig(transport.TLSClientConfig, opts)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	client := &http.Client{Transport: transport}
+	if a.local.clients == nil {
+		a.local.clients = make(map[localClientKey]*http.Client)
+	}
+	a.local.clients[key] = client
+	return client, nil
+}
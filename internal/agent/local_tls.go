@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func localTLSConfig(base *tls.Config, opts *protocol.LocalTLS) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if base != nil {
+		cfg = base.Clone()
+	}
+	if name := strings.TrimSpace(opts.ServerName); name != "" {
+		cfg.ServerName = name
+	}
+	if opts.InsecureSkipVerify {
+		cfg.InsecureSkipVerify = true
+	}
+	if caPEM := strings.TrimSpace(opts.CAPEM); caPEM != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(caPEM)) {
+			return nil, fmt.Errorf("ca_pem contains no certificates")
+		}
+		cfg.RootCAs = pool
+	}
+	if pin := strings.TrimSpace(opts.PinnedSHA256); pin != "" {
+		want, err := hex.DecodeString(strings.ReplaceAll(pin, ":", ""))
+		if err != nil || len(want) != sha256.Size {
+			return nil, fmt.Errorf("pinned_sha256 must be 64 hex digits")
+		}
+		// The pin replaces chain and hostname verification.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("local target sent no certificate")
+			}
+			got := sha256.Sum256(state.PeerCertificates[0].Raw)
+			if subtle.ConstantTimeCompare(got[:], want) != 1 {
+				return fmt.Errorf("local target certificate sha256 %x does not match the pinned fingerprint", got)
+			}
+			return nil
+		}
+	}
+	return cfg, nil
+}
+
+func parseTunnelTLS(raw string, tunnels []protocol.TunnelConfig) error {
+	index := make(map[string]int, len(tunnels))
+	for i, tunnel := range tunnels {
+		index[tunnel.ID] = i
+	}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tunnelID, option, ok := strings.Cut(entry, "=")
+		tunnelID = strings.TrimSpace(tunnelID)
+		i, known := index[tunnelID]
+		if !ok || !known {
+			return fmt.Errorf("invalid tunnel TLS option %q; expected <tunnel id>=insecure|pin:<sha256>|ca:<file>|server_name:<name>", entry)
+		}
+		opts := tunnels[i].TLS
+		if opts == nil {
+			opts = &protocol.LocalTLS{}
+		}
+		kind, value, _ := strings.Cut(strings.TrimSpace(option), ":")
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(kind) {
+		case "insecure":
+			opts.InsecureSkipVerify = true
+		case "pin":
+			opts.PinnedSHA256 = value
+		case "ca":
+			pemData, err := os.ReadFile(value)
+			if err != nil {
+				return fmt.Errorf("read CA for tunnel %q: %w", tunnelID, err)
+			}
+			opts.CAPEM = string(pemData)
+		case "server_name":
+			opts.ServerName = value
+		default:
+			return fmt.Errorf("unknown TLS option %q for tunnel %q", kind, tunnelID)
+		}
+		if _, err := localTLSConfig(nil, opts); err != nil {
+			return fmt.Errorf("tunnel %q: %w", tunnelID, err)
+		}
+		tunnels[i].TLS = opts
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const (
+	defaultOfflineQueueMax      = 1000
+	defaultOfflineReplayPeriod  = 5 * time.Second
+	offlineQueueFileSuffix      = ".json"
+	offlineQueuedResponseHeader = "X-Proxer-Offline-Queued"
+)
+
+type offlineQueue struct {
+	// mu serializes captures so the size limit holds.
+	mu  sync.Mutex
+	seq atomic.Uint64
+}
+
+func parseOfflineQueueTargets(raw string, tunnels []protocol.TunnelConfig) ([]string, error) {
+	known := make(map[string]struct{}, len(tunnels))
+	for _, tunnel := range tunnels {
+		known[tunnel.ID] = struct{}{}
+	}
+
+	targets := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, entry := range strings.Split(raw, ",") {
+		target := strings.TrimSpace(entry)
+		if target == "" {
+			continue
+		}
+		if _, ok := seen[target]; ok {
+			continue
+		}
+		seen[target] = struct{}{}
+		if _, _, err := net.SplitHostPort(target); err != nil && !filepath.IsAbs(target) {
+			if _, ok := known[target]; !ok {
+				return nil, fmt.Errorf("offline queue target %q is neither a tunnel id, host:port nor a socket path", target)
+			}
+		}
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets, nil
+}
+
+func defaultOfflineQueueDir() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "proxer", "offline-queue")
+	}
+	return filepath.Join(os.TempDir(), "proxer-offline-queue")
+}
+
+func offlineTarget(proxyReq *protocol.ProxyRequest) string {
+	target := proxyReq.LocalTarget
+	if target == nil {
+		return proxyReq.TunnelID
+	}
+	if socketPath := strings.TrimSpace(target.Socket); socketPath != "" {
+		return socketPath
+	}
+	host := strings.TrimSpace(target.Host)
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(target.Port))
+}
+
+// capturesOffline reports whether a request that could not reach its local
+// target should be queued. Only methods that deliver something, like webhook
+// POSTs, are; a queued GET would answer its caller with nothing useful.
+func (a *Agent) capturesOffline(proxyReq *protocol.ProxyRequest, err error) bool {
+	if len(a.cfg.OfflineQueueTargets) == 0 || !isDialError(err) {
+		return false
+	}
+	switch proxyReq.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+	target := offlineTarget(proxyReq)
+	for _, candidate := range a.cfg.OfflineQueueTargets {
+		if candidate == target {
+			return true
+		}
+	}
+	return false
+}
+
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func (a *Agent) captureOffline(proxyReq *protocol.ProxyRequest, start time.Time) *protocol.ProxyResponse {
+	a.offline.mu.Lock()
+	defer a.offline.mu.Unlock()
+
+	dir := a.cfg.OfflineQueueDir
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		a.logger.Printf("offline queue: create %s: %v", dir, err)
+		return nil
+	}
+	if queued, err := listOfflineQueue(dir); err != nil || len(queued) >= a.cfg.OfflineQueueMax {
+		a.logger.Printf("offline queue: not queueing request_id=%s: queue is full or unreadable", proxyReq.RequestID)
+		return nil
+	}
+	data, err := json.Marshal(proxyReq)
+	if err != nil {
+		a.logger.Printf("offline queue: encode request_id=%s: %v", proxyReq.RequestID, err)
+		return nil
+	}
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), a.offline.seq.Add(1)%1000000, offlineQueueFileSuffix)
+	tmpPath := filepath.Join(dir, name+".tmp")
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		a.logger.Printf("offline queue: write request_id=%s: %v", proxyReq.RequestID, err)
+		return nil
+	}
+	if err := os.Rename(tmpPath, filepath.Join(dir, name)); err != nil {
+		_ = os.Remove(tmpPath)
+		a.logger.Printf("offline queue: persist request_id=%s: %v", proxyReq.RequestID, err)
+		return nil
+	}
+	a.logger.Printf("offline queue: local target %s is down; queued request_id=%s for replay", offlineTarget(proxyReq), proxyReq.RequestID)
+
+	body, _ := json.Marshal(map[string]any{"queued": true, "request_id": proxyReq.RequestID})
+	return &protocol.ProxyResponse{
+		RequestID: proxyReq.RequestID,
+		TunnelID:  proxyReq.TunnelID,
+		Status:    http.StatusAccepted,
+		Headers: map[string][]string{
+			"Content-Type":              {"application/json"},
+			offlineQueuedResponseHeader: {"true"},
+		},
+		Body:      body,
+		BytesIn:   int64(len(proxyReq.Body)),
+		BytesOut:  int64(len(body)),
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+}
+
+func listOfflineQueue(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), offlineQueueFileSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (a *Agent) offlineReplayLoop(ctx context.Context, done <-chan struct{}) {
+	ticker := time.NewTicker(a.cfg.OfflineReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		a.replayOfflineQueue()
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// replayOfflineQueue delivers queued requests until one still cannot reach
+// its target. A request that reached its target is dropped from the queue
+// whatever the response, as the target has seen it.
+func (a *Agent) replayOfflineQueue() {
+	names, err := listOfflineQueue(a.cfg.OfflineQueueDir)
+	if err != nil {
+		a.logger.Printf("offline queue: list %s: %v", a.cfg.OfflineQueueDir, err)
+		return
+	}
+	down := make(map[string]struct{})
+	for _, name := range names {
+		path := filepath.Join(a.cfg.OfflineQueueDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var proxyReq protocol.ProxyRequest
+		if err := json.Unmarshal(data, &proxyReq); err != nil {
+			a.logger.Printf("offline queue: dropping unreadable %s: %v", name, err)
+			_ = os.Remove(path)
+			continue
+		}
+		// Keep each target's requests in order behind the first that fails.
+		target := offlineTarget(&proxyReq)
+		if _, ok := down[target]; ok {
+			continue
+		}
+		response, err := a.forwardLocal(context.Background(), &proxyReq, false)
+		if isDialError(err) {
+			down[target] = struct{}{}
+			continue
+		}
+		_ = os.Remove(path)
+		a.logger.Printf("offline queue: replayed request_id=%s to %s: status=%d", proxyReq.RequestID, target, response.Status)
+	}
+}
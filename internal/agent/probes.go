@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Probes struct {
+	mu    sync.RWMutex
+	event RuntimeEvent
+}
+
+func NewProbes() *Probes {
+	return &Probes{event: RuntimeEvent{State: RuntimeStateStarting, At: time.Now().UTC()}}
+}
+
+func (p *Probes) Observe(event RuntimeEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.event = event
+}
+
+func (p *Probes) live() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.event.State != RuntimeStateError && p.event.State != RuntimeStateStopped
+}
+
+func (p *Probes) ready() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.event.State == RuntimeStateRunning && strings.TrimSpace(p.event.SessionID) != ""
+}
+
+func (p *Probes) Handler() http.Handler {
+	mux := http.NewServeMux()
+	live := func(w http.ResponseWriter, r *http.Request) { p.write(w, p.live()) }
+	mux.HandleFunc("/healthz", live)
+	mux.HandleFunc("/livez", live)
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) { p.write(w, p.ready()) })
+	return mux
+}
+
+func (p *Probes) write(w http.ResponseWriter, ok bool) {
+	p.mu.RLock()
+	event := p.event
+	p.mu.RUnlock()
+
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"ok":      ok,
+		"state":   event.State,
+		"message": event.Message,
+		"error":   event.Error,
+		"at":      event.At,
+	})
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const secretRotationRetry = time.Minute
+
+func (a *Agent) scheduleSecretRotation(rotateAfter, expiresAt *time.Time) {
+	a.sessionMu.Lock()
+	defer a.sessionMu.Unlock()
+	a.secretRotateAt = time.Time{}
+	if rotateAfter != nil {
+		a.secretRotateAt = *rotateAfter
+	}
+	if expiresAt != nil {
+		a.logger.Printf("connector secret expires %s; rotating it from %s", expiresAt.Format(time.RFC3339), a.secretRotateAt.Format(time.RFC3339))
+	}
+}
+
+func (a *Agent) secretRotationDue(now time.Time) bool {
+	a.sessionMu.RLock()
+	defer a.sessionMu.RUnlock()
+	return a.isConnectorMode() && !a.secretRotateAt.IsZero() && !now.Before(a.secretRotateAt)
+}
+
+// rotateSecretIfDue replaces the connector secret once its rotation window
+// opens. The gateway keeps accepting the old secret for a grace period, so
+// a failed attempt is retried later without dropping the session.
+func (a *Agent) rotateSecretIfDue(ctx context.Context) {
+	now := time.Now()
+	if !a.secretRotationDue(now) {
+		return
+	}
+	err := a.rotateSecret(ctx)
+	if err == nil {
+		return
+	}
+	a.logger.Printf("connector secret rotation failed: %v", err)
+	a.sessionMu.Lock()
+	a.secretRotateAt = now.Add(secretRotationRetry)
+	a.sessionMu.Unlock()
+}
+
+func (a *Agent) rotateSecret(ctx context.Context) error {
+	requestBody, err := json.Marshal(protocol.RotateSecretRequest{
+		ConnectorID:     a.cfg.ConnectorID,
+		ConnectorSecret: a.cfg.ConnectorSecret,
+	})
+	if err != nil {
+		return fmt.Errorf("encode rotate payload: %w", err)
+	}
+
+	requestCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(requestCtx, http.MethodPost, strings.TrimRight(a.cfg.GatewayBaseURL, "/")+"/api/agent/rotate", bytes.NewReader(requestBody))
+	if err != nil {
+		return fmt.Errorf("build rotate request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := a.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("post rotate request: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+		return fmt.Errorf("rotate rejected (status %d): %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var payload protocol.RotateSecretResponse
+	if err := json.NewDecoder(response.Body).Decode(&payload); err != nil {
+		return fmt.Errorf("decode rotate response: %w", err)
+	}
+	if strings.TrimSpace(payload.ConnectorSecret) == "" {
+		return fmt.Errorf("rotate response did not include a secret")
+	}
+
+	a.cfg.ConnectorSecret = payload.ConnectorSecret
+	a.logger.Printf("rotated connector secret for %s", a.cfg.ConnectorID)
+	if err := a.saveConnectorSecret(payload.ConnectorSecret); err != nil {
+		a.logger.Printf("save rotated connector secret: %v", err)
+	}
+	if a.cfg.SecretHook != nil {
+		a.cfg.SecretHook(a.cfg.ConnectorID, payload.ConnectorSecret)
+	}
+	a.scheduleSecretRotation(payload.RotateAfter, payload.ExpiresAt)
+	return nil
+}
+
+// saveConnectorSecret writes secret to ConnectorSecretFile, if set, through
+// a temporary file so a crash cannot leave it half written.
+func (a *Agent) saveConnectorSecret(secret string) error {
+	path := strings.TrimSpace(a.cfg.ConnectorSecretFile)
+	if path == "" {
+		if a.cfg.SecretHook == nil {
+			a.logger.Printf("rotated connector secret is only kept in memory; set PROXER_AGENT_CONNECTOR_SECRET_FILE to keep it across restarts")
+		}
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".connector-secret-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(secret + "\n"); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
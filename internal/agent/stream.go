@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/httpx"
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const streamDialTimeout = 10 * time.Second
+
+func (a *Agent) handleStream(ctx context.Context, sessionID string, proxyReq *protocol.ProxyRequest) error {
+	start := time.Now()
+	response := &protocol.ProxyResponse{
+		RequestID: proxyReq.RequestID,
+		TunnelID:  proxyReq.TunnelID,
+		Status:    http.StatusOK,
+	}
+	conn, err := dialStreamTarget(proxyReq)
+	if err != nil {
+		response.Status = http.StatusBadGateway
+		response.Error = fmt.Sprintf("dial local target: %v", err)
+	}
+	response.LatencyMs = time.Since(start).Milliseconds()
+	a.logProxyRequest(proxyReq, response)
+	a.conn.recordRequest(response.Error != "")
+
+	if err := a.submitResponse(ctx, sessionID, response); err != nil {
+		if conn != nil {
+			_ = conn.Close()
+		}
+		return fmt.Errorf("request_id=%s: %w", proxyReq.RequestID, err)
+	}
+	if conn != nil {
+		go func() {
+			if err := a.pipeStream(ctx, a.streamURL(sessionID, proxyReq.RequestID), conn); err != nil {
+				a.logger.Printf("stream request_id=%s: %v", proxyReq.RequestID, err)
+			}
+		}()
+	}
+	return nil
+}
+
+func dialStreamTarget(proxyReq *protocol.ProxyRequest) (net.Conn, error) {
+	target := proxyReq.LocalTarget
+	if target == nil {
+		return nil, errors.New("streams need a connector route with a local target")
+	}
+	timeout := streamDialTimeout
+	if proxyReq.TimeoutMs > 0 && time.Duration(proxyReq.TimeoutMs)*time.Millisecond < timeout {
+		timeout = time.Duration(proxyReq.TimeoutMs) * time.Millisecond
+	}
+	if socketPath := strings.TrimSpace(target.Socket); socketPath != "" {
+		return net.DialTimeout("unix", socketPath, timeout)
+	}
+	host := strings.TrimSpace(target.Host)
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if target.Port < 1 || target.Port > 65535 {
+		return nil, fmt.Errorf("port must be between 1 and 65535")
+	}
+	return net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(target.Port)), timeout)
+}
+
+func (a *Agent) streamURL(sessionID, requestID string) string {
+	query := url.Values{}
+	query.Set("session_id", sessionID)
+	query.Set("request_id", requestID)
+	return strings.TrimRight(a.cfg.GatewayBaseURL, "/") + "/api/agent/stream?" + query.Encode()
+}
+
+func (a *Agent) pipeStream(ctx context.Context, streamURL string, conn net.Conn) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer conn.Close()
+
+	uplinkBody, uplinkWriter := io.Pipe()
+	go func() {
+		_, err := io.Copy(uplinkWriter, httpx.ThrottleReader(ctx, conn, a.bandwidth))
+		_ = uplinkWriter.CloseWithError(err)
+	}()
+	uplinkErr := make(chan error, 1)
+	go func() {
+		uplinkErr <- a.streamRequest(ctx, http.MethodPost, streamURL, uplinkBody, nil)
+		// The gateway ends the uplink when its client is gone.
+		cancel()
+	}()
+
+	downlinkErr := a.streamRequest(ctx, http.MethodGet, streamURL, nil, httpx.ThrottleWriter(ctx, conn, a.bandwidth))
+	_ = conn.Close()
+	err := <-uplinkErr
+	if downlinkErr != nil {
+		err = downlinkErr
+	}
+	if err == nil || ctx.Err() != nil || errors.Is(err, net.ErrClosed) || errors.Is(err, io.ErrClosedPipe) {
+		return nil
+	}
+	return err
+}
+
+func (a *Agent) streamRequest(ctx context.Context, method, streamURL string, body io.Reader, sink io.Writer) error {
+	request, err := http.NewRequestWithContext(ctx, method, streamURL, body)
+	if err != nil {
+		return fmt.Errorf("build stream request: %w", err)
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/octet-stream")
+	}
+	response, err := a.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("stream %s: %w", strings.ToLower(method), err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= http.StatusMultipleChoices {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+		return fmt.Errorf("stream %s rejected (status %d): %s", strings.ToLower(method), response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if sink != nil {
+		_, err = io.Copy(sink, response.Body)
+	}
+	return err
+}
@@ -0,0 +1,19 @@
+package agent
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+const unixSocketScheme = "unix"
+
+const unixSocketHost = "localhost"
+
+func parseUnixSocketTarget(target string) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(target))
+	if err != nil || parsed.Scheme != unixSocketScheme || parsed.Path == "" {
+		return "", false
+	}
+	return filepath.FromSlash(parsed.Path), true
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// openUpload starts streaming the relayed body of requestID from the
+// gateway. The gateway sends it as the caller uploads it, so reading can
+// block for as long as the caller takes.
+func (a *Agent) openUpload(ctx context.Context, sessionID, requestID string) (io.ReadCloser, error) {
+	query := url.Values{}
+	query.Set("session_id", sessionID)
+	query.Set("request_id", requestID)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(a.cfg.GatewayBaseURL, "/")+"/api/agent/upload?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("build upload request: %w", err)
+	}
+	response, err := a.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("open upload: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+		response.Body.Close()
+		return nil, fmt.Errorf("open upload rejected (status %d): %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return response.Body, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,105 @@
+package echoserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxBodyBytes = 1 << 20
+	MaxDelay     = time.Minute
+
+	StatusParam  = "echo_status"
+	DelayParam   = "echo_delay"
+	StatusHeader = "X-Echo-Status"
+	DelayHeader  = "X-Echo-Delay"
+)
+
+type Options struct {
+	Name    string
+	Status  int
+	Latency time.Duration
+}
+
+type Response struct {
+	Service    string      `json:"service,omitempty"`
+	Method     string      `json:"method"`
+	Host       string      `json:"host"`
+	Path       string      `json:"path"`
+	Query      string      `json:"query"`
+	Headers    http.Header `json:"headers"`
+	Body       string      `json:"body"`
+	BodyBytes  int         `json:"body_bytes"`
+	BodyBinary bool        `json:"body_binary,omitempty"`
+	Status     int         `json:"status"`
+	DelayMs    int64       `json:"delay_ms"`
+}
+
+func Handler(opts Options) http.Handler {
+	if opts.Status == 0 {
+		opts.Status = http.StatusOK
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, err := override(r, StatusParam, StatusHeader, opts.Status, strconv.Atoi)
+		if err != nil || status < 100 || status > 999 {
+			http.Error(w, "invalid "+StatusParam, http.StatusBadRequest)
+			return
+		}
+		delay, err := override(r, DelayParam, DelayHeader, opts.Latency, time.ParseDuration)
+		if err != nil || delay < 0 || delay > MaxDelay {
+			http.Error(w, "invalid "+DelayParam+" (a duration up to "+MaxDelay.String()+")", http.StatusBadRequest)
+			return
+		}
+
+		body, _ := io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes))
+		response := Response{
+			Service:   opts.Name,
+			Method:    r.Method,
+			Host:      r.Host,
+			Path:      r.URL.Path,
+			Query:     r.URL.RawQuery,
+			Headers:   r.Header,
+			Body:      string(body),
+			BodyBytes: len(body),
+			Status:    status,
+			DelayMs:   delay.Milliseconds(),
+		}
+		if !utf8.Valid(body) {
+			response.Body = ""
+			response.BodyBinary = true
+		}
+
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if r.Method != http.MethodHead {
+			encoder := json.NewEncoder(w)
+			encoder.SetEscapeHTML(false)
+			_ = encoder.Encode(response)
+		}
+	})
+}
+
+func override[T any](r *http.Request, param, header string, fallback T, parse func(string) (T, error)) (T, error) {
+	value := strings.TrimSpace(r.URL.Query().Get(param))
+	if value == "" {
+		value = strings.TrimSpace(r.Header.Get(header))
+	}
+	if value == "" {
+		return fallback, nil
+	}
+	return parse(value)
+}
@@ -0,0 +1,49 @@
+package echoserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerEchoesRequestAndHonorsOverrides(t *testing.T) {
+	handler := Handler(Options{Name: "api", Status: http.StatusAccepted})
+
+	request := httptest.NewRequest(http.MethodPost, "/items?id=7&echo_status=418", strings.NewReader(`{"a":1}`))
+	request.Header.Set("X-Trace", "abc")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	var echoed Response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &echoed); err != nil {
+		t.Fatalf("decode %q: %v", recorder.Body.String(), err)
+	}
+	if recorder.Code != http.StatusTeapot || echoed.Service != "api" || echoed.Method != http.MethodPost || echoed.Path != "/items" ||
+		echoed.Query != "id=7&echo_status=418" || echoed.Body != `{"a":1}` || echoed.Headers.Get("X-Trace") != "abc" {
+		t.Fatalf("unexpected echo %d %+v", recorder.Code, echoed)
+	}
+
+	request = httptest.NewRequest(http.MethodPut, "/bin", strings.NewReader("\xff\xfe"))
+	request.Header.Set(DelayHeader, "20ms")
+	recorder = httptest.NewRecorder()
+	started := time.Now()
+	handler.ServeHTTP(recorder, request)
+	if elapsed := time.Since(started); elapsed < 20*time.Millisecond {
+		t.Fatalf("expected the requested delay, took %s", elapsed)
+	}
+	echoed = Response{}
+	_ = json.Unmarshal(recorder.Body.Bytes(), &echoed)
+	if recorder.Code != http.StatusAccepted || !echoed.BodyBinary || echoed.BodyBytes != 2 || echoed.DelayMs != 20 {
+		t.Fatalf("unexpected binary echo %d %+v", recorder.Code, echoed)
+	}
+
+	for _, target := range []string{"/?echo_status=abc", "/?echo_delay=2h", "/?echo_delay=-1s"} {
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("expected %s to be rejected, got %d", target, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package gateway
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ipViolationWindow = time.Minute
+
+type IPBan struct {
+	IP         string    `json:"ip"`
+	Reason     string    `json:"reason"`
+	Violations int       `json:"violations,omitempty"`
+	BannedBy   string    `json:"banned_by"`
+	BannedAt   time.Time `json:"banned_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type ipViolationCounter struct {
+	count       int
+	windowStart time.Time
+}
+
+type IPBanList struct {
+	mu         sync.RWMutex
+	bans       map[string]IPBan
+	violations map[string]ipViolationCounter
+}
+
+func NewIPBanList() *IPBanList {
+	return &IPBanList{
+		bans:       make(map[string]IPBan),
+		violations: make(map[string]ipViolationCounter),
+	}
+}
+
+func (l *IPBanList) IsBanned(ip string, now time.Time) (IPBan, bool) {
+	ip = strings.TrimSpace(ip)
+	if ip == "" {
+		return IPBan{}, false
+	}
+	l.mu.RLock()
+	ban, ok := l.bans[ip]
+	l.mu.RUnlock()
+	if !ok {
+		return IPBan{}, false
+	}
+	if !now.Before(ban.ExpiresAt) {
+		l.mu.Lock()
+		if current, exists := l.bans[ip]; exists && !now.Before(current.ExpiresAt) {
+			delete(l.bans, ip)
+		}
+		l.mu.Unlock()
+		return IPBan{}, false
+	}
+	return ban, true
+}
+
+func (l *IPBanList) RecordViolation(ip string, threshold int, banFor time.Duration, now time.Time) (IPBan, bool) {
+	ip = strings.TrimSpace(ip)
+	if ip == "" || threshold <= 0 || banFor <= 0 {
+		return IPBan{}, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	counter := l.violations[ip]
+	if counter.windowStart.IsZero() || now.Sub(counter.windowStart) > ipViolationWindow {
+		counter = ipViolationCounter{windowStart: now}
+	}
+	counter.count++
+	if counter.count < threshold {
+		l.violations[ip] = counter
+		return IPBan{}, false
+	}
+	delete(l.violations, ip)
+	ban := IPBan{
+		IP:         ip,
+		Reason:     "repeated rate limit violations",
+		Violations: counter.count,
+		BannedBy:   "system",
+		BannedAt:   now.UTC(),
+		ExpiresAt:  now.Add(banFor).UTC(),
+	}
+	l.bans[ip] = ban
+	return ban, true
+}
+
+func (l *IPBanList) Sweep(now time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ip, counter := range l.violations {
+		if now.Sub(counter.windowStart) > ipViolationWindow {
+			delete(l.violations, ip)
+		}
+	}
+	expired := 0
+	for ip, ban := range l.bans {
+		if !now.Before(ban.ExpiresAt) {
+			delete(l.bans, ip)
+			expired++
+		}
+	}
+	return expired
+}
+
+func (l *IPBanList) Ban(ip, reason, bannedBy string, banFor time.Duration) (IPBan, error) {
+	ip = strings.TrimSpace(ip)
+	if net.ParseIP(ip) == nil {
+		return IPBan{}, fmt.Errorf("invalid ip %q", ip)
+	}
+	if banFor <= 0 {
+		return IPBan{}, fmt.Errorf("ban duration must be > 0")
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		reason = "manual ban"
+	}
+	now := time.Now().UTC()
+	ban := IPBan{
+		IP:        ip,
+		Reason:    reason,
+		BannedBy:  strings.TrimSpace(bannedBy),
+		BannedAt:  now,
+		ExpiresAt: now.Add(banFor),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bans[ip] = ban
+	delete(l.violations, ip)
+	return ban, nil
+}
+
+func (l *IPBanList) Unban(ip string) bool {
+	ip = strings.TrimSpace(ip)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.bans[ip]; !ok {
+		return false
+	}
+	delete(l.bans, ip)
+	delete(l.violations, ip)
+	return true
+}
+
+func (l *IPBanList) Clear() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	count := len(l.bans)
+	l.bans = make(map[string]IPBan)
+	l.violations = make(map[string]ipViolationCounter)
+	return count
+}
+
+func (l *IPBanList) List(now time.Time) []IPBan {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	bans := make([]IPBan, 0, len(l.bans))
+	for _, ban := range l.bans {
+		if now.Before(ban.ExpiresAt) {
+			bans = append(bans, ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].BannedAt.After(bans[j].BannedAt) })
+	return bans
+}
+
+func (s *Server) proxyClientIP(r *http.Request) string {
+	if s.config().TrustForwardedFor {
+		return signupClientIP(r)
+	}
+	return extractIP(r.RemoteAddr)
+}
+
+func (s *Server) admitClientIP(clientIP, requestID string, now time.Time) (ban IPBan, banned bool, allowed bool) {
+	if ban, banned := s.ipBans.IsBanned(clientIP, now); banned {
+		return ban, true, false
+	}
+	cfg := s.config()
+	if cfg.ProxyIPRPS <= 0 || s.rateLimiter.Allow("ip:"+clientIP, cfg.ProxyIPRPS) {
+		return IPBan{}, false, true
+	}
+	if ban, banned := s.ipBans.RecordViolation(clientIP, cfg.ProxyIPBanThreshold, cfg.ProxyIPBanDuration, now); banned {
+		s.incidentStore.AddForRequest("warning", "abuse", fmt.Sprintf("client %s banned until %s after %d rate limit violations", clientIP, ban.ExpiresAt.Format(time.RFC3339), ban.Violations), requestID)
+		s.persistState()
+	}
+	return IPBan{}, false, false
+}
+
+func (s *Server) checkClientAbuse(w http.ResponseWriter, r *http.Request, tenantID, routeID string) bool {
+	clientIP := s.proxyClientIP(r)
+	if clientIP == "" {
+		return true
+	}
+	now := time.Now().UTC()
+	ban, banned, allowed := s.admitClientIP(clientIP, w.Header().Get("X-Proxer-Request-ID"), now)
+	if banned {
+		w.Header().Set("Retry-After", strconv.Itoa(int(ban.ExpiresAt.Sub(now).Seconds())+1))
+		writeJSON(w, http.StatusForbidden, map[string]any{
+			"error":      "client_banned",
+			"message":    "client is temporarily banned",
+			"expires_at": ban.ExpiresAt,
+			"request_id": w.Header().Get("X-Proxer-Request-ID"),
+		})
+		return false
+	}
+	if allowed {
+		return true
+	}
+	cfg := s.config()
+	if s.writeCustomErrorPage(w, tenantID, routeID, errorPageRateLimited, http.StatusTooManyRequests, "client_rate_limit_exceeded", "client request rate exceeded") {
+		return false
+	}
+	writeJSON(w, http.StatusTooManyRequests, map[string]any{
+		"error":      "client_rate_limit_exceeded",
+		"message":    "client request rate exceeded",
+		"client_rps": cfg.ProxyIPRPS,
+		"request_id": w.Header().Get("X-Proxer-Request-ID"),
+	})
+	return false
+}
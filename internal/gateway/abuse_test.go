@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIPBanListBansRepeatOffenders(t *testing.T) {
+	bans := NewIPBanList()
+	now := time.Now().UTC()
+
+	for i := 0; i < 2; i++ {
+		if _, banned := bans.RecordViolation("203.0.113.7", 3, time.Minute, now); banned {
+			t.Fatalf("expected no ban before threshold")
+		}
+	}
+	ban, banned := bans.RecordViolation("203.0.113.7", 3, time.Minute, now)
+	if !banned || ban.Violations != 3 {
+		t.Fatalf("expected ban after threshold, got %+v (banned=%v)", ban, banned)
+	}
+	if _, ok := bans.IsBanned("203.0.113.7", now.Add(30*time.Second)); !ok {
+		t.Fatalf("expected client to be banned")
+	}
+	if _, ok := bans.IsBanned("203.0.113.7", now.Add(2*time.Minute)); ok {
+		t.Fatalf("expected ban to expire")
+	}
+
+	if _, err := bans.Ban("not-an-ip", "", "admin", time.Minute); err == nil {
+		t.Fatalf("expected invalid ip to be rejected")
+	}
+	if _, err := bans.Ban("198.51.100.1", "", "admin", time.Minute); err != nil {
+		t.Fatalf("manual ban: %v", err)
+	}
+	if len(bans.List(time.Now().UTC())) != 1 {
+		t.Fatalf("expected one active ban")
+	}
+	if !bans.Unban("198.51.100.1") {
+		t.Fatalf("expected unban to succeed")
+	}
+}
+
+func TestPerClientStateIsSwept(t *testing.T) {
+	limiter := NewRateLimiter()
+	limiter.Allow("ip:192.0.2.1", 1)
+	limiter.Allow("ip:192.0.2.2", 1)
+	limiter.Allow("ip:192.0.2.2", 1)
+	now := time.Now().UTC()
+	if dropped := limiter.Sweep(now); dropped != 0 {
+		t.Fatalf("expected buckets still refilling to be kept, dropped %d", dropped)
+	}
+	if dropped := limiter.Sweep(now.Add(3 * time.Second)); dropped != 2 || len(limiter.Snapshot()) != 0 {
+		t.Fatalf("expected refilled buckets to be dropped, dropped %d", dropped)
+	}
+
+	bans := NewIPBanList()
+	bans.RecordViolation("192.0.2.1", 3, time.Minute, now)
+	bans.RecordViolation("192.0.2.2", 1, time.Minute, now)
+	if expired := bans.Sweep(now.Add(30 * time.Second)); expired != 0 || len(bans.violations) != 1 {
+		t.Fatalf("expected the current window and ban to be kept, got %d expired and %d counters", expired, len(bans.violations))
+	}
+	if expired := bans.Sweep(now.Add(2 * time.Minute)); expired != 1 || len(bans.violations) != 0 || len(bans.bans) != 0 {
+		t.Fatalf("expected the stale counter and expired ban to be dropped, got %d expired", expired)
+	}
+}
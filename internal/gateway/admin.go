@@ -1,6 +1,8 @@
 package gateway
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"sort"
@@ -14,28 +16,32 @@ type adminCreateUserRequest struct {
 	Password string `json:"password"`
 	Role     string `json:"role"`
 	TenantID string `json:"tenant_id"`
+	OrgID    string `json:"org_id"`
 	Status   string `json:"status"`
 }
 
 type adminUpdateUserRequest struct {
 	Role     string `json:"role"`
 	TenantID string `json:"tenant_id"`
+	OrgID    string `json:"org_id"`
 	Status   string `json:"status"`
 	Password string `json:"password"`
 }
 
 type planUpsertRequest struct {
-	ID              string   `json:"id"`
-	Name            string   `json:"name"`
-	Description     string   `json:"description"`
-	MaxRoutes       int      `json:"max_routes"`
-	MaxConnectors   int      `json:"max_connectors"`
-	MaxRPS          float64  `json:"max_rps"`
-	MaxMonthlyGB    float64  `json:"max_monthly_gb"`
-	TLSEnabled      bool     `json:"tls_enabled"`
-	PriceMonthlyUSD *float64 `json:"price_monthly_usd,omitempty"`
-	PriceAnnualUSD  *float64 `json:"price_annual_usd,omitempty"`
-	PublicOrder     *int     `json:"public_order,omitempty"`
+	ID                    string   `json:"id"`
+	Name                  string   `json:"name"`
+	Description           string   `json:"description"`
+	MaxRoutes             int      `json:"max_routes"`
+	MaxConnectors         int      `json:"max_connectors"`
+	MaxRPS                float64  `json:"max_rps"`
+	MaxMonthlyGB          float64  `json:"max_monthly_gb"`
+	TLSEnabled            bool     `json:"tls_enabled"`
+	PriceMonthlyUSD       *float64 `json:"price_monthly_usd,omitempty"`
+	PriceAnnualUSD        *float64 `json:"price_annual_usd,omitempty"`
+	PublicOrder           *int     `json:"public_order,omitempty"`
+	SelfServe             *bool    `json:"self_serve,omitempty"`
+	MaxRequestTimeoutSecs *int     `json:"max_request_timeout_seconds,omitempty"`
 }
 
 type assignTenantPlanRequest struct {
@@ -57,9 +63,13 @@ func (s *Server) handleAdminUsers(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
-		writeJSON(w, http.StatusOK, map[string]any{
-			"users": s.authStore.ListUsers(),
-		})
+		page, ok := paginateList(w, r, s.authStore.ListUsers(), "username")
+		if !ok {
+			return
+		}
+		writeListPage(w, map[string]any{
+			"users": page.Items,
+		}, page.Total, page.NextCursor)
 	case http.MethodPost:
 		var request adminCreateUserRequest
 		if !s.decodeJSON(w, r, &request, "admin user payload") {
@@ -70,13 +80,19 @@ func (s *Server) handleAdminUsers(w http.ResponseWriter, r *http.Request) {
 			role = RoleMember
 		}
 		tenantID := strings.TrimSpace(request.TenantID)
+		if role == RoleOrgAdmin {
+			var ok bool
+			if tenantID, ok = s.orgAdminHomeTenant(w, request.OrgID, tenantID); !ok {
+				return
+			}
+		}
 		if role != RoleSuperAdmin {
 			if tenantID == "" {
-				http.Error(w, "tenant_id is required for non-super-admin users", http.StatusBadRequest)
+				writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "tenant_id is required for non-super-admin users")
 				return
 			}
 			if !s.ruleStore.HasTenant(tenantID) {
-				http.Error(w, "tenant not found", http.StatusNotFound)
+				writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
 				return
 			}
 		}
@@ -85,10 +101,11 @@ func (s *Server) handleAdminUsers(w http.ResponseWriter, r *http.Request) {
 			Password: request.Password,
 			Role:     role,
 			TenantID: tenantID,
+			OrgID:    request.OrgID,
 			Status:   request.Status,
 		})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 			return
 		}
 		writeJSON(w, http.StatusCreated, map[string]any{
@@ -97,7 +114,7 @@ func (s *Server) handleAdminUsers(w http.ResponseWriter, r *http.Request) {
 		})
 		s.persistState()
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 	}
 }
 
@@ -109,14 +126,18 @@ func (s *Server) handleAdminUserByID(w http.ResponseWriter, r *http.Request) {
 	if !s.requireSuperAdmin(w, user) {
 		return
 	}
+	if target, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/users/"), "/impersonate"); ok {
+		s.handleAdminImpersonate(w, r, user, strings.TrimSpace(target))
+		return
+	}
 	if r.Method != http.MethodPatch {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	username := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/api/admin/users/"))
 	if username == "" {
-		http.Error(w, "missing user id", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing user id")
 		return
 	}
 
@@ -126,18 +147,27 @@ func (s *Server) handleAdminUserByID(w http.ResponseWriter, r *http.Request) {
 	}
 	if request.Role != "" && strings.TrimSpace(request.Role) != RoleSuperAdmin {
 		tenantID := strings.TrimSpace(request.TenantID)
-		if tenantID == "" {
-			existing, exists := s.authStore.GetUser(username)
-			if exists {
-				tenantID = existing.TenantID
+		existing, exists := s.authStore.GetUser(username)
+		if tenantID == "" && exists {
+			tenantID = existing.TenantID
+		}
+		if strings.TrimSpace(request.Role) == RoleOrgAdmin {
+			orgID := request.OrgID
+			if strings.TrimSpace(orgID) == "" {
+				orgID = existing.OrgID
+			}
+			var ok bool
+			if tenantID, ok = s.orgAdminHomeTenant(w, orgID, tenantID); !ok {
+				return
 			}
+			request.TenantID = tenantID
 		}
 		if tenantID == "" {
-			http.Error(w, "tenant_id is required for non-super-admin users", http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "tenant_id is required for non-super-admin users")
 			return
 		}
 		if !s.ruleStore.HasTenant(tenantID) {
-			http.Error(w, "tenant not found", http.StatusNotFound)
+			writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
 			return
 		}
 	}
@@ -146,11 +176,12 @@ func (s *Server) handleAdminUserByID(w http.ResponseWriter, r *http.Request) {
 		Username: username,
 		Role:     request.Role,
 		TenantID: request.TenantID,
+		OrgID:    request.OrgID,
 		Status:   request.Status,
 		Password: request.Password,
 	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{
@@ -160,9 +191,25 @@ func (s *Server) handleAdminUserByID(w http.ResponseWriter, r *http.Request) {
 	s.persistState()
 }
 
+func (s *Server) orgAdminHomeTenant(w http.ResponseWriter, orgID, tenantID string) (string, bool) {
+	org, ok := s.orgStore.Get(orgID)
+	if !ok {
+		writeAPIErrorDetails(w, http.StatusNotFound, errCodeNotFound, "organization not found", map[string]any{"org_id": orgID})
+		return "", false
+	}
+	if len(org.TenantIDs) == 0 {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "organization has no tenants")
+		return "", false
+	}
+	if tenantID != "" && s.orgStore.TenantInOrg(org.ID, tenantID) {
+		return normalizeIdentifier(tenantID), true
+	}
+	return org.TenantIDs[0], true
+}
+
 func (s *Server) handleAdminStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	user, ok := s.requireAuth(w, r)
@@ -200,6 +247,11 @@ func (s *Server) handleAdminStats(w http.ResponseWriter, r *http.Request) {
 		funnelAnalytics = s.funnelAnalytics.Summary()
 	}
 
+	// Stats only carry the heaviest routes and connectors across tenants;
+	// the daily breakdown is in /api/me/usage.
+	transfer := s.transfer.Summary("", defaultTransferDays, time.Now().UTC())
+	transfer.Daily = nil
+
 	writeJSON(w, http.StatusOK, map[string]any{
 		"generated_at":      time.Now().UTC().Format(time.RFC3339),
 		"user_count":        len(users),
@@ -209,17 +261,20 @@ func (s *Server) handleAdminStats(w http.ResponseWriter, r *http.Request) {
 		"active_connectors": activeConnectors,
 		"roles":             roles,
 		"monthly_usage":     monthlyUsage,
+		"transfer":          transfer,
 		"plan_assignments":  s.planStore.ListAssignments(),
 		"active_tls_certs":  s.tlsStore.ActiveCertificateCount(),
 		"funnel_analytics":  funnelAnalytics,
-		"storage_driver":    s.cfg.StorageDriver,
+		"system":            s.hub.Status(),
+		"tenant_latency":    s.hub.TenantLatencies(),
+		"storage_driver":    s.config().StorageDriver,
 		"uptime_seconds":    int(time.Since(s.startedAt).Seconds()),
 	})
 }
 
 func (s *Server) handleAdminIncidents(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	user, ok := s.requireAuth(w, r)
@@ -241,9 +296,33 @@ func (s *Server) handleAdminIncidents(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Server) handleAdminAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	if !s.requireSuperAdmin(w, user) {
+		return
+	}
+
+	limit := 100
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 && parsed <= 1000 {
+			limit = parsed
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"events": s.auditStore.List(r.URL.Query().Get("tenant_id"), limit),
+	})
+}
+
 func (s *Server) handleAdminSystemStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	user, ok := s.requireAuth(w, r)
@@ -254,24 +333,33 @@ func (s *Server) handleAdminSystemStatus(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	cfg := s.config()
 	hubStatus := s.hub.Status()
 	storage := s.storageHealth()
-	if _, ok := storage["sqlite_path"]; !ok && strings.TrimSpace(s.cfg.SQLitePath) != "" {
-		storage["sqlite_path"] = s.cfg.SQLitePath
+	if _, ok := storage["sqlite_path"]; !ok && strings.TrimSpace(cfg.SQLitePath) != "" {
+		storage["sqlite_path"] = cfg.SQLitePath
 	}
 	writeJSON(w, http.StatusOK, map[string]any{
 		"gateway": map[string]any{
 			"status":          "ok",
-			"listen_addr":     s.cfg.ListenAddr,
-			"public_base_url": s.cfg.PublicBaseURL,
+			"listen_addr":     cfg.ListenAddr,
+			"public_base_url": cfg.PublicBaseURL,
 			"uptime_seconds":  int(time.Since(s.startedAt).Seconds()),
 		},
-		"storage": storage,
-		"runtime": hubStatus,
+		"storage":       storage,
+		"runtime":       hubStatus,
+		"rate_limiter":  s.rateLimiterHealth(),
+		"sessions":      s.authStore.SessionHealth(),
+		"client_writes": s.clientWrites.status(),
 		"tls": map[string]any{
-			"tls_listen_addr":     s.cfg.TLSListenAddr,
+			"tls_listen_addr":     cfg.TLSListenAddr,
 			"active_certificates": s.tlsStore.ActiveCertificateCount(),
 		},
+		"agents": map[string]any{
+			"min_agent_version":    cfg.MinAgentVersion,
+			"min_protocol_version": cfg.MinAgentProtocol,
+			"outdated_connectors":  s.outdatedConnectors(),
+		},
 		"generated_at": time.Now().UTC().Format(time.RFC3339),
 	})
 }
@@ -295,9 +383,13 @@ func (s *Server) handleAdminPlans(w http.ResponseWriter, r *http.Request) {
 		if !s.decodeJSON(w, r, &request, "plan payload") {
 			return
 		}
+		if parseBoolQuery(r, "dry_run") {
+			s.writePlanDryRun(w, s.buildPlanInput(request.ID, request, user.Username))
+			return
+		}
 		plan, err := s.planStore.UpsertPlan(s.buildPlanInput(request.ID, request, user.Username))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 			return
 		}
 		writeJSON(w, http.StatusCreated, map[string]any{
@@ -306,13 +398,20 @@ func (s *Server) handleAdminPlans(w http.ResponseWriter, r *http.Request) {
 		})
 		s.persistState()
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 	}
 }
 
 func (s *Server) handleAdminPlanByID(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPatch {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	planID, action, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/api/admin/plans/")), "/")
+	allowed := map[string]string{"": http.MethodPatch, "versions": http.MethodGet, "migrate": http.MethodPost}
+	method, known := allowed[action]
+	if !known {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "not found")
+		return
+	}
+	if r.Method != method {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	user, ok := s.requireAuth(w, r)
@@ -323,9 +422,16 @@ func (s *Server) handleAdminPlanByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	planID := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/api/admin/plans/"))
 	if planID == "" {
-		http.Error(w, "missing plan id", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing plan id")
+		return
+	}
+	switch action {
+	case "versions":
+		s.handleAdminPlanVersions(w, planID)
+		return
+	case "migrate":
+		s.handleAdminPlanMigrate(w, r, user, planID)
 		return
 	}
 
@@ -334,9 +440,13 @@ func (s *Server) handleAdminPlanByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	request.ID = planID
+	if parseBoolQuery(r, "dry_run") {
+		s.writePlanDryRun(w, s.buildPlanInput(request.ID, request, user.Username))
+		return
+	}
 	plan, err := s.planStore.UpsertPlan(s.buildPlanInput(request.ID, request, user.Username))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{
@@ -346,16 +456,33 @@ func (s *Server) handleAdminPlanByID(w http.ResponseWriter, r *http.Request) {
 	s.persistState()
 }
 
+func (s *Server) writePlanDryRun(w http.ResponseWriter, input Plan) {
+	plan, err := s.planStore.ValidatePlan(input)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": "plan valid",
+		"dry_run": true,
+		"plan":    plan,
+	})
+}
+
 func (s *Server) buildPlanInput(planID string, request planUpsertRequest, createdBy string) Plan {
 	planID = normalizeIdentifier(planID)
 	existing, exists := s.planStore.GetPlan(planID)
 	priceMonthly := 0.0
 	priceAnnual := 0.0
 	publicOrder := 0
+	selfServe := false
+	maxRequestTimeout := 0
 	if exists {
 		priceMonthly = existing.PriceMonthlyUSD
 		priceAnnual = existing.PriceAnnualUSD
 		publicOrder = existing.PublicOrder
+		selfServe = existing.SelfServe
+		maxRequestTimeout = existing.MaxRequestTimeoutSecs
 	}
 	if request.PriceMonthlyUSD != nil {
 		priceMonthly = *request.PriceMonthlyUSD
@@ -366,19 +493,27 @@ func (s *Server) buildPlanInput(planID string, request planUpsertRequest, create
 	if request.PublicOrder != nil {
 		publicOrder = *request.PublicOrder
 	}
+	if request.SelfServe != nil {
+		selfServe = *request.SelfServe
+	}
+	if request.MaxRequestTimeoutSecs != nil {
+		maxRequestTimeout = *request.MaxRequestTimeoutSecs
+	}
 	return Plan{
-		ID:              planID,
-		Name:            request.Name,
-		Description:     request.Description,
-		MaxRoutes:       request.MaxRoutes,
-		MaxConnectors:   request.MaxConnectors,
-		MaxRPS:          request.MaxRPS,
-		MaxMonthlyGB:    request.MaxMonthlyGB,
-		TLSEnabled:      request.TLSEnabled,
-		PriceMonthlyUSD: priceMonthly,
-		PriceAnnualUSD:  priceAnnual,
-		PublicOrder:     publicOrder,
-		CreatedBy:       createdBy,
+		ID:                    planID,
+		Name:                  request.Name,
+		Description:           request.Description,
+		MaxRoutes:             request.MaxRoutes,
+		MaxConnectors:         request.MaxConnectors,
+		MaxRPS:                request.MaxRPS,
+		MaxMonthlyGB:          request.MaxMonthlyGB,
+		TLSEnabled:            request.TLSEnabled,
+		PriceMonthlyUSD:       priceMonthly,
+		PriceAnnualUSD:        priceAnnual,
+		PublicOrder:           publicOrder,
+		SelfServe:             selfServe,
+		MaxRequestTimeoutSecs: maxRequestTimeout,
+		CreatedBy:             createdBy,
 	}
 }
 
@@ -390,24 +525,33 @@ func (s *Server) handleAdminTenantsSubresource(w http.ResponseWriter, r *http.Re
 	if !s.requireSuperAdmin(w, user) {
 		return
 	}
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
 
 	suffix := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/api/admin/tenants/"))
 	parts := strings.Split(suffix, "/")
-	if len(parts) != 2 || strings.TrimSpace(parts[1]) != "assign-plan" {
-		http.Error(w, "invalid admin tenant path", http.StatusBadRequest)
+	if len(parts) != 2 {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid admin tenant path")
 		return
 	}
 	tenantID := strings.TrimSpace(parts[0])
 	if tenantID == "" {
-		http.Error(w, "missing tenant id", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing tenant id")
+		return
+	}
+	switch action := strings.TrimSpace(parts[1]); action {
+	case "assign-plan":
+	case "suspend", "archive", "reactivate":
+		s.handleAdminTenantStatus(w, r, user, tenantID, action)
+		return
+	default:
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid admin tenant path")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	if !s.ruleStore.HasTenant(tenantID) {
-		http.Error(w, "tenant not found", http.StatusNotFound)
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
 		return
 	}
 
@@ -417,9 +561,12 @@ func (s *Server) handleAdminTenantsSubresource(w http.ResponseWriter, r *http.Re
 	}
 	assignment, err := s.planStore.AssignTenantPlan(tenantID, request.PlanID, user.Username)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
+	s.auditStore.Record(user.Username, "plan.assign", tenantID, map[string]string{
+		"plan_id": assignment.PlanID,
+	})
 	s.refreshTenantUsage(tenantID)
 	writeJSON(w, http.StatusOK, map[string]any{
 		"message":    "plan assigned",
@@ -447,9 +594,13 @@ func (s *Server) handleAdminTLSCertificates(w http.ResponseWriter, r *http.Reque
 		if !s.decodeJSON(w, r, &request, "tls certificate payload") {
 			return
 		}
+		if err := s.enforceCertificatePlans(request.Hostname); err != nil {
+			writePlanFeatureError(w, err)
+			return
+		}
 		cert, err := s.tlsStore.Upsert(request)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 			return
 		}
 		writeJSON(w, http.StatusCreated, map[string]any{
@@ -458,7 +609,7 @@ func (s *Server) handleAdminTLSCertificates(w http.ResponseWriter, r *http.Reque
 		})
 		s.persistState()
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 	}
 }
 
@@ -473,7 +624,7 @@ func (s *Server) handleAdminTLSCertificateByID(w http.ResponseWriter, r *http.Re
 
 	id := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/api/admin/tls/certificates/"))
 	if id == "" {
-		http.Error(w, "missing certificate id", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing certificate id")
 		return
 	}
 
@@ -484,12 +635,18 @@ func (s *Server) handleAdminTLSCertificateByID(w http.ResponseWriter, r *http.Re
 			return
 		}
 		if request.Active == nil {
-			http.Error(w, "active is required", http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "active is required")
 			return
 		}
+		if existing, ok := s.tlsStore.Get(id); ok && *request.Active {
+			if err := s.enforceCertificatePlans(existing.Hostname); err != nil {
+				writePlanFeatureError(w, err)
+				return
+			}
+		}
 		cert, err := s.tlsStore.SetActive(id, *request.Active)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]any{
@@ -499,25 +656,116 @@ func (s *Server) handleAdminTLSCertificateByID(w http.ResponseWriter, r *http.Re
 		s.persistState()
 	case http.MethodDelete:
 		if ok := s.tlsStore.Delete(id); !ok {
-			http.Error(w, "certificate not found", http.StatusNotFound)
+			writeAPIError(w, http.StatusNotFound, errCodeNotFound, "certificate not found")
 			return
 		}
 		s.persistState()
 		w.WriteHeader(http.StatusNoContent)
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 	}
 }
 
-func (s *Server) maybeRecordProxyIncident(err error, tunnelKey string) {
+func (s *Server) maybeRecordProxyIncident(err error, tunnelKey, requestID string) {
 	if err == nil {
 		return
 	}
 	source := "proxy"
 	severity := "warning"
 	message := fmt.Sprintf("%s: %v", tunnelKey, err)
-	if strings.Contains(strings.ToLower(err.Error()), "timeout") {
+	if errors.Is(err, ErrProxyRequestTimeout) || errors.Is(err, context.DeadlineExceeded) || strings.Contains(strings.ToLower(err.Error()), "timeout") {
 		severity = "critical"
 	}
-	s.incidentStore.Add(severity, source, message)
+	s.incidentStore.AddForRequest(severity, source, message, requestID)
+}
+
+type adminBanIPRequest struct {
+	IP       string `json:"ip"`
+	Reason   string `json:"reason"`
+	Duration string `json:"duration"`
+}
+
+func (s *Server) handleAdminIPBans(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	if !s.requireSuperAdmin(w, user) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"bans": s.ipBans.List(time.Now().UTC()),
+		})
+	case http.MethodPost:
+		var request adminBanIPRequest
+		if !s.decodeJSON(w, r, &request, "ip ban payload") {
+			return
+		}
+		duration := s.config().ProxyIPBanDuration
+		if raw := strings.TrimSpace(request.Duration); raw != "" {
+			parsed, err := time.ParseDuration(raw)
+			if err != nil {
+				writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("invalid duration: %v", err))
+				return
+			}
+			duration = parsed
+		}
+		ban, err := s.ipBans.Ban(request.IP, request.Reason, user.Username, duration)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		s.auditStore.Record(user.Username, "ip.ban", "", map[string]string{
+			"ip":         ban.IP,
+			"reason":     ban.Reason,
+			"expires_at": ban.ExpiresAt.Format(time.RFC3339),
+		})
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"message": "ip banned",
+			"ban":     ban,
+		})
+		s.persistState()
+	case http.MethodDelete:
+		cleared := s.ipBans.Clear()
+		s.auditStore.Record(user.Username, "ip.unban_all", "", map[string]string{
+			"cleared": strconv.Itoa(cleared),
+		})
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message": "ip bans cleared",
+			"cleared": cleared,
+		})
+		s.persistState()
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleAdminIPBanByIP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	if !s.requireSuperAdmin(w, user) {
+		return
+	}
+
+	ip := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/api/admin/ip-bans/"))
+	if ip == "" {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing ip")
+		return
+	}
+	if !s.ipBans.Unban(ip) {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "ban not found")
+		return
+	}
+	s.auditStore.Record(user.Username, "ip.unban", "", map[string]string{"ip": ip})
+	s.persistState()
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestAgentTransportNegotiatesFramesAndKeepsJSON(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", AgentToken: "token"}, nil)
+	defer server.hub.Close()
+
+	register := func(encodings []string) protocol.RegisterResponse {
+		t.Helper()
+		body, _ := json.Marshal(protocol.RegisterRequest{
+			AgentID:   "agent-1",
+			Token:     "token",
+			Tunnels:   []protocol.TunnelConfig{{ID: "app", Target: "http://127.0.0.1:3000"}},
+			Encodings: encodings,
+		})
+		recorder := httptest.NewRecorder()
+		server.handleAgentRegister(recorder, httptest.NewRequest(http.MethodPost, "/api/agent/register", bytes.NewReader(body)))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("register: %d %s", recorder.Code, recorder.Body.String())
+		}
+		var response protocol.RegisterResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("decode register response: %v", err)
+		}
+		return response
+	}
+	if legacy := register(nil); legacy.Encoding != "" {
+		t.Fatalf("expected agents without encodings to keep JSON, got %q", legacy.Encoding)
+	}
+	registration := register([]string{protocol.EncodingFrame})
+	if registration.Encoding != protocol.EncodingFrame {
+		t.Fatalf("expected the frame encoding, got %q", registration.Encoding)
+	}
+
+	body := []byte{0x00, 0xff, 0x10, '"', '\n'}
+	done := make(chan *protocol.ProxyResponse, 1)
+	go func() {
+		response, err := server.hub.DispatchProxyRequest(context.Background(), "app", &protocol.ProxyRequest{Method: http.MethodPost, Path: "/upload", Body: body})
+		if err != nil {
+			t.Errorf("dispatch: %v", err)
+		}
+		done <- response
+	}()
+
+	pull := httptest.NewRequest(http.MethodGet, "/api/agent/pull?wait=2&session_id="+registration.SessionID, nil)
+	pull.Header.Set("Accept", protocol.FrameContentType)
+	recorder := httptest.NewRecorder()
+	server.handleAgentPull(recorder, pull)
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != protocol.FrameContentType {
+		t.Fatalf("expected a framed pull, got %d %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if bytes.Contains(recorder.Body.Bytes(), []byte("AP8Q")) {
+		t.Fatalf("expected the body to travel raw, not base64")
+	}
+	pulled, err := protocol.ReadPullFrame(recorder.Body)
+	if err != nil {
+		t.Fatalf("read pull frame: %v", err)
+	}
+	if pulled.Request == nil || pulled.Request.Path != "/upload" || !bytes.Equal(pulled.Request.Body, body) {
+		t.Fatalf("unexpected pulled request %+v", pulled.Request)
+	}
+
+	var frame bytes.Buffer
+	if err := protocol.WriteSubmitResponseFrame(&frame, protocol.SubmitResponseRequest{
+		SessionID: registration.SessionID,
+		Response:  &protocol.ProxyResponse{RequestID: pulled.Request.RequestID, TunnelID: pulled.Request.TunnelID, Status: http.StatusCreated, Body: body},
+	}); err != nil {
+		t.Fatalf("write response frame: %v", err)
+	}
+	respond := httptest.NewRequest(http.MethodPost, "/api/agent/respond", &frame)
+	respond.Header.Set("Content-Type", protocol.FrameContentType)
+	recorder = httptest.NewRecorder()
+	server.handleAgentRespond(recorder, respond)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("respond: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if response := <-done; response == nil || response.Status != http.StatusCreated || !bytes.Equal(response.Body, body) {
+		t.Fatalf("unexpected proxied response %+v", response)
+	}
+
+	respond = httptest.NewRequest(http.MethodPost, "/api/agent/respond", strings.NewReader("\x00\x00"))
+	respond.Header.Set("Content-Type", protocol.FrameContentType)
+	recorder = httptest.NewRecorder()
+	server.handleAgentRespond(recorder, respond)
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected a truncated frame to be rejected, got %d", recorder.Code)
+	}
+}
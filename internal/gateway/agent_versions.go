@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+type outdatedConnector struct {
+	ConnectorID     string `json:"connector_id"`
+	TenantID        string `json:"tenant_id"`
+	AgentID         string `json:"agent_id,omitempty"`
+	AgentVersion    string `json:"agent_version,omitempty"`
+	ProtocolVersion int    `json:"protocol_version,omitempty"`
+	Reason          string `json:"reason"`
+}
+
+// agentOutdatedReason explains why an agent reporting agentVersion and
+// protocolVersion is below cfg's minimums, or returns "" if it is not.
+// Agents that send no protocol version speak version 1, and agents that
+// send no release predate every minimum release; development builds, whose
+// release does not parse, are only held to the protocol minimum.
+func agentOutdatedReason(cfg Config, agentVersion string, protocolVersion int) string {
+	if protocolVersion <= 0 {
+		protocolVersion = 1
+	}
+	if cfg.MinAgentProtocol > 0 && protocolVersion < cfg.MinAgentProtocol {
+		return fmt.Sprintf("agent protocol %d is older than the required %d", protocolVersion, cfg.MinAgentProtocol)
+	}
+	minimum := strings.TrimSpace(cfg.MinAgentVersion)
+	if minimum == "" {
+		return ""
+	}
+	agentVersion = strings.TrimSpace(agentVersion)
+	if agentVersion == "" {
+		return fmt.Sprintf("agent does not report its version; %s or newer is required", minimum)
+	}
+	if cmp, ok := protocol.CompareVersions(agentVersion, minimum); ok && cmp < 0 {
+		return fmt.Sprintf("agent %s is older than the required %s", agentVersion, minimum)
+	}
+	return ""
+}
+
+func (s *Server) rejectOutdatedAgent(w http.ResponseWriter, payload *protocol.RegisterRequest) bool {
+	cfg := s.config()
+	reason := agentOutdatedReason(cfg, payload.AgentVersion, payload.ProtocolVersion)
+	if reason == "" {
+		return false
+	}
+	writeAPIErrorDetails(w, http.StatusUpgradeRequired, errCodeAgentOutdated, reason, map[string]any{
+		"agent_version":        strings.TrimSpace(payload.AgentVersion),
+		"protocol_version":     payload.ProtocolVersion,
+		"min_agent_version":    cfg.MinAgentVersion,
+		"min_protocol_version": cfg.MinAgentProtocol,
+		"download_url":         strings.TrimRight(cfg.PublicBaseURL, "/") + "/api/public/downloads",
+	})
+	return true
+}
+
+func (s *Server) outdatedConnectors() []outdatedConnector {
+	cfg := s.config()
+	outdated := []outdatedConnector{}
+	for _, connector := range s.connectorStore.ListAll() {
+		connection, connected := s.hub.GetConnectorConnection(connector.ID)
+		if !connected {
+			continue
+		}
+		if reason := agentOutdatedReason(cfg, connection.AgentVersion, connection.ProtocolVersion); reason != "" {
+			outdated = append(outdated, outdatedConnector{
+				ConnectorID:     connector.ID,
+				TenantID:        connector.TenantID,
+				AgentID:         connection.AgentID,
+				AgentVersion:    connection.AgentVersion,
+				ProtocolVersion: connection.ProtocolVersion,
+				Reason:          reason,
+			})
+		}
+	}
+	return outdated
+}
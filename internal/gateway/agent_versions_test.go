@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMinimumAgentVersionEnforcedAtRegistration(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", MinAgentVersion: "1.4.0", PublicBaseURL: "https://proxer.test"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	if _, err := server.connectorStore.Create(Connector{ID: "edge", TenantID: DefaultTenantID, Name: "edge"}); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+	secret, err := server.connectorStore.RotateCredential("edge")
+	if err != nil {
+		t.Fatalf("rotate credential: %v", err)
+	}
+	register := func(extra string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		body := `{"agent_id":"a1","connector_id":"edge","connector_secret":"` + secret + `","protocol_version":2` + extra + `}`
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/agent/register", strings.NewReader(body)))
+		return recorder
+	}
+
+	for _, extra := range []string{``, `,"agent_version":"1.3.9"`, `,"agent_version":"1.4.0-beta.1"`} {
+		recorder := register(extra)
+		var rejection apiError
+		if err := json.Unmarshal(recorder.Body.Bytes(), &rejection); err != nil || recorder.Code != http.StatusUpgradeRequired || rejection.Code != errCodeAgentOutdated {
+			t.Fatalf("register%s: expected 426 agent_outdated, got %d %s", extra, recorder.Code, recorder.Body.String())
+		}
+		if rejection.Details["min_agent_version"] != "1.4.0" || rejection.Details["download_url"] != "https://proxer.test/api/public/downloads" {
+			t.Fatalf("unexpected rejection details %+v", rejection.Details)
+		}
+	}
+	for _, version := range []string{"1.4.0", "dev"} {
+		if recorder := register(`,"agent_version":"` + version + `"`); recorder.Code != http.StatusOK {
+			t.Fatalf("register %s: %d %s", version, recorder.Code, recorder.Body.String())
+		}
+	}
+	if view := server.buildConnectorView(Connector{ID: "edge"}); view.Outdated || view.AgentVersion != "dev" || view.ProtocolVersion != 2 {
+		t.Fatalf("unexpected connector view %+v", view)
+	}
+
+	if recorder := register(`,"agent_version":"1.4.0"`); recorder.Code != http.StatusOK {
+		t.Fatalf("register: %d %s", recorder.Code, recorder.Body.String())
+	}
+	server.cfgMu.Lock()
+	server.cfg.MinAgentVersion = "1.5.0"
+	server.cfgMu.Unlock()
+	view := server.buildConnectorView(Connector{ID: "edge"})
+	if !view.Outdated || !strings.Contains(view.OutdatedReason, "1.5.0") {
+		t.Fatalf("expected the connected agent to be flagged after the minimum was raised, got %+v", view)
+	}
+	outdated := server.outdatedConnectors()
+	if len(outdated) != 1 || outdated[0].ConnectorID != "edge" || outdated[0].AgentVersion != "1.4.0" {
+		t.Fatalf("unexpected outdated connectors %+v", outdated)
+	}
+
+	server.cfgMu.Lock()
+	server.cfg.MinAgentVersion = ""
+	server.cfg.MinAgentProtocol = 2
+	server.cfgMu.Unlock()
+	legacy := httptest.NewRecorder()
+	mux.ServeHTTP(legacy, httptest.NewRequest(http.MethodPost, "/api/agent/register", strings.NewReader(`{"agent_id":"a1","connector_id":"edge","connector_secret":"`+secret+`"}`)))
+	if legacy.Code != http.StatusUpgradeRequired || !strings.Contains(legacy.Body.String(), "protocol 1") {
+		t.Fatalf("expected a version 1 agent to be refused, got %d %s", legacy.Code, legacy.Body.String())
+	}
+}
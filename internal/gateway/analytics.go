@@ -155,7 +155,7 @@ var allowedFunnelEvents = map[string]struct{}{
 
 func (s *Server) handlePublicAnalyticsEvent(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	var request funnelEventInput
@@ -169,7 +169,7 @@ func (s *Server) handlePublicAnalyticsEvent(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	if _, ok := s.funnelAnalytics.Record(request, signupClientIP(r)); !ok {
-		http.Error(w, "invalid event", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid event")
 		return
 	}
 	writeJSON(w, http.StatusAccepted, map[string]any{
@@ -179,7 +179,7 @@ func (s *Server) handlePublicAnalyticsEvent(w http.ResponseWriter, r *http.Reque
 
 func (s *Server) handleAdminFunnelAnalytics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	user, ok := s.requireAuth(w, r)
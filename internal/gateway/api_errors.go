@@ -0,0 +1,137 @@
+package gateway
+
+import "net/http"
+
+// apiErrorCode is the machine-readable code of an API error response. SDKs
+// and the console branch on it; messages are for people and may change.
+type apiErrorCode string
+
+const (
+	errCodeInvalidRequest         apiErrorCode = "invalid_request"
+	errCodeUnauthorized           apiErrorCode = "unauthorized"
+	errCodeInvalidCredentials     apiErrorCode = "invalid_credentials"
+	errCodeForbidden              apiErrorCode = "forbidden"
+	errCodeSuperAdminRequired     apiErrorCode = "super_admin_required"
+	errCodeTenantAdminRequired    apiErrorCode = "tenant_admin_required"
+	errCodeTenantAccessDenied     apiErrorCode = "tenant_access_denied"
+	errCodeTenantSuspended        apiErrorCode = "tenant_suspended"
+	errCodeConnectorAccessDenied  apiErrorCode = "connector_access_denied"
+	errCodePlanLimitExceeded      apiErrorCode = "plan_limit_exceeded"
+	errCodePlanNotAvailable       apiErrorCode = "plan_not_available"
+	errCodePlanFeatureUnavailable apiErrorCode = "plan_feature_unavailable"
+	errCodeSignupDisabled         apiErrorCode = "signup_disabled"
+	errCodeCSRFTokenInvalid       apiErrorCode = "csrf_token_invalid"
+	errCodeNotFound               apiErrorCode = "not_found"
+	errCodeTenantNotFound         apiErrorCode = "tenant_not_found"
+	errCodeRouteNotFound          apiErrorCode = "route_not_found"
+	errCodeDomainNotFound         apiErrorCode = "domain_not_found"
+	errCodeConnectorNotFound      apiErrorCode = "connector_not_found"
+	errCodePlanNotFound           apiErrorCode = "plan_not_found"
+	errCodeUnknownSession         apiErrorCode = "unknown_session"
+	errCodeMethodNotAllowed       apiErrorCode = "method_not_allowed"
+	errCodeConflict               apiErrorCode = "conflict"
+	errCodeUsernameTaken          apiErrorCode = "username_taken"
+	errCodeDomainTaken            apiErrorCode = "domain_taken"
+	errCodeSessionNotResumable    apiErrorCode = "session_not_resumable"
+	errCodeRotationNotDue         apiErrorCode = "secret_rotation_not_due"
+	errCodeAgentOutdated          apiErrorCode = "agent_outdated"
+	errCodePayloadTooLarge        apiErrorCode = "payload_too_large"
+	errCodeResponseTooLarge       apiErrorCode = "response_too_large"
+	errCodeRateLimited            apiErrorCode = "rate_limited"
+	errCodeInternal               apiErrorCode = "internal_error"
+	errCodeNotImplemented         apiErrorCode = "not_implemented"
+	errCodeUnavailable            apiErrorCode = "unavailable"
+	errCodeShuttingDown           apiErrorCode = "gateway_shutting_down"
+)
+
+// apiErrorCodes maps every code to the status it is sent with and what it
+// means; the OpenAPI document is generated from it.
+var apiErrorCodes = map[apiErrorCode]struct {
+	Status      int
+	Description string
+}{
+	errCodeInvalidRequest:         {http.StatusBadRequest, "The request body, path or query is invalid"},
+	errCodeUnauthorized:           {http.StatusUnauthorized, "Missing or expired session"},
+	errCodeInvalidCredentials:     {http.StatusUnauthorized, "Wrong username, password or connector secret"},
+	errCodeForbidden:              {http.StatusForbidden, "The caller may not perform this operation"},
+	errCodeSuperAdminRequired:     {http.StatusForbidden, "Super admin required"},
+	errCodeTenantAdminRequired:    {http.StatusForbidden, "Tenant admin required"},
+	errCodeTenantAccessDenied:     {http.StatusForbidden, "The caller may not access or change this tenant"},
+	errCodeTenantSuspended:        {http.StatusForbidden, "The tenant is suspended or archived"},
+	errCodeConnectorAccessDenied:  {http.StatusForbidden, "The caller may not access this connector"},
+	errCodePlanLimitExceeded:      {http.StatusForbidden, "The tenant's plan does not allow more of this resource"},
+	errCodePlanNotAvailable:       {http.StatusForbidden, "The plan cannot be chosen self-serve"},
+	errCodePlanFeatureUnavailable: {http.StatusForbidden, "The tenant's plan does not include this feature; details list the plans that do"},
+	errCodeSignupDisabled:         {http.StatusForbidden, "Public signup is disabled"},
+	errCodeCSRFTokenInvalid:       {http.StatusForbidden, "Cookie-authenticated writes need the proxer_csrf cookie value in X-Proxer-CSRF-Token"},
+	errCodeNotFound:               {http.StatusNotFound, "The resource does not exist"},
+	errCodeTenantNotFound:         {http.StatusNotFound, "The tenant does not exist"},
+	errCodeRouteNotFound:          {http.StatusNotFound, "The route does not exist"},
+	errCodeDomainNotFound:         {http.StatusNotFound, "The custom domain is not attached to this tenant"},
+	errCodeConnectorNotFound:      {http.StatusNotFound, "The connector does not exist"},
+	errCodePlanNotFound:           {http.StatusNotFound, "The plan does not exist"},
+	errCodeUnknownSession:         {http.StatusNotFound, "The agent session is unknown; register again"},
+	errCodeMethodNotAllowed:       {http.StatusMethodNotAllowed, "The path does not support this method"},
+	errCodeConflict:               {http.StatusConflict, "The request conflicts with the current state"},
+	errCodeUsernameTaken:          {http.StatusConflict, "The username already exists"},
+	errCodeDomainTaken:            {http.StatusConflict, "The custom domain is attached to another tenant"},
+	errCodeSessionNotResumable:    {http.StatusConflict, "The agent session cannot be resumed; register again"},
+	errCodeRotationNotDue:         {http.StatusConflict, "The connector secret is not in its rotation window yet"},
+	errCodeAgentOutdated:          {http.StatusUpgradeRequired, "The agent is older than the gateway's minimum version or protocol; details name the minimums and the download URL"},
+	errCodePayloadTooLarge:        {http.StatusRequestEntityTooLarge, "The request body exceeds the gateway limit"},
+	errCodeResponseTooLarge:       {http.StatusBadGateway, "The upstream response exceeds the route, gateway or agent size limit"},
+	errCodeRateLimited:            {http.StatusTooManyRequests, "Too many requests; retry later"},
+	errCodeInternal:               {http.StatusInternalServerError, "Unexpected gateway error"},
+	errCodeNotImplemented:         {http.StatusNotImplemented, "The gateway is not configured for this operation"},
+	errCodeUnavailable:            {http.StatusServiceUnavailable, "The gateway cannot serve the request right now"},
+	errCodeShuttingDown:           {http.StatusServiceUnavailable, "The gateway is shutting down; register again once it is back"},
+}
+
+type apiError struct {
+	Code      apiErrorCode   `json:"code"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code apiErrorCode, message string) {
+	writeAPIErrorDetails(w, status, code, message, nil)
+}
+
+func writeAPIErrorDetails(w http.ResponseWriter, status int, code apiErrorCode, message string, details map[string]any) {
+	writeJSON(w, status, apiError{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: w.Header().Get("X-Proxer-Request-ID"),
+	})
+}
+
+func errorCodeForStatus(status int) apiErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return errCodeInvalidRequest
+	case http.StatusUnauthorized:
+		return errCodeUnauthorized
+	case http.StatusForbidden:
+		return errCodeForbidden
+	case http.StatusNotFound:
+		return errCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return errCodeMethodNotAllowed
+	case http.StatusConflict:
+		return errCodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return errCodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return errCodeRateLimited
+	case http.StatusNotImplemented:
+		return errCodeNotImplemented
+	case http.StatusServiceUnavailable:
+		return errCodeUnavailable
+	}
+	if status >= http.StatusInternalServerError {
+		return errCodeInternal
+	}
+	return errCodeInvalidRequest
+}
@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestAPIErrorsUseJSONEnvelopeWithRequestID(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	sessionID, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	cases := []struct {
+		method, path, token string
+		status              int
+		code                apiErrorCode
+	}{
+		{http.MethodGet, "/api/auth/me", "", http.StatusUnauthorized, errCodeUnauthorized},
+		{http.MethodGet, "/api/tenants/missing/routes", sessionID, http.StatusNotFound, errCodeTenantNotFound},
+		{http.MethodPut, "/api/admin/users", sessionID, http.StatusMethodNotAllowed, errCodeMethodNotAllowed},
+		{http.MethodGet, "/api/tenants/default/routes?limit=0", sessionID, http.StatusBadRequest, errCodeInvalidRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		if recorder.Code != tc.status {
+			t.Fatalf("%s %s: expected %d, got %d: %s", tc.method, tc.path, tc.status, recorder.Code, recorder.Body.String())
+		}
+		var body apiError
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s %s: expected JSON error, got %q", tc.method, tc.path, recorder.Body.String())
+		}
+		if body.Code != tc.code || body.Message == "" {
+			t.Fatalf("%s %s: expected code %s with a message, got %+v", tc.method, tc.path, tc.code, body)
+		}
+		if body.RequestID == "" || body.RequestID != recorder.Header().Get("X-Proxer-Request-ID") {
+			t.Fatalf("%s %s: expected request_id to match the response header, got %+v", tc.method, tc.path, body)
+		}
+	}
+}
+
+func TestOpenAPIDocumentsErrorCodes(t *testing.T) {
+	for _, op := range managementAPI {
+		for _, code := range op.errorCodes() {
+			if _, ok := apiErrorCodes[code]; !ok {
+				t.Fatalf("%s %s: undocumented error code %q", op.Method, op.Path, code)
+			}
+		}
+	}
+
+	doc := buildOpenAPIDocument(managementAPI)
+	paths := doc["paths"].(map[string]map[string]any)
+	upsert := paths["/api/tenants/{tenantId}/routes"]["post"].(map[string]any)
+	forbidden, ok := upsert["responses"].(map[string]any)["403"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected a 403 response on route upsert, got %v", upsert["responses"])
+	}
+	codes := forbidden["x-error-codes"].([]apiErrorCode)
+	if !slices.Contains(codes, errCodePlanLimitExceeded) || !slices.Contains(codes, errCodeTenantAccessDenied) || !slices.Contains(codes, errCodeCSRFTokenInvalid) {
+		t.Fatalf("expected plan, tenant access and CSRF codes on 403, got %v", codes)
+	}
+}
@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// consoleContentSecurityPolicy allows the embedded console to load only its
+// own scripts, styles and images. React sets inline style attributes.
+const consoleContentSecurityPolicy = "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; connect-src 'self'; frame-ancestors 'none'; base-uri 'self'; form-action 'self'"
+
+const apiContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+
+var rateLimitedAuthPaths = map[string]bool{
+	"/api/auth/login":    true,
+	"/api/auth/register": true,
+	"/api/agent/pair":    true,
+}
+
+// withListenerMiddleware wraps a listener mux with the console and API layer:
+// security headers, a request ID on every API response and per-IP rate
+// limits on auth endpoints. Proxied /t/ traffic passes through untouched so
+// tenant apps keep control of their own headers.
+func (s *Server) withListenerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/t/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "same-origin")
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			header.Set("Content-Security-Policy", consoleContentSecurityPolicy)
+			next.ServeHTTP(w, r)
+			return
+		}
+		header.Set("Content-Security-Policy", apiContentSecurityPolicy)
+		header.Set("X-Proxer-Request-ID", s.nextRequestID())
+
+		if rateLimitedAuthPaths[r.URL.Path] && r.Method == http.MethodPost && !s.allowAuthAttempt(r) {
+			rpm := s.config().AuthRateLimitRPM
+			header.Set("Retry-After", strconv.Itoa(int(math.Ceil(60/float64(rpm)))))
+			writeAPIErrorDetails(w, http.StatusTooManyRequests, errCodeRateLimited, "too many authentication attempts",
+				map[string]any{"limit_per_minute": rpm})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) allowAuthAttempt(r *http.Request) bool {
+	clientIP := s.proxyClientIP(r)
+	if clientIP == "" {
+		clientIP = "unknown"
+	}
+	rpm := float64(s.config().AuthRateLimitRPM)
+	return s.rateLimiter.AllowBurst("auth:"+clientIP, rpm/60, rpm)
+}
+
+// sessionLookupsPerMinute bounds per client the session lookups that go to
+// the sqlite store: a client making up tokens would otherwise start a sqlite3
+// process with every request. A signed-in browser needs about 12 a minute.
+const sessionLookupsPerMinute = 120
+
+func (s *Server) allowSessionLookup(r *http.Request, sessionID string) bool {
+	if s.authStore.SessionCached(sessionID) {
+		return true
+	}
+	clientIP := s.proxyClientIP(r)
+	if clientIP == "" {
+		clientIP = "unknown"
+	}
+	return s.rateLimiter.AllowBurst("session:"+clientIP, sessionLookupsPerMinute/60, sessionLookupsPerMinute)
+}
+
+func writeSessionLookupLimited(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	writeAPIError(w, http.StatusTooManyRequests, errCodeRateLimited, "too many session lookups")
+}
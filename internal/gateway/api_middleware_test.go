@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListenerMiddlewareSetsSecurityHeaders(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	handler := server.withListenerMiddleware(public)
+
+	console := httptest.NewRecorder()
+	handler.ServeHTTP(console, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := console.Header().Get("Content-Security-Policy"); !strings.Contains(got, "script-src 'self'") {
+		t.Fatalf("expected console CSP, got %q", got)
+	}
+	if console.Header().Get("X-Proxer-Request-ID") != "" {
+		t.Fatalf("expected no request ID on console pages")
+	}
+
+	api := httptest.NewRecorder()
+	handler.ServeHTTP(api, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	for name, want := range map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"Referrer-Policy":         "same-origin",
+		"Content-Security-Policy": apiContentSecurityPolicy,
+	} {
+		if got := api.Header().Get(name); got != want {
+			t.Fatalf("expected %s %q on API responses, got %q", name, want, got)
+		}
+	}
+	if !strings.HasPrefix(api.Header().Get("X-Proxer-Request-ID"), "gw-") {
+		t.Fatalf("expected a request ID on API responses, got %q", api.Header().Get("X-Proxer-Request-ID"))
+	}
+
+	proxied := httptest.NewRecorder()
+	handler.ServeHTTP(proxied, httptest.NewRequest(http.MethodGet, "/t/default/missing/", nil))
+	if proxied.Header().Get("Content-Security-Policy") != "" || proxied.Header().Get("Referrer-Policy") != "" {
+		t.Fatalf("expected proxied responses to keep their own headers, got %v", proxied.Header())
+	}
+}
+
+func TestListenerMiddlewareRateLimitsLoginPerIP(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", AuthRateLimitRPM: 3}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	handler := server.withListenerMiddleware(public)
+
+	login := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"username":"admin","password":"wrong"}`))
+		req.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+	for attempt := 1; attempt <= 3; attempt++ {
+		if recorder := login("203.0.113.7:5000"); recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", attempt, recorder.Code)
+		}
+	}
+	limited := login("203.0.113.7:5001")
+	if limited.Code != http.StatusTooManyRequests || limited.Header().Get("Retry-After") != "20" {
+		t.Fatalf("expected 429 with Retry-After 20, got %d %q", limited.Code, limited.Header().Get("Retry-After"))
+	}
+	var body apiError
+	if err := json.Unmarshal(limited.Body.Bytes(), &body); err != nil || body.Code != errCodeRateLimited {
+		t.Fatalf("expected rate_limited error, got %s", limited.Body.String())
+	}
+	if recorder := login("198.51.100.9:5000"); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected other clients to keep their own budget, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type AuditEvent struct {
+	ID        string            `json:"id"`
+	Actor     string            `json:"actor"`
+	Action    string            `json:"action"`
+	TenantID  string            `json:"tenant_id,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// maxAuditEvents bounds the audit log across all tenants; the oldest events
+// are dropped first. Tenant retention settings can prune sooner.
+const maxAuditEvents = 50000
+
+type AuditStore struct {
+	mu      sync.RWMutex
+	items   map[string]AuditEvent
+	order   []string
+	counter uint64
+}
+
+func NewAuditStore() *AuditStore {
+	return &AuditStore{
+		items: make(map[string]AuditEvent),
+	}
+}
+
+func (s *AuditStore) Record(actor, action, tenantID string, details map[string]string) AuditEvent {
+	actor = strings.TrimSpace(actor)
+	if actor == "" {
+		actor = "system"
+	}
+	event := AuditEvent{
+		ID:        fmt.Sprintf("aud-%d-%d", time.Now().UnixNano(), atomic.AddUint64(&s.counter, 1)),
+		Actor:     actor,
+		Action:    strings.TrimSpace(action),
+		TenantID:  normalizeIdentifier(tenantID),
+		Details:   copyStringMap(details),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[event.ID] = event
+	s.order = append(s.order, event.ID)
+	if drop := len(s.order) - maxAuditEvents; drop > 0 {
+		for _, id := range s.order[:drop] {
+			delete(s.items, id)
+		}
+		s.order = append(s.order[:0:0], s.order[drop:]...)
+	}
+	return event
+}
+
+func (s *AuditStore) List(tenantID string, limit int) []AuditEvent {
+	if limit <= 0 {
+		limit = 100
+	}
+	tenantID = normalizeIdentifier(tenantID)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]AuditEvent, 0, min(limit, len(s.order)))
+	for i := len(s.order) - 1; i >= 0 && len(items) < limit; i-- {
+		event := s.items[s.order[i]]
+		if tenantID != "" && event.TenantID != tenantID {
+			continue
+		}
+		event.Details = copyStringMap(event.Details)
+		items = append(items, event)
+	}
+	return items
+}
+
+func (s *AuditStore) Find(tenantID, action, key, value string) []AuditEvent {
+	tenantID = normalizeIdentifier(tenantID)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]AuditEvent, 0)
+	for _, id := range s.order {
+		event := s.items[id]
+		if event.TenantID != tenantID || event.Action != action || event.Details[key] != value {
+			continue
+		}
+		event.Details = copyStringMap(event.Details)
+		items = append(items, event)
+	}
+	return items
+}
+
+func (s *AuditStore) PruneTenant(tenantID string, cutoff time.Time) int {
+	tenantID = normalizeIdentifier(tenantID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.order[:0]
+	for _, id := range s.order {
+		if event := s.items[id]; event.TenantID == tenantID && event.CreatedAt.Before(cutoff) {
+			delete(s.items, id)
+			continue
+		}
+		kept = append(kept, id)
+	}
+	pruned := len(s.order) - len(kept)
+	s.order = kept
+	return pruned
+}
@@ -0,0 +1,31 @@
+package gateway
+
+import "testing"
+
+func TestAuditStoreDropsOldestBeyondCeiling(t *testing.T) {
+	store := NewAuditStore()
+	first := store.Record("ann", "route.upserted", "acme", nil)
+	for i := 0; i < maxAuditEvents; i++ {
+		store.Record("ann", "route.upserted", "acme", nil)
+	}
+	last := store.Record("bob", "route.deleted", "other", nil)
+
+	if len(store.items) != maxAuditEvents || len(store.order) != maxAuditEvents {
+		t.Fatalf("expected the log to hold %d events, got %d", maxAuditEvents, len(store.items))
+	}
+	if _, ok := store.items[first.ID]; ok {
+		t.Fatal("expected the oldest event to be dropped")
+	}
+	if events := store.List("", 1); len(events) != 1 || events[0].ID != last.ID {
+		t.Fatalf("expected the newest event first, got %+v", events)
+	}
+	if events := store.List("other", 10); len(events) != 1 {
+		t.Fatalf("expected one event for other, got %+v", events)
+	}
+
+	restored := NewAuditStore()
+	restored.Restore(store.Snapshot())
+	if len(restored.order) != maxAuditEvents || restored.List("", 1)[0].ID != last.ID {
+		t.Fatalf("expected the snapshot to restore in order, got %d events", len(restored.order))
+	}
+}
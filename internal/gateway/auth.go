@@ -8,12 +8,15 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	storepkg "github.com/szaher/try/proxer/internal/store"
 )
 
 const (
 	RoleSuperAdmin  = "super_admin"
 	RoleTenantAdmin = "tenant_admin"
 	RoleMember      = "member"
+	RoleOrgAdmin    = "org_admin"
 	// Backward compatibility for migrated/admin-created users.
 	RoleAdmin = RoleSuperAdmin
 )
@@ -22,28 +25,37 @@ type User struct {
 	Username  string    `json:"username"`
 	Role      string    `json:"role"`
 	TenantID  string    `json:"tenant_id"`
+	OrgID     string    `json:"org_id,omitempty"`
 	Status    string    `json:"status"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type authUserRecord struct {
-	user         User
-	passwordHash string
+	user             User
+	passwordHash     string
+	notifications    NotificationPreferences
+	unsubscribeToken string
 }
 
-type authSession struct {
-	ID        string
-	Username  string
-	ExpiresAt time.Time
+// sessionRefreshInterval is how far a session's sliding expiry must move
+// before it is written back, so active users do not write to the session
+// store on every request.
+const sessionRefreshInterval = time.Minute
+
+type Impersonation struct {
+	ImpersonatedBy string    `json:"impersonated_by"`
+	Username       string    `json:"username"`
+	ExpiresAt      time.Time `json:"expires_at"`
 }
 
 type AuthStore struct {
 	sessionTTL time.Duration
 
-	mu       sync.RWMutex
-	users    map[string]authUserRecord
-	sessions map[string]authSession
+	mu    sync.RWMutex
+	users map[string]authUserRecord
+	// sessions has its own locking and is not guarded by mu.
+	sessions storepkg.SessionStore
 }
 
 func NewAuthStore(adminUsername, adminPassword string, sessionTTL time.Duration) (*AuthStore, error) {
@@ -53,7 +65,7 @@ func NewAuthStore(adminUsername, adminPassword string, sessionTTL time.Duration)
 	store := &AuthStore{
 		sessionTTL: sessionTTL,
 		users:      make(map[string]authUserRecord),
-		sessions:   make(map[string]authSession),
+		sessions:   storepkg.NewMemorySessionStore(),
 	}
 
 	adminUsername = normalizeUsername(adminUsername)
@@ -82,6 +94,7 @@ type RegisterUserInput struct {
 	Username string
 	Password string
 	TenantID string
+	OrgID    string
 	Role     string
 	Status   string
 }
@@ -92,6 +105,15 @@ func (s *AuthStore) RegisterUser(input RegisterUserInput) (User, error) {
 	return s.registerUserLocked(input)
 }
 
+func (s *AuthStore) SetSessionTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionTTL = ttl
+}
+
 func (s *AuthStore) registerUserLocked(input RegisterUserInput) (User, error) {
 	username := normalizeUsername(input.Username)
 	if !identifierPattern.MatchString(username) {
@@ -119,9 +141,16 @@ func (s *AuthStore) registerUserLocked(input RegisterUserInput) (User, error) {
 	if role == "admin" {
 		role = RoleSuperAdmin
 	}
-	if role != RoleMember && role != RoleTenantAdmin && role != RoleSuperAdmin {
+	if !validRole(role) {
 		return User{}, fmt.Errorf("invalid role %q", role)
 	}
+	orgID := ""
+	if role == RoleOrgAdmin {
+		orgID = normalizeIdentifier(input.OrgID)
+		if !identifierPattern.MatchString(orgID) {
+			return User{}, fmt.Errorf("org_admin users need a valid org_id")
+		}
+	}
 
 	status := strings.ToLower(strings.TrimSpace(input.Status))
 	if status == "" {
@@ -143,6 +172,7 @@ func (s *AuthStore) registerUserLocked(input RegisterUserInput) (User, error) {
 		Username:  username,
 		Role:      role,
 		TenantID:  tenantID,
+		OrgID:     orgID,
 		Status:    status,
 		CreatedAt: now,
 		UpdatedAt: now,
@@ -175,17 +205,40 @@ func (s *AuthStore) Authenticate(username, password string) (User, bool) {
 	return record.user, true
 }
 
+// SetSessionStore replaces where sessions are kept. Sessions in the previous
+// store are not carried over.
+func (s *AuthStore) SetSessionStore(sessions storepkg.SessionStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = sessions
+}
+
+func (s *AuthStore) sessionStore() storepkg.SessionStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sessions
+}
+
+func (s *AuthStore) SessionCached(sessionID string) bool {
+	cache, ok := s.sessionStore().(interface{ Cached(string) bool })
+	return !ok || cache.Cached(strings.TrimSpace(sessionID))
+}
+
+func (s *AuthStore) SessionHealth() map[string]any {
+	return s.sessionStore().Health()
+}
+
 func (s *AuthStore) NewSession(username string) (string, error) {
 	username = normalizeUsername(username)
 	if username == "" {
 		return "", fmt.Errorf("missing username")
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.cleanupExpiredSessionsLocked(time.Now().UTC())
-
-	if _, ok := s.users[username]; !ok {
+	s.mu.RLock()
+	_, ok := s.users[username]
+	sessions := s.sessions
+	s.mu.RUnlock()
+	if !ok {
 		return "", fmt.Errorf("unknown user")
 	}
 
@@ -193,43 +246,90 @@ func (s *AuthStore) NewSession(username string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	s.sessions[token] = authSession{
+	session := storepkg.SessionRecord{
 		ID:        token,
 		Username:  username,
 		ExpiresAt: time.Now().UTC().Add(s.sessionTTL),
 	}
+	if err := sessions.Put(session); err != nil {
+		return "", fmt.Errorf("store session: %w", err)
+	}
 	return token, nil
 }
 
-func (s *AuthStore) ResolveSession(sessionID string) (User, bool) {
-	sessionID = strings.TrimSpace(sessionID)
-	if sessionID == "" {
-		return User{}, false
+func (s *AuthStore) NewImpersonationSession(actor, username string, ttl time.Duration) (string, Impersonation, error) {
+	actor = normalizeUsername(actor)
+	username = normalizeUsername(username)
+
+	s.mu.RLock()
+	record, ok := s.users[username]
+	sessions := s.sessions
+	s.mu.RUnlock()
+	if !ok {
+		return "", Impersonation{}, fmt.Errorf("unknown user")
+	}
+	if username == actor || record.user.Role == RoleSuperAdmin {
+		return "", Impersonation{}, fmt.Errorf("super admins cannot be impersonated")
+	}
+	if record.user.Status != "active" {
+		return "", Impersonation{}, fmt.Errorf("user %q is not active", username)
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	now := time.Now().UTC()
-	s.cleanupExpiredSessionsLocked(now)
+	token, err := randomToken(32)
+	if err != nil {
+		return "", Impersonation{}, err
+	}
+	session := storepkg.SessionRecord{
+		ID:             token,
+		Username:       username,
+		ExpiresAt:      time.Now().UTC().Add(ttl),
+		ImpersonatedBy: actor,
+	}
+	if err := sessions.Put(session); err != nil {
+		return "", Impersonation{}, fmt.Errorf("store session: %w", err)
+	}
+	return token, Impersonation{ImpersonatedBy: actor, Username: username, ExpiresAt: session.ExpiresAt}, nil
+}
 
-	session, ok := s.sessions[sessionID]
-	if !ok {
-		return User{}, false
+// ResolveSession returns the user of sessionID and, for impersonation
+// sessions, who is acting as them. A session store error counts as no
+// session.
+func (s *AuthStore) ResolveSession(sessionID string) (User, *Impersonation, bool) {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return User{}, nil, false
 	}
-	if now.After(session.ExpiresAt) {
-		delete(s.sessions, sessionID)
-		return User{}, false
+
+	sessions := s.sessionStore()
+	session, ok, err := sessions.Get(sessionID)
+	if err != nil || !ok {
+		return User{}, nil, false
 	}
+
+	s.mu.RLock()
 	record, ok := s.users[session.Username]
+	admin, adminOK := s.users[session.ImpersonatedBy]
+	s.mu.RUnlock()
 	if !ok {
-		delete(s.sessions, sessionID)
-		return User{}, false
+		_ = sessions.Delete(sessionID)
+		return User{}, nil, false
+	}
+	if session.ImpersonatedBy != "" {
+		// The session ends once the admin loses super admin or either user is
+		// disabled.
+		if !adminOK || admin.user.Role != RoleSuperAdmin || admin.user.Status != "active" || record.user.Status != "active" {
+			_ = sessions.Delete(sessionID)
+			return User{}, nil, false
+		}
+		return record.user, &Impersonation{ImpersonatedBy: session.ImpersonatedBy, Username: session.Username, ExpiresAt: session.ExpiresAt}, true
 	}
 
 	// Sliding expiration for active sessions.
-	session.ExpiresAt = now.Add(s.sessionTTL)
-	s.sessions[sessionID] = session
-	return record.user, true
+	if expiresAt := time.Now().UTC().Add(s.sessionTTL); expiresAt.Sub(session.ExpiresAt) > sessionRefreshInterval {
+		session.ExpiresAt = expiresAt
+		_ = sessions.Put(session)
+	}
+	return record.user, nil, true
 }
 
 func (s *AuthStore) DeleteSession(sessionID string) {
@@ -237,10 +337,7 @@ func (s *AuthStore) DeleteSession(sessionID string) {
 	if sessionID == "" {
 		return
 	}
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	delete(s.sessions, sessionID)
+	_ = s.sessionStore().Delete(sessionID)
 }
 
 func (s *AuthStore) ListUsers() []User {
@@ -274,6 +371,7 @@ type UpdateUserInput struct {
 	Username string
 	Role     string
 	TenantID string
+	OrgID    string
 	Status   string
 	Password string
 }
@@ -297,7 +395,7 @@ func (s *AuthStore) UpdateUser(input UpdateUserInput) (User, error) {
 		if normalized == "admin" {
 			normalized = RoleSuperAdmin
 		}
-		if normalized != RoleSuperAdmin && normalized != RoleTenantAdmin && normalized != RoleMember {
+		if !validRole(normalized) {
 			return User{}, fmt.Errorf("invalid role %q", role)
 		}
 		record.user.Role = normalized
@@ -305,6 +403,17 @@ func (s *AuthStore) UpdateUser(input UpdateUserInput) (User, error) {
 			record.user.TenantID = ""
 		}
 	}
+	if orgID := normalizeIdentifier(input.OrgID); orgID != "" {
+		if !identifierPattern.MatchString(orgID) {
+			return User{}, fmt.Errorf("invalid org id %q", orgID)
+		}
+		record.user.OrgID = orgID
+	}
+	if record.user.Role != RoleOrgAdmin {
+		record.user.OrgID = ""
+	} else if record.user.OrgID == "" {
+		return User{}, fmt.Errorf("org_admin users need a valid org_id")
+	}
 
 	if tenantID := strings.TrimSpace(input.TenantID); tenantID != "" {
 		if !identifierPattern.MatchString(tenantID) {
@@ -334,12 +443,12 @@ func (s *AuthStore) UpdateUser(input UpdateUserInput) (User, error) {
 	return record.user, nil
 }
 
-func (s *AuthStore) cleanupExpiredSessionsLocked(now time.Time) {
-	for id, session := range s.sessions {
-		if now.After(session.ExpiresAt) {
-			delete(s.sessions, id)
-		}
+func validRole(role string) bool {
+	switch role {
+	case RoleSuperAdmin, RoleOrgAdmin, RoleTenantAdmin, RoleMember:
+		return true
 	}
+	return false
 }
 
 func hashPassword(password string) string {
@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequireAuthAcceptsBearerSessionToken(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	sessionID, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tenants", nil)
+	req.Header.Set("Authorization", "Bearer "+sessionID)
+	recorder := httptest.NewRecorder()
+	server.handleTenants(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected bearer session to list tenants, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if !strings.Contains(recorder.Body.String(), `"tenants"`) {
+		t.Fatalf("expected tenants payload, got %s", recorder.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/tenants", nil)
+	req.Header.Set("Authorization", "Bearer not-a-session")
+	recorder = httptest.NewRecorder()
+	server.handleTenants(recorder, req)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unknown bearer token to be rejected, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,226 @@
+package gateway
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	backupFormatVersion   = 1
+	backupManifestName    = "manifest.json"
+	backupSnapshotName    = "snapshot.json"
+	maxBackupSnapshotSize = 512 << 20
+)
+
+type BackupManifest struct {
+	FormatVersion   int       `json:"format_version"`
+	CreatedAt       time.Time `json:"created_at"`
+	SourceDriver    string    `json:"source_driver"`
+	SnapshotVersion int       `json:"snapshot_version"`
+	SnapshotSavedAt time.Time `json:"snapshot_saved_at"`
+	SnapshotSHA256  string    `json:"snapshot_sha256"`
+	SnapshotBytes   int       `json:"snapshot_bytes"`
+}
+
+func ValidateSnapshotPayload(payload []byte) (ServerSnapshot, error) {
+	var snapshot ServerSnapshot
+	if err := json.Unmarshal(payload, &snapshot); err != nil {
+		return ServerSnapshot{}, fmt.Errorf("decode snapshot: %w", err)
+	}
+	if snapshot.Version <= 0 {
+		return ServerSnapshot{}, errors.New("snapshot has no version; not a proxer gateway snapshot")
+	}
+	return snapshot, nil
+}
+
+func WriteBackupArchive(w io.Writer, payload []byte, sourceDriver string) (BackupManifest, error) {
+	snapshot, err := ValidateSnapshotPayload(payload)
+	if err != nil {
+		return BackupManifest{}, err
+	}
+	sum := sha256.Sum256(payload)
+	manifest := BackupManifest{
+		FormatVersion:   backupFormatVersion,
+		CreatedAt:       time.Now().UTC(),
+		SourceDriver:    sourceDriver,
+		SnapshotVersion: snapshot.Version,
+		SnapshotSavedAt: snapshot.SavedAt,
+		SnapshotSHA256:  hex.EncodeToString(sum[:]),
+		SnapshotBytes:   len(payload),
+	}
+	manifestPayload, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return BackupManifest{}, err
+	}
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	for _, entry := range []struct {
+		name    string
+		payload []byte
+	}{
+		{backupManifestName, manifestPayload},
+		{backupSnapshotName, payload},
+	} {
+		header := &tar.Header{
+			Name:    entry.name,
+			Mode:    0o600,
+			Size:    int64(len(entry.payload)),
+			ModTime: manifest.CreatedAt,
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return BackupManifest{}, fmt.Errorf("write %s header: %w", entry.name, err)
+		}
+		if _, err := archive.Write(entry.payload); err != nil {
+			return BackupManifest{}, fmt.Errorf("write %s: %w", entry.name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return BackupManifest{}, err
+	}
+	if err := gz.Close(); err != nil {
+		return BackupManifest{}, err
+	}
+	return manifest, nil
+}
+
+func ReadBackupArchive(r io.Reader) ([]byte, BackupManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, BackupManifest{}, fmt.Errorf("open backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest BackupManifest
+	var payload []byte
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, BackupManifest{}, fmt.Errorf("read backup archive: %w", err)
+		}
+		if header.Size > maxBackupSnapshotSize {
+			return nil, BackupManifest{}, fmt.Errorf("backup entry %s is too large", header.Name)
+		}
+		content, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, BackupManifest{}, fmt.Errorf("read %s: %w", header.Name, err)
+		}
+		switch header.Name {
+		case backupManifestName:
+			if err := json.Unmarshal(content, &manifest); err != nil {
+				return nil, BackupManifest{}, fmt.Errorf("decode manifest: %w", err)
+			}
+		case backupSnapshotName:
+			payload = content
+		}
+	}
+	if manifest.FormatVersion != backupFormatVersion {
+		return nil, BackupManifest{}, fmt.Errorf("unsupported backup format version %d", manifest.FormatVersion)
+	}
+	if payload == nil {
+		return nil, BackupManifest{}, errors.New("backup archive has no snapshot")
+	}
+	sum := sha256.Sum256(payload)
+	if hex.EncodeToString(sum[:]) != manifest.SnapshotSHA256 {
+		return nil, BackupManifest{}, errors.New("backup snapshot checksum mismatch")
+	}
+	if _, err := ValidateSnapshotPayload(payload); err != nil {
+		return nil, BackupManifest{}, err
+	}
+	return payload, manifest, nil
+}
+
+func (s *Server) handleAdminBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	if !s.requireSuperAdmin(w, user) {
+		return
+	}
+
+	payload, err := json.Marshal(s.buildSnapshot())
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, fmt.Sprintf("encode snapshot: %v", err))
+		return
+	}
+	var archive bytes.Buffer
+	manifest, err := WriteBackupArchive(&archive, payload, s.config().StorageDriver)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, fmt.Sprintf("build backup: %v", err))
+		return
+	}
+	s.auditStore.Record(user.Username, "gateway.backup", "", map[string]string{
+		"snapshot_sha256": manifest.SnapshotSHA256,
+	})
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "proxer-backup-"+manifest.CreatedAt.Format("20060102-150405")+".tar.gz"))
+	w.Header().Set("Content-Length", strconv.Itoa(archive.Len()))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(archive.Bytes())
+}
+
+// handleAdminRestore replaces the running gateway state with a backup. Agent
+// sessions and console logins are not part of a snapshot and are kept.
+func (s *Server) handleAdminRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	if !s.requireSuperAdmin(w, user) {
+		return
+	}
+
+	payload, manifest, err := ReadBackupArchive(http.MaxBytesReader(w, r.Body, maxBackupSnapshotSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeAPIError(w, http.StatusRequestEntityTooLarge, errCodePayloadTooLarge, "payload exceeds request body limit")
+			return
+		}
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("invalid backup: %v", err))
+		return
+	}
+	snapshot, err := ValidateSnapshotPayload(payload)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("invalid backup: %v", err))
+		return
+	}
+
+	s.applySnapshot(snapshot)
+	s.refreshUsageAllTenants()
+	s.auditStore.Record(user.Username, "gateway.restore", "", map[string]string{
+		"snapshot_sha256": manifest.SnapshotSHA256,
+		"source_driver":   manifest.SourceDriver,
+		"snapshot_saved":  manifest.SnapshotSavedAt.Format(time.RFC3339),
+	})
+	s.logger.Printf("restored gateway state from backup created_at=%s source_driver=%s", manifest.CreatedAt.Format(time.RFC3339), manifest.SourceDriver)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message":  "state restored",
+		"manifest": manifest,
+	})
+	s.persistState()
+}
@@ -0,0 +1,48 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestBackupArchiveRoundTripRestoresState(t *testing.T) {
+	source := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := source.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "shop", Target: "http://127.0.0.1:7000"}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	payload, err := json.Marshal(source.buildSnapshot())
+	if err != nil {
+		t.Fatalf("encode snapshot: %v", err)
+	}
+
+	var archive bytes.Buffer
+	manifest, err := WriteBackupArchive(&archive, payload, "memory")
+	if err != nil {
+		t.Fatalf("write backup: %v", err)
+	}
+	restoredPayload, readManifest, err := ReadBackupArchive(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("read backup: %v", err)
+	}
+	if readManifest.SnapshotSHA256 != manifest.SnapshotSHA256 || !bytes.Equal(restoredPayload, payload) {
+		t.Fatalf("expected snapshot to round trip unchanged")
+	}
+
+	snapshot, err := ValidateSnapshotPayload(restoredPayload)
+	if err != nil {
+		t.Fatalf("validate snapshot: %v", err)
+	}
+	target := NewServer(Config{StorageDriver: "memory"}, nil)
+	target.applySnapshot(snapshot)
+	if _, ok := target.ruleStore.GetForTenant(DefaultTenantID, "shop"); !ok {
+		t.Fatalf("expected restored route in target server")
+	}
+
+	if _, err := WriteBackupArchive(&archive, []byte(`{"rules":{}}`), "memory"); err == nil {
+		t.Fatalf("expected payload without snapshot version to be rejected")
+	}
+	if _, _, err := ReadBackupArchive(bytes.NewReader(archive.Bytes()[:archive.Len()/2])); err == nil {
+		t.Fatalf("expected truncated archive to be rejected")
+	}
+}
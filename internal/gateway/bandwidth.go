@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/szaher/try/proxer/internal/httpx"
+)
+
+// minBandwidthBytesPerSecond keeps limits from stalling a tunnel outright.
+const minBandwidthBytesPerSecond = 1024
+
+func validateBandwidthLimit(field string, bytesPerSecond int64) error {
+	if bytesPerSecond != 0 && bytesPerSecond < minBandwidthBytesPerSecond {
+		return fmt.Errorf("%s must be 0 (unlimited) or at least %d", field, minBandwidthBytesPerSecond)
+	}
+	return nil
+}
+
+// BandwidthLimiters holds the byte token buckets of routes and connectors
+// with a max_bytes_per_second. Every request and stream of a route or
+// connector draws from the same bucket, so the limit covers their sum.
+type BandwidthLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*httpx.BandwidthLimiter
+}
+
+func NewBandwidthLimiters() *BandwidthLimiters {
+	return &BandwidthLimiters{limiters: make(map[string]*httpx.BandwidthLimiter)}
+}
+
+func (b *BandwidthLimiters) get(key string, bytesPerSecond int64) *httpx.BandwidthLimiter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	limiter, ok := b.limiters[key]
+	if bytesPerSecond <= 0 {
+		delete(b.limiters, key)
+		return nil
+	}
+	if !ok {
+		limiter = httpx.NewBandwidthLimiter(bytesPerSecond)
+		b.limiters[key] = limiter
+	} else {
+		limiter.SetRate(bytesPerSecond)
+	}
+	return limiter
+}
+
+func (s *Server) routeBandwidth(rule Rule) *httpx.BandwidthLimiter {
+	return s.bandwidth.get("route:"+MakeTunnelKey(rule.TenantID, rule.ID), rule.MaxBytesPerSecond)
+}
+
+func (s *Server) connectorBandwidth(connectorID string) *httpx.BandwidthLimiter {
+	connector, ok := s.connectorStore.Get(connectorID)
+	if !ok {
+		return nil
+	}
+	return s.bandwidth.get("connector:"+connector.ID, connector.MaxBytesPerSecond)
+}
+
+type throttledResponseWriter struct {
+	http.ResponseWriter
+	body io.Writer
+}
+
+func throttleResponse(ctx context.Context, w http.ResponseWriter, limiters ...*httpx.BandwidthLimiter) http.ResponseWriter {
+	for _, limiter := range limiters {
+		if limiter != nil {
+			return &throttledResponseWriter{ResponseWriter: w, body: httpx.ThrottleWriter(ctx, w, limiters...)}
+		}
+	}
+	return w
+}
+
+func (w *throttledResponseWriter) Write(p []byte) (int, error) {
+	return w.body.Write(p)
+}
+
+func (w *throttledResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
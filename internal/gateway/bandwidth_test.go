@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestConnectorBandwidthLimitPacesResponses(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.connectorStore.Create(Connector{ID: "laptop", TenantID: DefaultTenantID, MaxBytesPerSecond: 100}); err == nil {
+		t.Fatalf("expected a limit below %d bytes per second to be rejected", minBandwidthBytesPerSecond)
+	}
+	if _, err := server.connectorStore.Create(Connector{ID: "laptop", TenantID: DefaultTenantID, Labels: map[string]string{"os": "mac"}}); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+	connector, err := server.connectorStore.SetBandwidthLimit("laptop", 4096)
+	if err != nil || connector.MaxBytesPerSecond != 4096 || connector.Labels["os"] != "mac" {
+		t.Fatalf("expected the limit to be set and labels kept, got %+v, %v", connector, err)
+	}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "app", ConnectorID: "laptop", LocalPort: 3000, MaxBytesPerSecond: 512}); err == nil {
+		t.Fatalf("expected a route limit below %d bytes per second to be rejected", minBandwidthBytesPerSecond)
+	}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "app", ConnectorID: "laptop", LocalPort: 3000}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	registered, err := server.hub.RegisterConnectorSession("laptop", "agent-laptop")
+	if err != nil {
+		t.Fatalf("register connector: %v", err)
+	}
+	payload := bytes.Repeat([]byte("x"), 8192)
+	go func() {
+		pulled, err := server.hub.PullRequest(context.Background(), registered.SessionID)
+		if err != nil {
+			return
+		}
+		_ = server.hub.SubmitProxyResponse(registered.SessionID, &protocol.ProxyResponse{
+			RequestID: pulled.RequestID,
+			TunnelID:  pulled.TunnelID,
+			Status:    http.StatusOK,
+			Body:      payload,
+		})
+	}()
+
+	// The bucket starts with one second of tokens, so the second half of the
+	// body waits about a second.
+	start := time.Now()
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/app/", nil))
+	elapsed := time.Since(start)
+	if recorder.Code != http.StatusOK || recorder.Body.Len() != len(payload) {
+		t.Fatalf("expected the full body, got %d with %d bytes", recorder.Code, recorder.Body.Len())
+	}
+	if elapsed < 900*time.Millisecond || elapsed > 5*time.Second {
+		t.Fatalf("expected 8 KiB at 4 KiB/s to take about a second, took %s", elapsed)
+	}
+}
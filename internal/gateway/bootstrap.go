@@ -0,0 +1,236 @@
+package gateway
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	bootstrapShellSh         = "sh"
+	bootstrapShellPowerShell = "powershell"
+)
+
+type bootstrapScriptData struct {
+	GatewayBaseURL string
+	ConnectorID    string
+	Profile        string
+	PairToken      string
+	ExpiresAt      string
+	Downloads      []bootstrapDownload
+}
+
+type bootstrapDownload struct {
+	Match  string
+	URL    string
+	SHA256 string
+}
+
+func bootstrapShell(r *http.Request) (string, error) {
+	shell := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("shell")))
+	switch shell {
+	case "":
+		if platform, _ := detectClientPlatform(r); platform == "windows" {
+			return bootstrapShellPowerShell, nil
+		}
+		return bootstrapShellSh, nil
+	case bootstrapShellSh, bootstrapShellPowerShell:
+		return shell, nil
+	case "ps1", "pwsh":
+		return bootstrapShellPowerShell, nil
+	}
+	return "", fmt.Errorf("shell must be %q or %q", bootstrapShellSh, bootstrapShellPowerShell)
+}
+
+// bootstrapDownloads lists the binaries a script can install, exact arch
+// builds before universal or arch-less ones so the first match wins.
+func bootstrapDownloads(downloads []PublicDownloadBinary, shell string) []bootstrapDownload {
+	exact := make([]bootstrapDownload, 0)
+	fallback := make([]bootstrapDownload, 0)
+	for _, binary := range downloads {
+		var match string
+		switch {
+		case shell == bootstrapShellPowerShell && binary.Platform == "windows":
+			match = binary.Arch
+		case shell == bootstrapShellSh && binary.Platform == "linux":
+			match = "Linux/" + binary.Arch
+		case shell == bootstrapShellSh && binary.Platform == "macos":
+			match = "Darwin/" + binary.Arch
+		default:
+			continue
+		}
+		download := bootstrapDownload{URL: binary.URL, SHA256: binary.SHA256}
+		if binary.Arch == "" || binary.Arch == "universal" {
+			download.Match = strings.TrimSuffix(match, binary.Arch) + "*"
+			if shell == bootstrapShellPowerShell {
+				download.Match = "*"
+			}
+			fallback = append(fallback, download)
+			continue
+		}
+		download.Match = match
+		exact = append(exact, download)
+	}
+	return append(exact, fallback...)
+}
+
+func renderBootstrapScript(shell string, data bootstrapScriptData) (string, error) {
+	tmpl := bootstrapShTemplate
+	if shell == bootstrapShellPowerShell {
+		tmpl = bootstrapPowerShellTemplate
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func newBootstrapScriptData(gatewayBaseURL string, connector Connector, token PairToken, downloads []bootstrapDownload) bootstrapScriptData {
+	return bootstrapScriptData{
+		GatewayBaseURL: gatewayBaseURL,
+		ConnectorID:    connector.ID,
+		Profile:        connector.ID,
+		PairToken:      token.Token,
+		ExpiresAt:      token.ExpiresAt.UTC().Format(time.RFC3339),
+		Downloads:      downloads,
+	}
+}
+
+func shSingleQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func psSingleQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+var bootstrapShTemplate = template.Must(template.New("bootstrap.sh").Funcs(template.FuncMap{"q": shSingleQuote}).Parse(`#!/bin/sh
+# Proxer agent bootstrap for connector {{.ConnectorID}}.
+# The pair token below is single-use and expires at {{.ExpiresAt}}.
+set -eu
+
+PROXER_GATEWAY_BASE_URL={{q .GatewayBaseURL}}
+PROXER_CONNECTOR_ID={{q .ConnectorID}}
+PROXER_PROFILE={{q .Profile}}
+PROXER_PAIR_TOKEN={{q .PairToken}}
+PROXER_INSTALL_DIR="${PROXER_INSTALL_DIR:-$HOME/.local/bin}"
+
+os=$(uname -s)
+case "$(uname -m)" in
+  x86_64|amd64) arch=amd64 ;;
+  arm64|aarch64) arch=arm64 ;;
+  *) arch=$(uname -m) ;;
+esac
+
+download_url=""
+download_sha256=""
+case "$os/$arch" in
+{{- range .Downloads}}
+  {{.Match}}) download_url={{q .URL}}; download_sha256={{q .SHA256}} ;;
+{{- end}}
+  *) ;;
+esac
+
+fetch() {
+  if command -v curl >/dev/null 2>&1; then
+    curl -fsSL -o "$2" "$1"
+  else
+    wget -qO "$2" "$1"
+  fi
+}
+
+verify() {
+  [ -n "$2" ] || return 0
+  if command -v sha256sum >/dev/null 2>&1; then
+    actual=$(sha256sum "$1" | cut -d ' ' -f 1)
+  else
+    actual=$(shasum -a 256 "$1" | cut -d ' ' -f 1)
+  fi
+  if [ "$actual" != "$2" ]; then
+    echo "proxer-agent download checksum mismatch" >&2
+    exit 1
+  fi
+}
+
+agent=$(command -v proxer-agent 2>/dev/null || true)
+if [ -z "$agent" ]; then
+  if [ -z "$download_url" ]; then
+    echo "proxer-agent is not installed and the gateway offers no download for $os/$arch" >&2
+    exit 1
+  fi
+  tmp=$(mktemp -d)
+  trap 'rm -rf "$tmp"' EXIT
+  mkdir -p "$PROXER_INSTALL_DIR"
+  case "$os" in
+    Darwin)
+      fetch "$download_url" "$tmp/proxer-agent.zip"
+      verify "$tmp/proxer-agent.zip" "$download_sha256"
+      mkdir -p "$HOME/Applications"
+      unzip -qo "$tmp/proxer-agent.zip" -d "$HOME/Applications"
+      ln -sf "$HOME/Applications/Proxer Agent.app/Contents/MacOS/proxer-agent" "$PROXER_INSTALL_DIR/proxer-agent"
+      ;;
+    *)
+      fetch "$download_url" "$tmp/proxer-agent"
+      verify "$tmp/proxer-agent" "$download_sha256"
+      chmod 0755 "$tmp/proxer-agent"
+      mv "$tmp/proxer-agent" "$PROXER_INSTALL_DIR/proxer-agent"
+      ;;
+  esac
+  agent="$PROXER_INSTALL_DIR/proxer-agent"
+fi
+
+if ! "$agent" profile add --name "$PROXER_PROFILE" --gateway "$PROXER_GATEWAY_BASE_URL" --agent-id "$PROXER_CONNECTOR_ID" --mode connector 2>/dev/null; then
+  "$agent" profile edit "$PROXER_PROFILE" --gateway "$PROXER_GATEWAY_BASE_URL"
+fi
+"$agent" pair --token "$PROXER_PAIR_TOKEN" --profile "$PROXER_PROFILE"
+"$agent" profile use "$PROXER_PROFILE"
+"$agent" config set start_at_login true
+echo "proxer-agent is paired with connector $PROXER_CONNECTOR_ID; start it with: $agent run --profile $PROXER_PROFILE"
+`))
+
+var bootstrapPowerShellTemplate = template.Must(template.New("bootstrap.ps1").Funcs(template.FuncMap{"q": psSingleQuote}).Parse(`# Proxer agent bootstrap for connector {{.ConnectorID}}.
+# The pair token below is single-use and expires at {{.ExpiresAt}}.
+$ErrorActionPreference = 'Stop'
+
+$GatewayBaseUrl = {{q .GatewayBaseURL}}
+$ConnectorId = {{q .ConnectorID}}
+$ProfileName = {{q .Profile}}
+$PairToken = {{q .PairToken}}
+
+$Arch = if ($env:PROCESSOR_ARCHITECTURE -eq 'ARM64') { 'arm64' } else { 'amd64' }
+$Downloads = @(
+{{- range .Downloads}}
+  @{ Match = {{q .Match}}; Url = {{q .URL}}; Sha256 = {{q .SHA256}} }
+{{- end}}
+)
+
+function Invoke-Agent {
+  & $script:Agent @args
+  if ($LASTEXITCODE -ne 0) { throw "proxer-agent $($args -join ' ') failed" }
+}
+
+$Agent = (Get-Command proxer-agent -ErrorAction SilentlyContinue).Source
+if (-not $Agent) { $Agent = Join-Path $env:ProgramFiles 'Proxer Agent\proxer-agent.exe' }
+if (-not (Test-Path $Agent)) {
+  $Download = $Downloads | Where-Object { $_.Match -eq $Arch -or $_.Match -eq '*' } | Select-Object -First 1
+  if (-not $Download) { throw "proxer-agent is not installed and the gateway offers no download for windows/$Arch" }
+  $Msi = Join-Path $env:TEMP 'proxer-agent.msi'
+  Invoke-WebRequest -UseBasicParsing -Uri $Download.Url -OutFile $Msi
+  if ($Download.Sha256 -and (Get-FileHash -Algorithm SHA256 $Msi).Hash.ToLower() -ne $Download.Sha256) {
+    throw 'proxer-agent download checksum mismatch'
+  }
+  Start-Process msiexec.exe -ArgumentList '/i', "` + "`" + `"$Msi` + "`" + `"", '/qn' -Wait
+  Remove-Item $Msi
+}
+
+& $Agent profile add --name $ProfileName --gateway $GatewayBaseUrl --agent-id $ConnectorId --mode connector 2>$null
+if ($LASTEXITCODE -ne 0) { Invoke-Agent profile edit $ProfileName --gateway $GatewayBaseUrl }
+Invoke-Agent pair --token $PairToken --profile $ProfileName
+Invoke-Agent profile use $ProfileName
+Invoke-Agent config set start_at_login true
+Write-Host "proxer-agent is paired with connector $ConnectorId; start it with: & '$Agent' run --profile $ProfileName"
+`))
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestConnectorBootstrapScriptPairsWithFreshToken(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", PublicBaseURL: "https://gw.example.com"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	if _, err := server.connectorStore.Create(Connector{ID: "edge", TenantID: DefaultTenantID, Name: "edge"}); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+	session, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	call := func(path, userAgent string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+session)
+		req.Header.Set("User-Agent", userAgent)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := call("/api/connectors/edge/bootstrap", "curl/8.5.0")
+	if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("expected a plain-text script, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	script := recorder.Body.String()
+	if !strings.HasPrefix(script, "#!/bin/sh") || !strings.Contains(script, "PROXER_GATEWAY_BASE_URL='https://gw.example.com'") {
+		t.Fatalf("expected a sh script with the gateway URL baked in, got:\n%s", script)
+	}
+	token := regexp.MustCompile(`PROXER_PAIR_TOKEN='([^']+)'`).FindStringSubmatch(script)
+	if token == nil {
+		t.Fatalf("expected a pair token in the script, got:\n%s", script)
+	}
+	if connector, _, err := server.connectorStore.ConsumePairToken(token[1]); err != nil || connector.ID != "edge" {
+		t.Fatalf("expected the script's pair token to pair connector edge, got %+v %v", connector, err)
+	}
+
+	recorder = call("/api/connectors/edge/bootstrap", "Mozilla/5.0 (Windows NT; Windows NT 10.0; en-US) WindowsPowerShell/5.1")
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "$GatewayBaseUrl = 'https://gw.example.com'") {
+		t.Fatalf("expected a PowerShell script for a Windows caller, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call("/api/connectors/edge/bootstrap?shell=fish", "curl/8.5.0"); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected an unknown shell to be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestBootstrapDownloadsPreferExactArch(t *testing.T) {
+	downloads := []PublicDownloadBinary{
+		{Platform: "macos", Arch: "universal", URL: "https://dl/mac.zip"},
+		{Platform: "linux", Arch: "amd64", URL: "https://dl/linux-amd64.AppImage", SHA256: "abc"},
+		{Platform: "linux", Arch: "", URL: "https://dl/linux.AppImage"},
+		{Platform: "windows", Arch: "amd64", URL: "https://dl/win.msi"},
+	}
+	got := bootstrapDownloads(downloads, bootstrapShellSh)
+	want := []string{"Linux/amd64", "Darwin/*", "Linux/*"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d sh downloads, got %+v", len(want), got)
+	}
+	for i, match := range want {
+		if got[i].Match != match {
+			t.Fatalf("expected download %d to match %q, got %+v", i, match, got)
+		}
+	}
+	if got := bootstrapDownloads(downloads, bootstrapShellPowerShell); len(got) != 1 || got[0].Match != "amd64" || got[0].URL != "https://dl/win.msi" {
+		t.Fatalf("expected only the Windows MSI for PowerShell, got %+v", got)
+	}
+	if quoted := shSingleQuote("it's"); quoted != `'it'\''s'` {
+		t.Fatalf("unexpected sh quoting %s", quoted)
+	}
+	if quoted := psSingleQuote("it's"); quoted != `'it''s'` {
+		t.Fatalf("unexpected PowerShell quoting %s", quoted)
+	}
+}
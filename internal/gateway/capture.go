@@ -0,0 +1,336 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const (
+	defaultCaptureEntries   = 50
+	maxCaptureEntries       = 200
+	defaultCaptureBodyBytes = 64 << 10
+	maxCaptureBodyBytes     = 256 << 10
+)
+
+type RouteCapture struct {
+	MaxEntries   int   `json:"max_entries,omitempty"`
+	MaxBodyBytes int64 `json:"max_body_bytes,omitempty"`
+}
+
+func normalizeRouteCapture(input *RouteCapture) (*RouteCapture, error) {
+	if input == nil {
+		return nil, nil
+	}
+	if input.MaxEntries < 0 || input.MaxEntries > maxCaptureEntries {
+		return nil, fmt.Errorf("capture.max_entries must be between 0 and %d", maxCaptureEntries)
+	}
+	if input.MaxBodyBytes < 0 || input.MaxBodyBytes > maxCaptureBodyBytes {
+		return nil, fmt.Errorf("capture.max_body_bytes must be between 0 and %d", maxCaptureBodyBytes)
+	}
+	capture := *input
+	if capture.MaxEntries == 0 {
+		capture.MaxEntries = defaultCaptureEntries
+	}
+	if capture.MaxBodyBytes == 0 {
+		capture.MaxBodyBytes = defaultCaptureBodyBytes
+	}
+	return &capture, nil
+}
+
+type CapturedMessage struct {
+	Headers       map[string][]string `json:"headers,omitempty"`
+	Body          []byte              `json:"body,omitempty"`
+	BodySize      int64               `json:"body_size"`
+	BodyTruncated bool                `json:"body_truncated,omitempty"`
+	BodyOmitted   bool                `json:"body_omitted,omitempty"`
+}
+
+type CapturedExchange struct {
+	ID          string          `json:"id"`
+	TenantID    string          `json:"tenant_id"`
+	RouteID     string          `json:"route_id"`
+	StartedAt   time.Time       `json:"started_at"`
+	DurationMs  int64           `json:"duration_ms"`
+	Method      string          `json:"method"`
+	URL         string          `json:"url"`
+	HTTPVersion string          `json:"http_version"`
+	Status      int             `json:"status"`
+	Request     CapturedMessage `json:"request"`
+	Response    CapturedMessage `json:"response"`
+}
+
+type captureSummary struct {
+	ID                string    `json:"id"`
+	StartedAt         time.Time `json:"started_at"`
+	DurationMs        int64     `json:"duration_ms"`
+	Method            string    `json:"method"`
+	URL               string    `json:"url"`
+	Status            int       `json:"status"`
+	RequestBodySize   int64     `json:"request_body_size"`
+	ResponseBodySize  int64     `json:"response_body_size"`
+	ResponseMediaType string    `json:"response_content_type,omitempty"`
+}
+
+func (e CapturedExchange) summary() captureSummary {
+	return captureSummary{
+		ID:                e.ID,
+		StartedAt:         e.StartedAt,
+		DurationMs:        e.DurationMs,
+		Method:            e.Method,
+		URL:               e.URL,
+		Status:            e.Status,
+		RequestBodySize:   e.Request.BodySize,
+		ResponseBodySize:  e.Response.BodySize,
+		ResponseMediaType: firstHeaderValue(e.Response.Headers, "Content-Type"),
+	}
+}
+
+// CaptureStore keeps captured exchanges per route, oldest first. Captures
+// live in memory only and do not survive a restart.
+type CaptureStore struct {
+	mu     sync.Mutex
+	routes map[string][]CapturedExchange
+}
+
+func NewCaptureStore() *CaptureStore {
+	return &CaptureStore{routes: make(map[string][]CapturedExchange)}
+}
+
+func (s *CaptureStore) Add(exchange CapturedExchange, limit int) {
+	key := MakeTunnelKey(exchange.TenantID, exchange.RouteID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exchanges := append(s.routes[key], exchange)
+	if limit > 0 && len(exchanges) > limit {
+		exchanges = append([]CapturedExchange(nil), exchanges[len(exchanges)-limit:]...)
+	}
+	s.routes[key] = exchanges
+}
+
+func (s *CaptureStore) List(tenantID, routeID string, since, until time.Time) []CapturedExchange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exchanges := make([]CapturedExchange, 0)
+	for _, exchange := range s.routes[MakeTunnelKey(tenantID, routeID)] {
+		if !since.IsZero() && exchange.StartedAt.Before(since) {
+			continue
+		}
+		if !until.IsZero() && !exchange.StartedAt.Before(until) {
+			continue
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	return exchanges
+}
+
+func (s *CaptureStore) Get(tenantID, routeID, id string) (CapturedExchange, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, exchange := range s.routes[MakeTunnelKey(tenantID, routeID)] {
+		if exchange.ID == id {
+			return exchange, true
+		}
+	}
+	return CapturedExchange{}, false
+}
+
+func (s *CaptureStore) Clear(tenantID, routeID string) int {
+	key := MakeTunnelKey(tenantID, routeID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := len(s.routes[key])
+	delete(s.routes, key)
+	return count
+}
+
+// captureExchange records a proxied exchange of a route with capture
+// enabled. Streamed uploads are relayed without being buffered, so only
+// their size is kept.
+func (s *Server) captureExchange(rule Rule, r *http.Request, requestID string, body []byte, bodySize int64, streamed bool, resp *protocol.ProxyResponse, started time.Time) {
+	policy := s.redaction(rule.TenantID)
+	keepBodies := s.retention(rule.TenantID).storesBodies()
+	captureBody := func(body []byte, size int64, omitted bool) CapturedMessage {
+		message := CapturedMessage{BodySize: size, BodyOmitted: omitted || (!keepBodies && size > 0)}
+		if message.BodyOmitted || len(body) == 0 {
+			return message
+		}
+		body = policy.RedactBody(body)
+		if int64(len(body)) > rule.Capture.MaxBodyBytes {
+			body = body[:rule.Capture.MaxBodyBytes]
+			message.BodyTruncated = true
+		}
+		message.Body = append([]byte(nil), body...)
+		return message
+	}
+
+	request := captureBody(body, bodySize, streamed)
+	request.Headers = policy.RedactHeaders(r.Header)
+	response := captureBody(resp.Body, int64(len(resp.Body)), false)
+	response.Headers = policy.RedactHeaders(resp.Headers)
+
+	status := resp.Status
+	if status <= 0 {
+		status = http.StatusBadGateway
+	}
+	s.captures.Add(CapturedExchange{
+		ID:          requestID,
+		TenantID:    rule.TenantID,
+		RouteID:     rule.ID,
+		StartedAt:   started.UTC(),
+		DurationMs:  time.Since(started).Milliseconds(),
+		Method:      r.Method,
+		URL:         policy.RedactText(capturedURL(r)),
+		HTTPVersion: r.Proto,
+		Status:      status,
+		Request:     request,
+		Response:    response,
+	}, rule.Capture.MaxEntries)
+}
+
+func capturedURL(r *http.Request) string {
+	target := *r.URL
+	if query := target.Query(); query.Has("access_token") {
+		query.Set("access_token", redactedValue)
+		target.RawQuery = query.Encode()
+	}
+	return inferRequestBaseURL(r) + target.RequestURI()
+}
+
+func firstHeaderValue(headers map[string][]string, name string) string {
+	for key, values := range headers {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func parseQueryTime(name, raw string, now time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, raw); err == nil {
+		return parsed, nil
+	}
+	if ago, err := time.ParseDuration(raw); err == nil && ago >= 0 {
+		return now.Add(-ago), nil
+	}
+	return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp or a duration such as 15m", name)
+}
+
+func captureWindow(r *http.Request) (time.Time, time.Time, error) {
+	now := time.Now().UTC()
+	since, err := parseQueryTime("since", r.URL.Query().Get("since"), now)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	until, err := parseQueryTime("until", r.URL.Query().Get("until"), now)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !since.IsZero() && !until.IsZero() && !since.Before(until) {
+		return time.Time{}, time.Time{}, fmt.Errorf("since must be before until")
+	}
+	return since, until, nil
+}
+
+func (s *Server) handleRouteCaptures(w http.ResponseWriter, r *http.Request, user User, tenantID, routeID, action string) {
+	rule, exists := s.ruleStore.GetForTenant(tenantID, routeID)
+	if !exists {
+		writeAPIError(w, http.StatusNotFound, errCodeRouteNotFound, "route not found")
+		return
+	}
+	if r.Method == http.MethodDelete && action == "captures" {
+		if !s.canMutateTenant(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden route mutation")
+			return
+		}
+		s.captures.Clear(rule.TenantID, rule.ID)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	since, until, err := captureWindow(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	exchanges := s.captures.List(rule.TenantID, rule.ID, since, until)
+
+	if action == "captures:har" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", rule.TenantID+"-"+rule.ID+".har"))
+		writeJSON(w, http.StatusOK, buildHAR(exchanges))
+		return
+	}
+	summaries := make([]captureSummary, 0, len(exchanges))
+	for i := len(exchanges) - 1; i >= 0; i-- {
+		summaries = append(summaries, exchanges[i].summary())
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id": rule.TenantID,
+		"route_id":  rule.ID,
+		"capture":   rule.Capture,
+		"captures":  summaries,
+	})
+}
+
+// handleRouteCaptureByID returns one captured exchange. The request and
+// response parts serve the captured body as it was sent, for viewing in a
+// browser tab; it is sandboxed so captured pages cannot run scripts on the
+// console's origin.
+func (s *Server) handleRouteCaptureByID(w http.ResponseWriter, r *http.Request, tenantID, routeID, id, part string) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	rule, exists := s.ruleStore.GetForTenant(tenantID, routeID)
+	if !exists {
+		writeAPIError(w, http.StatusNotFound, errCodeRouteNotFound, "route not found")
+		return
+	}
+	exchange, ok := s.captures.Get(rule.TenantID, rule.ID, id)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "capture not found")
+		return
+	}
+
+	var message CapturedMessage
+	switch part {
+	case "":
+		writeJSON(w, http.StatusOK, map[string]any{"capture": exchange})
+		return
+	case "request":
+		message = exchange.Request
+	case "response":
+		message = exchange.Response
+	default:
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid tenant subresource path")
+		return
+	}
+	contentType := firstHeaderValue(message.Headers, "Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "inline")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Proxer-Body-Size", fmt.Sprint(message.BodySize))
+	if message.BodyTruncated {
+		w.Header().Set("X-Proxer-Body-Truncated", "true")
+	}
+	if message.BodyOmitted {
+		w.Header().Set("X-Proxer-Body-Omitted", "true")
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(message.Body)
+}
@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouteCaptureExportsHAR(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/logo.png" {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte{0x89, 'P', 'N', 'G', 0xff})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "shop", Target: upstream.URL, Capture: &RouteCapture{MaxEntries: 3}}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	session, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	call := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+session)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	requestIDs := make([]string, 0, 4)
+	for _, path := range []string{"/t/shop/first", "/t/shop/orders?page=2&access_token=secret", "/t/shop/logo.png"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer upstream-secret")
+		recorder := httptest.NewRecorder()
+		server.handleProxy(recorder, req)
+		requestIDs = append(requestIDs, recorder.Header().Get("X-Proxer-Request-ID"))
+	}
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodPost, "/t/shop/orders", strings.NewReader(`{"qty":1}`)))
+	requestIDs = append(requestIDs, recorder.Header().Get("X-Proxer-Request-ID"))
+
+	recorder = call(http.MethodGet, "/api/tenants/default/routes/shop/captures")
+	var listed struct {
+		Captures []captureSummary `json:"captures"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &listed); err != nil || recorder.Code != http.StatusOK {
+		t.Fatalf("list captures: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if len(listed.Captures) != 3 || listed.Captures[0].ID != requestIDs[3] || listed.Captures[2].ID != requestIDs[1] {
+		t.Fatalf("expected the three newest exchanges, newest first, got %+v", listed.Captures)
+	}
+
+	recorder = call(http.MethodGet, "/api/tenants/default/routes/shop/captures/"+requestIDs[2]+"/response")
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "image/png" || recorder.Header().Get("Content-Security-Policy") != "sandbox" || recorder.Body.Len() != 5 {
+		t.Fatalf("expected the captured image inline, got %d %v", recorder.Code, recorder.Header())
+	}
+	if recorder := call(http.MethodGet, "/api/tenants/default/routes/shop/captures/"+requestIDs[0]); recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected an evicted capture to be gone, got %d", recorder.Code)
+	}
+
+	recorder = call(http.MethodGet, "/api/tenants/default/routes/shop/captures:har")
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Header().Get("Content-Disposition"), "default-shop.har") {
+		t.Fatalf("expected a HAR download, got %d %v", recorder.Code, recorder.Header())
+	}
+	var har harDocument
+	if err := json.Unmarshal(recorder.Body.Bytes(), &har); err != nil {
+		t.Fatalf("decode HAR: %v", err)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 3 {
+		t.Fatalf("expected three HAR 1.2 entries, got %+v", har.Log)
+	}
+	orders, logo, post := har.Log.Entries[0], har.Log.Entries[1], har.Log.Entries[2]
+	if strings.Contains(orders.Request.URL, "secret") || len(orders.Request.QueryString) != 2 || orders.Response.Content.Text != `{"path":"/orders"}` {
+		t.Fatalf("unexpected orders entry: %+v", orders)
+	}
+	for _, header := range orders.Request.Headers {
+		if header.Name == "Authorization" && header.Value != redactedValue {
+			t.Fatalf("expected the authorization header to be redacted, got %q", header.Value)
+		}
+	}
+	if logo.Response.Content.Encoding != "base64" || logo.Response.Content.MimeType != "image/png" {
+		t.Fatalf("expected the image to be base64 encoded, got %+v", logo.Response.Content)
+	}
+	if post.Request.PostData == nil || post.Request.PostData.Text != `{"qty":1}` || post.Request.BodySize != 9 {
+		t.Fatalf("expected the posted body in the HAR, got %+v", post.Request)
+	}
+
+	if recorder := call(http.MethodGet, "/api/tenants/default/routes/shop/captures:har?since=1h&until=2000-01-01T00:00:00Z"); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected an empty window to be rejected, got %d", recorder.Code)
+	}
+	recorder = call(http.MethodGet, "/api/tenants/default/routes/shop/captures:har?until=2000-01-01T00:00:00Z")
+	if err := json.Unmarshal(recorder.Body.Bytes(), &har); err != nil || len(har.Log.Entries) != 0 {
+		t.Fatalf("expected no exchanges before 2000, got %d entries", len(har.Log.Entries))
+	}
+
+	if recorder := call(http.MethodDelete, "/api/tenants/default/routes/shop/captures"); recorder.Code != http.StatusNoContent {
+		t.Fatalf("expected captures to be cleared, got %d", recorder.Code)
+	}
+	if exchanges := server.captures.List(DefaultTenantID, "shop", time.Time{}, time.Time{}); len(exchanges) != 0 {
+		t.Fatalf("expected no captures after clearing, got %d", len(exchanges))
+	}
+}
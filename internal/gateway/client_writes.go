@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultClientSendBufferBytes = 64 << 10
+	minClientSendBufferBytes     = 4 << 10
+	maxClientSendBufferBytes     = 16 << 20
+)
+
+type clientWriteStats struct {
+	pendingBytes atomic.Int64
+	slowAborts   atomic.Int64
+	closedAborts atomic.Int64
+}
+
+type ClientWriteStatus struct {
+	PendingBytes int64 `json:"pending_bytes"`
+	SlowAborts   int64 `json:"slow_aborts"`
+	ClosedAborts int64 `json:"closed_aborts"`
+}
+
+func (c *clientWriteStats) status() ClientWriteStatus {
+	return ClientWriteStatus{
+		PendingBytes: c.pendingBytes.Load(),
+		SlowAborts:   c.slowAborts.Load(),
+		ClosedAborts: c.closedAborts.Load(),
+	}
+}
+
+// clientResponseWriter writes a proxied response to a public client one send
+// buffer at a time. The client must accept each buffer within timeout or the
+// connection is cut off, so a stalled reader cannot hold the response in
+// gateway memory for longer than that.
+type clientResponseWriter struct {
+	http.ResponseWriter
+	controller *http.ResponseController
+	timeout    time.Duration
+	sendBuffer int
+	stats      *clientWriteStats
+	written    bool
+	aborted    bool
+}
+
+func (s *Server) newClientResponseWriter(w http.ResponseWriter) *clientResponseWriter {
+	cfg := s.config()
+	sendBuffer := cfg.ClientSendBufferBytes
+	if sendBuffer <= 0 {
+		sendBuffer = defaultClientSendBufferBytes
+	}
+	return &clientResponseWriter{
+		ResponseWriter: w,
+		controller:     http.NewResponseController(w),
+		timeout:        cfg.ClientWriteTimeout,
+		sendBuffer:     sendBuffer,
+		stats:          &s.clientWrites,
+	}
+}
+
+func (w *clientResponseWriter) Write(p []byte) (int, error) {
+	if w.aborted {
+		return 0, http.ErrAbortHandler
+	}
+	w.stats.pendingBytes.Add(int64(len(p)))
+	defer w.stats.pendingBytes.Add(-int64(len(p)))
+	written := 0
+	for written < len(p) {
+		chunk := p[written:min(len(p), written+w.sendBuffer)]
+		w.extendDeadline()
+		n, err := w.ResponseWriter.Write(chunk)
+		written += n
+		w.written = w.written || n > 0
+		if err != nil {
+			w.abort(err)
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// finish flushes what the server still buffers under the deadline, then
+// clears the deadline so it does not carry over to the next request on a
+// kept-alive connection.
+func (w *clientResponseWriter) finish() {
+	if w.timeout <= 0 || w.aborted {
+		return
+	}
+	if w.written {
+		w.extendDeadline()
+		if err := w.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			w.abort(err)
+			return
+		}
+	}
+	_ = w.controller.SetWriteDeadline(time.Time{})
+}
+
+func (w *clientResponseWriter) extendDeadline() {
+	if w.timeout > 0 {
+		_ = w.controller.SetWriteDeadline(time.Now().Add(w.timeout))
+	}
+}
+
+func (w *clientResponseWriter) abort(err error) {
+	w.aborted = true
+	var netErr net.Error
+	if errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		w.stats.slowAborts.Add(1)
+		return
+	}
+	w.stats.closedAborts.Add(1)
+}
+
+func (w *clientResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// setBufferedContentLength frames a fully buffered body by its length, so
+// flushing it early does not switch the response to chunked encoding.
+func setBufferedContentLength(header http.Header, status int, body []byte, hasTrailers bool) {
+	if len(body) == 0 || hasTrailers || status == http.StatusNoContent || status == http.StatusNotModified || status < http.StatusOK {
+		return
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+}
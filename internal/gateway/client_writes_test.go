@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientWritesCutOffStalledClients(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", ClientWriteTimeout: 100 * time.Millisecond}, nil)
+	done := make(chan struct{}, 4)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() { done <- struct{}{} }()
+		body := []byte("ok")
+		if r.URL.Path == "/large" {
+			body = bytes.Repeat([]byte("x"), 64<<20)
+		}
+		writer := server.newClientResponseWriter(w)
+		setBufferedContentLength(writer.Header(), http.StatusOK, body, false)
+		_, _ = writer.Write(body)
+		writer.finish()
+	}))
+	defer upstream.Close()
+
+	conn, err := net.Dial("tcp", upstream.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	get := func(path string) *http.Response {
+		t.Helper()
+		fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: proxer.test\r\n\r\n", path)
+		response, err := http.ReadResponse(reader, nil)
+		if err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		if string(body) != "ok" {
+			t.Fatalf("unexpected body %q", body)
+		}
+		return response
+	}
+
+	// The deadline is cleared after a response, so a kept-alive connection
+	// idle for longer than the timeout still gets its next response.
+	get("/small")
+	<-done
+	time.Sleep(200 * time.Millisecond)
+	if response := get("/small"); response.ContentLength != 2 {
+		t.Fatalf("expected a buffered body to keep its content length, got %d", response.ContentLength)
+	}
+	<-done
+
+	fmt.Fprintf(conn, "GET /large HTTP/1.1\r\nHost: proxer.test\r\n\r\n")
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("the write to a stalled client was not cut off")
+	}
+	status := server.clientWrites.status()
+	if status.SlowAborts != 1 || status.PendingBytes != 0 {
+		t.Fatalf("unexpected client write status %+v", status)
+	}
+}
@@ -2,26 +2,47 @@ package gateway
 
 import (
 	"fmt"
-	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+	"github.com/szaher/try/proxer/internal/redis"
 )
 
 type Config struct {
 	ListenAddr             string
 	TLSListenAddr          string
+	HTTP2Enabled           bool
+	AdminListenAddr        string
+	AdminTLSCertFile       string
+	AdminTLSKeyFile        string
+	AgentListenAddr        string
+	AgentTLSCertFile       string
+	AgentTLSKeyFile        string
+	AgentBaseURL           string
+	SSHListenAddr          string
+	SSHHostKeyFile         string
 	AgentToken             string
+	MinAgentVersion        string
+	MinAgentProtocol       int
 	PublicBaseURL          string
 	PublicSignupEnabled    bool
 	PublicSignupRPM        int
+	AuthRateLimitRPM       int
 	RequestTimeout         time.Duration
 	ProxyRequestTimeout    time.Duration
 	MaxRequestBodyBytes    int64
 	MaxResponseBodyBytes   int64
+	ClientWriteTimeout     time.Duration
+	ClientSendBufferBytes  int
 	MaxPendingPerSession   int
 	MaxPendingGlobal       int
 	PairTokenTTL           time.Duration
+	ConnectorSecretTTL     time.Duration
+	ConnectorSecretGrace   time.Duration
+	TrashRetention         time.Duration
 	AdminUsername          string
 	AdminPassword          string
 	SuperAdminUsername     string
@@ -29,6 +50,9 @@ type Config struct {
 	SessionTTL             time.Duration
 	StorageDriver          string
 	SQLitePath             string
+	RateLimitBackend       string
+	RedisURL               string
+	SessionStore           string
 	TLSKeyEncryptionKey    string
 	GitHubReleaseRepo      string
 	GitHubReleaseTag       string
@@ -36,140 +60,400 @@ type Config struct {
 	PublicDownloadCacheTTL time.Duration
 	DevMode                bool
 	MemberWriteEnabled     bool
+	WebhookURL             string
+	SMTPAddr               string
+	SMTPFrom               string
+	SMTPUsername           string
+	SMTPPassword           string
+	MetricsToken           string
+	UsageWarningPercents   []int
+	ReservedNames          []string
+	BlockedNamePatterns    []string
+	ProxyIPRPS             float64
+	ProxyIPBanThreshold    int
+	ProxyIPBanDuration     time.Duration
+	TrustForwardedFor      bool
+	InjectTraceparent      bool
+	TLSExpiryWarningDays   int
+	ReverseForwardTargets  []string
+	PassthroughHostnames   []string
+	// Fault* inject failures into agent dispatch for testing; see
+	// FaultInjection. They are refused unless DevMode is on.
+	FaultDropResponsePercent float64
+	FaultDispatchDelay       time.Duration
+	FaultKillSessionPercent  float64
 }
 
 func LoadConfigFromEnv() (Config, error) {
+	return LoadConfig("")
+}
+
+func LoadConfig(path string) (Config, error) {
+	src := configSource{path: strings.TrimSpace(path)}
+	if src.path != "" {
+		values, err := readConfigFile(src.path)
+		if err != nil {
+			return Config{}, err
+		}
+		src.values = values
+	}
+
 	cfg := Config{
-		ListenAddr:             readEnv("PROXER_LISTEN_ADDR", ":8080"),
-		TLSListenAddr:          strings.TrimSpace(os.Getenv("PROXER_TLS_LISTEN_ADDR")),
-		AgentToken:             readEnv("PROXER_AGENT_TOKEN", "dev-agent-token"),
-		PublicBaseURL:          readEnv("PROXER_PUBLIC_BASE_URL", "http://localhost:8080"),
+		ListenAddr:             src.read("PROXER_LISTEN_ADDR", ":8080"),
+		TLSListenAddr:          src.get("PROXER_TLS_LISTEN_ADDR"),
+		AdminListenAddr:        src.get("PROXER_ADMIN_LISTEN_ADDR"),
+		AdminTLSCertFile:       src.get("PROXER_ADMIN_TLS_CERT_FILE"),
+		AdminTLSKeyFile:        src.get("PROXER_ADMIN_TLS_KEY_FILE"),
+		AgentListenAddr:        src.get("PROXER_AGENT_LISTEN_ADDR"),
+		AgentTLSCertFile:       src.get("PROXER_AGENT_TLS_CERT_FILE"),
+		AgentTLSKeyFile:        src.get("PROXER_AGENT_TLS_KEY_FILE"),
+		AgentBaseURL:           src.get("PROXER_AGENT_BASE_URL"),
+		SSHListenAddr:          src.get("PROXER_SSH_LISTEN_ADDR"),
+		SSHHostKeyFile:         src.get("PROXER_SSH_HOST_KEY_FILE"),
+		AgentToken:             src.read("PROXER_AGENT_TOKEN", "dev-agent-token"),
+		MinAgentVersion:        src.get("PROXER_MIN_AGENT_VERSION"),
+		PublicBaseURL:          src.read("PROXER_PUBLIC_BASE_URL", "http://localhost:8080"),
 		PublicSignupRPM:        30,
+		AuthRateLimitRPM:       20,
+		TLSExpiryWarningDays:   14,
 		RequestTimeout:         30 * time.Second,
 		ProxyRequestTimeout:    30 * time.Second,
 		MaxRequestBodyBytes:    10 << 20,
 		MaxResponseBodyBytes:   20 << 20,
+		ClientWriteTimeout:     30 * time.Second,
+		ClientSendBufferBytes:  defaultClientSendBufferBytes,
 		MaxPendingPerSession:   1024,
 		MaxPendingGlobal:       10000,
 		PairTokenTTL:           10 * time.Minute,
-		AdminUsername:          readEnv("PROXER_ADMIN_USER", "admin"),
-		AdminPassword:          readEnv("PROXER_ADMIN_PASSWORD", "admin123"),
-		SuperAdminUsername:     strings.TrimSpace(os.Getenv("PROXER_SUPER_ADMIN_USER")),
-		SuperAdminPassword:     strings.TrimSpace(os.Getenv("PROXER_SUPER_ADMIN_PASSWORD")),
+		ConnectorSecretGrace:   24 * time.Hour,
+		TrashRetention:         7 * 24 * time.Hour,
+		AdminUsername:          src.read("PROXER_ADMIN_USER", "admin"),
+		AdminPassword:          src.read("PROXER_ADMIN_PASSWORD", "admin123"),
+		SuperAdminUsername:     src.get("PROXER_SUPER_ADMIN_USER"),
+		SuperAdminPassword:     src.get("PROXER_SUPER_ADMIN_PASSWORD"),
 		SessionTTL:             24 * time.Hour,
-		StorageDriver:          readEnv("PROXER_STORAGE_DRIVER", "sqlite"),
-		SQLitePath:             readEnv("PROXER_SQLITE_PATH", "/data/proxer.db"),
-		TLSKeyEncryptionKey:    strings.TrimSpace(os.Getenv("PROXER_TLS_KEY_ENCRYPTION_KEY")),
-		GitHubReleaseRepo:      strings.TrimSpace(os.Getenv("PROXER_GITHUB_RELEASE_REPO")),
-		GitHubReleaseTag:       strings.TrimSpace(os.Getenv("PROXER_GITHUB_RELEASE_TAG")),
-		GitHubToken:            strings.TrimSpace(os.Getenv("PROXER_GITHUB_TOKEN")),
+		StorageDriver:          src.read("PROXER_STORAGE_DRIVER", "sqlite"),
+		SQLitePath:             src.read("PROXER_SQLITE_PATH", "/data/proxer.db"),
+		RateLimitBackend:       strings.ToLower(src.read("PROXER_RATE_LIMIT_BACKEND", rateLimitBackendMemory)),
+		RedisURL:               src.get("PROXER_REDIS_URL"),
+		SessionStore:           strings.ToLower(src.read("PROXER_SESSION_STORE", "memory")),
+		TLSKeyEncryptionKey:    src.get("PROXER_TLS_KEY_ENCRYPTION_KEY"),
+		GitHubReleaseRepo:      src.get("PROXER_GITHUB_RELEASE_REPO"),
+		GitHubReleaseTag:       src.get("PROXER_GITHUB_RELEASE_TAG"),
+		GitHubToken:            src.get("PROXER_GITHUB_TOKEN"),
 		PublicDownloadCacheTTL: 15 * time.Minute,
-		DevMode:                readEnvBool("PROXER_DEV_MODE", true),
-		MemberWriteEnabled:     readEnvBool("PROXER_MEMBER_WRITE_ENABLED", true),
+		UsageWarningPercents:   []int{80, 95},
+		ProxyIPRPS:             100,
+		ProxyIPBanThreshold:    20,
+		ProxyIPBanDuration:     15 * time.Minute,
+		TrustForwardedFor:      src.readBool("PROXER_TRUST_FORWARDED_FOR", false),
+		InjectTraceparent:      src.readBool("PROXER_INJECT_TRACEPARENT", false),
+		HTTP2Enabled:           src.readBool("PROXER_HTTP2_ENABLED", true),
+		DevMode:                src.readBool("PROXER_DEV_MODE", true),
+		MemberWriteEnabled:     src.readBool("PROXER_MEMBER_WRITE_ENABLED", true),
+		WebhookURL:             src.get("PROXER_WEBHOOK_URL"),
+		SMTPAddr:               src.get("PROXER_SMTP_ADDR"),
+		SMTPFrom:               src.read("PROXER_SMTP_FROM", "proxer@localhost"),
+		SMTPUsername:           src.get("PROXER_SMTP_USERNAME"),
+		SMTPPassword:           src.get("PROXER_SMTP_PASSWORD"),
+		MetricsToken:           src.get("PROXER_METRICS_TOKEN"),
 	}
-	if explicitSignupEnabled, ok := readOptionalEnvBool("PROXER_PUBLIC_SIGNUP_ENABLED"); ok {
+	if explicitSignupEnabled, ok := src.readOptionalBool("PROXER_PUBLIC_SIGNUP_ENABLED"); ok {
 		cfg.PublicSignupEnabled = explicitSignupEnabled
 	} else {
 		cfg.PublicSignupEnabled = cfg.DevMode
 	}
 
-	if timeoutStr := strings.TrimSpace(os.Getenv("PROXER_REQUEST_TIMEOUT")); timeoutStr != "" {
+	if timeoutStr := src.get("PROXER_REQUEST_TIMEOUT"); timeoutStr != "" {
 		timeout, err := time.ParseDuration(timeoutStr)
 		if err != nil {
-			return Config{}, fmt.Errorf("parse PROXER_REQUEST_TIMEOUT: %w", err)
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_REQUEST_TIMEOUT"), err)
 		}
 		cfg.RequestTimeout = timeout
 	}
-	if timeoutStr := strings.TrimSpace(os.Getenv("PROXER_PROXY_REQUEST_TIMEOUT")); timeoutStr != "" {
+	if timeoutStr := src.get("PROXER_PROXY_REQUEST_TIMEOUT"); timeoutStr != "" {
 		timeout, err := time.ParseDuration(timeoutStr)
 		if err != nil {
-			return Config{}, fmt.Errorf("parse PROXER_PROXY_REQUEST_TIMEOUT: %w", err)
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_PROXY_REQUEST_TIMEOUT"), err)
 		}
 		cfg.ProxyRequestTimeout = timeout
 	}
-	if sessionTTLStr := strings.TrimSpace(os.Getenv("PROXER_SESSION_TTL")); sessionTTLStr != "" {
+	if sessionTTLStr := src.get("PROXER_SESSION_TTL"); sessionTTLStr != "" {
 		sessionTTL, err := time.ParseDuration(sessionTTLStr)
 		if err != nil {
-			return Config{}, fmt.Errorf("parse PROXER_SESSION_TTL: %w", err)
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_SESSION_TTL"), err)
 		}
 		cfg.SessionTTL = sessionTTL
 	}
-	if pairTokenTTLStr := strings.TrimSpace(os.Getenv("PROXER_PAIR_TOKEN_TTL")); pairTokenTTLStr != "" {
+	if pairTokenTTLStr := src.get("PROXER_PAIR_TOKEN_TTL"); pairTokenTTLStr != "" {
 		ttl, err := time.ParseDuration(pairTokenTTLStr)
 		if err != nil {
-			return Config{}, fmt.Errorf("parse PROXER_PAIR_TOKEN_TTL: %w", err)
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_PAIR_TOKEN_TTL"), err)
 		}
 		cfg.PairTokenTTL = ttl
 	}
-	if maxReqBodyStr := strings.TrimSpace(os.Getenv("PROXER_MAX_REQUEST_BODY_BYTES")); maxReqBodyStr != "" {
+	if secretTTLStr := src.get("PROXER_CONNECTOR_SECRET_TTL"); secretTTLStr != "" {
+		ttl, err := time.ParseDuration(secretTTLStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_CONNECTOR_SECRET_TTL"), err)
+		}
+		cfg.ConnectorSecretTTL = ttl
+	}
+	if secretGraceStr := src.get("PROXER_CONNECTOR_SECRET_GRACE"); secretGraceStr != "" {
+		grace, err := time.ParseDuration(secretGraceStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_CONNECTOR_SECRET_GRACE"), err)
+		}
+		cfg.ConnectorSecretGrace = grace
+	}
+	if trashRetentionStr := src.get("PROXER_TRASH_RETENTION"); trashRetentionStr != "" {
+		retention, err := time.ParseDuration(trashRetentionStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_TRASH_RETENTION"), err)
+		}
+		cfg.TrashRetention = retention
+	}
+	if maxReqBodyStr := src.get("PROXER_MAX_REQUEST_BODY_BYTES"); maxReqBodyStr != "" {
 		value, err := strconv.ParseInt(maxReqBodyStr, 10, 64)
 		if err != nil {
-			return Config{}, fmt.Errorf("parse PROXER_MAX_REQUEST_BODY_BYTES: %w", err)
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_MAX_REQUEST_BODY_BYTES"), err)
 		}
 		cfg.MaxRequestBodyBytes = value
 	}
-	if maxRespBodyStr := strings.TrimSpace(os.Getenv("PROXER_MAX_RESPONSE_BODY_BYTES")); maxRespBodyStr != "" {
+	if maxRespBodyStr := src.get("PROXER_MAX_RESPONSE_BODY_BYTES"); maxRespBodyStr != "" {
 		value, err := strconv.ParseInt(maxRespBodyStr, 10, 64)
 		if err != nil {
-			return Config{}, fmt.Errorf("parse PROXER_MAX_RESPONSE_BODY_BYTES: %w", err)
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_MAX_RESPONSE_BODY_BYTES"), err)
 		}
 		cfg.MaxResponseBodyBytes = value
 	}
-	if maxSessionPendingStr := strings.TrimSpace(os.Getenv("PROXER_MAX_PENDING_PER_SESSION")); maxSessionPendingStr != "" {
+	if writeTimeoutStr := src.get("PROXER_CLIENT_WRITE_TIMEOUT"); writeTimeoutStr != "" {
+		timeout, err := time.ParseDuration(writeTimeoutStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_CLIENT_WRITE_TIMEOUT"), err)
+		}
+		cfg.ClientWriteTimeout = timeout
+	}
+	if sendBufferStr := src.get("PROXER_CLIENT_SEND_BUFFER_BYTES"); sendBufferStr != "" {
+		value, err := strconv.Atoi(sendBufferStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_CLIENT_SEND_BUFFER_BYTES"), err)
+		}
+		cfg.ClientSendBufferBytes = value
+	}
+	if maxSessionPendingStr := src.get("PROXER_MAX_PENDING_PER_SESSION"); maxSessionPendingStr != "" {
 		value, err := strconv.Atoi(maxSessionPendingStr)
 		if err != nil {
-			return Config{}, fmt.Errorf("parse PROXER_MAX_PENDING_PER_SESSION: %w", err)
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_MAX_PENDING_PER_SESSION"), err)
 		}
 		cfg.MaxPendingPerSession = value
 	}
-	if maxGlobalPendingStr := strings.TrimSpace(os.Getenv("PROXER_MAX_PENDING_GLOBAL")); maxGlobalPendingStr != "" {
+	if maxGlobalPendingStr := src.get("PROXER_MAX_PENDING_GLOBAL"); maxGlobalPendingStr != "" {
 		value, err := strconv.Atoi(maxGlobalPendingStr)
 		if err != nil {
-			return Config{}, fmt.Errorf("parse PROXER_MAX_PENDING_GLOBAL: %w", err)
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_MAX_PENDING_GLOBAL"), err)
 		}
 		cfg.MaxPendingGlobal = value
 	}
-	if signupRPMRaw := strings.TrimSpace(os.Getenv("PROXER_PUBLIC_SIGNUP_RPM")); signupRPMRaw != "" {
+	if signupRPMRaw := src.get("PROXER_PUBLIC_SIGNUP_RPM"); signupRPMRaw != "" {
 		value, err := strconv.Atoi(signupRPMRaw)
 		if err != nil {
-			return Config{}, fmt.Errorf("parse PROXER_PUBLIC_SIGNUP_RPM: %w", err)
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_PUBLIC_SIGNUP_RPM"), err)
 		}
 		cfg.PublicSignupRPM = value
 	}
-	if downloadTTLRaw := strings.TrimSpace(os.Getenv("PROXER_PUBLIC_DOWNLOAD_CACHE_TTL")); downloadTTLRaw != "" {
+	if authRPMRaw := src.get("PROXER_AUTH_RATE_LIMIT_RPM"); authRPMRaw != "" {
+		value, err := strconv.Atoi(authRPMRaw)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_AUTH_RATE_LIMIT_RPM"), err)
+		}
+		cfg.AuthRateLimitRPM = value
+	}
+	if minProtocolRaw := src.get("PROXER_MIN_AGENT_PROTOCOL"); minProtocolRaw != "" {
+		value, err := strconv.Atoi(minProtocolRaw)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_MIN_AGENT_PROTOCOL"), err)
+		}
+		cfg.MinAgentProtocol = value
+	}
+	if expiryDaysRaw := src.get("PROXER_TLS_EXPIRY_WARNING_DAYS"); expiryDaysRaw != "" {
+		value, err := strconv.Atoi(expiryDaysRaw)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_TLS_EXPIRY_WARNING_DAYS"), err)
+		}
+		cfg.TLSExpiryWarningDays = value
+	}
+	if downloadTTLRaw := src.get("PROXER_PUBLIC_DOWNLOAD_CACHE_TTL"); downloadTTLRaw != "" {
 		value, err := time.ParseDuration(downloadTTLRaw)
 		if err != nil {
-			return Config{}, fmt.Errorf("parse PROXER_PUBLIC_DOWNLOAD_CACHE_TTL: %w", err)
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_PUBLIC_DOWNLOAD_CACHE_TTL"), err)
 		}
 		cfg.PublicDownloadCacheTTL = value
 	}
+	if thresholdsRaw := src.get("PROXER_USAGE_WARNING_THRESHOLDS"); thresholdsRaw != "" {
+		values, err := parsePercentList(thresholdsRaw)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_USAGE_WARNING_THRESHOLDS"), err)
+		}
+		cfg.UsageWarningPercents = values
+	}
+	if ipRPSRaw := src.get("PROXER_PROXY_IP_RPS"); ipRPSRaw != "" {
+		value, err := strconv.ParseFloat(ipRPSRaw, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_PROXY_IP_RPS"), err)
+		}
+		cfg.ProxyIPRPS = value
+	}
+	if banThresholdRaw := src.get("PROXER_PROXY_IP_BAN_THRESHOLD"); banThresholdRaw != "" {
+		value, err := strconv.Atoi(banThresholdRaw)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_PROXY_IP_BAN_THRESHOLD"), err)
+		}
+		cfg.ProxyIPBanThreshold = value
+	}
+	if banDurationRaw := src.get("PROXER_PROXY_IP_BAN_DURATION"); banDurationRaw != "" {
+		value, err := time.ParseDuration(banDurationRaw)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_PROXY_IP_BAN_DURATION"), err)
+		}
+		cfg.ProxyIPBanDuration = value
+	}
+	for _, fault := range []struct {
+		key   string
+		value *float64
+	}{
+		{"PROXER_FAULT_DROP_RESPONSE_PERCENT", &cfg.FaultDropResponsePercent},
+		{"PROXER_FAULT_KILL_SESSION_PERCENT", &cfg.FaultKillSessionPercent},
+	} {
+		raw := src.get(fault.key)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name(fault.key), err)
+		}
+		if value < 0 || value > 100 {
+			return Config{}, fmt.Errorf("%s must be between 0 and 100", src.name(fault.key))
+		}
+		*fault.value = value
+	}
+	if dispatchDelayRaw := src.get("PROXER_FAULT_DISPATCH_DELAY"); dispatchDelayRaw != "" {
+		value, err := time.ParseDuration(dispatchDelayRaw)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_FAULT_DISPATCH_DELAY"), err)
+		}
+		if value < 0 {
+			return Config{}, fmt.Errorf("%s must be >= 0", src.name("PROXER_FAULT_DISPATCH_DELAY"))
+		}
+		cfg.FaultDispatchDelay = value
+	}
+	if reservedRaw, ok := src.lookup("PROXER_RESERVED_NAMES"); ok {
+		cfg.ReservedNames = splitCommaList(reservedRaw)
+	}
+	cfg.BlockedNamePatterns = splitCommaList(src.get("PROXER_BLOCKED_NAME_PATTERNS"))
+	if _, err := NewNamePolicy(cfg.ReservedNames, cfg.BlockedNamePatterns); err != nil {
+		return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_BLOCKED_NAME_PATTERNS"), err)
+	}
+
+	cfg.ReverseForwardTargets = splitCommaList(src.get("PROXER_REVERSE_FORWARD_TARGETS"))
+	if err := validateReverseForwardPatterns(cfg.ReverseForwardTargets); err != nil {
+		return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_REVERSE_FORWARD_TARGETS"), err)
+	}
+
+	for _, raw := range splitCommaList(src.get("PROXER_TLS_PASSTHROUGH_HOSTNAMES")) {
+		hostname, err := normalizeDomainHostname(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: %w", src.name("PROXER_TLS_PASSTHROUGH_HOSTNAMES"), err)
+		}
+		cfg.PassthroughHostnames = append(cfg.PassthroughHostnames, hostname)
+	}
 
 	if strings.TrimSpace(cfg.AgentToken) == "" {
-		return Config{}, fmt.Errorf("PROXER_AGENT_TOKEN cannot be empty")
+		return Config{}, fmt.Errorf("%s cannot be empty", src.name("PROXER_AGENT_TOKEN"))
 	}
 	if strings.TrimSpace(cfg.AdminPassword) == "" {
-		return Config{}, fmt.Errorf("PROXER_ADMIN_PASSWORD cannot be empty")
+		return Config{}, fmt.Errorf("%s cannot be empty", src.name("PROXER_ADMIN_PASSWORD"))
 	}
 	if cfg.MaxRequestBodyBytes <= 0 {
-		return Config{}, fmt.Errorf("PROXER_MAX_REQUEST_BODY_BYTES must be > 0")
+		return Config{}, fmt.Errorf("%s must be > 0", src.name("PROXER_MAX_REQUEST_BODY_BYTES"))
 	}
 	if cfg.MaxResponseBodyBytes <= 0 {
-		return Config{}, fmt.Errorf("PROXER_MAX_RESPONSE_BODY_BYTES must be > 0")
+		return Config{}, fmt.Errorf("%s must be > 0", src.name("PROXER_MAX_RESPONSE_BODY_BYTES"))
+	}
+	if cfg.ClientWriteTimeout < 0 {
+		return Config{}, fmt.Errorf("%s must be >= 0", src.name("PROXER_CLIENT_WRITE_TIMEOUT"))
+	}
+	if cfg.ClientSendBufferBytes < minClientSendBufferBytes || cfg.ClientSendBufferBytes > maxClientSendBufferBytes {
+		return Config{}, fmt.Errorf("%s must be between %d and %d", src.name("PROXER_CLIENT_SEND_BUFFER_BYTES"), minClientSendBufferBytes, maxClientSendBufferBytes)
 	}
 	if cfg.MaxPendingPerSession <= 0 {
-		return Config{}, fmt.Errorf("PROXER_MAX_PENDING_PER_SESSION must be > 0")
+		return Config{}, fmt.Errorf("%s must be > 0", src.name("PROXER_MAX_PENDING_PER_SESSION"))
 	}
 	if cfg.MaxPendingGlobal <= 0 {
-		return Config{}, fmt.Errorf("PROXER_MAX_PENDING_GLOBAL must be > 0")
+		return Config{}, fmt.Errorf("%s must be > 0", src.name("PROXER_MAX_PENDING_GLOBAL"))
 	}
 	if cfg.PublicSignupRPM <= 0 {
-		return Config{}, fmt.Errorf("PROXER_PUBLIC_SIGNUP_RPM must be > 0")
+		return Config{}, fmt.Errorf("%s must be > 0", src.name("PROXER_PUBLIC_SIGNUP_RPM"))
+	}
+	if cfg.AuthRateLimitRPM <= 0 {
+		return Config{}, fmt.Errorf("%s must be > 0", src.name("PROXER_AUTH_RATE_LIMIT_RPM"))
+	}
+	if cfg.TrashRetention <= 0 {
+		return Config{}, fmt.Errorf("%s must be > 0", src.name("PROXER_TRASH_RETENTION"))
+	}
+	if cfg.ConnectorSecretTTL < 0 {
+		return Config{}, fmt.Errorf("%s must be >= 0", src.name("PROXER_CONNECTOR_SECRET_TTL"))
+	}
+	if cfg.ConnectorSecretGrace < 0 {
+		return Config{}, fmt.Errorf("%s must be >= 0", src.name("PROXER_CONNECTOR_SECRET_GRACE"))
+	}
+	if cfg.TLSExpiryWarningDays <= 0 {
+		return Config{}, fmt.Errorf("%s must be > 0", src.name("PROXER_TLS_EXPIRY_WARNING_DAYS"))
 	}
 	if cfg.PublicDownloadCacheTTL <= 0 {
-		return Config{}, fmt.Errorf("PROXER_PUBLIC_DOWNLOAD_CACHE_TTL must be > 0")
+		return Config{}, fmt.Errorf("%s must be > 0", src.name("PROXER_PUBLIC_DOWNLOAD_CACHE_TTL"))
+	}
+	if _, ok := protocol.ParseVersion(cfg.MinAgentVersion); cfg.MinAgentVersion != "" && !ok {
+		return Config{}, fmt.Errorf("%s must be a version such as 1.4.0", src.name("PROXER_MIN_AGENT_VERSION"))
+	}
+	if cfg.MinAgentProtocol < 0 || cfg.MinAgentProtocol > protocol.ProtocolVersion {
+		return Config{}, fmt.Errorf("%s must be between 0 and %d", src.name("PROXER_MIN_AGENT_PROTOCOL"), protocol.ProtocolVersion)
+	}
+	if cfg.ProxyIPRPS < 0 {
+		return Config{}, fmt.Errorf("%s must be >= 0", src.name("PROXER_PROXY_IP_RPS"))
+	}
+	if cfg.ProxyIPBanThreshold < 0 {
+		return Config{}, fmt.Errorf("%s must be >= 0", src.name("PROXER_PROXY_IP_BAN_THRESHOLD"))
+	}
+	if cfg.ProxyIPBanDuration <= 0 {
+		return Config{}, fmt.Errorf("%s must be > 0", src.name("PROXER_PROXY_IP_BAN_DURATION"))
+	}
+	if err := validateListenerConfig(cfg, src); err != nil {
+		return Config{}, err
 	}
 	if cfg.StorageDriver != "memory" && cfg.StorageDriver != "sqlite" {
-		return Config{}, fmt.Errorf("PROXER_STORAGE_DRIVER must be memory or sqlite")
+		return Config{}, fmt.Errorf("%s must be memory or sqlite", src.name("PROXER_STORAGE_DRIVER"))
+	}
+	if cfg.RateLimitBackend != rateLimitBackendMemory && cfg.RateLimitBackend != rateLimitBackendRedis {
+		return Config{}, fmt.Errorf("%s must be memory or redis", src.name("PROXER_RATE_LIMIT_BACKEND"))
+	}
+	if cfg.RateLimitBackend == rateLimitBackendRedis && cfg.RedisURL == "" {
+		return Config{}, fmt.Errorf("%s is required when %s is redis", src.name("PROXER_REDIS_URL"), src.name("PROXER_RATE_LIMIT_BACKEND"))
+	}
+	switch cfg.SessionStore {
+	case "memory", "sqlite":
+	case "redis":
+		if cfg.RedisURL == "" {
+			return Config{}, fmt.Errorf("%s is required when %s is redis", src.name("PROXER_REDIS_URL"), src.name("PROXER_SESSION_STORE"))
+		}
+	default:
+		return Config{}, fmt.Errorf("%s must be memory, sqlite or redis", src.name("PROXER_SESSION_STORE"))
+	}
+	if cfg.RedisURL != "" {
+		if _, err := redis.New(cfg.RedisURL); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", src.name("PROXER_REDIS_URL"), err)
+		}
 	}
 	if strings.TrimSpace(cfg.SuperAdminUsername) == "" {
 		cfg.SuperAdminUsername = cfg.AdminUsername
@@ -178,44 +462,83 @@ func LoadConfigFromEnv() (Config, error) {
 		cfg.SuperAdminPassword = cfg.AdminPassword
 	}
 	if !cfg.DevMode && (strings.TrimSpace(cfg.SuperAdminUsername) == "" || strings.TrimSpace(cfg.SuperAdminPassword) == "") {
-		return Config{}, fmt.Errorf("PROXER_SUPER_ADMIN_USER and PROXER_SUPER_ADMIN_PASSWORD are required when PROXER_DEV_MODE=false")
+		return Config{}, fmt.Errorf("%s and PROXER_SUPER_ADMIN_PASSWORD are required when PROXER_DEV_MODE=false", src.name("PROXER_SUPER_ADMIN_USER"))
+	}
+	if !cfg.DevMode && cfg.faultInjection().Active() {
+		return Config{}, fmt.Errorf("PROXER_FAULT_* settings require PROXER_DEV_MODE=true")
 	}
 	return cfg, nil
 }
 
-func readEnv(key, fallback string) string {
-	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
-		return value
+func validateListenerConfig(cfg Config, src configSource) error {
+	addrs := map[string]string{}
+	for _, listener := range []struct {
+		key  string
+		addr string
+	}{
+		{"PROXER_LISTEN_ADDR", cfg.ListenAddr},
+		{"PROXER_TLS_LISTEN_ADDR", cfg.TLSListenAddr},
+		{"PROXER_ADMIN_LISTEN_ADDR", cfg.AdminListenAddr},
+		{"PROXER_AGENT_LISTEN_ADDR", cfg.AgentListenAddr},
+	} {
+		if listener.addr == "" {
+			continue
+		}
+		if other, ok := addrs[listener.addr]; ok {
+			return fmt.Errorf("%s must differ from %s", src.name(listener.key), src.name(other))
+		}
+		addrs[listener.addr] = listener.key
 	}
-	return fallback
+	for _, listener := range []struct {
+		prefix string
+		addr   string
+		cert   string
+		key    string
+	}{
+		{"PROXER_ADMIN", cfg.AdminListenAddr, cfg.AdminTLSCertFile, cfg.AdminTLSKeyFile},
+		{"PROXER_AGENT", cfg.AgentListenAddr, cfg.AgentTLSCertFile, cfg.AgentTLSKeyFile},
+	} {
+		if (listener.cert == "") != (listener.key == "") {
+			return fmt.Errorf("%s and %s must be set together", src.name(listener.prefix+"_TLS_CERT_FILE"), src.name(listener.prefix+"_TLS_KEY_FILE"))
+		}
+		if listener.cert != "" && listener.addr == "" {
+			return fmt.Errorf("%s requires %s", src.name(listener.prefix+"_TLS_CERT_FILE"), src.name(listener.prefix+"_LISTEN_ADDR"))
+		}
+	}
+	return nil
 }
 
-func readEnvBool(key string, fallback bool) bool {
-	value := strings.TrimSpace(strings.ToLower(os.Getenv(key)))
-	if value == "" {
-		return fallback
-	}
-	switch value {
-	case "1", "true", "yes", "y", "on":
-		return true
-	case "0", "false", "no", "n", "off":
-		return false
-	default:
-		return fallback
+func splitCommaList(raw string) []string {
+	values := make([]string, 0)
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
 	}
+	return values
 }
 
-func readOptionalEnvBool(key string) (bool, bool) {
-	value := strings.TrimSpace(strings.ToLower(os.Getenv(key)))
-	if value == "" {
-		return false, false
-	}
-	switch value {
-	case "1", "true", "yes", "y", "on":
-		return true, true
-	case "0", "false", "no", "n", "off":
-		return false, true
-	default:
-		return false, false
+func parsePercentList(raw string) ([]int, error) {
+	values := make([]int, 0)
+	seen := make(map[int]struct{})
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSuffix(strings.TrimSpace(part), "%")
+		if part == "" {
+			continue
+		}
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		if value < 1 || value > 99 {
+			return nil, fmt.Errorf("threshold %d must be between 1 and 99", value)
+		}
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		values = append(values, value)
 	}
+	sort.Ints(values)
+	return values, nil
 }
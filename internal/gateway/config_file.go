@@ -0,0 +1,374 @@
+package gateway
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type configValueKind int
+
+const (
+	configString configValueKind = iota
+	configBool
+	configInt
+	configFloat
+	configDuration
+	configList
+)
+
+var configFileKeys = map[string]configValueKind{
+	"listen_addr":                 configString,
+	"tls_listen_addr":             configString,
+	"http2_enabled":               configBool,
+	"admin_listen_addr":           configString,
+	"admin_tls_cert_file":         configString,
+	"admin_tls_key_file":          configString,
+	"agent_listen_addr":           configString,
+	"agent_tls_cert_file":         configString,
+	"agent_tls_key_file":          configString,
+	"agent_base_url":              configString,
+	"ssh_listen_addr":             configString,
+	"ssh_host_key_file":           configString,
+	"agent_token":                 configString,
+	"min_agent_version":           configString,
+	"min_agent_protocol":          configInt,
+	"public_base_url":             configString,
+	"public_signup_enabled":       configBool,
+	"public_signup_rpm":           configInt,
+	"auth_rate_limit_rpm":         configInt,
+	"request_timeout":             configDuration,
+	"proxy_request_timeout":       configDuration,
+	"max_request_body_bytes":      configInt,
+	"max_response_body_bytes":     configInt,
+	"client_write_timeout":        configDuration,
+	"client_send_buffer_bytes":    configInt,
+	"max_pending_per_session":     configInt,
+	"max_pending_global":          configInt,
+	"pair_token_ttl":              configDuration,
+	"connector_secret_ttl":        configDuration,
+	"connector_secret_grace":      configDuration,
+	"trash_retention":             configDuration,
+	"admin_user":                  configString,
+	"admin_password":              configString,
+	"super_admin_user":            configString,
+	"super_admin_password":        configString,
+	"session_ttl":                 configDuration,
+	"storage_driver":              configString,
+	"sqlite_path":                 configString,
+	"rate_limit_backend":          configString,
+	"redis_url":                   configString,
+	"session_store":               configString,
+	"tls_key_encryption_key":      configString,
+	"github_release_repo":         configString,
+	"github_release_tag":          configString,
+	"github_token":                configString,
+	"public_download_cache_ttl":   configDuration,
+	"dev_mode":                    configBool,
+	"member_write_enabled":        configBool,
+	"webhook_url":                 configString,
+	"smtp_addr":                   configString,
+	"smtp_from":                   configString,
+	"smtp_username":               configString,
+	"smtp_password":               configString,
+	"metrics_token":               configString,
+	"usage_warning_thresholds":    configList,
+	"reserved_names":              configList,
+	"blocked_name_patterns":       configList,
+	"reverse_forward_targets":     configList,
+	"tls_passthrough_hostnames":   configList,
+	"proxy_ip_rps":                configFloat,
+	"proxy_ip_ban_threshold":      configInt,
+	"proxy_ip_ban_duration":       configDuration,
+	"trust_forwarded_for":         configBool,
+	"inject_traceparent":          configBool,
+	"tls_expiry_warning_days":     configInt,
+	"fault_drop_response_percent": configFloat,
+	"fault_dispatch_delay":        configDuration,
+	"fault_kill_session_percent":  configFloat,
+}
+
+type configFileValue struct {
+	key   string
+	value string
+	line  int
+}
+
+// configSource resolves settings from the environment first and the config
+// file second, and remembers where a value came from so errors name the key
+// the operator actually wrote.
+type configSource struct {
+	path   string
+	values map[string]configFileValue
+}
+
+func (src configSource) lookup(envKey string) (string, bool) {
+	envValue, envSet := os.LookupEnv(envKey)
+	if strings.TrimSpace(envValue) != "" {
+		return envValue, true
+	}
+	if entry, ok := src.values[envKey]; ok {
+		return entry.value, true
+	}
+	return envValue, envSet
+}
+
+func (src configSource) get(envKey string) string {
+	value, _ := src.lookup(envKey)
+	return strings.TrimSpace(value)
+}
+
+func (src configSource) read(envKey, fallback string) string {
+	if value := src.get(envKey); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func (src configSource) readBool(envKey string, fallback bool) bool {
+	if value, ok := parseConfigBool(src.get(envKey)); ok {
+		return value
+	}
+	return fallback
+}
+
+func (src configSource) readOptionalBool(envKey string) (bool, bool) {
+	return parseConfigBool(src.get(envKey))
+}
+
+func (src configSource) name(envKey string) string {
+	if strings.TrimSpace(os.Getenv(envKey)) == "" {
+		if entry, ok := src.values[envKey]; ok {
+			return fmt.Sprintf("%s (%s:%d)", entry.key, src.path, entry.line)
+		}
+	}
+	return envKey
+}
+
+func parseConfigBool(raw string) (bool, bool) {
+	switch strings.TrimSpace(strings.ToLower(raw)) {
+	case "1", "true", "yes", "y", "on":
+		return true, true
+	case "0", "false", "no", "n", "off":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+func configEnvName(key string) string {
+	return "PROXER_" + strings.ToUpper(key)
+}
+
+func readConfigFile(path string) (map[string]configFileValue, error) {
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	var entries []configFileValue
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		entries, err = parseYAMLConfig(payload)
+	case ".toml":
+		entries, err = parseTOMLConfig(payload)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported extension (use .yaml, .yml, .json or .toml)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	values := make(map[string]configFileValue, len(entries))
+	for _, entry := range entries {
+		kind, ok := configFileKeys[entry.key]
+		if !ok {
+			return nil, fmt.Errorf("config file %s:%d: unknown key %q", path, entry.line, entry.key)
+		}
+		envKey := configEnvName(entry.key)
+		if previous, ok := values[envKey]; ok {
+			return nil, fmt.Errorf("config file %s:%d: duplicate key %q (first set on line %d)", path, entry.line, entry.key, previous.line)
+		}
+		if err := validateConfigValue(kind, entry.value); err != nil {
+			return nil, fmt.Errorf("config file %s:%d: %s: %w", path, entry.line, entry.key, err)
+		}
+		values[envKey] = entry
+	}
+	return values, nil
+}
+
+func validateConfigValue(kind configValueKind, value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	switch kind {
+	case configBool:
+		if _, ok := parseConfigBool(value); !ok {
+			return fmt.Errorf("expected a boolean, got %q", value)
+		}
+	case configInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("expected an integer, got %q", value)
+		}
+	case configFloat:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("expected a number, got %q", value)
+		}
+	case configDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("expected a duration such as 30s or 15m, got %q", value)
+		}
+	}
+	return nil
+}
+
+func parseYAMLConfig(payload []byte) ([]configFileValue, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(payload, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping of settings", root.Line)
+	}
+
+	entries := make([]configFileValue, 0, len(root.Content)/2)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		keyNode, valueNode := root.Content[i], root.Content[i+1]
+		entry := configFileValue{key: strings.TrimSpace(keyNode.Value), line: keyNode.Line}
+		switch valueNode.Kind {
+		case yaml.ScalarNode:
+			if valueNode.Tag != "!!null" {
+				entry.value = valueNode.Value
+			}
+		case yaml.SequenceNode:
+			items := make([]string, 0, len(valueNode.Content))
+			for _, item := range valueNode.Content {
+				if item.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("line %d: %s: list items must be plain values", item.Line, entry.key)
+				}
+				items = append(items, item.Value)
+			}
+			entry.value = strings.Join(items, ",")
+		default:
+			return nil, fmt.Errorf("line %d: %s: nested sections are not supported", valueNode.Line, entry.key)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func parseTOMLConfig(payload []byte) ([]configFileValue, error) {
+	lines := strings.Split(string(payload), "\n")
+	entries := make([]configFileValue, 0)
+	for index := 0; index < len(lines); index++ {
+		lineNumber := index + 1
+		line := strings.TrimSpace(stripTOMLComment(lines[index]))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("line %d: tables are not supported; use top-level keys", lineNumber)
+		}
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNumber)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		raw = strings.TrimSpace(raw)
+		// Arrays may span several lines until the closing bracket.
+		for strings.HasPrefix(raw, "[") && !strings.HasSuffix(raw, "]") && index+1 < len(lines) {
+			index++
+			raw += " " + strings.TrimSpace(stripTOMLComment(lines[index]))
+		}
+
+		value, err := parseTOMLValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", lineNumber, key, err)
+		}
+		entries = append(entries, configFileValue{key: key, value: value, line: lineNumber})
+	}
+	return entries, nil
+}
+
+func parseTOMLValue(raw string) (string, error) {
+	switch {
+	case raw == "":
+		return "", fmt.Errorf("missing value")
+	case strings.HasPrefix(raw, "["):
+		if !strings.HasSuffix(raw, "]") {
+			return "", fmt.Errorf("unterminated array")
+		}
+		items := make([]string, 0)
+		for _, part := range splitTOMLArray(strings.TrimSuffix(strings.TrimPrefix(raw, "["), "]")) {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			item, err := parseTOMLValue(part)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, item)
+		}
+		return strings.Join(items, ","), nil
+	case strings.HasPrefix(raw, `"`):
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return value, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	default:
+		return strings.ReplaceAll(raw, "_", ""), nil
+	}
+}
+
+func stripTOMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func splitTOMLArray(raw string) []string {
+	parts := make([]string, 0)
+	var quote rune
+	start := 0
+	for i, r := range raw {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			parts = append(parts, raw[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, raw[start:])
+}
@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigFromYAMLFile(t *testing.T) {
+	path := writeConfigFile(t, "gateway.yaml", `
+listen_addr: ":9090"
+storage_driver: memory
+request_timeout: 45s
+max_pending_global: 500
+dev_mode: false
+super_admin_user: root
+super_admin_password: secret
+reserved_names: [admin, billing]
+usage_warning_thresholds:
+  - 50
+  - 90
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.ListenAddr != ":9090" || cfg.StorageDriver != "memory" || cfg.MaxPendingGlobal != 500 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if cfg.RequestTimeout != 45*time.Second || cfg.DevMode {
+		t.Fatalf("expected request_timeout=45s dev_mode=false, got %s %t", cfg.RequestTimeout, cfg.DevMode)
+	}
+	if strings.Join(cfg.ReservedNames, ",") != "admin,billing" {
+		t.Fatalf("unexpected reserved names: %v", cfg.ReservedNames)
+	}
+	if len(cfg.UsageWarningPercents) != 2 || cfg.UsageWarningPercents[0] != 50 {
+		t.Fatalf("unexpected usage thresholds: %v", cfg.UsageWarningPercents)
+	}
+}
+
+func TestLoadConfigFromTOMLFile(t *testing.T) {
+	path := writeConfigFile(t, "gateway.toml", `
+# gateway settings
+listen_addr = ":9191"
+storage_driver = "memory"
+proxy_ip_rps = 2.5
+blocked_name_patterns = [
+  "^spam",
+  "casino", # trailing comment
+]
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.ListenAddr != ":9191" || cfg.ProxyIPRPS != 2.5 {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	if strings.Join(cfg.BlockedNamePatterns, ",") != "^spam,casino" {
+		t.Fatalf("unexpected blocked patterns: %v", cfg.BlockedNamePatterns)
+	}
+}
+
+func TestLoadConfigEnvOverridesFile(t *testing.T) {
+	path := writeConfigFile(t, "gateway.yaml", "listen_addr: \":9090\"\nstorage_driver: memory\n")
+	t.Setenv("PROXER_LISTEN_ADDR", ":7070")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.ListenAddr != ":7070" {
+		t.Fatalf("expected env var to override file, got %q", cfg.ListenAddr)
+	}
+}
+
+func TestLoadConfigErrorsNameFileKey(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "unknown key", content: "storage_driver: memory\nlisten_adr: \":1\"\n", want: `:2: unknown key "listen_adr"`},
+		{name: "bad duration", content: "storage_driver: memory\nsession_ttl: soon\n", want: ":2: session_ttl: expected a duration"},
+		{name: "range check", content: "storage_driver: memory\nmax_pending_global: 0\n", want: "max_pending_global ("},
+		{name: "bad driver", content: "storage_driver: postgres\n", want: "storage_driver ("},
+		{name: "fault percent", content: "storage_driver: memory\nfault_drop_response_percent: 150\n", want: "fault_drop_response_percent ("},
+		{name: "faults outside dev mode", content: "storage_driver: memory\ndev_mode: false\nfault_dispatch_delay: 1s\n", want: "require PROXER_DEV_MODE=true"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfigFile(t, "gateway.yaml", tc.content)
+			_, err := LoadConfig(path)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type ConfigLoader func() (Config, error)
+
+var errConfigReloadUnavailable = errors.New("config reload is not configured for this gateway")
+
+type configReloadField struct {
+	key        string
+	reloadable bool
+	value      func(Config) any
+}
+
+// configReloadFields lists every setting with whether a running gateway can
+// apply a new value. Listeners, storage, credentials seeded at startup and
+// the release download cache need a restart.
+var configReloadFields = []configReloadField{
+	{"listen_addr", false, func(c Config) any { return c.ListenAddr }},
+	{"tls_listen_addr", false, func(c Config) any { return c.TLSListenAddr }},
+	{"http2_enabled", false, func(c Config) any { return c.HTTP2Enabled }},
+	{"admin_listen_addr", false, func(c Config) any { return c.AdminListenAddr }},
+	{"admin_tls_cert_file", false, func(c Config) any { return c.AdminTLSCertFile }},
+	{"admin_tls_key_file", false, func(c Config) any { return c.AdminTLSKeyFile }},
+	{"agent_listen_addr", false, func(c Config) any { return c.AgentListenAddr }},
+	{"agent_tls_cert_file", false, func(c Config) any { return c.AgentTLSCertFile }},
+	{"agent_tls_key_file", false, func(c Config) any { return c.AgentTLSKeyFile }},
+	{"ssh_listen_addr", false, func(c Config) any { return c.SSHListenAddr }},
+	{"ssh_host_key_file", false, func(c Config) any { return c.SSHHostKeyFile }},
+	{"agent_token", false, func(c Config) any { return c.AgentToken }},
+	{"storage_driver", false, func(c Config) any { return c.StorageDriver }},
+	{"sqlite_path", false, func(c Config) any { return c.SQLitePath }},
+	{"rate_limit_backend", false, func(c Config) any { return c.RateLimitBackend }},
+	{"redis_url", false, func(c Config) any { return c.RedisURL }},
+	{"session_store", false, func(c Config) any { return c.SessionStore }},
+	{"tls_key_encryption_key", false, func(c Config) any { return c.TLSKeyEncryptionKey }},
+	{"admin_user", false, func(c Config) any { return c.AdminUsername }},
+	{"admin_password", false, func(c Config) any { return c.AdminPassword }},
+	{"super_admin_user", false, func(c Config) any { return c.SuperAdminUsername }},
+	{"super_admin_password", false, func(c Config) any { return c.SuperAdminPassword }},
+	{"github_release_repo", false, func(c Config) any { return c.GitHubReleaseRepo }},
+	{"github_release_tag", false, func(c Config) any { return c.GitHubReleaseTag }},
+	{"github_token", false, func(c Config) any { return c.GitHubToken }},
+	{"public_download_cache_ttl", false, func(c Config) any { return c.PublicDownloadCacheTTL }},
+	{"public_base_url", true, func(c Config) any { return c.PublicBaseURL }},
+	{"agent_base_url", true, func(c Config) any { return c.AgentBaseURL }},
+	{"min_agent_version", true, func(c Config) any { return c.MinAgentVersion }},
+	{"min_agent_protocol", true, func(c Config) any { return c.MinAgentProtocol }},
+	{"request_timeout", true, func(c Config) any { return c.RequestTimeout }},
+	{"proxy_request_timeout", true, func(c Config) any { return c.ProxyRequestTimeout }},
+	{"max_request_body_bytes", true, func(c Config) any { return c.MaxRequestBodyBytes }},
+	{"max_response_body_bytes", true, func(c Config) any { return c.MaxResponseBodyBytes }},
+	{"client_write_timeout", true, func(c Config) any { return c.ClientWriteTimeout }},
+	{"client_send_buffer_bytes", true, func(c Config) any { return c.ClientSendBufferBytes }},
+	{"max_pending_per_session", true, func(c Config) any { return c.MaxPendingPerSession }},
+	{"max_pending_global", true, func(c Config) any { return c.MaxPendingGlobal }},
+	{"pair_token_ttl", true, func(c Config) any { return c.PairTokenTTL }},
+	{"connector_secret_ttl", true, func(c Config) any { return c.ConnectorSecretTTL }},
+	{"connector_secret_grace", true, func(c Config) any { return c.ConnectorSecretGrace }},
+	{"trash_retention", true, func(c Config) any { return c.TrashRetention }},
+	{"session_ttl", true, func(c Config) any { return c.SessionTTL }},
+	{"dev_mode", true, func(c Config) any { return c.DevMode }},
+	{"public_signup_enabled", true, func(c Config) any { return c.PublicSignupEnabled }},
+	{"public_signup_rpm", true, func(c Config) any { return c.PublicSignupRPM }},
+	{"auth_rate_limit_rpm", true, func(c Config) any { return c.AuthRateLimitRPM }},
+	{"member_write_enabled", true, func(c Config) any { return c.MemberWriteEnabled }},
+	{"webhook_url", true, func(c Config) any { return c.WebhookURL }},
+	{"smtp_addr", true, func(c Config) any { return c.SMTPAddr }},
+	{"smtp_from", true, func(c Config) any { return c.SMTPFrom }},
+	{"smtp_username", true, func(c Config) any { return c.SMTPUsername }},
+	{"smtp_password", true, func(c Config) any { return c.SMTPPassword }},
+	{"metrics_token", true, func(c Config) any { return c.MetricsToken }},
+	{"usage_warning_thresholds", true, func(c Config) any { return c.UsageWarningPercents }},
+	{"reserved_names", true, func(c Config) any { return c.ReservedNames }},
+	{"blocked_name_patterns", true, func(c Config) any { return c.BlockedNamePatterns }},
+	{"reverse_forward_targets", true, func(c Config) any { return c.ReverseForwardTargets }},
+	{"tls_passthrough_hostnames", true, func(c Config) any { return c.PassthroughHostnames }},
+	{"proxy_ip_rps", true, func(c Config) any { return c.ProxyIPRPS }},
+	{"proxy_ip_ban_threshold", true, func(c Config) any { return c.ProxyIPBanThreshold }},
+	{"proxy_ip_ban_duration", true, func(c Config) any { return c.ProxyIPBanDuration }},
+	{"trust_forwarded_for", true, func(c Config) any { return c.TrustForwardedFor }},
+	{"inject_traceparent", true, func(c Config) any { return c.InjectTraceparent }},
+	{"tls_expiry_warning_days", true, func(c Config) any { return c.TLSExpiryWarningDays }},
+	{"fault_drop_response_percent", true, func(c Config) any { return c.FaultDropResponsePercent }},
+	{"fault_dispatch_delay", true, func(c Config) any { return c.FaultDispatchDelay }},
+	{"fault_kill_session_percent", true, func(c Config) any { return c.FaultKillSessionPercent }},
+}
+
+type ConfigReloadResult struct {
+	Applied         []string  `json:"applied"`
+	RestartRequired []string  `json:"restart_required"`
+	ReloadedAt      time.Time `json:"reloaded_at"`
+}
+
+func (s *Server) config() Config {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.cfg
+}
+
+func (s *Server) currentNamePolicy() *NamePolicy {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.namePolicy
+}
+
+func (s *Server) SetConfigLoader(loader ConfigLoader) {
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	s.configLoader = loader
+}
+
+// ReloadConfig loads the config again and applies the settings that are safe
+// to change at runtime. Settings that need a restart keep their current value
+// and are reported in RestartRequired. Agent sessions are not touched.
+func (s *Server) ReloadConfig(actor string) (ConfigReloadResult, error) {
+	s.cfgMu.RLock()
+	loader := s.configLoader
+	s.cfgMu.RUnlock()
+	if loader == nil {
+		return ConfigReloadResult{}, errConfigReloadUnavailable
+	}
+	loaded, err := loader()
+	if err != nil {
+		return ConfigReloadResult{}, err
+	}
+	next := normalizeConfig(loaded)
+	namePolicy, err := NewNamePolicy(next.ReservedNames, next.BlockedNamePatterns)
+	if err != nil {
+		return ConfigReloadResult{}, fmt.Errorf("name policy: %w", err)
+	}
+
+	s.cfgMu.Lock()
+	current := s.cfg
+	result := ConfigReloadResult{
+		Applied:         make([]string, 0),
+		RestartRequired: make([]string, 0),
+		ReloadedAt:      time.Now().UTC(),
+	}
+	for _, field := range configReloadFields {
+		if reflect.DeepEqual(field.value(current), field.value(next)) {
+			continue
+		}
+		if field.reloadable {
+			result.Applied = append(result.Applied, field.key)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, field.key)
+		}
+	}
+	next.ListenAddr = current.ListenAddr
+	next.TLSListenAddr = current.TLSListenAddr
+	next.HTTP2Enabled = current.HTTP2Enabled
+	next.AdminListenAddr = current.AdminListenAddr
+	next.AdminTLSCertFile = current.AdminTLSCertFile
+	next.AdminTLSKeyFile = current.AdminTLSKeyFile
+	next.AgentListenAddr = current.AgentListenAddr
+	next.AgentTLSCertFile = current.AgentTLSCertFile
+	next.AgentTLSKeyFile = current.AgentTLSKeyFile
+	next.SSHListenAddr = current.SSHListenAddr
+	next.SSHHostKeyFile = current.SSHHostKeyFile
+	next.AgentToken = current.AgentToken
+	next.StorageDriver = current.StorageDriver
+	next.SQLitePath = current.SQLitePath
+	next.RateLimitBackend = current.RateLimitBackend
+	next.RedisURL = current.RedisURL
+	next.SessionStore = current.SessionStore
+	next.TLSKeyEncryptionKey = current.TLSKeyEncryptionKey
+	next.AdminUsername = current.AdminUsername
+	next.AdminPassword = current.AdminPassword
+	next.SuperAdminUsername = current.SuperAdminUsername
+	next.SuperAdminPassword = current.SuperAdminPassword
+	next.GitHubReleaseRepo = current.GitHubReleaseRepo
+	next.GitHubReleaseTag = current.GitHubReleaseTag
+	next.GitHubToken = current.GitHubToken
+	next.PublicDownloadCacheTTL = current.PublicDownloadCacheTTL
+	s.cfg = next
+	s.namePolicy = namePolicy
+	s.cfgMu.Unlock()
+
+	s.hub.SetLimits(next.PublicBaseURL, next.ProxyRequestTimeout, next.MaxPendingPerSession, next.MaxPendingGlobal)
+	s.ruleStore.SetNamePolicy(namePolicy)
+	s.connectorStore.SetPairTokenTTL(next.PairTokenTTL)
+	s.connectorStore.SetSecretPolicy(next.ConnectorSecretTTL, next.ConnectorSecretGrace)
+	s.authStore.SetSessionTTL(next.SessionTTL)
+	s.webhooks.SetURL(next.WebhookURL)
+	s.applyFaultInjection(next)
+
+	s.logger.Printf("config reloaded by %s applied=[%s] restart_required=[%s]", actor, strings.Join(result.Applied, ","), strings.Join(result.RestartRequired, ","))
+	s.auditStore.Record(actor, "config.reload", "", map[string]string{
+		"applied":          strings.Join(result.Applied, ","),
+		"restart_required": strings.Join(result.RestartRequired, ","),
+	})
+	s.persistState()
+	return result, nil
+}
+
+func (s *Server) handleAdminConfigReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	if !s.requireSuperAdmin(w, user) {
+		return
+	}
+
+	result, err := s.ReloadConfig(user.Username)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errConfigReloadUnavailable) {
+			status = http.StatusNotImplemented
+		}
+		writeAPIError(w, status, errorCodeForStatus(status), fmt.Sprintf("reload config: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
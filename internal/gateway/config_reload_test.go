@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReloadConfigAppliesReloadableSettings(t *testing.T) {
+	initial := Config{StorageDriver: "memory", ListenAddr: ":8080", PublicBaseURL: "http://old.example", ProxyRequestTimeout: 30 * time.Second}
+	server := NewServer(initial, nil)
+
+	next := initial
+	next.ListenAddr = ":9090"
+	next.PublicBaseURL = "http://new.example"
+	next.ProxyRequestTimeout = 5 * time.Second
+	next.MaxPendingGlobal = 42
+	server.SetConfigLoader(func() (Config, error) { return next, nil })
+
+	result, err := server.ReloadConfig("tester")
+	if err != nil {
+		t.Fatalf("reload config: %v", err)
+	}
+	applied := strings.Join(result.Applied, ",")
+	if !strings.Contains(applied, "public_base_url") || !strings.Contains(applied, "proxy_request_timeout") || !strings.Contains(applied, "max_pending_global") {
+		t.Fatalf("unexpected applied settings: %v", result.Applied)
+	}
+	if len(result.RestartRequired) != 1 || result.RestartRequired[0] != "listen_addr" {
+		t.Fatalf("expected listen_addr to require restart, got %v", result.RestartRequired)
+	}
+
+	cfg := server.config()
+	if cfg.ListenAddr != ":8080" {
+		t.Fatalf("listen addr must not change without restart, got %q", cfg.ListenAddr)
+	}
+	if cfg.PublicBaseURL != "http://new.example" {
+		t.Fatalf("expected public base url to reload, got %q", cfg.PublicBaseURL)
+	}
+	if got := server.hub.RequestTimeout(); got != 5*time.Second {
+		t.Fatalf("expected hub timeout 5s, got %s", got)
+	}
+	if status := server.hub.Status(); status.MaxPendingGlobal != 42 {
+		t.Fatalf("expected hub max pending 42, got %d", status.MaxPendingGlobal)
+	}
+}
+
+func TestReloadConfigKeepsSettingsOnError(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", PublicBaseURL: "http://old.example"}, nil)
+	if _, err := server.ReloadConfig("tester"); !errors.Is(err, errConfigReloadUnavailable) {
+		t.Fatalf("expected reload to be unavailable without a loader, got %v", err)
+	}
+
+	server.SetConfigLoader(func() (Config, error) {
+		return Config{StorageDriver: "memory", PublicBaseURL: "http://new.example", BlockedNamePatterns: []string{"("}}, nil
+	})
+	if _, err := server.ReloadConfig("tester"); err == nil {
+		t.Fatalf("expected invalid name pattern to fail reload")
+	}
+	if got := server.config().PublicBaseURL; got != "http://old.example" {
+		t.Fatalf("failed reload must keep current settings, got %q", got)
+	}
+}
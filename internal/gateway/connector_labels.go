@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const maxConnectorLabels = 16
+
+var (
+	labelKeyPattern   = regexp.MustCompile(`^[a-z0-9]([a-z0-9._/-]{0,61}[a-z0-9])?$`)
+	labelValuePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{0,63}$`)
+)
+
+func normalizeConnectorLabels(field string, input map[string]string) (map[string]string, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	if len(input) > maxConnectorLabels {
+		return nil, fmt.Errorf("%s supports at most %d labels", field, maxConnectorLabels)
+	}
+	labels := make(map[string]string, len(input))
+	for key, value := range input {
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if !labelKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("%s has an invalid label key %q", field, key)
+		}
+		if !labelValuePattern.MatchString(value) {
+			return nil, fmt.Errorf("%s has an invalid value %q for label %q", field, value, key)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// labelsMatch reports whether labels has every key and value in selector.
+// An empty selector matches nothing so unlabelled routes never fan out.
+func labelsMatch(labels, selector map[string]string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for key, want := range selector {
+		if got, ok := labels[key]; !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Rule) servedBy(connector Connector) bool {
+	if r.ConnectorID != "" {
+		return r.ConnectorID == connector.ID
+	}
+	return r.TenantID == connector.TenantID && labelsMatch(connector.Labels, r.ConnectorSelector)
+}
+
+func (s *ConnectorStore) SetLabels(id string, labels map[string]string) (Connector, error) {
+	id = normalizeIdentifier(id)
+	labels, err := normalizeConnectorLabels("labels", labels)
+	if err != nil {
+		return Connector{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	connector, ok := s.connectors[id]
+	if !ok {
+		return Connector{}, fmt.Errorf("connector %q not found", id)
+	}
+	connector.Labels = labels
+	connector.UpdatedAt = time.Now().UTC()
+	s.connectors[id] = connector
+	return connector, nil
+}
+
+func (s *ConnectorStore) SetBandwidthLimit(id string, bytesPerSecond int64) (Connector, error) {
+	id = normalizeIdentifier(id)
+	if err := validateBandwidthLimit("max_bytes_per_second", bytesPerSecond); err != nil {
+		return Connector{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	connector, ok := s.connectors[id]
+	if !ok {
+		return Connector{}, fmt.Errorf("connector %q not found", id)
+	}
+	connector.MaxBytesPerSecond = bytesPerSecond
+	connector.UpdatedAt = time.Now().UTC()
+	s.connectors[id] = connector
+	return connector, nil
+}
+
+func (s *ConnectorStore) MatchSelector(tenantID string, selector map[string]string) []string {
+	tenantID = normalizeIdentifier(tenantID)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0)
+	for _, connector := range s.connectors {
+		if connector.TenantID == tenantID && labelsMatch(connector.Labels, selector) {
+			ids = append(ids, connector.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *Server) resolveConnector(upstream Rule) (string, bool) {
+	if upstream.ConnectorID != "" {
+		return upstream.ConnectorID, true
+	}
+	return s.hub.PickConnector(s.connectorStore.MatchSelector(upstream.TenantID, upstream.ConnectorSelector))
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestConnectorSelectorRoutesToOnlineMatchingConnector(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	for _, connector := range []Connector{
+		{ID: "mac-1", TenantID: DefaultTenantID, Labels: map[string]string{"OS": "mac", "team": "payments"}},
+		{ID: "mac-2", TenantID: DefaultTenantID, Labels: map[string]string{"os": "mac", "team": "payments"}},
+		{ID: "linux-1", TenantID: DefaultTenantID, Labels: map[string]string{"os": "linux", "team": "payments"}},
+	} {
+		if _, err := server.connectorStore.Create(connector); err != nil {
+			t.Fatalf("create connector: %v", err)
+		}
+	}
+	if got := server.connectorStore.MatchSelector(DefaultTenantID, map[string]string{"os": "mac"}); len(got) != 2 || got[0] != "mac-1" {
+		t.Fatalf("expected both mac connectors to match, got %v", got)
+	}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
+		ID:                "pay",
+		ConnectorSelector: map[string]string{"os": "mac", "team": "payments"},
+		LocalPort:         3000,
+	}); err != nil {
+		t.Fatalf("upsert selector route: %v", err)
+	}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
+		ID:                "both",
+		ConnectorID:       "mac-1",
+		ConnectorSelector: map[string]string{"os": "mac"},
+		LocalPort:         3000,
+	}); err == nil {
+		t.Fatalf("expected connector_selector combined with connector_id to be rejected")
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/pay/", nil))
+	if recorder.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502 with no matching connector online, got %d", recorder.Code)
+	}
+
+	if _, err := server.hub.RegisterConnectorSession("linux-1", "agent-linux"); err != nil {
+		t.Fatalf("register linux connector: %v", err)
+	}
+	registered, err := server.hub.RegisterConnectorSession("mac-2", "agent-mac")
+	if err != nil {
+		t.Fatalf("register mac connector: %v", err)
+	}
+	go func() {
+		pulled, err := server.hub.PullRequest(context.Background(), registered.SessionID)
+		if err != nil {
+			return
+		}
+		_ = server.hub.SubmitProxyResponse(registered.SessionID, &protocol.ProxyResponse{
+			RequestID: pulled.RequestID,
+			TunnelID:  pulled.TunnelID,
+			Status:    http.StatusOK,
+			Body:      []byte(pulled.ConnectorID),
+		})
+	}()
+
+	recorder = httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/pay/", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "mac-2" {
+		t.Fatalf("expected the online mac connector to serve the route, got %d %q", recorder.Code, recorder.Body.String())
+	}
+
+	rule, _ := server.ruleStore.GetForTenant(DefaultTenantID, "pay")
+	if view := server.buildRouteViewWithConnected(rule, nil); !view.Connected || view.AgentID != "agent-mac" {
+		t.Fatalf("expected route view to report the matching connector online, got %+v", view)
+	}
+	mac2, _ := server.connectorStore.Get("mac-2")
+	linux, _ := server.connectorStore.Get("linux-1")
+	if !rule.servedBy(mac2) || rule.servedBy(linux) {
+		t.Fatalf("expected only matching connectors to serve the route")
+	}
+}
+
+func TestConnectorLabelsValidationAndPick(t *testing.T) {
+	if _, err := normalizeConnectorLabels("labels", map[string]string{"bad key": "x"}); err == nil {
+		t.Fatalf("expected label key with a space to be rejected")
+	}
+	if _, err := normalizeConnectorLabels("labels", map[string]string{"os": "mac os"}); err == nil {
+		t.Fatalf("expected label value with a space to be rejected")
+	}
+	if labelsMatch(map[string]string{"os": "mac"}, nil) {
+		t.Fatalf("expected an empty selector to match nothing")
+	}
+
+	hub := NewHub("token", "http://localhost", time.Second, 0, 0)
+	if _, ok := hub.PickConnector([]string{"a", "b"}); ok {
+		t.Fatalf("expected no pick when no candidate is online")
+	}
+	if _, err := hub.RegisterConnectorSession("b", "agent-b"); err != nil {
+		t.Fatalf("register connector session: %v", err)
+	}
+	if picked, ok := hub.PickConnector([]string{"a", "b"}); !ok || picked != "b" {
+		t.Fatalf("expected the online candidate to be picked, got %q", picked)
+	}
+}
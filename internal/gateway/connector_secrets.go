@@ -0,0 +1,266 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const connectorSecretSweepInterval = 10 * time.Minute
+
+var ErrSecretRotationNotDue = errors.New("connector secret is not due for rotation")
+
+type connectorSecretExpiry struct {
+	ConnectorID string    `json:"connector_id"`
+	TenantID    string    `json:"tenant_id"`
+	RotateAfter time.Time `json:"rotate_after"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Expired     bool      `json:"expired"`
+}
+
+func (s *ConnectorStore) SetSecretPolicy(ttl, grace time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secretTTL = max(ttl, 0)
+	s.secretGrace = max(grace, 0)
+}
+
+func (s *ConnectorStore) newCredentialLocked(connectorID string, now time.Time) (string, connectorCredential, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", connectorCredential{}, err
+	}
+	credential := connectorCredential{
+		ConnectorID: connectorID,
+		SecretHash:  hashConnectorSecret(secret),
+		UpdatedAt:   now,
+	}
+	if s.secretTTL > 0 {
+		credential.ExpiresAt = now.Add(s.secretTTL)
+	}
+	return secret, credential, nil
+}
+
+func (c connectorCredential) accepts(hash string, now time.Time) bool {
+	if hash == c.SecretHash {
+		return c.ExpiresAt.IsZero() || now.Before(c.ExpiresAt)
+	}
+	return c.PreviousHash != "" && hash == c.PreviousHash && now.Before(c.PreviousExpiresAt)
+}
+
+// rotateAfter opens the rotation window for the last quarter of the secret's
+// lifetime. It is zero for secrets that do not expire.
+func (c connectorCredential) rotateAfter() time.Time {
+	if c.ExpiresAt.IsZero() {
+		return time.Time{}
+	}
+	return c.UpdatedAt.Add(c.ExpiresAt.Sub(c.UpdatedAt) * 3 / 4)
+}
+
+func (c connectorCredential) snapshot() connectorCredentialSnapshot {
+	return connectorCredentialSnapshot{
+		ConnectorID:       c.ConnectorID,
+		SecretHash:        c.SecretHash,
+		UpdatedAt:         c.UpdatedAt,
+		ExpiresAt:         optionalTime(c.ExpiresAt),
+		PreviousHash:      c.PreviousHash,
+		PreviousExpiresAt: optionalTime(c.PreviousExpiresAt),
+	}
+}
+
+func credentialFromSnapshot(snapshot connectorCredentialSnapshot) connectorCredential {
+	credential := connectorCredential{
+		ConnectorID:  snapshot.ConnectorID,
+		SecretHash:   snapshot.SecretHash,
+		UpdatedAt:    snapshot.UpdatedAt,
+		PreviousHash: snapshot.PreviousHash,
+	}
+	if snapshot.ExpiresAt != nil {
+		credential.ExpiresAt = snapshot.ExpiresAt.UTC()
+	}
+	if snapshot.PreviousExpiresAt != nil {
+		credential.PreviousExpiresAt = snapshot.PreviousExpiresAt.UTC()
+	}
+	return credential
+}
+
+func optionalTime(value time.Time) *time.Time {
+	if value.IsZero() {
+		return nil
+	}
+	return &value
+}
+
+func (s *ConnectorStore) SecretSchedule(connectorID string) (rotateAfter, expiresAt *time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	credential, ok := s.credentials[normalizeIdentifier(connectorID)]
+	if !ok {
+		return nil, nil
+	}
+	return optionalTime(credential.rotateAfter()), optionalTime(credential.ExpiresAt)
+}
+
+// RenewCredential replaces a secret in its rotation window on behalf of the
+// agent holding it. Unlike RotateCredential, the old secret keeps working for
+// the grace period, though not past its own expiry.
+func (s *ConnectorStore) RenewCredential(connectorID, secret string) (protocol.RotateSecretResponse, error) {
+	connectorID = normalizeIdentifier(connectorID)
+	hash := hashConnectorSecret(secret)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	current, ok := s.credentials[connectorID]
+	if !ok || hash != current.SecretHash || !current.accepts(hash, now) {
+		return protocol.RotateSecretResponse{}, errors.New("invalid connector credentials")
+	}
+	if rotateAfter := current.rotateAfter(); rotateAfter.IsZero() || now.Before(rotateAfter) {
+		return protocol.RotateSecretResponse{}, ErrSecretRotationNotDue
+	}
+
+	newSecret, credential, err := s.newCredentialLocked(connectorID, now)
+	if err != nil {
+		return protocol.RotateSecretResponse{}, err
+	}
+	if s.secretGrace > 0 {
+		credential.PreviousHash = current.SecretHash
+		credential.PreviousExpiresAt = now.Add(s.secretGrace)
+		if current.ExpiresAt.Before(credential.PreviousExpiresAt) {
+			credential.PreviousExpiresAt = current.ExpiresAt
+		}
+	}
+	s.credentials[connectorID] = credential
+	return protocol.RotateSecretResponse{
+		ConnectorID:        connectorID,
+		ConnectorSecret:    newSecret,
+		ExpiresAt:          optionalTime(credential.ExpiresAt),
+		RotateAfter:        optionalTime(credential.rotateAfter()),
+		PreviousValidUntil: optionalTime(credential.PreviousExpiresAt),
+	}, nil
+}
+
+func (s *ConnectorStore) ExpiringSecrets(now time.Time) []connectorSecretExpiry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]connectorSecretExpiry, 0)
+	for id, credential := range s.credentials {
+		rotateAfter := credential.rotateAfter()
+		if rotateAfter.IsZero() || now.Before(rotateAfter) {
+			continue
+		}
+		out = append(out, connectorSecretExpiry{
+			ConnectorID: id,
+			TenantID:    s.connectors[id].TenantID,
+			RotateAfter: rotateAfter,
+			ExpiresAt:   credential.ExpiresAt,
+			Expired:     !now.Before(credential.ExpiresAt),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ExpiresAt.Equal(out[j].ExpiresAt) {
+			return out[i].ConnectorID < out[j].ConnectorID
+		}
+		return out[i].ExpiresAt.Before(out[j].ExpiresAt)
+	})
+	return out
+}
+
+func (s *ConnectorStore) markSecretWarned(connectorID string, expiresAt time.Time, expired bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := expiresAt.Format(time.RFC3339)
+	if expired {
+		key += "/expired"
+	}
+	if s.secretWarned[connectorID] == key {
+		return false
+	}
+	s.secretWarned[connectorID] = key
+	return true
+}
+
+func (s *Server) runConnectorSecretLoop(ctx context.Context) {
+	s.checkConnectorSecretExpiry(time.Now().UTC())
+	ticker := time.NewTicker(connectorSecretSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.checkConnectorSecretExpiry(now.UTC())
+		}
+	}
+}
+
+func (s *Server) checkConnectorSecretExpiry(now time.Time) {
+	for _, secret := range s.connectorStore.ExpiringSecrets(now) {
+		warnAt := secret.RotateAfter.Add(secret.ExpiresAt.Sub(secret.RotateAfter) / 2)
+		if now.Before(warnAt) {
+			continue
+		}
+		if !s.connectorStore.markSecretWarned(secret.ConnectorID, secret.ExpiresAt, secret.Expired) {
+			continue
+		}
+		severity := "warning"
+		message := fmt.Sprintf("connector %s secret expires on %s; the agent has not rotated it", secret.ConnectorID, secret.ExpiresAt.Format(time.RFC3339))
+		if secret.Expired {
+			severity = "critical"
+			message = fmt.Sprintf("connector %s secret expired on %s; pair the connector again or rotate its secret", secret.ConnectorID, secret.ExpiresAt.Format(time.RFC3339))
+		}
+		s.incidentStore.Add(severity, "connector", message)
+		s.webhooks.Emit("connector.secret_expiring", secret.TenantID, secret)
+	}
+}
+
+func (s *Server) annotateConnectorSecret(response *protocol.RegisterResponse, connectorID string) {
+	if connectorID == "" {
+		return
+	}
+	response.SecretRotateAfter, response.SecretExpiresAt = s.connectorStore.SecretSchedule(connectorID)
+}
+
+func (s *Server) handleAgentRotate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var payload protocol.RotateSecretRequest
+	if !s.decodeJSON(w, r, &payload, "rotate payload") {
+		return
+	}
+	connectorID := strings.TrimSpace(payload.ConnectorID)
+	if connectorID == "" || !s.connectorStore.Authenticate(connectorID, payload.ConnectorSecret) {
+		writeAPIError(w, http.StatusUnauthorized, errCodeInvalidCredentials, "invalid connector credentials")
+		return
+	}
+	if !s.requireConnectorTenantActive(w, connectorID) {
+		return
+	}
+
+	response, err := s.connectorStore.RenewCredential(connectorID, payload.ConnectorSecret)
+	if err != nil {
+		if errors.Is(err, ErrSecretRotationNotDue) {
+			writeAPIError(w, http.StatusConflict, errCodeRotationNotDue, err.Error())
+			return
+		}
+		writeAPIError(w, http.StatusUnauthorized, errCodeInvalidCredentials, err.Error())
+		return
+	}
+	connector, _ := s.connectorStore.Get(connectorID)
+	s.auditStore.Record("connector:"+response.ConnectorID, "connector.secret_rotated", connector.TenantID, map[string]string{
+		"connector_id": response.ConnectorID,
+	})
+	s.persistState()
+	writeJSON(w, http.StatusOK, response)
+}
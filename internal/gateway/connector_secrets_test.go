@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestConnectorSecretsExpireAndRotateWithGrace(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", ConnectorSecretTTL: 4 * time.Hour, ConnectorSecretGrace: time.Hour}, nil)
+	defer server.hub.Close()
+	store := server.connectorStore
+
+	if _, err := store.Create(Connector{ID: "laptop", TenantID: DefaultTenantID}); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+	secret, err := store.RotateCredential("laptop")
+	if err != nil {
+		t.Fatalf("issue credential: %v", err)
+	}
+	rotateAfter, expiresAt := store.SecretSchedule("laptop")
+	if rotateAfter == nil || expiresAt == nil || expiresAt.Sub(*rotateAfter) != time.Hour {
+		t.Fatalf("expected the last hour of a 4h secret to be the rotation window, got %v %v", rotateAfter, expiresAt)
+	}
+	if _, err := store.RenewCredential("laptop", secret); !errors.Is(err, ErrSecretRotationNotDue) {
+		t.Fatalf("expected a fresh secret not to be renewable, got %v", err)
+	}
+
+	// Age the secret into its rotation window.
+	credential := store.credentials["laptop"]
+	credential.UpdatedAt = credential.UpdatedAt.Add(-3*time.Hour - time.Minute)
+	credential.ExpiresAt = credential.ExpiresAt.Add(-3*time.Hour - time.Minute)
+	store.credentials["laptop"] = credential
+
+	body, _ := json.Marshal(protocol.RotateSecretRequest{ConnectorID: "laptop", ConnectorSecret: secret})
+	recorder := httptest.NewRecorder()
+	server.handleAgentRotate(recorder, httptest.NewRequest(http.MethodPost, "/api/agent/rotate", bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("rotate: %d %s", recorder.Code, recorder.Body.String())
+	}
+	var rotated protocol.RotateSecretResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &rotated); err != nil {
+		t.Fatalf("decode rotate response: %v", err)
+	}
+	if rotated.ConnectorSecret == "" || rotated.ConnectorSecret == secret || rotated.PreviousValidUntil == nil {
+		t.Fatalf("unexpected rotate response %+v", rotated)
+	}
+	if !rotated.PreviousValidUntil.Equal(credential.ExpiresAt) {
+		t.Fatalf("expected the old secret to stop at its own expiry, got %v want %v", rotated.PreviousValidUntil, credential.ExpiresAt)
+	}
+	if !store.Authenticate("laptop", secret) || !store.Authenticate("laptop", rotated.ConnectorSecret) {
+		t.Fatalf("expected both secrets to work during the grace period")
+	}
+	if _, err := store.RenewCredential("laptop", secret); err == nil {
+		t.Fatalf("expected the previous secret not to be able to rotate again")
+	}
+
+	credential = store.credentials["laptop"]
+	credential.PreviousExpiresAt = time.Now().Add(-time.Second)
+	store.credentials["laptop"] = credential
+	if store.Authenticate("laptop", secret) {
+		t.Fatalf("expected the previous secret to be rejected after the grace period")
+	}
+
+	credential.ExpiresAt = time.Now().Add(-time.Second)
+	store.credentials["laptop"] = credential
+	if store.Authenticate("laptop", rotated.ConnectorSecret) {
+		t.Fatalf("expected an expired secret to be rejected")
+	}
+}
+
+func TestConnectorSecretExpiryRaisesIncidentOnce(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", ConnectorSecretTTL: 4 * time.Hour}, nil)
+	defer server.hub.Close()
+
+	if _, err := server.connectorStore.Create(Connector{ID: "laptop", TenantID: DefaultTenantID}); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+	if _, err := server.connectorStore.RotateCredential("laptop"); err != nil {
+		t.Fatalf("issue credential: %v", err)
+	}
+	_, expiresAt := server.connectorStore.SecretSchedule("laptop")
+
+	server.checkConnectorSecretExpiry(expiresAt.Add(-50 * time.Minute))
+	if incidents := server.incidentStore.List(10); len(incidents) != 0 {
+		t.Fatalf("expected no incident early in the rotation window, got %+v", incidents)
+	}
+	server.checkConnectorSecretExpiry(expiresAt.Add(-20 * time.Minute))
+	server.checkConnectorSecretExpiry(expiresAt.Add(-10 * time.Minute))
+	incidents := server.incidentStore.List(10)
+	if len(incidents) != 1 || incidents[0].Source != "connector" || incidents[0].Severity != "warning" {
+		t.Fatalf("expected one connector warning, got %+v", incidents)
+	}
+
+	server.checkConnectorSecretExpiry(expiresAt.Add(time.Minute))
+	incidents = server.incidentStore.List(10)
+	if len(incidents) != 2 || incidents[0].Severity != "critical" {
+		t.Fatalf("expected a critical incident once the secret expired, got %+v", incidents)
+	}
+}
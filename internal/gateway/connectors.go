@@ -11,11 +11,13 @@ import (
 )
 
 type Connector struct {
-	ID        string    `json:"id"`
-	TenantID  string    `json:"tenant_id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                string            `json:"id"`
+	TenantID          string            `json:"tenant_id"`
+	Name              string            `json:"name"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	MaxBytesPerSecond int64             `json:"max_bytes_per_second,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
 }
 
 type PairToken struct {
@@ -29,6 +31,11 @@ type connectorCredential struct {
 	ConnectorID string
 	SecretHash  string
 	UpdatedAt   time.Time
+	// ExpiresAt is zero for secrets that do not expire. PreviousHash is the
+	// secret an agent rotated away from, accepted until PreviousExpiresAt.
+	ExpiresAt         time.Time
+	PreviousHash      string
+	PreviousExpiresAt time.Time
 }
 
 type pairTokenRecord struct {
@@ -38,6 +45,9 @@ type pairTokenRecord struct {
 
 type ConnectorStore struct {
 	pairTokenTTL time.Duration
+	secretTTL    time.Duration
+	secretGrace  time.Duration
+	secretWarned map[string]string
 
 	mu          sync.RWMutex
 	connectors  map[string]Connector
@@ -54,10 +64,28 @@ func NewConnectorStore(pairTokenTTL time.Duration) *ConnectorStore {
 		connectors:   make(map[string]Connector),
 		credentials:  make(map[string]connectorCredential),
 		pairTokens:   make(map[string]pairTokenRecord),
+		secretWarned: make(map[string]string),
 	}
 }
 
+func (s *ConnectorStore) SetPairTokenTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pairTokenTTL = ttl
+}
+
 func (s *ConnectorStore) Create(input Connector) (Connector, error) {
+	return s.create(input, true)
+}
+
+func (s *ConnectorStore) ValidateCreate(input Connector) (Connector, error) {
+	return s.create(input, false)
+}
+
+func (s *ConnectorStore) create(input Connector, apply bool) (Connector, error) {
 	id := normalizeIdentifier(input.ID)
 	if !identifierPattern.MatchString(id) {
 		return Connector{}, fmt.Errorf("invalid connector id %q (allowed: letters, numbers, _, -, max 64)", id)
@@ -72,14 +100,23 @@ func (s *ConnectorStore) Create(input Connector) (Connector, error) {
 	if name == "" {
 		name = id
 	}
+	labels, err := normalizeConnectorLabels("labels", input.Labels)
+	if err != nil {
+		return Connector{}, err
+	}
+	if err := validateBandwidthLimit("max_bytes_per_second", input.MaxBytesPerSecond); err != nil {
+		return Connector{}, err
+	}
 
 	now := time.Now().UTC()
 	connector := Connector{
-		ID:        id,
-		TenantID:  tenantID,
-		Name:      name,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:                id,
+		TenantID:          tenantID,
+		Name:              name,
+		Labels:            labels,
+		MaxBytesPerSecond: input.MaxBytesPerSecond,
+		CreatedAt:         now,
+		UpdatedAt:         now,
 	}
 
 	s.mu.Lock()
@@ -89,7 +126,9 @@ func (s *ConnectorStore) Create(input Connector) (Connector, error) {
 	if _, exists := s.connectors[id]; exists {
 		return Connector{}, fmt.Errorf("connector %q already exists", id)
 	}
-	s.connectors[id] = connector
+	if apply {
+		s.connectors[id] = connector
+	}
 	return connector, nil
 }
 
@@ -194,6 +233,82 @@ func (s *ConnectorStore) Delete(id string) bool {
 	return true
 }
 
+func (s *ConnectorStore) Take(id string) (Connector, *connectorCredentialSnapshot, bool) {
+	id = normalizeIdentifier(id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	connector, ok := s.connectors[id]
+	if !ok {
+		return Connector{}, nil, false
+	}
+	var credential *connectorCredentialSnapshot
+	if stored, ok := s.credentials[id]; ok {
+		snapshot := stored.snapshot()
+		credential = &snapshot
+	}
+	delete(s.connectors, id)
+	delete(s.credentials, id)
+	for token, record := range s.pairTokens {
+		if record.token.ConnectorID == id {
+			delete(s.pairTokens, token)
+		}
+	}
+	return connector, credential, true
+}
+
+func (s *ConnectorStore) TakeTenant(tenantID string) ([]Connector, []connectorCredentialSnapshot) {
+	tenantID = normalizeIdentifier(tenantID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	connectors := make([]Connector, 0)
+	credentials := make([]connectorCredentialSnapshot, 0)
+	for id, connector := range s.connectors {
+		if connector.TenantID != tenantID {
+			continue
+		}
+		connectors = append(connectors, connector)
+		if credential, ok := s.credentials[id]; ok {
+			credentials = append(credentials, credential.snapshot())
+		}
+		delete(s.connectors, id)
+		delete(s.credentials, id)
+	}
+	for token, record := range s.pairTokens {
+		if _, ok := s.connectors[record.token.ConnectorID]; !ok {
+			delete(s.pairTokens, token)
+		}
+	}
+	sort.Slice(connectors, func(i, j int) bool { return connectors[i].ID < connectors[j].ID })
+	sort.Slice(credentials, func(i, j int) bool { return credentials[i].ConnectorID < credentials[j].ConnectorID })
+	return connectors, credentials
+}
+
+func (s *ConnectorStore) PutTenant(connectors []Connector, credentials []connectorCredentialSnapshot) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var skipped []string
+	restored := make(map[string]struct{}, len(connectors))
+	for _, connector := range connectors {
+		if _, taken := s.connectors[connector.ID]; taken {
+			skipped = append(skipped, connector.ID)
+			continue
+		}
+		s.connectors[connector.ID] = connector
+		restored[connector.ID] = struct{}{}
+	}
+	for _, credential := range credentials {
+		if _, ok := restored[credential.ConnectorID]; ok {
+			s.credentials[credential.ConnectorID] = credentialFromSnapshot(credential)
+		}
+	}
+	return skipped
+}
+
 func (s *ConnectorStore) NewPairToken(connectorID string) (PairToken, error) {
 	connectorID = normalizeIdentifier(connectorID)
 	if connectorID == "" {
@@ -253,15 +368,11 @@ func (s *ConnectorStore) ConsumePairToken(pairToken string) (Connector, string,
 		return Connector{}, "", fmt.Errorf("connector not found for pair token")
 	}
 
-	secret, err := randomToken(32)
+	secret, credential, err := s.newCredentialLocked(connector.ID, now)
 	if err != nil {
 		return Connector{}, "", err
 	}
-	s.credentials[connector.ID] = connectorCredential{
-		ConnectorID: connector.ID,
-		SecretHash:  hashConnectorSecret(secret),
-		UpdatedAt:   now,
-	}
+	s.credentials[connector.ID] = credential
 	record.used = true
 	s.pairTokens[pairToken] = record
 	return connector, secret, nil
@@ -279,15 +390,11 @@ func (s *ConnectorStore) RotateCredential(connectorID string) (string, error) {
 	if _, ok := s.connectors[connectorID]; !ok {
 		return "", fmt.Errorf("connector %q not found", connectorID)
 	}
-	secret, err := randomToken(32)
+	secret, credential, err := s.newCredentialLocked(connectorID, time.Now().UTC())
 	if err != nil {
 		return "", err
 	}
-	s.credentials[connectorID] = connectorCredential{
-		ConnectorID: connectorID,
-		SecretHash:  hashConnectorSecret(secret),
-		UpdatedAt:   time.Now().UTC(),
-	}
+	s.credentials[connectorID] = credential
 	return secret, nil
 }
 
@@ -305,7 +412,7 @@ func (s *ConnectorStore) Authenticate(connectorID, secret string) bool {
 	if !ok {
 		return false
 	}
-	return credential.SecretHash == hashConnectorSecret(secret)
+	return credential.accepts(hashConnectorSecret(secret), time.Now().UTC())
 }
 
 func (s *ConnectorStore) cleanupExpiredPairTokensLocked(now time.Time) {
@@ -0,0 +1,176 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var defaultCORSMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// CORSPolicy is applied by the gateway on behalf of local dev servers that do
+// not emit CORS headers themselves. When set, it replaces any upstream
+// Access-Control-* response headers.
+type CORSPolicy struct {
+	AllowedOrigins   []string `json:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods,omitempty"`
+	AllowedHeaders   []string `json:"allowed_headers,omitempty"`
+	ExposedHeaders   []string `json:"exposed_headers,omitempty"`
+	AllowCredentials bool     `json:"allow_credentials,omitempty"`
+	MaxAgeSeconds    int      `json:"max_age_seconds,omitempty"`
+}
+
+func normalizeCORSPolicy(input *CORSPolicy) (*CORSPolicy, error) {
+	if input == nil {
+		return nil, nil
+	}
+	policy := &CORSPolicy{
+		AllowedOrigins:   make([]string, 0, len(input.AllowedOrigins)),
+		AllowedMethods:   normalizeHeaderTokens(input.AllowedMethods, strings.ToUpper),
+		AllowedHeaders:   normalizeHeaderTokens(input.AllowedHeaders, http.CanonicalHeaderKey),
+		ExposedHeaders:   normalizeHeaderTokens(input.ExposedHeaders, http.CanonicalHeaderKey),
+		AllowCredentials: input.AllowCredentials,
+		MaxAgeSeconds:    input.MaxAgeSeconds,
+	}
+	for _, origin := range input.AllowedOrigins {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if origin != "*" {
+			parsed, err := url.Parse(strings.Replace(origin, "*.", "", 1))
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return nil, fmt.Errorf("invalid cors origin %q", origin)
+			}
+		}
+		policy.AllowedOrigins = append(policy.AllowedOrigins, origin)
+	}
+	if len(policy.AllowedOrigins) == 0 {
+		return nil, fmt.Errorf("cors.allowed_origins must not be empty")
+	}
+	if policy.AllowCredentials {
+		for _, origin := range policy.AllowedOrigins {
+			if origin == "*" {
+				return nil, fmt.Errorf("cors.allow_credentials cannot be combined with wildcard origin")
+			}
+		}
+	}
+	if len(policy.AllowedMethods) == 0 {
+		policy.AllowedMethods = append([]string(nil), defaultCORSMethods...)
+	}
+	if policy.MaxAgeSeconds < 0 {
+		return nil, fmt.Errorf("cors.max_age_seconds cannot be negative")
+	}
+	return policy, nil
+}
+
+func normalizeHeaderTokens(values []string, normalize func(string) string) []string {
+	out := make([]string, 0, len(values))
+	seen := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		value = normalize(value)
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		out = append(out, value)
+	}
+	return out
+}
+
+func (p *CORSPolicy) allowsOrigin(origin string) bool {
+	origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+	if p == nil || origin == "" {
+		return false
+	}
+	for _, allowed := range p.AllowedOrigins {
+		switch {
+		case allowed == "*":
+			return true
+		case strings.EqualFold(allowed, origin):
+			return true
+		case strings.Contains(allowed, "://*."):
+			scheme, suffix, _ := strings.Cut(allowed, "://*.")
+			if strings.HasPrefix(strings.ToLower(origin), strings.ToLower(scheme)+"://") &&
+				strings.HasSuffix(strings.ToLower(origin), "."+strings.ToLower(suffix)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *CORSPolicy) allowsMethod(method string) bool {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	for _, allowed := range p.AllowedMethods {
+		if allowed == "*" || allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CORSPolicy) writeOriginHeaders(header http.Header, origin string) {
+	if p.AllowedOrigins[0] == "*" && len(p.AllowedOrigins) == 1 && !p.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", strings.TrimRight(strings.TrimSpace(origin), "/"))
+		header.Add("Vary", "Origin")
+	}
+	if p.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (p *CORSPolicy) isPreflight(r *http.Request) bool {
+	return p != nil &&
+		r.Method == http.MethodOptions &&
+		strings.TrimSpace(r.Header.Get("Origin")) != "" &&
+		strings.TrimSpace(r.Header.Get("Access-Control-Request-Method")) != ""
+}
+
+func (p *CORSPolicy) writePreflight(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	requestedMethod := r.Header.Get("Access-Control-Request-Method")
+	if !p.allowsOrigin(origin) || !p.allowsMethod(requestedMethod) {
+		http.Error(w, "cors preflight rejected", http.StatusForbidden)
+		return
+	}
+	header := w.Header()
+	p.writeOriginHeaders(header, origin)
+	header.Set("Access-Control-Allow-Methods", strings.Join(p.AllowedMethods, ", "))
+	if len(p.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(p.AllowedHeaders, ", "))
+	} else if requested := strings.TrimSpace(r.Header.Get("Access-Control-Request-Headers")); requested != "" {
+		header.Set("Access-Control-Allow-Headers", requested)
+		header.Add("Vary", "Access-Control-Request-Headers")
+	}
+	if p.MaxAgeSeconds > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(p.MaxAgeSeconds))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *CORSPolicy) applyToResponse(header http.Header, upstream map[string][]string, origin string) {
+	if p == nil {
+		return
+	}
+	for key := range upstream {
+		if strings.HasPrefix(strings.ToLower(key), "access-control-") {
+			delete(upstream, key)
+		}
+	}
+	if !p.allowsOrigin(origin) {
+		return
+	}
+	p.writeOriginHeaders(header, origin)
+	if len(p.ExposedHeaders) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+	}
+}
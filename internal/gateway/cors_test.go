@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalizeCORSPolicyRejectsWildcardWithCredentials(t *testing.T) {
+	if _, err := normalizeCORSPolicy(&CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}); err == nil {
+		t.Fatalf("expected wildcard origin with credentials to be rejected")
+	}
+	if _, err := normalizeCORSPolicy(&CORSPolicy{}); err == nil {
+		t.Fatalf("expected empty allowed_origins to be rejected")
+	}
+	policy, err := normalizeCORSPolicy(&CORSPolicy{AllowedOrigins: []string{"https://*.example.com/"}, AllowedMethods: []string{"get", "post"}})
+	if err != nil {
+		t.Fatalf("normalize cors policy: %v", err)
+	}
+	if !policy.allowsOrigin("https://app.example.com") || policy.allowsOrigin("https://example.org") {
+		t.Fatalf("unexpected origin matching for %+v", policy)
+	}
+	if !policy.allowsMethod("POST") || policy.allowsMethod("DELETE") {
+		t.Fatalf("unexpected method matching for %+v", policy)
+	}
+}
+
+func TestProxyAppliesRouteCORSPolicy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			t.Errorf("preflight should not reach upstream")
+		}
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("X-Total", "3")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
+		ID:     "web",
+		Target: upstream.URL,
+		CORS: &CORSPolicy{
+			AllowedOrigins:   []string{"https://app.example.com"},
+			ExposedHeaders:   []string{"x-total"},
+			AllowCredentials: true,
+			MaxAgeSeconds:    600,
+		},
+	}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/t/web/items", nil)
+	preflight.Header.Set("Origin", "https://app.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "PUT")
+	preflight.Header.Set("Access-Control-Request-Headers", "content-type")
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, preflight)
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("expected preflight 204, got %d", recorder.Code)
+	}
+	if got := recorder.Header().Get("Access-Control-Allow-Headers"); got != "content-type" {
+		t.Fatalf("expected requested headers to be echoed, got %q", got)
+	}
+	if got := recorder.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Fatalf("unexpected max age %q", got)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/t/web/items", nil)
+	request.Header.Set("Origin", "https://app.example.com")
+	recorder = httptest.NewRecorder()
+	server.handleProxy(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", recorder.Code)
+	}
+	if got := recorder.Header().Values("Access-Control-Allow-Origin"); len(got) != 1 || got[0] != "https://app.example.com" {
+		t.Fatalf("expected policy origin to replace upstream header, got %v", got)
+	}
+	if got := recorder.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Fatalf("unexpected credentials header %q", got)
+	}
+	if got := recorder.Header().Get("Access-Control-Expose-Headers"); got != "X-Total" {
+		t.Fatalf("unexpected expose headers %q", got)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/t/web/items", nil)
+	request.Header.Set("Origin", "https://evil.example.net")
+	recorder = httptest.NewRecorder()
+	server.handleProxy(recorder, request)
+	if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("expected disallowed origin to receive no cors headers, got %q", got)
+	}
+}
@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCookieSessionWritesRequireCSRFToken(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	sessionID, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	createTenant := func(id string, withToken bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/tenants", strings.NewReader(`{"id":"`+id+`"}`))
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: sessionID})
+		if withToken {
+			req.AddCookie(&http.Cookie{Name: csrfCookieName, Value: csrfTokenFor(sessionID)})
+			req.Header.Set(csrfHeaderName, csrfTokenFor(sessionID))
+		}
+		recorder := httptest.NewRecorder()
+		server.handleTenants(recorder, req)
+		return recorder
+	}
+
+	rejected := createTenant("forged", false)
+	if rejected.Code != http.StatusForbidden {
+		t.Fatalf("expected cookie write without token to be rejected, got %d: %s", rejected.Code, rejected.Body.String())
+	}
+	var body apiError
+	if err := json.Unmarshal(rejected.Body.Bytes(), &body); err != nil || body.Code != errCodeCSRFTokenInvalid {
+		t.Fatalf("expected csrf_token_invalid, got %s", rejected.Body.String())
+	}
+	if !strings.Contains(rejected.Header().Get("Set-Cookie"), csrfCookieName+"="+csrfTokenFor(sessionID)) {
+		t.Fatalf("expected the CSRF cookie to be issued, got %q", rejected.Header().Get("Set-Cookie"))
+	}
+
+	if accepted := createTenant("team-a", true); accepted.Code != http.StatusOK {
+		t.Fatalf("expected cookie write with token to succeed, got %d: %s", accepted.Code, accepted.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/tenants", strings.NewReader(`{"id":"team-b"}`))
+	req.Header.Set("Authorization", "Bearer "+sessionID)
+	recorder := httptest.NewRecorder()
+	server.handleTenants(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected bearer write without token to succeed, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestLoginSetsCSRFCookie(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"username":"admin","password":"admin123"}`))
+	recorder := httptest.NewRecorder()
+	server.handleAuthLogin(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected login to succeed, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range recorder.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	session, csrf := cookies[sessionCookieName], cookies[csrfCookieName]
+	if session == nil || csrf == nil {
+		t.Fatalf("expected session and CSRF cookies, got %v", recorder.Result().Cookies())
+	}
+	if csrf.HttpOnly || csrf.Value != csrfTokenFor(session.Value) {
+		t.Fatalf("expected a script-readable CSRF cookie bound to the session, got %+v", csrf)
+	}
+}
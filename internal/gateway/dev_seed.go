@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const (
+	devConnectorID = "dev-laptop"
+	devDemoRouteID = "demo"
+	devAppRouteID  = "app"
+)
+
+type DevSeed struct {
+	TenantID     string
+	ConnectorID  string
+	DemoRouteURL string
+	AppRouteURL  string
+	AppLocalPort int
+	PairToken    PairToken
+	PairCommand  string
+	PairDeepLink string
+}
+
+func (s *Server) SeedDevData(localPort int) (DevSeed, error) {
+	if localPort <= 0 || localPort > 65535 {
+		return DevSeed{}, fmt.Errorf("invalid local port %d", localPort)
+	}
+	if _, err := s.connectorStore.Create(Connector{ID: devConnectorID, TenantID: DefaultTenantID, Name: "Dev laptop"}); err != nil {
+		return DevSeed{}, fmt.Errorf("create dev connector: %w", err)
+	}
+	routes := []Rule{
+		{
+			ID: devDemoRouteID,
+			Mock: &RouteMock{
+				Status:  http.StatusOK,
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    `{"message":"hello from proxer","route":"demo"}`,
+			},
+		},
+		{
+			ID:          devAppRouteID,
+			ConnectorID: devConnectorID,
+			LocalScheme: "http",
+			LocalHost:   "127.0.0.1",
+			LocalPort:   localPort,
+		},
+	}
+	for _, route := range routes {
+		rule, err := s.ruleStore.UpsertForTenant(DefaultTenantID, route)
+		if err != nil {
+			return DevSeed{}, fmt.Errorf("create dev route %s: %w", route.ID, err)
+		}
+		s.hub.EnsureTunnelMetric(MakeTunnelKey(DefaultTenantID, rule.ID))
+	}
+	pairToken, err := s.connectorStore.NewPairToken(devConnectorID)
+	if err != nil {
+		return DevSeed{}, fmt.Errorf("issue dev pair token: %w", err)
+	}
+	s.refreshTenantUsage(DefaultTenantID)
+	s.persistState()
+
+	return DevSeed{
+		TenantID:     DefaultTenantID,
+		ConnectorID:  devConnectorID,
+		DemoRouteURL: s.routePublicURL(DefaultTenantID, devDemoRouteID),
+		AppRouteURL:  s.routePublicURL(DefaultTenantID, devAppRouteID),
+		AppLocalPort: localPort,
+		PairToken:    pairToken,
+		PairCommand:  s.pairCommand(pairToken.Token),
+		PairDeepLink: protocol.PairLink(s.agentBaseURL(), pairToken.Token),
+	}, nil
+}
@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSeedDevDataServesDemoAndPairsConnector(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", PublicBaseURL: "http://localhost:9000"}, nil)
+	defer server.hub.Close()
+
+	if _, err := server.SeedDevData(0); err == nil {
+		t.Fatalf("expected an invalid app port to be rejected")
+	}
+	seed, err := server.SeedDevData(5173)
+	if err != nil {
+		t.Fatalf("seed dev data: %v", err)
+	}
+	if seed.DemoRouteURL != "http://localhost:9000/t/default/demo/" || !strings.Contains(seed.PairCommand, seed.PairToken.Token) || !strings.HasPrefix(seed.PairDeepLink, "proxer-agent://pair?") {
+		t.Fatalf("unexpected seed %+v", seed)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/default/demo/", nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "hello from proxer") {
+		t.Fatalf("expected the demo route to answer without an agent, got %d %q", recorder.Code, recorder.Body.String())
+	}
+	if route, ok := server.ruleStore.GetForTenant(DefaultTenantID, "app"); !ok || route.ConnectorID != seed.ConnectorID || route.LocalPort != 5173 {
+		t.Fatalf("unexpected app route %+v", route)
+	}
+	connector, secret, err := server.connectorStore.ConsumePairToken(seed.PairToken.Token)
+	if err != nil || connector.ID != seed.ConnectorID || !server.connectorStore.Authenticate(connector.ID, secret) {
+		t.Fatalf("expected the printed pair token to pair the dev connector: %v", err)
+	}
+}
@@ -0,0 +1,422 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	domainStatusPending  = "pending"
+	domainStatusVerified = "verified"
+	domainStatusFailed   = "failed"
+
+	domainChallengePrefix = "_proxer-challenge."
+	domainTXTValuePrefix  = "proxer-verification="
+	domainVerifyTimeout   = 10 * time.Second
+)
+
+var errDomainTaken = errors.New("domain is verified by another tenant")
+
+type CustomDomain struct {
+	Hostname          string     `json:"hostname"`
+	TenantID          string     `json:"tenant_id"`
+	RouteID           string     `json:"route_id"`
+	Status            string     `json:"status"`
+	VerificationToken string     `json:"verification_token"`
+	LastError         string     `json:"last_error,omitempty"`
+	CheckedAt         *time.Time `json:"checked_at,omitempty"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+type domainChallenge struct {
+	TXTName     string `json:"txt_name"`
+	TXTValue    string `json:"txt_value"`
+	CNAMEName   string `json:"cname_name,omitempty"`
+	CNAMETarget string `json:"cname_target,omitempty"`
+	PointTo     string `json:"point_to,omitempty"`
+}
+
+type customDomainView struct {
+	CustomDomain
+	Challenge domainChallenge `json:"challenge"`
+}
+
+type attachDomainRequest struct {
+	Hostname string `json:"hostname"`
+	RouteID  string `json:"route_id"`
+}
+
+type domainResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+}
+
+type DomainStore struct {
+	mu      sync.RWMutex
+	domains map[string]CustomDomain
+}
+
+func NewDomainStore() *DomainStore {
+	return &DomainStore{domains: make(map[string]CustomDomain)}
+}
+
+// Attach binds hostname to a tenant route. Attaching a domain the tenant
+// already has moves it to routeID and keeps its verification. Only a
+// verified domain is held against other tenants: their pending or failed
+// claim is replaced by a new one.
+func (s *DomainStore) Attach(tenantID, routeID, hostname string) (CustomDomain, error) {
+	now := time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	domain, ok := s.domains[hostname]
+	if ok && domain.TenantID != tenantID {
+		if domain.Status == domainStatusVerified {
+			return CustomDomain{}, errDomainTaken
+		}
+		ok = false
+	}
+	if !ok {
+		domain = CustomDomain{
+			Hostname:          hostname,
+			TenantID:          tenantID,
+			Status:            domainStatusPending,
+			VerificationToken: randomHex(16),
+			CreatedAt:         now,
+		}
+	}
+	domain.RouteID = routeID
+	domain.UpdatedAt = now
+	s.domains[hostname] = domain
+	return domain, nil
+}
+
+func (s *DomainStore) Get(hostname string) (CustomDomain, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	domain, ok := s.domains[hostname]
+	return domain, ok
+}
+
+func (s *DomainStore) Verified(hostname string) (CustomDomain, bool) {
+	domain, ok := s.Get(hostname)
+	return domain, ok && domain.Status == domainStatusVerified
+}
+
+func (s *DomainStore) ListTenant(tenantID string) []CustomDomain {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]CustomDomain, 0)
+	for _, domain := range s.domains {
+		if domain.TenantID == tenantID {
+			out = append(out, domain)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Hostname < out[j].Hostname })
+	return out
+}
+
+// RecordCheck stores the result of a verification attempt. A verified
+// domain that fails a later check stops being served.
+func (s *DomainStore) RecordCheck(hostname string, checkErr error) (CustomDomain, bool) {
+	now := time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	domain, ok := s.domains[hostname]
+	if !ok {
+		return CustomDomain{}, false
+	}
+	domain.CheckedAt = &now
+	domain.UpdatedAt = now
+	if checkErr != nil {
+		domain.Status = domainStatusFailed
+		domain.LastError = checkErr.Error()
+	} else {
+		domain.Status = domainStatusVerified
+		domain.LastError = ""
+		domain.VerifiedAt = &now
+	}
+	s.domains[hostname] = domain
+	return domain, true
+}
+
+func (s *DomainStore) Delete(tenantID, hostname string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	domain, ok := s.domains[hostname]
+	if !ok || domain.TenantID != tenantID {
+		return false
+	}
+	delete(s.domains, hostname)
+	return true
+}
+
+func (s *DomainStore) DeleteTenant(tenantID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hostname, domain := range s.domains {
+		if domain.TenantID == tenantID {
+			delete(s.domains, hostname)
+		}
+	}
+}
+
+func (s *DomainStore) TakeTenant(tenantID string) []CustomDomain {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	taken := make([]CustomDomain, 0)
+	for hostname, domain := range s.domains {
+		if domain.TenantID == tenantID {
+			taken = append(taken, domain)
+			delete(s.domains, hostname)
+		}
+	}
+	sort.Slice(taken, func(i, j int) bool { return taken[i].Hostname < taken[j].Hostname })
+	return taken
+}
+
+func (s *DomainStore) PutTenant(domains []CustomDomain) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var skipped []string
+	for _, domain := range domains {
+		if _, taken := s.domains[domain.Hostname]; taken {
+			skipped = append(skipped, domain.Hostname)
+			continue
+		}
+		s.domains[domain.Hostname] = domain
+	}
+	return skipped
+}
+
+func (s *DomainStore) Snapshot() []CustomDomain {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]CustomDomain, 0, len(s.domains))
+	for _, domain := range s.domains {
+		out = append(out, domain)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Hostname < out[j].Hostname })
+	return out
+}
+
+func (s *DomainStore) Restore(domains []CustomDomain) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.domains = make(map[string]CustomDomain, len(domains))
+	for _, domain := range domains {
+		if hostname, err := normalizeDomainHostname(domain.Hostname); err == nil {
+			domain.Hostname = hostname
+			s.domains[hostname] = domain
+		}
+	}
+}
+
+func normalizeDomainHostname(raw string) (string, error) {
+	hostname := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(raw)), ".")
+	if hostname == "" {
+		return "", fmt.Errorf("hostname is required")
+	}
+	if len(hostname) > 253 || net.ParseIP(hostname) != nil || !strings.Contains(hostname, ".") {
+		return "", fmt.Errorf("invalid hostname %q", raw)
+	}
+	for _, label := range strings.Split(hostname, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", fmt.Errorf("invalid hostname %q", raw)
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return "", fmt.Errorf("invalid hostname %q", raw)
+			}
+		}
+	}
+	return hostname, nil
+}
+
+func requestHostname(host string) string {
+	if parsed, _, err := net.SplitHostPort(host); err == nil {
+		host = parsed
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+func (s *Server) gatewayHostname() string {
+	parsed, err := url.Parse(s.config().PublicBaseURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+func (s *Server) domainView(domain CustomDomain) customDomainView {
+	challenge := domainChallenge{
+		TXTName:  domainChallengePrefix + domain.Hostname,
+		TXTValue: domainTXTValuePrefix + domain.VerificationToken,
+	}
+	if gatewayHost := s.gatewayHostname(); gatewayHost != "" && net.ParseIP(gatewayHost) == nil {
+		challenge.CNAMEName = challenge.TXTName
+		challenge.CNAMETarget = domain.VerificationToken + "." + gatewayHost
+		challenge.PointTo = gatewayHost
+	}
+	return customDomainView{CustomDomain: domain, Challenge: challenge}
+}
+
+func (s *Server) checkDomainChallenge(ctx context.Context, domain CustomDomain) error {
+	challenge := s.domainView(domain).Challenge
+	records, txtErr := s.domainResolver.LookupTXT(ctx, challenge.TXTName)
+	for _, record := range records {
+		if strings.TrimSpace(record) == challenge.TXTValue {
+			return nil
+		}
+	}
+	if challenge.CNAMETarget != "" {
+		cname, err := s.domainResolver.LookupCNAME(ctx, challenge.CNAMEName)
+		if err == nil && strings.EqualFold(strings.TrimSuffix(cname, "."), challenge.CNAMETarget) {
+			return nil
+		}
+	}
+	if txtErr != nil {
+		return fmt.Errorf("look up TXT %s: %v", challenge.TXTName, txtErr)
+	}
+	return fmt.Errorf("no TXT record %q found at %s", challenge.TXTValue, challenge.TXTName)
+}
+
+type customDomainContextKey struct{}
+
+func (s *Server) withCustomDomains(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		domain, ok := s.domainStore.Verified(requestHostname(r.Host))
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		routed := r.Clone(context.WithValue(r.Context(), customDomainContextKey{}, domain.Hostname))
+		prefix := "/t/" + url.PathEscape(domain.TenantID) + "/" + url.PathEscape(domain.RouteID)
+		routed.URL.Path = prefix + r.URL.Path
+		if r.URL.RawPath != "" {
+			routed.URL.RawPath = prefix + r.URL.RawPath
+		}
+		s.handleProxy(w, routed)
+	})
+}
+
+func proxyPublicPrefix(r *http.Request, forwardPath string) string {
+	if _, ok := r.Context().Value(customDomainContextKey{}).(string); ok {
+		return ""
+	}
+	return routePublicPrefix(r.URL.Path, forwardPath)
+}
+
+func (s *Server) handleTenantDomains(w http.ResponseWriter, r *http.Request, user User, tenantID string) {
+	if !s.ruleStore.HasTenant(tenantID) {
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		domains := s.domainStore.ListTenant(tenantID)
+		views := make([]customDomainView, 0, len(domains))
+		for _, domain := range domains {
+			views = append(views, s.domainView(domain))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"tenant_id": tenantID,
+			"domains":   views,
+		})
+	case http.MethodPost:
+		if !s.canMutateTenantConfig(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAdminRequired, "forbidden tenant configuration access")
+			return
+		}
+		var request attachDomainRequest
+		if !s.decodeJSON(w, r, &request, "domain payload") {
+			return
+		}
+		hostname, err := normalizeDomainHostname(request.Hostname)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		if hostname == s.gatewayHostname() {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "the gateway's own host cannot be attached as a custom domain")
+			return
+		}
+		if err := s.enforceTLSFeature(tenantID); err != nil {
+			writePlanFeatureError(w, err)
+			return
+		}
+		routeID := strings.TrimSpace(request.RouteID)
+		if _, ok := s.ruleStore.GetForTenant(tenantID, routeID); !ok {
+			writeAPIErrorDetails(w, http.StatusNotFound, errCodeRouteNotFound, "route not found", map[string]any{"route_id": routeID})
+			return
+		}
+		domain, err := s.domainStore.Attach(tenantID, routeID, hostname)
+		if err != nil {
+			writeAPIError(w, http.StatusConflict, errCodeDomainTaken, fmt.Sprintf("%s: %v", hostname, err))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message": "domain attached; publish the challenge record and verify",
+			"domain":  s.domainView(domain),
+		})
+		s.persistState()
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleTenantDomainByHost(w http.ResponseWriter, r *http.Request, user User, tenantID, rawHostname, action string) {
+	hostname, err := normalizeDomainHostname(rawHostname)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	domain, ok := s.domainStore.Get(hostname)
+	if !ok || domain.TenantID != tenantID {
+		writeAPIErrorDetails(w, http.StatusNotFound, errCodeDomainNotFound, "domain not found", map[string]any{"hostname": hostname})
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{"domain": s.domainView(domain)})
+	case action == "" && r.Method == http.MethodDelete:
+		if !s.canMutateTenantConfig(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAdminRequired, "forbidden tenant configuration access")
+			return
+		}
+		s.domainStore.Delete(tenantID, hostname)
+		s.persistState()
+		w.WriteHeader(http.StatusNoContent)
+	case action == "verify" && r.Method == http.MethodPost:
+		if !s.canMutateTenantConfig(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAdminRequired, "forbidden tenant configuration access")
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), domainVerifyTimeout)
+		defer cancel()
+		domain, _ = s.domainStore.RecordCheck(hostname, s.checkDomainChallenge(ctx, domain))
+		message := "domain verified"
+		if domain.Status != domainStatusVerified {
+			message = "domain verification failed"
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message": message,
+			"domain":  s.domainView(domain),
+		})
+		s.persistState()
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeDomainResolver struct {
+	txt map[string][]string
+}
+
+func (f fakeDomainResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if records, ok := f.txt[name]; ok {
+		return records, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func (f fakeDomainResolver) LookupCNAME(_ context.Context, host string) (string, error) {
+	return "", errors.New("no such host")
+}
+
+func TestCustomDomainVerificationAndRouting(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Host+" "+r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory", PublicBaseURL: "https://gw.proxer.test"}, nil)
+	resolver := fakeDomainResolver{txt: map[string][]string{}}
+	server.domainResolver = resolver
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "web", Target: upstream.URL}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	if _, err := server.ruleStore.UpsertTenant(Tenant{ID: "other"}); err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+	for _, tenantID := range []string{DefaultTenantID, "other"} {
+		if _, err := server.planStore.AssignTenantPlan(tenantID, "pro", "test"); err != nil {
+			t.Fatalf("assign plan: %v", err)
+		}
+	}
+	sessionID, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+sessionID)
+		recorder := httptest.NewRecorder()
+		server.handleTenantSubresources(recorder, req)
+		return recorder
+	}
+	decode := func(recorder *httptest.ResponseRecorder) customDomainView {
+		var payload struct {
+			Domain customDomainView `json:"domain"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode %q: %v", recorder.Body.String(), err)
+		}
+		return payload.Domain
+	}
+	serve := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.withCustomDomains(http.NotFoundHandler()).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://Demo.Customer.com/hello", nil))
+		return recorder
+	}
+
+	if _, err := server.ruleStore.UpsertForTenant("other", Rule{ID: "web", Target: upstream.URL}); err != nil {
+		t.Fatalf("upsert other route: %v", err)
+	}
+	if squatted := call(http.MethodPost, "/api/tenants/other/domains", `{"hostname":"demo.customer.com","route_id":"web"}`); squatted.Code != http.StatusOK {
+		t.Fatalf("attach in other tenant: %d %s", squatted.Code, squatted.Body.String())
+	}
+	attached := call(http.MethodPost, "/api/tenants/default/domains", `{"hostname":"Demo.Customer.com.","route_id":"web"}`)
+	if attached.Code != http.StatusOK {
+		t.Fatalf("attach: %d %s", attached.Code, attached.Body.String())
+	}
+	domain := decode(attached)
+	if domain.Hostname != "demo.customer.com" || domain.Status != domainStatusPending || domain.Challenge.CNAMETarget != domain.VerificationToken+".gw.proxer.test" {
+		t.Fatalf("unexpected pending domain: %+v", domain)
+	}
+	if recorder := serve(); recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected pending domain not to be served, got %d", recorder.Code)
+	}
+
+	if failed := decode(call(http.MethodPost, "/api/tenants/default/domains/demo.customer.com/verify", "")); failed.Status != domainStatusFailed || failed.LastError == "" {
+		t.Fatalf("expected verification without a record to fail, got %+v", failed)
+	}
+	resolver.txt[domain.Challenge.TXTName] = []string{"unrelated", domain.Challenge.TXTValue}
+	if verified := decode(call(http.MethodPost, "/api/tenants/default/domains/demo.customer.com/verify", "")); verified.Status != domainStatusVerified || verified.VerifiedAt == nil {
+		t.Fatalf("expected domain to verify, got %+v", verified)
+	}
+	if recorder := serve(); recorder.Code != http.StatusOK || recorder.Body.String() != strings.TrimPrefix(upstream.URL, "http://")+" /hello" {
+		t.Fatalf("expected verified domain to reach the route, got %d %q", recorder.Code, recorder.Body.String())
+	}
+
+	if len(server.domainStore.ListTenant("other")) != 0 {
+		t.Fatal("expected the other tenant's pending claim to be replaced")
+	}
+	if taken := call(http.MethodPost, "/api/tenants/other/domains", `{"hostname":"demo.customer.com","route_id":"web"}`); taken.Code != http.StatusConflict || !strings.Contains(taken.Body.String(), string(errCodeDomainTaken)) {
+		t.Fatalf("expected domain_taken, got %d %s", taken.Code, taken.Body.String())
+	}
+
+	if deleted := call(http.MethodDelete, "/api/tenants/default/domains/demo.customer.com", ""); deleted.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d %s", deleted.Code, deleted.Body.String())
+	}
+	if recorder := serve(); recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected detached domain not to be served, got %d", recorder.Code)
+	}
+}
+
+func TestTLSFeatureGatesDomainsAndCertificates(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", PublicBaseURL: "https://gw.proxer.test"}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "web", Target: "http://127.0.0.1:9"}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	sessionID, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	call := func(handler http.HandlerFunc, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+sessionID)
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		return recorder
+	}
+	certPEM, keyPEM := testCertificatePEM(t, "*.customer.com", time.Now().Add(90*24*time.Hour))
+	certBody, _ := json.Marshal(TLSCertificateInput{ID: "customer", Hostname: "*.customer.com", CertPEM: certPEM, KeyPEM: keyPEM, Active: true})
+
+	pro, _ := server.planStore.GetPlan("pro")
+	pro.SelfServe = true
+	if _, err := server.planStore.UpsertPlan(pro); err != nil {
+		t.Fatalf("open pro to self-serve: %v", err)
+	}
+
+	denied := call(server.handleTenantSubresources, http.MethodPost, "/api/tenants/default/domains", `{"hostname":"demo.customer.com","route_id":"web"}`)
+	if denied.Code != http.StatusForbidden || !strings.Contains(denied.Body.String(), string(errCodePlanFeatureUnavailable)) {
+		t.Fatalf("expected free plan attach to be refused, got %d %s", denied.Code, denied.Body.String())
+	}
+	var apiErr apiError
+	if err := json.Unmarshal(denied.Body.Bytes(), &apiErr); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if apiErr.Details["feature"] != planFeatureTLS || apiErr.Details["plan_id"] != "free" || apiErr.Details["upgrade_url"] != "/api/me/plan" {
+		t.Fatalf("unexpected error details: %+v", apiErr.Details)
+	}
+	if upgrades, _ := apiErr.Details["upgrade_plans"].([]any); len(upgrades) == 0 || upgrades[0] != "pro" {
+		t.Fatalf("expected pro as the first upgrade, got %+v", apiErr.Details["upgrade_plans"])
+	}
+
+	if _, err := server.planStore.AssignTenantPlan(DefaultTenantID, "pro", "test"); err != nil {
+		t.Fatalf("assign plan: %v", err)
+	}
+	if attached := call(server.handleTenantSubresources, http.MethodPost, "/api/tenants/default/domains", `{"hostname":"demo.customer.com","route_id":"web"}`); attached.Code != http.StatusOK {
+		t.Fatalf("attach on pro: %d %s", attached.Code, attached.Body.String())
+	}
+
+	// After a downgrade the domain stays attached, but no certificate may be
+	// uploaded or activated for it.
+	if _, err := server.planStore.AssignTenantPlan(DefaultTenantID, "free", "test"); err != nil {
+		t.Fatalf("assign plan: %v", err)
+	}
+	if upload := call(server.handleAdminTLSCertificates, http.MethodPost, "/api/admin/tls/certificates", string(certBody)); upload.Code != http.StatusForbidden || !strings.Contains(upload.Body.String(), string(errCodePlanFeatureUnavailable)) {
+		t.Fatalf("expected certificate upload to be refused, got %d %s", upload.Code, upload.Body.String())
+	}
+	if _, err := server.planStore.AssignTenantPlan(DefaultTenantID, "pro", "test"); err != nil {
+		t.Fatalf("assign plan: %v", err)
+	}
+	if upload := call(server.handleAdminTLSCertificates, http.MethodPost, "/api/admin/tls/certificates", string(certBody)); upload.Code != http.StatusCreated {
+		t.Fatalf("certificate upload on pro: %d %s", upload.Code, upload.Body.String())
+	}
+}
+
+func TestNormalizeDomainHostname(t *testing.T) {
+	for _, invalid := range []string{"", "localhost", "10.0.0.1", "demo.example.com:443", "*.example.com", "-bad.example.com", "a..b"} {
+		if _, err := normalizeDomainHostname(invalid); err == nil {
+			t.Fatalf("expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -7,43 +7,78 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"path"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
 )
 
 type PublicDownloadBinary struct {
-	Platform  string `json:"platform"`
-	Label     string `json:"label"`
-	FileName  string `json:"file_name"`
-	URL       string `json:"url"`
-	SizeBytes int64  `json:"size_bytes,omitempty"`
+	Platform     string `json:"platform"`
+	Arch         string `json:"arch,omitempty"`
+	Label        string `json:"label"`
+	FileName     string `json:"file_name"`
+	URL          string `json:"url"`
+	SizeBytes    int64  `json:"size_bytes,omitempty"`
+	SHA256       string `json:"sha256,omitempty"`
+	SignatureURL string `json:"signature_url,omitempty"`
+	// Verified is set when the release's checksum file and the digest GitHub
+	// computed for the asset agree.
+	Verified bool `json:"verified,omitempty"`
 }
 
 type PublicDownloadsResponse struct {
 	Source          string                 `json:"source"`
 	Available       bool                   `json:"available"`
 	Repo            string                 `json:"repo,omitempty"`
+	Channel         string                 `json:"channel,omitempty"`
 	Tag             string                 `json:"tag,omitempty"`
+	Version         string                 `json:"version,omitempty"`
+	Prerelease      bool                   `json:"prerelease,omitempty"`
 	ReleaseURL      string                 `json:"release_url,omitempty"`
 	ReleaseNotesURL string                 `json:"release_notes_url,omitempty"`
 	ChecksumsURL    string                 `json:"checksums_url,omitempty"`
 	Downloads       []PublicDownloadBinary `json:"downloads"`
 	Message         string                 `json:"message,omitempty"`
 	GeneratedAt     string                 `json:"generated_at"`
+	MinAgentVersion string                 `json:"min_agent_version,omitempty"`
+	// DetectedPlatform, DetectedArch and Recommended describe the caller's
+	// machine; they are filled in per request and never cached.
+	DetectedPlatform string                `json:"detected_platform,omitempty"`
+	DetectedArch     string                `json:"detected_arch,omitempty"`
+	Recommended      *PublicDownloadBinary `json:"recommended,omitempty"`
 }
 
 type githubReleaseAsset struct {
 	Name               string `json:"name"`
 	BrowserDownloadURL string `json:"browser_download_url"`
 	Size               int64  `json:"size"`
+	Digest             string `json:"digest,omitempty"`
 }
 
+const maxChecksumFileBytes = 1 << 20
+
 type githubReleasePayload struct {
-	TagName string               `json:"tag_name"`
-	HTMLURL string               `json:"html_url"`
-	Assets  []githubReleaseAsset `json:"assets"`
+	TagName    string               `json:"tag_name"`
+	HTMLURL    string               `json:"html_url"`
+	Draft      bool                 `json:"draft,omitempty"`
+	Prerelease bool                 `json:"prerelease,omitempty"`
+	Assets     []githubReleaseAsset `json:"assets"`
+}
+
+const (
+	releaseChannelStable = "stable"
+	releaseChannelBeta   = "beta"
+)
+
+const maxBetaReleaseScan = 20
+
+type cachedDownloads struct {
+	at       time.Time
+	response PublicDownloadsResponse
 }
 
 type GitHubReleaseDownloadsProvider struct {
@@ -55,9 +90,8 @@ type GitHubReleaseDownloadsProvider struct {
 	apiBase  string
 	now      func() time.Time
 
-	mu       sync.Mutex
-	cachedAt time.Time
-	cached   PublicDownloadsResponse
+	mu     sync.Mutex
+	cached map[string]cachedDownloads
 }
 
 func NewGitHubReleaseDownloadsProvider(cfg Config) *GitHubReleaseDownloadsProvider {
@@ -75,27 +109,34 @@ func NewGitHubReleaseDownloadsProvider(cfg Config) *GitHubReleaseDownloadsProvid
 }
 
 func (p *GitHubReleaseDownloadsProvider) Resolve(ctx context.Context) PublicDownloadsResponse {
+	return p.ResolveChannel(ctx, releaseChannelStable)
+}
+
+func (p *GitHubReleaseDownloadsProvider) ResolveChannel(ctx context.Context, channel string) PublicDownloadsResponse {
 	now := p.now().UTC()
 
 	p.mu.Lock()
-	if !p.cachedAt.IsZero() && now.Sub(p.cachedAt) < p.cacheTTL {
-		cached := p.cached
+	if cached, ok := p.cached[channel]; ok && now.Sub(cached.at) < p.cacheTTL {
 		p.mu.Unlock()
-		return cached
+		return cached.response
 	}
 	p.mu.Unlock()
 
-	resolved := p.resolveUncached(ctx)
+	resolved := p.resolveUncached(ctx, channel)
+	resolved.Channel = channel
+	resolved.Version = protocol.VersionFromTag(resolved.Tag)
 	resolved.GeneratedAt = now.Format(time.RFC3339)
 
 	p.mu.Lock()
-	p.cached = resolved
-	p.cachedAt = now
+	if p.cached == nil {
+		p.cached = make(map[string]cachedDownloads)
+	}
+	p.cached[channel] = cachedDownloads{at: now, response: resolved}
 	p.mu.Unlock()
 	return resolved
 }
 
-func (p *GitHubReleaseDownloadsProvider) resolveUncached(ctx context.Context) PublicDownloadsResponse {
+func (p *GitHubReleaseDownloadsProvider) resolveUncached(ctx context.Context, channel string) PublicDownloadsResponse {
 	repo := strings.TrimSpace(p.repo)
 	if repo == "" {
 		return unavailableDownloadsResponse("", "download artifacts are not configured yet")
@@ -104,14 +145,67 @@ func (p *GitHubReleaseDownloadsProvider) resolveUncached(ctx context.Context) Pu
 		return unavailableDownloadsResponse(repo, "invalid PROXER_GITHUB_RELEASE_REPO format, expected owner/repo")
 	}
 
-	endpointPath := fmt.Sprintf("/repos/%s/releases/latest", repo)
-	if tag := strings.TrimSpace(p.tag); tag != "" {
-		endpointPath = fmt.Sprintf("/repos/%s/releases/tags/%s", repo, url.PathEscape(tag))
+	var release githubReleasePayload
+	if channel == releaseChannelBeta {
+		var releases []githubReleasePayload
+		if message := p.getGitHubJSON(ctx, fmt.Sprintf("/repos/%s/releases?per_page=%d", repo, maxBetaReleaseScan), &releases); message != "" {
+			return unavailableDownloadsResponse(repo, message)
+		}
+		found := false
+		for _, candidate := range releases {
+			if !candidate.Draft {
+				release, found = candidate, true
+				break
+			}
+		}
+		if !found {
+			return unavailableDownloadsResponse(repo, "no published releases found for the beta channel")
+		}
+	} else {
+		endpointPath := fmt.Sprintf("/repos/%s/releases/latest", repo)
+		if tag := strings.TrimSpace(p.tag); tag != "" {
+			endpointPath = fmt.Sprintf("/repos/%s/releases/tags/%s", repo, url.PathEscape(tag))
+		}
+		if message := p.getGitHubJSON(ctx, endpointPath, &release); message != "" {
+			return unavailableDownloadsResponse(repo, message)
+		}
+	}
+
+	downloads, checksumsURL, releaseNotesURL := mapGitHubReleaseAssets(release.Assets)
+	downloads, problems := p.verifyDownloads(ctx, downloads, release.Assets)
+	if len(downloads) == 0 {
+		return PublicDownloadsResponse{
+			Source:          "github_releases",
+			Available:       false,
+			Repo:            repo,
+			Tag:             strings.TrimSpace(release.TagName),
+			ReleaseURL:      strings.TrimSpace(release.HTMLURL),
+			ChecksumsURL:    checksumsURL,
+			ReleaseNotesURL: releaseNotesURL,
+			Downloads:       []PublicDownloadBinary{},
+			Message:         "no desktop binaries found in the configured release",
+		}
 	}
+
+	return PublicDownloadsResponse{
+		Source:          "github_releases",
+		Available:       true,
+		Repo:            repo,
+		Tag:             strings.TrimSpace(release.TagName),
+		Prerelease:      release.Prerelease,
+		ReleaseURL:      strings.TrimSpace(release.HTMLURL),
+		ReleaseNotesURL: releaseNotesURL,
+		ChecksumsURL:    checksumsURL,
+		Downloads:       downloads,
+		Message:         strings.Join(problems, "; "),
+	}
+}
+
+func (p *GitHubReleaseDownloadsProvider) getGitHubJSON(ctx context.Context, endpointPath string, out any) string {
 	requestURL := strings.TrimRight(p.apiBase, "/") + endpointPath
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 	if err != nil {
-		return unavailableDownloadsResponse(repo, fmt.Sprintf("build GitHub release request: %v", err))
+		return fmt.Sprintf("build GitHub release request: %v", err)
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
 	req.Header.Set("User-Agent", "proxer-gateway")
@@ -121,7 +215,7 @@ func (p *GitHubReleaseDownloadsProvider) resolveUncached(ctx context.Context) Pu
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return unavailableDownloadsResponse(repo, fmt.Sprintf("fetch release from GitHub: %v", err))
+		return fmt.Sprintf("fetch release from GitHub: %v", err)
 	}
 	defer resp.Body.Close()
 
@@ -131,39 +225,111 @@ func (p *GitHubReleaseDownloadsProvider) resolveUncached(ctx context.Context) Pu
 		if message == "" {
 			message = resp.Status
 		}
-		return unavailableDownloadsResponse(repo, fmt.Sprintf("GitHub release lookup failed: %s", message))
+		return fmt.Sprintf("GitHub release lookup failed: %s", message)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Sprintf("decode GitHub release payload: %v", err)
 	}
+	return ""
+}
 
-	var release githubReleasePayload
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return unavailableDownloadsResponse(repo, fmt.Sprintf("decode GitHub release payload: %v", err))
+// verifyDownloads fills in SHA256 sums from the release's checksum files and
+// drops binaries whose sum disagrees with the digest GitHub reports, so a
+// tampered or half-uploaded asset is never offered. It returns what could
+// not be checked.
+func (p *GitHubReleaseDownloadsProvider) verifyDownloads(ctx context.Context, downloads []PublicDownloadBinary, assets []githubReleaseAsset) ([]PublicDownloadBinary, []string) {
+	problems := make([]string, 0)
+	sums := make(map[string]string)
+	digests := make(map[string]string)
+	for _, asset := range assets {
+		name := strings.TrimSpace(asset.Name)
+		if digest, ok := strings.CutPrefix(strings.ToLower(strings.TrimSpace(asset.Digest)), "sha256:"); ok {
+			digests[name] = digest
+		}
+		if !isChecksumAsset(strings.ToLower(name)) || strings.TrimSpace(asset.BrowserDownloadURL) == "" {
+			continue
+		}
+		parsed, err := p.fetchChecksums(ctx, asset.BrowserDownloadURL)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("read %s: %v", name, err))
+			continue
+		}
+		for file, sum := range parsed {
+			sums[file] = sum
+		}
 	}
 
-	downloads, checksumsURL, releaseNotesURL := mapGitHubReleaseAssets(release.Assets)
-	if len(downloads) == 0 {
-		return PublicDownloadsResponse{
-			Source:          "github_releases",
-			Available:       false,
-			Repo:            repo,
-			Tag:             strings.TrimSpace(release.TagName),
-			ReleaseURL:      strings.TrimSpace(release.HTMLURL),
-			ChecksumsURL:    checksumsURL,
-			ReleaseNotesURL: releaseNotesURL,
-			Downloads:       []PublicDownloadBinary{},
-			Message:         "no desktop binaries found in the configured release",
+	verified := make([]PublicDownloadBinary, 0, len(downloads))
+	for _, binary := range downloads {
+		sum, digest := sums[binary.FileName], digests[binary.FileName]
+		if sum != "" && digest != "" && sum != digest {
+			problems = append(problems, fmt.Sprintf("%s withheld: checksum does not match the published asset", binary.FileName))
+			continue
+		}
+		if sum == "" {
+			sum = digest
 		}
+		binary.SHA256 = sum
+		binary.Verified = sums[binary.FileName] != "" && digest != ""
+		verified = append(verified, binary)
 	}
+	return verified, problems
+}
 
-	return PublicDownloadsResponse{
-		Source:          "github_releases",
-		Available:       true,
-		Repo:            repo,
-		Tag:             strings.TrimSpace(release.TagName),
-		ReleaseURL:      strings.TrimSpace(release.HTMLURL),
-		ReleaseNotesURL: releaseNotesURL,
-		ChecksumsURL:    checksumsURL,
-		Downloads:       downloads,
+func (p *GitHubReleaseDownloadsProvider) fetchChecksums(ctx context.Context, assetURL string) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, assetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "proxer-gateway")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxChecksumFileBytes))
+	if err != nil {
+		return nil, err
+	}
+	return parseChecksums(string(body)), nil
+}
+
+// parseChecksums reads sha256sum output ("<hex>  <file>", with "*" before
+// binary-mode names) and BSD-style "SHA256 (<file>) = <hex>" lines. Other
+// lines are ignored.
+func parseChecksums(text string) map[string]string {
+	sums := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		var sum, file string
+		if rest, ok := strings.CutPrefix(line, "SHA256 ("); ok {
+			name, hexSum, found := strings.Cut(rest, ") = ")
+			if !found {
+				continue
+			}
+			file, sum = name, hexSum
+		} else {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			sum, file = fields[0], strings.TrimPrefix(fields[1], "*")
+		}
+		sum = strings.ToLower(strings.TrimSpace(sum))
+		if len(sum) != 64 || strings.Trim(sum, "0123456789abcdef") != "" {
+			continue
+		}
+		sums[path.Base(strings.TrimSpace(file))] = sum
+	}
+	return sums
+}
+
+func isChecksumAsset(lowerName string) bool {
+	return (strings.Contains(lowerName, "checksum") && strings.HasSuffix(lowerName, ".txt")) ||
+		strings.HasSuffix(lowerName, "sha256sums") || strings.HasSuffix(lowerName, "sha256sums.txt")
 }
 
 func unavailableDownloadsResponse(repo, message string) PublicDownloadsResponse {
@@ -178,6 +344,7 @@ func unavailableDownloadsResponse(repo, message string) PublicDownloadsResponse
 
 func mapGitHubReleaseAssets(assets []githubReleaseAsset) ([]PublicDownloadBinary, string, string) {
 	byPlatform := map[string]PublicDownloadBinary{}
+	signatures := map[string]string{}
 	checksumsURL := ""
 	releaseNotesURL := ""
 	for _, asset := range assets {
@@ -188,8 +355,14 @@ func mapGitHubReleaseAssets(assets []githubReleaseAsset) ([]PublicDownloadBinary
 		}
 
 		lower := strings.ToLower(name)
-		if checksumsURL == "" && strings.Contains(lower, "checksum") && strings.HasSuffix(lower, ".txt") {
-			checksumsURL = assetURL
+		if signed, ok := signedAssetName(name); ok {
+			signatures[signed] = assetURL
+			continue
+		}
+		if isChecksumAsset(lower) {
+			if checksumsURL == "" {
+				checksumsURL = assetURL
+			}
 			continue
 		}
 		if releaseNotesURL == "" && strings.Contains(lower, "release-notes") && strings.HasSuffix(lower, ".md") {
@@ -201,11 +374,17 @@ func mapGitHubReleaseAssets(assets []githubReleaseAsset) ([]PublicDownloadBinary
 		if platform == "" {
 			continue
 		}
-		if _, exists := byPlatform[platform]; exists {
+		arch := classifyAssetArch(lower)
+		key := platform + "/" + arch
+		if _, exists := byPlatform[key]; exists {
 			continue
 		}
-		byPlatform[platform] = PublicDownloadBinary{
+		if arch != "" {
+			label += " (" + arch + ")"
+		}
+		byPlatform[key] = PublicDownloadBinary{
 			Platform:  platform,
+			Arch:      arch,
 			Label:     label,
 			FileName:  name,
 			URL:       assetURL,
@@ -213,23 +392,96 @@ func mapGitHubReleaseAssets(assets []githubReleaseAsset) ([]PublicDownloadBinary
 		}
 	}
 
-	order := []string{"macos", "linux", "windows"}
+	order := map[string]int{"macos": 0, "linux": 1, "windows": 2}
 	downloads := make([]PublicDownloadBinary, 0, len(byPlatform))
-	for _, platform := range order {
-		if binary, ok := byPlatform[platform]; ok {
-			downloads = append(downloads, binary)
-			delete(byPlatform, platform)
+	for _, binary := range byPlatform {
+		binary.SignatureURL = signatures[binary.FileName]
+		downloads = append(downloads, binary)
+	}
+	sort.Slice(downloads, func(i, j int) bool {
+		left, right := downloads[i], downloads[j]
+		if left.Platform != right.Platform {
+			leftOrder, leftKnown := order[left.Platform]
+			rightOrder, rightKnown := order[right.Platform]
+			if leftKnown != rightKnown {
+				return leftKnown
+			}
+			if leftKnown {
+				return leftOrder < rightOrder
+			}
+			return left.Platform < right.Platform
 		}
+		return left.Arch < right.Arch
+	})
+	return downloads, checksumsURL, releaseNotesURL
+}
+
+func signedAssetName(name string) (string, bool) {
+	lower := strings.ToLower(name)
+	for _, suffix := range []string{".sig", ".asc", ".minisig"} {
+		if strings.HasSuffix(lower, suffix) {
+			return name[:len(name)-len(suffix)], true
+		}
+	}
+	return "", false
+}
+
+func classifyAssetArch(lowerName string) string {
+	switch {
+	case strings.Contains(lowerName, "arm64") || strings.Contains(lowerName, "aarch64"):
+		return "arm64"
+	case strings.Contains(lowerName, "x86_64") || strings.Contains(lowerName, "amd64") || strings.Contains(lowerName, "x64"):
+		return "amd64"
+	case strings.Contains(lowerName, "universal"):
+		return "universal"
+	}
+	return ""
+}
+
+func detectClientPlatform(r *http.Request) (platform, arch string) {
+	hintPlatform := strings.ToLower(strings.Trim(r.Header.Get("Sec-CH-UA-Platform"), `" `))
+	hintArch := strings.ToLower(strings.Trim(r.Header.Get("Sec-CH-UA-Arch"), `" `))
+	agent := strings.ToLower(r.UserAgent())
+
+	switch {
+	case hintPlatform == "macos" || (hintPlatform == "" && (strings.Contains(agent, "macintosh") || strings.Contains(agent, "mac os x"))):
+		platform = "macos"
+	case hintPlatform == "windows" || (hintPlatform == "" && strings.Contains(agent, "windows")):
+		platform = "windows"
+	case hintPlatform == "linux" || (hintPlatform == "" && strings.Contains(agent, "linux") && !strings.Contains(agent, "android")):
+		platform = "linux"
+	}
+	switch {
+	case hintArch == "arm" || strings.Contains(agent, "aarch64") || strings.Contains(agent, "arm64"):
+		arch = "arm64"
+	case hintArch == "x86" || strings.Contains(agent, "x86_64") || strings.Contains(agent, "win64") || strings.Contains(agent, "x64"):
+		arch = "amd64"
 	}
-	remaining := make([]string, 0, len(byPlatform))
-	for platform := range byPlatform {
-		remaining = append(remaining, platform)
+
+	if value := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("platform"))); value != "" {
+		platform = value
 	}
-	sort.Strings(remaining)
-	for _, platform := range remaining {
-		downloads = append(downloads, byPlatform[platform])
+	if value := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("arch"))); value != "" {
+		arch = value
 	}
-	return downloads, checksumsURL, releaseNotesURL
+	return platform, arch
+}
+
+func recommendDownload(downloads []PublicDownloadBinary, platform, arch string) *PublicDownloadBinary {
+	var fallback *PublicDownloadBinary
+	for i := range downloads {
+		binary := downloads[i]
+		if binary.Platform != platform {
+			continue
+		}
+		if arch != "" && binary.Arch == arch {
+			return &binary
+		}
+		if fallback == nil || (fallback.Arch != "" && fallback.Arch != "universal" && (binary.Arch == "" || binary.Arch == "universal")) {
+			fallback = &binary
+		}
+	}
+	return fallback
 }
 
 func classifyDesktopAsset(lowerName string) (platform, label string) {
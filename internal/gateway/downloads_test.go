@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -102,3 +103,134 @@ func TestGitHubReleaseDownloadsProviderResolveFromGitHubAPI(t *testing.T) {
 		t.Fatalf("expected 3 platform downloads, got %d", len(payload.Downloads))
 	}
 }
+
+func TestGitHubReleaseDownloadsVerifyChecksumsAndRecommendPlatform(t *testing.T) {
+	linuxSum := strings.Repeat("a", 64)
+	armSum := strings.Repeat("b", 64)
+	windowsSum := strings.Repeat("c", 64)
+	releasePayload, err := json.Marshal(githubReleasePayload{
+		TagName: "desktop-agent-v1.3.0",
+		Assets: []githubReleaseAsset{
+			{Name: "proxer-agent-v1.3.0-x86_64.AppImage", BrowserDownloadURL: "https://example.com/linux-amd64.AppImage", Digest: "sha256:" + linuxSum},
+			{Name: "proxer-agent-v1.3.0-x86_64.AppImage.sig", BrowserDownloadURL: "https://example.com/linux-amd64.AppImage.sig"},
+			{Name: "proxer-agent-v1.3.0-aarch64.AppImage", BrowserDownloadURL: "https://example.com/linux-arm64.AppImage"},
+			{Name: "proxer-agent-v1.3.0-x64.msi", BrowserDownloadURL: "https://example.com/windows.msi", Digest: "sha256:" + strings.Repeat("d", 64)},
+			{Name: "linux-checksums.txt", BrowserDownloadURL: "https://example.com/linux-checksums.txt"},
+			{Name: "windows-checksums.txt", BrowserDownloadURL: "https://example.com/windows-checksums.txt"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal release payload: %v", err)
+	}
+	bodies := map[string]string{
+		"/repos/acme/proxer/releases/latest": string(releasePayload),
+		"/linux-checksums.txt":               linuxSum + "  proxer-agent-v1.3.0-x86_64.AppImage\n" + armSum + " *proxer-agent-v1.3.0-aarch64.AppImage\n",
+		"/windows-checksums.txt":             "SHA256 (proxer-agent-v1.3.0-x64.msi) = " + windowsSum + "\n",
+	}
+	provider := &GitHubReleaseDownloadsProvider{
+		repo:     "acme/proxer",
+		cacheTTL: time.Minute,
+		client: &http.Client{
+			Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				body, ok := bodies[req.URL.Path]
+				if !ok {
+					t.Fatalf("unexpected request %s", req.URL)
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+			}),
+		},
+		apiBase: "https://api.github.test",
+		now:     func() time.Time { return time.Now().UTC() },
+	}
+
+	payload := provider.Resolve(context.Background())
+	if len(payload.Downloads) != 2 {
+		t.Fatalf("expected the mismatched windows binary to be withheld, got %+v", payload.Downloads)
+	}
+	if !strings.Contains(payload.Message, "proxer-agent-v1.3.0-x64.msi withheld") {
+		t.Fatalf("expected the mismatch to be reported, got %q", payload.Message)
+	}
+	amd64, arm64 := payload.Downloads[0], payload.Downloads[1]
+	if amd64.Arch != "amd64" || amd64.SHA256 != linuxSum || !amd64.Verified || amd64.SignatureURL != "https://example.com/linux-amd64.AppImage.sig" {
+		t.Fatalf("unexpected amd64 download %+v", amd64)
+	}
+	if arm64.Arch != "arm64" || arm64.SHA256 != armSum || arm64.Verified {
+		t.Fatalf("expected the arm64 sum without a GitHub digest to be unverified, got %+v", arm64)
+	}
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	server.downloads = provider
+	req := httptest.NewRequest(http.MethodGet, "/api/public/downloads", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux aarch64) AppleWebKit/537.36")
+	recorder := httptest.NewRecorder()
+	server.handlePublicDownloads(recorder, req)
+	var detected PublicDownloadsResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &detected); err != nil {
+		t.Fatalf("decode downloads: %v", err)
+	}
+	if detected.DetectedPlatform != "linux" || detected.DetectedArch != "arm64" || detected.Recommended == nil || detected.Recommended.Arch != "arm64" {
+		t.Fatalf("unexpected platform detection %+v", detected)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/public/downloads?platform=linux", nil)
+	req.Header.Set("Sec-CH-UA-Platform", `"Windows"`)
+	req.Header.Set("Sec-CH-UA-Arch", `"x86"`)
+	recorder = httptest.NewRecorder()
+	server.handlePublicDownloads(recorder, req)
+	detected = PublicDownloadsResponse{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &detected); err != nil {
+		t.Fatalf("decode downloads: %v", err)
+	}
+	if detected.DetectedPlatform != "linux" || detected.Recommended == nil || detected.Recommended.Arch != "amd64" {
+		t.Fatalf("expected the platform query to override client hints, got %+v", detected)
+	}
+}
+
+func TestGitHubReleaseDownloadsBetaChannelIncludesPrereleases(t *testing.T) {
+	releases, err := json.Marshal([]githubReleasePayload{
+		{TagName: "desktop-agent-v1.4.0-beta.2", Draft: true},
+		{TagName: "desktop-agent-v1.4.0-beta.1", Prerelease: true, Assets: []githubReleaseAsset{
+			{Name: "proxer-agent-v1.4.0-beta.1-x86_64.AppImage", BrowserDownloadURL: "https://example.com/beta.appimage", Size: 22},
+		}},
+		{TagName: "desktop-agent-v1.3.0"},
+	})
+	if err != nil {
+		t.Fatalf("marshal releases: %v", err)
+	}
+	latest, err := json.Marshal(githubReleasePayload{TagName: "desktop-agent-v1.3.0", Assets: []githubReleaseAsset{
+		{Name: "proxer-agent-v1.3.0-x86_64.AppImage", BrowserDownloadURL: "https://example.com/stable.appimage", Size: 22},
+	}})
+	if err != nil {
+		t.Fatalf("marshal latest release: %v", err)
+	}
+
+	provider := &GitHubReleaseDownloadsProvider{
+		repo:     "acme/proxer",
+		cacheTTL: time.Minute,
+		client: &http.Client{
+			Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				body := ""
+				switch req.URL.Path {
+				case "/repos/acme/proxer/releases/latest":
+					body = string(latest)
+				case "/repos/acme/proxer/releases":
+					body = string(releases)
+				default:
+					t.Fatalf("unexpected request path %q", req.URL.Path)
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+			}),
+		},
+		apiBase: "https://api.github.test",
+		now:     func() time.Time { return time.Now().UTC() },
+	}
+
+	stable := provider.ResolveChannel(context.Background(), releaseChannelStable)
+	if stable.Version != "1.3.0" || stable.Prerelease || stable.Channel != releaseChannelStable {
+		t.Fatalf("unexpected stable channel %+v", stable)
+	}
+	beta := provider.ResolveChannel(context.Background(), releaseChannelBeta)
+	if beta.Version != "1.4.0-beta.1" || !beta.Prerelease || beta.Channel != releaseChannelBeta || len(beta.Downloads) != 1 {
+		t.Fatalf("expected the newest non-draft prerelease on beta, got %+v", beta)
+	}
+}
@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDryRunValidatesWithoutStoring(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	if _, err := server.connectorStore.Create(Connector{ID: "edge", TenantID: DefaultTenantID, Name: "edge"}); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+	session, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+session)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := call(http.MethodPost, "/api/tenants/default/routes?dry_run=true", `{"id":"app","connector_id":"edge","local_port":3000}`)
+	var payload struct {
+		DryRun bool      `json:"dry_run"`
+		Route  routeView `json:"route"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil || recorder.Code != http.StatusOK || !payload.DryRun || payload.Route.ID != "app" {
+		t.Fatalf("expected the route it would store, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if _, ok := server.ruleStore.GetForTenant(DefaultTenantID, "app"); ok {
+		t.Fatalf("expected the dry run not to store the route")
+	}
+	if versions := server.routeVersionEvents(DefaultTenantID, "app"); len(versions) != 0 {
+		t.Fatalf("expected no route version, got %+v", versions)
+	}
+	if recorder := call(http.MethodPost, "/api/tenants/default/routes?dry_run=true", `{"id":"app","connector_id":"missing","local_port":3000}`); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected an unknown connector to be rejected, got %d", recorder.Code)
+	}
+	if recorder := call(http.MethodPost, "/api/rules?dry_run=true", `{"id":"legacy","target":"http://127.0.0.1:9"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("expected the legacy rule dry run to pass, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	if recorder := call(http.MethodPost, "/api/connectors?dry_run=true", `{"id":"edge","name":"again"}`); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected a duplicate connector to be rejected, got %d", recorder.Code)
+	}
+	if recorder := call(http.MethodPost, "/api/connectors?dry_run=true", `{"id":"lab","name":"lab"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("connector dry run: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if _, ok := server.connectorStore.Get("lab"); ok {
+		t.Fatalf("expected the dry run not to store the connector")
+	}
+	if recorder := call(http.MethodPatch, "/api/connectors/edge?dry_run=true", `{"max_bytes_per_second":1024}`); recorder.Code != http.StatusOK {
+		t.Fatalf("connector patch dry run: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if connector, _ := server.connectorStore.Get("edge"); connector.MaxBytesPerSecond != 0 {
+		t.Fatalf("expected the dry run not to update the connector, got %+v", connector)
+	}
+
+	if recorder := call(http.MethodPost, "/api/admin/plans?dry_run=true", `{"id":"team","name":"Team","max_routes":5}`); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected an incomplete plan to be rejected, got %d", recorder.Code)
+	}
+	if recorder := call(http.MethodPost, "/api/admin/plans?dry_run=true", `{"id":"team","name":"Team","max_routes":5,"max_connectors":2,"max_rps":10,"max_monthly_gb":5}`); recorder.Code != http.StatusOK {
+		t.Fatalf("plan dry run: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if _, ok := server.planStore.GetPlan("team"); ok {
+		t.Fatalf("expected the dry run not to store the plan")
+	}
+}
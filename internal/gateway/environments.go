@@ -0,0 +1,321 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultEnvironmentName = "default"
+
+var ErrEnvironmentInUse = errors.New("environment is referenced by routes")
+
+var environmentVariablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func newDefaultEnvironments(tenantID string, now time.Time) map[string]TenantEnvironment {
+	return map[string]TenantEnvironment{
+		DefaultEnvironmentName: {
+			TenantID:    tenantID,
+			Name:        DefaultEnvironmentName,
+			Scheme:      "http",
+			Host:        "host.docker.internal",
+			DefaultPort: 3000,
+			Variables:   map[string]string{},
+			UpdatedAt:   now,
+		},
+	}
+}
+
+func normalizeEnvironmentName(name string) string {
+	name = normalizeIdentifier(name)
+	if name == "" {
+		return DefaultEnvironmentName
+	}
+	return name
+}
+
+func (s *RuleStore) GetNamedEnvironment(tenantID, name string) (TenantEnvironment, bool) {
+	tenantID = normalizeIdentifier(tenantID)
+	if tenantID == "" {
+		return TenantEnvironment{}, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	env, ok := s.envs[tenantID][normalizeEnvironmentName(name)]
+	if !ok {
+		return TenantEnvironment{}, false
+	}
+	env.Variables = copyStringMap(env.Variables)
+	return env, true
+}
+
+func (s *RuleStore) ListEnvironments(tenantID string) []TenantEnvironment {
+	tenantID = normalizeIdentifier(tenantID)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	envs := make([]TenantEnvironment, 0, len(s.envs[tenantID]))
+	for _, env := range s.envs[tenantID] {
+		env.Variables = copyStringMap(env.Variables)
+		envs = append(envs, env)
+	}
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Name < envs[j].Name })
+	return envs
+}
+
+func (s *RuleStore) DeleteEnvironment(tenantID, name string) (bool, error) {
+	tenantID = normalizeIdentifier(tenantID)
+	name = normalizeEnvironmentName(name)
+	if name == DefaultEnvironmentName {
+		return false, fmt.Errorf("the %s environment cannot be deleted", DefaultEnvironmentName)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.envs[tenantID][name]; !ok {
+		return false, nil
+	}
+	routes := make([]string, 0)
+	for _, rule := range s.rules {
+		if rule.TenantID == tenantID && rule.Environment == name {
+			routes = append(routes, rule.ID)
+		}
+	}
+	if len(routes) > 0 {
+		sort.Strings(routes)
+		return false, fmt.Errorf("%w: %s", ErrEnvironmentInUse, strings.Join(routes, ", "))
+	}
+	delete(s.envs[tenantID], name)
+	return true, nil
+}
+
+// resolveEnvironmentRoutesLocked re-resolves the templates of every route
+// using env and returns the updated routes by key. It fails without side
+// effects if any of them no longer resolves.
+func (s *RuleStore) resolveEnvironmentRoutesLocked(env TenantEnvironment) (map[string]Rule, error) {
+	resolver := newEnvironmentResolver(env)
+	resolved := make(map[string]Rule)
+	for key, rule := range s.rules {
+		if rule.TenantID != env.TenantID || normalizeEnvironmentName(rule.Environment) != env.Name {
+			continue
+		}
+		if rule.TargetTemplate == "" && rule.BasePathTemplate == "" {
+			continue
+		}
+		// Connector routes without their own target show one built from the
+		// base path, which has to follow it.
+		derived := rule.UsesConnector() && rule.Target == connectorTarget(rule.LocalScheme, rule.LocalHost, rule.LocalPort, rule.LocalSocket, rule.LocalBasePath)
+		if rule.BasePathTemplate != "" {
+			basePath, err := resolver.expand(rule.BasePathTemplate)
+			if err != nil {
+				return nil, fmt.Errorf("route %q local_base_path: %w", rule.ID, err)
+			}
+			if basePath != "" && !strings.HasPrefix(basePath, "/") && rule.UsesConnector() {
+				basePath = "/" + basePath
+			}
+			rule.LocalBasePath = basePath
+		}
+		if rule.TargetTemplate != "" {
+			target, err := resolver.expand(rule.TargetTemplate)
+			if err != nil {
+				return nil, fmt.Errorf("route %q target: %w", rule.ID, err)
+			}
+			if !rule.UsesConnector() {
+				if err := validateTargetURL(target); err != nil {
+					return nil, fmt.Errorf("route %q: %w", rule.ID, err)
+				}
+			}
+			rule.Target = target
+		} else if derived {
+			rule.Target = connectorTarget(rule.LocalScheme, rule.LocalHost, rule.LocalPort, rule.LocalSocket, rule.LocalBasePath)
+		}
+		resolved[key] = rule
+	}
+	return resolved, nil
+}
+
+func hasEnvironmentReference(value string) bool {
+	return strings.Contains(value, "${")
+}
+
+// validateEnvironmentVariables checks variable names and that every variable
+// resolves, so references to unknown variables and cycles are refused when
+// the environment is saved rather than when a route uses it.
+func validateEnvironmentVariables(env TenantEnvironment) error {
+	names := make([]string, 0, len(env.Variables))
+	for name := range env.Variables {
+		if !environmentVariablePattern.MatchString(name) {
+			return fmt.Errorf("invalid variable name %q (allowed: letters, numbers and _, not starting with a number)", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	resolver := newEnvironmentResolver(env)
+	for _, name := range names {
+		if _, err := resolver.lookup(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// environmentResolver expands ${NAME} references against an environment.
+// Variables may reference each other; SCHEME, HOST and PORT resolve to the
+// environment's own settings unless a variable overrides them. Values come
+// only from the environment, never from the gateway's process environment.
+type environmentResolver struct {
+	env      TenantEnvironment
+	resolved map[string]string
+	visiting []string
+}
+
+func newEnvironmentResolver(env TenantEnvironment) *environmentResolver {
+	return &environmentResolver{env: env, resolved: make(map[string]string)}
+}
+
+func (r *environmentResolver) lookup(name string) (string, error) {
+	if value, ok := r.resolved[name]; ok {
+		return value, nil
+	}
+	raw, ok := r.env.Variables[name]
+	if !ok {
+		switch name {
+		case "SCHEME":
+			return r.env.Scheme, nil
+		case "HOST":
+			return r.env.Host, nil
+		case "PORT":
+			return strconv.Itoa(r.env.DefaultPort), nil
+		}
+		return "", fmt.Errorf("environment %q has no variable %q", r.env.Name, name)
+	}
+	for i, visiting := range r.visiting {
+		if visiting == name {
+			cycle := append(append([]string{}, r.visiting[i:]...), name)
+			return "", fmt.Errorf("environment %q variables form a cycle: %s", r.env.Name, strings.Join(cycle, " -> "))
+		}
+	}
+	r.visiting = append(r.visiting, name)
+	value, err := r.expand(raw)
+	r.visiting = r.visiting[:len(r.visiting)-1]
+	if err != nil {
+		return "", err
+	}
+	r.resolved[name] = value
+	return value, nil
+}
+
+func (r *environmentResolver) expand(template string) (string, error) {
+	var expanded strings.Builder
+	for {
+		start := strings.Index(template, "${")
+		if start < 0 {
+			expanded.WriteString(template)
+			return expanded.String(), nil
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference in %q", template)
+		}
+		name := template[start+2 : start+end]
+		if !environmentVariablePattern.MatchString(name) {
+			return "", fmt.Errorf("invalid variable reference %q", "${"+name+"}")
+		}
+		value, err := r.lookup(name)
+		if err != nil {
+			return "", err
+		}
+		expanded.WriteString(template[:start])
+		expanded.WriteString(value)
+		template = template[start+end+1:]
+	}
+}
+
+func (s *Server) handleTenantEnvironments(w http.ResponseWriter, r *http.Request, tenantID string) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.ruleStore.HasTenant(tenantID) {
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id":    tenantID,
+		"environments": s.ruleStore.ListEnvironments(tenantID),
+	})
+}
+
+func (s *Server) handleTenantEnvironmentByName(w http.ResponseWriter, r *http.Request, user User, tenantID, name string) {
+	if !s.ruleStore.HasTenant(tenantID) {
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		env, ok := s.ruleStore.GetNamedEnvironment(tenantID, name)
+		if !ok {
+			writeAPIError(w, http.StatusNotFound, errCodeNotFound, "environment not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"tenant_id":   tenantID,
+			"environment": env,
+		})
+	case http.MethodPut:
+		if !s.canMutateTenantConfig(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAdminRequired, "forbidden tenant configuration access")
+			return
+		}
+		var request upsertEnvironmentRequest
+		if !s.decodeJSON(w, r, &request, "environment payload") {
+			return
+		}
+		env, err := s.ruleStore.UpsertEnvironment(TenantEnvironment{
+			TenantID:    tenantID,
+			Name:        name,
+			Scheme:      request.Scheme,
+			Host:        request.Host,
+			DefaultPort: request.DefaultPort,
+			Variables:   request.Variables,
+		})
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message":     "environment upserted",
+			"tenant_id":   tenantID,
+			"environment": env,
+		})
+		s.persistState()
+	case http.MethodDelete:
+		if !s.canMutateTenantConfig(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAdminRequired, "forbidden tenant configuration access")
+			return
+		}
+		deleted, err := s.ruleStore.DeleteEnvironment(tenantID, name)
+		if err != nil {
+			writeAPIError(w, http.StatusConflict, errCodeConflict, err.Error())
+			return
+		}
+		if !deleted {
+			writeAPIError(w, http.StatusNotFound, errCodeNotFound, "environment not found")
+			return
+		}
+		s.persistState()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+	}
+}
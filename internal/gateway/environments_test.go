@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRoutesResolveNamedEnvironmentVariables(t *testing.T) {
+	store := NewRuleStore()
+	staging := TenantEnvironment{
+		TenantID: DefaultTenantID,
+		Name:     "staging",
+		Host:     "staging.internal",
+		Variables: map[string]string{
+			"API_HOST": "api.${HOST}",
+			"API_URL":  "${SCHEME}://${API_HOST}:${PORT}",
+		},
+	}
+	if _, err := store.UpsertEnvironment(staging); err != nil {
+		t.Fatalf("upsert environment: %v", err)
+	}
+
+	rule, err := store.UpsertForTenant(DefaultTenantID, Rule{ID: "api", Environment: "staging", Target: "${API_URL}/v1"})
+	if err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	if rule.Target != "http://api.staging.internal:80/v1" || rule.TargetTemplate != "${API_URL}/v1" {
+		t.Fatalf("expected the target to be resolved from the environment, got %q from %q", rule.Target, rule.TargetTemplate)
+	}
+
+	staging.Scheme = "https"
+	if _, err := store.UpsertEnvironment(staging); err != nil {
+		t.Fatalf("update environment: %v", err)
+	}
+	rule, _ = store.GetForTenant(DefaultTenantID, "api")
+	if rule.Target != "https://api.staging.internal:443/v1" {
+		t.Fatalf("expected the route to follow the environment, got %q", rule.Target)
+	}
+
+	if _, err := store.UpsertForTenant(DefaultTenantID, Rule{ID: "missing", Environment: "staging", Target: "${NOPE}"}); err == nil {
+		t.Fatal("expected a reference to an unknown variable to be refused")
+	}
+	if _, err := store.UpsertForTenant(DefaultTenantID, Rule{ID: "other", Environment: "prod", Target: "http://example.com"}); err == nil {
+		t.Fatal("expected an unknown environment to be refused")
+	}
+
+	delete(staging.Variables, "API_HOST")
+	if _, err := store.UpsertEnvironment(staging); err == nil {
+		t.Fatal("expected a change leaving a route unresolvable to be refused")
+	}
+	if _, err := store.DeleteEnvironment(DefaultTenantID, "staging"); !errors.Is(err, ErrEnvironmentInUse) {
+		t.Fatalf("expected deleting a referenced environment to fail, got %v", err)
+	}
+}
+
+func TestEnvironmentVariableCyclesAreRefused(t *testing.T) {
+	store := NewRuleStore()
+	_, err := store.UpsertEnvironment(TenantEnvironment{
+		TenantID:  DefaultTenantID,
+		Name:      "dev",
+		Host:      "localhost",
+		Variables: map[string]string{"A": "${B}", "B": "x${C}", "C": "${A}"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "A -> B -> C -> A") {
+		t.Fatalf("expected the cycle to be reported, got %v", err)
+	}
+}
+
+func TestConnectorBasePathsFollowTheEnvironment(t *testing.T) {
+	store := NewRuleStore()
+	env := TenantEnvironment{TenantID: DefaultTenantID, Host: "localhost", Variables: map[string]string{"VERSION": "v1"}}
+	if _, err := store.UpsertEnvironment(env); err != nil {
+		t.Fatalf("upsert environment: %v", err)
+	}
+	rule, err := store.UpsertForTenant(DefaultTenantID, Rule{ID: "app", ConnectorID: "laptop", LocalPort: 3000, LocalBasePath: "/api/${VERSION}"})
+	if err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	if rule.LocalBasePath != "/api/v1" || rule.Target != "http://127.0.0.1:3000/api/v1" {
+		t.Fatalf("expected the base path to be resolved, got %q and %q", rule.LocalBasePath, rule.Target)
+	}
+
+	env.Variables["VERSION"] = "v2"
+	if _, err := store.UpsertEnvironment(env); err != nil {
+		t.Fatalf("update environment: %v", err)
+	}
+	rule, _ = store.GetForTenant(DefaultTenantID, "app")
+	if rule.LocalBasePath != "/api/v2" || rule.Target != "http://127.0.0.1:3000/api/v2" {
+		t.Fatalf("expected the base path to follow the environment, got %q and %q", rule.LocalBasePath, rule.Target)
+	}
+}
@@ -0,0 +1,165 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"net/http"
+	"strings"
+	texttemplate "text/template"
+)
+
+const (
+	errorPageConnectorOffline = "connector_offline"
+	errorPageTimeout          = "timeout"
+	errorPageRateLimited      = "rate_limited"
+
+	maxErrorPageTemplateBytes = 64 << 10
+)
+
+type ErrorPages struct {
+	Format           string `json:"format"`
+	ConnectorOffline string `json:"connector_offline,omitempty"`
+	Timeout          string `json:"timeout,omitempty"`
+	RateLimited      string `json:"rate_limited,omitempty"`
+}
+
+type errorPageData struct {
+	RequestID string
+	TenantID  string
+	RouteID   string
+	Status    int
+	Error     string
+	Message   string
+}
+
+func normalizeErrorPages(input *ErrorPages) (*ErrorPages, error) {
+	if input == nil {
+		return nil, nil
+	}
+	pages := &ErrorPages{
+		Format:           strings.ToLower(strings.TrimSpace(input.Format)),
+		ConnectorOffline: strings.TrimSpace(input.ConnectorOffline),
+		Timeout:          strings.TrimSpace(input.Timeout),
+		RateLimited:      strings.TrimSpace(input.RateLimited),
+	}
+	if pages.Format == "" {
+		pages.Format = "html"
+	}
+	if pages.Format != "html" && pages.Format != "json" {
+		return nil, fmt.Errorf("error_pages.format must be html or json")
+	}
+	if pages.ConnectorOffline == "" && pages.Timeout == "" && pages.RateLimited == "" {
+		return nil, nil
+	}
+	for kind, raw := range map[string]string{
+		errorPageConnectorOffline: pages.ConnectorOffline,
+		errorPageTimeout:          pages.Timeout,
+		errorPageRateLimited:      pages.RateLimited,
+	} {
+		if raw == "" {
+			continue
+		}
+		if len(raw) > maxErrorPageTemplateBytes {
+			return nil, fmt.Errorf("error_pages.%s exceeds %d bytes", kind, maxErrorPageTemplateBytes)
+		}
+		if _, err := pages.render(kind, errorPageData{Status: http.StatusBadGateway}); err != nil {
+			return nil, fmt.Errorf("error_pages.%s: %w", kind, err)
+		}
+	}
+	return pages, nil
+}
+
+func (p *ErrorPages) template(kind string) string {
+	if p == nil {
+		return ""
+	}
+	switch kind {
+	case errorPageConnectorOffline:
+		return p.ConnectorOffline
+	case errorPageTimeout:
+		return p.Timeout
+	case errorPageRateLimited:
+		return p.RateLimited
+	default:
+		return ""
+	}
+}
+
+func (p *ErrorPages) render(kind string, data errorPageData) ([]byte, error) {
+	raw := p.template(kind)
+	if raw == "" {
+		return nil, fmt.Errorf("no template for %s", kind)
+	}
+	var out bytes.Buffer
+	if p.Format == "json" {
+		tmpl, err := texttemplate.New(kind).Funcs(texttemplate.FuncMap{
+			"json": func(value any) (string, error) {
+				encoded, err := json.Marshal(value)
+				return string(encoded), err
+			},
+		}).Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := tmpl.Execute(&out, data); err != nil {
+			return nil, err
+		}
+		if !json.Valid(out.Bytes()) {
+			return nil, fmt.Errorf("template does not render valid JSON")
+		}
+		return out.Bytes(), nil
+	}
+	tmpl, err := htmltemplate.New(kind).Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (s *Server) resolveErrorPages(tenantID, routeID, kind string) *ErrorPages {
+	if rule, ok := s.ruleStore.GetForTenant(tenantID, routeID); ok && rule.ErrorPages.template(kind) != "" {
+		return rule.ErrorPages
+	}
+	if tenant, ok := s.ruleStore.GetTenant(tenantID); ok && tenant.ErrorPages.template(kind) != "" {
+		return tenant.ErrorPages
+	}
+	return nil
+}
+
+// writeCustomErrorPage renders the configured page for kind and reports
+// whether it wrote a response; callers fall back to their default output.
+// gRPC calls never get a page, since their clients only read grpc-status.
+func (s *Server) writeCustomErrorPage(w http.ResponseWriter, tenantID, routeID, kind string, status int, errorCode, message string) bool {
+	if _, ok := w.(*grpcErrorWriter); ok {
+		return false
+	}
+	pages := s.resolveErrorPages(tenantID, routeID, kind)
+	if pages == nil {
+		return false
+	}
+	body, err := pages.render(kind, errorPageData{
+		RequestID: w.Header().Get("X-Proxer-Request-ID"),
+		TenantID:  tenantID,
+		RouteID:   routeID,
+		Status:    status,
+		Error:     errorCode,
+		Message:   message,
+	})
+	if err != nil {
+		s.logger.Printf("render %s error page for %s failed: %v", kind, MakeTunnelKey(tenantID, routeID), err)
+		return false
+	}
+	if pages.Format == "json" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+	return true
+}
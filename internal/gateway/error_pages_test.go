@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeErrorPagesRejectsInvalidJSONTemplate(t *testing.T) {
+	if _, err := normalizeErrorPages(&ErrorPages{Format: "json", Timeout: `{"error": {{.Error}}}`}); err == nil {
+		t.Fatalf("expected invalid json template to be rejected")
+	}
+	pages, err := normalizeErrorPages(&ErrorPages{Format: "json", Timeout: `{"error": {{json .Error}}, "request_id": {{json .RequestID}}}`})
+	if err != nil {
+		t.Fatalf("normalize json error pages: %v", err)
+	}
+	if pages == nil || pages.Format != "json" {
+		t.Fatalf("unexpected normalized pages: %+v", pages)
+	}
+}
+
+func TestWriteCustomErrorPagePrefersRouteOverTenant(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.SetTenantErrorPages(DefaultTenantID, &ErrorPages{
+		ConnectorOffline: "<h1>tenant offline {{.RequestID}}</h1>",
+		Timeout:          "<h1>tenant timeout</h1>",
+	}); err != nil {
+		t.Fatalf("set tenant error pages: %v", err)
+	}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
+		ID:         "app",
+		Target:     "http://127.0.0.1:3000",
+		ErrorPages: &ErrorPages{ConnectorOffline: "<h1>route offline {{.RequestID}}</h1>"},
+	}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("X-Proxer-Request-ID", "gw-1")
+	if !server.writeCustomErrorPage(recorder, DefaultTenantID, "app", errorPageConnectorOffline, 502, errorPageConnectorOffline, "offline") {
+		t.Fatalf("expected route error page to be rendered")
+	}
+	if body := recorder.Body.String(); !strings.Contains(body, "route offline gw-1") {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	recorder = httptest.NewRecorder()
+	if !server.writeCustomErrorPage(recorder, DefaultTenantID, "app", errorPageTimeout, 504, errorPageTimeout, "timeout") {
+		t.Fatalf("expected tenant timeout page to be rendered")
+	}
+	if body := recorder.Body.String(); !strings.Contains(body, "tenant timeout") {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	if server.writeCustomErrorPage(httptest.NewRecorder(), DefaultTenantID, "app", errorPageRateLimited, 429, "", "") {
+		t.Fatalf("expected no rate limited page to be configured")
+	}
+}
@@ -0,0 +1,314 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	eventPollInterval      = 2 * time.Second
+	eventHeartbeatInterval = 20 * time.Second
+	eventSubscriberBuffer  = 64
+)
+
+type StreamEvent struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	Data      any       `json:"data"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type eventSubscriber struct {
+	tenantID string
+	ch       chan StreamEvent
+	dropped  uint64
+}
+
+// EventBus fans console events out to live subscribers. Publishing never
+// blocks: a subscriber whose buffer is full misses events and is told so via
+// the dropped counter instead of stalling the request path.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[uint64]*eventSubscriber
+	nextID      uint64
+	counter     uint64
+	closed      bool
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[uint64]*eventSubscriber)}
+}
+
+func (b *EventBus) Subscribe(tenantID string) (*eventSubscriber, func()) {
+	subscriber := &eventSubscriber{
+		tenantID: strings.TrimSpace(tenantID),
+		ch:       make(chan StreamEvent, eventSubscriberBuffer),
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(subscriber.ch)
+		return subscriber, func() {}
+	}
+	b.nextID++
+	id := b.nextID
+	b.subscribers[id] = subscriber
+	b.mu.Unlock()
+
+	return subscriber, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[id]; ok {
+			delete(b.subscribers, id)
+			close(subscriber.ch)
+		}
+	}
+}
+
+func (b *EventBus) Publish(eventType, tenantID string, data any) {
+	event := StreamEvent{
+		ID:        fmt.Sprintf("%d", atomic.AddUint64(&b.counter, 1)),
+		Type:      strings.TrimSpace(eventType),
+		TenantID:  normalizeIdentifier(tenantID),
+		Data:      data,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, subscriber := range b.subscribers {
+		if subscriber.tenantID != "" && subscriber.tenantID != event.TenantID {
+			continue
+		}
+		select {
+		case subscriber.ch <- event:
+		default:
+			atomic.AddUint64(&subscriber.dropped, 1)
+		}
+	}
+}
+
+func (b *EventBus) SubscriberCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers)
+}
+
+// Close ends every open stream so HTTP shutdown is not held up by them.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for id, subscriber := range b.subscribers {
+		delete(b.subscribers, id)
+		close(subscriber.ch)
+	}
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	tenantID := normalizeIdentifier(r.URL.Query().Get("tenant"))
+	if !s.isSuperAdmin(user) {
+		if tenantID == "" {
+			tenantID = strings.TrimSpace(user.TenantID)
+		}
+		if !s.canAccessTenant(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden tenant access")
+			return
+		}
+	}
+
+	controller := http.NewResponseController(w)
+	_ = controller.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	subscriber, cancel := s.events.Subscribe(tenantID)
+	defer cancel()
+
+	fmt.Fprintf(w, "retry: 3000\n\n")
+	if err := writeStreamEvent(w, StreamEvent{
+		Type:      "stream.ready",
+		TenantID:  tenantID,
+		Data:      map[string]any{"tenant_id": tenantID},
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		return
+	}
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+	var reportedDrops uint64
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprintf(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, open := <-subscriber.ch:
+			if !open {
+				return
+			}
+			if dropped := atomic.LoadUint64(&subscriber.dropped); dropped != reportedDrops {
+				reportedDrops = dropped
+				if err := writeStreamEvent(w, StreamEvent{
+					Type:      "stream.lagged",
+					TenantID:  tenantID,
+					Data:      map[string]any{"dropped": dropped},
+					CreatedAt: time.Now().UTC(),
+				}); err != nil {
+					return
+				}
+			}
+			if err := writeStreamEvent(w, event); err != nil {
+				return
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, event StreamEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.ID != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", event.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, payload)
+	return err
+}
+
+func (s *Server) publishRouteUpserted(route Rule) {
+	s.events.Publish("route.upserted", route.TenantID, s.buildRouteView(route))
+}
+
+func (s *Server) publishRouteDeleted(tenantID, routeID, reason string) {
+	s.events.Publish("route.deleted", tenantID, map[string]string{
+		"tenant_id": tenantID,
+		"route_id":  routeID,
+		"reason":    reason,
+	})
+}
+
+type eventWatcher struct {
+	seeded     bool
+	connectors map[string]bool
+	tunnels    map[string]TunnelSnapshot
+	metrics    map[string]TunnelMetrics
+}
+
+func (s *Server) runEventLoop(ctx context.Context) {
+	ticker := time.NewTicker(eventPollInterval)
+	defer ticker.Stop()
+
+	watcher := &eventWatcher{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.events.SubscriberCount() == 0 {
+				watcher.seeded = false
+				continue
+			}
+			s.pollEvents(watcher)
+		}
+	}
+}
+
+func (s *Server) pollEvents(watcher *eventWatcher) {
+	emit := watcher.seeded
+
+	connectors := make(map[string]bool)
+	for _, connector := range s.connectorStore.ListAll() {
+		view := s.buildConnectorView(connector)
+		connectors[connector.ID] = view.Connected
+		if emit && view.Connected != watcher.connectors[connector.ID] {
+			eventType := "connector.disconnected"
+			if view.Connected {
+				eventType = "connector.connected"
+			}
+			s.events.Publish(eventType, connector.TenantID, view)
+		}
+	}
+
+	tunnels := make(map[string]TunnelSnapshot)
+	for _, tunnel := range s.hub.SnapshotTunnels() {
+		tunnels[tunnel.ID] = tunnel
+		if _, existed := watcher.tunnels[tunnel.ID]; emit && !existed {
+			tenantID, _ := ParseTunnelKey(tunnel.ID)
+			s.events.Publish("tunnel.connected", tenantID, tunnel)
+		}
+	}
+	if emit {
+		for id, tunnel := range watcher.tunnels {
+			if _, ok := tunnels[id]; !ok {
+				tenantID, _ := ParseTunnelKey(id)
+				s.events.Publish("tunnel.disconnected", tenantID, tunnel)
+			}
+		}
+	}
+
+	metrics := make(map[string]TunnelMetrics)
+	deltas := make(map[string][]map[string]any)
+	for _, route := range s.ruleStore.ListAll() {
+		key := MakeTunnelKey(route.TenantID, route.ID)
+		current := s.hub.GetTunnelMetrics(key)
+		metrics[key] = current
+		previous := watcher.metrics[key]
+		if !current.ResetAt.Equal(previous.ResetAt) {
+			previous = TunnelMetrics{}
+		}
+		if !emit || current.RequestCount == previous.RequestCount {
+			continue
+		}
+		deltas[route.TenantID] = append(deltas[route.TenantID], map[string]any{
+			"route_id":  route.ID,
+			"requests":  current.RequestCount - previous.RequestCount,
+			"errors":    current.ErrorCount - previous.ErrorCount,
+			"bytes_in":  current.BytesIn - previous.BytesIn,
+			"bytes_out": current.BytesOut - previous.BytesOut,
+			"metrics":   current,
+		})
+	}
+	for tenantID, routes := range deltas {
+		s.events.Publish("metrics.delta", tenantID, map[string]any{
+			"interval_seconds": int(eventPollInterval / time.Second),
+			"routes":           routes,
+		})
+	}
+
+	watcher.connectors = connectors
+	watcher.tunnels = tunnels
+	watcher.metrics = metrics
+	watcher.seeded = true
+}
@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestEventBusScopesSubscribersByTenant(t *testing.T) {
+	bus := NewEventBus()
+	acme, cancelAcme := bus.Subscribe("acme")
+	defer cancelAcme()
+	all, cancelAll := bus.Subscribe("")
+	defer cancelAll()
+
+	bus.Publish("route.deleted", "other", nil)
+	bus.Publish("route.deleted", "acme", nil)
+
+	if event := <-acme.ch; event.TenantID != "acme" {
+		t.Fatalf("expected only acme events, got %+v", event)
+	}
+	if len(all.ch) != 2 {
+		t.Fatalf("expected unscoped subscriber to see both events, got %d", len(all.ch))
+	}
+
+	bus.Close()
+	if _, open := <-acme.ch; open {
+		t.Fatalf("expected close to end subscriptions")
+	}
+}
+
+func TestPollEventsEmitsMetricDeltas(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	route, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "app", Target: "http://127.0.0.1:9"})
+	if err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	subscriber, cancel := server.events.Subscribe(DefaultTenantID)
+	defer cancel()
+
+	watcher := &eventWatcher{}
+	server.pollEvents(watcher)
+	server.hub.RecordProxyResponse(&protocol.ProxyResponse{TunnelID: MakeTunnelKey(route.TenantID, route.ID), Status: 200, BytesOut: 42})
+	server.pollEvents(watcher)
+
+	select {
+	case event := <-subscriber.ch:
+		if event.Type != "metrics.delta" {
+			t.Fatalf("expected metrics.delta, got %s", event.Type)
+		}
+		routes := event.Data.(map[string]any)["routes"].([]map[string]any)
+		if len(routes) != 1 || routes[0]["requests"] != int64(1) || routes[0]["bytes_out"] != int64(42) {
+			t.Fatalf("unexpected delta payload: %+v", routes)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected a metrics delta event")
+	}
+}
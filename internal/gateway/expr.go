@@ -0,0 +1,930 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Route middleware expressions use a small CEL-like language: string, int,
+// bool and list literals, the request object, comparison and boolean
+// operators, the ternary operator, and a fixed set of string functions.
+// Expressions are compiled when a route is saved and evaluated with a step
+// budget and deadline so a route cannot stall the proxy path.
+const (
+	maxExprLength      = 2048
+	maxExprDepth       = 32
+	maxExprSteps       = 10000
+	maxExprStringBytes = 64 << 10
+	maxExprCacheSize   = 4096
+)
+
+var (
+	errExprBudget  = errors.New("expression exceeded its step budget")
+	errExprTimeout = errors.New("expression exceeded its time limit")
+)
+
+type compiledExpr struct {
+	source string
+	root   exprNode
+}
+
+type exprEnv struct {
+	vars     map[string]any
+	steps    int
+	deadline time.Time
+}
+
+func (e *exprEnv) step() error {
+	e.steps++
+	if e.steps > maxExprSteps {
+		return errExprBudget
+	}
+	if e.steps%64 == 0 && !e.deadline.IsZero() && time.Now().After(e.deadline) {
+		return errExprTimeout
+	}
+	return nil
+}
+
+// exprHeaders is indexed case-insensitively, like HTTP header names.
+type exprHeaders map[string]string
+
+type exprNode interface {
+	eval(env *exprEnv) (any, error)
+}
+
+var exprCache = struct {
+	sync.Mutex
+	entries map[string]*compiledExpr
+}{entries: make(map[string]*compiledExpr)}
+
+// compileExprCached compiles source once; routes restored from storage hold
+// only the expression text.
+func compileExprCached(source string) (*compiledExpr, error) {
+	exprCache.Lock()
+	compiled, ok := exprCache.entries[source]
+	exprCache.Unlock()
+	if ok {
+		return compiled, nil
+	}
+	compiled, err := compileExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	exprCache.Lock()
+	if len(exprCache.entries) >= maxExprCacheSize {
+		exprCache.entries = make(map[string]*compiledExpr)
+	}
+	exprCache.entries[source] = compiled
+	exprCache.Unlock()
+	return compiled, nil
+}
+
+func compileExpr(source string) (*compiledExpr, error) {
+	if len(source) > maxExprLength {
+		return nil, fmt.Errorf("expression is longer than %d characters", maxExprLength)
+	}
+	tokens, err := lexExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	parser := &exprParser{tokens: tokens}
+	root, err := parser.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if next := parser.peek(); next.kind != exprTokenEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", next.text, next.pos)
+	}
+	return &compiledExpr{source: source, root: root}, nil
+}
+
+func (c *compiledExpr) eval(vars map[string]any, deadline time.Time) (any, error) {
+	return c.root.eval(&exprEnv{vars: vars, deadline: deadline})
+}
+
+func (c *compiledExpr) evalBool(vars map[string]any, deadline time.Time) (bool, error) {
+	value, err := c.eval(vars, deadline)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %s, expected bool", exprTypeName(value))
+	}
+	return result, nil
+}
+
+func (c *compiledExpr) evalString(vars map[string]any, deadline time.Time) (string, error) {
+	value, err := c.eval(vars, deadline)
+	if err != nil {
+		return "", err
+	}
+	return exprToString(value)
+}
+
+type exprTokenKind int
+
+const (
+	exprTokenEOF exprTokenKind = iota
+	exprTokenIdent
+	exprTokenString
+	exprTokenInt
+	exprTokenOp
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+	pos  int
+}
+
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "(", ")", "[", "]", ".", ",", "?", ":"}
+
+func lexExpr(source string) ([]exprToken, error) {
+	tokens := make([]exprToken, 0, 16)
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			value, end, err := lexExprString(source, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, exprToken{kind: exprTokenString, text: value, pos: i})
+			i = end
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(source) && source[i] >= '0' && source[i] <= '9' {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: exprTokenInt, text: source[start:i], pos: start})
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			start := i
+			for i < len(source) && (source[i] == '_' || (source[i] >= 'a' && source[i] <= 'z') || (source[i] >= 'A' && source[i] <= 'Z') || (source[i] >= '0' && source[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: exprTokenIdent, text: source[start:i], pos: start})
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, exprToken{kind: exprTokenOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+		}
+	}
+	return append(tokens, exprToken{kind: exprTokenEOF, pos: len(source)}), nil
+}
+
+func lexExprString(source string, start int) (string, int, error) {
+	quote := source[start]
+	var b strings.Builder
+	for i := start + 1; i < len(source); i++ {
+		c := source[i]
+		if c == quote {
+			return b.String(), i + 1, nil
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(source) {
+			break
+		}
+		switch source[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case '\\', '"', '\'':
+			b.WriteByte(source[i])
+		default:
+			return "", 0, fmt.Errorf("unsupported escape \\%c at offset %d", source[i], i)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string at offset %d", start)
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	depth  int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	token := p.tokens[p.pos]
+	if token.kind != exprTokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *exprParser) acceptOp(op string) bool {
+	if token := p.peek(); token.kind == exprTokenOp && token.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expectOp(op string) error {
+	if p.acceptOp(op) {
+		return nil
+	}
+	token := p.peek()
+	if token.kind == exprTokenEOF {
+		return fmt.Errorf("expected %q at end of expression", op)
+	}
+	return fmt.Errorf("expected %q at offset %d, found %q", op, token.pos, token.text)
+}
+
+func (p *exprParser) enter() error {
+	p.depth++
+	if p.depth > maxExprDepth {
+		return fmt.Errorf("expression nests deeper than %d levels", maxExprDepth)
+	}
+	return nil
+}
+
+func (p *exprParser) parseTernary() (exprNode, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.acceptOp("?") {
+		return cond, nil
+	}
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectOp(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return &exprTernary{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+var exprPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+}
+
+func (p *exprParser) parseBinary(level int) (exprNode, error) {
+	if level == len(exprPrecedence) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token := p.peek()
+		op := ""
+		for _, candidate := range exprPrecedence[level] {
+			if token.text == candidate && (token.kind == exprTokenOp || (token.kind == exprTokenIdent && candidate == "in")) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &exprBinary{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+	for _, op := range []string{"!", "-"} {
+		if p.acceptOp(op) {
+			operand, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return &exprUnary{op: op, operand: operand}, nil
+		}
+	}
+	return p.parsePostfix()
+}
+
+func (p *exprParser) parsePostfix() (exprNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.acceptOp("."):
+			name := p.next()
+			if name.kind != exprTokenIdent {
+				return nil, fmt.Errorf("expected a field or function name at offset %d", name.pos)
+			}
+			if p.acceptOp("(") {
+				args, err := p.parseArgs()
+				if err != nil {
+					return nil, err
+				}
+				call, err := newExprCall(name.text, node, args)
+				if err != nil {
+					return nil, err
+				}
+				node = call
+				continue
+			}
+			node = &exprSelect{operand: node, field: name.text}
+		case p.acceptOp("["):
+			index, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp("]"); err != nil {
+				return nil, err
+			}
+			node = &exprIndex{operand: node, index: index}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *exprParser) parseArgs() ([]exprNode, error) {
+	args := make([]exprNode, 0, 2)
+	if p.acceptOp(")") {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.acceptOp(")") {
+			return args, nil
+		}
+		if err := p.expectOp(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	token := p.next()
+	switch token.kind {
+	case exprTokenString:
+		return &exprLiteral{value: token.text}, nil
+	case exprTokenInt:
+		value, err := strconv.ParseInt(token.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q at offset %d", token.text, token.pos)
+		}
+		return &exprLiteral{value: value}, nil
+	case exprTokenIdent:
+		switch token.text {
+		case "true":
+			return &exprLiteral{value: true}, nil
+		case "false":
+			return &exprLiteral{value: false}, nil
+		case "request":
+			return &exprIdent{name: token.text}, nil
+		}
+		if p.acceptOp("(") {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			return newExprCall(token.text, nil, args)
+		}
+		return nil, fmt.Errorf("unknown identifier %q at offset %d", token.text, token.pos)
+	case exprTokenOp:
+		switch token.text {
+		case "(":
+			node, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expectOp(")")
+		case "[":
+			items, err := p.parseList()
+			if err != nil {
+				return nil, err
+			}
+			return &exprList{items: items}, nil
+		}
+		return nil, fmt.Errorf("unexpected %q at offset %d", token.text, token.pos)
+	}
+	return nil, errors.New("unexpected end of expression")
+}
+
+func (p *exprParser) parseList() ([]exprNode, error) {
+	items := make([]exprNode, 0, 4)
+	if p.acceptOp("]") {
+		return items, nil
+	}
+	for {
+		item, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.acceptOp("]") {
+			return items, nil
+		}
+		if err := p.expectOp(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+type exprLiteral struct{ value any }
+
+func (n *exprLiteral) eval(env *exprEnv) (any, error) {
+	return n.value, env.step()
+}
+
+type exprIdent struct{ name string }
+
+func (n *exprIdent) eval(env *exprEnv) (any, error) {
+	if err := env.step(); err != nil {
+		return nil, err
+	}
+	value, ok := env.vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not available", n.name)
+	}
+	return value, nil
+}
+
+type exprList struct{ items []exprNode }
+
+func (n *exprList) eval(env *exprEnv) (any, error) {
+	if err := env.step(); err != nil {
+		return nil, err
+	}
+	values := make([]any, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+type exprSelect struct {
+	operand exprNode
+	field   string
+}
+
+func (n *exprSelect) eval(env *exprEnv) (any, error) {
+	operand, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if err := env.step(); err != nil {
+		return nil, err
+	}
+	object, ok := operand.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot select .%s on %s", n.field, exprTypeName(operand))
+	}
+	value, ok := object[n.field]
+	if !ok {
+		return nil, fmt.Errorf("no such field %q", n.field)
+	}
+	return value, nil
+}
+
+type exprIndex struct {
+	operand exprNode
+	index   exprNode
+}
+
+func (n *exprIndex) eval(env *exprEnv) (any, error) {
+	operand, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if err := env.step(); err != nil {
+		return nil, err
+	}
+	switch container := operand.(type) {
+	case exprHeaders:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("header names must be strings, got %s", exprTypeName(index))
+		}
+		return container[strings.ToLower(key)], nil
+	case map[string]string:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("map keys must be strings, got %s", exprTypeName(index))
+		}
+		return container[key], nil
+	case []any:
+		position, ok := index.(int64)
+		if !ok || position < 0 || position >= int64(len(container)) {
+			return nil, fmt.Errorf("list index %v out of range", index)
+		}
+		return container[position], nil
+	}
+	return nil, fmt.Errorf("cannot index %s", exprTypeName(operand))
+}
+
+type exprUnary struct {
+	op      string
+	operand exprNode
+}
+
+func (n *exprUnary) eval(env *exprEnv) (any, error) {
+	operand, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if err := env.step(); err != nil {
+		return nil, err
+	}
+	switch value := operand.(type) {
+	case bool:
+		if n.op == "!" {
+			return !value, nil
+		}
+	case int64:
+		if n.op == "-" {
+			return -value, nil
+		}
+	}
+	return nil, fmt.Errorf("operator %s does not apply to %s", n.op, exprTypeName(operand))
+}
+
+type exprTernary struct {
+	cond, then, otherwise exprNode
+}
+
+func (n *exprTernary) eval(env *exprEnv) (any, error) {
+	cond, err := n.cond.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	truth, ok := cond.(bool)
+	if !ok {
+		return nil, fmt.Errorf("condition is %s, expected bool", exprTypeName(cond))
+	}
+	if truth {
+		return n.then.eval(env)
+	}
+	return n.otherwise.eval(env)
+}
+
+type exprBinary struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *exprBinary) eval(env *exprEnv) (any, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		truth, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s needs bool operands, got %s", n.op, exprTypeName(left))
+		}
+		if truth == (n.op == "||") {
+			return truth, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := right.(bool); !ok {
+			return nil, fmt.Errorf("operator %s needs bool operands, got %s", n.op, exprTypeName(right))
+		}
+		return right, nil
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if err := env.step(); err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==", "!=":
+		if exprTypeName(left) != exprTypeName(right) {
+			return nil, fmt.Errorf("cannot compare %s and %s", exprTypeName(left), exprTypeName(right))
+		}
+		equal := false
+		switch left.(type) {
+		case string, int64, bool:
+			equal = left == right
+		default:
+			return nil, fmt.Errorf("cannot compare %s values", exprTypeName(left))
+		}
+		return equal == (n.op == "=="), nil
+	case "<", "<=", ">", ">=":
+		cmp, err := exprCompare(left, right)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	case "in":
+		return exprContains(right, left)
+	case "+":
+		switch l := left.(type) {
+		case int64:
+			if r, ok := right.(int64); ok {
+				return l + r, nil
+			}
+		case string:
+			if r, ok := right.(string); ok {
+				if len(l)+len(r) > maxExprStringBytes {
+					return nil, fmt.Errorf("string result is longer than %d bytes", maxExprStringBytes)
+				}
+				return l + r, nil
+			}
+		}
+	case "-":
+		l, lok := left.(int64)
+		r, rok := right.(int64)
+		if lok && rok {
+			return l - r, nil
+		}
+	}
+	return nil, fmt.Errorf("operator %s does not apply to %s and %s", n.op, exprTypeName(left), exprTypeName(right))
+}
+
+func exprCompare(left, right any) (int, error) {
+	switch l := left.(type) {
+	case int64:
+		if r, ok := right.(int64); ok {
+			switch {
+			case l < r:
+				return -1, nil
+			case l > r:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return strings.Compare(l, r), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot order %s and %s", exprTypeName(left), exprTypeName(right))
+}
+
+func exprContains(container, item any) (bool, error) {
+	switch values := container.(type) {
+	case []any:
+		for _, value := range values {
+			if exprTypeName(value) == exprTypeName(item) && value == item {
+				return true, nil
+			}
+		}
+		return false, nil
+	case exprHeaders:
+		key, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("header names must be strings, got %s", exprTypeName(item))
+		}
+		_, found := values[strings.ToLower(key)]
+		return found, nil
+	case map[string]string:
+		key, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("map keys must be strings, got %s", exprTypeName(item))
+		}
+		_, found := values[key]
+		return found, nil
+	}
+	return false, fmt.Errorf("operator in does not apply to %s", exprTypeName(container))
+}
+
+type exprCall struct {
+	name    string
+	target  exprNode
+	args    []exprNode
+	pattern *regexp.Regexp
+}
+
+var exprMethodArity = map[string]int{
+	"startsWith": 1,
+	"endsWith":   1,
+	"contains":   1,
+	"matches":    1,
+	"lowerAscii": 0,
+	"upperAscii": 0,
+	"size":       0,
+}
+
+var exprFunctionArity = map[string]int{
+	"size":   1,
+	"int":    1,
+	"string": 1,
+}
+
+func newExprCall(name string, target exprNode, args []exprNode) (*exprCall, error) {
+	arity, ok := exprFunctionArity[name]
+	if target != nil {
+		arity, ok = exprMethodArity[name]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s()", name)
+	}
+	if len(args) != arity {
+		return nil, fmt.Errorf("%s() takes %d argument(s), got %d", name, arity, len(args))
+	}
+	call := &exprCall{name: name, target: target, args: args}
+	if name == "matches" {
+		literal, ok := args[0].(*exprLiteral)
+		pattern, isString := literal.valueString()
+		if !ok || !isString {
+			return nil, errors.New("matches() needs a string literal pattern")
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("matches() pattern: %w", err)
+		}
+		call.pattern = compiled
+	}
+	return call, nil
+}
+
+func (n *exprLiteral) valueString() (string, bool) {
+	if n == nil {
+		return "", false
+	}
+	value, ok := n.value.(string)
+	return value, ok
+}
+
+func (n *exprCall) eval(env *exprEnv) (any, error) {
+	var target any
+	if n.target != nil {
+		value, err := n.target.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		target = value
+	}
+	args := make([]any, 0, len(n.args))
+	for _, arg := range n.args {
+		value, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+	if err := env.step(); err != nil {
+		return nil, err
+	}
+
+	if n.target == nil {
+		switch n.name {
+		case "size":
+			return exprSize(args[0])
+		case "int":
+			switch value := args[0].(type) {
+			case int64:
+				return value, nil
+			case string:
+				parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("int(): %q is not an integer", value)
+				}
+				return parsed, nil
+			}
+			return nil, fmt.Errorf("int() does not apply to %s", exprTypeName(args[0]))
+		default:
+			value, err := exprToString(args[0])
+			if err != nil {
+				return nil, err
+			}
+			return value, nil
+		}
+	}
+
+	if n.name == "size" {
+		return exprSize(target)
+	}
+	text, ok := target.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s() applies to strings, got %s", n.name, exprTypeName(target))
+	}
+	switch n.name {
+	case "lowerAscii":
+		return strings.ToLower(text), nil
+	case "upperAscii":
+		return strings.ToUpper(text), nil
+	case "matches":
+		return n.pattern.MatchString(text), nil
+	}
+	arg, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s() needs a string argument, got %s", n.name, exprTypeName(args[0]))
+	}
+	switch n.name {
+	case "startsWith":
+		return strings.HasPrefix(text, arg), nil
+	case "endsWith":
+		return strings.HasSuffix(text, arg), nil
+	default:
+		return strings.Contains(text, arg), nil
+	}
+}
+
+func exprSize(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return int64(len(v)), nil
+	case []any:
+		return int64(len(v)), nil
+	case exprHeaders:
+		return int64(len(v)), nil
+	case map[string]string:
+		return int64(len(v)), nil
+	}
+	return nil, fmt.Errorf("size() does not apply to %s", exprTypeName(value))
+}
+
+func exprToString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("cannot convert %s to string", exprTypeName(value))
+}
+
+func exprTypeName(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case int64:
+		return "int"
+	case bool:
+		return "bool"
+	case []any:
+		return "list"
+	case exprHeaders, map[string]string, map[string]any:
+		return "map"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompiledExprEvaluatesRequest(t *testing.T) {
+	vars := map[string]any{
+		"request": map[string]any{
+			"method":  "POST",
+			"path":    "/api/v2/users",
+			"headers": exprHeaders{"x-build": "42", "x-env": "beta"},
+			"query":   map[string]string{"debug": "1"},
+		},
+	}
+	cases := map[string]any{
+		`request.method == "POST" && request.path.startsWith("/api/")`:                    true,
+		`request.headers["X-Env"] in ["beta", "canary"]`:                                  true,
+		`"x-missing" in request.headers || request.query["debug"] == "1"`:                 true,
+		`int(request.headers["x-build"]) >= 40`:                                           true,
+		`request.path.matches("^/api/v[0-9]+/")`:                                          true,
+		`!(size(request.path) > 5)`:                                                       false,
+		`request.headers["x-env"] == "beta" ? "v2-" + request.method.lowerAscii() : "v1"`: "v2-post",
+	}
+	for source, want := range cases {
+		compiled, err := compileExpr(source)
+		if err != nil {
+			t.Fatalf("compile %q: %v", source, err)
+		}
+		got, err := compiled.eval(vars, time.Time{})
+		if err != nil {
+			t.Fatalf("eval %q: %v", source, err)
+		}
+		if got != want {
+			t.Fatalf("eval %q: expected %v, got %v", source, want, got)
+		}
+	}
+}
+
+func TestCompileExprRejectsInvalidExpressions(t *testing.T) {
+	cases := map[string]string{
+		`request.path ==`:                    "end of expression",
+		`env.HOME == "x"`:                    "unknown identifier",
+		`request.path.matches(request.host)`: "string literal pattern",
+		`exec("rm")`:                         "unknown function",
+		`"unterminated`:                      "unterminated string",
+		strings.Repeat("(", 40) + "true" + strings.Repeat(")", 40): "nests deeper",
+	}
+	for source, want := range cases {
+		if _, err := compileExpr(source); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("compile %q: expected error containing %q, got %v", source, want, err)
+		}
+	}
+}
+
+func TestExprEnvEnforcesLimits(t *testing.T) {
+	if err := (&exprEnv{steps: maxExprSteps}).step(); !errors.Is(err, errExprBudget) {
+		t.Fatalf("expected step budget error, got %v", err)
+	}
+	compiled, err := compileExpr("[" + strings.Repeat("1, ", 100) + "1] == []")
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	if _, err := compiled.eval(map[string]any{}, time.Now().Add(-time.Second)); !errors.Is(err, errExprTimeout) {
+		t.Fatalf("expected time limit error, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+type ForwardedHeaders struct {
+	StripIncoming  bool `json:"strip_incoming,omitempty"`
+	EmitForwarded  bool `json:"emit_forwarded,omitempty"`
+	OmitXForwarded bool `json:"omit_x_forwarded,omitempty"`
+}
+
+func normalizeForwardedHeaders(input *ForwardedHeaders) *ForwardedHeaders {
+	if input == nil || (!input.StripIncoming && !input.EmitForwarded && !input.OmitXForwarded) {
+		return nil
+	}
+	policy := *input
+	return &policy
+}
+
+func (f *ForwardedHeaders) apply(headers map[string][]string, r *http.Request) {
+	trustIncoming := f == nil || !f.StripIncoming
+	if !trustIncoming {
+		for key := range headers {
+			if key == "Forwarded" || strings.HasPrefix(key, "X-Forwarded-") {
+				delete(headers, key)
+			}
+		}
+	}
+	proto := requestProto(r, trustIncoming)
+	port := requestPort(r, proto, trustIncoming)
+	remoteIP := extractIP(r.RemoteAddr)
+
+	if f == nil || !f.OmitXForwarded {
+		appendForwardHeader(headers, "X-Forwarded-Host", r.Host)
+		appendForwardHeader(headers, "X-Forwarded-Proto", proto)
+		appendForwardHeader(headers, "X-Forwarded-Port", port)
+		appendForwardHeader(headers, "X-Forwarded-For", remoteIP)
+	}
+	if f != nil && f.EmitForwarded {
+		appendForwardHeader(headers, "Forwarded", forwardedElement(remoteIP, r.Host, proto))
+	}
+}
+
+func forwardedElement(remoteIP, host, proto string) string {
+	var pairs []string
+	if remoteIP != "" {
+		node := remoteIP
+		if strings.Contains(node, ":") {
+			node = "[" + node + "]"
+		}
+		pairs = append(pairs, "for="+forwardedValue(node))
+	}
+	if host = strings.TrimSpace(host); host != "" {
+		pairs = append(pairs, "host="+forwardedValue(host))
+	}
+	if proto != "" {
+		pairs = append(pairs, "proto="+forwardedValue(proto))
+	}
+	return strings.Join(pairs, ";")
+}
+
+func forwardedValue(value string) string {
+	for _, r := range value {
+		if !isTokenRune(r) {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+		}
+	}
+	return value
+}
+
+func isTokenRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
+
+func appendForwardHeader(headers map[string][]string, key, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	headers[key] = append(headers[key], value)
+}
+
+// requestProto is the scheme the client used. Behind a trusted proxy it is
+// the incoming X-Forwarded-Proto.
+func requestProto(r *http.Request, trustIncoming bool) string {
+	if trustIncoming {
+		if proto := strings.TrimSpace(r.Header.Get("X-Forwarded-Proto")); proto != "" {
+			return proto
+		}
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+func requestPort(r *http.Request, proto string, trustIncoming bool) string {
+	if trustIncoming {
+		if port := strings.TrimSpace(r.Header.Get("X-Forwarded-Port")); port != "" {
+			return port
+		}
+	}
+	host := strings.TrimSpace(r.Host)
+	if host == "" {
+		return ""
+	}
+	if strings.Contains(host, ":") {
+		if _, parsedPort, err := net.SplitHostPort(host); err == nil {
+			return parsedPort
+		}
+	}
+	if proto == "https" {
+		return "443"
+	}
+	return "80"
+}
@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyForwardedHeaderPolicy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string][]string{
+			"xff":       r.Header.Values("X-Forwarded-For"),
+			"proto":     r.Header.Values("X-Forwarded-Proto"),
+			"forwarded": r.Header.Values("Forwarded"),
+			"host":      {r.Host},
+		})
+	}))
+	defer upstream.Close()
+
+	cases := []struct {
+		name      string
+		policy    *ForwardedHeaders
+		xff       []string
+		proto     []string
+		forwarded []string
+	}{
+		{name: "default", xff: []string{"198.51.100.1", "203.0.113.9"}, proto: []string{"https", "https"}, forwarded: []string{"for=198.51.100.1"}},
+		{name: "strip", policy: &ForwardedHeaders{StripIncoming: true}, xff: []string{"203.0.113.9"}, proto: []string{"http"}},
+		{name: "forwarded-only", policy: &ForwardedHeaders{StripIncoming: true, EmitForwarded: true, OmitXForwarded: true}, forwarded: []string{`for=203.0.113.9;host="public.test:8080";proto=http`}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := NewServer(Config{StorageDriver: "memory"}, nil)
+			if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "web", Target: upstream.URL, ForwardedHeaders: tc.policy}); err != nil {
+				t.Fatalf("upsert route: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "http://public.test:8080/t/web/", nil)
+			req.RemoteAddr = "203.0.113.9:4000"
+			req.Header.Set("X-Forwarded-For", "198.51.100.1")
+			req.Header.Set("X-Forwarded-Proto", "https")
+			req.Header.Set("Forwarded", "for=198.51.100.1")
+			recorder := httptest.NewRecorder()
+			server.handleProxy(recorder, req)
+
+			var seen map[string][]string
+			if err := json.Unmarshal(recorder.Body.Bytes(), &seen); err != nil {
+				t.Fatalf("decode upstream view %q: %v", recorder.Body.String(), err)
+			}
+			for field, want := range map[string][]string{"xff": tc.xff, "proto": tc.proto, "forwarded": tc.forwarded} {
+				if len(seen[field]) != len(want) {
+					t.Fatalf("%s: expected %v, got %v", field, want, seen[field])
+				}
+				for i := range want {
+					if seen[field][i] != want[i] {
+						t.Fatalf("%s: expected %v, got %v", field, want, seen[field])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestForwardedElementQuotesIPv6(t *testing.T) {
+	if got := forwardedElement("2001:db8::1", "example.com", "https"); got != `for="[2001:db8::1]";host=example.com;proto=https` {
+		t.Fatalf("unexpected element %q", got)
+	}
+}
@@ -53,7 +53,11 @@ func (s *Server) handleFrontend(w http.ResponseWriter, r *http.Request) {
 	if requestPath == "." {
 		requestPath = "/"
 	}
-	if strings.HasPrefix(requestPath, "/api/") || strings.HasPrefix(requestPath, "/t/") {
+	if strings.HasPrefix(requestPath, "/api/") {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "unknown API endpoint")
+		return
+	}
+	if strings.HasPrefix(requestPath, "/t/") {
 		http.NotFound(w, r)
 		return
 	}
@@ -103,7 +107,7 @@ func (s *Server) serveEmbeddedSPAIndex(w http.ResponseWriter, r *http.Request, f
 		return
 	}
 
-	baseURL := resolvePublicBaseURL(s.cfg.PublicBaseURL, r)
+	baseURL := resolvePublicBaseURL(s.config().PublicBaseURL, r)
 	seo := buildSEODocument(requestPath, baseURL)
 	rendered := injectSEOBlock(string(content), buildSEOBlock(seo))
 
@@ -143,7 +147,7 @@ func serveEmbeddedFile(w http.ResponseWriter, r *http.Request, fsys fs.FS, filen
 }
 
 func (s *Server) serveRobotsTxt(w http.ResponseWriter, r *http.Request) {
-	baseURL := resolvePublicBaseURL(s.cfg.PublicBaseURL, r)
+	baseURL := resolvePublicBaseURL(s.config().PublicBaseURL, r)
 	contentType := "text/plain; charset=utf-8"
 	body := []byte(fmt.Sprintf(
 		"User-agent: *\nAllow: /\nDisallow: /api/\nDisallow: /app\nDisallow: /login\nSitemap: %s\n",
@@ -155,7 +159,7 @@ func (s *Server) serveRobotsTxt(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) serveSitemapXML(w http.ResponseWriter, r *http.Request) {
-	baseURL := resolvePublicBaseURL(s.cfg.PublicBaseURL, r)
+	baseURL := resolvePublicBaseURL(s.config().PublicBaseURL, r)
 	urls := []sitemapURL{
 		{Loc: canonicalURL(baseURL, "/")},
 		{Loc: canonicalURL(baseURL, "/signup")},
@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	grpcStatusUnknown           = 2
+	grpcStatusDeadlineExceeded  = 4
+	grpcStatusNotFound          = 5
+	grpcStatusPermissionDenied  = 7
+	grpcStatusResourceExhausted = 8
+	grpcStatusUnimplemented     = 12
+	grpcStatusInternal          = 13
+	grpcStatusUnavailable       = 14
+	grpcStatusUnauthenticated   = 16
+)
+
+const maxGRPCMessageBytes = 1024
+
+// isGRPCRequest reports whether r is a gRPC call. Unary calls are proxied
+// like any other buffered request; streaming calls only work once both
+// directions have finished, so they need a streaming transport instead.
+func isGRPCRequest(r *http.Request) bool {
+	contentType := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Type")))
+	return contentType == "application/grpc" || strings.HasPrefix(contentType, "application/grpc+") || strings.HasPrefix(contentType, "application/grpc;")
+}
+
+func grpcStatusForHTTP(status int) int {
+	switch status {
+	case http.StatusBadRequest:
+		return grpcStatusInternal
+	case http.StatusUnauthorized:
+		return grpcStatusUnauthenticated
+	case http.StatusForbidden:
+		return grpcStatusPermissionDenied
+	case http.StatusNotFound:
+		return grpcStatusUnimplemented
+	case http.StatusGone:
+		return grpcStatusNotFound
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return grpcStatusResourceExhausted
+	case http.StatusGatewayTimeout:
+		return grpcStatusDeadlineExceeded
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return grpcStatusUnavailable
+	default:
+		return grpcStatusUnknown
+	}
+}
+
+func encodeGRPCMessage(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c >= 0x20 && c <= 0x7e && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// grpcErrorWriter turns error responses written for a gRPC call into
+// trailers-only gRPC responses (HTTP 200 with grpc-status and grpc-message),
+// which is the only error shape gRPC clients understand. Responses that
+// already carry grpc-status, such as ones from the upstream server, pass
+// through untouched.
+type grpcErrorWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	failed      bool
+	status      int
+	message     strings.Builder
+}
+
+func newGRPCErrorWriter(w http.ResponseWriter) *grpcErrorWriter {
+	return &grpcErrorWriter{ResponseWriter: w}
+}
+
+func (w *grpcErrorWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if status < http.StatusBadRequest || w.Header().Get("Grpc-Status") != "" {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.failed = true
+	w.status = status
+}
+
+func (w *grpcErrorWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.failed {
+		return w.ResponseWriter.Write(p)
+	}
+	if remaining := maxGRPCMessageBytes - w.message.Len(); remaining > 0 {
+		w.message.Write(p[:min(len(p), remaining)])
+	}
+	return len(p), nil
+}
+
+func (w *grpcErrorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *grpcErrorWriter) finish() {
+	if !w.failed {
+		return
+	}
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Del("Trailer")
+	header.Set("Content-Type", "application/grpc")
+	header.Set("Grpc-Status", strconv.Itoa(grpcStatusForHTTP(w.status)))
+	if message := strings.TrimSpace(w.message.String()); message != "" {
+		header.Set("Grpc-Message", encodeGRPCMessage(message))
+	}
+	w.ResponseWriter.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGRPCCallsGetTrailersOnlyErrors(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	request := httptest.NewRequest(http.MethodPost, "/t/default/missing/pkg.Service/Method", strings.NewReader(""))
+	request.Header.Set("Content-Type", "application/grpc+proto")
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected gRPC errors to use HTTP 200, got %d", recorder.Code)
+	}
+	if got := recorder.Header().Get("Grpc-Status"); got != "12" {
+		t.Fatalf("expected UNIMPLEMENTED for an unknown route, got %q", got)
+	}
+	if got := recorder.Header().Get("Content-Type"); got != "application/grpc" {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	if !strings.Contains(recorder.Header().Get("Grpc-Message"), "not found") || recorder.Body.Len() != 0 {
+		t.Fatalf("expected the error text in grpc-message and an empty body, got %q / %q", recorder.Header().Get("Grpc-Message"), recorder.Body.String())
+	}
+}
+
+func TestGRPCErrorWriterPassesUpstreamStatus(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer := newGRPCErrorWriter(recorder)
+	writer.Header().Set("Grpc-Status", "5")
+	writer.WriteHeader(http.StatusNotFound)
+	_, _ = writer.Write([]byte("upstream"))
+	writer.finish()
+	if recorder.Code != http.StatusNotFound || recorder.Body.String() != "upstream" {
+		t.Fatalf("expected upstream gRPC response to pass through, got %d %q", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestEncodeGRPCMessage(t *testing.T) {
+	if got := encodeGRPCMessage("50% done\nnext"); got != "50%25 done%0Anext" {
+		t.Fatalf("unexpected encoding %q", got)
+	}
+}
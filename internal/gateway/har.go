@@ -0,0 +1,232 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+// The types below follow the HAR 1.2 format
+// (http://www.softwareishard.com/blog/har-12-spec/), which browser devtools
+// import. Captures keep no cookies, cache or per-phase timing details, so
+// those fields carry the values the format uses for unknown data.
+
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+func buildHAR(exchanges []CapturedExchange) harDocument {
+	entries := make([]harEntry, 0, len(exchanges))
+	for _, exchange := range exchanges {
+		entries = append(entries, harEntryFor(exchange))
+	}
+	return harDocument{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "proxer", Version: fmt.Sprintf("protocol-%d", protocol.ProtocolVersion)},
+		Entries: entries,
+	}}
+}
+
+func harEntryFor(exchange CapturedExchange) harEntry {
+	httpVersion := exchange.HTTPVersion
+	if httpVersion == "" {
+		httpVersion = "HTTP/1.1"
+	}
+	duration := float64(exchange.DurationMs)
+	entry := harEntry{
+		StartedDateTime: exchange.StartedAt.UTC().Format(time.RFC3339Nano),
+		Time:            duration,
+		Request: harRequest{
+			Method:      exchange.Method,
+			URL:         exchange.URL,
+			HTTPVersion: httpVersion,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(exchange.Request.Headers),
+			QueryString: harQueryString(exchange.URL),
+			HeadersSize: -1,
+			BodySize:    exchange.Request.BodySize,
+		},
+		Response: harResponse{
+			Status:      exchange.Status,
+			StatusText:  http.StatusText(exchange.Status),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(exchange.Response.Headers),
+			Content:     harContentFor(exchange.Response),
+			RedirectURL: firstHeaderValue(exchange.Response.Headers, "Location"),
+			HeadersSize: -1,
+			BodySize:    exchange.Response.BodySize,
+		},
+		Timings: harTimings{Send: 0, Wait: duration, Receive: 0},
+		Comment: "proxer request " + exchange.ID,
+	}
+	if exchange.Request.BodySize > 0 {
+		postData := &harPostData{MimeType: firstHeaderValue(exchange.Request.Headers, "Content-Type")}
+		switch {
+		case exchange.Request.BodyOmitted:
+			postData.Comment = "body not captured"
+		case !utf8.Valid(exchange.Request.Body):
+			postData.Comment = "binary body not included"
+		default:
+			postData.Text = string(exchange.Request.Body)
+			if exchange.Request.BodyTruncated {
+				postData.Comment = "body truncated"
+			}
+		}
+		entry.Request.PostData = postData
+	}
+	return entry
+}
+
+// harContentFor keeps text bodies as they are and encodes others as base64,
+// which is how devtools export binary responses.
+func harContentFor(message CapturedMessage) harContent {
+	content := harContent{
+		Size:     message.BodySize,
+		MimeType: firstHeaderValue(message.Headers, "Content-Type"),
+	}
+	switch {
+	case message.BodyOmitted:
+		content.Comment = "body not captured"
+		return content
+	case len(message.Body) == 0:
+		return content
+	case harIsText(content.MimeType) && utf8.Valid(message.Body):
+		content.Text = string(message.Body)
+	default:
+		content.Text = base64.StdEncoding.EncodeToString(message.Body)
+		content.Encoding = "base64"
+	}
+	if message.BodyTruncated {
+		content.Comment = "body truncated"
+	}
+	return content
+}
+
+func harIsText(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-www-form-urlencoded":
+		return true
+	}
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+func harHeaders(headers map[string][]string) []harNameValue {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]harNameValue, 0, len(headers))
+	for _, name := range names {
+		for _, value := range headers[name] {
+			pairs = append(pairs, harNameValue{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
+
+func harQueryString(rawURL string) []harNameValue {
+	pairs := make([]harNameValue, 0)
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return pairs
+	}
+	query := parsed.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range query[name] {
+			pairs = append(pairs, harNameValue{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
 	"sync"
@@ -25,17 +26,27 @@ var (
 	ErrResponseTunnelMismatch  = errors.New("response tunnel mismatch")
 )
 
+// staleSweepInterval is how often the hub drops sessions whose agent stopped
+// polling. Lookups already treat them as gone once sessionTTL has passed.
+const staleSweepInterval = 5 * time.Second
+
 type TunnelMetrics struct {
-	TunnelID         string    `json:"tunnel_id"`
-	RequestCount     int64     `json:"request_count"`
-	ErrorCount       int64     `json:"error_count"`
-	BytesIn          int64     `json:"bytes_in"`
-	BytesOut         int64     `json:"bytes_out"`
-	TotalLatencyMs   int64     `json:"total_latency_ms"`
-	AverageLatencyMs float64   `json:"average_latency_ms"`
-	LastStatus       int       `json:"last_status"`
-	LastError        string    `json:"last_error,omitempty"`
-	LastSeen         time.Time `json:"last_seen,omitempty"`
+	TunnelID         string             `json:"tunnel_id"`
+	RequestCount     int64              `json:"request_count"`
+	ErrorCount       int64              `json:"error_count"`
+	TimeoutCount     int64              `json:"timeout_count"`
+	RetryCount       int64              `json:"retry_count"`
+	WebhooksVerified int64              `json:"webhooks_verified,omitempty"`
+	WebhooksRejected int64              `json:"webhooks_rejected,omitempty"`
+	BytesIn          int64              `json:"bytes_in"`
+	BytesOut         int64              `json:"bytes_out"`
+	TotalLatencyMs   int64              `json:"total_latency_ms"`
+	AverageLatencyMs float64            `json:"average_latency_ms"`
+	LastStatus       int                `json:"last_status"`
+	LastError        string             `json:"last_error,omitempty"`
+	LastSeen         time.Time          `json:"last_seen,omitempty"`
+	ResetAt          time.Time          `json:"reset_at,omitempty"`
+	Latency          LatencyPercentiles `json:"latency"`
 }
 
 type TunnelSnapshot struct {
@@ -53,19 +64,45 @@ type ConnectionSnapshot struct {
 }
 
 type ConnectorConnection struct {
-	ConnectorID string    `json:"connector_id"`
-	AgentID     string    `json:"agent_id"`
-	Connected   bool      `json:"connected"`
-	LastSeen    time.Time `json:"last_seen"`
+	ConnectorID     string        `json:"connector_id"`
+	AgentID         string        `json:"agent_id"`
+	Connected       bool          `json:"connected"`
+	LastSeen        time.Time     `json:"last_seen"`
+	Load            ConnectorLoad `json:"load"`
+	ProtocolVersion int           `json:"protocol_version,omitempty"`
+	Capabilities    []string      `json:"capabilities,omitempty"`
+	AgentVersion    string        `json:"agent_version,omitempty"`
+}
+
+// session is one agent connection. id, agentID, connectorID, capabilities
+// and queue never change; tunnels is guarded by Hub.mu and the rest by the
+// session's own mu.
+type session struct {
+	id           string
+	agentID      string
+	tunnels      map[string]protocol.TunnelConfig
+	connectorID  string
+	capabilities agentCapabilities
+	queue        *sessionQueue
+
+	mu         sync.Mutex
+	lastSeen   time.Time
+	inFlight   int
+	dispatched int64
+	latencyMs  float64
+	health     map[string]protocol.TargetHealth
 }
 
-type session struct {
-	id          string
-	agentID     string
-	tunnels     map[string]protocol.TunnelConfig
-	connectorID string
-	queue       chan *protocol.ProxyRequest
-	lastSeen    time.Time
+func (s *session) touch(now time.Time) {
+	s.mu.Lock()
+	s.lastSeen = now
+	s.mu.Unlock()
+}
+
+func (s *session) staleAt(now time.Time, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return now.Sub(s.lastSeen) > ttl
 }
 
 type dispatchResult struct {
@@ -73,48 +110,63 @@ type dispatchResult struct {
 	err      error
 }
 
-type pendingRequest struct {
-	requestID string
-	sessionID string
-	tunnelID  string
-	resultCh  chan dispatchResult
-}
-
 type Hub struct {
-	agentToken           string
+	agentToken string
+	sessionTTL time.Duration
+
+	// mu guards the session registry and the limits. Dispatching, polling
+	// and answering requests only read it: per-session counters sit behind
+	// session.mu, pending requests behind their shard locks and tunnel
+	// metrics in their own registry. Locks are taken in that order.
+	mu                   sync.RWMutex
 	publicBaseURL        string
 	requestTimeout       time.Duration
-	sessionTTL           time.Duration
 	maxPendingPerSession int
 	maxPendingGlobal     int
-
-	mu                sync.RWMutex
-	sessions          map[string]*session
-	tunnelSessions    map[string]string
-	connectorSessions map[string]string
-	configs           map[string]protocol.TunnelConfig
-	pending           map[string]pendingRequest
-	metrics           map[string]*TunnelMetrics
-	latencySamples    []int64
+	sessions             map[string]*session
+	tunnelSessions       map[string]string
+	connectorSessions    map[string]string
+	configs              map[string]protocol.TunnelConfig
+	faults               FaultInjection
+	closed               bool
+	closing              chan struct{}
+
+	streamsMu sync.Mutex
+	streams   map[string]*tunnelStream
+	uploadsMu sync.Mutex
+	uploads   map[string]*tunnelUpload
+
+	pending      *pendingTable
+	queueStats   queueStats
+	metrics      *metricsRegistry
+	timeseries   *TimeseriesStore
+	availability *AvailabilityStore
 
 	requestCounter uint64
 	sessionCounter uint64
 }
 
 type HubStatus struct {
-	ActiveSessions       int     `json:"active_sessions"`
-	ActiveTunnelSessions int     `json:"active_tunnel_sessions"`
-	ActiveConnectors     int     `json:"active_connectors"`
-	PendingRequests      int     `json:"pending_requests"`
-	MaxPendingGlobal     int     `json:"max_pending_global"`
-	MaxPendingPerSession int     `json:"max_pending_per_session"`
-	QueueDepthTotal      int     `json:"queue_depth_total"`
-	QueueDepthMax        int     `json:"queue_depth_max"`
-	P50LatencyMs         int64   `json:"p50_latency_ms"`
-	P95LatencyMs         int64   `json:"p95_latency_ms"`
-	RequestCount         int64   `json:"request_count"`
-	ErrorCount           int64   `json:"error_count"`
-	ErrorRate            float64 `json:"error_rate"`
+	ActiveSessions       int                `json:"active_sessions"`
+	ActiveTunnelSessions int                `json:"active_tunnel_sessions"`
+	ActiveConnectors     int                `json:"active_connectors"`
+	PendingRequests      int                `json:"pending_requests"`
+	MaxPendingGlobal     int                `json:"max_pending_global"`
+	MaxPendingPerSession int                `json:"max_pending_per_session"`
+	QueueDepthTotal      int                `json:"queue_depth_total"`
+	QueueDepthMax        int                `json:"queue_depth_max"`
+	QueueDepthByClass    map[string]int     `json:"queue_depth_by_class"`
+	P50LatencyMs         int64              `json:"p50_latency_ms"`
+	P90LatencyMs         int64              `json:"p90_latency_ms"`
+	P95LatencyMs         int64              `json:"p95_latency_ms"`
+	P99LatencyMs         int64              `json:"p99_latency_ms"`
+	RequestCount         int64              `json:"request_count"`
+	ErrorCount           int64              `json:"error_count"`
+	TimeoutCount         int64              `json:"timeout_count"`
+	RetryCount           int64              `json:"retry_count"`
+	ErrorRate            float64            `json:"error_rate"`
+	QueueWait            LatencyPercentiles `json:"queue_wait"`
+	QueueOverflow        QueueOverflowStats `json:"queue_overflow"`
 }
 
 func NewHub(agentToken, publicBaseURL string, requestTimeout time.Duration, maxPendingPerSession, maxPendingGlobal int) *Hub {
@@ -128,7 +180,7 @@ func NewHub(agentToken, publicBaseURL string, requestTimeout time.Duration, maxP
 		maxPendingGlobal = 10000
 	}
 
-	return &Hub{
+	h := &Hub{
 		agentToken:           agentToken,
 		publicBaseURL:        strings.TrimRight(publicBaseURL, "/"),
 		requestTimeout:       requestTimeout,
@@ -139,17 +191,54 @@ func NewHub(agentToken, publicBaseURL string, requestTimeout time.Duration, maxP
 		tunnelSessions:       make(map[string]string),
 		connectorSessions:    make(map[string]string),
 		configs:              make(map[string]protocol.TunnelConfig),
-		pending:              make(map[string]pendingRequest),
-		metrics:              make(map[string]*TunnelMetrics),
-		latencySamples:       make([]int64, 0, 512),
+		streams:              make(map[string]*tunnelStream),
+		uploads:              make(map[string]*tunnelUpload),
+		pending:              newPendingTable(),
+		metrics:              newMetricsRegistry(),
+		timeseries:           NewTimeseriesStore(),
+		availability:         NewAvailabilityStore(),
+		closing:              make(chan struct{}),
 	}
+	go h.sweepStaleSessions()
+	return h
+}
+
+func (h *Hub) Timeseries() *TimeseriesStore {
+	return h.timeseries
+}
+
+func (h *Hub) Availability() *AvailabilityStore {
+	return h.availability
 }
 
 func (h *Hub) RequestTimeout() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 	return h.requestTimeout
 }
 
+// SetLimits applies reloaded settings. A lower per-session pending limit takes
+// effect immediately; a higher one applies to sessions registered afterwards.
+func (h *Hub) SetLimits(publicBaseURL string, requestTimeout time.Duration, maxPendingPerSession, maxPendingGlobal int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.publicBaseURL = strings.TrimRight(publicBaseURL, "/")
+	if requestTimeout > 0 {
+		h.requestTimeout = requestTimeout
+	}
+	if maxPendingPerSession > 0 {
+		h.maxPendingPerSession = maxPendingPerSession
+	}
+	if maxPendingGlobal > 0 {
+		h.maxPendingGlobal = maxPendingGlobal
+	}
+}
+
 func (h *Hub) Register(message *protocol.RegisterRequest) (*protocol.RegisterResponse, error) {
+	return h.register(message, "")
+}
+
+func (h *Hub) register(message *protocol.RegisterRequest, sessionID string) (*protocol.RegisterResponse, error) {
 	if message == nil {
 		return nil, errors.New("missing registration payload")
 	}
@@ -176,26 +265,29 @@ func (h *Hub) Register(message *protocol.RegisterRequest) (*protocol.RegisterRes
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	h.cleanupStaleLocked(time.Now().UTC())
+	h.removeStaleLocked(time.Now().UTC())
 
-	agentID := strings.TrimSpace(message.AgentID)
-	if agentID == "" {
-		agentID = "anonymous-agent"
-	}
+	agentID := legacyAgentID(message.AgentID)
 
-	for sessionID, existing := range h.sessions {
+	if taken, ok := h.sessions[sessionID]; ok && (taken.agentID != agentID || taken.connectorID != "") {
+		return nil, ErrSessionNotResumable
+	}
+	for existingID, existing := range h.sessions {
 		if existing.agentID == agentID {
-			h.removeSessionLocked(sessionID)
+			h.removeSessionLocked(existingID)
 		}
 	}
 
-	sessionID := h.nextSessionID()
+	if sessionID == "" {
+		sessionID = h.nextSessionID()
+	}
 	s := &session{
-		id:       sessionID,
-		agentID:  agentID,
-		tunnels:  make(map[string]protocol.TunnelConfig),
-		queue:    make(chan *protocol.ProxyRequest, h.maxPendingPerSession),
-		lastSeen: time.Now().UTC(),
+		id:           sessionID,
+		agentID:      agentID,
+		tunnels:      make(map[string]protocol.TunnelConfig),
+		capabilities: negotiateCapabilities(message),
+		queue:        newSessionQueue(h.maxPendingPerSession),
+		lastSeen:     time.Now().UTC(),
 	}
 	h.sessions[sessionID] = s
 
@@ -207,9 +299,7 @@ func (h *Hub) Register(message *protocol.RegisterRequest) (*protocol.RegisterRes
 		h.tunnelSessions[tunnel.ID] = sessionID
 		h.configs[tunnel.ID] = tunnel
 		s.tunnels[tunnel.ID] = tunnel
-		if _, ok := h.metrics[tunnel.ID]; !ok {
-			h.metrics[tunnel.ID] = &TunnelMetrics{TunnelID: tunnel.ID}
-		}
+		h.metrics.ensure(tunnel.ID)
 		routes = append(routes, protocol.TunnelRoute{
 			ID:        tunnel.ID,
 			PublicURL: fmt.Sprintf("%s/t/%s/", h.publicBaseURL, tunnel.ID),
@@ -220,16 +310,26 @@ func (h *Hub) Register(message *protocol.RegisterRequest) (*protocol.RegisterRes
 		return routes[i].ID < routes[j].ID
 	})
 
-	return &protocol.RegisterResponse{
+	response := &protocol.RegisterResponse{
 		Accepted:      true,
 		Message:       "registered",
 		SessionID:     sessionID,
 		PublicBaseURL: h.publicBaseURL,
 		Tunnels:       routes,
-	}, nil
+	}
+	s.capabilities.annotate(response)
+	return response, nil
 }
 
 func (h *Hub) RegisterConnectorSession(connectorID, agentID string) (*protocol.RegisterResponse, error) {
+	return h.registerConnectorSession(connectorID, agentID, "", negotiateCapabilities(nil))
+}
+
+func (h *Hub) RegisterConnector(message *protocol.RegisterRequest) (*protocol.RegisterResponse, error) {
+	return h.registerConnectorSession(message.ConnectorID, message.AgentID, "", negotiateCapabilities(message))
+}
+
+func (h *Hub) registerConnectorSession(connectorID, agentID, sessionID string, capabilities agentCapabilities) (*protocol.RegisterResponse, error) {
 	connectorID = strings.TrimSpace(connectorID)
 	if connectorID == "" {
 		return nil, errors.New("missing connector id")
@@ -241,66 +341,127 @@ func (h *Hub) RegisterConnectorSession(connectorID, agentID string) (*protocol.R
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	h.cleanupStaleLocked(time.Now().UTC())
+	h.removeStaleLocked(time.Now().UTC())
 
-	for sessionID, existing := range h.sessions {
+	if taken, ok := h.sessions[sessionID]; ok && taken.connectorID != connectorID {
+		return nil, ErrSessionNotResumable
+	}
+	for existingID, existing := range h.sessions {
 		if existing.agentID == agentID {
-			h.removeSessionLocked(sessionID)
+			h.removeSessionLocked(existingID)
 		}
 	}
 	if existingSessionID, ok := h.connectorSessions[connectorID]; ok {
 		h.removeSessionLocked(existingSessionID)
 	}
 
-	sessionID := h.nextSessionID()
+	if sessionID == "" {
+		sessionID = h.nextSessionID()
+	}
 	s := &session{
-		id:          sessionID,
-		agentID:     agentID,
-		connectorID: connectorID,
-		tunnels:     make(map[string]protocol.TunnelConfig),
-		queue:       make(chan *protocol.ProxyRequest, h.maxPendingPerSession),
-		lastSeen:    time.Now().UTC(),
+		id:           sessionID,
+		agentID:      agentID,
+		connectorID:  connectorID,
+		tunnels:      make(map[string]protocol.TunnelConfig),
+		capabilities: capabilities,
+		queue:        newSessionQueue(h.maxPendingPerSession),
+		lastSeen:     time.Now().UTC(),
 	}
 	h.sessions[sessionID] = s
 	h.connectorSessions[connectorID] = sessionID
 
-	return &protocol.RegisterResponse{
+	response := &protocol.RegisterResponse{
 		Accepted:      true,
 		Message:       "registered connector session",
 		SessionID:     sessionID,
 		PublicBaseURL: h.publicBaseURL,
-	}, nil
+	}
+	capabilities.annotate(response)
+	return response, nil
 }
 
-func (h *Hub) PullRequest(ctx context.Context, sessionID string) (*protocol.ProxyRequest, error) {
-	h.mu.Lock()
-	h.cleanupStaleLocked(time.Now().UTC())
+// liveSessionLocked returns the session with sessionID unless its agent has
+// not been seen within sessionTTL. Callers hold mu for reading or writing.
+func (h *Hub) liveSessionLocked(sessionID string, now time.Time) (*session, bool) {
 	s, ok := h.sessions[sessionID]
+	if !ok || s.staleAt(now, h.sessionTTL) {
+		return nil, false
+	}
+	return s, true
+}
+
+func (h *Hub) liveSession(sessionID string) (*session, bool) {
+	now := time.Now().UTC()
+	h.mu.RLock()
+	s, ok := h.liveSessionLocked(sessionID, now)
+	h.mu.RUnlock()
+	if ok {
+		s.touch(now)
+	}
+	return s, ok
+}
+
+func (h *Hub) sessionOwner(sessionID string) (string, string, bool) {
+	s, ok := h.liveSession(sessionID)
 	if !ok {
-		h.mu.Unlock()
+		return "", "", false
+	}
+	return s.connectorID, s.agentID, true
+}
+
+func (h *Hub) PullRequest(ctx context.Context, sessionID string) (*protocol.ProxyRequest, error) {
+	s, ok := h.liveSession(sessionID)
+	if !ok || h.injectSessionKill(sessionID) {
 		return nil, ErrUnknownSession
 	}
-	s.lastSeen = time.Now().UTC()
 	queue := s.queue
-	h.mu.Unlock()
 
-	select {
-	case request := <-queue:
-		return request, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	for {
+		select {
+		case <-queue.ready:
+			request, wait := queue.popWithWait()
+			if request != nil && h.stampRemainingBudget(request) {
+				h.queueStats.recordWait(wait)
+				return request, nil
+			}
+		case <-h.closing:
+			return nil, ErrGatewayShuttingDown
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
 }
 
-func (h *Hub) Heartbeat(sessionID string) error {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.cleanupStaleLocked(time.Now().UTC())
-	s, ok := h.sessions[sessionID]
+// stampRemainingBudget sets the request's remaining deadline so the agent's
+// local call shares the gateway budget. Requests whose caller already gave up
+// are dropped.
+func (h *Hub) stampRemainingBudget(request *protocol.ProxyRequest) bool {
+	pending, ok := h.pending.get(request.RequestID)
+	if !ok {
+		return false
+	}
+	if pending.deadline.IsZero() {
+		return true
+	}
+	remaining := time.Until(pending.deadline)
+	if remaining <= 0 {
+		return false
+	}
+	request.TimeoutMs = remaining.Milliseconds()
+	if request.TimeoutMs == 0 {
+		request.TimeoutMs = 1
+	}
+	return true
+}
+
+func (h *Hub) Heartbeat(sessionID string, health []protocol.TargetHealth) error {
+	s, ok := h.liveSession(sessionID)
 	if !ok {
 		return ErrUnknownSession
 	}
-	s.lastSeen = time.Now().UTC()
+	s.mu.Lock()
+	s.setHealthLocked(health)
+	s.mu.Unlock()
 	return nil
 }
 
@@ -312,30 +473,21 @@ func (h *Hub) SubmitProxyResponse(sessionID string, response *protocol.ProxyResp
 	if requestID == "" {
 		return errors.New("missing request_id")
 	}
+	response.RequestID = requestID
 
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.cleanupStaleLocked(time.Now().UTC())
-
-	s, ok := h.sessions[sessionID]
+	s, ok := h.liveSession(sessionID)
 	if !ok {
 		return ErrUnknownSession
 	}
-	s.lastSeen = time.Now().UTC()
-
-	pending, ok := h.pending[requestID]
-	if !ok {
-		return ErrUnknownPendingRequest
-	}
-	if pending.sessionID != sessionID {
-		return ErrResponseSessionMismatch
+	if h.injectResponseDrop() {
+		return nil
 	}
-	if strings.TrimSpace(response.TunnelID) != pending.tunnelID {
-		return ErrResponseTunnelMismatch
+	pending, err := h.pending.claim(s, response)
+	if err != nil {
+		return err
 	}
-
-	delete(h.pending, requestID)
-	h.recordSuccessfulAttemptLocked(response)
+	s.finishRequest(pending, true)
+	h.recordResponse(response)
 	pending.resultCh <- dispatchResult{response: response}
 	return nil
 }
@@ -351,10 +503,14 @@ func (h *Hub) GetTunnelToken(tunnelID string) string {
 }
 
 func (h *Hub) IsTunnelConnected(tunnelID string) bool {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.cleanupStaleLocked(time.Now().UTC())
-	_, ok := h.tunnelSessions[tunnelID]
+	now := time.Now().UTC()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	sessionID, ok := h.tunnelSessions[tunnelID]
+	if !ok {
+		return false
+	}
+	_, ok = h.liveSessionLocked(sessionID, now)
 	return ok
 }
 
@@ -363,11 +519,24 @@ func (h *Hub) IsConnectorConnected(connectorID string) bool {
 	if connectorID == "" {
 		return false
 	}
+	now := time.Now().UTC()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	sessionID, ok := h.connectorSessions[connectorID]
+	if !ok {
+		return false
+	}
+	_, ok = h.liveSessionLocked(sessionID, now)
+	return ok
+}
+
+func (h *Hub) DisconnectConnector(connectorID string) {
+	connectorID = strings.TrimSpace(connectorID)
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	h.cleanupStaleLocked(time.Now().UTC())
-	_, ok := h.connectorSessions[connectorID]
-	return ok
+	if sessionID, ok := h.connectorSessions[connectorID]; ok {
+		h.removeSessionLocked(sessionID)
+	}
 }
 
 func (h *Hub) GetConnectorConnection(connectorID string) (ConnectorConnection, bool) {
@@ -376,59 +545,68 @@ func (h *Hub) GetConnectorConnection(connectorID string) (ConnectorConnection, b
 		return ConnectorConnection{}, false
 	}
 
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.cleanupStaleLocked(time.Now().UTC())
-
-	sessionID, ok := h.connectorSessions[connectorID]
-	if !ok {
-		return ConnectorConnection{
-			ConnectorID: connectorID,
-			Connected:   false,
-		}, false
-	}
-	s, ok := h.sessions[sessionID]
+	now := time.Now().UTC()
+	h.mu.RLock()
+	s, ok := h.liveSessionLocked(h.connectorSessions[connectorID], now)
+	h.mu.RUnlock()
 	if !ok {
-		delete(h.connectorSessions, connectorID)
 		return ConnectorConnection{
 			ConnectorID: connectorID,
 			Connected:   false,
 		}, false
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return ConnectorConnection{
-		ConnectorID: connectorID,
-		AgentID:     s.agentID,
-		Connected:   true,
-		LastSeen:    s.lastSeen,
+		ConnectorID:     connectorID,
+		AgentID:         s.agentID,
+		Connected:       true,
+		LastSeen:        s.lastSeen,
+		Load:            s.loadLocked(),
+		ProtocolVersion: s.capabilities.version,
+		Capabilities:    s.capabilities.names,
+		AgentVersion:    s.capabilities.agentVersion,
 	}, true
 }
 
 func (h *Hub) EnsureTunnelMetric(tunnelID string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	if _, ok := h.metrics[tunnelID]; !ok {
-		h.metrics[tunnelID] = &TunnelMetrics{TunnelID: tunnelID}
-	}
+	h.metrics.ensure(tunnelID)
 }
 
 func (h *Hub) GetTunnelMetrics(tunnelID string) TunnelMetrics {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.cleanupStaleLocked(time.Now().UTC())
-	return h.copyMetricLocked(tunnelID)
+	return h.metrics.get(tunnelID)
+}
+
+func (h *Hub) ResetTunnelMetrics(tenantID, routeID string) time.Time {
+	at := time.Now().UTC()
+	h.metrics.reset(tenantID, routeID, at)
+	return at
 }
 
 func (h *Hub) RecordProxyFailure(tunnelID string, bytesIn int64, errMsg string) {
 	h.recordFailedAttempt(tunnelID, bytesIn, errMsg)
 }
 
+func (h *Hub) RecordProxyTimeout(tunnelID string, bytesIn int64, errMsg string) {
+	h.recordTimedOutAttempt(tunnelID, bytesIn, errMsg)
+}
+
+func (h *Hub) RecordProxyRetries(tunnelID string, retries int) {
+	if retries <= 0 {
+		return
+	}
+	h.metrics.recordRetries(tunnelID, retries)
+}
+
+func (h *Hub) RecordWebhookVerification(tunnelID string, verified bool) {
+	h.metrics.recordWebhookVerification(tunnelID, verified)
+}
+
 func (h *Hub) RecordProxyResponse(response *protocol.ProxyResponse) {
 	if response == nil {
 		return
 	}
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.recordSuccessfulAttemptLocked(response)
+	h.recordResponse(response)
 }
 
 func (h *Hub) DispatchProxyRequest(ctx context.Context, tunnelID string, req *protocol.ProxyRequest) (*protocol.ProxyResponse, error) {
@@ -436,31 +614,37 @@ func (h *Hub) DispatchProxyRequest(ctx context.Context, tunnelID string, req *pr
 		return nil, errors.New("missing proxy request")
 	}
 
-	h.mu.Lock()
-	h.cleanupStaleLocked(time.Now().UTC())
+	now := time.Now().UTC()
+	h.mu.RLock()
 	sessionID, ok := h.tunnelSessions[tunnelID]
 	if !ok {
-		h.mu.Unlock()
+		h.mu.RUnlock()
 		h.recordFailedAttempt(tunnelID, int64(len(req.Body)), "tunnel not connected")
 		return nil, ErrTunnelNotConnected
 	}
-	session, ok := h.sessions[sessionID]
+	session, ok := h.liveSessionLocked(sessionID, now)
 	if !ok {
-		delete(h.tunnelSessions, tunnelID)
-		h.mu.Unlock()
+		h.mu.RUnlock()
 		h.recordFailedAttempt(tunnelID, int64(len(req.Body)), "tunnel session unavailable")
 		return nil, ErrTunnelNotConnected
 	}
-	requestID, resultCh, err := h.enqueueDispatchLocked(sessionID, session, tunnelID, req)
+	if err := session.unhealthyErr(tunnelID); err != nil {
+		h.mu.RUnlock()
+		return nil, err
+	}
+	if err := session.adapt(req); err != nil {
+		h.mu.RUnlock()
+		h.recordFailedAttempt(tunnelID, int64(len(req.Body)), err.Error())
+		return nil, err
+	}
+	requestID, resultCh, err := h.enqueueDispatchLocked(ctx, session, tunnelID, req)
+	h.mu.RUnlock()
 	if err != nil {
-		h.mu.Unlock()
 		h.recordFailedAttempt(tunnelID, int64(len(req.Body)), err.Error())
 		return nil, err
 	}
-	requestQueue := session.queue
-	h.mu.Unlock()
 
-	return h.waitForProxyResponse(ctx, tunnelID, requestID, requestQueue, req, resultCh)
+	return h.waitForProxyResponse(ctx, tunnelID, requestID, session.queue, req, resultCh)
 }
 
 func (h *Hub) DispatchProxyRequestToConnector(ctx context.Context, connectorID, tunnelID string, req *protocol.ProxyRequest) (*protocol.ProxyResponse, error) {
@@ -472,53 +656,60 @@ func (h *Hub) DispatchProxyRequestToConnector(ctx context.Context, connectorID,
 		return nil, errors.New("missing connector id")
 	}
 
-	h.mu.Lock()
-	h.cleanupStaleLocked(time.Now().UTC())
+	now := time.Now().UTC()
+	h.mu.RLock()
 	sessionID, ok := h.connectorSessions[connectorID]
 	if !ok {
-		h.mu.Unlock()
+		h.mu.RUnlock()
 		h.recordFailedAttempt(tunnelID, int64(len(req.Body)), "connector not connected")
 		return nil, ErrConnectorNotConnected
 	}
-	session, ok := h.sessions[sessionID]
+	session, ok := h.liveSessionLocked(sessionID, now)
 	if !ok {
-		delete(h.connectorSessions, connectorID)
-		h.mu.Unlock()
+		h.mu.RUnlock()
 		h.recordFailedAttempt(tunnelID, int64(len(req.Body)), "connector session unavailable")
 		return nil, ErrConnectorNotConnected
 	}
-	requestID, resultCh, err := h.enqueueDispatchLocked(sessionID, session, tunnelID, req)
+	if req.LocalTarget != nil {
+		if err := session.unhealthyErr(localTargetHealthKey(req.LocalTarget)); err != nil {
+			h.mu.RUnlock()
+			return nil, err
+		}
+	}
+	if err := session.adapt(req); err != nil {
+		h.mu.RUnlock()
+		h.recordFailedAttempt(tunnelID, int64(len(req.Body)), err.Error())
+		return nil, err
+	}
+	requestID, resultCh, err := h.enqueueDispatchLocked(ctx, session, tunnelID, req)
+	h.mu.RUnlock()
 	if err != nil {
-		h.mu.Unlock()
 		h.recordFailedAttempt(tunnelID, int64(len(req.Body)), err.Error())
 		return nil, err
 	}
-	requestQueue := session.queue
-	h.mu.Unlock()
 
-	return h.waitForProxyResponse(ctx, tunnelID, requestID, requestQueue, req, resultCh)
+	return h.waitForProxyResponse(ctx, tunnelID, requestID, session.queue, req, resultCh)
 }
 
 func (h *Hub) SnapshotTunnels() []TunnelSnapshot {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.cleanupStaleLocked(time.Now().UTC())
+	now := time.Now().UTC()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 
 	snapshots := make([]TunnelSnapshot, 0, len(h.tunnelSessions))
 	for tunnelID, sessionID := range h.tunnelSessions {
-		session, ok := h.sessions[sessionID]
+		session, ok := h.liveSessionLocked(sessionID, now)
 		if !ok {
 			continue
 		}
 		cfg := h.configs[tunnelID]
-		metric := h.copyMetricLocked(tunnelID)
 		snapshots = append(snapshots, TunnelSnapshot{
 			ID:            tunnelID,
 			Target:        cfg.Target,
 			RequiresToken: cfg.Token != "",
 			AgentID:       session.agentID,
 			PublicURL:     fmt.Sprintf("%s/t/%s/", h.publicBaseURL, tunnelID),
-			Metrics:       metric,
+			Metrics:       h.metrics.get(tunnelID),
 			Connection: ConnectionSnapshot{
 				Connected: true,
 			},
@@ -532,53 +723,83 @@ func (h *Hub) SnapshotTunnels() []TunnelSnapshot {
 }
 
 func (h *Hub) Status() HubStatus {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.cleanupStaleLocked(time.Now().UTC())
-
+	now := time.Now().UTC()
+	h.mu.RLock()
 	status := HubStatus{
-		ActiveSessions:       len(h.sessions),
-		ActiveTunnelSessions: len(h.tunnelSessions),
-		ActiveConnectors:     len(h.connectorSessions),
-		PendingRequests:      len(h.pending),
+		PendingRequests:      h.pending.Len(),
 		MaxPendingGlobal:     h.maxPendingGlobal,
 		MaxPendingPerSession: h.maxPendingPerSession,
 	}
 
-	for _, s := range h.sessions {
-		depth := len(s.queue)
+	status.QueueDepthByClass = make(map[string]int, len(priorityClasses))
+	for _, class := range priorityClasses {
+		status.QueueDepthByClass[class] = 0
+	}
+	live := make(map[string]bool, len(h.sessions))
+	for sessionID, s := range h.sessions {
+		if s.staleAt(now, h.sessionTTL) {
+			continue
+		}
+		live[sessionID] = true
+		depth := 0
+		for class, classDepth := range s.queue.depthByClass() {
+			status.QueueDepthByClass[class] += classDepth
+			depth += classDepth
+		}
 		status.QueueDepthTotal += depth
 		if depth > status.QueueDepthMax {
 			status.QueueDepthMax = depth
 		}
 	}
-
-	for _, metric := range h.metrics {
-		status.RequestCount += metric.RequestCount
-		status.ErrorCount += metric.ErrorCount
+	status.ActiveSessions = len(live)
+	for _, sessionID := range h.tunnelSessions {
+		if live[sessionID] {
+			status.ActiveTunnelSessions++
+		}
+	}
+	for _, sessionID := range h.connectorSessions {
+		if live[sessionID] {
+			status.ActiveConnectors++
+		}
 	}
+	h.mu.RUnlock()
+
+	totals := h.metrics.totals()
+	status.RequestCount = totals.requests
+	status.ErrorCount = totals.errors
+	status.TimeoutCount = totals.timeouts
+	status.RetryCount = totals.retries
 	if status.RequestCount > 0 {
 		status.ErrorRate = float64(status.ErrorCount) / float64(status.RequestCount)
 	}
 
-	if len(h.latencySamples) > 0 {
-		ordered := make([]int64, len(h.latencySamples))
-		copy(ordered, h.latencySamples)
-		sort.Slice(ordered, func(i, j int) bool { return ordered[i] < ordered[j] })
-		status.P50LatencyMs = percentileValue(ordered, 50)
-		status.P95LatencyMs = percentileValue(ordered, 95)
-	}
+	status.P50LatencyMs = h.metrics.quantile(0.50)
+	status.P90LatencyMs = h.metrics.quantile(0.90)
+	status.P95LatencyMs = h.metrics.quantile(0.95)
+	status.P99LatencyMs = h.metrics.quantile(0.99)
+	queueWait, queueOverflow := h.queueStats.snapshot()
+	status.QueueWait = queueWait.Percentiles()
+	status.QueueOverflow = queueOverflow
 
 	return status
 }
 
-func (h *Hub) enqueueDispatchLocked(sessionID string, session *session, tunnelID string, req *protocol.ProxyRequest) (string, chan dispatchResult, error) {
-	if len(h.pending) >= h.maxPendingGlobal {
+// enqueueDispatchLocked registers req as pending on session. Callers hold mu
+// for reading, so Close and session removal see every pending request. Only
+// the reject overflow policy refuses a full queue here; the others handle it
+// when the request is queued.
+func (h *Hub) enqueueDispatchLocked(ctx context.Context, session *session, tunnelID string, req *protocol.ProxyRequest) (string, chan dispatchResult, error) {
+	if h.closed {
+		return "", nil, ErrGatewayShuttingDown
+	}
+	if h.pending.Len() >= h.maxPendingGlobal {
 		return "", nil, ErrGlobalBackpressure
 	}
-	if len(session.queue) >= h.maxPendingPerSession {
+	if queueOverflowFrom(ctx).Policy == QueueOverflowReject && session.queue.Len() >= h.maxPendingPerSession {
+		h.queueStats.recordOverflow(func(stats *QueueOverflowStats) { stats.Rejected++ })
 		return "", nil, ErrAgentQueueFull
 	}
+	deadline, _ := ctx.Deadline()
 
 	requestID := strings.TrimSpace(req.RequestID)
 	if requestID == "" {
@@ -588,28 +809,36 @@ func (h *Hub) enqueueDispatchLocked(sessionID string, session *session, tunnelID
 	req.TunnelID = tunnelID
 
 	resultCh := make(chan dispatchResult, 1)
-	h.pending[requestID] = pendingRequest{
-		requestID: requestID,
-		sessionID: sessionID,
-		tunnelID:  tunnelID,
-		resultCh:  resultCh,
+	if !h.pending.add(pendingRequest{
+		requestID:  requestID,
+		session:    session,
+		tunnelID:   tunnelID,
+		deadline:   deadline,
+		enqueuedAt: time.Now(),
+		resultCh:   resultCh,
+		cancel:     newCancelSignal(),
+	}, h.maxPendingGlobal) {
+		return "", nil, ErrGlobalBackpressure
 	}
+	session.mu.Lock()
+	session.inFlight++
+	session.dispatched++
+	session.mu.Unlock()
 	return requestID, resultCh, nil
 }
 
 func (h *Hub) waitForProxyResponse(
 	ctx context.Context,
 	tunnelID, requestID string,
-	requestQueue chan *protocol.ProxyRequest,
+	requestQueue *sessionQueue,
 	req *protocol.ProxyRequest,
 	resultCh chan dispatchResult,
 ) (*protocol.ProxyResponse, error) {
-	select {
-	case requestQueue <- req:
-	default:
-		h.mu.Lock()
-		delete(h.pending, requestID)
-		h.mu.Unlock()
+	if err := h.injectDispatchDelay(ctx); err != nil {
+		return nil, h.abandonProxyRequest(ctx, tunnelID, requestID, req)
+	}
+	if !h.queueProxyRequest(ctx, requestQueue, req) {
+		h.releasePending(requestID)
 		h.recordFailedAttempt(tunnelID, int64(len(req.Body)), "agent queue is full")
 		return nil, ErrAgentQueueFull
 	}
@@ -626,15 +855,25 @@ func (h *Hub) waitForProxyResponse(
 		}
 		return result.response, nil
 	case <-ctx.Done():
-		h.mu.Lock()
-		delete(h.pending, requestID)
-		h.mu.Unlock()
-		h.recordFailedAttempt(tunnelID, int64(len(req.Body)), "timeout waiting for agent response")
-		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-			return nil, ErrProxyRequestTimeout
-		}
-		return nil, ctx.Err()
+		return nil, h.abandonProxyRequest(ctx, tunnelID, requestID, req)
+	}
+}
+
+func (h *Hub) abandonProxyRequest(ctx context.Context, tunnelID, requestID string, req *protocol.ProxyRequest) error {
+	reason := protocol.CancelReasonCallerGone
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		reason = protocol.CancelReasonTimeout
 	}
+	if pending, ok := h.pending.take(requestID); ok {
+		pending.session.finishRequest(pending, false)
+		pending.cancel.fire(reason)
+	}
+	if reason == protocol.CancelReasonTimeout {
+		h.recordTimedOutAttempt(tunnelID, int64(len(req.Body)), "timeout waiting for agent response")
+		return ErrProxyRequestTimeout
+	}
+	h.recordFailedAttempt(tunnelID, int64(len(req.Body)), "request cancelled waiting for agent response")
+	return ctx.Err()
 }
 
 func (h *Hub) nextRequestID() string {
@@ -648,64 +887,69 @@ func (h *Hub) nextSessionID() string {
 }
 
 func (h *Hub) recordFailedAttempt(tunnelID string, bytesIn int64, errMsg string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+	h.recordFailedAttemptStatus(tunnelID, bytesIn, http.StatusBadGateway, errMsg)
+}
 
-	metric, ok := h.metrics[tunnelID]
-	if !ok {
-		metric = &TunnelMetrics{TunnelID: tunnelID}
-		h.metrics[tunnelID] = metric
-	}
-	metric.RequestCount++
-	metric.ErrorCount++
-	metric.BytesIn += bytesIn
-	metric.LastStatus = 502
-	metric.LastError = errMsg
-	metric.LastSeen = time.Now().UTC()
-	if metric.RequestCount > 0 {
-		metric.AverageLatencyMs = float64(metric.TotalLatencyMs) / float64(metric.RequestCount)
-	}
+func (h *Hub) recordTimedOutAttempt(tunnelID string, bytesIn int64, errMsg string) {
+	h.recordFailedAttemptStatus(tunnelID, bytesIn, http.StatusGatewayTimeout, errMsg)
 }
 
-func (h *Hub) recordSuccessfulAttemptLocked(response *protocol.ProxyResponse) {
-	metric, ok := h.metrics[response.TunnelID]
-	if !ok {
-		metric = &TunnelMetrics{TunnelID: response.TunnelID}
-		h.metrics[response.TunnelID] = metric
-	}
-	metric.RequestCount++
-	if response.Error != "" || response.Status >= 500 {
-		metric.ErrorCount++
-	}
-	metric.BytesIn += response.BytesIn
-	metric.BytesOut += response.BytesOut
-	metric.TotalLatencyMs += response.LatencyMs
-	metric.LastStatus = response.Status
-	metric.LastError = response.Error
-	metric.LastSeen = time.Now().UTC()
-	if metric.RequestCount > 0 {
-		metric.AverageLatencyMs = float64(metric.TotalLatencyMs) / float64(metric.RequestCount)
-	}
-	if response.LatencyMs > 0 {
-		h.appendLatencyLocked(response.LatencyMs)
+func (h *Hub) recordFailedAttemptStatus(tunnelID string, bytesIn int64, status int, errMsg string) {
+	at := h.metrics.recordFailure(tunnelID, bytesIn, status, errMsg)
+	h.timeseries.Record(tunnelID, at, true, bytesIn, 0)
+	h.availability.RecordRequest(tunnelID, at, true, 0)
+}
+
+func (h *Hub) recordResponse(response *protocol.ProxyResponse) {
+	at := h.metrics.recordResponse(response)
+	failed := response.Error != "" || response.Status >= 500
+	h.timeseries.Record(response.TunnelID, at, failed, response.BytesIn, response.BytesOut)
+	h.availability.RecordRequest(response.TunnelID, at, failed, response.LatencyMs)
+}
+
+func (h *Hub) sweepStaleSessions() {
+	ticker := time.NewTicker(staleSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.closing:
+			return
+		case now := <-ticker.C:
+			h.removeStaleSessions(now.UTC())
+		}
 	}
 }
 
-func (h *Hub) copyMetricLocked(tunnelID string) TunnelMetrics {
-	metric, ok := h.metrics[tunnelID]
-	if !ok {
-		return TunnelMetrics{TunnelID: tunnelID}
+// removeStaleSessions drops the sessions not seen within sessionTTL, failing
+// their pending requests, and returns how many it dropped. It only takes the
+// write lock when there is a session to drop.
+func (h *Hub) removeStaleSessions(now time.Time) int {
+	h.mu.RLock()
+	stale := false
+	for _, s := range h.sessions {
+		if s.staleAt(now, h.sessionTTL) {
+			stale = true
+			break
+		}
+	}
+	h.mu.RUnlock()
+	if !stale {
+		return 0
 	}
-	copied := *metric
-	return copied
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.removeStaleLocked(now)
 }
 
-func (h *Hub) cleanupStaleLocked(now time.Time) {
+func (h *Hub) removeStaleLocked(now time.Time) int {
+	removed := 0
 	for sessionID, s := range h.sessions {
-		if now.Sub(s.lastSeen) > h.sessionTTL {
+		if s.staleAt(now, h.sessionTTL) {
 			h.removeSessionLocked(sessionID)
+			removed++
 		}
 	}
+	return removed
 }
 
 func (h *Hub) removeSessionLocked(sessionID string) {
@@ -727,15 +971,8 @@ func (h *Hub) removeSessionLocked(sessionID string) {
 	}
 	delete(h.sessions, sessionID)
 
-	for requestID, pending := range h.pending {
-		if pending.sessionID != sessionID {
-			continue
-		}
-		delete(h.pending, requestID)
-		select {
-		case pending.resultCh <- dispatchResult{err: ErrUnknownSession}:
-		default:
-		}
+	for _, pending := range h.pending.takeMatching(func(pending pendingRequest) bool { return pending.session == s }) {
+		pending.resultCh <- dispatchResult{err: ErrUnknownSession}
 	}
 }
 
@@ -751,31 +988,14 @@ func (h *Hub) removeTunnelFromSessionLocked(sessionID, tunnelID string) {
 	}
 }
 
-func (h *Hub) appendLatencyLocked(latencyMs int64) {
-	const maxSamples = 512
-	if len(h.latencySamples) >= maxSamples {
-		copy(h.latencySamples, h.latencySamples[1:])
-		h.latencySamples = h.latencySamples[:maxSamples-1]
-	}
-	h.latencySamples = append(h.latencySamples, latencyMs)
+func (h *Hub) TenantLatency(tenantID string) LatencyPercentiles {
+	return h.metrics.tenantPercentiles(normalizeIdentifier(tenantID))
 }
 
-func percentileValue(sorted []int64, percentile int) int64 {
-	if len(sorted) == 0 {
-		return 0
-	}
-	if percentile <= 0 {
-		return sorted[0]
-	}
-	if percentile >= 100 {
-		return sorted[len(sorted)-1]
-	}
-	index := int(float64(percentile) / 100.0 * float64(len(sorted)-1))
-	if index < 0 {
-		index = 0
-	}
-	if index >= len(sorted) {
-		index = len(sorted) - 1
-	}
-	return sorted[index]
+func (h *Hub) TenantLatencies() map[string]LatencyPercentiles {
+	return h.metrics.tenantPercentilesAll()
+}
+
+func (h *Hub) RouteLatencyHistograms() map[string]LatencyHistogram {
+	return h.metrics.routeHistograms()
 }
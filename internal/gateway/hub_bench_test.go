@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+// benchmarkHubDispatch measures a dispatch round trip through the hub with an
+// in-process agent answering every pulled request. Run it with
+// go test ./internal/gateway -run '^$' -bench HubDispatch; cmd/loadgen covers
+// the HTTP path end to end.
+func benchmarkHubDispatch(b *testing.B, parallel bool) {
+	hub := NewHub("bench-token", "http://localhost:8080", 5*time.Second, 0, 0)
+	registration, err := hub.RegisterConnectorSession("bench", "bench-agent")
+	if err != nil {
+		b.Fatalf("register connector session: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for range 4 {
+		go func() {
+			for {
+				request, err := hub.PullRequest(ctx, registration.SessionID)
+				if err != nil {
+					return
+				}
+				_ = hub.SubmitProxyResponse(registration.SessionID, &protocol.ProxyResponse{
+					RequestID: request.RequestID,
+					TunnelID:  request.TunnelID,
+					Status:    http.StatusOK,
+					Body:      []byte("ok"),
+				})
+			}
+		}()
+	}
+
+	dispatch := func() {
+		if _, err := hub.DispatchProxyRequestToConnector(ctx, "bench", "default/app", &protocol.ProxyRequest{Method: http.MethodGet, Path: "/"}); err != nil {
+			b.Errorf("dispatch: %v", err)
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	if parallel {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				dispatch()
+			}
+		})
+		return
+	}
+	for b.Loop() {
+		dispatch()
+	}
+}
+
+func BenchmarkHubDispatch(b *testing.B) {
+	benchmarkHubDispatch(b, false)
+}
+
+func BenchmarkHubDispatchParallel(b *testing.B) {
+	benchmarkHubDispatch(b, true)
+}
+
+// BenchmarkHubDispatchManySessions spreads parallel dispatches over many
+// connector sessions, where requests for different agents should not contend.
+func BenchmarkHubDispatchManySessions(b *testing.B) {
+	const sessions = 64
+	hub := NewHub("bench-token", "http://localhost:8080", 5*time.Second, 0, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := range sessions {
+		connectorID := fmt.Sprintf("bench-%d", i)
+		registration, err := hub.RegisterConnectorSession(connectorID, connectorID+"-agent")
+		if err != nil {
+			b.Fatalf("register connector session: %v", err)
+		}
+		go func() {
+			for {
+				request, err := hub.PullRequest(ctx, registration.SessionID)
+				if err != nil {
+					return
+				}
+				_ = hub.SubmitProxyResponse(registration.SessionID, &protocol.ProxyResponse{
+					RequestID: request.RequestID,
+					TunnelID:  request.TunnelID,
+					Status:    http.StatusOK,
+					LatencyMs: 3,
+				})
+			}
+		}()
+	}
+
+	var next atomic.Int64
+	b.ReportAllocs()
+	b.SetParallelism(8)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := next.Add(1) % sessions
+			connectorID := fmt.Sprintf("bench-%d", i)
+			tunnelID := fmt.Sprintf("default/app-%d", i)
+			if _, err := hub.DispatchProxyRequestToConnector(ctx, connectorID, tunnelID, &protocol.ProxyRequest{Method: http.MethodGet, Path: "/"}); err != nil {
+				b.Errorf("dispatch: %v", err)
+			}
+		}
+	})
+}
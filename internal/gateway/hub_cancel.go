@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+type cancelSignal struct {
+	once   sync.Once
+	done   chan struct{}
+	reason string
+}
+
+func newCancelSignal() *cancelSignal {
+	return &cancelSignal{done: make(chan struct{})}
+}
+
+func (c *cancelSignal) fire(reason string) {
+	if c == nil {
+		return
+	}
+	c.once.Do(func() {
+		c.reason = reason
+		close(c.done)
+	})
+}
+
+// WatchCancel waits until the caller of requestID gives up and returns the
+// reason, or returns an empty reason once ctx is done with the request still
+// pending. A request the gateway no longer waits for is reported at once.
+func (h *Hub) WatchCancel(ctx context.Context, sessionID, requestID string) (string, error) {
+	s, ok := h.liveSession(sessionID)
+	if !ok {
+		return "", ErrUnknownSession
+	}
+	pending, ok := h.pending.get(requestID)
+	if !ok {
+		return protocol.CancelReasonNotPending, nil
+	}
+	if pending.session != s {
+		return "", ErrResponseSessionMismatch
+	}
+	select {
+	case <-pending.cancel.done:
+		return pending.cancel.reason, nil
+	case <-h.closing:
+		return "", ErrGatewayShuttingDown
+	case <-ctx.Done():
+		return "", nil
+	}
+}
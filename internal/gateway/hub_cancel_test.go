@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestHubWatchCancelReportsCallersThatGaveUp(t *testing.T) {
+	hub := NewHub("token", "http://localhost", 30*time.Second, 4, 0)
+	registered, err := hub.RegisterConnectorSession("laptop", "agent-1")
+	if err != nil {
+		t.Fatalf("register connector session: %v", err)
+	}
+
+	callerCtx, hangUp := context.WithCancel(context.Background())
+	dispatched := make(chan error, 1)
+	go func() {
+		_, err := hub.DispatchProxyRequestToConnector(callerCtx, "laptop", "default/app", &protocol.ProxyRequest{RequestID: "req-1", Method: http.MethodGet, Path: "/"})
+		dispatched <- err
+	}()
+	request, err := hub.PullRequest(context.Background(), registered.SessionID)
+	if err != nil || request.RequestID != "req-1" {
+		t.Fatalf("expected req-1 to be pulled, got %+v %v", request, err)
+	}
+
+	idleCtx, cancelIdle := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelIdle()
+	if reason, err := hub.WatchCancel(idleCtx, registered.SessionID, "req-1"); err != nil || reason != "" {
+		t.Fatalf("expected a request still waited for not to be cancelled, got %q %v", reason, err)
+	}
+
+	watched := make(chan string, 1)
+	go func() {
+		reason, _ := hub.WatchCancel(context.Background(), registered.SessionID, "req-1")
+		watched <- reason
+	}()
+	time.Sleep(20 * time.Millisecond)
+	hangUp()
+	if err := <-dispatched; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the dispatch to end with the caller, got %v", err)
+	}
+	select {
+	case reason := <-watched:
+		if reason != protocol.CancelReasonCallerGone {
+			t.Fatalf("expected %q, got %q", protocol.CancelReasonCallerGone, reason)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("the watch did not see the caller give up")
+	}
+
+	if reason, err := hub.WatchCancel(context.Background(), registered.SessionID, "req-1"); err != nil || reason != protocol.CancelReasonNotPending {
+		t.Fatalf("expected a finished request to be reported as not pending, got %q %v", reason, err)
+	}
+	if _, err := hub.WatchCancel(context.Background(), "unknown", "req-1"); !errors.Is(err, ErrUnknownSession) {
+		t.Fatalf("expected an unknown session to be refused, got %v", err)
+	}
+}
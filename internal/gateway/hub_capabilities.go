@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+var ErrCapabilityUnsupported = errors.New("agent does not support this request")
+
+type agentCapabilities struct {
+	version      int
+	names        []string
+	agentVersion string
+}
+
+// negotiateCapabilities keeps the capabilities both the agent and the gateway
+// support. Agents without a protocol version get the legacy set.
+func negotiateCapabilities(message *protocol.RegisterRequest) agentCapabilities {
+	if message == nil {
+		return agentCapabilities{version: 1, names: slices.Clone(protocol.LegacyCapabilities)}
+	}
+	agentVersion := strings.TrimSpace(message.AgentVersion)
+	if message.ProtocolVersion <= 1 {
+		return agentCapabilities{version: 1, names: slices.Clone(protocol.LegacyCapabilities), agentVersion: agentVersion}
+	}
+	negotiated := agentCapabilities{version: min(message.ProtocolVersion, protocol.ProtocolVersion), agentVersion: agentVersion}
+	for _, name := range protocol.Capabilities {
+		if slices.ContainsFunc(message.Capabilities, func(offered string) bool {
+			return strings.EqualFold(strings.TrimSpace(offered), name)
+		}) {
+			negotiated.names = append(negotiated.names, name)
+		}
+	}
+	return negotiated
+}
+
+func (c agentCapabilities) has(name string) bool {
+	return slices.Contains(c.names, name)
+}
+
+func (c agentCapabilities) equal(other agentCapabilities) bool {
+	return c.version == other.version && slices.Equal(c.names, other.names) && c.agentVersion == other.agentVersion
+}
+
+func (c agentCapabilities) annotate(response *protocol.RegisterResponse) {
+	response.ProtocolVersion = protocol.ProtocolVersion
+	response.Capabilities = slices.Clone(c.names)
+}
+
+func (s *session) adapt(req *protocol.ProxyRequest) error {
+	if req.Stream == protocol.StreamTCP && !s.capabilities.has(protocol.CapabilityTCPStream) {
+		return ErrCapabilityUnsupported
+	}
+	if req.Upload && !s.capabilities.has(protocol.CapabilityUpload) {
+		return ErrCapabilityUnsupported
+	}
+	if req.Retry != nil && !s.capabilities.has(protocol.CapabilityRetry) {
+		req.Retry = nil
+	}
+	return nil
+}
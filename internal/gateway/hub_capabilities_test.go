@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestHubGatesRequestsOnNegotiatedCapabilities(t *testing.T) {
+	hub := NewHub("token", "http://localhost:8080", 5*time.Second, 0, 0)
+	defer hub.Close()
+
+	legacy, err := hub.RegisterConnectorSession("old", "old-agent")
+	if err != nil {
+		t.Fatalf("register legacy connector: %v", err)
+	}
+	if legacy.ProtocolVersion != protocol.ProtocolVersion || !slices.Equal(legacy.Capabilities, protocol.LegacyCapabilities) {
+		t.Fatalf("expected version 1 agents to keep the legacy capabilities, got v%d %v", legacy.ProtocolVersion, legacy.Capabilities)
+	}
+
+	message := &protocol.RegisterRequest{
+		AgentID:         "new-agent",
+		ConnectorID:     "new",
+		ProtocolVersion: protocol.ProtocolVersion + 1,
+		Capabilities:    []string{"batching", protocol.CapabilityRetry},
+	}
+	registration, err := hub.RegisterConnector(message)
+	if err != nil {
+		t.Fatalf("register connector: %v", err)
+	}
+	if !slices.Equal(registration.Capabilities, []string{protocol.CapabilityRetry}) {
+		t.Fatalf("expected only the shared capabilities, got %v", registration.Capabilities)
+	}
+	if connection, _ := hub.GetConnectorConnection("new"); connection.ProtocolVersion != protocol.ProtocolVersion || len(connection.Capabilities) != 1 {
+		t.Fatalf("expected the session to record its capabilities, got %+v", connection)
+	}
+
+	_, err = hub.DispatchProxyRequestToConnector(context.Background(), "new", "default/tls", &protocol.ProxyRequest{Method: http.MethodConnect, Stream: protocol.StreamTCP})
+	if !errors.Is(err, ErrCapabilityUnsupported) {
+		t.Fatalf("expected a stream to need tcp_stream, got %v", err)
+	}
+	if hub.pending.Len() != 0 {
+		t.Fatalf("expected the refused stream not to be queued")
+	}
+
+	// After an upgrade the agent resumes offering every capability.
+	message.Capabilities = protocol.Capabilities
+	resumed, err := hub.ResumeSession(registration.SessionID, message)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if resumed.SessionID != registration.SessionID || !slices.Equal(resumed.Capabilities, protocol.Capabilities) {
+		t.Fatalf("expected the session to continue with the new capabilities, got %s %v", resumed.SessionID, resumed.Capabilities)
+	}
+
+	message.Capabilities = nil
+	if _, err := hub.ResumeSession(registration.SessionID, message); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := hub.DispatchProxyRequestToConnector(context.Background(), "new", "default/app", &protocol.ProxyRequest{
+			Method: http.MethodGet,
+			Retry:  &protocol.RetryPolicy{Attempts: 3},
+		})
+		done <- err
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	request, err := hub.PullRequest(ctx, registration.SessionID)
+	if err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	if request.Retry != nil {
+		t.Fatalf("expected the retry policy to be dropped for an agent without retry")
+	}
+	if err := hub.SubmitProxyResponse(registration.SessionID, &protocol.ProxyResponse{RequestID: request.RequestID, TunnelID: request.TunnelID, Status: http.StatusOK}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"context"
+	"time"
+)
+
+// FaultInjection makes the hub misbehave on purpose, so integration tests and
+// dev gateways can check that timeouts, retries, incidents and agent
+// reconnects cope with a lossy tunnel. The zero value injects nothing.
+type FaultInjection struct {
+	DropResponsePercent float64
+	DispatchDelay       time.Duration
+	KillSessionPercent  float64
+}
+
+func (f FaultInjection) Active() bool {
+	return f.DropResponsePercent > 0 || f.DispatchDelay > 0 || f.KillSessionPercent > 0
+}
+
+func (h *Hub) SetFaultInjection(faults FaultInjection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.faults = faults
+}
+
+func (h *Hub) faultInjection() FaultInjection {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.faults
+}
+
+func (h *Hub) injectDispatchDelay(ctx context.Context) error {
+	delay := h.faultInjection().DispatchDelay
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *Hub) injectSessionKill(sessionID string) bool {
+	if !samplePercent(h.faultInjection().KillSessionPercent) {
+		return false
+	}
+	h.mu.Lock()
+	h.removeSessionLocked(sessionID)
+	h.mu.Unlock()
+	return true
+}
+
+func (h *Hub) injectResponseDrop() bool {
+	return samplePercent(h.faultInjection().DropResponsePercent)
+}
+
+func (cfg Config) faultInjection() FaultInjection {
+	return FaultInjection{
+		DropResponsePercent: cfg.FaultDropResponsePercent,
+		DispatchDelay:       cfg.FaultDispatchDelay,
+		KillSessionPercent:  cfg.FaultKillSessionPercent,
+	}
+}
+
+func (s *Server) applyFaultInjection(cfg Config) {
+	faults := cfg.faultInjection()
+	if faults.Active() {
+		s.logger.Printf("fault injection active, for testing only: drop_response=%g%% dispatch_delay=%s kill_session=%g%%", faults.DropResponsePercent, faults.DispatchDelay, faults.KillSessionPercent)
+	}
+	s.hub.SetFaultInjection(faults)
+}
+
+func (s *Server) SetFaultInjection(faults FaultInjection) {
+	s.cfgMu.Lock()
+	s.cfg.FaultDropResponsePercent = faults.DropResponsePercent
+	s.cfg.FaultDispatchDelay = faults.DispatchDelay
+	s.cfg.FaultKillSessionPercent = faults.KillSessionPercent
+	cfg := s.cfg
+	s.cfgMu.Unlock()
+	s.applyFaultInjection(cfg)
+}
@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestHubFaultInjectionDropsResponsesAndKillsSessions(t *testing.T) {
+	hub := NewHub("token", "http://localhost:8080", 5*time.Second, 0, 0)
+	defer hub.Close()
+	registration, err := hub.RegisterConnectorSession("edge", "agent-1")
+	if err != nil {
+		t.Fatalf("register connector session: %v", err)
+	}
+	hub.SetFaultInjection(FaultInjection{DropResponsePercent: 100})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := hub.DispatchProxyRequestToConnector(ctx, "edge", "default/app", &protocol.ProxyRequest{Method: http.MethodGet, Path: "/"})
+		done <- err
+	}()
+	request, err := hub.PullRequest(ctx, registration.SessionID)
+	if err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	if err := hub.SubmitProxyResponse(registration.SessionID, &protocol.ProxyResponse{RequestID: request.RequestID, TunnelID: request.TunnelID, Status: http.StatusOK}); err != nil {
+		t.Fatalf("expected a dropped response to look accepted, got %v", err)
+	}
+	if err := <-done; !errors.Is(err, ErrProxyRequestTimeout) {
+		t.Fatalf("expected the caller to time out, got %v", err)
+	}
+	if hub.pending.Len() != 0 {
+		t.Fatalf("expected the timed out request to be released")
+	}
+
+	hub.SetFaultInjection(FaultInjection{KillSessionPercent: 100})
+	if _, err := hub.PullRequest(context.Background(), registration.SessionID); !errors.Is(err, ErrUnknownSession) {
+		t.Fatalf("expected the poll to kill the session, got %v", err)
+	}
+	if hub.IsConnectorConnected("edge") {
+		t.Fatalf("expected the killed session to be gone")
+	}
+
+	hub.SetFaultInjection(FaultInjection{})
+	registration, err = hub.RegisterConnectorSession("edge", "agent-1")
+	if err != nil {
+		t.Fatalf("register again: %v", err)
+	}
+	pollCtx, pollCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer pollCancel()
+	if _, err := hub.PullRequest(pollCtx, registration.SessionID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected an idle poll once faults are off, got %v", err)
+	}
+}
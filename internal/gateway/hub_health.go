@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+var ErrTargetUnhealthy = errors.New("local target is unhealthy")
+
+func localTargetHealthKey(target *protocol.LocalTarget) string {
+	if socket := strings.TrimSpace(target.Socket); socket != "" {
+		return socket
+	}
+	return net.JoinHostPort(strings.TrimSpace(target.Host), strconv.Itoa(target.Port))
+}
+
+func (s *session) setHealthLocked(reports []protocol.TargetHealth) {
+	if len(reports) == 0 {
+		s.health = nil
+		return
+	}
+	s.health = make(map[string]protocol.TargetHealth, len(reports))
+	for _, report := range reports {
+		target := strings.TrimSpace(report.Target)
+		if target == "" {
+			continue
+		}
+		switch report.Status {
+		case protocol.HealthHealthy, protocol.HealthDegraded, protocol.HealthUnhealthy:
+		default:
+			continue
+		}
+		report.Target = target
+		s.health[target] = report
+	}
+}
+
+// unhealthyErrLocked returns ErrTargetUnhealthy when the session reported
+// target as unhealthy, and nil for healthy, degraded or unchecked targets.
+// Refused requests never reach the queue, so callers record the failure.
+func (s *session) unhealthyErrLocked(target string) error {
+	report, ok := s.health[target]
+	if !ok || report.Status != protocol.HealthUnhealthy {
+		return nil
+	}
+	if report.Error == "" {
+		return ErrTargetUnhealthy
+	}
+	return fmt.Errorf("%w: %s", ErrTargetUnhealthy, report.Error)
+}
+
+func (s *session) unhealthyErr(target string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.unhealthyErrLocked(target)
+}
+
+func (s *session) targetHealth(target string) (protocol.TargetHealth, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report, ok := s.health[target]
+	return report, ok
+}
+
+func (h *Hub) TunnelHealth(tunnelID string) (protocol.TargetHealth, bool) {
+	now := time.Now().UTC()
+	h.mu.RLock()
+	session, ok := h.liveSessionLocked(h.tunnelSessions[tunnelID], now)
+	h.mu.RUnlock()
+	if !ok {
+		return protocol.TargetHealth{}, false
+	}
+	return session.targetHealth(tunnelID)
+}
+
+func (h *Hub) ConnectorTargetHealth(connectorID, target string) (protocol.TargetHealth, bool) {
+	now := time.Now().UTC()
+	h.mu.RLock()
+	session, ok := h.liveSessionLocked(h.connectorSessions[connectorID], now)
+	h.mu.RUnlock()
+	if !ok {
+		return protocol.TargetHealth{}, false
+	}
+	return session.targetHealth(target)
+}
+
+func (s *Server) routeHealth(route Rule, connectedTunnelID string) (protocol.TargetHealth, bool) {
+	if route.UsesConnector() {
+		connectorID, ok := s.resolveConnector(route)
+		if !ok {
+			return protocol.TargetHealth{}, false
+		}
+		return s.hub.ConnectorTargetHealth(connectorID, localTargetHealthKey(route.localTarget()))
+	}
+	if connectedTunnelID == "" {
+		return protocol.TargetHealth{}, false
+	}
+	return s.hub.TunnelHealth(connectedTunnelID)
+}
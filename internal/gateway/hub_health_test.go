@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestUnhealthyConnectorTargetIsReportedAndRefused(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.connectorStore.Create(Connector{ID: "laptop", TenantID: DefaultTenantID}); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "app", ConnectorID: "laptop", LocalPort: 3000}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	registered, err := server.hub.RegisterConnectorSession("laptop", "agent-1")
+	if err != nil {
+		t.Fatalf("register connector session: %v", err)
+	}
+
+	if err := server.hub.Heartbeat(registered.SessionID, []protocol.TargetHealth{{
+		Target:              "127.0.0.1:3000",
+		Status:              protocol.HealthUnhealthy,
+		ConsecutiveFailures: 3,
+		Error:               "connection refused",
+		CheckedAt:           time.Now().UTC(),
+	}}); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+
+	rule, _ := server.ruleStore.GetForTenant(DefaultTenantID, "app")
+	view := server.buildRouteViewWithConnected(rule, nil)
+	if view.Health == nil || view.Health.Status != protocol.HealthUnhealthy {
+		t.Fatalf("expected route view to report the target unhealthy, got %+v", view.Health)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/app/", nil))
+	if recorder.Code != http.StatusServiceUnavailable || !strings.Contains(recorder.Body.String(), "connection refused") {
+		t.Fatalf("expected 503 naming the health failure, got %d %q", recorder.Code, recorder.Body.String())
+	}
+	if metric := server.hub.GetTunnelMetrics(MakeTunnelKey(DefaultTenantID, "app")); metric.ErrorCount != 1 {
+		t.Fatalf("expected the refused request to count as an error, got %+v", metric)
+	}
+
+	// Degraded targets keep receiving traffic; only unhealthy ones are refused.
+	if err := server.hub.Heartbeat(registered.SessionID, []protocol.TargetHealth{{Target: "127.0.0.1:3000", Status: protocol.HealthDegraded}}); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	if err := server.hub.sessions[registered.SessionID].unhealthyErrLocked("127.0.0.1:3000"); err != nil {
+		t.Fatalf("expected degraded target to accept dispatch, got %v", err)
+	}
+}
+
+func TestUnixSocketRouteDispatchesSocketTarget(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.connectorStore.Create(Connector{ID: "laptop", TenantID: DefaultTenantID}); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+	for _, invalid := range []Rule{
+		{ID: "docker", LocalSocket: "/var/run/docker.sock", Target: "http://example.com"},
+		{ID: "docker", ConnectorID: "laptop", LocalSocket: "docker.sock"},
+		{ID: "docker", ConnectorID: "laptop", LocalSocket: "/var/run/docker.sock", LocalPort: 2375},
+	} {
+		if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, invalid); err == nil {
+			t.Fatalf("expected %+v to be rejected", invalid)
+		}
+	}
+	rule, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "docker", ConnectorID: "laptop", LocalSocket: "/var/run/docker.sock"})
+	if err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	if rule.Target != "unix:///var/run/docker.sock" || rule.LocalHost != "" {
+		t.Fatalf("unexpected socket route %+v", rule)
+	}
+
+	registered, err := server.hub.RegisterConnectorSession("laptop", "agent-1")
+	if err != nil {
+		t.Fatalf("register connector session: %v", err)
+	}
+	go server.handleProxy(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/t/docker/_ping", nil))
+	pulled, err := server.hub.PullRequest(context.Background(), registered.SessionID)
+	if err != nil {
+		t.Fatalf("pull request: %v", err)
+	}
+	if pulled.LocalTarget == nil || pulled.LocalTarget.Socket != "/var/run/docker.sock" || pulled.Path != "/_ping" {
+		t.Fatalf("expected the request to target the socket, got %+v", pulled.LocalTarget)
+	}
+	if err := server.hub.SubmitProxyResponse(registered.SessionID, &protocol.ProxyResponse{RequestID: pulled.RequestID, TunnelID: pulled.TunnelID, Status: http.StatusOK}); err != nil {
+		t.Fatalf("submit response: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+const connectorLatencyWeight = 0.2
+
+type ConnectorLoad struct {
+	InFlight        int     `json:"in_flight"`
+	Queued          int     `json:"queued"`
+	RecentLatencyMs float64 `json:"recent_latency_ms"`
+	Dispatched      int64   `json:"dispatched"`
+}
+
+func (s *session) loadLocked() ConnectorLoad {
+	return ConnectorLoad{
+		InFlight:        s.inFlight,
+		Queued:          s.queue.Len(),
+		RecentLatencyMs: s.latencyMs,
+		Dispatched:      s.dispatched,
+	}
+}
+
+func (s *session) load() ConnectorLoad {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadLocked()
+}
+
+func (l ConnectorLoad) lessLoaded(other ConnectorLoad) bool {
+	if l.InFlight != other.InFlight {
+		return l.InFlight < other.InFlight
+	}
+	return l.RecentLatencyMs < other.RecentLatencyMs
+}
+
+func (h *Hub) PickConnector(candidates []string) (string, bool) {
+	now := time.Now().UTC()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	picked := ""
+	var pickedLoad ConnectorLoad
+	ties := 0
+	for _, connectorID := range candidates {
+		sessionID, ok := h.connectorSessions[connectorID]
+		if !ok {
+			continue
+		}
+		session, ok := h.liveSessionLocked(sessionID, now)
+		if !ok {
+			continue
+		}
+		load := session.load()
+		switch {
+		case picked == "" || load.lessLoaded(pickedLoad):
+			picked, pickedLoad, ties = session.connectorID, load, 1
+		case !pickedLoad.lessLoaded(load):
+			ties++
+			if rand.IntN(ties) == 0 {
+				picked, pickedLoad = session.connectorID, load
+			}
+		}
+	}
+	return picked, picked != ""
+}
+
+func (h *Hub) ConnectorLoads() map[string]ConnectorLoad {
+	now := time.Now().UTC()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	loads := make(map[string]ConnectorLoad, len(h.connectorSessions))
+	for connectorID, sessionID := range h.connectorSessions {
+		if session, ok := h.liveSessionLocked(sessionID, now); ok {
+			loads[connectorID] = session.load()
+		}
+	}
+	return loads
+}
+
+func (h *Hub) releasePending(requestID string) {
+	if pending, ok := h.pending.take(requestID); ok {
+		pending.session.finishRequest(pending, false)
+	}
+}
+
+func (s *session) finishRequest(pending pendingRequest, answered bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight > 0 {
+		s.inFlight--
+	}
+	if answered {
+		sample := float64(time.Since(pending.enqueuedAt).Milliseconds())
+		if s.latencyMs == 0 {
+			s.latencyMs = sample
+		} else {
+			s.latencyMs += connectorLatencyWeight * (sample - s.latencyMs)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestHubPrefersLeastLoadedConnector(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", MetricsToken: "scrape"}, nil)
+	for _, connectorID := range []string{"mac-1", "mac-2"} {
+		if _, err := server.connectorStore.Create(Connector{ID: connectorID, TenantID: DefaultTenantID}); err != nil {
+			t.Fatalf("create connector: %v", err)
+		}
+	}
+	hub := server.hub
+	busy, err := hub.RegisterConnectorSession("mac-1", "agent-1")
+	if err != nil {
+		t.Fatalf("register connector session: %v", err)
+	}
+	if _, err := hub.RegisterConnectorSession("mac-2", "agent-2"); err != nil {
+		t.Fatalf("register connector session: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := hub.DispatchProxyRequestToConnector(ctx, "mac-1", "default/app", &protocol.ProxyRequest{Method: http.MethodGet, Path: "/"})
+		done <- err
+	}()
+	pulled, err := hub.PullRequest(context.Background(), busy.SessionID)
+	if err != nil {
+		t.Fatalf("pull request: %v", err)
+	}
+
+	if load := hub.ConnectorLoads()["mac-1"]; load.InFlight != 1 || load.Dispatched != 1 {
+		t.Fatalf("expected one in-flight request on mac-1, got %+v", load)
+	}
+	for range 5 {
+		if picked, ok := hub.PickConnector([]string{"mac-1", "mac-2"}); !ok || picked != "mac-2" {
+			t.Fatalf("expected the idle connector to be picked, got %q", picked)
+		}
+	}
+
+	if err := hub.SubmitProxyResponse(busy.SessionID, &protocol.ProxyResponse{RequestID: pulled.RequestID, TunnelID: pulled.TunnelID, Status: http.StatusOK}); err != nil {
+		t.Fatalf("submit response: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	if load := hub.ConnectorLoads()["mac-1"]; load.InFlight != 0 || load.Dispatched != 1 {
+		t.Fatalf("expected mac-1 to be idle after the response, got %+v", load)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	request.Header.Set("Authorization", "Bearer scrape")
+	recorder := httptest.NewRecorder()
+	server.handlePrometheusMetrics(recorder, request)
+	for _, want := range []string{
+		`proxer_connector_dispatched_total{tenant="default",connector="mac-1"} 1`,
+		`proxer_connector_in_flight{tenant="default",connector="mac-2"} 0`,
+	} {
+		if !strings.Contains(recorder.Body.String(), want) {
+			t.Fatalf("expected %q in metrics output:\n%s", want, recorder.Body.String())
+		}
+	}
+}
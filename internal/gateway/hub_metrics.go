@@ -0,0 +1,250 @@
+package gateway
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const hubShardCount = 32
+
+func hubShard(key string) int {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return int(hash % hubShardCount)
+}
+
+// metricsRegistry holds the per-tunnel counters and latency histograms apart
+// from the session registry, sharded by tunnel so recording a result only
+// locks that tunnel's shard. The gateway-wide and per-tenant histograms share
+// one lock of their own.
+type metricsRegistry struct {
+	shards [hubShardCount]metricsShard
+
+	mu            sync.Mutex
+	latency       LatencyHistogram
+	tenantLatency map[string]*LatencyHistogram
+}
+
+type metricsShard struct {
+	mu      sync.Mutex
+	metrics map[string]*TunnelMetrics
+	latency map[string]*LatencyHistogram
+}
+
+type metricsTotals struct {
+	requests int64
+	errors   int64
+	timeouts int64
+	retries  int64
+}
+
+func newMetricsRegistry() *metricsRegistry {
+	registry := &metricsRegistry{tenantLatency: make(map[string]*LatencyHistogram)}
+	for i := range registry.shards {
+		registry.shards[i].metrics = make(map[string]*TunnelMetrics)
+		registry.shards[i].latency = make(map[string]*LatencyHistogram)
+	}
+	return registry
+}
+
+func (m *metricsRegistry) shard(tunnelID string) *metricsShard {
+	return &m.shards[hubShard(tunnelID)]
+}
+
+func (s *metricsShard) metricLocked(tunnelID string) *TunnelMetrics {
+	metric, ok := s.metrics[tunnelID]
+	if !ok {
+		metric = &TunnelMetrics{TunnelID: tunnelID}
+		s.metrics[tunnelID] = metric
+	}
+	return metric
+}
+
+func (m *metricsRegistry) ensure(tunnelID string) {
+	shard := m.shard(tunnelID)
+	shard.mu.Lock()
+	shard.metricLocked(tunnelID)
+	shard.mu.Unlock()
+}
+
+func (m *metricsRegistry) get(tunnelID string) TunnelMetrics {
+	shard := m.shard(tunnelID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	metric, ok := shard.metrics[tunnelID]
+	if !ok {
+		return TunnelMetrics{TunnelID: tunnelID}
+	}
+	copied := *metric
+	copied.Latency = shard.latency[tunnelID].Percentiles()
+	return copied
+}
+
+func (m *metricsRegistry) recordFailure(tunnelID string, bytesIn int64, status int, errMsg string) time.Time {
+	shard := m.shard(tunnelID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	metric := shard.metricLocked(tunnelID)
+	metric.RequestCount++
+	metric.ErrorCount++
+	if status == http.StatusGatewayTimeout {
+		metric.TimeoutCount++
+	}
+	metric.BytesIn += bytesIn
+	metric.LastStatus = status
+	metric.LastError = errMsg
+	metric.LastSeen = time.Now().UTC()
+	metric.AverageLatencyMs = float64(metric.TotalLatencyMs) / float64(metric.RequestCount)
+	return metric.LastSeen
+}
+
+func (m *metricsRegistry) recordResponse(response *protocol.ProxyResponse) time.Time {
+	shard := m.shard(response.TunnelID)
+	shard.mu.Lock()
+	metric := shard.metricLocked(response.TunnelID)
+	metric.RequestCount++
+	if response.Error != "" || response.Status >= 500 {
+		metric.ErrorCount++
+	}
+	if response.Status == http.StatusGatewayTimeout {
+		metric.TimeoutCount++
+	}
+	metric.RetryCount += int64(response.Retries)
+	metric.BytesIn += response.BytesIn
+	metric.BytesOut += response.BytesOut
+	metric.TotalLatencyMs += response.LatencyMs
+	metric.LastStatus = response.Status
+	metric.LastError = response.Error
+	metric.LastSeen = time.Now().UTC()
+	metric.AverageLatencyMs = float64(metric.TotalLatencyMs) / float64(metric.RequestCount)
+	at := metric.LastSeen
+	if response.LatencyMs > 0 {
+		route, ok := shard.latency[response.TunnelID]
+		if !ok {
+			route = &LatencyHistogram{}
+			shard.latency[response.TunnelID] = route
+		}
+		route.Record(response.LatencyMs)
+	}
+	shard.mu.Unlock()
+
+	if response.LatencyMs > 0 {
+		m.recordAggregateLatency(response.TunnelID, response.LatencyMs)
+	}
+	return at
+}
+
+func (m *metricsRegistry) recordAggregateLatency(tunnelID string, latencyMs int64) {
+	tenantID, _ := ParseTunnelKey(tunnelID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latency.Record(latencyMs)
+	tenant, ok := m.tenantLatency[tenantID]
+	if !ok {
+		tenant = &LatencyHistogram{}
+		m.tenantLatency[tenantID] = tenant
+	}
+	tenant.Record(latencyMs)
+}
+
+func (m *metricsRegistry) recordRetries(tunnelID string, retries int) {
+	shard := m.shard(tunnelID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	shard.metricLocked(tunnelID).RetryCount += int64(retries)
+}
+
+func (m *metricsRegistry) recordWebhookVerification(tunnelID string, verified bool) {
+	shard := m.shard(tunnelID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	metric := shard.metricLocked(tunnelID)
+	if verified {
+		metric.WebhooksVerified++
+	} else {
+		metric.WebhooksRejected++
+	}
+}
+
+func (m *metricsRegistry) reset(tenantID, routeID string, at time.Time) int {
+	tenantID = normalizeIdentifier(tenantID)
+	routeID = normalizeIdentifier(routeID)
+	reset := 0
+	for i := range m.shards {
+		shard := &m.shards[i]
+		shard.mu.Lock()
+		for tunnelID := range shard.metrics {
+			keyTenant, keyRoute := ParseTunnelKey(tunnelID)
+			if keyTenant != tenantID || (routeID != "" && keyRoute != routeID) {
+				continue
+			}
+			shard.metrics[tunnelID] = &TunnelMetrics{TunnelID: tunnelID, ResetAt: at}
+			delete(shard.latency, tunnelID)
+			reset++
+		}
+		shard.mu.Unlock()
+	}
+	if routeID == "" {
+		m.mu.Lock()
+		delete(m.tenantLatency, tenantID)
+		m.mu.Unlock()
+	}
+	return reset
+}
+
+func (m *metricsRegistry) totals() metricsTotals {
+	var totals metricsTotals
+	for i := range m.shards {
+		shard := &m.shards[i]
+		shard.mu.Lock()
+		for _, metric := range shard.metrics {
+			totals.requests += metric.RequestCount
+			totals.errors += metric.ErrorCount
+			totals.timeouts += metric.TimeoutCount
+			totals.retries += metric.RetryCount
+		}
+		shard.mu.Unlock()
+	}
+	return totals
+}
+
+func (m *metricsRegistry) quantile(q float64) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.latency.Quantile(q)
+}
+
+func (m *metricsRegistry) tenantPercentiles(tenantID string) LatencyPercentiles {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tenantLatency[tenantID].Percentiles()
+}
+
+func (m *metricsRegistry) tenantPercentilesAll() map[string]LatencyPercentiles {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]LatencyPercentiles, len(m.tenantLatency))
+	for tenantID, histogram := range m.tenantLatency {
+		out[tenantID] = histogram.Percentiles()
+	}
+	return out
+}
+
+func (m *metricsRegistry) routeHistograms() map[string]LatencyHistogram {
+	out := make(map[string]LatencyHistogram)
+	for i := range m.shards {
+		shard := &m.shards[i]
+		shard.mu.Lock()
+		for tunnelID, histogram := range shard.latency {
+			out[tunnelID] = *histogram
+		}
+		shard.mu.Unlock()
+	}
+	return out
+}
@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const (
+	QueueOverflowReject     = "reject"
+	QueueOverflowWait       = "wait"
+	QueueOverflowShedOldest = "shed_oldest"
+
+	defaultQueueOverflowWaitMs = 500
+	maxQueueOverflowWaitMs     = 10000
+)
+
+var ErrRequestShed = errors.New("request shed from a full agent queue")
+
+type RouteQueueOverflow struct {
+	Policy string `json:"policy"`
+	WaitMs int    `json:"wait_ms,omitempty"`
+}
+
+func normalizeRouteQueueOverflow(input *RouteQueueOverflow) (*RouteQueueOverflow, error) {
+	if input == nil {
+		return nil, nil
+	}
+	policy := RouteQueueOverflow{Policy: strings.ToLower(strings.TrimSpace(input.Policy))}
+	switch policy.Policy {
+	case "", QueueOverflowReject:
+		if input.WaitMs != 0 {
+			return nil, fmt.Errorf("queue_overflow.wait_ms only applies to the wait policy")
+		}
+		return nil, nil
+	case QueueOverflowWait:
+		if input.WaitMs < 0 || input.WaitMs > maxQueueOverflowWaitMs {
+			return nil, fmt.Errorf("queue_overflow.wait_ms must be between 0 and %d", maxQueueOverflowWaitMs)
+		}
+		policy.WaitMs = input.WaitMs
+		if policy.WaitMs == 0 {
+			policy.WaitMs = defaultQueueOverflowWaitMs
+		}
+	case QueueOverflowShedOldest:
+		if input.WaitMs != 0 {
+			return nil, fmt.Errorf("queue_overflow.wait_ms only applies to the wait policy")
+		}
+	default:
+		return nil, fmt.Errorf("queue_overflow.policy must be reject, wait or shed_oldest")
+	}
+	return &policy, nil
+}
+
+type queueOverflowContextKey struct{}
+
+func withQueueOverflow(ctx context.Context, policy *RouteQueueOverflow) context.Context {
+	if policy == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, queueOverflowContextKey{}, *policy)
+}
+
+func queueOverflowFrom(ctx context.Context) RouteQueueOverflow {
+	if policy, ok := ctx.Value(queueOverflowContextKey{}).(RouteQueueOverflow); ok {
+		return policy
+	}
+	return RouteQueueOverflow{Policy: QueueOverflowReject}
+}
+
+type QueueOverflowStats struct {
+	Rejected int64 `json:"rejected"`
+	Waited   int64 `json:"waited"`
+	Shed     int64 `json:"shed"`
+}
+
+type queueStats struct {
+	mu       sync.Mutex
+	wait     LatencyHistogram
+	overflow QueueOverflowStats
+}
+
+func (q *queueStats) recordWait(wait time.Duration) {
+	q.mu.Lock()
+	q.wait.Record(wait.Milliseconds())
+	q.mu.Unlock()
+}
+
+func (q *queueStats) recordOverflow(update func(*QueueOverflowStats)) {
+	q.mu.Lock()
+	update(&q.overflow)
+	q.mu.Unlock()
+}
+
+func (q *queueStats) snapshot() (LatencyHistogram, QueueOverflowStats) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.wait, q.overflow
+}
+
+func (h *Hub) queueProxyRequest(ctx context.Context, queue *sessionQueue, req *protocol.ProxyRequest) bool {
+	now := time.Now()
+	overflow := queueOverflowFrom(ctx)
+	switch overflow.Policy {
+	case QueueOverflowWait:
+		queued, waited := queue.pushWaiting(ctx, req, now, time.Duration(overflow.WaitMs)*time.Millisecond)
+		if waited {
+			h.queueStats.recordOverflow(func(stats *QueueOverflowStats) {
+				if queued {
+					stats.Waited++
+				} else {
+					stats.Rejected++
+				}
+			})
+		}
+		return queued
+	case QueueOverflowShedOldest:
+		shed := queue.pushShedding(req, now)
+		if shed == nil {
+			return true
+		}
+		h.queueStats.recordOverflow(func(stats *QueueOverflowStats) { stats.Shed++ })
+		if pending, ok := h.pending.take(shed.RequestID); ok {
+			pending.session.finishRequest(pending, false)
+			pending.resultCh <- dispatchResult{err: ErrRequestShed}
+		}
+		return true
+	}
+	if !queue.pushAt(req, now) {
+		h.queueStats.recordOverflow(func(stats *QueueOverflowStats) { stats.Rejected++ })
+		return false
+	}
+	return true
+}
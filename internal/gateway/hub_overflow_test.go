@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestHubQueueOverflowPolicies(t *testing.T) {
+	hub := NewHub("token", "http://localhost", 30*time.Second, 1, 0)
+	registered, err := hub.RegisterConnectorSession("laptop", "agent-1")
+	if err != nil {
+		t.Fatalf("register connector session: %v", err)
+	}
+	dispatch := func(policy *RouteQueueOverflow, requestID string) chan error {
+		result := make(chan error, 1)
+		ctx, cancel := context.WithTimeout(withQueueOverflow(context.Background(), policy), 5*time.Second)
+		go func() {
+			defer cancel()
+			_, err := hub.DispatchProxyRequestToConnector(ctx, "laptop", "default/app", &protocol.ProxyRequest{RequestID: requestID, Method: http.MethodGet, Path: "/"})
+			result <- err
+		}()
+		return result
+	}
+	waitQueued := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for hub.Status().QueueDepthTotal != n && time.Now().Before(deadline) {
+			time.Sleep(2 * time.Millisecond)
+		}
+		if depth := hub.Status().QueueDepthTotal; depth != n {
+			t.Fatalf("expected %d queued requests, got %d", n, depth)
+		}
+	}
+
+	first := dispatch(nil, "first")
+	waitQueued(1)
+	if err := <-dispatch(nil, "rejected"); !errors.Is(err, ErrAgentQueueFull) {
+		t.Fatalf("expected the default policy to reject, got %v", err)
+	}
+
+	dispatch(&RouteQueueOverflow{Policy: QueueOverflowShedOldest}, "newest")
+	if err := <-first; !errors.Is(err, ErrRequestShed) {
+		t.Fatalf("expected the oldest request to be shed, got %v", err)
+	}
+	waitQueued(1)
+
+	waited := dispatch(&RouteQueueOverflow{Policy: QueueOverflowWait, WaitMs: 2000}, "waited")
+	time.Sleep(20 * time.Millisecond)
+	for _, want := range []string{"newest", "waited"} {
+		request, err := hub.PullRequest(context.Background(), registered.SessionID)
+		if err != nil || request.RequestID != want {
+			t.Fatalf("expected %s to be pulled, got %+v %v", want, request, err)
+		}
+	}
+	select {
+	case err := <-waited:
+		t.Fatalf("expected the waiting request to be queued once room was made, got %v", err)
+	default:
+	}
+
+	dispatch(nil, "filler")
+	waitQueued(1)
+	if err := <-dispatch(&RouteQueueOverflow{Policy: QueueOverflowWait, WaitMs: 10}, "late"); !errors.Is(err, ErrAgentQueueFull) {
+		t.Fatalf("expected a wait past wait_ms to be rejected, got %v", err)
+	}
+
+	status := hub.Status()
+	if status.QueueOverflow.Rejected != 2 || status.QueueOverflow.Shed != 1 || status.QueueOverflow.Waited != 1 {
+		t.Fatalf("unexpected overflow counts %+v", status.QueueOverflow)
+	}
+	if status.QueueWait.Count != 2 {
+		t.Fatalf("expected two recorded queue waits, got %+v", status.QueueWait)
+	}
+}
+
+func TestNormalizeRouteQueueOverflow(t *testing.T) {
+	policy, err := normalizeRouteQueueOverflow(&RouteQueueOverflow{Policy: "WAIT"})
+	if err != nil || policy.Policy != QueueOverflowWait || policy.WaitMs != defaultQueueOverflowWaitMs {
+		t.Fatalf("unexpected wait policy %+v %v", policy, err)
+	}
+	if policy, err := normalizeRouteQueueOverflow(&RouteQueueOverflow{Policy: "reject"}); err != nil || policy != nil {
+		t.Fatalf("expected reject to normalize to the default, got %+v %v", policy, err)
+	}
+	for _, input := range []RouteQueueOverflow{{Policy: "drop"}, {Policy: QueueOverflowWait, WaitMs: maxQueueOverflowWaitMs + 1}, {Policy: QueueOverflowShedOldest, WaitMs: 10}} {
+		if _, err := normalizeRouteQueueOverflow(&input); err == nil {
+			t.Fatalf("expected %+v to be refused", input)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+type pendingRequest struct {
+	requestID  string
+	session    *session
+	tunnelID   string
+	deadline   time.Time
+	enqueuedAt time.Time
+	resultCh   chan dispatchResult
+	cancel     *cancelSignal
+}
+
+// pendingTable holds dispatched requests awaiting an agent response, sharded
+// by request ID. Whoever takes a request out owns its result channel, so each
+// request gets exactly one result.
+type pendingTable struct {
+	shards [hubShardCount]pendingShard
+	count  atomic.Int64
+}
+
+type pendingShard struct {
+	mu       sync.Mutex
+	requests map[string]pendingRequest
+}
+
+func newPendingTable() *pendingTable {
+	table := &pendingTable{}
+	for i := range table.shards {
+		table.shards[i].requests = make(map[string]pendingRequest)
+	}
+	return table
+}
+
+func (t *pendingTable) shard(requestID string) *pendingShard {
+	return &t.shards[hubShard(requestID)]
+}
+
+func (t *pendingTable) Len() int {
+	return int(t.count.Load())
+}
+
+func (t *pendingTable) add(request pendingRequest, limit int) bool {
+	if t.count.Add(1) > int64(limit) {
+		t.count.Add(-1)
+		return false
+	}
+	shard := t.shard(request.requestID)
+	shard.mu.Lock()
+	shard.requests[request.requestID] = request
+	shard.mu.Unlock()
+	return true
+}
+
+func (t *pendingTable) get(requestID string) (pendingRequest, bool) {
+	shard := t.shard(requestID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	request, ok := shard.requests[requestID]
+	return request, ok
+}
+
+func (t *pendingTable) take(requestID string) (pendingRequest, bool) {
+	shard := t.shard(requestID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	request, ok := shard.requests[requestID]
+	if ok {
+		delete(shard.requests, requestID)
+		t.count.Add(-1)
+	}
+	return request, ok
+}
+
+func (t *pendingTable) claim(s *session, response *protocol.ProxyResponse) (pendingRequest, error) {
+	shard := t.shard(response.RequestID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	request, ok := shard.requests[response.RequestID]
+	if !ok {
+		return pendingRequest{}, ErrUnknownPendingRequest
+	}
+	if request.session != s {
+		return pendingRequest{}, ErrResponseSessionMismatch
+	}
+	if strings.TrimSpace(response.TunnelID) != request.tunnelID {
+		return pendingRequest{}, ErrResponseTunnelMismatch
+	}
+	delete(shard.requests, response.RequestID)
+	t.count.Add(-1)
+	return request, nil
+}
+
+func (t *pendingTable) takeMatching(match func(pendingRequest) bool) []pendingRequest {
+	var taken []pendingRequest
+	for i := range t.shards {
+		shard := &t.shards[i]
+		shard.mu.Lock()
+		for requestID, request := range shard.requests {
+			if match != nil && !match(request) {
+				continue
+			}
+			delete(shard.requests, requestID)
+			t.count.Add(-1)
+			taken = append(taken, request)
+		}
+		shard.mu.Unlock()
+	}
+	return taken
+}
@@ -0,0 +1,181 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const (
+	PriorityHealth      = "health"
+	PriorityInteractive = "interactive"
+	PriorityBulk        = "bulk"
+
+	bulkBodyThreshold = 256 << 10
+)
+
+var priorityClasses = []string{PriorityHealth, PriorityInteractive, PriorityBulk}
+
+// priorityDrainOrder is one weighted round: health 4, interactive 2, bulk 1.
+// Bulk still gets a slot every round so large uploads cannot starve.
+var priorityDrainOrder = []int{0, 1, 0, 2, 0, 1, 0}
+
+var healthCheckPaths = []string{"/health", "/healthz", "/livez", "/readyz", "/ready", "/ping"}
+
+func classifyProxyRequest(req *protocol.ProxyRequest) int {
+	method := strings.ToUpper(req.Method)
+	if method == http.MethodOptions || method == http.MethodHead {
+		return 0
+	}
+	if len(req.Body) >= bulkBodyThreshold {
+		return 2
+	}
+	if method == http.MethodGet {
+		path := strings.ToLower(strings.TrimRight(req.Path, "/"))
+		for _, healthPath := range healthCheckPaths {
+			if path == healthPath || strings.HasSuffix(path, healthPath) {
+				return 0
+			}
+		}
+	}
+	return 1
+}
+
+// sessionQueue holds one FIFO per priority class. ready carries one token per
+// queued request so pullers can block without holding the lock; freed is
+// closed, and replaced, whenever a request leaves the queue.
+type sessionQueue struct {
+	mu       sync.Mutex
+	classes  [3][]queuedRequest
+	cursor   int
+	capacity int
+	ready    chan struct{}
+	freed    chan struct{}
+}
+
+type queuedRequest struct {
+	req      *protocol.ProxyRequest
+	queuedAt time.Time
+}
+
+func newSessionQueue(capacity int) *sessionQueue {
+	return &sessionQueue{
+		capacity: capacity,
+		ready:    make(chan struct{}, capacity),
+		freed:    make(chan struct{}),
+	}
+}
+
+func (q *sessionQueue) push(req *protocol.ProxyRequest) bool {
+	return q.pushAt(req, time.Now())
+}
+
+func (q *sessionQueue) pushAt(req *protocol.ProxyRequest, queuedAt time.Time) bool {
+	q.mu.Lock()
+	if q.lenLocked() >= q.capacity {
+		q.mu.Unlock()
+		return false
+	}
+	class := classifyProxyRequest(req)
+	q.classes[class] = append(q.classes[class], queuedRequest{req: req, queuedAt: queuedAt})
+	q.mu.Unlock()
+	q.ready <- struct{}{}
+	return true
+}
+
+func (q *sessionQueue) pushWaiting(ctx context.Context, req *protocol.ProxyRequest, queuedAt time.Time, wait time.Duration) (queued, waited bool) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		q.mu.Lock()
+		freed := q.freed
+		q.mu.Unlock()
+		if q.pushAt(req, queuedAt) {
+			return true, waited
+		}
+		waited = true
+		select {
+		case <-freed:
+		case <-timer.C:
+			return false, true
+		case <-ctx.Done():
+			return false, true
+		}
+	}
+}
+
+func (q *sessionQueue) pushShedding(req *protocol.ProxyRequest, queuedAt time.Time) *protocol.ProxyRequest {
+	q.mu.Lock()
+	var shed *protocol.ProxyRequest
+	if q.lenLocked() >= q.capacity {
+		oldest := -1
+		for class, items := range q.classes {
+			if len(items) > 0 && (oldest < 0 || items[0].queuedAt.Before(q.classes[oldest][0].queuedAt)) {
+				oldest = class
+			}
+		}
+		if oldest >= 0 {
+			shed = q.classes[oldest][0].req
+			q.classes[oldest][0] = queuedRequest{}
+			q.classes[oldest] = q.classes[oldest][1:]
+		}
+	}
+	class := classifyProxyRequest(req)
+	q.classes[class] = append(q.classes[class], queuedRequest{req: req, queuedAt: queuedAt})
+	q.mu.Unlock()
+	if shed == nil {
+		// The shed request's ready token now stands for req.
+		q.ready <- struct{}{}
+	}
+	return shed
+}
+
+func (q *sessionQueue) pop() *protocol.ProxyRequest {
+	req, _ := q.popWithWait()
+	return req
+}
+
+// popWithWait takes the next request by weighted round robin and reports how
+// long it was queued. Callers must hold a ready token.
+func (q *sessionQueue) popWithWait() (*protocol.ProxyRequest, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := 0; i < len(priorityDrainOrder); i++ {
+		class := priorityDrainOrder[(q.cursor+i)%len(priorityDrainOrder)]
+		if len(q.classes[class]) == 0 {
+			continue
+		}
+		q.cursor = (q.cursor + i + 1) % len(priorityDrainOrder)
+		item := q.classes[class][0]
+		q.classes[class][0] = queuedRequest{}
+		q.classes[class] = q.classes[class][1:]
+		close(q.freed)
+		q.freed = make(chan struct{})
+		return item.req, time.Since(item.queuedAt)
+	}
+	return nil, 0
+}
+
+func (q *sessionQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.lenLocked()
+}
+
+func (q *sessionQueue) lenLocked() int {
+	return len(q.classes[0]) + len(q.classes[1]) + len(q.classes[2])
+}
+
+func (q *sessionQueue) depthByClass() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	depths := make(map[string]int, len(priorityClasses))
+	for i, name := range priorityClasses {
+		depths[name] = len(q.classes[i])
+	}
+	return depths
+}
@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestSessionQueueDrainsByWeightedPriority(t *testing.T) {
+	queue := newSessionQueue(16)
+	upload := make([]byte, bulkBodyThreshold)
+	for i := 0; i < 3; i++ {
+		queue.push(&protocol.ProxyRequest{RequestID: "bulk", Method: http.MethodPost, Path: "/upload", Body: upload})
+	}
+	queue.push(&protocol.ProxyRequest{RequestID: "page", Method: http.MethodGet, Path: "/"})
+	queue.push(&protocol.ProxyRequest{RequestID: "health", Method: http.MethodGet, Path: "/api/healthz"})
+	queue.push(&protocol.ProxyRequest{RequestID: "preflight", Method: http.MethodOptions, Path: "/api/items"})
+
+	depths := queue.depthByClass()
+	if depths[PriorityHealth] != 2 || depths[PriorityInteractive] != 1 || depths[PriorityBulk] != 3 {
+		t.Fatalf("unexpected class depths %v", depths)
+	}
+
+	order := make([]string, 0, 6)
+	for range 6 {
+		<-queue.ready
+		order = append(order, queue.pop().RequestID)
+	}
+	want := []string{"health", "page", "preflight", "bulk", "bulk", "bulk"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("unexpected drain order %v, want %v", order, want)
+		}
+	}
+}
+
+func TestSessionQueueRejectsWhenFull(t *testing.T) {
+	queue := newSessionQueue(1)
+	if !queue.push(&protocol.ProxyRequest{Method: http.MethodGet}) {
+		t.Fatalf("expected first push to succeed")
+	}
+	if queue.push(&protocol.ProxyRequest{Method: http.MethodOptions}) {
+		t.Fatalf("expected push beyond capacity to fail")
+	}
+}
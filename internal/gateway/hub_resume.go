@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+var ErrSessionNotResumable = errors.New("session cannot be resumed; register again")
+
+var resumableSessionID = regexp.MustCompile(`^sess-[0-9]{1,20}-[0-9]{1,20}$`)
+
+// ResumeSession lets an agent keep its session ID across a gateway restart.
+// A session the hub still knows is returned as is if it belongs to the same
+// connector or legacy agent; an unknown one is registered again under the
+// same ID. The caller must have checked the connector credentials; legacy
+// agents are checked against the agent token as in Register.
+func (h *Hub) ResumeSession(sessionID string, message *protocol.RegisterRequest) (*protocol.RegisterResponse, error) {
+	if message == nil {
+		return nil, errors.New("missing registration payload")
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	if !resumableSessionID.MatchString(sessionID) {
+		return nil, ErrSessionNotResumable
+	}
+	connectorID := strings.TrimSpace(message.ConnectorID)
+	if connectorID == "" && strings.TrimSpace(message.Token) != h.agentToken {
+		return nil, errors.New("agent token mismatch")
+	}
+
+	capabilities := negotiateCapabilities(message)
+	now := time.Now().UTC()
+	h.mu.RLock()
+	if existing, ok := h.liveSessionLocked(sessionID, now); ok {
+		if existing.connectorID != connectorID || (connectorID == "" && existing.agentID != legacyAgentID(message.AgentID)) {
+			h.mu.RUnlock()
+			return nil, ErrSessionNotResumable
+		}
+		// An agent restarted with other capabilities, such as after an
+		// upgrade, gets the session registered again under the same ID.
+		if existing.capabilities.equal(capabilities) {
+			defer h.mu.RUnlock()
+			existing.touch(now)
+			return h.resumedResponseLocked(existing), nil
+		}
+	}
+	h.mu.RUnlock()
+
+	var (
+		response *protocol.RegisterResponse
+		err      error
+	)
+	if connectorID != "" {
+		response, err = h.registerConnectorSession(connectorID, message.AgentID, sessionID, capabilities)
+	} else {
+		response, err = h.register(message, sessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	response.Message = "resumed session"
+	return response, nil
+}
+
+func (h *Hub) resumedResponseLocked(s *session) *protocol.RegisterResponse {
+	response := &protocol.RegisterResponse{
+		Accepted:      true,
+		Message:       "resumed session",
+		SessionID:     s.id,
+		PublicBaseURL: h.publicBaseURL,
+	}
+	s.capabilities.annotate(response)
+	if s.connectorID != "" {
+		return response
+	}
+	for id := range s.tunnels {
+		response.Tunnels = append(response.Tunnels, protocol.TunnelRoute{
+			ID:        id,
+			PublicURL: fmt.Sprintf("%s/t/%s/", h.publicBaseURL, id),
+		})
+	}
+	sort.Slice(response.Tunnels, func(i, j int) bool {
+		return response.Tunnels[i].ID < response.Tunnels[j].ID
+	})
+	return response
+}
+
+func legacyAgentID(agentID string) string {
+	agentID = strings.TrimSpace(agentID)
+	if agentID == "" {
+		return "anonymous-agent"
+	}
+	return agentID
+}
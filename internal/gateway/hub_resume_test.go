@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestHubResumeSessionKeepsIDOrAsksToRegister(t *testing.T) {
+	before := NewHub("token", "http://localhost", time.Second, 0, 0)
+	registered, err := before.RegisterConnectorSession("laptop", "agent-1")
+	if err != nil {
+		t.Fatalf("register connector session: %v", err)
+	}
+
+	// A restarted hub knows nothing of the session.
+	hub := NewHub("token", "http://localhost", time.Second, 0, 0)
+	resumed, err := hub.ResumeSession(registered.SessionID, &protocol.RegisterRequest{ConnectorID: "laptop", AgentID: "agent-1"})
+	if err != nil || resumed.SessionID != registered.SessionID {
+		t.Fatalf("expected session %s to be resumed, got %+v %v", registered.SessionID, resumed, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := hub.PullRequest(ctx, registered.SessionID); errors.Is(err, ErrUnknownSession) {
+		t.Fatalf("expected the resumed session to be usable")
+	}
+
+	again, err := hub.ResumeSession(registered.SessionID, &protocol.RegisterRequest{ConnectorID: "laptop", AgentID: "agent-1"})
+	if err != nil || again.SessionID != registered.SessionID {
+		t.Fatalf("expected resuming a live session to keep it, got %+v %v", again, err)
+	}
+	if _, err := hub.ResumeSession(registered.SessionID, &protocol.RegisterRequest{ConnectorID: "other"}); !errors.Is(err, ErrSessionNotResumable) {
+		t.Fatalf("expected another connector's session not to be resumable, got %v", err)
+	}
+	if _, err := hub.ResumeSession("made-up", &protocol.RegisterRequest{ConnectorID: "laptop"}); !errors.Is(err, ErrSessionNotResumable) {
+		t.Fatalf("expected a malformed session ID not to be resumable, got %v", err)
+	}
+	if _, err := hub.ResumeSession("sess-1-1", &protocol.RegisterRequest{Token: "wrong", Tunnels: []protocol.TunnelConfig{{ID: "a", Target: "http://127.0.0.1:1"}}}); err == nil || errors.Is(err, ErrSessionNotResumable) {
+		t.Fatalf("expected a legacy agent with a wrong token to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package gateway
+
+import "errors"
+
+// ErrGatewayShuttingDown fails requests the hub can no longer deliver because
+// the gateway is stopping. Pending requests are not persisted: the callers'
+// connections end with the process, so nobody could receive their responses
+// after a restart.
+var ErrGatewayShuttingDown = errors.New("gateway is shutting down")
+
+// Close fails every pending request with ErrGatewayShuttingDown, ends
+// tunnel streams and wakes agents waiting in PullRequest, so callers and
+// agents learn about a shutdown at once instead of through timeouts. Later
+// dispatches and pulls fail the same way. It returns the number of pending
+// requests it failed.
+func (h *Hub) Close() int {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return 0
+	}
+	h.closed = true
+	close(h.closing)
+
+	failed := h.pending.takeMatching(nil)
+	h.mu.Unlock()
+	for _, pending := range failed {
+		pending.session.finishRequest(pending, false)
+		pending.resultCh <- dispatchResult{err: ErrGatewayShuttingDown}
+	}
+
+	h.streamsMu.Lock()
+	streams := h.streams
+	h.streams = make(map[string]*tunnelStream)
+	h.streamsMu.Unlock()
+	for _, stream := range streams {
+		stream.close()
+	}
+	return len(failed)
+}
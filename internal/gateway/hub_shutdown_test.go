@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestHubCloseFailsPendingRequestsAndWakesAgents(t *testing.T) {
+	hub := NewHub("token", "http://localhost", 30*time.Second, 0, 0)
+	registered, err := hub.RegisterConnectorSession("laptop", "agent-1")
+	if err != nil {
+		t.Fatalf("register connector session: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	dispatchErr := make(chan error, 1)
+	go func() {
+		_, err := hub.DispatchProxyRequestToConnector(ctx, "laptop", "default/app", &protocol.ProxyRequest{Method: http.MethodGet, Path: "/"})
+		dispatchErr <- err
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Status().PendingRequests == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	pullErr := make(chan error, 1)
+	go func() {
+		// Drain the queued request, then wait for the next one.
+		_, _ = hub.PullRequest(context.Background(), registered.SessionID)
+		_, err := hub.PullRequest(context.Background(), registered.SessionID)
+		pullErr <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	if failed := hub.Close(); failed != 1 {
+		t.Fatalf("expected Close to fail one pending request, failed %d", failed)
+	}
+	for name, ch := range map[string]chan error{"dispatch": dispatchErr, "pull": pullErr} {
+		select {
+		case err := <-ch:
+			if !errors.Is(err, ErrGatewayShuttingDown) {
+				t.Fatalf("expected %s to fail with ErrGatewayShuttingDown, got %v", name, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s was not woken by Close", name)
+		}
+	}
+	if _, err := hub.DispatchProxyRequestToConnector(ctx, "laptop", "default/app", &protocol.ProxyRequest{Method: http.MethodGet}); !errors.Is(err, ErrGatewayShuttingDown) {
+		t.Fatalf("expected dispatch after Close to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+var ErrTunnelInUse = errors.New("tunnel is already connected")
+
+// registerSSHSession opens a session for an SSH client. It starts without
+// tunnels; each remote forward the client asks for adds one. SSH clients are
+// served in the gateway, which supports no streams, uploads or retries.
+func (h *Hub) registerSSHSession(agentID string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeStaleLocked(time.Now().UTC())
+
+	sessionID := h.nextSessionID()
+	h.sessions[sessionID] = &session{
+		id:           sessionID,
+		agentID:      agentID,
+		tunnels:      make(map[string]protocol.TunnelConfig),
+		capabilities: agentCapabilities{version: protocol.ProtocolVersion, agentVersion: "ssh"},
+		queue:        newSessionQueue(h.maxPendingPerSession),
+		lastSeen:     time.Now().UTC(),
+	}
+	return sessionID
+}
+
+// addSessionTunnel routes tunnel.ID to a live session. Unlike agent
+// registration it does not take the tunnel over from another live session.
+func (h *Hub) addSessionTunnel(sessionID string, tunnel protocol.TunnelConfig) error {
+	now := time.Now().UTC()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.liveSessionLocked(sessionID, now)
+	if !ok {
+		return ErrUnknownSession
+	}
+	if owner, ok := h.tunnelSessions[tunnel.ID]; ok && owner != sessionID {
+		if _, live := h.liveSessionLocked(owner, now); live {
+			return ErrTunnelInUse
+		}
+		h.removeTunnelFromSessionLocked(owner, tunnel.ID)
+	}
+	h.tunnelSessions[tunnel.ID] = sessionID
+	h.configs[tunnel.ID] = tunnel
+	s.tunnels[tunnel.ID] = tunnel
+	h.metrics.ensure(tunnel.ID)
+	return nil
+}
+
+func (h *Hub) removeSessionTunnel(sessionID, tunnelID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeTunnelFromSessionLocked(sessionID, strings.TrimSpace(tunnelID))
+}
+
+func (h *Hub) unregisterSession(sessionID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeSessionLocked(sessionID)
+}
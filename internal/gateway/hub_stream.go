@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/szaher/try/proxer/internal/httpx"
+)
+
+var (
+	ErrUnknownStream         = errors.New("unknown stream")
+	ErrStreamAlreadyAttached = errors.New("stream is already attached")
+)
+
+type tunnelStream struct {
+	connectorID string
+	conn        net.Conn
+	bandwidth   []*httpx.BandwidthLimiter
+
+	downlink   atomic.Bool
+	uplink     atomic.Bool
+	attached   chan struct{}
+	attachOnce sync.Once
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (t *tunnelStream) close() {
+	t.closeOnce.Do(func() {
+		_ = t.conn.Close()
+		close(t.done)
+	})
+}
+
+func (h *Hub) openStream(requestID, connectorID string, conn net.Conn, bandwidth ...*httpx.BandwidthLimiter) *tunnelStream {
+	stream := &tunnelStream{
+		connectorID: connectorID,
+		conn:        conn,
+		bandwidth:   bandwidth,
+		attached:    make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	h.streamsMu.Lock()
+	h.streams[requestID] = stream
+	h.streamsMu.Unlock()
+	return stream
+}
+
+func (h *Hub) closeStream(requestID string) {
+	h.streamsMu.Lock()
+	stream, ok := h.streams[requestID]
+	delete(h.streams, requestID)
+	h.streamsMu.Unlock()
+	if ok {
+		stream.close()
+	}
+}
+
+func (h *Hub) attachStream(sessionID, requestID string, uplink bool) (*tunnelStream, error) {
+	h.streamsMu.Lock()
+	stream, ok := h.streams[requestID]
+	h.streamsMu.Unlock()
+	h.mu.RLock()
+	_, sessionOK := h.sessions[sessionID]
+	owner := ""
+	if ok {
+		owner = h.connectorSessions[stream.connectorID]
+	}
+	h.mu.RUnlock()
+	if !sessionOK {
+		return nil, ErrUnknownSession
+	}
+	if !ok {
+		return nil, ErrUnknownStream
+	}
+	if owner != sessionID {
+		return nil, ErrResponseSessionMismatch
+	}
+
+	half := &stream.downlink
+	if uplink {
+		half = &stream.uplink
+	}
+	if half.Swap(true) {
+		return nil, ErrStreamAlreadyAttached
+	}
+	if stream.downlink.Load() && stream.uplink.Load() {
+		stream.attachOnce.Do(func() { close(stream.attached) })
+	}
+	return stream, nil
+}
@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestHubSweepsStaleSessionsAndFailsTheirRequests(t *testing.T) {
+	hub := NewHub("token", "http://localhost:8080", 5*time.Second, 0, 0)
+	defer hub.Close()
+	registration, err := hub.RegisterConnectorSession("edge", "agent-1")
+	if err != nil {
+		t.Fatalf("register connector session: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := hub.DispatchProxyRequestToConnector(context.Background(), "edge", "default/app", &protocol.ProxyRequest{Method: http.MethodGet, Path: "/"})
+		done <- err
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.pending.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if hub.pending.Len() != 1 {
+		t.Fatalf("expected one pending request")
+	}
+
+	session := hub.sessions[registration.SessionID]
+	session.touch(time.Now().UTC().Add(-2 * hub.sessionTTL))
+	if hub.IsConnectorConnected("edge") || hub.Status().ActiveSessions != 0 {
+		t.Fatalf("expected a stale session to be treated as gone before the sweep")
+	}
+	if err := hub.Heartbeat(registration.SessionID, nil); !errors.Is(err, ErrUnknownSession) {
+		t.Fatalf("expected the stale session to be unknown, got %v", err)
+	}
+
+	if removed := hub.removeStaleSessions(time.Now().UTC()); removed != 1 {
+		t.Fatalf("expected the sweep to drop one session, got %d", removed)
+	}
+	if err := <-done; !errors.Is(err, ErrUnknownSession) {
+		t.Fatalf("expected the pending request to fail, got %v", err)
+	}
+	if hub.pending.Len() != 0 || len(hub.sessions) != 0 {
+		t.Fatalf("expected the session and its requests to be gone")
+	}
+	if removed := hub.removeStaleSessions(time.Now().UTC()); removed != 0 {
+		t.Fatalf("expected nothing left to sweep, got %d", removed)
+	}
+}
+
+func TestPendingTableEnforcesLimitAndSingleClaim(t *testing.T) {
+	table := newPendingTable()
+	owner, other := &session{id: "a"}, &session{id: "b"}
+	if !table.add(pendingRequest{requestID: "r1", session: owner, tunnelID: "t"}, 1) {
+		t.Fatalf("expected the first request to fit")
+	}
+	if table.add(pendingRequest{requestID: "r2", session: owner, tunnelID: "t"}, 1) || table.Len() != 1 {
+		t.Fatalf("expected the limit to reject a second request")
+	}
+	if _, err := table.claim(other, &protocol.ProxyResponse{RequestID: "r1", TunnelID: "t"}); !errors.Is(err, ErrResponseSessionMismatch) {
+		t.Fatalf("expected another session's claim to fail, got %v", err)
+	}
+	if _, err := table.claim(owner, &protocol.ProxyResponse{RequestID: "r1", TunnelID: "x"}); !errors.Is(err, ErrResponseTunnelMismatch) {
+		t.Fatalf("expected a claim for another tunnel to fail, got %v", err)
+	}
+	if _, err := table.claim(owner, &protocol.ProxyResponse{RequestID: "r1", TunnelID: "t"}); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if _, err := table.claim(owner, &protocol.ProxyResponse{RequestID: "r1", TunnelID: "t"}); !errors.Is(err, ErrUnknownPendingRequest) || table.Len() != 0 {
+		t.Fatalf("expected a second claim to miss, got %v", err)
+	}
+}
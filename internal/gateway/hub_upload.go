@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrUnknownUpload         = errors.New("unknown upload")
+	ErrUploadAlreadyAttached = errors.New("upload is already attached")
+)
+
+// tunnelUpload is the body of a public request relayed to the agent while
+// the caller is still sending it. The agent attaches once and reads it
+// through /api/agent/upload; reads fail once the public request has ended.
+type tunnelUpload struct {
+	body     io.Reader
+	size     int64
+	attached atomic.Bool
+	closed   atomic.Bool
+	read     atomic.Int64
+	stop     func()
+
+	// readMu is held around body reads so that close can wait for one in
+	// flight: the body must not be read once the public handler returned.
+	readMu sync.Mutex
+
+	mu  sync.Mutex
+	err error
+}
+
+func (u *tunnelUpload) Read(p []byte) (int, error) {
+	u.readMu.Lock()
+	defer u.readMu.Unlock()
+	if u.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
+	n, err := u.body.Read(p)
+	u.read.Add(int64(n))
+	if err != nil && !errors.Is(err, io.EOF) {
+		u.mu.Lock()
+		if u.err == nil {
+			u.err = err
+		}
+		u.mu.Unlock()
+	}
+	return n, err
+}
+
+func (u *tunnelUpload) close() {
+	if u.closed.Swap(true) {
+		return
+	}
+	if u.stop != nil {
+		u.stop()
+	}
+	u.readMu.Lock()
+	u.readMu.Unlock()
+}
+
+func (u *tunnelUpload) exceeded() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return errors.Is(u.err, errBodyTooLarge)
+}
+
+func (h *Hub) openUpload(requestID string, upload *tunnelUpload) {
+	h.uploadsMu.Lock()
+	h.uploads[requestID] = upload
+	h.uploadsMu.Unlock()
+}
+
+func (h *Hub) closeUpload(requestID string) {
+	h.uploadsMu.Lock()
+	upload, ok := h.uploads[requestID]
+	delete(h.uploads, requestID)
+	h.uploadsMu.Unlock()
+	if ok {
+		upload.close()
+	}
+}
+
+func (h *Hub) attachUpload(sessionID, requestID string) (*tunnelUpload, error) {
+	s, ok := h.liveSession(sessionID)
+	if !ok {
+		return nil, ErrUnknownSession
+	}
+	h.uploadsMu.Lock()
+	upload, ok := h.uploads[requestID]
+	h.uploadsMu.Unlock()
+	if !ok {
+		return nil, ErrUnknownUpload
+	}
+	if pending, ok := h.pending.get(requestID); !ok || pending.session != s {
+		return nil, ErrResponseSessionMismatch
+	}
+	if upload.attached.Swap(true) {
+		return nil, ErrUploadAlreadyAttached
+	}
+	return upload, nil
+}
@@ -0,0 +1,172 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/httpx"
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	maxIdempotencyKeyLength   = 255
+	defaultIdempotencyTTLSecs = 24 * 60 * 60
+	maxIdempotencyTTLSecs     = 7 * 24 * 60 * 60
+	maxIdempotencyEntries     = 10000
+	maxIdempotencyBodyBytes   = 1 << 20
+)
+
+var (
+	errIdempotencyInFlight = errors.New("a request with this Idempotency-Key is still in progress")
+	errIdempotencyMismatch = errors.New("Idempotency-Key was already used for a different request")
+)
+
+// RouteIdempotency makes a route honor the Idempotency-Key header on POST and
+// PATCH requests: the first response for a key is kept for TTLSeconds and
+// replayed to duplicates instead of reaching the upstream again. Upstream
+// 5xx responses and gateway errors are not kept, so clients can retry them.
+type RouteIdempotency struct {
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+}
+
+func normalizeRouteIdempotency(input *RouteIdempotency) (*RouteIdempotency, error) {
+	if input == nil {
+		return nil, nil
+	}
+	if input.TTLSeconds < 0 || input.TTLSeconds > maxIdempotencyTTLSecs {
+		return nil, fmt.Errorf("idempotency.ttl_seconds must be between 0 and %d", maxIdempotencyTTLSecs)
+	}
+	policy := *input
+	if policy.TTLSeconds == 0 {
+		policy.TTLSeconds = defaultIdempotencyTTLSecs
+	}
+	return &policy, nil
+}
+
+func (p *RouteIdempotency) appliesTo(method string) bool {
+	return p != nil && (method == http.MethodPost || method == http.MethodPatch)
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	// response is nil while the first request is in flight.
+	response  *protocol.ProxyResponse
+	expiresAt time.Time
+}
+
+type IdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+func NewIdempotencyStore() *IdempotencyStore {
+	return &IdempotencyStore{entries: make(map[string]*idempotencyEntry)}
+}
+
+func (s *IdempotencyStore) begin(key, fingerprint string, ttl time.Duration, now time.Time) (*protocol.ProxyResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expiresAt) {
+		switch {
+		case entry.fingerprint != fingerprint:
+			return nil, errIdempotencyMismatch
+		case entry.response == nil:
+			return nil, errIdempotencyInFlight
+		default:
+			return entry.response, nil
+		}
+	}
+	if len(s.entries) >= maxIdempotencyEntries {
+		s.evictLocked(now)
+	}
+	s.entries[key] = &idempotencyEntry{fingerprint: fingerprint, expiresAt: now.Add(ttl)}
+	return nil, nil
+}
+
+func (s *IdempotencyStore) complete(key string, response *protocol.ProxyResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok || entry.response != nil {
+		return
+	}
+	if response.Status >= http.StatusInternalServerError || len(response.Body) > maxIdempotencyBodyBytes {
+		delete(s.entries, key)
+		return
+	}
+	kept := *response
+	kept.Headers = httpx.CloneMapHeader(response.Headers)
+	kept.Trailers = httpx.CloneMapHeader(response.Trailers)
+	entry.response = &kept
+}
+
+func (s *IdempotencyStore) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.entries[key]; ok && entry.response == nil {
+		delete(s.entries, key)
+	}
+}
+
+func (s *IdempotencyStore) evictLocked(now time.Time) {
+	oldestKey := ""
+	var oldest time.Time
+	for key, entry := range s.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(s.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.expiresAt.Before(oldest) {
+			oldestKey, oldest = key, entry.expiresAt
+		}
+	}
+	if len(s.entries) >= maxIdempotencyEntries {
+		delete(s.entries, oldestKey)
+	}
+}
+
+func idempotencyFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (s *Server) beginIdempotentRequest(w http.ResponseWriter, r *http.Request, rule Rule, requestID string, body []byte) (string, bool) {
+	key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	if key == "" || !rule.Idempotency.appliesTo(r.Method) {
+		return "", true
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		http.Error(w, fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength), http.StatusBadRequest)
+		return "", false
+	}
+
+	storeKey := MakeTunnelKey(rule.TenantID, rule.ID) + "\n" + key
+	ttl := time.Duration(rule.Idempotency.TTLSeconds) * time.Second
+	kept, err := s.idempotency.begin(storeKey, idempotencyFingerprint(r, body), ttl, time.Now().UTC())
+	switch {
+	case errors.Is(err, errIdempotencyInFlight):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return "", false
+	case errors.Is(err, errIdempotencyMismatch):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return "", false
+	case kept != nil:
+		replay := *kept
+		replay.RequestID = requestID
+		w.Header().Set("Idempotent-Replayed", "true")
+		rule.corsPolicy().applyToResponse(w.Header(), replay.Headers, r.Header.Get("Origin"))
+		s.writeProxyResponse(w, rule.TenantID, rule.ID, MakeTunnelKey(rule.TenantID, rule.ID), &replay)
+		return "", false
+	}
+	return storeKey, true
+}
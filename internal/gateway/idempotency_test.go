@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKeyReplaysFirstResponse(t *testing.T) {
+	var orders atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, "order %d", orders.Add(1))
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "shop", Target: upstream.URL, Idempotency: &RouteIdempotency{}}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	post := func(path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/t/shop"+path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		recorder := httptest.NewRecorder()
+		server.handleProxy(recorder, req)
+		return recorder
+	}
+
+	first := post("/orders", "k1", `{"qty":1}`)
+	replay := post("/orders", "k1", `{"qty":1}`)
+	if first.Code != http.StatusCreated || replay.Code != http.StatusCreated || replay.Body.String() != "order 1" || replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected the duplicate to replay order 1, got %d %q", replay.Code, replay.Body.String())
+	}
+	if mismatch := post("/orders", "k1", `{"qty":2}`); mismatch.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected a reused key with another body to be rejected, got %d", mismatch.Code)
+	}
+	if other := post("/orders", "", `{"qty":1}`); other.Body.String() != "order 2" || orders.Load() != 2 {
+		t.Fatalf("expected requests without a key to reach the upstream, got %q", other.Body.String())
+	}
+
+	post("/fail", "k2", "")
+	if retried := post("/fail", "k2", ""); retried.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("expected upstream 5xx responses not to be replayed")
+	}
+
+	store := NewIdempotencyStore()
+	now := time.Now()
+	if _, err := store.begin("default/shop\nk3", "a", time.Minute, now); err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if _, err := store.begin("default/shop\nk3", "a", time.Minute, now); err != errIdempotencyInFlight {
+		t.Fatalf("expected a concurrent duplicate to be in flight, got %v", err)
+	}
+	if _, err := store.begin("default/shop\nk3", "a", time.Minute, now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("expected an expired claim to be reusable, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultImpersonationTTL = 30 * time.Minute
+	maxImpersonationTTL     = 4 * time.Hour
+
+	impersonatedByHeader       = "X-Proxer-Impersonated-By"
+	impersonationExpiresHeader = "X-Proxer-Impersonation-Expires"
+)
+
+type impersonateRequest struct {
+	TTLSeconds int    `json:"ttl_seconds,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+func (s *Server) markImpersonation(w http.ResponseWriter, r *http.Request, user User, impersonation *Impersonation) {
+	w.Header().Set(impersonatedByHeader, impersonation.ImpersonatedBy)
+	w.Header().Set(impersonationExpiresHeader, impersonation.ExpiresAt.Format(time.RFC3339))
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return
+	}
+	s.auditStore.Record(impersonation.ImpersonatedBy, "impersonation.request", user.TenantID, map[string]string{
+		"username": user.Username,
+		"method":   r.Method,
+		"path":     r.URL.Path,
+	})
+}
+
+// handleAdminImpersonate opens a time-limited session as username for the
+// calling super admin. The session replaces the admin's console cookie, so
+// the console shows what the user sees until it expires or logs out, and is
+// returned as session_id for bearer use.
+func (s *Server) handleAdminImpersonate(w http.ResponseWriter, r *http.Request, admin User, username string) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	var request impersonateRequest
+	if r.ContentLength != 0 && !s.decodeJSON(w, r, &request, "impersonation payload") {
+		return
+	}
+	ttl := defaultImpersonationTTL
+	if request.TTLSeconds != 0 {
+		ttl = time.Duration(request.TTLSeconds) * time.Second
+		if ttl < time.Minute || ttl > maxImpersonationTTL {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("ttl_seconds must be between 60 and %d", int(maxImpersonationTTL.Seconds())))
+			return
+		}
+	}
+	target, ok := s.authStore.GetUser(username)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "user not found")
+		return
+	}
+
+	sessionID, impersonation, err := s.authStore.NewImpersonationSession(admin.Username, target.Username, ttl)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	s.auditStore.Record(admin.Username, "impersonation.start", target.TenantID, map[string]string{
+		"username":   target.Username,
+		"expires_at": impersonation.ExpiresAt.Format(time.RFC3339),
+		"reason":     strings.TrimSpace(request.Reason),
+	})
+
+	s.setSessionCookie(w, sessionID)
+	w.Header().Set(impersonatedByHeader, impersonation.ImpersonatedBy)
+	w.Header().Set(impersonationExpiresHeader, impersonation.ExpiresAt.Format(time.RFC3339))
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"message":       "impersonation started",
+		"session_id":    sessionID,
+		"user":          target,
+		"impersonation": impersonation,
+	})
+	s.persistState()
+}
@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminImpersonationIsBanneredTimeLimitedAndAudited(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	if _, err := server.ruleStore.UpsertTenant(Tenant{ID: "acme"}); err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+	if _, err := server.authStore.RegisterUser(RegisterUserInput{Username: "alice", Password: "secret-pass", TenantID: "acme", Role: RoleTenantAdmin}); err != nil {
+		t.Fatalf("register user: %v", err)
+	}
+	adminSession, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	call := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	if recorder := call(http.MethodPost, "/api/admin/users/admin/impersonate", adminSession, ""); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected super admins not to be impersonated, got %d", recorder.Code)
+	}
+	if recorder := call(http.MethodPost, "/api/admin/users/alice/impersonate", adminSession, `{"ttl_seconds":86400}`); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected a ttl above the maximum to be rejected, got %d", recorder.Code)
+	}
+	recorder := call(http.MethodPost, "/api/admin/users/alice/impersonate", adminSession, `{"ttl_seconds":600,"reason":"ticket 42"}`)
+	if recorder.Code != http.StatusCreated || recorder.Header().Get(impersonatedByHeader) != "admin" {
+		t.Fatalf("expected impersonation to start, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var started struct {
+		SessionID     string        `json:"session_id"`
+		Impersonation Impersonation `json:"impersonation"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &started); err != nil || started.SessionID == "" {
+		t.Fatalf("decode impersonation: %v %s", err, recorder.Body.String())
+	}
+
+	recorder = call(http.MethodGet, "/api/auth/me", started.SessionID, "")
+	if recorder.Code != http.StatusOK || recorder.Header().Get(impersonatedByHeader) != "admin" || recorder.Header().Get(impersonationExpiresHeader) == "" {
+		t.Fatalf("expected a bannered response, got %d %v", recorder.Code, recorder.Header())
+	}
+	var me struct {
+		User          User           `json:"user"`
+		Impersonation *Impersonation `json:"impersonation"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &me); err != nil || me.User.Username != "alice" || me.Impersonation == nil || me.Impersonation.ImpersonatedBy != "admin" {
+		t.Fatalf("expected alice's view with the impersonation, got %s", recorder.Body.String())
+	}
+	if recorder := call(http.MethodGet, "/api/admin/users", started.SessionID, ""); recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected the session to have alice's permissions, got %d", recorder.Code)
+	}
+	if recorder := call(http.MethodPut, "/api/tenants/acme/retention", started.SessionID, `{"audit":"30d"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("expected alice to update retention, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call(http.MethodPost, "/api/auth/logout", started.SessionID, ""); recorder.Code != http.StatusOK {
+		t.Fatalf("logout: %d", recorder.Code)
+	}
+	if recorder := call(http.MethodGet, "/api/auth/me", started.SessionID, ""); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the session to end on logout, got %d", recorder.Code)
+	}
+
+	actions := map[string]AuditEvent{}
+	for _, event := range server.auditStore.List("acme", 10) {
+		actions[event.Action] = event
+	}
+	for _, action := range []string{"impersonation.start", "impersonation.request", "impersonation.end"} {
+		if event, ok := actions[action]; !ok || event.Actor != "admin" {
+			t.Fatalf("expected %s by admin in the audit log, got %+v", action, actions)
+		}
+	}
+	if actions["impersonation.start"].Details["reason"] != "ticket 42" || actions["impersonation.request"].Details["path"] != "/api/tenants/acme/retention" {
+		t.Fatalf("unexpected audit details %+v", actions)
+	}
+}
+
+func TestImpersonationSessionEndsWhenTheAdminIsDisabled(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.authStore.RegisterUser(RegisterUserInput{Username: "ops", Password: "secret-pass", Role: RoleSuperAdmin}); err != nil {
+		t.Fatalf("register admin: %v", err)
+	}
+	if _, err := server.authStore.RegisterUser(RegisterUserInput{Username: "bob", Password: "secret-pass", TenantID: DefaultTenantID}); err != nil {
+		t.Fatalf("register user: %v", err)
+	}
+	sessionID, _, err := server.authStore.NewImpersonationSession("ops", "bob", defaultImpersonationTTL)
+	if err != nil {
+		t.Fatalf("impersonate: %v", err)
+	}
+	if _, impersonation, ok := server.authStore.ResolveSession(sessionID); !ok || impersonation == nil {
+		t.Fatalf("expected an impersonation session")
+	}
+	if _, err := server.authStore.UpdateUser(UpdateUserInput{Username: "ops", Status: "disabled"}); err != nil {
+		t.Fatalf("disable admin: %v", err)
+	}
+	if _, _, ok := server.authStore.ResolveSession(sessionID); ok {
+		t.Fatalf("expected the session to end with the admin disabled")
+	}
+}
@@ -14,6 +14,7 @@ type SystemIncident struct {
 	Severity   string     `json:"severity"`
 	Source     string     `json:"source"`
 	Message    string     `json:"message"`
+	RequestID  string     `json:"request_id,omitempty"`
 	CreatedAt  time.Time  `json:"created_at"`
 	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
 }
@@ -31,6 +32,10 @@ func NewIncidentStore() *IncidentStore {
 }
 
 func (s *IncidentStore) Add(severity, source, message string) SystemIncident {
+	return s.AddForRequest(severity, source, message, "")
+}
+
+func (s *IncidentStore) AddForRequest(severity, source, message, requestID string) SystemIncident {
 	severity = strings.ToLower(strings.TrimSpace(severity))
 	if severity == "" {
 		severity = "info"
@@ -46,6 +51,7 @@ func (s *IncidentStore) Add(severity, source, message string) SystemIncident {
 		Severity:  severity,
 		Source:    source,
 		Message:   message,
+		RequestID: strings.TrimSpace(requestID),
 		CreatedAt: time.Now().UTC(),
 	}
 
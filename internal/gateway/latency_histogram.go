@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+)
+
+const (
+	// Values below latencyLinearBuckets ms get exact buckets; above that each
+	// power of two is split into latencySubBuckets, keeping relative error
+	// under 12.5% in a fixed array.
+	latencyLinearBuckets = 16
+	latencySubBuckets    = 8
+	latencyBucketCount   = latencyLinearBuckets + 20*latencySubBuckets
+)
+
+type LatencyPercentiles struct {
+	Count int64 `json:"count"`
+	P50Ms int64 `json:"p50_ms"`
+	P90Ms int64 `json:"p90_ms"`
+	P99Ms int64 `json:"p99_ms"`
+	MaxMs int64 `json:"max_ms"`
+}
+
+type LatencyHistogram struct {
+	counts [latencyBucketCount]uint64
+	count  int64
+	sumMs  int64
+	maxMs  int64
+}
+
+func latencyBucketIndex(valueMs int64) int {
+	if valueMs < latencyLinearBuckets {
+		if valueMs < 0 {
+			return 0
+		}
+		return int(valueMs)
+	}
+	exponent := bits.Len64(uint64(valueMs)) - 1
+	sub := int(valueMs>>(exponent-3)) & (latencySubBuckets - 1)
+	index := latencyLinearBuckets + (exponent-4)*latencySubBuckets + sub
+	if index >= latencyBucketCount {
+		return latencyBucketCount - 1
+	}
+	return index
+}
+
+func latencyBucketUpperMs(index int) int64 {
+	if index < latencyLinearBuckets {
+		return int64(index)
+	}
+	offset := index - latencyLinearBuckets
+	exponent := offset/latencySubBuckets + 4
+	sub := offset % latencySubBuckets
+	return (int64(latencySubBuckets+sub+1) << (exponent - 3)) - 1
+}
+
+func (h *LatencyHistogram) Record(valueMs int64) {
+	if valueMs < 0 {
+		valueMs = 0
+	}
+	h.counts[latencyBucketIndex(valueMs)]++
+	h.count++
+	h.sumMs += valueMs
+	if valueMs > h.maxMs {
+		h.maxMs = valueMs
+	}
+}
+
+func (h *LatencyHistogram) Quantile(q float64) int64 {
+	if h == nil || h.count == 0 {
+		return 0
+	}
+	// Nearest-rank: the smallest bucket covering ceil(q*count) samples.
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	if rank > 0 {
+		rank--
+	}
+	if rank >= uint64(h.count) {
+		rank = uint64(h.count) - 1
+	}
+	var seen uint64
+	for i, count := range h.counts {
+		seen += count
+		if seen > rank {
+			upper := latencyBucketUpperMs(i)
+			if upper > h.maxMs {
+				return h.maxMs
+			}
+			return upper
+		}
+	}
+	return h.maxMs
+}
+
+func (h *LatencyHistogram) Percentiles() LatencyPercentiles {
+	if h == nil {
+		return LatencyPercentiles{}
+	}
+	return LatencyPercentiles{
+		Count: h.count,
+		P50Ms: h.Quantile(0.50),
+		P90Ms: h.Quantile(0.90),
+		P99Ms: h.Quantile(0.99),
+		MaxMs: h.maxMs,
+	}
+}
+
+func (h *LatencyHistogram) CumulativeCounts(boundsMs []int64) []uint64 {
+	sorted := append([]int64(nil), boundsMs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	out := make([]uint64, len(sorted))
+	if h == nil {
+		return out
+	}
+	var seen uint64
+	bucket := 0
+	for i, bound := range sorted {
+		for bucket < latencyBucketCount && latencyBucketUpperMs(bucket) <= bound {
+			seen += h.counts[bucket]
+			bucket++
+		}
+		out[i] = seen
+	}
+	return out
+}
+
+func (h *LatencyHistogram) Count() int64 {
+	if h == nil {
+		return 0
+	}
+	return h.count
+}
+
+func (h *LatencyHistogram) SumMs() int64 {
+	if h == nil {
+		return 0
+	}
+	return h.sumMs
+}
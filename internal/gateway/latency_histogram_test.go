@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestLatencyHistogramPercentilesWithinPrecision(t *testing.T) {
+	var histogram LatencyHistogram
+	for value := int64(1); value <= 1000; value++ {
+		histogram.Record(value)
+	}
+	percentiles := histogram.Percentiles()
+	for name, got := range map[string][2]int64{
+		"p50": {percentiles.P50Ms, 500},
+		"p90": {percentiles.P90Ms, 900},
+		"p99": {percentiles.P99Ms, 990},
+	} {
+		if got[0] < got[1] || float64(got[0]) > float64(got[1])*1.125 {
+			t.Fatalf("%s = %d, want within 12.5%% above %d", name, got[0], got[1])
+		}
+	}
+	if percentiles.Count != 1000 || percentiles.MaxMs != 1000 {
+		t.Fatalf("unexpected count/max %+v", percentiles)
+	}
+	for index := 0; index < latencyBucketCount-1; index++ {
+		upper := latencyBucketUpperMs(index)
+		if latencyBucketIndex(upper) != index || latencyBucketIndex(upper+1) != index+1 {
+			t.Fatalf("bucket %d upper bound %d is inconsistent", index, upper)
+		}
+	}
+}
+
+func TestHubTracksPerTenantLatencyAndServesPrometheus(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", MetricsToken: "scrape"}, nil)
+	for _, latency := range []int64{10, 20, 30, 400} {
+		server.hub.RecordProxyResponse(&protocol.ProxyResponse{TunnelID: "acme/web", Status: http.StatusOK, LatencyMs: latency})
+	}
+	server.hub.RecordProxyResponse(&protocol.ProxyResponse{TunnelID: "other/api", Status: http.StatusOK, LatencyMs: 5})
+
+	if got := server.hub.TenantLatency("acme"); got.Count != 4 || got.P99Ms != 400 {
+		t.Fatalf("unexpected acme latency %+v", got)
+	}
+	if got := server.hub.GetTunnelMetrics("acme/web").Latency; got.P50Ms < 20 || got.P50Ms > 30 {
+		t.Fatalf("unexpected route p50 %+v", got)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handlePrometheusMetrics(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unauthenticated scrape to fail, got %d", recorder.Code)
+	}
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	request.Header.Set("Authorization", "Bearer scrape")
+	recorder = httptest.NewRecorder()
+	server.handlePrometheusMetrics(recorder, request)
+	body := recorder.Body.String()
+	for _, want := range []string{
+		`proxer_route_latency_seconds_bucket{tenant="acme",route="web",le="0.025"} 2`,
+		`proxer_route_latency_seconds_count{tenant="acme",route="web"} 4`,
+		`proxer_route_requests_total{tenant="other",route="api"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in metrics output:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func listenDedicated(addr, certFile, keyFile string, http2 bool, handler http.Handler) (*http.Server, net.Listener, error) {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		Protocols:         listenerProtocols(http2),
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	if strings.TrimSpace(certFile) == "" {
+		return server, listener, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		_ = listener.Close()
+		return nil, nil, fmt.Errorf("load tls key pair: %w", err)
+	}
+	server.TLSConfig = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+		NextProtos:   listenerNextProtos(http2),
+	}
+	return server, tls.NewListener(listener, server.TLSConfig), nil
+}
+
+// listenerProtocols serves HTTP/2 next to HTTP/1.1 when enabled: negotiated
+// via ALPN on TLS listeners and as prior-knowledge h2c on plaintext ones.
+func listenerProtocols(http2 bool) *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	if http2 {
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	}
+	return protocols
+}
+
+func listenerNextProtos(http2 bool) []string {
+	if http2 {
+		return []string{"h2", "http/1.1"}
+	}
+	return []string{"http/1.1"}
+}
+
+func serveListener(name string, server *http.Server, listener net.Listener, errCh chan<- error) {
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errCh <- fmt.Errorf("serve %s: %w", name, err)
+	}
+}
+
+func (s *Server) AdminAddr() string {
+	if s.adminListener == nil {
+		return s.Addr()
+	}
+	return s.adminListener.Addr().String()
+}
+
+func (s *Server) AgentAddr() string {
+	if s.agentListener == nil {
+		return s.Addr()
+	}
+	return s.agentListener.Addr().String()
+}
+
+func (s *Server) agentBaseURL() string {
+	cfg := s.config()
+	if base := strings.TrimSpace(cfg.AgentBaseURL); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return strings.TrimRight(cfg.PublicBaseURL, "/")
+}
@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func routeStatus(handler http.Handler, method, path string) int {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+	return recorder.Code
+}
+
+func TestBuildListenerMuxesSplitsAdminAndAgentRoutes(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	cfg := server.config()
+	cfg.AdminListenAddr = "127.0.0.1:0"
+	cfg.AgentListenAddr = "127.0.0.1:1"
+
+	public, admin, agent := server.buildListenerMuxes(cfg)
+	if admin == nil || agent == nil {
+		t.Fatalf("expected dedicated admin and agent muxes")
+	}
+	if status := routeStatus(public, http.MethodGet, "/api/auth/me"); status != http.StatusNotFound {
+		t.Fatalf("expected console API to be absent from public listener, got %d", status)
+	}
+	if status := routeStatus(public, http.MethodPost, "/api/agent/register"); status != http.StatusNotFound {
+		t.Fatalf("expected agent API to be absent from public listener, got %d", status)
+	}
+	if status := routeStatus(admin, http.MethodGet, "/api/auth/me"); status != http.StatusUnauthorized {
+		t.Fatalf("expected console API on admin listener, got %d", status)
+	}
+	if status := routeStatus(agent, http.MethodGet, "/api/agent/register"); status != http.StatusMethodNotAllowed {
+		t.Fatalf("expected agent API on agent listener, got %d", status)
+	}
+	if status := routeStatus(admin, http.MethodGet, "/t/default/app/"); status != http.StatusNotFound {
+		t.Fatalf("expected proxy traffic to stay off the admin listener, got %d", status)
+	}
+	for name, mux := range map[string]http.Handler{"public": public, "admin": admin, "agent": agent} {
+		if status := routeStatus(mux, http.MethodGet, "/api/health"); status != http.StatusOK {
+			t.Fatalf("expected health check on %s listener, got %d", name, status)
+		}
+	}
+}
+
+func TestBuildListenerMuxesDefaultsToSingleListener(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	public, admin, agent := server.buildListenerMuxes(server.config())
+	if admin != nil || agent != nil {
+		t.Fatalf("expected no dedicated listeners by default")
+	}
+	if status := routeStatus(public, http.MethodGet, "/api/auth/me"); status != http.StatusUnauthorized {
+		t.Fatalf("expected console API on public listener, got %d", status)
+	}
+}
+
+func TestValidateListenerConfig(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{name: "duplicate", cfg: Config{ListenAddr: ":8080", AdminListenAddr: ":8080"}, want: "PROXER_ADMIN_LISTEN_ADDR must differ from PROXER_LISTEN_ADDR"},
+		{name: "half tls", cfg: Config{ListenAddr: ":8080", AgentListenAddr: ":8082", AgentTLSCertFile: "cert.pem"}, want: "must be set together"},
+		{name: "tls without listener", cfg: Config{ListenAddr: ":8080", AdminTLSCertFile: "cert.pem", AdminTLSKeyFile: "key.pem"}, want: "requires PROXER_ADMIN_LISTEN_ADDR"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateListenerConfig(tc.cfg, configSource{})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+	if err := validateListenerConfig(Config{ListenAddr: ":8080", AdminListenAddr: ":8081"}, configSource{}); err != nil {
+		t.Fatalf("expected distinct listeners to validate, got %v", err)
+	}
+}
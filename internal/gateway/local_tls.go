@@ -0,0 +1,45 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+// normalizeLocalTLS validates the TLS settings a connector route sends with
+// its local target. The agent applies them, so only their shape is checked
+// here.
+func normalizeLocalTLS(input *protocol.LocalTLS, usesConnector bool, localScheme string) (*protocol.LocalTLS, error) {
+	if input == nil {
+		return nil, nil
+	}
+	out := &protocol.LocalTLS{
+		InsecureSkipVerify: input.InsecureSkipVerify,
+		ServerName:         strings.TrimSpace(input.ServerName),
+		CAPEM:              strings.TrimSpace(input.CAPEM),
+	}
+	if pin := strings.TrimSpace(input.PinnedSHA256); pin != "" {
+		pin = strings.ToLower(strings.ReplaceAll(pin, ":", ""))
+		if decoded, err := hex.DecodeString(pin); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("local_tls.pinned_sha256 must be 64 hex digits")
+		}
+		out.PinnedSHA256 = pin
+	}
+	if out.CAPEM != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(out.CAPEM)) {
+		return nil, fmt.Errorf("local_tls.ca_pem must contain PEM certificates")
+	}
+	if *out == (protocol.LocalTLS{}) {
+		return nil, nil
+	}
+	if !usesConnector {
+		return nil, fmt.Errorf("local_tls requires connector_id or connector_selector")
+	}
+	if localScheme != "https" {
+		return nil, fmt.Errorf("local_tls requires local_scheme https")
+	}
+	return out, nil
+}
@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestLocalTLSIsValidatedAndSentWithConnectorTarget(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	pin := strings.Repeat("AB:", 31) + "AB"
+
+	for _, invalid := range []Rule{
+		{ID: "secure-api", Target: "https://example.com", LocalTLS: &protocol.LocalTLS{InsecureSkipVerify: true}},
+		{ID: "secure-api", ConnectorID: "laptop", LocalPort: 8443, LocalTLS: &protocol.LocalTLS{InsecureSkipVerify: true}},
+		{ID: "secure-api", ConnectorID: "laptop", LocalPort: 8443, LocalScheme: "https", LocalTLS: &protocol.LocalTLS{PinnedSHA256: "abc"}},
+		{ID: "secure-api", ConnectorID: "laptop", LocalPort: 8443, LocalScheme: "https", LocalTLS: &protocol.LocalTLS{CAPEM: "not a certificate"}},
+	} {
+		if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, invalid); err == nil {
+			t.Fatalf("expected %+v to be rejected", invalid.LocalTLS)
+		}
+	}
+
+	rule, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
+		ID:          "secure-api",
+		ConnectorID: "laptop",
+		LocalScheme: "https",
+		LocalPort:   8443,
+		LocalTLS:    &protocol.LocalTLS{PinnedSHA256: pin, ServerName: " api.test "},
+	})
+	if err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	target := rule.localTarget()
+	if target.TLS == nil || target.TLS.PinnedSHA256 != strings.Repeat("ab", 32) || target.TLS.ServerName != "api.test" {
+		t.Fatalf("expected normalized TLS settings on the local target, got %+v", target.TLS)
+	}
+	if split := (RouteUpstream{ConnectorID: "laptop", LocalScheme: "https", LocalPort: 9443}).applyTo(rule); split.LocalTLS != nil {
+		t.Fatalf("expected secondary upstreams not to inherit the route's TLS settings")
+	}
+}
@@ -1,8 +1,11 @@
 package gateway
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,7 +18,7 @@ type usageGauge struct {
 
 func (s *Server) handleMeDashboard(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	user, ok := s.requireAuth(w, r)
@@ -64,6 +67,7 @@ func (s *Server) handleMeDashboard(w http.ResponseWriter, r *http.Request) {
 	usage := s.planStore.GetUsage(tenantID, "")
 	trafficUsedGB := float64(usage.BytesIn+usage.BytesOut) / bytesPerGB
 	trafficPercent := usagePercent(plan, usage)
+	warningThreshold := activeUsageWarning(s.config().UsageWarningPercents, trafficPercent)
 
 	writeJSON(w, http.StatusOK, map[string]any{
 		"tenant_id": tenantID,
@@ -95,6 +99,13 @@ func (s *Server) handleMeDashboard(w http.ResponseWriter, r *http.Request) {
 			"connectors_offline":     len(connectors) - onlineConnectors,
 			"blocked_requests_month": usage.BlockedRequests,
 		},
+		"usage_warning": map[string]any{
+			"active":            warningThreshold > 0,
+			"threshold_percent": warningThreshold,
+			"used_percent":      math.Min(trafficPercent*100, 100),
+			"hard_cap_reached":  trafficPercent >= 1,
+		},
+		"latency":      s.hub.TenantLatency(tenantID),
 		"usage":        usage,
 		"routes":       routes,
 		"connectors":   connectorViews,
@@ -104,7 +115,7 @@ func (s *Server) handleMeDashboard(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleMeRoutes(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	user, ok := s.requireAuth(w, r)
@@ -139,7 +150,7 @@ func (s *Server) handleMeRoutes(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleMeConnectors(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	user, ok := s.requireAuth(w, r)
@@ -153,13 +164,19 @@ func (s *Server) handleMeConnectors(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleMeUsage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	user, ok := s.requireAuth(w, r)
 	if !ok {
 		return
 	}
+	days, err := parseTransferDays(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	now := time.Now().UTC()
 
 	if s.isSuperAdmin(user) {
 		tenants := s.ruleStore.ListTenants()
@@ -172,6 +189,7 @@ func (s *Server) handleMeUsage(w http.ResponseWriter, r *http.Request) {
 				"plan_id":   planID,
 				"plan":      plan,
 				"usage":     usage,
+				"transfer":  s.transfer.Summary(tenant.ID, days, now),
 			})
 		}
 		writeJSON(w, http.StatusOK, map[string]any{"tenants": items})
@@ -189,6 +207,7 @@ func (s *Server) handleMeUsage(w http.ResponseWriter, r *http.Request) {
 		"plan_id":   planID,
 		"plan":      plan,
 		"usage":     usage,
+		"transfer":  s.transfer.Summary(tenantID, days, now),
 	})
 }
 
@@ -215,3 +234,91 @@ func countActiveRoutes(routes []routeView) int {
 	}
 	return count
 }
+
+type changePlanRequest struct {
+	PlanID string `json:"plan_id"`
+}
+
+func (s *Server) handleMePlan(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	if s.isSuperAdmin(user) {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "super admin must use /api/admin/tenants/{id}/assign-plan")
+		return
+	}
+	tenantID := strings.TrimSpace(user.TenantID)
+	if tenantID == "" {
+		tenantID = DefaultTenantID
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		plan, planID := s.planStore.GetTenantPlan(tenantID)
+		available := make([]Plan, 0)
+		for _, candidate := range s.planStore.ListPlans() {
+			if candidate.SelfServe {
+				available = append(available, candidate)
+			}
+		}
+		response := map[string]any{
+			"tenant_id":       tenantID,
+			"plan_id":         planID,
+			"plan":            plan,
+			"available_plans": available,
+		}
+		if assignment, ok := s.planStore.GetTenantAssignment(tenantID); ok {
+			response["assignment"] = assignment
+		}
+		writeJSON(w, http.StatusOK, response)
+	case http.MethodPost:
+		if !s.canMutateTenantConfig(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAdminRequired, "tenant admin required")
+			return
+		}
+		var request changePlanRequest
+		if !s.decodeJSON(w, r, &request, "plan change payload") {
+			return
+		}
+		target, exists := s.planStore.GetPlan(request.PlanID)
+		if !exists {
+			writeAPIError(w, http.StatusNotFound, errCodePlanNotFound, "plan not found")
+			return
+		}
+		// The plan is vetted under the plan store lock, so a concurrent plan
+		// edit or change cannot slip between the check and the assignment.
+		status, code := http.StatusBadRequest, errCodeInvalidRequest
+		assignment, err := s.planStore.ChangeTenantPlan(tenantID, target.ID, user.Username, time.Now(), func(plan Plan) error {
+			if !plan.SelfServe {
+				status, code = http.StatusForbidden, errCodePlanNotAvailable
+				return errors.New("plan is not available for self-serve changes")
+			}
+			if err := s.validatePlanFit(tenantID, plan); err != nil {
+				status, code = http.StatusConflict, errCodeConflict
+				return err
+			}
+			target = plan
+			return nil
+		})
+		if err != nil {
+			writeAPIError(w, status, code, err.Error())
+			return
+		}
+		s.auditStore.Record(user.Username, "plan.change", tenantID, map[string]string{
+			"previous_plan_id":    assignment.PreviousPlanID,
+			"plan_id":             assignment.PlanID,
+			"prorated_amount_usd": strconv.FormatFloat(assignment.ProratedAmountUSD, 'f', 2, 64),
+		})
+		s.webhooks.Emit("plan.changed", tenantID, assignment)
+		s.refreshTenantUsage(tenantID)
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message":    "plan changed",
+			"assignment": assignment,
+			"plan":       target,
+		})
+		s.persistState()
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+	}
+}
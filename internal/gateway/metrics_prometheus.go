@@ -0,0 +1,172 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var prometheusLatencyBucketsMs = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000}
+
+// handlePrometheusMetrics serves hub counters and latency histograms in the
+// Prometheus text exposition format. Scrapers authenticate with
+// PROXER_METRICS_TOKEN; super admin sessions are accepted as well.
+func (s *Server) handlePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorizeMetricsScrape(w, r) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	status := s.hub.Status()
+	writePrometheusGauge(w, "proxer_active_sessions", "Active agent sessions.", float64(status.ActiveSessions))
+	writePrometheusGauge(w, "proxer_pending_requests", "Proxy requests waiting for an agent response.", float64(status.PendingRequests))
+	fmt.Fprintf(w, "# HELP proxer_queue_depth Queued proxy requests by priority class.\n# TYPE proxer_queue_depth gauge\n")
+	for _, class := range priorityClasses {
+		fmt.Fprintf(w, "proxer_queue_depth{class=%q} %d\n", class, status.QueueDepthByClass[class])
+	}
+	queueWait, queueOverflow := s.hub.queueStats.snapshot()
+	fmt.Fprintf(w, "# HELP proxer_queue_wait_seconds Time proxy requests waited in agent queues before an agent pulled them.\n# TYPE proxer_queue_wait_seconds histogram\n")
+	cumulative := queueWait.CumulativeCounts(prometheusLatencyBucketsMs)
+	for i, bound := range prometheusLatencyBucketsMs {
+		fmt.Fprintf(w, "proxer_queue_wait_seconds_bucket{le=%q} %d\n", formatPrometheusSeconds(bound), cumulative[i])
+	}
+	fmt.Fprintf(w, "proxer_queue_wait_seconds_bucket{le=\"+Inf\"} %d\n", queueWait.Count())
+	fmt.Fprintf(w, "proxer_queue_wait_seconds_sum %s\n", formatPrometheusSeconds(queueWait.SumMs()))
+	fmt.Fprintf(w, "proxer_queue_wait_seconds_count %d\n", queueWait.Count())
+	fmt.Fprintf(w, "# HELP proxer_queue_overflow_total Proxy requests that found their agent queue full, by outcome.\n# TYPE proxer_queue_overflow_total counter\n")
+	fmt.Fprintf(w, "proxer_queue_overflow_total{outcome=\"rejected\"} %d\n", queueOverflow.Rejected)
+	fmt.Fprintf(w, "proxer_queue_overflow_total{outcome=\"waited\"} %d\n", queueOverflow.Waited)
+	fmt.Fprintf(w, "proxer_queue_overflow_total{outcome=\"shed\"} %d\n", queueOverflow.Shed)
+	clientWrites := s.clientWrites.status()
+	writePrometheusGauge(w, "proxer_client_write_pending_bytes", "Proxied response bytes not yet accepted by public clients.", float64(clientWrites.PendingBytes))
+	fmt.Fprintf(w, "# HELP proxer_client_write_aborts_total Proxied responses cut off while writing to a public client, by reason.\n# TYPE proxer_client_write_aborts_total counter\n")
+	fmt.Fprintf(w, "proxer_client_write_aborts_total{reason=\"slow\"} %d\n", clientWrites.SlowAborts)
+	fmt.Fprintf(w, "proxer_client_write_aborts_total{reason=\"closed\"} %d\n", clientWrites.ClosedAborts)
+
+	routeMetrics := make(map[string]TunnelMetrics)
+	for _, route := range s.ruleStore.ListAll() {
+		key := MakeTunnelKey(route.TenantID, route.ID)
+		routeMetrics[key] = s.hub.GetTunnelMetrics(key)
+	}
+	histograms := s.hub.RouteLatencyHistograms()
+	keys := make([]string, 0, len(routeMetrics))
+	for key := range routeMetrics {
+		keys = append(keys, key)
+	}
+	for key := range histograms {
+		if _, ok := routeMetrics[key]; !ok {
+			routeMetrics[key] = s.hub.GetTunnelMetrics(key)
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(TunnelMetrics) int64
+	}{
+		{"proxer_route_requests_total", "Proxied requests per route.", func(m TunnelMetrics) int64 { return m.RequestCount }},
+		{"proxer_route_errors_total", "Failed proxied requests per route.", func(m TunnelMetrics) int64 { return m.ErrorCount }},
+		{"proxer_route_timeouts_total", "Timed out proxied requests per route.", func(m TunnelMetrics) int64 { return m.TimeoutCount }},
+		{"proxer_route_retries_total", "Upstream retries per route.", func(m TunnelMetrics) int64 { return m.RetryCount }},
+		{"proxer_route_webhooks_verified_total", "Webhook deliveries whose signature the gateway verified per route.", func(m TunnelMetrics) int64 { return m.WebhooksVerified }},
+		{"proxer_route_webhooks_rejected_total", "Webhook deliveries rejected for a missing or invalid signature per route.", func(m TunnelMetrics) int64 { return m.WebhooksRejected }},
+		{"proxer_route_bytes_in_total", "Request bytes received per route.", func(m TunnelMetrics) int64 { return m.BytesIn }},
+		{"proxer_route_bytes_out_total", "Response bytes sent per route.", func(m TunnelMetrics) int64 { return m.BytesOut }},
+	}
+	for _, counter := range counters {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", counter.name, counter.help, counter.name)
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s{%s} %d\n", counter.name, routeLabels(key), counter.value(routeMetrics[key]))
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP proxer_route_latency_seconds Upstream latency per route.\n# TYPE proxer_route_latency_seconds histogram\n")
+	for _, key := range keys {
+		histogram, ok := histograms[key]
+		if !ok {
+			continue
+		}
+		labels := routeLabels(key)
+		cumulative := histogram.CumulativeCounts(prometheusLatencyBucketsMs)
+		for i, bound := range prometheusLatencyBucketsMs {
+			fmt.Fprintf(w, "proxer_route_latency_seconds_bucket{%s,le=%q} %d\n", labels, formatPrometheusSeconds(bound), cumulative[i])
+		}
+		fmt.Fprintf(w, "proxer_route_latency_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, histogram.Count())
+		fmt.Fprintf(w, "proxer_route_latency_seconds_sum{%s} %s\n", labels, formatPrometheusSeconds(histogram.SumMs()))
+		fmt.Fprintf(w, "proxer_route_latency_seconds_count{%s} %d\n", labels, histogram.Count())
+	}
+
+	s.writeConnectorLoadMetrics(w)
+}
+
+func (s *Server) writeConnectorLoadMetrics(w io.Writer) {
+	loads := s.hub.ConnectorLoads()
+	connectorIDs := make([]string, 0, len(loads))
+	for connectorID := range loads {
+		connectorIDs = append(connectorIDs, connectorID)
+	}
+	sort.Strings(connectorIDs)
+	labels := make(map[string]string, len(connectorIDs))
+	for _, connectorID := range connectorIDs {
+		connector, _ := s.connectorStore.Get(connectorID)
+		labels[connectorID] = fmt.Sprintf("tenant=%q,connector=%q", connector.TenantID, connectorID)
+	}
+
+	series := []struct {
+		name  string
+		help  string
+		kind  string
+		value func(ConnectorLoad) float64
+	}{
+		{"proxer_connector_in_flight", "Proxy requests dispatched to a connector and not yet answered.", "gauge", func(l ConnectorLoad) float64 { return float64(l.InFlight) }},
+		{"proxer_connector_queued", "Proxy requests waiting for a connector to pull them.", "gauge", func(l ConnectorLoad) float64 { return float64(l.Queued) }},
+		{"proxer_connector_recent_latency_seconds", "Moving average round-trip latency per connector.", "gauge", func(l ConnectorLoad) float64 { return l.RecentLatencyMs / 1000 }},
+		{"proxer_connector_dispatched_total", "Proxy requests dispatched per connector session.", "counter", func(l ConnectorLoad) float64 { return float64(l.Dispatched) }},
+	}
+	for _, metric := range series {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, connectorID := range connectorIDs {
+			fmt.Fprintf(w, "%s{%s} %s\n", metric.name, labels[connectorID], strconv.FormatFloat(metric.value(loads[connectorID]), 'f', -1, 64))
+		}
+	}
+}
+
+func (s *Server) authorizeMetricsScrape(w http.ResponseWriter, r *http.Request) bool {
+	if token := s.config().MetricsToken; token != "" {
+		provided := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if subtle.ConstantTimeCompare([]byte(token), []byte(provided)) == 1 {
+			return true
+		}
+	}
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return false
+	}
+	return s.requireSuperAdmin(w, user)
+}
+
+func routeLabels(tunnelKey string) string {
+	tenantID, routeID := ParseTunnelKey(tunnelKey)
+	if _, variant := splitTunnelVariant(tunnelKey); variant != "" {
+		return fmt.Sprintf("tenant=%q,route=%q,variant=%q", tenantID, routeID, variant)
+	}
+	return fmt.Sprintf("tenant=%q,route=%q", tenantID, routeID)
+}
+
+func writePrometheusGauge(w io.Writer, name, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+func formatPrometheusSeconds(ms int64) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', -1, 64)
+}
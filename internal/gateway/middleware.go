@@ -0,0 +1,203 @@
+package gateway
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/httpx"
+)
+
+const (
+	maxRouteMiddleware     = 32
+	routeMiddlewareTimeout = 10 * time.Millisecond
+
+	middlewareActionDeny         = "deny"
+	middlewareActionSetHeader    = "set_header"
+	middlewareActionRemoveHeader = "remove_header"
+	middlewareActionUpstream     = "upstream"
+)
+
+type RouteMiddleware struct {
+	When      string         `json:"when,omitempty"`
+	Action    string         `json:"action"`
+	Status    int            `json:"status,omitempty"`
+	Message   string         `json:"message,omitempty"`
+	Header    string         `json:"header,omitempty"`
+	Value     string         `json:"value,omitempty"`
+	ValueExpr string         `json:"value_expr,omitempty"`
+	Upstream  *RouteUpstream `json:"upstream,omitempty"`
+}
+
+func normalizeRouteMiddleware(input []RouteMiddleware) ([]RouteMiddleware, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	if len(input) > maxRouteMiddleware {
+		return nil, fmt.Errorf("middleware supports at most %d steps", maxRouteMiddleware)
+	}
+	out := make([]RouteMiddleware, 0, len(input))
+	for index, item := range input {
+		field := fmt.Sprintf("middleware[%d]", index)
+		step := RouteMiddleware{
+			When:   strings.TrimSpace(item.When),
+			Action: strings.ToLower(strings.TrimSpace(item.Action)),
+		}
+		if step.When != "" {
+			if _, err := compileExprCached(step.When); err != nil {
+				return nil, fmt.Errorf("%s.when: %w", field, err)
+			}
+		}
+		switch step.Action {
+		case middlewareActionDeny:
+			step.Status = item.Status
+			if step.Status == 0 {
+				step.Status = http.StatusForbidden
+			}
+			if step.Status < 400 || step.Status > 599 {
+				return nil, fmt.Errorf("%s.status must be a 4xx or 5xx code", field)
+			}
+			step.Message = strings.TrimSpace(item.Message)
+		case middlewareActionSetHeader, middlewareActionRemoveHeader:
+			step.Header = http.CanonicalHeaderKey(strings.TrimSpace(item.Header))
+			if step.Header == "" || strings.ContainsAny(step.Header, " \t:") {
+				return nil, fmt.Errorf("%s.header must be a header name", field)
+			}
+			if httpx.IsHopByHopHeader(step.Header) || step.Header == "Host" || step.Header == "Content-Length" || step.Header == "X-Proxer-Request-Id" {
+				return nil, fmt.Errorf("%s.header %q cannot be changed by middleware", field, step.Header)
+			}
+			if step.Action == middlewareActionRemoveHeader {
+				break
+			}
+			step.Value = item.Value
+			step.ValueExpr = strings.TrimSpace(item.ValueExpr)
+			if step.Value != "" && step.ValueExpr != "" {
+				return nil, fmt.Errorf("%s.value cannot be combined with value_expr", field)
+			}
+			if step.ValueExpr != "" {
+				if _, err := compileExprCached(step.ValueExpr); err != nil {
+					return nil, fmt.Errorf("%s.value_expr: %w", field, err)
+				}
+			}
+		case middlewareActionUpstream:
+			if item.Upstream == nil {
+				return nil, fmt.Errorf("%s.upstream is required", field)
+			}
+			upstream, err := normalizeRouteUpstream(field+".upstream", *item.Upstream)
+			if err != nil {
+				return nil, err
+			}
+			step.Upstream = &upstream
+		default:
+			return nil, fmt.Errorf("%s.action must be deny, set_header, remove_header or upstream", field)
+		}
+		out = append(out, step)
+	}
+	return out, nil
+}
+
+type middlewareOutcome struct {
+	denyStatus  int
+	denyMessage string
+	headerOps   []RouteMiddleware
+	headerVals  []string
+	upstream    *RouteUpstream
+}
+
+func middlewareRequestVars(r *http.Request, forwardPath string) map[string]any {
+	headers := make(exprHeaders, len(r.Header))
+	for name, values := range r.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+	query := make(map[string]string)
+	for name, values := range r.URL.Query() {
+		if len(values) > 0 {
+			query[name] = values[0]
+		}
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	remoteIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+	return map[string]any{
+		"request": map[string]any{
+			"method":    r.Method,
+			"path":      forwardPath,
+			"host":      r.Host,
+			"scheme":    scheme,
+			"remote_ip": remoteIP,
+			"headers":   headers,
+			"query":     query,
+		},
+	}
+}
+
+// evaluateRouteMiddleware runs steps against r within routeMiddlewareTimeout.
+// Any evaluation error fails the request rather than skipping a step, so a
+// broken deny rule cannot silently let traffic through.
+func evaluateRouteMiddleware(steps []RouteMiddleware, r *http.Request, forwardPath string) (middlewareOutcome, error) {
+	outcome := middlewareOutcome{}
+	if len(steps) == 0 {
+		return outcome, nil
+	}
+	vars := middlewareRequestVars(r, forwardPath)
+	deadline := time.Now().Add(routeMiddlewareTimeout)
+	for index, step := range steps {
+		if step.When != "" {
+			when, err := compileExprCached(step.When)
+			if err != nil {
+				return outcome, fmt.Errorf("middleware[%d].when: %w", index, err)
+			}
+			matched, err := when.evalBool(vars, deadline)
+			if err != nil {
+				return outcome, fmt.Errorf("middleware[%d].when: %w", index, err)
+			}
+			if !matched {
+				continue
+			}
+		}
+		switch step.Action {
+		case middlewareActionDeny:
+			outcome.denyStatus = step.Status
+			outcome.denyMessage = step.Message
+			if outcome.denyMessage == "" {
+				outcome.denyMessage = http.StatusText(step.Status)
+			}
+			return outcome, nil
+		case middlewareActionSetHeader, middlewareActionRemoveHeader:
+			value := step.Value
+			if step.ValueExpr != "" {
+				valueExpr, err := compileExprCached(step.ValueExpr)
+				if err != nil {
+					return outcome, fmt.Errorf("middleware[%d].value_expr: %w", index, err)
+				}
+				if value, err = valueExpr.evalString(vars, deadline); err != nil {
+					return outcome, fmt.Errorf("middleware[%d].value_expr: %w", index, err)
+				}
+			}
+			outcome.headerOps = append(outcome.headerOps, step)
+			outcome.headerVals = append(outcome.headerVals, value)
+		case middlewareActionUpstream:
+			if outcome.upstream == nil {
+				outcome.upstream = step.Upstream
+			}
+		}
+	}
+	return outcome, nil
+}
+
+func (o middlewareOutcome) applyHeaders(headers map[string][]string) {
+	for index, step := range o.headerOps {
+		if step.Action == middlewareActionRemoveHeader {
+			delete(headers, step.Header)
+			continue
+		}
+		headers[step.Header] = []string{o.headerVals[index]}
+	}
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProxyAppliesRouteMiddleware(t *testing.T) {
+	stable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "stable "+r.Header.Get("X-Env")+" "+r.Header.Get("X-Debug"))
+	}))
+	defer stable.Close()
+	beta := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "beta")
+	}))
+	defer beta.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
+		ID:     "web",
+		Target: stable.URL,
+		Middleware: []RouteMiddleware{
+			{When: `request.path.startsWith("/admin")`, Action: "deny", Message: "admin is private"},
+			{Action: "set_header", Header: "x-env", ValueExpr: `"prod-" + request.method.lowerAscii()`},
+			{Action: "remove_header", Header: "X-Debug"},
+			{When: `request.headers["x-version"] == "beta"`, Action: "upstream", Upstream: &RouteUpstream{Target: beta.URL}},
+		},
+	}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/web/admin/users", nil))
+	if recorder.Code != http.StatusForbidden || !strings.Contains(recorder.Body.String(), "admin is private") {
+		t.Fatalf("expected middleware deny, got %d %q", recorder.Code, recorder.Body.String())
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/t/web/home", nil)
+	request.Header.Set("X-Debug", "1")
+	recorder = httptest.NewRecorder()
+	server.handleProxy(recorder, request)
+	if got := recorder.Body.String(); got != "stable prod-get " {
+		t.Fatalf("unexpected upstream view %q", got)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/t/web/home", nil)
+	request.Header.Set("X-Version", "beta")
+	recorder = httptest.NewRecorder()
+	server.handleProxy(recorder, request)
+	if got := recorder.Body.String(); got != "beta" {
+		t.Fatalf("expected middleware to pick the beta upstream, got %q", got)
+	}
+}
+
+func TestNormalizeRouteMiddlewareRejectsInvalidSteps(t *testing.T) {
+	cases := []struct {
+		step RouteMiddleware
+		want string
+	}{
+		{step: RouteMiddleware{Action: "exec"}, want: "action must be"},
+		{step: RouteMiddleware{When: "request.path ==", Action: "deny"}, want: "middleware[0].when"},
+		{step: RouteMiddleware{Action: "set_header", Header: "Host", Value: "x"}, want: "cannot be changed"},
+		{step: RouteMiddleware{Action: "deny", Status: 302}, want: "4xx or 5xx"},
+		{step: RouteMiddleware{Action: "upstream"}, want: "upstream is required"},
+	}
+	for _, tc := range cases {
+		if _, err := normalizeRouteMiddleware([]RouteMiddleware{tc.step}); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("expected error containing %q, got %v", tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/httpx"
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+// maxInFlightMirrors caps concurrent shadow requests across all routes; when
+// every slot is busy new requests are simply not mirrored.
+const maxInFlightMirrors = 64
+
+type RouteUpstream struct {
+	Target        string `json:"target,omitempty"`
+	ConnectorID   string `json:"connector_id,omitempty"`
+	LocalScheme   string `json:"local_scheme,omitempty"`
+	LocalHost     string `json:"local_host,omitempty"`
+	LocalPort     int    `json:"local_port,omitempty"`
+	LocalBasePath string `json:"local_base_path,omitempty"`
+}
+
+// RouteMirror copies a share of a route's requests to a second upstream in
+// the background. Mirrored responses are discarded, so the secondary target
+// never affects what the client sees.
+type RouteMirror struct {
+	Percent float64 `json:"percent"`
+	RouteUpstream
+}
+
+func normalizeRouteUpstream(field string, input RouteUpstream) (RouteUpstream, error) {
+	upstream := RouteUpstream{
+		Target:      strings.TrimSpace(input.Target),
+		ConnectorID: normalizeIdentifier(input.ConnectorID),
+	}
+	if upstream.ConnectorID == "" {
+		parsed, err := url.Parse(upstream.Target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || strings.TrimSpace(parsed.Host) == "" {
+			return RouteUpstream{}, fmt.Errorf("%s.target must be an http or https URL with a host", field)
+		}
+		return upstream, nil
+	}
+	if upstream.Target != "" {
+		return RouteUpstream{}, fmt.Errorf("%s.target cannot be combined with %s.connector_id", field, field)
+	}
+	if !identifierPattern.MatchString(upstream.ConnectorID) {
+		return RouteUpstream{}, fmt.Errorf("invalid %s.connector_id %q", field, input.ConnectorID)
+	}
+	upstream.LocalScheme = strings.ToLower(strings.TrimSpace(input.LocalScheme))
+	if upstream.LocalScheme == "" {
+		upstream.LocalScheme = "http"
+	}
+	if upstream.LocalScheme != "http" && upstream.LocalScheme != "https" {
+		return RouteUpstream{}, fmt.Errorf("%s.local_scheme must be http or https", field)
+	}
+	upstream.LocalHost = strings.TrimSpace(input.LocalHost)
+	if upstream.LocalHost == "" {
+		upstream.LocalHost = "127.0.0.1"
+	}
+	if strings.Contains(upstream.LocalHost, "://") {
+		return RouteUpstream{}, fmt.Errorf("%s.local_host should not include scheme", field)
+	}
+	if input.LocalPort < 1 || input.LocalPort > 65535 {
+		return RouteUpstream{}, fmt.Errorf("%s.local_port must be between 1 and 65535 when %s.connector_id is set", field, field)
+	}
+	upstream.LocalPort = input.LocalPort
+	upstream.LocalBasePath = strings.TrimSpace(input.LocalBasePath)
+	if upstream.LocalBasePath != "" && !strings.HasPrefix(upstream.LocalBasePath, "/") {
+		upstream.LocalBasePath = "/" + upstream.LocalBasePath
+	}
+	return upstream, nil
+}
+
+func (u RouteUpstream) applyTo(rule Rule) Rule {
+	rule.Target = u.Target
+	rule.ConnectorID = u.ConnectorID
+	rule.ConnectorSelector = nil
+	rule.LocalScheme = u.LocalScheme
+	rule.LocalHost = u.LocalHost
+	rule.LocalPort = u.LocalPort
+	rule.LocalSocket = ""
+	rule.LocalTLS = nil
+	rule.LocalBasePath = u.LocalBasePath
+	rule.PathRoutes = nil
+	return rule
+}
+
+func normalizeRoutePercent(field string, percent float64) error {
+	if percent <= 0 || percent > 100 {
+		return fmt.Errorf("%s.percent must be greater than 0 and at most 100", field)
+	}
+	return nil
+}
+
+func samplePercent(percent float64) bool {
+	return percent >= 100 || rand.Float64()*100 < percent
+}
+
+func normalizeRouteMirror(input *RouteMirror) (*RouteMirror, error) {
+	if input == nil {
+		return nil, nil
+	}
+	if err := normalizeRoutePercent("mirror", input.Percent); err != nil {
+		return nil, err
+	}
+	upstream, err := normalizeRouteUpstream("mirror", input.RouteUpstream)
+	if err != nil {
+		return nil, err
+	}
+	return &RouteMirror{Percent: input.Percent, RouteUpstream: upstream}, nil
+}
+
+func (m *RouteMirror) sample() bool {
+	return m != nil && samplePercent(m.Percent)
+}
+
+// mirrorRequest sends a copy of proxyReq to the route's mirror target without
+// blocking the caller. It must be called before proxyReq is dispatched, while
+// Path is still the forwarded path.
+func (s *Server) mirrorRequest(rule Rule, proxyReq *protocol.ProxyRequest, timeout time.Duration) {
+	if !rule.Mirror.sample() {
+		return
+	}
+	select {
+	case s.mirrorSlots <- struct{}{}:
+	default:
+		return
+	}
+
+	target := rule.Mirror.applyTo(rule)
+	key := variantTunnelKey(rule.TenantID, rule.ID, routeVariantMirror)
+	shadow := *proxyReq
+	shadow.RequestID = proxyReq.RequestID + "-mirror"
+	shadow.Headers = httpx.CloneHTTPHeader(proxyReq.Headers)
+	shadow.Headers["X-Proxer-Mirror"] = []string{"1"}
+	shadow.Headers["X-Proxer-Request-ID"] = []string{shadow.RequestID}
+	if target.UsesConnector() {
+		shadow.TunnelID = key
+		shadow.ConnectorID = target.ConnectorID
+		shadow.LocalTarget = target.localTarget()
+		shadow.Path = joinWithBasePath(target.LocalBasePath, shadow.Path)
+	}
+
+	go func() {
+		defer func() { <-s.mirrorSlots }()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		if target.UsesConnector() {
+			// The hub records metrics for connector dispatches itself,
+			// except for requests it refuses before dispatch.
+			if _, err := s.hub.DispatchProxyRequestToConnector(ctx, target.ConnectorID, key, &shadow); errors.Is(err, ErrTargetUnhealthy) {
+				s.hub.RecordProxyFailure(key, int64(len(shadow.Body)), err.Error())
+			}
+			return
+		}
+
+		resp, err := s.forwardDirect(ctx, target, &shadow, nil)
+		if err != nil {
+			s.hub.RecordProxyFailure(key, int64(len(shadow.Body)), err.Error())
+			return
+		}
+		resp.TunnelID = key
+		s.hub.RecordProxyResponse(resp)
+	}()
+}
@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestNormalizeRouteMirror(t *testing.T) {
+	cases := []struct {
+		name  string
+		input RouteMirror
+		want  string
+	}{
+		{name: "percent", input: RouteMirror{Percent: 0, RouteUpstream: RouteUpstream{Target: "http://shadow.test"}}, want: "mirror.percent"},
+		{name: "target", input: RouteMirror{Percent: 10, RouteUpstream: RouteUpstream{Target: "shadow.test"}}, want: "mirror.target"},
+		{name: "both", input: RouteMirror{Percent: 10, RouteUpstream: RouteUpstream{Target: "http://shadow.test", ConnectorID: "c1", LocalPort: 3000}}, want: "cannot be combined"},
+		{name: "port", input: RouteMirror{Percent: 10, RouteUpstream: RouteUpstream{ConnectorID: "c1"}}, want: "mirror.local_port"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := normalizeRouteMirror(&tc.input); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got %v", tc.want, err)
+			}
+		})
+	}
+	mirror, err := normalizeRouteMirror(&RouteMirror{Percent: 25, RouteUpstream: RouteUpstream{ConnectorID: " c1 ", LocalPort: 3001, LocalBasePath: "v2"}})
+	if err != nil {
+		t.Fatalf("normalize mirror: %v", err)
+	}
+	if mirror.ConnectorID != "c1" || mirror.LocalHost != "127.0.0.1" || mirror.LocalScheme != "http" || mirror.LocalBasePath != "/v2" {
+		t.Fatalf("unexpected normalized mirror: %+v", mirror)
+	}
+}
+
+func TestProxyMirrorsRequestsWithoutAffectingResponse(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "primary")
+	}))
+	defer primary.Close()
+
+	mirrored := make(chan string, 1)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mirrored <- r.Method + " " + r.URL.Path + " " + string(body) + " " + r.Header.Get("X-Proxer-Mirror")
+		http.Error(w, "shadow is broken", http.StatusInternalServerError)
+	}))
+	defer shadow.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
+		ID:     "web",
+		Target: primary.URL,
+		Mirror: &RouteMirror{Percent: 100, RouteUpstream: RouteUpstream{Target: shadow.URL}},
+	}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodPost, "/t/web/orders", strings.NewReader("payload")))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "primary" {
+		t.Fatalf("mirror must not affect the primary response, got %d %q", recorder.Code, recorder.Body.String())
+	}
+	select {
+	case got := <-mirrored:
+		if got != "POST /orders payload 1" {
+			t.Fatalf("unexpected mirrored request %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("request was not mirrored")
+	}
+}
+
+func TestConnectorMirrorUsesItsOwnBasePath(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	sessions := make(map[string]string)
+	for _, connectorID := range []string{"laptop", "shadow"} {
+		registered, err := server.hub.RegisterConnectorSession(connectorID, "agent-"+connectorID)
+		if err != nil {
+			t.Fatalf("register connector session: %v", err)
+		}
+		sessions[connectorID] = registered.SessionID
+	}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
+		ID:            "web",
+		ConnectorID:   "laptop",
+		LocalPort:     3000,
+		LocalBasePath: "/app",
+		Mirror:        &RouteMirror{Percent: 100, RouteUpstream: RouteUpstream{ConnectorID: "shadow", LocalPort: 3001, LocalBasePath: "/v2"}},
+	}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		recorder := httptest.NewRecorder()
+		server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/web/orders", nil))
+		done <- recorder
+	}()
+	for connectorID, want := range map[string]string{"laptop": "/app/orders", "shadow": "/v2/orders"} {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		pulled, err := server.hub.PullRequest(ctx, sessions[connectorID])
+		cancel()
+		if err != nil {
+			t.Fatalf("pull %s request: %v", connectorID, err)
+		}
+		if pulled.Path != want {
+			t.Fatalf("expected %s to get %q, got %q", connectorID, want, pulled.Path)
+		}
+		if err := server.hub.SubmitProxyResponse(sessions[connectorID], &protocol.ProxyResponse{RequestID: pulled.RequestID, TunnelID: pulled.TunnelID, Status: http.StatusOK}); err != nil {
+			t.Fatalf("submit %s response: %v", connectorID, err)
+		}
+	}
+	if recorder := <-done; recorder.Code != http.StatusOK {
+		t.Fatalf("expected the primary response, got %d", recorder.Code)
+	}
+}
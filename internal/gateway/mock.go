@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const (
+	maxMockFiles     = 64
+	maxMockBodyBytes = 1 << 20
+)
+
+type RouteMock struct {
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Files   []RouteMockFile   `json:"files,omitempty"`
+}
+
+type RouteMockFile struct {
+	Path    string            `json:"path"`
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+func normalizeRouteMock(input *RouteMock) (*RouteMock, error) {
+	if input == nil {
+		return nil, nil
+	}
+	if len(input.Files) > maxMockFiles {
+		return nil, fmt.Errorf("mock.files supports at most %d entries", maxMockFiles)
+	}
+	status, err := normalizeMockStatus("mock.status", input.Status)
+	if err != nil {
+		return nil, err
+	}
+	headers, err := normalizeMockHeaders("mock.headers", input.Headers)
+	if err != nil {
+		return nil, err
+	}
+	mock := &RouteMock{Status: status, Headers: headers, Body: input.Body}
+	total := len(mock.Body)
+	seen := make(map[string]struct{}, len(input.Files))
+	for _, item := range input.Files {
+		path := strings.TrimSpace(item.Path)
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("mock.files path %q must start with /", item.Path)
+		}
+		if _, ok := seen[path]; ok {
+			return nil, fmt.Errorf("duplicate mock.files path %q", path)
+		}
+		seen[path] = struct{}{}
+		field := fmt.Sprintf("mock.files %q", path)
+		fileStatus, err := normalizeMockStatus(field+" status", item.Status)
+		if err != nil {
+			return nil, err
+		}
+		fileHeaders, err := normalizeMockHeaders(field+" headers", item.Headers)
+		if err != nil {
+			return nil, err
+		}
+		total += len(item.Body)
+		mock.Files = append(mock.Files, RouteMockFile{Path: path, Status: fileStatus, Headers: fileHeaders, Body: item.Body})
+	}
+	if total > maxMockBodyBytes {
+		return nil, fmt.Errorf("mock bodies exceed %d bytes in total", maxMockBodyBytes)
+	}
+	return mock, nil
+}
+
+func normalizeMockStatus(field string, status int) (int, error) {
+	if status == 0 {
+		return http.StatusOK, nil
+	}
+	if status < 200 || status > 599 {
+		return 0, fmt.Errorf("%s must be between 200 and 599", field)
+	}
+	return status, nil
+}
+
+func normalizeMockHeaders(field string, input map[string]string) (map[string]string, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	headers := make(map[string]string, len(input))
+	for name, value := range input {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if name == "" || strings.ContainsAny(name, " \t:") {
+			return nil, fmt.Errorf("%s has an invalid header name %q", field, name)
+		}
+		if name == "Content-Length" {
+			return nil, fmt.Errorf("%s cannot set Content-Length", field)
+		}
+		headers[name] = value
+	}
+	return headers, nil
+}
+
+func (m *RouteMock) respond(requestID, tunnelKey, path string) *protocol.ProxyResponse {
+	status, headers, body := m.Status, m.Headers, m.Body
+	for _, file := range m.Files {
+		if file.Path == path {
+			status, headers, body = file.Status, file.Headers, file.Body
+			break
+		}
+	}
+	responseHeaders := make(map[string][]string, len(headers)+1)
+	for name, value := range headers {
+		responseHeaders[name] = []string{value}
+	}
+	if _, ok := responseHeaders["Content-Type"]; !ok && body != "" {
+		responseHeaders["Content-Type"] = []string{http.DetectContentType([]byte(body))}
+	}
+	responseHeaders["X-Proxer-Mock"] = []string{"1"}
+	return &protocol.ProxyResponse{
+		RequestID: requestID,
+		TunnelID:  tunnelKey,
+		Status:    status,
+		Headers:   responseHeaders,
+		Body:      []byte(body),
+		BytesOut:  int64(len(body)),
+	}
+}
@@ -0,0 +1,56 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProxyServesRouteMock(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
+		ID: "soon",
+		Mock: &RouteMock{
+			Status: http.StatusServiceUnavailable,
+			Body:   "coming soon",
+			Files: []RouteMockFile{
+				{Path: "/health", Headers: map[string]string{"content-type": "application/json"}, Body: `{"ok":true}`},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("upsert mock route without target: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/soon/health", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != `{"ok":true}` || recorder.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected mock file response %d %q %q", recorder.Code, recorder.Body.String(), recorder.Header().Get("Content-Type"))
+	}
+
+	recorder = httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/soon/anything", nil))
+	if recorder.Code != http.StatusServiceUnavailable || recorder.Body.String() != "coming soon" || recorder.Header().Get("X-Proxer-Mock") != "1" {
+		t.Fatalf("unexpected default mock response %d %q", recorder.Code, recorder.Body.String())
+	}
+	if metric := server.hub.GetTunnelMetrics(MakeTunnelKey(DefaultTenantID, "soon")); metric.RequestCount != 2 {
+		t.Fatalf("expected mock responses to count in route metrics, got %d", metric.RequestCount)
+	}
+}
+
+func TestNormalizeRouteMockRejectsInvalidInput(t *testing.T) {
+	cases := []struct {
+		mock RouteMock
+		want string
+	}{
+		{mock: RouteMock{Status: 99}, want: "mock.status"},
+		{mock: RouteMock{Headers: map[string]string{"Content-Length": "1"}}, want: "Content-Length"},
+		{mock: RouteMock{Files: []RouteMockFile{{Path: "index.html"}}}, want: "must start with /"},
+		{mock: RouteMock{Body: strings.Repeat("x", maxMockBodyBytes+1)}, want: "exceed"},
+	}
+	for _, tc := range cases {
+		if _, err := normalizeRouteMock(&tc.mock); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("expected error containing %q, got %v", tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var defaultReservedNames = []string{
+	"admin", "api", "assets", "auth", "billing", "dashboard", "health",
+	"login", "logout", "proxer", "register", "root", "signup", "static",
+	"status", "support", "system", "www",
+}
+
+type NamePolicy struct {
+	reserved map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+func NewNamePolicy(reserved, patterns []string) (*NamePolicy, error) {
+	if reserved == nil {
+		reserved = defaultReservedNames
+	}
+	policy := &NamePolicy{
+		reserved: make(map[string]struct{}, len(reserved)),
+		patterns: make([]*regexp.Regexp, 0, len(patterns)),
+	}
+	for _, name := range reserved {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			policy.reserved[name] = struct{}{}
+		}
+	}
+	for _, raw := range patterns {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		pattern, err := regexp.Compile("(?i)" + raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocked name pattern %q: %w", raw, err)
+		}
+		policy.patterns = append(policy.patterns, pattern)
+	}
+	return policy, nil
+}
+
+func (p *NamePolicy) Check(name string) error {
+	if p == nil {
+		return nil
+	}
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	if _, ok := p.reserved[normalized]; ok {
+		return fmt.Errorf("name %q is reserved", name)
+	}
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(normalized) {
+			return fmt.Errorf("name %q is not allowed", name)
+		}
+	}
+	return nil
+}
+
+func (p *NamePolicy) Allowed(name string) bool {
+	return p.Check(name) == nil
+}
@@ -0,0 +1,29 @@
+package gateway
+
+import "testing"
+
+func TestRuleStoreRejectsReservedAndBlockedRouteNames(t *testing.T) {
+	policy, err := NewNamePolicy(nil, []string{"^bad", "scam"})
+	if err != nil {
+		t.Fatalf("build name policy: %v", err)
+	}
+	store := NewRuleStore()
+	for _, routeID := range []string{"admin", "API", "bad-word", "free-scam-site"} {
+		if _, err := store.UpsertForTenant(DefaultTenantID, Rule{ID: routeID, Target: "http://127.0.0.1:3000"}); err != nil {
+			t.Fatalf("expected %q to be accepted before policy is set: %v", routeID, err)
+		}
+	}
+
+	store.SetNamePolicy(policy)
+	for _, routeID := range []string{"login", "Status", "bad-idea", "my-scam"} {
+		if _, err := store.UpsertForTenant(DefaultTenantID, Rule{ID: routeID, Target: "http://127.0.0.1:3000"}); err == nil {
+			t.Fatalf("expected %q to be rejected", routeID)
+		}
+	}
+	if _, err := store.UpsertForTenant(DefaultTenantID, Rule{ID: "admin", Target: "http://127.0.0.1:4000"}); err != nil {
+		t.Fatalf("expected existing route to remain editable: %v", err)
+	}
+	if _, err := store.UpsertForTenant(DefaultTenantID, Rule{ID: "webapp", Target: "http://127.0.0.1:3000"}); err != nil {
+		t.Fatalf("expected unreserved route to be accepted: %v", err)
+	}
+}
@@ -0,0 +1,385 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	notifyModeOff       = "off"
+	notifyModeImmediate = "immediate"
+	notifyModeDigest    = "digest"
+
+	notificationDigestInterval = 24 * time.Hour
+	// maxDigestEvents bounds what one user's digest holds; older events are
+	// dropped first.
+	maxDigestEvents = 200
+)
+
+var notificationEventTypes = []string{
+	"connector.secret_expiring",
+	"plan.changed",
+	"route.check_failed",
+	"route.check_recovered",
+	"tls.expiring",
+	"usage.threshold",
+}
+
+type NotificationPreferences struct {
+	Email      string    `json:"email"`
+	Mode       string    `json:"mode"`
+	EventTypes []string  `json:"event_types"`
+	UpdatedAt  time.Time `json:"updated_at,omitempty"`
+}
+
+type notificationSubscriber struct {
+	User             User
+	Preferences      NotificationPreferences
+	UnsubscribeToken string
+}
+
+type mailMessage struct {
+	To             string
+	Subject        string
+	Body           string
+	UnsubscribeURL string
+}
+
+type mailSender func(mailMessage) error
+
+type userNotifier struct {
+	mu      sync.Mutex
+	send    mailSender
+	pending map[string][]WebhookEvent
+}
+
+func newUserNotifier(send mailSender) *userNotifier {
+	return &userNotifier{send: send, pending: make(map[string][]WebhookEvent)}
+}
+
+func (n *userNotifier) queue(username string, event WebhookEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	events := append(n.pending[username], event)
+	if len(events) > maxDigestEvents {
+		events = events[len(events)-maxDigestEvents:]
+	}
+	n.pending[username] = events
+}
+
+func (n *userNotifier) drain() map[string][]WebhookEvent {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	pending := n.pending
+	n.pending = make(map[string][]WebhookEvent)
+	return pending
+}
+
+func normalizeNotificationPreferences(prefs NotificationPreferences) (NotificationPreferences, error) {
+	prefs.Email = strings.TrimSpace(prefs.Email)
+	prefs.Mode = strings.ToLower(strings.TrimSpace(prefs.Mode))
+	if prefs.Mode == "" {
+		prefs.Mode = notifyModeOff
+	}
+	if prefs.Mode != notifyModeOff && prefs.Mode != notifyModeImmediate && prefs.Mode != notifyModeDigest {
+		return NotificationPreferences{}, fmt.Errorf("mode must be %q, %q or %q", notifyModeImmediate, notifyModeDigest, notifyModeOff)
+	}
+	if prefs.Email != "" {
+		address, err := mail.ParseAddress(prefs.Email)
+		if err != nil {
+			return NotificationPreferences{}, fmt.Errorf("invalid email %q", prefs.Email)
+		}
+		prefs.Email = address.Address
+	} else if prefs.Mode != notifyModeOff {
+		return NotificationPreferences{}, fmt.Errorf("email is required to receive notifications")
+	}
+	eventTypes := make([]string, 0, len(prefs.EventTypes))
+	for _, eventType := range prefs.EventTypes {
+		eventType = strings.TrimSpace(eventType)
+		if !slices.Contains(notificationEventTypes, eventType) {
+			return NotificationPreferences{}, fmt.Errorf("unknown event type %q", eventType)
+		}
+		if !slices.Contains(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	sort.Strings(eventTypes)
+	prefs.EventTypes = eventTypes
+	return prefs, nil
+}
+
+func (p NotificationPreferences) wants(eventType string) bool {
+	return len(p.EventTypes) == 0 || slices.Contains(p.EventTypes, eventType)
+}
+
+func (s *AuthStore) NotificationPreferences(username string) (NotificationPreferences, bool) {
+	username = normalizeUsername(username)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, ok := s.users[username]
+	if !ok {
+		return NotificationPreferences{}, false
+	}
+	prefs := record.notifications
+	if prefs.Mode == "" {
+		prefs.Mode = notifyModeOff
+	}
+	if prefs.EventTypes == nil {
+		prefs.EventTypes = []string{}
+	}
+	return prefs, true
+}
+
+func (s *AuthStore) SetNotificationPreferences(username string, prefs NotificationPreferences) (NotificationPreferences, error) {
+	prefs, err := normalizeNotificationPreferences(prefs)
+	if err != nil {
+		return NotificationPreferences{}, err
+	}
+	username = normalizeUsername(username)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.users[username]
+	if !ok {
+		return NotificationPreferences{}, fmt.Errorf("user %q not found", username)
+	}
+	if record.unsubscribeToken == "" {
+		token, err := randomToken(24)
+		if err != nil {
+			return NotificationPreferences{}, err
+		}
+		record.unsubscribeToken = token
+	}
+	prefs.UpdatedAt = time.Now().UTC()
+	record.notifications = prefs
+	s.users[username] = record
+	return prefs, nil
+}
+
+func (s *AuthStore) Unsubscribe(token string) (string, bool) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for username, record := range s.users {
+		if record.unsubscribeToken != token {
+			continue
+		}
+		record.notifications.Mode = notifyModeOff
+		record.notifications.UpdatedAt = time.Now().UTC()
+		s.users[username] = record
+		return username, true
+	}
+	return "", false
+}
+
+func (s *AuthStore) NotificationSubscribers() []notificationSubscriber {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	subscribers := make([]notificationSubscriber, 0)
+	for _, record := range s.users {
+		if record.user.Status != "active" || record.notifications.Mode == "" || record.notifications.Mode == notifyModeOff {
+			continue
+		}
+		subscribers = append(subscribers, notificationSubscriber{
+			User:             record.user,
+			Preferences:      record.notifications,
+			UnsubscribeToken: record.unsubscribeToken,
+		})
+	}
+	sort.Slice(subscribers, func(i, j int) bool { return subscribers[i].User.Username < subscribers[j].User.Username })
+	return subscribers
+}
+
+func (s *Server) notificationRecipient(user User, event WebhookEvent) bool {
+	if event.TenantID == "" {
+		return s.isSuperAdmin(user)
+	}
+	if s.isOrgAdmin(user) {
+		return s.inUserOrg(user, event.TenantID)
+	}
+	return !s.isSuperAdmin(user) && normalizeIdentifier(user.TenantID) == event.TenantID
+}
+
+func (s *Server) notifyUsers(event WebhookEvent) {
+	if s.config().SMTPAddr == "" {
+		return
+	}
+	for _, subscriber := range s.authStore.NotificationSubscribers() {
+		if !subscriber.Preferences.wants(event.Type) || !s.notificationRecipient(subscriber.User, event) {
+			continue
+		}
+		if subscriber.Preferences.Mode == notifyModeDigest {
+			s.notifier.queue(subscriber.User.Username, event)
+			continue
+		}
+		message := mailMessage{
+			To:             subscriber.Preferences.Email,
+			Subject:        fmt.Sprintf("[proxer] %s", notificationTitle(event)),
+			Body:           formatNotificationEvent(event),
+			UnsubscribeURL: s.unsubscribeURL(subscriber.UnsubscribeToken),
+		}
+		go s.deliverNotification(message)
+	}
+}
+
+func (s *Server) sendNotificationDigests() {
+	pending := s.notifier.drain()
+	if len(pending) == 0 {
+		return
+	}
+	for _, subscriber := range s.authStore.NotificationSubscribers() {
+		events := pending[subscriber.User.Username]
+		if len(events) == 0 || subscriber.Preferences.Mode != notifyModeDigest {
+			continue
+		}
+		var body strings.Builder
+		fmt.Fprintf(&body, "%d events since the last digest.\n", len(events))
+		for _, event := range events {
+			body.WriteString("\n")
+			body.WriteString(formatNotificationEvent(event))
+		}
+		s.deliverNotification(mailMessage{
+			To:             subscriber.Preferences.Email,
+			Subject:        fmt.Sprintf("[proxer] Daily digest: %d events", len(events)),
+			Body:           body.String(),
+			UnsubscribeURL: s.unsubscribeURL(subscriber.UnsubscribeToken),
+		})
+	}
+}
+
+func (s *Server) runNotificationDigestLoop(ctx context.Context) {
+	ticker := time.NewTicker(notificationDigestInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sendNotificationDigests()
+		}
+	}
+}
+
+func (s *Server) deliverNotification(message mailMessage) {
+	if err := s.notifier.send(message); err != nil {
+		s.logger.Printf("notification email to %s failed: %v", message.To, err)
+		s.incidentStore.Add("warning", "notifications", fmt.Sprintf("notification email delivery failed: %v", err))
+	}
+}
+
+func (s *Server) unsubscribeURL(token string) string {
+	return strings.TrimRight(s.config().PublicBaseURL, "/") + "/api/public/unsubscribe?token=" + url.QueryEscape(token)
+}
+
+func (s *Server) sendSMTPMail(message mailMessage) error {
+	cfg := s.config()
+	if cfg.SMTPAddr == "" {
+		return fmt.Errorf("smtp is not configured")
+	}
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		host, _, _ := strings.Cut(cfg.SMTPAddr, ":")
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, host)
+	}
+	var raw strings.Builder
+	fmt.Fprintf(&raw, "From: %s\r\n", cfg.SMTPFrom)
+	fmt.Fprintf(&raw, "To: %s\r\n", message.To)
+	fmt.Fprintf(&raw, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&raw, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	raw.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n")
+	if message.UnsubscribeURL != "" {
+		fmt.Fprintf(&raw, "List-Unsubscribe: <%s>\r\n", message.UnsubscribeURL)
+		raw.WriteString("List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+	}
+	raw.WriteString("\r\n")
+	raw.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	if message.UnsubscribeURL != "" {
+		fmt.Fprintf(&raw, "\r\n\r\nTo stop these emails, open %s\r\n", message.UnsubscribeURL)
+	}
+	return smtp.SendMail(cfg.SMTPAddr, auth, cfg.SMTPFrom, []string{message.To}, []byte(raw.String()))
+}
+
+func notificationTitle(event WebhookEvent) string {
+	if event.TenantID == "" {
+		return event.Type
+	}
+	return fmt.Sprintf("%s for tenant %s", event.Type, event.TenantID)
+}
+
+func formatNotificationEvent(event WebhookEvent) string {
+	data, err := json.MarshalIndent(event.Data, "", "  ")
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", event.Data))
+	}
+	return fmt.Sprintf("%s at %s\n%s\n", notificationTitle(event), event.CreatedAt.Format(time.RFC3339), data)
+}
+
+func (s *Server) handleMeNotifications(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		prefs, ok := s.authStore.NotificationPreferences(user.Username)
+		if !ok {
+			writeAPIError(w, http.StatusNotFound, errCodeNotFound, "user not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"preferences":       prefs,
+			"event_types":       notificationEventTypes,
+			"delivery_disabled": s.config().SMTPAddr == "",
+		})
+	case http.MethodPut:
+		var request NotificationPreferences
+		if !s.decodeJSON(w, r, &request, "notification preferences payload") {
+			return
+		}
+		prefs, err := s.authStore.SetNotificationPreferences(user.Username, request)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		s.auditStore.Record(user.Username, "notifications.update", user.TenantID, map[string]string{
+			"mode":        prefs.Mode,
+			"event_types": strings.Join(prefs.EventTypes, ","),
+		})
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message":     "notification preferences updated",
+			"preferences": prefs,
+		})
+		s.persistState()
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handlePublicUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	username, ok := s.authStore.Unsubscribe(r.URL.Query().Get("token"))
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "unsubscribe link is invalid")
+		return
+	}
+	s.auditStore.Record(username, "notifications.unsubscribe", "", nil)
+	writeJSON(w, http.StatusOK, map[string]any{"message": "you will no longer receive notification emails"})
+	s.persistState()
+}
@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotificationPreferencesDigestsAndUnsubscribe(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", SMTPAddr: "smtp.test:25", PublicBaseURL: "https://proxer.test"}, nil)
+	sent := make(chan mailMessage, 16)
+	server.notifier.send = func(message mailMessage) error {
+		sent <- message
+		return nil
+	}
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	for _, username := range []string{"alice", "bob"} {
+		if _, err := server.authStore.RegisterUser(RegisterUserInput{Username: username, Password: "secret123", TenantID: DefaultTenantID, Role: RoleMember}); err != nil {
+			t.Fatalf("register %s: %v", username, err)
+		}
+	}
+	call := func(username, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if username != "" {
+			session, err := server.authStore.NewSession(username)
+			if err != nil {
+				t.Fatalf("create session: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+session)
+		}
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	if recorder := call("alice", http.MethodPut, "/api/me/notifications", `{"email":"alice@example.com","mode":"immediate","event_types":["plan.changed"]}`); recorder.Code != http.StatusOK {
+		t.Fatalf("set alice preferences: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call("bob", http.MethodPut, "/api/me/notifications", `{"email":"bob@example.com","mode":"digest"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("set bob preferences: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call("bob", http.MethodPut, "/api/me/notifications", `{"mode":"immediate"}`); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected notifications without an email to be refused, got %d", recorder.Code)
+	}
+	if recorder := call("bob", http.MethodPut, "/api/me/notifications", `{"email":"bob@example.com","mode":"digest","event_types":["nope"]}`); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected unknown event types to be refused, got %d", recorder.Code)
+	}
+
+	server.webhooks.Emit("plan.changed", DefaultTenantID, map[string]string{"plan_id": "pro"})
+	server.webhooks.Emit("usage.threshold", DefaultTenantID, map[string]int{"percent": 80})
+	server.webhooks.Emit("plan.changed", "other", map[string]string{"plan_id": "pro"})
+
+	var immediate mailMessage
+	select {
+	case immediate = <-sent:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected an immediate email")
+	}
+	if immediate.To != "alice@example.com" || !strings.Contains(immediate.Subject, "plan.changed") || !strings.HasPrefix(immediate.UnsubscribeURL, "https://proxer.test/api/public/unsubscribe?token=") {
+		t.Fatalf("unexpected immediate email %+v", immediate)
+	}
+
+	server.sendNotificationDigests()
+	var digest mailMessage
+	select {
+	case digest = <-sent:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected a digest email")
+	}
+	if digest.To != "bob@example.com" || !strings.Contains(digest.Subject, "2 events") || !strings.Contains(digest.Body, "usage.threshold") {
+		t.Fatalf("unexpected digest %+v", digest)
+	}
+	select {
+	case extra := <-sent:
+		t.Fatalf("unexpected extra email %+v", extra)
+	default:
+	}
+
+	parsed, err := url.Parse(immediate.UnsubscribeURL)
+	if err != nil {
+		t.Fatalf("parse unsubscribe url: %v", err)
+	}
+	if recorder := call("", http.MethodPost, parsed.RequestURI(), ""); recorder.Code != http.StatusOK {
+		t.Fatalf("unsubscribe: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call("", http.MethodGet, "/api/public/unsubscribe?token=wrong", ""); recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected an unknown token to be refused, got %d", recorder.Code)
+	}
+	if prefs, _ := server.authStore.NotificationPreferences("alice"); prefs.Mode != notifyModeOff || prefs.Email != "alice@example.com" {
+		t.Fatalf("expected alice to be unsubscribed, got %+v", prefs)
+	}
+	server.webhooks.Emit("plan.changed", DefaultTenantID, nil)
+	select {
+	case extra := <-sent:
+		t.Fatalf("expected no email after unsubscribing, got %+v", extra)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	restored, err := NewAuthStore("admin", "admin123", time.Hour)
+	if err != nil {
+		t.Fatalf("create auth store: %v", err)
+	}
+	restored.RestoreUsers(server.authStore.SnapshotUsers())
+	if prefs, _ := restored.NotificationPreferences("bob"); prefs.Mode != notifyModeDigest || prefs.Email != "bob@example.com" {
+		t.Fatalf("expected preferences to survive a restore, got %+v", prefs)
+	}
+	if username, ok := restored.Unsubscribe(parsed.Query().Get("token")); !ok || username != "alice" {
+		t.Fatalf("expected the unsubscribe token to survive a restore, got %q %v", username, ok)
+	}
+}
@@ -0,0 +1,681 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type apiAccess string
+
+const (
+	apiAccessPublic     apiAccess = "public"
+	apiAccessSession    apiAccess = "session"
+	apiAccessSuperAdmin apiAccess = "super_admin"
+)
+
+type apiOperation struct {
+	Method   string
+	Path     string
+	Tag      string
+	Summary  string
+	Access   apiAccess
+	Query    []string
+	Request  any
+	Response any
+	Status   int
+	Errors   []apiErrorCode
+}
+
+type apiObject map[string]any
+
+var listQueryParams = []string{"limit", "cursor", "sort"}
+
+// managementAPI lists the console and management endpoints. Every route
+// registered by registerConsoleRoutes under /api/ must be covered here;
+// TestManagementAPICoversConsoleRoutes enforces it.
+var managementAPI = []apiOperation{
+	{Method: http.MethodGet, Path: "/api/health", Tag: "system", Summary: "Gateway health", Access: apiAccessPublic,
+		Response: apiObject{"status": "", "transport": "", "tunnel_count": 0, "storage": apiObject{}, "timestamp": ""}},
+	{Method: http.MethodGet, Path: "/api/openapi.json", Tag: "system", Summary: "This OpenAPI document", Access: apiAccessPublic,
+		Response: apiObject{}},
+	{Method: http.MethodGet, Path: "/api/docs", Tag: "system", Summary: "Swagger UI for this document", Access: apiAccessSuperAdmin},
+
+	{Method: http.MethodPost, Path: "/api/auth/login", Tag: "auth", Summary: "Start a console session", Access: apiAccessPublic,
+		Request: loginRequest{}, Response: apiObject{"message": "", "user": User{}},
+		Errors: []apiErrorCode{errCodeInvalidCredentials, errCodeRateLimited}},
+	{Method: http.MethodPost, Path: "/api/auth/logout", Tag: "auth", Summary: "End the current session", Access: apiAccessSession,
+		Response: apiObject{"message": ""}},
+	{Method: http.MethodGet, Path: "/api/auth/me", Tag: "auth", Summary: "Current user and visible tenants; impersonation sessions include impersonation", Access: apiAccessSession,
+		Response: apiObject{"user": User{}, "tenants": []tenantView{}, "impersonation": &Impersonation{}}},
+	{Method: http.MethodPost, Path: "/api/auth/register", Tag: "auth", Summary: "Register a member user, creating the tenant if needed", Access: apiAccessPublic,
+		Request: registerRequest{}, Response: apiObject{"message": "", "user": User{}}, Status: http.StatusCreated,
+		Errors: []apiErrorCode{errCodeUsernameTaken, errCodeRateLimited}},
+
+	{Method: http.MethodGet, Path: "/api/events", Tag: "events", Summary: "Server-sent console events", Access: apiAccessSession, Query: []string{"tenant"},
+		Errors: []apiErrorCode{errCodeTenantAccessDenied}},
+	{Method: http.MethodGet, Path: "/api/public/plans", Tag: "public", Summary: "Plans offered at signup", Access: apiAccessPublic,
+		Response: apiObject{"plans": []publicPlanView{}}},
+	{Method: http.MethodGet, Path: "/api/public/downloads", Tag: "public", Summary: "Agent download links per OS and arch with SHA-256 sums and the one recommended for the caller", Access: apiAccessPublic, Query: []string{"channel", "platform", "arch"},
+		Response: PublicDownloadsResponse{}},
+	{Method: http.MethodPost, Path: "/api/public/signup", Tag: "public", Summary: "Self-serve signup", Access: apiAccessPublic,
+		Request:  publicSignupRequest{},
+		Response: apiObject{"message": "", "user": User{}, "tenant": Tenant{}, "assignment": TenantPlanAssignment{}, "redirect": ""}, Status: http.StatusCreated,
+		Errors: []apiErrorCode{errCodeSignupDisabled, errCodeUsernameTaken, errCodeRateLimited}},
+	{Method: http.MethodPost, Path: "/api/public/events", Tag: "public", Summary: "Record a funnel analytics event", Access: apiAccessPublic,
+		Request: funnelEventInput{}, Response: apiObject{"message": ""}, Status: http.StatusAccepted},
+	{Method: http.MethodGet, Path: "/api/public/unsubscribe", Tag: "public", Summary: "Turn notification emails off from an email link", Access: apiAccessPublic, Query: []string{"token"},
+		Response: apiObject{"message": ""}, Errors: []apiErrorCode{errCodeNotFound}},
+	{Method: http.MethodPost, Path: "/api/public/unsubscribe", Tag: "public", Summary: "One-click unsubscribe from notification emails", Access: apiAccessPublic, Query: []string{"token"},
+		Response: apiObject{"message": ""}, Errors: []apiErrorCode{errCodeNotFound}},
+
+	{Method: http.MethodGet, Path: "/api/me/dashboard", Tag: "me", Summary: "Dashboard summary for the caller's tenant", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "plan": apiObject{"id": "", "name": ""}, "gauges": map[string]usageGauge{}, "usage": UsageSnapshot{},
+			"latency": LatencyPercentiles{}, "routes": []routeView{}, "connectors": []connectorView{}}},
+	{Method: http.MethodGet, Path: "/api/me/routes", Tag: "me", Summary: "Routes of the caller's tenant", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "routes": []routeView{}}},
+	{Method: http.MethodGet, Path: "/api/me/connectors", Tag: "me", Summary: "Connectors visible to the caller", Access: apiAccessSession,
+		Response: apiObject{"connectors": []connectorView{}}},
+	{Method: http.MethodGet, Path: "/api/me/usage", Tag: "me", Summary: "Current-month usage of the caller's tenant with daily per-route and per-connector transfer; super admins get every tenant under tenants", Access: apiAccessSession, Query: []string{"days"},
+		Response: apiObject{"tenant_id": "", "plan_id": "", "plan": Plan{}, "usage": UsageSnapshot{}, "transfer": TransferSummary{}}},
+	{Method: http.MethodGet, Path: "/api/me/plan", Tag: "me", Summary: "Current plan and self-serve alternatives", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "plan_id": "", "plan": Plan{}, "available_plans": []Plan{}, "assignment": TenantPlanAssignment{}}},
+	{Method: http.MethodPost, Path: "/api/me/plan", Tag: "me", Summary: "Change to a self-serve plan", Access: apiAccessSession,
+		Request: changePlanRequest{}, Response: apiObject{"message": "", "assignment": TenantPlanAssignment{}, "plan": Plan{}},
+		Errors: []apiErrorCode{errCodeTenantAdminRequired, errCodePlanNotFound, errCodePlanNotAvailable, errCodeConflict}},
+	{Method: http.MethodGet, Path: "/api/me/notifications", Tag: "me", Summary: "Caller's notification email preferences", Access: apiAccessSession,
+		Response: apiObject{"preferences": NotificationPreferences{}, "event_types": []string{}, "delivery_disabled": false}},
+	{Method: http.MethodPut, Path: "/api/me/notifications", Tag: "me", Summary: "Set notification email preferences (immediate, digest or off)", Access: apiAccessSession,
+		Request: NotificationPreferences{}, Response: apiObject{"message": "", "preferences": NotificationPreferences{}}},
+
+	{Method: http.MethodGet, Path: "/api/admin/users", Tag: "admin", Summary: "List users", Access: apiAccessSuperAdmin, Query: listQueryParams,
+		Response: apiObject{"users": []User{}, "total": 0, "next_cursor": ""}},
+	{Method: http.MethodPost, Path: "/api/admin/users", Tag: "admin", Summary: "Create a user", Access: apiAccessSuperAdmin,
+		Request: adminCreateUserRequest{}, Response: apiObject{"message": "", "user": User{}}, Status: http.StatusCreated,
+		Errors: []apiErrorCode{errCodeTenantNotFound}},
+	{Method: http.MethodPatch, Path: "/api/admin/users/{username}", Tag: "admin", Summary: "Update a user's role, tenant, status or password", Access: apiAccessSuperAdmin,
+		Request: adminUpdateUserRequest{}, Response: apiObject{"message": "", "user": User{}},
+		Errors: []apiErrorCode{errCodeTenantNotFound, errCodeNotFound}},
+	{Method: http.MethodPost, Path: "/api/admin/users/{username}/impersonate", Tag: "admin", Summary: "Open a time-limited session as a tenant user", Access: apiAccessSuperAdmin,
+		Request: impersonateRequest{}, Response: apiObject{"message": "", "session_id": "", "user": User{}, "impersonation": Impersonation{}}, Status: http.StatusCreated,
+		Errors: []apiErrorCode{errCodeNotFound}},
+	{Method: http.MethodGet, Path: "/api/admin/stats", Tag: "admin", Summary: "Gateway-wide counts and hub status", Access: apiAccessSuperAdmin,
+		Response: apiObject{"generated_at": "", "user_count": 0, "tenant_count": 0, "route_count": 0, "connector_count": 0, "active_connectors": 0, "transfer": TransferSummary{}, "system": HubStatus{}}},
+	{Method: http.MethodGet, Path: "/api/admin/incidents", Tag: "admin", Summary: "Recent system incidents", Access: apiAccessSuperAdmin, Query: []string{"limit"},
+		Response: apiObject{"incidents": []SystemIncident{}}},
+	{Method: http.MethodGet, Path: "/api/admin/audit", Tag: "admin", Summary: "Audit log", Access: apiAccessSuperAdmin, Query: []string{"tenant_id", "limit"},
+		Response: apiObject{"events": []AuditEvent{}}},
+	{Method: http.MethodGet, Path: "/api/admin/backup", Tag: "admin", Summary: "Download a state archive", Access: apiAccessSuperAdmin},
+	{Method: http.MethodPost, Path: "/api/admin/restore", Tag: "admin", Summary: "Restore a state archive", Access: apiAccessSuperAdmin,
+		Response: apiObject{"message": "", "manifest": BackupManifest{}},
+		Errors:   []apiErrorCode{errCodeInvalidRequest, errCodePayloadTooLarge}},
+	{Method: http.MethodPost, Path: "/api/admin/config/reload", Tag: "admin", Summary: "Reload the gateway config file", Access: apiAccessSuperAdmin,
+		Response: ConfigReloadResult{},
+		Errors:   []apiErrorCode{errCodeInvalidRequest, errCodeNotImplemented}},
+	{Method: http.MethodGet, Path: "/api/admin/ip-bans", Tag: "admin", Summary: "List IP bans", Access: apiAccessSuperAdmin,
+		Response: apiObject{"bans": []IPBan{}}},
+	{Method: http.MethodPost, Path: "/api/admin/ip-bans", Tag: "admin", Summary: "Ban an IP", Access: apiAccessSuperAdmin,
+		Request: adminBanIPRequest{}, Response: apiObject{"message": "", "ban": IPBan{}}, Status: http.StatusCreated},
+	{Method: http.MethodDelete, Path: "/api/admin/ip-bans", Tag: "admin", Summary: "Clear all IP bans", Access: apiAccessSuperAdmin,
+		Response: apiObject{"message": "", "cleared": 0}},
+	{Method: http.MethodDelete, Path: "/api/admin/ip-bans/{ip}", Tag: "admin", Summary: "Lift an IP ban", Access: apiAccessSuperAdmin,
+		Errors: []apiErrorCode{errCodeNotFound}},
+	{Method: http.MethodGet, Path: "/api/admin/system-status", Tag: "admin", Summary: "Gateway, storage and hub status", Access: apiAccessSuperAdmin,
+		Response: apiObject{"gateway": apiObject{"status": "", "listen_addr": "", "public_base_url": "", "uptime_seconds": 0},
+			"agents": apiObject{"min_agent_version": "", "min_protocol_version": 0, "outdated_connectors": []outdatedConnector{}}}},
+	{Method: http.MethodGet, Path: "/api/admin/analytics/funnel", Tag: "admin", Summary: "Signup funnel analytics", Access: apiAccessSuperAdmin,
+		Response: apiObject{"totals": map[string]int{}, "by_day": []any{}, "recent": []any{}}},
+	{Method: http.MethodGet, Path: "/api/admin/plans", Tag: "admin", Summary: "List plans", Access: apiAccessSuperAdmin,
+		Response: apiObject{"plans": []Plan{}}},
+	{Method: http.MethodPost, Path: "/api/admin/plans", Tag: "admin", Summary: "Create or replace a plan", Access: apiAccessSuperAdmin, Query: []string{"dry_run"},
+		Request: planUpsertRequest{}, Response: apiObject{"message": "", "plan": Plan{}}, Status: http.StatusCreated},
+	{Method: http.MethodPatch, Path: "/api/admin/plans/{planId}", Tag: "admin", Summary: "Update a plan", Access: apiAccessSuperAdmin, Query: []string{"dry_run"},
+		Request: planUpsertRequest{}, Response: apiObject{"message": "", "plan": Plan{}},
+		Errors: []apiErrorCode{errCodePlanNotFound}},
+	{Method: http.MethodGet, Path: "/api/admin/plans/{planId}/versions", Tag: "admin", Summary: "Plan versions with the tenants on each", Access: apiAccessSuperAdmin,
+		Response: apiObject{"plan_id": "", "current_version": 0, "versions": []PlanVersionTenants{}},
+		Errors:   []apiErrorCode{errCodePlanNotFound}},
+	{Method: http.MethodPost, Path: "/api/admin/plans/{planId}/migrate", Tag: "admin", Summary: "Move grandfathered tenants to the plan's current version", Access: apiAccessSuperAdmin,
+		Request: migratePlanRequest{}, Response: apiObject{"message": "", "plan_id": "", "migrated": []TenantPlanAssignment{}},
+		Errors: []apiErrorCode{errCodePlanNotFound}},
+	{Method: http.MethodPost, Path: "/api/admin/tenants/{tenantId}/assign-plan", Tag: "admin", Summary: "Assign a plan to a tenant", Access: apiAccessSuperAdmin,
+		Request: assignTenantPlanRequest{}, Response: apiObject{"message": "", "assignment": TenantPlanAssignment{}},
+		Errors: []apiErrorCode{errCodeTenantNotFound, errCodePlanNotFound}},
+	{Method: http.MethodPost, Path: "/api/admin/tenants/{tenantId}/suspend", Tag: "admin", Summary: "Suspend a tenant: its routes answer 403 or 451 and its agents are refused", Access: apiAccessSuperAdmin,
+		Request: suspendTenantRequest{}, Response: apiObject{"message": "", "tenant": tenantView{}, "details": map[string]string{}},
+		Errors: []apiErrorCode{errCodeTenantNotFound, errCodeConflict}},
+	{Method: http.MethodPost, Path: "/api/admin/tenants/{tenantId}/archive", Tag: "admin", Summary: "Archive a suspended tenant, moving its routes, connectors and domains out of the gateway", Access: apiAccessSuperAdmin,
+		Response: apiObject{"message": "", "tenant": tenantView{}, "details": map[string]string{}},
+		Errors:   []apiErrorCode{errCodeTenantNotFound, errCodeConflict}},
+	{Method: http.MethodGet, Path: "/api/admin/tenants/{tenantId}/archive", Tag: "admin", Summary: "Export the archive of an archived tenant", Access: apiAccessSuperAdmin,
+		Response: TenantArchive{}, Errors: []apiErrorCode{errCodeTenantNotFound, errCodeNotFound}},
+	{Method: http.MethodPost, Path: "/api/admin/tenants/{tenantId}/reactivate", Tag: "admin", Summary: "Reactivate a suspended or archived tenant, restoring its archive", Access: apiAccessSuperAdmin,
+		Response: apiObject{"message": "", "tenant": tenantView{}, "details": map[string]string{}},
+		Errors:   []apiErrorCode{errCodeTenantNotFound, errCodeConflict}},
+	{Method: http.MethodGet, Path: "/api/admin/tls/certificates", Tag: "admin", Summary: "List TLS certificates", Access: apiAccessSuperAdmin,
+		Response: apiObject{"certificates": []TLSCertificate{}}},
+	{Method: http.MethodPost, Path: "/api/admin/tls/certificates", Tag: "admin", Summary: "Add or replace a TLS certificate", Access: apiAccessSuperAdmin,
+		Request: TLSCertificateInput{}, Response: apiObject{"message": "", "certificate": TLSCertificate{}}, Status: http.StatusCreated,
+		Errors: []apiErrorCode{errCodePlanFeatureUnavailable}},
+	{Method: http.MethodPatch, Path: "/api/admin/tls/certificates/{certificateId}", Tag: "admin", Summary: "Update a TLS certificate", Access: apiAccessSuperAdmin,
+		Request: patchTLSCertificateRequest{}, Response: apiObject{"message": "", "certificate": TLSCertificate{}},
+		Errors: []apiErrorCode{errCodeNotFound, errCodePlanFeatureUnavailable}},
+	{Method: http.MethodDelete, Path: "/api/admin/tls/certificates/{certificateId}", Tag: "admin", Summary: "Delete a TLS certificate", Access: apiAccessSuperAdmin,
+		Errors: []apiErrorCode{errCodeNotFound}},
+	{Method: http.MethodGet, Path: "/api/admin/tls/expiring", Tag: "admin", Summary: "TLS certificates nearing expiry", Access: apiAccessSuperAdmin, Query: []string{"days"},
+		Response: apiObject{"generated_at": "", "within_days": 0, "certificates": []expiringCertificate{}}},
+
+	{Method: http.MethodGet, Path: "/api/tunnels", Tag: "routes", Summary: "Live tunnels visible to the caller", Access: apiAccessSession, Query: listQueryParams,
+		Response: apiObject{"generated_at": "", "tunnels": []tunnelView{}, "total": 0, "next_cursor": ""}},
+
+	{Method: http.MethodGet, Path: "/api/connectors", Tag: "connectors", Summary: "List connectors", Access: apiAccessSession, Query: listQueryParams,
+		Response: apiObject{"generated_at": "", "connectors": []connectorView{}, "total": 0, "next_cursor": ""}},
+	{Method: http.MethodPost, Path: "/api/connectors", Tag: "connectors", Summary: "Create a connector", Access: apiAccessSession, Query: []string{"dry_run"},
+		Request: createConnectorRequest{}, Response: apiObject{"message": "", "connector": connectorView{}}, Status: http.StatusCreated,
+		Errors: []apiErrorCode{errCodeTenantNotFound, errCodeTenantAccessDenied, errCodePlanLimitExceeded}},
+	{Method: http.MethodPatch, Path: "/api/connectors/{connectorId}", Tag: "connectors", Summary: "Update connector labels or bandwidth limit", Access: apiAccessSession, Query: []string{"dry_run"},
+		Request: updateConnectorRequest{}, Response: apiObject{"message": "", "connector": connectorView{}},
+		Errors: []apiErrorCode{errCodeConnectorNotFound, errCodeConnectorAccessDenied}},
+	{Method: http.MethodDelete, Path: "/api/connectors/{connectorId}", Tag: "connectors", Summary: "Move a connector to the trash", Access: apiAccessSession,
+		Errors: []apiErrorCode{errCodeConnectorNotFound, errCodeConnectorAccessDenied}},
+	{Method: http.MethodPost, Path: "/api/connectors/{connectorId}/pair", Tag: "connectors", Summary: "Issue a pairing token", Access: apiAccessSession,
+		Response: pairConnectorResponse{},
+		Errors:   []apiErrorCode{errCodeConnectorNotFound, errCodeConnectorAccessDenied}},
+	{Method: http.MethodGet, Path: "/api/connectors/{connectorId}/bootstrap", Tag: "connectors", Summary: "Shell or PowerShell script that installs the agent, creates a profile and pairs it with a fresh token", Access: apiAccessSession, Query: []string{"shell", "channel"},
+		Errors: []apiErrorCode{errCodeConnectorNotFound, errCodeConnectorAccessDenied}},
+	{Method: http.MethodPost, Path: "/api/connectors/{connectorId}/bootstrap", Tag: "connectors", Summary: "Same as GET, for clients that only POST", Access: apiAccessSession, Query: []string{"shell", "channel"},
+		Errors: []apiErrorCode{errCodeConnectorNotFound, errCodeConnectorAccessDenied}},
+	{Method: http.MethodPost, Path: "/api/connectors/{connectorId}/rotate", Tag: "connectors", Summary: "Rotate the connector secret", Access: apiAccessSession,
+		Response: apiObject{"message": "", "connector_id": "", "connector_secret": ""},
+		Errors:   []apiErrorCode{errCodeConnectorNotFound, errCodeConnectorAccessDenied}},
+
+	{Method: http.MethodGet, Path: "/api/orgs", Tag: "orgs", Summary: "Organizations visible to the caller", Access: apiAccessSession,
+		Response: apiObject{"organizations": []Organization{}}},
+	{Method: http.MethodPost, Path: "/api/orgs", Tag: "orgs", Summary: "Create an organization or replace its name and tenants", Access: apiAccessSuperAdmin,
+		Request: upsertOrgRequest{}, Response: apiObject{"message": "", "organization": Organization{}},
+		Errors: []apiErrorCode{errCodeTenantNotFound, errCodeConflict}},
+	{Method: http.MethodGet, Path: "/api/orgs/{orgId}", Tag: "orgs", Summary: "Organization with its tenants", Access: apiAccessSession,
+		Response: apiObject{"organization": Organization{}, "tenants": []tenantView{}},
+		Errors:   []apiErrorCode{errCodeForbidden, errCodeNotFound}},
+	{Method: http.MethodDelete, Path: "/api/orgs/{orgId}", Tag: "orgs", Summary: "Delete an organization and its tokens, keeping its tenants", Access: apiAccessSuperAdmin,
+		Errors: []apiErrorCode{errCodeNotFound}},
+	{Method: http.MethodGet, Path: "/api/orgs/{orgId}/usage", Tag: "orgs", Summary: "Consolidated usage and billing of the organization's tenants", Access: apiAccessSession, Query: []string{"month"},
+		Response: orgUsageReport{}, Errors: []apiErrorCode{errCodeForbidden, errCodeNotFound}},
+	{Method: http.MethodGet, Path: "/api/orgs/{orgId}/tokens", Tag: "orgs", Summary: "Organization API tokens", Access: apiAccessSession,
+		Response: apiObject{"org_id": "", "tokens": []OrgToken{}}, Errors: []apiErrorCode{errCodeForbidden, errCodeNotFound}},
+	{Method: http.MethodPost, Path: "/api/orgs/{orgId}/tokens", Tag: "orgs", Summary: "Create an organization API token; the secret is only returned here", Access: apiAccessSession,
+		Request: createOrgTokenRequest{}, Response: apiObject{"message": "", "token": OrgToken{}, "secret": ""}, Status: http.StatusCreated,
+		Errors: []apiErrorCode{errCodeForbidden, errCodeNotFound}},
+	{Method: http.MethodDelete, Path: "/api/orgs/{orgId}/tokens/{tokenId}", Tag: "orgs", Summary: "Revoke an organization API token", Access: apiAccessSession,
+		Errors: []apiErrorCode{errCodeForbidden, errCodeNotFound}},
+
+	{Method: http.MethodGet, Path: "/api/tenants", Tag: "tenants", Summary: "List tenants", Access: apiAccessSession,
+		Response: apiObject{"generated_at": "", "tenants": []tenantView{}}},
+	{Method: http.MethodPost, Path: "/api/tenants", Tag: "tenants", Summary: "Create or rename a tenant", Access: apiAccessSuperAdmin,
+		Request: upsertTenantRequest{}, Response: apiObject{"message": "", "tenant": Tenant{}}},
+	{Method: http.MethodDelete, Path: "/api/tenants/{tenantId}", Tag: "tenants", Summary: "Delete a tenant and its routes", Access: apiAccessSuperAdmin,
+		Errors: []apiErrorCode{errCodeTenantNotFound}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/environment", Tag: "tenants", Summary: "Tenant environment defaults", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "environment": TenantEnvironment{}},
+		Errors:   []apiErrorCode{errCodeNotFound}},
+	{Method: http.MethodPut, Path: "/api/tenants/{tenantId}/environment", Tag: "tenants", Summary: "Replace tenant environment defaults", Access: apiAccessSession,
+		Request: upsertEnvironmentRequest{}, Response: apiObject{"message": "", "tenant_id": "", "environment": TenantEnvironment{}},
+		Errors: []apiErrorCode{errCodeTenantAdminRequired}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/environments", Tag: "tenants", Summary: "List the tenant's named environments", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "environments": []TenantEnvironment{}},
+		Errors:   []apiErrorCode{errCodeTenantNotFound}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/environments/{name}", Tag: "tenants", Summary: "Named tenant environment", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "environment": TenantEnvironment{}},
+		Errors:   []apiErrorCode{errCodeNotFound}},
+	{Method: http.MethodPut, Path: "/api/tenants/{tenantId}/environments/{name}", Tag: "tenants", Summary: "Create or replace a named environment and re-resolve the routes using it", Access: apiAccessSession,
+		Request: upsertEnvironmentRequest{}, Response: apiObject{"message": "", "tenant_id": "", "environment": TenantEnvironment{}},
+		Errors: []apiErrorCode{errCodeTenantAdminRequired}},
+	{Method: http.MethodDelete, Path: "/api/tenants/{tenantId}/environments/{name}", Tag: "tenants", Summary: "Delete a named environment no route references", Access: apiAccessSession,
+		Errors: []apiErrorCode{errCodeTenantAdminRequired, errCodeNotFound, errCodeConflict}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/error-pages", Tag: "tenants", Summary: "Tenant error pages", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "error_pages": &ErrorPages{}}},
+	{Method: http.MethodPut, Path: "/api/tenants/{tenantId}/error-pages", Tag: "tenants", Summary: "Replace tenant error pages", Access: apiAccessSession,
+		Request: ErrorPages{}, Response: apiObject{"message": "", "tenant_id": "", "error_pages": &ErrorPages{}},
+		Errors: []apiErrorCode{errCodeTenantAdminRequired}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/redaction", Tag: "tenants", Summary: "Tenant redaction policy for stored and logged request details", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "redaction": &RedactionPolicy{}, "default_headers": []string{}},
+		Errors:   []apiErrorCode{errCodeTenantNotFound}},
+	{Method: http.MethodPut, Path: "/api/tenants/{tenantId}/redaction", Tag: "tenants", Summary: "Replace the tenant redaction policy", Access: apiAccessSession,
+		Request: RedactionPolicy{}, Response: apiObject{"message": "", "tenant_id": "", "redaction": &RedactionPolicy{}, "default_headers": []string{}},
+		Errors: []apiErrorCode{errCodeTenantNotFound, errCodeTenantAdminRequired}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/retention", Tag: "tenants", Summary: "Tenant data retention settings", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "retention": &TenantRetention{}},
+		Errors:   []apiErrorCode{errCodeTenantNotFound}},
+	{Method: http.MethodPut, Path: "/api/tenants/{tenantId}/retention", Tag: "tenants", Summary: "Replace tenant data retention settings and prune right away", Access: apiAccessSession,
+		Request: TenantRetention{}, Response: apiObject{"message": "", "tenant_id": "", "retention": &TenantRetention{}},
+		Errors: []apiErrorCode{errCodeTenantNotFound, errCodeTenantAdminRequired}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/domains", Tag: "tenants", Summary: "List custom domains", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "domains": []customDomainView{}},
+		Errors:   []apiErrorCode{errCodeTenantNotFound}},
+	{Method: http.MethodPost, Path: "/api/tenants/{tenantId}/domains", Tag: "tenants", Summary: "Attach a custom domain to a route", Access: apiAccessSession,
+		Request: attachDomainRequest{}, Response: apiObject{"message": "", "domain": customDomainView{}},
+		Errors: []apiErrorCode{errCodeTenantAdminRequired, errCodeTenantNotFound, errCodePlanFeatureUnavailable, errCodeRouteNotFound, errCodeDomainTaken}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/domains/{hostname}", Tag: "tenants", Summary: "Custom domain status", Access: apiAccessSession,
+		Response: apiObject{"domain": customDomainView{}},
+		Errors:   []apiErrorCode{errCodeDomainNotFound}},
+	{Method: http.MethodDelete, Path: "/api/tenants/{tenantId}/domains/{hostname}", Tag: "tenants", Summary: "Detach a custom domain", Access: apiAccessSession,
+		Errors: []apiErrorCode{errCodeTenantAdminRequired, errCodeDomainNotFound}},
+	{Method: http.MethodPost, Path: "/api/tenants/{tenantId}/domains/{hostname}/verify", Tag: "tenants", Summary: "Check a custom domain's DNS challenge", Access: apiAccessSession,
+		Response: apiObject{"message": "", "domain": customDomainView{}},
+		Errors:   []apiErrorCode{errCodeTenantAdminRequired, errCodeDomainNotFound}},
+
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/routes", Tag: "routes", Summary: "List routes", Access: apiAccessSession, Query: listQueryParams,
+		Response: apiObject{"generated_at": "", "tenant_id": "", "routes": []routeView{}, "total": 0, "next_cursor": ""}},
+	{Method: http.MethodPost, Path: "/api/tenants/{tenantId}/routes", Tag: "routes", Summary: "Create or replace a route", Access: apiAccessSession, Query: []string{"dry_run"},
+		Request: upsertRuleRequest{}, Response: apiObject{"message": "", "route": routeView{}},
+		Errors: []apiErrorCode{errCodePlanLimitExceeded}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/routes:export", Tag: "routes", Summary: "Export portable route definitions", Access: apiAccessSession, Query: []string{"format", "include_secrets"},
+		Response: routeDocument{}},
+	{Method: http.MethodPost, Path: "/api/tenants/{tenantId}/routes:import", Tag: "routes", Summary: "Import route definitions", Access: apiAccessSession, Query: []string{"dry_run", "on_conflict"},
+		Request:  routeDocument{},
+		Response: apiObject{"tenant_id": "", "dry_run": false, "on_conflict": "", "applied": false, "summary": map[string]int{}, "results": []routeImportResult{}}},
+	{Method: http.MethodDelete, Path: "/api/tenants/{tenantId}/routes/{routeId}", Tag: "routes", Summary: "Move a route to the trash", Access: apiAccessSession,
+		Errors: []apiErrorCode{errCodeRouteNotFound}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/routes/{routeId}/timeseries", Tag: "routes", Summary: "Per-minute route traffic", Access: apiAccessSession, Query: []string{"window"},
+		Response: apiObject{"tenant_id": "", "route_id": "", "window_seconds": 0, "step_seconds": 0, "points": []TimeseriesPoint{}, "totals": map[string]int64{}},
+		Errors:   []apiErrorCode{errCodeRouteNotFound}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/routes/{routeId}/metrics", Tag: "routes", Summary: "Route counters since their last reset, and traffic since a time", Access: apiAccessSession, Query: []string{"since"},
+		Response: apiObject{"tenant_id": "", "route_id": "", "metrics": TunnelMetrics{}, "window": TimeseriesPoint{}},
+		Errors:   []apiErrorCode{errCodeRouteNotFound}},
+	{Method: http.MethodPost, Path: "/api/tenants/{tenantId}/routes/{routeId}/metrics:reset", Tag: "routes", Summary: "Reset a route's counters", Access: apiAccessSession,
+		Response: apiObject{"message": "", "tenant_id": "", "route_id": "", "reset_at": ""},
+		Errors:   []apiErrorCode{errCodeRouteNotFound, errCodeTenantAccessDenied}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/routes/{routeId}/sla", Tag: "routes", Summary: "Route availability and error budget", Access: apiAccessSession, Query: []string{"window", "objective"},
+		Response: apiObject{"generated_at": "", "tenant_id": "", "window": "", "from": "", "objective_percent": 0.0, "route": SLAReport{}},
+		Errors:   []apiErrorCode{errCodeRouteNotFound}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/routes/{routeId}/history", Tag: "routes", Summary: "Route versions with who changed which fields, newest first", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "route_id": "", "versions": []RouteVersion{}},
+		Errors:   []apiErrorCode{errCodeRouteNotFound, errCodeTenantAccessDenied}},
+	{Method: http.MethodPost, Path: "/api/tenants/{tenantId}/routes/{routeId}/rollback", Tag: "routes", Summary: "Roll a route back to an earlier version", Access: apiAccessSession,
+		Request: rollbackRouteRequest{}, Response: apiObject{"message": "", "route": routeView{}},
+		Errors: []apiErrorCode{errCodeRouteNotFound, errCodeNotFound, errCodeTenantAccessDenied, errCodePlanLimitExceeded}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/routes/{routeId}/captures", Tag: "routes", Summary: "Captured exchanges of a route with capture enabled, newest first", Access: apiAccessSession, Query: []string{"since", "until"},
+		Response: apiObject{"tenant_id": "", "route_id": "", "capture": RouteCapture{}, "captures": []captureSummary{}},
+		Errors:   []apiErrorCode{errCodeRouteNotFound}},
+	{Method: http.MethodDelete, Path: "/api/tenants/{tenantId}/routes/{routeId}/captures", Tag: "routes", Summary: "Clear a route's captured exchanges", Access: apiAccessSession,
+		Errors: []apiErrorCode{errCodeRouteNotFound, errCodeTenantAccessDenied}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/routes/{routeId}/captures:har", Tag: "routes", Summary: "Export a route's captured exchanges as a HAR 1.2 file", Access: apiAccessSession, Query: []string{"since", "until"},
+		Response: harDocument{},
+		Errors:   []apiErrorCode{errCodeRouteNotFound}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/routes/{routeId}/captures/{captureId}", Tag: "routes", Summary: "A captured exchange with headers and bodies", Access: apiAccessSession,
+		Response: apiObject{"capture": CapturedExchange{}},
+		Errors:   []apiErrorCode{errCodeRouteNotFound, errCodeNotFound}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/routes/{routeId}/captures/{captureId}/response", Tag: "routes", Summary: "Captured response body with its content type, for viewing inline", Access: apiAccessSession,
+		Errors: []apiErrorCode{errCodeRouteNotFound, errCodeNotFound}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/routes/{routeId}/captures/{captureId}/request", Tag: "routes", Summary: "Captured request body with its content type, for viewing inline", Access: apiAccessSession,
+		Errors: []apiErrorCode{errCodeRouteNotFound, errCodeNotFound}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/sla", Tag: "routes", Summary: "Tenant and per-route availability and error budgets", Access: apiAccessSession, Query: []string{"window", "objective"},
+		Response: apiObject{"generated_at": "", "tenant_id": "", "window": "", "from": "", "objective_percent": 0.0, "tenant": SLAReport{}, "routes": []SLAReport{}},
+		Errors:   []apiErrorCode{errCodeTenantNotFound}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/metrics", Tag: "routes", Summary: "Counters of every route since their last reset, and traffic since a time", Access: apiAccessSession, Query: []string{"since"},
+		Response: apiObject{"tenant_id": "", "routes": []routeMetricsView{}, "window": TimeseriesPoint{}},
+		Errors:   []apiErrorCode{errCodeTenantNotFound}},
+	{Method: http.MethodPost, Path: "/api/tenants/{tenantId}/metrics:reset", Tag: "routes", Summary: "Reset the counters of every route of a tenant", Access: apiAccessSession,
+		Response: apiObject{"message": "", "tenant_id": "", "reset_at": ""},
+		Errors:   []apiErrorCode{errCodeTenantNotFound, errCodeTenantAdminRequired}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/trash", Tag: "routes", Summary: "List deleted routes and connectors that can still be restored", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "retention_seconds": 0, "items": []TrashItem{}},
+		Errors:   []apiErrorCode{errCodeTenantNotFound, errCodeTenantAccessDenied}},
+	{Method: http.MethodPost, Path: "/api/tenants/{tenantId}/trash/{kind}/{id}/restore", Tag: "routes", Summary: "Restore a deleted route or connector; kind is routes or connectors", Access: apiAccessSession,
+		Response: apiObject{"message": "", "restored": []string{}},
+		Errors:   []apiErrorCode{errCodeNotFound, errCodeTenantAccessDenied, errCodeTenantSuspended, errCodeConflict}},
+	{Method: http.MethodDelete, Path: "/api/tenants/{tenantId}/trash/{kind}/{id}", Tag: "routes", Summary: "Purge a deleted route or connector", Access: apiAccessSession,
+		Errors: []apiErrorCode{errCodeNotFound, errCodeTenantAccessDenied}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/forwards", Tag: "connectors", Summary: "List reverse forwards the tenant's agents asked for, with their approval status", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "forwards": []ReverseForward{}},
+		Errors:   []apiErrorCode{errCodeTenantAccessDenied}},
+	{Method: http.MethodPost, Path: "/api/tenants/{tenantId}/forwards/{forwardId}/approve", Tag: "connectors", Summary: "Let the agent reach the forward's target from its machine", Access: apiAccessSession,
+		Response: apiObject{"forward": ReverseForward{}},
+		Errors:   []apiErrorCode{errCodeTenantAdminRequired, errCodeNotFound}},
+	{Method: http.MethodPost, Path: "/api/tenants/{tenantId}/forwards/{forwardId}/deny", Tag: "connectors", Summary: "Refuse a reverse forward and close its open connections", Access: apiAccessSession,
+		Response: apiObject{"forward": ReverseForward{}},
+		Errors:   []apiErrorCode{errCodeTenantAdminRequired, errCodeNotFound}},
+	{Method: http.MethodDelete, Path: "/api/tenants/{tenantId}/forwards/{forwardId}", Tag: "connectors", Summary: "Remove a reverse forward and close its open connections; the agent has to ask again", Access: apiAccessSession,
+		Errors: []apiErrorCode{errCodeTenantAdminRequired, errCodeNotFound}},
+	{Method: http.MethodGet, Path: "/api/tenants/{tenantId}/ssh-keys", Tag: "connectors", Summary: "List the SSH keys that may publish the tenant's routes with ssh -R", Access: apiAccessSession,
+		Response: apiObject{"tenant_id": "", "ssh_addr": "", "ssh_keys": []SSHKey{}},
+		Errors:   []apiErrorCode{errCodeTenantNotFound, errCodeTenantAccessDenied}},
+	{Method: http.MethodPost, Path: "/api/tenants/{tenantId}/ssh-keys", Tag: "connectors", Summary: "Register an SSH public key in authorized_keys format", Access: apiAccessSession,
+		Request: addSSHKeyRequest{}, Response: apiObject{"ssh_key": SSHKey{}},
+		Errors: []apiErrorCode{errCodeTenantNotFound, errCodeTenantAdminRequired, errCodeConflict}},
+	{Method: http.MethodDelete, Path: "/api/tenants/{tenantId}/ssh-keys/{keyId}", Tag: "connectors", Summary: "Remove an SSH key and disconnect the clients using it", Access: apiAccessSession,
+		Errors: []apiErrorCode{errCodeTenantAdminRequired, errCodeNotFound}},
+
+	{Method: http.MethodGet, Path: "/api/rules", Tag: "routes", Summary: "List default-tenant routes (legacy)", Access: apiAccessSession,
+		Response: apiObject{"generated_at": "", "tenant_id": "", "rules": []routeView{}}},
+	{Method: http.MethodPost, Path: "/api/rules", Tag: "routes", Summary: "Create or replace a default-tenant route (legacy)", Access: apiAccessSession, Query: []string{"dry_run"},
+		Request: upsertRuleRequest{}, Response: apiObject{"message": "", "rule": routeView{}},
+		Errors: []apiErrorCode{errCodeTenantAccessDenied, errCodePlanLimitExceeded}},
+	{Method: http.MethodDelete, Path: "/api/rules/{routeId}", Tag: "routes", Summary: "Delete a default-tenant route (legacy)", Access: apiAccessSession,
+		Errors: []apiErrorCode{errCodeTenantAccessDenied, errCodeRouteNotFound}},
+}
+
+var (
+	openAPIOnce     sync.Once
+	openAPIDocument []byte
+	openAPIErr      error
+)
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	openAPIOnce.Do(func() {
+		openAPIDocument, openAPIErr = json.MarshalIndent(buildOpenAPIDocument(managementAPI), "", "  ")
+	})
+	if openAPIErr != nil {
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, fmt.Sprintf("build openapi document: %v", openAPIErr))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPIDocument)
+}
+
+func (s *Server) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	if !s.requireSuperAdmin(w, user) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", apiDocsContentSecurityPolicy)
+	_, _ = w.Write([]byte(apiDocsPage))
+}
+
+// apiDocsContentSecurityPolicy lets the docs page load Swagger UI from its
+// CDN and run the inline snippet that starts it.
+const apiDocsContentSecurityPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline' https://unpkg.com; style-src 'self' 'unsafe-inline' https://unpkg.com; img-src 'self' data: https://unpkg.com; frame-ancestors 'none'"
+
+const apiDocsPage = `<!doctype html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Proxer API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/api/openapi.json", dom_id: "#swagger-ui", withCredentials: true });
+  </script>
+</body>
+</html>
+`
+
+func buildOpenAPIDocument(operations []apiOperation) map[string]any {
+	schemas := newSchemaRegistry()
+	paths := map[string]map[string]any{}
+	for _, op := range operations {
+		item := paths[op.Path]
+		if item == nil {
+			item = map[string]any{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = op.document(schemas)
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Proxer Gateway Management API",
+			"version":     "1",
+			"description": "Console and management endpoints of the Proxer gateway. Authenticated operations accept the proxer_session cookie set by /api/auth/login or the same session ID as a bearer token.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]any{
+				"sessionCookie": map[string]any{"type": "apiKey", "in": "cookie", "name": sessionCookieName},
+				"bearerSession": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+func (op apiOperation) document(schemas *schemaRegistry) map[string]any {
+	doc := map[string]any{
+		"summary":     op.Summary,
+		"tags":        []string{op.Tag},
+		"operationId": operationID(op.Method, op.Path),
+	}
+	var parameters []map[string]any
+	for _, segment := range strings.Split(op.Path, "/") {
+		name, ok := strings.CutPrefix(segment, "{")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, "}")
+		parameters = append(parameters, map[string]any{
+			"name": name, "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+		})
+	}
+	for _, name := range op.Query {
+		parameters = append(parameters, map[string]any{
+			"name": name, "in": "query", "schema": map[string]any{"type": "string"},
+		})
+	}
+	if len(parameters) > 0 {
+		doc["parameters"] = parameters
+	}
+	if op.Request != nil {
+		doc["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": schemas.schemaFor(op.Request)}},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	if op.Response != nil {
+		success["content"] = map[string]any{"application/json": map[string]any{"schema": schemas.schemaFor(op.Response)}}
+	}
+	responses := map[string]any{fmt.Sprint(status): success}
+	if op.Access == apiAccessPublic {
+		doc["security"] = []map[string][]string{}
+	} else {
+		doc["security"] = []map[string][]string{{"sessionCookie": {}}, {"bearerSession": {}}}
+	}
+
+	byStatus := map[int][]apiErrorCode{}
+	for _, code := range op.errorCodes() {
+		errStatus := apiErrorCodes[code].Status
+		byStatus[errStatus] = append(byStatus[errStatus], code)
+	}
+	errorSchema := schemas.schemaFor(apiError{})
+	for errStatus, codes := range byStatus {
+		descriptions := make([]string, 0, len(codes))
+		for _, code := range codes {
+			descriptions = append(descriptions, fmt.Sprintf("%s: %s", code, apiErrorCodes[code].Description))
+		}
+		responses[fmt.Sprint(errStatus)] = map[string]any{
+			"description":   strings.Join(descriptions, "; "),
+			"x-error-codes": codes,
+			"content":       map[string]any{"application/json": map[string]any{"schema": errorSchema}},
+		}
+	}
+	doc["responses"] = responses
+	return doc
+}
+
+func (op apiOperation) errorCodes() []apiErrorCode {
+	seen := map[apiErrorCode]bool{}
+	for _, code := range op.Errors {
+		seen[code] = true
+	}
+	switch op.Access {
+	case apiAccessSuperAdmin:
+		seen[errCodeSuperAdminRequired] = true
+		seen[errCodeUnauthorized] = true
+	case apiAccessSession:
+		seen[errCodeUnauthorized] = true
+	}
+	if op.Access != apiAccessPublic && op.Method != http.MethodGet {
+		seen[errCodeCSRFTokenInvalid] = true
+	}
+	if op.Request != nil {
+		seen[errCodeInvalidRequest] = true
+		seen[errCodePayloadTooLarge] = true
+	}
+	if len(op.Query) > 0 {
+		seen[errCodeInvalidRequest] = true
+	}
+	if strings.HasPrefix(op.Path, "/api/tenants/{tenantId}/") {
+		seen[errCodeTenantNotFound] = true
+		seen[errCodeTenantAccessDenied] = true
+	}
+	codes := make([]apiErrorCode, 0, len(seen))
+	for code := range seen {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "/api/"), "/") {
+		if strings.HasPrefix(segment, "{") {
+			continue
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool {
+			return r == '-' || r == ':' || r == '.' || r == '_'
+		}) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+type schemaRegistry struct {
+	schemas map[string]any
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: map[string]any{}, names: map[reflect.Type]string{}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (r *schemaRegistry) schemaFor(value any) map[string]any {
+	if object, ok := value.(apiObject); ok {
+		properties := map[string]any{}
+		for name, field := range object {
+			properties[name] = r.schemaFor(field)
+		}
+		return map[string]any{"type": "object", "properties": properties}
+	}
+	return r.schemaForType(reflect.TypeOf(value))
+}
+
+func (r *schemaRegistry) schemaForType(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == reflect.TypeOf(time.Duration(0)):
+		return map[string]any{"type": "integer", "format": "int64"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": r.schemaForType(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": r.schemaForType(t.Elem())}
+	case reflect.Struct:
+		return r.structRef(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+func (r *schemaRegistry) structRef(t reflect.Type) map[string]any {
+	name, ok := r.names[t]
+	if !ok {
+		name = schemaName(t)
+		for taken := r.schemas[name] != nil; taken; taken = r.schemas[name] != nil {
+			name += "_"
+		}
+		r.names[t] = name
+		r.schemas[name] = map[string]any{}
+		r.schemas[name] = r.structSchema(t)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for key, value := range r.structSchema(field.Type)["properties"].(map[string]any) {
+				properties[key] = value
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = r.schemaForType(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	if name == "" {
+		return "Object"
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type recordingRegistrar struct {
+	patterns []string
+}
+
+func (r *recordingRegistrar) HandleFunc(pattern string, _ func(http.ResponseWriter, *http.Request)) {
+	r.patterns = append(r.patterns, pattern)
+}
+
+func TestManagementAPICoversConsoleRoutes(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	registrar := &recordingRegistrar{}
+	server.registerConsoleRoutes(registrar)
+
+	for _, pattern := range registrar.patterns {
+		if !strings.HasPrefix(pattern, "/api/") {
+			continue
+		}
+		covered := false
+		for _, op := range managementAPI {
+			if op.Path == pattern || (strings.HasSuffix(pattern, "/") && strings.HasPrefix(op.Path, pattern)) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			t.Errorf("route %s has no managementAPI entry", pattern)
+		}
+	}
+}
+
+func TestOpenAPIDocumentDescribesManagementAPI(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	recorder := httptest.NewRecorder()
+	server.handleOpenAPI(recorder, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("expected an OpenAPI 3 document, got %q", doc.OpenAPI)
+	}
+	routes := doc.Paths["/api/tenants/{tenantId}/routes"]
+	if _, ok := routes["get"]; !ok {
+		t.Fatalf("expected GET routes operation, got %v", routes)
+	}
+	if !strings.Contains(string(routes["post"]), "#/components/schemas/UpsertRuleRequest") {
+		t.Fatalf("expected route upsert to reference its request schema, got %s", routes["post"])
+	}
+	view, ok := doc.Components.Schemas["RouteView"]
+	if !ok {
+		t.Fatalf("expected RouteView schema")
+	}
+	if _, ok := view.Properties["public_url"]; !ok {
+		t.Fatalf("expected RouteView.public_url, got %v", view.Properties)
+	}
+	user := doc.Components.Schemas["User"]
+	for name := range user.Properties {
+		if strings.Contains(name, "password") {
+			t.Fatalf("expected User schema to omit %s", name)
+		}
+	}
+}
+
+func TestAPIDocsRequireSuperAdmin(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	recorder := httptest.NewRecorder()
+	server.handleAPIDocs(recorder, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a session, got %d", recorder.Code)
+	}
+
+	sessionID, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/docs", nil)
+	req.Header.Set("Authorization", "Bearer "+sessionID)
+	recorder = httptest.NewRecorder()
+	server.handleAPIDocs(recorder, req)
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "/api/openapi.json") {
+		t.Fatalf("expected swagger ui page, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,564 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	orgTokenPrefix     = "pxo_"
+	orgTokenUserPrefix = "org-token:"
+	maxOrgTokenTTL     = 365 * 24 * time.Hour
+)
+
+var errTenantInOtherOrg = errors.New("tenant belongs to another organization")
+
+type Organization struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	TenantIDs []string  `json:"tenant_ids"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type OrgToken struct {
+	ID         string     `json:"id"`
+	OrgID      string     `json:"org_id"`
+	Name       string     `json:"name"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type orgTokenRecord struct {
+	Token OrgToken `json:"token"`
+	Hash  string   `json:"hash"`
+}
+
+type orgStoreSnapshot struct {
+	Organizations []Organization   `json:"organizations,omitempty"`
+	Tokens        []orgTokenRecord `json:"tokens,omitempty"`
+}
+
+type upsertOrgRequest struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	TenantIDs []string `json:"tenant_ids"`
+}
+
+type createOrgTokenRequest struct {
+	Name           string `json:"name"`
+	ExpiresInHours int    `json:"expires_in_hours,omitempty"`
+}
+
+type OrgStore struct {
+	mu     sync.RWMutex
+	orgs   map[string]Organization
+	tokens map[string]orgTokenRecord
+}
+
+func NewOrgStore() *OrgStore {
+	return &OrgStore{
+		orgs:   make(map[string]Organization),
+		tokens: make(map[string]orgTokenRecord),
+	}
+}
+
+func (s *OrgStore) Upsert(input Organization) (Organization, error) {
+	orgID := normalizeIdentifier(input.ID)
+	if !identifierPattern.MatchString(orgID) {
+		return Organization{}, fmt.Errorf("invalid organization id %q", orgID)
+	}
+	tenantIDs := make([]string, 0, len(input.TenantIDs))
+	seen := make(map[string]bool)
+	for _, raw := range input.TenantIDs {
+		tenantID := normalizeIdentifier(raw)
+		if !identifierPattern.MatchString(tenantID) {
+			return Organization{}, fmt.Errorf("invalid tenant id %q", raw)
+		}
+		if !seen[tenantID] {
+			seen[tenantID] = true
+			tenantIDs = append(tenantIDs, tenantID)
+		}
+	}
+	sort.Strings(tenantIDs)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, other := range s.orgs {
+		if other.ID == orgID {
+			continue
+		}
+		for _, tenantID := range other.TenantIDs {
+			if seen[tenantID] {
+				return Organization{}, fmt.Errorf("%s: %w %q", tenantID, errTenantInOtherOrg, other.ID)
+			}
+		}
+	}
+	now := time.Now().UTC()
+	org, ok := s.orgs[orgID]
+	if !ok {
+		org = Organization{ID: orgID, CreatedAt: now}
+	}
+	org.Name = strings.TrimSpace(input.Name)
+	if org.Name == "" {
+		org.Name = orgID
+	}
+	org.TenantIDs = tenantIDs
+	org.UpdatedAt = now
+	s.orgs[orgID] = org
+	return org, nil
+}
+
+func (s *OrgStore) Get(orgID string) (Organization, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	org, ok := s.orgs[normalizeIdentifier(orgID)]
+	return org, ok
+}
+
+func (s *OrgStore) List() []Organization {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Organization, 0, len(s.orgs))
+	for _, org := range s.orgs {
+		out = append(out, org)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (s *OrgStore) Delete(orgID string) bool {
+	orgID = normalizeIdentifier(orgID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.orgs[orgID]; !ok {
+		return false
+	}
+	delete(s.orgs, orgID)
+	for id, record := range s.tokens {
+		if record.Token.OrgID == orgID {
+			delete(s.tokens, id)
+		}
+	}
+	return true
+}
+
+func (s *OrgStore) TenantInOrg(orgID, tenantID string) bool {
+	org, ok := s.Get(orgID)
+	if !ok {
+		return false
+	}
+	tenantID = normalizeIdentifier(tenantID)
+	for _, member := range org.TenantIDs {
+		if member == tenantID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *OrgStore) RemoveTenant(tenantID string) {
+	tenantID = normalizeIdentifier(tenantID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, org := range s.orgs {
+		kept := org.TenantIDs[:0:0]
+		for _, member := range org.TenantIDs {
+			if member != tenantID {
+				kept = append(kept, member)
+			}
+		}
+		if len(kept) != len(org.TenantIDs) {
+			org.TenantIDs = kept
+			org.UpdatedAt = time.Now().UTC()
+			s.orgs[id] = org
+		}
+	}
+}
+
+func (s *OrgStore) CreateToken(orgID, name, createdBy string, ttl time.Duration) (OrgToken, string, error) {
+	orgID = normalizeIdentifier(orgID)
+	secret, err := randomToken(24)
+	if err != nil {
+		return OrgToken{}, "", err
+	}
+	secret = orgTokenPrefix + secret
+	now := time.Now().UTC()
+	token := OrgToken{
+		ID:        randomHex(8),
+		OrgID:     orgID,
+		Name:      strings.TrimSpace(name),
+		CreatedBy: strings.TrimSpace(createdBy),
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		token.ExpiresAt = &expiresAt
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.orgs[orgID]; !ok {
+		return OrgToken{}, "", fmt.Errorf("organization %q not found", orgID)
+	}
+	s.tokens[token.ID] = orgTokenRecord{Token: token, Hash: hashOrgToken(secret)}
+	return token, secret, nil
+}
+
+func (s *OrgStore) ListTokens(orgID string) []OrgToken {
+	orgID = normalizeIdentifier(orgID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]OrgToken, 0)
+	for _, record := range s.tokens {
+		if record.Token.OrgID == orgID {
+			out = append(out, record.Token)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+func (s *OrgStore) DeleteToken(orgID, tokenID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.tokens[strings.TrimSpace(tokenID)]
+	if !ok || record.Token.OrgID != normalizeIdentifier(orgID) {
+		return false
+	}
+	delete(s.tokens, record.Token.ID)
+	return true
+}
+
+func (s *OrgStore) ResolveToken(secret string) (OrgToken, bool) {
+	hash := hashOrgToken(strings.TrimSpace(secret))
+	now := time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, record := range s.tokens {
+		if record.Hash != hash {
+			continue
+		}
+		if record.Token.ExpiresAt != nil && now.After(*record.Token.ExpiresAt) {
+			return OrgToken{}, false
+		}
+		record.Token.LastUsedAt = &now
+		s.tokens[id] = record
+		return record.Token, true
+	}
+	return OrgToken{}, false
+}
+
+func (s *OrgStore) Snapshot() orgStoreSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot := orgStoreSnapshot{}
+	for _, org := range s.orgs {
+		snapshot.Organizations = append(snapshot.Organizations, org)
+	}
+	sort.Slice(snapshot.Organizations, func(i, j int) bool { return snapshot.Organizations[i].ID < snapshot.Organizations[j].ID })
+	for _, record := range s.tokens {
+		snapshot.Tokens = append(snapshot.Tokens, record)
+	}
+	sort.Slice(snapshot.Tokens, func(i, j int) bool { return snapshot.Tokens[i].Token.ID < snapshot.Tokens[j].Token.ID })
+	return snapshot
+}
+
+func (s *OrgStore) Restore(snapshot orgStoreSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orgs = make(map[string]Organization, len(snapshot.Organizations))
+	s.tokens = make(map[string]orgTokenRecord, len(snapshot.Tokens))
+	for _, org := range snapshot.Organizations {
+		org.ID = normalizeIdentifier(org.ID)
+		if identifierPattern.MatchString(org.ID) {
+			s.orgs[org.ID] = org
+		}
+	}
+	for _, record := range snapshot.Tokens {
+		if _, ok := s.orgs[record.Token.OrgID]; ok && record.Token.ID != "" && record.Hash != "" {
+			s.tokens[record.Token.ID] = record
+		}
+	}
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func hashOrgToken(secret string) string {
+	sum := sha256.Sum256([]byte("proxer-org-token:" + secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// orgTokenUser is the org admin an org API token acts as. Its tenant is the
+// organization's first tenant, for the /api/me endpoints.
+func (s *Server) orgTokenUser(secret string) (User, bool) {
+	token, ok := s.orgStore.ResolveToken(secret)
+	if !ok {
+		return User{}, false
+	}
+	org, ok := s.orgStore.Get(token.OrgID)
+	if !ok || len(org.TenantIDs) == 0 {
+		return User{}, false
+	}
+	return User{
+		Username:  orgTokenUserPrefix + token.ID,
+		Role:      RoleOrgAdmin,
+		TenantID:  org.TenantIDs[0],
+		OrgID:     org.ID,
+		Status:    "active",
+		CreatedAt: token.CreatedAt,
+		UpdatedAt: token.CreatedAt,
+	}, true
+}
+
+func (s *Server) isOrgAdmin(user User) bool {
+	return strings.TrimSpace(user.Role) == RoleOrgAdmin
+}
+
+func (s *Server) inUserOrg(user User, tenantID string) bool {
+	return s.isOrgAdmin(user) && s.orgStore.TenantInOrg(user.OrgID, tenantID)
+}
+
+func (s *Server) canAccessOrg(user User, orgID string) bool {
+	return s.isSuperAdmin(user) || (s.isOrgAdmin(user) && user.OrgID == normalizeIdentifier(orgID))
+}
+
+func (s *Server) handleOrgs(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		orgs := make([]Organization, 0)
+		for _, org := range s.orgStore.List() {
+			if s.canAccessOrg(user, org.ID) {
+				orgs = append(orgs, org)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"organizations": orgs})
+	case http.MethodPost:
+		if !s.requireSuperAdmin(w, user) {
+			return
+		}
+		var request upsertOrgRequest
+		if !s.decodeJSON(w, r, &request, "organization payload") {
+			return
+		}
+		for _, tenantID := range request.TenantIDs {
+			if !s.ruleStore.HasTenant(normalizeIdentifier(tenantID)) {
+				writeAPIErrorDetails(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found", map[string]any{"tenant_id": tenantID})
+				return
+			}
+		}
+		org, err := s.orgStore.Upsert(Organization{ID: request.ID, Name: request.Name, TenantIDs: request.TenantIDs})
+		if errors.Is(err, errTenantInOtherOrg) {
+			writeAPIError(w, http.StatusConflict, errCodeConflict, err.Error())
+			return
+		}
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		s.auditStore.Record(user.Username, "org.upsert", "", map[string]string{
+			"org_id":  org.ID,
+			"tenants": strings.Join(org.TenantIDs, ","),
+		})
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message":      "organization upserted",
+			"organization": org,
+		})
+		s.persistState()
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleOrgByID(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/orgs/"), "/"), "/")
+	orgID := normalizeIdentifier(parts[0])
+	if !s.canAccessOrg(user, orgID) {
+		writeAPIError(w, http.StatusForbidden, errCodeForbidden, "forbidden organization access")
+		return
+	}
+	org, ok := s.orgStore.Get(orgID)
+	if !ok {
+		writeAPIErrorDetails(w, http.StatusNotFound, errCodeNotFound, "organization not found", map[string]any{"org_id": orgID})
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		s.handleOrg(w, r, user, org)
+	case len(parts) == 2 && parts[1] == "usage":
+		if r.Method != http.MethodGet {
+			writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.orgUsage(org, r.URL.Query().Get("month"), time.Now().UTC()))
+	case len(parts) == 2 && parts[1] == "tokens":
+		s.handleOrgTokens(w, r, user, org)
+	case len(parts) == 3 && parts[1] == "tokens":
+		if r.Method != http.MethodDelete {
+			writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		if !s.orgStore.DeleteToken(org.ID, parts[2]) {
+			writeAPIErrorDetails(w, http.StatusNotFound, errCodeNotFound, "token not found", map[string]any{"token_id": parts[2]})
+			return
+		}
+		s.auditStore.Record(user.Username, "org.token.delete", "", map[string]string{"org_id": org.ID, "token_id": parts[2]})
+		s.persistState()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "not found")
+	}
+}
+
+func (s *Server) handleOrg(w http.ResponseWriter, r *http.Request, user User, org Organization) {
+	switch r.Method {
+	case http.MethodGet:
+		tenants := make([]tenantView, 0, len(org.TenantIDs))
+		for _, tenant := range s.buildTenantViews() {
+			if s.orgStore.TenantInOrg(org.ID, tenant.ID) {
+				tenants = append(tenants, tenant)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"organization": org,
+			"tenants":      tenants,
+		})
+	case http.MethodDelete:
+		if !s.requireSuperAdmin(w, user) {
+			return
+		}
+		s.orgStore.Delete(org.ID)
+		s.auditStore.Record(user.Username, "org.delete", "", map[string]string{"org_id": org.ID})
+		s.persistState()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleOrgTokens(w http.ResponseWriter, r *http.Request, user User, org Organization) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"org_id": org.ID,
+			"tokens": s.orgStore.ListTokens(org.ID),
+		})
+	case http.MethodPost:
+		// Tokens cannot mint further tokens.
+		if strings.HasPrefix(user.Username, orgTokenUserPrefix) {
+			writeAPIError(w, http.StatusForbidden, errCodeForbidden, "org tokens cannot create tokens")
+			return
+		}
+		var request createOrgTokenRequest
+		if !s.decodeJSON(w, r, &request, "org token payload") {
+			return
+		}
+		ttl := time.Duration(request.ExpiresInHours) * time.Hour
+		if ttl < 0 || ttl > maxOrgTokenTTL {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("expires_in_hours must be between 0 and %d", int(maxOrgTokenTTL.Hours())))
+			return
+		}
+		token, secret, err := s.orgStore.CreateToken(org.ID, request.Name, user.Username, ttl)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		s.auditStore.Record(user.Username, "org.token.create", "", map[string]string{"org_id": org.ID, "token_id": token.ID})
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"message": "token created; the secret is not shown again",
+			"token":   token,
+			"secret":  secret,
+		})
+		s.persistState()
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+type orgTenantUsage struct {
+	TenantID          string        `json:"tenant_id"`
+	PlanID            string        `json:"plan_id"`
+	PlanVersion       int           `json:"plan_version"`
+	PriceMonthlyUSD   float64       `json:"price_monthly_usd"`
+	ProratedAmountUSD float64       `json:"prorated_amount_usd"`
+	Usage             UsageSnapshot `json:"usage"`
+}
+
+type orgUsageTotals struct {
+	Tenants           int     `json:"tenants"`
+	RoutesUsed        int     `json:"routes_used"`
+	ConnectorsUsed    int     `json:"connectors_used"`
+	BytesIn           int64   `json:"bytes_in"`
+	BytesOut          int64   `json:"bytes_out"`
+	Requests          int64   `json:"requests"`
+	BlockedRequests   int64   `json:"blocked_requests"`
+	PriceMonthlyUSD   float64 `json:"price_monthly_usd"`
+	ProratedAmountUSD float64 `json:"prorated_amount_usd"`
+	AmountDueUSD      float64 `json:"amount_due_usd"`
+}
+
+type orgUsageReport struct {
+	OrgID    string           `json:"org_id"`
+	MonthKey string           `json:"month_key"`
+	Tenants  []orgTenantUsage `json:"tenants"`
+	Totals   orgUsageTotals   `json:"totals"`
+}
+
+func (s *Server) orgUsage(org Organization, month string, now time.Time) orgUsageReport {
+	monthKey := normalizeMonthKey(month)
+	if monthKey == "" {
+		monthKey = now.Format("2006-01")
+	}
+	report := orgUsageReport{OrgID: org.ID, MonthKey: monthKey, Tenants: make([]orgTenantUsage, 0, len(org.TenantIDs))}
+	for _, tenantID := range org.TenantIDs {
+		plan, planID := s.planStore.GetTenantPlan(tenantID)
+		line := orgTenantUsage{
+			TenantID:        tenantID,
+			PlanID:          planID,
+			PlanVersion:     plan.Version,
+			PriceMonthlyUSD: plan.PriceMonthlyUSD,
+			Usage:           s.planStore.GetUsage(tenantID, monthKey),
+		}
+		if assignment, ok := s.planStore.GetTenantAssignment(tenantID); ok && assignment.AssignedAt.Format("2006-01") == monthKey {
+			line.ProratedAmountUSD = assignment.ProratedAmountUSD
+		}
+		report.Tenants = append(report.Tenants, line)
+
+		totals := &report.Totals
+		totals.Tenants++
+		totals.RoutesUsed += line.Usage.RoutesUsed
+		totals.ConnectorsUsed += line.Usage.ConnectorsUsed
+		totals.BytesIn += line.Usage.BytesIn
+		totals.BytesOut += line.Usage.BytesOut
+		totals.Requests += line.Usage.Requests
+		totals.BlockedRequests += line.Usage.BlockedRequests
+		totals.PriceMonthlyUSD += line.PriceMonthlyUSD
+		totals.ProratedAmountUSD += line.ProratedAmountUSD
+	}
+	report.Totals.PriceMonthlyUSD = roundCents(report.Totals.PriceMonthlyUSD)
+	report.Totals.ProratedAmountUSD = roundCents(report.Totals.ProratedAmountUSD)
+	report.Totals.AmountDueUSD = roundCents(report.Totals.PriceMonthlyUSD + report.Totals.ProratedAmountUSD)
+	return report
+}
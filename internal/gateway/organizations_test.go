@@ -0,0 +1,140 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrganizationsScopeOrgAdminsAndTokens(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	for _, tenantID := range []string{"team-a", "team-b", "outsider"} {
+		if _, err := server.ruleStore.UpsertTenant(Tenant{ID: tenantID}); err != nil {
+			t.Fatalf("create tenant: %v", err)
+		}
+	}
+	if _, err := server.planStore.AssignTenantPlan("team-b", "pro", "test"); err != nil {
+		t.Fatalf("assign plan: %v", err)
+	}
+	server.planStore.RecordRequest("team-a", 100, 200)
+	server.planStore.RecordRequest("team-b", 1000, 2000)
+	server.planStore.RecordRequest("outsider", 5, 5)
+
+	adminSession, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	call := func(credential, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+credential)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	if recorder := call(adminSession, http.MethodPost, "/api/orgs", `{"id":"acme","name":"Acme","tenant_ids":["team-b","team-a"]}`); recorder.Code != http.StatusOK {
+		t.Fatalf("create org: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call(adminSession, http.MethodPost, "/api/orgs", `{"id":"rival","tenant_ids":["team-a"]}`); recorder.Code != http.StatusConflict {
+		t.Fatalf("expected a tenant in two orgs to conflict, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call(adminSession, http.MethodPost, "/api/admin/users", `{"username":"olivia","password":"secret123","role":"org_admin","org_id":"acme"}`); recorder.Code != http.StatusCreated {
+		t.Fatalf("create org admin: %d %s", recorder.Code, recorder.Body.String())
+	}
+	orgAdmin, _ := server.authStore.GetUser("olivia")
+	if orgAdmin.OrgID != "acme" || orgAdmin.TenantID != "team-a" {
+		t.Fatalf("unexpected org admin %+v", orgAdmin)
+	}
+	orgSession, err := server.authStore.NewSession("olivia")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	var tenants struct {
+		Tenants []tenantView `json:"tenants"`
+	}
+	if err := json.Unmarshal(call(orgSession, http.MethodGet, "/api/tenants", "").Body.Bytes(), &tenants); err != nil || len(tenants.Tenants) != 2 {
+		t.Fatalf("expected the org admin to see both org tenants, got %+v (%v)", tenants.Tenants, err)
+	}
+	if recorder := call(orgSession, http.MethodPost, "/api/tenants/team-b/routes", `{"id":"app","target":"http://127.0.0.1:9"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("expected the org admin to manage team-b routes, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call(orgSession, http.MethodGet, "/api/tenants/outsider/routes", ""); recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected tenants outside the org to stay isolated, got %d", recorder.Code)
+	}
+	if recorder := call(orgSession, http.MethodPost, "/api/orgs", `{"id":"acme","tenant_ids":["outsider"]}`); recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected org admins not to change org membership, got %d", recorder.Code)
+	}
+
+	var usage orgUsageReport
+	recorder := call(orgSession, http.MethodGet, "/api/orgs/acme/usage", "")
+	if err := json.Unmarshal(recorder.Body.Bytes(), &usage); err != nil || recorder.Code != http.StatusOK {
+		t.Fatalf("org usage: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if usage.Totals.Tenants != 2 || usage.Totals.BytesIn != 1100 || usage.Totals.BytesOut != 2200 || usage.Totals.Requests != 2 {
+		t.Fatalf("unexpected usage totals %+v", usage.Totals)
+	}
+	if usage.Totals.PriceMonthlyUSD != 20 || usage.Totals.AmountDueUSD != 20 {
+		t.Fatalf("expected the pro tenant's price to roll up, got %+v", usage.Totals)
+	}
+
+	var created struct {
+		Token  OrgToken `json:"token"`
+		Secret string   `json:"secret"`
+	}
+	recorder = call(orgSession, http.MethodPost, "/api/orgs/acme/tokens", `{"name":"ci","expires_in_hours":24}`)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &created); err != nil || recorder.Code != http.StatusCreated || !strings.HasPrefix(created.Secret, orgTokenPrefix) {
+		t.Fatalf("create token: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call(created.Secret, http.MethodGet, "/api/tenants/team-a/routes", ""); recorder.Code != http.StatusOK {
+		t.Fatalf("expected the token to reach org tenants, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call(created.Secret, http.MethodGet, "/api/tenants/outsider/routes", ""); recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected the token to be scoped to the org, got %d", recorder.Code)
+	}
+	if recorder := call(created.Secret, http.MethodGet, "/api/admin/users", ""); recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected the token not to be a super admin, got %d", recorder.Code)
+	}
+	if recorder := call(created.Secret, http.MethodPost, "/api/orgs/acme/tokens", `{"name":"again"}`); recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected tokens not to mint tokens, got %d", recorder.Code)
+	}
+	if recorder := call(orgSession, http.MethodDelete, "/api/orgs/acme/tokens/"+created.Token.ID, ""); recorder.Code != http.StatusNoContent {
+		t.Fatalf("revoke token: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call(created.Secret, http.MethodGet, "/api/tenants/team-a/routes", ""); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a revoked token to be refused, got %d", recorder.Code)
+	}
+
+	restored := NewOrgStore()
+	restored.Restore(server.orgStore.Snapshot())
+	if !restored.TenantInOrg("acme", "team-b") || restored.TenantInOrg("acme", "outsider") {
+		t.Fatalf("unexpected restored orgs %+v", restored.List())
+	}
+}
+
+func TestOrgTokensExpire(t *testing.T) {
+	store := NewOrgStore()
+	if _, err := store.Upsert(Organization{ID: "acme", TenantIDs: []string{"team-a"}}); err != nil {
+		t.Fatalf("create org: %v", err)
+	}
+	_, secret, err := store.CreateToken("acme", "ci", "olivia", time.Hour)
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if _, ok := store.ResolveToken(secret); !ok {
+		t.Fatalf("expected a fresh token to resolve")
+	}
+	for id, record := range store.tokens {
+		expired := time.Now().Add(-time.Minute)
+		record.Token.ExpiresAt = &expired
+		store.tokens[id] = record
+	}
+	if _, ok := store.ResolveToken(secret); ok {
+		t.Fatalf("expected an expired token to be refused")
+	}
+}
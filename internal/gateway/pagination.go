@@ -0,0 +1,300 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultListPageSize = 100
+	maxListPageSize     = 500
+)
+
+// listPage is one page of a list endpoint. Without limit or cursor the page
+// holds every matching item, which keeps the pre-pagination responses.
+type listPage[T any] struct {
+	Items      []T
+	Total      int
+	NextCursor string
+}
+
+// listCursor marks the last item of a page by its sort value and ID, so the
+// next page starts after it even when items are added or removed meanwhile.
+type listCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+func paginateList[T any](w http.ResponseWriter, r *http.Request, items []T, idField string) (listPage[T], bool) {
+	page, err := applyListQuery(r.URL.Query(), items, idField)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return listPage[T]{}, false
+	}
+	return page, true
+}
+
+func writeListPage(w http.ResponseWriter, payload map[string]any, total int, nextCursor string) {
+	payload["total"] = total
+	if nextCursor != "" {
+		payload["next_cursor"] = nextCursor
+	}
+	writeJSON(w, http.StatusOK, payload)
+}
+
+func applyListQuery[T any](query url.Values, items []T, idField string) (listPage[T], error) {
+	itemType := reflect.TypeOf((*T)(nil)).Elem()
+
+	limit := 0
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxListPageSize {
+			return listPage[T]{}, fmt.Errorf("limit must be between 1 and %d", maxListPageSize)
+		}
+		limit = parsed
+	}
+	rawCursor := strings.TrimSpace(query.Get("cursor"))
+	if rawCursor != "" && limit == 0 {
+		limit = defaultListPageSize
+	}
+
+	sortSpec := strings.TrimSpace(query.Get("sort"))
+	sortField, descending := strings.CutPrefix(sortSpec, "-")
+	if sortField != "" {
+		if _, ok := listFieldKind(itemType, sortField); !ok {
+			return listPage[T]{}, fmt.Errorf("cannot sort by %q", sortField)
+		}
+	} else if limit > 0 {
+		sortField = idField
+	}
+
+	filters := map[string]string{}
+	for key, values := range query {
+		switch key {
+		case "limit", "cursor", "sort":
+			continue
+		}
+		if _, ok := listFieldKind(itemType, key); ok && len(values) > 0 {
+			filters[key] = strings.TrimSpace(values[0])
+		}
+	}
+
+	matched := make([]T, 0, len(items))
+	for _, item := range items {
+		keep := true
+		for field, want := range filters {
+			if got, _ := listFieldValue(item, field); !strings.EqualFold(got, want) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			matched = append(matched, item)
+		}
+	}
+
+	if sortField != "" {
+		kind, _ := listFieldKind(itemType, sortField)
+		sort.SliceStable(matched, func(i, j int) bool {
+			return listItemLess(matched[i], matched[j], sortField, idField, kind, descending)
+		})
+	}
+
+	page := listPage[T]{Items: matched, Total: len(matched)}
+	if limit == 0 {
+		return page, nil
+	}
+
+	start := 0
+	if rawCursor != "" {
+		cursor, err := decodeListCursor(rawCursor)
+		if err != nil || cursor.Sort != sortSpecOrDefault(sortSpec, idField) {
+			return listPage[T]{}, fmt.Errorf("invalid cursor for this sort")
+		}
+		kind, _ := listFieldKind(itemType, sortField)
+		start = sort.Search(len(matched), func(i int) bool {
+			value, _ := listFieldValue(matched[i], sortField)
+			id, _ := listFieldValue(matched[i], idField)
+			return listKeyAfter(value, id, cursor.Value, cursor.ID, kind, descending)
+		})
+	}
+	end := min(start+limit, len(matched))
+	page.Items = matched[start:end]
+	if end < len(matched) {
+		last := matched[end-1]
+		value, _ := listFieldValue(last, sortField)
+		id, _ := listFieldValue(last, idField)
+		page.NextCursor = encodeListCursor(listCursor{Sort: sortSpecOrDefault(sortSpec, idField), Value: value, ID: id})
+	}
+	return page, nil
+}
+
+func sortSpecOrDefault(sortSpec, idField string) string {
+	if sortSpec == "" {
+		return idField
+	}
+	return sortSpec
+}
+
+func encodeListCursor(cursor listCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(raw string) (listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return listCursor{}, err
+	}
+	var cursor listCursor
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
+
+func listItemLess[T any](a, b T, sortField, idField string, kind reflect.Kind, descending bool) bool {
+	aValue, _ := listFieldValue(a, sortField)
+	bValue, _ := listFieldValue(b, sortField)
+	aID, _ := listFieldValue(a, idField)
+	bID, _ := listFieldValue(b, idField)
+	return listKeyAfter(bValue, bID, aValue, aID, kind, descending)
+}
+
+// listKeyAfter reports whether the key (value, id) sorts after (afterValue,
+// afterID). IDs break ties in ascending order either way.
+func listKeyAfter(value, id, afterValue, afterID string, kind reflect.Kind, descending bool) bool {
+	cmp := compareListValues(value, afterValue, kind)
+	if descending {
+		cmp = -cmp
+	}
+	if cmp != 0 {
+		return cmp > 0
+	}
+	return id > afterID
+}
+
+func compareListValues(a, b string, kind reflect.Kind) int {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		af, _ := strconv.ParseFloat(a, 64)
+		bf, _ := strconv.ParseFloat(b, 64)
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	case reflect.Struct:
+		at, _ := time.Parse(time.RFC3339Nano, a)
+		bt, _ := time.Parse(time.RFC3339Nano, b)
+		return at.Compare(bt)
+	default:
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}
+}
+
+var listTimeType = reflect.TypeOf(time.Time{})
+
+func listFieldKind(t reflect.Type, path string) (reflect.Kind, bool) {
+	for _, name := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || t == listTimeType {
+			return reflect.Invalid, false
+		}
+		field, ok := jsonField(t, name)
+		if !ok {
+			return reflect.Invalid, false
+		}
+		t = field.Type
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return t.Kind(), true
+	case reflect.Struct:
+		return reflect.Struct, t == listTimeType
+	default:
+		return reflect.Invalid, false
+	}
+}
+
+func listFieldValue(item any, path string) (string, bool) {
+	value := reflect.ValueOf(item)
+	for _, name := range strings.Split(path, ".") {
+		for value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return "", false
+			}
+			value = value.Elem()
+		}
+		if value.Kind() != reflect.Struct {
+			return "", false
+		}
+		field, ok := jsonField(value.Type(), name)
+		if !ok {
+			return "", false
+		}
+		value = value.FieldByIndex(field.Index)
+	}
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return "", false
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64), true
+	case reflect.Struct:
+		if t, ok := value.Interface().(time.Time); ok {
+			return t.UTC().Format(time.RFC3339Nano), true
+		}
+	}
+	return "", false
+}
+
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tagName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tagName == "-" {
+			continue
+		}
+		if tagName == "" {
+			tagName = field.Name
+		}
+		if tagName == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestApplyListQueryPagesFiltersAndSorts(t *testing.T) {
+	connectors := []connectorView{
+		{ID: "c", TenantID: "acme", Connected: true, Load: &ConnectorLoad{InFlight: 1}},
+		{ID: "a", TenantID: "acme", Connected: false},
+		{ID: "e", TenantID: "beta", Connected: true, Load: &ConnectorLoad{InFlight: 7}},
+		{ID: "b", TenantID: "acme", Connected: true, Load: &ConnectorLoad{InFlight: 3}},
+		{ID: "d", TenantID: "acme", Connected: true, Load: &ConnectorLoad{InFlight: 3}},
+	}
+
+	page, err := applyListQuery(url.Values{}, connectors, "id")
+	if err != nil || len(page.Items) != 5 || page.Items[0].ID != "c" || page.NextCursor != "" {
+		t.Fatalf("expected unpaged list in original order, got %+v err=%v", page, err)
+	}
+
+	var ids []string
+	query := url.Values{"limit": {"2"}, "tenant_id": {"acme"}}
+	for {
+		page, err := applyListQuery(query, connectors, "id")
+		if err != nil {
+			t.Fatalf("page: %v", err)
+		}
+		if page.Total != 4 {
+			t.Fatalf("expected 4 acme connectors in total, got %d", page.Total)
+		}
+		for _, item := range page.Items {
+			ids = append(ids, item.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+	if got := ids; len(got) != 4 || got[0] != "a" || got[1] != "b" || got[2] != "c" || got[3] != "d" {
+		t.Fatalf("expected acme connectors a-d by id across pages, got %v", got)
+	}
+
+	page, err = applyListQuery(url.Values{"connected": {"true"}, "sort": {"-load.in_flight"}}, connectors, "id")
+	if err != nil {
+		t.Fatalf("sort by nested field: %v", err)
+	}
+	if len(page.Items) != 4 || page.Items[0].ID != "e" || page.Items[1].ID != "b" || page.Items[2].ID != "d" || page.Items[3].ID != "c" {
+		t.Fatalf("expected connected connectors by in-flight desc with id ties, got %+v", page.Items)
+	}
+
+	first, _ := applyListQuery(url.Values{"limit": {"1"}, "sort": {"-load.in_flight"}}, connectors, "id")
+	if _, err := applyListQuery(url.Values{"limit": {"1"}, "cursor": {first.NextCursor}}, connectors, "id"); err == nil {
+		t.Fatalf("expected cursor from another sort to be rejected")
+	}
+	for _, bad := range []url.Values{{"limit": {"0"}}, {"limit": {"501"}}, {"sort": {"labels"}}, {"sort": {"nope"}}} {
+		if _, err := applyListQuery(bad, connectors, "id"); err == nil {
+			t.Fatalf("expected %v to be rejected", bad)
+		}
+	}
+}
+
+func TestTenantRoutesListSupportsPagination(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	for _, id := range []string{"web", "api-v2", "docs"} {
+		if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: id, Target: "http://127.0.0.1:3000"}); err != nil {
+			t.Fatalf("upsert route %s: %v", id, err)
+		}
+	}
+	sessionID, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tenants/default/routes?limit=2&sort=-id", nil)
+	req.Header.Set("Authorization", "Bearer "+sessionID)
+	recorder := httptest.NewRecorder()
+	server.handleTenantSubresources(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var payload struct {
+		Routes     []routeView `json:"routes"`
+		Total      int         `json:"total"`
+		NextCursor string      `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if payload.Total != 3 || len(payload.Routes) != 2 || payload.Routes[0].ID != "web" || payload.Routes[1].ID != "docs" || payload.NextCursor == "" {
+		t.Fatalf("expected first page web, docs with a cursor, got %+v", payload)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/tenants/default/routes?limit=x", nil)
+	req.Header.Set("Authorization", "Bearer "+sessionID)
+	recorder = httptest.NewRecorder()
+	server.handleTenantSubresources(recorder, req)
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid limit, got %d", recorder.Code)
+	}
+}
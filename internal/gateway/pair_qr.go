@@ -0,0 +1,25 @@
+package gateway
+
+import "github.com/szaher/try/proxer/internal/qrcode"
+
+const (
+	pairQRModulePixels = 8
+	pairQRBorder       = 4
+)
+
+type pairQRCode struct {
+	SVG string `json:"svg"`
+	PNG []byte `json:"png"`
+}
+
+func newPairQRCode(link string) (*pairQRCode, error) {
+	code, err := qrcode.Encode(link)
+	if err != nil {
+		return nil, err
+	}
+	image, err := code.PNG(pairQRModulePixels, pairQRBorder)
+	if err != nil {
+		return nil, err
+	}
+	return &pairQRCode{SVG: code.SVG(pairQRBorder), PNG: image}, nil
+}
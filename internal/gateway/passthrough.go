@@ -0,0 +1,450 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/httpx"
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+// TLSPassthrough makes the TLS listener forward connections whose SNI names
+// one of Hostnames to the route's upstream without terminating them, so TLS
+// runs end to end between the caller and the local service. The gateway never
+// sees the plaintext: route tokens, rewrites, CORS, middleware and per-request
+// metrics do not apply.
+type TLSPassthrough struct {
+	Hostnames []string `json:"hostnames"`
+}
+
+const (
+	maxPassthroughHostnames   = 16
+	passthroughConnectTimeout = 10 * time.Second
+	clientHelloTimeout        = 10 * time.Second
+	streamCopyBufferSize      = 32 << 10
+)
+
+var errClientHelloRead = errors.New("client hello read")
+
+func normalizeTLSPassthrough(input *TLSPassthrough) (*TLSPassthrough, error) {
+	if input == nil || len(input.Hostnames) == 0 {
+		return nil, nil
+	}
+	if len(input.Hostnames) > maxPassthroughHostnames {
+		return nil, fmt.Errorf("tls_passthrough supports at most %d hostnames", maxPassthroughHostnames)
+	}
+	seen := make(map[string]bool, len(input.Hostnames))
+	hostnames := make([]string, 0, len(input.Hostnames))
+	for _, raw := range input.Hostnames {
+		hostname, err := normalizeDomainHostname(raw)
+		if err != nil {
+			return nil, fmt.Errorf("tls_passthrough: %w", err)
+		}
+		if !seen[hostname] {
+			seen[hostname] = true
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	sort.Strings(hostnames)
+	return &TLSPassthrough{Hostnames: hostnames}, nil
+}
+
+func (s *Server) passthroughHostnameAllowed(tenantID, hostname string) bool {
+	if hostname == "" || hostname == s.gatewayHostname() {
+		return false
+	}
+	for _, allowed := range s.config().PassthroughHostnames {
+		if hostname == allowed {
+			return true
+		}
+	}
+	domain, ok := s.domainStore.Verified(hostname)
+	return ok && domain.TenantID == normalizeIdentifier(tenantID)
+}
+
+func (s *Server) validateTLSPassthrough(tenantID string, input *TLSPassthrough) error {
+	passthrough, err := normalizeTLSPassthrough(input)
+	if err != nil || passthrough == nil {
+		return err
+	}
+	for _, hostname := range passthrough.Hostnames {
+		if !s.passthroughHostnameAllowed(tenantID, hostname) {
+			return fmt.Errorf("tls_passthrough hostname %q must be a verified custom domain of the tenant", hostname)
+		}
+	}
+	return nil
+}
+
+func (s *RuleStore) checkPassthroughHostnamesLocked(key string, passthrough *TLSPassthrough) error {
+	if passthrough == nil {
+		return nil
+	}
+	for otherKey, rule := range s.rules {
+		if otherKey == key || rule.TLSPassthrough == nil {
+			continue
+		}
+		for _, hostname := range passthrough.Hostnames {
+			for _, taken := range rule.TLSPassthrough.Hostnames {
+				if hostname == taken {
+					return fmt.Errorf("tls_passthrough hostname %q is used by another route", hostname)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *RuleStore) PassthroughRoute(hostname string) (Rule, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, rule := range s.rules {
+		if rule.TLSPassthrough == nil {
+			continue
+		}
+		for _, candidate := range rule.TLSPassthrough.Hostnames {
+			if candidate == hostname {
+				return rule, true
+			}
+		}
+	}
+	return Rule{}, false
+}
+
+func (s *Server) passthroughRoute(serverName string) (Rule, bool) {
+	hostname := requestHostname(serverName)
+	if hostname == "" {
+		return Rule{}, false
+	}
+	rule, ok := s.ruleStore.PassthroughRoute(hostname)
+	if !ok || rule.ScheduleState(time.Now().UTC()) != RouteScheduleActive {
+		return Rule{}, false
+	}
+	// Checked again here as the domain may have failed verification or been
+	// removed since the route was saved.
+	if !s.passthroughHostnameAllowed(rule.TenantID, hostname) {
+		return Rule{}, false
+	}
+	return rule, true
+}
+
+// passthroughListener sends TLS connections whose SNI names a passthrough
+// route to servePassthrough and the rest on to the TLS server. ClientHellos
+// are read off the accept loop so a slow client cannot stall other accepts.
+type passthroughListener struct {
+	net.Listener
+	server    *Server
+	conns     chan net.Conn
+	errs      chan error
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *Server) newPassthroughListener(inner net.Listener) *passthroughListener {
+	listener := &passthroughListener{
+		Listener: inner,
+		server:   s,
+		conns:    make(chan net.Conn),
+		errs:     make(chan error),
+		closed:   make(chan struct{}),
+	}
+	go listener.acceptLoop()
+	return listener
+}
+
+func (l *passthroughListener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			select {
+			case l.errs <- err:
+			case <-l.closed:
+				return
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go l.route(conn)
+	}
+}
+
+func (l *passthroughListener) route(conn net.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(clientHelloTimeout))
+	serverName, peeked, err := peekServerName(conn)
+	_ = conn.SetReadDeadline(time.Time{})
+	replayed := &peekedConn{Conn: conn, reader: io.MultiReader(bytes.NewReader(peeked), conn)}
+	if err == nil {
+		if rule, ok := l.server.passthroughRoute(serverName); ok {
+			l.server.servePassthrough(replayed, rule, serverName)
+			return
+		}
+	}
+
+	select {
+	case l.conns <- replayed:
+	case <-l.closed:
+		_ = conn.Close()
+	}
+}
+
+func (l *passthroughListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errs:
+		return nil, err
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *passthroughListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
+
+// peekServerName reads the ClientHello from conn and returns its SNI along
+// with the bytes consumed, which must be replayed to whoever serves conn.
+func peekServerName(conn net.Conn) (string, []byte, error) {
+	var peeked bytes.Buffer
+	serverName, seen := "", false
+	err := tls.Server(readOnlyConn{reader: io.TeeReader(conn, &peeked)}, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName, seen = hello.ServerName, true
+			return nil, errClientHelloRead
+		},
+	}).Handshake()
+	if !seen {
+		return "", peeked.Bytes(), err
+	}
+	return serverName, peeked.Bytes(), nil
+}
+
+// readOnlyConn lets crypto/tls parse a ClientHello without answering it.
+type readOnlyConn struct {
+	reader io.Reader
+}
+
+func (c readOnlyConn) Read(p []byte) (int, error)     { return c.reader.Read(p) }
+func (readOnlyConn) Write([]byte) (int, error)        { return 0, io.ErrClosedPipe }
+func (readOnlyConn) Close() error                     { return nil }
+func (readOnlyConn) LocalAddr() net.Addr              { return nil }
+func (readOnlyConn) RemoteAddr() net.Addr             { return nil }
+func (readOnlyConn) SetDeadline(time.Time) error      { return nil }
+func (readOnlyConn) SetReadDeadline(time.Time) error  { return nil }
+func (readOnlyConn) SetWriteDeadline(time.Time) error { return nil }
+
+type peekedConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func (c *peekedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func (s *Server) servePassthrough(conn net.Conn, rule Rule, serverName string) {
+	defer conn.Close()
+	routeKey := MakeTunnelKey(rule.TenantID, rule.ID)
+	requestID := s.nextRequestID()
+	if clientIP := extractIP(conn.RemoteAddr().String()); clientIP != "" {
+		if _, _, allowed := s.admitClientIP(clientIP, requestID, time.Now().UTC()); !allowed {
+			return
+		}
+	}
+	if _, inactive := s.tenantInactive(rule.TenantID); inactive {
+		return
+	}
+	plan, _ := s.planStore.GetTenantPlan(rule.TenantID)
+	usage := s.planStore.GetUsage(rule.TenantID, "")
+	if capBytes := int64(plan.MaxMonthlyGB * bytesPerGB); capBytes > 0 && usage.BytesIn+usage.BytesOut >= capBytes {
+		s.planStore.RecordBlockedRequest(rule.TenantID)
+		return
+	}
+	if !rule.UsesConnector() {
+		bytesIn, bytesOut := s.passthroughDirect(conn, rule, routeKey, requestID)
+		s.recordTrafficUsage(rule.TenantID, rule.ID, "", plan, bytesIn, bytesOut)
+		return
+	}
+
+	connectorID, ok := s.resolveConnector(rule)
+	if !ok {
+		s.hub.RecordProxyFailure(routeKey, 0, "tls passthrough: no online connector")
+		return
+	}
+	counted := &countingConn{Conn: conn}
+	defer func() {
+		if read, written := counted.read.Load(), counted.written.Load(); read+written > 0 {
+			s.recordTrafficUsage(rule.TenantID, rule.ID, connectorID, plan, read, written)
+		}
+	}()
+	stream := s.hub.openStream(requestID, connectorID, counted, s.routeBandwidth(rule), s.connectorBandwidth(connectorID))
+	defer s.hub.closeStream(requestID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), passthroughConnectTimeout)
+	defer cancel()
+	response, err := s.hub.DispatchProxyRequestToConnector(ctx, connectorID, routeKey, &protocol.ProxyRequest{
+		RequestID:   requestID,
+		Method:      http.MethodConnect,
+		Host:        serverName,
+		RemoteAddr:  conn.RemoteAddr().String(),
+		LocalTarget: rule.localTarget(),
+		Stream:      protocol.StreamTCP,
+	})
+	if err == nil && response.Error != "" {
+		err = errors.New(response.Error)
+	}
+	if err != nil {
+		s.logger.Printf("tls passthrough route=%s request_id=%s: %v", routeKey, requestID, err)
+		return
+	}
+
+	select {
+	case <-stream.attached:
+	case <-stream.done:
+		return
+	case <-time.After(passthroughConnectTimeout):
+		s.logger.Printf("tls passthrough route=%s request_id=%s: agent did not attach the stream", routeKey, requestID)
+		return
+	}
+	<-stream.done
+}
+
+func (s *Server) passthroughDirect(conn net.Conn, rule Rule, routeKey, requestID string) (int64, int64) {
+	start := time.Now()
+	target, err := url.Parse(rule.Target)
+	if err != nil {
+		s.hub.RecordProxyFailure(routeKey, 0, fmt.Sprintf("tls passthrough: invalid target: %v", err))
+		return 0, 0
+	}
+	address := target.Host
+	if target.Port() == "" {
+		port := "443"
+		if target.Scheme == "http" {
+			port = "80"
+		}
+		address = net.JoinHostPort(target.Hostname(), port)
+	}
+	upstream, err := net.DialTimeout("tcp", address, passthroughConnectTimeout)
+	if err != nil {
+		s.hub.RecordProxyFailure(routeKey, 0, fmt.Sprintf("tls passthrough: %v", err))
+		return 0, 0
+	}
+	defer upstream.Close()
+
+	bytesIn, bytesOut := pipeConns(conn, upstream, s.routeBandwidth(rule))
+	s.hub.RecordProxyResponse(&protocol.ProxyResponse{
+		RequestID: requestID,
+		TunnelID:  routeKey,
+		Status:    http.StatusOK,
+		LatencyMs: time.Since(start).Milliseconds(),
+		BytesIn:   bytesIn,
+		BytesOut:  bytesOut,
+	})
+	return bytesIn, bytesOut
+}
+
+type countingConn struct {
+	net.Conn
+	read    atomic.Int64
+	written atomic.Int64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+func pipeConns(client, upstream net.Conn, bandwidth ...*httpx.BandwidthLimiter) (int64, int64) {
+	ctx := context.Background()
+	var bytesIn int64
+	done := make(chan struct{})
+	go func() {
+		bytesIn, _ = io.Copy(httpx.ThrottleWriter(ctx, upstream, bandwidth...), client)
+		_ = upstream.Close()
+		close(done)
+	}()
+	bytesOut, _ := io.Copy(httpx.ThrottleWriter(ctx, client, bandwidth...), upstream)
+	_ = client.Close()
+	<-done
+	return bytesIn, bytesOut
+}
+
+func (s *Server) handleAgentStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	query := r.URL.Query()
+	sessionID := strings.TrimSpace(query.Get("session_id"))
+	requestID := strings.TrimSpace(query.Get("request_id"))
+	if sessionID == "" || requestID == "" {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing session_id or request_id")
+		return
+	}
+
+	stream, err := s.hub.attachStream(sessionID, requestID, r.Method == http.MethodPost)
+	switch {
+	case errors.Is(err, ErrUnknownSession):
+		writeAPIError(w, http.StatusNotFound, errCodeUnknownSession, err.Error())
+		return
+	case errors.Is(err, ErrUnknownStream):
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, err.Error())
+		return
+	case err != nil:
+		writeAPIError(w, http.StatusConflict, errCodeConflict, err.Error())
+		return
+	}
+	defer stream.close()
+
+	if r.Method == http.MethodPost {
+		_, _ = io.Copy(httpx.ThrottleWriter(r.Context(), stream.conn, stream.bandwidth...), r.Body)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	controller := http.NewResponseController(w)
+	if err := controller.Flush(); err != nil {
+		return
+	}
+	buffer := make([]byte, streamCopyBufferSize)
+	for {
+		n, readErr := stream.conn.Read(buffer)
+		if n > 0 {
+			if err := httpx.WaitBandwidth(r.Context(), n, stream.bandwidth...); err != nil {
+				return
+			}
+			if _, err := w.Write(buffer[:n]); err != nil {
+				return
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+		}
+		if readErr != nil {
+			return
+		}
+	}
+}
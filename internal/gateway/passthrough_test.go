@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+)
+
+func TestPeekServerNameReplaysClientHello(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		_ = tls.Client(client, &tls.Config{ServerName: "Shop.Example.com", InsecureSkipVerify: true}).Handshake()
+		_ = client.Close()
+	}()
+
+	serverName, peeked, err := peekServerName(server)
+	if err != nil {
+		t.Fatalf("peek server name: %v", err)
+	}
+	if serverName != "Shop.Example.com" || len(peeked) == 0 || peeked[0] != 0x16 {
+		t.Fatalf("unexpected peek result %q with %d bytes", serverName, len(peeked))
+	}
+}
+
+func TestPassthroughHostnamesAreUniqueAcrossRoutes(t *testing.T) {
+	store := NewRuleStore()
+	if _, err := store.UpsertTenant(Tenant{ID: "acme"}); err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+	passthrough := &TLSPassthrough{Hostnames: []string{"Shop.Example.com", "shop.example.com"}}
+	rule, err := store.UpsertForTenant("acme", Rule{ID: "shop", Target: "https://10.0.0.5", TLSPassthrough: passthrough})
+	if err != nil {
+		t.Fatalf("upsert shop: %v", err)
+	}
+	if got := rule.TLSPassthrough.Hostnames; len(got) != 1 || got[0] != "shop.example.com" {
+		t.Fatalf("expected normalized hostnames, got %v", got)
+	}
+	if _, err := store.UpsertForTenant(DefaultTenantID, Rule{ID: "copy", Target: "https://10.0.0.6", TLSPassthrough: passthrough}); err == nil {
+		t.Fatalf("expected a duplicate passthrough hostname to be rejected")
+	}
+	if found, ok := store.PassthroughRoute("shop.example.com"); !ok || found.ID != "shop" {
+		t.Fatalf("expected passthrough lookup to find shop, got %+v %v", found, ok)
+	}
+}
+
+func TestPassthroughHostnamesRequireOwnership(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", PublicBaseURL: "https://gw.example.com", PassthroughHostnames: []string{"shared.example.com"}}, nil)
+	for _, tenantID := range []string{"acme", "rival"} {
+		if _, err := server.ruleStore.UpsertTenant(Tenant{ID: tenantID}); err != nil {
+			t.Fatalf("create tenant: %v", err)
+		}
+	}
+	for _, hostname := range []string{"shop.acme.example", "pending.acme.example"} {
+		if _, err := server.domainStore.Attach("acme", "shop", hostname); err != nil {
+			t.Fatalf("attach domain: %v", err)
+		}
+	}
+	server.domainStore.RecordCheck("shop.acme.example", nil)
+
+	cases := []struct {
+		tenantID, hostname string
+		allowed            bool
+	}{
+		{"acme", "shop.acme.example", true},
+		{"acme", "shared.example.com", true},
+		{"acme", "pending.acme.example", false},
+		{"acme", "gw.example.com", false},
+		{"rival", "shop.acme.example", false},
+		{"rival", "unclaimed.example.com", false},
+	}
+	for _, tc := range cases {
+		err := server.validateTLSPassthrough(tc.tenantID, &TLSPassthrough{Hostnames: []string{tc.hostname}})
+		if (err == nil) != tc.allowed {
+			t.Fatalf("%s passing %s through: expected allowed=%v, got %v", tc.tenantID, tc.hostname, tc.allowed, err)
+		}
+	}
+
+	// Routes saved without the API check, such as restored state, are not
+	// served for hostnames the tenant does not own.
+	if _, err := server.ruleStore.UpsertForTenant("rival", Rule{ID: "grab", Target: "https://10.0.0.7", TLSPassthrough: &TLSPassthrough{Hostnames: []string{"shop.acme.example"}}}); err != nil {
+		t.Fatalf("upsert rival route: %v", err)
+	}
+	if _, ok := server.passthroughRoute("shop.acme.example"); ok {
+		t.Fatal("expected another tenant's verified domain not to be passed through")
+	}
+}
@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const maxPathRoutes = 32
+
+type PathRoute struct {
+	Prefix        string `json:"prefix"`
+	Target        string `json:"target,omitempty"`
+	LocalScheme   string `json:"local_scheme,omitempty"`
+	LocalHost     string `json:"local_host,omitempty"`
+	LocalPort     int    `json:"local_port,omitempty"`
+	LocalBasePath string `json:"local_base_path,omitempty"`
+}
+
+// normalizePathRoutes validates sub-rules against the parent route mode and
+// orders them longest prefix first so the first match wins.
+func normalizePathRoutes(input []PathRoute, usesConnector bool, defaultScheme, defaultHost string) ([]PathRoute, error) {
+	if len(input) == 0 {
+		return nil, nil
+	}
+	if len(input) > maxPathRoutes {
+		return nil, fmt.Errorf("path_routes supports at most %d entries", maxPathRoutes)
+	}
+	out := make([]PathRoute, 0, len(input))
+	seen := make(map[string]struct{}, len(input))
+	for _, item := range input {
+		prefix := strings.TrimSpace(item.Prefix)
+		if !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("path_routes prefix %q must start with /", item.Prefix)
+		}
+		if len(prefix) > 1 {
+			prefix = strings.TrimRight(prefix, "/")
+		}
+		if _, ok := seen[prefix]; ok {
+			return nil, fmt.Errorf("duplicate path_routes prefix %q", prefix)
+		}
+		seen[prefix] = struct{}{}
+
+		normalized := PathRoute{Prefix: prefix}
+		if !usesConnector {
+			target := strings.TrimSpace(item.Target)
+			parsed, err := url.Parse(target)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || strings.TrimSpace(parsed.Host) == "" {
+				return nil, fmt.Errorf("path_routes %q target must be an http or https URL with a host", prefix)
+			}
+			normalized.Target = target
+			out = append(out, normalized)
+			continue
+		}
+
+		normalized.LocalScheme = strings.ToLower(strings.TrimSpace(item.LocalScheme))
+		if normalized.LocalScheme == "" {
+			normalized.LocalScheme = defaultScheme
+		}
+		if normalized.LocalScheme != "http" && normalized.LocalScheme != "https" {
+			return nil, fmt.Errorf("path_routes %q local_scheme must be http or https", prefix)
+		}
+		normalized.LocalHost = strings.TrimSpace(item.LocalHost)
+		if normalized.LocalHost == "" {
+			normalized.LocalHost = defaultHost
+		}
+		if strings.Contains(normalized.LocalHost, "://") {
+			return nil, fmt.Errorf("path_routes %q local_host should not include scheme", prefix)
+		}
+		if item.LocalPort < 1 || item.LocalPort > 65535 {
+			return nil, fmt.Errorf("path_routes %q local_port must be between 1 and 65535", prefix)
+		}
+		normalized.LocalPort = item.LocalPort
+		normalized.LocalBasePath = strings.TrimSpace(item.LocalBasePath)
+		if normalized.LocalBasePath != "" && !strings.HasPrefix(normalized.LocalBasePath, "/") {
+			normalized.LocalBasePath = "/" + normalized.LocalBasePath
+		}
+		out = append(out, normalized)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return len(out[i].Prefix) > len(out[j].Prefix) })
+	return out, nil
+}
+
+func (p PathRoute) matches(path string) bool {
+	if p.Prefix == "/" {
+		return true
+	}
+	if !strings.HasPrefix(path, p.Prefix) {
+		return false
+	}
+	return len(path) == len(p.Prefix) || path[len(p.Prefix)] == '/'
+}
+
+func (r Rule) upstreamFor(path string) Rule {
+	if path == "" {
+		path = "/"
+	}
+	for _, pathRoute := range r.PathRoutes {
+		if !pathRoute.matches(path) {
+			continue
+		}
+		if r.UsesConnector() {
+			r.LocalScheme = pathRoute.LocalScheme
+			r.LocalHost = pathRoute.LocalHost
+			r.LocalPort = pathRoute.LocalPort
+			r.LocalSocket = ""
+			r.LocalBasePath = pathRoute.LocalBasePath
+		} else {
+			r.Target = pathRoute.Target
+		}
+		return r
+	}
+	return r
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRuleUpstreamForPrefersLongestPrefix(t *testing.T) {
+	store := NewRuleStore()
+	rule, err := store.UpsertForTenant(DefaultTenantID, Rule{
+		ID:          "dev",
+		ConnectorID: "laptop",
+		LocalPort:   3000,
+		PathRoutes: []PathRoute{
+			{Prefix: "/api/", LocalPort: 8080},
+			{Prefix: "/api/admin", LocalPort: 9090, LocalBasePath: "internal"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	cases := map[string]int{
+		"/":                3000,
+		"/apis":            3000,
+		"/api":             8080,
+		"/api/users":       8080,
+		"/api/admin/stats": 9090,
+	}
+	for path, wantPort := range cases {
+		if got := rule.upstreamFor(path); got.LocalPort != wantPort || got.LocalHost != "127.0.0.1" {
+			t.Fatalf("path %q: expected port %d on default host, got %+v", path, wantPort, got)
+		}
+	}
+	if got := rule.upstreamFor("/api/admin").LocalBasePath; got != "/internal" {
+		t.Fatalf("expected normalized base path, got %q", got)
+	}
+}
+
+func TestRuleStoreRejectsInvalidPathRoutes(t *testing.T) {
+	store := NewRuleStore()
+	invalid := []Rule{
+		{ID: "a", Target: "http://127.0.0.1:3000", PathRoutes: []PathRoute{{Prefix: "api", Target: "http://127.0.0.1:8080"}}},
+		{ID: "b", Target: "http://127.0.0.1:3000", PathRoutes: []PathRoute{{Prefix: "/api", LocalPort: 8080}}},
+		{ID: "c", ConnectorID: "laptop", LocalPort: 3000, PathRoutes: []PathRoute{{Prefix: "/api"}}},
+		{ID: "d", Target: "http://127.0.0.1:3000", PathRoutes: []PathRoute{
+			{Prefix: "/api", Target: "http://127.0.0.1:8080"},
+			{Prefix: "/api/", Target: "http://127.0.0.1:8081"},
+		}},
+	}
+	for _, rule := range invalid {
+		if _, err := store.UpsertForTenant(DefaultTenantID, rule); err == nil {
+			t.Fatalf("expected route %q to be rejected", rule.ID)
+		}
+	}
+}
+
+func TestProxyDirectRouteUsesPathRouteTarget(t *testing.T) {
+	frontend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "frontend "+r.URL.Path)
+	}))
+	defer frontend.Close()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "backend "+r.URL.Path)
+	}))
+	defer backend.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
+		ID:         "web",
+		Target:     frontend.URL,
+		PathRoutes: []PathRoute{{Prefix: "/api", Target: backend.URL}},
+	}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	for path, want := range map[string]string{
+		"/t/web/api/users": "backend /api/users",
+		"/t/web/index.js":  "frontend /index.js",
+	} {
+		recorder := httptest.NewRecorder()
+		server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if got := recorder.Body.String(); got != want {
+			t.Fatalf("path %q: expected %q, got %q", path, want, got)
+		}
+	}
+}
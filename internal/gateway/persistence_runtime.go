@@ -9,14 +9,25 @@ import (
 
 func (s *Server) buildSnapshot() ServerSnapshot {
 	return ServerSnapshot{
-		Version:    1,
-		SavedAt:    time.Now().UTC(),
-		AuthUsers:  s.authStore.SnapshotUsers(),
-		Rules:      s.ruleStore.Snapshot(),
-		Connectors: s.connectorStore.Snapshot(),
-		Plans:      s.planStore.Snapshot(),
-		Incidents:  s.incidentStore.Snapshot(),
-		TLSRecords: s.tlsStore.SnapshotRecords(),
+		Version:      1,
+		SavedAt:      time.Now().UTC(),
+		AuthUsers:    s.authStore.SnapshotUsers(),
+		Rules:        s.ruleStore.Snapshot(),
+		Connectors:   s.connectorStore.Snapshot(),
+		Plans:        s.planStore.Snapshot(),
+		Incidents:    s.incidentStore.Snapshot(),
+		Audit:        s.auditStore.Snapshot(),
+		IPBans:       s.ipBans.List(time.Now().UTC()),
+		Domains:      s.domainStore.Snapshot(),
+		TLSRecords:   s.tlsStore.SnapshotRecords(),
+		Timeseries:   s.hub.Timeseries().Snapshot(),
+		Availability: s.hub.Availability().Snapshot(),
+		Transfer:     s.transfer.Snapshot(),
+		Archives:     s.tenantArchives.Snapshot(),
+		Trash:        s.trash.Snapshot(),
+		Orgs:         s.orgStore.Snapshot(),
+		Forwards:     s.reverseForwards.Snapshot(),
+		SSHKeys:      s.sshKeys.Snapshot(),
 	}
 }
 
@@ -39,15 +50,29 @@ func (s *Server) restorePersistentState() error {
 		return nil
 	}
 
+	s.applySnapshot(snapshot)
+	s.logger.Printf("restored persisted state using driver=%s saved_at=%s", s.persistence.Driver(), snapshot.SavedAt.Format(time.RFC3339))
+	return nil
+}
+
+func (s *Server) applySnapshot(snapshot ServerSnapshot) {
 	s.authStore.RestoreUsers(snapshot.AuthUsers)
 	s.ruleStore.Restore(snapshot.Rules)
 	s.connectorStore.Restore(snapshot.Connectors)
 	s.planStore.Restore(snapshot.Plans)
 	s.incidentStore.Restore(snapshot.Incidents)
+	s.auditStore.Restore(snapshot.Audit)
+	s.ipBans.Restore(snapshot.IPBans)
+	s.domainStore.Restore(snapshot.Domains)
 	s.tlsStore.RestoreRecords(snapshot.TLSRecords)
-
-	s.logger.Printf("restored persisted state using driver=%s saved_at=%s", s.persistence.Driver(), snapshot.SavedAt.Format(time.RFC3339))
-	return nil
+	s.hub.Timeseries().Restore(snapshot.Timeseries)
+	s.hub.Availability().Restore(snapshot.Availability)
+	s.transfer.Restore(snapshot.Transfer)
+	s.tenantArchives.Restore(snapshot.Archives)
+	s.trash.Restore(snapshot.Trash)
+	s.orgStore.Restore(snapshot.Orgs)
+	s.reverseForwards.Restore(snapshot.Forwards)
+	s.sshKeys.Restore(snapshot.SSHKeys)
 }
 
 func (s *Server) persistState() {
@@ -88,7 +113,7 @@ func (s *Server) runPersistenceLoop(ctx context.Context) {
 func (s *Server) storageHealth() map[string]any {
 	if s.persistence == nil {
 		return map[string]any{
-			"driver": s.cfg.StorageDriver,
+			"driver": s.config().StorageDriver,
 			"status": "unknown",
 		}
 	}
@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const planFeatureTLS = "tls"
+
+type planFeatureError struct {
+	TenantID     string
+	PlanID       string
+	Feature      string
+	UpgradePlans []string
+}
+
+func (e *planFeatureError) Error() string {
+	message := fmt.Sprintf("plan %q of tenant %q does not include %s", e.PlanID, e.TenantID, e.Feature)
+	if len(e.UpgradePlans) > 0 {
+		message += "; upgrade to " + strings.Join(e.UpgradePlans, " or ")
+	}
+	return message
+}
+
+func (s *Server) enforceTLSFeature(tenantID string) error {
+	tenantID = normalizeIdentifier(tenantID)
+	plan, planID := s.planStore.GetTenantPlan(tenantID)
+	if plan.TLSEnabled {
+		return nil
+	}
+	upgrades := make([]string, 0)
+	for _, candidate := range s.planStore.ListPlans() {
+		if candidate.SelfServe && candidate.TLSEnabled {
+			upgrades = append(upgrades, candidate.ID)
+		}
+	}
+	return &planFeatureError{TenantID: tenantID, PlanID: planID, Feature: planFeatureTLS, UpgradePlans: upgrades}
+}
+
+func (s *Server) enforceCertificatePlans(hostname string) error {
+	checked := make(map[string]struct{})
+	for _, domain := range s.domainStore.Snapshot() {
+		if _, done := checked[domain.TenantID]; done || !hostMatches(hostname, domain.Hostname) {
+			continue
+		}
+		checked[domain.TenantID] = struct{}{}
+		if err := s.enforceTLSFeature(domain.TenantID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePlanFeatureError(w http.ResponseWriter, err error) {
+	var featureErr *planFeatureError
+	if !errors.As(err, &featureErr) {
+		writeAPIError(w, http.StatusForbidden, errCodePlanLimitExceeded, err.Error())
+		return
+	}
+	writeAPIErrorDetails(w, http.StatusForbidden, errCodePlanFeatureUnavailable, featureErr.Error(), map[string]any{
+		"tenant_id":     featureErr.TenantID,
+		"plan_id":       featureErr.PlanID,
+		"feature":       featureErr.Feature,
+		"upgrade_plans": featureErr.UpgradePlans,
+		"upgrade_url":   "/api/me/plan",
+	})
+}
@@ -0,0 +1,174 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+type PlanVersionTenants struct {
+	Plan    Plan     `json:"plan"`
+	Current bool     `json:"current"`
+	Tenants []string `json:"tenants"`
+}
+
+type migratePlanRequest struct {
+	FromVersion int      `json:"from_version,omitempty"`
+	TenantIDs   []string `json:"tenant_ids,omitempty"`
+}
+
+// planTermsEqual reports whether two plans differ only in bookkeeping
+// fields, so saving b over a needs no new version.
+func planTermsEqual(a, b Plan) bool {
+	for _, plan := range []*Plan{&a, &b} {
+		plan.Version = 0
+		plan.CreatedBy = ""
+		plan.CreatedAt = time.Time{}
+		plan.UpdatedAt = time.Time{}
+	}
+	return a == b
+}
+
+// planVersionLocked returns the given version of a plan; version zero, from
+// assignments made before plans were versioned, is the current one.
+func (s *PlanStore) planVersionLocked(planID string, version int) (Plan, bool) {
+	current, ok := s.plans[planID]
+	if !ok {
+		return Plan{}, false
+	}
+	if version == 0 || version == current.Version {
+		return current, true
+	}
+	for _, plan := range s.versions[planID] {
+		if plan.Version == version {
+			return plan, true
+		}
+	}
+	return current, true
+}
+
+func (s *PlanStore) ListPlanVersions(planID string) ([]PlanVersionTenants, bool) {
+	planID = normalizeIdentifier(planID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	current, ok := s.plans[planID]
+	if !ok {
+		return nil, false
+	}
+	byVersion := make(map[int]*PlanVersionTenants)
+	out := []*PlanVersionTenants{{Plan: current, Current: true, Tenants: []string{}}}
+	byVersion[current.Version] = out[0]
+	for _, plan := range s.versions[planID] {
+		entry := &PlanVersionTenants{Plan: plan, Tenants: []string{}}
+		byVersion[plan.Version] = entry
+		out = append(out, entry)
+	}
+	for _, assignment := range s.assignments {
+		if assignment.PlanID != planID {
+			continue
+		}
+		entry, ok := byVersion[assignment.PlanVersion]
+		if !ok {
+			entry = out[0]
+		}
+		entry.Tenants = append(entry.Tenants, assignment.TenantID)
+	}
+
+	versions := make([]PlanVersionTenants, 0, len(out))
+	for _, entry := range out {
+		sort.Strings(entry.Tenants)
+		versions = append(versions, *entry)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Plan.Version > versions[j].Plan.Version })
+	return versions, true
+}
+
+func (s *PlanStore) MigrateTenants(planID string, fromVersion int, tenantIDs []string) ([]TenantPlanAssignment, error) {
+	planID = normalizeIdentifier(planID)
+	only := make(map[string]bool, len(tenantIDs))
+	for _, tenantID := range tenantIDs {
+		only[normalizeIdentifier(tenantID)] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.plans[planID]
+	if !ok {
+		return nil, fmt.Errorf("plan %q not found", planID)
+	}
+	if fromVersion < 0 || fromVersion >= current.Version {
+		return nil, fmt.Errorf("from_version must be an older version of plan %q (current is %d)", planID, current.Version)
+	}
+	migrated := make([]TenantPlanAssignment, 0)
+	for tenantID, assignment := range s.assignments {
+		if assignment.PlanID != planID || assignment.PlanVersion == current.Version {
+			continue
+		}
+		if fromVersion > 0 && assignment.PlanVersion != fromVersion {
+			continue
+		}
+		if len(only) > 0 && !only[tenantID] {
+			continue
+		}
+		assignment.PlanVersion = current.Version
+		s.assignments[tenantID] = assignment
+		migrated = append(migrated, assignment)
+	}
+	sort.Slice(migrated, func(i, j int) bool { return migrated[i].TenantID < migrated[j].TenantID })
+	return migrated, nil
+}
+
+func (s *Server) handleAdminPlanVersions(w http.ResponseWriter, planID string) {
+	versions, ok := s.planStore.ListPlanVersions(planID)
+	if !ok {
+		writeAPIErrorDetails(w, http.StatusNotFound, errCodePlanNotFound, "plan not found", map[string]any{"plan_id": planID})
+		return
+	}
+	if normalizeIdentifier(planID) == "free" {
+		for _, tenant := range s.ruleStore.ListTenants() {
+			if _, assigned := s.planStore.GetTenantAssignment(tenant.ID); !assigned {
+				versions[0].Tenants = append(versions[0].Tenants, tenant.ID)
+			}
+		}
+		sort.Strings(versions[0].Tenants)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"plan_id":         versions[0].Plan.ID,
+		"current_version": versions[0].Plan.Version,
+		"versions":        versions,
+	})
+}
+
+func (s *Server) handleAdminPlanMigrate(w http.ResponseWriter, r *http.Request, user User, planID string) {
+	if _, ok := s.planStore.GetPlan(planID); !ok {
+		writeAPIErrorDetails(w, http.StatusNotFound, errCodePlanNotFound, "plan not found", map[string]any{"plan_id": planID})
+		return
+	}
+	var request migratePlanRequest
+	if !s.decodeJSON(w, r, &request, "plan migration payload") {
+		return
+	}
+	migrated, err := s.planStore.MigrateTenants(planID, request.FromVersion, request.TenantIDs)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	for _, assignment := range migrated {
+		s.auditStore.Record(user.Username, "plan.migrate", assignment.TenantID, map[string]string{
+			"plan_id":      assignment.PlanID,
+			"plan_version": fmt.Sprintf("%d", assignment.PlanVersion),
+		})
+		s.refreshTenantUsage(assignment.TenantID)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message":  fmt.Sprintf("%d tenants migrated", len(migrated)),
+		"plan_id":  normalizeIdentifier(planID),
+		"migrated": migrated,
+	})
+	if len(migrated) > 0 {
+		s.persistState()
+	}
+}
@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 	"sync"
@@ -9,20 +10,23 @@ import (
 )
 
 type Plan struct {
-	ID              string    `json:"id"`
-	Name            string    `json:"name"`
-	Description     string    `json:"description"`
-	MaxRoutes       int       `json:"max_routes"`
-	MaxConnectors   int       `json:"max_connectors"`
-	MaxRPS          float64   `json:"max_rps"`
-	MaxMonthlyGB    float64   `json:"max_monthly_gb"`
-	TLSEnabled      bool      `json:"tls_enabled"`
-	PriceMonthlyUSD float64   `json:"price_monthly_usd"`
-	PriceAnnualUSD  float64   `json:"price_annual_usd"`
-	PublicOrder     int       `json:"public_order"`
-	CreatedBy       string    `json:"created_by"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID                    string    `json:"id"`
+	Version               int       `json:"version"`
+	Name                  string    `json:"name"`
+	Description           string    `json:"description"`
+	MaxRoutes             int       `json:"max_routes"`
+	MaxConnectors         int       `json:"max_connectors"`
+	MaxRPS                float64   `json:"max_rps"`
+	MaxMonthlyGB          float64   `json:"max_monthly_gb"`
+	TLSEnabled            bool      `json:"tls_enabled"`
+	PriceMonthlyUSD       float64   `json:"price_monthly_usd"`
+	PriceAnnualUSD        float64   `json:"price_annual_usd"`
+	PublicOrder           int       `json:"public_order"`
+	SelfServe             bool      `json:"self_serve"`
+	MaxRequestTimeoutSecs int       `json:"max_request_timeout_seconds,omitempty"`
+	CreatedBy             string    `json:"created_by"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
 }
 
 type planPricingDefaults struct {
@@ -50,10 +54,14 @@ var defaultPlanPricingByID = map[string]planPricingDefaults{
 }
 
 type TenantPlanAssignment struct {
-	TenantID   string    `json:"tenant_id"`
-	PlanID     string    `json:"plan_id"`
-	AssignedBy string    `json:"assigned_by"`
-	AssignedAt time.Time `json:"assigned_at"`
+	TenantID          string     `json:"tenant_id"`
+	PlanID            string     `json:"plan_id"`
+	PlanVersion       int        `json:"plan_version,omitempty"`
+	PreviousPlanID    string     `json:"previous_plan_id,omitempty"`
+	ProratedAmountUSD float64    `json:"prorated_amount_usd"`
+	PeriodEnd         *time.Time `json:"period_end,omitempty"`
+	AssignedBy        string     `json:"assigned_by"`
+	AssignedAt        time.Time  `json:"assigned_at"`
 }
 
 type UsageSnapshot struct {
@@ -67,12 +75,16 @@ type UsageSnapshot struct {
 	BlockedRequests int64     `json:"blocked_requests"`
 	Warned80        bool      `json:"warned_80"`
 	Warned95        bool      `json:"warned_95"`
+	WarnedPercents  []int     `json:"warned_percents,omitempty"`
 	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 type PlanStore struct {
-	mu          sync.RWMutex
-	plans       map[string]Plan
+	mu    sync.RWMutex
+	plans map[string]Plan
+	// versions holds the superseded versions of each plan, oldest first,
+	// for tenants grandfathered on them.
+	versions    map[string][]Plan
 	assignments map[string]TenantPlanAssignment
 	usage       map[string]UsageSnapshot
 }
@@ -81,56 +93,67 @@ func NewPlanStore() *PlanStore {
 	now := time.Now().UTC()
 	plans := map[string]Plan{
 		"free": {
-			ID:              "free",
-			Name:            "Free",
-			Description:     "Starter plan",
-			MaxRoutes:       5,
-			MaxConnectors:   2,
-			MaxRPS:          10,
-			MaxMonthlyGB:    10,
-			TLSEnabled:      false,
-			PriceMonthlyUSD: 0,
-			PriceAnnualUSD:  0,
-			PublicOrder:     1,
-			CreatedBy:       "system",
-			CreatedAt:       now,
-			UpdatedAt:       now,
+			ID:                    "free",
+			Name:                  "Free",
+			Description:           "Starter plan",
+			MaxRoutes:             5,
+			MaxConnectors:         2,
+			MaxRPS:                10,
+			MaxMonthlyGB:          10,
+			TLSEnabled:            false,
+			PriceMonthlyUSD:       0,
+			PriceAnnualUSD:        0,
+			PublicOrder:           1,
+			SelfServe:             false,
+			MaxRequestTimeoutSecs: 30,
+			CreatedBy:             "system",
+			CreatedAt:             now,
+			UpdatedAt:             now,
 		},
 		"pro": {
-			ID:              "pro",
-			Name:            "Pro",
-			Description:     "Professional plan",
-			MaxRoutes:       50,
-			MaxConnectors:   10,
-			MaxRPS:          100,
-			MaxMonthlyGB:    500,
-			TLSEnabled:      true,
-			PriceMonthlyUSD: 20,
-			PriceAnnualUSD:  200,
-			PublicOrder:     2,
-			CreatedBy:       "system",
-			CreatedAt:       now,
-			UpdatedAt:       now,
+			ID:                    "pro",
+			Name:                  "Pro",
+			Description:           "Professional plan",
+			MaxRoutes:             50,
+			MaxConnectors:         10,
+			MaxRPS:                100,
+			MaxMonthlyGB:          500,
+			TLSEnabled:            true,
+			PriceMonthlyUSD:       20,
+			PriceAnnualUSD:        200,
+			PublicOrder:           2,
+			SelfServe:             false,
+			MaxRequestTimeoutSecs: 120,
+			CreatedBy:             "system",
+			CreatedAt:             now,
+			UpdatedAt:             now,
 		},
 		"business": {
-			ID:              "business",
-			Name:            "Business",
-			Description:     "Business scale plan",
-			MaxRoutes:       250,
-			MaxConnectors:   50,
-			MaxRPS:          500,
-			MaxMonthlyGB:    5000,
-			TLSEnabled:      true,
-			PriceMonthlyUSD: 100,
-			PriceAnnualUSD:  1000,
-			PublicOrder:     3,
-			CreatedBy:       "system",
-			CreatedAt:       now,
-			UpdatedAt:       now,
+			ID:                    "business",
+			Name:                  "Business",
+			Description:           "Business scale plan",
+			MaxRoutes:             250,
+			MaxConnectors:         50,
+			MaxRPS:                500,
+			MaxMonthlyGB:          5000,
+			TLSEnabled:            true,
+			PriceMonthlyUSD:       100,
+			PriceAnnualUSD:        1000,
+			PublicOrder:           3,
+			SelfServe:             false,
+			MaxRequestTimeoutSecs: 300,
+			CreatedBy:             "system",
+			CreatedAt:             now,
+			UpdatedAt:             now,
 		},
 	}
+	for id, plan := range plans {
+		plan.Version = 1
+		plans[id] = plan
+	}
 	return &PlanStore{
 		plans:       plans,
+		versions:    make(map[string][]Plan),
 		assignments: make(map[string]TenantPlanAssignment),
 		usage:       make(map[string]UsageSnapshot),
 	}
@@ -165,6 +188,14 @@ func (s *PlanStore) GetPlan(planID string) (Plan, bool) {
 }
 
 func (s *PlanStore) UpsertPlan(input Plan) (Plan, error) {
+	return s.upsertPlan(input, true)
+}
+
+func (s *PlanStore) ValidatePlan(input Plan) (Plan, error) {
+	return s.upsertPlan(input, false)
+}
+
+func (s *PlanStore) upsertPlan(input Plan, apply bool) (Plan, error) {
 	planID := normalizeIdentifier(input.ID)
 	if !identifierPattern.MatchString(planID) {
 		return Plan{}, fmt.Errorf("invalid plan id %q", planID)
@@ -185,13 +216,18 @@ func (s *PlanStore) UpsertPlan(input Plan) (Plan, error) {
 	if input.PublicOrder < 0 {
 		return Plan{}, fmt.Errorf("public_order must be >= 0")
 	}
+	if input.MaxRequestTimeoutSecs < 0 {
+		return Plan{}, fmt.Errorf("max_request_timeout_seconds must be >= 0")
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	now := time.Now().UTC()
-	existing, ok := s.plans[planID]
+	previous, ok := s.plans[planID]
+	existing := previous
 	if !ok {
 		existing.CreatedAt = now
+		existing.Version = 1
 	}
 	existing.ID = planID
 	existing.Name = name
@@ -204,6 +240,8 @@ func (s *PlanStore) UpsertPlan(input Plan) (Plan, error) {
 	existing.PriceMonthlyUSD = input.PriceMonthlyUSD
 	existing.PriceAnnualUSD = input.PriceAnnualUSD
 	existing.PublicOrder = input.PublicOrder
+	existing.SelfServe = input.SelfServe
+	existing.MaxRequestTimeoutSecs = input.MaxRequestTimeoutSecs
 	existing.CreatedBy = strings.TrimSpace(input.CreatedBy)
 	if existing.CreatedBy == "" {
 		existing.CreatedBy = "system"
@@ -217,7 +255,18 @@ func (s *PlanStore) UpsertPlan(input Plan) (Plan, error) {
 		existing.PublicOrder = defaults.PublicOrder
 	}
 	existing.UpdatedAt = now
-	s.plans[planID] = existing
+	// Changed terms make a new version; tenants keep the one they were
+	// assigned until they are migrated.
+	superseded := ok && !planTermsEqual(previous, existing)
+	if superseded {
+		existing.Version = previous.Version + 1
+	}
+	if apply {
+		if superseded {
+			s.versions[planID] = append(s.versions[planID], previous)
+		}
+		s.plans[planID] = existing
+	}
 	return existing, nil
 }
 
@@ -234,14 +283,64 @@ func (s *PlanStore) AssignTenantPlan(tenantID, planID, assignedBy string) (Tenan
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.plans[planID]; !ok {
+	plan, ok := s.plans[planID]
+	if !ok {
+		return TenantPlanAssignment{}, fmt.Errorf("plan %q not found", planID)
+	}
+	assignment := TenantPlanAssignment{
+		TenantID:    tenantID,
+		PlanID:      planID,
+		PlanVersion: plan.Version,
+		AssignedBy:  strings.TrimSpace(assignedBy),
+		AssignedAt:  time.Now().UTC(),
+	}
+	s.assignments[tenantID] = assignment
+	return assignment, nil
+}
+
+// ChangeTenantPlan moves a tenant to another plan mid-cycle and records the
+// prorated price difference for the remainder of the current month. A
+// positive amount is owed by the tenant, a negative amount is a credit.
+// check, when set, vets the target plan under the store lock, so the plan it
+// approved is the plan assigned; its error aborts the change.
+func (s *PlanStore) ChangeTenantPlan(tenantID, planID, changedBy string, now time.Time, check func(Plan) error) (TenantPlanAssignment, error) {
+	tenantID = normalizeIdentifier(tenantID)
+	planID = normalizeIdentifier(planID)
+	if !identifierPattern.MatchString(tenantID) {
+		return TenantPlanAssignment{}, fmt.Errorf("invalid tenant id %q", tenantID)
+	}
+	if !identifierPattern.MatchString(planID) {
+		return TenantPlanAssignment{}, fmt.Errorf("invalid plan id %q", planID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target, ok := s.plans[planID]
+	if !ok {
 		return TenantPlanAssignment{}, fmt.Errorf("plan %q not found", planID)
 	}
+	previous, previousPlanID := s.tenantPlanLocked(tenantID)
+	if previousPlanID == planID {
+		return TenantPlanAssignment{}, fmt.Errorf("tenant is already on plan %q", planID)
+	}
+	if check != nil {
+		if err := check(target); err != nil {
+			return TenantPlanAssignment{}, err
+		}
+	}
+
+	now = now.UTC()
+	periodEnd := billingPeriodEnd(now)
 	assignment := TenantPlanAssignment{
-		TenantID:   tenantID,
-		PlanID:     planID,
-		AssignedBy: strings.TrimSpace(assignedBy),
-		AssignedAt: time.Now().UTC(),
+		TenantID:          tenantID,
+		PlanID:            planID,
+		PlanVersion:       target.Version,
+		PreviousPlanID:    previousPlanID,
+		ProratedAmountUSD: prorateMonthlyPrice(previous.PriceMonthlyUSD, target.PriceMonthlyUSD, now),
+		PeriodEnd:         &periodEnd,
+		AssignedBy:        strings.TrimSpace(changedBy),
+		AssignedAt:        now,
 	}
 	s.assignments[tenantID] = assignment
 	return assignment, nil
@@ -255,17 +354,16 @@ func (s *PlanStore) GetTenantPlan(tenantID string) (Plan, string) {
 
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.tenantPlanLocked(tenantID)
+}
 
-	planID := "free"
+func (s *PlanStore) tenantPlanLocked(tenantID string) (Plan, string) {
 	if assignment, ok := s.assignments[tenantID]; ok {
-		planID = assignment.PlanID
-	}
-	plan, ok := s.plans[planID]
-	if !ok {
-		plan = s.plans["free"]
-		planID = "free"
+		if plan, ok := s.planVersionLocked(assignment.PlanID, assignment.PlanVersion); ok {
+			return plan, assignment.PlanID
+		}
 	}
-	return plan, planID
+	return s.plans["free"], "free"
 }
 
 func (s *PlanStore) GetTenantAssignment(tenantID string) (TenantPlanAssignment, bool) {
@@ -356,15 +454,51 @@ func (s *PlanStore) ListUsageByTenant(tenantID string) []UsageSnapshot {
 	return out
 }
 
-func (s *PlanStore) MarkWarnings(tenantID string, warned80, warned95 bool) UsageSnapshot {
-	return s.recordUsage(tenantID, func(usage *UsageSnapshot) {
-		if warned80 {
-			usage.Warned80 = true
+// RecordTraffic records a request like RecordRequest and, when capBytes is
+// set, marks the warning thresholds the month's traffic has crossed. The
+// thresholds it returns were marked by this call, so concurrent requests
+// report each crossing once.
+func (s *PlanStore) RecordTraffic(tenantID string, bytesIn, bytesOut, capBytes int64, thresholds []int) (UsageSnapshot, []int) {
+	var crossed []int
+	usage := s.recordUsage(tenantID, func(usage *UsageSnapshot) {
+		usage.Requests++
+		usage.BytesIn += bytesIn
+		usage.BytesOut += bytesOut
+		if capBytes <= 0 {
+			return
 		}
-		if warned95 {
-			usage.Warned95 = true
+		percent := float64(usage.BytesIn+usage.BytesOut) / float64(capBytes) * 100
+		for _, threshold := range thresholds {
+			if percent < float64(threshold) || usage.HasWarned(threshold) {
+				continue
+			}
+			usage.markWarned(threshold)
+			crossed = append(crossed, threshold)
 		}
 	})
+	return usage, crossed
+}
+
+func (u *UsageSnapshot) markWarned(percent int) {
+	u.WarnedPercents = append(u.WarnedPercents, percent)
+	sort.Ints(u.WarnedPercents)
+	if percent >= 80 {
+		u.Warned80 = true
+	}
+	if percent >= 95 {
+		u.Warned95 = true
+	}
+}
+
+func (u UsageSnapshot) HasWarned(percent int) bool {
+	for _, warned := range u.WarnedPercents {
+		if warned == percent {
+			return true
+		}
+	}
+	// Snapshots written before thresholds were configurable only carry the
+	// fixed 80/95 flags.
+	return (percent == 80 && u.Warned80) || (percent == 95 && u.Warned95)
 }
 
 func (s *PlanStore) recordUsage(tenantID string, mutate func(*UsageSnapshot)) UsageSnapshot {
@@ -391,6 +525,19 @@ func (s *PlanStore) recordUsage(tenantID string, mutate func(*UsageSnapshot)) Us
 	return usage
 }
 
+func billingPeriodEnd(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func prorateMonthlyPrice(fromPrice, toPrice float64, now time.Time) float64 {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := billingPeriodEnd(now)
+	remaining := end.Sub(now).Seconds() / end.Sub(start).Seconds()
+	return math.Round((toPrice-fromPrice)*remaining*100) / 100
+}
+
 func usageKey(tenantID, monthKey string) string {
 	return tenantID + ":" + monthKey
 }
@@ -0,0 +1,232 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPlanStoreChangeTenantPlanRecordsProration(t *testing.T) {
+	store := NewPlanStore()
+	now := time.Date(2026, time.April, 16, 0, 0, 0, 0, time.UTC)
+
+	upgrade, err := store.ChangeTenantPlan("acme", "pro", "owner", now, nil)
+	if err != nil {
+		t.Fatalf("upgrade plan: %v", err)
+	}
+	if upgrade.PreviousPlanID != "free" || upgrade.PlanID != "pro" {
+		t.Fatalf("unexpected upgrade assignment: %+v", upgrade)
+	}
+	if upgrade.ProratedAmountUSD != 10 {
+		t.Fatalf("expected half-month charge of 10, got %.2f", upgrade.ProratedAmountUSD)
+	}
+	if upgrade.PeriodEnd == nil || !upgrade.PeriodEnd.Equal(time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected period end: %v", upgrade.PeriodEnd)
+	}
+
+	downgrade, err := store.ChangeTenantPlan("acme", "free", "owner", now, nil)
+	if err != nil {
+		t.Fatalf("downgrade plan: %v", err)
+	}
+	if downgrade.ProratedAmountUSD != -10 {
+		t.Fatalf("expected half-month credit of -10, got %.2f", downgrade.ProratedAmountUSD)
+	}
+
+	if _, err := store.ChangeTenantPlan("acme", "free", "owner", now, nil); err == nil {
+		t.Fatalf("expected error when changing to the current plan")
+	}
+}
+
+func TestPlanStoreChangeTenantPlanChecksTargetUnderLock(t *testing.T) {
+	store := NewPlanStore()
+	for _, plan := range store.ListPlans() {
+		if plan.SelfServe {
+			t.Fatalf("expected built-in plan %q not to be self-serve", plan.ID)
+		}
+	}
+	refused := errors.New("refused")
+	if _, err := store.ChangeTenantPlan("acme", "pro", "owner", time.Now(), func(plan Plan) error {
+		if plan.ID != "pro" {
+			t.Fatalf("expected the target plan to be checked, got %q", plan.ID)
+		}
+		return refused
+	}); !errors.Is(err, refused) {
+		t.Fatalf("expected the check to abort the change, got %v", err)
+	}
+	if _, planID := store.GetTenantPlan("acme"); planID != "free" {
+		t.Fatalf("expected the tenant to stay on free, got %q", planID)
+	}
+}
+
+func TestPlanEditsGrandfatherExistingTenants(t *testing.T) {
+	store := NewPlanStore()
+	if _, err := store.AssignTenantPlan("acme", "pro", "admin"); err != nil {
+		t.Fatalf("assign plan: %v", err)
+	}
+	pro, _ := store.GetPlan("pro")
+	if _, err := store.UpsertPlan(pro); err != nil {
+		t.Fatalf("resave plan: %v", err)
+	}
+	if resaved, _ := store.GetPlan("pro"); resaved.Version != 1 {
+		t.Fatalf("expected an unchanged save to keep version 1, got %d", resaved.Version)
+	}
+
+	pro.MaxRoutes = 20
+	edited, err := store.UpsertPlan(pro)
+	if err != nil {
+		t.Fatalf("edit plan: %v", err)
+	}
+	if edited.Version != 2 {
+		t.Fatalf("expected the edit to create version 2, got %d", edited.Version)
+	}
+	if plan, planID := store.GetTenantPlan("acme"); planID != "pro" || plan.Version != 1 || plan.MaxRoutes != 50 {
+		t.Fatalf("expected acme to keep pro v1, got %s %+v", planID, plan)
+	}
+	if _, err := store.AssignTenantPlan("beta", "pro", "admin"); err != nil {
+		t.Fatalf("assign plan: %v", err)
+	}
+	if plan, _ := store.GetTenantPlan("beta"); plan.Version != 2 || plan.MaxRoutes != 20 {
+		t.Fatalf("expected a new assignment to get v2, got %+v", plan)
+	}
+
+	restored := NewPlanStore()
+	restored.Restore(store.Snapshot())
+	versions, ok := restored.ListPlanVersions("pro")
+	if !ok || len(versions) != 2 || !versions[0].Current || versions[0].Plan.Version != 2 {
+		t.Fatalf("unexpected versions after restore: %+v", versions)
+	}
+	if len(versions[0].Tenants) != 1 || versions[0].Tenants[0] != "beta" || len(versions[1].Tenants) != 1 || versions[1].Tenants[0] != "acme" {
+		t.Fatalf("unexpected tenants per version: %+v", versions)
+	}
+
+	if _, err := restored.MigrateTenants("pro", 2, nil); err == nil {
+		t.Fatalf("expected migrating from the current version to fail")
+	}
+	migrated, err := restored.MigrateTenants("pro", 0, nil)
+	if err != nil || len(migrated) != 1 || migrated[0].TenantID != "acme" || migrated[0].PlanVersion != 2 {
+		t.Fatalf("unexpected migration %+v: %v", migrated, err)
+	}
+	if plan, _ := restored.GetTenantPlan("acme"); plan.MaxRoutes != 20 {
+		t.Fatalf("expected acme on the new limits, got %+v", plan)
+	}
+}
+
+func TestPlanStoreRestoreTreatsUnversionedAssignmentsAsCurrent(t *testing.T) {
+	store := NewPlanStore()
+	store.Restore(planStoreSnapshot{
+		Plans:       []Plan{{ID: "pro", MaxRoutes: 50, MaxConnectors: 10, MaxRPS: 100, MaxMonthlyGB: 500}},
+		Assignments: []TenantPlanAssignment{{TenantID: "acme", PlanID: "pro"}},
+	})
+	assignment, _ := store.GetTenantAssignment("acme")
+	if plan, _ := store.GetTenantPlan("acme"); plan.Version != 1 || assignment.PlanVersion != 1 {
+		t.Fatalf("expected legacy data on version 1, got plan %+v assignment %+v", plan, assignment)
+	}
+}
+
+func TestAdminPlanVersionsAndMigration(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	session, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+session)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+	if _, err := server.planStore.AssignTenantPlan(DefaultTenantID, "pro", "test"); err != nil {
+		t.Fatalf("assign plan: %v", err)
+	}
+	if recorder := call(http.MethodPatch, "/api/admin/plans/pro", `{"name":"Pro","max_routes":60,"max_connectors":10,"max_rps":100,"max_monthly_gb":500,"tls_enabled":true}`); recorder.Code != http.StatusOK {
+		t.Fatalf("edit plan: %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	var listing struct {
+		CurrentVersion int                  `json:"current_version"`
+		Versions       []PlanVersionTenants `json:"versions"`
+	}
+	recorder := call(http.MethodGet, "/api/admin/plans/pro/versions", "")
+	if err := json.Unmarshal(recorder.Body.Bytes(), &listing); err != nil || recorder.Code != http.StatusOK {
+		t.Fatalf("list versions: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if listing.CurrentVersion != 2 || len(listing.Versions) != 2 || len(listing.Versions[1].Tenants) != 1 || listing.Versions[1].Tenants[0] != DefaultTenantID {
+		t.Fatalf("expected the default tenant grandfathered on v1, got %+v", listing)
+	}
+	if plan, _ := server.planStore.GetTenantPlan(DefaultTenantID); plan.MaxRoutes != 50 {
+		t.Fatalf("expected grandfathered limits, got %+v", plan)
+	}
+
+	if recorder := call(http.MethodPost, "/api/admin/plans/pro/migrate", `{"from_version":1}`); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "1 tenants migrated") {
+		t.Fatalf("migrate: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if plan, _ := server.planStore.GetTenantPlan(DefaultTenantID); plan.MaxRoutes != 60 {
+		t.Fatalf("expected migrated limits, got %+v", plan)
+	}
+	if recorder := call(http.MethodGet, "/api/admin/plans/missing/versions", ""); recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected unknown plan to 404, got %d", recorder.Code)
+	}
+	if recorder := call(http.MethodGet, "/api/admin/plans/pro/migrate", ""); recorder.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected GET migrate to be refused, got %d", recorder.Code)
+	}
+}
+
+func TestUsageThresholdsAlertOnceUnderConcurrency(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", SMTPAddr: "smtp.test:25", UsageWarningPercents: []int{80, 95}}, nil)
+	sent := make(chan mailMessage, 64)
+	server.notifier.send = func(message mailMessage) error {
+		sent <- message
+		return nil
+	}
+	if _, err := server.authStore.RegisterUser(RegisterUserInput{Username: "alice", Password: "secret123", TenantID: DefaultTenantID, Role: RoleMember}); err != nil {
+		t.Fatalf("register user: %v", err)
+	}
+	if _, err := server.authStore.SetNotificationPreferences("alice", NotificationPreferences{Email: "alice@example.com", Mode: notifyModeImmediate, EventTypes: []string{"usage.threshold"}}); err != nil {
+		t.Fatalf("set preferences: %v", err)
+	}
+
+	// A cap of about 1 KiB, crossed many times over by concurrent requests.
+	plan := Plan{ID: "tiny", MaxMonthlyGB: 1.0 / (1 << 20)}
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.recordTrafficUsage(DefaultTenantID, "web", "", plan, 50, 50)
+		}()
+	}
+	wg.Wait()
+
+	alerts := 0
+	for _, incident := range server.incidentStore.List(0) {
+		if incident.Source == "traffic" {
+			alerts++
+		}
+	}
+	if alerts != 2 {
+		t.Fatalf("expected one incident per threshold, got %d", alerts)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case message := <-sent:
+			if message.To != "alice@example.com" || !strings.Contains(message.Body, "usage.threshold") {
+				t.Fatalf("unexpected email: %+v", message)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected an email per threshold, got %d", i)
+		}
+	}
+	select {
+	case message := <-sent:
+		t.Fatalf("expected no duplicate emails, got %+v", message)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"strings"
+	"time"
 )
 
 const bytesPerGB = 1024 * 1024 * 1024
@@ -54,6 +55,19 @@ func (s *Server) enforceConnectorLimit(tenantID string) error {
 	return nil
 }
 
+func (s *Server) validatePlanFit(tenantID string, plan Plan) error {
+	tenantID = normalizeIdentifier(tenantID)
+	routes := s.ruleStore.RouteCountByTenant()[tenantID]
+	if plan.MaxRoutes > 0 && routes > plan.MaxRoutes {
+		return fmt.Errorf("plan %q allows %d routes but tenant has %d", plan.ID, plan.MaxRoutes, routes)
+	}
+	connectors := s.connectorStore.CountByTenant(tenantID)
+	if plan.MaxConnectors > 0 && connectors > plan.MaxConnectors {
+		return fmt.Errorf("plan %q allows %d connectors but tenant has %d", plan.ID, plan.MaxConnectors, connectors)
+	}
+	return nil
+}
+
 func (s *Server) refreshTenantUsage(tenantID string) {
 	tenantID = normalizeIdentifier(tenantID)
 	if tenantID == "" {
@@ -78,31 +92,45 @@ func (s *Server) refreshUsageAllTenants() {
 	}
 }
 
-func (s *Server) recordTrafficUsage(tenantID string, plan Plan, bytesIn, bytesOut int64) {
+func (s *Server) recordTrafficUsage(tenantID, routeID, connectorID string, plan Plan, bytesIn, bytesOut int64) {
 	tenantID = strings.TrimSpace(tenantID)
 	if tenantID == "" {
 		tenantID = DefaultTenantID
 	}
-	before := s.planStore.GetUsage(tenantID, "")
-	after := s.planStore.RecordRequest(tenantID, bytesIn, bytesOut)
-
+	s.transfer.Record(tenantID, routeID, connectorID, bytesIn, bytesOut, time.Now())
 	capBytes := int64(plan.MaxMonthlyGB * bytesPerGB)
-	if capBytes <= 0 {
+	after, crossed := s.planStore.RecordTraffic(tenantID, bytesIn, bytesOut, capBytes, s.config().UsageWarningPercents)
+	if len(crossed) == 0 {
 		return
 	}
-	beforeRatio := float64(before.BytesIn+before.BytesOut) / float64(capBytes)
-	afterRatio := float64(after.BytesIn+after.BytesOut) / float64(capBytes)
+	usedPercent := math.Min(float64(after.BytesIn+after.BytesOut)/float64(capBytes)*100, 100)
 
-	if afterRatio >= 0.80 && !before.Warned80 {
-		s.planStore.MarkWarnings(tenantID, true, false)
-		s.incidentStore.Add("warning", "traffic", fmt.Sprintf("tenant %s reached %.1f%% monthly traffic", tenantID, math.Min(afterRatio*100, 100)))
-	}
-	if afterRatio >= 0.95 && !before.Warned95 {
-		s.planStore.MarkWarnings(tenantID, true, true)
-		s.incidentStore.Add("critical", "traffic", fmt.Sprintf("tenant %s reached %.1f%% monthly traffic", tenantID, math.Min(afterRatio*100, 100)))
+	// usage.threshold events also reach the tenant's users who subscribed
+	// to email notifications.
+	for _, threshold := range crossed {
+		severity := "warning"
+		if threshold >= 95 {
+			severity = "critical"
+		}
+		s.incidentStore.Add(severity, "traffic", fmt.Sprintf("tenant %s reached %.1f%% monthly traffic", tenantID, usedPercent))
+		s.webhooks.Emit("usage.threshold", tenantID, map[string]any{
+			"threshold_percent": threshold,
+			"used_percent":      usedPercent,
+			"used_bytes":        after.BytesIn + after.BytesOut,
+			"cap_bytes":         capBytes,
+			"month_key":         after.MonthKey,
+		})
 	}
+}
 
-	_ = beforeRatio
+func activeUsageWarning(thresholds []int, ratio float64) int {
+	active := 0
+	for _, threshold := range thresholds {
+		if ratio*100 >= float64(threshold) && threshold > active {
+			active = threshold
+		}
+	}
+	return active
 }
 
 func usagePercent(plan Plan, usage UsageSnapshot) float64 {
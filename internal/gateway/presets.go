@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+// devCORSPolicy is the policy of the cors_allow_all preset: any origin, with
+// credentials, so the origin is echoed back rather than answered with "*".
+var devCORSPolicy = &CORSPolicy{
+	AllowedOrigins:   []string{"*"},
+	AllowedMethods:   defaultCORSMethods,
+	AllowCredentials: true,
+}
+
+type RoutePresets struct {
+	NoCache                   bool `json:"no_cache,omitempty"`
+	StripConditional          bool `json:"strip_conditional,omitempty"`
+	RewriteLocalhostRedirects bool `json:"rewrite_localhost_redirects,omitempty"`
+	CORSAllowAll              bool `json:"cors_allow_all,omitempty"`
+}
+
+func normalizeRoutePresets(input *RoutePresets, cors *CORSPolicy) (*RoutePresets, error) {
+	if input == nil || *input == (RoutePresets{}) {
+		return nil, nil
+	}
+	if input.CORSAllowAll && cors != nil {
+		return nil, fmt.Errorf("presets.cors_allow_all cannot be combined with cors")
+	}
+	presets := *input
+	return &presets, nil
+}
+
+func (r Rule) corsPolicy() *CORSPolicy {
+	if r.CORS == nil && r.Presets != nil && r.Presets.CORSAllowAll {
+		return devCORSPolicy
+	}
+	return r.CORS
+}
+
+func (p *RoutePresets) applyToRequest(headers map[string][]string) {
+	if p == nil || !p.StripConditional {
+		return
+	}
+	http.Header(headers).Del("If-None-Match")
+	http.Header(headers).Del("If-Modified-Since")
+}
+
+func (p *RoutePresets) applyToResponse(resp *protocol.ProxyResponse, publicBase, publicPrefix string) {
+	if p == nil || resp == nil {
+		return
+	}
+	if resp.Headers == nil {
+		resp.Headers = make(map[string][]string)
+	}
+	headers := http.Header(resp.Headers)
+	if p.StripConditional {
+		headers.Del("ETag")
+		headers.Del("Last-Modified")
+	}
+	if p.NoCache {
+		headers.Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+		headers.Set("Pragma", "no-cache")
+		headers.Set("Expires", "0")
+	}
+	if p.RewriteLocalhostRedirects {
+		if location, ok := publicLocation(headers.Get("Location"), publicBase, publicPrefix); ok {
+			headers.Set("Location", location)
+		}
+	}
+}
+
+func publicLocation(location, publicBase, publicPrefix string) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(location))
+	if err != nil || !parsed.IsAbs() || (parsed.Scheme != "http" && parsed.Scheme != "https") || !isLoopbackHost(parsed.Hostname()) {
+		return "", false
+	}
+	relative := &url.URL{Path: parsed.Path, RawPath: parsed.RawPath, RawQuery: parsed.RawQuery, Fragment: parsed.Fragment}
+	path := relative.String()
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return strings.TrimRight(publicBase, "/") + prefixRootRelativeURL(path, strings.TrimRight(publicPrefix, "/")), true
+}
+
+func isLoopbackHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
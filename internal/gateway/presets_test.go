@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestRoutePresets(t *testing.T) {
+	var conditional string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditional = r.Header.Get("If-None-Match") + r.Header.Get("If-Modified-Since")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Header().Set("Cache-Control", "max-age=3600")
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "app", Target: upstream.URL, CORS: &CORSPolicy{AllowedOrigins: []string{"*"}}, Presets: &RoutePresets{CORSAllowAll: true}}); err == nil {
+		t.Fatal("expected cors_allow_all with a cors policy to be rejected")
+	}
+	presets := &RoutePresets{NoCache: true, StripConditional: true, RewriteLocalhostRedirects: true, CORSAllowAll: true}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "app", Target: upstream.URL, Presets: presets}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/t/app/index.html", nil)
+	req.Header.Set("If-None-Match", `"v1"`)
+	req.Header.Set("If-Modified-Since", "Mon, 02 Jan 2006 15:04:05 GMT")
+	req.Header.Set("Origin", "http://localhost:5173")
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, req)
+	if recorder.Code != http.StatusOK || conditional != "" {
+		t.Fatalf("expected a full response without conditional headers upstream, got %d and %q", recorder.Code, conditional)
+	}
+	headers := recorder.Header()
+	if headers.Get("ETag") != "" || headers.Get("Last-Modified") != "" {
+		t.Fatalf("expected validators to be stripped, got %v", headers)
+	}
+	if headers.Get("Cache-Control") != "no-store, no-cache, must-revalidate, max-age=0" || headers.Get("Pragma") != "no-cache" || headers.Get("Expires") != "0" {
+		t.Fatalf("expected no-cache headers, got %v", headers)
+	}
+	if headers.Get("Access-Control-Allow-Origin") != "http://localhost:5173" || headers.Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf("expected the origin to be allowed with credentials, got %v", headers)
+	}
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/t/app/api", nil)
+	preflight.Header.Set("Origin", "https://anything.example")
+	preflight.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	recorder = httptest.NewRecorder()
+	server.handleProxy(recorder, preflight)
+	if recorder.Code != http.StatusNoContent || recorder.Header().Get("Access-Control-Allow-Origin") != "https://anything.example" {
+		t.Fatalf("expected the preflight to be answered for any origin, got %d %v", recorder.Code, recorder.Header())
+	}
+}
+
+func TestRoutePresetsRewriteLocalhostRedirects(t *testing.T) {
+	resp := &protocol.ProxyResponse{Status: http.StatusFound, Headers: map[string][]string{"Location": {"http://localhost:3000/dashboard?tab=1"}}}
+	(&RoutePresets{RewriteLocalhostRedirects: true}).applyToResponse(resp, "https://demo.example", "/t/app")
+	if got := resp.Headers["Location"][0]; got != "https://demo.example/t/app/dashboard?tab=1" {
+		t.Fatalf("expected the localhost redirect to point at the public URL, got %q", got)
+	}
+
+	cases := []struct {
+		location, prefix, want string
+		ok                     bool
+	}{
+		{"http://127.0.0.1:8080/a/b?c=d#e", "/t/app", "https://demo.example/t/app/a/b?c=d#e", true},
+		{"https://localhost", "", "https://demo.example/", true},
+		{"http://api.localhost:4000/x", "", "https://demo.example/x", true},
+		{"http://[::1]:3000/", "/t/app", "https://demo.example/t/app/", true},
+		{"https://github.com/login", "/t/app", "", false},
+		{"/relative", "/t/app", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := publicLocation(tc.location, "https://demo.example", tc.prefix)
+		if ok != tc.ok || got != tc.want {
+			t.Fatalf("publicLocation(%q) = %q, %v; want %q, %v", tc.location, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -28,7 +29,7 @@ type publicPlanView struct {
 
 func (s *Server) handlePublicPlans(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
@@ -56,32 +57,43 @@ func (s *Server) handlePublicPlans(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handlePublicDownloads(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	if s.downloads == nil {
 		writeJSON(w, http.StatusOK, unavailableDownloadsResponse("", "download provider is not configured"))
 		return
 	}
-	writeJSON(w, http.StatusOK, s.downloads.Resolve(r.Context()))
+	channel := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("channel")))
+	if channel == "" {
+		channel = releaseChannelStable
+	}
+	if channel != releaseChannelStable && channel != releaseChannelBeta {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("channel must be %q or %q", releaseChannelStable, releaseChannelBeta))
+		return
+	}
+	payload := s.downloads.ResolveChannel(r.Context(), channel)
+	payload.MinAgentVersion = s.config().MinAgentVersion
+	payload.DetectedPlatform, payload.DetectedArch = detectClientPlatform(r)
+	payload.Recommended = recommendDownload(payload.Downloads, payload.DetectedPlatform, payload.DetectedArch)
+	w.Header().Set("Accept-CH", "Sec-CH-UA-Platform, Sec-CH-UA-Arch")
+	w.Header().Set("Vary", "User-Agent, Sec-CH-UA-Platform, Sec-CH-UA-Arch")
+	writeJSON(w, http.StatusOK, payload)
 }
 
 func (s *Server) handlePublicSignup(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
-	if !s.cfg.PublicSignupEnabled {
-		http.Error(w, "public signup is disabled", http.StatusForbidden)
+	if !s.config().PublicSignupEnabled {
+		writeAPIError(w, http.StatusForbidden, errCodeSignupDisabled, "public signup is disabled")
 		return
 	}
 
 	clientIP := signupClientIP(r)
 	if !s.allowSignupForIP(clientIP) {
-		writeJSON(w, http.StatusTooManyRequests, map[string]any{
-			"message":    "signup rate limit exceeded",
-			"retry_hint": "try again shortly",
-		})
+		writeAPIError(w, http.StatusTooManyRequests, errCodeRateLimited, "signup rate limit exceeded; try again shortly")
 		return
 	}
 
@@ -92,20 +104,32 @@ func (s *Server) handlePublicSignup(w http.ResponseWriter, r *http.Request) {
 
 	username := normalizeUsername(request.Username)
 	if username == "" {
-		http.Error(w, "username is required", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "username is required")
 		return
 	}
 	if _, exists := s.authStore.GetUser(username); exists {
-		http.Error(w, "username already exists", http.StatusConflict)
+		writeAPIError(w, http.StatusConflict, errCodeUsernameTaken, "username already exists")
 		return
 	}
 
-	tenantID := s.generateTenantSlugFromUsername(username)
-	tenantName := fmt.Sprintf("%s workspace", username)
-	tenantExisted := s.ruleStore.HasTenant(tenantID)
-	createdTenant, err := s.ruleStore.UpsertTenant(Tenant{ID: tenantID, Name: tenantName})
+	if err := s.currentNamePolicy().Check(slugifyTenantID(username)); err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "username is not allowed")
+		return
+	}
+	tenantID, ok := s.generateTenantSlugFromUsername(username)
+	if !ok {
+		writeAPIError(w, http.StatusConflict, errCodeConflict, "no free workspace name for this username; choose another username")
+		return
+	}
+	// CreateTenant fails if a concurrent signup took the slug since it was
+	// picked: a public signup never joins an existing tenant.
+	createdTenant, err := s.ruleStore.CreateTenant(Tenant{ID: tenantID, Name: fmt.Sprintf("%s workspace", username)})
+	if errors.Is(err, errTenantExists) {
+		writeAPIError(w, http.StatusConflict, errCodeConflict, "workspace name was just taken; try again")
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -117,23 +141,21 @@ func (s *Server) handlePublicSignup(w http.ResponseWriter, r *http.Request) {
 		Status:   "active",
 	})
 	if err != nil {
-		if !tenantExisted {
-			s.ruleStore.DeleteTenant(tenantID)
-		}
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.ruleStore.DeleteTenant(tenantID)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
 	assignment, err := s.planStore.AssignTenantPlan(tenantID, "free", "public-signup")
 	if err != nil {
-		http.Error(w, fmt.Sprintf("assign free plan: %v", err), http.StatusInternalServerError)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, fmt.Sprintf("assign free plan: %v", err))
 		return
 	}
 	s.refreshTenantUsage(tenantID)
 
 	sessionID, err := s.authStore.NewSession(user.Username)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("create session: %v", err), http.StatusInternalServerError)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, fmt.Sprintf("create session: %v", err))
 		return
 	}
 	s.setSessionCookie(w, sessionID)
@@ -153,7 +175,7 @@ func (s *Server) allowSignupForIP(clientIP string) bool {
 	if clientIP == "" {
 		clientIP = "unknown"
 	}
-	ratePerSecond := float64(s.cfg.PublicSignupRPM) / 60.0
+	ratePerSecond := float64(s.config().PublicSignupRPM) / 60.0
 	return s.rateLimiter.Allow("public-signup:"+clientIP, ratePerSecond)
 }
 
@@ -175,11 +197,14 @@ func signupClientIP(r *http.Request) string {
 	return extractIP(r.RemoteAddr)
 }
 
-func (s *Server) generateTenantSlugFromUsername(username string) string {
+func (s *Server) generateTenantSlugFromUsername(username string) (string, bool) {
 	base := slugifyTenantID(username)
 	const maxLen = 64
 	candidate := base
-	for suffix := 2; s.ruleStore.HasTenant(candidate); suffix++ {
+	free := func(candidate string) bool {
+		return !s.ruleStore.HasTenant(candidate) && s.currentNamePolicy().Allowed(candidate)
+	}
+	for suffix := 2; suffix <= 1000 && !free(candidate); suffix++ {
 		suffixPart := "-" + strconv.Itoa(suffix)
 		trimmedBase := base
 		maxBaseLen := maxLen - len(suffixPart)
@@ -194,7 +219,7 @@ func (s *Server) generateTenantSlugFromUsername(username string) string {
 		}
 		candidate = trimmedBase + suffixPart
 	}
-	return candidate
+	return candidate, free(candidate)
 }
 
 func slugifyTenantID(value string) string {
@@ -0,0 +1,34 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPublicSignupNeverJoinsExistingTenant(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", PublicSignupEnabled: true, PublicSignupRPM: 600}, nil)
+	for suffix := 1; suffix <= 1000; suffix++ {
+		tenantID := "alice"
+		if suffix > 1 {
+			tenantID += "-" + strconv.Itoa(suffix)
+		}
+		if _, err := server.ruleStore.UpsertTenant(Tenant{ID: tenantID}); err != nil {
+			t.Fatalf("create tenant: %v", err)
+		}
+	}
+	if _, err := server.ruleStore.CreateTenant(Tenant{ID: "alice"}); err != errTenantExists {
+		t.Fatalf("expected creating an existing tenant to fail, got %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handlePublicSignup(recorder, httptest.NewRequest(http.MethodPost, "/api/public/signup", strings.NewReader(`{"username":"alice","password":"correct-horse-battery"}`)))
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("expected 409 when every workspace name is taken, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	if _, exists := server.authStore.GetUser("alice"); exists {
+		t.Fatal("expected no user to be created")
+	}
+}
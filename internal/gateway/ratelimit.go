@@ -12,6 +12,17 @@ type tokenBucket struct {
 	lastRefill time.Time
 }
 
+type RateLimitBackend interface {
+	Allow(key string, rate float64) bool
+	AllowBurst(key string, rate, burst float64) bool
+	Sweep(now time.Time) int
+}
+
+const (
+	rateLimitBackendMemory = "memory"
+	rateLimitBackendRedis  = "redis"
+)
+
 type RateLimiter struct {
 	mu      sync.Mutex
 	buckets map[string]*tokenBucket
@@ -24,10 +35,13 @@ func NewRateLimiter() *RateLimiter {
 }
 
 func (l *RateLimiter) Allow(key string, rate float64) bool {
+	return l.AllowBurst(key, rate, rate*2)
+}
+
+func (l *RateLimiter) AllowBurst(key string, rate, burst float64) bool {
 	if rate <= 0 {
 		return false
 	}
-	burst := rate * 2
 	if burst < 1 {
 		burst = 1
 	}
@@ -63,6 +77,22 @@ func (l *RateLimiter) Allow(key string, rate float64) bool {
 	return true
 }
 
+// Sweep drops the buckets that have refilled by now. A full bucket behaves
+// exactly like a new one, so this only bounds memory for keys such as client
+// IPs that come and go.
+func (l *RateLimiter) Sweep(now time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	dropped := 0
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.lastRefill).Seconds()*bucket.rate >= bucket.burst {
+			delete(l.buckets, key)
+			dropped++
+		}
+	}
+	return dropped
+}
+
 func (l *RateLimiter) Snapshot() map[string]float64 {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -72,3 +102,10 @@ func (l *RateLimiter) Snapshot() map[string]float64 {
 	}
 	return out
 }
+
+func (s *Server) rateLimiterHealth() map[string]any {
+	if redisLimiter, ok := s.rateLimiter.(*RedisRateLimiter); ok {
+		return redisLimiter.Health()
+	}
+	return map[string]any{"backend": rateLimitBackendMemory}
+}
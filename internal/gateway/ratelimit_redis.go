@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/redis"
+)
+
+const (
+	redisRateLimitPrefix = "proxer:ratelimit:"
+	// redisRateLimitTimeout bounds one limiter decision, so a slow Redis
+	// delays requests by at most this much before the local fallback.
+	redisRateLimitTimeout = 250 * time.Millisecond
+	redisRateLimitRetry   = 5 * time.Second
+)
+
+// tokenBucketScript refills and takes from the bucket at KEYS[1] in one
+// step. The clock is the Redis server's, so replicas with skewed clocks
+// share one notion of time. ARGV is the rate per second and the burst.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) + tonumber(clock[2]) / 1000000
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+if now > ts then
+  tokens = math.min(burst, tokens + (now - ts) * rate)
+end
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return allowed
+`)
+
+// RedisRateLimiter keeps token buckets in Redis so tenant, route and abuse
+// limits hold across gateway replicas. While Redis cannot be reached it
+// falls back to per-replica buckets rather than failing requests.
+type RedisRateLimiter struct {
+	client   *redis.Client
+	fallback *RateLimiter
+	logger   *log.Logger
+
+	mu          sync.Mutex
+	failedUntil time.Time
+}
+
+func NewRedisRateLimiter(client *redis.Client, logger *log.Logger) *RedisRateLimiter {
+	return &RedisRateLimiter{client: client, fallback: NewRateLimiter(), logger: logger}
+}
+
+func (l *RedisRateLimiter) Allow(key string, rate float64) bool {
+	return l.AllowBurst(key, rate, rate*2)
+}
+
+func (l *RedisRateLimiter) AllowBurst(key string, rate, burst float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	now := time.Now()
+	l.mu.Lock()
+	degraded := now.Before(l.failedUntil)
+	l.mu.Unlock()
+	if degraded {
+		return l.fallback.AllowBurst(key, rate, burst)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisRateLimitTimeout)
+	defer cancel()
+	reply, err := tokenBucketScript.Run(ctx, l.client, []string{redisRateLimitPrefix + key},
+		strconv.FormatFloat(rate, 'f', -1, 64), strconv.FormatFloat(burst, 'f', -1, 64))
+	if err == nil {
+		if allowed, ok := reply.(int64); ok {
+			return allowed == 1
+		}
+		err = fmt.Errorf("unexpected reply %v", reply)
+	}
+	l.mu.Lock()
+	if !now.Before(l.failedUntil) {
+		l.logger.Printf("redis rate limiter at %s unavailable, using local limits for %s: %v", l.client.Addr(), redisRateLimitRetry, err)
+	}
+	l.failedUntil = now.Add(redisRateLimitRetry)
+	l.mu.Unlock()
+	return l.fallback.AllowBurst(key, rate, burst)
+}
+
+// Sweep drops refilled buckets of the local fallback; Redis expires its own
+// keys.
+func (l *RedisRateLimiter) Sweep(now time.Time) int {
+	return l.fallback.Sweep(now)
+}
+
+func (l *RedisRateLimiter) Health() map[string]any {
+	l.mu.Lock()
+	degraded := time.Now().Before(l.failedUntil)
+	l.mu.Unlock()
+	return map[string]any{
+		"backend":  rateLimitBackendRedis,
+		"addr":     l.client.Addr(),
+		"degraded": degraded,
+	}
+}
+
+func newRateLimitBackend(cfg Config, logger *log.Logger) RateLimitBackend {
+	if cfg.RateLimitBackend != rateLimitBackendRedis {
+		return NewRateLimiter()
+	}
+	client, err := redis.New(cfg.RedisURL)
+	if err != nil {
+		panic(fmt.Errorf("initialize redis rate limiter: %w", err))
+	}
+	return NewRedisRateLimiter(client, logger)
+}
@@ -0,0 +1,145 @@
+package gateway
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/szaher/try/proxer/internal/redis"
+)
+
+// serveFakeRedisBuckets answers the token bucket script with a fixed burst
+// per key and no refill, standing in for a Redis shared by replicas. stop
+// closes it along with its connections.
+func serveFakeRedisBuckets(t *testing.T) (addr string, stop func()) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	var mu sync.Mutex
+	taken := map[string]int{}
+	conns := []net.Conn{}
+	stop = func() {
+		listener.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	t.Cleanup(stop)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					args, err := readFakeRedisCommand(reader)
+					if err != nil {
+						return
+					}
+					// EVALSHA sha numkeys key rate burst
+					burst, _ := strconv.ParseFloat(args[5], 64)
+					mu.Lock()
+					allowed := float64(taken[args[3]]) < burst
+					if allowed {
+						taken[args[3]]++
+					}
+					mu.Unlock()
+					reply := ":0\r\n"
+					if allowed {
+						reply = ":1\r\n"
+					}
+					if _, err := io.WriteString(conn, reply); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String(), stop
+}
+
+func readFakeRedisCommand(reader *bufio.Reader) ([]string, error) {
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+	args := make([]string, count)
+	for i := range args {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		value, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(value, "\r\n")
+	}
+	return args, nil
+}
+
+func TestRedisRateLimiterSharesBucketsAndFallsBack(t *testing.T) {
+	addr, stop := serveFakeRedisBuckets(t)
+	newReplica := func() *RedisRateLimiter {
+		client, err := redis.New("redis://" + addr)
+		if err != nil {
+			t.Fatalf("redis client: %v", err)
+		}
+		return NewRedisRateLimiter(client, log.New(io.Discard, "", 0))
+	}
+	first, second := newReplica(), newReplica()
+
+	if !first.AllowBurst("tenant:acme", 1, 2) || !second.AllowBurst("tenant:acme", 1, 2) {
+		t.Fatalf("expected the shared burst of 2 to admit one request per replica")
+	}
+	if first.AllowBurst("tenant:acme", 1, 2) || second.AllowBurst("tenant:acme", 1, 2) {
+		t.Fatalf("expected the shared bucket to be empty on both replicas")
+	}
+	if !second.AllowBurst("tenant:other", 1, 2) {
+		t.Fatalf("expected other keys to have their own bucket")
+	}
+
+	stop()
+	if !first.AllowBurst("tenant:acme", 1, 1) {
+		t.Fatalf("expected the local fallback to admit requests while redis is down")
+	}
+	if health := first.Health(); health["degraded"] != true {
+		t.Fatalf("expected the limiter to report degraded, got %+v", health)
+	}
+	if first.AllowBurst("tenant:acme", 1, 1) {
+		t.Fatalf("expected the local fallback to enforce the limit")
+	}
+}
+
+func TestLoadConfigRateLimitBackend(t *testing.T) {
+	t.Setenv("PROXER_RATE_LIMIT_BACKEND", "redis")
+	if _, err := LoadConfig(""); err == nil || !strings.Contains(err.Error(), "PROXER_REDIS_URL") {
+		t.Fatalf("expected redis without a url to be refused, got %v", err)
+	}
+	t.Setenv("PROXER_REDIS_URL", "redis://cache.internal:6380/1")
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.RateLimitBackend != rateLimitBackendRedis {
+		t.Fatalf("unexpected backend %q", cfg.RateLimitBackend)
+	}
+	if _, ok := NewServer(Config{StorageDriver: "memory", RateLimitBackend: cfg.RateLimitBackend, RedisURL: cfg.RedisURL}, nil).rateLimiter.(*RedisRateLimiter); !ok {
+		t.Fatalf("expected the server to use the redis limiter")
+	}
+}
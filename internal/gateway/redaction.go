@@ -0,0 +1,247 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	redactedValue = "[REDACTED]"
+
+	maxRedactionRules = 64
+)
+
+var defaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Proxer-Tunnel-Token"}
+
+type RedactionPolicy struct {
+	Headers    []string `json:"headers,omitempty"`
+	JSONFields []string `json:"json_fields,omitempty"`
+	Patterns   []string `json:"patterns,omitempty"`
+
+	once     sync.Once
+	patterns []*regexp.Regexp
+}
+
+func normalizeRedactionPolicy(input *RedactionPolicy) (*RedactionPolicy, error) {
+	if input == nil {
+		return nil, nil
+	}
+	policy := &RedactionPolicy{}
+	seen := make(map[string]struct{})
+	for _, raw := range input.Headers {
+		name := http.CanonicalHeaderKey(strings.TrimSpace(raw))
+		if name == "" {
+			return nil, fmt.Errorf("redaction.headers cannot contain empty names")
+		}
+		if _, ok := seen["h:"+name]; !ok {
+			seen["h:"+name] = struct{}{}
+			policy.Headers = append(policy.Headers, name)
+		}
+	}
+	for _, raw := range input.JSONFields {
+		path := strings.TrimSpace(raw)
+		for _, segment := range strings.Split(path, ".") {
+			if segment == "" {
+				return nil, fmt.Errorf("redaction.json_fields %q is not a dot separated path", raw)
+			}
+		}
+		if _, ok := seen["j:"+path]; !ok {
+			seen["j:"+path] = struct{}{}
+			policy.JSONFields = append(policy.JSONFields, path)
+		}
+	}
+	for _, raw := range input.Patterns {
+		pattern := strings.TrimSpace(raw)
+		if pattern == "" {
+			return nil, fmt.Errorf("redaction.patterns cannot contain empty patterns")
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction.patterns %q: %w", raw, err)
+		}
+		if compiled.MatchString("") {
+			return nil, fmt.Errorf("redaction.patterns %q matches empty text", raw)
+		}
+		if _, ok := seen["p:"+pattern]; !ok {
+			seen["p:"+pattern] = struct{}{}
+			policy.Patterns = append(policy.Patterns, pattern)
+		}
+	}
+	if len(policy.Headers)+len(policy.JSONFields)+len(policy.Patterns) > maxRedactionRules {
+		return nil, fmt.Errorf("redaction allows at most %d headers, json fields and patterns", maxRedactionRules)
+	}
+	if len(policy.Headers)+len(policy.JSONFields)+len(policy.Patterns) == 0 {
+		return nil, nil
+	}
+	return policy, nil
+}
+
+// compiledPatterns compiles Patterns on first use, so policies restored from
+// a snapshot need no separate step. Patterns were validated when the policy
+// was set; any that no longer compile are skipped.
+func (p *RedactionPolicy) compiledPatterns() []*regexp.Regexp {
+	if p == nil {
+		return nil
+	}
+	p.once.Do(func() {
+		for _, raw := range p.Patterns {
+			if compiled, err := regexp.Compile(raw); err == nil {
+				p.patterns = append(p.patterns, compiled)
+			}
+		}
+	})
+	return p.patterns
+}
+
+func (p *RedactionPolicy) redactsHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	for _, header := range defaultRedactedHeaders {
+		if header == name {
+			return true
+		}
+	}
+	if p == nil {
+		return false
+	}
+	for _, header := range p.Headers {
+		if header == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RedactionPolicy) RedactHeaders(headers map[string][]string) map[string][]string {
+	if headers == nil {
+		return nil
+	}
+	out := make(map[string][]string, len(headers))
+	for name, values := range headers {
+		if p.redactsHeader(name) {
+			masked := make([]string, len(values))
+			for i := range masked {
+				masked[i] = redactedValue
+			}
+			out[name] = masked
+			continue
+		}
+		redacted := make([]string, len(values))
+		for i, value := range values {
+			redacted[i] = p.RedactText(value)
+		}
+		out[name] = redacted
+	}
+	return out
+}
+
+func (p *RedactionPolicy) RedactBody(body []byte) []byte {
+	if p == nil || len(body) == 0 {
+		return body
+	}
+	if len(p.JSONFields) > 0 {
+		if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			var document any
+			decoder := json.NewDecoder(bytes.NewReader(trimmed))
+			decoder.UseNumber()
+			if decoder.Decode(&document) == nil {
+				for _, path := range p.JSONFields {
+					document = redactJSONPath(document, strings.Split(path, "."))
+				}
+				if encoded, err := json.Marshal(document); err == nil {
+					body = encoded
+				}
+			}
+		}
+	}
+	return []byte(p.RedactText(string(body)))
+}
+
+func (p *RedactionPolicy) RedactText(text string) string {
+	for _, pattern := range p.compiledPatterns() {
+		text = pattern.ReplaceAllLiteralString(text, redactedValue)
+	}
+	return text
+}
+
+func redactJSONPath(value any, path []string) any {
+	switch typed := value.(type) {
+	case []any:
+		for i, item := range typed {
+			typed[i] = redactJSONPath(item, path)
+		}
+		return typed
+	case map[string]any:
+		if len(path) == 0 {
+			return redactedValue
+		}
+		for key, child := range typed {
+			if path[0] != "*" && path[0] != key {
+				continue
+			}
+			if len(path) == 1 {
+				typed[key] = redactedValue
+			} else {
+				typed[key] = redactJSONPath(child, path[1:])
+			}
+		}
+		return typed
+	default:
+		if len(path) == 0 {
+			return redactedValue
+		}
+		return value
+	}
+}
+
+func (s *Server) redaction(tenantID string) *RedactionPolicy {
+	tenant, ok := s.ruleStore.GetTenant(tenantID)
+	if !ok {
+		return nil
+	}
+	return tenant.Redaction
+}
+
+func (s *Server) handleTenantRedaction(w http.ResponseWriter, r *http.Request, user User, tenantID string) {
+	tenant, ok := s.ruleStore.GetTenant(tenantID)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"tenant_id":       tenant.ID,
+			"redaction":       tenant.Redaction,
+			"default_headers": defaultRedactedHeaders,
+		})
+	case http.MethodPut:
+		if !s.canMutateTenantConfig(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAdminRequired, "forbidden tenant configuration access")
+			return
+		}
+		var request RedactionPolicy
+		if !s.decodeJSON(w, r, &request, "redaction payload") {
+			return
+		}
+		tenant, err := s.ruleStore.SetTenantRedaction(tenantID, &request)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message":         "redaction policy updated",
+			"tenant_id":       tenant.ID,
+			"redaction":       tenant.Redaction,
+			"default_headers": defaultRedactedHeaders,
+		})
+		s.persistState()
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedactionPolicyMasksHeadersJSONFieldsAndPatterns(t *testing.T) {
+	if _, err := normalizeRedactionPolicy(&RedactionPolicy{Patterns: []string{"("}}); err == nil {
+		t.Fatalf("expected an invalid pattern to be rejected")
+	}
+	if _, err := normalizeRedactionPolicy(&RedactionPolicy{JSONFields: []string{"user..password"}}); err == nil {
+		t.Fatalf("expected an empty path segment to be rejected")
+	}
+	policy, err := normalizeRedactionPolicy(&RedactionPolicy{
+		Headers:    []string{"x-api-key"},
+		JSONFields: []string{"user.password", "cards.*"},
+		Patterns:   []string{`\b\d{3}-\d{2}-\d{4}\b`},
+	})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+
+	headers := policy.RedactHeaders(map[string][]string{
+		"Authorization": {"Bearer abc"},
+		"X-Api-Key":     {"k1"},
+		"X-Note":        {"ssn 123-45-6789"},
+	})
+	if headers["Authorization"][0] != redactedValue || headers["X-Api-Key"][0] != redactedValue || headers["X-Note"][0] != "ssn "+redactedValue {
+		t.Fatalf("unexpected headers %+v", headers)
+	}
+	if got := (*RedactionPolicy)(nil).RedactHeaders(map[string][]string{"Cookie": {"a=b"}}); got["Cookie"][0] != redactedValue {
+		t.Fatalf("expected cookies to be masked without a policy, got %+v", got)
+	}
+
+	body := string(policy.RedactBody([]byte(`{"user":{"name":"ann","password":"hunter2"},"cards":[{"pan":"4111"}],"note":"123-45-6789"}`)))
+	for _, leaked := range []string{"hunter2", "4111", "123-45-6789"} {
+		if strings.Contains(body, leaked) {
+			t.Fatalf("expected %q to be masked in %s", leaked, body)
+		}
+	}
+	if !strings.Contains(body, `"name":"ann"`) {
+		t.Fatalf("expected other fields to be kept, got %s", body)
+	}
+	if got := string(policy.RedactBody([]byte("plain 123-45-6789"))); got != "plain "+redactedValue {
+		t.Fatalf("expected patterns to apply to non-JSON bodies, got %q", got)
+	}
+}
+
+func TestSyntheticCheckIncidentsApplyTenantRedaction(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":"db down","token":"sk-live-123"}`))
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.SetTenantRedaction(DefaultTenantID, &RedactionPolicy{JSONFields: []string{"token"}}); err != nil {
+		t.Fatalf("set redaction: %v", err)
+	}
+	rule, err := server.ruleStore.Upsert(Rule{ID: "app", Target: upstream.URL, SyntheticCheck: &RouteSyntheticCheck{FailureThreshold: 1}})
+	if err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	status := server.runSyntheticCheck(context.Background(), rule)
+	incidents := server.incidentStore.List(10)
+	if len(incidents) != 1 {
+		t.Fatalf("expected an incident, got %+v", incidents)
+	}
+	for _, detail := range []string{status.LastError, incidents[0].Message} {
+		if strings.Contains(detail, "sk-live-123") || !strings.Contains(detail, "db down") {
+			t.Fatalf("expected the token to be masked, got %q", detail)
+		}
+	}
+}
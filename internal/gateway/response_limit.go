@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const responseLimitHeader = "X-Proxer-Limit-Exceeded"
+
+const (
+	responseLimitRoute   = "route"
+	responseLimitGateway = "gateway"
+)
+
+// responseBodyLimit is the response size limit for a request and where it
+// comes from. A route override is re-clamped here because the gateway limit
+// may have been lowered since the route was saved.
+func (s *Server) responseBodyLimit(rule Rule, hasRule bool) (int64, string) {
+	limit := s.config().MaxResponseBodyBytes
+	if hasRule && rule.MaxResponseBodyBytes > 0 && rule.MaxResponseBodyBytes < limit {
+		return rule.MaxResponseBodyBytes, responseLimitRoute
+	}
+	return limit, responseLimitGateway
+}
+
+// enforceResponseLimit flags an agent response over limit, from agents that
+// predate ProxyRequest.MaxResponseBodyBytes, as if the agent had.
+func enforceResponseLimit(response *protocol.ProxyResponse, limit int64) {
+	if response.LimitExceeded != nil || limit <= 0 || int64(len(response.Body)) <= limit {
+		return
+	}
+	size := int64(len(response.Body))
+	response.LimitExceeded = &protocol.ResponseLimit{Source: protocol.ResponseLimitRequest, LimitBytes: limit, ReadBytes: size, ContentLength: size}
+	response.Body = nil
+}
+
+func writeResponseLimitError(w http.ResponseWriter, requestSource string, exceeded *protocol.ResponseLimit) {
+	source := exceeded.Source
+	if source == protocol.ResponseLimitRequest || source == "" {
+		source = requestSource
+	}
+	message := fmt.Sprintf("upstream response exceeds the %s limit of %d bytes", source, exceeded.LimitBytes)
+	w.Header().Set(responseLimitHeader, fmt.Sprintf("response-body; source=%s; limit=%d", source, exceeded.LimitBytes))
+	writeAPIErrorDetails(w, http.StatusBadGateway, errCodeResponseTooLarge, message, map[string]any{
+		"source":         source,
+		"limit_bytes":    exceeded.LimitBytes,
+		"read_bytes":     exceeded.ReadBytes,
+		"content_length": exceeded.ContentLength,
+	})
+}
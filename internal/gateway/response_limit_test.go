@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestProxyAbortsResponsesOverTheLimit(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			for range 64 {
+				_, _ = w.Write([]byte(strings.Repeat("x", 1024)))
+				w.(http.Flusher).Flush()
+			}
+			return
+		}
+		w.Header().Set("Content-Length", "4096")
+		_, _ = w.Write([]byte(strings.Repeat("x", 4096)))
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory", MaxResponseBodyBytes: 8192}, nil)
+	for _, rule := range []Rule{
+		{ID: "app", Target: upstream.URL},
+		{ID: "small", Target: upstream.URL, MaxResponseBodyBytes: 1024},
+	} {
+		if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, rule); err != nil {
+			t.Fatalf("upsert route %s: %v", rule.ID, err)
+		}
+	}
+
+	cases := []struct {
+		path          string
+		header        string
+		readBytes     int64
+		contentLength int64
+	}{
+		{path: "/t/app/chunked", header: "response-body; source=gateway; limit=8192", readBytes: 8193, contentLength: -1},
+		{path: "/t/small/", header: "response-body; source=route; limit=1024", readBytes: 0, contentLength: 4096},
+	}
+	for _, tc := range cases {
+		recorder := httptest.NewRecorder()
+		server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if recorder.Code != http.StatusBadGateway || recorder.Header().Get(responseLimitHeader) != tc.header {
+			t.Fatalf("%s: unexpected %d %q", tc.path, recorder.Code, recorder.Header().Get(responseLimitHeader))
+		}
+		var body apiError
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: decode error: %v", tc.path, err)
+		}
+		if body.Code != errCodeResponseTooLarge || body.Details["read_bytes"] != float64(tc.readBytes) || body.Details["content_length"] != float64(tc.contentLength) {
+			t.Fatalf("%s: unexpected error %+v", tc.path, body)
+		}
+	}
+	if metric := server.hub.GetTunnelMetrics(MakeTunnelKey(DefaultTenantID, "small")); metric.ErrorCount != 1 {
+		t.Fatalf("expected the cut off response to count as an error, got %+v", metric)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/app/", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.Len() != 4096 {
+		t.Fatalf("expected a response under the limit to pass, got %d with %d bytes", recorder.Code, recorder.Body.Len())
+	}
+
+	if _, code, err := server.validateRouteRequest(DefaultTenantID, upsertRuleRequest{ID: "big", Target: upstream.URL, MaxResponseBodyBytes: 8193}); err == nil || code != errCodeInvalidRequest {
+		t.Fatalf("expected a route limit above the gateway limit to be rejected, got %v", err)
+	}
+}
+
+func TestEnforceResponseLimitFlagsOversizedAgentResponses(t *testing.T) {
+	response := &protocol.ProxyResponse{Status: http.StatusOK, Body: []byte("0123456789")}
+	enforceResponseLimit(response, 16)
+	if response.LimitExceeded != nil {
+		t.Fatalf("expected a small response to pass")
+	}
+	enforceResponseLimit(response, 4)
+	if response.LimitExceeded == nil || response.LimitExceeded.ReadBytes != 10 || response.Body != nil {
+		t.Fatalf("expected an oversized response from an older agent to be flagged, got %+v", response.LimitExceeded)
+	}
+}
@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const maxResponseRewriteBytes = 2 << 20
+
+// rootRelativeAttrPattern matches HTML URL attributes whose value starts with a
+// single "/"; protocol-relative "//host" values are left alone.
+var rootRelativeAttrPattern = regexp.MustCompile(`(?i)(\s(?:href|src|action|formaction|poster|data-src)\s*=\s*["'])/([^/][^"']*)?(["'])`)
+
+func (rw *RouteRewrite) rewritesResponses() bool {
+	return rw != nil && rw.ResponseURLs
+}
+
+// rewriteResponseURLs prefixes root-relative URLs in HTML bodies and Location
+// headers with the route's public prefix so apps that assume they are served
+// from "/" keep working under /t/{tenant}/{route}/. Compressed, non-HTML and
+// oversized bodies are passed through untouched.
+func rewriteResponseURLs(resp *protocol.ProxyResponse, publicPrefix string) {
+	publicPrefix = strings.TrimRight(publicPrefix, "/")
+	if resp == nil || publicPrefix == "" {
+		return
+	}
+	headers := http.Header(resp.Headers)
+	if location := headers.Get("Location"); location != "" {
+		headers.Set("Location", prefixRootRelativeURL(location, publicPrefix))
+	}
+
+	if len(resp.Body) == 0 || len(resp.Body) > maxResponseRewriteBytes {
+		return
+	}
+	if encoding := strings.TrimSpace(headers.Get("Content-Encoding")); encoding != "" && !strings.EqualFold(encoding, "identity") {
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(headers.Get("Content-Type"))
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return
+	}
+
+	rewritten := rootRelativeAttrPattern.ReplaceAllFunc(resp.Body, func(match []byte) []byte {
+		parts := rootRelativeAttrPattern.FindSubmatch(match)
+		path := "/" + string(parts[2])
+		return []byte(string(parts[1]) + prefixRootRelativeURL(path, publicPrefix) + string(parts[3]))
+	})
+	resp.Body = rewritten
+	if headers.Get("Content-Length") != "" {
+		headers.Set("Content-Length", strconv.Itoa(len(rewritten)))
+	}
+}
+
+func prefixRootRelativeURL(value, publicPrefix string) string {
+	if !strings.HasPrefix(value, "/") || strings.HasPrefix(value, "//") {
+		return value
+	}
+	if value == publicPrefix || strings.HasPrefix(value, publicPrefix+"/") {
+		return value
+	}
+	return publicPrefix + value
+}
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	retentionSweepInterval = 10 * time.Minute
+	minRetentionPeriod     = time.Hour
+	maxRetentionPeriod     = 365 * 24 * time.Hour
+)
+
+type TenantRetention struct {
+	Timeseries    string `json:"timeseries,omitempty"`
+	Audit         string `json:"audit,omitempty"`
+	NoBodyStorage bool   `json:"no_body_storage,omitempty"`
+}
+
+func normalizeTenantRetention(input *TenantRetention) (*TenantRetention, error) {
+	if input == nil {
+		return nil, nil
+	}
+	retention := &TenantRetention{
+		Timeseries:    strings.ToLower(strings.TrimSpace(input.Timeseries)),
+		Audit:         strings.ToLower(strings.TrimSpace(input.Audit)),
+		NoBodyStorage: input.NoBodyStorage,
+	}
+	for field, raw := range map[string]string{"timeseries": retention.Timeseries, "audit": retention.Audit} {
+		if raw == "" {
+			continue
+		}
+		if _, err := parseRetentionPeriod(raw); err != nil {
+			return nil, fmt.Errorf("retention.%s: %w", field, err)
+		}
+	}
+	if *retention == (TenantRetention{}) {
+		return nil, nil
+	}
+	return retention, nil
+}
+
+func parseRetentionPeriod(raw string) (time.Duration, error) {
+	var period time.Duration
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid period %q", raw)
+		}
+		period = time.Duration(count) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return 0, fmt.Errorf("invalid period %q", raw)
+		}
+		period = parsed
+	}
+	if period < minRetentionPeriod || period > maxRetentionPeriod {
+		return 0, fmt.Errorf("period must be between 1h and 365d")
+	}
+	return period, nil
+}
+
+func (r *TenantRetention) timeseriesPeriod() (time.Duration, bool) {
+	if r == nil || r.Timeseries == "" {
+		return 0, false
+	}
+	period, err := parseRetentionPeriod(r.Timeseries)
+	return period, err == nil
+}
+
+func (r *TenantRetention) auditPeriod() (time.Duration, bool) {
+	if r == nil || r.Audit == "" {
+		return 0, false
+	}
+	period, err := parseRetentionPeriod(r.Audit)
+	return period, err == nil
+}
+
+func (r *TenantRetention) storesBodies() bool {
+	return r == nil || !r.NoBodyStorage
+}
+
+func (s *Server) retention(tenantID string) *TenantRetention {
+	tenant, ok := s.ruleStore.GetTenant(tenantID)
+	if !ok {
+		return nil
+	}
+	return tenant.Retention
+}
+
+func (s *Server) pruneRetention(now time.Time) int {
+	pruned := 0
+	for _, tenant := range s.ruleStore.ListTenants() {
+		if period, ok := tenant.Retention.timeseriesPeriod(); ok {
+			cutoff := now.Add(-period)
+			pruned += s.hub.Timeseries().PruneTenant(tenant.ID, cutoff)
+			pruned += s.hub.Availability().PruneTenant(tenant.ID, cutoff)
+			pruned += s.transfer.PruneTenant(tenant.ID, cutoff)
+		}
+		if period, ok := tenant.Retention.auditPeriod(); ok {
+			pruned += s.auditStore.PruneTenant(tenant.ID, now.Add(-period))
+		}
+	}
+	return pruned
+}
+
+func (s *Server) runRetentionLoop(ctx context.Context) {
+	ticker := time.NewTicker(retentionSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			// Per-client rate limit buckets and violation counters would
+			// otherwise grow with every address that ever sent a request.
+			s.rateLimiter.Sweep(now.UTC())
+			if s.pruneRetention(now.UTC())+s.trash.Purge(now.UTC())+s.ipBans.Sweep(now.UTC()) > 0 {
+				s.persistState()
+			}
+		}
+	}
+}
+
+func (s *Server) handleTenantRetention(w http.ResponseWriter, r *http.Request, user User, tenantID string) {
+	tenant, ok := s.ruleStore.GetTenant(tenantID)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"tenant_id": tenant.ID,
+			"retention": tenant.Retention,
+		})
+	case http.MethodPut:
+		if !s.canMutateTenantConfig(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAdminRequired, "forbidden tenant configuration access")
+			return
+		}
+		var request TenantRetention
+		if !s.decodeJSON(w, r, &request, "retention payload") {
+			return
+		}
+		tenant, err := s.ruleStore.SetTenantRetention(tenantID, &request)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		// Apply shorter periods right away rather than on the next sweep.
+		s.pruneRetention(time.Now().UTC())
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message":   "retention updated",
+			"tenant_id": tenant.ID,
+			"retention": tenant.Retention,
+		})
+		s.persistState()
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeTenantRetention(t *testing.T) {
+	for _, invalid := range []string{"week", "30m", "400d", "-1d"} {
+		if _, err := normalizeTenantRetention(&TenantRetention{Timeseries: invalid}); err == nil {
+			t.Fatalf("expected %q to be rejected", invalid)
+		}
+	}
+	if retention, err := normalizeTenantRetention(&TenantRetention{}); err != nil || retention != nil {
+		t.Fatalf("expected empty settings to clear retention, got %+v, %v", retention, err)
+	}
+	retention, err := normalizeTenantRetention(&TenantRetention{Timeseries: " 7D ", Audit: "12h"})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if period, ok := retention.timeseriesPeriod(); !ok || period != 7*24*time.Hour {
+		t.Fatalf("expected 7 days, got %s", period)
+	}
+	if period, ok := retention.auditPeriod(); !ok || period != 12*time.Hour {
+		t.Fatalf("expected 12 hours, got %s", period)
+	}
+}
+
+func TestPruneRetentionDropsOnlyExpiredTenantData(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.UpsertTenant(Tenant{ID: "acme"}); err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+	if _, err := server.ruleStore.SetTenantRetention("acme", &TenantRetention{Timeseries: "2h", Audit: "1h"}); err != nil {
+		t.Fatalf("set retention: %v", err)
+	}
+
+	now := time.Now().UTC()
+	old := now.Add(-3 * time.Hour)
+	for _, tenantID := range []string{"acme", DefaultTenantID} {
+		key := MakeTunnelKey(tenantID, "app")
+		server.hub.Timeseries().Record(key, old, false, 1, 1)
+		server.hub.Timeseries().Record(key, now, false, 1, 1)
+		server.hub.Availability().RecordRequest(key, old, false, 10)
+		server.hub.Availability().RecordRequest(key, now, false, 10)
+		server.transfer.Record(tenantID, "app", "", 1, 1, now.AddDate(0, 0, -1))
+	}
+	event := server.auditStore.Record("ann", "route.upserted", "acme", nil)
+	server.auditStore.items[event.ID] = AuditEvent{ID: event.ID, TenantID: "acme", CreatedAt: old}
+
+	if pruned := server.pruneRetention(now); pruned != 4 {
+		t.Fatalf("expected a point, a bucket, a transfer record and an audit event to be pruned, got %d", pruned)
+	}
+	if points := server.hub.Timeseries().Snapshot()[MakeTunnelKey("acme", "app")]; len(points) != 1 {
+		t.Fatalf("expected only the recent point of acme, got %+v", points)
+	}
+	if points := server.hub.Timeseries().Snapshot()[MakeTunnelKey(DefaultTenantID, "app")]; len(points) != 2 {
+		t.Fatalf("expected the default tenant to keep its points, got %+v", points)
+	}
+	if summary := server.transfer.Summary(DefaultTenantID, 30, now); len(summary.Routes) != 1 {
+		t.Fatalf("expected the default tenant to keep its transfer, got %+v", summary)
+	}
+	if events := server.auditStore.List("acme", 10); len(events) != 0 {
+		t.Fatalf("expected the old audit event to be pruned, got %+v", events)
+	}
+}
+
+func TestNoBodyStorageKeepsBodiesOutOfSyntheticFailures(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "customer jane@example.com not found", http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.SetTenantRetention(DefaultTenantID, &TenantRetention{NoBodyStorage: true}); err != nil {
+		t.Fatalf("set retention: %v", err)
+	}
+	rule, err := server.ruleStore.Upsert(Rule{ID: "app", Target: upstream.URL, SyntheticCheck: &RouteSyntheticCheck{FailureThreshold: 1}})
+	if err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	status := server.runSyntheticCheck(context.Background(), rule)
+	if !strings.HasPrefix(status.LastError, "status 500") || strings.Contains(status.LastError, "jane") {
+		t.Fatalf("expected the failure without the body, got %q", status.LastError)
+	}
+}
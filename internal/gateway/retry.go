@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const (
+	maxRetryAttempts       = 5
+	defaultRetryBackoffMs  = 100
+	defaultRetryMaxBackoff = 2000
+	maxRetryBackoffMs      = 30000
+)
+
+var defaultRetryOnStatus = []int{502, 503, 504}
+
+func normalizeRetryPolicy(input *protocol.RetryPolicy) (*protocol.RetryPolicy, error) {
+	if input == nil || input.Attempts == 0 {
+		return nil, nil
+	}
+	if input.Attempts < 1 || input.Attempts > maxRetryAttempts {
+		return nil, fmt.Errorf("retry.attempts must be between 1 and %d", maxRetryAttempts)
+	}
+	if input.Attempts == 1 {
+		return nil, nil
+	}
+	out := &protocol.RetryPolicy{
+		Attempts:     input.Attempts,
+		BackoffMs:    input.BackoffMs,
+		MaxBackoffMs: input.MaxBackoffMs,
+	}
+	if out.BackoffMs == 0 {
+		out.BackoffMs = defaultRetryBackoffMs
+	}
+	if out.MaxBackoffMs == 0 {
+		out.MaxBackoffMs = max(defaultRetryMaxBackoff, out.BackoffMs)
+	}
+	if out.BackoffMs < 0 || out.BackoffMs > maxRetryBackoffMs {
+		return nil, fmt.Errorf("retry.backoff_ms must be between 0 and %d", maxRetryBackoffMs)
+	}
+	if out.MaxBackoffMs < out.BackoffMs || out.MaxBackoffMs > maxRetryBackoffMs {
+		return nil, fmt.Errorf("retry.max_backoff_ms must be between backoff_ms and %d", maxRetryBackoffMs)
+	}
+
+	statuses := input.RetryOnStatus
+	if len(statuses) == 0 {
+		statuses = defaultRetryOnStatus
+	}
+	seen := make(map[int]bool, len(statuses))
+	for _, status := range statuses {
+		if status != 429 && (status < 500 || status > 599) {
+			return nil, fmt.Errorf("retry.retry_on_status must list 429 or 5xx statuses, got %d", status)
+		}
+		if !seen[status] {
+			seen[status] = true
+			out.RetryOnStatus = append(out.RetryOnStatus, status)
+		}
+	}
+	sort.Ints(out.RetryOnStatus)
+	return out, nil
+}
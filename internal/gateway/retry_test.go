@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestForwardDirectRetriesIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1)%3 != 0 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	policy, err := normalizeRetryPolicy(&protocol.RetryPolicy{Attempts: 3, BackoffMs: 1})
+	if err != nil {
+		t.Fatalf("normalize retry policy: %v", err)
+	}
+	rule := Rule{TenantID: DefaultTenantID, ID: "app", Target: upstream.URL, Retry: policy}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := server.forwardDirect(ctx, rule, &protocol.ProxyRequest{Method: http.MethodGet, Path: "/", TunnelID: "default/app", Retry: policy}, nil)
+	if err != nil {
+		t.Fatalf("forward: %v", err)
+	}
+	if resp.Status != http.StatusOK || resp.Retries != 2 {
+		t.Fatalf("expected success after two retries, got status %d with %d retries", resp.Status, resp.Retries)
+	}
+
+	calls.Store(0)
+	resp, err = server.forwardDirect(ctx, rule, &protocol.ProxyRequest{Method: http.MethodPost, Path: "/", TunnelID: "default/app", Retry: policy}, nil)
+	if err != nil {
+		t.Fatalf("forward: %v", err)
+	}
+	if resp.Status != http.StatusServiceUnavailable || resp.Retries != 0 || calls.Load() != 1 {
+		t.Fatalf("expected POST to be sent once, got status %d after %d calls", resp.Status, calls.Load())
+	}
+
+	server.hub.RecordProxyResponse(&protocol.ProxyResponse{TunnelID: "default/app", Status: http.StatusOK, Retries: 2})
+	server.hub.RecordProxyRetries("default/app", 1)
+	if metrics := server.hub.GetTunnelMetrics("default/app"); metrics.RetryCount != 3 {
+		t.Fatalf("expected 3 retries in route metrics, got %d", metrics.RetryCount)
+	}
+}
+
+func TestNormalizeRetryPolicy(t *testing.T) {
+	policy, err := normalizeRetryPolicy(&protocol.RetryPolicy{Attempts: 3, RetryOnStatus: []int{503, 429, 503}})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if policy.BackoffMs != defaultRetryBackoffMs || policy.MaxBackoffMs != defaultRetryMaxBackoff || len(policy.RetryOnStatus) != 2 || policy.RetryOnStatus[0] != 429 {
+		t.Fatalf("unexpected normalized policy %+v", policy)
+	}
+	if policy, err := normalizeRetryPolicy(&protocol.RetryPolicy{Attempts: 1}); err != nil || policy != nil {
+		t.Fatalf("expected a single attempt to disable retries, got %+v %v", policy, err)
+	}
+	for _, input := range []protocol.RetryPolicy{
+		{Attempts: 9},
+		{Attempts: 2, RetryOnStatus: []int{404}},
+		{Attempts: 2, BackoffMs: 500, MaxBackoffMs: 100},
+	} {
+		if _, err := normalizeRetryPolicy(&input); err == nil {
+			t.Fatalf("expected %+v to be rejected", input)
+		}
+	}
+}
@@ -0,0 +1,461 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+type ReverseForward struct {
+	ID          string     `json:"id"`
+	TenantID    string     `json:"tenant_id"`
+	ConnectorID string     `json:"connector_id"`
+	AgentID     string     `json:"agent_id,omitempty"`
+	Target      string     `json:"target"`
+	Status      string     `json:"status"`
+	RequestedAt time.Time  `json:"requested_at"`
+	DecidedBy   string     `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	// ActiveConnections counts the connections open right now; it is not
+	// persisted.
+	ActiveConnections int `json:"active_connections"`
+}
+
+var ErrReverseForwardsDisabled = errors.New("reverse forwards are disabled on this gateway; set PROXER_REVERSE_FORWARD_TARGETS")
+
+type ReverseForwardStore struct {
+	mu       sync.Mutex
+	counter  uint64
+	forwards map[string]ReverseForward
+	streams  map[string]map[string]struct{}
+}
+
+func NewReverseForwardStore() *ReverseForwardStore {
+	return &ReverseForwardStore{
+		forwards: make(map[string]ReverseForward),
+		streams:  make(map[string]map[string]struct{}),
+	}
+}
+
+func (s *ReverseForwardStore) Request(tenantID, connectorID, agentID string, targets []string) ([]ReverseForward, []ReverseForward) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	forwards := make([]ReverseForward, 0, len(targets))
+	created := make([]ReverseForward, 0)
+	for _, target := range targets {
+		forward, ok := s.findLocked(connectorID, target)
+		if !ok {
+			forward = ReverseForward{
+				ID:          fmt.Sprintf("fwd-%d-%d", now.UnixNano(), atomic.AddUint64(&s.counter, 1)),
+				TenantID:    tenantID,
+				ConnectorID: connectorID,
+				AgentID:     agentID,
+				Target:      target,
+				Status:      protocol.ForwardPending,
+				RequestedAt: now,
+			}
+			s.forwards[forward.ID] = forward
+			created = append(created, forward)
+		}
+		forwards = append(forwards, s.viewLocked(forward))
+	}
+	return forwards, created
+}
+
+func (s *ReverseForwardStore) findLocked(connectorID, target string) (ReverseForward, bool) {
+	for _, forward := range s.forwards {
+		if forward.ConnectorID == connectorID && forward.Target == target {
+			return forward, true
+		}
+	}
+	return ReverseForward{}, false
+}
+
+func (s *ReverseForwardStore) viewLocked(forward ReverseForward) ReverseForward {
+	forward.ActiveConnections = len(s.streams[forward.ID])
+	return forward
+}
+
+func (s *ReverseForwardStore) Get(id string) (ReverseForward, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	forward, ok := s.forwards[strings.TrimSpace(id)]
+	if !ok {
+		return ReverseForward{}, false
+	}
+	return s.viewLocked(forward), true
+}
+
+func (s *ReverseForwardStore) ListTenant(tenantID string) []ReverseForward {
+	tenantID = normalizeIdentifier(tenantID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	forwards := make([]ReverseForward, 0)
+	for _, forward := range s.forwards {
+		if forward.TenantID == tenantID {
+			forwards = append(forwards, s.viewLocked(forward))
+		}
+	}
+	sort.Slice(forwards, func(i, j int) bool {
+		if !forwards[i].RequestedAt.Equal(forwards[j].RequestedAt) {
+			return forwards[i].RequestedAt.Before(forwards[j].RequestedAt)
+		}
+		return forwards[i].ID < forwards[j].ID
+	})
+	return forwards
+}
+
+func (s *ReverseForwardStore) Decide(tenantID, id, status, actor string) (ReverseForward, []string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	forward, ok := s.forwards[strings.TrimSpace(id)]
+	if !ok || forward.TenantID != normalizeIdentifier(tenantID) {
+		return ReverseForward{}, nil, false
+	}
+	now := time.Now().UTC()
+	forward.Status = status
+	forward.DecidedBy = actor
+	forward.DecidedAt = &now
+	s.forwards[forward.ID] = forward
+	var streams []string
+	if status != protocol.ForwardApproved {
+		streams = s.takeStreamsLocked(forward.ID)
+	}
+	return s.viewLocked(forward), streams, true
+}
+
+func (s *ReverseForwardStore) Delete(tenantID, id string) ([]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	forward, ok := s.forwards[strings.TrimSpace(id)]
+	if !ok || forward.TenantID != normalizeIdentifier(tenantID) {
+		return nil, false
+	}
+	delete(s.forwards, forward.ID)
+	return s.takeStreamsLocked(forward.ID), true
+}
+
+func (s *ReverseForwardStore) DeleteTenant(tenantID string) []string {
+	tenantID = normalizeIdentifier(tenantID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var streams []string
+	for id, forward := range s.forwards {
+		if forward.TenantID == tenantID {
+			delete(s.forwards, id)
+			streams = append(streams, s.takeStreamsLocked(id)...)
+		}
+	}
+	return streams
+}
+
+func (s *ReverseForwardStore) takeStreamsLocked(id string) []string {
+	streams := make([]string, 0, len(s.streams[id]))
+	for streamID := range s.streams[id] {
+		streams = append(streams, streamID)
+	}
+	delete(s.streams, id)
+	return streams
+}
+
+func (s *ReverseForwardStore) trackStream(id, streamID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if forward, ok := s.forwards[id]; !ok || forward.Status != protocol.ForwardApproved {
+		return false
+	}
+	if s.streams[id] == nil {
+		s.streams[id] = make(map[string]struct{})
+	}
+	s.streams[id][streamID] = struct{}{}
+	return true
+}
+
+func (s *ReverseForwardStore) untrackStream(id, streamID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams[id], streamID)
+	if len(s.streams[id]) == 0 {
+		delete(s.streams, id)
+	}
+}
+
+func (s *ReverseForwardStore) Snapshot() []ReverseForward {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	forwards := make([]ReverseForward, 0, len(s.forwards))
+	for _, forward := range s.forwards {
+		forwards = append(forwards, forward)
+	}
+	sort.Slice(forwards, func(i, j int) bool { return forwards[i].ID < forwards[j].ID })
+	return forwards
+}
+
+func (s *ReverseForwardStore) Restore(forwards []ReverseForward) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forwards = make(map[string]ReverseForward, len(forwards))
+	for _, forward := range forwards {
+		if forward.ID == "" || forward.ConnectorID == "" {
+			continue
+		}
+		forward.ActiveConnections = 0
+		s.forwards[forward.ID] = forward
+	}
+}
+
+func normalizeForwardTarget(target string) (string, error) {
+	host, port, err := net.SplitHostPort(strings.TrimSpace(target))
+	if err != nil {
+		return "", fmt.Errorf("invalid forward target %q: expected host:port", target)
+	}
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" {
+		return "", fmt.Errorf("invalid forward target %q: missing host", target)
+	}
+	if value, err := strconv.Atoi(port); err != nil || value < 1 || value > 65535 {
+		return "", fmt.Errorf("invalid forward target %q: port must be between 1 and 65535", target)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+func validateReverseForwardPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		host, port, err := net.SplitHostPort(pattern)
+		if err != nil || host == "" || port == "" {
+			return fmt.Errorf("invalid pattern %q: expected host:port", pattern)
+		}
+		if _, err := path.Match(host, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if value, err := strconv.Atoi(port); port != "*" && (err != nil || value < 1 || value > 65535) {
+			return fmt.Errorf("invalid pattern %q: port must be * or between 1 and 65535", pattern)
+		}
+	}
+	return nil
+}
+
+func reverseForwardAllowed(patterns []string, target string) bool {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range patterns {
+		patternHost, patternPort, err := net.SplitHostPort(pattern)
+		if err != nil || (patternPort != "*" && patternPort != port) {
+			continue
+		}
+		if matched, _ := path.Match(strings.ToLower(patternHost), host); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) forwardSession(w http.ResponseWriter, sessionID string) (Connector, string, bool) {
+	if len(s.config().ReverseForwardTargets) == 0 {
+		writeAPIError(w, http.StatusNotImplemented, errCodeNotImplemented, ErrReverseForwardsDisabled.Error())
+		return Connector{}, "", false
+	}
+	if strings.TrimSpace(sessionID) == "" {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing session_id")
+		return Connector{}, "", false
+	}
+	connectorID, agentID, ok := s.hub.sessionOwner(strings.TrimSpace(sessionID))
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeUnknownSession, ErrUnknownSession.Error())
+		return Connector{}, "", false
+	}
+	connector, ok := s.connectorStore.Get(connectorID)
+	if connectorID == "" || !ok {
+		writeAPIError(w, http.StatusForbidden, errCodeForbidden, "reverse forwards need a connector agent")
+		return Connector{}, "", false
+	}
+	return connector, agentID, true
+}
+
+func (s *Server) handleAgentForwards(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	var payload protocol.ForwardsRequest
+	if !s.decodeJSON(w, r, &payload, "forwards payload") {
+		return
+	}
+	connector, agentID, ok := s.forwardSession(w, payload.SessionID)
+	if !ok {
+		return
+	}
+	patterns := s.config().ReverseForwardTargets
+	targets := make([]string, 0, len(payload.Targets))
+	for _, raw := range payload.Targets {
+		target, err := normalizeForwardTarget(raw)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		if !reverseForwardAllowed(patterns, target) {
+			writeAPIError(w, http.StatusForbidden, errCodeForbidden, fmt.Sprintf("forward target %s is not allowed on this gateway", target))
+			return
+		}
+		targets = append(targets, target)
+	}
+
+	forwards, created := s.reverseForwards.Request(connector.TenantID, connector.ID, agentID, targets)
+	for _, forward := range created {
+		s.auditStore.Record("connector:"+connector.ID, "forward.request", forward.TenantID, map[string]string{
+			"forward_id": forward.ID,
+			"target":     forward.Target,
+			"agent_id":   agentID,
+		})
+		s.events.Publish("forward.requested", forward.TenantID, forward)
+	}
+	if len(created) > 0 {
+		s.persistState()
+	}
+	response := protocol.ForwardsResponse{Forwards: make([]protocol.ForwardStatus, 0, len(forwards))}
+	for _, forward := range forwards {
+		response.Forwards = append(response.Forwards, protocol.ForwardStatus{ID: forward.ID, Target: forward.Target, Status: forward.Status})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleAgentForwardOpen(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	var payload protocol.ForwardOpenRequest
+	if !s.decodeJSON(w, r, &payload, "forward open payload") {
+		return
+	}
+	connector, _, ok := s.forwardSession(w, payload.SessionID)
+	if !ok {
+		return
+	}
+	forward, ok := s.reverseForwards.Get(payload.ForwardID)
+	if !ok || forward.ConnectorID != connector.ID {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "forward not found")
+		return
+	}
+	if forward.Status != protocol.ForwardApproved {
+		writeAPIError(w, http.StatusForbidden, errCodeForbidden, fmt.Sprintf("forward to %s is %s", forward.Target, forward.Status))
+		return
+	}
+	if !reverseForwardAllowed(s.config().ReverseForwardTargets, forward.Target) {
+		writeAPIError(w, http.StatusForbidden, errCodeForbidden, fmt.Sprintf("forward target %s is not allowed on this gateway", forward.Target))
+		return
+	}
+
+	conn, err := (&net.Dialer{Timeout: passthroughConnectTimeout}).DialContext(r.Context(), "tcp", forward.Target)
+	if err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, errCodeUnavailable, fmt.Sprintf("connect to %s: %v", forward.Target, err))
+		return
+	}
+	streamID := s.nextRequestID()
+	stream := s.hub.openStream(streamID, connector.ID, conn, s.connectorBandwidth(connector.ID))
+	if !s.reverseForwards.trackStream(forward.ID, streamID) {
+		s.hub.closeStream(streamID)
+		writeAPIError(w, http.StatusForbidden, errCodeForbidden, "forward is no longer approved")
+		return
+	}
+	go func() {
+		defer s.reverseForwards.untrackStream(forward.ID, streamID)
+		defer s.hub.closeStream(streamID)
+		select {
+		case <-stream.attached:
+		case <-stream.done:
+			return
+		case <-time.After(passthroughConnectTimeout):
+			s.logger.Printf("reverse forward %s stream=%s: agent did not attach the stream", forward.ID, streamID)
+			return
+		}
+		<-stream.done
+	}()
+	writeJSON(w, http.StatusOK, protocol.ForwardOpenResponse{StreamID: streamID})
+}
+
+func (s *Server) handleTenantForwards(w http.ResponseWriter, r *http.Request, tenantID string) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id": tenantID,
+		"forwards":  s.reverseForwards.ListTenant(tenantID),
+	})
+}
+
+func (s *Server) handleTenantForwardByID(w http.ResponseWriter, r *http.Request, user User, tenantID, forwardID, action string) {
+	wantMethod := http.MethodDelete
+	if action != "" {
+		wantMethod = http.MethodPost
+	}
+	if r.Method != wantMethod {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.canMutateTenantConfig(user, tenantID) {
+		writeAPIError(w, http.StatusForbidden, errCodeTenantAdminRequired, "forbidden tenant configuration access")
+		return
+	}
+
+	var (
+		streams []string
+		found   bool
+	)
+	switch action {
+	case "":
+		streams, found = s.reverseForwards.Delete(tenantID, forwardID)
+		if found {
+			s.auditStore.Record(user.Username, "forward.delete", tenantID, map[string]string{"forward_id": forwardID})
+		}
+	case "approve", "deny":
+		status := protocol.ForwardApproved
+		if action == "deny" {
+			status = protocol.ForwardDenied
+		}
+		var forward ReverseForward
+		forward, streams, found = s.reverseForwards.Decide(tenantID, forwardID, status, user.Username)
+		if found {
+			s.auditStore.Record(user.Username, "forward."+action, tenantID, map[string]string{
+				"forward_id":   forward.ID,
+				"target":       forward.Target,
+				"connector_id": forward.ConnectorID,
+			})
+			s.events.Publish("forward.updated", tenantID, forward)
+			writeJSON(w, http.StatusOK, map[string]any{"forward": forward})
+		}
+	default:
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid tenant subresource path")
+		return
+	}
+	if !found {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "forward not found")
+		return
+	}
+	s.closeReverseForwardStreams(streams)
+	s.persistState()
+	if action == "" {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) closeReverseForwardStreams(streams []string) {
+	for _, streamID := range streams {
+		s.hub.closeStream(streamID)
+	}
+}
@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestReverseForwardTargetPatterns(t *testing.T) {
+	patterns := []string{"*.staging.internal:443", "db.internal:*"}
+	if err := validateReverseForwardPatterns(patterns); err != nil {
+		t.Fatalf("expected valid patterns, got %v", err)
+	}
+	for _, invalid := range []string{"staging.internal", "[a:*", "api:0"} {
+		if err := validateReverseForwardPatterns([]string{invalid}); err == nil {
+			t.Fatalf("expected pattern %q to be rejected", invalid)
+		}
+	}
+	for target, want := range map[string]bool{
+		"api.staging.internal:443": true,
+		"api.staging.internal:80":  false,
+		"db.internal:5432":         true,
+		"db.internal.evil:5432":    false,
+		"staging.internal:443":     false,
+	} {
+		if got := reverseForwardAllowed(patterns, target); got != want {
+			t.Fatalf("reverseForwardAllowed(%q) = %v, want %v", target, got, want)
+		}
+	}
+	if target, err := normalizeForwardTarget("API.Staging.Internal:443"); err != nil || target != "api.staging.internal:443" {
+		t.Fatalf("unexpected normalized target %q %v", target, err)
+	}
+}
+
+func TestAgentForwardsNeedApprovalAndConnector(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", ReverseForwardTargets: []string{"127.0.0.1:*"}}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	if _, err := server.connectorStore.Create(Connector{ID: "laptop", TenantID: DefaultTenantID, Name: "laptop"}); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+	registered, err := server.hub.RegisterConnectorSession("laptop", "laptop-agent")
+	if err != nil {
+		t.Fatalf("register connector session: %v", err)
+	}
+	legacy, err := server.hub.Register(&protocol.RegisterRequest{AgentID: "legacy", Token: server.config().AgentToken, Tunnels: []protocol.TunnelConfig{{ID: "app", Target: "http://127.0.0.1:3000"}}})
+	if err != nil {
+		t.Fatalf("register legacy session: %v", err)
+	}
+	call := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := call("/api/agent/forwards", `{"session_id":"`+registered.SessionID+`","targets":["127.0.0.1:5432"]}`)
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"status": "pending"`) {
+		t.Fatalf("expected a pending forward, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	forwards := server.reverseForwards.ListTenant(DefaultTenantID)
+	if len(forwards) != 1 || forwards[0].ConnectorID != "laptop" {
+		t.Fatalf("expected one forward for the connector, got %+v", forwards)
+	}
+	if recorder := call("/api/agent/forwards/open", `{"session_id":"`+registered.SessionID+`","forward_id":"`+forwards[0].ID+`"}`); recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected a pending forward not to open, got %d", recorder.Code)
+	}
+	if recorder := call("/api/agent/forwards", `{"session_id":"`+registered.SessionID+`","targets":["10.0.0.5:5432"]}`); recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected a target outside the gateway's patterns to be refused, got %d", recorder.Code)
+	}
+	if recorder := call("/api/agent/forwards", `{"session_id":"`+legacy.SessionID+`","targets":["127.0.0.1:5432"]}`); recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected legacy agents to be refused, got %d", recorder.Code)
+	}
+
+	server.cfg.ReverseForwardTargets = nil
+	if recorder := call("/api/agent/forwards", `{"session_id":"`+registered.SessionID+`","targets":["127.0.0.1:5432"]}`); recorder.Code != http.StatusNotImplemented {
+		t.Fatalf("expected reverse forwards to be off without patterns, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,155 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const maxRedirectRules = 32
+
+// RouteRewrite adjusts the forwarded request before it reaches the upstream.
+// StripPrefix is removed first, then AddPrefix is prepended, so setting both
+// replaces one prefix with another. ResponseURLs opts into rewriting
+// root-relative URLs in HTML responses and Location headers.
+type RouteRewrite struct {
+	StripPrefix  string         `json:"strip_prefix,omitempty"`
+	AddPrefix    string         `json:"add_prefix,omitempty"`
+	Host         string         `json:"host,omitempty"`
+	PreserveHost bool           `json:"preserve_host,omitempty"`
+	Redirects    []RedirectRule `json:"redirects,omitempty"`
+	ResponseURLs bool           `json:"response_urls,omitempty"`
+}
+
+type RedirectRule struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Status int    `json:"status,omitempty"`
+}
+
+func normalizeRouteRewrite(input *RouteRewrite) (*RouteRewrite, error) {
+	if input == nil {
+		return nil, nil
+	}
+	rewrite := &RouteRewrite{
+		Host:         strings.TrimSpace(input.Host),
+		PreserveHost: input.PreserveHost,
+		ResponseURLs: input.ResponseURLs,
+	}
+	var err error
+	if rewrite.StripPrefix, err = normalizeRewritePrefix("strip_prefix", input.StripPrefix); err != nil {
+		return nil, err
+	}
+	if rewrite.AddPrefix, err = normalizeRewritePrefix("add_prefix", input.AddPrefix); err != nil {
+		return nil, err
+	}
+	if rewrite.Host != "" {
+		if rewrite.PreserveHost {
+			return nil, fmt.Errorf("rewrite.host cannot be combined with rewrite.preserve_host")
+		}
+		if strings.ContainsAny(rewrite.Host, "/ \t") {
+			return nil, fmt.Errorf("rewrite.host must be a host[:port] without scheme or path")
+		}
+	}
+	if len(input.Redirects) > maxRedirectRules {
+		return nil, fmt.Errorf("rewrite.redirects supports at most %d entries", maxRedirectRules)
+	}
+	for _, item := range input.Redirects {
+		from, err := normalizeRewritePrefix("rewrite.redirects from", item.From)
+		if err != nil {
+			return nil, err
+		}
+		if from == "" {
+			return nil, fmt.Errorf("rewrite.redirects from is required")
+		}
+		to := strings.TrimSpace(item.To)
+		if !strings.HasPrefix(to, "/") {
+			parsed, err := url.Parse(to)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return nil, fmt.Errorf("rewrite.redirects to %q must be a path or an http(s) URL", item.To)
+			}
+		}
+		status := item.Status
+		switch status {
+		case 0:
+			status = http.StatusMovedPermanently
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		default:
+			return nil, fmt.Errorf("rewrite.redirects status must be 301, 302, 307 or 308")
+		}
+		rewrite.Redirects = append(rewrite.Redirects, RedirectRule{From: from, To: to, Status: status})
+	}
+	if rewrite.StripPrefix == "" && rewrite.AddPrefix == "" && rewrite.Host == "" && !rewrite.PreserveHost && len(rewrite.Redirects) == 0 && !rewrite.ResponseURLs {
+		return nil, nil
+	}
+	return rewrite, nil
+}
+
+func normalizeRewritePrefix(field, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "/" {
+		return "", nil
+	}
+	if !strings.HasPrefix(value, "/") {
+		return "", fmt.Errorf("%s must start with /", field)
+	}
+	return strings.TrimRight(value, "/"), nil
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	return PathRoute{Prefix: prefix}.matches(path)
+}
+
+func (rw *RouteRewrite) rewritePath(path string) string {
+	if rw == nil {
+		return path
+	}
+	if rw.StripPrefix != "" && hasPathPrefix(path, rw.StripPrefix) {
+		path = strings.TrimPrefix(path, rw.StripPrefix)
+		if path == "" {
+			path = "/"
+		}
+	}
+	if rw.AddPrefix != "" {
+		path = joinWithBasePath(rw.AddPrefix, path)
+	}
+	return path
+}
+
+func (rw *RouteRewrite) upstreamHost(r *http.Request) string {
+	if rw == nil {
+		return ""
+	}
+	if rw.PreserveHost {
+		return r.Host
+	}
+	return rw.Host
+}
+
+func (rw *RouteRewrite) redirectFor(forwardPath, publicPrefix, rawQuery string) (string, int, bool) {
+	if rw == nil {
+		return "", 0, false
+	}
+	for _, redirect := range rw.Redirects {
+		if !hasPathPrefix(forwardPath, redirect.From) {
+			continue
+		}
+		location := redirect.To
+		if strings.HasPrefix(location, "/") {
+			location = strings.TrimRight(publicPrefix, "/") + location
+		}
+		if rawQuery != "" && !strings.Contains(location, "?") {
+			location += "?" + rawQuery
+		}
+		return location, redirect.Status, true
+	}
+	return "", 0, false
+}
+
+func routePublicPrefix(requestPath, forwardPath string) string {
+	if forwardPath != "/" && strings.HasSuffix(requestPath, forwardPath) {
+		return strings.TrimSuffix(requestPath, forwardPath)
+	}
+	return strings.TrimRight(requestPath, "/")
+}
@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestRouteRewritePath(t *testing.T) {
+	rewrite, err := normalizeRouteRewrite(&RouteRewrite{StripPrefix: "/app/", AddPrefix: "v2"})
+	if err == nil {
+		t.Fatalf("expected add_prefix without leading slash to be rejected")
+	}
+	rewrite, err = normalizeRouteRewrite(&RouteRewrite{StripPrefix: "/app/", AddPrefix: "/v2"})
+	if err != nil {
+		t.Fatalf("normalize rewrite: %v", err)
+	}
+	cases := map[string]string{
+		"/app":        "/v2/",
+		"/app/users":  "/v2/users",
+		"/apps/users": "/v2/apps/users",
+	}
+	for in, want := range cases {
+		if got := rewrite.rewritePath(in); got != want {
+			t.Fatalf("rewrite %q: expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestProxyAppliesRouteRewriteAndRedirects(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Host+" "+r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
+		ID:     "web",
+		Target: upstream.URL,
+		Rewrite: &RouteRewrite{
+			StripPrefix: "/web",
+			Host:        "myapp.test",
+			Redirects:   []RedirectRule{{From: "/old", To: "/new", Status: http.StatusFound}},
+		},
+	}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/web/web/login", nil))
+	if got := recorder.Body.String(); got != "myapp.test /login" {
+		t.Fatalf("unexpected upstream view %q", got)
+	}
+
+	recorder = httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/web/old/page?x=1", nil))
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("expected 302, got %d", recorder.Code)
+	}
+	if got := recorder.Header().Get("Location"); got != "/t/web/new?x=1" {
+		t.Fatalf("unexpected redirect location %q", got)
+	}
+}
+
+func TestRewriteResponseURLsPrefixesRootRelativeHTML(t *testing.T) {
+	resp := &protocol.ProxyResponse{
+		Status: http.StatusFound,
+		Headers: map[string][]string{
+			"Content-Type":   {"text/html; charset=utf-8"},
+			"Content-Length": {"0"},
+			"Location":       {"/login"},
+		},
+		Body: []byte(`<a href="/about">a</a><script src='/app.js'></script><img src="//cdn.test/x.png"><a href="/t/web/ok">b</a><a href="https://x.test/">c</a>`),
+	}
+	rewriteResponseURLs(resp, "/t/web")
+
+	want := `<a href="/t/web/about">a</a><script src='/t/web/app.js'></script><img src="//cdn.test/x.png"><a href="/t/web/ok">b</a><a href="https://x.test/">c</a>`
+	if got := string(resp.Body); got != want {
+		t.Fatalf("unexpected body:\n got %s\nwant %s", got, want)
+	}
+	if got := resp.Headers["Location"][0]; got != "/t/web/login" {
+		t.Fatalf("unexpected location %q", got)
+	}
+	if got := resp.Headers["Content-Length"][0]; got != strconv.Itoa(len(want)) {
+		t.Fatalf("unexpected content length %q", got)
+	}
+
+	resp = &protocol.ProxyResponse{
+		Headers: map[string][]string{"Content-Type": {"text/html"}, "Content-Encoding": {"gzip"}},
+		Body:    []byte(`<a href="/about">`),
+	}
+	rewriteResponseURLs(resp, "/t/web")
+	if string(resp.Body) != `<a href="/about">` {
+		t.Fatalf("expected compressed body to be left alone")
+	}
+}
@@ -0,0 +1,217 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// routeVersionAction marks the audit events that hold route history. Each
+// carries the route's portable definition, so history follows the audit
+// log's storage and retention.
+const routeVersionAction = "route.version"
+
+// RouteVersion is one saved state of a route. Changes lists the fields that
+// differ from the state before it; From is absent when the earlier version is
+// no longer kept. Token values are never stored, only that the token changed.
+type RouteVersion struct {
+	Version    int                `json:"version"`
+	Actor      string             `json:"actor"`
+	Source     string             `json:"source"`
+	RollbackTo int                `json:"rollback_to,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	Changes    []RouteFieldChange `json:"changes"`
+	Route      upsertRuleRequest  `json:"route"`
+}
+
+type RouteFieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from,omitempty"`
+	To    json.RawMessage `json:"to,omitempty"`
+}
+
+type rollbackRouteRequest struct {
+	Version int `json:"version"`
+}
+
+func routeDefinitionFields(definition upsertRuleRequest) map[string]json.RawMessage {
+	raw, _ := json.Marshal(definition)
+	fields := map[string]json.RawMessage{}
+	_ = json.Unmarshal(raw, &fields)
+	return fields
+}
+
+func changedRouteFields(before, after map[string]json.RawMessage) []string {
+	changed := make([]string, 0)
+	for field, value := range after {
+		if !bytes.Equal(before[field], value) {
+			changed = append(changed, field)
+		}
+	}
+	for field := range before {
+		if _, ok := after[field]; !ok {
+			changed = append(changed, field)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func (s *Server) recordRouteVersion(actor, source string, rule Rule, previous *Rule, rollbackTo int) {
+	definition := routeDefinitionFromRule(rule, false)
+	before := map[string]json.RawMessage{}
+	if previous != nil {
+		before = routeDefinitionFields(routeDefinitionFromRule(*previous, false))
+	}
+	changed := changedRouteFields(before, routeDefinitionFields(definition))
+	if previous != nil && previous.Token != rule.Token {
+		changed = append(changed, "token")
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	version := 1
+	if events := s.routeVersionEvents(rule.TenantID, rule.ID); len(events) > 0 {
+		last, _ := strconv.Atoi(events[len(events)-1].Details["version"])
+		version = last + 1
+	}
+	encoded, _ := json.Marshal(definition)
+	details := map[string]string{
+		"route_id":   rule.ID,
+		"version":    strconv.Itoa(version),
+		"source":     source,
+		"changes":    strings.Join(changed, ","),
+		"definition": string(encoded),
+	}
+	if rollbackTo > 0 {
+		details["rollback_to"] = strconv.Itoa(rollbackTo)
+	}
+	s.auditStore.Record(actor, routeVersionAction, rule.TenantID, details)
+}
+
+func optionalRule(rule Rule, ok bool) *Rule {
+	if !ok {
+		return nil
+	}
+	return &rule
+}
+
+func (s *Server) routeVersionEvents(tenantID, routeID string) []AuditEvent {
+	return s.auditStore.Find(tenantID, routeVersionAction, "route_id", normalizeIdentifier(routeID))
+}
+
+func (s *Server) routeHistory(tenantID, routeID string) []RouteVersion {
+	events := s.routeVersionEvents(tenantID, routeID)
+	versions := make([]RouteVersion, 0, len(events))
+	var previous map[string]json.RawMessage
+	for _, event := range events {
+		var definition upsertRuleRequest
+		if err := json.Unmarshal([]byte(event.Details["definition"]), &definition); err != nil {
+			continue
+		}
+		fields := routeDefinitionFields(definition)
+		version := RouteVersion{
+			Actor:     event.Actor,
+			Source:    event.Details["source"],
+			CreatedAt: event.CreatedAt,
+			Changes:   make([]RouteFieldChange, 0),
+			Route:     definition,
+		}
+		version.Version, _ = strconv.Atoi(event.Details["version"])
+		version.RollbackTo, _ = strconv.Atoi(event.Details["rollback_to"])
+		for _, field := range strings.Split(event.Details["changes"], ",") {
+			if field == "" {
+				continue
+			}
+			change := RouteFieldChange{Field: field}
+			if field != "token" {
+				change.To = fields[field]
+				if previous != nil {
+					change.From = previous[field]
+				}
+			}
+			version.Changes = append(version.Changes, change)
+		}
+		versions = append(versions, version)
+		previous = fields
+	}
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+	return versions
+}
+
+func (s *Server) handleRouteHistory(w http.ResponseWriter, r *http.Request, user User, tenantID, routeID, action string) {
+	current, exists := s.ruleStore.GetForTenant(tenantID, routeID)
+	if action == "history" {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		versions := s.routeHistory(tenantID, routeID)
+		if !exists && len(versions) == 0 {
+			writeAPIError(w, http.StatusNotFound, errCodeRouteNotFound, "route not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"tenant_id": normalizeIdentifier(tenantID),
+			"route_id":  normalizeIdentifier(routeID),
+			"versions":  versions,
+		})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.canMutateTenant(user, tenantID) {
+		writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden route mutation")
+		return
+	}
+	if !exists {
+		writeAPIError(w, http.StatusNotFound, errCodeRouteNotFound, "route not found")
+		return
+	}
+	var request rollbackRouteRequest
+	if !s.decodeJSON(w, r, &request, "rollback payload") {
+		return
+	}
+	var target *RouteVersion
+	for _, version := range s.routeHistory(tenantID, routeID) {
+		if version.Version == request.Version {
+			target = &version
+			break
+		}
+	}
+	if target == nil {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, fmt.Sprintf("route version %d not found", request.Version))
+		return
+	}
+
+	definition := target.Route
+	definition.Token = current.Token
+	input, code, err := s.validateRouteRequest(tenantID, definition)
+	if err != nil {
+		writeAPIError(w, apiErrorCodes[code].Status, code, err.Error())
+		return
+	}
+	route, err := s.ruleStore.UpsertForTenant(tenantID, input)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	s.recordRouteVersion(user.Username, "rollback", route, &current, target.Version)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": fmt.Sprintf("route rolled back to version %d", target.Version),
+		"route":   s.buildRouteView(route),
+	})
+	s.persistState()
+	s.publishRouteUpserted(route)
+}
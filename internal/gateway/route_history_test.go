@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteHistoryRecordsChangesAndRollsBack(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	session, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+session)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+	history := func() []RouteVersion {
+		t.Helper()
+		recorder := call(http.MethodGet, "/api/tenants/default/routes/app/history", "")
+		var payload struct {
+			Versions []RouteVersion `json:"versions"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode history: %v %s", err, recorder.Body.String())
+		}
+		return payload.Versions
+	}
+
+	for _, body := range []string{
+		`{"id":"app","target":"http://10.0.0.1:8080","token":"first-token"}`,
+		`{"id":"app","target":"http://10.0.0.1:8080","token":"first-token"}`,
+		`{"id":"app","target":"http://10.0.0.2:8080","token":"second-token","max_rps":5}`,
+	} {
+		if recorder := call(http.MethodPost, "/api/tenants/default/routes", body); recorder.Code != http.StatusOK {
+			t.Fatalf("upsert route: %d %s", recorder.Code, recorder.Body.String())
+		}
+	}
+
+	versions := history()
+	if len(versions) != 2 || versions[0].Version != 2 || versions[0].Actor != "admin" || versions[0].Source != "api" {
+		t.Fatalf("expected two versions, the unchanged write skipped, got %+v", versions)
+	}
+	changes := map[string]RouteFieldChange{}
+	for _, change := range versions[0].Changes {
+		changes[change.Field] = change
+	}
+	if target := changes["target"]; string(target.From) != `"http://10.0.0.1:8080"` || string(target.To) != `"http://10.0.0.2:8080"` {
+		t.Fatalf("expected the target diff, got %+v", versions[0].Changes)
+	}
+	if token, ok := changes["token"]; !ok || token.From != nil || token.To != nil {
+		t.Fatalf("expected a token change without values, got %+v", versions[0].Changes)
+	}
+	if _, ok := changes["max_rps"]; !ok || len(changes) != 3 {
+		t.Fatalf("expected target, max_rps and token to change, got %+v", versions[0].Changes)
+	}
+	if recorder := call(http.MethodGet, "/api/admin/audit", ""); strings.Contains(recorder.Body.String(), "first-token") {
+		t.Fatalf("expected tokens to stay out of the audit log")
+	}
+
+	if recorder := call(http.MethodPost, "/api/tenants/default/routes/app/rollback", `{"version":9}`); recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected an unknown version to be rejected, got %d", recorder.Code)
+	}
+	if recorder := call(http.MethodPost, "/api/tenants/default/routes/app/rollback", `{"version":1}`); recorder.Code != http.StatusOK {
+		t.Fatalf("rollback: %d %s", recorder.Code, recorder.Body.String())
+	}
+	rule, _ := server.ruleStore.GetForTenant(DefaultTenantID, "app")
+	if rule.Target != "http://10.0.0.1:8080" || rule.MaxRPS != 0 || rule.Token != "second-token" {
+		t.Fatalf("expected version 1 with the current token, got %+v", rule)
+	}
+	versions = history()
+	if len(versions) != 3 || versions[0].Version != 3 || versions[0].Source != "rollback" || versions[0].RollbackTo != 1 {
+		t.Fatalf("expected the rollback as version 3, got %+v", versions[0])
+	}
+}
@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+type routeMetricsView struct {
+	RouteID string           `json:"route_id"`
+	Metrics TunnelMetrics    `json:"metrics"`
+	Window  *TimeseriesPoint `json:"window,omitempty"`
+}
+
+// parseMetricsSince reads the since parameter of metric reads. Windows come
+// from the per-minute timeseries, so since must fall within its retention.
+func parseMetricsSince(r *http.Request, now time.Time) (time.Time, error) {
+	since, err := parseQueryTime("since", r.URL.Query().Get("since"), now)
+	if err != nil || since.IsZero() {
+		return since, err
+	}
+	if since.After(now) {
+		return time.Time{}, fmt.Errorf("since must not be in the future")
+	}
+	if since.Before(now.Add(-timeseriesRetention)) {
+		return time.Time{}, fmt.Errorf("since must be within the last %d hours", int(timeseriesRetention/time.Hour))
+	}
+	return since, nil
+}
+
+func (s *Server) routeMetricsView(tenantID, routeID string, since time.Time) routeMetricsView {
+	view := routeMetricsView{RouteID: routeID, Metrics: s.metricForRoute(tenantID, routeID)}
+	if since.IsZero() {
+		return view
+	}
+	window := TimeseriesPoint{Start: since.UTC().Truncate(timeseriesResolution)}
+	for _, key := range s.lookupTunnelKeys(tenantID, routeID) {
+		sum := s.hub.Timeseries().Sum(key, since)
+		window.Requests += sum.Requests
+		window.Errors += sum.Errors
+		window.BytesIn += sum.BytesIn
+		window.BytesOut += sum.BytesOut
+	}
+	view.Window = &window
+	return view
+}
+
+func (s *Server) handleRouteMetrics(w http.ResponseWriter, r *http.Request, user User, tenantID, routeID, action string) {
+	rule, exists := s.ruleStore.GetForTenant(tenantID, routeID)
+	if !exists {
+		writeAPIError(w, http.StatusNotFound, errCodeRouteNotFound, "route not found")
+		return
+	}
+	if action == "metrics:reset" {
+		if r.Method != http.MethodPost {
+			writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		if !s.canMutateTenant(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden route mutation")
+			return
+		}
+		at := s.hub.ResetTunnelMetrics(rule.TenantID, rule.ID)
+		s.auditStore.Record(user.Username, "metrics.reset", rule.TenantID, map[string]string{"route_id": rule.ID})
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message":   "route metrics reset",
+			"tenant_id": rule.TenantID,
+			"route_id":  rule.ID,
+			"reset_at":  at,
+		})
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	since, err := parseMetricsSince(r, time.Now().UTC())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	view := s.routeMetricsView(rule.TenantID, rule.ID, since)
+	response := map[string]any{
+		"tenant_id": rule.TenantID,
+		"route_id":  rule.ID,
+		"metrics":   view.Metrics,
+	}
+	if view.Window != nil {
+		response["window"] = view.Window
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleTenantMetrics(w http.ResponseWriter, r *http.Request, user User, tenantID, action string) {
+	if _, ok := s.ruleStore.GetTenant(tenantID); !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
+		return
+	}
+	if action == "metrics:reset" {
+		if r.Method != http.MethodPost {
+			writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		if !s.canMutateTenantConfig(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAdminRequired, "forbidden tenant configuration access")
+			return
+		}
+		at := s.hub.ResetTunnelMetrics(tenantID, "")
+		s.auditStore.Record(user.Username, "metrics.reset", tenantID, map[string]string{"scope": "tenant"})
+		writeJSON(w, http.StatusOK, map[string]any{
+			"message":   "tenant metrics reset",
+			"tenant_id": tenantID,
+			"reset_at":  at,
+		})
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	since, err := parseMetricsSince(r, time.Now().UTC())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	rules := s.ruleStore.ListForTenant(tenantID)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	routes := make([]routeMetricsView, 0, len(rules))
+	var window *TimeseriesPoint
+	if !since.IsZero() {
+		window = &TimeseriesPoint{Start: since.UTC().Truncate(timeseriesResolution)}
+	}
+	for _, rule := range rules {
+		view := s.routeMetricsView(tenantID, rule.ID, since)
+		if window != nil {
+			window.Requests += view.Window.Requests
+			window.Errors += view.Window.Errors
+			window.BytesIn += view.Window.BytesIn
+			window.BytesOut += view.Window.BytesOut
+		}
+		routes = append(routes, view)
+	}
+	response := map[string]any{
+		"tenant_id": tenantID,
+		"routes":    routes,
+	}
+	if window != nil {
+		response["window"] = window
+	}
+	writeJSON(w, http.StatusOK, response)
+}
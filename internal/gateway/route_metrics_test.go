@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRouteMetricsResetAndSince(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	for _, id := range []string{"shop", "web"} {
+		if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: id, Target: upstream.URL}); err != nil {
+			t.Fatalf("upsert route: %v", err)
+		}
+	}
+	session, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	call := func(method, path string, out any) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+session)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		if out != nil {
+			if err := json.Unmarshal(recorder.Body.Bytes(), out); err != nil {
+				t.Fatalf("decode %s %s: %v", method, path, err)
+			}
+		}
+		return recorder.Code
+	}
+	proxy := func(path string, times int) {
+		for i := 0; i < times; i++ {
+			server.handleProxy(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+	}
+	proxy("/t/shop/users", 3)
+	proxy("/t/web/", 2)
+
+	if code := call(http.MethodPost, "/api/tenants/default/routes/shop/metrics:reset", nil); code != http.StatusOK {
+		t.Fatalf("expected the route reset to succeed, got %d", code)
+	}
+	proxy("/t/shop/users", 1)
+
+	var route struct {
+		Metrics TunnelMetrics    `json:"metrics"`
+		Window  *TimeseriesPoint `json:"window"`
+	}
+	if code := call(http.MethodGet, "/api/tenants/default/routes/shop/metrics?since=10m", &route); code != http.StatusOK {
+		t.Fatalf("read route metrics: %d", code)
+	}
+	if route.Metrics.RequestCount != 1 || route.Metrics.ResetAt.IsZero() {
+		t.Fatalf("expected one request since the reset, got %+v", route.Metrics)
+	}
+	if route.Window == nil || route.Window.Requests != 4 {
+		t.Fatalf("expected the window to count all four requests, got %+v", route.Window)
+	}
+	if code := call(http.MethodGet, "/api/tenants/default/routes/shop/metrics?since=48h", nil); code != http.StatusBadRequest {
+		t.Fatalf("expected since beyond the timeseries retention to be rejected, got %d", code)
+	}
+
+	if code := call(http.MethodPost, "/api/tenants/default/metrics:reset", nil); code != http.StatusOK {
+		t.Fatalf("expected the tenant reset to succeed, got %d", code)
+	}
+	var tenant struct {
+		Routes []routeMetricsView `json:"routes"`
+		Window *TimeseriesPoint   `json:"window"`
+	}
+	if code := call(http.MethodGet, "/api/tenants/default/metrics?since="+time.Now().Add(-time.Hour).UTC().Format(time.RFC3339), &tenant); code != http.StatusOK {
+		t.Fatalf("read tenant metrics: %d", code)
+	}
+	if len(tenant.Routes) != 2 || tenant.Routes[0].Metrics.RequestCount != 0 || tenant.Routes[1].Metrics.RequestCount != 0 {
+		t.Fatalf("expected every route to be reset, got %+v", tenant.Routes)
+	}
+	if tenant.Window == nil || tenant.Window.Requests != 6 {
+		t.Fatalf("expected the tenant window to count six requests, got %+v", tenant.Window)
+	}
+
+	resets := 0
+	for _, event := range server.auditStore.List(DefaultTenantID, 0) {
+		if event.Action == "metrics.reset" && event.Actor == "admin" {
+			resets++
+		}
+	}
+	if resets != 2 {
+		t.Fatalf("expected both resets in the audit log, got %d", resets)
+	}
+}
@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const (
+	RouteScheduleActive    = "active"
+	RouteScheduleScheduled = "scheduled"
+	RouteScheduleExpired   = "expired"
+
+	routeExpirySweepInterval = 30 * time.Second
+)
+
+func (r Rule) ScheduleState(now time.Time) string {
+	if r.ExpiresAt != nil && !now.Before(*r.ExpiresAt) {
+		return RouteScheduleExpired
+	}
+	if r.ActiveFrom != nil && now.Before(*r.ActiveFrom) {
+		return RouteScheduleScheduled
+	}
+	return RouteScheduleActive
+}
+
+func (r Rule) RemainingTTL(now time.Time) (time.Duration, bool) {
+	if r.ExpiresAt == nil {
+		return 0, false
+	}
+	remaining := r.ExpiresAt.Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+func (r upsertRuleRequest) resolveExpiresAt(now time.Time) (*time.Time, error) {
+	ttlRaw := strings.TrimSpace(r.TTL)
+	if ttlRaw == "" {
+		return r.ExpiresAt, nil
+	}
+	if r.ExpiresAt != nil {
+		return nil, fmt.Errorf("set either ttl or expires_at, not both")
+	}
+	ttl, err := time.ParseDuration(ttlRaw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ttl: %w", err)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("ttl must be > 0")
+	}
+	start := now
+	if r.ActiveFrom != nil && r.ActiveFrom.After(now) {
+		start = *r.ActiveFrom
+	}
+	expiresAt := start.Add(ttl).UTC()
+	return &expiresAt, nil
+}
+
+func normalizeOptionalTime(value *time.Time) *time.Time {
+	if value == nil || value.IsZero() {
+		return nil
+	}
+	normalized := value.UTC()
+	return &normalized
+}
+
+func (s *Server) sweepExpiredRoutes(now time.Time) int {
+	removed := s.ruleStore.DeleteExpired(now)
+	for _, rule := range removed {
+		s.auditStore.Record("system", "route.expired", rule.TenantID, map[string]string{
+			"route_id":   rule.ID,
+			"expires_at": rule.ExpiresAt.Format(time.RFC3339),
+		})
+		s.refreshTenantUsage(rule.TenantID)
+		s.publishRouteDeleted(rule.TenantID, rule.ID, "expired")
+	}
+	if len(removed) > 0 {
+		s.logger.Printf("removed %d expired routes", len(removed))
+		s.persistState()
+	}
+	return len(removed)
+}
+
+func (s *Server) runRouteExpiryLoop(ctx context.Context) {
+	ticker := time.NewTicker(routeExpirySweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.sweepExpiredRoutes(now.UTC())
+		}
+	}
+}
+
+// annotateRegisteredRoutes tells agents which routes they serve and when those
+// routes expire. Connector sessions learn their bound routes here since they
+// do not declare tunnels themselves.
+func (s *Server) annotateRegisteredRoutes(response *protocol.RegisterResponse, connectorID string) {
+	if response == nil {
+		return
+	}
+	if connectorID != "" {
+		connector, ok := s.connectorStore.Get(connectorID)
+		if !ok {
+			return
+		}
+		routes := make([]protocol.TunnelRoute, 0)
+		for _, rule := range s.ruleStore.ListForTenant(connector.TenantID) {
+			if !rule.servedBy(connector) {
+				continue
+			}
+			routes = append(routes, protocol.TunnelRoute{
+				ID:        MakeTunnelKey(rule.TenantID, rule.ID),
+				PublicURL: s.routePublicURL(rule.TenantID, rule.ID),
+				ExpiresAt: rule.ExpiresAt,
+			})
+		}
+		response.Tunnels = routes
+		return
+	}
+	for i, route := range response.Tunnels {
+		tenantID, routeID := ParseTunnelKey(route.ID)
+		if rule, ok := s.ruleStore.GetForTenant(tenantID, routeID); ok {
+			response.Tunnels[i].ExpiresAt = rule.ExpiresAt
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRuleScheduleStateAndExpirySweep(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	now := time.Now().UTC()
+	activeFrom := now.Add(10 * time.Minute)
+	expiresAt := now.Add(time.Hour)
+
+	route, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
+		ID:             "demo",
+		Target:         "http://127.0.0.1:3000",
+		ActiveFrom:     &activeFrom,
+		ExpiresAt:      &expiresAt,
+		DeleteOnExpiry: true,
+	})
+	if err != nil {
+		t.Fatalf("upsert scheduled route: %v", err)
+	}
+	if state := route.ScheduleState(now); state != RouteScheduleScheduled {
+		t.Fatalf("expected scheduled state, got %q", state)
+	}
+	if state := route.ScheduleState(now.Add(30 * time.Minute)); state != RouteScheduleActive {
+		t.Fatalf("expected active state, got %q", state)
+	}
+	if remaining, ok := route.RemainingTTL(now); !ok || remaining != time.Hour {
+		t.Fatalf("unexpected remaining ttl %v (ok=%v)", remaining, ok)
+	}
+
+	if removed := server.sweepExpiredRoutes(now.Add(30 * time.Minute)); removed != 0 {
+		t.Fatalf("expected no routes removed before expiry, got %d", removed)
+	}
+	if removed := server.sweepExpiredRoutes(now.Add(2 * time.Hour)); removed != 1 {
+		t.Fatalf("expected expired route to be removed, got %d", removed)
+	}
+	if _, ok := server.ruleStore.GetForTenant(DefaultTenantID, "demo"); ok {
+		t.Fatalf("expected expired route to be deleted")
+	}
+	if events := server.auditStore.List(DefaultTenantID, 10); len(events) != 1 || events[0].Action != "route.expired" {
+		t.Fatalf("expected route.expired audit event, got %+v", events)
+	}
+}
+
+func TestExpiredRoutesStayEditable(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	expiresAt := time.Now().UTC().Add(time.Hour)
+	route, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "demo", Target: "http://127.0.0.1:3000", ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	// Let the route expire.
+	expired := time.Now().UTC().Add(-time.Minute)
+	server.ruleStore.mu.Lock()
+	stored := server.ruleStore.rules[ruleKey(DefaultTenantID, "demo")]
+	stored.ExpiresAt = &expired
+	server.ruleStore.rules[ruleKey(DefaultTenantID, "demo")] = stored
+	server.ruleStore.mu.Unlock()
+
+	route.Target = "http://127.0.0.1:4000"
+	route.ExpiresAt = &expired
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, route); err != nil {
+		t.Fatalf("expected an edit that keeps the expiry to succeed, got %v", err)
+	}
+	earlier := expired.Add(-time.Minute)
+	route.ExpiresAt = &earlier
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, route); err == nil {
+		t.Fatal("expected a new expiry in the past to be refused")
+	}
+}
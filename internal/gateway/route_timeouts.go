@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"fmt"
+	"time"
+)
+
+const maxRouteTimeoutSecs = 3600
+
+func normalizeRouteTimeouts(requestSecs, idleSecs, headerSecs int) error {
+	if requestSecs < 0 || requestSecs > maxRouteTimeoutSecs {
+		return fmt.Errorf("request_timeout_seconds must be between 0 and %d", maxRouteTimeoutSecs)
+	}
+	if idleSecs < 0 || idleSecs > maxRouteTimeoutSecs {
+		return fmt.Errorf("idle_timeout_seconds must be between 0 and %d", maxRouteTimeoutSecs)
+	}
+	if requestSecs > 0 && idleSecs > requestSecs {
+		return fmt.Errorf("idle_timeout_seconds cannot exceed request_timeout_seconds")
+	}
+	if headerSecs < 0 || headerSecs > maxRouteTimeoutSecs {
+		return fmt.Errorf("response_header_timeout_seconds must be between 0 and %d", maxRouteTimeoutSecs)
+	}
+	if requestSecs > 0 && headerSecs > requestSecs {
+		return fmt.Errorf("response_header_timeout_seconds cannot exceed request_timeout_seconds")
+	}
+	return nil
+}
+
+func (s *Server) maxRouteTimeout(plan Plan) time.Duration {
+	if plan.MaxRequestTimeoutSecs > 0 {
+		return time.Duration(plan.MaxRequestTimeoutSecs) * time.Second
+	}
+	return s.hub.RequestTimeout()
+}
+
+func (s *Server) validateRouteTimeouts(tenantID string, requestSecs, idleSecs, headerSecs int) error {
+	if requestSecs <= 0 && idleSecs <= 0 && headerSecs <= 0 {
+		return nil
+	}
+	plan, planID := s.planStore.GetTenantPlan(tenantID)
+	limit := s.maxRouteTimeout(plan)
+	for field, secs := range map[string]int{"request_timeout_seconds": requestSecs, "idle_timeout_seconds": idleSecs, "response_header_timeout_seconds": headerSecs} {
+		if time.Duration(secs)*time.Second > limit {
+			return fmt.Errorf("%s exceeds plan %q limit of %ds", field, planID, int(limit.Seconds()))
+		}
+	}
+	return nil
+}
+
+// proxyTimeouts resolves the request and idle timeouts for one proxied
+// request. Overrides are re-clamped here because the tenant plan may have
+// changed since the route was saved.
+func (s *Server) proxyTimeouts(rule Rule, hasRule bool, plan Plan) (time.Duration, time.Duration) {
+	requestTimeout := s.hub.RequestTimeout()
+	if !hasRule {
+		return requestTimeout, 0
+	}
+	if rule.RequestTimeoutSecs > 0 {
+		requestTimeout = time.Duration(rule.RequestTimeoutSecs) * time.Second
+		if limit := s.maxRouteTimeout(plan); requestTimeout > limit {
+			requestTimeout = limit
+		}
+	}
+	idleTimeout := time.Duration(rule.IdleTimeoutSecs) * time.Second
+	if idleTimeout > requestTimeout {
+		idleTimeout = requestTimeout
+	}
+	return requestTimeout, idleTimeout
+}
+
+// responseHeaderTimeout is how long the upstream of one proxied request may
+// take to send its response headers, or zero for no separate limit. Once
+// headers arrive the request and idle timeouts still apply to the body.
+func responseHeaderTimeout(rule Rule, hasRule bool, requestTimeout time.Duration) time.Duration {
+	if !hasRule || rule.HeaderTimeoutSecs <= 0 {
+		return 0
+	}
+	return min(time.Duration(rule.HeaderTimeoutSecs)*time.Second, requestTimeout)
+}
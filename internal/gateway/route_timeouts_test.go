@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/httpx"
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestProxyTimeoutsClampToPlan(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", ProxyRequestTimeout: 20 * time.Second}, nil)
+	plan := Plan{MaxRequestTimeoutSecs: 60}
+
+	requestTimeout, idleTimeout := server.proxyTimeouts(Rule{}, false, plan)
+	if requestTimeout != 20*time.Second || idleTimeout != 0 {
+		t.Fatalf("expected gateway default without rule, got %s/%s", requestTimeout, idleTimeout)
+	}
+	requestTimeout, idleTimeout = server.proxyTimeouts(Rule{RequestTimeoutSecs: 300, IdleTimeoutSecs: 90}, true, plan)
+	if requestTimeout != 60*time.Second || idleTimeout != 60*time.Second {
+		t.Fatalf("expected overrides clamped to plan, got %s/%s", requestTimeout, idleTimeout)
+	}
+	requestTimeout, _ = server.proxyTimeouts(Rule{RequestTimeoutSecs: 45}, true, Plan{})
+	if requestTimeout != 20*time.Second {
+		t.Fatalf("expected uncapped plan to stay on gateway default, got %s", requestTimeout)
+	}
+
+	if err := server.validateRouteTimeouts(DefaultTenantID, 31, 0, 0); err == nil {
+		t.Fatalf("expected free plan to reject request timeout above its cap")
+	}
+	if err := server.validateRouteTimeouts(DefaultTenantID, 30, 10, 5); err != nil {
+		t.Fatalf("expected timeouts within plan cap to be accepted: %v", err)
+	}
+	if err := normalizeRouteTimeouts(10, 20, 0); err == nil {
+		t.Fatalf("expected idle timeout above request timeout to be rejected")
+	}
+	if err := normalizeRouteTimeouts(10, 0, 20); err == nil {
+		t.Fatalf("expected response header timeout above request timeout to be rejected")
+	}
+}
+
+func TestHubPropagatesRemainingBudgetAndCountsTimeouts(t *testing.T) {
+	hub := NewHub("token", "http://localhost", time.Second, 0, 0)
+	registered, err := hub.RegisterConnectorSession("laptop", "agent-1")
+	if err != nil {
+		t.Fatalf("register connector session: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := hub.DispatchProxyRequestToConnector(ctx, "laptop", "default/app", &protocol.ProxyRequest{Method: http.MethodGet, Path: "/"})
+		errCh <- err
+	}()
+
+	pulled, err := hub.PullRequest(context.Background(), registered.SessionID)
+	if err != nil {
+		t.Fatalf("pull request: %v", err)
+	}
+	if pulled.TimeoutMs <= 0 || pulled.TimeoutMs > 200 {
+		t.Fatalf("expected remaining budget to be stamped, got %dms", pulled.TimeoutMs)
+	}
+	if err := <-errCh; !errors.Is(err, ErrProxyRequestTimeout) {
+		t.Fatalf("expected proxy timeout, got %v", err)
+	}
+	if metric := hub.GetTunnelMetrics("default/app"); metric.TimeoutCount != 1 || metric.ErrorCount != 1 {
+		t.Fatalf("expected one timeout recorded, got %+v", metric)
+	}
+	if status := hub.Status(); status.TimeoutCount != 1 {
+		t.Fatalf("expected hub status timeout count 1, got %d", status.TimeoutCount)
+	}
+}
+
+func TestResponseHeaderTimeoutSparesSlowBodies(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-headers" {
+			time.Sleep(300 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+		http.NewResponseController(w).Flush()
+		time.Sleep(300 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	rule := Rule{TenantID: DefaultTenantID, ID: "app", Target: upstream.URL}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := server.forwardDirect(ctx, rule, &protocol.ProxyRequest{Method: http.MethodGet, Path: "/slow-body", HeaderTimeoutMs: 100}, nil)
+	if err != nil || string(resp.Body) != "done" {
+		t.Fatalf("expected a body slower than the header timeout to complete, got %+v %v", resp, err)
+	}
+	if _, err := server.forwardDirect(ctx, rule, &protocol.ProxyRequest{Method: http.MethodGet, Path: "/slow-headers", HeaderTimeoutMs: 100}, nil); !errors.Is(err, httpx.ErrResponseHeaderTimeout) {
+		t.Fatalf("expected late headers to time out, got %v", err)
+	}
+	if timeout := responseHeaderTimeout(Rule{HeaderTimeoutSecs: 90}, true, 30*time.Second); timeout != 30*time.Second {
+		t.Fatalf("expected the header timeout to be capped by the request timeout, got %s", timeout)
+	}
+}
@@ -0,0 +1,431 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const routeDocumentVersion = 1
+
+const (
+	importActionCreate    = "create"
+	importActionUpdate    = "update"
+	importActionUnchanged = "unchanged"
+	importActionSkip      = "skip"
+	importActionConflict  = "conflict"
+	importActionInvalid   = "invalid"
+)
+
+type routeDocument struct {
+	Version    int                 `json:"version"`
+	TenantID   string              `json:"tenant_id,omitempty"`
+	ExportedAt *time.Time          `json:"exported_at,omitempty"`
+	Routes     []upsertRuleRequest `json:"routes"`
+}
+
+type routeImportResult struct {
+	ID     string `json:"id"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+
+	input Rule
+}
+
+// routeDefinitionFromRule is the portable form of a route: the same payload
+// the route API accepts, without derived fields. Tokens are only included on
+// request because export files are meant to be committed.
+func routeDefinitionFromRule(rule Rule, includeSecrets bool) upsertRuleRequest {
+	definition := upsertRuleRequest{
+		ID:                   rule.ID,
+		Environment:          rule.Environment,
+		MaxRPS:               rule.MaxRPS,
+		MaxBytesPerSecond:    rule.MaxBytesPerSecond,
+		RequestTimeoutSecs:   rule.RequestTimeoutSecs,
+		IdleTimeoutSecs:      rule.IdleTimeoutSecs,
+		HeaderTimeoutSecs:    rule.HeaderTimeoutSecs,
+		MaxResponseBodyBytes: rule.MaxResponseBodyBytes,
+		MaxUploadBytes:       rule.MaxUploadBytes,
+		Retry:                rule.Retry,
+		ErrorPages:           rule.ErrorPages,
+		CORS:                 rule.CORS,
+		Presets:              rule.Presets,
+		PathRoutes:           rule.PathRoutes,
+		Rewrite:              rule.Rewrite,
+		ForwardedHeaders:     rule.ForwardedHeaders,
+		Idempotency:          rule.Idempotency,
+		QueueOverflow:        rule.QueueOverflow,
+		SyntheticCheck:       rule.SyntheticCheck,
+		TLSPassthrough:       rule.TLSPassthrough,
+		Mirror:               rule.Mirror,
+		Split:                rule.Split,
+		Middleware:           rule.Middleware,
+		Mock:                 rule.Mock,
+		Capture:              rule.Capture,
+		WebhookVerification:  rule.WebhookVerification.withoutSecret(),
+		ActiveFrom:           rule.ActiveFrom,
+		ExpiresAt:            rule.ExpiresAt,
+		DeleteOnExpiry:       rule.DeleteOnExpiry,
+	}
+	if rule.UsesConnector() {
+		definition.ConnectorID = rule.ConnectorID
+		definition.ConnectorSelector = rule.ConnectorSelector
+		definition.LocalScheme = rule.LocalScheme
+		definition.LocalHost = rule.LocalHost
+		definition.LocalPort = rule.LocalPort
+		definition.LocalSocket = rule.LocalSocket
+		definition.LocalTLS = rule.LocalTLS
+		definition.LocalBasePath = rule.LocalBasePath
+		if rule.BasePathTemplate != "" {
+			definition.LocalBasePath = rule.BasePathTemplate
+		}
+	} else {
+		definition.Target = rule.Target
+		if rule.TargetTemplate != "" {
+			definition.Target = rule.TargetTemplate
+		}
+	}
+	if includeSecrets {
+		definition.Token = rule.Token
+		definition.WebhookVerification = rule.WebhookVerification
+	}
+	return definition
+}
+
+func (s *Server) handleTenantRoutesExport(w http.ResponseWriter, r *http.Request, user User, tenantID string) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.ruleStore.HasTenant(tenantID) {
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
+		return
+	}
+	format, err := routeDocumentFormat(r.URL.Query().Get("format"), "")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	includeSecrets := parseBoolQuery(r, "include_secrets")
+	if includeSecrets && !s.canMutateTenant(user, tenantID) {
+		writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden secret export")
+		return
+	}
+
+	routes := s.ruleStore.ListForTenant(tenantID)
+	exportedAt := time.Now().UTC()
+	document := routeDocument{
+		Version:    routeDocumentVersion,
+		TenantID:   tenantID,
+		ExportedAt: &exportedAt,
+		Routes:     make([]upsertRuleRequest, 0, len(routes)),
+	}
+	for _, route := range routes {
+		document.Routes = append(document.Routes, routeDefinitionFromRule(route, includeSecrets))
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", tenantID+"-routes."+format))
+	if format == "json" {
+		writeJSON(w, http.StatusOK, document)
+		return
+	}
+	payload, err := marshalYAMLDocument(document)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, fmt.Sprintf("encode routes: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(payload)
+}
+
+// handleTenantRoutesImport validates every route in the document before
+// touching the store, so an import either applies completely or not at all.
+func (s *Server) handleTenantRoutesImport(w http.ResponseWriter, r *http.Request, user User, tenantID string) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.canMutateTenant(user, tenantID) {
+		writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden route mutation")
+		return
+	}
+	if !s.ruleStore.HasTenant(tenantID) {
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
+		return
+	}
+	onConflict := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("on_conflict")))
+	switch onConflict {
+	case "":
+		onConflict = "fail"
+	case "fail", "skip", "overwrite":
+	default:
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "on_conflict must be fail, skip or overwrite")
+		return
+	}
+	dryRun := parseBoolQuery(r, "dry_run")
+
+	body, err := readAllWithLimit(r.Body, s.config().MaxRequestBodyBytes)
+	if err != nil {
+		if errors.Is(err, errBodyTooLarge) {
+			writeAPIError(w, http.StatusRequestEntityTooLarge, errCodePayloadTooLarge, "payload exceeds request body limit")
+			return
+		}
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("read routes document: %v", err))
+		return
+	}
+	format, err := routeDocumentFormat(r.URL.Query().Get("format"), r.Header.Get("Content-Type"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	document, err := decodeRouteDocument(body, format)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("invalid routes document: %v", err))
+		return
+	}
+
+	results := s.planRouteImport(tenantID, document.Routes, onConflict)
+	summary := make(map[string]int)
+	for _, result := range results {
+		summary[result.Action]++
+	}
+	payload := map[string]any{
+		"tenant_id":   tenantID,
+		"dry_run":     dryRun,
+		"on_conflict": onConflict,
+		"applied":     false,
+		"summary":     summary,
+		"results":     results,
+	}
+	status := http.StatusOK
+	switch {
+	case summary[importActionInvalid] > 0:
+		status = http.StatusBadRequest
+	case summary[importActionConflict] > 0:
+		status = http.StatusConflict
+	}
+	if dryRun {
+		writeJSON(w, http.StatusOK, payload)
+		return
+	}
+	if status != http.StatusOK {
+		writeJSON(w, status, payload)
+		return
+	}
+
+	for i, result := range results {
+		if result.Action != importActionCreate && result.Action != importActionUpdate {
+			continue
+		}
+		previous, existed := s.ruleStore.GetForTenant(tenantID, result.input.ID)
+		route, err := s.ruleStore.UpsertForTenant(tenantID, result.input)
+		if err != nil {
+			results[i].Action = importActionInvalid
+			results[i].Error = err.Error()
+			continue
+		}
+		s.recordRouteVersion(user.Username, "import", route, optionalRule(previous, existed), 0)
+		s.hub.EnsureTunnelMetric(MakeTunnelKey(route.TenantID, route.ID))
+		s.publishRouteUpserted(route)
+	}
+	s.auditStore.Record(user.Username, "routes.import", tenantID, map[string]string{
+		"created":   strconv.Itoa(summary[importActionCreate]),
+		"updated":   strconv.Itoa(summary[importActionUpdate]),
+		"unchanged": strconv.Itoa(summary[importActionUnchanged]),
+		"skipped":   strconv.Itoa(summary[importActionSkip]),
+	})
+	payload["applied"] = true
+	writeJSON(w, http.StatusOK, payload)
+	s.refreshTenantUsage(tenantID)
+	s.persistState()
+}
+
+// planRouteImport classifies each definition against the tenant's current
+// routes. Definitions without a token keep the existing route's token so
+// files exported without secrets round-trip cleanly.
+func (s *Server) planRouteImport(tenantID string, definitions []upsertRuleRequest, onConflict string) []routeImportResult {
+	results := make([]routeImportResult, 0, len(definitions))
+	seen := make(map[string]bool, len(definitions))
+	creates := 0
+	for _, definition := range definitions {
+		definition.ID = normalizeIdentifier(definition.ID)
+		result := routeImportResult{ID: definition.ID}
+		if seen[definition.ID] {
+			result.Action = importActionInvalid
+			result.Error = "duplicate route id in document"
+			results = append(results, result)
+			continue
+		}
+		seen[definition.ID] = true
+
+		existing, exists := s.ruleStore.GetForTenant(tenantID, definition.ID)
+		if exists && strings.TrimSpace(definition.Token) == "" {
+			definition.Token = existing.Token
+		}
+		input, _, err := s.validateRouteRequest(tenantID, definition)
+		if err == nil {
+			var candidate Rule
+			candidate, err = s.ruleStore.ValidateForTenant(tenantID, input)
+			if err == nil && exists && reflect.DeepEqual(routeDefinitionFromRule(existing, true), routeDefinitionFromRule(candidate, true)) {
+				result.Action = importActionUnchanged
+				results = append(results, result)
+				continue
+			}
+		}
+		switch {
+		case err != nil:
+			result.Action = importActionInvalid
+			result.Error = err.Error()
+		case !exists:
+			result.Action = importActionCreate
+			creates++
+		case onConflict == "overwrite":
+			result.Action = importActionUpdate
+		case onConflict == "skip":
+			result.Action = importActionSkip
+		default:
+			result.Action = importActionConflict
+			result.Error = "route already exists with a different definition"
+		}
+		result.input = input
+		results = append(results, result)
+	}
+
+	// validateRouteRequest checks the plan limit one route at a time; the
+	// batch as a whole must fit as well.
+	plan, planID := s.planStore.GetTenantPlan(tenantID)
+	if current := s.ruleStore.RouteCountByTenant()[tenantID]; plan.MaxRoutes > 0 && current+creates > plan.MaxRoutes {
+		for i := range results {
+			if results[i].Action == importActionCreate {
+				results[i].Action = importActionInvalid
+				results[i].Error = fmt.Sprintf("plan %q route limit would be exceeded: %d/%d", planID, current+creates, plan.MaxRoutes)
+			}
+		}
+	}
+	return results
+}
+
+func routeDocumentFormat(query, contentType string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(query)) {
+	case "json":
+		return "json", nil
+	case "yaml", "yml":
+		return "yaml", nil
+	case "":
+	default:
+		return "", fmt.Errorf("format must be json or yaml")
+	}
+	if strings.Contains(strings.ToLower(contentType), "yaml") {
+		return "yaml", nil
+	}
+	return "json", nil
+}
+
+// decodeRouteDocument accepts JSON or YAML. YAML is converted to JSON first so
+// both formats share the API's JSON field names and validation.
+func decodeRouteDocument(body []byte, format string) (routeDocument, error) {
+	var document routeDocument
+	payload := body
+	if format == "yaml" {
+		var generic any
+		if err := yaml.Unmarshal(body, &generic); err != nil {
+			return routeDocument{}, err
+		}
+		converted, err := json.Marshal(generic)
+		if err != nil {
+			return routeDocument{}, err
+		}
+		payload = converted
+	}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	if err := decoder.Decode(&document); err != nil {
+		return routeDocument{}, err
+	}
+	if document.Version != routeDocumentVersion {
+		return routeDocument{}, fmt.Errorf("unsupported document version %d", document.Version)
+	}
+	return document, nil
+}
+
+// marshalYAMLDocument renders the JSON form of document as YAML, keeping the
+// JSON field order so exports read the same as API responses.
+func marshalYAMLDocument(document any) ([]byte, error) {
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	node, err := jsonToYAMLNode(decoder)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func jsonToYAMLNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch value := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if value == '{' {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for decoder.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(key)})
+			}
+			child, err := jsonToYAMLNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(value.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+func parseBoolQuery(r *http.Request, key string) bool {
+	value, err := strconv.ParseBool(strings.TrimSpace(r.URL.Query().Get(key)))
+	return err == nil && value
+}
@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteExportImportRoundTrip(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	admin := User{Username: "admin", Role: RoleSuperAdmin}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "orders", Target: "http://127.0.0.1:9000", Token: "secret"}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handleTenantRoutesExport(recorder, httptest.NewRequest(http.MethodGet, "/api/tenants/default/routes:export?format=yaml", nil), admin, DefaultTenantID)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected export 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	exported := recorder.Body.String()
+	if !strings.Contains(exported, "target: http://127.0.0.1:9000") || strings.Contains(exported, "secret") {
+		t.Fatalf("unexpected yaml export:\n%s", exported)
+	}
+
+	changed := strings.Replace(exported, "9000", "9001", 1) + "  - id: web\n    target: http://127.0.0.1:8080\n"
+	importRoutes := func(query, body string) (int, map[string]any) {
+		request := httptest.NewRequest(http.MethodPost, "/api/tenants/default/routes:import?"+query, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/yaml")
+		recorder := httptest.NewRecorder()
+		server.handleTenantRoutesImport(recorder, request, admin, DefaultTenantID)
+		var payload map[string]any
+		if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode import response: %v: %s", err, recorder.Body.String())
+		}
+		return recorder.Code, payload
+	}
+
+	if code, payload := importRoutes("", exported); code != http.StatusOK || payload["summary"].(map[string]any)[importActionUnchanged] != float64(1) {
+		t.Fatalf("expected unchanged re-import, got %d %+v", code, payload)
+	}
+	if code, _ := importRoutes("", changed); code != http.StatusConflict {
+		t.Fatalf("expected conflict, got %d", code)
+	}
+	if _, ok := server.ruleStore.GetForTenant(DefaultTenantID, "web"); ok {
+		t.Fatalf("expected conflicting import to apply nothing")
+	}
+	if code, payload := importRoutes("dry_run=true&on_conflict=overwrite", changed); code != http.StatusOK || payload["applied"] != false {
+		t.Fatalf("expected dry run report, got %d %+v", code, payload)
+	}
+	if code, payload := importRoutes("on_conflict=overwrite", changed); code != http.StatusOK || payload["applied"] != true {
+		t.Fatalf("expected overwrite import, got %d %+v", code, payload)
+	}
+	route, _ := server.ruleStore.GetForTenant(DefaultTenantID, "orders")
+	if route.Target != "http://127.0.0.1:9001" || route.Token != "secret" {
+		t.Fatalf("expected updated target with preserved token, got %+v", route)
+	}
+	if _, ok := server.ruleStore.GetForTenant(DefaultTenantID, "web"); !ok {
+		t.Fatalf("expected new route to be created")
+	}
+}
@@ -1,6 +1,7 @@
 package gateway
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -8,21 +9,31 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
 )
 
 const DefaultTenantID = "default"
 
 var identifierPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,63}$`)
 
+var errTenantExists = errors.New("tenant already exists")
+
 type Tenant struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	ErrorPages *ErrorPages       `json:"error_pages,omitempty"`
+	Redaction  *RedactionPolicy  `json:"redaction,omitempty"`
+	Retention  *TenantRetention  `json:"retention,omitempty"`
+	Status     string            `json:"status,omitempty"`
+	Suspension *TenantSuspension `json:"suspension,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
 }
 
 type TenantEnvironment struct {
 	TenantID    string            `json:"tenant_id"`
+	Name        string            `json:"name"`
 	Scheme      string            `json:"scheme"`
 	Host        string            `json:"host"`
 	DefaultPort int               `json:"default_port"`
@@ -31,25 +42,58 @@ type TenantEnvironment struct {
 }
 
 type Rule struct {
-	TenantID      string    `json:"tenant_id,omitempty"`
-	ID            string    `json:"id"`
-	Target        string    `json:"target"`
-	Token         string    `json:"token,omitempty"`
-	MaxRPS        float64   `json:"max_rps,omitempty"`
-	ConnectorID   string    `json:"connector_id,omitempty"`
-	LocalScheme   string    `json:"local_scheme,omitempty"`
-	LocalHost     string    `json:"local_host,omitempty"`
-	LocalPort     int       `json:"local_port,omitempty"`
-	LocalBasePath string    `json:"local_base_path,omitempty"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	TenantID             string                `json:"tenant_id,omitempty"`
+	ID                   string                `json:"id"`
+	Target               string                `json:"target"`
+	Environment          string                `json:"environment,omitempty"`
+	TargetTemplate       string                `json:"target_template,omitempty"`
+	Token                string                `json:"token,omitempty"`
+	MaxRPS               float64               `json:"max_rps,omitempty"`
+	MaxBytesPerSecond    int64                 `json:"max_bytes_per_second,omitempty"`
+	RequestTimeoutSecs   int                   `json:"request_timeout_seconds,omitempty"`
+	IdleTimeoutSecs      int                   `json:"idle_timeout_seconds,omitempty"`
+	HeaderTimeoutSecs    int                   `json:"response_header_timeout_seconds,omitempty"`
+	MaxResponseBodyBytes int64                 `json:"max_response_body_bytes,omitempty"`
+	MaxUploadBytes       int64                 `json:"max_upload_bytes,omitempty"`
+	Retry                *protocol.RetryPolicy `json:"retry,omitempty"`
+	ConnectorID          string                `json:"connector_id,omitempty"`
+	ConnectorSelector    map[string]string     `json:"connector_selector,omitempty"`
+	LocalScheme          string                `json:"local_scheme,omitempty"`
+	LocalHost            string                `json:"local_host,omitempty"`
+	LocalPort            int                   `json:"local_port,omitempty"`
+	LocalSocket          string                `json:"local_socket,omitempty"`
+	LocalTLS             *protocol.LocalTLS    `json:"local_tls,omitempty"`
+	LocalBasePath        string                `json:"local_base_path,omitempty"`
+	BasePathTemplate     string                `json:"local_base_path_template,omitempty"`
+	ErrorPages           *ErrorPages           `json:"error_pages,omitempty"`
+	CORS                 *CORSPolicy           `json:"cors,omitempty"`
+	Presets              *RoutePresets         `json:"presets,omitempty"`
+	PathRoutes           []PathRoute           `json:"path_routes,omitempty"`
+	Rewrite              *RouteRewrite         `json:"rewrite,omitempty"`
+	ForwardedHeaders     *ForwardedHeaders     `json:"forwarded_headers,omitempty"`
+	Idempotency          *RouteIdempotency     `json:"idempotency,omitempty"`
+	QueueOverflow        *RouteQueueOverflow   `json:"queue_overflow,omitempty"`
+	SyntheticCheck       *RouteSyntheticCheck  `json:"synthetic_check,omitempty"`
+	TLSPassthrough       *TLSPassthrough       `json:"tls_passthrough,omitempty"`
+	Mirror               *RouteMirror          `json:"mirror,omitempty"`
+	Split                *RouteSplit           `json:"split,omitempty"`
+	Middleware           []RouteMiddleware     `json:"middleware,omitempty"`
+	Mock                 *RouteMock            `json:"mock,omitempty"`
+	Capture              *RouteCapture         `json:"capture,omitempty"`
+	WebhookVerification  *WebhookVerification  `json:"webhook_verification,omitempty"`
+	ActiveFrom           *time.Time            `json:"active_from,omitempty"`
+	ExpiresAt            *time.Time            `json:"expires_at,omitempty"`
+	DeleteOnExpiry       bool                  `json:"delete_on_expiry,omitempty"`
+	CreatedAt            time.Time             `json:"created_at"`
+	UpdatedAt            time.Time             `json:"updated_at"`
 }
 
 type RuleStore struct {
-	mu      sync.RWMutex
-	tenants map[string]Tenant
-	envs    map[string]TenantEnvironment
-	rules   map[string]Rule
+	mu         sync.RWMutex
+	tenants    map[string]Tenant
+	envs       map[string]map[string]TenantEnvironment
+	rules      map[string]Rule
+	namePolicy *NamePolicy
 }
 
 func NewRuleStore() *RuleStore {
@@ -60,22 +104,30 @@ func NewRuleStore() *RuleStore {
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
-	defaultEnv := TenantEnvironment{
-		TenantID:    DefaultTenantID,
-		Scheme:      "http",
-		Host:        "host.docker.internal",
-		DefaultPort: 3000,
-		Variables:   map[string]string{},
-		UpdatedAt:   now,
-	}
 	return &RuleStore{
 		tenants: map[string]Tenant{DefaultTenantID: defaultTenant},
-		envs:    map[string]TenantEnvironment{DefaultTenantID: defaultEnv},
+		envs:    map[string]map[string]TenantEnvironment{DefaultTenantID: newDefaultEnvironments(DefaultTenantID, now)},
 		rules:   make(map[string]Rule),
 	}
 }
 
+func (s *RuleStore) SetNamePolicy(policy *NamePolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.namePolicy = policy
+}
+
 func (s *RuleStore) UpsertTenant(input Tenant) (Tenant, error) {
+	return s.putTenant(input, false)
+}
+
+// CreateTenant adds a tenant and fails with errTenantExists when the ID is
+// already taken, so callers cannot end up joining an existing tenant.
+func (s *RuleStore) CreateTenant(input Tenant) (Tenant, error) {
+	return s.putTenant(input, true)
+}
+
+func (s *RuleStore) putTenant(input Tenant, create bool) (Tenant, error) {
 	tenantID := normalizeIdentifier(input.ID)
 	if !identifierPattern.MatchString(tenantID) {
 		return Tenant{}, fmt.Errorf("invalid tenant id %q (allowed: letters, numbers, _, -, max 64)", tenantID)
@@ -92,6 +144,9 @@ func (s *RuleStore) UpsertTenant(input Tenant) (Tenant, error) {
 	defer s.mu.Unlock()
 
 	existing, ok := s.tenants[tenantID]
+	if ok && create {
+		return Tenant{}, errTenantExists
+	}
 	if !ok {
 		existing.CreatedAt = now
 	}
@@ -100,14 +155,7 @@ func (s *RuleStore) UpsertTenant(input Tenant) (Tenant, error) {
 	existing.UpdatedAt = now
 	s.tenants[tenantID] = existing
 	if _, ok := s.envs[tenantID]; !ok {
-		s.envs[tenantID] = TenantEnvironment{
-			TenantID:    tenantID,
-			Scheme:      "http",
-			Host:        "host.docker.internal",
-			DefaultPort: 3000,
-			Variables:   map[string]string{},
-			UpdatedAt:   now,
-		}
+		s.envs[tenantID] = newDefaultEnvironments(tenantID, now)
 	}
 	return existing, nil
 }
@@ -134,6 +182,58 @@ func (s *RuleStore) DeleteTenant(tenantID string) bool {
 	return true
 }
 
+func (s *RuleStore) TakeTenantRules(tenantID string) []Rule {
+	tenantID = normalizeIdentifier(tenantID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	taken := make([]Rule, 0)
+	for key, rule := range s.rules {
+		if rule.TenantID == tenantID {
+			taken = append(taken, rule)
+			delete(s.rules, key)
+		}
+	}
+	sort.Slice(taken, func(i, j int) bool { return taken[i].ID < taken[j].ID })
+	return taken
+}
+
+func (s *RuleStore) PutTenantRules(tenantID string, rules []Rule) {
+	tenantID = normalizeIdentifier(tenantID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rule := range rules {
+		rule.TenantID = tenantID
+		s.rules[ruleKey(tenantID, rule.ID)] = rule
+	}
+}
+
+// RestoreRule puts back a deleted route as it was. It fails if the tenant is
+// gone or inactive, or the route ID was reused in the meantime.
+func (s *RuleStore) RestoreRule(rule Rule) error {
+	tenantID := normalizeIdentifier(rule.TenantID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if tenant, ok := s.tenants[tenantID]; !ok {
+		return fmt.Errorf("tenant %q not found", tenantID)
+	} else if status := tenant.status(); status != TenantStatusActive {
+		return fmt.Errorf("tenant %q is %s", tenantID, status)
+	}
+	key := ruleKey(tenantID, rule.ID)
+	if _, exists := s.rules[key]; exists {
+		return fmt.Errorf("route %q already exists", rule.ID)
+	}
+	rule.TenantID = tenantID
+	rule.UpdatedAt = time.Now().UTC()
+	s.rules[key] = rule
+	return nil
+}
+
 func (s *RuleStore) ListTenants() []Tenant {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -149,6 +249,101 @@ func (s *RuleStore) ListTenants() []Tenant {
 	return tenants
 }
 
+func (s *RuleStore) GetTenant(tenantID string) (Tenant, bool) {
+	tenantID = normalizeIdentifier(tenantID)
+	if tenantID == "" {
+		return Tenant{}, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tenant, ok := s.tenants[tenantID]
+	return tenant, ok
+}
+
+func (s *RuleStore) SetTenantErrorPages(tenantID string, input *ErrorPages) (Tenant, error) {
+	tenantID = normalizeIdentifier(tenantID)
+	pages, err := normalizeErrorPages(input)
+	if err != nil {
+		return Tenant{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenant, ok := s.tenants[tenantID]
+	if !ok {
+		return Tenant{}, fmt.Errorf("tenant %q not found", tenantID)
+	}
+	tenant.ErrorPages = pages
+	tenant.UpdatedAt = time.Now().UTC()
+	s.tenants[tenantID] = tenant
+	return tenant, nil
+}
+
+func (s *RuleStore) SetTenantRedaction(tenantID string, input *RedactionPolicy) (Tenant, error) {
+	tenantID = normalizeIdentifier(tenantID)
+	policy, err := normalizeRedactionPolicy(input)
+	if err != nil {
+		return Tenant{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenant, ok := s.tenants[tenantID]
+	if !ok {
+		return Tenant{}, fmt.Errorf("tenant %q not found", tenantID)
+	}
+	tenant.Redaction = policy
+	tenant.UpdatedAt = time.Now().UTC()
+	s.tenants[tenantID] = tenant
+	return tenant, nil
+}
+
+func (s *RuleStore) SetTenantRetention(tenantID string, input *TenantRetention) (Tenant, error) {
+	tenantID = normalizeIdentifier(tenantID)
+	retention, err := normalizeTenantRetention(input)
+	if err != nil {
+		return Tenant{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenant, ok := s.tenants[tenantID]
+	if !ok {
+		return Tenant{}, fmt.Errorf("tenant %q not found", tenantID)
+	}
+	tenant.Retention = retention
+	tenant.UpdatedAt = time.Now().UTC()
+	s.tenants[tenantID] = tenant
+	return tenant, nil
+}
+
+func (s *RuleStore) SetTenantStatus(tenantID, status string, suspension *TenantSuspension, from string) (Tenant, error) {
+	tenantID = normalizeIdentifier(tenantID)
+	if tenantID == DefaultTenantID && status != "" {
+		return Tenant{}, fmt.Errorf("the default tenant cannot be suspended")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenant, ok := s.tenants[tenantID]
+	if !ok {
+		return Tenant{}, fmt.Errorf("tenant %q not found", tenantID)
+	}
+	if current := tenant.status(); current != from {
+		return Tenant{}, fmt.Errorf("tenant %q is %s", tenantID, current)
+	}
+	tenant.Status = status
+	tenant.Suspension = suspension
+	tenant.UpdatedAt = time.Now().UTC()
+	s.tenants[tenantID] = tenant
+	return tenant, nil
+}
+
 func (s *RuleStore) HasTenant(tenantID string) bool {
 	tenantID = normalizeIdentifier(tenantID)
 	if tenantID == "" {
@@ -162,6 +357,14 @@ func (s *RuleStore) HasTenant(tenantID string) bool {
 }
 
 func (s *RuleStore) UpsertForTenant(tenantID string, input Rule) (Rule, error) {
+	return s.upsertForTenant(tenantID, input, true)
+}
+
+func (s *RuleStore) ValidateForTenant(tenantID string, input Rule) (Rule, error) {
+	return s.upsertForTenant(tenantID, input, false)
+}
+
+func (s *RuleStore) upsertForTenant(tenantID string, input Rule, apply bool) (Rule, error) {
 	tenantID = normalizeIdentifier(tenantID)
 	if !identifierPattern.MatchString(tenantID) {
 		return Rule{}, fmt.Errorf("invalid tenant id %q", tenantID)
@@ -178,25 +381,117 @@ func (s *RuleStore) UpsertForTenant(tenantID string, input Rule) (Rule, error) {
 	localScheme := strings.ToLower(strings.TrimSpace(input.LocalScheme))
 	localHost := strings.TrimSpace(input.LocalHost)
 	localPort := input.LocalPort
+	localSocket := strings.TrimSpace(input.LocalSocket)
 	localBasePath := strings.TrimSpace(input.LocalBasePath)
+	environment := normalizeIdentifier(input.Environment)
+	if environment != "" && !identifierPattern.MatchString(environment) {
+		return Rule{}, fmt.Errorf("invalid environment name %q", environment)
+	}
+	targetTemplate, basePathTemplate := "", ""
+	if hasEnvironmentReference(target) || hasEnvironmentReference(localBasePath) {
+		env, ok := s.GetNamedEnvironment(tenantID, normalizeEnvironmentName(environment))
+		if !ok {
+			return Rule{}, fmt.Errorf("environment %q not found", normalizeEnvironmentName(environment))
+		}
+		resolver := newEnvironmentResolver(env)
+		var err error
+		if hasEnvironmentReference(target) {
+			targetTemplate = target
+			if target, err = resolver.expand(target); err != nil {
+				return Rule{}, fmt.Errorf("target: %w", err)
+			}
+		}
+		if hasEnvironmentReference(localBasePath) {
+			basePathTemplate = localBasePath
+			if localBasePath, err = resolver.expand(localBasePath); err != nil {
+				return Rule{}, fmt.Errorf("local_base_path: %w", err)
+			}
+		}
+	}
 	maxRPS := input.MaxRPS
 	if maxRPS < 0 {
 		return Rule{}, fmt.Errorf("max_rps cannot be negative")
 	}
+	if err := validateBandwidthLimit("max_bytes_per_second", input.MaxBytesPerSecond); err != nil {
+		return Rule{}, err
+	}
+	errorPages, err := normalizeErrorPages(input.ErrorPages)
+	if err != nil {
+		return Rule{}, err
+	}
+	cors, err := normalizeCORSPolicy(input.CORS)
+	if err != nil {
+		return Rule{}, err
+	}
+	presets, err := normalizeRoutePresets(input.Presets, cors)
+	if err != nil {
+		return Rule{}, err
+	}
+	rewrite, err := normalizeRouteRewrite(input.Rewrite)
+	if err != nil {
+		return Rule{}, err
+	}
+	mirror, err := normalizeRouteMirror(input.Mirror)
+	if err != nil {
+		return Rule{}, err
+	}
+	split, err := normalizeRouteSplit(input.Split)
+	if err != nil {
+		return Rule{}, err
+	}
+	middleware, err := normalizeRouteMiddleware(input.Middleware)
+	if err != nil {
+		return Rule{}, err
+	}
+	mock, err := normalizeRouteMock(input.Mock)
+	if err != nil {
+		return Rule{}, err
+	}
+	capture, err := normalizeRouteCapture(input.Capture)
+	if err != nil {
+		return Rule{}, err
+	}
+	webhookVerification, err := normalizeRouteWebhookVerification(input.WebhookVerification)
+	if err != nil {
+		return Rule{}, err
+	}
+	connectorSelector, err := normalizeConnectorLabels("connector_selector", input.ConnectorSelector)
+	if err != nil {
+		return Rule{}, err
+	}
+	if connectorSelector != nil && connectorID != "" {
+		return Rule{}, fmt.Errorf("connector_selector cannot be combined with connector_id")
+	}
+	usesConnector := connectorID != "" || connectorSelector != nil
+	if err := normalizeRouteTimeouts(input.RequestTimeoutSecs, input.IdleTimeoutSecs, input.HeaderTimeoutSecs); err != nil {
+		return Rule{}, err
+	}
+	if input.MaxResponseBodyBytes < 0 {
+		return Rule{}, fmt.Errorf("max_response_body_bytes must be >= 0")
+	}
+	if input.MaxUploadBytes < 0 {
+		return Rule{}, fmt.Errorf("max_upload_bytes must be >= 0")
+	}
+	activeFrom := normalizeOptionalTime(input.ActiveFrom)
+	expiresAt := normalizeOptionalTime(input.ExpiresAt)
+	if expiresAt != nil && activeFrom != nil && !expiresAt.After(*activeFrom) {
+		return Rule{}, fmt.Errorf("expires_at must be after active_from")
+	}
+	if input.DeleteOnExpiry && expiresAt == nil {
+		return Rule{}, fmt.Errorf("delete_on_expiry requires expires_at")
+	}
 
-	if connectorID == "" {
-		parsedTarget, err := url.Parse(target)
-		if err != nil {
-			return Rule{}, fmt.Errorf("invalid target URL: %w", err)
-		}
-		if parsedTarget.Scheme != "http" && parsedTarget.Scheme != "https" {
-			return Rule{}, fmt.Errorf("target URL must use http or https")
-		}
-		if strings.TrimSpace(parsedTarget.Host) == "" {
-			return Rule{}, fmt.Errorf("target URL must include a host")
+	if localSocket != "" && !usesConnector {
+		return Rule{}, fmt.Errorf("local_socket requires connector_id or connector_selector")
+	}
+
+	// Mock routes may be created before their upstream exists.
+	if !usesConnector && (mock == nil || target != "") {
+		if err := validateTargetURL(target); err != nil {
+			return Rule{}, err
 		}
-	} else {
-		if !identifierPattern.MatchString(connectorID) {
+	} else if usesConnector {
+		if connectorID != "" && !identifierPattern.MatchString(connectorID) {
 			return Rule{}, fmt.Errorf("invalid connector id %q", connectorID)
 		}
 		if localScheme == "" {
@@ -205,54 +500,200 @@ func (s *RuleStore) UpsertForTenant(tenantID string, input Rule) (Rule, error) {
 		if localScheme != "http" && localScheme != "https" {
 			return Rule{}, fmt.Errorf("local_scheme must be http or https")
 		}
-		if localHost == "" {
+		if localHost == "" && localSocket == "" {
 			localHost = "127.0.0.1"
 		}
 		if strings.Contains(localHost, "://") {
 			return Rule{}, fmt.Errorf("local_host should not include scheme")
 		}
-		if localPort < 1 || localPort > 65535 {
-			return Rule{}, fmt.Errorf("local_port must be between 1 and 65535 when connector_id is set")
+		if localSocket != "" {
+			// Sockets live on the agent host, so only the path shape is checked.
+			if !strings.HasPrefix(localSocket, "/") {
+				return Rule{}, fmt.Errorf("local_socket must be an absolute path")
+			}
+			if localPort != 0 {
+				return Rule{}, fmt.Errorf("local_socket cannot be combined with local_port")
+			}
+		} else if localPort < 1 || localPort > 65535 {
+			return Rule{}, fmt.Errorf("local_port must be between 1 and 65535 when connector_id or connector_selector is set")
 		}
 		if localBasePath != "" && !strings.HasPrefix(localBasePath, "/") {
 			localBasePath = "/" + localBasePath
 		}
 		if target == "" {
-			target = fmt.Sprintf("%s://%s:%d%s", localScheme, localHost, localPort, localBasePath)
+			target = connectorTarget(localScheme, localHost, localPort, localSocket, localBasePath)
 		}
 	}
+	tlsPassthrough, err := normalizeTLSPassthrough(input.TLSPassthrough)
+	if err != nil {
+		return Rule{}, err
+	}
+	if tlsPassthrough != nil && !usesConnector && target == "" {
+		return Rule{}, fmt.Errorf("tls_passthrough requires a target, connector_id or connector_selector")
+	}
+	retry, err := normalizeRetryPolicy(input.Retry)
+	if err != nil {
+		return Rule{}, err
+	}
+	idempotency, err := normalizeRouteIdempotency(input.Idempotency)
+	if err != nil {
+		return Rule{}, err
+	}
+	queueOverflow, err := normalizeRouteQueueOverflow(input.QueueOverflow)
+	if err != nil {
+		return Rule{}, err
+	}
+	syntheticCheck, err := normalizeRouteSyntheticCheck(input.SyntheticCheck)
+	if err != nil {
+		return Rule{}, err
+	}
+	localTLS, err := normalizeLocalTLS(input.LocalTLS, usesConnector, localScheme)
+	if err != nil {
+		return Rule{}, err
+	}
+	pathRoutes, err := normalizePathRoutes(input.PathRoutes, usesConnector, localScheme, localHost)
+	if err != nil {
+		return Rule{}, err
+	}
 
 	now := time.Now().UTC()
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.tenants[tenantID]; !ok {
+	if tenant, ok := s.tenants[tenantID]; !ok {
 		return Rule{}, fmt.Errorf("tenant %q not found", tenantID)
+	} else if status := tenant.status(); status != TenantStatusActive {
+		return Rule{}, fmt.Errorf("tenant %q is %s", tenantID, status)
+	}
+
+	if _, ok := s.envs[tenantID][environment]; environment != "" && !ok {
+		return Rule{}, fmt.Errorf("environment %q not found", environment)
 	}
 
 	key := ruleKey(tenantID, routeID)
 	existing, ok := s.rules[key]
 	if !ok {
+		// Existing routes keep working if the blocklist changes later.
+		if err := s.namePolicy.Check(routeID); err != nil {
+			return Rule{}, err
+		}
 		existing.CreatedAt = now
 	}
+	// Only a new expiry must lie ahead, so expired routes can still be
+	// edited without moving it.
+	if expiresAt != nil && !expiresAt.After(now) && (existing.ExpiresAt == nil || !existing.ExpiresAt.Equal(*expiresAt)) {
+		return Rule{}, fmt.Errorf("expires_at must be in the future")
+	}
+	if err := s.checkPassthroughHostnamesLocked(key, tlsPassthrough); err != nil {
+		return Rule{}, err
+	}
+	if webhookVerification != nil && webhookVerification.Secret == "" {
+		if existing.WebhookVerification == nil || existing.WebhookVerification.Secret == "" {
+			return Rule{}, fmt.Errorf("webhook_verification.secret is required")
+		}
+		webhookVerification.Secret = existing.WebhookVerification.Secret
+	}
 	existing.TenantID = tenantID
 	existing.ID = routeID
 	existing.Target = target
+	existing.Environment = environment
+	existing.TargetTemplate = targetTemplate
 	existing.Token = token
 	existing.MaxRPS = maxRPS
+	existing.MaxBytesPerSecond = input.MaxBytesPerSecond
+	existing.RequestTimeoutSecs = input.RequestTimeoutSecs
+	existing.IdleTimeoutSecs = input.IdleTimeoutSecs
+	existing.HeaderTimeoutSecs = input.HeaderTimeoutSecs
+	existing.MaxResponseBodyBytes = input.MaxResponseBodyBytes
+	existing.MaxUploadBytes = input.MaxUploadBytes
+	existing.Retry = retry
 	existing.ConnectorID = connectorID
+	existing.ConnectorSelector = connectorSelector
 	existing.LocalScheme = localScheme
 	existing.LocalHost = localHost
 	existing.LocalPort = localPort
+	existing.LocalSocket = localSocket
+	existing.LocalTLS = localTLS
 	existing.LocalBasePath = localBasePath
+	existing.BasePathTemplate = basePathTemplate
+	existing.ErrorPages = errorPages
+	existing.CORS = cors
+	existing.Presets = presets
+	existing.PathRoutes = pathRoutes
+	existing.Rewrite = rewrite
+	existing.ForwardedHeaders = normalizeForwardedHeaders(input.ForwardedHeaders)
+	existing.Idempotency = idempotency
+	existing.QueueOverflow = queueOverflow
+	existing.SyntheticCheck = syntheticCheck
+	existing.TLSPassthrough = tlsPassthrough
+	existing.Mirror = mirror
+	existing.Split = split
+	existing.Middleware = middleware
+	existing.Mock = mock
+	existing.Capture = capture
+	existing.WebhookVerification = webhookVerification
+	existing.ActiveFrom = activeFrom
+	existing.ExpiresAt = expiresAt
+	existing.DeleteOnExpiry = input.DeleteOnExpiry
 	existing.UpdatedAt = now
-	s.rules[key] = existing
+	if apply {
+		s.rules[key] = existing
+	}
 	return existing, nil
 }
 
+func validateTargetURL(target string) error {
+	parsedTarget, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("invalid target URL: %w", err)
+	}
+	if parsedTarget.Scheme != "http" && parsedTarget.Scheme != "https" {
+		return fmt.Errorf("target URL must use http or https")
+	}
+	if strings.TrimSpace(parsedTarget.Host) == "" {
+		return fmt.Errorf("target URL must include a host")
+	}
+	return nil
+}
+
+func connectorTarget(scheme, host string, port int, socket, basePath string) string {
+	if socket != "" {
+		return fmt.Sprintf("unix://%s%s", socket, basePath)
+	}
+	return fmt.Sprintf("%s://%s:%d%s", scheme, host, port, basePath)
+}
+
 func (r Rule) UsesConnector() bool {
-	return strings.TrimSpace(r.ConnectorID) != ""
+	return strings.TrimSpace(r.ConnectorID) != "" || len(r.ConnectorSelector) > 0
+}
+
+func (r Rule) localTarget() *protocol.LocalTarget {
+	return &protocol.LocalTarget{
+		Scheme: r.LocalScheme,
+		Host:   r.LocalHost,
+		Port:   r.LocalPort,
+		Socket: r.LocalSocket,
+		TLS:    r.LocalTLS,
+	}
+}
+
+func (s *RuleStore) DeleteExpired(now time.Time) []Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := make([]Rule, 0)
+	for key, rule := range s.rules {
+		if !rule.DeleteOnExpiry || rule.ScheduleState(now) != RouteScheduleExpired {
+			continue
+		}
+		delete(s.rules, key)
+		removed = append(removed, rule)
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		return ruleKey(removed[i].TenantID, removed[i].ID) < ruleKey(removed[j].TenantID, removed[j].ID)
+	})
+	return removed
 }
 
 func (s *RuleStore) DeleteForTenant(tenantID, routeID string) bool {
@@ -342,28 +783,21 @@ func (s *RuleStore) RouteCountByTenant() map[string]int {
 }
 
 func (s *RuleStore) GetEnvironment(tenantID string) (TenantEnvironment, bool) {
-	tenantID = normalizeIdentifier(tenantID)
-	if tenantID == "" {
-		return TenantEnvironment{}, false
-	}
-
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	env, ok := s.envs[tenantID]
-	if !ok {
-		return TenantEnvironment{}, false
-	}
-	// Deep copy map to avoid exposing internal references.
-	env.Variables = copyStringMap(env.Variables)
-	return env, true
+	return s.GetNamedEnvironment(tenantID, DefaultEnvironmentName)
 }
 
+// UpsertEnvironment creates or replaces the environment named by input.Name,
+// or the default environment when it is empty. Routes referencing it are
+// re-resolved; if any of them would no longer resolve, nothing changes.
 func (s *RuleStore) UpsertEnvironment(input TenantEnvironment) (TenantEnvironment, error) {
 	tenantID := normalizeIdentifier(input.TenantID)
 	if !identifierPattern.MatchString(tenantID) {
 		return TenantEnvironment{}, fmt.Errorf("invalid tenant id %q", tenantID)
 	}
+	name := normalizeEnvironmentName(input.Name)
+	if !identifierPattern.MatchString(name) {
+		return TenantEnvironment{}, fmt.Errorf("invalid environment name %q (allowed: letters, numbers, _, -, max 64)", name)
+	}
 
 	scheme := strings.ToLower(strings.TrimSpace(input.Scheme))
 	if scheme == "" {
@@ -399,22 +833,37 @@ func (s *RuleStore) UpsertEnvironment(input TenantEnvironment) (TenantEnvironmen
 		variables = map[string]string{}
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if _, ok := s.tenants[tenantID]; !ok {
-		return TenantEnvironment{}, fmt.Errorf("tenant %q not found", tenantID)
-	}
-
 	env := TenantEnvironment{
 		TenantID:    tenantID,
+		Name:        name,
 		Scheme:      scheme,
 		Host:        host,
 		DefaultPort: port,
 		Variables:   variables,
 		UpdatedAt:   now,
 	}
-	s.envs[tenantID] = env
+	if err := validateEnvironmentVariables(env); err != nil {
+		return TenantEnvironment{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tenants[tenantID]; !ok {
+		return TenantEnvironment{}, fmt.Errorf("tenant %q not found", tenantID)
+	}
+	resolved, err := s.resolveEnvironmentRoutesLocked(env)
+	if err != nil {
+		return TenantEnvironment{}, err
+	}
+	if s.envs[tenantID] == nil {
+		s.envs[tenantID] = make(map[string]TenantEnvironment)
+	}
+	s.envs[tenantID][name] = env
+	for key, rule := range resolved {
+		s.rules[key] = rule
+	}
+	env.Variables = copyStringMap(env.Variables)
 	return env, nil
 }
 
@@ -463,7 +912,28 @@ func copyStringMap(input map[string]string) map[string]string {
 	return output
 }
 
+// Route variants get their own metrics under "{tenant}/{route}#{variant}".
+const (
+	routeVariantPrimary = "primary"
+	routeVariantCanary  = "canary"
+	routeVariantMirror  = "mirror"
+)
+
+func variantTunnelKey(tenantID, routeID, variant string) string {
+	return MakeTunnelKey(tenantID, routeID) + "#" + variant
+}
+
+func splitTunnelVariant(tunnelID string) (base string, variant string) {
+	if index := strings.IndexByte(tunnelID, '#'); index >= 0 {
+		return tunnelID[:index], tunnelID[index+1:]
+	}
+	return tunnelID, ""
+}
+
+// ParseTunnelKey ignores any variant suffix, so variant keys resolve to the
+// route they belong to.
 func ParseTunnelKey(tunnelID string) (tenantID string, routeID string) {
+	tunnelID, _ = splitTunnelVariant(tunnelID)
 	tunnelID = normalizeIdentifier(tunnelID)
 	if tunnelID == "" {
 		return DefaultTenantID, ""
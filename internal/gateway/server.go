@@ -16,6 +16,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -29,28 +30,54 @@ const sessionCookieName = "proxer_session"
 var errBodyTooLarge = errors.New("body too large")
 
 type Server struct {
-	cfg                  Config
-	logger               *log.Logger
-	hub                  *Hub
-	ruleStore            *RuleStore
-	authStore            *AuthStore
-	connectorStore       *ConnectorStore
-	planStore            *PlanStore
-	rateLimiter          *RateLimiter
-	incidentStore        *IncidentStore
-	funnelAnalytics      *FunnelAnalyticsStore
-	tlsStore             *TLSStore
-	downloads            *GitHubReleaseDownloadsProvider
-	persistence          storepkg.SnapshotStore
-	forwardHTTP          *http.Client
-	maxRequestBodyBytes  int64
-	maxResponseBodyBytes int64
+	cfgMu           sync.RWMutex
+	cfg             Config
+	configLoader    ConfigLoader
+	logger          *log.Logger
+	hub             *Hub
+	ruleStore       *RuleStore
+	authStore       *AuthStore
+	connectorStore  *ConnectorStore
+	planStore       *PlanStore
+	rateLimiter     RateLimitBackend
+	clientWrites    clientWriteStats
+	incidentStore   *IncidentStore
+	auditStore      *AuditStore
+	namePolicy      *NamePolicy
+	ipBans          *IPBanList
+	webhooks        *WebhookNotifier
+	notifier        *userNotifier
+	events          *EventBus
+	funnelAnalytics *FunnelAnalyticsStore
+	tlsStore        *TLSStore
+	domainStore     *DomainStore
+	orgStore        *OrgStore
+	idempotency     *IdempotencyStore
+	captures        *CaptureStore
+	reverseForwards *ReverseForwardStore
+	sshKeys         *SSHKeyStore
+	sshTunnels      *sshTunnels
+	synthetic       *SyntheticMonitor
+	bandwidth       *BandwidthLimiters
+	transfer        *TransferStore
+	tenantArchives  *TenantArchiveStore
+	trash           *TrashStore
+	domainResolver  domainResolver
+	downloads       *GitHubReleaseDownloadsProvider
+	persistence     storepkg.SnapshotStore
+	forwardHTTP     *http.Client
+	mirrorSlots     chan struct{}
 
 	httpServer  *http.Server
 	listener    net.Listener
 	tlsServer   *http.Server
 	tlsListener net.Listener
 
+	adminServer   *http.Server
+	adminListener net.Listener
+	agentServer   *http.Server
+	agentListener net.Listener
+
 	requestCounter uint64
 	startedAt      time.Time
 }
@@ -71,45 +98,115 @@ type tunnelView struct {
 }
 
 type routeView struct {
-	TenantID        string        `json:"tenant_id"`
-	RouteID         string        `json:"route_id"`
-	ID              string        `json:"id"`
-	TunnelKey       string        `json:"tunnel_key"`
-	Target          string        `json:"target"`
-	MaxRPS          float64       `json:"max_rps,omitempty"`
-	ConnectorID     string        `json:"connector_id,omitempty"`
-	LocalScheme     string        `json:"local_scheme,omitempty"`
-	LocalHost       string        `json:"local_host,omitempty"`
-	LocalPort       int           `json:"local_port,omitempty"`
-	LocalBasePath   string        `json:"local_base_path,omitempty"`
-	PublicURL       string        `json:"public_url"`
-	LegacyPublicURL string        `json:"legacy_public_url,omitempty"`
-	TokenConfigured bool          `json:"token_configured"`
-	Connected       bool          `json:"connected"`
-	AgentID         string        `json:"agent_id,omitempty"`
-	Metrics         TunnelMetrics `json:"metrics"`
-	CreatedAt       time.Time     `json:"created_at"`
-	UpdatedAt       time.Time     `json:"updated_at"`
+	TenantID             string                   `json:"tenant_id"`
+	RouteID              string                   `json:"route_id"`
+	ID                   string                   `json:"id"`
+	TunnelKey            string                   `json:"tunnel_key"`
+	Target               string                   `json:"target"`
+	Environment          string                   `json:"environment,omitempty"`
+	TargetTemplate       string                   `json:"target_template,omitempty"`
+	MaxRPS               float64                  `json:"max_rps,omitempty"`
+	MaxBytesPerSecond    int64                    `json:"max_bytes_per_second,omitempty"`
+	RequestTimeoutSecs   int                      `json:"request_timeout_seconds,omitempty"`
+	IdleTimeoutSecs      int                      `json:"idle_timeout_seconds,omitempty"`
+	HeaderTimeoutSecs    int                      `json:"response_header_timeout_seconds,omitempty"`
+	MaxResponseBodyBytes int64                    `json:"max_response_body_bytes,omitempty"`
+	MaxUploadBytes       int64                    `json:"max_upload_bytes,omitempty"`
+	Retry                *protocol.RetryPolicy    `json:"retry,omitempty"`
+	ConnectorID          string                   `json:"connector_id,omitempty"`
+	ConnectorSelector    map[string]string        `json:"connector_selector,omitempty"`
+	LocalScheme          string                   `json:"local_scheme,omitempty"`
+	LocalHost            string                   `json:"local_host,omitempty"`
+	LocalPort            int                      `json:"local_port,omitempty"`
+	LocalSocket          string                   `json:"local_socket,omitempty"`
+	LocalTLS             *protocol.LocalTLS       `json:"local_tls,omitempty"`
+	LocalBasePath        string                   `json:"local_base_path,omitempty"`
+	BasePathTemplate     string                   `json:"local_base_path_template,omitempty"`
+	ErrorPages           *ErrorPages              `json:"error_pages,omitempty"`
+	CORS                 *CORSPolicy              `json:"cors,omitempty"`
+	Presets              *RoutePresets            `json:"presets,omitempty"`
+	PathRoutes           []PathRoute              `json:"path_routes,omitempty"`
+	Rewrite              *RouteRewrite            `json:"rewrite,omitempty"`
+	ForwardedHeaders     *ForwardedHeaders        `json:"forwarded_headers,omitempty"`
+	Idempotency          *RouteIdempotency        `json:"idempotency,omitempty"`
+	QueueOverflow        *RouteQueueOverflow      `json:"queue_overflow,omitempty"`
+	SyntheticCheck       *RouteSyntheticCheck     `json:"synthetic_check,omitempty"`
+	TLSPassthrough       *TLSPassthrough          `json:"tls_passthrough,omitempty"`
+	Mirror               *RouteMirror             `json:"mirror,omitempty"`
+	Split                *RouteSplit              `json:"split,omitempty"`
+	Middleware           []RouteMiddleware        `json:"middleware,omitempty"`
+	Mock                 *RouteMock               `json:"mock,omitempty"`
+	Capture              *RouteCapture            `json:"capture,omitempty"`
+	WebhookVerification  *WebhookVerification     `json:"webhook_verification,omitempty"`
+	ActiveFrom           *time.Time               `json:"active_from,omitempty"`
+	ExpiresAt            *time.Time               `json:"expires_at,omitempty"`
+	ExpiresInSecs        *int64                   `json:"expires_in_seconds,omitempty"`
+	DeleteOnExpiry       bool                     `json:"delete_on_expiry,omitempty"`
+	ScheduleState        string                   `json:"schedule_state"`
+	PublicURL            string                   `json:"public_url"`
+	LegacyPublicURL      string                   `json:"legacy_public_url,omitempty"`
+	TokenConfigured      bool                     `json:"token_configured"`
+	Connected            bool                     `json:"connected"`
+	AgentID              string                   `json:"agent_id,omitempty"`
+	Health               *protocol.TargetHealth   `json:"health,omitempty"`
+	SyntheticStatus      *SyntheticCheckStatus    `json:"synthetic_status,omitempty"`
+	Metrics              TunnelMetrics            `json:"metrics"`
+	VariantMetrics       map[string]TunnelMetrics `json:"variant_metrics,omitempty"`
+	CreatedAt            time.Time                `json:"created_at"`
+	UpdatedAt            time.Time                `json:"updated_at"`
 }
 
 type tenantView struct {
-	ID         string    `json:"id"`
-	Name       string    `json:"name"`
-	RouteCount int       `json:"route_count"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Status     string            `json:"status"`
+	Suspension *TenantSuspension `json:"suspension,omitempty"`
+	RouteCount int               `json:"route_count"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
 }
 
 type upsertRuleRequest struct {
-	ID            string  `json:"id"`
-	Target        string  `json:"target"`
-	Token         string  `json:"token"`
-	MaxRPS        float64 `json:"max_rps"`
-	ConnectorID   string  `json:"connector_id"`
-	LocalScheme   string  `json:"local_scheme"`
-	LocalHost     string  `json:"local_host"`
-	LocalPort     int     `json:"local_port"`
-	LocalBasePath string  `json:"local_base_path"`
+	ID                   string                `json:"id"`
+	Target               string                `json:"target,omitempty"`
+	Environment          string                `json:"environment,omitempty"`
+	Token                string                `json:"token,omitempty"`
+	MaxRPS               float64               `json:"max_rps,omitempty"`
+	MaxBytesPerSecond    int64                 `json:"max_bytes_per_second,omitempty"`
+	RequestTimeoutSecs   int                   `json:"request_timeout_seconds,omitempty"`
+	IdleTimeoutSecs      int                   `json:"idle_timeout_seconds,omitempty"`
+	HeaderTimeoutSecs    int                   `json:"response_header_timeout_seconds,omitempty"`
+	MaxResponseBodyBytes int64                 `json:"max_response_body_bytes,omitempty"`
+	MaxUploadBytes       int64                 `json:"max_upload_bytes,omitempty"`
+	Retry                *protocol.RetryPolicy `json:"retry,omitempty"`
+	ConnectorID          string                `json:"connector_id,omitempty"`
+	ConnectorSelector    map[string]string     `json:"connector_selector,omitempty"`
+	LocalScheme          string                `json:"local_scheme,omitempty"`
+	LocalHost            string                `json:"local_host,omitempty"`
+	LocalPort            int                   `json:"local_port,omitempty"`
+	LocalSocket          string                `json:"local_socket,omitempty"`
+	LocalTLS             *protocol.LocalTLS    `json:"local_tls,omitempty"`
+	LocalBasePath        string                `json:"local_base_path,omitempty"`
+	ErrorPages           *ErrorPages           `json:"error_pages,omitempty"`
+	CORS                 *CORSPolicy           `json:"cors,omitempty"`
+	Presets              *RoutePresets         `json:"presets,omitempty"`
+	PathRoutes           []PathRoute           `json:"path_routes,omitempty"`
+	Rewrite              *RouteRewrite         `json:"rewrite,omitempty"`
+	ForwardedHeaders     *ForwardedHeaders     `json:"forwarded_headers,omitempty"`
+	Idempotency          *RouteIdempotency     `json:"idempotency,omitempty"`
+	QueueOverflow        *RouteQueueOverflow   `json:"queue_overflow,omitempty"`
+	SyntheticCheck       *RouteSyntheticCheck  `json:"synthetic_check,omitempty"`
+	TLSPassthrough       *TLSPassthrough       `json:"tls_passthrough,omitempty"`
+	Mirror               *RouteMirror          `json:"mirror,omitempty"`
+	Split                *RouteSplit           `json:"split,omitempty"`
+	Middleware           []RouteMiddleware     `json:"middleware,omitempty"`
+	Mock                 *RouteMock            `json:"mock,omitempty"`
+	Capture              *RouteCapture         `json:"capture,omitempty"`
+	WebhookVerification  *WebhookVerification  `json:"webhook_verification,omitempty"`
+	ActiveFrom           *time.Time            `json:"active_from,omitempty"`
+	ExpiresAt            *time.Time            `json:"expires_at,omitempty"`
+	TTL                  string                `json:"ttl,omitempty"`
+	DeleteOnExpiry       bool                  `json:"delete_on_expiry,omitempty"`
 }
 
 type upsertTenantRequest struct {
@@ -137,27 +234,43 @@ type registerRequest struct {
 }
 
 type connectorView struct {
-	ID          string    `json:"id"`
-	TenantID    string    `json:"tenant_id"`
-	Name        string    `json:"name"`
-	Connected   bool      `json:"connected"`
-	AgentID     string    `json:"agent_id,omitempty"`
-	LastSeen    time.Time `json:"last_seen,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	PairCommand string    `json:"pair_command,omitempty"`
+	ID                string            `json:"id"`
+	TenantID          string            `json:"tenant_id"`
+	Name              string            `json:"name"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	MaxBytesPerSecond int64             `json:"max_bytes_per_second,omitempty"`
+	Connected         bool              `json:"connected"`
+	Load              *ConnectorLoad    `json:"load,omitempty"`
+	AgentID           string            `json:"agent_id,omitempty"`
+	AgentVersion      string            `json:"agent_version,omitempty"`
+	ProtocolVersion   int               `json:"protocol_version,omitempty"`
+	Outdated          bool              `json:"outdated,omitempty"`
+	OutdatedReason    string            `json:"outdated_reason,omitempty"`
+	LastSeen          time.Time         `json:"last_seen,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+	PairCommand       string            `json:"pair_command,omitempty"`
 }
 
 type createConnectorRequest struct {
-	ID       string `json:"id"`
-	TenantID string `json:"tenant_id"`
-	Name     string `json:"name"`
+	ID                string            `json:"id"`
+	TenantID          string            `json:"tenant_id"`
+	Name              string            `json:"name"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	MaxBytesPerSecond int64             `json:"max_bytes_per_second,omitempty"`
+}
+
+type updateConnectorRequest struct {
+	Labels            *map[string]string `json:"labels"`
+	MaxBytesPerSecond *int64             `json:"max_bytes_per_second"`
 }
 
 type pairConnectorResponse struct {
 	Connector connectorView `json:"connector"`
 	PairToken PairToken     `json:"pair_token"`
 	Command   string        `json:"command"`
+	DeepLink  string        `json:"deep_link"`
+	QRCode    *pairQRCode   `json:"qr_code,omitempty"`
 }
 
 type resolvedProxyPath struct {
@@ -170,31 +283,7 @@ func NewServer(cfg Config, logger *log.Logger) *Server {
 	if logger == nil {
 		logger = log.New(io.Discard, "", 0)
 	}
-	if cfg.MaxRequestBodyBytes <= 0 {
-		cfg.MaxRequestBodyBytes = 10 << 20
-	}
-	if cfg.MaxResponseBodyBytes <= 0 {
-		cfg.MaxResponseBodyBytes = 20 << 20
-	}
-	if cfg.ProxyRequestTimeout <= 0 {
-		if cfg.RequestTimeout > 0 {
-			cfg.ProxyRequestTimeout = cfg.RequestTimeout
-		} else {
-			cfg.ProxyRequestTimeout = 30 * time.Second
-		}
-	}
-	if cfg.MaxPendingPerSession <= 0 {
-		cfg.MaxPendingPerSession = 1024
-	}
-	if cfg.MaxPendingGlobal <= 0 {
-		cfg.MaxPendingGlobal = 10000
-	}
-	if cfg.PublicSignupRPM <= 0 {
-		cfg.PublicSignupRPM = 30
-	}
-	if cfg.PublicDownloadCacheTTL <= 0 {
-		cfg.PublicDownloadCacheTTL = 15 * time.Minute
-	}
+	cfg = normalizeConfig(cfg)
 
 	superAdminUser := strings.TrimSpace(cfg.SuperAdminUsername)
 	if superAdminUser == "" {
@@ -221,34 +310,70 @@ func NewServer(cfg Config, logger *log.Logger) *Server {
 		MaxIdleConns:        200,
 		MaxIdleConnsPerHost: 100,
 		IdleConnTimeout:     90 * time.Second,
+		ForceAttemptHTTP2:   true,
 	}
 	persistence, err := storepkg.NewSnapshotStore(cfg.StorageDriver, cfg.SQLitePath)
 	if err != nil {
 		panic(fmt.Errorf("initialize state persistence: %w", err))
 	}
+	sessionStore, err := storepkg.NewSessionStore(cfg.SessionStore, cfg.SQLitePath, cfg.RedisURL, persistence)
+	if err != nil {
+		panic(fmt.Errorf("initialize session store: %w", err))
+	}
+	authStore.SetSessionStore(sessionStore)
+
+	namePolicy, err := NewNamePolicy(cfg.ReservedNames, cfg.BlockedNamePatterns)
+	if err != nil {
+		panic(fmt.Errorf("initialize name policy: %w", err))
+	}
+	ruleStore := NewRuleStore()
+	ruleStore.SetNamePolicy(namePolicy)
 
+	incidentStore := NewIncidentStore()
 	server := &Server{
 		cfg:             cfg,
 		logger:          logger,
 		hub:             hub,
-		ruleStore:       NewRuleStore(),
+		ruleStore:       ruleStore,
 		authStore:       authStore,
 		connectorStore:  NewConnectorStore(cfg.PairTokenTTL),
 		planStore:       NewPlanStore(),
-		rateLimiter:     NewRateLimiter(),
-		incidentStore:   NewIncidentStore(),
+		rateLimiter:     newRateLimitBackend(cfg, logger),
+		incidentStore:   incidentStore,
+		auditStore:      NewAuditStore(),
+		namePolicy:      namePolicy,
+		ipBans:          NewIPBanList(),
+		webhooks:        NewWebhookNotifier(cfg.WebhookURL, logger, incidentStore),
+		events:          NewEventBus(),
 		funnelAnalytics: NewFunnelAnalyticsStore(),
 		tlsStore:        NewTLSStore(cfg.TLSKeyEncryptionKey),
+		domainStore:     NewDomainStore(),
+		orgStore:        NewOrgStore(),
+		idempotency:     NewIdempotencyStore(),
+		captures:        NewCaptureStore(),
+		reverseForwards: NewReverseForwardStore(),
+		sshKeys:         NewSSHKeyStore(),
+		sshTunnels:      newSSHTunnels(),
+		synthetic:       NewSyntheticMonitor(),
+		bandwidth:       NewBandwidthLimiters(),
+		transfer:        NewTransferStore(),
+		tenantArchives:  NewTenantArchiveStore(),
+		trash:           NewTrashStore(),
+		domainResolver:  net.DefaultResolver,
 		downloads:       NewGitHubReleaseDownloadsProvider(cfg),
 		persistence:     persistence,
 		forwardHTTP: &http.Client{
 			Transport: transport,
 		},
-		maxRequestBodyBytes:  cfg.MaxRequestBodyBytes,
-		maxResponseBodyBytes: cfg.MaxResponseBodyBytes,
-		startedAt:            time.Now().UTC(),
+		mirrorSlots: make(chan struct{}, maxInFlightMirrors),
+		startedAt:   time.Now().UTC(),
 	}
 
+	server.connectorStore.SetSecretPolicy(cfg.ConnectorSecretTTL, cfg.ConnectorSecretGrace)
+	server.notifier = newUserNotifier(server.sendSMTPMail)
+	server.webhooks.SetObserver(server.notifyUsers)
+	server.applyFaultInjection(cfg)
+
 	if err := server.restorePersistentState(); err != nil {
 		panic(fmt.Errorf("restore persisted state: %w", err))
 	}
@@ -260,71 +385,87 @@ func NewServer(cfg Config, logger *log.Logger) *Server {
 	return server
 }
 
+// normalizeConfig fills in defaults for limits left unset, e.g. by tests that
+// build a Config literal instead of loading one.
+func normalizeConfig(cfg Config) Config {
+	if cfg.MaxRequestBodyBytes <= 0 {
+		cfg.MaxRequestBodyBytes = 10 << 20
+	}
+	if cfg.MaxResponseBodyBytes <= 0 {
+		cfg.MaxResponseBodyBytes = 20 << 20
+	}
+	if cfg.ProxyRequestTimeout <= 0 {
+		if cfg.RequestTimeout > 0 {
+			cfg.ProxyRequestTimeout = cfg.RequestTimeout
+		} else {
+			cfg.ProxyRequestTimeout = 30 * time.Second
+		}
+	}
+	if cfg.MaxPendingPerSession <= 0 {
+		cfg.MaxPendingPerSession = 1024
+	}
+	if cfg.MaxPendingGlobal <= 0 {
+		cfg.MaxPendingGlobal = 10000
+	}
+	if cfg.PublicSignupRPM <= 0 {
+		cfg.PublicSignupRPM = 30
+	}
+	if cfg.AuthRateLimitRPM <= 0 {
+		cfg.AuthRateLimitRPM = 20
+	}
+	if cfg.TLSExpiryWarningDays <= 0 {
+		cfg.TLSExpiryWarningDays = 14
+	}
+	if cfg.PublicDownloadCacheTTL <= 0 {
+		cfg.PublicDownloadCacheTTL = 15 * time.Minute
+	}
+	if cfg.ProxyIPBanDuration <= 0 {
+		cfg.ProxyIPBanDuration = 15 * time.Minute
+	}
+	if cfg.UsageWarningPercents == nil {
+		cfg.UsageWarningPercents = []int{80, 95}
+	}
+	return cfg
+}
+
 func (s *Server) Start(ctx context.Context) error {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.handleFrontend)
-	mux.HandleFunc("/api/auth/login", s.handleAuthLogin)
-	mux.HandleFunc("/api/auth/logout", s.handleAuthLogout)
-	mux.HandleFunc("/api/auth/me", s.handleAuthMe)
-	mux.HandleFunc("/api/auth/register", s.handleAuthRegister)
-	mux.HandleFunc("/api/health", s.handleHealth)
-	mux.HandleFunc("/api/public/plans", s.handlePublicPlans)
-	mux.HandleFunc("/api/public/downloads", s.handlePublicDownloads)
-	mux.HandleFunc("/api/public/signup", s.handlePublicSignup)
-	mux.HandleFunc("/api/public/events", s.handlePublicAnalyticsEvent)
-	mux.HandleFunc("/api/me/dashboard", s.handleMeDashboard)
-	mux.HandleFunc("/api/me/routes", s.handleMeRoutes)
-	mux.HandleFunc("/api/me/connectors", s.handleMeConnectors)
-	mux.HandleFunc("/api/me/usage", s.handleMeUsage)
-	mux.HandleFunc("/api/admin/users", s.handleAdminUsers)
-	mux.HandleFunc("/api/admin/users/", s.handleAdminUserByID)
-	mux.HandleFunc("/api/admin/stats", s.handleAdminStats)
-	mux.HandleFunc("/api/admin/incidents", s.handleAdminIncidents)
-	mux.HandleFunc("/api/admin/system-status", s.handleAdminSystemStatus)
-	mux.HandleFunc("/api/admin/analytics/funnel", s.handleAdminFunnelAnalytics)
-	mux.HandleFunc("/api/admin/plans", s.handleAdminPlans)
-	mux.HandleFunc("/api/admin/plans/", s.handleAdminPlanByID)
-	mux.HandleFunc("/api/admin/tenants/", s.handleAdminTenantsSubresource)
-	mux.HandleFunc("/api/admin/tls/certificates", s.handleAdminTLSCertificates)
-	mux.HandleFunc("/api/admin/tls/certificates/", s.handleAdminTLSCertificateByID)
-	mux.HandleFunc("/api/tunnels", s.handleTunnels)
-	mux.HandleFunc("/api/connectors", s.handleConnectors)
-	mux.HandleFunc("/api/connectors/", s.handleConnectorByID)
-	mux.HandleFunc("/api/tenants", s.handleTenants)
-	mux.HandleFunc("/api/tenants/", s.handleTenantSubresources)
-	// Backward-compatible default-tenant endpoints.
-	mux.HandleFunc("/api/rules", s.handleRules)
-	mux.HandleFunc("/api/rules/", s.handleRuleByID)
-	mux.HandleFunc("/api/agent/pair", s.handleAgentPair)
-	mux.HandleFunc("/api/agent/register", s.handleAgentRegister)
-	mux.HandleFunc("/api/agent/pull", s.handleAgentPull)
-	mux.HandleFunc("/api/agent/respond", s.handleAgentRespond)
-	mux.HandleFunc("/api/agent/heartbeat", s.handleAgentHeartbeat)
-	mux.HandleFunc("/t/", s.handleProxy)
+	cfg := s.config()
+	publicMux, adminMux, agentMux := s.buildListenerMuxes(cfg)
+	publicHandler := s.withCustomDomains(s.withListenerMiddleware(publicMux))
+
+	go s.runPersistenceLoop(ctx)
+	go s.runRouteExpiryLoop(ctx)
+	go s.runTLSExpiryLoop(ctx)
+	go s.runConnectorSecretLoop(ctx)
+	go s.runSyntheticCheckLoop(ctx)
+	go s.runRetentionLoop(ctx)
+	go s.runEventLoop(ctx)
+	go s.runNotificationDigestLoop(ctx)
 
 	s.httpServer = &http.Server{
-		Addr:              s.cfg.ListenAddr,
-		Handler:           mux,
+		Addr:              cfg.ListenAddr,
+		Handler:           publicHandler,
 		ReadHeaderTimeout: 10 * time.Second,
+		Protocols:         listenerProtocols(cfg.HTTP2Enabled),
 	}
-	go s.runPersistenceLoop(ctx)
-
-	listener, err := net.Listen("tcp", s.cfg.ListenAddr)
+	if strings.TrimSpace(cfg.SSHListenAddr) != "" {
+		if err := s.startSSH(ctx, cfg); err != nil {
+			return fmt.Errorf("ssh listener: %w", err)
+		}
+	}
+	listener, err := net.Listen("tcp", cfg.ListenAddr)
 	if err != nil {
-		return fmt.Errorf("listen on %s: %w", s.cfg.ListenAddr, err)
+		return fmt.Errorf("listen on %s: %w", cfg.ListenAddr, err)
 	}
 	s.listener = listener
 
-	errCh := make(chan error, 2)
-	go func() {
-		if serveErr := s.httpServer.Serve(listener); serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
-			errCh <- fmt.Errorf("serve gateway: %w", serveErr)
-		}
-	}()
+	errCh := make(chan error, 4)
+	go serveListener("gateway", s.httpServer, listener, errCh)
 
-	if strings.TrimSpace(s.cfg.TLSListenAddr) != "" {
+	if strings.TrimSpace(cfg.TLSListenAddr) != "" {
 		tlsConfig := &tls.Config{
 			MinVersion: tls.VersionTLS12,
+			NextProtos: listenerNextProtos(cfg.HTTP2Enabled),
 			GetCertificate: func(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
 				serverName := ""
 				if info != nil {
@@ -334,33 +475,59 @@ func (s *Server) Start(ctx context.Context) error {
 			},
 		}
 		s.tlsServer = &http.Server{
-			Addr:              s.cfg.TLSListenAddr,
-			Handler:           mux,
+			Addr:              cfg.TLSListenAddr,
+			Handler:           publicHandler,
 			ReadHeaderTimeout: 10 * time.Second,
 			TLSConfig:         tlsConfig,
+			Protocols:         listenerProtocols(cfg.HTTP2Enabled),
 		}
-		rawTLSListener, tlsErr := net.Listen("tcp", s.cfg.TLSListenAddr)
+		rawTLSListener, tlsErr := net.Listen("tcp", cfg.TLSListenAddr)
 		if tlsErr != nil {
-			return fmt.Errorf("listen on tls addr %s: %w", s.cfg.TLSListenAddr, tlsErr)
+			return fmt.Errorf("listen on tls addr %s: %w", cfg.TLSListenAddr, tlsErr)
 		}
-		s.tlsListener = tls.NewListener(rawTLSListener, tlsConfig)
-		go func() {
-			if serveErr := s.tlsServer.Serve(s.tlsListener); serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
-				errCh <- fmt.Errorf("serve tls gateway: %w", serveErr)
-			}
-		}()
+		s.tlsListener = tls.NewListener(s.newPassthroughListener(rawTLSListener), tlsConfig)
+		go serveListener("tls gateway", s.tlsServer, s.tlsListener, errCh)
+	}
+
+	if adminMux != nil {
+		server, listener, listenErr := listenDedicated(cfg.AdminListenAddr, cfg.AdminTLSCertFile, cfg.AdminTLSKeyFile, cfg.HTTP2Enabled, s.withListenerMiddleware(adminMux))
+		if listenErr != nil {
+			return fmt.Errorf("admin listener: %w", listenErr)
+		}
+		s.adminServer, s.adminListener = server, listener
+		go serveListener("admin", server, listener, errCh)
+	}
+	if agentMux != nil {
+		server, listener, listenErr := listenDedicated(cfg.AgentListenAddr, cfg.AgentTLSCertFile, cfg.AgentTLSKeyFile, cfg.HTTP2Enabled, s.withListenerMiddleware(agentMux))
+		if listenErr != nil {
+			return fmt.Errorf("agent listener: %w", listenErr)
+		}
+		s.agentServer, s.agentListener = server, listener
+		go serveListener("agent", server, listener, errCh)
 	}
 
 	select {
 	case <-ctx.Done():
+		s.events.Close()
+		if failed := s.hub.Close(); failed > 0 {
+			s.logger.Printf("failed %d pending proxy requests on shutdown", failed)
+		}
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		if shutdownErr := s.httpServer.Shutdown(shutdownCtx); shutdownErr != nil {
-			return fmt.Errorf("shutdown gateway: %w", shutdownErr)
-		}
-		if s.tlsServer != nil {
-			if shutdownErr := s.tlsServer.Shutdown(shutdownCtx); shutdownErr != nil {
-				return fmt.Errorf("shutdown tls gateway: %w", shutdownErr)
+		for _, server := range []struct {
+			name   string
+			server *http.Server
+		}{
+			{"gateway", s.httpServer},
+			{"tls gateway", s.tlsServer},
+			{"admin", s.adminServer},
+			{"agent", s.agentServer},
+		} {
+			if server.server == nil {
+				continue
+			}
+			if shutdownErr := server.server.Shutdown(shutdownCtx); shutdownErr != nil {
+				return fmt.Errorf("shutdown %s: %w", server.name, shutdownErr)
 			}
 		}
 		select {
@@ -379,13 +546,123 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 }
 
+// buildListenerMuxes splits the routes across listeners. The public mux always
+// serves /t/ and, unless a dedicated listener is configured, the console/API
+// and agent routes too; admin and agent are nil when not split out.
+func (s *Server) buildListenerMuxes(cfg Config) (public, admin, agent *http.ServeMux) {
+	public = http.NewServeMux()
+	public.HandleFunc("/api/health", s.handleHealth)
+	public.HandleFunc("/t/", s.handleProxy)
+
+	admin = public
+	if strings.TrimSpace(cfg.AdminListenAddr) != "" {
+		admin = http.NewServeMux()
+		admin.HandleFunc("/api/health", s.handleHealth)
+	}
+	s.registerConsoleRoutes(admin)
+
+	agent = public
+	if strings.TrimSpace(cfg.AgentListenAddr) != "" {
+		agent = http.NewServeMux()
+		agent.HandleFunc("/api/health", s.handleHealth)
+	}
+	s.registerAgentRoutes(agent)
+
+	if admin == public {
+		admin = nil
+	}
+	if agent == public {
+		agent = nil
+	}
+	return public, admin, agent
+}
+
+type routeRegistrar interface {
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+// registerConsoleRoutes adds the web console, auth, tenant and admin APIs.
+// New /api/ endpoints also need an entry in managementAPI.
+func (s *Server) registerConsoleRoutes(mux routeRegistrar) {
+	mux.HandleFunc("/", s.handleFrontend)
+	mux.HandleFunc("/api/auth/login", s.handleAuthLogin)
+	mux.HandleFunc("/api/auth/logout", s.handleAuthLogout)
+	mux.HandleFunc("/api/auth/me", s.handleAuthMe)
+	mux.HandleFunc("/api/auth/register", s.handleAuthRegister)
+	mux.HandleFunc("/api/openapi.json", s.handleOpenAPI)
+	mux.HandleFunc("/api/docs", s.handleAPIDocs)
+	mux.HandleFunc("/metrics", s.handlePrometheusMetrics)
+	mux.HandleFunc("/api/events", s.handleEvents)
+	mux.HandleFunc("/api/public/plans", s.handlePublicPlans)
+	mux.HandleFunc("/api/public/downloads", s.handlePublicDownloads)
+	mux.HandleFunc("/api/public/signup", s.handlePublicSignup)
+	mux.HandleFunc("/api/public/events", s.handlePublicAnalyticsEvent)
+	mux.HandleFunc("/api/public/unsubscribe", s.handlePublicUnsubscribe)
+	mux.HandleFunc("/api/me/dashboard", s.handleMeDashboard)
+	mux.HandleFunc("/api/me/routes", s.handleMeRoutes)
+	mux.HandleFunc("/api/me/connectors", s.handleMeConnectors)
+	mux.HandleFunc("/api/me/usage", s.handleMeUsage)
+	mux.HandleFunc("/api/me/plan", s.handleMePlan)
+	mux.HandleFunc("/api/me/notifications", s.handleMeNotifications)
+	mux.HandleFunc("/api/admin/users", s.handleAdminUsers)
+	mux.HandleFunc("/api/admin/users/", s.handleAdminUserByID)
+	mux.HandleFunc("/api/admin/stats", s.handleAdminStats)
+	mux.HandleFunc("/api/admin/incidents", s.handleAdminIncidents)
+	mux.HandleFunc("/api/admin/audit", s.handleAdminAudit)
+	mux.HandleFunc("/api/admin/backup", s.handleAdminBackup)
+	mux.HandleFunc("/api/admin/restore", s.handleAdminRestore)
+	mux.HandleFunc("/api/admin/config/reload", s.handleAdminConfigReload)
+	mux.HandleFunc("/api/admin/ip-bans", s.handleAdminIPBans)
+	mux.HandleFunc("/api/admin/ip-bans/", s.handleAdminIPBanByIP)
+	mux.HandleFunc("/api/admin/system-status", s.handleAdminSystemStatus)
+	mux.HandleFunc("/api/admin/analytics/funnel", s.handleAdminFunnelAnalytics)
+	mux.HandleFunc("/api/admin/plans", s.handleAdminPlans)
+	mux.HandleFunc("/api/admin/plans/", s.handleAdminPlanByID)
+	mux.HandleFunc("/api/admin/tenants/", s.handleAdminTenantsSubresource)
+	mux.HandleFunc("/api/admin/tls/certificates", s.handleAdminTLSCertificates)
+	mux.HandleFunc("/api/admin/tls/certificates/", s.handleAdminTLSCertificateByID)
+	mux.HandleFunc("/api/admin/tls/expiring", s.handleAdminTLSExpiring)
+	mux.HandleFunc("/api/tunnels", s.handleTunnels)
+	mux.HandleFunc("/api/connectors", s.handleConnectors)
+	mux.HandleFunc("/api/connectors/", s.handleConnectorByID)
+	mux.HandleFunc("/api/orgs", s.handleOrgs)
+	mux.HandleFunc("/api/orgs/", s.handleOrgByID)
+	mux.HandleFunc("/api/tenants", s.handleTenants)
+	mux.HandleFunc("/api/tenants/", s.handleTenantSubresources)
+	// Backward-compatible default-tenant endpoints.
+	mux.HandleFunc("/api/rules", s.handleRules)
+	mux.HandleFunc("/api/rules/", s.handleRuleByID)
+}
+
+func (s *Server) registerAgentRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/agent/pair", s.handleAgentPair)
+	mux.HandleFunc("/api/agent/register", s.handleAgentRegister)
+	mux.HandleFunc("/api/agent/resume", s.handleAgentResume)
+	mux.HandleFunc("/api/agent/rotate", s.handleAgentRotate)
+	mux.HandleFunc("/api/agent/pull", s.handleAgentPull)
+	mux.HandleFunc("/api/agent/cancel", s.handleAgentCancel)
+	mux.HandleFunc("/api/agent/upload", s.handleAgentUpload)
+	mux.HandleFunc("/api/agent/respond", s.handleAgentRespond)
+	mux.HandleFunc("/api/agent/heartbeat", s.handleAgentHeartbeat)
+	mux.HandleFunc("/api/agent/stream", s.handleAgentStream)
+	mux.HandleFunc("/api/agent/forwards", s.handleAgentForwards)
+	mux.HandleFunc("/api/agent/forwards/open", s.handleAgentForwardOpen)
+}
+
 func (s *Server) Addr() string {
 	if s.listener == nil {
-		return s.cfg.ListenAddr
+		return s.config().ListenAddr
 	}
 	return s.listener.Addr().String()
 }
 
+func (s *Server) TLSAddr() string {
+	if s.tlsListener == nil {
+		return s.config().TLSListenAddr
+	}
+	return s.tlsListener.Addr().String()
+}
+
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
@@ -1386,7 +1663,7 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
@@ -1397,13 +1674,13 @@ func (s *Server) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
 
 	user, ok := s.authStore.Authenticate(request.Username, request.Password)
 	if !ok {
-		http.Error(w, "invalid username or password", http.StatusUnauthorized)
+		writeAPIError(w, http.StatusUnauthorized, errCodeInvalidCredentials, "invalid username or password")
 		return
 	}
 
 	sessionID, err := s.authStore.NewSession(user.Username)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("create session: %v", err), http.StatusInternalServerError)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, fmt.Sprintf("create session: %v", err))
 		return
 	}
 
@@ -1416,12 +1693,25 @@ func (s *Server) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleAuthLogout(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	if cookie, err := r.Cookie(sessionCookieName); err == nil {
-		s.authStore.DeleteSession(cookie.Value)
+	if sessionID := sessionTokenFromRequest(r); sessionID != "" {
+		if !s.checkCSRF(w, r, sessionID) {
+			return
+		}
+		if !s.allowSessionLookup(r, sessionID) {
+			writeSessionLookupLimited(w)
+			return
+		}
+		if user, impersonation, ok := s.authStore.ResolveSession(sessionID); ok && impersonation != nil {
+			s.auditStore.Record(impersonation.ImpersonatedBy, "impersonation.end", user.TenantID, map[string]string{
+				"username": user.Username,
+			})
+			s.persistState()
+		}
+		s.authStore.DeleteSession(sessionID)
 	}
 	s.clearSessionCookie(w)
 	writeJSON(w, http.StatusOK, map[string]any{"message": "logged out"})
@@ -1429,24 +1719,28 @@ func (s *Server) handleAuthLogout(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleAuthMe(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	user, ok := s.requireAuth(w, r)
+	user, impersonation, ok := s.requireSession(w, r)
 	if !ok {
 		return
 	}
 	tenants := s.filterTenantsForUser(user)
-	writeJSON(w, http.StatusOK, map[string]any{
+	payload := map[string]any{
 		"user":    user,
 		"tenants": tenants,
-	})
+	}
+	if impersonation != nil {
+		payload["impersonation"] = impersonation
+	}
+	writeJSON(w, http.StatusOK, payload)
 }
 
 func (s *Server) handleAuthRegister(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
@@ -1457,12 +1751,12 @@ func (s *Server) handleAuthRegister(w http.ResponseWriter, r *http.Request) {
 
 	tenantID := strings.TrimSpace(request.TenantID)
 	if tenantID == "" {
-		http.Error(w, "tenant_id is required", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "tenant_id is required")
 		return
 	}
 	if !s.ruleStore.HasTenant(tenantID) {
 		if _, err := s.ruleStore.UpsertTenant(Tenant{ID: tenantID, Name: request.TenantName}); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 			return
 		}
 		s.refreshTenantUsage(tenantID)
@@ -1475,7 +1769,7 @@ func (s *Server) handleAuthRegister(w http.ResponseWriter, r *http.Request) {
 		Role:     RoleMember,
 	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -1512,11 +1806,15 @@ func (s *Server) handleTunnels(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	page, ok := paginateList(w, r, filtered, "id")
+	if !ok {
+		return
+	}
 	payload := map[string]any{
 		"generated_at": time.Now().UTC().Format(time.RFC3339),
-		"tunnels":      filtered,
+		"tunnels":      page.Items,
 	}
-	writeJSON(w, http.StatusOK, payload)
+	writeListPage(w, payload, page.Total, page.NextCursor)
 }
 
 func (s *Server) handleConnectors(w http.ResponseWriter, r *http.Request) {
@@ -1527,10 +1825,14 @@ func (s *Server) handleConnectors(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
-		writeJSON(w, http.StatusOK, map[string]any{
+		page, ok := paginateList(w, r, s.buildConnectorViewsForUser(user), "id")
+		if !ok {
+			return
+		}
+		writeListPage(w, map[string]any{
 			"generated_at": time.Now().UTC().Format(time.RFC3339),
-			"connectors":   s.buildConnectorViewsForUser(user),
-		})
+			"connectors":   page.Items,
+		}, page.Total, page.NextCursor)
 	case http.MethodPost:
 		var request createConnectorRequest
 		if !s.decodeJSON(w, r, &request, "connector payload") {
@@ -1545,29 +1847,49 @@ func (s *Server) handleConnectors(w http.ResponseWriter, r *http.Request) {
 			tenantID = DefaultTenantID
 		}
 		if !s.canAccessTenant(user, tenantID) {
-			http.Error(w, "forbidden tenant access", http.StatusForbidden)
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden tenant access")
 			return
 		}
 		if !s.canMutateTenant(user, tenantID) {
-			http.Error(w, "forbidden tenant access", http.StatusForbidden)
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden tenant access")
 			return
 		}
 		if !s.ruleStore.HasTenant(tenantID) {
-			http.Error(w, "tenant not found", http.StatusNotFound)
+			writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
+			return
+		}
+		if tenant, inactive := s.tenantInactive(tenantID); inactive {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantSuspended, fmt.Sprintf("tenant %q is %s", tenant.ID, tenant.status()))
 			return
 		}
 		if err := s.enforceConnectorLimit(tenantID); err != nil {
-			http.Error(w, err.Error(), http.StatusForbidden)
+			writeAPIError(w, http.StatusForbidden, errCodePlanLimitExceeded, err.Error())
 			return
 		}
 
-		connector, err := s.connectorStore.Create(Connector{
-			ID:       request.ID,
-			TenantID: tenantID,
-			Name:     request.Name,
-		})
+		input := Connector{
+			ID:                request.ID,
+			TenantID:          tenantID,
+			Name:              request.Name,
+			Labels:            request.Labels,
+			MaxBytesPerSecond: request.MaxBytesPerSecond,
+		}
+		if parseBoolQuery(r, "dry_run") {
+			candidate, err := s.connectorStore.ValidateCreate(input)
+			if err != nil {
+				writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{
+				"message":   "connector valid",
+				"dry_run":   true,
+				"connector": s.buildConnectorView(candidate),
+			})
+			return
+		}
+		connector, err := s.connectorStore.Create(input)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 			return
 		}
 
@@ -1578,7 +1900,7 @@ func (s *Server) handleConnectors(w http.ResponseWriter, r *http.Request) {
 		s.refreshTenantUsage(tenantID)
 		s.persistState()
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 	}
 }
 
@@ -1590,32 +1912,81 @@ func (s *Server) handleConnectorByID(w http.ResponseWriter, r *http.Request) {
 
 	connectorID, action, err := parseConnectorPath(r.URL.Path)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
 	connector, ok := s.connectorStore.Get(connectorID)
 	if !ok {
-		http.Error(w, "connector not found", http.StatusNotFound)
+		writeAPIError(w, http.StatusNotFound, errCodeConnectorNotFound, "connector not found")
 		return
 	}
 	if !s.canAccessTenant(user, connector.TenantID) {
-		http.Error(w, "forbidden connector access", http.StatusForbidden)
+		writeAPIError(w, http.StatusForbidden, errCodeConnectorAccessDenied, "forbidden connector access")
 		return
 	}
 
 	switch action {
 	case "":
-		if r.Method != http.MethodDelete {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		if r.Method != http.MethodDelete && r.Method != http.MethodPatch {
+			writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 			return
 		}
 		if !s.canMutateTenant(user, connector.TenantID) {
-			http.Error(w, "forbidden connector access", http.StatusForbidden)
+			writeAPIError(w, http.StatusForbidden, errCodeConnectorAccessDenied, "forbidden connector access")
+			return
+		}
+		if r.Method == http.MethodPatch {
+			var request updateConnectorRequest
+			if !s.decodeJSON(w, r, &request, "connector payload") {
+				return
+			}
+			if request.MaxBytesPerSecond != nil {
+				if err := validateBandwidthLimit("max_bytes_per_second", *request.MaxBytesPerSecond); err != nil {
+					writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+					return
+				}
+			}
+			if parseBoolQuery(r, "dry_run") {
+				candidate := connector
+				if request.Labels != nil {
+					if candidate.Labels, err = normalizeConnectorLabels("labels", *request.Labels); err != nil {
+						writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+						return
+					}
+				}
+				if request.MaxBytesPerSecond != nil {
+					candidate.MaxBytesPerSecond = *request.MaxBytesPerSecond
+				}
+				writeJSON(w, http.StatusOK, map[string]any{
+					"message":   "connector valid",
+					"dry_run":   true,
+					"connector": s.buildConnectorView(candidate),
+				})
+				return
+			}
+			updated := connector
+			if request.Labels != nil {
+				if updated, err = s.connectorStore.SetLabels(connectorID, *request.Labels); err != nil {
+					writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+					return
+				}
+			}
+			if request.MaxBytesPerSecond != nil {
+				if updated, err = s.connectorStore.SetBandwidthLimit(connectorID, *request.MaxBytesPerSecond); err != nil {
+					writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+					return
+				}
+			}
+			writeJSON(w, http.StatusOK, map[string]any{
+				"message":   "connector updated",
+				"connector": s.buildConnectorView(updated),
+			})
+			s.persistState()
 			return
 		}
-		if ok := s.connectorStore.Delete(connectorID); !ok {
-			http.Error(w, "connector not found", http.StatusNotFound)
+		if ok := s.trashConnector(connectorID, user.Username); !ok {
+			writeAPIError(w, http.StatusNotFound, errCodeConnectorNotFound, "connector not found")
 			return
 		}
 		s.refreshTenantUsage(connector.TenantID)
@@ -1623,37 +1994,83 @@ func (s *Server) handleConnectorByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNoContent)
 	case "pair":
 		if r.Method != http.MethodPost {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 			return
 		}
 		if !s.canMutateTenant(user, connector.TenantID) {
-			http.Error(w, "forbidden connector access", http.StatusForbidden)
+			writeAPIError(w, http.StatusForbidden, errCodeConnectorAccessDenied, "forbidden connector access")
 			return
 		}
 		pairToken, err := s.connectorStore.NewPairToken(connectorID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 			return
 		}
-		command := fmt.Sprintf("PROXER_GATEWAY_BASE_URL=%s PROXER_AGENT_PAIR_TOKEN=%s proxer-agent",
-			strings.TrimRight(s.cfg.PublicBaseURL, "/"), pairToken.Token)
+		command := s.pairCommand(pairToken.Token)
+		deepLink := protocol.PairLink(s.agentBaseURL(), pairToken.Token)
+		qrCode, err := newPairQRCode(deepLink)
+		if err != nil {
+			s.logger.Printf("render pair QR code for connector %s: %v", connectorID, err)
+		}
 		writeJSON(w, http.StatusOK, pairConnectorResponse{
 			Connector: s.buildConnectorView(connector),
 			PairToken: pairToken,
 			Command:   command,
+			DeepLink:  deepLink,
+			QRCode:    qrCode,
 		})
+	case "bootstrap":
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		if !s.canMutateTenant(user, connector.TenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeConnectorAccessDenied, "forbidden connector access")
+			return
+		}
+		shell, err := bootstrapShell(r)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		channel := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("channel")))
+		if channel == "" {
+			channel = releaseChannelStable
+		}
+		if channel != releaseChannelStable && channel != releaseChannelBeta {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("channel must be %q or %q", releaseChannelStable, releaseChannelBeta))
+			return
+		}
+		var downloads []bootstrapDownload
+		if s.downloads != nil {
+			downloads = bootstrapDownloads(s.downloads.ResolveChannel(r.Context(), channel).Downloads, shell)
+		}
+		pairToken, err := s.connectorStore.NewPairToken(connectorID)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		script, err := renderBootstrapScript(shell, newBootstrapScriptData(s.agentBaseURL(), connector, pairToken, downloads))
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, errCodeInternal, "render bootstrap script")
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, script)
 	case "rotate":
 		if r.Method != http.MethodPost {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 			return
 		}
 		if !s.canMutateTenant(user, connector.TenantID) {
-			http.Error(w, "forbidden connector access", http.StatusForbidden)
+			writeAPIError(w, http.StatusForbidden, errCodeConnectorAccessDenied, "forbidden connector access")
 			return
 		}
 		secret, err := s.connectorStore.RotateCredential(connectorID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]any{
@@ -1663,13 +2080,13 @@ func (s *Server) handleConnectorByID(w http.ResponseWriter, r *http.Request) {
 		})
 		s.persistState()
 	default:
-		http.Error(w, "invalid connector path", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid connector path")
 	}
 }
 
 func (s *Server) handleAgentPair(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
@@ -1680,7 +2097,7 @@ func (s *Server) handleAgentPair(w http.ResponseWriter, r *http.Request) {
 
 	connector, secret, err := s.connectorStore.ConsumePairToken(request.PairToken)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -1714,7 +2131,7 @@ func (s *Server) handleTenants(w http.ResponseWriter, r *http.Request) {
 		}
 		tenant, err := s.ruleStore.UpsertTenant(Tenant{ID: request.ID, Name: request.Name})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]any{
@@ -1724,7 +2141,7 @@ func (s *Server) handleTenants(w http.ResponseWriter, r *http.Request) {
 		s.refreshTenantUsage(tenant.ID)
 		s.persistState()
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 	}
 }
 
@@ -1736,7 +2153,7 @@ func (s *Server) handleTenantSubresources(w http.ResponseWriter, r *http.Request
 
 	segments, err := parseTenantSubresourcePath(r.URL.Path)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -1744,16 +2161,20 @@ func (s *Server) handleTenantSubresources(w http.ResponseWriter, r *http.Request
 	case 1:
 		tenantID := segments[0]
 		if r.Method != http.MethodDelete {
-			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 			return
 		}
 		if !s.requireSuperAdmin(w, user) {
 			return
 		}
 		if ok := s.ruleStore.DeleteTenant(tenantID); !ok {
-			http.Error(w, "tenant not found or cannot be deleted", http.StatusNotFound)
+			writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found or cannot be deleted")
 			return
 		}
+		s.domainStore.DeleteTenant(tenantID)
+		s.orgStore.RemoveTenant(tenantID)
+		s.closeReverseForwardStreams(s.reverseForwards.DeleteTenant(tenantID))
+		s.sshTunnels.disconnectKeys(s.sshKeys.DeleteTenant(tenantID)...)
 		s.refreshTenantUsage(tenantID)
 		s.persistState()
 		w.WriteHeader(http.StatusNoContent)
@@ -1761,7 +2182,7 @@ func (s *Server) handleTenantSubresources(w http.ResponseWriter, r *http.Request
 	case 2:
 		tenantID := segments[0]
 		if !s.canAccessTenant(user, tenantID) {
-			http.Error(w, "forbidden tenant access", http.StatusForbidden)
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden tenant access")
 			return
 		}
 		switch segments[1] {
@@ -1771,25 +2192,123 @@ func (s *Server) handleTenantSubresources(w http.ResponseWriter, r *http.Request
 		case "environment":
 			s.handleTenantEnvironment(w, r, user, tenantID)
 			return
+		case "environments":
+			s.handleTenantEnvironments(w, r, tenantID)
+			return
+		case "error-pages":
+			s.handleTenantErrorPages(w, r, user, tenantID)
+			return
+		case "redaction":
+			s.handleTenantRedaction(w, r, user, tenantID)
+			return
+		case "retention":
+			s.handleTenantRetention(w, r, user, tenantID)
+			return
+		case "routes:export":
+			s.handleTenantRoutesExport(w, r, user, tenantID)
+			return
+		case "routes:import":
+			s.handleTenantRoutesImport(w, r, user, tenantID)
+			return
+		case "domains":
+			s.handleTenantDomains(w, r, user, tenantID)
+			return
+		case "sla":
+			s.handleTenantSLA(w, r, tenantID)
+			return
+		case "trash":
+			s.handleTenantTrash(w, r, tenantID)
+			return
+		case "forwards":
+			s.handleTenantForwards(w, r, tenantID)
+			return
+		case "ssh-keys":
+			s.handleTenantSSHKeys(w, r, user, tenantID)
+			return
+		case "metrics", "metrics:reset":
+			s.handleTenantMetrics(w, r, user, tenantID, segments[1])
+			return
 		default:
-			http.Error(w, "invalid tenant subresource path", http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid tenant subresource path")
 			return
 		}
 	case 3:
 		tenantID := segments[0]
 		if !s.canAccessTenant(user, tenantID) {
-			http.Error(w, "forbidden tenant access", http.StatusForbidden)
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden tenant access")
 			return
 		}
-		if segments[1] != "routes" {
-			http.Error(w, "invalid tenant subresource path", http.StatusBadRequest)
-			return
+		switch segments[1] {
+		case "routes":
+			s.handleTenantRouteByID(w, r, user, tenantID, segments[2])
+		case "domains":
+			s.handleTenantDomainByHost(w, r, user, tenantID, segments[2], "")
+		case "environments":
+			s.handleTenantEnvironmentByName(w, r, user, tenantID, segments[2])
+		case "forwards":
+			s.handleTenantForwardByID(w, r, user, tenantID, segments[2], "")
+		case "ssh-keys":
+			s.handleTenantSSHKeyByID(w, r, user, tenantID, segments[2])
+		default:
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid tenant subresource path")
 		}
-		routeID := segments[2]
-		s.handleTenantRouteByID(w, r, user, tenantID, routeID)
+		return
+	case 4:
+		tenantID := segments[0]
+		if !s.canAccessTenant(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden tenant access")
+			return
+		}
+		switch {
+		case segments[1] == "routes" && segments[3] == "timeseries":
+			s.handleRouteTimeseries(w, r, tenantID, segments[2])
+		case segments[1] == "routes" && segments[3] == "sla":
+			s.handleRouteSLA(w, r, tenantID, segments[2])
+		case segments[1] == "routes" && (segments[3] == "history" || segments[3] == "rollback"):
+			s.handleRouteHistory(w, r, user, tenantID, segments[2], segments[3])
+		case segments[1] == "routes" && (segments[3] == "captures" || segments[3] == "captures:har"):
+			s.handleRouteCaptures(w, r, user, tenantID, segments[2], segments[3])
+		case segments[1] == "routes" && (segments[3] == "metrics" || segments[3] == "metrics:reset"):
+			s.handleRouteMetrics(w, r, user, tenantID, segments[2], segments[3])
+		case segments[1] == "domains" && segments[3] == "verify":
+			s.handleTenantDomainByHost(w, r, user, tenantID, segments[2], "verify")
+		case segments[1] == "trash":
+			s.handleTenantTrashItem(w, r, user, tenantID, segments[2], segments[3], "")
+		case segments[1] == "forwards":
+			s.handleTenantForwardByID(w, r, user, tenantID, segments[2], segments[3])
+		default:
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid tenant subresource path")
+		}
+		return
+	case 5:
+		tenantID := segments[0]
+		if !s.canAccessTenant(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden tenant access")
+			return
+		}
+		switch {
+		case segments[1] == "trash":
+			s.handleTenantTrashItem(w, r, user, tenantID, segments[2], segments[3], segments[4])
+		case segments[1] == "routes" && segments[3] == "captures":
+			s.handleRouteCaptureByID(w, r, tenantID, segments[2], segments[4], "")
+		default:
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid tenant subresource path")
+		}
+		return
+	case 6:
+		tenantID := segments[0]
+		if !s.canAccessTenant(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden tenant access")
+			return
+		}
+		if segments[1] != "routes" || segments[3] != "captures" {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid tenant subresource path")
+			return
+		}
+		s.handleRouteCaptureByID(w, r, tenantID, segments[2], segments[4], segments[5])
 		return
 	default:
-		http.Error(w, "invalid tenant subresource path", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid tenant subresource path")
 		return
 	}
 }
@@ -1797,107 +2316,102 @@ func (s *Server) handleTenantSubresources(w http.ResponseWriter, r *http.Request
 func (s *Server) handleTenantEnvironment(w http.ResponseWriter, r *http.Request, user User, tenantID string) {
 	tenantID = strings.TrimSpace(tenantID)
 	if tenantID == "" {
-		http.Error(w, "missing tenant id", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing tenant id")
 		return
 	}
-	if !s.ruleStore.HasTenant(tenantID) {
-		http.Error(w, "tenant not found", http.StatusNotFound)
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	s.handleTenantEnvironmentByName(w, r, user, tenantID, DefaultEnvironmentName)
+}
+
+func (s *Server) handleTenantErrorPages(w http.ResponseWriter, r *http.Request, user User, tenantID string) {
+	tenant, ok := s.ruleStore.GetTenant(tenantID)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
 		return
 	}
 
 	switch r.Method {
 	case http.MethodGet:
-		env, ok := s.ruleStore.GetEnvironment(tenantID)
-		if !ok {
-			http.Error(w, "environment not found", http.StatusNotFound)
-			return
-		}
 		writeJSON(w, http.StatusOK, map[string]any{
-			"tenant_id":   tenantID,
-			"environment": env,
+			"tenant_id":   tenant.ID,
+			"error_pages": tenant.ErrorPages,
 		})
 	case http.MethodPut:
 		if !s.canMutateTenantConfig(user, tenantID) {
-			http.Error(w, "forbidden tenant configuration access", http.StatusForbidden)
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAdminRequired, "forbidden tenant configuration access")
 			return
 		}
-		var request upsertEnvironmentRequest
-		if !s.decodeJSON(w, r, &request, "environment payload") {
+		var request ErrorPages
+		if !s.decodeJSON(w, r, &request, "error pages payload") {
 			return
 		}
-		env, err := s.ruleStore.UpsertEnvironment(TenantEnvironment{
-			TenantID:    tenantID,
-			Scheme:      request.Scheme,
-			Host:        request.Host,
-			DefaultPort: request.DefaultPort,
-			Variables:   request.Variables,
-		})
+		tenant, err := s.ruleStore.SetTenantErrorPages(tenantID, &request)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]any{
-			"message":     "environment upserted",
-			"tenant_id":   tenantID,
-			"environment": env,
+			"message":     "error pages updated",
+			"tenant_id":   tenant.ID,
+			"error_pages": tenant.ErrorPages,
 		})
 		s.persistState()
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 	}
 }
 
 func (s *Server) handleTenantRoutes(w http.ResponseWriter, r *http.Request, user User, tenantID string) {
 	tenantID = strings.TrimSpace(tenantID)
 	if tenantID == "" {
-		http.Error(w, "missing tenant id", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing tenant id")
 		return
 	}
 	if !s.ruleStore.HasTenant(tenantID) {
-		http.Error(w, "tenant not found", http.StatusNotFound)
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
 		return
 	}
 
 	switch r.Method {
 	case http.MethodGet:
+		page, ok := paginateList(w, r, s.buildRouteViews(tenantID), "id")
+		if !ok {
+			return
+		}
 		payload := map[string]any{
 			"generated_at": time.Now().UTC().Format(time.RFC3339),
 			"tenant_id":    tenantID,
-			"routes":       s.buildRouteViews(tenantID),
+			"routes":       page.Items,
 		}
-		writeJSON(w, http.StatusOK, payload)
+		writeListPage(w, payload, page.Total, page.NextCursor)
 	case http.MethodPost:
 		if !s.canMutateTenant(user, tenantID) {
-			http.Error(w, "forbidden route mutation", http.StatusForbidden)
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden route mutation")
 			return
 		}
 		var request upsertRuleRequest
 		if !s.decodeJSON(w, r, &request, "route payload") {
 			return
 		}
-		if err := s.enforceRouteLimit(tenantID, request.ID); err != nil {
-			http.Error(w, err.Error(), http.StatusForbidden)
+		input, code, err := s.validateRouteRequest(tenantID, request)
+		if err != nil {
+			writeAPIError(w, apiErrorCodes[code].Status, code, err.Error())
 			return
 		}
-		if err := s.validateConnectorRouteBinding(tenantID, request.ConnectorID); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if parseBoolQuery(r, "dry_run") {
+			s.writeRouteDryRun(w, tenantID, input, "route")
 			return
 		}
-		route, err := s.ruleStore.UpsertForTenant(tenantID, Rule{
-			ID:            request.ID,
-			Target:        request.Target,
-			Token:         request.Token,
-			MaxRPS:        request.MaxRPS,
-			ConnectorID:   request.ConnectorID,
-			LocalScheme:   request.LocalScheme,
-			LocalHost:     request.LocalHost,
-			LocalPort:     request.LocalPort,
-			LocalBasePath: request.LocalBasePath,
-		})
+		previous, existed := s.ruleStore.GetForTenant(tenantID, input.ID)
+		route, err := s.ruleStore.UpsertForTenant(tenantID, input)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 			return
 		}
+		s.recordRouteVersion(user.Username, "api", route, optionalRule(previous, existed), 0)
 		s.hub.EnsureTunnelMetric(MakeTunnelKey(route.TenantID, route.ID))
 		writeJSON(w, http.StatusOK, map[string]any{
 			"message": "route upserted",
@@ -1905,27 +2419,29 @@ func (s *Server) handleTenantRoutes(w http.ResponseWriter, r *http.Request, user
 		})
 		s.refreshTenantUsage(tenantID)
 		s.persistState()
+		s.publishRouteUpserted(route)
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 	}
 }
 
 func (s *Server) handleTenantRouteByID(w http.ResponseWriter, r *http.Request, user User, tenantID, routeID string) {
 	if r.Method != http.MethodDelete {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	if !s.canMutateTenant(user, tenantID) {
-		http.Error(w, "forbidden route mutation", http.StatusForbidden)
+		writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden route mutation")
 		return
 	}
 
-	if ok := s.ruleStore.DeleteForTenant(tenantID, routeID); !ok {
-		http.Error(w, "route not found", http.StatusNotFound)
+	if ok := s.trashRoute(tenantID, routeID, user.Username); !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeRouteNotFound, "route not found")
 		return
 	}
 	s.refreshTenantUsage(tenantID)
 	s.persistState()
+	s.publishRouteDeleted(tenantID, routeID, "deleted")
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -1935,7 +2451,7 @@ func (s *Server) handleRules(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if !s.canAccessTenant(user, DefaultTenantID) {
-		http.Error(w, "forbidden tenant access", http.StatusForbidden)
+		writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden tenant access")
 		return
 	}
 
@@ -1949,36 +2465,29 @@ func (s *Server) handleRules(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, payload)
 	case http.MethodPost:
 		if !s.canMutateTenant(user, DefaultTenantID) {
-			http.Error(w, "forbidden route mutation", http.StatusForbidden)
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden route mutation")
 			return
 		}
 		var request upsertRuleRequest
 		if !s.decodeJSON(w, r, &request, "rule payload") {
 			return
 		}
-		if err := s.enforceRouteLimit(DefaultTenantID, request.ID); err != nil {
-			http.Error(w, err.Error(), http.StatusForbidden)
+		input, code, err := s.validateRouteRequest(DefaultTenantID, request)
+		if err != nil {
+			writeAPIError(w, apiErrorCodes[code].Status, code, err.Error())
 			return
 		}
-		if err := s.validateConnectorRouteBinding(DefaultTenantID, request.ConnectorID); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if parseBoolQuery(r, "dry_run") {
+			s.writeRouteDryRun(w, DefaultTenantID, input, "rule")
 			return
 		}
-		rule, err := s.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
-			ID:            request.ID,
-			Target:        request.Target,
-			Token:         request.Token,
-			MaxRPS:        request.MaxRPS,
-			ConnectorID:   request.ConnectorID,
-			LocalScheme:   request.LocalScheme,
-			LocalHost:     request.LocalHost,
-			LocalPort:     request.LocalPort,
-			LocalBasePath: request.LocalBasePath,
-		})
+		previous, existed := s.ruleStore.GetForTenant(DefaultTenantID, input.ID)
+		rule, err := s.ruleStore.UpsertForTenant(DefaultTenantID, input)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 			return
 		}
+		s.recordRouteVersion(user.Username, "api", rule, optionalRule(previous, existed), 0)
 		s.hub.EnsureTunnelMetric(MakeTunnelKey(DefaultTenantID, rule.ID))
 		writeJSON(w, http.StatusOK, map[string]any{
 			"message": "rule upserted",
@@ -1986,8 +2495,9 @@ func (s *Server) handleRules(w http.ResponseWriter, r *http.Request) {
 		})
 		s.refreshTenantUsage(DefaultTenantID)
 		s.persistState()
+		s.publishRouteUpserted(rule)
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 	}
 }
 
@@ -1997,36 +2507,37 @@ func (s *Server) handleRuleByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if !s.canAccessTenant(user, DefaultTenantID) {
-		http.Error(w, "forbidden tenant access", http.StatusForbidden)
+		writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden tenant access")
 		return
 	}
 
 	if r.Method != http.MethodDelete {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 	if !s.canMutateTenant(user, DefaultTenantID) {
-		http.Error(w, "forbidden route mutation", http.StatusForbidden)
+		writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden route mutation")
 		return
 	}
 
 	routeID, err := parseRulePathID(r.URL.Path)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
-	if ok := s.ruleStore.DeleteForTenant(DefaultTenantID, routeID); !ok {
-		http.Error(w, "rule not found", http.StatusNotFound)
+	if ok := s.trashRoute(DefaultTenantID, routeID, user.Username); !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeRouteNotFound, "rule not found")
 		return
 	}
 	s.refreshTenantUsage(DefaultTenantID)
 	s.persistState()
+	s.publishRouteDeleted(DefaultTenantID, routeID, "deleted")
 	w.WriteHeader(http.StatusNoContent)
 }
 
 func (s *Server) handleAgentRegister(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
@@ -2034,6 +2545,9 @@ func (s *Server) handleAgentRegister(w http.ResponseWriter, r *http.Request) {
 	if !s.decodeJSON(w, r, &payload, "register payload") {
 		return
 	}
+	if s.rejectOutdatedAgent(w, &payload) {
+		return
+	}
 
 	var (
 		response *protocol.RegisterResponse
@@ -2042,10 +2556,13 @@ func (s *Server) handleAgentRegister(w http.ResponseWriter, r *http.Request) {
 	connectorID := strings.TrimSpace(payload.ConnectorID)
 	if connectorID != "" {
 		if !s.connectorStore.Authenticate(connectorID, payload.ConnectorSecret) {
-			http.Error(w, "invalid connector credentials", http.StatusUnauthorized)
+			writeAPIError(w, http.StatusUnauthorized, errCodeInvalidCredentials, "invalid connector credentials")
+			return
+		}
+		if !s.requireConnectorTenantActive(w, connectorID) {
 			return
 		}
-		response, err = s.hub.RegisterConnectorSession(connectorID, payload.AgentID)
+		response, err = s.hub.RegisterConnector(&payload)
 	} else {
 		response, err = s.hub.Register(&payload)
 	}
@@ -2054,22 +2571,79 @@ func (s *Server) handleAgentRegister(w http.ResponseWriter, r *http.Request) {
 		if strings.Contains(err.Error(), "token mismatch") {
 			status = http.StatusUnauthorized
 		}
-		http.Error(w, err.Error(), status)
+		writeAPIError(w, status, errorCodeForStatus(status), err.Error())
+		return
+	}
+	s.annotateRegisteredRoutes(response, connectorID)
+	s.annotateConnectorSecret(response, connectorID)
+	response.Encoding = negotiateAgentEncoding(payload.Encodings)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleAgentResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var payload protocol.ResumeRequest
+	if !s.decodeJSON(w, r, &payload, "resume payload") {
+		return
+	}
+	if s.rejectOutdatedAgent(w, &payload.RegisterRequest) {
+		return
+	}
+	connectorID := strings.TrimSpace(payload.ConnectorID)
+	if connectorID != "" && !s.connectorStore.Authenticate(connectorID, payload.ConnectorSecret) {
+		writeAPIError(w, http.StatusUnauthorized, errCodeInvalidCredentials, "invalid connector credentials")
+		return
+	}
+	if connectorID != "" && !s.requireConnectorTenantActive(w, connectorID) {
+		return
+	}
+
+	response, err := s.hub.ResumeSession(payload.SessionID, &payload.RegisterRequest)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrSessionNotResumable):
+			writeAPIError(w, http.StatusConflict, errCodeSessionNotResumable, err.Error())
+		case strings.Contains(err.Error(), "token mismatch"):
+			writeAPIError(w, http.StatusUnauthorized, errCodeUnauthorized, err.Error())
+		default:
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		}
 		return
 	}
+	s.annotateRegisteredRoutes(response, connectorID)
+	s.annotateConnectorSecret(response, connectorID)
+	response.Encoding = negotiateAgentEncoding(payload.Encodings)
 
 	writeJSON(w, http.StatusOK, response)
 }
 
+func negotiateAgentEncoding(offered []string) string {
+	for _, encoding := range offered {
+		if strings.EqualFold(strings.TrimSpace(encoding), protocol.EncodingFrame) {
+			return protocol.EncodingFrame
+		}
+	}
+	return ""
+}
+
+func acceptsFrame(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), protocol.FrameContentType)
+}
+
 func (s *Server) handleAgentPull(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	sessionID := strings.TrimSpace(r.URL.Query().Get("session_id"))
 	if sessionID == "" {
-		http.Error(w, "missing session_id", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing session_id")
 		return
 	}
 
@@ -2085,14 +2659,18 @@ func (s *Server) handleAgentPull(w http.ResponseWriter, r *http.Request) {
 	request, err := s.hub.PullRequest(ctx, sessionID)
 	if err != nil {
 		if errors.Is(err, ErrUnknownSession) {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			writeAPIError(w, http.StatusNotFound, errCodeUnknownSession, err.Error())
+			return
+		}
+		if errors.Is(err, ErrGatewayShuttingDown) {
+			writeAPIError(w, http.StatusServiceUnavailable, errCodeShuttingDown, err.Error())
 			return
 		}
 		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, err.Error())
 		return
 	}
 	if request == nil {
@@ -2100,35 +2678,92 @@ func (s *Server) handleAgentPull(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if acceptsFrame(r) {
+		w.Header().Set("Content-Type", protocol.FrameContentType)
+		w.WriteHeader(http.StatusOK)
+		_ = protocol.WritePullFrame(w, protocol.PullResponse{Request: request})
+		return
+	}
 	writeJSON(w, http.StatusOK, protocol.PullResponse{Request: request})
 }
 
+// handleAgentCancel long-polls until the caller of a request the agent is
+// working on gives up, answering 200 with a CancelNotice, or 204 once the
+// wait elapses with the request still wanted.
+func (s *Server) handleAgentCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	query := r.URL.Query()
+	sessionID := strings.TrimSpace(query.Get("session_id"))
+	requestID := strings.TrimSpace(query.Get("request_id"))
+	if sessionID == "" || requestID == "" {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing session_id or request_id")
+		return
+	}
+
+	wait := 25 * time.Second
+	if waitRaw := strings.TrimSpace(query.Get("wait")); waitRaw != "" {
+		if seconds, err := strconv.Atoi(waitRaw); err == nil && seconds > 0 && seconds <= 60 {
+			wait = time.Duration(seconds) * time.Second
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+	reason, err := s.hub.WatchCancel(ctx, sessionID, requestID)
+	switch {
+	case errors.Is(err, ErrUnknownSession):
+		writeAPIError(w, http.StatusNotFound, errCodeUnknownSession, err.Error())
+		return
+	case errors.Is(err, ErrResponseSessionMismatch):
+		writeAPIError(w, http.StatusConflict, errCodeConflict, err.Error())
+		return
+	case errors.Is(err, ErrGatewayShuttingDown):
+		writeAPIError(w, http.StatusServiceUnavailable, errCodeShuttingDown, err.Error())
+		return
+	case err != nil:
+		writeAPIError(w, http.StatusInternalServerError, errCodeInternal, err.Error())
+		return
+	}
+	if reason == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, protocol.CancelNotice{RequestID: requestID, Reason: reason})
+}
+
 func (s *Server) handleAgentRespond(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	var payload protocol.SubmitResponseRequest
-	if !s.decodeJSON(w, r, &payload, "response payload") {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), protocol.FrameContentType) {
+		if !s.decodeFrame(w, r, &payload) {
+			return
+		}
+	} else if !s.decodeJSON(w, r, &payload, "response payload") {
 		return
 	}
 
 	if strings.TrimSpace(payload.SessionID) == "" {
-		http.Error(w, "missing session_id", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing session_id")
 		return
 	}
 
 	if err := s.hub.SubmitProxyResponse(payload.SessionID, payload.Response); err != nil {
 		if errors.Is(err, ErrUnknownSession) {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			writeAPIError(w, http.StatusNotFound, errCodeUnknownSession, err.Error())
 			return
 		}
 		if errors.Is(err, ErrUnknownPendingRequest) || errors.Is(err, ErrResponseSessionMismatch) || errors.Is(err, ErrResponseTunnelMismatch) {
-			http.Error(w, err.Error(), http.StatusConflict)
+			writeAPIError(w, http.StatusConflict, errCodeConflict, err.Error())
 			return
 		}
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -2137,7 +2772,7 @@ func (s *Server) handleAgentRespond(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleAgentHeartbeat(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
 		return
 	}
 
@@ -2146,16 +2781,16 @@ func (s *Server) handleAgentHeartbeat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if strings.TrimSpace(payload.SessionID) == "" {
-		http.Error(w, "missing session_id", http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing session_id")
 		return
 	}
 
-	if err := s.hub.Heartbeat(payload.SessionID); err != nil {
+	if err := s.hub.Heartbeat(payload.SessionID, payload.Health); err != nil {
 		if errors.Is(err, ErrUnknownSession) {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			writeAPIError(w, http.StatusNotFound, errCodeUnknownSession, err.Error())
 			return
 		}
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -2163,8 +2798,14 @@ func (s *Server) handleAgentHeartbeat(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleProxy(w http.ResponseWriter, r *http.Request) {
+	started := time.Now()
 	requestID := s.nextRequestID()
 	w.Header().Set("X-Proxer-Request-ID", requestID)
+	if isGRPCRequest(r) {
+		grpcWriter := newGRPCErrorWriter(w)
+		defer grpcWriter.finish()
+		w = grpcWriter
+	}
 
 	resolved, err := s.resolveProxyPath(r.URL.Path)
 	if err != nil {
@@ -2172,12 +2813,37 @@ func (s *Server) handleProxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s.writeTenantSuspended(w, resolved.TenantID) {
+		return
+	}
+	if !s.checkClientAbuse(w, r, resolved.TenantID, resolved.RouteID) {
+		return
+	}
+
 	lookupKeys := s.lookupTunnelKeys(resolved.TenantID, resolved.RouteID)
 	rule, hasRule := s.ruleStore.GetForTenant(resolved.TenantID, resolved.RouteID)
 	plan, planID := s.planStore.GetTenantPlan(resolved.TenantID)
 
+	if hasRule {
+		switch rule.ScheduleState(time.Now().UTC()) {
+		case RouteScheduleExpired:
+			http.Error(w, fmt.Sprintf("route %q has expired", resolved.RouteID), http.StatusGone)
+			return
+		case RouteScheduleScheduled:
+			http.Error(w, fmt.Sprintf("route %q is not active yet", resolved.RouteID), http.StatusNotFound)
+			return
+		}
+	}
+	if cors := rule.corsPolicy(); hasRule && cors.isPreflight(r) {
+		cors.writePreflight(w, r)
+		return
+	}
+
 	if !s.rateLimiter.Allow("tenant:"+resolved.TenantID, plan.MaxRPS) {
 		s.planStore.RecordBlockedRequest(resolved.TenantID)
+		if s.writeCustomErrorPage(w, resolved.TenantID, resolved.RouteID, errorPageRateLimited, http.StatusTooManyRequests, "tenant_rate_limit_exceeded", "tenant request rate exceeded") {
+			return
+		}
 		writeJSON(w, http.StatusTooManyRequests, map[string]any{
 			"error":     "tenant_rate_limit_exceeded",
 			"message":   "tenant request rate exceeded",
@@ -2196,6 +2862,9 @@ func (s *Server) handleProxy(w http.ResponseWriter, r *http.Request) {
 	}
 	if !s.rateLimiter.Allow("route:"+resolved.TenantID+":"+resolved.RouteID, routeRate) {
 		s.planStore.RecordBlockedRequest(resolved.TenantID)
+		if s.writeCustomErrorPage(w, resolved.TenantID, resolved.RouteID, errorPageRateLimited, http.StatusTooManyRequests, "route_rate_limit_exceeded", "route request rate exceeded") {
+			return
+		}
 		writeJSON(w, http.StatusTooManyRequests, map[string]any{
 			"error":      "route_rate_limit_exceeded",
 			"message":    "route request rate exceeded",
@@ -2212,6 +2881,9 @@ func (s *Server) handleProxy(w http.ResponseWriter, r *http.Request) {
 	monthlyCapBytes := int64(plan.MaxMonthlyGB * bytesPerGB)
 	if monthlyCapBytes > 0 && usage.BytesIn+usage.BytesOut >= monthlyCapBytes {
 		s.planStore.RecordBlockedRequest(resolved.TenantID)
+		if s.writeCustomErrorPage(w, resolved.TenantID, resolved.RouteID, errorPageRateLimited, http.StatusTooManyRequests, "monthly_traffic_cap_exceeded", "monthly traffic cap exceeded") {
+			return
+		}
 		writeJSON(w, http.StatusTooManyRequests, map[string]any{
 			"error":              "monthly_traffic_cap_exceeded",
 			"message":            "monthly traffic cap exceeded",
@@ -2244,7 +2916,33 @@ func (s *Server) handleProxy(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	body, err := readAllWithLimit(r.Body, s.maxRequestBodyBytes)
+	forwardPath := resolved.ForwardPath
+	if hasRule {
+		if location, status, ok := rule.Rewrite.redirectFor(forwardPath, proxyPublicPrefix(r, forwardPath), r.URL.RawQuery); ok {
+			http.Redirect(w, r, location, status)
+			return
+		}
+		forwardPath = rule.Rewrite.rewritePath(forwardPath)
+	}
+
+	var middleware middlewareOutcome
+	if hasRule && len(rule.Middleware) > 0 {
+		middleware, err = evaluateRouteMiddleware(rule.Middleware, r, resolved.ForwardPath)
+		if err != nil {
+			s.logger.Printf("route middleware for %s failed: %s", MakeTunnelKey(resolved.TenantID, resolved.RouteID), s.redaction(resolved.TenantID).RedactText(err.Error()))
+			http.Error(w, "route middleware failed", http.StatusInternalServerError)
+			return
+		}
+		if middleware.denyStatus != 0 {
+			http.Error(w, middleware.denyMessage, middleware.denyStatus)
+			return
+		}
+	}
+
+	// Route and connector bandwidth limits pace both the request body and
+	// the response written back to the client.
+	bandwidth := []*httpx.BandwidthLimiter{s.routeBandwidth(rule)}
+	body, upload, err := s.readProxyBody(w, r, rule, hasRule, bandwidth)
 	if err != nil {
 		if errors.Is(err, errBodyTooLarge) {
 			http.Error(w, "request body exceeds limit", http.StatusRequestEntityTooLarge)
@@ -2253,46 +2951,128 @@ func (s *Server) handleProxy(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("read request body: %v", err), http.StatusBadRequest)
 		return
 	}
+	if hasRule && rule.WebhookVerification != nil && !s.verifyWebhookRequest(w, r, rule, body, upload != nil) {
+		return
+	}
+	idempotencyKey := ""
+	if hasRule && upload == nil {
+		var proceed bool
+		if idempotencyKey, proceed = s.beginIdempotentRequest(w, r, rule, requestID, body); !proceed {
+			return
+		}
+		if idempotencyKey != "" {
+			defer s.idempotency.release(idempotencyKey)
+		}
+	}
 
 	headers := httpx.CloneHTTPHeader(r.Header)
-	enrichForwardHeaders(headers, r)
+	var forwarded *ForwardedHeaders
+	if hasRule {
+		forwarded = rule.ForwardedHeaders
+	}
+	forwarded.apply(headers, r)
 	headers["X-Proxer-Request-ID"] = []string{requestID}
+	if s.config().InjectTraceparent {
+		injectTraceparent(headers)
+	}
+	if httpx.WantsTrailers(r.Header) {
+		headers["Te"] = []string{"trailers"}
+	}
+	middleware.applyHeaders(headers)
+	if hasRule {
+		rule.Presets.applyToRequest(headers)
+	}
 
 	proxyReq := &protocol.ProxyRequest{
 		RequestID:  requestID,
 		Method:     r.Method,
-		Path:       resolved.ForwardPath,
+		Path:       forwardPath,
 		Query:      forwardQuery,
 		Headers:    headers,
 		Body:       body,
 		RemoteAddr: r.RemoteAddr,
 	}
+	if len(r.Trailer) > 0 {
+		proxyReq.Trailers = httpx.CloneHTTPHeader(r.Trailer)
+	}
+	if hasRule {
+		proxyReq.Host = rule.Rewrite.upstreamHost(r)
+	}
+	if upload != nil {
+		// The relayed body can be read once, so it is neither mirrored nor
+		// retried.
+		proxyReq.Upload = true
+		proxyReq.UploadBytes = upload.size
+		s.hub.openUpload(requestID, upload)
+		defer s.hub.closeUpload(requestID)
+	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), s.hub.RequestTimeout())
+	requestTimeout, idleTimeout := s.proxyTimeouts(rule, hasRule, plan)
+	proxyReq.IdleTimeoutMs = idleTimeout.Milliseconds()
+	proxyReq.HeaderTimeoutMs = responseHeaderTimeout(rule, hasRule, requestTimeout).Milliseconds()
+	responseLimit, responseLimitSource := s.responseBodyLimit(rule, hasRule)
+	proxyReq.MaxResponseBodyBytes = responseLimit
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
 	defer cancel()
+	if hasRule {
+		if upload == nil {
+			s.mirrorRequest(rule, proxyReq, requestTimeout)
+			proxyReq.Retry = rule.Retry
+		}
+		ctx = withQueueOverflow(ctx, rule.QueueOverflow)
+	}
 
 	var (
 		proxyResp   *protocol.ProxyResponse
 		dispatchKey string
 	)
 
-	if hasRule && rule.UsesConnector() {
+	upstream := rule.upstreamFor(resolved.ForwardPath)
+	routeKey := MakeTunnelKey(resolved.TenantID, resolved.RouteID)
+	// pinned is set when split or middleware picked the upstream, which takes
+	// precedence over a legacy agent tunnel registered under the route name.
+	pinned := false
+	if middleware.upstream != nil {
+		upstream = middleware.upstream.applyTo(rule)
+		pinned = true
+	} else if hasRule && rule.Split != nil && rule.Mock == nil {
+		variant := routeVariantPrimary
+		if pinned = rule.Split.sample(); pinned {
+			upstream = rule.Split.applyTo(rule)
+			routeKey = variantTunnelKey(resolved.TenantID, resolved.RouteID, routeVariantCanary)
+			variant = routeVariantCanary
+		}
+		w.Header().Set("X-Proxer-Variant", variant)
+	}
+	if hasRule && rule.Mock != nil {
 		dispatchKey = MakeTunnelKey(resolved.TenantID, resolved.RouteID)
-		proxyReq.TunnelID = dispatchKey
-		proxyReq.ConnectorID = rule.ConnectorID
-		proxyReq.LocalTarget = &protocol.LocalTarget{
-			Scheme: rule.LocalScheme,
-			Host:   rule.LocalHost,
-			Port:   rule.LocalPort,
+		proxyResp = rule.Mock.respond(requestID, dispatchKey, resolved.ForwardPath)
+		proxyResp.BytesIn = int64(len(proxyReq.Body))
+		s.hub.RecordProxyResponse(proxyResp)
+	} else if hasRule && upstream.UsesConnector() {
+		dispatchKey = routeKey
+		connectorID, ok := s.resolveConnector(upstream)
+		if !ok {
+			s.writeDispatchError(w, dispatchKey, int64(len(proxyReq.Body)), fmt.Errorf("no online connector matches selector: %w", ErrConnectorNotConnected))
+			return
 		}
-		proxyReq.Path = joinWithBasePath(rule.LocalBasePath, resolved.ForwardPath)
+		proxyReq.TunnelID = dispatchKey
+		proxyReq.ConnectorID = connectorID
+		proxyReq.LocalTarget = upstream.localTarget()
+		proxyReq.Path = joinWithBasePath(upstream.LocalBasePath, forwardPath)
 
-		proxyResp, err = s.hub.DispatchProxyRequestToConnector(ctx, rule.ConnectorID, dispatchKey, proxyReq)
+		connectorBandwidth := s.connectorBandwidth(connectorID)
+		bandwidth = append(bandwidth, connectorBandwidth)
+		if err := connectorBandwidth.WaitN(ctx, len(proxyReq.Body)); err != nil {
+			s.writeDispatchError(w, dispatchKey, int64(len(proxyReq.Body)), err)
+			return
+		}
+		proxyResp, err = s.hub.DispatchProxyRequestToConnector(ctx, connectorID, dispatchKey, proxyReq)
 		if err != nil {
 			s.writeDispatchError(w, dispatchKey, int64(len(proxyReq.Body)), err)
 			return
 		}
-	} else if key, connected := s.firstConnectedTunnelKey(lookupKeys); connected {
+	} else if key, connected := s.firstConnectedTunnelKey(lookupKeys); connected && !pinned {
 		dispatchKey = key
 		proxyResp, err = s.hub.DispatchProxyRequest(ctx, dispatchKey, proxyReq)
 		if err != nil {
@@ -2300,22 +3080,39 @@ func (s *Server) handleProxy(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	} else if hasRule {
-		dispatchKey = MakeTunnelKey(resolved.TenantID, resolved.RouteID)
-		proxyResp, err = s.forwardDirect(ctx, rule, proxyReq)
+		dispatchKey = routeKey
+		proxyReq.TunnelID = dispatchKey
+		var uploadBody io.Reader
+		if upload != nil {
+			uploadBody = upload
+		}
+		proxyResp, err = s.forwardDirect(ctx, upstream, proxyReq, uploadBody)
+		if err != nil && upload != nil && upload.exceeded() {
+			http.Error(w, "request body exceeds the route upload limit", http.StatusRequestEntityTooLarge)
+			return
+		}
 		if err != nil {
-			s.hub.RecordProxyFailure(dispatchKey, int64(len(proxyReq.Body)), err.Error())
-			s.maybeRecordProxyIncident(err, dispatchKey)
+			s.maybeRecordProxyIncident(err, dispatchKey, requestID)
 			status := http.StatusBadGateway
+			pageKind := errorPageConnectorOffline
 			switch {
-			case errors.Is(err, ErrProxyRequestTimeout) || errors.Is(err, context.DeadlineExceeded):
+			case errors.Is(err, ErrProxyRequestTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, httpx.ErrIdleTimeout) || errors.Is(err, httpx.ErrResponseHeaderTimeout):
 				status = http.StatusGatewayTimeout
-			case errors.Is(err, errBodyTooLarge):
-				status = http.StatusRequestEntityTooLarge
+				pageKind = errorPageTimeout
+			}
+			if status == http.StatusGatewayTimeout {
+				s.hub.RecordProxyTimeout(dispatchKey, int64(len(proxyReq.Body)), err.Error())
+			} else {
+				s.hub.RecordProxyFailure(dispatchKey, int64(len(proxyReq.Body)), err.Error())
+			}
+			if pageKind != "" && s.writeCustomErrorPage(w, resolved.TenantID, resolved.RouteID, pageKind, status, "upstream_unavailable", "upstream is unavailable") {
+				return
 			}
-			http.Error(w, fmt.Sprintf("direct forward failed: %v", err), status)
+			http.Error(w, fmt.Sprintf("direct forward failed: %v (request %s)", err, requestID), status)
 			return
 		}
 		proxyResp.RequestID = requestID
+		proxyResp.TunnelID = dispatchKey
 		s.hub.RecordProxyResponse(proxyResp)
 	} else {
 		http.Error(w, fmt.Sprintf("route %q not found for tenant %q", resolved.RouteID, resolved.TenantID), http.StatusNotFound)
@@ -2326,15 +3123,45 @@ func (s *Server) handleProxy(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "proxy response was nil", http.StatusBadGateway)
 		return
 	}
+	if upload != nil && upload.exceeded() {
+		http.Error(w, "request body exceeds the route upload limit", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !hasRule || rule.Mock == nil {
+		enforceResponseLimit(proxyResp, responseLimit)
+	}
+	if proxyResp.LimitExceeded != nil {
+		writeResponseLimitError(w, responseLimitSource, proxyResp.LimitExceeded)
+		return
+	}
 
 	if strings.TrimSpace(proxyResp.RequestID) == "" {
 		proxyResp.RequestID = requestID
 	}
-	s.recordTrafficUsage(resolved.TenantID, plan, int64(len(body)), int64(len(proxyResp.Body)))
-	s.writeProxyResponse(w, resolved.TenantID, resolved.RouteID, dispatchKey, proxyResp)
+	bytesIn := int64(len(body))
+	if upload != nil {
+		bytesIn = upload.read.Load()
+	}
+	s.recordTrafficUsage(resolved.TenantID, resolved.RouteID, proxyReq.ConnectorID, plan, bytesIn, int64(len(proxyResp.Body)))
+	if hasRule && rule.Rewrite.rewritesResponses() {
+		rewriteResponseURLs(proxyResp, proxyPublicPrefix(r, resolved.ForwardPath))
+	}
+	if hasRule {
+		rule.Presets.applyToResponse(proxyResp, inferRequestBaseURL(r), proxyPublicPrefix(r, resolved.ForwardPath))
+		rule.corsPolicy().applyToResponse(w.Header(), proxyResp.Headers, r.Header.Get("Origin"))
+	}
+	if idempotencyKey != "" {
+		s.idempotency.complete(idempotencyKey, proxyResp)
+	}
+	if hasRule && rule.Capture != nil {
+		s.captureExchange(rule, r, requestID, body, bytesIn, upload != nil, proxyResp, started)
+	}
+	clientWriter := s.newClientResponseWriter(w)
+	s.writeProxyResponse(throttleResponse(r.Context(), clientWriter, bandwidth...), resolved.TenantID, resolved.RouteID, dispatchKey, proxyResp)
+	clientWriter.finish()
 }
 
-func (s *Server) forwardDirect(ctx context.Context, rule Rule, proxyReq *protocol.ProxyRequest) (*protocol.ProxyResponse, error) {
+func (s *Server) forwardDirect(ctx context.Context, rule Rule, proxyReq *protocol.ProxyRequest, upload io.Reader) (*protocol.ProxyResponse, error) {
 	start := time.Now()
 
 	targetURL, err := buildTargetURL(rule.Target, proxyReq.Path, proxyReq.Query)
@@ -2342,10 +3169,24 @@ func (s *Server) forwardDirect(ctx context.Context, rule Rule, proxyReq *protoco
 		return nil, fmt.Errorf("build target URL: %w", err)
 	}
 
-	outboundReq, err := http.NewRequestWithContext(ctx, proxyReq.Method, targetURL, bytes.NewReader(proxyReq.Body))
+	idleCtx, watchdog, cancelIdle := httpx.WithIdleTimeout(ctx, time.Duration(proxyReq.IdleTimeoutMs)*time.Millisecond)
+	defer cancelIdle()
+	idleCtx, headerTimer, cancelHeaderTimer := httpx.WithResponseHeaderTimeout(idleCtx, time.Duration(proxyReq.HeaderTimeoutMs)*time.Millisecond)
+	defer cancelHeaderTimer()
+
+	var body io.Reader = bytes.NewReader(proxyReq.Body)
+	if upload != nil {
+		body = headerTimer.Body(upload)
+	} else {
+		headerTimer.Start()
+	}
+	outboundReq, err := http.NewRequestWithContext(idleCtx, proxyReq.Method, targetURL, body)
 	if err != nil {
 		return nil, fmt.Errorf("construct outbound request: %w", err)
 	}
+	if upload != nil {
+		outboundReq.ContentLength = proxyReq.UploadBytes
+	}
 
 	for header, values := range proxyReq.Headers {
 		if httpx.IsHopByHopHeader(header) || strings.EqualFold(header, "Host") || strings.EqualFold(header, "Content-Length") {
@@ -2360,15 +3201,46 @@ func (s *Server) forwardDirect(ctx context.Context, rule Rule, proxyReq *protoco
 	outboundReq.Header.Set("X-Proxer-Tenant-ID", rule.TenantID)
 	outboundReq.Header.Set("X-Proxer-Route-ID", rule.ID)
 	outboundReq.Header.Set("X-Proxer-Route-Mode", "direct")
+	if host := strings.TrimSpace(proxyReq.Host); host != "" {
+		outboundReq.Host = host
+	}
+	httpx.ForwardTrailers(outboundReq, proxyReq.Headers, proxyReq.Trailers)
 
-	outboundResp, err := s.forwardHTTP.Do(outboundReq)
+	outboundResp, retries, err := httpx.DoWithRetry(idleCtx, proxyReq.Retry, proxyReq.Method, func() (*http.Response, error) {
+		watchdog.Touch()
+		return s.forwardHTTP.Do(httpx.CloneForRetry(outboundReq))
+	})
+	headerTimer.Received()
 	if err != nil {
+		s.hub.RecordProxyRetries(proxyReq.TunnelID, retries)
+		if httpx.IsIdleTimeout(idleCtx) {
+			err = httpx.ErrIdleTimeout
+		}
+		if httpx.IsResponseHeaderTimeout(idleCtx) {
+			err = httpx.ErrResponseHeaderTimeout
+		}
 		return nil, fmt.Errorf("forward request to target %s: %w", rule.Target, err)
 	}
 	defer outboundResp.Body.Close()
-
-	responseBody, err := readAllWithLimit(outboundResp.Body, s.maxResponseBodyBytes)
+	watchdog.Touch()
+
+	responseBody, exceeded, err := httpx.ReadLimitedBody(watchdog.Reader(outboundResp.Body), outboundResp.ContentLength, proxyReq.MaxResponseBodyBytes, protocol.ResponseLimitRequest)
+	if exceeded != nil {
+		return &protocol.ProxyResponse{
+			RequestID:     proxyReq.RequestID,
+			TunnelID:      MakeTunnelKey(rule.TenantID, rule.ID),
+			Status:        http.StatusBadGateway,
+			Error:         "upstream response exceeded the size limit",
+			BytesIn:       int64(len(proxyReq.Body)),
+			LatencyMs:     time.Since(start).Milliseconds(),
+			Retries:       retries,
+			LimitExceeded: exceeded,
+		}, nil
+	}
 	if err != nil {
+		if httpx.IsIdleTimeout(idleCtx) {
+			err = httpx.ErrIdleTimeout
+		}
 		return nil, fmt.Errorf("read upstream response: %w", err)
 	}
 
@@ -2381,6 +3253,10 @@ func (s *Server) forwardDirect(ctx context.Context, rule Rule, proxyReq *protoco
 		BytesIn:   int64(len(proxyReq.Body)),
 		BytesOut:  int64(len(responseBody)),
 		LatencyMs: time.Since(start).Milliseconds(),
+		Retries:   retries,
+	}
+	if len(outboundResp.Trailer) > 0 {
+		response.Trailers = httpx.CloneHTTPHeader(outboundResp.Trailer)
 	}
 	return response, nil
 }
@@ -2399,30 +3275,66 @@ func (s *Server) writeProxyResponse(w http.ResponseWriter, tenantID, routeID, tu
 	w.Header().Set("X-Proxer-Tenant-ID", tenantID)
 	w.Header().Set("X-Proxer-Route-ID", routeID)
 	httpx.WriteHeaderMap(w.Header(), proxyResp.Headers)
+	httpx.AnnounceTrailers(w.Header(), proxyResp.Trailers)
+	setBufferedContentLength(w.Header(), status, proxyResp.Body, len(proxyResp.Trailers) > 0)
 	w.WriteHeader(status)
 	if _, err := w.Write(proxyResp.Body); err != nil {
 		s.logger.Printf("write proxied response failed: %v", err)
 	}
+	httpx.WriteTrailers(w.Header(), proxyResp.Trailers)
+}
+
+func sessionTokenFromRequest(r *http.Request) string {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil && strings.TrimSpace(cookie.Value) != "" {
+		return strings.TrimSpace(cookie.Value)
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return ""
 }
 
 func (s *Server) requireAuth(w http.ResponseWriter, r *http.Request) (User, bool) {
-	cookie, err := r.Cookie(sessionCookieName)
-	if err != nil || strings.TrimSpace(cookie.Value) == "" {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return User{}, false
+	user, _, ok := s.requireSession(w, r)
+	return user, ok
+}
+
+func (s *Server) requireSession(w http.ResponseWriter, r *http.Request) (User, *Impersonation, bool) {
+	sessionID := sessionTokenFromRequest(r)
+	if sessionID == "" {
+		writeAPIError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return User{}, nil, false
 	}
 
-	user, ok := s.authStore.ResolveSession(cookie.Value)
+	if strings.HasPrefix(sessionID, orgTokenPrefix) {
+		// Org API tokens are bearer-only, so CSRF does not apply.
+		user, ok := s.orgTokenUser(sessionID)
+		if !ok {
+			writeAPIError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		}
+		return user, nil, ok
+	}
+	if !s.allowSessionLookup(r, sessionID) {
+		writeSessionLookupLimited(w)
+		return User{}, nil, false
+	}
+	user, impersonation, ok := s.authStore.ResolveSession(sessionID)
 	if !ok {
 		s.clearSessionCookie(w)
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return User{}, false
+		writeAPIError(w, http.StatusUnauthorized, errCodeUnauthorized, "unauthorized")
+		return User{}, nil, false
+	}
+	if !s.checkCSRF(w, r, sessionID) {
+		return User{}, nil, false
 	}
-	return user, true
+	if impersonation != nil {
+		s.markImpersonation(w, r, user, impersonation)
+	}
+	return user, impersonation, true
 }
 
 func (s *Server) setSessionCookie(w http.ResponseWriter, sessionID string) {
-	ttl := s.cfg.SessionTTL
+	ttl := s.config().SessionTTL
 	if ttl <= 0 {
 		ttl = 24 * time.Hour
 	}
@@ -2435,6 +3347,7 @@ func (s *Server) setSessionCookie(w http.ResponseWriter, sessionID string) {
 		Expires:  time.Now().UTC().Add(ttl),
 		MaxAge:   int(ttl.Seconds()),
 	})
+	s.setCSRFCookie(w, sessionID, ttl)
 }
 
 func (s *Server) clearSessionCookie(w http.ResponseWriter) {
@@ -2447,6 +3360,7 @@ func (s *Server) clearSessionCookie(w http.ResponseWriter) {
 		MaxAge:   -1,
 		Expires:  time.Unix(0, 0),
 	})
+	s.clearCSRFCookie(w)
 }
 
 func (s *Server) canAccessTenant(user User, tenantID string) bool {
@@ -2457,6 +3371,9 @@ func (s *Server) canAccessTenant(user User, tenantID string) bool {
 	if s.isSuperAdmin(user) {
 		return true
 	}
+	if s.isOrgAdmin(user) {
+		return s.inUserOrg(user, tenantID)
+	}
 	return strings.TrimSpace(user.TenantID) == tenantID
 }
 
@@ -2480,6 +3397,9 @@ func (s *Server) canMutateTenant(user User, tenantID string) bool {
 	if s.isSuperAdmin(user) {
 		return true
 	}
+	if s.isOrgAdmin(user) {
+		return s.inUserOrg(user, tenantID)
+	}
 	if strings.TrimSpace(user.TenantID) != tenantID {
 		return false
 	}
@@ -2487,7 +3407,7 @@ func (s *Server) canMutateTenant(user User, tenantID string) bool {
 		return true
 	}
 	if s.isMember(user) {
-		return s.cfg.MemberWriteEnabled
+		return s.config().MemberWriteEnabled
 	}
 	return false
 }
@@ -2500,6 +3420,9 @@ func (s *Server) canMutateTenantConfig(user User, tenantID string) bool {
 	if s.isSuperAdmin(user) {
 		return true
 	}
+	if s.isOrgAdmin(user) {
+		return s.inUserOrg(user, tenantID)
+	}
 	if !s.isTenantAdmin(user) {
 		return false
 	}
@@ -2510,7 +3433,7 @@ func (s *Server) requireSuperAdmin(w http.ResponseWriter, user User) bool {
 	if s.isSuperAdmin(user) {
 		return true
 	}
-	http.Error(w, "super admin required", http.StatusForbidden)
+	writeAPIError(w, http.StatusForbidden, errCodeSuperAdminRequired, "super admin required")
 	return false
 }
 
@@ -2521,7 +3444,7 @@ func (s *Server) filterTenantsForUser(user User) []tenantView {
 	}
 	filtered := make([]tenantView, 0, len(all))
 	for _, tenant := range all {
-		if tenant.ID == user.TenantID {
+		if s.canAccessTenant(user, tenant.ID) {
 			filtered = append(filtered, tenant)
 		}
 	}
@@ -2534,6 +3457,8 @@ func (s *Server) buildConnectorViewsForUser(user User) []connectorView {
 		for _, tenant := range s.ruleStore.ListTenants() {
 			tenantIDs = append(tenantIDs, tenant.ID)
 		}
+	} else if org, ok := s.orgStore.Get(user.OrgID); ok && s.isOrgAdmin(user) {
+		tenantIDs = append(tenantIDs, org.TenantIDs...)
 	} else {
 		tenantIDs = append(tenantIDs, user.TenantID)
 	}
@@ -2548,16 +3473,23 @@ func (s *Server) buildConnectorViewsForUser(user User) []connectorView {
 
 func (s *Server) buildConnectorView(connector Connector) connectorView {
 	view := connectorView{
-		ID:        connector.ID,
-		TenantID:  connector.TenantID,
-		Name:      connector.Name,
-		CreatedAt: connector.CreatedAt,
-		UpdatedAt: connector.UpdatedAt,
+		ID:                connector.ID,
+		TenantID:          connector.TenantID,
+		Name:              connector.Name,
+		Labels:            connector.Labels,
+		MaxBytesPerSecond: connector.MaxBytesPerSecond,
+		CreatedAt:         connector.CreatedAt,
+		UpdatedAt:         connector.UpdatedAt,
 	}
 	if connection, connected := s.hub.GetConnectorConnection(connector.ID); connected {
 		view.Connected = connection.Connected
+		view.Load = &connection.Load
 		view.AgentID = connection.AgentID
+		view.AgentVersion = connection.AgentVersion
+		view.ProtocolVersion = connection.ProtocolVersion
 		view.LastSeen = connection.LastSeen
+		view.OutdatedReason = agentOutdatedReason(s.config(), connection.AgentVersion, connection.ProtocolVersion)
+		view.Outdated = view.OutdatedReason != ""
 	}
 	return view
 }
@@ -2568,17 +3500,27 @@ func (s *Server) buildTenantViews() []tenantView {
 
 	views := make([]tenantView, 0, len(tenants))
 	for _, tenant := range tenants {
-		views = append(views, tenantView{
-			ID:         tenant.ID,
-			Name:       tenant.Name,
-			RouteCount: routeCounts[tenant.ID],
-			CreatedAt:  tenant.CreatedAt,
-			UpdatedAt:  tenant.UpdatedAt,
-		})
+		views = append(views, newTenantView(tenant, routeCounts[tenant.ID]))
 	}
 	return views
 }
 
+func (s *Server) buildTenantView(tenant Tenant) tenantView {
+	return newTenantView(tenant, s.ruleStore.RouteCountByTenant()[tenant.ID])
+}
+
+func newTenantView(tenant Tenant, routeCount int) tenantView {
+	return tenantView{
+		ID:         tenant.ID,
+		Name:       tenant.Name,
+		Status:     tenant.status(),
+		Suspension: tenant.Suspension,
+		RouteCount: routeCount,
+		CreatedAt:  tenant.CreatedAt,
+		UpdatedAt:  tenant.UpdatedAt,
+	}
+}
+
 func (s *Server) buildTunnelViews() []tunnelView {
 	connected := s.hub.SnapshotTunnels()
 	viewsByKey := make(map[string]tunnelView, len(connected))
@@ -2643,7 +3585,8 @@ func (s *Server) buildTunnelViews() []tunnelView {
 			Source:          "rule",
 		}
 		if rule.UsesConnector() {
-			if connectorConn, connected := s.hub.GetConnectorConnection(rule.ConnectorID); connected {
+			connectorID, _ := s.resolveConnector(rule)
+			if connectorConn, connected := s.hub.GetConnectorConnection(connectorID); connected {
 				view := viewsByKey[canonicalKey]
 				view.Connection.Connected = true
 				view.AgentID = connectorConn.AgentID
@@ -2719,33 +3662,90 @@ func (s *Server) buildRouteViewWithConnected(route Rule, connectedByKey map[stri
 	}
 
 	view := routeView{
-		TenantID:        route.TenantID,
-		RouteID:         route.ID,
-		ID:              route.ID,
-		TunnelKey:       canonicalKey,
-		Target:          route.Target,
-		MaxRPS:          route.MaxRPS,
-		ConnectorID:     route.ConnectorID,
-		LocalScheme:     route.LocalScheme,
-		LocalHost:       route.LocalHost,
-		LocalPort:       route.LocalPort,
-		LocalBasePath:   route.LocalBasePath,
-		PublicURL:       s.routePublicURL(route.TenantID, route.ID),
-		LegacyPublicURL: legacyURL,
-		TokenConfigured: strings.TrimSpace(route.Token) != "",
-		Metrics:         s.metricForRoute(route.TenantID, route.ID),
-		CreatedAt:       route.CreatedAt,
-		UpdatedAt:       route.UpdatedAt,
+		TenantID:             route.TenantID,
+		RouteID:              route.ID,
+		ID:                   route.ID,
+		TunnelKey:            canonicalKey,
+		Target:               route.Target,
+		Environment:          route.Environment,
+		TargetTemplate:       route.TargetTemplate,
+		MaxRPS:               route.MaxRPS,
+		MaxBytesPerSecond:    route.MaxBytesPerSecond,
+		RequestTimeoutSecs:   route.RequestTimeoutSecs,
+		IdleTimeoutSecs:      route.IdleTimeoutSecs,
+		HeaderTimeoutSecs:    route.HeaderTimeoutSecs,
+		MaxResponseBodyBytes: route.MaxResponseBodyBytes,
+		MaxUploadBytes:       route.MaxUploadBytes,
+		Retry:                route.Retry,
+		ConnectorID:          route.ConnectorID,
+		ConnectorSelector:    route.ConnectorSelector,
+		LocalScheme:          route.LocalScheme,
+		LocalHost:            route.LocalHost,
+		LocalPort:            route.LocalPort,
+		LocalSocket:          route.LocalSocket,
+		LocalTLS:             route.LocalTLS,
+		LocalBasePath:        route.LocalBasePath,
+		BasePathTemplate:     route.BasePathTemplate,
+		ErrorPages:           route.ErrorPages,
+		CORS:                 route.CORS,
+		Presets:              route.Presets,
+		PathRoutes:           route.PathRoutes,
+		Rewrite:              route.Rewrite,
+		ForwardedHeaders:     route.ForwardedHeaders,
+		Idempotency:          route.Idempotency,
+		QueueOverflow:        route.QueueOverflow,
+		SyntheticCheck:       route.SyntheticCheck,
+		TLSPassthrough:       route.TLSPassthrough,
+		Mirror:               route.Mirror,
+		Split:                route.Split,
+		Middleware:           route.Middleware,
+		Mock:                 route.Mock,
+		Capture:              route.Capture,
+		WebhookVerification:  route.WebhookVerification.withoutSecret(),
+		ActiveFrom:           route.ActiveFrom,
+		ExpiresAt:            route.ExpiresAt,
+		DeleteOnExpiry:       route.DeleteOnExpiry,
+		ScheduleState:        route.ScheduleState(time.Now().UTC()),
+		PublicURL:            s.routePublicURL(route.TenantID, route.ID),
+		LegacyPublicURL:      legacyURL,
+		TokenConfigured:      strings.TrimSpace(route.Token) != "",
+		Metrics:              s.metricForRoute(route.TenantID, route.ID),
+		CreatedAt:            route.CreatedAt,
+		UpdatedAt:            route.UpdatedAt,
+	}
+	if remaining, ok := route.RemainingTTL(time.Now().UTC()); ok {
+		seconds := int64(remaining.Seconds())
+		view.ExpiresInSecs = &seconds
+	}
+	if route.Split != nil || route.Mirror != nil {
+		view.VariantMetrics = make(map[string]TunnelMetrics, 2)
+		if route.Split != nil {
+			view.VariantMetrics[routeVariantCanary] = s.hub.GetTunnelMetrics(variantTunnelKey(route.TenantID, route.ID, routeVariantCanary))
+		}
+		if route.Mirror != nil {
+			view.VariantMetrics[routeVariantMirror] = s.hub.GetTunnelMetrics(variantTunnelKey(route.TenantID, route.ID, routeVariantMirror))
+		}
 	}
 
+	connectedTunnelID := ""
 	if route.UsesConnector() {
-		if connectorConn, ok := s.hub.GetConnectorConnection(route.ConnectorID); ok {
+		connectorID, _ := s.resolveConnector(route)
+		if connectorConn, ok := s.hub.GetConnectorConnection(connectorID); ok {
 			view.Connected = connectorConn.Connected
 			view.AgentID = connectorConn.AgentID
 		}
 	} else if connected, ok := connectedByKey[canonicalKey]; ok {
 		view.Connected = true
 		view.AgentID = connected.AgentID
+		connectedTunnelID = connected.ID
+	}
+	if health, ok := s.routeHealth(route, connectedTunnelID); ok {
+		view.Health = &health
+	}
+	if route.SyntheticCheck != nil {
+		if status, ok := s.synthetic.Status(canonicalKey); ok {
+			view.SyntheticStatus = &status
+		}
 	}
 	return view
 }
@@ -2884,9 +3884,13 @@ func (s *Server) metricForRoute(tenantID, routeID string) TunnelMetrics {
 		metric := s.hub.GetTunnelMetrics(key)
 		combined.RequestCount += metric.RequestCount
 		combined.ErrorCount += metric.ErrorCount
+		combined.RetryCount += metric.RetryCount
 		combined.BytesIn += metric.BytesIn
 		combined.BytesOut += metric.BytesOut
 		combined.TotalLatencyMs += metric.TotalLatencyMs
+		if metric.ResetAt.After(combined.ResetAt) {
+			combined.ResetAt = metric.ResetAt
+		}
 		if metric.LastSeen.After(latestSeen) {
 			latestSeen = metric.LastSeen
 			combined.LastSeen = metric.LastSeen
@@ -2900,13 +3904,17 @@ func (s *Server) metricForRoute(tenantID, routeID string) TunnelMetrics {
 	return combined
 }
 
+func (s *Server) pairCommand(pairToken string) string {
+	return fmt.Sprintf("PROXER_GATEWAY_BASE_URL=%s PROXER_AGENT_PAIR_TOKEN=%s proxer-agent", s.agentBaseURL(), pairToken)
+}
+
 func (s *Server) routePublicURL(tenantID, routeID string) string {
-	base := strings.TrimRight(s.cfg.PublicBaseURL, "/")
+	base := strings.TrimRight(s.config().PublicBaseURL, "/")
 	return base + "/t/" + url.PathEscape(tenantID) + "/" + url.PathEscape(routeID) + "/"
 }
 
 func (s *Server) legacyRoutePublicURL(routeID string) string {
-	base := strings.TrimRight(s.cfg.PublicBaseURL, "/")
+	base := strings.TrimRight(s.config().PublicBaseURL, "/")
 	return base + "/t/" + url.PathEscape(routeID) + "/"
 }
 
@@ -3038,55 +4046,6 @@ func joinWithBasePath(basePath, path string) string {
 	return basePath + path
 }
 
-func enrichForwardHeaders(headers map[string][]string, r *http.Request) {
-	appendForwardHeader(headers, "X-Forwarded-Host", r.Host)
-	appendForwardHeader(headers, "X-Forwarded-Proto", requestProto(r))
-	if port := requestPort(r); port != "" {
-		appendForwardHeader(headers, "X-Forwarded-Port", port)
-	}
-	if remoteIP := extractIP(r.RemoteAddr); remoteIP != "" {
-		appendForwardHeader(headers, "X-Forwarded-For", remoteIP)
-	}
-}
-
-func appendForwardHeader(headers map[string][]string, key, value string) {
-	value = strings.TrimSpace(value)
-	if value == "" {
-		return
-	}
-	headers[key] = append(headers[key], value)
-}
-
-func requestProto(r *http.Request) string {
-	if proto := strings.TrimSpace(r.Header.Get("X-Forwarded-Proto")); proto != "" {
-		return proto
-	}
-	if r.TLS != nil {
-		return "https"
-	}
-	return "http"
-}
-
-func requestPort(r *http.Request) string {
-	if port := strings.TrimSpace(r.Header.Get("X-Forwarded-Port")); port != "" {
-		return port
-	}
-	host := strings.TrimSpace(r.Host)
-	if host == "" {
-		return ""
-	}
-	if strings.Contains(host, ":") {
-		if parsedHost, parsedPort, err := net.SplitHostPort(host); err == nil {
-			_ = parsedHost
-			return parsedPort
-		}
-	}
-	if requestProto(r) == "https" {
-		return "443"
-	}
-	return "80"
-}
-
 func extractIP(remoteAddr string) string {
 	remoteAddr = strings.TrimSpace(remoteAddr)
 	if remoteAddr == "" {
@@ -3099,20 +4058,37 @@ func extractIP(remoteAddr string) string {
 }
 
 func (s *Server) decodeJSON(w http.ResponseWriter, r *http.Request, target any, label string) bool {
-	reader := http.MaxBytesReader(w, r.Body, s.maxRequestBodyBytes)
+	reader := http.MaxBytesReader(w, r.Body, s.config().MaxRequestBodyBytes)
 	decoder := json.NewDecoder(reader)
 	if err := decoder.Decode(target); err != nil {
 		var maxBytesErr *http.MaxBytesError
 		if errors.As(err, &maxBytesErr) {
-			http.Error(w, "payload exceeds request body limit", http.StatusRequestEntityTooLarge)
+			writeAPIErrorDetails(w, http.StatusRequestEntityTooLarge, errCodePayloadTooLarge, "payload exceeds request body limit",
+				map[string]any{"limit_bytes": maxBytesErr.Limit})
 			return false
 		}
-		http.Error(w, fmt.Sprintf("invalid %s: %v", label, err), http.StatusBadRequest)
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("invalid %s: %v", label, err))
 		return false
 	}
 	return true
 }
 
+func (s *Server) decodeFrame(w http.ResponseWriter, r *http.Request, target *protocol.SubmitResponseRequest) bool {
+	payload, err := protocol.ReadSubmitResponseFrame(http.MaxBytesReader(w, r.Body, s.config().MaxRequestBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeAPIErrorDetails(w, http.StatusRequestEntityTooLarge, errCodePayloadTooLarge, "payload exceeds request body limit",
+				map[string]any{"limit_bytes": maxBytesErr.Limit})
+			return false
+		}
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, fmt.Sprintf("invalid response frame: %v", err))
+		return false
+	}
+	*target = payload
+	return true
+}
+
 func readAllWithLimit(reader io.Reader, maxBytes int64) ([]byte, error) {
 	if maxBytes <= 0 {
 		return io.ReadAll(reader)
@@ -3130,17 +4106,133 @@ func readAllWithLimit(reader io.Reader, maxBytes int64) ([]byte, error) {
 
 func (s *Server) writeDispatchError(w http.ResponseWriter, tunnelKey string, bytesIn int64, err error) {
 	status := http.StatusBadGateway
+	pageKind := ""
 	switch {
-	case errors.Is(err, ErrAgentQueueFull), errors.Is(err, ErrGlobalBackpressure):
+	case errors.Is(err, ErrAgentQueueFull), errors.Is(err, ErrRequestShed), errors.Is(err, ErrGlobalBackpressure):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, ErrGatewayShuttingDown):
 		status = http.StatusServiceUnavailable
+		w.Header().Set("Retry-After", "5")
 	case errors.Is(err, ErrProxyRequestTimeout), errors.Is(err, context.DeadlineExceeded):
 		status = http.StatusGatewayTimeout
+		pageKind = errorPageTimeout
 	case errors.Is(err, ErrTunnelNotConnected), errors.Is(err, ErrConnectorNotConnected), errors.Is(err, ErrUnknownSession):
 		status = http.StatusBadGateway
+		pageKind = errorPageConnectorOffline
+	case errors.Is(err, ErrTargetUnhealthy):
+		status = http.StatusServiceUnavailable
+		pageKind = errorPageConnectorOffline
+	}
+	if status == http.StatusGatewayTimeout {
+		s.hub.RecordProxyTimeout(tunnelKey, bytesIn, err.Error())
+	} else {
+		s.hub.RecordProxyFailure(tunnelKey, bytesIn, err.Error())
+	}
+	requestID := w.Header().Get("X-Proxer-Request-ID")
+	if !errors.Is(err, ErrGatewayShuttingDown) {
+		s.maybeRecordProxyIncident(err, tunnelKey, requestID)
+	}
+	if pageKind != "" {
+		tenantID, routeID := ParseTunnelKey(tunnelKey)
+		if s.writeCustomErrorPage(w, tenantID, routeID, pageKind, status, pageKind, err.Error()) {
+			return
+		}
+	}
+	http.Error(w, fmt.Sprintf("proxy dispatch failed: %v (request %s)", err, requestID), status)
+}
+
+func (s *Server) validateRouteRequest(tenantID string, request upsertRuleRequest) (Rule, apiErrorCode, error) {
+	if err := s.enforceRouteLimit(tenantID, request.ID); err != nil {
+		return Rule{}, errCodePlanLimitExceeded, err
+	}
+	if err := s.validateConnectorRouteBinding(tenantID, request.ConnectorID); err != nil {
+		return Rule{}, errCodeInvalidRequest, err
+	}
+	if request.Mirror != nil {
+		if err := s.validateConnectorRouteBinding(tenantID, request.Mirror.ConnectorID); err != nil {
+			return Rule{}, errCodeInvalidRequest, fmt.Errorf("mirror: %w", err)
+		}
 	}
-	s.hub.RecordProxyFailure(tunnelKey, bytesIn, err.Error())
-	s.maybeRecordProxyIncident(err, tunnelKey)
-	http.Error(w, fmt.Sprintf("proxy dispatch failed: %v", err), status)
+	if request.Split != nil {
+		if err := s.validateConnectorRouteBinding(tenantID, request.Split.ConnectorID); err != nil {
+			return Rule{}, errCodeInvalidRequest, fmt.Errorf("split: %w", err)
+		}
+	}
+	for index, step := range request.Middleware {
+		if step.Upstream == nil {
+			continue
+		}
+		if err := s.validateConnectorRouteBinding(tenantID, step.Upstream.ConnectorID); err != nil {
+			return Rule{}, errCodeInvalidRequest, fmt.Errorf("middleware[%d].upstream: %w", index, err)
+		}
+	}
+	if err := s.validateRouteTimeouts(tenantID, request.RequestTimeoutSecs, request.IdleTimeoutSecs, request.HeaderTimeoutSecs); err != nil {
+		return Rule{}, errCodeInvalidRequest, err
+	}
+	if err := s.validateTLSPassthrough(tenantID, request.TLSPassthrough); err != nil {
+		return Rule{}, errCodeInvalidRequest, err
+	}
+	if limit := s.config().MaxResponseBodyBytes; request.MaxResponseBodyBytes > limit {
+		return Rule{}, errCodeInvalidRequest, fmt.Errorf("max_response_body_bytes exceeds the gateway limit of %d", limit)
+	}
+	expiresAt, err := request.resolveExpiresAt(time.Now().UTC())
+	if err != nil {
+		return Rule{}, errCodeInvalidRequest, err
+	}
+	return Rule{
+		ID:                   request.ID,
+		Target:               request.Target,
+		Environment:          request.Environment,
+		Token:                request.Token,
+		MaxRPS:               request.MaxRPS,
+		MaxBytesPerSecond:    request.MaxBytesPerSecond,
+		RequestTimeoutSecs:   request.RequestTimeoutSecs,
+		IdleTimeoutSecs:      request.IdleTimeoutSecs,
+		HeaderTimeoutSecs:    request.HeaderTimeoutSecs,
+		MaxResponseBodyBytes: request.MaxResponseBodyBytes,
+		MaxUploadBytes:       request.MaxUploadBytes,
+		Retry:                request.Retry,
+		ConnectorID:          request.ConnectorID,
+		ConnectorSelector:    request.ConnectorSelector,
+		LocalScheme:          request.LocalScheme,
+		LocalHost:            request.LocalHost,
+		LocalPort:            request.LocalPort,
+		LocalSocket:          request.LocalSocket,
+		LocalTLS:             request.LocalTLS,
+		LocalBasePath:        request.LocalBasePath,
+		ErrorPages:           request.ErrorPages,
+		CORS:                 request.CORS,
+		Presets:              request.Presets,
+		PathRoutes:           request.PathRoutes,
+		Rewrite:              request.Rewrite,
+		ForwardedHeaders:     request.ForwardedHeaders,
+		Idempotency:          request.Idempotency,
+		QueueOverflow:        request.QueueOverflow,
+		SyntheticCheck:       request.SyntheticCheck,
+		TLSPassthrough:       request.TLSPassthrough,
+		Mirror:               request.Mirror,
+		Split:                request.Split,
+		Middleware:           request.Middleware,
+		Mock:                 request.Mock,
+		Capture:              request.Capture,
+		WebhookVerification:  request.WebhookVerification,
+		ActiveFrom:           request.ActiveFrom,
+		ExpiresAt:            expiresAt,
+		DeleteOnExpiry:       request.DeleteOnExpiry,
+	}, "", nil
+}
+
+func (s *Server) writeRouteDryRun(w http.ResponseWriter, tenantID string, input Rule, key string) {
+	candidate, err := s.ruleStore.ValidateForTenant(tenantID, input)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": key + " valid",
+		"dry_run": true,
+		key:       s.buildRouteView(candidate),
+	})
 }
 
 func (s *Server) validateConnectorRouteBinding(tenantID, connectorID string) error {
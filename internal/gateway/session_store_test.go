@@ -0,0 +1,149 @@
+package gateway
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSQLiteSessionsSurviveGatewayRestart(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skipf("sqlite3 not available: %v", err)
+	}
+	cfg := Config{StorageDriver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "proxer.db"), SessionStore: "sqlite"}
+	first := NewServer(cfg, nil)
+	sessionID, err := first.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	restarted := NewServer(cfg, nil)
+	if user, _, ok := restarted.authStore.ResolveSession(sessionID); !ok || user.Username != "admin" {
+		t.Fatalf("expected the session to survive a restart, got %+v ok=%v", user, ok)
+	}
+	if output, err := exec.Command("sqlite3", cfg.SQLitePath, "SELECT count(*) FROM proxer_sessions WHERE token_hash='"+sessionID+"';").CombinedOutput(); err != nil || strings.TrimSpace(string(output)) != "0" {
+		t.Fatalf("expected the raw token not to be stored, got %q %v", output, err)
+	}
+	restarted.authStore.DeleteSession(sessionID)
+	if _, _, ok := NewServer(cfg, nil).authStore.ResolveSession(sessionID); ok {
+		t.Fatalf("expected a logout to end the session for every gateway")
+	}
+}
+
+func TestSQLiteSessionLookupsOfUnknownTokensAreLimited(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skipf("sqlite3 not available: %v", err)
+	}
+	server := NewServer(Config{StorageDriver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "proxer.db"), SessionStore: "sqlite"}, nil)
+	if !server.authStore.SessionCached("made-up") {
+		t.Fatal("expected a token of the wrong form never to be looked up")
+	}
+	lookup := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		server.requireAuth(recorder, req)
+		return recorder.Code
+	}
+	limited := false
+	for i := 0; i < sessionLookupsPerMinute+1; i++ {
+		token, _ := randomToken(32)
+		if code := lookup(token); code == http.StatusTooManyRequests {
+			limited = true
+			break
+		}
+	}
+	if !limited {
+		t.Fatal("expected lookups of made-up tokens to be rate limited")
+	}
+	if code := lookup("made-up"); code != http.StatusUnauthorized {
+		t.Fatalf("expected malformed tokens to be refused without a lookup, got %d", code)
+	}
+}
+
+// serveFakeRedisKeys answers GET, SET and DEL from a map shared by every
+// connection.
+func serveFakeRedisKeys(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	var mu sync.Mutex
+	values := map[string]string{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					args, err := readFakeRedisCommand(reader)
+					if err != nil {
+						return
+					}
+					reply := "-ERR unknown command\r\n"
+					mu.Lock()
+					switch args[0] {
+					case "GET":
+						reply = "$-1\r\n"
+						if value, ok := values[args[1]]; ok {
+							reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+						}
+					case "SET":
+						values[args[1]] = args[2]
+						reply = "+OK\r\n"
+					case "DEL":
+						delete(values, args[1])
+						reply = ":1\r\n"
+					}
+					mu.Unlock()
+					if _, err := io.WriteString(conn, reply); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestRedisSessionsAreSharedAcrossReplicas(t *testing.T) {
+	cfg := Config{StorageDriver: "memory", SessionStore: "redis", RedisURL: "redis://" + serveFakeRedisKeys(t)}
+	first, second := NewServer(cfg, nil), NewServer(cfg, nil)
+
+	sessionID, err := first.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if user, _, ok := second.authStore.ResolveSession(sessionID); !ok || user.Username != "admin" {
+		t.Fatalf("expected the other replica to accept the session, got %+v ok=%v", user, ok)
+	}
+	second.authStore.DeleteSession(sessionID)
+	if _, _, ok := first.authStore.ResolveSession(sessionID); ok {
+		t.Fatalf("expected a logout on one replica to end the session on the other")
+	}
+}
+
+func TestLoadConfigSessionStore(t *testing.T) {
+	t.Setenv("PROXER_SESSION_STORE", "redis")
+	if _, err := LoadConfig(""); err == nil || !strings.Contains(err.Error(), "PROXER_REDIS_URL") {
+		t.Fatalf("expected a redis session store without a url to be refused, got %v", err)
+	}
+	t.Setenv("PROXER_SESSION_STORE", "postgres")
+	if _, err := LoadConfig(""); err == nil || !strings.Contains(err.Error(), "PROXER_SESSION_STORE") {
+		t.Fatalf("expected an unknown session store to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package gateway
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	availabilityResolution = time.Hour
+	availabilityRetention  = 30 * 24 * time.Hour
+	defaultSLAObjective    = 99.9
+)
+
+var slaWindows = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+type AvailabilityBucket struct {
+	Start          time.Time `json:"start"`
+	Requests       int64     `json:"requests"`
+	Errors         int64     `json:"errors"`
+	LatencyMsTotal int64     `json:"latency_ms_total"`
+	Checks         int64     `json:"checks,omitempty"`
+	CheckFailures  int64     `json:"check_failures,omitempty"`
+}
+
+// AvailabilityStore keeps hourly buckets per route for SLA reports, which
+// span longer windows than the per-minute timeseries. Buckets older than the
+// retention window are dropped.
+type AvailabilityStore struct {
+	mu      sync.RWMutex
+	buckets map[string][]AvailabilityBucket
+}
+
+func NewAvailabilityStore() *AvailabilityStore {
+	return &AvailabilityStore{buckets: make(map[string][]AvailabilityBucket)}
+}
+
+func (s *AvailabilityStore) RecordRequest(tunnelKey string, at time.Time, failed bool, latencyMs int64) {
+	s.update(tunnelKey, at, func(bucket *AvailabilityBucket) {
+		bucket.Requests++
+		if failed {
+			bucket.Errors++
+		}
+		bucket.LatencyMsTotal += latencyMs
+	})
+}
+
+func (s *AvailabilityStore) RecordCheck(tunnelKey string, at time.Time, failed bool) {
+	s.update(tunnelKey, at, func(bucket *AvailabilityBucket) {
+		bucket.Checks++
+		if failed {
+			bucket.CheckFailures++
+		}
+	})
+}
+
+func (s *AvailabilityStore) update(tunnelKey string, at time.Time, apply func(*AvailabilityBucket)) {
+	tunnelKey = strings.TrimSpace(tunnelKey)
+	if tunnelKey == "" {
+		return
+	}
+	hour := at.UTC().Truncate(availabilityResolution)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buckets := s.buckets[tunnelKey]
+	index := len(buckets)
+	for index > 0 && buckets[index-1].Start.After(hour) {
+		index--
+	}
+	if index == 0 || !buckets[index-1].Start.Equal(hour) {
+		if n := len(buckets); n > 0 && !hour.After(buckets[n-1].Start.Add(-availabilityRetention)) {
+			return
+		}
+		buckets = append(buckets, AvailabilityBucket{})
+		copy(buckets[index+1:], buckets[index:])
+		buckets[index] = AvailabilityBucket{Start: hour}
+		index++
+	}
+	apply(&buckets[index-1])
+	s.buckets[tunnelKey] = trimAvailability(buckets, buckets[len(buckets)-1].Start.Add(-availabilityRetention))
+}
+
+func (s *AvailabilityStore) PruneTenant(tenantID string, cutoff time.Time) int {
+	prefix := ruleKey(normalizeIdentifier(tenantID), "")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pruned := 0
+	for key, buckets := range s.buckets {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		kept := trimAvailability(buckets, cutoff.Add(-availabilityResolution))
+		pruned += len(buckets) - len(kept)
+		if len(kept) == 0 {
+			delete(s.buckets, key)
+		} else {
+			s.buckets[key] = kept
+		}
+	}
+	return pruned
+}
+
+func (s *AvailabilityStore) Sum(tunnelKey string, window time.Duration, now time.Time) AvailabilityBucket {
+	start := availabilityWindowStart(window, now)
+	total := AvailabilityBucket{Start: start}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, bucket := range s.buckets[strings.TrimSpace(tunnelKey)] {
+		if bucket.Start.Before(start) || bucket.Start.After(now) {
+			continue
+		}
+		total.Requests += bucket.Requests
+		total.Errors += bucket.Errors
+		total.LatencyMsTotal += bucket.LatencyMsTotal
+		total.Checks += bucket.Checks
+		total.CheckFailures += bucket.CheckFailures
+	}
+	return total
+}
+
+func availabilityWindowStart(window time.Duration, now time.Time) time.Time {
+	return now.UTC().Truncate(availabilityResolution).Add(-window + availabilityResolution)
+}
+
+func trimAvailability(buckets []AvailabilityBucket, cutoff time.Time) []AvailabilityBucket {
+	drop := 0
+	for drop < len(buckets) && !buckets[drop].Start.After(cutoff) {
+		drop++
+	}
+	if drop == 0 {
+		return buckets
+	}
+	return append(buckets[:0:0], buckets[drop:]...)
+}
+
+// SLAReport is the availability of a route, or of all routes of a tenant,
+// over a window. AvailabilityPercent is the lower of the request and
+// synthetic check availability, and is absent without traffic or checks.
+type SLAReport struct {
+	RouteID                    string          `json:"route_id,omitempty"`
+	Requests                   int64           `json:"requests"`
+	Errors                     int64           `json:"errors"`
+	RequestAvailabilityPercent *float64        `json:"request_availability_percent,omitempty"`
+	Checks                     int64           `json:"checks"`
+	CheckFailures              int64           `json:"check_failures"`
+	CheckAvailabilityPercent   *float64        `json:"check_availability_percent,omitempty"`
+	AvailabilityPercent        *float64        `json:"availability_percent,omitempty"`
+	MeanLatencyMs              float64         `json:"mean_latency_ms"`
+	ErrorBudget                *SLAErrorBudget `json:"error_budget,omitempty"`
+	MeetsObjective             bool            `json:"meets_objective"`
+}
+
+type SLAErrorBudget struct {
+	AllowedDowntimeSecs   int64   `json:"allowed_downtime_seconds"`
+	EstimatedDowntimeSecs int64   `json:"estimated_downtime_seconds"`
+	ConsumedPercent       float64 `json:"consumed_percent"`
+	RemainingPercent      float64 `json:"remaining_percent"`
+}
+
+func buildSLAReport(routeID string, totals AvailabilityBucket, window time.Duration, objective float64) SLAReport {
+	report := SLAReport{
+		RouteID:        routeID,
+		Requests:       totals.Requests,
+		Errors:         totals.Errors,
+		Checks:         totals.Checks,
+		CheckFailures:  totals.CheckFailures,
+		MeetsObjective: true,
+	}
+	if totals.Requests > 0 {
+		report.MeanLatencyMs = math.Round(float64(totals.LatencyMsTotal)/float64(totals.Requests)*10) / 10
+		report.RequestAvailabilityPercent = percentOf(totals.Requests-totals.Errors, totals.Requests)
+		report.AvailabilityPercent = report.RequestAvailabilityPercent
+	}
+	if totals.Checks > 0 {
+		report.CheckAvailabilityPercent = percentOf(totals.Checks-totals.CheckFailures, totals.Checks)
+		if report.AvailabilityPercent == nil || *report.CheckAvailabilityPercent < *report.AvailabilityPercent {
+			report.AvailabilityPercent = report.CheckAvailabilityPercent
+		}
+	}
+	if report.AvailabilityPercent == nil {
+		return report
+	}
+
+	availability := *report.AvailabilityPercent
+	allowed := 100 - objective
+	consumed := (100 - availability) / allowed * 100
+	report.ErrorBudget = &SLAErrorBudget{
+		AllowedDowntimeSecs:   int64(math.Round(window.Seconds() * allowed / 100)),
+		EstimatedDowntimeSecs: int64(math.Round(window.Seconds() * (100 - availability) / 100)),
+		ConsumedPercent:       roundPercent(consumed),
+		RemainingPercent:      roundPercent(math.Max(0, 100-consumed)),
+	}
+	report.MeetsObjective = availability >= objective
+	return report
+}
+
+func percentOf(part, total int64) *float64 {
+	value := roundPercent(float64(part) / float64(total) * 100)
+	return &value
+}
+
+// roundPercent keeps three decimals, enough to tell 99.9 from 99.95.
+func roundPercent(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+func parseSLAQuery(r *http.Request) (string, time.Duration, float64, error) {
+	windowName := strings.TrimSpace(r.URL.Query().Get("window"))
+	if windowName == "" {
+		windowName = "30d"
+	}
+	window, ok := slaWindows[windowName]
+	if !ok {
+		return "", 0, 0, fmt.Errorf("window must be 24h, 7d or 30d")
+	}
+	objective := defaultSLAObjective
+	if raw := strings.TrimSpace(r.URL.Query().Get("objective")); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value <= 0 || value >= 100 {
+			return "", 0, 0, fmt.Errorf("objective must be a percentage above 0 and below 100")
+		}
+		objective = value
+	}
+	return windowName, window, objective, nil
+}
+
+func (s *Server) handleRouteSLA(w http.ResponseWriter, r *http.Request, tenantID, routeID string) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if _, ok := s.ruleStore.GetForTenant(tenantID, routeID); !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeRouteNotFound, "route not found")
+		return
+	}
+	windowName, window, objective, err := parseSLAQuery(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	totals := s.hub.Availability().Sum(MakeTunnelKey(tenantID, routeID), window, now)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"generated_at":      now,
+		"tenant_id":         tenantID,
+		"window":            windowName,
+		"from":              totals.Start,
+		"objective_percent": objective,
+		"route":             buildSLAReport(routeID, totals, window, objective),
+	})
+}
+
+func (s *Server) handleTenantSLA(w http.ResponseWriter, r *http.Request, tenantID string) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if _, ok := s.ruleStore.GetTenant(tenantID); !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
+		return
+	}
+	windowName, window, objective, err := parseSLAQuery(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	rules := s.ruleStore.ListForTenant(tenantID)
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	routes := make([]SLAReport, 0, len(rules))
+	tenantTotals := AvailabilityBucket{Start: availabilityWindowStart(window, now)}
+	for _, rule := range rules {
+		totals := s.hub.Availability().Sum(MakeTunnelKey(tenantID, rule.ID), window, now)
+		tenantTotals.Requests += totals.Requests
+		tenantTotals.Errors += totals.Errors
+		tenantTotals.LatencyMsTotal += totals.LatencyMsTotal
+		tenantTotals.Checks += totals.Checks
+		tenantTotals.CheckFailures += totals.CheckFailures
+		routes = append(routes, buildSLAReport(rule.ID, totals, window, objective))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"generated_at":      now,
+		"tenant_id":         tenantID,
+		"window":            windowName,
+		"from":              tenantTotals.Start,
+		"objective_percent": objective,
+		"tenant":            buildSLAReport("", tenantTotals, window, objective),
+		"routes":            routes,
+	})
+}
@@ -0,0 +1,54 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAvailabilityStoreSumsWindowsOfHourlyBuckets(t *testing.T) {
+	store := NewAvailabilityStore()
+	now := time.Now().UTC().Truncate(time.Hour).Add(30 * time.Minute)
+
+	for i := 0; i < 998; i++ {
+		store.RecordRequest("default/app", now, false, 20)
+	}
+	store.RecordRequest("default/app", now.Add(-3*time.Hour), true, 0)
+	store.RecordRequest("default/app", now.Add(-3*24*time.Hour), true, 0)
+	store.RecordRequest("default/app", now.Add(-31*24*time.Hour), true, 0)
+	store.RecordCheck("default/app", now, false)
+	store.RecordCheck("default/app", now.Add(-time.Hour), true)
+
+	day := store.Sum("default/app", 24*time.Hour, now)
+	if day.Requests != 999 || day.Errors != 1 || day.Checks != 2 || day.CheckFailures != 1 {
+		t.Fatalf("unexpected 24h totals %+v", day)
+	}
+	if month := store.Sum("default/app", 30*24*time.Hour, now); month.Requests != 1000 || month.Errors != 2 {
+		t.Fatalf("expected the 31 day old request to be dropped, got %+v", month)
+	}
+
+	restored := NewAvailabilityStore()
+	restored.Restore(store.Snapshot())
+	if got := restored.Sum("default/app", 7*24*time.Hour, now); got != store.Sum("default/app", 7*24*time.Hour, now) {
+		t.Fatalf("expected snapshot round trip, got %+v", got)
+	}
+}
+
+func TestBuildSLAReportComputesAvailabilityAndErrorBudget(t *testing.T) {
+	report := buildSLAReport("app", AvailabilityBucket{Requests: 2000, Errors: 1, LatencyMsTotal: 30000}, 30*24*time.Hour, 99.9)
+	if report.AvailabilityPercent == nil || *report.AvailabilityPercent != 99.95 || report.MeanLatencyMs != 15 || !report.MeetsObjective {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if budget := report.ErrorBudget; budget.ConsumedPercent != 50 || budget.RemainingPercent != 50 || budget.AllowedDowntimeSecs != 2592 {
+		t.Fatalf("unexpected error budget %+v", budget)
+	}
+
+	// The synthetic checks saw more downtime than the requests did.
+	report = buildSLAReport("app", AvailabilityBucket{Requests: 2000, Errors: 1, Checks: 100, CheckFailures: 2}, 24*time.Hour, 99.9)
+	if *report.AvailabilityPercent != 98 || report.MeetsObjective || report.ErrorBudget.RemainingPercent != 0 {
+		t.Fatalf("expected the check availability to count, got %+v", report)
+	}
+
+	if empty := buildSLAReport("idle", AvailabilityBucket{}, 24*time.Hour, 99.9); empty.AvailabilityPercent != nil || empty.ErrorBudget != nil || !empty.MeetsObjective {
+		t.Fatalf("expected no availability without data, got %+v", empty)
+	}
+}
@@ -3,12 +3,23 @@ package gateway
 import "time"
 
 type ServerSnapshot struct {
-	Version    int                            `json:"version"`
-	SavedAt    time.Time                      `json:"saved_at"`
-	AuthUsers  []authUserSnapshot             `json:"auth_users"`
-	Rules      ruleStoreSnapshot              `json:"rules"`
-	Connectors connectorStoreSnapshot         `json:"connectors"`
-	Plans      planStoreSnapshot              `json:"plans"`
-	Incidents  incidentStoreSnapshot          `json:"incidents"`
-	TLSRecords []tlsCertificateRecordSnapshot `json:"tls_records"`
+	Version      int                             `json:"version"`
+	SavedAt      time.Time                       `json:"saved_at"`
+	AuthUsers    []authUserSnapshot              `json:"auth_users"`
+	Rules        ruleStoreSnapshot               `json:"rules"`
+	Connectors   connectorStoreSnapshot          `json:"connectors"`
+	Plans        planStoreSnapshot               `json:"plans"`
+	Incidents    incidentStoreSnapshot           `json:"incidents"`
+	Audit        auditStoreSnapshot              `json:"audit"`
+	IPBans       []IPBan                         `json:"ip_bans,omitempty"`
+	Domains      []CustomDomain                  `json:"domains,omitempty"`
+	TLSRecords   []tlsCertificateRecordSnapshot  `json:"tls_records"`
+	Timeseries   map[string][]TimeseriesPoint    `json:"timeseries,omitempty"`
+	Availability map[string][]AvailabilityBucket `json:"availability,omitempty"`
+	Transfer     []TransferRecord                `json:"transfer,omitempty"`
+	Archives     []TenantArchive                 `json:"tenant_archives,omitempty"`
+	Trash        []TrashItem                     `json:"trash,omitempty"`
+	Orgs         orgStoreSnapshot                `json:"orgs"`
+	Forwards     []ReverseForward                `json:"reverse_forwards,omitempty"`
+	SSHKeys      []SSHKey                        `json:"ssh_keys,omitempty"`
 }
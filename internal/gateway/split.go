@@ -0,0 +1,24 @@
+package gateway
+
+type RouteSplit struct {
+	Percent float64 `json:"percent"`
+	RouteUpstream
+}
+
+func normalizeRouteSplit(input *RouteSplit) (*RouteSplit, error) {
+	if input == nil {
+		return nil, nil
+	}
+	if err := normalizeRoutePercent("split", input.Percent); err != nil {
+		return nil, err
+	}
+	upstream, err := normalizeRouteUpstream("split", input.RouteUpstream)
+	if err != nil {
+		return nil, err
+	}
+	return &RouteSplit{Percent: input.Percent, RouteUpstream: upstream}, nil
+}
+
+func (sp *RouteSplit) sample() bool {
+	return sp != nil && samplePercent(sp.Percent)
+}
@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxySplitsTrafficWithPerVariantMetrics(t *testing.T) {
+	stable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "stable")
+	}))
+	defer stable.Close()
+	canary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "canary")
+	}))
+	defer canary.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{
+		ID:     "web",
+		Target: stable.URL,
+		Split:  &RouteSplit{Percent: 100, RouteUpstream: RouteUpstream{Target: canary.URL}},
+	}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/web/", nil))
+	if recorder.Body.String() != "canary" || recorder.Header().Get("X-Proxer-Variant") != "canary" {
+		t.Fatalf("expected canary variant, got %q (%s)", recorder.Body.String(), recorder.Header().Get("X-Proxer-Variant"))
+	}
+
+	rule, _ := server.ruleStore.GetForTenant(DefaultTenantID, "web")
+	view := server.buildRouteViewWithConnected(rule, nil)
+	if view.VariantMetrics[routeVariantCanary].RequestCount != 1 || view.Metrics.RequestCount != 0 {
+		t.Fatalf("expected the request to count for the canary only, got canary=%d primary=%d", view.VariantMetrics[routeVariantCanary].RequestCount, view.Metrics.RequestCount)
+	}
+
+	if _, err := normalizeRouteSplit(&RouteSplit{Percent: 150, RouteUpstream: RouteUpstream{Target: canary.URL}}); err == nil {
+		t.Fatalf("expected split percent above 100 to be rejected")
+	}
+	if tenantID, routeID := ParseTunnelKey(variantTunnelKey("acme", "web", routeVariantCanary)); tenantID != "acme" || routeID != "web" {
+		t.Fatalf("variant keys must resolve to their route, got %s/%s", tenantID, routeID)
+	}
+}
@@ -0,0 +1,236 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHKey is a public key a tenant admin registered for publishing routes
+// with ssh -R. A key belongs to one tenant, so the key alone decides where
+// an SSH client's forwards are published.
+type SSHKey struct {
+	ID          string     `json:"id"`
+	TenantID    string     `json:"tenant_id"`
+	Name        string     `json:"name"`
+	PublicKey   string     `json:"public_key"`
+	Fingerprint string     `json:"fingerprint"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+}
+
+var ErrSSHKeyTaken = errors.New("this public key is already registered")
+
+type SSHKeyStore struct {
+	mu      sync.Mutex
+	counter uint64
+	keys    map[string]SSHKey
+}
+
+func NewSSHKeyStore() *SSHKeyStore {
+	return &SSHKeyStore{keys: make(map[string]SSHKey)}
+}
+
+func parseSSHPublicKey(raw string) (string, string, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(raw)))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid public_key: %w", err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))), ssh.FingerprintSHA256(key), nil
+}
+
+func (s *SSHKeyStore) Add(tenantID, name, publicKey, actor string) (SSHKey, error) {
+	normalized, fingerprint, err := parseSSHPublicKey(publicKey)
+	if err != nil {
+		return SSHKey{}, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = fingerprint
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lookupLocked(fingerprint); ok {
+		return SSHKey{}, ErrSSHKeyTaken
+	}
+	now := time.Now().UTC()
+	key := SSHKey{
+		ID:          fmt.Sprintf("sshkey-%d-%d", now.UnixNano(), atomic.AddUint64(&s.counter, 1)),
+		TenantID:    normalizeIdentifier(tenantID),
+		Name:        name,
+		PublicKey:   normalized,
+		Fingerprint: fingerprint,
+		CreatedBy:   actor,
+		CreatedAt:   now,
+	}
+	s.keys[key.ID] = key
+	return key, nil
+}
+
+func (s *SSHKeyStore) lookupLocked(fingerprint string) (SSHKey, bool) {
+	for _, key := range s.keys {
+		if key.Fingerprint == fingerprint {
+			return key, true
+		}
+	}
+	return SSHKey{}, false
+}
+
+func (s *SSHKeyStore) Authenticate(fingerprint string) (SSHKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.lookupLocked(fingerprint)
+	if !ok {
+		return SSHKey{}, false
+	}
+	now := time.Now().UTC()
+	key.LastUsedAt = &now
+	s.keys[key.ID] = key
+	return key, true
+}
+
+func (s *SSHKeyStore) ListTenant(tenantID string) []SSHKey {
+	tenantID = normalizeIdentifier(tenantID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]SSHKey, 0)
+	for _, key := range s.keys {
+		if key.TenantID == tenantID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+func (s *SSHKeyStore) Delete(tenantID, id string) (SSHKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[strings.TrimSpace(id)]
+	if !ok || key.TenantID != normalizeIdentifier(tenantID) {
+		return SSHKey{}, false
+	}
+	delete(s.keys, key.ID)
+	return key, true
+}
+
+func (s *SSHKeyStore) DeleteTenant(tenantID string) []string {
+	tenantID = normalizeIdentifier(tenantID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0)
+	for id, key := range s.keys {
+		if key.TenantID == tenantID {
+			delete(s.keys, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (s *SSHKeyStore) Snapshot() []SSHKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]SSHKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+func (s *SSHKeyStore) Restore(keys []SSHKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = make(map[string]SSHKey, len(keys))
+	for _, key := range keys {
+		if key.ID == "" || key.TenantID == "" || key.Fingerprint == "" {
+			continue
+		}
+		s.keys[key.ID] = key
+	}
+}
+
+type addSSHKeyRequest struct {
+	Name      string `json:"name,omitempty"`
+	PublicKey string `json:"public_key"`
+}
+
+func (s *Server) handleTenantSSHKeys(w http.ResponseWriter, r *http.Request, user User, tenantID string) {
+	if !s.ruleStore.HasTenant(tenantID) {
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"tenant_id": tenantID,
+			"ssh_addr":  s.SSHAddr(),
+			"ssh_keys":  s.sshKeys.ListTenant(tenantID),
+		})
+	case http.MethodPost:
+		if !s.canMutateTenantConfig(user, tenantID) {
+			writeAPIError(w, http.StatusForbidden, errCodeTenantAdminRequired, "forbidden tenant configuration access")
+			return
+		}
+		var request addSSHKeyRequest
+		if !s.decodeJSON(w, r, &request, "ssh key payload") {
+			return
+		}
+		key, err := s.sshKeys.Add(tenantID, request.Name, request.PublicKey, user.Username)
+		if errors.Is(err, ErrSSHKeyTaken) {
+			writeAPIError(w, http.StatusConflict, errCodeConflict, err.Error())
+			return
+		}
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+			return
+		}
+		s.auditStore.Record(user.Username, "ssh_key.create", tenantID, map[string]string{
+			"ssh_key_id":  key.ID,
+			"fingerprint": key.Fingerprint,
+		})
+		s.persistState()
+		writeJSON(w, http.StatusCreated, map[string]any{"ssh_key": key})
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleTenantSSHKeyByID(w http.ResponseWriter, r *http.Request, user User, tenantID, keyID string) {
+	if r.Method != http.MethodDelete {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.canMutateTenantConfig(user, tenantID) {
+		writeAPIError(w, http.StatusForbidden, errCodeTenantAdminRequired, "forbidden tenant configuration access")
+		return
+	}
+	key, ok := s.sshKeys.Delete(tenantID, keyID)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, "ssh key not found")
+		return
+	}
+	s.sshTunnels.disconnectKeys(key.ID)
+	s.auditStore.Record(user.Username, "ssh_key.delete", tenantID, map[string]string{
+		"ssh_key_id":  key.ID,
+		"fingerprint": key.Fingerprint,
+	})
+	s.persistState()
+	w.WriteHeader(http.StatusNoContent)
+}
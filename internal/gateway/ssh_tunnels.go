@@ -0,0 +1,541 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/szaher/try/proxer/internal/httpx"
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const (
+	// sshPollWait bounds each pull for an SSH client's requests. It stays
+	// well under the hub's session TTL so a quiet client keeps its session.
+	sshPollWait          = 20 * time.Second
+	sshKeepaliveInterval = 30 * time.Second
+	sshHandshakeTimeout  = 10 * time.Second
+
+	sshKeyIDExtension    = "proxer-ssh-key-id"
+	sshTenantIDExtension = "proxer-tenant-id"
+)
+
+// sshTunnels is the SSH front-end: clients authenticate with a tenant's SSH
+// key and publish routes with remote forwards (ssh -R). Each client gets a
+// hub session like an agent, so public requests reach it through the same
+// dispatch path; the gateway relays them over forwarded-tcpip channels.
+type sshTunnels struct {
+	mu       sync.Mutex
+	listener net.Listener
+	clients  map[*sshClient]struct{}
+}
+
+func newSSHTunnels() *sshTunnels {
+	return &sshTunnels{clients: make(map[*sshClient]struct{})}
+}
+
+func (t *sshTunnels) addr() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.listener == nil {
+		return ""
+	}
+	return t.listener.Addr().String()
+}
+
+func (t *sshTunnels) track(client *sshClient) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clients[client] = struct{}{}
+}
+
+func (t *sshTunnels) untrack(client *sshClient) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.clients, client)
+}
+
+func (t *sshTunnels) disconnectKeys(keyIDs ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for client := range t.clients {
+		if slices.Contains(keyIDs, client.keyID) {
+			_ = client.conn.Close()
+		}
+	}
+}
+
+func (s *Server) SSHAddr() string {
+	if addr := s.sshTunnels.addr(); addr != "" {
+		return addr
+	}
+	return s.config().SSHListenAddr
+}
+
+func (s *Server) startSSH(ctx context.Context, cfg Config) error {
+	hostKey, err := loadSSHHostKey(cfg.SSHHostKeyFile)
+	if err != nil {
+		return err
+	}
+	serverConfig := &ssh.ServerConfig{
+		ServerVersion: "SSH-2.0-proxer",
+		PublicKeyCallback: func(meta ssh.ConnMetadata, publicKey ssh.PublicKey) (*ssh.Permissions, error) {
+			key, ok := s.sshKeys.Authenticate(ssh.FingerprintSHA256(publicKey))
+			if !ok {
+				return nil, errors.New("unknown public key")
+			}
+			if _, inactive := s.tenantInactive(key.TenantID); inactive {
+				return nil, fmt.Errorf("tenant %s is suspended", key.TenantID)
+			}
+			return &ssh.Permissions{Extensions: map[string]string{
+				sshKeyIDExtension:    key.ID,
+				sshTenantIDExtension: key.TenantID,
+			}}, nil
+		},
+	}
+	serverConfig.AddHostKey(hostKey)
+	if strings.TrimSpace(cfg.SSHHostKeyFile) == "" {
+		s.logger.Printf("ssh: using a temporary host key %s; set PROXER_SSH_HOST_KEY_FILE to keep it across restarts", ssh.FingerprintSHA256(hostKey.PublicKey()))
+	}
+
+	listener, err := net.Listen("tcp", cfg.SSHListenAddr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", cfg.SSHListenAddr, err)
+	}
+	s.sshTunnels.mu.Lock()
+	s.sshTunnels.listener = listener
+	s.sshTunnels.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+	go func() {
+		for {
+			raw, err := listener.Accept()
+			if err != nil {
+				if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+					s.logger.Printf("ssh: accept: %v", err)
+				}
+				return
+			}
+			go s.serveSSHConn(ctx, raw, serverConfig)
+		}
+	}()
+	return nil
+}
+
+func loadSSHHostKey(path string) (ssh.Signer, error) {
+	path = strings.TrimSpace(path)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			signer, err := ssh.ParsePrivateKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("parse ssh host key %s: %w", path, err)
+			}
+			return signer, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("read ssh host key: %w", err)
+		}
+	}
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate ssh host key: %w", err)
+	}
+	if path != "" {
+		block, err := ssh.MarshalPrivateKey(privateKey, "proxer gateway")
+		if err != nil {
+			return nil, fmt.Errorf("encode ssh host key: %w", err)
+		}
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			return nil, fmt.Errorf("write ssh host key: %w", err)
+		}
+	}
+	return ssh.NewSignerFromKey(privateKey)
+}
+
+// sshForward is one remote forward: the route it publishes and the bind
+// address and port the client asked for, which it expects back on each
+// forwarded-tcpip channel.
+type sshForward struct {
+	routeID string
+	addr    string
+	port    uint32
+}
+
+type sshClient struct {
+	server    *Server
+	conn      *ssh.ServerConn
+	keyID     string
+	tenantID  string
+	sessionID string
+
+	mu       sync.Mutex
+	forwards map[string]sshForward
+	notices  []string
+	consoles map[ssh.Channel]struct{}
+}
+
+func (s *Server) serveSSHConn(ctx context.Context, raw net.Conn, serverConfig *ssh.ServerConfig) {
+	_ = raw.SetDeadline(time.Now().Add(sshHandshakeTimeout))
+	conn, channels, requests, err := ssh.NewServerConn(raw, serverConfig)
+	if err != nil {
+		_ = raw.Close()
+		return
+	}
+	_ = raw.SetDeadline(time.Time{})
+
+	client := &sshClient{
+		server:   s,
+		conn:     conn,
+		keyID:    conn.Permissions.Extensions[sshKeyIDExtension],
+		tenantID: conn.Permissions.Extensions[sshTenantIDExtension],
+		forwards: make(map[string]sshForward),
+		consoles: make(map[ssh.Channel]struct{}),
+	}
+	client.sessionID = s.hub.registerSSHSession(fmt.Sprintf("ssh:%s@%s", client.keyID, conn.RemoteAddr()))
+	s.sshTunnels.track(client)
+	s.logger.Printf("ssh: client connected tenant=%s key=%s user=%s remote=%s", client.tenantID, client.keyID, conn.User(), conn.RemoteAddr())
+
+	ctx, cancel := context.WithCancel(ctx)
+	go client.handleRequests(requests)
+	go client.handleChannels(channels)
+	go client.pull(ctx)
+	go client.keepalive(ctx)
+	_ = conn.Wait()
+
+	cancel()
+	s.sshTunnels.untrack(client)
+	s.hub.unregisterSession(client.sessionID)
+	s.logger.Printf("ssh: client disconnected tenant=%s key=%s remote=%s", client.tenantID, client.keyID, conn.RemoteAddr())
+}
+
+func (c *sshClient) handleRequests(requests <-chan *ssh.Request) {
+	for request := range requests {
+		switch request.Type {
+		case "tcpip-forward":
+			var payload struct {
+				Addr string
+				Port uint32
+			}
+			if err := ssh.Unmarshal(request.Payload, &payload); err != nil {
+				_ = request.Reply(false, nil)
+				continue
+			}
+			port, err := c.forward(payload.Addr, payload.Port)
+			if err != nil {
+				c.notify("proxer: cannot forward %s: %v", payload.Addr, err)
+				_ = request.Reply(false, nil)
+				continue
+			}
+			var reply []byte
+			if payload.Port == 0 {
+				reply = ssh.Marshal(struct{ Port uint32 }{port})
+			}
+			_ = request.Reply(true, reply)
+		case "cancel-tcpip-forward":
+			var payload struct {
+				Addr string
+				Port uint32
+			}
+			if err := ssh.Unmarshal(request.Payload, &payload); err != nil {
+				_ = request.Reply(false, nil)
+				continue
+			}
+			c.cancelForward(payload.Addr, payload.Port)
+			_ = request.Reply(true, nil)
+		default:
+			_ = request.Reply(false, nil)
+		}
+	}
+}
+
+// sshWildcardBind reports whether a forward's bind address names no route,
+// as with ssh -R 80:localhost:3000. Those publish the route named by the SSH
+// user instead.
+func sshWildcardBind(addr string) bool {
+	switch strings.ToLower(strings.TrimSpace(addr)) {
+	case "", "*", "localhost", "0.0.0.0", "127.0.0.1", "::", "::1":
+		return true
+	}
+	return false
+}
+
+func (c *sshClient) forward(addr string, port uint32) (uint32, error) {
+	routeID := normalizeIdentifier(addr)
+	if sshWildcardBind(addr) {
+		routeID = normalizeIdentifier(c.conn.User())
+	}
+	if !identifierPattern.MatchString(routeID) {
+		return 0, fmt.Errorf("invalid route name %q; use ssh -R <route>:80:localhost:<port>", routeID)
+	}
+	if err := c.server.currentNamePolicy().Check(routeID); err != nil {
+		return 0, err
+	}
+	if _, inactive := c.server.tenantInactive(c.tenantID); inactive {
+		return 0, fmt.Errorf("tenant %s is suspended", c.tenantID)
+	}
+	rule, ok := c.server.ruleStore.GetForTenant(c.tenantID, routeID)
+	if !ok {
+		return 0, fmt.Errorf("route %q does not exist; create it under /api/tenants/%s/routes first", routeID, c.tenantID)
+	}
+	if rule.UsesConnector() || rule.Mock != nil {
+		return 0, fmt.Errorf("route %q is served by a connector or mock", routeID)
+	}
+	if port == 0 {
+		port = 80
+	}
+
+	tunnelKey := MakeTunnelKey(c.tenantID, routeID)
+	err := c.server.hub.addSessionTunnel(c.sessionID, protocol.TunnelConfig{
+		ID:     tunnelKey,
+		Target: fmt.Sprintf("ssh://%s@%s", c.conn.User(), c.conn.RemoteAddr()),
+	})
+	if errors.Is(err, ErrTunnelInUse) {
+		return 0, fmt.Errorf("route %q is already connected elsewhere", routeID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	c.forwards[tunnelKey] = sshForward{routeID: routeID, addr: addr, port: port}
+	c.mu.Unlock()
+
+	c.server.auditStore.Record("ssh:"+c.keyID, "ssh.forward", c.tenantID, map[string]string{
+		"route_id":    routeID,
+		"ssh_key_id":  c.keyID,
+		"remote_addr": c.conn.RemoteAddr().String(),
+	})
+	c.notify("Forwarding %s to your ssh -R target", c.server.routePublicURL(c.tenantID, routeID))
+	return port, nil
+}
+
+func (c *sshClient) cancelForward(addr string, port uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for tunnelKey, forward := range c.forwards {
+		if forward.addr == addr && (port == 0 || forward.port == port) {
+			delete(c.forwards, tunnelKey)
+			c.server.hub.removeSessionTunnel(c.sessionID, tunnelKey)
+		}
+	}
+}
+
+func (c *sshClient) notify(format string, args ...any) {
+	line := fmt.Sprintf(format, args...) + "\r\n"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notices = append(c.notices, line)
+	for console := range c.consoles {
+		_, _ = console.Write([]byte(line))
+	}
+}
+
+// handleChannels accepts session channels, which only print notices: ssh
+// without -N opens one and waits on it.
+func (c *sshClient) handleChannels(channels <-chan ssh.NewChannel) {
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "only remote forwards (ssh -R) are supported")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go c.console(channel, requests)
+	}
+}
+
+func (c *sshClient) console(channel ssh.Channel, requests <-chan *ssh.Request) {
+	go func() {
+		for request := range requests {
+			switch request.Type {
+			case "shell", "pty-req", "env", "window-change":
+				_ = request.Reply(true, nil)
+			default:
+				_ = request.Reply(false, nil)
+			}
+		}
+	}()
+
+	c.mu.Lock()
+	c.consoles[channel] = struct{}{}
+	for _, line := range c.notices {
+		_, _ = channel.Write([]byte(line))
+	}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.consoles, channel)
+		c.mu.Unlock()
+		_ = channel.Close()
+	}()
+
+	buf := make([]byte, 256)
+	for {
+		n, err := channel.Read(buf)
+		if err != nil {
+			return
+		}
+		// Ctrl-C or Ctrl-D on the terminal disconnects, as users expect.
+		if bytes.ContainsAny(buf[:n], "\x03\x04") {
+			_ = c.conn.Close()
+			return
+		}
+	}
+}
+
+func (c *sshClient) pull(ctx context.Context) {
+	for ctx.Err() == nil {
+		pollCtx, cancel := context.WithTimeout(ctx, sshPollWait)
+		request, err := c.server.hub.PullRequest(pollCtx, c.sessionID)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			continue
+		}
+		if err != nil {
+			_ = c.conn.Close()
+			return
+		}
+		go func() {
+			_ = c.server.hub.SubmitProxyResponse(c.sessionID, c.roundTrip(ctx, request))
+		}()
+	}
+}
+
+func (c *sshClient) keepalive(ctx context.Context) {
+	ticker := time.NewTicker(sshKeepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = c.conn.Close()
+			return
+		case <-ticker.C:
+			if _, _, err := c.conn.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				_ = c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (c *sshClient) roundTrip(ctx context.Context, proxyReq *protocol.ProxyRequest) *protocol.ProxyResponse {
+	start := time.Now()
+	response := &protocol.ProxyResponse{
+		RequestID: proxyReq.RequestID,
+		TunnelID:  proxyReq.TunnelID,
+		Status:    http.StatusBadGateway,
+		BytesIn:   int64(len(proxyReq.Body)),
+	}
+	fail := func(format string, args ...any) *protocol.ProxyResponse {
+		response.Error = fmt.Sprintf(format, args...)
+		response.LatencyMs = time.Since(start).Milliseconds()
+		return response
+	}
+
+	c.mu.Lock()
+	forward, ok := c.forwards[proxyReq.TunnelID]
+	c.mu.Unlock()
+	if !ok {
+		response.Status = http.StatusNotFound
+		return fail("unknown tunnel id %q", proxyReq.TunnelID)
+	}
+	timeout := c.server.hub.RequestTimeout()
+	if proxyReq.TimeoutMs > 0 {
+		timeout = time.Duration(proxyReq.TimeoutMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	originHost, originPort, _ := net.SplitHostPort(proxyReq.RemoteAddr)
+	port, _ := strconv.ParseUint(originPort, 10, 32)
+	channel, requests, err := c.conn.OpenChannel("forwarded-tcpip", ssh.Marshal(struct {
+		Addr       string
+		Port       uint32
+		OriginAddr string
+		OriginPort uint32
+	}{forward.addr, forward.port, originHost, uint32(port)}))
+	if err != nil {
+		return fail("open forwarded channel: %v", err)
+	}
+	go ssh.DiscardRequests(requests)
+	defer channel.Close()
+	stop := context.AfterFunc(ctx, func() { _ = channel.Close() })
+	defer stop()
+
+	targetURL, err := buildTargetURL("http://localhost", proxyReq.Path, proxyReq.Query)
+	if err != nil {
+		return fail("build target URL: %v", err)
+	}
+	outboundReq, err := http.NewRequestWithContext(ctx, proxyReq.Method, targetURL, bytes.NewReader(proxyReq.Body))
+	if err != nil {
+		return fail("construct outbound request: %v", err)
+	}
+	for header, values := range proxyReq.Headers {
+		if httpx.IsHopByHopHeader(header) || strings.EqualFold(header, "Host") || strings.EqualFold(header, "Content-Length") {
+			continue
+		}
+		for _, value := range values {
+			outboundReq.Header.Add(header, value)
+		}
+	}
+	outboundReq.Header.Set("X-Proxer-Tunnel-ID", proxyReq.TunnelID)
+	outboundReq.Header.Set("X-Proxer-Request-ID", proxyReq.RequestID)
+	if host := strings.TrimSpace(proxyReq.Host); host != "" {
+		outboundReq.Host = host
+	}
+	outboundReq.Close = true
+	httpx.ForwardTrailers(outboundReq, proxyReq.Headers, proxyReq.Trailers)
+
+	if err := outboundReq.Write(channel); err != nil {
+		return fail("write request to ssh client: %v", err)
+	}
+	outboundResp, err := http.ReadResponse(bufio.NewReader(channel), outboundReq)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			response.Status = http.StatusGatewayTimeout
+		}
+		return fail("read response from ssh client: %v", err)
+	}
+	defer outboundResp.Body.Close()
+
+	body, exceeded, err := httpx.ReadLimitedBody(outboundResp.Body, outboundResp.ContentLength, proxyReq.MaxResponseBodyBytes, protocol.ResponseLimitRequest)
+	if exceeded != nil {
+		response.LimitExceeded = exceeded
+		return fail("ssh client response exceeded the %d byte limit", exceeded.LimitBytes)
+	}
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			response.Status = http.StatusGatewayTimeout
+		}
+		return fail("read response from ssh client: %v", err)
+	}
+	response.Status = outboundResp.StatusCode
+	response.Headers = httpx.CloneHTTPHeader(outboundResp.Header)
+	if len(outboundResp.Trailer) > 0 {
+		response.Trailers = httpx.CloneHTTPHeader(outboundResp.Trailer)
+	}
+	response.Body = body
+	response.BytesOut = int64(len(body))
+	response.LatencyMs = time.Since(start).Milliseconds()
+	return response
+}
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// dialSSHForwarder connects as user with signer and answers every
+// forwarded-tcpip channel with the bind address it was opened for.
+func dialSSHForwarder(t *testing.T, addr, user string, signer ssh.Signer) ssh.Conn {
+	t.Helper()
+	netConn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial ssh listener: %v", err)
+	}
+	conn, channels, requests, err := ssh.NewClientConn(netConn, addr, &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("ssh handshake: %v", err)
+	}
+	go ssh.DiscardRequests(requests)
+	go func() {
+		for newChannel := range channels {
+			var payload struct {
+				Addr       string
+				Port       uint32
+				OriginAddr string
+				OriginPort uint32
+			}
+			_ = ssh.Unmarshal(newChannel.ExtraData(), &payload)
+			channel, channelRequests, err := newChannel.Accept()
+			if err != nil {
+				continue
+			}
+			go ssh.DiscardRequests(channelRequests)
+			go func() {
+				defer channel.Close()
+				request, err := http.ReadRequest(bufio.NewReader(channel))
+				if err != nil {
+					return
+				}
+				body := fmt.Sprintf("%s %s via %s:%d", request.Method, request.URL.Path, payload.Addr, payload.Port)
+				fmt.Fprintf(channel, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s", len(body), body)
+			}()
+		}
+	}()
+	return conn
+}
+
+func requestSSHForward(conn ssh.Conn, addr string, port uint32) bool {
+	ok, _, err := conn.SendRequest("tcpip-forward", true, ssh.Marshal(struct {
+		Addr string
+		Port uint32
+	}{addr, port}))
+	return err == nil && ok
+}
+
+func TestSSHRemoteForwardsPublishRoutes(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", SSHListenAddr: "127.0.0.1:0"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := server.startSSH(ctx, server.config()); err != nil {
+		t.Fatalf("start ssh: %v", err)
+	}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "edge", ConnectorID: "laptop", LocalPort: 3000}); err != nil {
+		t.Fatalf("create connector route: %v", err)
+	}
+	for _, id := range []string{"shop", "demo"} {
+		if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: id, Target: "http://127.0.0.1:9"}); err != nil {
+			t.Fatalf("create route: %v", err)
+		}
+	}
+
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(privateKey)
+	session, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+session)
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+	publicKey, _ := json.Marshal(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	recorder := call(http.MethodPost, "/api/tenants/default/ssh-keys", `{"name":"laptop","public_key":`+string(publicKey)+`}`)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("expected the key to be registered, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call(http.MethodPost, "/api/tenants/default/ssh-keys", `{"public_key":`+string(publicKey)+`}`); recorder.Code != http.StatusConflict {
+		t.Fatalf("expected a registered key to be refused again, got %d", recorder.Code)
+	}
+	if recorder := call(http.MethodPost, "/api/tenants/default/ssh-keys", `{"public_key":"ssh-ed25519 nope"}`); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid key to be refused, got %d", recorder.Code)
+	}
+	keys := server.sshKeys.ListTenant(DefaultTenantID)
+	if len(keys) != 1 || keys[0].Fingerprint != ssh.FingerprintSHA256(signer.PublicKey()) {
+		t.Fatalf("expected one key with the client's fingerprint, got %+v", keys)
+	}
+
+	conn := dialSSHForwarder(t, server.SSHAddr(), "demo", signer)
+	defer conn.Close()
+	if !requestSSHForward(conn, "shop", 80) || !requestSSHForward(conn, "localhost", 0) {
+		t.Fatal("expected the named and wildcard forwards to be accepted")
+	}
+	for _, refused := range []string{"bad/name", "api", "edge", "missing"} {
+		if requestSSHForward(conn, refused, 80) {
+			t.Fatalf("expected forward %q to be refused", refused)
+		}
+	}
+	other := dialSSHForwarder(t, server.SSHAddr(), "other", signer)
+	defer other.Close()
+	if requestSSHForward(other, "shop", 80) {
+		t.Fatal("expected a route served by another client to be refused")
+	}
+
+	for path, want := range map[string]string{
+		"/t/default/shop/hello": "GET /hello via shop:80",
+		"/t/default/demo/":      "GET / via localhost:80",
+	} {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusOK || recorder.Body.String() != want {
+			t.Fatalf("%s: expected %q, got %d: %s", path, want, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	if recorder := call(http.MethodDelete, "/api/tenants/default/ssh-keys/"+keys[0].ID, ""); recorder.Code != http.StatusNoContent {
+		t.Fatalf("expected the key to be deleted, got %d", recorder.Code)
+	}
+	closed := make(chan struct{})
+	go func() {
+		_ = conn.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected deleting the key to disconnect its clients")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for server.hub.IsTunnelConnected(MakeTunnelKey(DefaultTenantID, "shop")) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the client's routes to go away with it")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
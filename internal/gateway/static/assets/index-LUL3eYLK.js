@@ -57,4 +57,4 @@ Error generating stack: `+a.message+`
  * @license MIT
  */var jr="popstate";function c0(t={}){function e(a,n){let{pathname:u,search:i,hash:c}=a.location;return Jc("",{pathname:u,search:i,hash:c},n.state&&n.state.usr||null,n.state&&n.state.key||"default")}function l(a,n){return typeof n=="string"?n:on(n)}return s0(e,l,null,t)}function I(t,e){if(t===!1||t===null||typeof t>"u")throw new Error(e)}function ee(t,e){if(!t){typeof console<"u"&&console.warn(e);try{throw new Error(e)}catch{}}}function f0(){return Math.random().toString(36).substring(2,10)}function Ur(t,e){return{usr:t.state,key:t.key,idx:e}}function Jc(t,e,l=null,a){return{pathname:typeof t=="string"?t:t.pathname,search:"",hash:"",...typeof e=="string"?Sa(e):e,state:l,key:e&&e.key||a||f0()}}function on({pathname:t="/",search:e="",hash:l=""}){return e&&e!=="?"&&(t+=e.charAt(0)==="?"?e:"?"+e),l&&l!=="#"&&(t+=l.charAt(0)==="#"?l:"#"+l),t}function Sa(t){let e={};if(t){let l=t.indexOf("#");l>=0&&(e.hash=t.substring(l),t=t.substring(0,l));let a=t.indexOf("?");a>=0&&(e.search=t.substring(a),t=t.substring(0,a)),t&&(e.pathname=t)}return e}function s0(t,e,l,a={}){let{window:n=document.defaultView,v5Compat:u=!1}=a,i=n.history,c="POP",f=null,r=h();r==null&&(r=0,i.replaceState({...i.state,idx:r},""));function h(){return(i.state||{idx:null}).idx}function o(){c="POP";let A=h(),m=A==null?null:A-r;r=A,f&&f({action:c,location:T.location,delta:m})}function d(A,m){c="PUSH";let s=Jc(T.location,A,m);r=h()+1;let y=Ur(s,r),S=T.createHref(s);try{i.pushState(y,"",S)}catch(z){if(z instanceof DOMException&&z.name==="DataCloneError")throw z;n.location.assign(S)}u&&f&&f({action:c,location:T.location,delta:1})}function v(A,m){c="REPLACE";let s=Jc(T.location,A,m);r=h();let y=Ur(s,r),S=T.createHref(s);i.replaceState(y,"",S),u&&f&&f({action:c,location:T.location,delta:0})}function E(A){return r0(A)}let T={get action(){return c},get location(){return t(n,i)},listen(A){if(f)throw new Error("A history only accepts one active listener");return n.addEventListener(jr,o),f=A,()=>{n.removeEventListener(jr,o),f=null}},createHref(A){return e(n,A)},createURL:E,encodeLocation(A){let m=E(A);return{pathname:m.pathname,search:m.search,hash:m.hash}},push:d,replace:v,go(A){return i.go(A)}};return T}function r0(t,e=!1){let l="http://localhost";typeof window<"u"&&(l=window.location.origin!=="null"?window.location.origin:window.location.href),I(l,"No window.location.(origin|href) available to create URL");let a=typeof t=="string"?t:on(t);return a=a.replace(/ $/,"%20"),!e&&a.startsWith("//")&&(a=l+a),new URL(a,l)}function Zh(t,e,l="/"){return o0(t,e,l,!1)}function o0(t,e,l,a){let n=typeof e=="string"?Sa(e):e,u=De(n.pathname||"/",l);if(u==null)return null;let i=wh(t);d0(i);let c=null;for(let f=0;c==null&&f<i.length;++f){let r=z0(u);c=E0(i[f],r,a)}return c}function wh(t,e=[],l=[],a="",n=!1){let u=(i,c,f=n,r)=>{let h={relativePath:r===void 0?i.path||"":r,caseSensitive:i.caseSensitive===!0,childrenIndex:c,route:i};if(h.relativePath.startsWith("/")){if(!h.relativePath.startsWith(a)&&f)return;I(h.relativePath.startsWith(a),`Absolute route path "${h.relativePath}" nested under path "${a}" is not valid. An absolute child route path must start with the combined path of all its parent routes.`),h.relativePath=h.relativePath.slice(a.length)}let o=Ae([a,h.relativePath]),d=l.concat(h);i.children&&i.children.length>0&&(I(i.index!==!0,`Index routes must not have child routes. Please remove all child routes from route path "${o}".`),wh(i.children,e,d,o,f)),!(i.path==null&&!i.index)&&e.push({path:o,score:S0(o,i.index),routesMeta:d})};return t.forEach((i,c)=>{var f;if(i.path===""||!((f=i.path)!=null&&f.includes("?")))u(i,c);else for(let r of Vh(i.path))u(i,c,!0,r)}),e}function Vh(t){let e=t.split("/");if(e.length===0)return[];let[l,...a]=e,n=l.endsWith("?"),u=l.replace(/\?$/,"");if(a.length===0)return n?[u,""]:[u];let i=Vh(a.join("/")),c=[];return c.push(...i.map(f=>f===""?u:[u,f].join("/"))),n&&c.push(...i),c.map(f=>t.startsWith("/")&&f===""?"/":f)}function d0(t){t.sort((e,l)=>e.score!==l.score?l.score-e.score:b0(e.routesMeta.map(a=>a.childrenIndex),l.routesMeta.map(a=>a.childrenIndex)))}var h0=/^:[\w-]+$/,m0=3,y0=2,v0=1,g0=10,p0=-2,Hr=t=>t==="*";function S0(t,e){let l=t.split("/"),a=l.length;return l.some(Hr)&&(a+=p0),e&&(a+=y0),l.filter(n=>!Hr(n)).reduce((n,u)=>n+(h0.test(u)?m0:u===""?v0:g0),a)}function b0(t,e){return t.length===e.length&&t.slice(0,-1).every((a,n)=>a===e[n])?t[t.length-1]-e[e.length-1]:0}function E0(t,e,l=!1){let{routesMeta:a}=t,n={},u="/",i=[];for(let c=0;c<a.length;++c){let f=a[c],r=c===a.length-1,h=u==="/"?e:e.slice(u.length)||"/",o=Lu({path:f.relativePath,caseSensitive:f.caseSensitive,end:r},h),d=f.route;if(!o&&r&&l&&!a[a.length-1].route.index&&(o=Lu({path:f.relativePath,caseSensitive:f.caseSensitive,end:!1},h)),!o)return null;Object.assign(n,o.params),i.push({params:n,pathname:Ae([u,o.pathname]),pathnameBase:O0(Ae([u,o.pathnameBase])),route:d}),o.pathnameBase!=="/"&&(u=Ae([u,o.pathnameBase]))}return i}function Lu(t,e){typeof t=="string"&&(t={path:t,caseSensitive:!1,end:!0});let[l,a]=T0(t.path,t.caseSensitive,t.end),n=e.match(l);if(!n)return null;let u=n[0],i=u.replace(/(.)\/+$/,"$1"),c=n.slice(1);return{params:a.reduce((r,{paramName:h,isOptional:o},d)=>{if(h==="*"){let E=c[d]||"";i=u.slice(0,u.length-E.length).replace(/(.)\/+$/,"$1")}const v=c[d];return o&&!v?r[h]=void 0:r[h]=(v||"").replace(/%2F/g,"/"),r},{}),pathname:u,pathnameBase:i,pattern:t}}function T0(t,e=!1,l=!0){ee(t==="*"||!t.endsWith("*")||t.endsWith("/*"),`Route path "${t}" will be treated as if it were "${t.replace(/\*$/,"/*")}" because the \`*\` character must always follow a \`/\` in the pattern. To get rid of this warning, please change the route path to "${t.replace(/\*$/,"/*")}".`);let a=[],n="^"+t.replace(/\/*\*?$/,"").replace(/^\/*/,"/").replace(/[\\.*+^${}|()[\]]/g,"\\$&").replace(/\/:([\w-]+)(\?)?/g,(i,c,f)=>(a.push({paramName:c,isOptional:f!=null}),f?"/?([^\\/]+)?":"/([^\\/]+)")).replace(/\/([\w-]+)\?(\/|$)/g,"(/$1)?$2");return t.endsWith("*")?(a.push({paramName:"*"}),n+=t==="*"||t==="/*"?"(.*)$":"(?:\\/(.+)|\\/*)$"):l?n+="\\/*$":t!==""&&t!=="/"&&(n+="(?:(?=\\/|$))"),[new RegExp(n,e?void 0:"i"),a]}function z0(t){try{return t.split("/").map(e=>decodeURIComponent(e).replace(/\//g,"%2F")).join("/")}catch(e){return ee(!1,`The URL path "${t}" could not be decoded because it is a malformed URL segment. This is probably due to a bad percent encoding (${e}).`),t}}function De(t,e){if(e==="/")return t;if(!t.toLowerCase().startsWith(e.toLowerCase()))return null;let l=e.endsWith("/")?e.length-1:e.length,a=t.charAt(l);return a&&a!=="/"?null:t.slice(l)||"/"}var A0=/^(?:[a-z][a-z0-9+.-]*:|\/\/)/i;function _0(t,e="/"){let{pathname:l,search:a="",hash:n=""}=typeof t=="string"?Sa(t):t,u;return l?(l=l.replace(/\/\/+/g,"/"),l.startsWith("/")?u=Br(l.substring(1),"/"):u=Br(l,e)):u=e,{pathname:u,search:R0(a),hash:M0(n)}}function Br(t,e){let l=e.replace(/\/+$/,"").split("/");return t.split("/").forEach(n=>{n===".."?l.length>1&&l.pop():n!=="."&&l.push(n)}),l.length>1?l.join("/"):"/"}function $i(t,e,l,a){return`Cannot include a '${t}' character in a manually specified \`to.${e}\` field [${JSON.stringify(a)}].  Please separate it out to the \`to.${l}\` field. Alternatively you may provide the full path as a string in <Link to="..."> and the router will parse it for you.`}function x0(t){return t.filter((e,l)=>l===0||e.route.path&&e.route.path.length>0)}function Ff(t){let e=x0(t);return e.map((l,a)=>a===e.length-1?l.pathname:l.pathnameBase)}function Pf(t,e,l,a=!1){let n;typeof t=="string"?n=Sa(t):(n={...t},I(!n.pathname||!n.pathname.includes("?"),$i("?","pathname","search",n)),I(!n.pathname||!n.pathname.includes("#"),$i("#","pathname","hash",n)),I(!n.search||!n.search.includes("#"),$i("#","search","hash",n)));let u=t===""||n.pathname==="",i=u?"/":n.pathname,c;if(i==null)c=l;else{let o=e.length-1;if(!a&&i.startsWith("..")){let d=i.split("/");for(;d[0]==="..";)d.shift(),o-=1;n.pathname=d.join("/")}c=o>=0?e[o]:"/"}let f=_0(n,c),r=i&&i!=="/"&&i.endsWith("/"),h=(u||i===".")&&l.endsWith("/");return!f.pathname.endsWith("/")&&(r||h)&&(f.pathname+="/"),f}var Ae=t=>t.join("/").replace(/\/\/+/g,"/"),O0=t=>t.replace(/\/+$/,"").replace(/^\/*/,"/"),R0=t=>!t||t==="?"?"":t.startsWith("?")?t:"?"+t,M0=t=>!t||t==="#"?"":t.startsWith("#")?t:"#"+t,D0=class{constructor(t,e,l,a=!1){this.status=t,this.statusText=e||"",this.internal=a,l instanceof Error?(this.data=l.toString(),this.error=l):this.data=l}};function N0(t){return t!=null&&typeof t.status=="number"&&typeof t.statusText=="string"&&typeof t.internal=="boolean"&&"data"in t}function C0(t){return t.map(e=>e.route.path).filter(Boolean).join("/").replace(/\/\/*/g,"/")||"/"}var Kh=typeof window<"u"&&typeof window.document<"u"&&typeof window.document.createElement<"u";function Jh(t,e){let l=t;if(typeof l!="string"||!A0.test(l))return{absoluteURL:void 0,isExternal:!1,to:l};let a=l,n=!1;if(Kh)try{let u=new URL(window.location.href),i=l.startsWith("//")?new URL(u.protocol+l):new URL(l),c=De(i.pathname,e);i.origin===u.origin&&c!=null?l=c+i.search+i.hash:n=!0}catch{ee(!1,`<Link to="${l}"> contains an invalid URL which will probably break when clicked - please update to a valid URL path.`)}return{absoluteURL:a,isExternal:n,to:l}}Object.getOwnPropertyNames(Object.prototype).sort().join("\0");var $h=["POST","PUT","PATCH","DELETE"];new Set($h);var j0=["GET",...$h];new Set(j0);var ba=p.createContext(null);ba.displayName="DataRouter";var ii=p.createContext(null);ii.displayName="DataRouterState";var U0=p.createContext(!1),kh=p.createContext({isTransitioning:!1});kh.displayName="ViewTransition";var H0=p.createContext(new Map);H0.displayName="Fetchers";var B0=p.createContext(null);B0.displayName="Await";var Xt=p.createContext(null);Xt.displayName="Navigation";var An=p.createContext(null);An.displayName="Location";var re=p.createContext({outlet:null,matches:[],isDataRoute:!1});re.displayName="Route";var If=p.createContext(null);If.displayName="RouteError";var Wh="REACT_ROUTER_ERROR",q0="REDIRECT",Y0="ROUTE_ERROR_RESPONSE";function L0(t){if(t.startsWith(`${Wh}:${q0}:{`))try{let e=JSON.parse(t.slice(28));if(typeof e=="object"&&e&&typeof e.status=="number"&&typeof e.statusText=="string"&&typeof e.location=="string"&&typeof e.reloadDocument=="boolean"&&typeof e.replace=="boolean")return e}catch{}}function G0(t){if(t.startsWith(`${Wh}:${Y0}:{`))try{let e=JSON.parse(t.slice(40));if(typeof e=="object"&&e&&typeof e.status=="number"&&typeof e.statusText=="string")return new D0(e.status,e.statusText,e.data)}catch{}}function X0(t,{relative:e}={}){I(Ea(),"useHref() may be used only in the context of a <Router> component.");let{basename:l,navigator:a}=p.useContext(Xt),{hash:n,pathname:u,search:i}=_n(t,{relative:e}),c=u;return l!=="/"&&(c=u==="/"?l:Ae([l,u])),a.createHref({pathname:c,search:i,hash:n})}function Ea(){return p.useContext(An)!=null}function Ce(){return I(Ea(),"useLocation() may be used only in the context of a <Router> component."),p.useContext(An).location}var Fh="You should call navigate() in a React.useEffect(), not when your component is first rendered.";function Ph(t){p.useContext(Xt).static||p.useLayoutEffect(t)}function ci(){let{isDataRoute:t}=p.useContext(re);return t?tg():Q0()}function Q0(){I(Ea(),"useNavigate() may be used only in the context of a <Router> component.");let t=p.useContext(ba),{basename:e,navigator:l}=p.useContext(Xt),{matches:a}=p.useContext(re),{pathname:n}=Ce(),u=JSON.stringify(Ff(a)),i=p.useRef(!1);return Ph(()=>{i.current=!0}),p.useCallback((f,r={})=>{if(ee(i.current,Fh),!i.current)return;if(typeof f=="number"){l.go(f);return}let h=Pf(f,JSON.parse(u),n,r.relative==="path");t==null&&e!=="/"&&(h.pathname=h.pathname==="/"?e:Ae([e,h.pathname])),(r.replace?l.replace:l.push)(h,r.state,r)},[e,l,u,n,t])}p.createContext(null);function _n(t,{relative:e}={}){let{matches:l}=p.useContext(re),{pathname:a}=Ce(),n=JSON.stringify(Ff(l));return p.useMemo(()=>Pf(t,JSON.parse(n),a,e==="path"),[t,n,a,e])}function Z0(t,e){return Ih(t,e)}function Ih(t,e,l,a,n){var s;I(Ea(),"useRoutes() may be used only in the context of a <Router> component.");let{navigator:u}=p.useContext(Xt),{matches:i}=p.useContext(re),c=i[i.length-1],f=c?c.params:{},r=c?c.pathname:"/",h=c?c.pathnameBase:"/",o=c&&c.route;{let y=o&&o.path||"";em(r,!o||y.endsWith("*")||y.endsWith("*?"),`You rendered descendant <Routes> (or called \`useRoutes()\`) at "${r}" (under <Route path="${y}">) but the parent route path has no trailing "*". This means if you navigate deeper, the parent won't match anymore and therefore the child routes will never render.
 
-Please change the parent <Route path="${y}"> to <Route path="${y==="/"?"*":`${y}/*`}">.`)}let d=Ce(),v;if(e){let y=typeof e=="string"?Sa(e):e;I(h==="/"||((s=y.pathname)==null?void 0:s.startsWith(h)),`When overriding the location using \`<Routes location>\` or \`useRoutes(routes, location)\`, the location pathname must begin with the portion of the URL pathname that was matched by all parent routes. The current pathname base is "${h}" but pathname "${y.pathname}" was given in the \`location\` prop.`),v=y}else v=d;let E=v.pathname||"/",T=E;if(h!=="/"){let y=h.replace(/^\//,"").split("/");T="/"+E.replace(/^\//,"").split("/").slice(y.length).join("/")}let A=Zh(t,{pathname:T});ee(o||A!=null,`No routes matched location "${v.pathname}${v.search}${v.hash}" `),ee(A==null||A[A.length-1].route.element!==void 0||A[A.length-1].route.Component!==void 0||A[A.length-1].route.lazy!==void 0,`Matched leaf route at location "${v.pathname}${v.search}${v.hash}" does not have an element or Component. This means it will render an <Outlet /> with a null value by default resulting in an "empty" page.`);let m=$0(A&&A.map(y=>Object.assign({},y,{params:Object.assign({},f,y.params),pathname:Ae([h,u.encodeLocation?u.encodeLocation(y.pathname.replace(/\?/g,"%3F").replace(/#/g,"%23")).pathname:y.pathname]),pathnameBase:y.pathnameBase==="/"?h:Ae([h,u.encodeLocation?u.encodeLocation(y.pathnameBase.replace(/\?/g,"%3F").replace(/#/g,"%23")).pathname:y.pathnameBase])})),i,l,a,n);return e&&m?p.createElement(An.Provider,{value:{location:{pathname:"/",search:"",hash:"",state:null,key:"default",...v},navigationType:"POP"}},m):m}function w0(){let t=I0(),e=N0(t)?`${t.status} ${t.statusText}`:t instanceof Error?t.message:JSON.stringify(t),l=t instanceof Error?t.stack:null,a="rgba(200,200,200, 0.5)",n={padding:"0.5rem",backgroundColor:a},u={padding:"2px 4px",backgroundColor:a},i=null;return console.error("Error handled by React Router default ErrorBoundary:",t),i=p.createElement(p.Fragment,null,p.createElement("p",null,"💿 Hey developer 👋"),p.createElement("p",null,"You can provide a way better UX than this when your app throws errors by providing your own ",p.createElement("code",{style:u},"ErrorBoundary")," or"," ",p.createElement("code",{style:u},"errorElement")," prop on your route.")),p.createElement(p.Fragment,null,p.createElement("h2",null,"Unexpected Application Error!"),p.createElement("h3",{style:{fontStyle:"italic"}},e),l?p.createElement("pre",{style:n},l):null,i)}var V0=p.createElement(w0,null),tm=class extends p.Component{constructor(t){super(t),this.state={location:t.location,revalidation:t.revalidation,error:t.error}}static getDerivedStateFromError(t){return{error:t}}static getDerivedStateFromProps(t,e){return e.location!==t.location||e.revalidation!=="idle"&&t.revalidation==="idle"?{error:t.error,location:t.location,revalidation:t.revalidation}:{error:t.error!==void 0?t.error:e.error,location:e.location,revalidation:t.revalidation||e.revalidation}}componentDidCatch(t,e){this.props.onError?this.props.onError(t,e):console.error("React Router caught the following error during render",t)}render(){let t=this.state.error;if(this.context&&typeof t=="object"&&t&&"digest"in t&&typeof t.digest=="string"){const l=G0(t.digest);l&&(t=l)}let e=t!==void 0?p.createElement(re.Provider,{value:this.props.routeContext},p.createElement(If.Provider,{value:t,children:this.props.component})):this.props.children;return this.context?p.createElement(K0,{error:t},e):e}};tm.contextType=U0;var ki=new WeakMap;function K0({children:t,error:e}){let{basename:l}=p.useContext(Xt);if(typeof e=="object"&&e&&"digest"in e&&typeof e.digest=="string"){let a=L0(e.digest);if(a){let n=ki.get(e);if(n)throw n;let u=Jh(a.location,l);if(Kh&&!ki.get(e))if(u.isExternal||a.reloadDocument)window.location.href=u.absoluteURL||u.to;else{const i=Promise.resolve().then(()=>window.__reactRouterDataRouter.navigate(u.to,{replace:a.replace}));throw ki.set(e,i),i}return p.createElement("meta",{httpEquiv:"refresh",content:`0;url=${u.absoluteURL||u.to}`})}}return t}function J0({routeContext:t,match:e,children:l}){let a=p.useContext(ba);return a&&a.static&&a.staticContext&&(e.route.errorElement||e.route.ErrorBoundary)&&(a.staticContext._deepestRenderedBoundaryId=e.route.id),p.createElement(re.Provider,{value:t},l)}function $0(t,e=[],l=null,a=null,n=null){if(t==null){if(!l)return null;if(l.errors)t=l.matches;else if(e.length===0&&!l.initialized&&l.matches.length>0)t=l.matches;else return null}let u=t,i=l==null?void 0:l.errors;if(i!=null){let h=u.findIndex(o=>o.route.id&&(i==null?void 0:i[o.route.id])!==void 0);I(h>=0,`Could not find a matching route for errors on route IDs: ${Object.keys(i).join(",")}`),u=u.slice(0,Math.min(u.length,h+1))}let c=!1,f=-1;if(l)for(let h=0;h<u.length;h++){let o=u[h];if((o.route.HydrateFallback||o.route.hydrateFallbackElement)&&(f=h),o.route.id){let{loaderData:d,errors:v}=l,E=o.route.loader&&!d.hasOwnProperty(o.route.id)&&(!v||v[o.route.id]===void 0);if(o.route.lazy||E){c=!0,f>=0?u=u.slice(0,f+1):u=[u[0]];break}}}let r=l&&a?(h,o)=>{var d,v;a(h,{location:l.location,params:((v=(d=l.matches)==null?void 0:d[0])==null?void 0:v.params)??{},unstable_pattern:C0(l.matches),errorInfo:o})}:void 0;return u.reduceRight((h,o,d)=>{let v,E=!1,T=null,A=null;l&&(v=i&&o.route.id?i[o.route.id]:void 0,T=o.route.errorElement||V0,c&&(f<0&&d===0?(em("route-fallback",!1,"No `HydrateFallback` element provided to render during initial hydration"),E=!0,A=null):f===d&&(E=!0,A=o.route.hydrateFallbackElement||null)));let m=e.concat(u.slice(0,d+1)),s=()=>{let y;return v?y=T:E?y=A:o.route.Component?y=p.createElement(o.route.Component,null):o.route.element?y=o.route.element:y=h,p.createElement(J0,{match:o,routeContext:{outlet:h,matches:m,isDataRoute:l!=null},children:y})};return l&&(o.route.ErrorBoundary||o.route.errorElement||d===0)?p.createElement(tm,{location:l.location,revalidation:l.revalidation,component:T,error:v,children:s(),routeContext:{outlet:null,matches:m,isDataRoute:!0},onError:r}):s()},null)}function ts(t){return`${t} must be used within a data router.  See https://reactrouter.com/en/main/routers/picking-a-router.`}function k0(t){let e=p.useContext(ba);return I(e,ts(t)),e}function W0(t){let e=p.useContext(ii);return I(e,ts(t)),e}function F0(t){let e=p.useContext(re);return I(e,ts(t)),e}function es(t){let e=F0(t),l=e.matches[e.matches.length-1];return I(l.route.id,`${t} can only be used on routes that contain a unique "id"`),l.route.id}function P0(){return es("useRouteId")}function I0(){var a;let t=p.useContext(If),e=W0("useRouteError"),l=es("useRouteError");return t!==void 0?t:(a=e.errors)==null?void 0:a[l]}function tg(){let{router:t}=k0("useNavigate"),e=es("useNavigate"),l=p.useRef(!1);return Ph(()=>{l.current=!0}),p.useCallback(async(n,u={})=>{ee(l.current,Fh),l.current&&(typeof n=="number"?await t.navigate(n):await t.navigate(n,{fromRouteId:e,...u}))},[t,e])}var qr={};function em(t,e,l){!e&&!qr[t]&&(qr[t]=!0,ee(!1,l))}p.memo(eg);function eg({routes:t,future:e,state:l,onError:a}){return Ih(t,void 0,l,a,e)}function Vn({to:t,replace:e,state:l,relative:a}){I(Ea(),"<Navigate> may be used only in the context of a <Router> component.");let{static:n}=p.useContext(Xt);ee(!n,"<Navigate> must not be used on the initial render in a <StaticRouter>. This is a no-op, but you should modify your code so the <Navigate> is only ever rendered in response to some user interaction or state change.");let{matches:u}=p.useContext(re),{pathname:i}=Ce(),c=ci(),f=Pf(t,Ff(u),i,a==="path"),r=JSON.stringify(f);return p.useEffect(()=>{c(JSON.parse(r),{replace:e,state:l,relative:a})},[c,r,a,e,l]),null}function Ul(t){I(!1,"A <Route> is only ever to be used as the child of <Routes> element, never rendered directly. Please wrap your <Route> in a <Routes>.")}function lg({basename:t="/",children:e=null,location:l,navigationType:a="POP",navigator:n,static:u=!1,unstable_useTransitions:i}){I(!Ea(),"You cannot render a <Router> inside another <Router>. You should never have more than one in your app.");let c=t.replace(/^\/*/,"/"),f=p.useMemo(()=>({basename:c,navigator:n,static:u,unstable_useTransitions:i,future:{}}),[c,n,u,i]);typeof l=="string"&&(l=Sa(l));let{pathname:r="/",search:h="",hash:o="",state:d=null,key:v="default"}=l,E=p.useMemo(()=>{let T=De(r,c);return T==null?null:{location:{pathname:T,search:h,hash:o,state:d,key:v},navigationType:a}},[c,r,h,o,d,v,a]);return ee(E!=null,`<Router basename="${c}"> is not able to match the URL "${r}${h}${o}" because it does not start with the basename, so the <Router> won't render anything.`),E==null?null:p.createElement(Xt.Provider,{value:f},p.createElement(An.Provider,{children:e,value:E}))}function ag({children:t,location:e}){return Z0($c(t),e)}function $c(t,e=[]){let l=[];return p.Children.forEach(t,(a,n)=>{if(!p.isValidElement(a))return;let u=[...e,n];if(a.type===p.Fragment){l.push.apply(l,$c(a.props.children,u));return}I(a.type===Ul,`[${typeof a.type=="string"?a.type:a.type.name}] is not a <Route> component. All component children of <Routes> must be a <Route> or <React.Fragment>`),I(!a.props.index||!a.props.children,"An index route cannot have child routes.");let i={id:a.props.id||u.join("-"),caseSensitive:a.props.caseSensitive,element:a.props.element,Component:a.props.Component,index:a.props.index,path:a.props.path,middleware:a.props.middleware,loader:a.props.loader,action:a.props.action,hydrateFallbackElement:a.props.hydrateFallbackElement,HydrateFallback:a.props.HydrateFallback,errorElement:a.props.errorElement,ErrorBoundary:a.props.ErrorBoundary,hasErrorBoundary:a.props.hasErrorBoundary===!0||a.props.ErrorBoundary!=null||a.props.errorElement!=null,shouldRevalidate:a.props.shouldRevalidate,handle:a.props.handle,lazy:a.props.lazy};a.props.children&&(i.children=$c(a.props.children,u)),l.push(i)}),l}var fu="get",su="application/x-www-form-urlencoded";function fi(t){return typeof HTMLElement<"u"&&t instanceof HTMLElement}function ng(t){return fi(t)&&t.tagName.toLowerCase()==="button"}function ug(t){return fi(t)&&t.tagName.toLowerCase()==="form"}function ig(t){return fi(t)&&t.tagName.toLowerCase()==="input"}function cg(t){return!!(t.metaKey||t.altKey||t.ctrlKey||t.shiftKey)}function fg(t,e){return t.button===0&&(!e||e==="_self")&&!cg(t)}var Kn=null;function sg(){if(Kn===null)try{new FormData(document.createElement("form"),0),Kn=!1}catch{Kn=!0}return Kn}var rg=new Set(["application/x-www-form-urlencoded","multipart/form-data","text/plain"]);function Wi(t){return t!=null&&!rg.has(t)?(ee(!1,`"${t}" is not a valid \`encType\` for \`<Form>\`/\`<fetcher.Form>\` and will default to "${su}"`),null):t}function og(t,e){let l,a,n,u,i;if(ug(t)){let c=t.getAttribute("action");a=c?De(c,e):null,l=t.getAttribute("method")||fu,n=Wi(t.getAttribute("enctype"))||su,u=new FormData(t)}else if(ng(t)||ig(t)&&(t.type==="submit"||t.type==="image")){let c=t.form;if(c==null)throw new Error('Cannot submit a <button> or <input type="submit"> without a <form>');let f=t.getAttribute("formaction")||c.getAttribute("action");if(a=f?De(f,e):null,l=t.getAttribute("formmethod")||c.getAttribute("method")||fu,n=Wi(t.getAttribute("formenctype"))||Wi(c.getAttribute("enctype"))||su,u=new FormData(c,t),!sg()){let{name:r,type:h,value:o}=t;if(h==="image"){let d=r?`${r}.`:"";u.append(`${d}x`,"0"),u.append(`${d}y`,"0")}else r&&u.append(r,o)}}else{if(fi(t))throw new Error('Cannot submit element that is not <form>, <button>, or <input type="submit|image">');l=fu,a=null,n=su,i=t}return u&&n==="text/plain"&&(i=u,u=void 0),{action:a,method:l.toLowerCase(),encType:n,formData:u,body:i}}Object.getOwnPropertyNames(Object.prototype).sort().join("\0");function ls(t,e){if(t===!1||t===null||typeof t>"u")throw new Error(e)}function dg(t,e,l,a){let n=typeof t=="string"?new URL(t,typeof window>"u"?"server://singlefetch/":window.location.origin):t;return l?n.pathname.endsWith("/")?n.pathname=`${n.pathname}_.${a}`:n.pathname=`${n.pathname}.${a}`:n.pathname==="/"?n.pathname=`_root.${a}`:e&&De(n.pathname,e)==="/"?n.pathname=`${e.replace(/\/$/,"")}/_root.${a}`:n.pathname=`${n.pathname.replace(/\/$/,"")}.${a}`,n}async function hg(t,e){if(t.id in e)return e[t.id];try{let l=await import(t.module);return e[t.id]=l,l}catch(l){return console.error(`Error loading route module \`${t.module}\`, reloading page...`),console.error(l),window.__reactRouterContext&&window.__reactRouterContext.isSpaMode,window.location.reload(),new Promise(()=>{})}}function mg(t){return t==null?!1:t.href==null?t.rel==="preload"&&typeof t.imageSrcSet=="string"&&typeof t.imageSizes=="string":typeof t.rel=="string"&&typeof t.href=="string"}async function yg(t,e,l){let a=await Promise.all(t.map(async n=>{let u=e.routes[n.route.id];if(u){let i=await hg(u,l);return i.links?i.links():[]}return[]}));return Sg(a.flat(1).filter(mg).filter(n=>n.rel==="stylesheet"||n.rel==="preload").map(n=>n.rel==="stylesheet"?{...n,rel:"prefetch",as:"style"}:{...n,rel:"prefetch"}))}function Yr(t,e,l,a,n,u){let i=(f,r)=>l[r]?f.route.id!==l[r].route.id:!0,c=(f,r)=>{var h;return l[r].pathname!==f.pathname||((h=l[r].route.path)==null?void 0:h.endsWith("*"))&&l[r].params["*"]!==f.params["*"]};return u==="assets"?e.filter((f,r)=>i(f,r)||c(f,r)):u==="data"?e.filter((f,r)=>{var o;let h=a.routes[f.route.id];if(!h||!h.hasLoader)return!1;if(i(f,r)||c(f,r))return!0;if(f.route.shouldRevalidate){let d=f.route.shouldRevalidate({currentUrl:new URL(n.pathname+n.search+n.hash,window.origin),currentParams:((o=l[0])==null?void 0:o.params)||{},nextUrl:new URL(t,window.origin),nextParams:f.params,defaultShouldRevalidate:!0});if(typeof d=="boolean")return d}return!0}):[]}function vg(t,e,{includeHydrateFallback:l}={}){return gg(t.map(a=>{let n=e.routes[a.route.id];if(!n)return[];let u=[n.module];return n.clientActionModule&&(u=u.concat(n.clientActionModule)),n.clientLoaderModule&&(u=u.concat(n.clientLoaderModule)),l&&n.hydrateFallbackModule&&(u=u.concat(n.hydrateFallbackModule)),n.imports&&(u=u.concat(n.imports)),u}).flat(1))}function gg(t){return[...new Set(t)]}function pg(t){let e={},l=Object.keys(t).sort();for(let a of l)e[a]=t[a];return e}function Sg(t,e){let l=new Set;return new Set(e),t.reduce((a,n)=>{let u=JSON.stringify(pg(n));return l.has(u)||(l.add(u),a.push({key:u,link:n})),a},[])}function lm(){let t=p.useContext(ba);return ls(t,"You must render this element inside a <DataRouterContext.Provider> element"),t}function bg(){let t=p.useContext(ii);return ls(t,"You must render this element inside a <DataRouterStateContext.Provider> element"),t}var as=p.createContext(void 0);as.displayName="FrameworkContext";function am(){let t=p.useContext(as);return ls(t,"You must render this element inside a <HydratedRouter> element"),t}function Eg(t,e){let l=p.useContext(as),[a,n]=p.useState(!1),[u,i]=p.useState(!1),{onFocus:c,onBlur:f,onMouseEnter:r,onMouseLeave:h,onTouchStart:o}=e,d=p.useRef(null);p.useEffect(()=>{if(t==="render"&&i(!0),t==="viewport"){let T=m=>{m.forEach(s=>{i(s.isIntersecting)})},A=new IntersectionObserver(T,{threshold:.5});return d.current&&A.observe(d.current),()=>{A.disconnect()}}},[t]),p.useEffect(()=>{if(a){let T=setTimeout(()=>{i(!0)},100);return()=>{clearTimeout(T)}}},[a]);let v=()=>{n(!0)},E=()=>{n(!1),i(!1)};return l?t!=="intent"?[u,d,{}]:[u,d,{onFocus:Ma(c,v),onBlur:Ma(f,E),onMouseEnter:Ma(r,v),onMouseLeave:Ma(h,E),onTouchStart:Ma(o,v)}]:[!1,d,{}]}function Ma(t,e){return l=>{t&&t(l),l.defaultPrevented||e(l)}}function Tg({page:t,...e}){let{router:l}=lm(),a=p.useMemo(()=>Zh(l.routes,t,l.basename),[l.routes,t,l.basename]);return a?p.createElement(Ag,{page:t,matches:a,...e}):null}function zg(t){let{manifest:e,routeModules:l}=am(),[a,n]=p.useState([]);return p.useEffect(()=>{let u=!1;return yg(t,e,l).then(i=>{u||n(i)}),()=>{u=!0}},[t,e,l]),a}function Ag({page:t,matches:e,...l}){let a=Ce(),{future:n,manifest:u,routeModules:i}=am(),{basename:c}=lm(),{loaderData:f,matches:r}=bg(),h=p.useMemo(()=>Yr(t,e,r,u,a,"data"),[t,e,r,u,a]),o=p.useMemo(()=>Yr(t,e,r,u,a,"assets"),[t,e,r,u,a]),d=p.useMemo(()=>{if(t===a.pathname+a.search+a.hash)return[];let T=new Set,A=!1;if(e.forEach(s=>{var S;let y=u.routes[s.route.id];!y||!y.hasLoader||(!h.some(z=>z.route.id===s.route.id)&&s.route.id in f&&((S=i[s.route.id])!=null&&S.shouldRevalidate)||y.hasClientLoader?A=!0:T.add(s.route.id))}),T.size===0)return[];let m=dg(t,c,n.unstable_trailingSlashAwareDataRequests,"data");return A&&T.size>0&&m.searchParams.set("_routes",e.filter(s=>T.has(s.route.id)).map(s=>s.route.id).join(",")),[m.pathname+m.search]},[c,n.unstable_trailingSlashAwareDataRequests,f,a,u,h,e,t,i]),v=p.useMemo(()=>vg(o,u),[o,u]),E=zg(o);return p.createElement(p.Fragment,null,d.map(T=>p.createElement("link",{key:T,rel:"prefetch",as:"fetch",href:T,...l})),v.map(T=>p.createElement("link",{key:T,rel:"modulepreload",href:T,...l})),E.map(({key:T,link:A})=>p.createElement("link",{key:T,nonce:l.nonce,...A,crossOrigin:A.crossOrigin??l.crossOrigin})))}function _g(...t){return e=>{t.forEach(l=>{typeof l=="function"?l(e):l!=null&&(l.current=e)})}}var xg=typeof window<"u"&&typeof window.document<"u"&&typeof window.document.createElement<"u";try{xg&&(window.__reactRouterVersion="7.13.0")}catch{}function Og({basename:t,children:e,unstable_useTransitions:l,window:a}){let n=p.useRef();n.current==null&&(n.current=c0({window:a,v5Compat:!0}));let u=n.current,[i,c]=p.useState({action:u.action,location:u.location}),f=p.useCallback(r=>{l===!1?c(r):p.startTransition(()=>c(r))},[l]);return p.useLayoutEffect(()=>u.listen(f),[u,f]),p.createElement(lg,{basename:t,children:e,location:i.location,navigationType:i.action,navigator:u,unstable_useTransitions:l})}var nm=/^(?:[a-z][a-z0-9+.-]*:|\/\/)/i,Ot=p.forwardRef(function({onClick:e,discover:l="render",prefetch:a="none",relative:n,reloadDocument:u,replace:i,state:c,target:f,to:r,preventScrollReset:h,viewTransition:o,unstable_defaultShouldRevalidate:d,...v},E){let{basename:T,unstable_useTransitions:A}=p.useContext(Xt),m=typeof r=="string"&&nm.test(r),s=Jh(r,T);r=s.to;let y=X0(r,{relative:n}),[S,z,O]=Eg(a,v),_=Ng(r,{replace:i,state:c,target:f,preventScrollReset:h,relative:n,viewTransition:o,unstable_defaultShouldRevalidate:d,unstable_useTransitions:A});function R(j){e&&e(j),j.defaultPrevented||_(j)}let D=p.createElement("a",{...v,...O,href:s.absoluteURL||y,onClick:s.isExternal||u?e:R,ref:_g(E,z),target:f,"data-discover":!m&&l==="render"?"true":void 0});return S&&!m?p.createElement(p.Fragment,null,D,p.createElement(Tg,{page:y})):D});Ot.displayName="Link";var Rg=p.forwardRef(function({"aria-current":e="page",caseSensitive:l=!1,className:a="",end:n=!1,style:u,to:i,viewTransition:c,children:f,...r},h){let o=_n(i,{relative:r.relative}),d=Ce(),v=p.useContext(ii),{navigator:E,basename:T}=p.useContext(Xt),A=v!=null&&Bg(o)&&c===!0,m=E.encodeLocation?E.encodeLocation(o).pathname:o.pathname,s=d.pathname,y=v&&v.navigation&&v.navigation.location?v.navigation.location.pathname:null;l||(s=s.toLowerCase(),y=y?y.toLowerCase():null,m=m.toLowerCase()),y&&T&&(y=De(y,T)||y);const S=m!=="/"&&m.endsWith("/")?m.length-1:m.length;let z=s===m||!n&&s.startsWith(m)&&s.charAt(S)==="/",O=y!=null&&(y===m||!n&&y.startsWith(m)&&y.charAt(m.length)==="/"),_={isActive:z,isPending:O,isTransitioning:A},R=z?e:void 0,D;typeof a=="function"?D=a(_):D=[a,z?"active":null,O?"pending":null,A?"transitioning":null].filter(Boolean).join(" ");let j=typeof u=="function"?u(_):u;return p.createElement(Ot,{...r,"aria-current":R,className:D,ref:h,style:j,to:i,viewTransition:c},typeof f=="function"?f(_):f)});Rg.displayName="NavLink";var Mg=p.forwardRef(({discover:t="render",fetcherKey:e,navigate:l,reloadDocument:a,replace:n,state:u,method:i=fu,action:c,onSubmit:f,relative:r,preventScrollReset:h,viewTransition:o,unstable_defaultShouldRevalidate:d,...v},E)=>{let{unstable_useTransitions:T}=p.useContext(Xt),A=Ug(),m=Hg(c,{relative:r}),s=i.toLowerCase()==="get"?"get":"post",y=typeof c=="string"&&nm.test(c),S=z=>{if(f&&f(z),z.defaultPrevented)return;z.preventDefault();let O=z.nativeEvent.submitter,_=(O==null?void 0:O.getAttribute("formmethod"))||i,R=()=>A(O||z.currentTarget,{fetcherKey:e,method:_,navigate:l,replace:n,state:u,relative:r,preventScrollReset:h,viewTransition:o,unstable_defaultShouldRevalidate:d});T&&l!==!1?p.startTransition(()=>R()):R()};return p.createElement("form",{ref:E,method:s,action:m,onSubmit:a?f:S,...v,"data-discover":!y&&t==="render"?"true":void 0})});Mg.displayName="Form";function Dg(t){return`${t} must be used within a data router.  See https://reactrouter.com/en/main/routers/picking-a-router.`}function um(t){let e=p.useContext(ba);return I(e,Dg(t)),e}function Ng(t,{target:e,replace:l,state:a,preventScrollReset:n,relative:u,viewTransition:i,unstable_defaultShouldRevalidate:c,unstable_useTransitions:f}={}){let r=ci(),h=Ce(),o=_n(t,{relative:u});return p.useCallback(d=>{if(fg(d,e)){d.preventDefault();let v=l!==void 0?l:on(h)===on(o),E=()=>r(t,{replace:v,state:a,preventScrollReset:n,relative:u,viewTransition:i,unstable_defaultShouldRevalidate:c});f?p.startTransition(()=>E()):E()}},[h,r,o,l,a,e,t,n,u,i,c,f])}var Cg=0,jg=()=>`__${String(++Cg)}__`;function Ug(){let{router:t}=um("useSubmit"),{basename:e}=p.useContext(Xt),l=P0(),a=t.fetch,n=t.navigate;return p.useCallback(async(u,i={})=>{let{action:c,method:f,encType:r,formData:h,body:o}=og(u,e);if(i.navigate===!1){let d=i.fetcherKey||jg();await a(d,l,i.action||c,{unstable_defaultShouldRevalidate:i.unstable_defaultShouldRevalidate,preventScrollReset:i.preventScrollReset,formData:h,body:o,formMethod:i.method||f,formEncType:i.encType||r,flushSync:i.flushSync})}else await n(i.action||c,{unstable_defaultShouldRevalidate:i.unstable_defaultShouldRevalidate,preventScrollReset:i.preventScrollReset,formData:h,body:o,formMethod:i.method||f,formEncType:i.encType||r,replace:i.replace,state:i.state,fromRouteId:l,flushSync:i.flushSync,viewTransition:i.viewTransition})},[a,n,e,l])}function Hg(t,{relative:e}={}){let{basename:l}=p.useContext(Xt),a=p.useContext(re);I(a,"useFormAction must be used inside a RouteContext");let[n]=a.matches.slice(-1),u={..._n(t||".",{relative:e})},i=Ce();if(t==null){u.search=i.search;let c=new URLSearchParams(u.search),f=c.getAll("index");if(f.some(h=>h==="")){c.delete("index"),f.filter(o=>o).forEach(o=>c.append("index",o));let h=c.toString();u.search=h?`?${h}`:""}}return(!t||t===".")&&n.route.index&&(u.search=u.search?u.search.replace(/^\?/,"?index&"):"?index"),l!=="/"&&(u.pathname=u.pathname==="/"?l:Ae([l,u.pathname])),on(u)}function Bg(t,{relative:e}={}){let l=p.useContext(kh);I(l!=null,"`useViewTransitionState` must be used within `react-router-dom`'s `RouterProvider`.  Did you accidentally import `RouterProvider` from `react-router`?");let{basename:a}=um("useViewTransitionState"),n=_n(t,{relative:e});if(!l.isTransitioning)return!1;let u=De(l.currentLocation.pathname,a)||l.currentLocation.pathname,i=De(l.nextLocation.pathname,a)||l.nextLocation.pathname;return Lu(n.pathname,i)!=null||Lu(n.pathname,u)!=null}const qg="modulepreload",Yg=function(t){return"/"+t},Lr={},Lg=function(e,l,a){let n=Promise.resolve();if(l&&l.length>0){document.getElementsByTagName("link");const i=document.querySelector("meta[property=csp-nonce]"),c=(i==null?void 0:i.nonce)||(i==null?void 0:i.getAttribute("nonce"));n=Promise.allSettled(l.map(f=>{if(f=Yg(f),f in Lr)return;Lr[f]=!0;const r=f.endsWith(".css"),h=r?'[rel="stylesheet"]':"";if(document.querySelector(`link[href="${f}"]${h}`))return;const o=document.createElement("link");if(o.rel=r?"stylesheet":qg,r||(o.as="script"),o.crossOrigin="",o.href=f,c&&o.setAttribute("nonce",c),document.head.appendChild(o),r)return new Promise((d,v)=>{o.addEventListener("load",d),o.addEventListener("error",()=>v(new Error(`Unable to preload CSS for ${f}`)))})}))}function u(i){const c=new Event("vite:preloadError",{cancelable:!0});if(c.payload=i,window.dispatchEvent(c),!c.defaultPrevented)throw i}return n.then(i=>{for(const c of i||[])c.status==="rejected"&&u(c.reason);return e().catch(u)})},Gg=p.lazy(()=>Lg(()=>import("./WorkspaceApp-C_zx76mg.js"),[]));class im extends Error{constructor(e,l,a){super(e),this.name="ApiError",this.status=l,this.payload=a}}function Xg(t){return typeof t=="object"&&t!==null}function Gu(t){return t instanceof Error?t.message:typeof t=="string"?t:"Request failed"}async function pl(t,e){const l=new Headers((e==null?void 0:e.headers)??void 0);(e==null?void 0:e.body)!==void 0&&!l.has("Content-Type")&&l.set("Content-Type","application/json");const a=await fetch(t,{credentials:"include",...e,headers:l});if(a.status===204)return null;const i=(a.headers.get("content-type")??"").includes("application/json")?await a.json():await a.text();if(!a.ok){let c=`Request failed (${a.status})`;throw typeof i=="string"&&i.trim()!==""?c=i:Xg(i)&&typeof i.message=="string"&&(c=i.message),new im(c,a.status,i)}return i}function ae(t){if(typeof window>"u"||typeof t.event!="string"||t.event.trim()==="")return;const e={source:"web",page_path:window.location.pathname,...t},l=JSON.stringify(e);try{if(typeof navigator<"u"&&typeof navigator.sendBeacon=="function"){const a=new Blob([l],{type:"application/json"});if(navigator.sendBeacon("/api/public/events",a))return}}catch{}fetch("/api/public/events",{method:"POST",headers:{"Content-Type":"application/json"},body:l,keepalive:!0}).catch(()=>{})}function Da(t){const e=Number(t??0);return Number.isFinite(e)?Math.abs(e)>=100?`${Math.round(e)}`:e.toFixed(2).replace(/\.00$/,""):"0"}function Qg(t){const e=Number(t??0);return!Number.isFinite(e)||e<=0?"-":e<1024*1024?`${Math.round(e/1024)} KB`:`${(e/(1024*1024)).toFixed(1)} MB`}function Zg({onLoggedIn:t}){const e=ci(),[l,a]=p.useState(""),[n,u]=p.useState(""),[i,c]=p.useState(""),[f,r]=p.useState(!1),h=p.useCallback(async o=>{o.preventDefault(),r(!0),c("");try{await pl("/api/auth/login",{method:"POST",body:JSON.stringify({username:l,password:n})}),await t(),e("/app")}catch(d){c(Gu(d))}finally{r(!1)}},[e,t,n,l]);return g.jsxs("main",{className:"auth-shell",children:[g.jsx("div",{className:"orb orb-a"}),g.jsx("div",{className:"orb orb-b"}),g.jsxs("section",{className:"auth-card",children:[g.jsx("h1",{children:"Proxer"}),g.jsx("p",{children:"Route internet traffic to localhost with tenant-scoped governance."}),g.jsxs("form",{className:"stack",onSubmit:h,children:[g.jsxs("label",{children:["Username",g.jsx("input",{value:l,onChange:o=>a(o.target.value),required:!0})]}),g.jsxs("label",{children:["Password",g.jsx("input",{type:"password",value:n,onChange:o=>u(o.target.value),required:!0})]}),g.jsx("button",{type:"submit",disabled:f,children:f?"Logging in...":"Login"})]}),i?g.jsx("p",{className:"status error",children:i}):null,g.jsxs("div",{className:"auth-links",children:[g.jsx(Ot,{to:"/signup",children:"Create account"}),g.jsx(Ot,{to:"/",children:"Back to website"})]})]})]})}function wg({onSignedUp:t}){const e=ci(),[l,a]=p.useState(""),[n,u]=p.useState(""),[i,c]=p.useState(""),[f,r]=p.useState(""),[h,o]=p.useState(!1),d=p.useCallback(async v=>{if(v.preventDefault(),r(""),n!==i){r("Passwords do not match.");return}o(!0),ae({event:"signup_submit",outcome:"attempt"});try{await pl("/api/public/signup",{method:"POST",body:JSON.stringify({username:l,password:n})}),ae({event:"signup_success",outcome:"success"}),await t(),e("/app")}catch(E){r(Gu(E)),ae({event:"signup_failure",outcome:"error"})}finally{o(!1)}},[i,e,t,n,l]);return g.jsxs("main",{className:"auth-shell",children:[g.jsx("div",{className:"orb orb-a"}),g.jsx("div",{className:"orb orb-b"}),g.jsxs("section",{className:"auth-card",children:[g.jsx("h1",{children:"Create Workspace"}),g.jsx("p",{children:"Get instant access with a tenant admin account on the free plan."}),g.jsxs("form",{className:"stack",onSubmit:d,children:[g.jsxs("label",{children:["Username",g.jsx("input",{value:l,onChange:v=>a(v.target.value),required:!0})]}),g.jsxs("label",{children:["Password",g.jsx("input",{type:"password",value:n,minLength:6,onChange:v=>u(v.target.value),required:!0})]}),g.jsxs("label",{children:["Confirm Password",g.jsx("input",{type:"password",value:i,minLength:6,onChange:v=>c(v.target.value),required:!0})]}),g.jsx("button",{type:"submit",disabled:h,children:h?"Creating account...":"Sign up"})]}),f?g.jsx("p",{className:"status error",children:f}):null,g.jsxs("div",{className:"auth-links",children:[g.jsx(Ot,{to:"/login",children:"Already have an account?"}),g.jsx(Ot,{to:"/",children:"Back to website"})]})]})]})}function Vg({me:t}){const[e,l]=p.useState([]),[a,n]=p.useState(null),[u,i]=p.useState(!0),[c,f]=p.useState(!0),[r,h]=p.useState(""),[o,d]=p.useState(""),[v,E]=p.useState("monthly");p.useEffect(()=>{ae({event:"landing_view"});let s=!0;const y=async()=>{i(!0),h("");try{const z=await pl("/api/public/plans");s&&l(z.plans??[])}catch(z){s&&h(Gu(z))}finally{s&&i(!1)}},S=async()=>{f(!0),d("");try{const z=await pl("/api/public/downloads");s&&n(z)}catch(z){s&&d(Gu(z))}finally{s&&f(!1)}};return Promise.all([y(),S()]),()=>{s=!1}},[]);const T=[...e].sort((s,y)=>Number(s.public_order??0)-Number(y.public_order??0)),A=p.useCallback(s=>{s!==v&&(E(s),ae({event:"pricing_toggle",billing:s}))},[v]),m=[{title:"Connector-Based Routing",text:"Pair host agents once, then bind routes to connectors with explicit local target metadata."},{title:"Tenant Isolation",text:"Route, connector, and access boundaries are tenant-scoped with role-aware controls."},{title:"Traffic Governance",text:"Hard limits for RPS and monthly transfer keep usage deterministic under every plan."},{title:"TLS + Cert Control",text:"Super admins manage certificates and active status from one control plane."},{title:"Request Fidelity",text:"Methods, query, headers, cookies, and body are preserved end-to-end."},{title:"Local-First Deploy",text:"Run everything with Docker Compose and no external dependencies for day one."}];return g.jsxs(g.Fragment,{children:[g.jsx("a",{className:"skip-link",href:"#main-marketing",children:"Skip to main content"}),g.jsxs("main",{id:"main-marketing",className:"marketing-site",children:[g.jsxs("section",{className:"marketing-hero-shell","aria-labelledby":"hero-title",children:[g.jsxs("header",{className:"marketing-nav",children:[g.jsxs("div",{className:"hero-brand-wrap",children:[g.jsx("div",{className:"hero-mark",children:"P"}),g.jsxs("div",{children:[g.jsx("strong",{children:"Proxer"}),g.jsx("p",{children:"Public tunnels with governance"})]})]}),g.jsxs("nav",{className:"marketing-links","aria-label":"Marketing navigation",children:[g.jsx("a",{href:"#features",children:"Features"}),g.jsx("a",{href:"#pricing",children:"Plans"}),g.jsx("a",{href:"#downloads",children:"Downloads"}),g.jsx("a",{href:"#faq",children:"FAQ"})]}),g.jsxs("div",{className:"hero-actions",children:[t?g.jsx(Ot,{to:"/app",children:"Open Workspace"}):g.jsx(Ot,{to:"/login",children:"Login"}),t?null:g.jsx(Ot,{className:"cta",to:"/signup",onClick:()=>ae({event:"signup_cta_click",outcome:"header"}),children:"Start Free"})]})]}),g.jsxs("div",{className:"hero-grid",children:[g.jsxs("article",{className:"hero-copy",children:[g.jsx("p",{className:"eyebrow",children:"HTTP/HTTPS localhost exposure for serious teams"}),g.jsx("h1",{id:"hero-title",children:"Ship local apps to the internet with control-plane discipline."}),g.jsx("p",{children:"Proxer combines ngrok-style reachability with role-based tenancy, hard plan enforcement, and operational visibility. Same development speed, better governance."}),g.jsxs("div",{className:"hero-cta",children:[g.jsx(Ot,{className:"cta",to:t?"/app":"/signup",onClick:()=>{t||ae({event:"signup_cta_click",outcome:"hero"})},children:t?"Open Workspace":"Create Workspace"}),g.jsx(Ot,{className:"cta-outline",to:"/login",children:"Sign in"})]}),g.jsxs("div",{className:"hero-proof-row",children:[g.jsx("span",{children:"Protocol scope: HTTP/HTTPS"}),g.jsx("span",{children:"Multi-tenant isolation"}),g.jsx("span",{children:"Docker Compose ready"})]}),g.jsxs("div",{className:"hero-proof-row",children:[g.jsx("span",{children:"Request/response fidelity preserved"}),g.jsx("span",{children:"Deterministic 4xx/5xx failure mapping"}),g.jsx("span",{children:"Super-admin observability"})]})]}),g.jsxs("aside",{className:"hero-terminal",children:[g.jsxs("div",{className:"hero-terminal-head",children:[g.jsx("span",{}),g.jsx("span",{}),g.jsx("span",{}),g.jsx("p",{children:"live-route-preview"})]}),g.jsxs("div",{className:"hero-terminal-body code",children:[g.jsx("p",{children:"$ proxer-agent pair --token <pair_token>"}),g.jsx("p",{children:"connector status: online"}),g.jsx("p",{children:"route: /t/acme/api -> 127.0.0.1:3000"}),g.jsx("p",{children:"tenant cap: 100 rps, 500 GB/month"}),g.jsx("p",{children:"response: 200 OK (47 ms)"})]})]})]})]}),g.jsxs("section",{id:"features",className:"marketing-panel","aria-labelledby":"features-title",children:[g.jsxs("header",{className:"section-head",children:[g.jsx("p",{className:"eyebrow",children:"Why teams switch"}),g.jsx("h2",{id:"features-title",children:"Built for production-minded local development"})]}),g.jsx("div",{className:"feature-grid",children:m.map(s=>g.jsxs("article",{className:"feature-card",children:[g.jsx("h3",{children:s.title}),g.jsx("p",{children:s.text})]},s.title))})]}),g.jsxs("section",{className:"marketing-panel","aria-labelledby":"workflow-title",children:[g.jsxs("header",{className:"section-head",children:[g.jsx("p",{className:"eyebrow",children:"How it works"}),g.jsx("h2",{id:"workflow-title",children:"Three-step setup"})]}),g.jsxs("div",{className:"steps-grid",children:[g.jsxs("article",{children:[g.jsx("span",{children:"1"}),g.jsx("h3",{children:"Create connector"}),g.jsx("p",{children:"Generate a short-lived pairing command from the workspace."})]}),g.jsxs("article",{children:[g.jsx("span",{children:"2"}),g.jsx("h3",{children:"Pair host agent"}),g.jsx("p",{children:"Run the desktop agent on your machine and establish a secure connector session."})]}),g.jsxs("article",{children:[g.jsx("span",{children:"3"}),g.jsx("h3",{children:"Create route"}),g.jsx("p",{children:"Bind public path to local target and start serving traffic instantly."})]})]})]}),g.jsxs("section",{id:"pricing",className:"marketing-panel","aria-labelledby":"pricing-title",children:[g.jsxs("header",{className:"section-head split",children:[g.jsxs("div",{children:[g.jsx("p",{className:"eyebrow",children:"Pricing"}),g.jsx("h2",{id:"pricing-title",children:"Choose the right operational envelope"})]}),g.jsxs("div",{className:"billing-toggle",role:"group","aria-label":"Billing cycle",children:[g.jsx("button",{className:v==="monthly"?"active":"",type:"button",onClick:()=>A("monthly"),children:"Monthly"}),g.jsx("button",{className:v==="annual"?"active":"",type:"button",onClick:()=>A("annual"),children:"Annual"})]})]}),u?g.jsx("p",{role:"status","aria-live":"polite",children:"Loading plans..."}):null,r?g.jsx("p",{className:"status error",children:r}):null,!u&&!r?g.jsx("div",{className:"plan-grid",children:T.map(s=>{const S=String(s.id||"").toLowerCase()==="pro",z=v==="annual"?s.price_annual_usd:s.price_monthly_usd,O=v==="annual"?"year":"month";return g.jsxs("article",{className:`plan-card${S?" highlighted":""}`,children:[S?g.jsx("span",{className:"plan-badge",children:"Most Popular"}):null,g.jsx("h3",{children:s.name}),g.jsxs("p",{className:"plan-price",children:["$",Da(z)," ",g.jsxs("small",{children:["/ ",O]})]}),g.jsx("p",{className:"plan-subprice",children:s.description||"Managed routing plan"}),g.jsxs("ul",{children:[g.jsxs("li",{children:[Da(s.max_routes)," routes"]}),g.jsxs("li",{children:[Da(s.max_connectors)," connectors"]}),g.jsxs("li",{children:[Da(s.max_rps)," requests / sec"]}),g.jsxs("li",{children:[Da(s.max_monthly_gb)," GB transfer / month"]}),g.jsx("li",{children:s.tls_enabled?"TLS certificates included":"No TLS certificate management"})]}),g.jsx(Ot,{className:S?"cta":"cta-outline",to:t?"/app":"/signup",onClick:()=>{t||ae({event:"plan_cta_click",plan_id:s.id,billing:v})},children:t?"Use Plan":"Start Free"})]},s.id)})}):null]}),g.jsxs("section",{id:"downloads",className:"marketing-panel","aria-labelledby":"downloads-title",children:[g.jsxs("header",{className:"section-head",children:[g.jsx("p",{className:"eyebrow",children:"Desktop agent"}),g.jsx("h2",{id:"downloads-title",children:"Download binaries for your host machine"})]}),c?g.jsx("p",{role:"status","aria-live":"polite",children:"Loading downloads..."}):null,o?g.jsx("p",{className:"status error",children:o}):null,!c&&!o?g.jsxs(g.Fragment,{children:[a!=null&&a.available?g.jsx("div",{className:"download-grid",children:(a.downloads??[]).map(s=>g.jsxs("article",{className:"download-card",children:[g.jsx("h3",{children:s.label}),g.jsx("p",{className:"code",children:s.file_name}),g.jsx("p",{children:Qg(s.size_bytes)}),g.jsx("a",{className:"cta-outline",href:s.url,target:"_blank",rel:"noreferrer",onClick:()=>ae({event:"download_click",platform:s.platform,file_name:s.file_name}),children:"Download"})]},`${s.platform}:${s.file_name}`))}):g.jsx("p",{children:(a==null?void 0:a.message)||"Downloads are not available yet."}),g.jsxs("div",{className:"download-meta",children:[a!=null&&a.release_url?g.jsx("a",{href:a.release_url,target:"_blank",rel:"noreferrer",children:"Release page"}):null,a!=null&&a.checksums_url?g.jsx("a",{href:a.checksums_url,target:"_blank",rel:"noreferrer",children:"Checksums"}):null,a!=null&&a.release_notes_url?g.jsx("a",{href:a.release_notes_url,target:"_blank",rel:"noreferrer",children:"Release notes"}):null]})]}):null]}),g.jsxs("section",{id:"faq",className:"marketing-panel","aria-labelledby":"faq-title",children:[g.jsxs("header",{className:"section-head",children:[g.jsx("p",{className:"eyebrow",children:"FAQ"}),g.jsx("h2",{id:"faq-title",children:"Answers before you deploy"})]}),g.jsxs("div",{className:"faq-grid",children:[g.jsxs("article",{children:[g.jsx("h3",{children:"Does Proxer return responses from localhost back to callers?"}),g.jsx("p",{children:"Yes. Gateway dispatches to connector agent, agent calls localhost app, and response is returned."})]}),g.jsxs("article",{children:[g.jsx("h3",{children:"Can I run it entirely local?"}),g.jsx("p",{children:"Yes. The full stack runs with Docker Compose and supports native desktop agents."})]}),g.jsxs("article",{children:[g.jsx("h3",{children:"How are limits enforced?"}),g.jsx("p",{children:"Plan caps and runtime limits are hard enforced with deterministic error responses."})]}),g.jsxs("article",{children:[g.jsx("h3",{children:"Can super admins control plans and certificates?"}),g.jsx("p",{children:"Yes. Super admin pages include users, tenants, plans, TLS certificates, and system status."})]})]})]}),g.jsxs("section",{className:"marketing-final-cta","aria-labelledby":"final-cta-title",children:[g.jsx("h2",{id:"final-cta-title",children:"Ready to expose localhost with operational guardrails?"}),g.jsx("p",{children:"Spin up a workspace in minutes and route your first endpoint to the public internet."}),g.jsxs("div",{className:"hero-cta",children:[g.jsx(Ot,{className:"cta",to:t?"/app":"/signup",onClick:()=>{t||ae({event:"signup_cta_click",outcome:"footer"})},children:t?"Open Workspace":"Create Workspace"}),g.jsx(Ot,{className:"cta-outline",to:"/login",children:"Login"})]})]})]})]})}function Kg(){const t=Ce(),[e,l]=p.useState(null),[a,n]=p.useState(!0),u=p.useCallback(async()=>{n(!0);try{const f=await pl("/api/auth/me");l(f)}catch{l(null)}finally{n(!1)}},[]);p.useEffect(()=>{if(!t.pathname.startsWith("/app")){n(!1);return}u()},[t.pathname,u]);const i=p.useCallback(async(f,r)=>{try{return await pl(f,r)}catch(h){throw h instanceof im&&h.status===401&&l(null),h}},[]),c=p.useCallback(async()=>{try{await pl("/api/auth/logout",{method:"POST"})}catch{}l(null)},[]);return g.jsxs(ag,{children:[g.jsx(Ul,{path:"/",element:g.jsx(Vg,{me:e})}),g.jsx(Ul,{path:"/login",element:a?g.jsx("main",{className:"auth-shell",children:"Checking session..."}):e?g.jsx(Vn,{to:"/app",replace:!0}):g.jsx(Zg,{onLoggedIn:u})}),g.jsx(Ul,{path:"/signup",element:a?g.jsx("main",{className:"auth-shell",children:"Checking session..."}):e?g.jsx(Vn,{to:"/app",replace:!0}):g.jsx(wg,{onSignedUp:u})}),g.jsx(Ul,{path:"/app/*",element:a?g.jsx("main",{className:"auth-shell",children:"Checking session..."}):e?g.jsx(p.Suspense,{fallback:g.jsx("main",{className:"auth-shell",children:"Loading workspace..."}),children:g.jsx(Gg,{me:e,api:i,onLogout:c})}):g.jsx(Vn,{to:"/login",replace:!0})}),g.jsx(Ul,{path:"*",element:g.jsx(Vn,{to:"/",replace:!0})})]})}i0.createRoot(document.getElementById("root")).render(g.jsx(Om.StrictMode,{children:g.jsx(Og,{children:g.jsx(Kg,{})})}));export{g as j,p as r};
+Please change the parent <Route path="${y}"> to <Route path="${y==="/"?"*":`${y}/*`}">.`)}let d=Ce(),v;if(e){let y=typeof e=="string"?Sa(e):e;I(h==="/"||((s=y.pathname)==null?void 0:s.startsWith(h)),`When overriding the location using \`<Routes location>\` or \`useRoutes(routes, location)\`, the location pathname must begin with the portion of the URL pathname that was matched by all parent routes. The current pathname base is "${h}" but pathname "${y.pathname}" was given in the \`location\` prop.`),v=y}else v=d;let E=v.pathname||"/",T=E;if(h!=="/"){let y=h.replace(/^\//,"").split("/");T="/"+E.replace(/^\//,"").split("/").slice(y.length).join("/")}let A=Zh(t,{pathname:T});ee(o||A!=null,`No routes matched location "${v.pathname}${v.search}${v.hash}" `),ee(A==null||A[A.length-1].route.element!==void 0||A[A.length-1].route.Component!==void 0||A[A.length-1].route.lazy!==void 0,`Matched leaf route at location "${v.pathname}${v.search}${v.hash}" does not have an element or Component. This means it will render an <Outlet /> with a null value by default resulting in an "empty" page.`);let m=$0(A&&A.map(y=>Object.assign({},y,{params:Object.assign({},f,y.params),pathname:Ae([h,u.encodeLocation?u.encodeLocation(y.pathname.replace(/\?/g,"%3F").replace(/#/g,"%23")).pathname:y.pathname]),pathnameBase:y.pathnameBase==="/"?h:Ae([h,u.encodeLocation?u.encodeLocation(y.pathnameBase.replace(/\?/g,"%3F").replace(/#/g,"%23")).pathname:y.pathnameBase])})),i,l,a,n);return e&&m?p.createElement(An.Provider,{value:{location:{pathname:"/",search:"",hash:"",state:null,key:"default",...v},navigationType:"POP"}},m):m}function w0(){let t=I0(),e=N0(t)?`${t.status} ${t.statusText}`:t instanceof Error?t.message:JSON.stringify(t),l=t instanceof Error?t.stack:null,a="rgba(200,200,200, 0.5)",n={padding:"0.5rem",backgroundColor:a},u={padding:"2px 4px",backgroundColor:a},i=null;return console.error("Error handled by React Router default ErrorBoundary:",t),i=p.createElement(p.Fragment,null,p.createElement("p",null,"💿 Hey developer 👋"),p.createElement("p",null,"You can provide a way better UX than this when your app throws errors by providing your own ",p.createElement("code",{style:u},"ErrorBoundary")," or"," ",p.createElement("code",{style:u},"errorElement")," prop on your route.")),p.createElement(p.Fragment,null,p.createElement("h2",null,"Unexpected Application Error!"),p.createElement("h3",{style:{fontStyle:"italic"}},e),l?p.createElement("pre",{style:n},l):null,i)}var V0=p.createElement(w0,null),tm=class extends p.Component{constructor(t){super(t),this.state={location:t.location,revalidation:t.revalidation,error:t.error}}static getDerivedStateFromError(t){return{error:t}}static getDerivedStateFromProps(t,e){return e.location!==t.location||e.revalidation!=="idle"&&t.revalidation==="idle"?{error:t.error,location:t.location,revalidation:t.revalidation}:{error:t.error!==void 0?t.error:e.error,location:e.location,revalidation:t.revalidation||e.revalidation}}componentDidCatch(t,e){this.props.onError?this.props.onError(t,e):console.error("React Router caught the following error during render",t)}render(){let t=this.state.error;if(this.context&&typeof t=="object"&&t&&"digest"in t&&typeof t.digest=="string"){const l=G0(t.digest);l&&(t=l)}let e=t!==void 0?p.createElement(re.Provider,{value:this.props.routeContext},p.createElement(If.Provider,{value:t,children:this.props.component})):this.props.children;return this.context?p.createElement(K0,{error:t},e):e}};tm.contextType=U0;var ki=new WeakMap;function K0({children:t,error:e}){let{basename:l}=p.useContext(Xt);if(typeof e=="object"&&e&&"digest"in e&&typeof e.digest=="string"){let a=L0(e.digest);if(a){let n=ki.get(e);if(n)throw n;let u=Jh(a.location,l);if(Kh&&!ki.get(e))if(u.isExternal||a.reloadDocument)window.location.href=u.absoluteURL||u.to;else{const i=Promise.resolve().then(()=>window.__reactRouterDataRouter.navigate(u.to,{replace:a.replace}));throw ki.set(e,i),i}return p.createElement("meta",{httpEquiv:"refresh",content:`0;url=${u.absoluteURL||u.to}`})}}return t}function J0({routeContext:t,match:e,children:l}){let a=p.useContext(ba);return a&&a.static&&a.staticContext&&(e.route.errorElement||e.route.ErrorBoundary)&&(a.staticContext._deepestRenderedBoundaryId=e.route.id),p.createElement(re.Provider,{value:t},l)}function $0(t,e=[],l=null,a=null,n=null){if(t==null){if(!l)return null;if(l.errors)t=l.matches;else if(e.length===0&&!l.initialized&&l.matches.length>0)t=l.matches;else return null}let u=t,i=l==null?void 0:l.errors;if(i!=null){let h=u.findIndex(o=>o.route.id&&(i==null?void 0:i[o.route.id])!==void 0);I(h>=0,`Could not find a matching route for errors on route IDs: ${Object.keys(i).join(",")}`),u=u.slice(0,Math.min(u.length,h+1))}let c=!1,f=-1;if(l)for(let h=0;h<u.length;h++){let o=u[h];if((o.route.HydrateFallback||o.route.hydrateFallbackElement)&&(f=h),o.route.id){let{loaderData:d,errors:v}=l,E=o.route.loader&&!d.hasOwnProperty(o.route.id)&&(!v||v[o.route.id]===void 0);if(o.route.lazy||E){c=!0,f>=0?u=u.slice(0,f+1):u=[u[0]];break}}}let r=l&&a?(h,o)=>{var d,v;a(h,{location:l.location,params:((v=(d=l.matches)==null?void 0:d[0])==null?void 0:v.params)??{},unstable_pattern:C0(l.matches),errorInfo:o})}:void 0;return u.reduceRight((h,o,d)=>{let v,E=!1,T=null,A=null;l&&(v=i&&o.route.id?i[o.route.id]:void 0,T=o.route.errorElement||V0,c&&(f<0&&d===0?(em("route-fallback",!1,"No `HydrateFallback` element provided to render during initial hydration"),E=!0,A=null):f===d&&(E=!0,A=o.route.hydrateFallbackElement||null)));let m=e.concat(u.slice(0,d+1)),s=()=>{let y;return v?y=T:E?y=A:o.route.Component?y=p.createElement(o.route.Component,null):o.route.element?y=o.route.element:y=h,p.createElement(J0,{match:o,routeContext:{outlet:h,matches:m,isDataRoute:l!=null},children:y})};return l&&(o.route.ErrorBoundary||o.route.errorElement||d===0)?p.createElement(tm,{location:l.location,revalidation:l.revalidation,component:T,error:v,children:s(),routeContext:{outlet:null,matches:m,isDataRoute:!0},onError:r}):s()},null)}function ts(t){return`${t} must be used within a data router.  See https://reactrouter.com/en/main/routers/picking-a-router.`}function k0(t){let e=p.useContext(ba);return I(e,ts(t)),e}function W0(t){let e=p.useContext(ii);return I(e,ts(t)),e}function F0(t){let e=p.useContext(re);return I(e,ts(t)),e}function es(t){let e=F0(t),l=e.matches[e.matches.length-1];return I(l.route.id,`${t} can only be used on routes that contain a unique "id"`),l.route.id}function P0(){return es("useRouteId")}function I0(){var a;let t=p.useContext(If),e=W0("useRouteError"),l=es("useRouteError");return t!==void 0?t:(a=e.errors)==null?void 0:a[l]}function tg(){let{router:t}=k0("useNavigate"),e=es("useNavigate"),l=p.useRef(!1);return Ph(()=>{l.current=!0}),p.useCallback(async(n,u={})=>{ee(l.current,Fh),l.current&&(typeof n=="number"?await t.navigate(n):await t.navigate(n,{fromRouteId:e,...u}))},[t,e])}var qr={};function em(t,e,l){!e&&!qr[t]&&(qr[t]=!0,ee(!1,l))}p.memo(eg);function eg({routes:t,future:e,state:l,onError:a}){return Ih(t,void 0,l,a,e)}function Vn({to:t,replace:e,state:l,relative:a}){I(Ea(),"<Navigate> may be used only in the context of a <Router> component.");let{static:n}=p.useContext(Xt);ee(!n,"<Navigate> must not be used on the initial render in a <StaticRouter>. This is a no-op, but you should modify your code so the <Navigate> is only ever rendered in response to some user interaction or state change.");let{matches:u}=p.useContext(re),{pathname:i}=Ce(),c=ci(),f=Pf(t,Ff(u),i,a==="path"),r=JSON.stringify(f);return p.useEffect(()=>{c(JSON.parse(r),{replace:e,state:l,relative:a})},[c,r,a,e,l]),null}function Ul(t){I(!1,"A <Route> is only ever to be used as the child of <Routes> element, never rendered directly. Please wrap your <Route> in a <Routes>.")}function lg({basename:t="/",children:e=null,location:l,navigationType:a="POP",navigator:n,static:u=!1,unstable_useTransitions:i}){I(!Ea(),"You cannot render a <Router> inside another <Router>. You should never have more than one in your app.");let c=t.replace(/^\/*/,"/"),f=p.useMemo(()=>({basename:c,navigator:n,static:u,unstable_useTransitions:i,future:{}}),[c,n,u,i]);typeof l=="string"&&(l=Sa(l));let{pathname:r="/",search:h="",hash:o="",state:d=null,key:v="default"}=l,E=p.useMemo(()=>{let T=De(r,c);return T==null?null:{location:{pathname:T,search:h,hash:o,state:d,key:v},navigationType:a}},[c,r,h,o,d,v,a]);return ee(E!=null,`<Router basename="${c}"> is not able to match the URL "${r}${h}${o}" because it does not start with the basename, so the <Router> won't render anything.`),E==null?null:p.createElement(Xt.Provider,{value:f},p.createElement(An.Provider,{children:e,value:E}))}function ag({children:t,location:e}){return Z0($c(t),e)}function $c(t,e=[]){let l=[];return p.Children.forEach(t,(a,n)=>{if(!p.isValidElement(a))return;let u=[...e,n];if(a.type===p.Fragment){l.push.apply(l,$c(a.props.children,u));return}I(a.type===Ul,`[${typeof a.type=="string"?a.type:a.type.name}] is not a <Route> component. All component children of <Routes> must be a <Route> or <React.Fragment>`),I(!a.props.index||!a.props.children,"An index route cannot have child routes.");let i={id:a.props.id||u.join("-"),caseSensitive:a.props.caseSensitive,element:a.props.element,Component:a.props.Component,index:a.props.index,path:a.props.path,middleware:a.props.middleware,loader:a.props.loader,action:a.props.action,hydrateFallbackElement:a.props.hydrateFallbackElement,HydrateFallback:a.props.HydrateFallback,errorElement:a.props.errorElement,ErrorBoundary:a.props.ErrorBoundary,hasErrorBoundary:a.props.hasErrorBoundary===!0||a.props.ErrorBoundary!=null||a.props.errorElement!=null,shouldRevalidate:a.props.shouldRevalidate,handle:a.props.handle,lazy:a.props.lazy};a.props.children&&(i.children=$c(a.props.children,u)),l.push(i)}),l}var fu="get",su="application/x-www-form-urlencoded";function fi(t){return typeof HTMLElement<"u"&&t instanceof HTMLElement}function ng(t){return fi(t)&&t.tagName.toLowerCase()==="button"}function ug(t){return fi(t)&&t.tagName.toLowerCase()==="form"}function ig(t){return fi(t)&&t.tagName.toLowerCase()==="input"}function cg(t){return!!(t.metaKey||t.altKey||t.ctrlKey||t.shiftKey)}function fg(t,e){return t.button===0&&(!e||e==="_self")&&!cg(t)}var Kn=null;function sg(){if(Kn===null)try{new FormData(document.createElement("form"),0),Kn=!1}catch{Kn=!0}return Kn}var rg=new Set(["application/x-www-form-urlencoded","multipart/form-data","text/plain"]);function Wi(t){return t!=null&&!rg.has(t)?(ee(!1,`"${t}" is not a valid \`encType\` for \`<Form>\`/\`<fetcher.Form>\` and will default to "${su}"`),null):t}function og(t,e){let l,a,n,u,i;if(ug(t)){let c=t.getAttribute("action");a=c?De(c,e):null,l=t.getAttribute("method")||fu,n=Wi(t.getAttribute("enctype"))||su,u=new FormData(t)}else if(ng(t)||ig(t)&&(t.type==="submit"||t.type==="image")){let c=t.form;if(c==null)throw new Error('Cannot submit a <button> or <input type="submit"> without a <form>');let f=t.getAttribute("formaction")||c.getAttribute("action");if(a=f?De(f,e):null,l=t.getAttribute("formmethod")||c.getAttribute("method")||fu,n=Wi(t.getAttribute("formenctype"))||Wi(c.getAttribute("enctype"))||su,u=new FormData(c,t),!sg()){let{name:r,type:h,value:o}=t;if(h==="image"){let d=r?`${r}.`:"";u.append(`${d}x`,"0"),u.append(`${d}y`,"0")}else r&&u.append(r,o)}}else{if(fi(t))throw new Error('Cannot submit element that is not <form>, <button>, or <input type="submit|image">');l=fu,a=null,n=su,i=t}return u&&n==="text/plain"&&(i=u,u=void 0),{action:a,method:l.toLowerCase(),encType:n,formData:u,body:i}}Object.getOwnPropertyNames(Object.prototype).sort().join("\0");function ls(t,e){if(t===!1||t===null||typeof t>"u")throw new Error(e)}function dg(t,e,l,a){let n=typeof t=="string"?new URL(t,typeof window>"u"?"server://singlefetch/":window.location.origin):t;return l?n.pathname.endsWith("/")?n.pathname=`${n.pathname}_.${a}`:n.pathname=`${n.pathname}.${a}`:n.pathname==="/"?n.pathname=`_root.${a}`:e&&De(n.pathname,e)==="/"?n.pathname=`${e.replace(/\/$/,"")}/_root.${a}`:n.pathname=`${n.pathname.replace(/\/$/,"")}.${a}`,n}async function hg(t,e){if(t.id in e)return e[t.id];try{let l=await import(t.module);return e[t.id]=l,l}catch(l){return console.error(`Error loading route module \`${t.module}\`, reloading page...`),console.error(l),window.__reactRouterContext&&window.__reactRouterContext.isSpaMode,window.location.reload(),new Promise(()=>{})}}function mg(t){return t==null?!1:t.href==null?t.rel==="preload"&&typeof t.imageSrcSet=="string"&&typeof t.imageSizes=="string":typeof t.rel=="string"&&typeof t.href=="string"}async function yg(t,e,l){let a=await Promise.all(t.map(async n=>{let u=e.routes[n.route.id];if(u){let i=await hg(u,l);return i.links?i.links():[]}return[]}));return Sg(a.flat(1).filter(mg).filter(n=>n.rel==="stylesheet"||n.rel==="preload").map(n=>n.rel==="stylesheet"?{...n,rel:"prefetch",as:"style"}:{...n,rel:"prefetch"}))}function Yr(t,e,l,a,n,u){let i=(f,r)=>l[r]?f.route.id!==l[r].route.id:!0,c=(f,r)=>{var h;return l[r].pathname!==f.pathname||((h=l[r].route.path)==null?void 0:h.endsWith("*"))&&l[r].params["*"]!==f.params["*"]};return u==="assets"?e.filter((f,r)=>i(f,r)||c(f,r)):u==="data"?e.filter((f,r)=>{var o;let h=a.routes[f.route.id];if(!h||!h.hasLoader)return!1;if(i(f,r)||c(f,r))return!0;if(f.route.shouldRevalidate){let d=f.route.shouldRevalidate({currentUrl:new URL(n.pathname+n.search+n.hash,window.origin),currentParams:((o=l[0])==null?void 0:o.params)||{},nextUrl:new URL(t,window.origin),nextParams:f.params,defaultShouldRevalidate:!0});if(typeof d=="boolean")return d}return!0}):[]}function vg(t,e,{includeHydrateFallback:l}={}){return gg(t.map(a=>{let n=e.routes[a.route.id];if(!n)return[];let u=[n.module];return n.clientActionModule&&(u=u.concat(n.clientActionModule)),n.clientLoaderModule&&(u=u.concat(n.clientLoaderModule)),l&&n.hydrateFallbackModule&&(u=u.concat(n.hydrateFallbackModule)),n.imports&&(u=u.concat(n.imports)),u}).flat(1))}function gg(t){return[...new Set(t)]}function pg(t){let e={},l=Object.keys(t).sort();for(let a of l)e[a]=t[a];return e}function Sg(t,e){let l=new Set;return new Set(e),t.reduce((a,n)=>{let u=JSON.stringify(pg(n));return l.has(u)||(l.add(u),a.push({key:u,link:n})),a},[])}function lm(){let t=p.useContext(ba);return ls(t,"You must render this element inside a <DataRouterContext.Provider> element"),t}function bg(){let t=p.useContext(ii);return ls(t,"You must render this element inside a <DataRouterStateContext.Provider> element"),t}var as=p.createContext(void 0);as.displayName="FrameworkContext";function am(){let t=p.useContext(as);return ls(t,"You must render this element inside a <HydratedRouter> element"),t}function Eg(t,e){let l=p.useContext(as),[a,n]=p.useState(!1),[u,i]=p.useState(!1),{onFocus:c,onBlur:f,onMouseEnter:r,onMouseLeave:h,onTouchStart:o}=e,d=p.useRef(null);p.useEffect(()=>{if(t==="render"&&i(!0),t==="viewport"){let T=m=>{m.forEach(s=>{i(s.isIntersecting)})},A=new IntersectionObserver(T,{threshold:.5});return d.current&&A.observe(d.current),()=>{A.disconnect()}}},[t]),p.useEffect(()=>{if(a){let T=setTimeout(()=>{i(!0)},100);return()=>{clearTimeout(T)}}},[a]);let v=()=>{n(!0)},E=()=>{n(!1),i(!1)};return l?t!=="intent"?[u,d,{}]:[u,d,{onFocus:Ma(c,v),onBlur:Ma(f,E),onMouseEnter:Ma(r,v),onMouseLeave:Ma(h,E),onTouchStart:Ma(o,v)}]:[!1,d,{}]}function Ma(t,e){return l=>{t&&t(l),l.defaultPrevented||e(l)}}function Tg({page:t,...e}){let{router:l}=lm(),a=p.useMemo(()=>Zh(l.routes,t,l.basename),[l.routes,t,l.basename]);return a?p.createElement(Ag,{page:t,matches:a,...e}):null}function zg(t){let{manifest:e,routeModules:l}=am(),[a,n]=p.useState([]);return p.useEffect(()=>{let u=!1;return yg(t,e,l).then(i=>{u||n(i)}),()=>{u=!0}},[t,e,l]),a}function Ag({page:t,matches:e,...l}){let a=Ce(),{future:n,manifest:u,routeModules:i}=am(),{basename:c}=lm(),{loaderData:f,matches:r}=bg(),h=p.useMemo(()=>Yr(t,e,r,u,a,"data"),[t,e,r,u,a]),o=p.useMemo(()=>Yr(t,e,r,u,a,"assets"),[t,e,r,u,a]),d=p.useMemo(()=>{if(t===a.pathname+a.search+a.hash)return[];let T=new Set,A=!1;if(e.forEach(s=>{var S;let y=u.routes[s.route.id];!y||!y.hasLoader||(!h.some(z=>z.route.id===s.route.id)&&s.route.id in f&&((S=i[s.route.id])!=null&&S.shouldRevalidate)||y.hasClientLoader?A=!0:T.add(s.route.id))}),T.size===0)return[];let m=dg(t,c,n.unstable_trailingSlashAwareDataRequests,"data");return A&&T.size>0&&m.searchParams.set("_routes",e.filter(s=>T.has(s.route.id)).map(s=>s.route.id).join(",")),[m.pathname+m.search]},[c,n.unstable_trailingSlashAwareDataRequests,f,a,u,h,e,t,i]),v=p.useMemo(()=>vg(o,u),[o,u]),E=zg(o);return p.createElement(p.Fragment,null,d.map(T=>p.createElement("link",{key:T,rel:"prefetch",as:"fetch",href:T,...l})),v.map(T=>p.createElement("link",{key:T,rel:"modulepreload",href:T,...l})),E.map(({key:T,link:A})=>p.createElement("link",{key:T,nonce:l.nonce,...A,crossOrigin:A.crossOrigin??l.crossOrigin})))}function _g(...t){return e=>{t.forEach(l=>{typeof l=="function"?l(e):l!=null&&(l.current=e)})}}var xg=typeof window<"u"&&typeof window.document<"u"&&typeof window.document.createElement<"u";try{xg&&(window.__reactRouterVersion="7.13.0")}catch{}function Og({basename:t,children:e,unstable_useTransitions:l,window:a}){let n=p.useRef();n.current==null&&(n.current=c0({window:a,v5Compat:!0}));let u=n.current,[i,c]=p.useState({action:u.action,location:u.location}),f=p.useCallback(r=>{l===!1?c(r):p.startTransition(()=>c(r))},[l]);return p.useLayoutEffect(()=>u.listen(f),[u,f]),p.createElement(lg,{basename:t,children:e,location:i.location,navigationType:i.action,navigator:u,unstable_useTransitions:l})}var nm=/^(?:[a-z][a-z0-9+.-]*:|\/\/)/i,Ot=p.forwardRef(function({onClick:e,discover:l="render",prefetch:a="none",relative:n,reloadDocument:u,replace:i,state:c,target:f,to:r,preventScrollReset:h,viewTransition:o,unstable_defaultShouldRevalidate:d,...v},E){let{basename:T,unstable_useTransitions:A}=p.useContext(Xt),m=typeof r=="string"&&nm.test(r),s=Jh(r,T);r=s.to;let y=X0(r,{relative:n}),[S,z,O]=Eg(a,v),_=Ng(r,{replace:i,state:c,target:f,preventScrollReset:h,relative:n,viewTransition:o,unstable_defaultShouldRevalidate:d,unstable_useTransitions:A});function R(j){e&&e(j),j.defaultPrevented||_(j)}let D=p.createElement("a",{...v,...O,href:s.absoluteURL||y,onClick:s.isExternal||u?e:R,ref:_g(E,z),target:f,"data-discover":!m&&l==="render"?"true":void 0});return S&&!m?p.createElement(p.Fragment,null,D,p.createElement(Tg,{page:y})):D});Ot.displayName="Link";var Rg=p.forwardRef(function({"aria-current":e="page",caseSensitive:l=!1,className:a="",end:n=!1,style:u,to:i,viewTransition:c,children:f,...r},h){let o=_n(i,{relative:r.relative}),d=Ce(),v=p.useContext(ii),{navigator:E,basename:T}=p.useContext(Xt),A=v!=null&&Bg(o)&&c===!0,m=E.encodeLocation?E.encodeLocation(o).pathname:o.pathname,s=d.pathname,y=v&&v.navigation&&v.navigation.location?v.navigation.location.pathname:null;l||(s=s.toLowerCase(),y=y?y.toLowerCase():null,m=m.toLowerCase()),y&&T&&(y=De(y,T)||y);const S=m!=="/"&&m.endsWith("/")?m.length-1:m.length;let z=s===m||!n&&s.startsWith(m)&&s.charAt(S)==="/",O=y!=null&&(y===m||!n&&y.startsWith(m)&&y.charAt(m.length)==="/"),_={isActive:z,isPending:O,isTransitioning:A},R=z?e:void 0,D;typeof a=="function"?D=a(_):D=[a,z?"active":null,O?"pending":null,A?"transitioning":null].filter(Boolean).join(" ");let j=typeof u=="function"?u(_):u;return p.createElement(Ot,{...r,"aria-current":R,className:D,ref:h,style:j,to:i,viewTransition:c},typeof f=="function"?f(_):f)});Rg.displayName="NavLink";var Mg=p.forwardRef(({discover:t="render",fetcherKey:e,navigate:l,reloadDocument:a,replace:n,state:u,method:i=fu,action:c,onSubmit:f,relative:r,preventScrollReset:h,viewTransition:o,unstable_defaultShouldRevalidate:d,...v},E)=>{let{unstable_useTransitions:T}=p.useContext(Xt),A=Ug(),m=Hg(c,{relative:r}),s=i.toLowerCase()==="get"?"get":"post",y=typeof c=="string"&&nm.test(c),S=z=>{if(f&&f(z),z.defaultPrevented)return;z.preventDefault();let O=z.nativeEvent.submitter,_=(O==null?void 0:O.getAttribute("formmethod"))||i,R=()=>A(O||z.currentTarget,{fetcherKey:e,method:_,navigate:l,replace:n,state:u,relative:r,preventScrollReset:h,viewTransition:o,unstable_defaultShouldRevalidate:d});T&&l!==!1?p.startTransition(()=>R()):R()};return p.createElement("form",{ref:E,method:s,action:m,onSubmit:a?f:S,...v,"data-discover":!y&&t==="render"?"true":void 0})});Mg.displayName="Form";function Dg(t){return`${t} must be used within a data router.  See https://reactrouter.com/en/main/routers/picking-a-router.`}function um(t){let e=p.useContext(ba);return I(e,Dg(t)),e}function Ng(t,{target:e,replace:l,state:a,preventScrollReset:n,relative:u,viewTransition:i,unstable_defaultShouldRevalidate:c,unstable_useTransitions:f}={}){let r=ci(),h=Ce(),o=_n(t,{relative:u});return p.useCallback(d=>{if(fg(d,e)){d.preventDefault();let v=l!==void 0?l:on(h)===on(o),E=()=>r(t,{replace:v,state:a,preventScrollReset:n,relative:u,viewTransition:i,unstable_defaultShouldRevalidate:c});f?p.startTransition(()=>E()):E()}},[h,r,o,l,a,e,t,n,u,i,c,f])}var Cg=0,jg=()=>`__${String(++Cg)}__`;function Ug(){let{router:t}=um("useSubmit"),{basename:e}=p.useContext(Xt),l=P0(),a=t.fetch,n=t.navigate;return p.useCallback(async(u,i={})=>{let{action:c,method:f,encType:r,formData:h,body:o}=og(u,e);if(i.navigate===!1){let d=i.fetcherKey||jg();await a(d,l,i.action||c,{unstable_defaultShouldRevalidate:i.unstable_defaultShouldRevalidate,preventScrollReset:i.preventScrollReset,formData:h,body:o,formMethod:i.method||f,formEncType:i.encType||r,flushSync:i.flushSync})}else await n(i.action||c,{unstable_defaultShouldRevalidate:i.unstable_defaultShouldRevalidate,preventScrollReset:i.preventScrollReset,formData:h,body:o,formMethod:i.method||f,formEncType:i.encType||r,replace:i.replace,state:i.state,fromRouteId:l,flushSync:i.flushSync,viewTransition:i.viewTransition})},[a,n,e,l])}function Hg(t,{relative:e}={}){let{basename:l}=p.useContext(Xt),a=p.useContext(re);I(a,"useFormAction must be used inside a RouteContext");let[n]=a.matches.slice(-1),u={..._n(t||".",{relative:e})},i=Ce();if(t==null){u.search=i.search;let c=new URLSearchParams(u.search),f=c.getAll("index");if(f.some(h=>h==="")){c.delete("index"),f.filter(o=>o).forEach(o=>c.append("index",o));let h=c.toString();u.search=h?`?${h}`:""}}return(!t||t===".")&&n.route.index&&(u.search=u.search?u.search.replace(/^\?/,"?index&"):"?index"),l!=="/"&&(u.pathname=u.pathname==="/"?l:Ae([l,u.pathname])),on(u)}function Bg(t,{relative:e}={}){let l=p.useContext(kh);I(l!=null,"`useViewTransitionState` must be used within `react-router-dom`'s `RouterProvider`.  Did you accidentally import `RouterProvider` from `react-router`?");let{basename:a}=um("useViewTransitionState"),n=_n(t,{relative:e});if(!l.isTransitioning)return!1;let u=De(l.currentLocation.pathname,a)||l.currentLocation.pathname,i=De(l.nextLocation.pathname,a)||l.nextLocation.pathname;return Lu(n.pathname,i)!=null||Lu(n.pathname,u)!=null}const qg="modulepreload",Yg=function(t){return"/"+t},Lr={},Lg=function(e,l,a){let n=Promise.resolve();if(l&&l.length>0){document.getElementsByTagName("link");const i=document.querySelector("meta[property=csp-nonce]"),c=(i==null?void 0:i.nonce)||(i==null?void 0:i.getAttribute("nonce"));n=Promise.allSettled(l.map(f=>{if(f=Yg(f),f in Lr)return;Lr[f]=!0;const r=f.endsWith(".css"),h=r?'[rel="stylesheet"]':"";if(document.querySelector(`link[href="${f}"]${h}`))return;const o=document.createElement("link");if(o.rel=r?"stylesheet":qg,r||(o.as="script"),o.crossOrigin="",o.href=f,c&&o.setAttribute("nonce",c),document.head.appendChild(o),r)return new Promise((d,v)=>{o.addEventListener("load",d),o.addEventListener("error",()=>v(new Error(`Unable to preload CSS for ${f}`)))})}))}function u(i){const c=new Event("vite:preloadError",{cancelable:!0});if(c.payload=i,window.dispatchEvent(c),!c.defaultPrevented)throw i}return n.then(i=>{for(const c of i||[])c.status==="rejected"&&u(c.reason);return e().catch(u)})},Gg=p.lazy(()=>Lg(()=>import("./WorkspaceApp-C_zx76mg.js"),[]));class im extends Error{constructor(e,l,a){super(e),this.name="ApiError",this.status=l,this.payload=a}}function Xg(t){return typeof t=="object"&&t!==null}function Gu(t){return t instanceof Error?t.message:typeof t=="string"?t:"Request failed"}async function pl(t,e){const l=new Headers((e==null?void 0:e.headers)??void 0);(e==null?void 0:e.body)!==void 0&&!l.has("Content-Type")&&l.set("Content-Type","application/json");{const m=(((e==null?void 0:e.method)??"GET")+"").toUpperCase(),c=typeof document>"u"?null:document.cookie.match(/(?:^|;\s*)proxer_csrf=([^;]*)/);m!=="GET"&&m!=="HEAD"&&c&&l.set("X-Proxer-CSRF-Token",decodeURIComponent(c[1]))}const a=await fetch(t,{credentials:"include",...e,headers:l});if(a.status===204)return null;const i=(a.headers.get("content-type")??"").includes("application/json")?await a.json():await a.text();if(!a.ok){let c=`Request failed (${a.status})`;throw typeof i=="string"&&i.trim()!==""?c=i:Xg(i)&&typeof i.message=="string"&&(c=i.message),new im(c,a.status,i)}return i}function ae(t){if(typeof window>"u"||typeof t.event!="string"||t.event.trim()==="")return;const e={source:"web",page_path:window.location.pathname,...t},l=JSON.stringify(e);try{if(typeof navigator<"u"&&typeof navigator.sendBeacon=="function"){const a=new Blob([l],{type:"application/json"});if(navigator.sendBeacon("/api/public/events",a))return}}catch{}fetch("/api/public/events",{method:"POST",headers:{"Content-Type":"application/json"},body:l,keepalive:!0}).catch(()=>{})}function Da(t){const e=Number(t??0);return Number.isFinite(e)?Math.abs(e)>=100?`${Math.round(e)}`:e.toFixed(2).replace(/\.00$/,""):"0"}function Qg(t){const e=Number(t??0);return!Number.isFinite(e)||e<=0?"-":e<1024*1024?`${Math.round(e/1024)} KB`:`${(e/(1024*1024)).toFixed(1)} MB`}function Zg({onLoggedIn:t}){const e=ci(),[l,a]=p.useState(""),[n,u]=p.useState(""),[i,c]=p.useState(""),[f,r]=p.useState(!1),h=p.useCallback(async o=>{o.preventDefault(),r(!0),c("");try{await pl("/api/auth/login",{method:"POST",body:JSON.stringify({username:l,password:n})}),await t(),e("/app")}catch(d){c(Gu(d))}finally{r(!1)}},[e,t,n,l]);return g.jsxs("main",{className:"auth-shell",children:[g.jsx("div",{className:"orb orb-a"}),g.jsx("div",{className:"orb orb-b"}),g.jsxs("section",{className:"auth-card",children:[g.jsx("h1",{children:"Proxer"}),g.jsx("p",{children:"Route internet traffic to localhost with tenant-scoped governance."}),g.jsxs("form",{className:"stack",onSubmit:h,children:[g.jsxs("label",{children:["Username",g.jsx("input",{value:l,onChange:o=>a(o.target.value),required:!0})]}),g.jsxs("label",{children:["Password",g.jsx("input",{type:"password",value:n,onChange:o=>u(o.target.value),required:!0})]}),g.jsx("button",{type:"submit",disabled:f,children:f?"Logging in...":"Login"})]}),i?g.jsx("p",{className:"status error",children:i}):null,g.jsxs("div",{className:"auth-links",children:[g.jsx(Ot,{to:"/signup",children:"Create account"}),g.jsx(Ot,{to:"/",children:"Back to website"})]})]})]})}function wg({onSignedUp:t}){const e=ci(),[l,a]=p.useState(""),[n,u]=p.useState(""),[i,c]=p.useState(""),[f,r]=p.useState(""),[h,o]=p.useState(!1),d=p.useCallback(async v=>{if(v.preventDefault(),r(""),n!==i){r("Passwords do not match.");return}o(!0),ae({event:"signup_submit",outcome:"attempt"});try{await pl("/api/public/signup",{method:"POST",body:JSON.stringify({username:l,password:n})}),ae({event:"signup_success",outcome:"success"}),await t(),e("/app")}catch(E){r(Gu(E)),ae({event:"signup_failure",outcome:"error"})}finally{o(!1)}},[i,e,t,n,l]);return g.jsxs("main",{className:"auth-shell",children:[g.jsx("div",{className:"orb orb-a"}),g.jsx("div",{className:"orb orb-b"}),g.jsxs("section",{className:"auth-card",children:[g.jsx("h1",{children:"Create Workspace"}),g.jsx("p",{children:"Get instant access with a tenant admin account on the free plan."}),g.jsxs("form",{className:"stack",onSubmit:d,children:[g.jsxs("label",{children:["Username",g.jsx("input",{value:l,onChange:v=>a(v.target.value),required:!0})]}),g.jsxs("label",{children:["Password",g.jsx("input",{type:"password",value:n,minLength:6,onChange:v=>u(v.target.value),required:!0})]}),g.jsxs("label",{children:["Confirm Password",g.jsx("input",{type:"password",value:i,minLength:6,onChange:v=>c(v.target.value),required:!0})]}),g.jsx("button",{type:"submit",disabled:h,children:h?"Creating account...":"Sign up"})]}),f?g.jsx("p",{className:"status error",children:f}):null,g.jsxs("div",{className:"auth-links",children:[g.jsx(Ot,{to:"/login",children:"Already have an account?"}),g.jsx(Ot,{to:"/",children:"Back to website"})]})]})]})}function Vg({me:t}){const[e,l]=p.useState([]),[a,n]=p.useState(null),[u,i]=p.useState(!0),[c,f]=p.useState(!0),[r,h]=p.useState(""),[o,d]=p.useState(""),[v,E]=p.useState("monthly");p.useEffect(()=>{ae({event:"landing_view"});let s=!0;const y=async()=>{i(!0),h("");try{const z=await pl("/api/public/plans");s&&l(z.plans??[])}catch(z){s&&h(Gu(z))}finally{s&&i(!1)}},S=async()=>{f(!0),d("");try{const z=await pl("/api/public/downloads");s&&n(z)}catch(z){s&&d(Gu(z))}finally{s&&f(!1)}};return Promise.all([y(),S()]),()=>{s=!1}},[]);const T=[...e].sort((s,y)=>Number(s.public_order??0)-Number(y.public_order??0)),A=p.useCallback(s=>{s!==v&&(E(s),ae({event:"pricing_toggle",billing:s}))},[v]),m=[{title:"Connector-Based Routing",text:"Pair host agents once, then bind routes to connectors with explicit local target metadata."},{title:"Tenant Isolation",text:"Route, connector, and access boundaries are tenant-scoped with role-aware controls."},{title:"Traffic Governance",text:"Hard limits for RPS and monthly transfer keep usage deterministic under every plan."},{title:"TLS + Cert Control",text:"Super admins manage certificates and active status from one control plane."},{title:"Request Fidelity",text:"Methods, query, headers, cookies, and body are preserved end-to-end."},{title:"Local-First Deploy",text:"Run everything with Docker Compose and no external dependencies for day one."}];return g.jsxs(g.Fragment,{children:[g.jsx("a",{className:"skip-link",href:"#main-marketing",children:"Skip to main content"}),g.jsxs("main",{id:"main-marketing",className:"marketing-site",children:[g.jsxs("section",{className:"marketing-hero-shell","aria-labelledby":"hero-title",children:[g.jsxs("header",{className:"marketing-nav",children:[g.jsxs("div",{className:"hero-brand-wrap",children:[g.jsx("div",{className:"hero-mark",children:"P"}),g.jsxs("div",{children:[g.jsx("strong",{children:"Proxer"}),g.jsx("p",{children:"Public tunnels with governance"})]})]}),g.jsxs("nav",{className:"marketing-links","aria-label":"Marketing navigation",children:[g.jsx("a",{href:"#features",children:"Features"}),g.jsx("a",{href:"#pricing",children:"Plans"}),g.jsx("a",{href:"#downloads",children:"Downloads"}),g.jsx("a",{href:"#faq",children:"FAQ"})]}),g.jsxs("div",{className:"hero-actions",children:[t?g.jsx(Ot,{to:"/app",children:"Open Workspace"}):g.jsx(Ot,{to:"/login",children:"Login"}),t?null:g.jsx(Ot,{className:"cta",to:"/signup",onClick:()=>ae({event:"signup_cta_click",outcome:"header"}),children:"Start Free"})]})]}),g.jsxs("div",{className:"hero-grid",children:[g.jsxs("article",{className:"hero-copy",children:[g.jsx("p",{className:"eyebrow",children:"HTTP/HTTPS localhost exposure for serious teams"}),g.jsx("h1",{id:"hero-title",children:"Ship local apps to the internet with control-plane discipline."}),g.jsx("p",{children:"Proxer combines ngrok-style reachability with role-based tenancy, hard plan enforcement, and operational visibility. Same development speed, better governance."}),g.jsxs("div",{className:"hero-cta",children:[g.jsx(Ot,{className:"cta",to:t?"/app":"/signup",onClick:()=>{t||ae({event:"signup_cta_click",outcome:"hero"})},children:t?"Open Workspace":"Create Workspace"}),g.jsx(Ot,{className:"cta-outline",to:"/login",children:"Sign in"})]}),g.jsxs("div",{className:"hero-proof-row",children:[g.jsx("span",{children:"Protocol scope: HTTP/HTTPS"}),g.jsx("span",{children:"Multi-tenant isolation"}),g.jsx("span",{children:"Docker Compose ready"})]}),g.jsxs("div",{className:"hero-proof-row",children:[g.jsx("span",{children:"Request/response fidelity preserved"}),g.jsx("span",{children:"Deterministic 4xx/5xx failure mapping"}),g.jsx("span",{children:"Super-admin observability"})]})]}),g.jsxs("aside",{className:"hero-terminal",children:[g.jsxs("div",{className:"hero-terminal-head",children:[g.jsx("span",{}),g.jsx("span",{}),g.jsx("span",{}),g.jsx("p",{children:"live-route-preview"})]}),g.jsxs("div",{className:"hero-terminal-body code",children:[g.jsx("p",{children:"$ proxer-agent pair --token <pair_token>"}),g.jsx("p",{children:"connector status: online"}),g.jsx("p",{children:"route: /t/acme/api -> 127.0.0.1:3000"}),g.jsx("p",{children:"tenant cap: 100 rps, 500 GB/month"}),g.jsx("p",{children:"response: 200 OK (47 ms)"})]})]})]})]}),g.jsxs("section",{id:"features",className:"marketing-panel","aria-labelledby":"features-title",children:[g.jsxs("header",{className:"section-head",children:[g.jsx("p",{className:"eyebrow",children:"Why teams switch"}),g.jsx("h2",{id:"features-title",children:"Built for production-minded local development"})]}),g.jsx("div",{className:"feature-grid",children:m.map(s=>g.jsxs("article",{className:"feature-card",children:[g.jsx("h3",{children:s.title}),g.jsx("p",{children:s.text})]},s.title))})]}),g.jsxs("section",{className:"marketing-panel","aria-labelledby":"workflow-title",children:[g.jsxs("header",{className:"section-head",children:[g.jsx("p",{className:"eyebrow",children:"How it works"}),g.jsx("h2",{id:"workflow-title",children:"Three-step setup"})]}),g.jsxs("div",{className:"steps-grid",children:[g.jsxs("article",{children:[g.jsx("span",{children:"1"}),g.jsx("h3",{children:"Create connector"}),g.jsx("p",{children:"Generate a short-lived pairing command from the workspace."})]}),g.jsxs("article",{children:[g.jsx("span",{children:"2"}),g.jsx("h3",{children:"Pair host agent"}),g.jsx("p",{children:"Run the desktop agent on your machine and establish a secure connector session."})]}),g.jsxs("article",{children:[g.jsx("span",{children:"3"}),g.jsx("h3",{children:"Create route"}),g.jsx("p",{children:"Bind public path to local target and start serving traffic instantly."})]})]})]}),g.jsxs("section",{id:"pricing",className:"marketing-panel","aria-labelledby":"pricing-title",children:[g.jsxs("header",{className:"section-head split",children:[g.jsxs("div",{children:[g.jsx("p",{className:"eyebrow",children:"Pricing"}),g.jsx("h2",{id:"pricing-title",children:"Choose the right operational envelope"})]}),g.jsxs("div",{className:"billing-toggle",role:"group","aria-label":"Billing cycle",children:[g.jsx("button",{className:v==="monthly"?"active":"",type:"button",onClick:()=>A("monthly"),children:"Monthly"}),g.jsx("button",{className:v==="annual"?"active":"",type:"button",onClick:()=>A("annual"),children:"Annual"})]})]}),u?g.jsx("p",{role:"status","aria-live":"polite",children:"Loading plans..."}):null,r?g.jsx("p",{className:"status error",children:r}):null,!u&&!r?g.jsx("div",{className:"plan-grid",children:T.map(s=>{const S=String(s.id||"").toLowerCase()==="pro",z=v==="annual"?s.price_annual_usd:s.price_monthly_usd,O=v==="annual"?"year":"month";return g.jsxs("article",{className:`plan-card${S?" highlighted":""}`,children:[S?g.jsx("span",{className:"plan-badge",children:"Most Popular"}):null,g.jsx("h3",{children:s.name}),g.jsxs("p",{className:"plan-price",children:["$",Da(z)," ",g.jsxs("small",{children:["/ ",O]})]}),g.jsx("p",{className:"plan-subprice",children:s.description||"Managed routing plan"}),g.jsxs("ul",{children:[g.jsxs("li",{children:[Da(s.max_routes)," routes"]}),g.jsxs("li",{children:[Da(s.max_connectors)," connectors"]}),g.jsxs("li",{children:[Da(s.max_rps)," requests / sec"]}),g.jsxs("li",{children:[Da(s.max_monthly_gb)," GB transfer / month"]}),g.jsx("li",{children:s.tls_enabled?"TLS certificates included":"No TLS certificate management"})]}),g.jsx(Ot,{className:S?"cta":"cta-outline",to:t?"/app":"/signup",onClick:()=>{t||ae({event:"plan_cta_click",plan_id:s.id,billing:v})},children:t?"Use Plan":"Start Free"})]},s.id)})}):null]}),g.jsxs("section",{id:"downloads",className:"marketing-panel","aria-labelledby":"downloads-title",children:[g.jsxs("header",{className:"section-head",children:[g.jsx("p",{className:"eyebrow",children:"Desktop agent"}),g.jsx("h2",{id:"downloads-title",children:"Download binaries for your host machine"})]}),c?g.jsx("p",{role:"status","aria-live":"polite",children:"Loading downloads..."}):null,o?g.jsx("p",{className:"status error",children:o}):null,!c&&!o?g.jsxs(g.Fragment,{children:[a!=null&&a.available?g.jsx("div",{className:"download-grid",children:(a.downloads??[]).map(s=>g.jsxs("article",{className:"download-card",children:[g.jsx("h3",{children:s.label}),g.jsx("p",{className:"code",children:s.file_name}),g.jsx("p",{children:Qg(s.size_bytes)}),g.jsx("a",{className:"cta-outline",href:s.url,target:"_blank",rel:"noreferrer",onClick:()=>ae({event:"download_click",platform:s.platform,file_name:s.file_name}),children:"Download"})]},`${s.platform}:${s.file_name}`))}):g.jsx("p",{children:(a==null?void 0:a.message)||"Downloads are not available yet."}),g.jsxs("div",{className:"download-meta",children:[a!=null&&a.release_url?g.jsx("a",{href:a.release_url,target:"_blank",rel:"noreferrer",children:"Release page"}):null,a!=null&&a.checksums_url?g.jsx("a",{href:a.checksums_url,target:"_blank",rel:"noreferrer",children:"Checksums"}):null,a!=null&&a.release_notes_url?g.jsx("a",{href:a.release_notes_url,target:"_blank",rel:"noreferrer",children:"Release notes"}):null]})]}):null]}),g.jsxs("section",{id:"faq",className:"marketing-panel","aria-labelledby":"faq-title",children:[g.jsxs("header",{className:"section-head",children:[g.jsx("p",{className:"eyebrow",children:"FAQ"}),g.jsx("h2",{id:"faq-title",children:"Answers before you deploy"})]}),g.jsxs("div",{className:"faq-grid",children:[g.jsxs("article",{children:[g.jsx("h3",{children:"Does Proxer return responses from localhost back to callers?"}),g.jsx("p",{children:"Yes. Gateway dispatches to connector agent, agent calls localhost app, and response is returned."})]}),g.jsxs("article",{children:[g.jsx("h3",{children:"Can I run it entirely local?"}),g.jsx("p",{children:"Yes. The full stack runs with Docker Compose and supports native desktop agents."})]}),g.jsxs("article",{children:[g.jsx("h3",{children:"How are limits enforced?"}),g.jsx("p",{children:"Plan caps and runtime limits are hard enforced with deterministic error responses."})]}),g.jsxs("article",{children:[g.jsx("h3",{children:"Can super admins control plans and certificates?"}),g.jsx("p",{children:"Yes. Super admin pages include users, tenants, plans, TLS certificates, and system status."})]})]})]}),g.jsxs("section",{className:"marketing-final-cta","aria-labelledby":"final-cta-title",children:[g.jsx("h2",{id:"final-cta-title",children:"Ready to expose localhost with operational guardrails?"}),g.jsx("p",{children:"Spin up a workspace in minutes and route your first endpoint to the public internet."}),g.jsxs("div",{className:"hero-cta",children:[g.jsx(Ot,{className:"cta",to:t?"/app":"/signup",onClick:()=>{t||ae({event:"signup_cta_click",outcome:"footer"})},children:t?"Open Workspace":"Create Workspace"}),g.jsx(Ot,{className:"cta-outline",to:"/login",children:"Login"})]})]})]})]})}function Kg(){const t=Ce(),[e,l]=p.useState(null),[a,n]=p.useState(!0),u=p.useCallback(async()=>{n(!0);try{const f=await pl("/api/auth/me");l(f)}catch{l(null)}finally{n(!1)}},[]);p.useEffect(()=>{if(!t.pathname.startsWith("/app")){n(!1);return}u()},[t.pathname,u]);const i=p.useCallback(async(f,r)=>{try{return await pl(f,r)}catch(h){throw h instanceof im&&h.status===401&&l(null),h}},[]),c=p.useCallback(async()=>{try{await pl("/api/auth/logout",{method:"POST"})}catch{}l(null)},[]);return g.jsxs(ag,{children:[g.jsx(Ul,{path:"/",element:g.jsx(Vg,{me:e})}),g.jsx(Ul,{path:"/login",element:a?g.jsx("main",{className:"auth-shell",children:"Checking session..."}):e?g.jsx(Vn,{to:"/app",replace:!0}):g.jsx(Zg,{onLoggedIn:u})}),g.jsx(Ul,{path:"/signup",element:a?g.jsx("main",{className:"auth-shell",children:"Checking session..."}):e?g.jsx(Vn,{to:"/app",replace:!0}):g.jsx(wg,{onSignedUp:u})}),g.jsx(Ul,{path:"/app/*",element:a?g.jsx("main",{className:"auth-shell",children:"Checking session..."}):e?g.jsx(p.Suspense,{fallback:g.jsx("main",{className:"auth-shell",children:"Loading workspace..."}),children:g.jsx(Gg,{me:e,api:i,onLogout:c})}):g.jsx(Vn,{to:"/login",replace:!0})}),g.jsx(Ul,{path:"*",element:g.jsx(Vn,{to:"/",replace:!0})})]})}i0.createRoot(document.getElementById("root")).render(g.jsx(Om.StrictMode,{children:g.jsx(Og,{children:g.jsx(Kg,{})})}));export{g as j,p as r};
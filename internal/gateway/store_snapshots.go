@@ -7,8 +7,10 @@ import (
 )
 
 type authUserSnapshot struct {
-	User         User   `json:"user"`
-	PasswordHash string `json:"password_hash"`
+	User             User                     `json:"user"`
+	PasswordHash     string                   `json:"password_hash"`
+	Notifications    *NotificationPreferences `json:"notifications,omitempty"`
+	UnsubscribeToken string                   `json:"unsubscribe_token,omitempty"`
 }
 
 type ruleStoreSnapshot struct {
@@ -18,9 +20,12 @@ type ruleStoreSnapshot struct {
 }
 
 type connectorCredentialSnapshot struct {
-	ConnectorID string    `json:"connector_id"`
-	SecretHash  string    `json:"secret_hash"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ConnectorID       string     `json:"connector_id"`
+	SecretHash        string     `json:"secret_hash"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	PreviousHash      string     `json:"previous_hash,omitempty"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
 }
 
 type connectorStoreSnapshot struct {
@@ -30,6 +35,7 @@ type connectorStoreSnapshot struct {
 
 type planStoreSnapshot struct {
 	Plans       []Plan                 `json:"plans"`
+	Versions    []Plan                 `json:"versions,omitempty"`
 	Assignments []TenantPlanAssignment `json:"assignments"`
 	Usage       []UsageSnapshot        `json:"usage"`
 }
@@ -39,6 +45,11 @@ type incidentStoreSnapshot struct {
 	Counter uint64           `json:"counter"`
 }
 
+type auditStoreSnapshot struct {
+	Items   []AuditEvent `json:"items"`
+	Counter uint64       `json:"counter"`
+}
+
 type tlsCertificateRecordSnapshot struct {
 	Meta    TLSCertificate `json:"meta"`
 	CertPEM string         `json:"cert_pem"`
@@ -51,10 +62,16 @@ func (s *AuthStore) SnapshotUsers() []authUserSnapshot {
 
 	users := make([]authUserSnapshot, 0, len(s.users))
 	for _, record := range s.users {
-		users = append(users, authUserSnapshot{
-			User:         record.user,
-			PasswordHash: record.passwordHash,
-		})
+		snapshot := authUserSnapshot{
+			User:             record.user,
+			PasswordHash:     record.passwordHash,
+			UnsubscribeToken: record.unsubscribeToken,
+		}
+		if record.notifications.Mode != "" {
+			notifications := record.notifications
+			snapshot.Notifications = &notifications
+		}
+		users = append(users, snapshot)
 	}
 	sort.Slice(users, func(i, j int) bool { return users[i].User.Username < users[j].User.Username })
 	return users
@@ -65,7 +82,6 @@ func (s *AuthStore) RestoreUsers(users []authUserSnapshot) {
 	defer s.mu.Unlock()
 
 	s.users = make(map[string]authUserRecord, len(users))
-	s.sessions = make(map[string]authSession)
 
 	for _, snapshot := range users {
 		username := normalizeUsername(snapshot.User.Username)
@@ -85,10 +101,13 @@ func (s *AuthStore) RestoreUsers(users []authUserSnapshot) {
 				role = RoleSuperAdmin
 			}
 		}
-		if role != RoleSuperAdmin && role != RoleTenantAdmin && role != RoleMember {
+		if !validRole(role) || (role == RoleOrgAdmin && user.OrgID == "") {
 			role = RoleMember
 		}
 		user.Role = role
+		if role != RoleOrgAdmin {
+			user.OrgID = ""
+		}
 		if role == RoleSuperAdmin {
 			user.TenantID = ""
 		} else {
@@ -112,10 +131,18 @@ func (s *AuthStore) RestoreUsers(users []authUserSnapshot) {
 		if user.UpdatedAt.IsZero() {
 			user.UpdatedAt = user.CreatedAt
 		}
-		s.users[username] = authUserRecord{
-			user:         user,
-			passwordHash: snapshot.PasswordHash,
+		record := authUserRecord{
+			user:             user,
+			passwordHash:     snapshot.PasswordHash,
+			unsubscribeToken: snapshot.UnsubscribeToken,
 		}
+		if snapshot.Notifications != nil {
+			if prefs, err := normalizeNotificationPreferences(*snapshot.Notifications); err == nil {
+				prefs.UpdatedAt = snapshot.Notifications.UpdatedAt
+				record.notifications = prefs
+			}
+		}
+		s.users[username] = record
 	}
 }
 
@@ -170,12 +197,19 @@ func (s *RuleStore) Snapshot() ruleStoreSnapshot {
 	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
 
 	envs := make([]TenantEnvironment, 0, len(s.envs))
-	for _, env := range s.envs {
-		copied := env
-		copied.Variables = copyStringMap(env.Variables)
-		envs = append(envs, copied)
+	for _, named := range s.envs {
+		for _, env := range named {
+			copied := env
+			copied.Variables = copyStringMap(env.Variables)
+			envs = append(envs, copied)
+		}
 	}
-	sort.Slice(envs, func(i, j int) bool { return envs[i].TenantID < envs[j].TenantID })
+	sort.Slice(envs, func(i, j int) bool {
+		if envs[i].TenantID == envs[j].TenantID {
+			return envs[i].Name < envs[j].Name
+		}
+		return envs[i].TenantID < envs[j].TenantID
+	})
 
 	rules := make([]Rule, 0, len(s.rules))
 	for _, rule := range s.rules {
@@ -200,7 +234,7 @@ func (s *RuleStore) Restore(snapshot ruleStoreSnapshot) {
 	defer s.mu.Unlock()
 
 	s.tenants = make(map[string]Tenant)
-	s.envs = make(map[string]TenantEnvironment)
+	s.envs = make(map[string]map[string]TenantEnvironment)
 	s.rules = make(map[string]Rule)
 
 	for _, tenant := range snapshot.Tenants {
@@ -231,6 +265,11 @@ func (s *RuleStore) Restore(snapshot ruleStoreSnapshot) {
 			continue
 		}
 		env.TenantID = tenantID
+		// Snapshots from before named environments hold only the default.
+		env.Name = normalizeEnvironmentName(env.Name)
+		if !identifierPattern.MatchString(env.Name) {
+			continue
+		}
 		if env.Scheme != "https" {
 			env.Scheme = "http"
 		}
@@ -244,21 +283,20 @@ func (s *RuleStore) Restore(snapshot ruleStoreSnapshot) {
 		if env.UpdatedAt.IsZero() {
 			env.UpdatedAt = time.Now().UTC()
 		}
-		s.envs[tenantID] = env
+		if s.envs[tenantID] == nil {
+			s.envs[tenantID] = make(map[string]TenantEnvironment)
+		}
+		s.envs[tenantID][env.Name] = env
 	}
 
 	for tenantID := range s.tenants {
-		if _, ok := s.envs[tenantID]; ok {
+		if _, ok := s.envs[tenantID][DefaultEnvironmentName]; ok {
 			continue
 		}
-		s.envs[tenantID] = TenantEnvironment{
-			TenantID:    tenantID,
-			Scheme:      "http",
-			Host:        "host.docker.internal",
-			DefaultPort: 3000,
-			Variables:   map[string]string{},
-			UpdatedAt:   time.Now().UTC(),
+		if s.envs[tenantID] == nil {
+			s.envs[tenantID] = make(map[string]TenantEnvironment)
 		}
+		s.envs[tenantID][DefaultEnvironmentName] = newDefaultEnvironments(tenantID, time.Now().UTC())[DefaultEnvironmentName]
 	}
 
 	for _, rule := range snapshot.Rules {
@@ -277,7 +315,7 @@ func (s *RuleStore) Restore(snapshot ruleStoreSnapshot) {
 		if rule.LocalScheme != "https" {
 			rule.LocalScheme = "http"
 		}
-		if strings.TrimSpace(rule.LocalHost) == "" && rule.ConnectorID != "" {
+		if strings.TrimSpace(rule.LocalHost) == "" && rule.UsesConnector() && rule.LocalSocket == "" {
 			rule.LocalHost = "127.0.0.1"
 		}
 		if rule.CreatedAt.IsZero() {
@@ -297,14 +335,7 @@ func (s *RuleStore) Restore(snapshot ruleStoreSnapshot) {
 			CreatedAt: now,
 			UpdatedAt: now,
 		}
-		s.envs[DefaultTenantID] = TenantEnvironment{
-			TenantID:    DefaultTenantID,
-			Scheme:      "http",
-			Host:        "host.docker.internal",
-			DefaultPort: 3000,
-			Variables:   map[string]string{},
-			UpdatedAt:   now,
-		}
+		s.envs[DefaultTenantID] = newDefaultEnvironments(DefaultTenantID, now)
 	}
 }
 
@@ -325,11 +356,7 @@ func (s *ConnectorStore) Snapshot() connectorStoreSnapshot {
 
 	credentials := make([]connectorCredentialSnapshot, 0, len(s.credentials))
 	for _, credential := range s.credentials {
-		credentials = append(credentials, connectorCredentialSnapshot{
-			ConnectorID: credential.ConnectorID,
-			SecretHash:  credential.SecretHash,
-			UpdatedAt:   credential.UpdatedAt,
-		})
+		credentials = append(credentials, credential.snapshot())
 	}
 	sort.Slice(credentials, func(i, j int) bool { return credentials[i].ConnectorID < credentials[j].ConnectorID })
 
@@ -355,6 +382,11 @@ func (s *ConnectorStore) Restore(snapshot connectorStoreSnapshot) {
 		if strings.TrimSpace(connector.Name) == "" {
 			connector.Name = connectorID
 		}
+		if labels, err := normalizeConnectorLabels("labels", connector.Labels); err == nil {
+			connector.Labels = labels
+		} else {
+			connector.Labels = nil
+		}
 		now := time.Now().UTC()
 		if connector.CreatedAt.IsZero() {
 			connector.CreatedAt = now
@@ -376,11 +408,9 @@ func (s *ConnectorStore) Restore(snapshot connectorStoreSnapshot) {
 		if strings.TrimSpace(credential.SecretHash) == "" {
 			continue
 		}
-		s.credentials[connectorID] = connectorCredential{
-			ConnectorID: connectorID,
-			SecretHash:  strings.TrimSpace(credential.SecretHash),
-			UpdatedAt:   credential.UpdatedAt,
-		}
+		credential.ConnectorID = connectorID
+		credential.SecretHash = strings.TrimSpace(credential.SecretHash)
+		s.credentials[connectorID] = credentialFromSnapshot(credential)
 	}
 }
 
@@ -394,6 +424,11 @@ func (s *PlanStore) Snapshot() planStoreSnapshot {
 	}
 	sort.Slice(plans, func(i, j int) bool { return plans[i].ID < plans[j].ID })
 
+	versions := make([]Plan, 0)
+	for _, plan := range plans {
+		versions = append(versions, s.versions[plan.ID]...)
+	}
+
 	assignments := make([]TenantPlanAssignment, 0, len(s.assignments))
 	for _, assignment := range s.assignments {
 		assignments = append(assignments, assignment)
@@ -413,6 +448,7 @@ func (s *PlanStore) Snapshot() planStoreSnapshot {
 
 	return planStoreSnapshot{
 		Plans:       plans,
+		Versions:    versions,
 		Assignments: assignments,
 		Usage:       usage,
 	}
@@ -428,6 +464,7 @@ func (s *PlanStore) Restore(snapshot planStoreSnapshot) {
 	for id, plan := range defaults.plans {
 		s.plans[id] = plan
 	}
+	s.versions = make(map[string][]Plan)
 	s.assignments = make(map[string]TenantPlanAssignment)
 	s.usage = make(map[string]UsageSnapshot)
 
@@ -455,8 +492,22 @@ func (s *PlanStore) Restore(snapshot planStoreSnapshot) {
 		if plan.UpdatedAt.IsZero() {
 			plan.UpdatedAt = plan.CreatedAt
 		}
+		if plan.Version <= 0 {
+			plan.Version = 1
+		}
 		s.plans[planID] = plan
 	}
+	for _, plan := range snapshot.Versions {
+		plan.ID = normalizeIdentifier(plan.ID)
+		if current, ok := s.plans[plan.ID]; !ok || plan.Version <= 0 || plan.Version >= current.Version {
+			continue
+		}
+		s.versions[plan.ID] = append(s.versions[plan.ID], plan)
+	}
+	for planID := range s.versions {
+		versions := s.versions[planID]
+		sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	}
 
 	for _, assignment := range snapshot.Assignments {
 		tenantID := normalizeIdentifier(assignment.TenantID)
@@ -469,6 +520,11 @@ func (s *PlanStore) Restore(snapshot planStoreSnapshot) {
 		}
 		assignment.TenantID = tenantID
 		assignment.PlanID = planID
+		// Assignments saved before plans were versioned, or to a version
+		// that is gone, are on the current version.
+		if plan, _ := s.planVersionLocked(planID, assignment.PlanVersion); plan.Version != assignment.PlanVersion {
+			assignment.PlanVersion = s.plans[planID].Version
+		}
 		if assignment.AssignedAt.IsZero() {
 			assignment.AssignedAt = time.Now().UTC()
 		}
@@ -517,6 +573,157 @@ func (s *IncidentStore) Restore(snapshot incidentStoreSnapshot) {
 	s.counter = snapshot.Counter
 }
 
+func (s *AuditStore) Snapshot() auditStoreSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]AuditEvent, 0, len(s.order))
+	for _, id := range s.order {
+		event := s.items[id]
+		event.Details = copyStringMap(event.Details)
+		items = append(items, event)
+	}
+	return auditStoreSnapshot{Items: items, Counter: s.counter}
+}
+
+func (s *AuditStore) Restore(snapshot auditStoreSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]AuditEvent, 0, len(snapshot.Items))
+	for _, event := range snapshot.Items {
+		event.ID = strings.TrimSpace(event.ID)
+		if event.ID == "" {
+			continue
+		}
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = time.Now().UTC()
+		}
+		event.Details = copyStringMap(event.Details)
+		events = append(events, event)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+	if len(events) > maxAuditEvents {
+		events = events[len(events)-maxAuditEvents:]
+	}
+	s.items = make(map[string]AuditEvent, len(events))
+	s.order = make([]string, 0, len(events))
+	for _, event := range events {
+		if _, dup := s.items[event.ID]; !dup {
+			s.order = append(s.order, event.ID)
+		}
+		s.items[event.ID] = event
+	}
+	s.counter = snapshot.Counter
+}
+
+func (l *IPBanList) Restore(bans []IPBan) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now().UTC()
+	l.bans = make(map[string]IPBan, len(bans))
+	l.violations = make(map[string]ipViolationCounter)
+	for _, ban := range bans {
+		ip := strings.TrimSpace(ban.IP)
+		if ip == "" || !now.Before(ban.ExpiresAt) {
+			continue
+		}
+		ban.IP = ip
+		l.bans[ip] = ban
+	}
+}
+
+func (s *TimeseriesStore) Snapshot() map[string][]TimeseriesPoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[string][]TimeseriesPoint, len(s.series))
+	for key, points := range s.series {
+		out[key] = append([]TimeseriesPoint(nil), points...)
+	}
+	return out
+}
+
+func (s *TimeseriesStore) Restore(snapshot map[string][]TimeseriesPoint) {
+	cutoff := time.Now().UTC().Truncate(timeseriesResolution).Add(-timeseriesRetention)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.series = make(map[string][]TimeseriesPoint, len(snapshot))
+	for key, points := range snapshot {
+		key = strings.TrimSpace(key)
+		if key == "" || len(points) == 0 {
+			continue
+		}
+		sorted := append([]TimeseriesPoint(nil), points...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+		if trimmed := trimTimeseries(sorted, cutoff); len(trimmed) > 0 {
+			s.series[key] = trimmed
+		}
+	}
+}
+
+func (s *AvailabilityStore) Snapshot() map[string][]AvailabilityBucket {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[string][]AvailabilityBucket, len(s.buckets))
+	for key, buckets := range s.buckets {
+		out[key] = append([]AvailabilityBucket(nil), buckets...)
+	}
+	return out
+}
+
+func (s *AvailabilityStore) Restore(snapshot map[string][]AvailabilityBucket) {
+	cutoff := time.Now().UTC().Truncate(availabilityResolution).Add(-availabilityRetention)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buckets = make(map[string][]AvailabilityBucket, len(snapshot))
+	for key, buckets := range snapshot {
+		key = strings.TrimSpace(key)
+		if key == "" || len(buckets) == 0 {
+			continue
+		}
+		sorted := append([]AvailabilityBucket(nil), buckets...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+		if trimmed := trimAvailability(sorted, cutoff); len(trimmed) > 0 {
+			s.buckets[key] = trimmed
+		}
+	}
+}
+
+func (s *TransferStore) Snapshot() []TransferRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]TransferRecord, 0, len(s.records))
+	for _, record := range s.records {
+		out = append(out, *record)
+	}
+	sort.Slice(out, func(i, j int) bool { return transferRecordKey(out[i]) < transferRecordKey(out[j]) })
+	return out
+}
+
+func (s *TransferStore) Restore(snapshot []TransferRecord) {
+	cutoff := time.Now().UTC().AddDate(0, 0, -(transferRetentionDays - 1)).Format(transferDateLayout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = make(map[string]*TransferRecord, len(snapshot))
+	s.oldest = cutoff
+	for _, record := range snapshot {
+		if record.Date < cutoff {
+			continue
+		}
+		s.addLocked(record)
+	}
+}
+
 func (s *TLSStore) SnapshotRecords() []tlsCertificateRecordSnapshot {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -0,0 +1,372 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	syntheticSweepInterval     = time.Second
+	defaultSyntheticInterval   = 60
+	minSyntheticInterval       = 10
+	maxSyntheticInterval       = 24 * 60 * 60
+	defaultSyntheticThreshold  = 3
+	maxSyntheticThreshold      = 100
+	maxSyntheticBodyBytes      = 64 << 10
+	maxSyntheticTimeout        = 60 * time.Second
+	syntheticErrorSnippetBytes = 200
+	syntheticUserAgent         = "proxer-synthetic-check"
+)
+
+const (
+	SyntheticStatusPassing  = "passing"
+	SyntheticStatusDegraded = "degraded"
+	SyntheticStatusFailing  = "failing"
+)
+
+type RouteSyntheticCheck struct {
+	Method           string            `json:"method,omitempty"`
+	Path             string            `json:"path,omitempty"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	Body             string            `json:"body,omitempty"`
+	ExpectStatus     int               `json:"expect_status,omitempty"`
+	IntervalSeconds  int               `json:"interval_seconds,omitempty"`
+	FailureThreshold int               `json:"failure_threshold,omitempty"`
+}
+
+type SyntheticCheckStatus struct {
+	Status              string     `json:"status"`
+	ConsecutiveFailures int        `json:"consecutive_failures,omitempty"`
+	Checks              int64      `json:"checks"`
+	Failures            int64      `json:"failures"`
+	UptimePercent       float64    `json:"uptime_percent"`
+	LastStatusCode      int        `json:"last_status_code,omitempty"`
+	LastLatencyMs       int64      `json:"last_latency_ms"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastCheckedAt       *time.Time `json:"last_checked_at,omitempty"`
+}
+
+func normalizeRouteSyntheticCheck(input *RouteSyntheticCheck) (*RouteSyntheticCheck, error) {
+	if input == nil {
+		return nil, nil
+	}
+	check := *input
+	check.Method = strings.ToUpper(strings.TrimSpace(check.Method))
+	switch check.Method {
+	case "":
+		check.Method = http.MethodGet
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+	default:
+		return nil, fmt.Errorf("synthetic_check.method %q is not supported", input.Method)
+	}
+	check.Path = strings.TrimSpace(check.Path)
+	if check.Path == "" {
+		check.Path = "/"
+	}
+	if !strings.HasPrefix(check.Path, "/") {
+		return nil, fmt.Errorf("synthetic_check.path must start with /")
+	}
+	if _, err := url.ParseRequestURI(check.Path); err != nil {
+		return nil, fmt.Errorf("invalid synthetic_check.path: %w", err)
+	}
+	if len(check.Body) > maxSyntheticBodyBytes {
+		return nil, fmt.Errorf("synthetic_check.body must be at most %d bytes", maxSyntheticBodyBytes)
+	}
+	if len(check.Headers) > 0 {
+		headers := make(map[string]string, len(check.Headers))
+		for name, value := range check.Headers {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" || strings.ContainsAny(name, " \t\r\n:") || strings.ContainsAny(value, "\r\n") {
+				return nil, fmt.Errorf("invalid synthetic_check header %q", name)
+			}
+			headers[name] = value
+		}
+		check.Headers = headers
+	}
+	if check.ExpectStatus != 0 && (check.ExpectStatus < 100 || check.ExpectStatus > 599) {
+		return nil, fmt.Errorf("synthetic_check.expect_status must be between 100 and 599")
+	}
+	switch {
+	case check.IntervalSeconds == 0:
+		check.IntervalSeconds = defaultSyntheticInterval
+	case check.IntervalSeconds < minSyntheticInterval || check.IntervalSeconds > maxSyntheticInterval:
+		return nil, fmt.Errorf("synthetic_check.interval_seconds must be between %d and %d", minSyntheticInterval, maxSyntheticInterval)
+	}
+	switch {
+	case check.FailureThreshold == 0:
+		check.FailureThreshold = defaultSyntheticThreshold
+	case check.FailureThreshold < 1 || check.FailureThreshold > maxSyntheticThreshold:
+		return nil, fmt.Errorf("synthetic_check.failure_threshold must be between 1 and %d", maxSyntheticThreshold)
+	}
+	return &check, nil
+}
+
+func (c *RouteSyntheticCheck) passes(status int) bool {
+	if c.ExpectStatus != 0 {
+		return status == c.ExpectStatus
+	}
+	return status >= 200 && status < 400
+}
+
+func (c *RouteSyntheticCheck) expectation() string {
+	if c.ExpectStatus != 0 {
+		return fmt.Sprintf("want %d", c.ExpectStatus)
+	}
+	return "want 2xx or 3xx"
+}
+
+type syntheticState struct {
+	status     SyntheticCheckStatus
+	nextAt     time.Time
+	running    bool
+	incidentID string
+}
+
+// SyntheticMonitor keeps the schedule and results of synthetic checks by
+// route key. Results live in gateway memory and restart with it.
+type SyntheticMonitor struct {
+	mu     sync.Mutex
+	states map[string]*syntheticState
+}
+
+func NewSyntheticMonitor() *SyntheticMonitor {
+	return &SyntheticMonitor{states: make(map[string]*syntheticState)}
+}
+
+func (m *SyntheticMonitor) claimDue(key string, interval time.Duration, now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.states[key]
+	if !ok {
+		state = &syntheticState{}
+		m.states[key] = state
+	}
+	if state.running || now.Before(state.nextAt) {
+		return false
+	}
+	state.running = true
+	state.nextAt = now.Add(interval)
+	return true
+}
+
+func (m *SyntheticMonitor) record(key string, threshold, statusCode int, latency time.Duration, errText string, now time.Time) (resolveIncidentID string, startedFailing bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.states[key]
+	if !ok {
+		state = &syntheticState{}
+		m.states[key] = state
+	}
+	state.running = false
+
+	status := &state.status
+	wasFailing := status.Status == SyntheticStatusFailing
+	status.Checks++
+	status.LastStatusCode = statusCode
+	status.LastLatencyMs = latency.Milliseconds()
+	status.LastError = errText
+	status.LastCheckedAt = &now
+	if errText == "" {
+		status.ConsecutiveFailures = 0
+		status.Status = SyntheticStatusPassing
+	} else {
+		status.Failures++
+		status.ConsecutiveFailures++
+		status.Status = SyntheticStatusDegraded
+		if status.ConsecutiveFailures >= threshold {
+			status.Status = SyntheticStatusFailing
+		}
+	}
+	status.UptimePercent = math.Round(float64(status.Checks-status.Failures)/float64(status.Checks)*10000) / 100
+
+	switch {
+	case wasFailing && status.Status != SyntheticStatusFailing:
+		resolveIncidentID, state.incidentID = state.incidentID, ""
+	case !wasFailing && status.Status == SyntheticStatusFailing:
+		startedFailing = true
+	}
+	return resolveIncidentID, startedFailing
+}
+
+func (m *SyntheticMonitor) setIncident(key, incidentID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if state, ok := m.states[key]; ok {
+		state.incidentID = incidentID
+	}
+}
+
+func (m *SyntheticMonitor) Status(key string) (SyntheticCheckStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.states[key]
+	if !ok || state.status.Checks == 0 {
+		return SyntheticCheckStatus{}, false
+	}
+	status := state.status
+	if status.LastCheckedAt != nil {
+		checkedAt := *status.LastCheckedAt
+		status.LastCheckedAt = &checkedAt
+	}
+	return status, true
+}
+
+func (m *SyntheticMonitor) retain(keys map[string]struct{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.states {
+		if _, ok := keys[key]; !ok {
+			delete(m.states, key)
+		}
+	}
+}
+
+func (s *Server) runSyntheticCheckLoop(ctx context.Context) {
+	ticker := time.NewTicker(syntheticSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.startDueSyntheticChecks(ctx, now.UTC())
+		}
+	}
+}
+
+// startDueSyntheticChecks starts the checks of active routes that are due,
+// each in its own goroutine so a slow route does not delay the others.
+func (s *Server) startDueSyntheticChecks(ctx context.Context, now time.Time) {
+	keys := make(map[string]struct{})
+	for _, rule := range s.ruleStore.ListAll() {
+		if rule.SyntheticCheck == nil || rule.ScheduleState(now) != RouteScheduleActive {
+			continue
+		}
+		key := MakeTunnelKey(rule.TenantID, rule.ID)
+		keys[key] = struct{}{}
+		if !s.synthetic.claimDue(key, time.Duration(rule.SyntheticCheck.IntervalSeconds)*time.Second, now) {
+			continue
+		}
+		go s.runSyntheticCheck(ctx, rule)
+	}
+	s.synthetic.retain(keys)
+}
+
+// runSyntheticCheck sends rule's check through the proxy handler, so it
+// covers rate limits, tokens, middleware and the agent or upstream, and
+// records the result. Checks count in the route's metrics like any request.
+func (s *Server) runSyntheticCheck(ctx context.Context, rule Rule) SyntheticCheckStatus {
+	check := rule.SyntheticCheck
+	key := MakeTunnelKey(rule.TenantID, rule.ID)
+	timeout := min(time.Duration(check.IntervalSeconds)*time.Second, maxSyntheticTimeout)
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	target := "/t/" + url.PathEscape(rule.TenantID) + "/" + url.PathEscape(rule.ID) + check.Path
+	request, err := http.NewRequestWithContext(checkCtx, check.Method, target, strings.NewReader(check.Body))
+	errText := ""
+	statusCode := 0
+	start := time.Now()
+	if err != nil {
+		errText = fmt.Sprintf("build check request: %v", err)
+	} else {
+		request.RemoteAddr = "127.0.0.1:0"
+		for name, value := range check.Headers {
+			request.Header.Set(name, value)
+		}
+		request.Header.Set("User-Agent", syntheticUserAgent)
+		request.Header.Set("X-Proxer-Synthetic-Check", "true")
+		token := rule.Token
+		if legacyToken := s.lookupTunnelToken(s.lookupTunnelKeys(rule.TenantID, rule.ID)); legacyToken != "" {
+			token = legacyToken
+		}
+		if token != "" {
+			request.Header.Set("X-Proxer-Tunnel-Token", token)
+		}
+
+		recorder := &syntheticRecorder{header: make(http.Header)}
+		s.handleProxy(recorder, request)
+		statusCode = recorder.statusCode()
+		if !check.passes(statusCode) {
+			errText = fmt.Sprintf("status %d, %s", statusCode, check.expectation())
+			if snippet := recorder.snippet(s.redaction(rule.TenantID)); snippet != "" && s.retention(rule.TenantID).storesBodies() {
+				errText += ": " + snippet
+			}
+		}
+	}
+	now := time.Now().UTC()
+	s.hub.Availability().RecordCheck(key, now, errText != "")
+	resolveID, startedFailing := s.synthetic.record(key, check.FailureThreshold, statusCode, time.Since(start), errText, now)
+	status, _ := s.synthetic.Status(key)
+
+	event := map[string]any{
+		"tenant_id":            rule.TenantID,
+		"route_id":             rule.ID,
+		"method":               check.Method,
+		"path":                 check.Path,
+		"status_code":          statusCode,
+		"error":                errText,
+		"consecutive_failures": status.ConsecutiveFailures,
+		"uptime_percent":       status.UptimePercent,
+	}
+	switch {
+	case startedFailing:
+		incident := s.incidentStore.Add("warning", "synthetic",
+			fmt.Sprintf("synthetic check of route %s failed %d times in a row: %s", key, status.ConsecutiveFailures, errText))
+		s.synthetic.setIncident(key, incident.ID)
+		s.webhooks.Emit("route.check_failed", rule.TenantID, event)
+	case resolveID != "":
+		s.incidentStore.Resolve(resolveID)
+		s.webhooks.Emit("route.check_recovered", rule.TenantID, event)
+	}
+	return status
+}
+
+type syntheticRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *syntheticRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *syntheticRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *syntheticRecorder) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	if remaining := maxSyntheticBodyBytes - r.body.Len(); remaining > 0 {
+		r.body.Write(data[:min(len(data), remaining)])
+	}
+	return len(data), nil
+}
+
+// snippet is the start of the body for failure details, redacted first so a
+// masked JSON field or pattern is not cut in half.
+func (r *syntheticRecorder) snippet(redaction *RedactionPolicy) string {
+	body := strings.TrimSpace(string(redaction.RedactBody(r.body.Bytes())))
+	if len(body) > syntheticErrorSnippetBytes {
+		body = body[:syntheticErrorSnippetBytes]
+	}
+	return body
+}
+
+func (r *syntheticRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSyntheticChecksAlertAfterConsecutiveFailuresAndRecover(t *testing.T) {
+	var healthy atomic.Bool
+	var seen atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen.Store(r.Method + " " + r.URL.RequestURI() + " " + r.Header.Get("X-Proxer-Synthetic-Check"))
+		if !healthy.Load() {
+			http.Error(w, "deploy in progress", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	rule, err := server.ruleStore.Upsert(Rule{
+		ID:             "app",
+		Target:         upstream.URL,
+		Token:          "secret",
+		SyntheticCheck: &RouteSyntheticCheck{Method: "post", Path: "/hook?probe=1", FailureThreshold: 2},
+	})
+	if err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+
+	ctx := context.Background()
+	if status := server.runSyntheticCheck(ctx, rule); status.Status != SyntheticStatusDegraded || status.LastStatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected a degraded check after one failure, got %+v", status)
+	}
+	if got := seen.Load(); got != "POST /hook?probe=1 true" {
+		t.Fatalf("upstream saw %q", got)
+	}
+	if incidents := server.incidentStore.List(10); len(incidents) != 0 {
+		t.Fatalf("expected no incident below the threshold, got %+v", incidents)
+	}
+
+	status := server.runSyntheticCheck(ctx, rule)
+	if status.Status != SyntheticStatusFailing || status.ConsecutiveFailures != 2 || status.UptimePercent != 0 {
+		t.Fatalf("expected a failing check, got %+v", status)
+	}
+	incidents := server.incidentStore.List(10)
+	if len(incidents) != 1 || incidents[0].Source != "synthetic" || incidents[0].ResolvedAt != nil {
+		t.Fatalf("expected one open synthetic incident, got %+v", incidents)
+	}
+	server.runSyntheticCheck(ctx, rule)
+	if incidents := server.incidentStore.List(10); len(incidents) != 1 {
+		t.Fatalf("expected a single incident while failing, got %d", len(incidents))
+	}
+
+	healthy.Store(true)
+	status = server.runSyntheticCheck(ctx, rule)
+	if status.Status != SyntheticStatusPassing || status.Checks != 4 || status.UptimePercent != 25 {
+		t.Fatalf("expected the check to recover, got %+v", status)
+	}
+	if incidents := server.incidentStore.List(10); incidents[0].ResolvedAt == nil {
+		t.Fatalf("expected the incident to be resolved on recovery")
+	}
+	if view := server.buildRouteView(rule); view.SyntheticStatus == nil || view.SyntheticStatus.Checks != 4 {
+		t.Fatalf("expected the route view to carry the check status, got %+v", view.SyntheticStatus)
+	}
+}
+
+func TestNormalizeRouteSyntheticCheck(t *testing.T) {
+	check, err := normalizeRouteSyntheticCheck(&RouteSyntheticCheck{Headers: map[string]string{"x-probe": "1"}})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if check.Method != http.MethodGet || check.Path != "/" || check.IntervalSeconds != defaultSyntheticInterval || check.FailureThreshold != defaultSyntheticThreshold || check.Headers["X-Probe"] != "1" {
+		t.Fatalf("unexpected normalized check %+v", check)
+	}
+	for _, input := range []RouteSyntheticCheck{
+		{Method: "CONNECT"},
+		{Path: "health"},
+		{IntervalSeconds: 5},
+		{ExpectStatus: 99},
+		{FailureThreshold: -1},
+		{Headers: map[string]string{"Bad Header": "x"}},
+	} {
+		if _, err := normalizeRouteSyntheticCheck(&input); err == nil {
+			t.Fatalf("expected %+v to be rejected", input)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TenantStatusActive    = "active"
+	TenantStatusSuspended = "suspended"
+	TenantStatusArchived  = "archived"
+
+	defaultSuspendedMessage = "This tenant has been suspended."
+)
+
+type TenantSuspension struct {
+	Reason      string    `json:"reason,omitempty"`
+	StatusCode  int       `json:"status_code"`
+	Message     string    `json:"message"`
+	SuspendedBy string    `json:"suspended_by"`
+	SuspendedAt time.Time `json:"suspended_at"`
+}
+
+// TenantArchive holds the routes, connectors and domains taken out of the
+// gateway when a suspended tenant is archived, so reactivating it can put
+// them back. Connector credentials keep their secret hashes, so agents
+// reconnect with the secrets they have.
+type TenantArchive struct {
+	TenantID    string                        `json:"tenant_id"`
+	ArchivedBy  string                        `json:"archived_by"`
+	ArchivedAt  time.Time                     `json:"archived_at"`
+	Routes      []Rule                        `json:"routes"`
+	Connectors  []Connector                   `json:"connectors"`
+	Credentials []connectorCredentialSnapshot `json:"credentials,omitempty"`
+	Domains     []CustomDomain                `json:"domains"`
+}
+
+type suspendTenantRequest struct {
+	Reason     string `json:"reason,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+type TenantArchiveStore struct {
+	mu       sync.RWMutex
+	archives map[string]TenantArchive
+}
+
+func NewTenantArchiveStore() *TenantArchiveStore {
+	return &TenantArchiveStore{archives: make(map[string]TenantArchive)}
+}
+
+func (s *TenantArchiveStore) Put(archive TenantArchive) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.archives[archive.TenantID] = archive
+}
+
+func (s *TenantArchiveStore) Get(tenantID string) (TenantArchive, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	archive, ok := s.archives[normalizeIdentifier(tenantID)]
+	return archive, ok
+}
+
+func (s *TenantArchiveStore) Delete(tenantID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.archives, normalizeIdentifier(tenantID))
+}
+
+func (s *TenantArchiveStore) Snapshot() []TenantArchive {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]TenantArchive, 0, len(s.archives))
+	for _, archive := range s.archives {
+		out = append(out, archive)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TenantID < out[j].TenantID })
+	return out
+}
+
+func (s *TenantArchiveStore) Restore(archives []TenantArchive) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.archives = make(map[string]TenantArchive, len(archives))
+	for _, archive := range archives {
+		if tenantID := normalizeIdentifier(archive.TenantID); tenantID != "" {
+			archive.TenantID = tenantID
+			s.archives[tenantID] = archive
+		}
+	}
+}
+
+func (t Tenant) status() string {
+	if t.Status == "" {
+		return TenantStatusActive
+	}
+	return t.Status
+}
+
+func (s *Server) tenantInactive(tenantID string) (Tenant, bool) {
+	tenant, ok := s.ruleStore.GetTenant(tenantID)
+	if !ok || tenant.status() == TenantStatusActive {
+		return Tenant{}, false
+	}
+	return tenant, true
+}
+
+func (s *Server) writeTenantSuspended(w http.ResponseWriter, tenantID string) bool {
+	tenant, inactive := s.tenantInactive(tenantID)
+	if !inactive {
+		return false
+	}
+	status, message := http.StatusForbidden, defaultSuspendedMessage
+	if tenant.Suspension != nil {
+		status, message = tenant.Suspension.StatusCode, tenant.Suspension.Message
+	}
+	http.Error(w, message, status)
+	return true
+}
+
+func (s *Server) requireConnectorTenantActive(w http.ResponseWriter, connectorID string) bool {
+	connector, ok := s.connectorStore.Get(connectorID)
+	if !ok {
+		return true
+	}
+	if tenant, inactive := s.tenantInactive(connector.TenantID); inactive {
+		writeAPIError(w, http.StatusForbidden, errCodeTenantSuspended, fmt.Sprintf("tenant %q is %s", tenant.ID, tenant.status()))
+		return false
+	}
+	return true
+}
+
+func (s *Server) suspendTenant(tenantID, actor string, request suspendTenantRequest) (Tenant, error) {
+	suspension := &TenantSuspension{
+		Reason:      strings.TrimSpace(request.Reason),
+		StatusCode:  request.StatusCode,
+		Message:     strings.TrimSpace(request.Message),
+		SuspendedBy: actor,
+		SuspendedAt: time.Now().UTC(),
+	}
+	if suspension.StatusCode == 0 {
+		suspension.StatusCode = http.StatusForbidden
+	}
+	if suspension.StatusCode != http.StatusForbidden && suspension.StatusCode != http.StatusUnavailableForLegalReasons {
+		return Tenant{}, fmt.Errorf("status_code must be 403 or 451")
+	}
+	if suspension.Message == "" {
+		suspension.Message = defaultSuspendedMessage
+	}
+	tenant, err := s.ruleStore.SetTenantStatus(tenantID, TenantStatusSuspended, suspension, TenantStatusActive)
+	if err != nil {
+		return Tenant{}, err
+	}
+	for _, connector := range s.connectorStore.ListForTenants([]string{tenant.ID}) {
+		s.hub.DisconnectConnector(connector.ID)
+	}
+	return tenant, nil
+}
+
+func (s *Server) archiveTenant(tenantID, actor string) (TenantArchive, error) {
+	tenant, ok := s.ruleStore.GetTenant(tenantID)
+	if !ok {
+		return TenantArchive{}, fmt.Errorf("tenant %q not found", tenantID)
+	}
+	if tenant.status() != TenantStatusSuspended {
+		return TenantArchive{}, fmt.Errorf("only suspended tenants can be archived")
+	}
+	if _, err := s.ruleStore.SetTenantStatus(tenant.ID, TenantStatusArchived, tenant.Suspension, TenantStatusSuspended); err != nil {
+		return TenantArchive{}, err
+	}
+	connectors, credentials := s.connectorStore.TakeTenant(tenant.ID)
+	for _, connector := range connectors {
+		s.hub.DisconnectConnector(connector.ID)
+	}
+	archive := TenantArchive{
+		TenantID:    tenant.ID,
+		ArchivedBy:  actor,
+		ArchivedAt:  time.Now().UTC(),
+		Routes:      s.ruleStore.TakeTenantRules(tenant.ID),
+		Connectors:  connectors,
+		Credentials: credentials,
+		Domains:     s.domainStore.TakeTenant(tenant.ID),
+	}
+	s.tenantArchives.Put(archive)
+	s.refreshTenantUsage(tenant.ID)
+	return archive, nil
+}
+
+// reactivateTenant makes tenantID active again, first putting back what its
+// archive holds. Connectors and domains whose IDs or hostnames were taken in
+// the meantime are skipped and listed.
+func (s *Server) reactivateTenant(tenantID string) (Tenant, []string, error) {
+	tenant, ok := s.ruleStore.GetTenant(tenantID)
+	if !ok {
+		return Tenant{}, nil, fmt.Errorf("tenant %q not found", tenantID)
+	}
+	if tenant.status() == TenantStatusActive {
+		return Tenant{}, nil, fmt.Errorf("tenant %q is already active", tenant.ID)
+	}
+
+	var skipped []string
+	if archive, ok := s.tenantArchives.Get(tenant.ID); ok {
+		s.ruleStore.PutTenantRules(tenant.ID, archive.Routes)
+		for _, id := range s.connectorStore.PutTenant(archive.Connectors, archive.Credentials) {
+			skipped = append(skipped, "connector:"+id)
+		}
+		for _, hostname := range s.domainStore.PutTenant(archive.Domains) {
+			skipped = append(skipped, "domain:"+hostname)
+		}
+	}
+	tenant, err := s.ruleStore.SetTenantStatus(tenant.ID, "", nil, tenant.status())
+	if err != nil {
+		return Tenant{}, nil, err
+	}
+	s.tenantArchives.Delete(tenant.ID)
+	s.refreshTenantUsage(tenant.ID)
+	return tenant, skipped, nil
+}
+
+func (s *Server) handleAdminTenantStatus(w http.ResponseWriter, r *http.Request, user User, tenantID, action string) {
+	if _, ok := s.ruleStore.GetTenant(tenantID); !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
+		return
+	}
+	if action == "archive" && r.Method == http.MethodGet {
+		archive, ok := s.tenantArchives.Get(tenantID)
+		if !ok {
+			writeAPIError(w, http.StatusNotFound, errCodeNotFound, "tenant is not archived")
+			return
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "tenant-"+archive.TenantID+"-archive.json"))
+		writeJSON(w, http.StatusOK, archive)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var (
+		tenant  Tenant
+		details = map[string]string{}
+		err     error
+	)
+	switch action {
+	case "suspend":
+		var request suspendTenantRequest
+		if !s.decodeJSON(w, r, &request, "suspend payload") {
+			return
+		}
+		tenant, err = s.suspendTenant(tenantID, user.Username, request)
+		details["reason"] = strings.TrimSpace(request.Reason)
+	case "archive":
+		var archive TenantArchive
+		archive, err = s.archiveTenant(tenantID, user.Username)
+		tenant, _ = s.ruleStore.GetTenant(tenantID)
+		details["routes"] = fmt.Sprint(len(archive.Routes))
+		details["connectors"] = fmt.Sprint(len(archive.Connectors))
+		details["domains"] = fmt.Sprint(len(archive.Domains))
+	case "reactivate":
+		var skipped []string
+		tenant, skipped, err = s.reactivateTenant(tenantID)
+		details["skipped"] = strings.Join(skipped, ",")
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusConflict, errCodeConflict, err.Error())
+		return
+	}
+	s.auditStore.Record(user.Username, "tenant."+action, tenant.ID, details)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": "tenant " + tenant.status(),
+		"tenant":  s.buildTenantView(tenant),
+		"details": details,
+	})
+	s.persistState()
+}
@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTenantSuspensionArchiveAndReactivation(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	if _, err := server.ruleStore.UpsertTenant(Tenant{ID: "acme"}); err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+	if _, err := server.ruleStore.UpsertForTenant("acme", Rule{ID: "app", Target: "http://127.0.0.1:9"}); err != nil {
+		t.Fatalf("create route: %v", err)
+	}
+	if _, err := server.connectorStore.Create(Connector{ID: "edge", TenantID: "acme", Name: "edge"}); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+	secret, err := server.connectorStore.RotateCredential("edge")
+	if err != nil {
+		t.Fatalf("rotate credential: %v", err)
+	}
+	if _, err := server.domainStore.Attach("acme", "app", "app.acme.example"); err != nil {
+		t.Fatalf("attach domain: %v", err)
+	}
+	adminSession, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+adminSession)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+	tenantOf := func(recorder *httptest.ResponseRecorder) tenantView {
+		t.Helper()
+		var payload struct {
+			Tenant tenantView `json:"tenant"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode tenant: %v %s", err, recorder.Body.String())
+		}
+		return payload.Tenant
+	}
+
+	if recorder := call(http.MethodPost, "/api/admin/tenants/acme/archive", ""); recorder.Code != http.StatusConflict {
+		t.Fatalf("expected an active tenant not to be archived, got %d", recorder.Code)
+	}
+	if recorder := call(http.MethodPost, "/api/admin/tenants/default/suspend", "{}"); recorder.Code != http.StatusConflict {
+		t.Fatalf("expected the default tenant not to be suspended, got %d", recorder.Code)
+	}
+	if recorder := call(http.MethodPost, "/api/admin/tenants/acme/suspend", `{"status_code":500}`); recorder.Code != http.StatusConflict {
+		t.Fatalf("expected status 500 to be rejected, got %d", recorder.Code)
+	}
+	recorder := call(http.MethodPost, "/api/admin/tenants/acme/suspend", `{"reason":"court order","status_code":451,"message":"Blocked by order."}`)
+	if tenant := tenantOf(recorder); recorder.Code != http.StatusOK || tenant.Status != TenantStatusSuspended || tenant.Suspension.StatusCode != http.StatusUnavailableForLegalReasons {
+		t.Fatalf("expected acme to be suspended, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	proxied := httptest.NewRecorder()
+	mux.ServeHTTP(proxied, httptest.NewRequest(http.MethodGet, "/t/acme/app/", nil))
+	if proxied.Code != http.StatusUnavailableForLegalReasons || !strings.Contains(proxied.Body.String(), "Blocked by order.") {
+		t.Fatalf("expected the suspension page, got %d: %s", proxied.Code, proxied.Body.String())
+	}
+	register := httptest.NewRecorder()
+	mux.ServeHTTP(register, httptest.NewRequest(http.MethodPost, "/api/agent/register", strings.NewReader(`{"agent_id":"a1","connector_id":"edge","connector_secret":"`+secret+`"}`)))
+	if register.Code != http.StatusForbidden || !strings.Contains(register.Body.String(), string(errCodeTenantSuspended)) {
+		t.Fatalf("expected the agent to be refused, got %d: %s", register.Code, register.Body.String())
+	}
+	if _, err := server.ruleStore.UpsertForTenant("acme", Rule{ID: "other", Target: "http://127.0.0.1:9"}); err == nil {
+		t.Fatalf("expected a suspended tenant not to add routes")
+	}
+
+	recorder = call(http.MethodPost, "/api/admin/tenants/acme/archive", "")
+	if tenant := tenantOf(recorder); recorder.Code != http.StatusOK || tenant.Status != TenantStatusArchived || tenant.RouteCount != 0 {
+		t.Fatalf("expected acme to be archived, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if _, ok := server.connectorStore.Get("edge"); ok {
+		t.Fatalf("expected the connector to be moved into the archive")
+	}
+	if _, ok := server.domainStore.Get("app.acme.example"); ok {
+		t.Fatalf("expected the domain to be freed")
+	}
+	recorder = call(http.MethodGet, "/api/admin/tenants/acme/archive", "")
+	var archive TenantArchive
+	if err := json.Unmarshal(recorder.Body.Bytes(), &archive); err != nil || len(archive.Routes) != 1 || len(archive.Connectors) != 1 || len(archive.Credentials) != 1 || len(archive.Domains) != 1 {
+		t.Fatalf("expected the archive export, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	restarted := NewServer(Config{StorageDriver: "memory"}, nil)
+	restarted.applySnapshot(server.buildSnapshot())
+	if _, ok := restarted.tenantArchives.Get("acme"); !ok {
+		t.Fatalf("expected the archive to be persisted")
+	}
+
+	recorder = call(http.MethodPost, "/api/admin/tenants/acme/reactivate", "")
+	if tenant := tenantOf(recorder); recorder.Code != http.StatusOK || tenant.Status != TenantStatusActive || tenant.Suspension != nil || tenant.RouteCount != 1 {
+		t.Fatalf("expected acme to be reactivated, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if !server.connectorStore.Authenticate("edge", secret) {
+		t.Fatalf("expected the connector to keep its secret")
+	}
+	if _, ok := server.domainStore.Get("app.acme.example"); !ok {
+		t.Fatalf("expected the domain to be restored")
+	}
+	if _, ok := server.tenantArchives.Get("acme"); ok {
+		t.Fatalf("expected the archive to be dropped")
+	}
+
+	actions := map[string]bool{}
+	for _, event := range server.auditStore.List("acme", 10) {
+		actions[event.Action] = true
+	}
+	for _, action := range []string{"tenant.suspend", "tenant.archive", "tenant.reactivate"} {
+		if !actions[action] {
+			t.Fatalf("expected %s in the audit log, got %+v", action, actions)
+		}
+	}
+}
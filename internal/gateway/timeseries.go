@@ -0,0 +1,199 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	timeseriesResolution = time.Minute
+	timeseriesRetention  = 24 * time.Hour
+)
+
+type TimeseriesPoint struct {
+	Start    time.Time `json:"start"`
+	Requests int64     `json:"requests"`
+	Errors   int64     `json:"errors"`
+	BytesIn  int64     `json:"bytes_in"`
+	BytesOut int64     `json:"bytes_out"`
+}
+
+// TimeseriesStore keeps per-minute traffic buckets for each route. Only
+// minutes with traffic are stored and buckets older than the retention window
+// are dropped, so memory is bounded by retention per active route.
+type TimeseriesStore struct {
+	mu     sync.RWMutex
+	series map[string][]TimeseriesPoint
+}
+
+func NewTimeseriesStore() *TimeseriesStore {
+	return &TimeseriesStore{series: make(map[string][]TimeseriesPoint)}
+}
+
+func (s *TimeseriesStore) Record(tunnelKey string, at time.Time, failed bool, bytesIn, bytesOut int64) {
+	tunnelKey = strings.TrimSpace(tunnelKey)
+	if tunnelKey == "" {
+		return
+	}
+	minute := at.UTC().Truncate(timeseriesResolution)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	points := s.series[tunnelKey]
+	index := len(points)
+	for index > 0 && points[index-1].Start.After(minute) {
+		index--
+	}
+	if index == 0 || !points[index-1].Start.Equal(minute) {
+		if n := len(points); n > 0 && !minute.After(points[n-1].Start.Add(-timeseriesRetention)) {
+			return
+		}
+		points = append(points, TimeseriesPoint{})
+		copy(points[index+1:], points[index:])
+		points[index] = TimeseriesPoint{Start: minute}
+		index++
+	}
+	point := &points[index-1]
+	point.Requests++
+	if failed {
+		point.Errors++
+	}
+	point.BytesIn += bytesIn
+	point.BytesOut += bytesOut
+	s.series[tunnelKey] = trimTimeseries(points, points[len(points)-1].Start.Add(-timeseriesRetention))
+}
+
+func (s *TimeseriesStore) PruneTenant(tenantID string, cutoff time.Time) int {
+	prefix := ruleKey(normalizeIdentifier(tenantID), "")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pruned := 0
+	for key, points := range s.series {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		kept := trimTimeseries(points, cutoff.Add(-timeseriesResolution))
+		pruned += len(points) - len(kept)
+		if len(kept) == 0 {
+			delete(s.series, key)
+		} else {
+			s.series[key] = kept
+		}
+	}
+	return pruned
+}
+
+func (s *TimeseriesStore) Query(tunnelKey string, window time.Duration, now time.Time) []TimeseriesPoint {
+	if window <= 0 || window > timeseriesRetention {
+		window = timeseriesRetention
+	}
+	end := now.UTC().Truncate(timeseriesResolution)
+	start := end.Add(-window + timeseriesResolution)
+
+	s.mu.RLock()
+	stored := s.series[strings.TrimSpace(tunnelKey)]
+	byMinute := make(map[int64]TimeseriesPoint, len(stored))
+	for _, point := range stored {
+		if !point.Start.Before(start) && !point.Start.After(end) {
+			byMinute[point.Start.Unix()] = point
+		}
+	}
+	s.mu.RUnlock()
+
+	points := make([]TimeseriesPoint, 0, int(window/timeseriesResolution))
+	for minute := start; !minute.After(end); minute = minute.Add(timeseriesResolution) {
+		point, ok := byMinute[minute.Unix()]
+		if !ok {
+			point = TimeseriesPoint{Start: minute}
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+func (s *TimeseriesStore) Sum(tunnelKey string, since time.Time) TimeseriesPoint {
+	total := TimeseriesPoint{Start: since.UTC().Truncate(timeseriesResolution)}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, point := range s.series[strings.TrimSpace(tunnelKey)] {
+		if point.Start.Before(total.Start) {
+			continue
+		}
+		total.Requests += point.Requests
+		total.Errors += point.Errors
+		total.BytesIn += point.BytesIn
+		total.BytesOut += point.BytesOut
+	}
+	return total
+}
+
+func trimTimeseries(points []TimeseriesPoint, cutoff time.Time) []TimeseriesPoint {
+	drop := 0
+	for drop < len(points) && !points[drop].Start.After(cutoff) {
+		drop++
+	}
+	if drop == 0 {
+		return points
+	}
+	return append(points[:0:0], points[drop:]...)
+}
+
+func (s *Server) handleRouteTimeseries(w http.ResponseWriter, r *http.Request, tenantID, routeID string) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if _, ok := s.ruleStore.GetForTenant(tenantID, routeID); !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeRouteNotFound, "route not found")
+		return
+	}
+	window, err := parseTimeseriesWindow(r.URL.Query().Get("window"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+
+	points := s.hub.Timeseries().Query(MakeTunnelKey(tenantID, routeID), window, time.Now())
+	var totals TimeseriesPoint
+	for _, point := range points {
+		totals.Requests += point.Requests
+		totals.Errors += point.Errors
+		totals.BytesIn += point.BytesIn
+		totals.BytesOut += point.BytesOut
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id":      tenantID,
+		"route_id":       routeID,
+		"window_seconds": int64(window / time.Second),
+		"step_seconds":   int64(timeseriesResolution / time.Second),
+		"points":         points,
+		"totals": map[string]int64{
+			"requests":  totals.Requests,
+			"errors":    totals.Errors,
+			"bytes_in":  totals.BytesIn,
+			"bytes_out": totals.BytesOut,
+		},
+	})
+}
+
+func parseTimeseriesWindow(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Hour, nil
+	}
+	window, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window %q", raw)
+	}
+	if window < timeseriesResolution || window > timeseriesRetention {
+		return 0, fmt.Errorf("window must be between %s and %s", timeseriesResolution, timeseriesRetention)
+	}
+	return window.Truncate(timeseriesResolution), nil
+}
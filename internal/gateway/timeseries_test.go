@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeseriesStoreBucketsPerMinute(t *testing.T) {
+	store := NewTimeseriesStore()
+	now := time.Now().UTC().Truncate(time.Minute).Add(30 * time.Second)
+
+	store.Record("default/app", now.Add(-2*time.Minute), false, 100, 400)
+	store.Record("default/app", now.Add(-2*time.Minute+10*time.Second), true, 50, 0)
+	store.Record("default/app", now, false, 10, 20)
+	store.Record("default/app", now.Add(-25*time.Hour), false, 1, 1)
+
+	points := store.Query("default/app", 5*time.Minute, now)
+	if len(points) != 5 {
+		t.Fatalf("expected 5 dense points, got %d", len(points))
+	}
+	if !points[4].Start.Equal(now.Truncate(time.Minute)) {
+		t.Fatalf("expected last point at current minute, got %s", points[4].Start)
+	}
+	busy := points[2]
+	if busy.Requests != 2 || busy.Errors != 1 || busy.BytesIn != 150 || busy.BytesOut != 400 {
+		t.Fatalf("unexpected aggregated bucket: %+v", busy)
+	}
+	if points[3].Requests != 0 || points[4].Requests != 1 {
+		t.Fatalf("unexpected sparse fill: %+v", points)
+	}
+
+	restored := NewTimeseriesStore()
+	restored.Restore(store.Snapshot())
+	if got := restored.Query("default/app", 5*time.Minute, now); got[2] != busy {
+		t.Fatalf("expected snapshot round trip, got %+v", got[2])
+	}
+}
+
+func TestParseTimeseriesWindow(t *testing.T) {
+	if window, err := parseTimeseriesWindow(""); err != nil || window != time.Hour {
+		t.Fatalf("expected 1h default, got %s (%v)", window, err)
+	}
+	if window, err := parseTimeseriesWindow("90m"); err != nil || window != 90*time.Minute {
+		t.Fatalf("expected 90m window, got %s (%v)", window, err)
+	}
+	for _, raw := range []string{"abc", "30s", "48h"} {
+		if _, err := parseTimeseriesWindow(raw); err == nil {
+			t.Fatalf("expected window %q to be rejected", raw)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tlsExpirySweepInterval = time.Hour
+
+type expiringCertificate struct {
+	ID            string    `json:"id"`
+	Hostname      string    `json:"hostname"`
+	Active        bool      `json:"active"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	DaysRemaining int       `json:"days_remaining"`
+	Expired       bool      `json:"expired"`
+}
+
+func (s *TLSStore) Expiring(now, cutoff time.Time) []expiringCertificate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]expiringCertificate, 0)
+	for _, record := range s.cert {
+		meta := record.meta
+		if !meta.ExpiresAt.Before(cutoff) {
+			continue
+		}
+		out = append(out, expiringCertificate{
+			ID:            meta.ID,
+			Hostname:      meta.Hostname,
+			Active:        meta.Active,
+			ExpiresAt:     meta.ExpiresAt,
+			DaysRemaining: int(meta.ExpiresAt.Sub(now).Hours() / 24),
+			Expired:       !meta.ExpiresAt.After(now),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ExpiresAt.Equal(out[j].ExpiresAt) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].ExpiresAt.Before(out[j].ExpiresAt)
+	})
+	return out
+}
+
+// markExpiryWarned records that the certificate's current expiry was alerted
+// and reports whether it had not been yet. Uploading a renewed certificate
+// changes ExpiresAt and re-arms the alert.
+func (s *TLSStore) markExpiryWarned(id string, expiresAt time.Time, expired bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := expiresAt.Format(time.RFC3339)
+	if expired {
+		key += "/expired"
+	}
+	if s.warned[id] == key {
+		return false
+	}
+	s.warned[id] = key
+	return true
+}
+
+func (s *Server) runTLSExpiryLoop(ctx context.Context) {
+	s.checkTLSExpiry(time.Now().UTC())
+	ticker := time.NewTicker(tlsExpirySweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.checkTLSExpiry(now.UTC())
+		}
+	}
+}
+
+func (s *Server) checkTLSExpiry(now time.Time) {
+	days := s.config().TLSExpiryWarningDays
+	for _, cert := range s.tlsStore.Expiring(now, now.AddDate(0, 0, days)) {
+		if !cert.Active || !s.tlsStore.markExpiryWarned(cert.ID, cert.ExpiresAt, cert.Expired) {
+			continue
+		}
+		severity := "warning"
+		message := fmt.Sprintf("tls certificate %s for %s expires in %d days on %s", cert.ID, cert.Hostname, cert.DaysRemaining, cert.ExpiresAt.Format(time.RFC3339))
+		if cert.Expired {
+			severity = "critical"
+			message = fmt.Sprintf("tls certificate %s for %s expired on %s", cert.ID, cert.Hostname, cert.ExpiresAt.Format(time.RFC3339))
+		}
+		s.incidentStore.Add(severity, "tls", message)
+		s.webhooks.Emit("tls.expiring", "", cert)
+	}
+}
+
+func (s *Server) handleAdminTLSExpiring(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	if !s.requireSuperAdmin(w, user) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	days := s.config().TLSExpiryWarningDays
+	if raw := strings.TrimSpace(r.URL.Query().Get("days")); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 || value > 3650 {
+			writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "days must be between 1 and 3650")
+			return
+		}
+		days = value
+	}
+	now := time.Now().UTC()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"generated_at": now,
+		"within_days":  days,
+		"certificates": s.tlsStore.Expiring(now, now.AddDate(0, 0, days)),
+	})
+}
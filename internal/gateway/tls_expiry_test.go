@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testCertificatePEM(t *testing.T, hostname string, notAfter time.Time) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: hostname},
+		DNSNames:     []string{hostname},
+		NotBefore:    notAfter.AddDate(0, -3, 0),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestTLSExpiryAlertsOncePerCertificate(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", TLSExpiryWarningDays: 14}, nil)
+	now := time.Now().UTC()
+	for _, cert := range []struct {
+		id       string
+		notAfter time.Time
+		active   bool
+	}{
+		{"soon", now.Add(5*24*time.Hour + time.Hour), true},
+		{"later", now.AddDate(0, 6, 0), true},
+		{"standby", now.Add(2 * 24 * time.Hour), false},
+	} {
+		certPEM, keyPEM := testCertificatePEM(t, cert.id+".example.com", cert.notAfter)
+		if _, err := server.tlsStore.Upsert(TLSCertificateInput{ID: cert.id, Hostname: cert.id + ".example.com", CertPEM: certPEM, KeyPEM: keyPEM, Active: cert.active}); err != nil {
+			t.Fatalf("upsert %s: %v", cert.id, err)
+		}
+	}
+
+	server.checkTLSExpiry(now)
+	server.checkTLSExpiry(now.Add(time.Hour))
+	incidents := server.incidentStore.List(10)
+	if len(incidents) != 1 || incidents[0].Source != "tls" || incidents[0].Severity != "warning" {
+		t.Fatalf("expected one tls warning for the active expiring certificate, got %+v", incidents)
+	}
+
+	server.checkTLSExpiry(now.AddDate(0, 0, 7))
+	incidents = server.incidentStore.List(10)
+	if len(incidents) != 2 || incidents[0].Severity != "critical" {
+		t.Fatalf("expected a critical incident once the certificate expired, got %+v", incidents)
+	}
+
+	sessionID, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/tls/expiring?days=30", nil)
+	req.Header.Set("Authorization", "Bearer "+sessionID)
+	recorder := httptest.NewRecorder()
+	server.handleAdminTLSExpiring(recorder, req)
+	var payload struct {
+		WithinDays   int                   `json:"within_days"`
+		Certificates []expiringCertificate `json:"certificates"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode %q: %v", recorder.Body.String(), err)
+	}
+	if payload.WithinDays != 30 || len(payload.Certificates) != 2 || payload.Certificates[0].ID != "standby" || payload.Certificates[1].DaysRemaining != 5 {
+		t.Fatalf("unexpected expiring summary: %+v", payload)
+	}
+}
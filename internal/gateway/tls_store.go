@@ -42,9 +42,10 @@ type tlsCertificateRecord struct {
 }
 
 type TLSStore struct {
-	mu   sync.RWMutex
-	key  []byte
-	cert map[string]tlsCertificateRecord
+	mu     sync.RWMutex
+	key    []byte
+	cert   map[string]tlsCertificateRecord
+	warned map[string]string
 }
 
 func NewTLSStore(encryptionKey string) *TLSStore {
@@ -55,8 +56,9 @@ func NewTLSStore(encryptionKey string) *TLSStore {
 		keyBytes = sum[:]
 	}
 	return &TLSStore{
-		key:  keyBytes,
-		cert: make(map[string]tlsCertificateRecord),
+		key:    keyBytes,
+		cert:   make(map[string]tlsCertificateRecord),
+		warned: make(map[string]string),
 	}
 }
 
@@ -188,6 +190,7 @@ func (s *TLSStore) Delete(id string) bool {
 		return false
 	}
 	delete(s.cert, id)
+	delete(s.warned, id)
 	return true
 }
 
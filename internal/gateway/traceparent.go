@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const traceparentHeader = "Traceparent"
+
+func nextTraceparent(incoming string) string {
+	spanID := randomHex(8)
+	if traceID, flags, ok := parseTraceparent(incoming); ok {
+		return "00-" + traceID + "-" + spanID + "-" + flags
+	}
+	return "00-" + randomHex(16) + "-" + spanID + "-01"
+}
+
+func parseTraceparent(value string) (traceID, flags string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return "", "", false
+	}
+	if !isLowerHex(parts[1], 32) || !isLowerHex(parts[2], 16) || !isLowerHex(parts[3], 2) {
+		return "", "", false
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", "", false
+	}
+	return parts[1], parts[3], true
+}
+
+func isLowerHex(value string, length int) bool {
+	if len(value) != length {
+		return false
+	}
+	for _, r := range value {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(size int) string {
+	buf := make([]byte, size)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func injectTraceparent(headers map[string][]string) {
+	incoming := http.Header(headers).Get(traceparentHeader)
+	headers[traceparentHeader] = []string{nextTraceparent(incoming)}
+}
@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNextTraceparentContinuesValidTraces(t *testing.T) {
+	incoming := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+	next := nextTraceparent(incoming)
+	traceID, flags, ok := parseTraceparent(next)
+	if !ok || traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || flags != "00" {
+		t.Fatalf("expected trace and flags to be kept, got %q", next)
+	}
+	if next == incoming {
+		t.Fatalf("expected a new parent span ID")
+	}
+
+	for _, invalid := range []string{"", "garbage", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "00-00000000000000000000000000000000-00f067aa0ba902b7-01"} {
+		fresh := nextTraceparent(invalid)
+		traceID, flags, ok := parseTraceparent(fresh)
+		if !ok || flags != "01" || strings.Contains(invalid, traceID) {
+			t.Fatalf("expected a new sampled trace for %q, got %q", invalid, fresh)
+		}
+	}
+}
+
+func TestProxyInjectsTraceparentWhenEnabled(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Header.Get("X-Proxer-Request-ID")+" "+r.Header.Get("Traceparent"))
+	}))
+	defer upstream.Close()
+
+	for _, enabled := range []bool{false, true} {
+		server := NewServer(Config{StorageDriver: "memory", InjectTraceparent: enabled}, nil)
+		if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "web", Target: upstream.URL}); err != nil {
+			t.Fatalf("upsert route: %v", err)
+		}
+		recorder := httptest.NewRecorder()
+		server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/web/", nil))
+		requestID, traceparent, _ := strings.Cut(recorder.Body.String(), " ")
+		if requestID == "" || requestID != recorder.Header().Get("X-Proxer-Request-ID") {
+			t.Fatalf("expected upstream to see the client's request ID, got %q", recorder.Body.String())
+		}
+		if _, _, ok := parseTraceparent(traceparent); ok != enabled {
+			t.Fatalf("inject=%v: unexpected traceparent %q", enabled, traceparent)
+		}
+	}
+}
+
+func TestProxyIncidentsCarryRequestID(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "down", Target: "http://127.0.0.1:1"}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodGet, "/t/down/", nil))
+	requestID := recorder.Header().Get("X-Proxer-Request-ID")
+	if recorder.Code != http.StatusBadGateway || !strings.Contains(recorder.Body.String(), requestID) {
+		t.Fatalf("expected 502 naming request %s, got %d %q", requestID, recorder.Code, recorder.Body.String())
+	}
+	incidents := server.incidentStore.List(10)
+	if len(incidents) == 0 || incidents[0].RequestID != requestID {
+		t.Fatalf("expected a proxy incident for request %s, got %+v", requestID, incidents)
+	}
+}
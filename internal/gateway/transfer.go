@@ -0,0 +1,198 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	transferDateLayout    = "2006-01-02"
+	transferRetentionDays = 62
+	defaultTransferDays   = 30
+
+	TransferKindRoute     = "route"
+	TransferKindConnector = "connector"
+)
+
+type TransferRecord struct {
+	TenantID     string `json:"tenant_id"`
+	Date         string `json:"date"`
+	Kind         string `json:"kind"`
+	ID           string `json:"id"`
+	IngressBytes int64  `json:"ingress_bytes"`
+	EgressBytes  int64  `json:"egress_bytes"`
+	Requests     int64  `json:"requests"`
+}
+
+type TransferTotal struct {
+	TenantID     string `json:"tenant_id"`
+	ID           string `json:"id"`
+	IngressBytes int64  `json:"ingress_bytes"`
+	EgressBytes  int64  `json:"egress_bytes"`
+	TotalBytes   int64  `json:"total_bytes"`
+	Requests     int64  `json:"requests"`
+}
+
+type TransferSummary struct {
+	From       string           `json:"from"`
+	To         string           `json:"to"`
+	Routes     []TransferTotal  `json:"routes"`
+	Connectors []TransferTotal  `json:"connectors"`
+	Daily      []TransferRecord `json:"daily,omitempty"`
+}
+
+type TransferStore struct {
+	mu      sync.RWMutex
+	records map[string]*TransferRecord
+	oldest  string
+}
+
+func NewTransferStore() *TransferStore {
+	return &TransferStore{records: make(map[string]*TransferRecord)}
+}
+
+func transferRecordKey(record TransferRecord) string {
+	return strings.Join([]string{record.TenantID, record.Date, record.Kind, record.ID}, "\x00")
+}
+
+func (s *TransferStore) Record(tenantID, routeID, connectorID string, ingressBytes, egressBytes int64, at time.Time) {
+	tenantID = normalizeIdentifier(tenantID)
+	if tenantID == "" {
+		tenantID = DefaultTenantID
+	}
+	date := at.UTC().Format(transferDateLayout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(time.Now())
+	if date < s.oldest {
+		return
+	}
+	s.addLocked(TransferRecord{TenantID: tenantID, Date: date, Kind: TransferKindRoute, ID: routeID, IngressBytes: ingressBytes, EgressBytes: egressBytes, Requests: 1})
+	if connectorID != "" {
+		s.addLocked(TransferRecord{TenantID: tenantID, Date: date, Kind: TransferKindConnector, ID: connectorID, IngressBytes: ingressBytes, EgressBytes: egressBytes, Requests: 1})
+	}
+}
+
+func (s *TransferStore) addLocked(delta TransferRecord) {
+	if strings.TrimSpace(delta.ID) == "" {
+		return
+	}
+	key := transferRecordKey(delta)
+	record, ok := s.records[key]
+	if !ok {
+		kept := delta
+		s.records[key] = &kept
+		return
+	}
+	record.IngressBytes += delta.IngressBytes
+	record.EgressBytes += delta.EgressBytes
+	record.Requests += delta.Requests
+}
+
+func (s *TransferStore) pruneLocked(now time.Time) {
+	cutoff := now.UTC().AddDate(0, 0, -(transferRetentionDays - 1)).Format(transferDateLayout)
+	if cutoff <= s.oldest {
+		return
+	}
+	for key, record := range s.records {
+		if record.Date < cutoff {
+			delete(s.records, key)
+		}
+	}
+	s.oldest = cutoff
+}
+
+func (s *TransferStore) PruneTenant(tenantID string, cutoff time.Time) int {
+	tenantID = normalizeIdentifier(tenantID)
+	date := cutoff.UTC().Format(transferDateLayout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pruned := 0
+	for key, record := range s.records {
+		if record.TenantID == tenantID && record.Date < date {
+			delete(s.records, key)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+func (s *TransferStore) Summary(tenantID string, days int, now time.Time) TransferSummary {
+	tenantID = normalizeIdentifier(tenantID)
+	to := now.UTC().Format(transferDateLayout)
+	from := now.UTC().AddDate(0, 0, -(days - 1)).Format(transferDateLayout)
+	summary := TransferSummary{From: from, To: to, Routes: []TransferTotal{}, Connectors: []TransferTotal{}, Daily: []TransferRecord{}}
+
+	routes := make(map[string]*TransferTotal)
+	connectors := make(map[string]*TransferTotal)
+	s.mu.RLock()
+	for _, record := range s.records {
+		if (tenantID != "" && record.TenantID != tenantID) || record.Date < from || record.Date > to {
+			continue
+		}
+		summary.Daily = append(summary.Daily, *record)
+		totals := routes
+		if record.Kind == TransferKindConnector {
+			totals = connectors
+		}
+		key := record.TenantID + "\x00" + record.ID
+		total, ok := totals[key]
+		if !ok {
+			total = &TransferTotal{TenantID: record.TenantID, ID: record.ID}
+			totals[key] = total
+		}
+		total.IngressBytes += record.IngressBytes
+		total.EgressBytes += record.EgressBytes
+		total.TotalBytes += record.IngressBytes + record.EgressBytes
+		total.Requests += record.Requests
+	}
+	s.mu.RUnlock()
+
+	summary.Routes = sortedTransferTotals(routes)
+	summary.Connectors = sortedTransferTotals(connectors)
+	sort.Slice(summary.Daily, func(i, j int) bool {
+		a, b := summary.Daily[i], summary.Daily[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return transferRecordKey(a) < transferRecordKey(b)
+	})
+	return summary
+}
+
+func sortedTransferTotals(totals map[string]*TransferTotal) []TransferTotal {
+	out := make([]TransferTotal, 0, len(totals))
+	for _, total := range totals {
+		out = append(out, *total)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TotalBytes != out[j].TotalBytes {
+			return out[i].TotalBytes > out[j].TotalBytes
+		}
+		if out[i].TenantID != out[j].TenantID {
+			return out[i].TenantID < out[j].TenantID
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+func parseTransferDays(r *http.Request) (int, error) {
+	raw := strings.TrimSpace(r.URL.Query().Get("days"))
+	if raw == "" {
+		return defaultTransferDays, nil
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 1 || days > transferRetentionDays {
+		return 0, fmt.Errorf("days must be between 1 and %d", transferRetentionDays)
+	}
+	return days, nil
+}
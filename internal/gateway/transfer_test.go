@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransferStoreSplitsTrafficByRouteConnectorAndDay(t *testing.T) {
+	store := NewTransferStore()
+	now := time.Now().UTC()
+	store.Record("acme", "api", "laptop", 100, 1000, now)
+	store.Record("acme", "api", "laptop", 50, 500, now.Add(-24*time.Hour))
+	store.Record("acme", "web", "", 10, 20, now)
+	store.Record("other", "api", "server", 1, 2, now)
+	store.Record("acme", "api", "laptop", 999, 999, now.AddDate(0, 0, -transferRetentionDays))
+
+	summary := store.Summary("acme", 30, now)
+	if summary.From != now.AddDate(0, 0, -29).Format(transferDateLayout) || summary.To != now.Format(transferDateLayout) {
+		t.Fatalf("unexpected window %s..%s", summary.From, summary.To)
+	}
+	if len(summary.Routes) != 2 || summary.Routes[0].ID != "api" || summary.Routes[0].IngressBytes != 150 || summary.Routes[0].EgressBytes != 1500 || summary.Routes[0].Requests != 2 {
+		t.Fatalf("expected api to be the heaviest route with both days summed, got %+v", summary.Routes)
+	}
+	if len(summary.Connectors) != 1 || summary.Connectors[0].ID != "laptop" || summary.Connectors[0].TotalBytes != 1650 {
+		t.Fatalf("expected only the laptop connector, got %+v", summary.Connectors)
+	}
+	if len(summary.Daily) != 5 || summary.Daily[0].Date != now.AddDate(0, 0, -1).Format(transferDateLayout) {
+		t.Fatalf("expected daily records for two days, got %+v", summary.Daily)
+	}
+
+	today := store.Summary("acme", 1, now)
+	if today.Routes[0].IngressBytes != 100 {
+		t.Fatalf("expected a one day window to skip yesterday, got %+v", today.Routes)
+	}
+	if all := store.Summary("", 30, now); len(all.Connectors) != 2 {
+		t.Fatalf("expected connectors of every tenant, got %+v", all.Connectors)
+	}
+
+	restored := NewTransferStore()
+	restored.Restore(store.Snapshot())
+	if got := restored.Summary("acme", 30, now); len(got.Daily) != len(summary.Daily) {
+		t.Fatalf("expected the snapshot to round-trip, got %+v", got.Daily)
+	}
+}
@@ -0,0 +1,307 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTrashRetention = 7 * 24 * time.Hour
+
+	TrashKindRoute     = "route"
+	TrashKindConnector = "connector"
+)
+
+type TrashItem struct {
+	Kind       string                       `json:"kind"`
+	TenantID   string                       `json:"tenant_id"`
+	ID         string                       `json:"id"`
+	DeletedBy  string                       `json:"deleted_by"`
+	DeletedAt  time.Time                    `json:"deleted_at"`
+	PurgeAt    time.Time                    `json:"purge_at"`
+	Route      *Rule                        `json:"route,omitempty"`
+	Connector  *Connector                   `json:"connector,omitempty"`
+	Credential *connectorCredentialSnapshot `json:"credential,omitempty"`
+}
+
+func (item TrashItem) view() TrashItem {
+	item.Credential = nil
+	if item.Route != nil {
+		route := *item.Route
+		route.WebhookVerification = route.WebhookVerification.withoutSecret()
+		item.Route = &route
+	}
+	return item
+}
+
+type TrashStore struct {
+	mu    sync.RWMutex
+	items map[string]TrashItem
+}
+
+func NewTrashStore() *TrashStore {
+	return &TrashStore{items: make(map[string]TrashItem)}
+}
+
+func trashKey(kind, tenantID, id string) string {
+	return kind + "/" + tenantID + "/" + id
+}
+
+func (s *TrashStore) Put(item TrashItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[trashKey(item.Kind, item.TenantID, item.ID)] = item
+}
+
+func (s *TrashStore) Get(kind, tenantID, id string) (TrashItem, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item, ok := s.items[trashKey(kind, normalizeIdentifier(tenantID), normalizeIdentifier(id))]
+	return item, ok
+}
+
+func (s *TrashStore) Delete(kind, tenantID, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := trashKey(kind, normalizeIdentifier(tenantID), normalizeIdentifier(id))
+	if _, ok := s.items[key]; !ok {
+		return false
+	}
+	delete(s.items, key)
+	return true
+}
+
+func (s *TrashStore) List(tenantID string) []TrashItem {
+	tenantID = normalizeIdentifier(tenantID)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]TrashItem, 0)
+	for _, item := range s.items {
+		if item.TenantID == tenantID {
+			out = append(out, item)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].DeletedAt.Equal(out[j].DeletedAt) {
+			return trashKey(out[i].Kind, out[i].TenantID, out[i].ID) < trashKey(out[j].Kind, out[j].TenantID, out[j].ID)
+		}
+		return out[i].DeletedAt.After(out[j].DeletedAt)
+	})
+	return out
+}
+
+func (s *TrashStore) Purge(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purged := 0
+	for key, item := range s.items {
+		if !now.Before(item.PurgeAt) {
+			delete(s.items, key)
+			purged++
+		}
+	}
+	return purged
+}
+
+func (s *TrashStore) Snapshot() []TrashItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]TrashItem, 0, len(s.items))
+	for _, item := range s.items {
+		out = append(out, item)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return trashKey(out[i].Kind, out[i].TenantID, out[i].ID) < trashKey(out[j].Kind, out[j].TenantID, out[j].ID)
+	})
+	return out
+}
+
+func (s *TrashStore) Restore(items []TrashItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = make(map[string]TrashItem, len(items))
+	for _, item := range items {
+		if item.Route == nil && item.Connector == nil {
+			continue
+		}
+		s.items[trashKey(item.Kind, item.TenantID, item.ID)] = item
+	}
+}
+
+func (s *Server) trashRetention() time.Duration {
+	if retention := s.config().TrashRetention; retention > 0 {
+		return retention
+	}
+	return defaultTrashRetention
+}
+
+func (s *Server) trashRoute(tenantID, routeID, actor string) bool {
+	rule, ok := s.ruleStore.GetForTenant(tenantID, routeID)
+	if !ok || !s.ruleStore.DeleteForTenant(tenantID, routeID) {
+		return false
+	}
+	now := time.Now().UTC()
+	s.trash.Put(TrashItem{
+		Kind:      TrashKindRoute,
+		TenantID:  rule.TenantID,
+		ID:        rule.ID,
+		DeletedBy: actor,
+		DeletedAt: now,
+		PurgeAt:   now.Add(s.trashRetention()),
+		Route:     &rule,
+	})
+	return true
+}
+
+func (s *Server) trashConnector(connectorID, actor string) bool {
+	connector, credential, ok := s.connectorStore.Take(connectorID)
+	if !ok {
+		return false
+	}
+	now := time.Now().UTC()
+	s.trash.Put(TrashItem{
+		Kind:       TrashKindConnector,
+		TenantID:   connector.TenantID,
+		ID:         connector.ID,
+		DeletedBy:  actor,
+		DeletedAt:  now,
+		PurgeAt:    now.Add(s.trashRetention()),
+		Connector:  &connector,
+		Credential: credential,
+	})
+	return true
+}
+
+func (s *Server) restoreTrashedConnector(item TrashItem) error {
+	if err := s.enforceConnectorLimit(item.TenantID); err != nil {
+		return err
+	}
+	var credentials []connectorCredentialSnapshot
+	if item.Credential != nil {
+		credentials = append(credentials, *item.Credential)
+	}
+	if skipped := s.connectorStore.PutTenant([]Connector{*item.Connector}, credentials); len(skipped) > 0 {
+		return fmt.Errorf("connector %q already exists", item.ID)
+	}
+	s.trash.Delete(item.Kind, item.TenantID, item.ID)
+	return nil
+}
+
+func (s *Server) restoreTrashedRoute(item TrashItem) ([]string, error) {
+	rule := *item.Route
+	if _, exists := s.ruleStore.GetForTenant(item.TenantID, rule.ID); exists {
+		return nil, fmt.Errorf("route %q already exists", rule.ID)
+	}
+	if err := s.enforceRouteLimit(item.TenantID, rule.ID); err != nil {
+		return nil, err
+	}
+	restored := []string{TrashKindRoute + ":" + rule.ID}
+	if connectorID := rule.ConnectorID; connectorID != "" {
+		if _, ok := s.connectorStore.Get(connectorID); !ok {
+			trashed, inTrash := s.trash.Get(TrashKindConnector, item.TenantID, connectorID)
+			if !inTrash {
+				return nil, fmt.Errorf("connector %q no longer exists", connectorID)
+			}
+			if err := s.restoreTrashedConnector(trashed); err != nil {
+				return nil, err
+			}
+			restored = append(restored, TrashKindConnector+":"+connectorID)
+		}
+	}
+	if err := s.ruleStore.RestoreRule(rule); err != nil {
+		return nil, err
+	}
+	s.trash.Delete(item.Kind, item.TenantID, item.ID)
+	s.hub.EnsureTunnelMetric(MakeTunnelKey(rule.TenantID, rule.ID))
+	s.publishRouteUpserted(rule)
+	return restored, nil
+}
+
+func (s *Server) handleTenantTrash(w http.ResponseWriter, r *http.Request, tenantID string) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.ruleStore.HasTenant(tenantID) {
+		writeAPIError(w, http.StatusNotFound, errCodeTenantNotFound, "tenant not found")
+		return
+	}
+	items := s.trash.List(tenantID)
+	for i := range items {
+		items[i] = items[i].view()
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tenant_id":         normalizeIdentifier(tenantID),
+		"retention_seconds": int(s.trashRetention().Seconds()),
+		"items":             items,
+	})
+}
+
+func (s *Server) handleTenantTrashItem(w http.ResponseWriter, r *http.Request, user User, tenantID, kind, id, action string) {
+	switch kind {
+	case "routes":
+		kind = TrashKindRoute
+	case "connectors":
+		kind = TrashKindConnector
+	default:
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid tenant subresource path")
+		return
+	}
+	switch {
+	case action == "" && r.Method == http.MethodDelete:
+	case action == "restore" && r.Method == http.MethodPost:
+	case action == "" || action == "restore":
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	default:
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "invalid tenant subresource path")
+		return
+	}
+	if !s.canMutateTenant(user, tenantID) {
+		writeAPIError(w, http.StatusForbidden, errCodeTenantAccessDenied, "forbidden tenant access")
+		return
+	}
+	item, ok := s.trash.Get(kind, tenantID, id)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, kind+" is not in the trash")
+		return
+	}
+
+	if action == "" {
+		s.trash.Delete(kind, tenantID, id)
+		s.auditStore.Record(user.Username, kind+".purged", item.TenantID, map[string]string{"id": item.ID})
+		s.persistState()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if tenant, inactive := s.tenantInactive(item.TenantID); inactive {
+		writeAPIError(w, http.StatusForbidden, errCodeTenantSuspended, fmt.Sprintf("tenant %q is %s", tenant.ID, tenant.status()))
+		return
+	}
+	restored := []string{kind + ":" + item.ID}
+	var err error
+	if kind == TrashKindRoute {
+		restored, err = s.restoreTrashedRoute(item)
+	} else {
+		err = s.restoreTrashedConnector(item)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusConflict, errCodeConflict, err.Error())
+		return
+	}
+	for _, key := range restored {
+		restoredKind, restoredID, _ := strings.Cut(key, ":")
+		s.auditStore.Record(user.Username, restoredKind+".restored", item.TenantID, map[string]string{"id": restoredID})
+	}
+	s.refreshTenantUsage(item.TenantID)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message":  kind + " restored",
+		"restored": restored,
+	})
+	s.persistState()
+}
@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeletedRouteAndConnectorAreRestoredFromTrash(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	public, _, _ := server.buildListenerMuxes(server.config())
+	mux := server.withListenerMiddleware(public)
+	if _, err := server.connectorStore.Create(Connector{ID: "edge", TenantID: DefaultTenantID, Name: "edge"}); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+	secret, err := server.connectorStore.RotateCredential("edge")
+	if err != nil {
+		t.Fatalf("rotate credential: %v", err)
+	}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "app", Token: "route-token", ConnectorID: "edge", LocalPort: 3000, WebhookVerification: &WebhookVerification{Provider: "github", Secret: "hook-secret"}}); err != nil {
+		t.Fatalf("create route: %v", err)
+	}
+	session, err := server.authStore.NewSession("admin")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	call := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(""))
+		req.Header.Set("Authorization", "Bearer "+session)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	if recorder := call(http.MethodDelete, "/api/connectors/edge"); recorder.Code != http.StatusNoContent {
+		t.Fatalf("delete connector: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := call(http.MethodDelete, "/api/tenants/default/routes/app"); recorder.Code != http.StatusNoContent {
+		t.Fatalf("delete route: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if _, ok := server.ruleStore.GetForTenant(DefaultTenantID, "app"); ok {
+		t.Fatalf("expected the route to be deleted")
+	}
+
+	recorder := call(http.MethodGet, "/api/tenants/default/trash")
+	var listed struct {
+		Items []TrashItem `json:"items"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &listed); err != nil || len(listed.Items) != 2 {
+		t.Fatalf("expected two trash items, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if item := listed.Items[0]; item.Kind != TrashKindRoute || item.DeletedBy != "admin" || !item.PurgeAt.Equal(item.DeletedAt.Add(defaultTrashRetention)) {
+		t.Fatalf("expected the route first with its purge time, got %+v", item)
+	}
+	if strings.Contains(recorder.Body.String(), "secret_hash") {
+		t.Fatalf("expected the connector credential to stay out of the listing")
+	}
+	if strings.Contains(recorder.Body.String(), "hook-secret") || listed.Items[0].Route.WebhookVerification.Provider != "github" {
+		t.Fatalf("expected the webhook secret to stay out of the listing: %s", recorder.Body.String())
+	}
+
+	recorder = call(http.MethodPost, "/api/tenants/default/trash/routes/app/restore")
+	var restored struct {
+		Restored []string `json:"restored"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &restored); err != nil || recorder.Code != http.StatusOK || len(restored.Restored) != 2 {
+		t.Fatalf("expected the route and its connector to be restored, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	rule, ok := server.ruleStore.GetForTenant(DefaultTenantID, "app")
+	if !ok || rule.ConnectorID != "edge" || rule.Token != "route-token" || rule.WebhookVerification.Secret != "hook-secret" {
+		t.Fatalf("expected the route as it was, got %+v", rule)
+	}
+	if !server.connectorStore.Authenticate("edge", secret) {
+		t.Fatalf("expected the connector to keep its secret")
+	}
+	if items := server.trash.List(DefaultTenantID); len(items) != 0 {
+		t.Fatalf("expected an empty trash, got %+v", items)
+	}
+	if recorder := call(http.MethodPost, "/api/tenants/default/trash/routes/app/restore"); recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected a second restore to miss, got %d", recorder.Code)
+	}
+}
+
+func TestTrashRestoreConflictsAndPurge(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "app", Target: "http://127.0.0.1:9"}); err != nil {
+		t.Fatalf("create route: %v", err)
+	}
+	if !server.trashRoute(DefaultTenantID, "app", "admin") {
+		t.Fatalf("expected the route to be trashed")
+	}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "app", Target: "http://127.0.0.1:10"}); err != nil {
+		t.Fatalf("recreate route: %v", err)
+	}
+	item, _ := server.trash.Get(TrashKindRoute, DefaultTenantID, "app")
+	if _, err := server.restoreTrashedRoute(item); err == nil {
+		t.Fatalf("expected restoring over a reused route ID to fail")
+	}
+
+	if purged := server.trash.Purge(time.Now().UTC()); purged != 0 {
+		t.Fatalf("expected nothing to be purged yet, got %d", purged)
+	}
+	if purged := server.trash.Purge(time.Now().UTC().Add(defaultTrashRetention)); purged != 1 {
+		t.Fatalf("expected the route to be purged, got %d", purged)
+	}
+}
@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/httpx"
+)
+
+const uploadSegmentBytes = 256 << 10
+
+func (s *Server) readProxyBody(w http.ResponseWriter, r *http.Request, rule Rule, hasRule bool, bandwidth []*httpx.BandwidthLimiter) ([]byte, *tunnelUpload, error) {
+	reader := httpx.ThrottleReader(r.Context(), r.Body, bandwidth...)
+	limit := s.config().MaxRequestBodyBytes
+	if !hasRule || rule.MaxUploadBytes <= limit {
+		body, err := readAllWithLimit(reader, limit)
+		return body, nil, err
+	}
+	if r.ContentLength > rule.MaxUploadBytes {
+		return nil, nil, errBodyTooLarge
+	}
+	var prefix []byte
+	if r.ContentLength <= limit {
+		var err error
+		prefix, err = io.ReadAll(&io.LimitedReader{R: reader, N: limit + 1})
+		if err != nil {
+			return nil, nil, err
+		}
+		if int64(len(prefix)) <= limit {
+			return prefix, nil, nil
+		}
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	upload := &tunnelUpload{
+		body: &uploadLimitReader{r: io.MultiReader(bytes.NewReader(prefix), httpx.ThrottleReader(ctx, r.Body, bandwidth...)), remaining: rule.MaxUploadBytes},
+		size: r.ContentLength,
+		stop: func() {
+			cancel()
+			_ = http.NewResponseController(w).SetReadDeadline(time.Now())
+		},
+	}
+	return nil, upload, nil
+}
+
+type uploadLimitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *uploadLimitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		return int(l.remaining), errBodyTooLarge
+	}
+	l.remaining -= int64(n)
+	return n, err
+}
+
+func (s *Server) handleAgentUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	query := r.URL.Query()
+	sessionID := strings.TrimSpace(query.Get("session_id"))
+	requestID := strings.TrimSpace(query.Get("request_id"))
+	if sessionID == "" || requestID == "" {
+		writeAPIError(w, http.StatusBadRequest, errCodeInvalidRequest, "missing session_id or request_id")
+		return
+	}
+
+	upload, err := s.hub.attachUpload(sessionID, requestID)
+	switch {
+	case errors.Is(err, ErrUnknownSession):
+		writeAPIError(w, http.StatusNotFound, errCodeUnknownSession, err.Error())
+		return
+	case errors.Is(err, ErrUnknownUpload):
+		writeAPIError(w, http.StatusNotFound, errCodeNotFound, err.Error())
+		return
+	case err != nil:
+		writeAPIError(w, http.StatusConflict, errCodeConflict, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if upload.size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(upload.size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	controller := http.NewResponseController(w)
+	buffer := make([]byte, uploadSegmentBytes)
+	for {
+		n, readErr := fillSegment(upload, buffer)
+		if n > 0 {
+			if _, err := w.Write(buffer[:n]); err != nil {
+				return
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			return
+		}
+		if readErr != nil {
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+func fillSegment(r io.Reader, buffer []byte) (int, error) {
+	filled := 0
+	for filled < len(buffer) {
+		n, err := r.Read(buffer[filled:])
+		filled += n
+		if err != nil {
+			return filled, err
+		}
+	}
+	return filled, nil
+}
@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func TestLargeUploadsAreRelayedToTheAgent(t *testing.T) {
+	server := NewServer(Config{StorageDriver: "memory", MaxRequestBodyBytes: 1024}, nil)
+	if _, err := server.connectorStore.Create(Connector{ID: "laptop", TenantID: DefaultTenantID}); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "app", ConnectorID: "laptop", LocalPort: 3000, MaxUploadBytes: 1 << 20}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	registered, err := server.hub.RegisterConnector(&protocol.RegisterRequest{
+		AgentID:         "agent-laptop",
+		ConnectorID:     "laptop",
+		ProtocolVersion: protocol.ProtocolVersion,
+		Capabilities:    []string{protocol.CapabilityUpload},
+	})
+	if err != nil {
+		t.Fatalf("register connector: %v", err)
+	}
+
+	payload := bytes.Repeat([]byte("x"), 300<<10)
+	go func() {
+		pulled, err := server.hub.PullRequest(context.Background(), registered.SessionID)
+		if err != nil {
+			return
+		}
+		response := &protocol.ProxyResponse{RequestID: pulled.RequestID, TunnelID: pulled.TunnelID, Status: http.StatusBadRequest}
+		if pulled.Upload && len(pulled.Body) == 0 && pulled.UploadBytes == int64(len(payload)) {
+			recorder := httptest.NewRecorder()
+			server.handleAgentUpload(recorder, httptest.NewRequest(http.MethodGet, "/api/agent/upload?session_id="+registered.SessionID+"&request_id="+pulled.RequestID, nil))
+			response.Status = http.StatusCreated
+			response.Body = []byte(strconv.Itoa(recorder.Body.Len()))
+		}
+		_ = server.hub.SubmitProxyResponse(registered.SessionID, response)
+	}()
+
+	recorder := httptest.NewRecorder()
+	server.handleProxy(recorder, httptest.NewRequest(http.MethodPost, "/t/app/upload", bytes.NewReader(payload)))
+	if recorder.Code != http.StatusCreated || recorder.Body.String() != strconv.Itoa(len(payload)) {
+		t.Fatalf("expected the agent to stream the whole upload, got %d %q", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestUploadLimitsForDirectRoutes(t *testing.T) {
+	received := make(chan int, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- len(body)
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory", MaxRequestBodyBytes: 1024}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "small", Target: upstream.URL}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "uploads", Target: upstream.URL, MaxUploadBytes: 4096}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	post := func(path string, size int, declareLength bool) int {
+		t.Helper()
+		request := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(bytes.Repeat([]byte("x"), size)))
+		if !declareLength {
+			request.ContentLength = -1
+		}
+		recorder := httptest.NewRecorder()
+		server.handleProxy(recorder, request)
+		return recorder.Code
+	}
+
+	if status := post("/t/small/", 3000, true); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected a route without max_upload_bytes to keep the request limit, got %d", status)
+	}
+	for _, declareLength := range []bool{true, false} {
+		if status := post("/t/uploads/", 3000, declareLength); status != http.StatusOK {
+			t.Fatalf("expected the upload to be relayed, got %d", status)
+		}
+		if size := <-received; size != 3000 {
+			t.Fatalf("expected the upstream to receive 3000 bytes, got %d", size)
+		}
+	}
+	if status := post("/t/uploads/", 5000, true); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected a declared length over max_upload_bytes to be refused, got %d", status)
+	}
+	if status := post("/t/uploads/", 5000, false); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected a body that passes max_upload_bytes to be refused, got %d", status)
+	}
+}
+
+func TestClosingUploadWaitsForReadInFlight(t *testing.T) {
+	body, writer := io.Pipe()
+	reading := make(chan struct{})
+	upload := &tunnelUpload{
+		body: readerFunc(func(p []byte) (int, error) {
+			close(reading)
+			return body.Read(p)
+		}),
+		stop: func() { _ = writer.CloseWithError(io.ErrUnexpectedEOF) },
+	}
+	read := make(chan error, 1)
+	go func() {
+		_, err := upload.Read(make([]byte, 8))
+		read <- err
+	}()
+	<-reading
+
+	upload.close()
+	select {
+	case err := <-read:
+		if err != io.ErrUnexpectedEOF {
+			t.Fatalf("expected the pending read to be unblocked, got %v", err)
+		}
+	default:
+		t.Fatal("expected close to wait for the read in flight")
+	}
+	if _, err := upload.Read(make([]byte, 8)); err != io.ErrClosedPipe {
+		t.Fatalf("expected reads after close to fail, got %v", err)
+	}
+}
+
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,233 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	webhookProviderStripe = "stripe"
+	webhookProviderGitHub = "github"
+	webhookProviderSlack  = "slack"
+	webhookProviderHMAC   = "hmac"
+
+	defaultWebhookToleranceSecs = 300
+	maxWebhookToleranceSecs     = 24 * 60 * 60
+	defaultWebhookHMACHeader    = "X-Signature"
+)
+
+var (
+	errWebhookSignatureMissing = errors.New("missing webhook signature")
+	errWebhookSignatureInvalid = errors.New("webhook signature does not match")
+	errWebhookTimestampInvalid = errors.New("webhook timestamp is missing or outside the tolerance")
+	errWebhookBodyTooLarge     = errors.New("webhook body is too large to verify")
+)
+
+type WebhookVerification struct {
+	Provider         string `json:"provider"`
+	Secret           string `json:"secret,omitempty"`
+	Header           string `json:"header,omitempty"`
+	Algorithm        string `json:"algorithm,omitempty"`
+	Encoding         string `json:"encoding,omitempty"`
+	Prefix           string `json:"prefix,omitempty"`
+	ToleranceSeconds int    `json:"tolerance_seconds,omitempty"`
+}
+
+func normalizeRouteWebhookVerification(input *WebhookVerification) (*WebhookVerification, error) {
+	if input == nil {
+		return nil, nil
+	}
+	policy := WebhookVerification{
+		Provider:         strings.ToLower(strings.TrimSpace(input.Provider)),
+		Secret:           strings.TrimSpace(input.Secret),
+		ToleranceSeconds: input.ToleranceSeconds,
+	}
+	switch policy.Provider {
+	case webhookProviderStripe, webhookProviderSlack:
+		if policy.ToleranceSeconds < 0 || policy.ToleranceSeconds > maxWebhookToleranceSecs {
+			return nil, fmt.Errorf("webhook_verification.tolerance_seconds must be between 0 and %d", maxWebhookToleranceSecs)
+		}
+		if policy.ToleranceSeconds == 0 {
+			policy.ToleranceSeconds = defaultWebhookToleranceSecs
+		}
+	case webhookProviderGitHub:
+		policy.ToleranceSeconds = 0
+	case webhookProviderHMAC:
+		policy.ToleranceSeconds = 0
+		policy.Header = http.CanonicalHeaderKey(strings.TrimSpace(input.Header))
+		if policy.Header == "" {
+			policy.Header = defaultWebhookHMACHeader
+		}
+		policy.Algorithm = strings.ToLower(strings.TrimSpace(input.Algorithm))
+		if policy.Algorithm == "" {
+			policy.Algorithm = "sha256"
+		}
+		if webhookHash(policy.Algorithm) == nil {
+			return nil, fmt.Errorf("webhook_verification.algorithm must be sha1, sha256 or sha512")
+		}
+		policy.Encoding = strings.ToLower(strings.TrimSpace(input.Encoding))
+		if policy.Encoding == "" {
+			policy.Encoding = "hex"
+		}
+		if policy.Encoding != "hex" && policy.Encoding != "base64" {
+			return nil, fmt.Errorf("webhook_verification.encoding must be hex or base64")
+		}
+		policy.Prefix = input.Prefix
+	default:
+		return nil, fmt.Errorf("webhook_verification.provider must be stripe, github, slack or hmac")
+	}
+	return &policy, nil
+}
+
+func (p *WebhookVerification) withoutSecret() *WebhookVerification {
+	if p == nil {
+		return nil
+	}
+	copied := *p
+	copied.Secret = ""
+	return &copied
+}
+
+func webhookHash(algorithm string) func() hash.Hash {
+	switch algorithm {
+	case "sha1":
+		return sha1.New
+	case "sha256":
+		return sha256.New
+	case "sha512":
+		return sha512.New
+	}
+	return nil
+}
+
+func webhookMAC(newHash func() hash.Hash, secret string, parts ...string) []byte {
+	mac := hmac.New(newHash, []byte(secret))
+	for _, part := range parts {
+		mac.Write([]byte(part))
+	}
+	return mac.Sum(nil)
+}
+
+func matchesHex(signature string, expected []byte) bool {
+	decoded, err := hex.DecodeString(strings.TrimSpace(signature))
+	return err == nil && hmac.Equal(decoded, expected)
+}
+
+func (p *WebhookVerification) verify(headers http.Header, body []byte, now time.Time) error {
+	switch p.Provider {
+	case webhookProviderStripe:
+		return p.verifyStripe(headers.Get("Stripe-Signature"), body, now)
+	case webhookProviderGitHub:
+		signature := headers.Get("X-Hub-Signature-256")
+		if signature == "" {
+			return errWebhookSignatureMissing
+		}
+		hexSignature, ok := strings.CutPrefix(signature, "sha256=")
+		if !ok || !matchesHex(hexSignature, webhookMAC(sha256.New, p.Secret, string(body))) {
+			return errWebhookSignatureInvalid
+		}
+		return nil
+	case webhookProviderSlack:
+		signature := headers.Get("X-Slack-Signature")
+		if signature == "" {
+			return errWebhookSignatureMissing
+		}
+		timestamp := headers.Get("X-Slack-Request-Timestamp")
+		if !p.timestampFresh(timestamp, now) {
+			return errWebhookTimestampInvalid
+		}
+		hexSignature, ok := strings.CutPrefix(signature, "v0=")
+		if !ok || !matchesHex(hexSignature, webhookMAC(sha256.New, p.Secret, "v0:", timestamp, ":", string(body))) {
+			return errWebhookSignatureInvalid
+		}
+		return nil
+	default:
+		signature := strings.TrimSpace(headers.Get(p.Header))
+		if signature == "" {
+			return errWebhookSignatureMissing
+		}
+		signature, ok := strings.CutPrefix(signature, p.Prefix)
+		if !ok {
+			return errWebhookSignatureInvalid
+		}
+		expected := webhookMAC(webhookHash(p.Algorithm), p.Secret, string(body))
+		if p.Encoding == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(signature)
+			if err != nil || !hmac.Equal(decoded, expected) {
+				return errWebhookSignatureInvalid
+			}
+			return nil
+		}
+		if !matchesHex(signature, expected) {
+			return errWebhookSignatureInvalid
+		}
+		return nil
+	}
+}
+
+func (p *WebhookVerification) verifyStripe(header string, body []byte, now time.Time) error {
+	if header == "" {
+		return errWebhookSignatureMissing
+	}
+	timestamp := ""
+	signatures := make([]string, 0, 1)
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if !p.timestampFresh(timestamp, now) {
+		return errWebhookTimestampInvalid
+	}
+	expected := webhookMAC(sha256.New, p.Secret, timestamp, ".", string(body))
+	for _, signature := range signatures {
+		if matchesHex(signature, expected) {
+			return nil
+		}
+	}
+	return errWebhookSignatureInvalid
+}
+
+func (p *WebhookVerification) timestampFresh(raw string, now time.Time) bool {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil {
+		return false
+	}
+	age := now.Sub(time.Unix(seconds, 0)).Seconds()
+	return math.Abs(age) <= float64(p.ToleranceSeconds)
+}
+
+func (s *Server) verifyWebhookRequest(w http.ResponseWriter, r *http.Request, rule Rule, body []byte, streamed bool) bool {
+	err := errWebhookBodyTooLarge
+	if !streamed {
+		err = rule.WebhookVerification.verify(r.Header, body, time.Now())
+	}
+	s.hub.RecordWebhookVerification(MakeTunnelKey(rule.TenantID, rule.ID), err == nil)
+	if err == nil {
+		return true
+	}
+	writeJSON(w, http.StatusUnauthorized, map[string]any{
+		"error":     "webhook_verification_failed",
+		"message":   err.Error(),
+		"provider":  rule.WebhookVerification.Provider,
+		"tenant_id": rule.TenantID,
+		"route_id":  rule.ID,
+	})
+	return false
+}
@@ -0,0 +1,119 @@
+package gateway
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func signWebhook(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookVerificationProviders(t *testing.T) {
+	const body = `{"type":"invoice.paid"}`
+	now := time.Unix(1700000000, 0)
+	timestamp := fmt.Sprint(now.Unix())
+	stale := fmt.Sprint(now.Add(-10 * time.Minute).Unix())
+	sha1MAC := hmac.New(sha1.New, []byte("s3cret"))
+	sha1MAC.Write([]byte(body))
+
+	cases := []struct {
+		name    string
+		policy  WebhookVerification
+		headers map[string]string
+		want    error
+	}{
+		{"stripe", WebhookVerification{Provider: "stripe"}, map[string]string{"Stripe-Signature": "t=" + timestamp + ",v1=00,v1=" + signWebhook("s3cret", timestamp+"."+body)}, nil},
+		{"stripe stale", WebhookVerification{Provider: "stripe"}, map[string]string{"Stripe-Signature": "t=" + stale + ",v1=" + signWebhook("s3cret", stale+"."+body)}, errWebhookTimestampInvalid},
+		{"stripe wrong secret", WebhookVerification{Provider: "stripe"}, map[string]string{"Stripe-Signature": "t=" + timestamp + ",v1=" + signWebhook("other", timestamp+"."+body)}, errWebhookSignatureInvalid},
+		{"github", WebhookVerification{Provider: "github"}, map[string]string{"X-Hub-Signature-256": "sha256=" + signWebhook("s3cret", body)}, nil},
+		{"github missing", WebhookVerification{Provider: "github"}, nil, errWebhookSignatureMissing},
+		{"slack", WebhookVerification{Provider: "slack"}, map[string]string{"X-Slack-Request-Timestamp": timestamp, "X-Slack-Signature": "v0=" + signWebhook("s3cret", "v0:"+timestamp+":"+body)}, nil},
+		{"slack tampered", WebhookVerification{Provider: "slack"}, map[string]string{"X-Slack-Request-Timestamp": timestamp, "X-Slack-Signature": "v0=" + signWebhook("s3cret", "v0:"+timestamp+":{}")}, errWebhookSignatureInvalid},
+		{"hmac", WebhookVerification{Provider: "hmac"}, map[string]string{"X-Signature": signWebhook("s3cret", body)}, nil},
+		{"hmac sha1 base64 prefix", WebhookVerification{Provider: "hmac", Header: "x-sig", Algorithm: "sha1", Encoding: "base64", Prefix: "sha1="}, map[string]string{"X-Sig": "sha1=" + base64.StdEncoding.EncodeToString(sha1MAC.Sum(nil))}, nil},
+	}
+	for _, tc := range cases {
+		tc.policy.Secret = "s3cret"
+		policy, err := normalizeRouteWebhookVerification(&tc.policy)
+		if err != nil {
+			t.Fatalf("%s: normalize: %v", tc.name, err)
+		}
+		headers := http.Header{}
+		for name, value := range tc.headers {
+			headers.Set(name, value)
+		}
+		if err := policy.verify(headers, []byte(body), now); err != tc.want {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+
+	for _, invalid := range []WebhookVerification{{Provider: "paypal"}, {Provider: "hmac", Algorithm: "md5"}, {Provider: "hmac", Encoding: "base32"}, {Provider: "stripe", ToleranceSeconds: -1}} {
+		if _, err := normalizeRouteWebhookVerification(&invalid); err == nil {
+			t.Fatalf("expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestWebhookVerificationRejectsBeforeUpstream(t *testing.T) {
+	var deliveries atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer upstream.Close()
+
+	server := NewServer(Config{StorageDriver: "memory"}, nil)
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "hooks", Target: upstream.URL, WebhookVerification: &WebhookVerification{Provider: "github"}}); err == nil {
+		t.Fatal("expected a webhook verification without a secret to be rejected")
+	}
+	if _, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "hooks", Target: upstream.URL, WebhookVerification: &WebhookVerification{Provider: "github", Secret: "s3cret"}}); err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	// Updating without the secret keeps it, since route views never show it.
+	route, err := server.ruleStore.UpsertForTenant(DefaultTenantID, Rule{ID: "hooks", Target: upstream.URL, WebhookVerification: &WebhookVerification{Provider: "github"}})
+	if err != nil || route.WebhookVerification.Secret != "s3cret" {
+		t.Fatalf("expected the secret to be kept, got %+v, %v", route.WebhookVerification, err)
+	}
+	if view := server.buildRouteView(route); view.WebhookVerification.Secret != "" {
+		t.Fatal("expected route views to hide the webhook secret")
+	}
+
+	deliver := func(signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/t/hooks/github", strings.NewReader(`{"action":"opened"}`))
+		if signature != "" {
+			req.Header.Set("X-Hub-Signature-256", signature)
+		}
+		recorder := httptest.NewRecorder()
+		server.handleProxy(recorder, req)
+		return recorder.Code
+	}
+	if code := deliver("sha256=" + signWebhook("s3cret", `{"action":"opened"}`)); code != http.StatusNoContent {
+		t.Fatalf("expected a signed delivery to be forwarded, got %d", code)
+	}
+	if code := deliver("sha256=" + signWebhook("guess", `{"action":"opened"}`)); code != http.StatusUnauthorized {
+		t.Fatalf("expected a forged delivery to be rejected, got %d", code)
+	}
+	if code := deliver(""); code != http.StatusUnauthorized {
+		t.Fatalf("expected an unsigned delivery to be rejected, got %d", code)
+	}
+	if deliveries.Load() != 1 {
+		t.Fatalf("expected only the signed delivery to reach the upstream, got %d", deliveries.Load())
+	}
+	metrics := server.hub.GetTunnelMetrics(MakeTunnelKey(DefaultTenantID, "hooks"))
+	if metrics.WebhooksVerified != 1 || metrics.WebhooksRejected != 2 || metrics.RequestCount != 1 {
+		t.Fatalf("expected 1 verified and 2 rejected deliveries, got %+v", metrics)
+	}
+}
@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type WebhookEvent struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	Data      any       `json:"data"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookNotifier struct {
+	mu        sync.RWMutex
+	url       string
+	client    *http.Client
+	logger    *log.Logger
+	incidents *IncidentStore
+	counter   uint64
+	observer  func(WebhookEvent)
+}
+
+func NewWebhookNotifier(url string, logger *log.Logger, incidents *IncidentStore) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:       strings.TrimSpace(url),
+		client:    &http.Client{Timeout: 10 * time.Second},
+		logger:    logger,
+		incidents: incidents,
+	}
+}
+
+func (n *WebhookNotifier) Enabled() bool {
+	return n != nil && n.target() != ""
+}
+
+func (n *WebhookNotifier) target() string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.url
+}
+
+func (n *WebhookNotifier) SetURL(url string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.url = strings.TrimSpace(url)
+}
+
+func (n *WebhookNotifier) SetObserver(fn func(WebhookEvent)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.observer = fn
+}
+
+func (n *WebhookNotifier) Emit(eventType, tenantID string, data any) {
+	if n == nil {
+		return
+	}
+	n.mu.RLock()
+	observer := n.observer
+	n.mu.RUnlock()
+	if observer == nil && !n.Enabled() {
+		return
+	}
+	event := WebhookEvent{
+		ID:        fmt.Sprintf("evt-%d-%d", time.Now().UnixNano(), atomic.AddUint64(&n.counter, 1)),
+		Type:      strings.TrimSpace(eventType),
+		TenantID:  normalizeIdentifier(tenantID),
+		Data:      data,
+		CreatedAt: time.Now().UTC(),
+	}
+	if observer != nil {
+		observer(event)
+	}
+	if !n.Enabled() {
+		return
+	}
+	go func() {
+		if err := n.deliver(event); err != nil {
+			n.logger.Printf("webhook delivery failed type=%s: %v", event.Type, err)
+			if n.incidents != nil {
+				n.incidents.Add("warning", "webhook", fmt.Sprintf("webhook %s delivery failed: %v", event.Type, err))
+			}
+		}
+	}()
+}
+
+func (n *WebhookNotifier) deliver(event WebhookEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.target(), bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Proxer-Event", event.Type)
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package httpx
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+const bandwidthChunk = 16 << 10
+
+type BandwidthLimiter struct {
+	mu         sync.Mutex
+	rate       float64
+	tokens     float64
+	lastRefill time.Time
+}
+
+func NewBandwidthLimiter(bytesPerSecond int64) *BandwidthLimiter {
+	return &BandwidthLimiter{rate: float64(bytesPerSecond), tokens: float64(bytesPerSecond), lastRefill: time.Now()}
+}
+
+func (l *BandwidthLimiter) SetRate(bytesPerSecond int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refillLocked(time.Now())
+	l.rate = float64(bytesPerSecond)
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+}
+
+func (l *BandwidthLimiter) chunk() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate >= bandwidthChunk {
+		return bandwidthChunk
+	}
+	return max(int(l.rate), 1)
+}
+
+func (l *BandwidthLimiter) refillLocked(now time.Time) {
+	if elapsed := now.Sub(l.lastRefill).Seconds(); elapsed > 0 {
+		l.tokens += elapsed * l.rate
+		if l.tokens > l.rate {
+			l.tokens = l.rate
+		}
+	}
+	l.lastRefill = now
+}
+
+func (l *BandwidthLimiter) WaitN(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	l.refillLocked(time.Now())
+	l.tokens -= float64(n)
+	var wait time.Duration
+	if l.tokens < 0 && l.rate > 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func WaitBandwidth(ctx context.Context, n int, limiters ...*BandwidthLimiter) error {
+	for _, limiter := range limiters {
+		if err := limiter.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasLimiter(limiters []*BandwidthLimiter) bool {
+	return chunkFor(limiters) > 0
+}
+
+func chunkFor(limiters []*BandwidthLimiter) int {
+	chunk := 0
+	for _, limiter := range limiters {
+		if limiter == nil {
+			continue
+		}
+		if size := limiter.chunk(); chunk == 0 || size < chunk {
+			chunk = size
+		}
+	}
+	return chunk
+}
+
+func ThrottleReader(ctx context.Context, r io.Reader, limiters ...*BandwidthLimiter) io.Reader {
+	if !hasLimiter(limiters) {
+		return r
+	}
+	return &throttledReader{ctx: ctx, reader: r, limiters: limiters}
+}
+
+type throttledReader struct {
+	ctx      context.Context
+	reader   io.Reader
+	limiters []*BandwidthLimiter
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if chunk := chunkFor(r.limiters); len(p) > chunk {
+		p = p[:chunk]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := WaitBandwidth(r.ctx, n, r.limiters...); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+func ThrottleWriter(ctx context.Context, w io.Writer, limiters ...*BandwidthLimiter) io.Writer {
+	if !hasLimiter(limiters) {
+		return w
+	}
+	return &throttledWriter{ctx: ctx, writer: w, limiters: limiters}
+}
+
+type throttledWriter struct {
+	ctx      context.Context
+	writer   io.Writer
+	limiters []*BandwidthLimiter
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	size := chunkFor(w.limiters)
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > size {
+			chunk = chunk[:size]
+		}
+		if err := WaitBandwidth(w.ctx, len(chunk), w.limiters...); err != nil {
+			return written, err
+		}
+		n, err := w.writer.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,75 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+var ErrResponseHeaderTimeout = errors.New("upstream response header timeout")
+
+type HeaderTimer struct {
+	mu       sync.Mutex
+	timer    *time.Timer
+	timeout  time.Duration
+	cancel   context.CancelCauseFunc
+	received bool
+}
+
+func WithResponseHeaderTimeout(parent context.Context, timeout time.Duration) (context.Context, *HeaderTimer, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	timer := &HeaderTimer{timeout: timeout, cancel: cancel}
+	return ctx, timer, func() {
+		timer.Received()
+		cancel(context.Canceled)
+	}
+}
+
+func (t *HeaderTimer) Start() {
+	if t == nil || t.timeout <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer == nil && !t.received {
+		t.timer = time.AfterFunc(t.timeout, func() { t.cancel(ErrResponseHeaderTimeout) })
+	}
+}
+
+func (t *HeaderTimer) Received() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.received = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+func (t *HeaderTimer) Body(r io.Reader) io.Reader {
+	if t == nil || t.timeout <= 0 {
+		return r
+	}
+	return &headerTimerBody{reader: r, timer: t}
+}
+
+type headerTimerBody struct {
+	reader io.Reader
+	timer  *HeaderTimer
+}
+
+func (b *headerTimerBody) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	if errors.Is(err, io.EOF) {
+		b.timer.Start()
+	}
+	return n, err
+}
+
+func IsResponseHeaderTimeout(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrResponseHeaderTimeout)
+}
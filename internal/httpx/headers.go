@@ -52,6 +52,66 @@ func CloneMapHeader(src map[string][]string) map[string][]string {
 	return dst
 }
 
+// WantsTrailers reports whether the client sent "TE: trailers". TE is
+// hop-by-hop, but gRPC servers require it, so proxies re-add it per hop.
+func WantsTrailers(header map[string][]string) bool {
+	for _, value := range http.Header(header).Values("Te") {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), "trailers") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ForwardTrailers re-adds "TE: trailers" and attaches buffered request
+// trailers to an outbound request. The body is sent chunked so HTTP/1.1
+// upstreams can receive the trailers too.
+func ForwardTrailers(req *http.Request, header, trailers map[string][]string) {
+	if WantsTrailers(header) {
+		req.Header.Set("Te", "trailers")
+	}
+	if len(trailers) == 0 {
+		return
+	}
+	req.Trailer = make(http.Header, len(trailers))
+	for k, values := range trailers {
+		if IsHopByHopHeader(k) {
+			continue
+		}
+		req.Trailer[http.CanonicalHeaderKey(k)] = append([]string(nil), values...)
+	}
+	req.ContentLength = -1
+}
+
+// AnnounceTrailers declares trailer keys on dst before the header is written.
+// Content-Length is dropped so HTTP/1.1 responses switch to chunked encoding,
+// the only framing that can carry trailers.
+func AnnounceTrailers(dst http.Header, trailers map[string][]string) {
+	if len(trailers) == 0 {
+		return
+	}
+	dst.Del("Content-Length")
+	for k := range trailers {
+		if IsHopByHopHeader(k) {
+			continue
+		}
+		dst.Add("Trailer", k)
+	}
+}
+
+func WriteTrailers(dst http.Header, trailers map[string][]string) {
+	for k, values := range trailers {
+		if IsHopByHopHeader(k) {
+			continue
+		}
+		for _, value := range values {
+			dst.Add(http.TrailerPrefix+k, value)
+		}
+	}
+}
+
 func WriteHeaderMap(dst http.Header, src map[string][]string) {
 	for k, values := range src {
 		if IsHopByHopHeader(k) {
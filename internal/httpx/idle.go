@@ -0,0 +1,59 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrIdleTimeout = errors.New("upstream idle timeout")
+
+type IdleWatchdog struct {
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func WithIdleTimeout(parent context.Context, timeout time.Duration) (context.Context, *IdleWatchdog, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	watchdog := &IdleWatchdog{timeout: timeout}
+	if timeout > 0 {
+		watchdog.timer = time.AfterFunc(timeout, func() { cancel(ErrIdleTimeout) })
+	}
+	return ctx, watchdog, func() {
+		if watchdog.timer != nil {
+			watchdog.timer.Stop()
+		}
+		cancel(context.Canceled)
+	}
+}
+
+func (w *IdleWatchdog) Touch() {
+	if w != nil && w.timer != nil {
+		w.timer.Reset(w.timeout)
+	}
+}
+
+func (w *IdleWatchdog) Reader(r io.Reader) io.Reader {
+	if w == nil || w.timer == nil {
+		return r
+	}
+	return &idleReader{reader: r, watchdog: w}
+}
+
+type idleReader struct {
+	reader   io.Reader
+	watchdog *IdleWatchdog
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.watchdog.Touch()
+	}
+	return n, err
+}
+
+func IsIdleTimeout(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrIdleTimeout)
+}
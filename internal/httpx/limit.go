@@ -0,0 +1,33 @@
+package httpx
+
+import (
+	"io"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+// ReadLimitedBody reads a response body of the declared contentLength up to
+// limit bytes; limit <= 0 means no limit. A body declared longer than limit
+// is refused without reading, and a chunked or unknown-length one is read
+// only until it passes limit. Either way the rest stays unread, so closing
+// the response aborts the transfer instead of draining it. Over-limit bodies
+// return a nil body and a ResponseLimit from source.
+func ReadLimitedBody(body io.Reader, contentLength, limit int64, source string) ([]byte, *protocol.ResponseLimit, error) {
+	if limit <= 0 {
+		data, err := io.ReadAll(body)
+		return data, nil, err
+	}
+	exceeded := &protocol.ResponseLimit{Source: source, LimitBytes: limit, ContentLength: contentLength}
+	if contentLength > limit {
+		return nil, exceeded, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if int64(len(data)) > limit {
+		exceeded.ReadBytes = int64(len(data))
+		return nil, exceeded, nil
+	}
+	return data, nil, nil
+}
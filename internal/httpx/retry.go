@@ -0,0 +1,108 @@
+package httpx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+func IsIdempotentMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// DoWithRetry calls send, which must build a fresh request each time, and
+// retries idempotent methods under policy when the upstream cannot be reached
+// or answers with a status in policy.RetryOnStatus. A response is returned
+// as is when its Retry-After, or the backoff, would outlast ctx. It also
+// returns the number of retries made.
+func DoWithRetry(ctx context.Context, policy *protocol.RetryPolicy, method string, send func() (*http.Response, error)) (*http.Response, int, error) {
+	attempts := 1
+	if policy != nil && IsIdempotentMethod(method) {
+		attempts = policy.Attempts
+	}
+	for retries := 0; ; retries++ {
+		resp, err := send()
+		if retries+1 >= attempts || ctx.Err() != nil {
+			return resp, retries, err
+		}
+		delay := retryBackoff(policy, retries)
+		if err == nil {
+			if !slices.Contains(policy.RetryOnStatus, resp.StatusCode) {
+				return resp, retries, nil
+			}
+			if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok && after > delay {
+				delay = after
+			}
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return resp, retries, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, retries, context.Cause(ctx)
+		case <-timer.C:
+		}
+	}
+}
+
+// CloneForRetry returns a copy of req with a fresh body, so it can be sent
+// again. Requests built with http.NewRequest from a bytes.Reader support it.
+func CloneForRetry(req *http.Request) *http.Request {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			clone.Body = body
+		}
+	}
+	return clone
+}
+
+func retryBackoff(policy *protocol.RetryPolicy, retries int) time.Duration {
+	delay := time.Duration(policy.BackoffMs) * time.Millisecond
+	limit := time.Duration(policy.MaxBackoffMs) * time.Millisecond
+	for i := 0; i < retries && delay < limit; i++ {
+		delay *= 2
+	}
+	if limit > 0 && delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := at.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
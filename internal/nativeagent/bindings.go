@@ -3,6 +3,8 @@ package nativeagent
 import (
 	"context"
 	"fmt"
+
+	"github.com/szaher/try/proxer/internal/agent"
 )
 
 // DesktopBindings is a Wails-ready API surface for native GUI integrations.
@@ -42,9 +44,23 @@ func (b *DesktopBindings) SetActiveProfile(id string) (AgentProfile, error) {
 }
 
 func (b *DesktopBindings) PairProfile(id, pairToken string) (AgentProfile, error) {
+	if isPairLink(pairToken) {
+		return b.service.PairFromLink(pairToken, id)
+	}
+	if token := extractPairToken(pairToken); token != "" {
+		pairToken = token
+	}
 	return b.service.PairProfile(id, pairToken)
 }
 
+func (b *DesktopBindings) ExportProfile(id, passphrase string) (ProfileBundle, error) {
+	return b.service.ExportProfile(id, passphrase)
+}
+
+func (b *DesktopBindings) ImportProfile(bundle ProfileBundle, opts ProfileImportOptions) (AgentProfile, error) {
+	return b.service.ImportProfile(bundle, opts)
+}
+
 func (b *DesktopBindings) StartAgent(profile string) error {
 	return b.service.Start(profile)
 }
@@ -57,6 +73,37 @@ func (b *DesktopBindings) GetRuntimeStatus() (NativeStatusSnapshot, error) {
 	return b.service.Status()
 }
 
+func (b *DesktopBindings) GetProfileStatuses() ([]NativeStatusSnapshot, error) {
+	return b.service.ProfileStatuses()
+}
+
+func (b *DesktopBindings) GetTrayState() (TrayState, error) {
+	status, err := b.service.Status()
+	if err != nil {
+		return TrayState{}, err
+	}
+	activeName := ""
+	if active, err := b.service.ActiveProfile(); err == nil {
+		activeName = active.Name
+	}
+	return buildTrayState(status, activeName), nil
+}
+
+func (b *DesktopBindings) PairActiveProfile(pasted string) (AgentProfile, error) {
+	if isPairLink(pasted) {
+		return b.service.PairFromLink(pasted, "")
+	}
+	token := extractPairToken(pasted)
+	if token == "" {
+		return AgentProfile{}, fmt.Errorf("no pair token found in pasted text")
+	}
+	return b.service.PairProfile("", token)
+}
+
+func (b *DesktopBindings) PairFromLink(link string) (AgentProfile, error) {
+	return b.service.PairFromLink(link, "")
+}
+
 func (b *DesktopBindings) SubscribeEvents() (<-chan NativeStatusSnapshot, error) {
 	ctx := context.Background()
 	return b.service.SubscribeRuntimeEvents(ctx)
@@ -70,8 +117,12 @@ func (b *DesktopBindings) SetAppSettings(input AppSettingsInput) (AgentSettings,
 	return b.service.SetAppSettings(input)
 }
 
-func (b *DesktopBindings) CheckForUpdates() (UpdateCheckResult, error) {
-	return b.service.CheckForUpdates()
+func (b *DesktopBindings) CheckForUpdates(profileIDOrName string) (UpdateCheckResult, error) {
+	return b.service.CheckForUpdates(profileIDOrName)
+}
+
+func (b *DesktopBindings) ApplyUpdate(profileIDOrName string) (UpdateApplyResult, error) {
+	return b.service.ApplyUpdate(profileIDOrName)
 }
 
 func (b *DesktopBindings) GetLogTail(lines int) ([]string, error) {
@@ -80,3 +131,19 @@ func (b *DesktopBindings) GetLogTail(lines int) ([]string, error) {
 	}
 	return b.service.ReadLogTail(lines)
 }
+
+func (b *DesktopBindings) DiscoverPorts(ports string) ([]agent.DiscoveredPort, error) {
+	return b.service.DiscoverPorts(context.Background(), ports)
+}
+
+func (b *DesktopBindings) AddDiscoveredTunnels(id, ports string) (DiscoveryResult, error) {
+	discovered, err := b.service.DiscoverPorts(context.Background(), ports)
+	if err != nil {
+		return DiscoveryResult{}, err
+	}
+	profile, added, err := b.service.AddDiscoveredTunnels(id, discovered)
+	if err != nil {
+		return DiscoveryResult{}, err
+	}
+	return DiscoveryResult{Discovered: discovered, Added: added, Profile: &profile}, nil
+}
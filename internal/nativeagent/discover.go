@@ -0,0 +1,88 @@
+package nativeagent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/agent"
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+type DiscoveryResult struct {
+	Discovered []agent.DiscoveredPort  `json:"discovered"`
+	Added      []protocol.TunnelConfig `json:"added,omitempty"`
+	Profile    *AgentProfile           `json:"profile,omitempty"`
+}
+
+func (s *Service) DiscoverPorts(ctx context.Context, portsRaw string) ([]agent.DiscoveredPort, error) {
+	if strings.TrimSpace(portsRaw) == "" {
+		portsRaw = agent.DefaultDiscoveryPorts
+	}
+	ports, err := agent.ParsePortList(portsRaw)
+	if err != nil {
+		return nil, err
+	}
+	return agent.DiscoverLocalPorts(ctx, "127.0.0.1", ports), nil
+}
+
+// AddDiscoveredTunnels adds a legacy tunnel to the profile for every
+// discovered port it does not already forward to. Connector profiles are
+// refused because their routes are defined on the gateway.
+func (s *Service) AddDiscoveredTunnels(idOrName string, discovered []agent.DiscoveredPort) (AgentProfile, []protocol.TunnelConfig, error) {
+	var (
+		updated AgentProfile
+		added   []protocol.TunnelConfig
+	)
+	_, err := s.store.Update(func(settings *AgentSettings) error {
+		index := profileIndexByIDOrName(*settings, idOrName)
+		if index < 0 {
+			return fmt.Errorf("profile %q not found", idOrName)
+		}
+		profile := settings.Profiles[index]
+		if profile.Mode != ModeLegacyTunnels {
+			return fmt.Errorf("profile %q uses connector mode; create gateway routes for discovered ports instead", profile.Name)
+		}
+
+		ids := make(map[string]struct{}, len(profile.LegacyTunnels))
+		targets := make(map[string]struct{}, len(profile.LegacyTunnels))
+		for _, tunnel := range profile.LegacyTunnels {
+			ids[tunnel.ID] = struct{}{}
+			targets[strings.TrimRight(tunnel.Target, "/")] = struct{}{}
+		}
+		for _, port := range discovered {
+			if _, ok := targets[strings.TrimRight(port.TargetURL, "/")]; ok {
+				continue
+			}
+			id := port.TunnelID
+			if id == "" {
+				id = agent.SuggestedTunnelID(port.Port)
+			}
+			if _, ok := ids[id]; ok {
+				continue
+			}
+			tunnel := protocol.TunnelConfig{ID: id, Target: port.TargetURL}
+			ids[id] = struct{}{}
+			targets[strings.TrimRight(port.TargetURL, "/")] = struct{}{}
+			profile.LegacyTunnels = append(profile.LegacyTunnels, tunnel)
+			added = append(added, tunnel)
+		}
+		if len(added) == 0 {
+			updated = profile
+			return nil
+		}
+		sort.Slice(profile.LegacyTunnels, func(i, j int) bool {
+			return profile.LegacyTunnels[i].ID < profile.LegacyTunnels[j].ID
+		})
+		profile.UpdatedAt = time.Now().UTC()
+		settings.Profiles[index] = profile
+		updated = profile
+		return nil
+	})
+	if err != nil {
+		return AgentProfile{}, nil, err
+	}
+	return updated, added, nil
+}
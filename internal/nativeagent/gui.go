@@ -202,10 +202,93 @@ func newGUIServerMux(bindings *DesktopBindings) *http.ServeMux {
 				return
 			}
 			writeJSON(w, http.StatusOK, updated)
+		case action == "discover" && r.Method == http.MethodPost:
+			var payload struct {
+				Ports string `json:"ports"`
+			}
+			if r.Body != nil {
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+			}
+			result, err := bindings.AddDiscoveredTunnels(profileRef, payload.Ports)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, result)
+		case action == "export" && r.Method == http.MethodPost:
+			var payload struct {
+				Passphrase string `json:"passphrase"`
+			}
+			if r.Body != nil {
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+			}
+			bundle, err := bindings.ExportProfile(profileRef, payload.Passphrase)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, bundle)
 		default:
 			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		}
 	})
+	mux.HandleFunc("/api/profiles/import", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+		var payload struct {
+			Bundle        ProfileBundle `json:"bundle"`
+			Name          string        `json:"name"`
+			Passphrase    string        `json:"passphrase"`
+			SecretBackend string        `json:"secret_backend"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid json payload: %w", err))
+			return
+		}
+		imported, err := bindings.ImportProfile(payload.Bundle, ProfileImportOptions{
+			Name:          payload.Name,
+			Passphrase:    payload.Passphrase,
+			SecretBackend: payload.SecretBackend,
+		})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, imported)
+	})
+	mux.HandleFunc("/api/pair/link", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+		var payload struct {
+			Link string `json:"link"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid json payload: %w", err))
+			return
+		}
+		paired, err := bindings.PairFromLink(payload.Link)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, paired)
+	})
+	mux.HandleFunc("/api/discover", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+		discovered, err := bindings.DiscoverPorts(r.URL.Query().Get("ports"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, DiscoveryResult{Discovered: discovered})
+	})
 	mux.HandleFunc("/api/runtime/start", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
@@ -244,6 +327,14 @@ func newGUIServerMux(bindings *DesktopBindings) *http.ServeMux {
 		}
 		writeJSON(w, http.StatusOK, status)
 	})
+	mux.HandleFunc("/api/status/profiles", func(w http.ResponseWriter, r *http.Request) {
+		statuses, err := bindings.GetProfileStatuses()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, statuses)
+	})
 	mux.HandleFunc("/api/logs", func(w http.ResponseWriter, r *http.Request) {
 		tailLines := 200
 		if tailRaw := strings.TrimSpace(r.URL.Query().Get("tail")); tailRaw != "" {
@@ -265,7 +356,19 @@ func newGUIServerMux(bindings *DesktopBindings) *http.ServeMux {
 		streamRuntimeEvents(w, r, bindings)
 	})
 	mux.HandleFunc("/api/update/check", func(w http.ResponseWriter, r *http.Request) {
-		result, err := bindings.CheckForUpdates()
+		result, err := bindings.CheckForUpdates(r.URL.Query().Get("profile"))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	})
+	mux.HandleFunc("/api/update/apply", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+			return
+		}
+		result, err := bindings.ApplyUpdate(r.URL.Query().Get("profile"))
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, err)
 			return
@@ -382,6 +485,7 @@ type profilePayload struct {
 	LegacyTunnels   string         `json:"legacy_tunnels"`
 	ConnectorSecret string         `json:"connector_secret"`
 	AgentToken      string         `json:"agent_token"`
+	SecretBackend   string         `json:"secret_backend"`
 	Runtime         runtimePayload `json:"runtime"`
 }
 
@@ -390,6 +494,7 @@ type runtimePayload struct {
 	PollWait             string `json:"poll_wait"`
 	HeartbeatInterval    string `json:"heartbeat_interval"`
 	MaxResponseBodyBytes int64  `json:"max_response_body_bytes"`
+	MaxBytesPerSecond    int64  `json:"max_bytes_per_second,omitempty"`
 	ProxyURL             string `json:"proxy_url,omitempty"`
 	NoProxy              string `json:"no_proxy,omitempty"`
 	TLSSkipVerify        *bool  `json:"tls_skip_verify,omitempty"`
@@ -407,11 +512,13 @@ func (p profilePayload) toInput() ProfileInput {
 		LegacyTunnels:   p.LegacyTunnels,
 		ConnectorSecret: p.ConnectorSecret,
 		AgentToken:      p.AgentToken,
+		SecretBackend:   p.SecretBackend,
 		Runtime: RuntimeOptions{
 			RequestTimeout:       p.Runtime.RequestTimeout,
 			PollWait:             p.Runtime.PollWait,
 			HeartbeatInterval:    p.Runtime.HeartbeatInterval,
 			MaxResponseBodyBytes: p.Runtime.MaxResponseBodyBytes,
+			MaxBytesPerSecond:    p.Runtime.MaxBytesPerSecond,
 			ProxyURL:             p.Runtime.ProxyURL,
 			NoProxy:              p.Runtime.NoProxy,
 			CAFile:               p.Runtime.CAFile,
@@ -1,6 +1,10 @@
 package nativeagent
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
 
 func TestParseProfileRoute(t *testing.T) {
 	t.Parallel()
@@ -41,3 +45,45 @@ func TestProfilePayloadToInputTLSFlag(t *testing.T) {
 		t.Fatalf("Runtime.TLSSkipVerify = false, want true")
 	}
 }
+
+func TestBuildTrayState(t *testing.T) {
+	t.Parallel()
+	stopped := buildTrayState(NativeStatusSnapshot{}, "laptop")
+	if stopped.StatusLabel != "Status: stopped" || stopped.ProfileLabel != "Profile: laptop" {
+		t.Fatalf("stopped tray = %+v", stopped)
+	}
+	if !stopped.CanStart || stopped.CanStop || len(stopped.URLs) != 0 {
+		t.Fatalf("stopped tray actions = %+v", stopped)
+	}
+
+	running := buildTrayState(NativeStatusSnapshot{
+		State:       RuntimeStateRunning,
+		ProfileName: "work",
+		Routes: []protocol.TunnelRoute{
+			{ID: "app", PublicURL: "https://gw.example.test/t/app/"},
+			{ID: "pending"},
+		},
+	}, "laptop")
+	if running.ProfileLabel != "Profile: work" || running.CanStart || !running.CanStop {
+		t.Fatalf("running tray = %+v", running)
+	}
+	if len(running.URLs) != 1 || running.URLs[0].URL != "https://gw.example.test/t/app/" {
+		t.Fatalf("running tray urls = %+v", running.URLs)
+	}
+}
+
+func TestExtractPairToken(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"  pair_abc123\n": "pair_abc123",
+		"proxer-agent pair --token pair_abc123 --profile dev": "pair_abc123",
+		"proxer-agent pair --token=pair_abc123":               "pair_abc123",
+		"https://gw.example.test/pair?pair_token=pair_abc123": "pair_abc123",
+		"not a token": "",
+	}
+	for pasted, want := range cases {
+		if got := extractPairToken(pasted); got != want {
+			t.Fatalf("extractPairToken(%q) = %q, want %q", pasted, got, want)
+		}
+	}
+}
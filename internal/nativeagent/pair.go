@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -55,3 +56,53 @@ func pairWithGateway(ctx context.Context, gatewayBaseURL, agentID, pairToken str
 	}
 	return pairResp, nil
 }
+
+func (s *Service) PairFromLink(link, idOrName string) (AgentProfile, error) {
+	gatewayBaseURL, pairToken, err := protocol.ParsePairLink(link)
+	if err != nil {
+		return AgentProfile{}, err
+	}
+	profile, err := s.profileForGateway(gatewayBaseURL, idOrName)
+	if err != nil {
+		return AgentProfile{}, err
+	}
+	return s.PairProfile(profile.ID, pairToken)
+}
+
+func (s *Service) profileForGateway(gatewayBaseURL, idOrName string) (AgentProfile, error) {
+	sameGateway := func(profile AgentProfile) bool {
+		return strings.EqualFold(strings.TrimRight(strings.TrimSpace(profile.GatewayBaseURL), "/"), gatewayBaseURL)
+	}
+	if strings.TrimSpace(idOrName) != "" {
+		profile, err := s.ResolveProfile(idOrName)
+		if err != nil || sameGateway(profile) {
+			return profile, err
+		}
+		return s.UpdateProfile(profile.ID, ProfileInput{GatewayBaseURL: gatewayBaseURL})
+	}
+
+	if active, err := s.ActiveProfile(); err == nil && sameGateway(active) {
+		return active, nil
+	}
+	profiles, err := s.ListProfiles()
+	if err != nil {
+		return AgentProfile{}, err
+	}
+	names := map[string]bool{}
+	for _, profile := range profiles {
+		if sameGateway(profile) {
+			return profile, nil
+		}
+		names[strings.ToLower(strings.TrimSpace(profile.Name))] = true
+	}
+
+	base := gatewayBaseURL
+	if parsed, err := url.Parse(gatewayBaseURL); err == nil && parsed.Hostname() != "" {
+		base = parsed.Hostname()
+	}
+	name := base
+	for i := 2; names[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return s.CreateProfile(ProfileInput{Name: name, GatewayBaseURL: gatewayBaseURL, Mode: ModeConnector})
+}
@@ -65,6 +65,14 @@ func SettingsPath() (string, error) {
 	return filepath.Join(dir, settingsFileName), nil
 }
 
+func SecretsFilePath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, secretsFileName), nil
+}
+
 func StatusPath() (string, error) {
 	dir, err := ConfigDir()
 	if err != nil {
@@ -0,0 +1,216 @@
+package nativeagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const profileBundleVersion = 1
+
+// Names of the secrets a profile bundle can carry. They double as the
+// additional data of each sealed value. Per-tunnel tokens are named with
+// bundleSecretTunnelPrefix followed by the tunnel ID.
+const (
+	bundleSecretConnector    = "connector_secret"
+	bundleSecretAgentToken   = "agent_token"
+	bundleSecretTunnelPrefix = "tunnel_token:"
+)
+
+// ProfileBundle is a portable copy of one profile, used to move a setup to
+// another machine or to share it as a team template. Secrets are only
+// included when the exporter supplies a passphrase, and are re-wrapped under
+// it rather than copied from the local secret store.
+type ProfileBundle struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Profile    AgentProfile   `json:"profile"`
+	Secrets    *BundleSecrets `json:"secrets,omitempty"`
+}
+
+type BundleSecrets struct {
+	KDF        string            `json:"kdf"`
+	Iterations int               `json:"iterations"`
+	Salt       string            `json:"salt"`
+	Values     map[string]string `json:"values"`
+}
+
+type ProfileImportOptions struct {
+	Name          string
+	Passphrase    string
+	SecretBackend string
+}
+
+func (s *Service) ExportProfile(idOrName, passphrase string) (ProfileBundle, error) {
+	profile, err := s.ResolveProfile(idOrName)
+	if err != nil {
+		return ProfileBundle{}, err
+	}
+	bundle := ProfileBundle{
+		Version:    profileBundleVersion,
+		ExportedAt: time.Now().UTC(),
+		Profile:    portableProfile(profile),
+	}
+	if passphrase == "" {
+		return bundle, nil
+	}
+
+	ctx := context.Background()
+	values := map[string]string{}
+	for name, ref := range map[string]SecretRef{
+		bundleSecretConnector:  profile.ConnectorSecretRef,
+		bundleSecretAgentToken: profile.AgentTokenRef,
+	} {
+		if strings.TrimSpace(ref.Key) == "" {
+			continue
+		}
+		value, err := s.secretsFor(profile).Get(ctx, ref.Key)
+		if errors.Is(err, ErrSecretNotFound) {
+			continue
+		}
+		if err != nil {
+			return ProfileBundle{}, fmt.Errorf("read %s from %s: %w", name, secretBackendLabel(profile.SecretBackend), err)
+		}
+		values[name] = value
+	}
+	for _, tunnel := range profile.LegacyTunnels {
+		if tunnel.Token != "" {
+			values[bundleSecretTunnelPrefix+tunnel.ID] = tunnel.Token
+		}
+	}
+	salt, err := newSecretSalt()
+	if err != nil {
+		return ProfileBundle{}, err
+	}
+	key, err := deriveSecretKey(passphrase, salt, secretKDFIterations)
+	if err != nil {
+		return ProfileBundle{}, err
+	}
+	aead, err := secretAEAD(key)
+	if err != nil {
+		return ProfileBundle{}, err
+	}
+	secrets := &BundleSecrets{KDF: secretKDFName, Iterations: secretKDFIterations, Salt: salt, Values: map[string]string{}}
+	for name, value := range values {
+		if secrets.Values[name], err = sealSecretValue(aead, name, value); err != nil {
+			return ProfileBundle{}, err
+		}
+	}
+	bundle.Secrets = secrets
+	return bundle, nil
+}
+
+func (s *Service) ImportProfile(bundle ProfileBundle, opts ProfileImportOptions) (AgentProfile, error) {
+	if bundle.Version != profileBundleVersion {
+		return AgentProfile{}, fmt.Errorf("unsupported profile bundle version %d", bundle.Version)
+	}
+	values := map[string]string{}
+	if bundle.Secrets != nil && len(bundle.Secrets.Values) > 0 {
+		if opts.Passphrase == "" {
+			return AgentProfile{}, fmt.Errorf("profile bundle contains encrypted secrets; a passphrase is required")
+		}
+		if bundle.Secrets.KDF != secretKDFName || bundle.Secrets.Iterations <= 0 {
+			return AgentProfile{}, fmt.Errorf("unsupported profile bundle key derivation %q", bundle.Secrets.KDF)
+		}
+		key, err := deriveSecretKey(opts.Passphrase, bundle.Secrets.Salt, bundle.Secrets.Iterations)
+		if err != nil {
+			return AgentProfile{}, err
+		}
+		aead, err := secretAEAD(key)
+		if err != nil {
+			return AgentProfile{}, err
+		}
+		for name, sealed := range bundle.Secrets.Values {
+			if name != bundleSecretConnector && name != bundleSecretAgentToken && !strings.HasPrefix(name, bundleSecretTunnelPrefix) {
+				return AgentProfile{}, fmt.Errorf("unknown secret %q in profile bundle", name)
+			}
+			if values[name], err = openSecretValue(aead, name, sealed); err != nil {
+				return AgentProfile{}, err
+			}
+		}
+	}
+
+	profileID, err := newProfileID()
+	if err != nil {
+		return AgentProfile{}, err
+	}
+	profile := portableProfile(bundle.Profile)
+	profile.ID = profileID
+	if name := strings.TrimSpace(opts.Name); name != "" {
+		profile.Name = name
+	}
+	if backend := strings.TrimSpace(opts.SecretBackend); backend != "" {
+		profile.SecretBackend = backend
+	}
+	for i := range profile.LegacyTunnels {
+		profile.LegacyTunnels[i].Token = values[bundleSecretTunnelPrefix+profile.LegacyTunnels[i].ID]
+	}
+	profile = applyProfileDefaults(profile)
+	profile.ConnectorSecretRef = SecretRef{Key: secretKeyForProfile(profile.ID, "connector_secret")}
+	profile.AgentTokenRef = SecretRef{Key: secretKeyForProfile(profile.ID, "agent_token")}
+	if err := validateProfile(profile); err != nil {
+		return AgentProfile{}, err
+	}
+
+	settings, err := s.store.Load()
+	if err != nil {
+		return AgentProfile{}, err
+	}
+	if err := ensureUniqueProfileName(settings, profile.Name, ""); err != nil {
+		return AgentProfile{}, fmt.Errorf("%w; import it under another name", err)
+	}
+
+	ctx := context.Background()
+	if value := values[bundleSecretConnector]; value != "" {
+		if err := s.secretsFor(profile).Set(ctx, profile.ConnectorSecretRef.Key, value); err != nil {
+			return AgentProfile{}, err
+		}
+	}
+	if value := values[bundleSecretAgentToken]; value != "" {
+		if err := s.secretsFor(profile).Set(ctx, profile.AgentTokenRef.Key, value); err != nil {
+			return AgentProfile{}, err
+		}
+	}
+
+	createdAt := time.Now().UTC()
+	profile.CreatedAt = createdAt
+	profile.UpdatedAt = createdAt
+	_, err = s.store.Update(func(settings *AgentSettings) error {
+		if err := ensureUniqueProfileName(*settings, profile.Name, ""); err != nil {
+			return err
+		}
+		settings.Profiles = append(settings.Profiles, profile)
+		if strings.TrimSpace(settings.ActiveProfileID) == "" {
+			settings.ActiveProfileID = profile.ID
+		}
+		return nil
+	})
+	if err != nil {
+		_ = s.secretsFor(profile).Delete(ctx, profile.ConnectorSecretRef.Key)
+		_ = s.secretsFor(profile).Delete(ctx, profile.AgentTokenRef.Key)
+		return AgentProfile{}, err
+	}
+	return profile, nil
+}
+
+func portableProfile(profile AgentProfile) AgentProfile {
+	tunnels := make([]protocol.TunnelConfig, len(profile.LegacyTunnels))
+	for i, tunnel := range profile.LegacyTunnels {
+		tunnel.Token = ""
+		tunnels[i] = tunnel
+	}
+	if len(tunnels) == 0 {
+		tunnels = nil
+	}
+	profile.LegacyTunnels = tunnels
+	profile.ID = ""
+	profile.ConnectorSecretRef = SecretRef{}
+	profile.AgentTokenRef = SecretRef{}
+	profile.CreatedAt = time.Time{}
+	profile.UpdatedAt = time.Time{}
+	return profile
+}
@@ -0,0 +1,138 @@
+package nativeagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const runtimesDirName = "runtimes"
+
+func (s *Service) ProfileStatusPath(profileID string) string {
+	return filepath.Join(filepath.Dir(s.statusPath), runtimesDirName, profileID, statusFileName)
+}
+
+func (s *Service) ProfileLogPath(profileID string) string {
+	return filepath.Join(filepath.Dir(s.logPath), runtimesDirName, profileID, logFileName)
+}
+
+func (s *Service) StartProfiles(refs []string) ([]AgentProfile, error) {
+	settings, err := s.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	var profiles []AgentProfile
+	if len(refs) == 0 {
+		profiles = append(profiles, settings.Profiles...)
+	} else {
+		for _, ref := range refs {
+			index := profileIndexByIDOrName(settings, strings.TrimSpace(ref))
+			if index < 0 {
+				return nil, fmt.Errorf("profile %q not found", ref)
+			}
+			profiles = append(profiles, settings.Profiles[index])
+		}
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("no profiles configured")
+	}
+
+	var started []AgentProfile
+	var errs []error
+	for _, profile := range profiles {
+		if err := s.startProfileRuntime(applyProfileDefaults(profile)); err != nil {
+			errs = append(errs, fmt.Errorf("profile %s: %w", profile.Name, err))
+			continue
+		}
+		started = append(started, profile)
+	}
+	return started, errors.Join(errs...)
+}
+
+func (s *Service) startProfileRuntime(profile AgentProfile) error {
+	connectorSecret, agentToken, err := s.profileCredentials(profile)
+	if err != nil {
+		return err
+	}
+
+	s.runtimesMu.Lock()
+	defer s.runtimesMu.Unlock()
+	runtime := s.profileRuntimes[profile.ID]
+	if runtime == nil {
+		runtime = NewRuntimeManager(s.ProfileStatusPath(profile.ID), s.ProfileLogPath(profile.ID))
+		runtime.logPrefix = fmt.Sprintf("[agent %s] ", profile.Name)
+		if s.profileRuntimes == nil {
+			s.profileRuntimes = make(map[string]*RuntimeManager)
+		}
+		s.profileRuntimes[profile.ID] = runtime
+	}
+	return runtime.Start(profile, connectorSecret, agentToken)
+}
+
+func (s *Service) StopProfiles() error {
+	var errs []error
+	for id, runtime := range s.runningProfileRuntimes() {
+		if err := runtime.Stop(15 * time.Second); err != nil {
+			errs = append(errs, fmt.Errorf("profile %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) WaitProfiles(ctx context.Context) error {
+	var errs []error
+	for id, runtime := range s.runningProfileRuntimes() {
+		if err := runtime.Wait(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("profile %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) runningProfileRuntimes() map[string]*RuntimeManager {
+	s.runtimesMu.Lock()
+	defer s.runtimesMu.Unlock()
+	out := make(map[string]*RuntimeManager, len(s.profileRuntimes))
+	for id, runtime := range s.profileRuntimes {
+		out[id] = runtime
+	}
+	return out
+}
+
+// ProfileStatuses reports the runtime status of every profile, read from the
+// per-profile status files so other processes see runtimes started by
+// StartProfiles. Profiles that never ran that way report stopped.
+func (s *Service) ProfileStatuses() ([]NativeStatusSnapshot, error) {
+	settings, err := s.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]NativeStatusSnapshot, 0, len(settings.Profiles))
+	for _, profile := range settings.Profiles {
+		path := s.ProfileStatusPath(profile.ID)
+		snapshot := NativeStatusSnapshot{State: RuntimeStateStopped, UpdatedAt: profile.UpdatedAt}
+		if _, err := os.Stat(path); err == nil {
+			snapshot, err = ReadStatusSnapshot(path)
+			if err != nil {
+				return nil, fmt.Errorf("read status of profile %s: %w", profile.Name, err)
+			}
+		}
+		snapshot.ProfileID = profile.ID
+		snapshot.ProfileName = profile.Name
+		if snapshot.AgentID == "" {
+			snapshot.AgentID = profile.AgentID
+		}
+		if snapshot.Mode == "" {
+			snapshot.Mode = profile.Mode
+		}
+		statuses = append(statuses, snapshot)
+	}
+	return statuses, nil
+}
@@ -13,11 +13,15 @@ import (
 	"time"
 
 	"github.com/szaher/try/proxer/internal/agent"
+	"github.com/szaher/try/proxer/internal/protocol"
 )
 
 type RuntimeManager struct {
 	statusPath string
 	logPath    string
+	// logPrefix overrides the "[agent] " log prefix, so runtimes sharing
+	// stdout can be told apart.
+	logPrefix string
 
 	mu      sync.RWMutex
 	state   NativeStatusSnapshot
@@ -28,6 +32,8 @@ type RuntimeManager struct {
 
 	nextSubscriberID int
 	subscribers      map[int]chan NativeStatusSnapshot
+
+	secretHook func(profile AgentProfile, secret string)
 }
 
 func NewRuntimeManager(statusPath, logPath string) *RuntimeManager {
@@ -76,7 +82,11 @@ func (m *RuntimeManager) Start(profile AgentProfile, connectorSecret, agentToken
 		m.mu.Unlock()
 		return err
 	}
-	logger := log.New(logWriter, "[agent] ", log.LstdFlags|log.Lmicroseconds)
+	prefix := "[agent] "
+	if m.logPrefix != "" {
+		prefix = m.logPrefix
+	}
+	logger := log.New(logWriter, prefix, log.LstdFlags|log.Lmicroseconds)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	m.cancel = cancel
@@ -87,6 +97,11 @@ func (m *RuntimeManager) Start(profile AgentProfile, connectorSecret, agentToken
 	cfg.EventHook = func(ev agent.RuntimeEvent) {
 		m.handleAgentEvent(profile, ev)
 	}
+	if secretHook := m.secretHook; secretHook != nil {
+		cfg.SecretHook = func(_, secret string) {
+			secretHook(profile, secret)
+		}
+	}
 	client := agent.New(cfg, logger)
 	m.mu.Unlock()
 
@@ -117,6 +132,12 @@ func (m *RuntimeManager) Start(profile AgentProfile, connectorSecret, agentToken
 	return nil
 }
 
+func (m *RuntimeManager) SetSecretHook(hook func(profile AgentProfile, secret string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secretHook = hook
+}
+
 func (m *RuntimeManager) Stop(timeout time.Duration) error {
 	m.mu.Lock()
 	if !m.running {
@@ -185,6 +206,10 @@ func (m *RuntimeManager) handleAgentEvent(profile AgentProfile, ev agent.Runtime
 	m.state.ProfileName = profile.Name
 	m.state.Mode = profile.Mode
 	m.state.SessionID = ev.SessionID
+	m.state.Routes = ev.Routes
+	if ev.Connection != nil {
+		m.state.Connection = ev.Connection
+	}
 	m.state.UpdatedAt = ev.At.UTC()
 	if m.state.StartedAt == nil {
 		at := ev.At.UTC()
@@ -249,11 +274,13 @@ func profileToAgentConfig(profile AgentProfile, connectorSecret, agentToken stri
 		RequestTimeout:       requestTimeout,
 		PollWait:             pollWait,
 		MaxResponseBodyBytes: profile.Runtime.MaxResponseBodyBytes,
+		MaxBytesPerSecond:    profile.Runtime.MaxBytesPerSecond,
 		ProxyURL:             profile.Runtime.ProxyURL,
 		NoProxy:              profile.Runtime.NoProxy,
 		TLSSkipVerify:        profile.Runtime.TLSSkipVerify,
 		CAFile:               profile.Runtime.CAFile,
 		LogLevel:             profile.Runtime.LogLevel,
+		Version:              BuildVersion(),
 	}
 
 	switch profile.Mode {
@@ -372,9 +399,16 @@ func sendLatestSnapshot(ch chan NativeStatusSnapshot, snapshot NativeStatusSnaps
 
 func cloneStatusSnapshot(snapshot NativeStatusSnapshot) NativeStatusSnapshot {
 	cloned := snapshot
+	if snapshot.Routes != nil {
+		cloned.Routes = append([]protocol.TunnelRoute(nil), snapshot.Routes...)
+	}
 	if snapshot.StartedAt != nil {
 		startedAt := *snapshot.StartedAt
 		cloned.StartedAt = &startedAt
 	}
+	if snapshot.Connection != nil {
+		connection := *snapshot.Connection
+		cloned.Connection = &connection
+	}
 	return cloned
 }
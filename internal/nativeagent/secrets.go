@@ -6,6 +6,11 @@ import (
 	"fmt"
 )
 
+const (
+	SecretBackendKeychain = "keychain"
+	SecretBackendFile     = "file"
+)
+
 var (
 	ErrSecretNotFound    = errors.New("secret not found")
 	ErrSecretUnavailable = errors.New("secret store unavailable")
@@ -21,6 +26,13 @@ func NewSecretStore() SecretStore {
 	return newPlatformSecretStore()
 }
 
+func validateSecretBackend(backend string) error {
+	if backend != SecretBackendKeychain && backend != SecretBackendFile {
+		return fmt.Errorf("secret_backend must be %q or %q", SecretBackendKeychain, SecretBackendFile)
+	}
+	return nil
+}
+
 func secretKeyForProfile(profileID, field string) string {
 	return fmt.Sprintf("profile/%s/%s", profileID, field)
 }
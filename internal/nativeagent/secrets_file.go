@@ -0,0 +1,273 @@
+package nativeagent
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	secretsFileName = "secrets.enc.json"
+
+	encryptedSecretsVersion = 1
+	secretKDFName           = "pbkdf2-sha256"
+	secretKDFIterations     = 600_000
+	secretKeyCommandTimeout = 15 * time.Second
+)
+
+// encryptedSecretsFile is the on-disk layout of the file secret backend. Each
+// value is AES-256-GCM sealed with the secret key as additional data, so
+// ciphertexts cannot be swapped between keys.
+type encryptedSecretsFile struct {
+	Version    int               `json:"version"`
+	KDF        string            `json:"kdf"`
+	Iterations int               `json:"iterations"`
+	Salt       string            `json:"salt"`
+	Secrets    map[string]string `json:"secrets"`
+}
+
+type fileSecretStore struct {
+	path       string
+	iterations int
+	passphrase func(ctx context.Context) (string, error)
+
+	mu        sync.Mutex
+	keySalt   string
+	keySource string
+	key       []byte
+}
+
+func NewFileSecretStore(path string) SecretStore {
+	return &fileSecretStore{path: path, iterations: secretKDFIterations, passphrase: passphraseFromEnv}
+}
+
+func passphraseFromEnv(ctx context.Context) (string, error) {
+	if value := os.Getenv("PROXER_AGENT_SECRET_PASSPHRASE"); strings.TrimSpace(value) != "" {
+		return value, nil
+	}
+	if path := strings.TrimSpace(os.Getenv("PROXER_AGENT_SECRET_PASSPHRASE_FILE")); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read secret passphrase file: %w: %w", ErrSecretUnavailable, err)
+		}
+		if value := strings.TrimSpace(string(data)); value != "" {
+			return value, nil
+		}
+		return "", fmt.Errorf("secret passphrase file %s is empty: %w", path, ErrSecretUnavailable)
+	}
+	if command := strings.TrimSpace(os.Getenv("PROXER_AGENT_SECRET_KEY_COMMAND")); command != "" {
+		ctx, cancel := context.WithTimeout(ctx, secretKeyCommandTimeout)
+		defer cancel()
+		output, err := shellCommand(ctx, command).Output()
+		if err != nil {
+			return "", fmt.Errorf("run PROXER_AGENT_SECRET_KEY_COMMAND: %w: %w", ErrSecretUnavailable, err)
+		}
+		if value := strings.TrimSpace(string(output)); value != "" {
+			return value, nil
+		}
+		return "", fmt.Errorf("PROXER_AGENT_SECRET_KEY_COMMAND printed nothing: %w", ErrSecretUnavailable)
+	}
+	return "", fmt.Errorf("encrypted secret file needs PROXER_AGENT_SECRET_PASSPHRASE, PROXER_AGENT_SECRET_PASSPHRASE_FILE or PROXER_AGENT_SECRET_KEY_COMMAND: %w", ErrSecretUnavailable)
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if filepath.Separator == '\\' {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+func (s *fileSecretStore) Set(ctx context.Context, key, value string) error {
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("secret key is required")
+	}
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("secret value is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.loadLocked()
+	if err != nil {
+		return err
+	}
+	aead, err := s.cipherLocked(ctx, file)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealSecretValue(aead, key, value)
+	if err != nil {
+		return err
+	}
+	file.Secrets[key] = sealed
+	return s.saveLocked(file)
+}
+
+func (s *fileSecretStore) Get(ctx context.Context, key string) (string, error) {
+	if strings.TrimSpace(key) == "" {
+		return "", fmt.Errorf("secret key is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.loadLocked()
+	if err != nil {
+		return "", err
+	}
+	encoded, ok := file.Secrets[key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	aead, err := s.cipherLocked(ctx, file)
+	if err != nil {
+		return "", err
+	}
+	return openSecretValue(aead, key, encoded)
+}
+
+func (s *fileSecretStore) Delete(ctx context.Context, key string) error {
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("secret key is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.loadLocked()
+	if err != nil {
+		return err
+	}
+	if _, ok := file.Secrets[key]; !ok {
+		return nil
+	}
+	delete(file.Secrets, key)
+	return s.saveLocked(file)
+}
+
+func (s *fileSecretStore) loadLocked() (*encryptedSecretsFile, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		salt, err := newSecretSalt()
+		if err != nil {
+			return nil, err
+		}
+		return &encryptedSecretsFile{
+			Version:    encryptedSecretsVersion,
+			KDF:        secretKDFName,
+			Iterations: s.iterations,
+			Salt:       salt,
+			Secrets:    map[string]string{},
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read secrets file: %w", err)
+	}
+	var file encryptedSecretsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse secrets file %s: %w", s.path, err)
+	}
+	if file.Version != encryptedSecretsVersion || file.KDF != secretKDFName || file.Iterations <= 0 {
+		return nil, fmt.Errorf("unsupported secrets file %s (version %d, kdf %q)", s.path, file.Version, file.KDF)
+	}
+	if file.Secrets == nil {
+		file.Secrets = map[string]string{}
+	}
+	return &file, nil
+}
+
+func (s *fileSecretStore) saveLocked(file *encryptedSecretsFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode secrets file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("create secrets directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write secrets file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replace secrets file: %w", err)
+	}
+	return nil
+}
+
+// cipherLocked derives the file key from the passphrase. The derived key is
+// cached for the passphrase and salt, since the KDF is deliberately slow.
+func (s *fileSecretStore) cipherLocked(ctx context.Context, file *encryptedSecretsFile) (cipher.AEAD, error) {
+	passphrase, err := s.passphrase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	source := fmt.Sprintf("%x", sha256.Sum256([]byte(passphrase)))
+	if s.key == nil || s.keySalt != file.Salt || s.keySource != source {
+		key, err := deriveSecretKey(passphrase, file.Salt, file.Iterations)
+		if err != nil {
+			return nil, fmt.Errorf("secrets file %s: %w", s.path, err)
+		}
+		s.key, s.keySalt, s.keySource = key, file.Salt, source
+	}
+	return secretAEAD(s.key)
+}
+
+func newSecretSalt() (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(salt), nil
+}
+
+func deriveSecretKey(passphrase, encodedSalt string, iterations int) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(encodedSalt)
+	if err != nil || len(salt) == 0 {
+		return nil, fmt.Errorf("invalid key salt")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive secrets key: %w", err)
+	}
+	return key, nil
+}
+
+func secretAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealSecretValue(aead cipher.AEAD, name, value string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func openSecretValue(aead cipher.AEAD, name, encoded string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("secret %q is corrupt", name)
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("decrypt secret %q (wrong passphrase?): %w", name, ErrSecretUnavailable)
+	}
+	return string(plain), nil
+}
@@ -11,15 +11,20 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Service struct {
-	store      *Store
-	secrets    SecretStore
-	runtime    *RuntimeManager
-	statusPath string
-	logPath    string
+	store       *Store
+	secrets     SecretStore
+	fileSecrets SecretStore
+	runtime     *RuntimeManager
+	statusPath  string
+	logPath     string
+
+	runtimesMu      sync.Mutex
+	profileRuntimes map[string]*RuntimeManager
 }
 
 var pairWithGatewayExchange = pairWithGateway
@@ -35,6 +40,8 @@ type ProfileInput struct {
 	LegacyTunnels           string
 	ConnectorSecret         string
 	AgentToken              string
+	SecretBackend           string
+	UpdateChannel           string
 }
 
 type AppSettingsInput struct {
@@ -55,13 +62,20 @@ func NewService() (*Service, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Service{
-		store:      store,
-		secrets:    NewSecretStore(),
-		runtime:    NewRuntimeManager(statusPath, logPath),
-		statusPath: statusPath,
-		logPath:    logPath,
-	}, nil
+	secretsPath, err := SecretsFilePath()
+	if err != nil {
+		return nil, err
+	}
+	service := &Service{
+		store:       store,
+		secrets:     NewSecretStore(),
+		fileSecrets: NewFileSecretStore(secretsPath),
+		runtime:     NewRuntimeManager(statusPath, logPath),
+		statusPath:  statusPath,
+		logPath:     logPath,
+	}
+	service.runtime.SetSecretHook(service.saveRotatedSecret)
+	return service, nil
 }
 
 func NewServiceWithDependencies(store *Store, secrets SecretStore, runtime *RuntimeManager, statusPath, logPath string) *Service {
@@ -78,13 +92,59 @@ func NewServiceWithDependencies(store *Store, secrets SecretStore, runtime *Runt
 	if runtime == nil {
 		runtime = NewRuntimeManager(statusPath, logPath)
 	}
-	return &Service{
-		store:      store,
-		secrets:    secrets,
-		runtime:    runtime,
-		statusPath: statusPath,
-		logPath:    logPath,
+	service := &Service{
+		store:       store,
+		secrets:     secrets,
+		fileSecrets: NewFileSecretStore(filepath.Join(filepath.Dir(store.path), secretsFileName)),
+		runtime:     runtime,
+		statusPath:  statusPath,
+		logPath:     logPath,
+	}
+	runtime.SetSecretHook(service.saveRotatedSecret)
+	return service
+}
+
+func (s *Service) saveRotatedSecret(profile AgentProfile, secret string) {
+	if profile.ConnectorSecretRef.Key == "" {
+		return
+	}
+	_ = s.secretsFor(profile).Set(context.Background(), profile.ConnectorSecretRef.Key, secret)
+}
+
+func (s *Service) secretsFor(profile AgentProfile) SecretStore {
+	if profile.SecretBackend == SecretBackendFile {
+		return s.fileSecrets
+	}
+	return s.secrets
+}
+
+// moveProfileSecrets copies a profile's stored credentials to its new backend
+// and then removes them from the old one. Nothing is removed unless every
+// copy succeeded.
+func (s *Service) moveProfileSecrets(from, to AgentProfile) error {
+	ctx := context.Background()
+	source, target := s.secretsFor(from), s.secretsFor(to)
+	var moved []string
+	for _, key := range []string{from.ConnectorSecretRef.Key, from.AgentTokenRef.Key} {
+		if strings.TrimSpace(key) == "" {
+			continue
+		}
+		value, err := source.Get(ctx, key)
+		if errors.Is(err, ErrSecretNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("read secret from %s: %w", secretBackendLabel(from.SecretBackend), err)
+		}
+		if err := target.Set(ctx, key, value); err != nil {
+			return fmt.Errorf("write secret to %s: %w", secretBackendLabel(to.SecretBackend), err)
+		}
+		moved = append(moved, key)
+	}
+	for _, key := range moved {
+		_ = source.Delete(ctx, key)
 	}
+	return nil
 }
 
 func (s *Service) Settings() (AgentSettings, error) {
@@ -176,6 +236,8 @@ func (s *Service) CreateProfile(input ProfileInput) (AgentProfile, error) {
 		AgentID:        strings.TrimSpace(input.AgentID),
 		Mode:           strings.TrimSpace(input.Mode),
 		ConnectorID:    strings.TrimSpace(input.ConnectorID),
+		SecretBackend:  input.SecretBackend,
+		UpdateChannel:  input.UpdateChannel,
 		Runtime:        input.Runtime,
 	}
 	profile = applyProfileDefaults(profile)
@@ -190,12 +252,12 @@ func (s *Service) CreateProfile(input ProfileInput) (AgentProfile, error) {
 	}
 
 	if profile.Mode == ModeConnector && strings.TrimSpace(input.ConnectorSecret) != "" {
-		if err := s.secrets.Set(context.Background(), profile.ConnectorSecretRef.Key, strings.TrimSpace(input.ConnectorSecret)); err != nil {
+		if err := s.secretsFor(profile).Set(context.Background(), profile.ConnectorSecretRef.Key, strings.TrimSpace(input.ConnectorSecret)); err != nil {
 			return AgentProfile{}, err
 		}
 	}
 	if profile.Mode == ModeLegacyTunnels && strings.TrimSpace(input.AgentToken) != "" {
-		if err := s.secrets.Set(context.Background(), profile.AgentTokenRef.Key, strings.TrimSpace(input.AgentToken)); err != nil {
+		if err := s.secretsFor(profile).Set(context.Background(), profile.AgentTokenRef.Key, strings.TrimSpace(input.AgentToken)); err != nil {
 			return AgentProfile{}, err
 		}
 	}
@@ -228,6 +290,7 @@ func (s *Service) UpdateProfile(idOrName string, input ProfileInput) (AgentProfi
 			return fmt.Errorf("profile %q not found", idOrName)
 		}
 		profile := settings.Profiles[index]
+		previous := profile
 
 		if name := strings.TrimSpace(input.Name); name != "" {
 			profile.Name = name
@@ -244,6 +307,12 @@ func (s *Service) UpdateProfile(idOrName string, input ProfileInput) (AgentProfi
 		if connectorID := strings.TrimSpace(input.ConnectorID); connectorID != "" {
 			profile.ConnectorID = connectorID
 		}
+		if backend := strings.TrimSpace(input.SecretBackend); backend != "" {
+			profile.SecretBackend = backend
+		}
+		if channel := strings.TrimSpace(input.UpdateChannel); channel != "" {
+			profile.UpdateChannel = channel
+		}
 		if input.Runtime != (RuntimeOptions{}) || input.RuntimeTLSSkipVerifySet {
 			merged := profile.Runtime
 			if v := strings.TrimSpace(input.Runtime.RequestTimeout); v != "" {
@@ -258,6 +327,10 @@ func (s *Service) UpdateProfile(idOrName string, input ProfileInput) (AgentProfi
 			if input.Runtime.MaxResponseBodyBytes > 0 {
 				merged.MaxResponseBodyBytes = input.Runtime.MaxResponseBodyBytes
 			}
+			// A negative limit clears the profile's bandwidth limit.
+			if input.Runtime.MaxBytesPerSecond != 0 {
+				merged.MaxBytesPerSecond = max(input.Runtime.MaxBytesPerSecond, 0)
+			}
 			if v := strings.TrimSpace(input.Runtime.ProxyURL); v != "" {
 				merged.ProxyURL = v
 			}
@@ -280,6 +353,14 @@ func (s *Service) UpdateProfile(idOrName string, input ProfileInput) (AgentProfi
 			if err != nil {
 				return err
 			}
+			// The mapping string has no TLS settings; keep those of tunnels that stay.
+			for i := range tunnels {
+				for _, previous := range profile.LegacyTunnels {
+					if previous.ID == tunnels[i].ID {
+						tunnels[i].TLS = previous.TLS
+					}
+				}
+			}
 			profile.LegacyTunnels = tunnels
 		}
 
@@ -290,6 +371,11 @@ func (s *Service) UpdateProfile(idOrName string, input ProfileInput) (AgentProfi
 		if err := validateProfile(profile); err != nil {
 			return err
 		}
+		if profile.SecretBackend != previous.SecretBackend {
+			if err := s.moveProfileSecrets(previous, profile); err != nil {
+				return err
+			}
+		}
 
 		profile.UpdatedAt = time.Now().UTC()
 		settings.Profiles[index] = profile
@@ -301,12 +387,12 @@ func (s *Service) UpdateProfile(idOrName string, input ProfileInput) (AgentProfi
 	}
 
 	if strings.TrimSpace(input.ConnectorSecret) != "" {
-		if err := s.secrets.Set(context.Background(), updated.ConnectorSecretRef.Key, strings.TrimSpace(input.ConnectorSecret)); err != nil {
+		if err := s.secretsFor(updated).Set(context.Background(), updated.ConnectorSecretRef.Key, strings.TrimSpace(input.ConnectorSecret)); err != nil {
 			return AgentProfile{}, err
 		}
 	}
 	if strings.TrimSpace(input.AgentToken) != "" {
-		if err := s.secrets.Set(context.Background(), updated.AgentTokenRef.Key, strings.TrimSpace(input.AgentToken)); err != nil {
+		if err := s.secretsFor(updated).Set(context.Background(), updated.AgentTokenRef.Key, strings.TrimSpace(input.AgentToken)); err != nil {
 			return AgentProfile{}, err
 		}
 	}
@@ -335,10 +421,10 @@ func (s *Service) DeleteProfile(idOrName string) error {
 		return err
 	}
 	if removed.ConnectorSecretRef.Key != "" {
-		_ = s.secrets.Delete(context.Background(), removed.ConnectorSecretRef.Key)
+		_ = s.secretsFor(removed).Delete(context.Background(), removed.ConnectorSecretRef.Key)
 	}
 	if removed.AgentTokenRef.Key != "" {
-		_ = s.secrets.Delete(context.Background(), removed.AgentTokenRef.Key)
+		_ = s.secretsFor(removed).Delete(context.Background(), removed.AgentTokenRef.Key)
 	}
 	return nil
 }
@@ -372,7 +458,7 @@ func (s *Service) PairProfile(idOrName, pairToken string) (AgentProfile, error)
 	if err != nil {
 		return AgentProfile{}, err
 	}
-	if err := s.secrets.Set(context.Background(), profile.ConnectorSecretRef.Key, pairResp.ConnectorSecret); err != nil {
+	if err := s.secretsFor(profile).Set(context.Background(), profile.ConnectorSecretRef.Key, pairResp.ConnectorSecret); err != nil {
 		return AgentProfile{}, err
 	}
 	return s.UpdateProfile(profile.ID, ProfileInput{
@@ -387,33 +473,38 @@ func (s *Service) Start(profileIDOrName string) error {
 		return err
 	}
 	profile = applyProfileDefaults(profile)
+	connectorSecret, agentToken, err := s.profileCredentials(profile)
+	if err != nil {
+		return err
+	}
+	return s.runtime.Start(profile, connectorSecret, agentToken)
+}
 
-	connectorSecret := ""
-	agentToken := ""
+func (s *Service) profileCredentials(profile AgentProfile) (connectorSecret, agentToken string, err error) {
 	if profile.Mode == ModeConnector {
-		connectorSecret, err = s.secrets.Get(context.Background(), profile.ConnectorSecretRef.Key)
+		connectorSecret, err = s.secretsFor(profile).Get(context.Background(), profile.ConnectorSecretRef.Key)
 		if err != nil {
 			if errors.Is(err, ErrSecretNotFound) {
-				return fmt.Errorf("missing connector secret in system keychain; pair profile again")
+				return "", "", fmt.Errorf("missing connector secret in %s; pair profile again", secretBackendLabel(profile.SecretBackend))
 			}
 			if errors.Is(err, ErrSecretUnavailable) {
-				return fmt.Errorf("system secret store unavailable for connector credentials: %s", secretStoreUnavailableRemediation())
+				return "", "", fmt.Errorf("%s unavailable for connector credentials: %s", secretBackendLabel(profile.SecretBackend), secretStoreUnavailableRemediation(profile.SecretBackend, err))
 			}
-			return err
+			return "", "", err
 		}
 	} else {
-		agentToken, err = s.secrets.Get(context.Background(), profile.AgentTokenRef.Key)
+		agentToken, err = s.secretsFor(profile).Get(context.Background(), profile.AgentTokenRef.Key)
 		if err != nil {
 			if errors.Is(err, ErrSecretNotFound) {
-				return fmt.Errorf("missing legacy agent token in system keychain")
+				return "", "", fmt.Errorf("missing legacy agent token in %s", secretBackendLabel(profile.SecretBackend))
 			}
 			if errors.Is(err, ErrSecretUnavailable) {
-				return fmt.Errorf("system secret store unavailable for legacy token: %s", secretStoreUnavailableRemediation())
+				return "", "", fmt.Errorf("%s unavailable for legacy token: %s", secretBackendLabel(profile.SecretBackend), secretStoreUnavailableRemediation(profile.SecretBackend, err))
 			}
-			return err
+			return "", "", err
 		}
 	}
-	return s.runtime.Start(profile, connectorSecret, agentToken)
+	return connectorSecret, agentToken, nil
 }
 
 func (s *Service) Stop() error {
@@ -443,23 +534,26 @@ func (s *Service) SubscribeRuntimeEvents(ctx context.Context) (<-chan NativeStat
 	return s.runtime.Subscribe(ctx, 32), nil
 }
 
-func (s *Service) CheckForUpdates() (UpdateCheckResult, error) {
-	return UpdateCheckResult{
-		CurrentVersion: BuildVersion(),
-		Message:        "Auto-update is not enabled in v1. Use release artifacts from the project repository.",
-	}, nil
+func secretBackendLabel(backend string) string {
+	if backend == SecretBackendFile {
+		return "encrypted secret file"
+	}
+	return "system secret store"
 }
 
-func secretStoreUnavailableRemediation() string {
+func secretStoreUnavailableRemediation(backend string, cause error) string {
+	if backend == SecretBackendFile {
+		return cause.Error()
+	}
 	switch runtime.GOOS {
 	case "darwin":
 		return "allow access to macOS Keychain for proxer-agent and retry"
 	case "linux":
-		return "install libsecret/secret-tool and ensure a Secret Service keyring session is unlocked"
+		return "install libsecret/secret-tool and ensure a Secret Service keyring session is unlocked, or switch the profile to --secret-backend file"
 	case "windows":
 		return "run under a user profile with DPAPI available and retry"
 	default:
-		return "verify operating-system keychain availability"
+		return "verify operating-system keychain availability, or switch the profile to --secret-backend file"
 	}
 }
 
@@ -2,9 +2,16 @@ package nativeagent
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -177,6 +184,109 @@ func TestServicePairProfile(t *testing.T) {
 	}
 }
 
+func TestServicePairFromLinkPicksProfileByGateway(t *testing.T) {
+	original := pairWithGatewayExchange
+	defer func() {
+		pairWithGatewayExchange = original
+	}()
+	var pairedGateway string
+	pairWithGatewayExchange = func(ctx context.Context, gatewayBaseURL, agentID, pairToken string) (protocol.PairAgentResponse, error) {
+		if pairToken != "tok" {
+			return protocol.PairAgentResponse{}, fmt.Errorf("unexpected pair token %q", pairToken)
+		}
+		pairedGateway = gatewayBaseURL
+		return protocol.PairAgentResponse{ConnectorID: "conn-1", ConnectorSecret: "conn-secret", TenantID: "tenant-a"}, nil
+	}
+
+	service, secrets := newTestService(t)
+	link := protocol.PairLink("https://gw.example.com/", "tok")
+
+	created, err := service.PairFromLink(link, "")
+	if err != nil {
+		t.Fatalf("PairFromLink() error = %v", err)
+	}
+	if created.Name != "gw.example.com" || created.GatewayBaseURL != "https://gw.example.com" || created.ConnectorID != "conn-1" {
+		t.Fatalf("expected a new profile for the gateway, got %+v", created)
+	}
+	if pairedGateway != "https://gw.example.com" || secrets.values[created.ConnectorSecretRef.Key] != "conn-secret" {
+		t.Fatalf("expected pairing against the link's gateway, got %q", pairedGateway)
+	}
+
+	if _, err := service.CreateProfile(ProfileInput{Name: "laptop", GatewayBaseURL: "http://127.0.0.1:18080"}); err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+	again, err := service.PairFromLink(link, "")
+	if err != nil {
+		t.Fatalf("PairFromLink() error = %v", err)
+	}
+	if again.ID != created.ID {
+		t.Fatalf("expected the existing profile for the gateway to be paired, got %q", again.Name)
+	}
+
+	moved, err := service.PairFromLink(link, "laptop")
+	if err != nil {
+		t.Fatalf("PairFromLink() error = %v", err)
+	}
+	if moved.Name != "laptop" || moved.GatewayBaseURL != "https://gw.example.com" {
+		t.Fatalf("expected the named profile to move to the link's gateway, got %+v", moved)
+	}
+
+	if _, err := service.PairFromLink("proxer-agent://pair?token=tok", ""); err == nil {
+		t.Fatalf("expected a link without a gateway to be rejected")
+	}
+}
+
+func TestServiceDiscoverPortsAddsTunnels(t *testing.T) {
+	t.Parallel()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><title>Vite  App</title></head></html>"))
+	}))
+	defer upstream.Close()
+	port := upstream.Listener.Addr().(*net.TCPAddr).Port
+
+	service, _ := newTestService(t)
+	discovered, err := service.DiscoverPorts(context.Background(), strconv.Itoa(port))
+	if err != nil {
+		t.Fatalf("DiscoverPorts() error = %v", err)
+	}
+	if len(discovered) != 1 || discovered[0].Title != "Vite App" || discovered[0].Status != http.StatusOK {
+		t.Fatalf("discovered = %+v, want one port titled %q", discovered, "Vite App")
+	}
+
+	created, err := service.CreateProfile(ProfileInput{
+		Name:           "dev",
+		GatewayBaseURL: "http://127.0.0.1:18080",
+		AgentID:        "agent-1",
+		Mode:           ModeLegacyTunnels,
+		LegacyTunnels:  "app3000=http://127.0.0.1:3000",
+		Runtime: RuntimeOptions{
+			RequestTimeout:       "45s",
+			PollWait:             "25s",
+			HeartbeatInterval:    "10s",
+			MaxResponseBodyBytes: 20 << 20,
+			LogLevel:             "info",
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+	updated, added, err := service.AddDiscoveredTunnels(created.ID, discovered)
+	if err != nil {
+		t.Fatalf("AddDiscoveredTunnels() error = %v", err)
+	}
+	if len(added) != 1 || added[0].ID != fmt.Sprintf("app%d", port) || added[0].Target != upstream.URL {
+		t.Fatalf("added = %+v, want tunnel for %s", added, upstream.URL)
+	}
+	if len(updated.LegacyTunnels) != 2 {
+		t.Fatalf("len(LegacyTunnels) = %d, want 2", len(updated.LegacyTunnels))
+	}
+
+	if _, added, err = service.AddDiscoveredTunnels(created.ID, discovered); err != nil || len(added) != 0 {
+		t.Fatalf("second AddDiscoveredTunnels() = %+v, %v; want nothing added", added, err)
+	}
+}
+
 func TestReadLogTailLines(t *testing.T) {
 	t.Parallel()
 	logPath := filepath.Join(t.TempDir(), "agent.log")
@@ -260,3 +370,328 @@ func TestServiceStartConnectorSecretMissingMapsToPairHint(t *testing.T) {
 		t.Fatalf("Start() error = %q, want pair profile again hint", err.Error())
 	}
 }
+
+func newTestFileSecretStore(path, passphrase string) *fileSecretStore {
+	return &fileSecretStore{
+		path:       path,
+		iterations: 1000,
+		passphrase: func(context.Context) (string, error) { return passphrase, nil },
+	}
+}
+
+func TestFileSecretStoreRoundTrip(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), secretsFileName)
+	store := newTestFileSecretStore(path, "correct horse")
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "profile-1/connector-secret", "s3cret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read secrets file: %v", err)
+	}
+	if strings.Contains(string(raw), "s3cret") {
+		t.Fatalf("secrets file contains the plaintext secret")
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0o077 != 0 {
+		t.Fatalf("secrets file mode = %v, want owner-only", info.Mode().Perm())
+	}
+
+	reopened := newTestFileSecretStore(path, "correct horse")
+	got, err := reopened.Get(ctx, "profile-1/connector-secret")
+	if err != nil || got != "s3cret" {
+		t.Fatalf("Get() = %q, %v; want s3cret", got, err)
+	}
+	if _, err := reopened.Get(ctx, "missing"); err != ErrSecretNotFound {
+		t.Fatalf("Get(missing) error = %v, want ErrSecretNotFound", err)
+	}
+
+	wrong := newTestFileSecretStore(path, "wrong")
+	if _, err := wrong.Get(ctx, "profile-1/connector-secret"); !errors.Is(err, ErrSecretUnavailable) {
+		t.Fatalf("Get() with wrong passphrase error = %v, want ErrSecretUnavailable", err)
+	}
+
+	if err := reopened.Delete(ctx, "profile-1/connector-secret"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := reopened.Get(ctx, "profile-1/connector-secret"); err != ErrSecretNotFound {
+		t.Fatalf("Get() after delete error = %v, want ErrSecretNotFound", err)
+	}
+}
+
+func TestServiceUpdateProfileMovesSecretsBetweenBackends(t *testing.T) {
+	t.Parallel()
+	service, keychain := newTestService(t)
+	fileStore := &fakeSecretStore{values: map[string]string{}}
+	service.fileSecrets = fileStore
+
+	created, err := service.CreateProfile(ProfileInput{
+		Name:            "headless",
+		GatewayBaseURL:  "http://127.0.0.1:18080",
+		AgentID:         "agent-1",
+		Mode:            ModeConnector,
+		ConnectorID:     "conn-1",
+		ConnectorSecret: "connector-secret",
+	})
+	if err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+	if created.SecretBackend != SecretBackendKeychain {
+		t.Fatalf("default secret backend = %q, want %q", created.SecretBackend, SecretBackendKeychain)
+	}
+
+	updated, err := service.UpdateProfile(created.ID, ProfileInput{SecretBackend: SecretBackendFile})
+	if err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+	if updated.SecretBackend != SecretBackendFile {
+		t.Fatalf("secret backend = %q, want %q", updated.SecretBackend, SecretBackendFile)
+	}
+	key := created.ConnectorSecretRef.Key
+	if got := fileStore.values[key]; got != "connector-secret" {
+		t.Fatalf("file backend secret = %q, want connector-secret", got)
+	}
+	if _, ok := keychain.values[key]; ok {
+		t.Fatalf("secret still present in keychain after moving backends")
+	}
+
+	if _, err := service.UpdateProfile(created.ID, ProfileInput{SecretBackend: "vault"}); err == nil {
+		t.Fatalf("UpdateProfile() with unknown backend error = nil")
+	}
+}
+
+func TestServiceExportImportProfileBundle(t *testing.T) {
+	t.Parallel()
+	source, _ := newTestService(t)
+
+	created, err := source.CreateProfile(ProfileInput{
+		Name:           "laptop",
+		GatewayBaseURL: "https://gateway.example.test",
+		AgentID:        "agent-1",
+		Mode:           ModeLegacyTunnels,
+		LegacyTunnels:  "app@tunnel-token=http://127.0.0.1:3000",
+		AgentToken:     "legacy-token",
+	})
+	if err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+
+	template, err := source.ExportProfile(created.Name, "")
+	if err != nil {
+		t.Fatalf("ExportProfile() template error = %v", err)
+	}
+	if template.Secrets != nil || template.Profile.LegacyTunnels[0].Token != "" {
+		t.Fatalf("template export carries secrets: %+v", template)
+	}
+
+	bundle, err := source.ExportProfile(created.Name, "bundle-pass")
+	if err != nil {
+		t.Fatalf("ExportProfile() error = %v", err)
+	}
+	if bundle.Profile.ID != "" || bundle.Profile.AgentTokenRef.Key != "" {
+		t.Fatalf("bundle keeps machine-specific fields: %+v", bundle.Profile)
+	}
+	if bundle.Secrets == nil || len(bundle.Secrets.Values) != 2 {
+		t.Fatalf("bundle secrets = %+v, want agent token and tunnel token", bundle.Secrets)
+	}
+
+	target, secrets := newTestService(t)
+	if _, err := target.ImportProfile(bundle, ProfileImportOptions{}); err == nil {
+		t.Fatalf("ImportProfile() without passphrase error = nil")
+	}
+	if _, err := target.ImportProfile(bundle, ProfileImportOptions{Passphrase: "wrong"}); !errors.Is(err, ErrSecretUnavailable) {
+		t.Fatalf("ImportProfile() with wrong passphrase error = %v, want ErrSecretUnavailable", err)
+	}
+	imported, err := target.ImportProfile(bundle, ProfileImportOptions{Passphrase: "bundle-pass"})
+	if err != nil {
+		t.Fatalf("ImportProfile() error = %v", err)
+	}
+	if imported.ID == "" || imported.ID == created.ID {
+		t.Fatalf("imported profile id = %q, want a fresh id", imported.ID)
+	}
+	if imported.Name != "laptop" || imported.GatewayBaseURL != "https://gateway.example.test" {
+		t.Fatalf("imported profile = %+v", imported)
+	}
+	if got := secrets.values[imported.AgentTokenRef.Key]; got != "legacy-token" {
+		t.Fatalf("imported agent token = %q, want legacy-token", got)
+	}
+	if got := imported.LegacyTunnels[0].Token; got != "tunnel-token" {
+		t.Fatalf("imported tunnel token = %q, want tunnel-token", got)
+	}
+
+	if _, err := target.ImportProfile(bundle, ProfileImportOptions{Passphrase: "bundle-pass"}); err == nil {
+		t.Fatalf("ImportProfile() with duplicate name error = nil")
+	}
+	renamed, err := target.ImportProfile(template, ProfileImportOptions{Name: "teammate"})
+	if err != nil {
+		t.Fatalf("ImportProfile() template error = %v", err)
+	}
+	if _, ok := secrets.values[renamed.AgentTokenRef.Key]; ok {
+		t.Fatalf("template import stored a secret")
+	}
+}
+
+func TestServiceStartProfilesRunsEachProfileIsolated(t *testing.T) {
+	t.Parallel()
+	service, _ := newTestService(t)
+
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer gateway.Close()
+
+	var ids []string
+	for _, name := range []string{"work", "home"} {
+		created, err := service.CreateProfile(ProfileInput{
+			Name:           name,
+			GatewayBaseURL: gateway.URL,
+			AgentID:        "agent-" + name,
+			Mode:           ModeLegacyTunnels,
+			LegacyTunnels:  "app=http://127.0.0.1:3000",
+			AgentToken:     "token-" + name,
+		})
+		if err != nil {
+			t.Fatalf("CreateProfile(%s) error = %v", name, err)
+		}
+		ids = append(ids, created.ID)
+	}
+
+	started, err := service.StartProfiles(nil)
+	if err != nil {
+		t.Fatalf("StartProfiles() error = %v", err)
+	}
+	defer service.StopProfiles()
+	if len(started) != 2 {
+		t.Fatalf("started %d profiles, want 2", len(started))
+	}
+	if _, err := service.StartProfiles([]string{"work"}); err == nil {
+		t.Fatalf("StartProfiles() for a running profile error = nil")
+	}
+
+	statuses, err := service.ProfileStatuses()
+	if err != nil {
+		t.Fatalf("ProfileStatuses() error = %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("len(statuses) = %d, want 2", len(statuses))
+	}
+	for i, status := range statuses {
+		if status.ProfileID != ids[i] || status.State == RuntimeStateStopped {
+			t.Fatalf("status[%d] = %+v, want a started runtime for %s", i, status, ids[i])
+		}
+		if _, err := os.Stat(service.ProfileStatusPath(ids[i])); err != nil {
+			t.Fatalf("per-profile status file missing: %v", err)
+		}
+	}
+	if service.ProfileLogPath(ids[0]) == service.ProfileLogPath(ids[1]) {
+		t.Fatalf("profiles share a log path")
+	}
+	if primary, _ := service.Status(); primary.ProfileID != "" {
+		t.Fatalf("primary runtime status = %+v, want untouched", primary)
+	}
+
+	if err := service.StopProfiles(); err != nil {
+		t.Fatalf("StopProfiles() error = %v", err)
+	}
+	statuses, err = service.ProfileStatuses()
+	if err != nil {
+		t.Fatalf("ProfileStatuses() after stop error = %v", err)
+	}
+	for _, status := range statuses {
+		if status.State != RuntimeStateStopped && status.State != RuntimeStateError {
+			t.Fatalf("status after stop = %q, want stopped", status.State)
+		}
+	}
+}
+
+func TestServiceCheckAndApplyUpdateFollowsProfileChannel(t *testing.T) {
+	originalVersion := version
+	defer func() {
+		version = originalVersion
+	}()
+	version = "1.2.0"
+
+	build := []byte("proxer-agent 1.3.0-beta.1")
+	sum := sha256.Sum256(build)
+	var gateway *httptest.Server
+	gateway = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/public/downloads":
+			if r.URL.Query().Get("channel") != UpdateChannelBeta {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"available":true,"channel":"stable","version":"1.2.0","min_agent_version":"1.1.0"}`))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"available":true,"channel":"beta","version":"1.3.0-beta.1","prerelease":true,"min_agent_version":"1.3.0-beta.1","recommended":{"file_name":"proxer-agent.bin","url":%q,"sha256":%q}}`,
+				gateway.URL+"/files/proxer-agent.bin", hex.EncodeToString(sum[:]))
+		case "/files/proxer-agent.bin":
+			_, _ = w.Write(build)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer gateway.Close()
+
+	service, _ := newTestService(t)
+	if result, err := service.CheckForUpdates(""); err != nil || result.UpdateAvailable {
+		t.Fatalf("CheckForUpdates() without profiles = %+v, %v; want a message only", result, err)
+	}
+	created, err := service.CreateProfile(ProfileInput{
+		Name:           "dev",
+		GatewayBaseURL: gateway.URL,
+		AgentID:        "agent-1",
+		Mode:           ModeConnector,
+		Runtime: RuntimeOptions{
+			RequestTimeout:       "45s",
+			PollWait:             "25s",
+			HeartbeatInterval:    "10s",
+			MaxResponseBodyBytes: 20 << 20,
+			LogLevel:             "info",
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+	if created.UpdateChannel != UpdateChannelStable {
+		t.Fatalf("UpdateChannel = %q, want %q", created.UpdateChannel, UpdateChannelStable)
+	}
+
+	stable, err := service.CheckForUpdates(created.ID)
+	if err != nil {
+		t.Fatalf("CheckForUpdates(stable) error = %v", err)
+	}
+	if stable.UpdateAvailable || stable.Required || stable.Channel != UpdateChannelStable {
+		t.Fatalf("stable check = %+v, want up to date", stable)
+	}
+
+	if _, err := service.UpdateProfile(created.ID, ProfileInput{UpdateChannel: "nightly"}); err == nil {
+		t.Fatalf("UpdateProfile() accepted an unknown update channel")
+	}
+	if _, err := service.UpdateProfile(created.ID, ProfileInput{UpdateChannel: UpdateChannelBeta}); err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+	beta, err := service.CheckForUpdates(created.ID)
+	if err != nil {
+		t.Fatalf("CheckForUpdates(beta) error = %v", err)
+	}
+	if !beta.UpdateAvailable || !beta.Required || beta.LatestVersion != "1.3.0-beta.1" {
+		t.Fatalf("beta check = %+v, want a required 1.3.0-beta.1 update", beta)
+	}
+
+	applied, err := service.ApplyUpdate(created.ID)
+	if err != nil {
+		t.Fatalf("ApplyUpdate() error = %v", err)
+	}
+	data, err := os.ReadFile(applied.Path)
+	if err != nil || string(data) != string(build) {
+		t.Fatalf("downloaded update = %q, %v; want the verified build", data, err)
+	}
+
+	build = []byte("tampered")
+	if _, err := service.ApplyUpdate(created.ID); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("ApplyUpdate() with a bad checksum error = %v, want checksum mismatch", err)
+	}
+}
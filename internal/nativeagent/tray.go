@@ -0,0 +1,93 @@
+package nativeagent
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+// TrayState is what the tray menu shows for the current runtime. It is built
+// the same way on every platform so the macOS menu-bar item and the Windows
+// and Linux tray icons stay in step.
+type TrayState struct {
+	StatusLabel  string    `json:"status_label"`
+	ProfileLabel string    `json:"profile_label"`
+	Tooltip      string    `json:"tooltip"`
+	URLs         []TrayURL `json:"urls,omitempty"`
+	CanStart     bool      `json:"can_start"`
+	CanStop      bool      `json:"can_stop"`
+}
+
+type TrayURL struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+func buildTrayState(status NativeStatusSnapshot, activeProfileName string) TrayState {
+	state := strings.TrimSpace(status.State)
+	if state == "" {
+		state = RuntimeStateStopped
+	}
+	profileName := strings.TrimSpace(status.ProfileName)
+	if profileName == "" || state == RuntimeStateStopped {
+		profileName = strings.TrimSpace(activeProfileName)
+	}
+	if profileName == "" {
+		profileName = "none"
+	}
+
+	tray := TrayState{
+		StatusLabel:  "Status: " + state,
+		ProfileLabel: "Profile: " + profileName,
+		Tooltip:      fmt.Sprintf("Proxer Agent - %s (%s)", state, profileName),
+	}
+	switch state {
+	case RuntimeStateStopped, RuntimeStateError:
+		tray.CanStart = true
+	default:
+		tray.CanStop = true
+	}
+	if state != RuntimeStateStopped {
+		for _, route := range status.Routes {
+			if strings.TrimSpace(route.PublicURL) == "" {
+				continue
+			}
+			tray.URLs = append(tray.URLs, TrayURL{
+				Label: fmt.Sprintf("Copy %s: %s", route.ID, route.PublicURL),
+				URL:   route.PublicURL,
+			})
+		}
+	}
+	return tray
+}
+
+func isPairLink(pasted string) bool {
+	_, _, err := protocol.ParsePairLink(pasted)
+	return err == nil
+}
+
+func extractPairToken(pasted string) string {
+	pasted = strings.TrimSpace(pasted)
+	if parsed, err := url.Parse(pasted); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+		for _, key := range []string{"pair_token", "token"} {
+			if value := strings.TrimSpace(parsed.Query().Get(key)); value != "" {
+				return value
+			}
+		}
+	}
+	fields := strings.Fields(pasted)
+	for i, field := range fields {
+		if value, ok := strings.CutPrefix(field, "--token="); ok {
+			return strings.Trim(value, `"'`)
+		}
+		if field == "--token" && i+1 < len(fields) {
+			return strings.Trim(fields[i+1], `"'`)
+		}
+	}
+	if len(fields) == 1 {
+		return strings.Trim(fields[0], `"'`)
+	}
+	return ""
+}
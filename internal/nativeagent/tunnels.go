@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/szaher/try/proxer/internal/agent"
 	"github.com/szaher/try/proxer/internal/protocol"
 )
 
@@ -47,6 +48,9 @@ func parseTunnelMappings(raw string) ([]protocol.TunnelConfig, error) {
 		if _, err := url.ParseRequestURI(rhs); err != nil {
 			return nil, fmt.Errorf("invalid target URL for tunnel %q: %w", id, err)
 		}
+		if err := agent.CheckFileTunnelTarget(rhs); err != nil {
+			return nil, fmt.Errorf("invalid target URL for tunnel %q: %w", id, err)
+		}
 		seen[id] = struct{}{}
 		tunnels = append(tunnels, protocol.TunnelConfig{ID: id, Target: rhs, Token: token})
 	}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/szaher/try/proxer/internal/agent"
 	"github.com/szaher/try/proxer/internal/protocol"
 )
 
@@ -16,6 +17,9 @@ const (
 
 	LaunchModeTrayWindow = "tray_window"
 
+	UpdateChannelStable = "stable"
+	UpdateChannelBeta   = "beta"
+
 	RuntimeStateStopped  = "stopped"
 	RuntimeStateStarting = "starting"
 	RuntimeStateRunning  = "running"
@@ -44,6 +48,8 @@ type AgentProfile struct {
 	ConnectorID        string                  `json:"connector_id,omitempty"`
 	ConnectorSecretRef SecretRef               `json:"connector_secret_ref,omitempty"`
 	AgentTokenRef      SecretRef               `json:"agent_token_ref,omitempty"`
+	SecretBackend      string                  `json:"secret_backend,omitempty"`
+	UpdateChannel      string                  `json:"update_channel,omitempty"`
 	Runtime            RuntimeOptions          `json:"runtime"`
 	LegacyTunnels      []protocol.TunnelConfig `json:"legacy_tunnels,omitempty"`
 	CreatedAt          time.Time               `json:"created_at"`
@@ -55,6 +61,7 @@ type RuntimeOptions struct {
 	PollWait             string `json:"poll_wait"`
 	HeartbeatInterval    string `json:"heartbeat_interval"`
 	MaxResponseBodyBytes int64  `json:"max_response_body_bytes"`
+	MaxBytesPerSecond    int64  `json:"max_bytes_per_second,omitempty"`
 	ProxyURL             string `json:"proxy_url,omitempty"`
 	NoProxy              string `json:"no_proxy,omitempty"`
 	TLSSkipVerify        bool   `json:"tls_skip_verify"`
@@ -67,24 +74,42 @@ type SecretRef struct {
 }
 
 type NativeStatusSnapshot struct {
-	State       string     `json:"state"`
-	Message     string     `json:"message,omitempty"`
-	Error       string     `json:"error,omitempty"`
-	ProfileID   string     `json:"profile_id,omitempty"`
-	ProfileName string     `json:"profile_name,omitempty"`
-	AgentID     string     `json:"agent_id,omitempty"`
-	SessionID   string     `json:"session_id,omitempty"`
-	Mode        string     `json:"mode,omitempty"`
-	PID         int        `json:"pid"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	StartedAt   *time.Time `json:"started_at,omitempty"`
+	State       string                 `json:"state"`
+	Message     string                 `json:"message,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	ProfileID   string                 `json:"profile_id,omitempty"`
+	ProfileName string                 `json:"profile_name,omitempty"`
+	AgentID     string                 `json:"agent_id,omitempty"`
+	SessionID   string                 `json:"session_id,omitempty"`
+	Mode        string                 `json:"mode,omitempty"`
+	Routes      []protocol.TunnelRoute `json:"routes,omitempty"`
+	Connection  *agent.ConnectionStats `json:"connection,omitempty"`
+	PID         int                    `json:"pid"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	StartedAt   *time.Time             `json:"started_at,omitempty"`
 }
 
 type UpdateCheckResult struct {
-	CurrentVersion string `json:"current_version"`
-	LatestVersion  string `json:"latest_version,omitempty"`
-	DownloadURL    string `json:"download_url,omitempty"`
-	Message        string `json:"message"`
+	CurrentVersion  string `json:"current_version"`
+	ProfileID       string `json:"profile_id,omitempty"`
+	Channel         string `json:"channel,omitempty"`
+	LatestVersion   string `json:"latest_version,omitempty"`
+	Prerelease      bool   `json:"prerelease,omitempty"`
+	UpdateAvailable bool   `json:"update_available"`
+	Required        bool   `json:"required,omitempty"`
+	MinVersion      string `json:"min_version,omitempty"`
+	ReleaseURL      string `json:"release_url,omitempty"`
+	DownloadURL     string `json:"download_url,omitempty"`
+	FileName        string `json:"file_name,omitempty"`
+	SHA256          string `json:"sha256,omitempty"`
+	Message         string `json:"message"`
+}
+
+type UpdateApplyResult struct {
+	Check     UpdateCheckResult `json:"check"`
+	Path      string            `json:"path,omitempty"`
+	Installed bool              `json:"installed"`
+	Message   string            `json:"message"`
 }
 
 func defaultSettings() AgentSettings {
@@ -130,9 +155,20 @@ func applyProfileDefaults(p AgentProfile) AgentProfile {
 	if p.Runtime.MaxResponseBodyBytes <= 0 {
 		p.Runtime.MaxResponseBodyBytes = 20 << 20
 	}
+	if p.Runtime.MaxBytesPerSecond < 0 {
+		p.Runtime.MaxBytesPerSecond = 0
+	}
 	if strings.TrimSpace(p.Runtime.LogLevel) == "" {
 		p.Runtime.LogLevel = "info"
 	}
+	p.SecretBackend = strings.ToLower(strings.TrimSpace(p.SecretBackend))
+	if p.SecretBackend == "" {
+		p.SecretBackend = SecretBackendKeychain
+	}
+	p.UpdateChannel = strings.ToLower(strings.TrimSpace(p.UpdateChannel))
+	if p.UpdateChannel == "" {
+		p.UpdateChannel = UpdateChannelStable
+	}
 	if strings.TrimSpace(p.ID) != "" && strings.TrimSpace(p.ConnectorSecretRef.Key) == "" {
 		p.ConnectorSecretRef = SecretRef{Key: secretKeyForProfile(p.ID, "connector_secret")}
 	}
@@ -156,12 +192,21 @@ func validateProfile(p AgentProfile) error {
 	if mode != ModeConnector && mode != ModeLegacyTunnels {
 		return fmt.Errorf("mode must be %q or %q", ModeConnector, ModeLegacyTunnels)
 	}
+	if err := validateSecretBackend(p.SecretBackend); err != nil {
+		return err
+	}
+	if p.UpdateChannel != UpdateChannelStable && p.UpdateChannel != UpdateChannelBeta {
+		return fmt.Errorf("update_channel must be %q or %q", UpdateChannelStable, UpdateChannelBeta)
+	}
 	if mode == ModeLegacyTunnels && len(p.LegacyTunnels) == 0 {
 		return fmt.Errorf("legacy_tunnels mode requires at least one tunnel")
 	}
 	if p.Runtime.MaxResponseBodyBytes <= 0 {
 		return fmt.Errorf("max_response_body_bytes must be > 0")
 	}
+	if err := agent.ValidateBandwidthLimit(p.Runtime.MaxBytesPerSecond); err != nil {
+		return fmt.Errorf("max_bytes_per_second %w", err)
+	}
 	if _, err := time.ParseDuration(p.Runtime.RequestTimeout); err != nil {
 		return fmt.Errorf("invalid request_timeout: %w", err)
 	}
@@ -0,0 +1,236 @@
+package nativeagent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+const (
+	updatesDirName         = "updates"
+	maxUpdateDownloadBytes = 1 << 30
+)
+
+type releaseDownloads struct {
+	Available       bool   `json:"available"`
+	Channel         string `json:"channel"`
+	Tag             string `json:"tag"`
+	Version         string `json:"version"`
+	Prerelease      bool   `json:"prerelease"`
+	ReleaseURL      string `json:"release_url"`
+	Message         string `json:"message"`
+	MinAgentVersion string `json:"min_agent_version"`
+	Recommended     *struct {
+		FileName  string `json:"file_name"`
+		URL       string `json:"url"`
+		SizeBytes int64  `json:"size_bytes"`
+		SHA256    string `json:"sha256"`
+	} `json:"recommended"`
+}
+
+var fetchReleaseDownloads = func(ctx context.Context, gatewayBaseURL, channel string) (releaseDownloads, error) {
+	query := url.Values{}
+	query.Set("channel", channel)
+	query.Set("platform", releasePlatform(runtime.GOOS))
+	query.Set("arch", runtime.GOARCH)
+	requestCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	request, err := http.NewRequestWithContext(requestCtx, http.MethodGet, strings.TrimRight(gatewayBaseURL, "/")+"/api/public/downloads?"+query.Encode(), nil)
+	if err != nil {
+		return releaseDownloads{}, err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return releaseDownloads{}, fmt.Errorf("contact gateway: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return releaseDownloads{}, fmt.Errorf("gateway answered %s", response.Status)
+	}
+	var payload releaseDownloads
+	if err := json.NewDecoder(response.Body).Decode(&payload); err != nil {
+		return releaseDownloads{}, fmt.Errorf("decode downloads: %w", err)
+	}
+	return payload, nil
+}
+
+func releasePlatform(goos string) string {
+	if goos == "darwin" {
+		return "macos"
+	}
+	return goos
+}
+
+func (s *Service) CheckForUpdates(idOrName string) (UpdateCheckResult, error) {
+	result := UpdateCheckResult{CurrentVersion: BuildVersion()}
+	profile, err := s.ResolveProfile(idOrName)
+	if err != nil {
+		if strings.TrimSpace(idOrName) != "" {
+			return UpdateCheckResult{}, err
+		}
+		result.Message = "Create a profile to check its gateway for updates."
+		return result, nil
+	}
+	result.ProfileID = profile.ID
+	result.Channel = profile.UpdateChannel
+
+	release, err := fetchReleaseDownloads(context.Background(), profile.GatewayBaseURL, profile.UpdateChannel)
+	if err != nil {
+		return UpdateCheckResult{}, fmt.Errorf("check for updates: %w", err)
+	}
+	result.LatestVersion = release.Version
+	result.Prerelease = release.Prerelease
+	result.ReleaseURL = release.ReleaseURL
+	result.MinVersion = release.MinAgentVersion
+	if release.Recommended != nil {
+		result.DownloadURL = release.Recommended.URL
+		result.FileName = release.Recommended.FileName
+		result.SHA256 = release.Recommended.SHA256
+	}
+	if minimum := release.MinAgentVersion; minimum != "" {
+		if cmp, ok := protocol.CompareVersions(result.CurrentVersion, minimum); ok && cmp < 0 {
+			result.Required = true
+		}
+	}
+
+	if !release.Available || release.Version == "" {
+		result.Message = strings.TrimSpace(release.Message)
+		if result.Message == "" {
+			result.Message = fmt.Sprintf("No %s release is published on this gateway.", profile.UpdateChannel)
+		}
+		return result, nil
+	}
+	cmp, ok := protocol.CompareVersions(result.CurrentVersion, release.Version)
+	switch {
+	case !ok:
+		result.Message = fmt.Sprintf("Development build; the latest %s release is %s.", profile.UpdateChannel, release.Version)
+	case cmp < 0:
+		result.UpdateAvailable = true
+		result.Message = fmt.Sprintf("Version %s is available on the %s channel.", release.Version, profile.UpdateChannel)
+	default:
+		result.Message = fmt.Sprintf("Up to date on the %s channel.", profile.UpdateChannel)
+	}
+	if result.Required {
+		result.Message += fmt.Sprintf(" This gateway requires agent %s or newer.", release.MinAgentVersion)
+	}
+	if result.UpdateAvailable && result.DownloadURL == "" {
+		result.Message += fmt.Sprintf(" No build for %s/%s is published.", releasePlatform(runtime.GOOS), runtime.GOARCH)
+	}
+	return result, nil
+}
+
+// ApplyUpdate downloads the build CheckForUpdates found into the updates
+// directory and verifies its SHA-256 sum. A Linux AppImage started from
+// $APPIMAGE is replaced in place and runs after a restart; other builds
+// are left for the user to install.
+func (s *Service) ApplyUpdate(idOrName string) (UpdateApplyResult, error) {
+	check, err := s.CheckForUpdates(idOrName)
+	if err != nil {
+		return UpdateApplyResult{}, err
+	}
+	result := UpdateApplyResult{Check: check}
+	if !check.UpdateAvailable {
+		result.Message = check.Message
+		return result, nil
+	}
+	if check.DownloadURL == "" || check.FileName == "" {
+		return UpdateApplyResult{}, fmt.Errorf("no %s/%s build of %s is published", releasePlatform(runtime.GOOS), runtime.GOARCH, check.LatestVersion)
+	}
+	if check.SHA256 == "" {
+		return UpdateApplyResult{}, fmt.Errorf("refusing to install %s: the release publishes no checksum for it", check.FileName)
+	}
+
+	dir := filepath.Join(filepath.Dir(s.store.path), updatesDirName)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return UpdateApplyResult{}, fmt.Errorf("create updates directory: %w", err)
+	}
+	target := filepath.Join(dir, filepath.Base(check.FileName))
+	if err := downloadVerified(check.DownloadURL, check.SHA256, target); err != nil {
+		return UpdateApplyResult{}, err
+	}
+	result.Path = target
+
+	if appImage := strings.TrimSpace(os.Getenv("APPIMAGE")); runtime.GOOS == "linux" && appImage != "" && strings.HasSuffix(strings.ToLower(target), ".appimage") {
+		if err := replaceFile(target, appImage); err != nil {
+			return UpdateApplyResult{}, fmt.Errorf("install update: %w", err)
+		}
+		result.Path = appImage
+		result.Installed = true
+		result.Message = fmt.Sprintf("Installed %s; restart proxer-agent to use it.", check.LatestVersion)
+		return result, nil
+	}
+	result.Message = fmt.Sprintf("Downloaded and verified %s; run %s to install it.", check.LatestVersion, target)
+	return result, nil
+}
+
+func downloadVerified(rawURL, wantSHA256, target string) error {
+	response, err := http.Get(rawURL)
+	if err != nil {
+		return fmt.Errorf("download update: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("download update: unexpected status %s", response.Status)
+	}
+
+	partial := target + ".partial"
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755)
+	if err != nil {
+		return fmt.Errorf("download update: %w", err)
+	}
+	hash := sha256.New()
+	written, copyErr := io.Copy(io.MultiWriter(file, hash), io.LimitReader(response.Body, maxUpdateDownloadBytes+1))
+	closeErr := file.Close()
+	if copyErr == nil && written > maxUpdateDownloadBytes {
+		copyErr = fmt.Errorf("larger than %d bytes", maxUpdateDownloadBytes)
+	}
+	if copyErr == nil {
+		copyErr = closeErr
+	}
+	if copyErr != nil {
+		_ = os.Remove(partial)
+		return fmt.Errorf("download update: %w", copyErr)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(got, wantSHA256) {
+		_ = os.Remove(partial)
+		return fmt.Errorf("download update: checksum mismatch (got %s, want %s)", got, wantSHA256)
+	}
+	return os.Rename(partial, target)
+}
+
+// replaceFile copies source over target through a sibling temporary file so
+// target is never left half written.
+func replaceFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	staged := target + ".new"
+	out, err := os.OpenFile(staged, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		_ = os.Remove(staged)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(staged)
+		return err
+	}
+	return os.Rename(staged, target)
+}
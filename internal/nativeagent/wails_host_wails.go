@@ -4,15 +4,21 @@ package nativeagent
 
 import (
 	"context"
-	"fmt"
+	_ "embed"
 	"net"
 	"net/http"
-	"strings"
+	"runtime"
 	"time"
 
 	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
+//go:embed icons/tray.png
+var trayIcon []byte
+
+//go:embed icons/tray-template.png
+var trayTemplateIcon []byte
+
 func runWailsHost(ctx context.Context) error {
 	if ctx == nil {
 		ctx = context.Background()
@@ -62,10 +68,10 @@ func runWailsHost(ctx context.Context) error {
 	window.SetURL(uiURL)
 	window.Center()
 
-	trayStatusItem := configureWailsMenuAndTray(app, window, bindings)
+	tray := configureWailsMenuAndTray(app, window, bindings)
 	streamCtx, cancelStream := context.WithCancel(context.Background())
 	defer cancelStream()
-	bridgeWailsRuntimeEvents(app, bindings, streamCtx, trayStatusItem)
+	bridgeWailsRuntimeEvents(app, bindings, streamCtx, tray)
 
 	go func() {
 		<-ctx.Done()
@@ -94,7 +100,7 @@ func startWailsLocalServer(bindings *DesktopBindings) (net.Listener, *http.Serve
 	return listener, server, url, nil
 }
 
-func configureWailsMenuAndTray(app *application.App, window *application.WebviewWindow, bindings *DesktopBindings) *application.MenuItem {
+func configureWailsMenuAndTray(app *application.App, window *application.WebviewWindow, bindings *DesktopBindings) *wailsTray {
 	appMenu := app.NewMenu()
 	proxerMenu := appMenu.AddSubmenu("Proxer")
 	proxerMenu.Add("Open Window").OnClick(func(ctx *application.Context) {
@@ -106,20 +112,10 @@ func configureWailsMenuAndTray(app *application.App, window *application.Webview
 	})
 	proxerMenu.AddSeparator()
 	proxerMenu.Add("Start Agent").OnClick(func(ctx *application.Context) {
-		if err := bindings.StartAgent(""); err != nil {
-			app.Event.Emit("nativeagent.error", map[string]any{"error": err.Error()})
-			return
-		}
-		status, _ := bindings.GetRuntimeStatus()
-		app.Event.Emit("nativeagent.runtime", status)
+		emitRuntimeAction(app, bindings, func() error { return bindings.StartAgent("") })
 	})
 	proxerMenu.Add("Stop Agent").OnClick(func(ctx *application.Context) {
-		if err := bindings.StopAgent(); err != nil {
-			app.Event.Emit("nativeagent.error", map[string]any{"error": err.Error()})
-			return
-		}
-		status, _ := bindings.GetRuntimeStatus()
-		app.Event.Emit("nativeagent.runtime", status)
+		emitRuntimeAction(app, bindings, bindings.StopAgent)
 	})
 	proxerMenu.AddSeparator()
 	proxerMenu.Add("Quit").OnClick(func(ctx *application.Context) {
@@ -127,10 +123,19 @@ func configureWailsMenuAndTray(app *application.App, window *application.Webview
 	})
 	app.Menu.Set(appMenu)
 
-	tray := app.SystemTray.New()
-	tray.SetLabel("Proxer")
-	tray.SetTooltip("Proxer Agent")
-	tray.OnClick(func() {
+	tray := &wailsTray{app: app, window: window, bindings: bindings, tray: app.SystemTray.New()}
+	// macOS draws a monochrome template image in the menu bar; Windows and
+	// Linux tray areas need a colored icon and ignore the label.
+	if runtime.GOOS == "darwin" {
+		tray.tray.SetTemplateIcon(trayTemplateIcon)
+		tray.tray.SetLabel("Proxer")
+	} else {
+		tray.tray.SetIcon(trayIcon)
+	}
+	// Left click toggles the window where the platform reports it (macOS,
+	// Windows); Linux desktops usually only open the menu, which also has
+	// Open Window.
+	tray.tray.OnClick(func() {
 		if window.IsVisible() {
 			window.Hide()
 			return
@@ -138,47 +143,103 @@ func configureWailsMenuAndTray(app *application.App, window *application.Webview
 		window.Show()
 		window.Focus()
 	})
+	tray.refresh()
 
-	trayMenu := app.NewMenu()
-	statusItem := trayMenu.Add("Status: stopped")
-	statusItem.SetEnabled(false)
-	trayMenu.AddSeparator()
-	trayMenu.Add("Open Window").OnClick(func(ctx *application.Context) {
-		window.Show()
-		window.Focus()
+	if settings, err := bindings.GetAppSettings(); err == nil {
+		if settings.StartAtLogin {
+			app.Event.Emit("nativeagent.info", map[string]any{"message": "Start-at-login is enabled"})
+		}
+	}
+
+	return tray
+}
+
+// wailsTray owns the tray icon and rebuilds its menu from TrayState whenever
+// the runtime changes, so the public URL list stays current.
+type wailsTray struct {
+	app      *application.App
+	window   *application.WebviewWindow
+	bindings *DesktopBindings
+	tray     *application.SystemTray
+}
+
+func (t *wailsTray) refresh() {
+	state, err := t.bindings.GetTrayState()
+	if err != nil {
+		state = buildTrayState(NativeStatusSnapshot{State: RuntimeStateError}, "")
+	}
+	application.InvokeAsync(func() {
+		t.render(state)
 	})
-	trayMenu.Add("Start Agent").OnClick(func(ctx *application.Context) {
-		if err := bindings.StartAgent(""); err != nil {
-			app.Event.Emit("nativeagent.error", map[string]any{"error": err.Error()})
+}
+
+func (t *wailsTray) render(state TrayState) {
+	menu := t.app.NewMenu()
+	menu.Add(state.StatusLabel).SetEnabled(false)
+	menu.Add(state.ProfileLabel).SetEnabled(false)
+	menu.AddSeparator()
+
+	urls := menu.AddSubmenu("Public URLs")
+	if len(state.URLs) == 0 {
+		urls.Add("No public URLs yet").SetEnabled(false)
+	}
+	for _, publicURL := range state.URLs {
+		publicURL := publicURL
+		urls.Add(publicURL.Label).OnClick(func(ctx *application.Context) {
+			if !t.app.Clipboard.SetText(publicURL.URL) {
+				t.app.Event.Emit("nativeagent.error", map[string]any{"error": "could not copy URL to the clipboard"})
+				return
+			}
+			t.app.Event.Emit("nativeagent.info", map[string]any{"message": "Copied " + publicURL.URL})
+		})
+	}
+	menu.AddSeparator()
+
+	menu.Add("Start Agent").SetEnabled(state.CanStart).OnClick(func(ctx *application.Context) {
+		emitRuntimeAction(t.app, t.bindings, func() error { return t.bindings.StartAgent("") })
+		t.refresh()
+	})
+	menu.Add("Stop Agent").SetEnabled(state.CanStop).OnClick(func(ctx *application.Context) {
+		emitRuntimeAction(t.app, t.bindings, t.bindings.StopAgent)
+		t.refresh()
+	})
+	menu.Add("Pair from Clipboard").OnClick(func(ctx *application.Context) {
+		pasted, ok := t.app.Clipboard.Text()
+		if !ok {
+			t.app.Event.Emit("nativeagent.error", map[string]any{"error": "clipboard is empty"})
 			return
 		}
-		status, _ := bindings.GetRuntimeStatus()
-		app.Event.Emit("nativeagent.runtime", status)
-	})
-	trayMenu.Add("Stop Agent").OnClick(func(ctx *application.Context) {
-		if err := bindings.StopAgent(); err != nil {
-			app.Event.Emit("nativeagent.error", map[string]any{"error": err.Error()})
+		profile, err := t.bindings.PairActiveProfile(pasted)
+		if err != nil {
+			t.app.Event.Emit("nativeagent.error", map[string]any{"error": err.Error()})
 			return
 		}
-		status, _ := bindings.GetRuntimeStatus()
-		app.Event.Emit("nativeagent.runtime", status)
+		t.app.Event.Emit("nativeagent.info", map[string]any{"message": "Paired profile " + profile.Name})
+		t.refresh()
 	})
-	trayMenu.AddSeparator()
-	trayMenu.Add("Quit").OnClick(func(ctx *application.Context) {
-		app.Quit()
+	menu.AddSeparator()
+	menu.Add("Open Window").OnClick(func(ctx *application.Context) {
+		t.window.Show()
+		t.window.Focus()
+	})
+	menu.Add("Quit").OnClick(func(ctx *application.Context) {
+		t.app.Quit()
 	})
-	tray.SetMenu(trayMenu)
 
-	if settings, err := bindings.GetAppSettings(); err == nil {
-		if settings.StartAtLogin {
-			app.Event.Emit("nativeagent.info", map[string]any{"message": "Start-at-login is enabled"})
-		}
-	}
+	t.tray.SetTooltip(state.Tooltip)
+	t.tray.SetMenu(menu)
+}
 
-	return statusItem
+func emitRuntimeAction(app *application.App, bindings *DesktopBindings, action func() error) {
+	if err := action(); err != nil {
+		app.Event.Emit("nativeagent.error", map[string]any{"error": err.Error()})
+		return
+	}
+	status, _ := bindings.GetRuntimeStatus()
+	app.Event.Emit("nativeagent.runtime", status)
 }
 
-func bridgeWailsRuntimeEvents(app *application.App, bindings *DesktopBindings, ctx context.Context, trayStatusItem *application.MenuItem) {
+func bridgeWailsRuntimeEvents(app *application.App, bindings *DesktopBindings, ctx context.Context, tray *wailsTray) {
 	events, err := bindings.service.SubscribeRuntimeEvents(ctx)
 	if err != nil {
 		app.Event.Emit("nativeagent.error", map[string]any{"error": err.Error()})
@@ -194,12 +255,8 @@ func bridgeWailsRuntimeEvents(app *application.App, bindings *DesktopBindings, c
 				if !ok {
 					return
 				}
-				if trayStatusItem != nil {
-					state := strings.TrimSpace(event.State)
-					if state == "" {
-						state = RuntimeStateStopped
-					}
-					trayStatusItem.SetLabel(fmt.Sprintf("Status: %s", state))
+				if tray != nil {
+					tray.refresh()
 				}
 				app.Event.Emit("nativeagent.runtime", event)
 			}
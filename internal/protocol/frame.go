@@ -0,0 +1,132 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// EncodingJSON carries pulled requests and submitted responses as JSON,
+	// with bodies base64 encoded. Every gateway and agent speaks it.
+	EncodingJSON  = "json"
+	EncodingFrame = "frame"
+)
+
+const FrameContentType = "application/vnd.proxer.frame"
+
+const maxFrameHeaderBytes = 16 << 20
+
+// A frame is a big-endian uint32 length and the message as JSON without its
+// body, then a uint32 length and the raw body bytes.
+
+func WritePullFrame(w io.Writer, response PullResponse) error {
+	if response.Request == nil {
+		return writeFrame(w, response, nil)
+	}
+	request := *response.Request
+	body := request.Body
+	request.Body = nil
+	return writeFrame(w, PullResponse{Request: &request}, body)
+}
+
+func ReadPullFrame(r io.Reader) (PullResponse, error) {
+	var response PullResponse
+	body, err := readFrame(r, &response)
+	if err != nil {
+		return PullResponse{}, err
+	}
+	if response.Request != nil && len(body) > 0 {
+		response.Request.Body = body
+	}
+	return response, nil
+}
+
+func WriteSubmitResponseFrame(w io.Writer, request SubmitResponseRequest) error {
+	if request.Response == nil {
+		return writeFrame(w, request, nil)
+	}
+	response := *request.Response
+	body := response.Body
+	response.Body = nil
+	return writeFrame(w, SubmitResponseRequest{SessionID: request.SessionID, Response: &response}, body)
+}
+
+func ReadSubmitResponseFrame(r io.Reader) (SubmitResponseRequest, error) {
+	var request SubmitResponseRequest
+	body, err := readFrame(r, &request)
+	if err != nil {
+		return SubmitResponseRequest{}, err
+	}
+	if request.Response != nil && len(body) > 0 {
+		request.Response.Body = body
+	}
+	return request, nil
+}
+
+func writeFrame(w io.Writer, header any, body []byte) error {
+	encoded, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("encode frame header: %w", err)
+	}
+	if len(encoded) > maxFrameHeaderBytes {
+		return errors.New("frame header too large")
+	}
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(encoded)))
+	if _, err := w.Write(length[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(encoded); err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(length[:], uint32(len(body)))
+	if _, err := w.Write(length[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+func readFrame(r io.Reader, header any) ([]byte, error) {
+	encoded, err := readFrameSection(r, maxFrameHeaderBytes)
+	if err != nil {
+		return nil, fmt.Errorf("read frame header: %w", err)
+	}
+	if err := json.Unmarshal(encoded, header); err != nil {
+		return nil, fmt.Errorf("decode frame header: %w", err)
+	}
+	body, err := readFrameSection(r, -1)
+	if err != nil {
+		return nil, fmt.Errorf("read frame body: %w", err)
+	}
+	return body, nil
+}
+
+// readFrameSection reads one length-prefixed section; a negative limit leaves
+// it to the reader, such as an http.MaxBytesReader, to bound its size.
+func readFrameSection(r io.Reader, limit int64) ([]byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+	size := int64(binary.BigEndian.Uint32(length[:]))
+	if limit >= 0 && size > limit {
+		return nil, fmt.Errorf("section of %d bytes exceeds %d", size, limit)
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	// Reads through a LimitReader rather than allocating size up front, so a
+	// bogus length cannot force a huge allocation.
+	data, err := io.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
@@ -1,30 +1,69 @@
 package protocol
 
+import "time"
+
 type TunnelConfig struct {
-	ID     string `json:"id"`
-	Target string `json:"target"`
-	Token  string `json:"token,omitempty"`
+	ID     string    `json:"id"`
+	Target string    `json:"target"`
+	Token  string    `json:"token,omitempty"`
+	TLS    *LocalTLS `json:"tls,omitempty"`
 }
 
 type TunnelRoute struct {
-	ID        string `json:"id"`
-	PublicURL string `json:"public_url"`
+	ID        string     `json:"id"`
+	PublicURL string     `json:"public_url"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
+// ProtocolVersion is the agent protocol version of this build. Agents and
+// gateways that predate versioning send none and are version 1.
+const ProtocolVersion = 2
+
+const (
+	CapabilityTCPStream = "tcp_stream"
+	CapabilityRetry     = "retry"
+	CapabilityCancel    = "cancel"
+	CapabilityUpload    = "upload"
+)
+
+var Capabilities = []string{CapabilityTCPStream, CapabilityRetry, CapabilityCancel, CapabilityUpload}
+
+// LegacyCapabilities are assumed for version 1 agents, which shipped with
+// them before capabilities were negotiated.
+var LegacyCapabilities = []string{CapabilityTCPStream, CapabilityRetry}
+
 type RegisterRequest struct {
 	AgentID         string         `json:"agent_id"`
 	Token           string         `json:"token,omitempty"`
 	Tunnels         []TunnelConfig `json:"tunnels,omitempty"`
 	ConnectorID     string         `json:"connector_id,omitempty"`
 	ConnectorSecret string         `json:"connector_secret,omitempty"`
+	ProtocolVersion int            `json:"protocol_version,omitempty"`
+	Capabilities    []string       `json:"capabilities,omitempty"`
+	Encodings       []string       `json:"encodings,omitempty"`
+	AgentVersion    string         `json:"agent_version,omitempty"`
+}
+
+// ResumeRequest asks the gateway to restore SessionID, typically after it
+// restarted, using the same credentials as a RegisterRequest. A gateway that
+// cannot resume it answers 409 session_not_resumable and the agent registers
+// again.
+type ResumeRequest struct {
+	SessionID string `json:"session_id"`
+	RegisterRequest
 }
 
 type RegisterResponse struct {
-	Accepted      bool          `json:"accepted"`
-	Message       string        `json:"message,omitempty"`
-	SessionID     string        `json:"session_id,omitempty"`
-	PublicBaseURL string        `json:"public_base_url,omitempty"`
-	Tunnels       []TunnelRoute `json:"tunnels,omitempty"`
+	Accepted          bool          `json:"accepted"`
+	Message           string        `json:"message,omitempty"`
+	SessionID         string        `json:"session_id,omitempty"`
+	PublicBaseURL     string        `json:"public_base_url,omitempty"`
+	Tunnels           []TunnelRoute `json:"tunnels,omitempty"`
+	Encoding          string        `json:"encoding,omitempty"`
+	ProtocolVersion   int           `json:"protocol_version,omitempty"`
+	Capabilities      []string      `json:"capabilities,omitempty"`
+	SecretExpiresAt   *time.Time    `json:"secret_expires_at,omitempty"`
+	SecretRotateAfter *time.Time    `json:"secret_rotate_after,omitempty"`
 }
 
 type PullResponse struct {
@@ -42,10 +81,38 @@ type PairAgentResponse struct {
 	TenantID        string `json:"tenant_id"`
 }
 
+type RotateSecretRequest struct {
+	ConnectorID     string `json:"connector_id"`
+	ConnectorSecret string `json:"connector_secret"`
+}
+
+// RotateSecretResponse carries the new secret. The old one keeps working
+// until PreviousValidUntil, so agents sharing it can catch up.
+type RotateSecretResponse struct {
+	ConnectorID        string     `json:"connector_id"`
+	ConnectorSecret    string     `json:"connector_secret"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	RotateAfter        *time.Time `json:"rotate_after,omitempty"`
+	PreviousValidUntil *time.Time `json:"previous_valid_until,omitempty"`
+}
+
 type LocalTarget struct {
-	Scheme string `json:"scheme"`
-	Host   string `json:"host"`
-	Port   int    `json:"port"`
+	Scheme string    `json:"scheme"`
+	Host   string    `json:"host"`
+	Port   int       `json:"port"`
+	Socket string    `json:"socket,omitempty"`
+	TLS    *LocalTLS `json:"tls,omitempty"`
+}
+
+// LocalTLS overrides how the agent verifies one HTTPS local target, so a
+// self-signed dev service does not need TLS verification disabled agent-wide.
+// PinnedSHA256 is the hex SHA-256 of the leaf certificate's DER bytes and
+// replaces chain verification; CAPEM replaces the trusted roots.
+type LocalTLS struct {
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+	PinnedSHA256       string `json:"pinned_sha256,omitempty"`
+	CAPEM              string `json:"ca_pem,omitempty"`
+	ServerName         string `json:"server_name,omitempty"`
 }
 
 type SubmitResponseRequest struct {
@@ -54,31 +121,132 @@ type SubmitResponseRequest struct {
 }
 
 type HeartbeatRequest struct {
+	SessionID string         `json:"session_id"`
+	AgentID   string         `json:"agent_id,omitempty"`
+	Health    []TargetHealth `json:"health,omitempty"`
+}
+
+const (
+	HealthHealthy   = "healthy"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+)
+
+type TargetHealth struct {
+	Target              string    `json:"target"`
+	Status              string    `json:"status"`
+	ConsecutiveFailures int       `json:"consecutive_failures,omitempty"`
+	Error               string    `json:"error,omitempty"`
+	CheckedAt           time.Time `json:"checked_at"`
+}
+
+type RetryPolicy struct {
+	Attempts      int   `json:"attempts"`
+	BackoffMs     int64 `json:"backoff_ms,omitempty"`
+	MaxBackoffMs  int64 `json:"max_backoff_ms,omitempty"`
+	RetryOnStatus []int `json:"retry_on_status,omitempty"`
+}
+
+const (
+	ResponseLimitRequest = "request"
+	ResponseLimitAgent   = "agent"
+)
+
+// ResponseLimit reports an upstream response abandoned because its body was
+// over a size limit. ReadBytes is how much was read before giving up: none
+// when the declared ContentLength was already over, which is -1 for chunked
+// and other unknown-length responses.
+type ResponseLimit struct {
+	Source        string `json:"source"`
+	LimitBytes    int64  `json:"limit_bytes"`
+	ReadBytes     int64  `json:"read_bytes"`
+	ContentLength int64  `json:"content_length"`
+}
+
+// StreamTCP marks a ProxyRequest that opens a raw byte stream to the local
+// target instead of an HTTP exchange, as used by TLS passthrough routes. The
+// agent answers once the local connection is up, then carries the bytes over
+// GET (gateway to target) and POST (target to gateway) /api/agent/stream.
+const StreamTCP = "tcp"
+
+const (
+	ForwardPending  = "pending"
+	ForwardApproved = "approved"
+	ForwardDenied   = "denied"
+)
+
+type ForwardsRequest struct {
+	SessionID string   `json:"session_id"`
+	Targets   []string `json:"targets"`
+}
+
+type ForwardStatus struct {
+	ID     string `json:"id"`
+	Target string `json:"target"`
+	Status string `json:"status"`
+}
+
+type ForwardsResponse struct {
+	Forwards []ForwardStatus `json:"forwards"`
+}
+
+type ForwardOpenRequest struct {
 	SessionID string `json:"session_id"`
-	AgentID   string `json:"agent_id,omitempty"`
+	ForwardID string `json:"forward_id"`
+}
+
+type ForwardOpenResponse struct {
+	StreamID string `json:"stream_id"`
 }
 
 type ProxyRequest struct {
-	RequestID   string              `json:"request_id"`
-	TunnelID    string              `json:"tunnel_id"`
-	ConnectorID string              `json:"connector_id,omitempty"`
-	Method      string              `json:"method"`
-	Path        string              `json:"path"`
-	Query       string              `json:"query,omitempty"`
-	Headers     map[string][]string `json:"headers,omitempty"`
-	Body        []byte              `json:"body,omitempty"`
-	RemoteAddr  string              `json:"remote_addr,omitempty"`
-	LocalTarget *LocalTarget        `json:"local_target,omitempty"`
+	RequestID            string              `json:"request_id"`
+	TunnelID             string              `json:"tunnel_id"`
+	ConnectorID          string              `json:"connector_id,omitempty"`
+	Method               string              `json:"method"`
+	Path                 string              `json:"path"`
+	Query                string              `json:"query,omitempty"`
+	Headers              map[string][]string `json:"headers,omitempty"`
+	Body                 []byte              `json:"body,omitempty"`
+	Trailers             map[string][]string `json:"trailers,omitempty"`
+	RemoteAddr           string              `json:"remote_addr,omitempty"`
+	LocalTarget          *LocalTarget        `json:"local_target,omitempty"`
+	Host                 string              `json:"host,omitempty"`
+	TimeoutMs            int64               `json:"timeout_ms,omitempty"`
+	IdleTimeoutMs        int64               `json:"idle_timeout_ms,omitempty"`
+	Stream               string              `json:"stream,omitempty"`
+	Retry                *RetryPolicy        `json:"retry,omitempty"`
+	MaxResponseBodyBytes int64               `json:"max_response_body_bytes,omitempty"`
+	HeaderTimeoutMs      int64               `json:"header_timeout_ms,omitempty"`
+	// Upload marks a body too large to carry inline, which the agent streams
+	// from /api/agent/upload instead of reading Body. UploadBytes is its
+	// length, or -1 when the caller did not declare one.
+	Upload      bool  `json:"upload,omitempty"`
+	UploadBytes int64 `json:"upload_bytes,omitempty"`
+}
+
+const (
+	CancelReasonCallerGone = "caller_gone"
+	CancelReasonTimeout    = "timeout"
+	CancelReasonNotPending = "not_pending"
+)
+
+type CancelNotice struct {
+	RequestID string `json:"request_id"`
+	Reason    string `json:"reason"`
 }
 
 type ProxyResponse struct {
-	RequestID string              `json:"request_id"`
-	TunnelID  string              `json:"tunnel_id"`
-	Status    int                 `json:"status"`
-	Headers   map[string][]string `json:"headers,omitempty"`
-	Body      []byte              `json:"body,omitempty"`
-	Error     string              `json:"error,omitempty"`
-	LatencyMs int64               `json:"latency_ms,omitempty"`
-	BytesIn   int64               `json:"bytes_in,omitempty"`
-	BytesOut  int64               `json:"bytes_out,omitempty"`
+	RequestID     string              `json:"request_id"`
+	TunnelID      string              `json:"tunnel_id"`
+	Status        int                 `json:"status"`
+	Headers       map[string][]string `json:"headers,omitempty"`
+	Body          []byte              `json:"body,omitempty"`
+	Trailers      map[string][]string `json:"trailers,omitempty"`
+	Error         string              `json:"error,omitempty"`
+	LatencyMs     int64               `json:"latency_ms,omitempty"`
+	BytesIn       int64               `json:"bytes_in,omitempty"`
+	BytesOut      int64               `json:"bytes_out,omitempty"`
+	Retries       int                 `json:"retries,omitempty"`
+	LimitExceeded *ResponseLimit      `json:"limit_exceeded,omitempty"`
 }
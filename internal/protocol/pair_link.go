@@ -0,0 +1,35 @@
+package protocol
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const PairLinkScheme = "proxer-agent"
+
+func PairLink(gatewayBaseURL, pairToken string) string {
+	query := url.Values{}
+	query.Set("gateway", strings.TrimRight(gatewayBaseURL, "/"))
+	query.Set("token", pairToken)
+	return PairLinkScheme + "://pair?" + query.Encode()
+}
+
+func ParsePairLink(link string) (gatewayBaseURL, pairToken string, err error) {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return "", "", fmt.Errorf("parse pair link: %w", err)
+	}
+	if !strings.EqualFold(parsed.Scheme, PairLinkScheme) || parsed.Host != "pair" {
+		return "", "", fmt.Errorf("not a %s://pair link", PairLinkScheme)
+	}
+	gatewayBaseURL = strings.TrimRight(strings.TrimSpace(parsed.Query().Get("gateway")), "/")
+	pairToken = strings.TrimSpace(parsed.Query().Get("token"))
+	if pairToken == "" {
+		return "", "", fmt.Errorf("pair link has no token")
+	}
+	if gateway, err := url.Parse(gatewayBaseURL); err != nil || (gateway.Scheme != "http" && gateway.Scheme != "https") || gateway.Host == "" {
+		return "", "", fmt.Errorf("pair link has no valid gateway URL")
+	}
+	return gatewayBaseURL, pairToken, nil
+}
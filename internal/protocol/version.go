@@ -0,0 +1,101 @@
+package protocol
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Version struct {
+	Major, Minor, Patch int
+	Prerelease          string
+}
+
+func ParseVersion(raw string) (Version, bool) {
+	raw = strings.TrimPrefix(strings.TrimSpace(raw), "v")
+	raw, _, _ = strings.Cut(raw, "+")
+	core, prerelease, _ := strings.Cut(raw, "-")
+	parts := strings.Split(core, ".")
+	if core == "" || len(parts) > 3 {
+		return Version{}, false
+	}
+	numbers := [3]int{}
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil || value < 0 {
+			return Version{}, false
+		}
+		numbers[i] = value
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2], Prerelease: prerelease}, true
+}
+
+func VersionFromTag(tag string) string {
+	tag = strings.TrimSpace(tag)
+	for i := 0; i < len(tag); i++ {
+		if tag[i] < '0' || tag[i] > '9' || (i > 0 && tag[i-1] != 'v' && tag[i-1] != '-' && tag[i-1] != '_') {
+			continue
+		}
+		if _, ok := ParseVersion(tag[i:]); ok {
+			return tag[i:]
+		}
+	}
+	return ""
+}
+
+// CompareVersions returns -1, 0 or 1 as a is older than, equal to or newer
+// than b, following semver precedence: a prerelease sorts before its
+// release. ok is false when either version does not parse, as for "dev"
+// builds.
+func CompareVersions(a, b string) (result int, ok bool) {
+	left, leftOK := ParseVersion(a)
+	right, rightOK := ParseVersion(b)
+	if !leftOK || !rightOK {
+		return 0, false
+	}
+	for _, pair := range [][2]int{{left.Major, right.Major}, {left.Minor, right.Minor}, {left.Patch, right.Patch}} {
+		if pair[0] != pair[1] {
+			return compareInts(pair[0], pair[1]), true
+		}
+	}
+	return comparePrerelease(left.Prerelease, right.Prerelease), true
+}
+
+func comparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+	left, right := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(left) && i < len(right); i++ {
+		if left[i] == right[i] {
+			continue
+		}
+		leftNumber, leftErr := strconv.Atoi(left[i])
+		rightNumber, rightErr := strconv.Atoi(right[i])
+		switch {
+		case leftErr == nil && rightErr == nil:
+			return compareInts(leftNumber, rightNumber)
+		case leftErr == nil:
+			return -1
+		case rightErr == nil:
+			return 1
+		}
+		return strings.Compare(left[i], right[i])
+	}
+	return compareInts(len(left), len(right))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,240 @@
+// Package qrcode encodes short text, such as pairing links, as QR codes
+// (ISO/IEC 18004) and renders them as SVG or PNG.
+//
+// It only needs what pairing uses: byte mode and error correction level M,
+// in the smallest version that fits.
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+var ErrTooLong = errors.New("qrcode: text too long")
+
+const (
+	minVersion   = 1
+	maxVersion   = 40
+	formatLevelM = 0
+)
+
+var (
+	eccCodewordsPerBlock = [maxVersion + 1]int{-1,
+		10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26,
+		26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28}
+	eccBlocks = [maxVersion + 1]int{-1,
+		1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16,
+		17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49}
+)
+
+type Code struct {
+	version  int
+	size     int
+	mask     int
+	modules  [][]bool
+	function [][]bool
+}
+
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+	version := minVersion
+	for ; version <= maxVersion; version++ {
+		if 4+countBits(version)+8*len(data) <= 8*dataCodewords(version) {
+			break
+		}
+	}
+	if version > maxVersion {
+		return nil, ErrTooLong
+	}
+
+	bits := &bitBuffer{}
+	bits.append(0b0100, 4)
+	bits.append(uint32(len(data)), countBits(version))
+	for _, b := range data {
+		bits.append(uint32(b), 8)
+	}
+	capacity := 8 * dataCodewords(version)
+	bits.append(0, min(4, capacity-bits.len()))
+	bits.append(0, (8-bits.len()%8)%8)
+	for pad := uint32(0xEC); bits.len() < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	code := newCode(version)
+	code.drawCodewords(addErrorCorrection(version, bits.bytes()))
+	code.mask = code.bestMask()
+	code.applyMask(code.mask)
+	code.drawFormatBits(code.mask)
+	return code, nil
+}
+
+func (c *Code) Size() int {
+	return c.size
+}
+
+func (c *Code) Dark(x, y int) bool {
+	return x >= 0 && x < c.size && y >= 0 && y < c.size && c.modules[y][x]
+}
+
+func (c *Code) SVG(border int) string {
+	border = max(border, 0)
+	dim := c.size + 2*border
+	var path strings.Builder
+	for y := range c.size {
+		for x := range c.size {
+			if c.modules[y][x] {
+				if path.Len() > 0 {
+					path.WriteByte(' ')
+				}
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+border, y+border)
+			}
+		}
+	}
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" version="1.1" viewBox="0 0 %d %d" stroke="none" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#ffffff"/><path d="%s" fill="#000000"/></svg>`, dim, dim, path.String())
+}
+
+func (c *Code) PNG(scale, border int) ([]byte, error) {
+	scale = max(scale, 1)
+	border = max(border, 0)
+	dim := (c.size + 2*border) * scale
+	img := image.NewPaletted(image.Rect(0, 0, dim, dim), color.Palette{color.White, color.Black})
+	for py := range dim {
+		for px := range dim {
+			if c.Dark(px/scale-border, py/scale-border) {
+				img.SetColorIndex(px, py, 1)
+			}
+		}
+	}
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+func rawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		alignments := version/7 + 2
+		result -= (25*alignments-10)*alignments - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+func dataCodewords(version int) int {
+	return rawDataModules(version)/8 - eccCodewordsPerBlock[version]*eccBlocks[version]
+}
+
+func addErrorCorrection(version int, data []byte) []byte {
+	numBlocks := eccBlocks[version]
+	eccLen := eccCodewordsPerBlock[version]
+	rawCodewords := rawDataModules(version) / 8
+	numShort := numBlocks - rawCodewords%numBlocks
+	shortLen := rawCodewords / numBlocks
+
+	divisor := reedSolomonDivisor(eccLen)
+	blocks := make([][]byte, 0, numBlocks)
+	for i, offset := 0, 0; i < numBlocks; i++ {
+		dataLen := shortLen - eccLen
+		if i >= numShort {
+			dataLen++
+		}
+		block := append([]byte(nil), data[offset:offset+dataLen]...)
+		offset += dataLen
+		ecc := reedSolomonRemainder(block, divisor)
+		if i < numShort {
+			// Pad short blocks so every block interleaves by index.
+			block = append(block, 0)
+		}
+		blocks = append(blocks, append(block, ecc...))
+	}
+
+	out := make([]byte, 0, rawCodewords)
+	for i := range shortLen + 1 {
+		for j, block := range blocks {
+			if i != shortLen-eccLen || j >= numShort {
+				out = append(out, block[i])
+			}
+		}
+	}
+	return out
+}
+
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+type bitBuffer struct {
+	bits []bool
+}
+
+func (b *bitBuffer) append(value uint32, length int) {
+	for i := length - 1; i >= 0; i-- {
+		b.bits = append(b.bits, (value>>i)&1 == 1)
+	}
+}
+
+func (b *bitBuffer) len() int {
+	return len(b.bits)
+}
+
+func (b *bitBuffer) bytes() []byte {
+	out := make([]byte, len(b.bits)/8)
+	for i, bit := range b.bits {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
@@ -0,0 +1,182 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomonMatchesStandardExample(t *testing.T) {
+	// ISO/IEC 18004 annex I: "01234567" as version 1-M.
+	data := []byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	want := []byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55}
+	if got := reedSolomonRemainder(data, reedSolomonDivisor(10)); !bytes.Equal(got, want) {
+		t.Fatalf("unexpected error correction codewords % X", got)
+	}
+}
+
+func TestFormatAndVersionBitsMatchStandardTables(t *testing.T) {
+	if got := readFormat(newCode(1)); got != 0b101010000010010 {
+		t.Fatalf("level M mask 0 format bits = %015b", got)
+	}
+	code := newCode(7)
+	version := 0
+	for i := range 18 {
+		version |= boolBit(code.Dark(code.size-11+i%3, i/3)) << i
+	}
+	if version != 0x07C94 {
+		t.Fatalf("version 7 bits = %018b", version)
+	}
+}
+
+func TestEncodeRoundTrips(t *testing.T) {
+	for _, text := range []string{
+		"proxer-agent://pair?gateway=http%3A%2F%2Flocalhost%3A8080&token=abc",
+		"proxer-agent://pair?gateway=https%3A%2F%2Fgateway.example.com&token=" + strings.Repeat("0123456789abcdef", 4),
+		strings.Repeat("x", 400),
+	} {
+		code, err := Encode(text)
+		if err != nil {
+			t.Fatalf("encode %d bytes: %v", len(text), err)
+		}
+		if got := decode(t, code); got != text {
+			t.Fatalf("version %d round trip = %q, want %q", code.version, got, text)
+		}
+	}
+	if _, err := Encode(strings.Repeat("x", 3000)); err != ErrTooLong {
+		t.Fatalf("expected text past version 40 to fail, got %v", err)
+	}
+}
+
+func TestRenderers(t *testing.T) {
+	code, err := Encode("proxer")
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if code.Size() != 21 || !code.Dark(0, 0) || code.Dark(7, 0) || code.Dark(-1, 0) {
+		t.Fatalf("expected a version 1 code with a finder in the corner")
+	}
+	if svg := code.SVG(4); !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, `viewBox="0 0 29 29"`) || !strings.Contains(svg, "M4,4h1v1h-1z") {
+		t.Fatalf("unexpected svg %q", svg)
+	}
+	encoded, err := code.PNG(2, 4)
+	if err != nil {
+		t.Fatalf("png: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 58 {
+		t.Fatalf("expected 2 pixels per module, got %d wide", bounds.Dx())
+	}
+	if r, _, _, _ := img.At(8, 8).RGBA(); r != 0 {
+		t.Fatalf("expected the finder corner to be dark")
+	}
+}
+
+// decode reads a code back: the format bits give the mask, the unmasked
+// data area gives the interleaved codewords, and each block must have a zero
+// Reed-Solomon remainder.
+func decode(t *testing.T, code *Code) string {
+	t.Helper()
+	format := readFormat(code) ^ 0x5412
+	if format>>13 != formatLevelM {
+		t.Fatalf("unexpected level in format bits %015b", format)
+	}
+	mask := format >> 10 & 7
+
+	reference := newCode(code.version)
+	var codewords []byte
+	var current byte
+	n := 0
+	for right := code.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := range code.size {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = code.size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if reference.function[y][x] {
+					continue
+				}
+				current = current<<1 | byte(boolBit(code.Dark(x, y) != maskSelects(mask, x, y)))
+				if n++; n%8 == 0 {
+					codewords = append(codewords, current)
+				}
+			}
+		}
+	}
+
+	numBlocks := eccBlocks[code.version]
+	eccLen := eccCodewordsPerBlock[code.version]
+	numShort := numBlocks - len(codewords)%numBlocks
+	shortLen := len(codewords) / numBlocks
+	blocks := make([][]byte, numBlocks)
+	index := 0
+	for i := range shortLen + 1 {
+		for j := range blocks {
+			if i != shortLen-eccLen || j >= numShort {
+				blocks[j] = append(blocks[j], codewords[index])
+				index++
+			}
+		}
+	}
+	var data []byte
+	for _, block := range blocks {
+		dataLen := len(block) - eccLen
+		if got := reedSolomonRemainder(block[:dataLen], reedSolomonDivisor(eccLen)); !bytes.Equal(got, block[dataLen:]) {
+			t.Fatalf("block error correction does not match its data")
+		}
+		data = append(data, block[:dataLen]...)
+	}
+
+	if data[0]>>4 != 0b0100 {
+		t.Fatalf("expected byte mode, got %04b", data[0]>>4)
+	}
+	bits := &bitReader{data: data, pos: 4}
+	length := bits.read(countBits(code.version))
+	out := make([]byte, length)
+	for i := range out {
+		out[i] = byte(bits.read(8))
+	}
+	return string(out)
+}
+
+func readFormat(code *Code) int {
+	format := 0
+	for i := 0; i <= 5; i++ {
+		format |= boolBit(code.Dark(8, i)) << i
+	}
+	format |= boolBit(code.Dark(8, 7))<<6 | boolBit(code.Dark(8, 8))<<7 | boolBit(code.Dark(7, 8))<<8
+	for i := 9; i < 15; i++ {
+		format |= boolBit(code.Dark(14-i, 8)) << i
+	}
+	return format
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) read(n int) int {
+	value := 0
+	for range n {
+		value = value<<1 | int(r.data[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+	return value
+}
+
+func boolBit(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
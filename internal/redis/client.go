@@ -0,0 +1,291 @@
+// Package redis is a small Redis client speaking RESP2 over TCP, enough for
+// the gateway's shared state: single commands and Lua scripts.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNil = errors.New("redis: nil reply")
+
+type Error string
+
+func (e Error) Error() string {
+	return "redis: " + string(e)
+}
+
+const (
+	defaultDialTimeout = 2 * time.Second
+	defaultIOTimeout   = 2 * time.Second
+	maxIdleConns       = 8
+	maxBulkBytes       = 64 << 20
+)
+
+type Client struct {
+	addr      string
+	username  string
+	password  string
+	db        int
+	ioTimeout time.Duration
+
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func New(rawURL string) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	if parsed.Scheme != "redis" {
+		return nil, fmt.Errorf("redis url must start with redis://")
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return nil, fmt.Errorf("redis url has no host")
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = "6379"
+	}
+	client := &Client{addr: net.JoinHostPort(host, port), ioTimeout: defaultIOTimeout}
+	if parsed.User != nil {
+		client.password, _ = parsed.User.Password()
+		client.username = parsed.User.Username()
+		if client.password == "" {
+			// redis://secret@host is the password alone.
+			client.password, client.username = client.username, ""
+		}
+	}
+	if path := strings.Trim(parsed.Path, "/"); path != "" {
+		db, err := strconv.Atoi(path)
+		if err != nil || db < 0 {
+			return nil, fmt.Errorf("redis url database must be a number, got %q", path)
+		}
+		client.db = db
+	}
+	return client, nil
+}
+
+func (c *Client) Addr() string {
+	return c.addr
+}
+
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, reused, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := cn.do(ctx, c.ioTimeout, args)
+	var replyErr Error
+	if err != nil && !errors.Is(err, ErrNil) && !errors.As(err, &replyErr) {
+		// The connection may hold half a reply; drop it. An idle one may
+		// have been closed by a server restart, so retry once on a new one.
+		cn.Close()
+		if !reused || ctx.Err() != nil {
+			return nil, err
+		}
+		if cn, err = c.dial(ctx); err != nil {
+			return nil, err
+		}
+		if reply, err = cn.do(ctx, c.ioTimeout, args); err != nil && !errors.Is(err, ErrNil) && !errors.As(err, &replyErr) {
+			cn.Close()
+			return nil, err
+		}
+	}
+	c.put(cn)
+	return reply, err
+}
+
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for _, cn := range c.idle {
+		cn.Close()
+	}
+	c.idle = nil
+	return nil
+}
+
+func (c *Client) get(ctx context.Context) (*conn, bool, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, false, errors.New("redis: client closed")
+	}
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return cn, true, nil
+	}
+	c.mu.Unlock()
+	cn, err := c.dial(ctx)
+	return cn, false, err
+}
+
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || len(c.idle) >= maxIdleConns {
+		cn.Close()
+		return
+	}
+	c.idle = append(c.idle, cn)
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	dialer := net.Dialer{Timeout: defaultDialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("redis: dial %s: %w", c.addr, err)
+	}
+	cn := &conn{Conn: netConn, reader: bufio.NewReader(netConn)}
+	if c.password != "" {
+		args := []string{"AUTH", c.password}
+		if c.username != "" {
+			args = []string{"AUTH", c.username, c.password}
+		}
+		if _, err := cn.do(ctx, c.ioTimeout, args); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := cn.do(ctx, c.ioTimeout, []string{"SELECT", strconv.Itoa(c.db)}); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+func (cn *conn) do(ctx context.Context, timeout time.Duration, args []string) (any, error) {
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := cn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	if _, err := cn.Write(encodeCommand(args)); err != nil {
+		return nil, fmt.Errorf("redis: write: %w", err)
+	}
+	return readReply(cn.reader)
+}
+
+func encodeCommand(args []string) []byte {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	return []byte(b.String())
+}
+
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: read: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		value, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad integer reply %q", line)
+		}
+		return value, nil
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size > maxBulkBytes {
+			return nil, fmt.Errorf("redis: bad bulk length %q", line)
+		}
+		if size < 0 {
+			return nil, ErrNil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("redis: read: %w", err)
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad array length %q", line)
+		}
+		if count < 0 {
+			return nil, ErrNil
+		}
+		items := make([]any, count)
+		for i := range items {
+			item, err := readReply(r)
+			if err != nil && !errors.Is(err, ErrNil) {
+				var replyErr Error
+				if !errors.As(err, &replyErr) {
+					return nil, err
+				}
+				item = replyErr
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", line)
+}
+
+// Script is a Lua script run with EVALSHA, falling back to EVAL the first
+// time the server does not have it cached.
+type Script struct {
+	src string
+	sha string
+}
+
+func NewScript(src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	return &Script{src: src, sha: hex.EncodeToString(sum[:])}
+}
+
+func (s *Script) Run(ctx context.Context, c *Client, keys []string, args ...string) (any, error) {
+	command := func(name, script string) []string {
+		out := append([]string{name, script, strconv.Itoa(len(keys))}, keys...)
+		return append(out, args...)
+	}
+	reply, err := c.Do(ctx, command("EVALSHA", s.sha)...)
+	var replyErr Error
+	if errors.As(err, &replyErr) && strings.HasPrefix(string(replyErr), "NOSCRIPT") {
+		return c.Do(ctx, command("EVAL", s.src)...)
+	}
+	return reply, err
+}
@@ -0,0 +1,163 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer answers RESP commands with handle and records them.
+type fakeServer struct {
+	listener net.Listener
+	handle   func(args []string) string
+
+	mu       sync.Mutex
+	commands [][]string
+}
+
+func newFakeServer(t *testing.T, handle func(args []string) string) *fakeServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := &fakeServer{listener: listener, handle: handle}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]any)
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, args)
+		s.mu.Unlock()
+		if _, err := conn.Write([]byte(s.handle(args))); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, len(s.commands))
+	for i, command := range s.commands {
+		names[i] = command[0]
+	}
+	return names
+}
+
+func TestNewParsesURL(t *testing.T) {
+	client, err := New("redis://:secret@cache.internal/2")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if client.Addr() != "cache.internal:6379" || client.password != "secret" || client.username != "" || client.db != 2 {
+		t.Fatalf("unexpected client %+v", client)
+	}
+	for _, raw := range []string{"http://cache:6379", "redis:///0", "redis://cache/x"} {
+		if _, err := New(raw); err == nil {
+			t.Fatalf("New(%q) accepted an invalid url", raw)
+		}
+	}
+}
+
+func TestClientDoAuthenticatesAndDecodesReplies(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		switch strings.ToUpper(args[0]) {
+		case "AUTH", "SELECT":
+			return "+OK\r\n"
+		case "GET":
+			if args[1] == "missing" {
+				return "$-1\r\n"
+			}
+			return "$5\r\nhello\r\n"
+		case "INCR":
+			return ":42\r\n"
+		case "MGET":
+			return "*2\r\n$1\r\na\r\n$-1\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	})
+	client, err := New("redis://user:pass@" + server.listener.Addr().String() + "/1")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	if reply, err := client.Do(ctx, "GET", "greeting"); err != nil || reply != "hello" {
+		t.Fatalf("GET = %v, %v", reply, err)
+	}
+	if _, err := client.Do(ctx, "GET", "missing"); !errors.Is(err, ErrNil) {
+		t.Fatalf("GET missing error = %v, want ErrNil", err)
+	}
+	if reply, err := client.Do(ctx, "INCR", "n"); err != nil || reply != int64(42) {
+		t.Fatalf("INCR = %v, %v", reply, err)
+	}
+	if reply, err := client.Do(ctx, "MGET", "a", "b"); err != nil || len(reply.([]any)) != 2 || reply.([]any)[1] != nil {
+		t.Fatalf("MGET = %#v, %v", reply, err)
+	}
+	var replyErr Error
+	if _, err := client.Do(ctx, "NOPE"); !errors.As(err, &replyErr) {
+		t.Fatalf("NOPE error = %v, want an Error reply", err)
+	}
+	// One pooled connection served every command after AUTH and SELECT.
+	if got := strings.Join(server.names(), ","); got != "AUTH,SELECT,GET,GET,INCR,MGET,NOPE" {
+		t.Fatalf("commands = %s", got)
+	}
+}
+
+func TestScriptFallsBackToEval(t *testing.T) {
+	loaded := false
+	server := newFakeServer(t, func(args []string) string {
+		switch args[0] {
+		case "EVALSHA":
+			if !loaded {
+				return "-NOSCRIPT No matching script\r\n"
+			}
+			return ":1\r\n"
+		case "EVAL":
+			loaded = true
+			return ":1\r\n"
+		}
+		return "-ERR unexpected\r\n"
+	})
+	client, err := New("redis://" + server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	script := NewScript("return 1")
+	for i := 0; i < 2; i++ {
+		if reply, err := script.Run(context.Background(), client, []string{"k"}, "a"); err != nil || reply != int64(1) {
+			t.Fatalf("Run() = %v, %v", reply, err)
+		}
+	}
+	if got := strings.Join(server.names(), ","); got != "EVALSHA,EVAL,EVALSHA" {
+		t.Fatalf("commands = %s", got)
+	}
+}
@@ -0,0 +1,350 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/redis"
+)
+
+// SessionRecord is one console login. ID is the session token the browser
+// holds; persistent stores key records by its SHA-256 so the database never
+// holds usable tokens.
+type SessionRecord struct {
+	ID             string
+	Username       string
+	ExpiresAt      time.Time
+	ImpersonatedBy string
+}
+
+type SessionStore interface {
+	Driver() string
+	Get(id string) (SessionRecord, bool, error)
+	Put(session SessionRecord) error
+	Delete(id string) error
+	Health() map[string]any
+}
+
+// NewSessionStore builds the session store for driver. A sqlite store shares
+// snapshots' database when it is one, so one process serializes all access.
+func NewSessionStore(driver, sqlitePath, redisURL string, snapshots SnapshotStore) (SessionStore, error) {
+	switch normalizeDriver(driver) {
+	case "memory":
+		return NewMemorySessionStore(), nil
+	case "sqlite":
+		sqlite, ok := snapshots.(*SQLiteSnapshotStore)
+		if !ok || sqlite.path != strings.TrimSpace(sqlitePath) {
+			var err error
+			if sqlite, err = NewSQLiteSnapshotStore(sqlitePath); err != nil {
+				return nil, err
+			}
+		}
+		return sqlite.Sessions(), nil
+	case "redis":
+		client, err := redis.New(redisURL)
+		if err != nil {
+			return nil, err
+		}
+		return NewRedisSessionStore(client), nil
+	default:
+		return nil, fmt.Errorf("unsupported session store %q", driver)
+	}
+}
+
+func sessionKey(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]SessionRecord
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]SessionRecord)}
+}
+
+func (s *MemorySessionStore) Driver() string {
+	return "memory"
+}
+
+func (s *MemorySessionStore) Get(id string) (SessionRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return SessionRecord{}, false, nil
+	}
+	if time.Now().After(session.ExpiresAt) {
+		delete(s.sessions, id)
+		return SessionRecord{}, false, nil
+	}
+	return session, true, nil
+}
+
+func (s *MemorySessionStore) Put(session SessionRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, existing := range s.sessions {
+		if now.After(existing.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *MemorySessionStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *MemorySessionStore) Health() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return map[string]any{
+		"driver":   "memory",
+		"status":   "ok",
+		"sessions": len(s.sessions),
+	}
+}
+
+const (
+	redisSessionPrefix  = "proxer:session:"
+	redisSessionTimeout = 2 * time.Second
+)
+
+type RedisSessionStore struct {
+	client *redis.Client
+}
+
+type redisSession struct {
+	Username       string    `json:"username"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	ImpersonatedBy string    `json:"impersonated_by,omitempty"`
+}
+
+func NewRedisSessionStore(client *redis.Client) *RedisSessionStore {
+	return &RedisSessionStore{client: client}
+}
+
+func (s *RedisSessionStore) Driver() string {
+	return "redis"
+}
+
+func (s *RedisSessionStore) Get(id string) (SessionRecord, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisSessionTimeout)
+	defer cancel()
+	reply, err := s.client.Do(ctx, "GET", redisSessionPrefix+sessionKey(id))
+	if errors.Is(err, redis.ErrNil) {
+		return SessionRecord{}, false, nil
+	}
+	if err != nil {
+		return SessionRecord{}, false, err
+	}
+	payload, _ := reply.(string)
+	var stored redisSession
+	if err := json.Unmarshal([]byte(payload), &stored); err != nil {
+		return SessionRecord{}, false, fmt.Errorf("decode session: %w", err)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return SessionRecord{}, false, nil
+	}
+	return SessionRecord{ID: id, Username: stored.Username, ExpiresAt: stored.ExpiresAt, ImpersonatedBy: stored.ImpersonatedBy}, true, nil
+}
+
+func (s *RedisSessionStore) Put(session SessionRecord) error {
+	ttl := time.Until(session.ExpiresAt).Milliseconds()
+	if ttl <= 0 {
+		return s.Delete(session.ID)
+	}
+	payload, err := json.Marshal(redisSession{Username: session.Username, ExpiresAt: session.ExpiresAt, ImpersonatedBy: session.ImpersonatedBy})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisSessionTimeout)
+	defer cancel()
+	_, err = s.client.Do(ctx, "SET", redisSessionPrefix+sessionKey(session.ID), string(payload), "PX", strconv.FormatInt(ttl, 10))
+	return err
+}
+
+func (s *RedisSessionStore) Delete(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisSessionTimeout)
+	defer cancel()
+	_, err := s.client.Do(ctx, "DEL", redisSessionPrefix+sessionKey(id))
+	return err
+}
+
+func (s *RedisSessionStore) Health() map[string]any {
+	ctx, cancel := context.WithTimeout(context.Background(), redisSessionTimeout)
+	defer cancel()
+	status := "ok"
+	if err := s.client.Ping(ctx); err != nil {
+		status = "error"
+	}
+	return map[string]any{
+		"driver": "redis",
+		"addr":   s.client.Addr(),
+		"status": status,
+	}
+}
+
+// sqliteSessionCacheTTL is how long a session read from SQLite is served
+// from memory. Each query starts a sqlite3 process, so without it every
+// console request would; a logout on another process takes effect within
+// this window.
+const sqliteSessionCacheTTL = 5 * time.Second
+
+type SQLiteSessionStore struct {
+	db *SQLiteSnapshotStore
+
+	mu    sync.Mutex
+	cache map[string]sqliteCachedSession
+}
+
+type sqliteCachedSession struct {
+	session  SessionRecord
+	found    bool
+	cachedAt time.Time
+}
+
+func (s *SQLiteSnapshotStore) Sessions() *SQLiteSessionStore {
+	return &SQLiteSessionStore{db: s, cache: make(map[string]sqliteCachedSession)}
+}
+
+func (s *SQLiteSessionStore) Driver() string {
+	return "sqlite"
+}
+
+func ValidSessionID(id string) bool {
+	if len(id) != 64 {
+		return false
+	}
+	for _, r := range id {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *SQLiteSessionStore) Cached(id string) bool {
+	if !ValidSessionID(id) {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached, ok := s.cache[id]
+	return ok && time.Since(cached.cachedAt) < sqliteSessionCacheTTL
+}
+
+// Get never queries for IDs that are not session tokens, so made-up cookies
+// cannot start a sqlite3 process each.
+func (s *SQLiteSessionStore) Get(id string) (SessionRecord, bool, error) {
+	if !ValidSessionID(id) {
+		return SessionRecord{}, false, nil
+	}
+	now := time.Now()
+	s.mu.Lock()
+	cached, ok := s.cache[id]
+	s.mu.Unlock()
+	if ok && now.Sub(cached.cachedAt) < sqliteSessionCacheTTL {
+		if !cached.found || now.After(cached.session.ExpiresAt) {
+			return SessionRecord{}, false, nil
+		}
+		return cached.session, true, nil
+	}
+
+	query := fmt.Sprintf("SELECT hex(username), expires_at, hex(impersonated_by) FROM proxer_sessions WHERE token_hash='%s' AND expires_at > %d;", sessionKey(id), now.UnixMilli())
+	s.db.mu.Lock()
+	output, err := s.db.execNoLock(query)
+	s.db.mu.Unlock()
+	if err != nil {
+		return SessionRecord{}, false, err
+	}
+	session := SessionRecord{ID: id}
+	found := output != ""
+	if found {
+		fields := strings.Split(output, "|")
+		if len(fields) != 3 {
+			return SessionRecord{}, false, fmt.Errorf("unexpected session row %q", output)
+		}
+		username, errUser := hex.DecodeString(fields[0])
+		expiresAt, errExpiry := strconv.ParseInt(fields[1], 10, 64)
+		impersonatedBy, errActor := hex.DecodeString(fields[2])
+		if err := errors.Join(errUser, errExpiry, errActor); err != nil {
+			return SessionRecord{}, false, fmt.Errorf("decode session row: %w", err)
+		}
+		session.Username = string(username)
+		session.ExpiresAt = time.UnixMilli(expiresAt).UTC()
+		session.ImpersonatedBy = string(impersonatedBy)
+	}
+	s.remember(id, session, found, now)
+	if !found {
+		return SessionRecord{}, false, nil
+	}
+	return session, true, nil
+}
+
+func (s *SQLiteSessionStore) Put(session SessionRecord) error {
+	now := time.Now()
+	query := fmt.Sprintf("BEGIN; DELETE FROM proxer_sessions WHERE expires_at <= %d; INSERT INTO proxer_sessions(token_hash, username, expires_at, impersonated_by) VALUES ('%s', CAST(X'%s' AS TEXT), %d, CAST(X'%s' AS TEXT)) ON CONFLICT(token_hash) DO UPDATE SET username=excluded.username, expires_at=excluded.expires_at, impersonated_by=excluded.impersonated_by; COMMIT;",
+		now.UnixMilli(), sessionKey(session.ID), hex.EncodeToString([]byte(session.Username)), session.ExpiresAt.UnixMilli(), hex.EncodeToString([]byte(session.ImpersonatedBy)))
+	s.db.mu.Lock()
+	_, err := s.db.execNoLock(query)
+	s.db.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.remember(session.ID, session, true, now)
+	return nil
+}
+
+func (s *SQLiteSessionStore) Delete(id string) error {
+	if !ValidSessionID(id) {
+		return nil
+	}
+	query := fmt.Sprintf("DELETE FROM proxer_sessions WHERE token_hash='%s';", sessionKey(id))
+	s.db.mu.Lock()
+	_, err := s.db.execNoLock(query)
+	s.db.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.cache, id)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *SQLiteSessionStore) Health() map[string]any {
+	health := s.db.Health()
+	return map[string]any{
+		"driver": "sqlite",
+		"path":   health["path"],
+		"status": health["status"],
+	}
+}
+
+func (s *SQLiteSessionStore) remember(id string, session SessionRecord, found bool, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, cached := range s.cache {
+		if now.Sub(cached.cachedAt) >= sqliteSessionCacheTTL {
+			delete(s.cache, key)
+		}
+	}
+	s.cache[id] = sqliteCachedSession{session: session, found: found, cachedAt: now}
+}
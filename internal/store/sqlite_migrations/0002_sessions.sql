@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS proxer_sessions (
+  token_hash TEXT PRIMARY KEY,
+  username TEXT NOT NULL,
+  expires_at INTEGER NOT NULL,
+  impersonated_by TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS proxer_sessions_expires_at ON proxer_sessions(expires_at);
@@ -0,0 +1,501 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+func (c *Client) Login(ctx context.Context, username, password string) (User, error) {
+	var payload struct {
+		User User `json:"user"`
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/api/auth/login", map[string]string{
+		"username": username,
+		"password": password,
+	}, &payload)
+	if err != nil {
+		return User{}, err
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == SessionCookieName && cookie.Value != "" {
+			c.SetToken(cookie.Value)
+			return payload.User, nil
+		}
+	}
+	return User{}, errors.New("login: gateway returned no session")
+}
+
+func (c *Client) Logout(ctx context.Context) error {
+	if err := c.Do(ctx, http.MethodPost, "/api/auth/logout", nil, nil); err != nil {
+		return err
+	}
+	c.SetToken("")
+	return nil
+}
+
+func (c *Client) Me(ctx context.Context) (User, []Tenant, error) {
+	var payload struct {
+		User    User     `json:"user"`
+		Tenants []Tenant `json:"tenants"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/api/auth/me", nil, &payload); err != nil {
+		return User{}, nil, err
+	}
+	return payload.User, payload.Tenants, nil
+}
+
+func (c *Client) ListTenants(ctx context.Context) ([]Tenant, error) {
+	var payload struct {
+		Tenants []Tenant `json:"tenants"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/api/tenants", nil, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Tenants, nil
+}
+
+func (c *Client) UpsertTenant(ctx context.Context, input TenantInput) (Tenant, error) {
+	var payload struct {
+		Tenant Tenant `json:"tenant"`
+	}
+	if err := c.Do(ctx, http.MethodPost, "/api/tenants", input, &payload); err != nil {
+		return Tenant{}, err
+	}
+	return payload.Tenant, nil
+}
+
+func (c *Client) DeleteTenant(ctx context.Context, tenantID string) error {
+	return c.Do(ctx, http.MethodDelete, "/api/tenants/"+url.PathEscape(tenantID), nil, nil)
+}
+
+func (c *Client) ListRoutes(ctx context.Context, tenantID string) ([]Route, error) {
+	var payload struct {
+		Routes []Route `json:"routes"`
+	}
+	if err := c.Do(ctx, http.MethodGet, tenantPath(tenantID, "routes"), nil, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Routes, nil
+}
+
+func (c *Client) GetRoute(ctx context.Context, tenantID, routeID string) (Route, error) {
+	routes, err := c.ListRoutes(ctx, tenantID)
+	if err != nil {
+		return Route{}, err
+	}
+	for _, route := range routes {
+		if route.ID == routeID {
+			return route, nil
+		}
+	}
+	path := tenantPath(tenantID, "routes", routeID)
+	return Route{}, &APIError{Method: http.MethodGet, Path: path, StatusCode: http.StatusNotFound, Code: CodeRouteNotFound, Message: fmt.Sprintf("route %q not found", routeID)}
+}
+
+func (c *Client) UpsertRoute(ctx context.Context, tenantID string, input RouteInput) (Route, error) {
+	var payload struct {
+		Route Route `json:"route"`
+	}
+	if err := c.Do(ctx, http.MethodPost, tenantPath(tenantID, "routes"), input, &payload); err != nil {
+		return Route{}, err
+	}
+	return payload.Route, nil
+}
+
+func (c *Client) ValidateRoute(ctx context.Context, tenantID string, input RouteInput) (Route, error) {
+	var payload struct {
+		Route Route `json:"route"`
+	}
+	if err := c.Do(ctx, http.MethodPost, tenantPath(tenantID, "routes")+"?dry_run=true", input, &payload); err != nil {
+		return Route{}, err
+	}
+	return payload.Route, nil
+}
+
+func (c *Client) RouteHistory(ctx context.Context, tenantID, routeID string) ([]RouteVersion, error) {
+	var payload struct {
+		Versions []RouteVersion `json:"versions"`
+	}
+	if err := c.Do(ctx, http.MethodGet, tenantPath(tenantID, "routes", routeID, "history"), nil, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Versions, nil
+}
+
+func (c *Client) RollbackRoute(ctx context.Context, tenantID, routeID string, version int) (Route, error) {
+	var payload struct {
+		Route Route `json:"route"`
+	}
+	if err := c.Do(ctx, http.MethodPost, tenantPath(tenantID, "routes", routeID, "rollback"), map[string]int{"version": version}, &payload); err != nil {
+		return Route{}, err
+	}
+	return payload.Route, nil
+}
+
+// DeleteRoute moves a route to the tenant's trash, from which RestoreFromTrash
+// brings it back until it is purged.
+func (c *Client) DeleteRoute(ctx context.Context, tenantID, routeID string) error {
+	return c.Do(ctx, http.MethodDelete, tenantPath(tenantID, "routes", routeID), nil, nil)
+}
+
+func (c *Client) ListTrash(ctx context.Context, tenantID string) ([]TrashItem, error) {
+	var payload struct {
+		Items []TrashItem `json:"items"`
+	}
+	if err := c.Do(ctx, http.MethodGet, tenantPath(tenantID, "trash"), nil, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Items, nil
+}
+
+func (c *Client) RestoreFromTrash(ctx context.Context, tenantID, kind, id string) ([]string, error) {
+	var payload struct {
+		Restored []string `json:"restored"`
+	}
+	if err := c.Do(ctx, http.MethodPost, tenantPath(tenantID, "trash", kind+"s", id, "restore"), nil, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Restored, nil
+}
+
+func (c *Client) ListDomains(ctx context.Context, tenantID string) ([]Domain, error) {
+	var payload struct {
+		Domains []Domain `json:"domains"`
+	}
+	if err := c.Do(ctx, http.MethodGet, tenantPath(tenantID, "domains"), nil, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Domains, nil
+}
+
+func (c *Client) AttachDomain(ctx context.Context, tenantID, hostname, routeID string) (Domain, error) {
+	body := map[string]string{"hostname": hostname, "route_id": routeID}
+	return c.sendDomain(ctx, tenantPath(tenantID, "domains"), body)
+}
+
+// VerifyDomain checks a domain's DNS challenge. A failed check is not an
+// error; the returned domain has status "failed" and a LastError.
+func (c *Client) VerifyDomain(ctx context.Context, tenantID, hostname string) (Domain, error) {
+	return c.sendDomain(ctx, tenantPath(tenantID, "domains", hostname, "verify"), nil)
+}
+
+func (c *Client) sendDomain(ctx context.Context, path string, body any) (Domain, error) {
+	var payload struct {
+		Domain Domain `json:"domain"`
+	}
+	if err := c.Do(ctx, http.MethodPost, path, body, &payload); err != nil {
+		return Domain{}, err
+	}
+	return payload.Domain, nil
+}
+
+func (c *Client) DeleteDomain(ctx context.Context, tenantID, hostname string) error {
+	return c.Do(ctx, http.MethodDelete, tenantPath(tenantID, "domains", hostname), nil, nil)
+}
+
+func (c *Client) ListConnectors(ctx context.Context) ([]Connector, error) {
+	var payload struct {
+		Connectors []Connector `json:"connectors"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/api/connectors", nil, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Connectors, nil
+}
+
+func (c *Client) CreateConnector(ctx context.Context, input ConnectorInput) (Connector, error) {
+	var payload struct {
+		Connector Connector `json:"connector"`
+	}
+	if err := c.Do(ctx, http.MethodPost, "/api/connectors", input, &payload); err != nil {
+		return Connector{}, err
+	}
+	return payload.Connector, nil
+}
+
+func (c *Client) ValidateConnector(ctx context.Context, input ConnectorInput) (Connector, error) {
+	var payload struct {
+		Connector Connector `json:"connector"`
+	}
+	if err := c.Do(ctx, http.MethodPost, "/api/connectors?dry_run=true", input, &payload); err != nil {
+		return Connector{}, err
+	}
+	return payload.Connector, nil
+}
+
+func (c *Client) SetConnectorLabels(ctx context.Context, connectorID string, labels map[string]string) (Connector, error) {
+	var payload struct {
+		Connector Connector `json:"connector"`
+	}
+	body := map[string]map[string]string{"labels": labels}
+	if err := c.Do(ctx, http.MethodPatch, "/api/connectors/"+url.PathEscape(connectorID), body, &payload); err != nil {
+		return Connector{}, err
+	}
+	return payload.Connector, nil
+}
+
+func (c *Client) SetConnectorBandwidth(ctx context.Context, connectorID string, bytesPerSecond int64) (Connector, error) {
+	var payload struct {
+		Connector Connector `json:"connector"`
+	}
+	body := map[string]int64{"max_bytes_per_second": bytesPerSecond}
+	if err := c.Do(ctx, http.MethodPatch, "/api/connectors/"+url.PathEscape(connectorID), body, &payload); err != nil {
+		return Connector{}, err
+	}
+	return payload.Connector, nil
+}
+
+func (c *Client) PairConnector(ctx context.Context, connectorID string) (ConnectorPairing, error) {
+	var pairing ConnectorPairing
+	err := c.Do(ctx, http.MethodPost, "/api/connectors/"+url.PathEscape(connectorID)+"/pair", nil, &pairing)
+	return pairing, err
+}
+
+// RotateConnectorSecret replaces a connector's secret and returns the new
+// one. Agents using the old secret must pair again.
+func (c *Client) RotateConnectorSecret(ctx context.Context, connectorID string) (string, error) {
+	var payload struct {
+		Secret string `json:"connector_secret"`
+	}
+	if err := c.Do(ctx, http.MethodPost, "/api/connectors/"+url.PathEscape(connectorID)+"/rotate", nil, &payload); err != nil {
+		return "", err
+	}
+	return payload.Secret, nil
+}
+
+func (c *Client) DeleteConnector(ctx context.Context, connectorID string) error {
+	return c.Do(ctx, http.MethodDelete, "/api/connectors/"+url.PathEscape(connectorID), nil, nil)
+}
+
+func (c *Client) ListUsers(ctx context.Context) ([]User, error) {
+	var payload struct {
+		Users []User `json:"users"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/api/admin/users", nil, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Users, nil
+}
+
+func (c *Client) CreateUser(ctx context.Context, input UserInput) (User, error) {
+	return c.sendUser(ctx, http.MethodPost, "/api/admin/users", input)
+}
+
+func (c *Client) UpdateUser(ctx context.Context, username string, input UserInput) (User, error) {
+	input.Username = ""
+	return c.sendUser(ctx, http.MethodPatch, "/api/admin/users/"+url.PathEscape(username), input)
+}
+
+func (c *Client) Impersonate(ctx context.Context, username string, ttl time.Duration, reason string) (string, Impersonation, error) {
+	var payload struct {
+		SessionID     string        `json:"session_id"`
+		Impersonation Impersonation `json:"impersonation"`
+	}
+	body := map[string]any{"ttl_seconds": int(ttl.Seconds()), "reason": reason}
+	if err := c.Do(ctx, http.MethodPost, "/api/admin/users/"+url.PathEscape(username)+"/impersonate", body, &payload); err != nil {
+		return "", Impersonation{}, err
+	}
+	return payload.SessionID, payload.Impersonation, nil
+}
+
+func (c *Client) sendUser(ctx context.Context, method, path string, input UserInput) (User, error) {
+	var payload struct {
+		User User `json:"user"`
+	}
+	if err := c.Do(ctx, method, path, input, &payload); err != nil {
+		return User{}, err
+	}
+	return payload.User, nil
+}
+
+func (c *Client) ListOrgs(ctx context.Context) ([]Organization, error) {
+	var payload struct {
+		Organizations []Organization `json:"organizations"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/api/orgs", nil, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Organizations, nil
+}
+
+func (c *Client) UpsertOrg(ctx context.Context, org Organization) (Organization, error) {
+	var payload struct {
+		Organization Organization `json:"organization"`
+	}
+	body := map[string]any{"id": org.ID, "name": org.Name, "tenant_ids": org.TenantIDs}
+	if err := c.Do(ctx, http.MethodPost, "/api/orgs", body, &payload); err != nil {
+		return Organization{}, err
+	}
+	return payload.Organization, nil
+}
+
+func (c *Client) DeleteOrg(ctx context.Context, orgID string) error {
+	return c.Do(ctx, http.MethodDelete, "/api/orgs/"+url.PathEscape(orgID), nil, nil)
+}
+
+func (c *Client) OrgUsage(ctx context.Context, orgID, month string) (OrgUsage, error) {
+	path := "/api/orgs/" + url.PathEscape(orgID) + "/usage"
+	if month != "" {
+		path += "?month=" + url.QueryEscape(month)
+	}
+	var usage OrgUsage
+	if err := c.Do(ctx, http.MethodGet, path, nil, &usage); err != nil {
+		return OrgUsage{}, err
+	}
+	return usage, nil
+}
+
+func (c *Client) CreateOrgToken(ctx context.Context, orgID, name string, ttl time.Duration) (OrgToken, string, error) {
+	var payload struct {
+		Token  OrgToken `json:"token"`
+		Secret string   `json:"secret"`
+	}
+	body := map[string]any{"name": name, "expires_in_hours": int(ttl.Hours())}
+	if err := c.Do(ctx, http.MethodPost, "/api/orgs/"+url.PathEscape(orgID)+"/tokens", body, &payload); err != nil {
+		return OrgToken{}, "", err
+	}
+	return payload.Token, payload.Secret, nil
+}
+
+func (c *Client) DeleteOrgToken(ctx context.Context, orgID, tokenID string) error {
+	return c.Do(ctx, http.MethodDelete, "/api/orgs/"+url.PathEscape(orgID)+"/tokens/"+url.PathEscape(tokenID), nil, nil)
+}
+
+func (c *Client) ListPlans(ctx context.Context) ([]Plan, error) {
+	return c.listPlans(ctx, "/api/admin/plans")
+}
+
+func (c *Client) PublicPlans(ctx context.Context) ([]Plan, error) {
+	return c.listPlans(ctx, "/api/public/plans")
+}
+
+func (c *Client) listPlans(ctx context.Context, path string) ([]Plan, error) {
+	var payload struct {
+		Plans []Plan `json:"plans"`
+	}
+	if err := c.Do(ctx, http.MethodGet, path, nil, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Plans, nil
+}
+
+func (c *Client) UpsertPlan(ctx context.Context, plan Plan) (Plan, error) {
+	return c.sendPlan(ctx, http.MethodPost, "/api/admin/plans", plan)
+}
+
+func (c *Client) UpdatePlan(ctx context.Context, planID string, plan Plan) (Plan, error) {
+	return c.sendPlan(ctx, http.MethodPatch, "/api/admin/plans/"+url.PathEscape(planID), plan)
+}
+
+func (c *Client) sendPlan(ctx context.Context, method, path string, plan Plan) (Plan, error) {
+	var payload struct {
+		Plan Plan `json:"plan"`
+	}
+	if err := c.Do(ctx, method, path, plan, &payload); err != nil {
+		return Plan{}, err
+	}
+	return payload.Plan, nil
+}
+
+func (c *Client) PlanVersions(ctx context.Context, planID string) ([]PlanVersion, error) {
+	var payload struct {
+		Versions []PlanVersion `json:"versions"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/api/admin/plans/"+url.PathEscape(planID)+"/versions", nil, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Versions, nil
+}
+
+func (c *Client) MigratePlanTenants(ctx context.Context, planID string, fromVersion int, tenantIDs ...string) ([]PlanAssignment, error) {
+	var payload struct {
+		Migrated []PlanAssignment `json:"migrated"`
+	}
+	body := map[string]any{"from_version": fromVersion, "tenant_ids": tenantIDs}
+	if err := c.Do(ctx, http.MethodPost, "/api/admin/plans/"+url.PathEscape(planID)+"/migrate", body, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Migrated, nil
+}
+
+func (c *Client) AssignPlan(ctx context.Context, tenantID, planID string) (PlanAssignment, error) {
+	var payload struct {
+		Assignment PlanAssignment `json:"assignment"`
+	}
+	path := "/api/admin/tenants/" + url.PathEscape(tenantID) + "/assign-plan"
+	if err := c.Do(ctx, http.MethodPost, path, map[string]string{"plan_id": planID}, &payload); err != nil {
+		return PlanAssignment{}, err
+	}
+	return payload.Assignment, nil
+}
+
+func (c *Client) SuspendTenant(ctx context.Context, tenantID string, input TenantSuspensionInput) (Tenant, error) {
+	return c.tenantAction(ctx, tenantID, "suspend", input)
+}
+
+func (c *Client) ArchiveTenant(ctx context.Context, tenantID string) (Tenant, error) {
+	return c.tenantAction(ctx, tenantID, "archive", nil)
+}
+
+func (c *Client) ReactivateTenant(ctx context.Context, tenantID string) (Tenant, error) {
+	return c.tenantAction(ctx, tenantID, "reactivate", nil)
+}
+
+func (c *Client) tenantAction(ctx context.Context, tenantID, action string, body any) (Tenant, error) {
+	var payload struct {
+		Tenant Tenant `json:"tenant"`
+	}
+	path := "/api/admin/tenants/" + url.PathEscape(tenantID) + "/" + action
+	if err := c.Do(ctx, http.MethodPost, path, body, &payload); err != nil {
+		return Tenant{}, err
+	}
+	return payload.Tenant, nil
+}
+
+func (c *Client) Usage(ctx context.Context) (PlanUsage, error) {
+	var usage PlanUsage
+	err := c.Do(ctx, http.MethodGet, "/api/me/usage", nil, &usage)
+	return usage, err
+}
+
+func (c *Client) AllUsage(ctx context.Context) ([]PlanUsage, error) {
+	var payload struct {
+		Tenants []PlanUsage `json:"tenants"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/api/me/usage", nil, &payload); err != nil {
+		return nil, err
+	}
+	return payload.Tenants, nil
+}
+
+func (c *Client) NotificationPreferences(ctx context.Context) (NotificationPreferences, error) {
+	var payload struct {
+		Preferences NotificationPreferences `json:"preferences"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/api/me/notifications", nil, &payload); err != nil {
+		return NotificationPreferences{}, err
+	}
+	return payload.Preferences, nil
+}
+
+func (c *Client) SetNotificationPreferences(ctx context.Context, prefs NotificationPreferences) (NotificationPreferences, error) {
+	var payload struct {
+		Preferences NotificationPreferences `json:"preferences"`
+	}
+	if err := c.Do(ctx, http.MethodPut, "/api/me/notifications", prefs, &payload); err != nil {
+		return NotificationPreferences{}, err
+	}
+	return payload.Preferences, nil
+}
+
+func tenantPath(tenantID string, parts ...string) string {
+	path := "/api/tenants/" + url.PathEscape(tenantID)
+	for _, part := range parts {
+		path += "/" + url.PathEscape(part)
+	}
+	return path
+}
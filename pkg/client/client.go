@@ -0,0 +1,203 @@
+// Package client is a Go client for the Proxer gateway management API. It
+// covers authentication, tenants, routes, connectors, users, plans and usage
+// with typed models, so other programs do not depend on gateway internals.
+//
+//	c, err := client.New(client.Config{BaseURL: "https://proxer.example.com"})
+//	if err != nil { ... }
+//	if _, err := c.Login(ctx, "admin", password); err != nil { ... }
+//	routes, err := c.ListRoutes(ctx, "default")
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const SessionCookieName = "proxer_session"
+
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 250 * time.Millisecond
+	maxRetryBackoff     = 5 * time.Second
+)
+
+type Config struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+	// MaxRetries is how often idempotent requests (GET, PUT, DELETE) are
+	// retried after network errors, 429 and 502-504 responses. Zero uses the
+	// default of 3; a negative value disables retries.
+	MaxRetries int
+	// RetryBackoff is the first retry delay, doubled per attempt. A
+	// Retry-After header from the gateway takes precedence.
+	RetryBackoff time.Duration
+}
+
+type Client struct {
+	baseURL      string
+	http         *http.Client
+	maxRetries   int
+	retryBackoff time.Duration
+
+	mu    sync.RWMutex
+	token string
+}
+
+func New(cfg Config) (*Client, error) {
+	baseURL := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	parsed, err := url.Parse(baseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: expected http(s)://host[:port]", cfg.BaseURL)
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	maxRetries := cfg.MaxRetries
+	switch {
+	case maxRetries == 0:
+		maxRetries = defaultMaxRetries
+	case maxRetries < 0:
+		maxRetries = 0
+	}
+	backoff := cfg.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	return &Client{
+		baseURL:      baseURL,
+		http:         httpClient,
+		maxRetries:   maxRetries,
+		retryBackoff: backoff,
+		token:        strings.TrimSpace(cfg.Token),
+	}, nil
+}
+
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = strings.TrimSpace(token)
+}
+
+func (c *Client) Do(ctx context.Context, method, path string, body, out any) error {
+	_, err := c.do(ctx, method, path, body, out)
+	return err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: encode request: %w", method, path, err)
+		}
+		payload = encoded
+	}
+
+	retries := 0
+	if idempotent(method) {
+		retries = c.maxRetries
+	}
+	for attempt := 0; ; attempt++ {
+		resp, data, err := c.send(ctx, method, path, payload)
+		if (err == nil && !retryableStatus(resp.StatusCode)) || attempt >= retries {
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			return resp, c.decode(method, path, resp, data, out)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		delay := c.retryBackoff << attempt
+		if resp != nil {
+			if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds >= 0 {
+				delay = time.Duration(seconds) * time.Second
+			}
+		}
+		delay = min(delay, maxRetryBackoff)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, payload []byte) (*http.Response, []byte, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
+
+func (c *Client) decode(method, path string, resp *http.Response, data []byte, out any) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newAPIError(method, path, resp.StatusCode, data)
+	}
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s %s: decode response: %w", method, path, err)
+	}
+	return nil
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientRetriesIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer session-1" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPost {
+			calls.Add(1)
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		if calls.Add(1) < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"tenants":[{"id":"default","name":"Default"}]}`))
+	}))
+	defer server.Close()
+
+	c, err := New(Config{BaseURL: server.URL, Token: "session-1", RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	tenants, err := c.ListTenants(context.Background())
+	if err != nil {
+		t.Fatalf("list tenants: %v", err)
+	}
+	if len(tenants) != 1 || tenants[0].ID != "default" || calls.Load() != 3 {
+		t.Fatalf("expected default tenant after 3 attempts, got %+v after %d", tenants, calls.Load())
+	}
+
+	calls.Store(0)
+	_, err = c.UpsertTenant(context.Background(), TenantInput{ID: "acme"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 APIError, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected POST not to be retried, got %d attempts", calls.Load())
+	}
+
+	c.SetToken("expired")
+	if _, err := c.ListTenants(context.Background()); !IsUnauthorized(err) {
+		t.Fatalf("expected unauthorized error, got %v", err)
+	}
+}
+
+func TestNewRejectsInvalidBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"", "127.0.0.1:8080", "ftp://gateway"} {
+		if _, err := New(Config{BaseURL: baseURL}); err == nil {
+			t.Fatalf("expected %q to be rejected", baseURL)
+		}
+	}
+}
+
+func TestClientDecodesErrorEnvelope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"code":"plan_limit_exceeded","message":"plan \"free\" route limit reached: 1/1","request_id":"gw-1-1"}`))
+	}))
+	defer server.Close()
+
+	c, err := New(Config{BaseURL: server.URL, Token: "session-1"})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	_, err = c.UpsertRoute(context.Background(), "default", RouteInput{ID: "second"})
+	if !HasCode(err, CodePlanLimitExceeded) {
+		t.Fatalf("expected plan_limit_exceeded, got %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.RequestID != "gw-1-1" || apiErr.Message != `plan "free" route limit reached: 1/1` {
+		t.Fatalf("unexpected error fields: %+v", apiErr)
+	}
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	CodeInvalidRequest         = "invalid_request"
+	CodeUnauthorized           = "unauthorized"
+	CodeInvalidCredentials     = "invalid_credentials"
+	CodeForbidden              = "forbidden"
+	CodeSuperAdminRequired     = "super_admin_required"
+	CodeTenantAdminRequired    = "tenant_admin_required"
+	CodeTenantAccessDenied     = "tenant_access_denied"
+	CodeConnectorAccessDenied  = "connector_access_denied"
+	CodePlanLimitExceeded      = "plan_limit_exceeded"
+	CodePlanNotAvailable       = "plan_not_available"
+	CodePlanFeatureUnavailable = "plan_feature_unavailable"
+	CodeSignupDisabled         = "signup_disabled"
+	CodeNotFound               = "not_found"
+	CodeTenantNotFound         = "tenant_not_found"
+	CodeRouteNotFound          = "route_not_found"
+	CodeDomainNotFound         = "domain_not_found"
+	CodeConnectorNotFound      = "connector_not_found"
+	CodePlanNotFound           = "plan_not_found"
+	CodeMethodNotAllowed       = "method_not_allowed"
+	CodeConflict               = "conflict"
+	CodeUsernameTaken          = "username_taken"
+	CodeDomainTaken            = "domain_taken"
+	CodePayloadTooLarge        = "payload_too_large"
+	CodeRateLimited            = "rate_limited"
+	CodeInternal               = "internal_error"
+)
+
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Code       string
+	Message    string
+	Details    map[string]any
+	RequestID  string
+}
+
+func (e *APIError) Error() string {
+	message := e.Message
+	if e.Code != "" {
+		message = e.Code + ": " + message
+	}
+	if e.RequestID != "" {
+		message += " (request " + e.RequestID + ")"
+	}
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode), message)
+}
+
+func HasCode(err error, code string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
+
+func newAPIError(method, path string, status int, body []byte) *APIError {
+	apiErr := &APIError{Method: method, Path: path, StatusCode: status}
+	var envelope struct {
+		Code      string         `json:"code"`
+		Message   string         `json:"message"`
+		Details   map[string]any `json:"details"`
+		RequestID string         `json:"request_id"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && envelope.Code != "" {
+		apiErr.Code = envelope.Code
+		apiErr.Message = envelope.Message
+		apiErr.Details = envelope.Details
+		apiErr.RequestID = envelope.RequestID
+		return apiErr
+	}
+	// Responses from proxies in front of the gateway are not JSON.
+	apiErr.Message = strings.TrimSpace(string(body))
+	return apiErr
+}
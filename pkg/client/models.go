@@ -0,0 +1,409 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type User struct {
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	TenantID  string    `json:"tenant_id"`
+	OrgID     string    `json:"org_id,omitempty"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UserInput struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Role     string `json:"role,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
+	OrgID    string `json:"org_id,omitempty"`
+	Status   string `json:"status,omitempty"`
+}
+
+const (
+	NotifyOff       = "off"
+	NotifyImmediate = "immediate"
+	NotifyDigest    = "digest"
+)
+
+type NotificationPreferences struct {
+	Email      string    `json:"email"`
+	Mode       string    `json:"mode"`
+	EventTypes []string  `json:"event_types"`
+	UpdatedAt  time.Time `json:"updated_at,omitempty"`
+}
+
+type Impersonation struct {
+	ImpersonatedBy string    `json:"impersonated_by"`
+	Username       string    `json:"username"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type Tenant struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Status     string            `json:"status,omitempty"`
+	Suspension *TenantSuspension `json:"suspension,omitempty"`
+	RouteCount int               `json:"route_count,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+type TenantSuspension struct {
+	Reason      string    `json:"reason,omitempty"`
+	StatusCode  int       `json:"status_code"`
+	Message     string    `json:"message"`
+	SuspendedBy string    `json:"suspended_by"`
+	SuspendedAt time.Time `json:"suspended_at"`
+}
+
+type TenantSuspensionInput struct {
+	Reason     string `json:"reason,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+type TenantInput struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+type LocalTLS struct {
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+	PinnedSHA256       string `json:"pinned_sha256,omitempty"`
+	CAPEM              string `json:"ca_pem,omitempty"`
+	ServerName         string `json:"server_name,omitempty"`
+}
+
+type RetryPolicy struct {
+	Attempts      int   `json:"attempts"`
+	BackoffMs     int64 `json:"backoff_ms,omitempty"`
+	MaxBackoffMs  int64 `json:"max_backoff_ms,omitempty"`
+	RetryOnStatus []int `json:"retry_on_status,omitempty"`
+}
+
+// RouteInput creates or replaces a route. Upserts replace the whole route,
+// so send every field to keep, including Token for protected routes.
+//
+// The nested policies (CORS, rewrite, forwarded headers, mirror, split,
+// middleware, mock, path routes and error pages) are passed through as raw JSON in the shape the
+// gateway documents in its OpenAPI document at /api/openapi.json.
+type RouteInput struct {
+	ID                   string            `json:"id"`
+	Target               string            `json:"target,omitempty"`
+	Environment          string            `json:"environment,omitempty"`
+	Token                string            `json:"token,omitempty"`
+	MaxRPS               float64           `json:"max_rps,omitempty"`
+	MaxBytesPerSecond    int64             `json:"max_bytes_per_second,omitempty"`
+	RequestTimeoutSecs   int               `json:"request_timeout_seconds,omitempty"`
+	IdleTimeoutSecs      int               `json:"idle_timeout_seconds,omitempty"`
+	HeaderTimeoutSecs    int               `json:"response_header_timeout_seconds,omitempty"`
+	MaxResponseBodyBytes int64             `json:"max_response_body_bytes,omitempty"`
+	MaxUploadBytes       int64             `json:"max_upload_bytes,omitempty"`
+	Retry                *RetryPolicy      `json:"retry,omitempty"`
+	ConnectorID          string            `json:"connector_id,omitempty"`
+	ConnectorSelector    map[string]string `json:"connector_selector,omitempty"`
+	LocalScheme          string            `json:"local_scheme,omitempty"`
+	LocalHost            string            `json:"local_host,omitempty"`
+	LocalPort            int               `json:"local_port,omitempty"`
+	LocalSocket          string            `json:"local_socket,omitempty"`
+	LocalTLS             *LocalTLS         `json:"local_tls,omitempty"`
+	LocalBasePath        string            `json:"local_base_path,omitempty"`
+	ActiveFrom           *time.Time        `json:"active_from,omitempty"`
+	ExpiresAt            *time.Time        `json:"expires_at,omitempty"`
+	TTL                  string            `json:"ttl,omitempty"`
+	DeleteOnExpiry       bool              `json:"delete_on_expiry,omitempty"`
+
+	ErrorPages          json.RawMessage `json:"error_pages,omitempty"`
+	CORS                json.RawMessage `json:"cors,omitempty"`
+	Presets             json.RawMessage `json:"presets,omitempty"`
+	PathRoutes          json.RawMessage `json:"path_routes,omitempty"`
+	Rewrite             json.RawMessage `json:"rewrite,omitempty"`
+	ForwardedHeaders    json.RawMessage `json:"forwarded_headers,omitempty"`
+	Idempotency         json.RawMessage `json:"idempotency,omitempty"`
+	QueueOverflow       json.RawMessage `json:"queue_overflow,omitempty"`
+	SyntheticCheck      json.RawMessage `json:"synthetic_check,omitempty"`
+	TLSPassthrough      json.RawMessage `json:"tls_passthrough,omitempty"`
+	Mirror              json.RawMessage `json:"mirror,omitempty"`
+	Split               json.RawMessage `json:"split,omitempty"`
+	Middleware          json.RawMessage `json:"middleware,omitempty"`
+	Mock                json.RawMessage `json:"mock,omitempty"`
+	Capture             json.RawMessage `json:"capture,omitempty"`
+	WebhookVerification json.RawMessage `json:"webhook_verification,omitempty"`
+}
+
+type Route struct {
+	RouteInput
+	TenantID        string                `json:"tenant_id"`
+	PublicURL       string                `json:"public_url"`
+	LegacyPublicURL string                `json:"legacy_public_url,omitempty"`
+	ScheduleState   string                `json:"schedule_state"`
+	ExpiresInSecs   *int64                `json:"expires_in_seconds,omitempty"`
+	TokenConfigured bool                  `json:"token_configured"`
+	Connected       bool                  `json:"connected"`
+	AgentID         string                `json:"agent_id,omitempty"`
+	Metrics         RouteMetrics          `json:"metrics"`
+	Health          *TargetHealth         `json:"health,omitempty"`
+	SyntheticStatus *SyntheticCheckStatus `json:"synthetic_status,omitempty"`
+	CreatedAt       time.Time             `json:"created_at"`
+	UpdatedAt       time.Time             `json:"updated_at"`
+}
+
+// Input returns the route's definition for a later UpsertRoute. Route tokens
+// are never returned by the gateway, so set Token again on protected routes.
+func (r Route) Input() RouteInput {
+	return r.RouteInput
+}
+
+type RouteMetrics struct {
+	RequestCount     int64     `json:"request_count"`
+	ErrorCount       int64     `json:"error_count"`
+	TimeoutCount     int64     `json:"timeout_count"`
+	RetryCount       int64     `json:"retry_count"`
+	WebhooksVerified int64     `json:"webhooks_verified,omitempty"`
+	WebhooksRejected int64     `json:"webhooks_rejected,omitempty"`
+	BytesIn          int64     `json:"bytes_in"`
+	BytesOut         int64     `json:"bytes_out"`
+	AverageLatencyMs float64   `json:"average_latency_ms"`
+	LastStatus       int       `json:"last_status"`
+	LastError        string    `json:"last_error,omitempty"`
+	LastSeen         time.Time `json:"last_seen,omitempty"`
+}
+
+type TargetHealth struct {
+	Target              string    `json:"target"`
+	Status              string    `json:"status"`
+	ConsecutiveFailures int       `json:"consecutive_failures,omitempty"`
+	Error               string    `json:"error,omitempty"`
+	CheckedAt           time.Time `json:"checked_at"`
+}
+
+type SyntheticCheckStatus struct {
+	Status              string     `json:"status"`
+	ConsecutiveFailures int        `json:"consecutive_failures,omitempty"`
+	Checks              int64      `json:"checks"`
+	Failures            int64      `json:"failures"`
+	UptimePercent       float64    `json:"uptime_percent"`
+	LastStatusCode      int        `json:"last_status_code,omitempty"`
+	LastLatencyMs       int64      `json:"last_latency_ms"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastCheckedAt       *time.Time `json:"last_checked_at,omitempty"`
+}
+
+type Connector struct {
+	ID                string            `json:"id"`
+	TenantID          string            `json:"tenant_id"`
+	Name              string            `json:"name"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	MaxBytesPerSecond int64             `json:"max_bytes_per_second,omitempty"`
+	Connected         bool              `json:"connected"`
+	AgentID           string            `json:"agent_id,omitempty"`
+	AgentVersion      string            `json:"agent_version,omitempty"`
+	ProtocolVersion   int               `json:"protocol_version,omitempty"`
+	Outdated          bool              `json:"outdated,omitempty"`
+	OutdatedReason    string            `json:"outdated_reason,omitempty"`
+	LastSeen          time.Time         `json:"last_seen,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+}
+
+type RouteVersion struct {
+	Version    int                `json:"version"`
+	Actor      string             `json:"actor"`
+	Source     string             `json:"source"`
+	RollbackTo int                `json:"rollback_to,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	Changes    []RouteFieldChange `json:"changes"`
+	Route      RouteInput         `json:"route"`
+}
+
+type RouteFieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from,omitempty"`
+	To    json.RawMessage `json:"to,omitempty"`
+}
+
+type TrashItem struct {
+	Kind      string      `json:"kind"`
+	TenantID  string      `json:"tenant_id"`
+	ID        string      `json:"id"`
+	DeletedBy string      `json:"deleted_by"`
+	DeletedAt time.Time   `json:"deleted_at"`
+	PurgeAt   time.Time   `json:"purge_at"`
+	Route     *RouteInput `json:"route,omitempty"`
+	Connector *Connector  `json:"connector,omitempty"`
+}
+
+type ConnectorInput struct {
+	ID                string            `json:"id"`
+	TenantID          string            `json:"tenant_id"`
+	Name              string            `json:"name,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	MaxBytesPerSecond int64             `json:"max_bytes_per_second,omitempty"`
+}
+
+type Domain struct {
+	Hostname          string          `json:"hostname"`
+	TenantID          string          `json:"tenant_id"`
+	RouteID           string          `json:"route_id"`
+	Status            string          `json:"status"`
+	VerificationToken string          `json:"verification_token"`
+	LastError         string          `json:"last_error,omitempty"`
+	CheckedAt         time.Time       `json:"checked_at,omitempty"`
+	VerifiedAt        time.Time       `json:"verified_at,omitempty"`
+	Challenge         DomainChallenge `json:"challenge"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+}
+
+type DomainChallenge struct {
+	TXTName     string `json:"txt_name"`
+	TXTValue    string `json:"txt_value"`
+	CNAMEName   string `json:"cname_name,omitempty"`
+	CNAMETarget string `json:"cname_target,omitempty"`
+	PointTo     string `json:"point_to,omitempty"`
+}
+
+type ConnectorPairing struct {
+	Connector Connector `json:"connector"`
+	PairToken struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	} `json:"pair_token"`
+	Command string `json:"command"`
+}
+
+type Plan struct {
+	ID                    string    `json:"id"`
+	Version               int       `json:"version,omitempty"`
+	Name                  string    `json:"name"`
+	Description           string    `json:"description"`
+	MaxRoutes             int       `json:"max_routes"`
+	MaxConnectors         int       `json:"max_connectors"`
+	MaxRPS                float64   `json:"max_rps"`
+	MaxMonthlyGB          float64   `json:"max_monthly_gb"`
+	TLSEnabled            bool      `json:"tls_enabled"`
+	PriceMonthlyUSD       float64   `json:"price_monthly_usd"`
+	PriceAnnualUSD        float64   `json:"price_annual_usd"`
+	PublicOrder           int       `json:"public_order"`
+	SelfServe             bool      `json:"self_serve"`
+	MaxRequestTimeoutSecs int       `json:"max_request_timeout_seconds,omitempty"`
+	CreatedBy             string    `json:"created_by,omitempty"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+type PlanAssignment struct {
+	TenantID          string     `json:"tenant_id"`
+	PlanID            string     `json:"plan_id"`
+	PlanVersion       int        `json:"plan_version,omitempty"`
+	PreviousPlanID    string     `json:"previous_plan_id,omitempty"`
+	ProratedAmountUSD float64    `json:"prorated_amount_usd"`
+	PeriodEnd         *time.Time `json:"period_end,omitempty"`
+	AssignedBy        string     `json:"assigned_by"`
+	AssignedAt        time.Time  `json:"assigned_at"`
+}
+
+type PlanVersion struct {
+	Plan    Plan     `json:"plan"`
+	Current bool     `json:"current"`
+	Tenants []string `json:"tenants"`
+}
+
+type Organization struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	TenantIDs []string  `json:"tenant_ids"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type OrgToken struct {
+	ID         string     `json:"id"`
+	OrgID      string     `json:"org_id"`
+	Name       string     `json:"name"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type OrgTenantUsage struct {
+	TenantID          string  `json:"tenant_id"`
+	PlanID            string  `json:"plan_id"`
+	PlanVersion       int     `json:"plan_version"`
+	PriceMonthlyUSD   float64 `json:"price_monthly_usd"`
+	ProratedAmountUSD float64 `json:"prorated_amount_usd"`
+	Usage             Usage   `json:"usage"`
+}
+
+type OrgUsageTotals struct {
+	Tenants           int     `json:"tenants"`
+	RoutesUsed        int     `json:"routes_used"`
+	ConnectorsUsed    int     `json:"connectors_used"`
+	BytesIn           int64   `json:"bytes_in"`
+	BytesOut          int64   `json:"bytes_out"`
+	Requests          int64   `json:"requests"`
+	BlockedRequests   int64   `json:"blocked_requests"`
+	PriceMonthlyUSD   float64 `json:"price_monthly_usd"`
+	ProratedAmountUSD float64 `json:"prorated_amount_usd"`
+	AmountDueUSD      float64 `json:"amount_due_usd"`
+}
+
+type OrgUsage struct {
+	OrgID    string           `json:"org_id"`
+	MonthKey string           `json:"month_key"`
+	Tenants  []OrgTenantUsage `json:"tenants"`
+	Totals   OrgUsageTotals   `json:"totals"`
+}
+
+type Usage struct {
+	TenantID        string    `json:"tenant_id"`
+	MonthKey        string    `json:"month_key"`
+	RoutesUsed      int       `json:"routes_used"`
+	ConnectorsUsed  int       `json:"connectors_used"`
+	BytesIn         int64     `json:"bytes_in"`
+	BytesOut        int64     `json:"bytes_out"`
+	Requests        int64     `json:"requests"`
+	BlockedRequests int64     `json:"blocked_requests"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type PlanUsage struct {
+	TenantID string          `json:"tenant_id"`
+	PlanID   string          `json:"plan_id"`
+	Plan     Plan            `json:"plan"`
+	Usage    Usage           `json:"usage"`
+	Transfer TransferSummary `json:"transfer"`
+}
+
+type TransferSummary struct {
+	From       string           `json:"from"`
+	To         string           `json:"to"`
+	Routes     []TransferTotal  `json:"routes"`
+	Connectors []TransferTotal  `json:"connectors"`
+	Daily      []TransferRecord `json:"daily,omitempty"`
+}
+
+type TransferTotal struct {
+	TenantID     string `json:"tenant_id"`
+	ID           string `json:"id"`
+	IngressBytes int64  `json:"ingress_bytes"`
+	EgressBytes  int64  `json:"egress_bytes"`
+	TotalBytes   int64  `json:"total_bytes"`
+	Requests     int64  `json:"requests"`
+}
+
+type TransferRecord struct {
+	TenantID     string `json:"tenant_id"`
+	Date         string `json:"date"`
+	Kind         string `json:"kind"`
+	ID           string `json:"id"`
+	IngressBytes int64  `json:"ingress_bytes"`
+	EgressBytes  int64  `json:"egress_bytes"`
+	Requests     int64  `json:"requests"`
+}
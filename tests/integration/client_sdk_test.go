@@ -0,0 +1,106 @@
+package integration_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/gateway"
+	"github.com/szaher/try/proxer/pkg/client"
+)
+
+func TestClientSDKManagesTenantsRoutesConnectorsAndPlans(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:    "127.0.0.1:0",
+		AgentToken:    "test-token",
+		PublicBaseURL: "http://localhost:8080",
+		StorageDriver: "memory",
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+
+	sdk, err := client.New(client.Config{BaseURL: "http://" + gatewayAddr})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	if _, err := sdk.ListTenants(ctx); !client.IsUnauthorized(err) {
+		t.Fatalf("expected unauthorized before login, got %v", err)
+	}
+	user, err := sdk.Login(ctx, "admin", "admin123")
+	if err != nil || user.Username != "admin" || sdk.Token() == "" {
+		t.Fatalf("login: user=%+v err=%v", user, err)
+	}
+
+	if _, err := sdk.UpsertTenant(ctx, client.TenantInput{ID: "acme", Name: "Acme"}); err != nil {
+		t.Fatalf("upsert tenant: %v", err)
+	}
+	route, err := sdk.UpsertRoute(ctx, "acme", client.RouteInput{ID: "app", Target: "http://127.0.0.1:3000", MaxRPS: 5})
+	if err != nil {
+		t.Fatalf("upsert route: %v", err)
+	}
+	if route.PublicURL == "" || route.TenantID != "acme" {
+		t.Fatalf("expected route view with public URL, got %+v", route)
+	}
+	input := route.Input()
+	input.MaxRPS = 9
+	if _, err := sdk.UpsertRoute(ctx, "acme", input); err != nil {
+		t.Fatalf("update route: %v", err)
+	}
+	fetched, err := sdk.GetRoute(ctx, "acme", "app")
+	if err != nil || fetched.MaxRPS != 9 || fetched.Target != "http://127.0.0.1:3000" {
+		t.Fatalf("expected updated route, got %+v err=%v", fetched, err)
+	}
+
+	connector, err := sdk.CreateConnector(ctx, client.ConnectorInput{ID: "laptop", TenantID: "acme", Labels: map[string]string{"os": "mac"}})
+	if err != nil || connector.TenantID != "acme" {
+		t.Fatalf("create connector: %+v err=%v", connector, err)
+	}
+	pairing, err := sdk.PairConnector(ctx, "laptop")
+	if err != nil || pairing.PairToken.Token == "" {
+		t.Fatalf("pair connector: %+v err=%v", pairing, err)
+	}
+
+	plans, err := sdk.ListPlans(ctx)
+	if err != nil || len(plans) == 0 {
+		t.Fatalf("list plans: %v err=%v", plans, err)
+	}
+	assignment, err := sdk.AssignPlan(ctx, "acme", plans[0].ID)
+	if err != nil || assignment.PlanID != plans[0].ID {
+		t.Fatalf("assign plan: %+v err=%v", assignment, err)
+	}
+	usage, err := sdk.AllUsage(ctx)
+	if err != nil {
+		t.Fatalf("all usage: %v", err)
+	}
+	found := false
+	for _, item := range usage {
+		if item.TenantID == "acme" {
+			found = item.PlanID == plans[0].ID && item.Usage.RoutesUsed == 1
+		}
+	}
+	if !found {
+		t.Fatalf("expected acme usage on plan %s with one route, got %+v", plans[0].ID, usage)
+	}
+
+	if err := sdk.DeleteRoute(ctx, "acme", "app"); err != nil {
+		t.Fatalf("delete route: %v", err)
+	}
+	if _, err := sdk.GetRoute(ctx, "acme", "app"); !client.IsNotFound(err) {
+		t.Fatalf("expected deleted route to be not found, got %v", err)
+	}
+	if err := sdk.Logout(ctx); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package integration_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/agent"
+	"github.com/szaher/try/proxer/internal/echoserver"
+	"github.com/szaher/try/proxer/internal/gateway"
+)
+
+// fakeDocker serves the two Docker Engine API calls the agent's Docker watch
+// makes: the running container list and the event stream.
+type fakeDocker struct {
+	mu         sync.Mutex
+	containers []map[string]any
+	events     chan struct{}
+}
+
+func (d *fakeDocker) set(containers ...map[string]any) {
+	d.mu.Lock()
+	d.containers = containers
+	d.mu.Unlock()
+	d.events <- struct{}{}
+}
+
+func (d *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/containers/json":
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(d.containers)
+	case "/events":
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-d.events:
+				_, _ = io.WriteString(w, `{"Type":"container","Action":"start"}`+"\n")
+				w.(http.Flusher).Flush()
+			}
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func labelledContainer(name, route string, publishedPort int) map[string]any {
+	return map[string]any{
+		"Id":     name + "-id",
+		"Names":  []string{"/" + name},
+		"Labels": map[string]string{agent.DockerLabelRoute: route, agent.DockerLabelPort: "3000"},
+		"Ports":  []map[string]any{{"IP": "0.0.0.0", "PrivatePort": 3000, "PublicPort": publishedPort, "Type": "tcp"}},
+	}
+}
+
+func TestAgentDockerWatchAddsAndRemovesContainerTunnels(t *testing.T) {
+	web := startEchoServer(t, echoserver.Options{Name: "web"})
+	defer web.Close(t)
+	api := startEchoServer(t, echoserver.Options{Name: "api"})
+	defer api.Close(t)
+
+	// t.TempDir paths can exceed the unix socket path limit.
+	socketDir, err := os.MkdirTemp("", "proxer-docker")
+	if err != nil {
+		t.Fatalf("create socket dir: %v", err)
+	}
+	defer os.RemoveAll(socketDir)
+	socketPath := filepath.Join(socketDir, "docker.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("listen on docker socket: %v", err)
+	}
+	docker := &fakeDocker{events: make(chan struct{}, 4)}
+	dockerServer := &http.Server{Handler: docker}
+	go func() { _ = dockerServer.Serve(listener) }()
+	defer dockerServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		AgentToken:     "test-token",
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 5 * time.Second,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	agentClient := agent.New(agent.Config{
+		GatewayBaseURL:       fmt.Sprintf("http://%s", gatewayAddr),
+		AgentToken:           "test-token",
+		AgentID:              "docker-agent",
+		HeartbeatInterval:    200 * time.Millisecond,
+		RequestTimeout:       5 * time.Second,
+		PollWait:             5 * time.Second,
+		MaxResponseBodyBytes: 1 << 20,
+		Docker:               &agent.DockerWatch{Socket: socketPath, Address: agent.DockerAddressPublished},
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = agentClient.Run(ctx) }()
+
+	port := func(server *testHTTPServer) int {
+		_, raw, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+		value, _ := strconv.Atoi(raw)
+		return value
+	}
+	docker.set(labelledContainer("stack-web-1", "web", port(web)), labelledContainer("stack-api-1", "api", port(api)))
+
+	tunnelIDs := func() map[string]bool {
+		ids := make(map[string]bool)
+		if response := fetchTunnelResponseNoFail(authedClient, fmt.Sprintf("http://%s/api/tunnels", gatewayAddr)); response != nil {
+			for _, tunnel := range response.Tunnels {
+				ids[tunnel.ID] = true
+			}
+		}
+		return ids
+	}
+	waitForTunnels := func(expected ...string) {
+		t.Helper()
+		deadline := time.Now().Add(8 * time.Second)
+		for {
+			ids := tunnelIDs()
+			matched := len(ids) == len(expected)
+			for _, id := range expected {
+				matched = matched && ids[id]
+			}
+			if matched {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected tunnels %v, got %v", expected, ids)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	waitForTunnels("api", "web")
+	mustProxyRequest(t, fmt.Sprintf("http://%s/t/web/", gatewayAddr), "web")
+	mustProxyRequest(t, fmt.Sprintf("http://%s/t/api/", gatewayAddr), "api")
+
+	// Well within the 5s poll wait, so the change cuts the idle pull short.
+	docker.set(labelledContainer("stack-web-1", "web", port(web)))
+	waitForTunnels("web")
+	mustProxyRequest(t, fmt.Sprintf("http://%s/t/web/", gatewayAddr), "web")
+}
@@ -0,0 +1,177 @@
+package integration_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/agent"
+	"github.com/szaher/try/proxer/internal/echoserver"
+	"github.com/szaher/try/proxer/internal/gateway"
+	"github.com/szaher/try/proxer/internal/protocol"
+)
+
+// faultTestGateway runs a dev-mode gateway with one agent tunnel "app" to an
+// echo server and returns the gateway address, an admin client and the
+// agent's log.
+func faultTestGateway(t *testing.T, ctx context.Context, proxyTimeout time.Duration) (*gateway.Server, string, *http.Client, *lockedBuffer) {
+	t.Helper()
+	target := startEchoServer(t, echoserver.Options{Name: "app"})
+	t.Cleanup(func() { target.Close(t) })
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:          "127.0.0.1:0",
+		AgentToken:          "test-token",
+		PublicBaseURL:       "http://localhost:8080",
+		RequestTimeout:      5 * time.Second,
+		ProxyRequestTimeout: proxyTimeout,
+		DevMode:             true,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	agentLog := &lockedBuffer{}
+	agentClient := agent.New(agent.Config{
+		GatewayBaseURL:       fmt.Sprintf("http://%s", gatewayAddr),
+		AgentToken:           "test-token",
+		AgentID:              "fault-agent",
+		HeartbeatInterval:    200 * time.Millisecond,
+		RequestTimeout:       5 * time.Second,
+		PollWait:             1 * time.Second,
+		MaxResponseBodyBytes: 1 << 20,
+		Tunnels:              []protocol.TunnelConfig{{ID: "app", Target: target.URL}},
+	}, log.New(agentLog, "", 0))
+	go func() { _ = agentClient.Run(ctx) }()
+	if err := waitForTunnelCount(authedClient, fmt.Sprintf("http://%s/api/tunnels", gatewayAddr), 1, 8*time.Second); err != nil {
+		t.Fatalf("tunnel was not registered: %v", err)
+	}
+	return gatewayServer, gatewayAddr, authedClient, agentLog
+}
+
+// waitForFaultProxyStatus polls the app tunnel until it answers expected.
+func waitForFaultProxyStatus(t *testing.T, gatewayAddr string, expected int, timeout time.Duration) string {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		response, err := http.Get(fmt.Sprintf("http://%s/t/app/", gatewayAddr))
+		if err != nil {
+			t.Fatalf("proxy request: %v", err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		_ = response.Body.Close()
+		if response.StatusCode == expected {
+			return string(body)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected status %d, last got %d (%s)", expected, response.StatusCode, body)
+		}
+		// Stay under the free plan's per-route request rate.
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func TestFaultInjectionDroppedResponsesTimeOutAndRaiseIncidents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gatewayServer, gatewayAddr, authedClient, _ := faultTestGateway(t, ctx, 500*time.Millisecond)
+	waitForFaultProxyStatus(t, gatewayAddr, http.StatusOK, 5*time.Second)
+
+	gatewayServer.SetFaultInjection(gateway.FaultInjection{DropResponsePercent: 100})
+	waitForFaultProxyStatus(t, gatewayAddr, http.StatusGatewayTimeout, 5*time.Second)
+
+	response, err := authedClient.Get(fmt.Sprintf("http://%s/api/admin/incidents", gatewayAddr))
+	if err != nil {
+		t.Fatalf("list incidents: %v", err)
+	}
+	var incidents struct {
+		Incidents []gateway.SystemIncident `json:"incidents"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&incidents)
+	_ = response.Body.Close()
+	if err != nil {
+		t.Fatalf("decode incidents: %v", err)
+	}
+	found := false
+	for _, incident := range incidents.Incidents {
+		if incident.Source == "proxy" && incident.Severity == "critical" && strings.Contains(incident.Message, "timed out") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a critical proxy timeout incident, got %+v", incidents.Incidents)
+	}
+
+	response, err = authedClient.Get(fmt.Sprintf("http://%s/api/admin/stats", gatewayAddr))
+	if err != nil {
+		t.Fatalf("admin stats: %v", err)
+	}
+	var stats struct {
+		System gateway.HubStatus `json:"system"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&stats)
+	_ = response.Body.Close()
+	if err != nil {
+		t.Fatalf("decode stats: %v", err)
+	}
+	if stats.System.TimeoutCount == 0 {
+		t.Fatalf("expected dropped responses to count as timeouts, got %+v", stats.System)
+	}
+
+	gatewayServer.SetFaultInjection(gateway.FaultInjection{})
+	if body := waitForFaultProxyStatus(t, gatewayAddr, http.StatusOK, 5*time.Second); !strings.Contains(body, `"service":"app"`) {
+		t.Fatalf("unexpected body after recovery %q", body)
+	}
+}
+
+func TestFaultInjectionKilledSessionsReconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gatewayServer, gatewayAddr, _, agentLog := faultTestGateway(t, ctx, 2*time.Second)
+	waitForFaultProxyStatus(t, gatewayAddr, http.StatusOK, 5*time.Second)
+
+	gatewayServer.SetFaultInjection(gateway.FaultInjection{KillSessionPercent: 100})
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(agentLog.String(), "session expired; re-registering") {
+		if time.Now().After(deadline) {
+			t.Fatalf("agent never saw its session killed; log:\n%s", agentLog.String())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	gatewayServer.SetFaultInjection(gateway.FaultInjection{})
+	waitForFaultProxyStatus(t, gatewayAddr, http.StatusOK, 8*time.Second)
+}
+
+func TestFaultInjectionDispatchDelayCountsAgainstTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gatewayServer, gatewayAddr, _, _ := faultTestGateway(t, ctx, time.Second)
+	waitForFaultProxyStatus(t, gatewayAddr, http.StatusOK, 5*time.Second)
+
+	gatewayServer.SetFaultInjection(gateway.FaultInjection{DispatchDelay: 300 * time.Millisecond})
+	started := time.Now()
+	waitForFaultProxyStatus(t, gatewayAddr, http.StatusOK, time.Second)
+	if elapsed := time.Since(started); elapsed < 300*time.Millisecond {
+		t.Fatalf("expected the dispatch delay to hold the request, took %s", elapsed)
+	}
+
+	gatewayServer.SetFaultInjection(gateway.FaultInjection{DispatchDelay: 2 * time.Second})
+	started = time.Now()
+	waitForFaultProxyStatus(t, gatewayAddr, http.StatusGatewayTimeout, time.Second)
+	if elapsed := time.Since(started); elapsed > 1900*time.Millisecond {
+		t.Fatalf("expected the request to time out at the proxy timeout, took %s", elapsed)
+	}
+}
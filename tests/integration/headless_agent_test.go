@@ -0,0 +1,111 @@
+package integration_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/agent"
+	"github.com/szaher/try/proxer/internal/echoserver"
+	"github.com/szaher/try/proxer/internal/gateway"
+)
+
+func TestHeadlessAgentRunsFromConfigFileWithProbes(t *testing.T) {
+	target := startEchoServer(t, echoserver.Options{Name: "svc"})
+	defer target.Close(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		AgentToken:     "test-token",
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 5 * time.Second,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "agent-token")
+	if err := os.WriteFile(tokenFile, []byte("test-token\n"), 0o600); err != nil {
+		t.Fatalf("write token file: %v", err)
+	}
+	configFile := filepath.Join(dir, "agent.yaml")
+	config := fmt.Sprintf(`apiVersion: proxer.dev/v1alpha1
+kind: ProxerAgent
+metadata:
+  name: cluster-agent
+spec:
+  gatewayBaseURL: http://%s
+  agentTokenFile: %s
+  tunnels:
+    - id: svc
+      target: %s
+  runtime:
+    heartbeatInterval: 200ms
+    pollWait: 1s
+  probes:
+    listenAddr: 127.0.0.1:0
+`, gatewayAddr, tokenFile, target.URL)
+	if err := os.WriteFile(configFile, []byte(config), 0o600); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+
+	cfg, options, err := agent.LoadHeadlessConfig(configFile)
+	if err != nil {
+		t.Fatalf("load headless config: %v", err)
+	}
+	if cfg.AgentID != "cluster-agent" || cfg.AgentToken != "test-token" || options.ProbeListenAddr != "127.0.0.1:0" {
+		t.Fatalf("unexpected headless config %+v %+v", cfg, options)
+	}
+
+	probes := agent.NewProbes()
+	probeServer := httptest.NewServer(probes.Handler())
+	defer probeServer.Close()
+	probeStatus := func(path string) int {
+		response, err := http.Get(probeServer.URL + path)
+		if err != nil {
+			t.Fatalf("probe %s: %v", path, err)
+		}
+		_ = response.Body.Close()
+		return response.StatusCode
+	}
+	if probeStatus("/healthz") != http.StatusOK || probeStatus("/readyz") != http.StatusServiceUnavailable {
+		t.Fatalf("expected a starting agent to be live but not ready")
+	}
+
+	cfg.EventHook = probes.Observe
+	go func() { _ = agent.New(cfg, log.New(io.Discard, "", 0)).Run(ctx) }()
+	if err := waitForHTTP(probeServer.URL+"/readyz", 8*time.Second); err != nil {
+		t.Fatalf("agent never became ready: %v", err)
+	}
+	mustProxyRequest(t, fmt.Sprintf("http://%s/t/svc/", gatewayAddr), "svc")
+
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for probeStatus("/healthz") != http.StatusServiceUnavailable {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a stopped agent to fail its liveness probe")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if _, _, err := agent.ParseHeadlessConfig([]byte(strings.Replace(config, "pollWait", "pollWiat", 1))); err == nil {
+		t.Fatalf("expected an unknown field to be rejected")
+	}
+}
@@ -0,0 +1,154 @@
+package integration_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/szaher/try/proxer/internal/agent"
+	"github.com/szaher/try/proxer/internal/echoserver"
+	"github.com/szaher/try/proxer/internal/gateway"
+)
+
+func TestReverseForwardReachesGatewayServiceAfterApproval(t *testing.T) {
+	staging := startEchoServer(t, echoserver.Options{Name: "staging-api"})
+	defer staging.Close(t)
+	stagingAddr := strings.TrimPrefix(staging.URL, "http://")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:            "127.0.0.1:0",
+		AgentToken:            "test-token",
+		PublicBaseURL:         "http://localhost:8080",
+		RequestTimeout:        5 * time.Second,
+		ReverseForwardTargets: []string{"127.0.0.1:*"},
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	mustPostJSONStatus(t, authedClient, fmt.Sprintf("http://%s/api/connectors", gatewayAddr), map[string]string{
+		"id":        "laptop",
+		"tenant_id": "default",
+	}, http.StatusCreated)
+	pairResp, err := authedClient.Post(fmt.Sprintf("http://%s/api/connectors/laptop/pair", gatewayAddr), "application/json", nil)
+	if err != nil {
+		t.Fatalf("pair connector failed: %v", err)
+	}
+	var pairPayload struct {
+		PairToken struct {
+			Token string `json:"token"`
+		} `json:"pair_token"`
+	}
+	err = json.NewDecoder(pairResp.Body).Decode(&pairPayload)
+	_ = pairResp.Body.Close()
+	if err != nil || pairPayload.PairToken.Token == "" {
+		t.Fatalf("decode pair payload: %v", err)
+	}
+
+	probe, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("reserve local port: %v", err)
+	}
+	localAddr := probe.Addr().String()
+	_ = probe.Close()
+	go func() {
+		_ = agent.New(agent.Config{
+			GatewayBaseURL:    fmt.Sprintf("http://%s", gatewayAddr),
+			AgentID:           "laptop-agent",
+			HeartbeatInterval: 200 * time.Millisecond,
+			RequestTimeout:    5 * time.Second,
+			PollWait:          1 * time.Second,
+			PairToken:         pairPayload.PairToken.Token,
+			Forwards:          []agent.ReverseForward{{Listen: localAddr, Target: stagingAddr}},
+		}, log.New(io.Discard, "", 0)).Run(ctx)
+	}()
+
+	type forwardView struct {
+		ID     string `json:"id"`
+		Target string `json:"target"`
+		Status string `json:"status"`
+	}
+	listForwards := func() []forwardView {
+		resp, err := authedClient.Get(fmt.Sprintf("http://%s/api/tenants/default/forwards", gatewayAddr))
+		if err != nil {
+			t.Fatalf("list forwards: %v", err)
+		}
+		defer resp.Body.Close()
+		var payload struct {
+			Forwards []forwardView `json:"forwards"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+			t.Fatalf("decode forwards: %v", err)
+		}
+		return payload.Forwards
+	}
+	var forward forwardView
+	deadline := time.Now().Add(8 * time.Second)
+	for forward.ID == "" {
+		if time.Now().After(deadline) {
+			t.Fatalf("agent never filed its forward")
+		}
+		if forwards := listForwards(); len(forwards) == 1 {
+			forward = forwards[0]
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if forward.Status != "pending" || forward.Target != stagingAddr {
+		t.Fatalf("expected a pending forward to %s, got %+v", stagingAddr, forward)
+	}
+
+	localClient := &http.Client{Timeout: 2 * time.Second, Transport: &http.Transport{DisableKeepAlives: true}}
+	if resp, err := localClient.Get("http://" + localAddr + "/"); err == nil {
+		_ = resp.Body.Close()
+		t.Fatalf("expected a pending forward to refuse connections, got %d", resp.StatusCode)
+	}
+
+	mustPostJSONStatus(t, authedClient, fmt.Sprintf("http://%s/api/tenants/default/forwards/%s/approve", gatewayAddr, forward.ID), map[string]string{}, http.StatusOK)
+	var service string
+	deadline = time.Now().Add(8 * time.Second)
+	for service != "staging-api" {
+		if time.Now().After(deadline) {
+			t.Fatalf("forward never reached the staging service after approval")
+		}
+		time.Sleep(100 * time.Millisecond)
+		resp, err := localClient.Get("http://" + localAddr + "/")
+		if err != nil {
+			continue
+		}
+		var payload struct {
+			Service string `json:"service"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&payload)
+		_ = resp.Body.Close()
+		service = payload.Service
+	}
+
+	mustPostJSONStatus(t, authedClient, fmt.Sprintf("http://%s/api/tenants/default/forwards/%s/deny", gatewayAddr, forward.ID), map[string]string{}, http.StatusOK)
+	deadline = time.Now().Add(8 * time.Second)
+	for {
+		resp, err := localClient.Get("http://" + localAddr + "/")
+		if err != nil {
+			break
+		}
+		_ = resp.Body.Close()
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a denied forward to refuse connections")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
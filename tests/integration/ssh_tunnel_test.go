@@ -0,0 +1,93 @@
+package integration_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/szaher/try/proxer/internal/echoserver"
+	"github.com/szaher/try/proxer/internal/gateway"
+)
+
+func TestSSHRemoteForwardServesRoute(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		SSHListenAddr:  "127.0.0.1:0",
+		AgentToken:     "test-token",
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 5 * time.Second,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatalf("build signer: %v", err)
+	}
+	mustPostJSONStatus(t, authedClient, fmt.Sprintf("http://%s/api/tenants/default/ssh-keys", gatewayAddr), map[string]string{
+		"name":       "laptop",
+		"public_key": string(ssh.MarshalAuthorizedKey(signer.PublicKey())),
+	}, http.StatusCreated)
+	mustPostJSONStatus(t, authedClient, fmt.Sprintf("http://%s/api/tenants/default/routes", gatewayAddr), map[string]string{
+		"id":     "laptop-app",
+		"target": "http://127.0.0.1:9",
+	}, http.StatusOK)
+
+	// The equivalent of ssh -R 80:localhost:<port> laptop-app@gateway: the
+	// wildcard bind publishes the route named by the SSH user.
+	client, err := ssh.Dial("tcp", gatewayServer.SSHAddr(), &ssh.ClientConfig{
+		User:            "laptop-app",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("ssh dial: %v", err)
+	}
+	defer client.Close()
+	listener, err := client.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("remote forward: %v", err)
+	}
+	go func() { _ = http.Serve(listener, echoserver.Handler(echoserver.Options{Name: "ssh-laptop"})) }()
+
+	mustProxyRequest(t, fmt.Sprintf("http://%s/t/default/laptop-app/hello", gatewayAddr), "ssh-laptop")
+
+	_ = client.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get(fmt.Sprintf("http://%s/t/default/laptop-app/hello", gatewayAddr))
+		if err != nil {
+			t.Fatalf("proxy request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the route to go offline with the ssh client")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -3,39 +3,40 @@ package integration_test
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"image/png"
 	"io"
 	"log"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"net/http/httptest"
 	"net/url"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/szaher/try/proxer/internal/agent"
+	"github.com/szaher/try/proxer/internal/echoserver"
 	"github.com/szaher/try/proxer/internal/gateway"
 	"github.com/szaher/try/proxer/internal/protocol"
 )
 
 func TestGatewayRoutesMultiplePortsAndTracksMetrics(t *testing.T) {
-	targetOne := startTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		_, _ = w.Write([]byte(fmt.Sprintf(`{"service":"one","path":"%s","query":"%s"}`,
-			r.URL.Path, r.URL.RawQuery)))
-	}))
+	targetOne := startEchoServer(t, echoserver.Options{Name: "one"})
 	defer targetOne.Close(t)
 
-	targetTwo := startTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		_, _ = w.Write([]byte(fmt.Sprintf(`{"service":"two","path":"%s","query":"%s"}`,
-			r.URL.Path, r.URL.RawQuery)))
-	}))
+	targetTwo := startEchoServer(t, echoserver.Options{Name: "two"})
 	defer targetTwo.Close(t)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -306,26 +307,10 @@ func TestGatewayRuleAPIConfiguresDirectForwarding(t *testing.T) {
 }
 
 func TestMultiTenantRoutesCanReuseSameRouteID(t *testing.T) {
-	targetA := startTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]any{
-			"service": "team-a",
-			"tenant":  "team-a",
-			"path":    r.URL.Path,
-			"query":   r.URL.RawQuery,
-		})
-	}))
+	targetA := startEchoServer(t, echoserver.Options{Name: "team-a"})
 	defer targetA.Close(t)
 
-	targetB := startTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]any{
-			"service": "team-b",
-			"tenant":  "team-b",
-			"path":    r.URL.Path,
-			"query":   r.URL.RawQuery,
-		})
-	}))
+	targetB := startEchoServer(t, echoserver.Options{Name: "team-b"})
 	defer targetB.Close(t)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -454,14 +439,7 @@ func TestMultiTenantRoutesCanReuseSameRouteID(t *testing.T) {
 }
 
 func TestConnectorPairingCreatesSessionAndRoutesToLocalhostTarget(t *testing.T) {
-	target := startTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]any{
-			"service": "connector",
-			"path":    r.URL.Path,
-			"query":   r.URL.RawQuery,
-		})
-	}))
+	target := startEchoServer(t, echoserver.Options{Name: "connector"})
 	defer target.Close(t)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -515,6 +493,11 @@ func TestConnectorPairingCreatesSessionAndRoutesToLocalhostTarget(t *testing.T)
 		PairToken struct {
 			Token string `json:"token"`
 		} `json:"pair_token"`
+		DeepLink string `json:"deep_link"`
+		QRCode   struct {
+			SVG string `json:"svg"`
+			PNG []byte `json:"png"`
+		} `json:"qr_code"`
 	}
 	if err := json.NewDecoder(pairResp.Body).Decode(&pairPayload); err != nil {
 		t.Fatalf("decode pair payload: %v", err)
@@ -522,6 +505,15 @@ func TestConnectorPairingCreatesSessionAndRoutesToLocalhostTarget(t *testing.T)
 	if strings.TrimSpace(pairPayload.PairToken.Token) == "" {
 		t.Fatalf("missing pair token")
 	}
+	if _, linkToken, err := protocol.ParsePairLink(pairPayload.DeepLink); err != nil || linkToken != pairPayload.PairToken.Token {
+		t.Fatalf("unexpected pair deep link %q: %v", pairPayload.DeepLink, err)
+	}
+	if !strings.HasPrefix(pairPayload.QRCode.SVG, "<svg") {
+		t.Fatalf("expected an SVG pair QR code, got %q", pairPayload.QRCode.SVG)
+	}
+	if _, err := png.Decode(bytes.NewReader(pairPayload.QRCode.PNG)); err != nil {
+		t.Fatalf("decode pair QR code PNG: %v", err)
+	}
 
 	agentCfg := agent.Config{
 		GatewayBaseURL:       fmt.Sprintf("http://%s", gatewayAddr),
@@ -663,12 +655,66 @@ func TestGatewayReturns413ForOversizedRequestBody(t *testing.T) {
 	}
 }
 
+func TestAgentAbortsLocalResponsesOverItsLimit(t *testing.T) {
+	target := startEchoServer(t, echoserver.Options{Name: "app"})
+	defer target.Close(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		AgentToken:     "test-token",
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 5 * time.Second,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	agentClient := agent.New(agent.Config{
+		GatewayBaseURL:       fmt.Sprintf("http://%s", gatewayAddr),
+		AgentToken:           "test-token",
+		AgentID:              "limit-agent",
+		HeartbeatInterval:    200 * time.Millisecond,
+		RequestTimeout:       5 * time.Second,
+		PollWait:             1 * time.Second,
+		MaxResponseBodyBytes: 2048,
+		Tunnels:              []protocol.TunnelConfig{{ID: "app", Target: target.URL}},
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = agentClient.Run(ctx) }()
+	if err := waitForTunnelCount(authedClient, fmt.Sprintf("http://%s/api/tunnels", gatewayAddr), 1, 8*time.Second); err != nil {
+		t.Fatalf("tunnel was not registered: %v", err)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/t/app/?pad=%s", gatewayAddr, strings.Repeat("x", 4096)))
+	if err != nil {
+		t.Fatalf("send request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || resp.Header.Get("X-Proxer-Limit-Exceeded") != "response-body; source=agent; limit=2048" {
+		t.Fatalf("expected the agent limit to cut off the response, got %d %q body=%s", resp.StatusCode, resp.Header.Get("X-Proxer-Limit-Exceeded"), body)
+	}
+	var apiErr struct {
+		Code    string         `json:"code"`
+		Details map[string]any `json:"details"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Code != "response_too_large" || apiErr.Details["limit_bytes"] != float64(2048) {
+		t.Fatalf("expected a response_too_large error, got %s (%v)", body, err)
+	}
+
+	mustProxyRequest(t, fmt.Sprintf("http://%s/t/app/", gatewayAddr), "app")
+}
+
 func TestGatewayReturns503WhenBackpressureLimitIsHit(t *testing.T) {
-	target := startTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		time.Sleep(500 * time.Millisecond)
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]any{"service": "slow"})
-	}))
+	target := startEchoServer(t, echoserver.Options{Name: "slow", Latency: 500 * time.Millisecond})
 	defer target.Close(t)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -930,11 +976,7 @@ func TestSuperAdminBootstrapCanAccessAdminEndpoints(t *testing.T) {
 		t.Fatalf("gateway health never became ready: %v", err)
 	}
 
-	jar, err := cookiejar.New(nil)
-	if err != nil {
-		t.Fatalf("create cookie jar: %v", err)
-	}
-	client := &http.Client{Jar: jar}
+	client := newConsoleClient(t)
 	mustPostJSONStatus(t, client, fmt.Sprintf("http://%s/api/auth/login", gatewayAddr), map[string]string{
 		"username": "root-admin",
 		"password": "root-pass-123",
@@ -992,11 +1034,7 @@ func TestSQLiteStatePersistenceAcrossRestart(t *testing.T) {
 		t.Fatalf("gateway #1 health: %v", err)
 	}
 
-	jar1, err := cookiejar.New(nil)
-	if err != nil {
-		t.Fatalf("create cookie jar: %v", err)
-	}
-	client1 := &http.Client{Jar: jar1}
+	client1 := newConsoleClient(t)
 	mustPostJSONStatus(t, client1, fmt.Sprintf("http://%s/api/auth/login", addr1), map[string]any{
 		"username": "persist-admin",
 		"password": "persist-pass-123",
@@ -1040,11 +1078,7 @@ func TestSQLiteStatePersistenceAcrossRestart(t *testing.T) {
 		t.Fatalf("gateway #2 health: %v", err)
 	}
 
-	jar2, err := cookiejar.New(nil)
-	if err != nil {
-		t.Fatalf("create cookie jar: %v", err)
-	}
-	client2 := &http.Client{Jar: jar2}
+	client2 := newConsoleClient(t)
 	mustPostJSONStatus(t, client2, fmt.Sprintf("http://%s/api/auth/login", addr2), map[string]any{
 		"username": "persist-admin",
 		"password": "persist-pass-123",
@@ -1450,11 +1484,7 @@ func TestPublicSignupCreatesTenantAdminAndSession(t *testing.T) {
 		t.Fatalf("gateway health never became ready: %v", err)
 	}
 
-	jar, err := cookiejar.New(nil)
-	if err != nil {
-		t.Fatalf("create cookie jar: %v", err)
-	}
-	client := &http.Client{Jar: jar}
+	client := newConsoleClient(t)
 
 	reqBody, _ := json.Marshal(map[string]any{
 		"username": "new_tenant_admin",
@@ -1578,11 +1608,7 @@ func TestPublicSignupSlugCollisionAddsSuffix(t *testing.T) {
 		"name": "Collision",
 	}, http.StatusOK)
 
-	jar, err := cookiejar.New(nil)
-	if err != nil {
-		t.Fatalf("create cookie jar: %v", err)
-	}
-	client := &http.Client{Jar: jar}
+	client := newConsoleClient(t)
 	body, _ := json.Marshal(map[string]any{
 		"username": "collision",
 		"password": "signup_pass_123",
@@ -1793,11 +1819,7 @@ func loginAsAdmin(t *testing.T, gatewayAddr string) *http.Client {
 
 func loginAsUser(t *testing.T, gatewayAddr, username, password string) *http.Client {
 	t.Helper()
-	jar, err := cookiejar.New(nil)
-	if err != nil {
-		t.Fatalf("create cookie jar: %v", err)
-	}
-	client := &http.Client{Jar: jar}
+	client := newConsoleClient(t)
 
 	payload := map[string]string{
 		"username": username,
@@ -1819,6 +1841,31 @@ func loginAsUser(t *testing.T, gatewayAddr, username, password string) *http.Cli
 	return client
 }
 
+// newConsoleClient returns a client that behaves like the console: it keeps
+// the session cookie and echoes the proxer_csrf cookie in the CSRF header.
+func newConsoleClient(t *testing.T) *http.Client {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("create cookie jar: %v", err)
+	}
+	return &http.Client{Jar: jar, Transport: csrfTransport{jar: jar}}
+}
+
+type csrfTransport struct {
+	jar http.CookieJar
+}
+
+func (c csrfTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, cookie := range c.jar.Cookies(req.URL) {
+		if cookie.Name == "proxer_csrf" {
+			req = req.Clone(req.Context())
+			req.Header.Set("X-Proxer-CSRF-Token", cookie.Value)
+		}
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
 type testHTTPServer struct {
 	URL    string
 	server *http.Server
@@ -1840,6 +1887,13 @@ func startTestHTTPServer(t *testing.T, handler http.Handler) *testHTTPServer {
 	}
 }
 
+// startEchoServer starts an upstream that answers with a JSON description of
+// each request, named by opts.Name as "service".
+func startEchoServer(t *testing.T, opts echoserver.Options) *testHTTPServer {
+	t.Helper()
+	return startTestHTTPServer(t, echoserver.Handler(opts))
+}
+
 func (s *testHTTPServer) Close(t *testing.T) {
 	t.Helper()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -1848,3 +1902,1016 @@ func (s *testHTTPServer) Close(t *testing.T) {
 		t.Fatalf("shutdown test server: %v", err)
 	}
 }
+
+func TestHTTP2TrailersSurviveTunnelRoundTrip(t *testing.T) {
+	type observed struct {
+		proto    int
+		te       string
+		checksum string
+	}
+	seen := make(chan observed, 1)
+	targetProtocols := new(http.Protocols)
+	targetProtocols.SetUnencryptedHTTP2(true)
+	target := startTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		seen <- observed{proto: r.ProtoMajor, te: r.Header.Get("Te"), checksum: r.Trailer.Get("X-Checksum")}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("payload"))
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+	}))
+	target.server.Protocols = targetProtocols
+	defer target.Close(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		AgentToken:     "test-token",
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 5 * time.Second,
+		HTTP2Enabled:   true,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	agentClient := agent.New(agent.Config{
+		GatewayBaseURL:    fmt.Sprintf("http://%s", gatewayAddr),
+		AgentToken:        "test-token",
+		AgentID:           "h2-agent",
+		HeartbeatInterval: 200 * time.Millisecond,
+		RequestTimeout:    5 * time.Second,
+		PollWait:          1 * time.Second,
+		UpstreamHTTP2:     agent.UpstreamHTTP2H2C,
+		Tunnels:           []protocol.TunnelConfig{{ID: "grpc", Target: target.URL}},
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = agentClient.Run(ctx) }()
+	if err := waitForTunnelCount(authedClient, fmt.Sprintf("http://%s/api/tunnels", gatewayAddr), 1, 8*time.Second); err != nil {
+		t.Fatalf("tunnel was not registered: %v", err)
+	}
+
+	clientProtocols := new(http.Protocols)
+	clientProtocols.SetUnencryptedHTTP2(true)
+	h2cClient := &http.Client{Transport: &http.Transport{Protocols: clientProtocols}, Timeout: 5 * time.Second}
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s/t/grpc/svc.Echo/Call", gatewayAddr), strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/grpc")
+	request.Header.Set("Te", "trailers")
+	request.Trailer = http.Header{"X-Checksum": []string{"abc"}}
+	response, err := h2cClient.Do(request)
+	if err != nil {
+		t.Fatalf("h2c request failed: %v", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+
+	if response.ProtoMajor != 2 {
+		t.Fatalf("expected HTTP/2 from gateway, got %s", response.Proto)
+	}
+	if string(body) != "payload" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if got := response.Trailer.Get("Grpc-Status"); got != "0" {
+		t.Fatalf("expected grpc-status trailer, got %q (trailers %v)", got, response.Trailer)
+	}
+	select {
+	case got := <-seen:
+		if got.proto != 2 || got.te != "trailers" || got.checksum != "abc" {
+			t.Fatalf("target saw proto=%d te=%q checksum=%q", got.proto, got.te, got.checksum)
+		}
+	default:
+		t.Fatalf("target was never called")
+	}
+}
+
+func TestAgentServesLocalDirectoryAsFileTunnel(t *testing.T) {
+	siteDir := t.TempDir()
+	for name, content := range map[string]string{
+		"index.html":          "<h1>home</h1>",
+		"assets/app.js":       "console.log('ok')",
+		".env":                "SECRET=1",
+		"downloads/notes.txt": "notes",
+	} {
+		path := filepath.Join(siteDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	target, err := agent.FileTunnelTarget(siteDir, false)
+	if err != nil {
+		t.Fatalf("file tunnel target: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		AgentToken:     "test-token",
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 5 * time.Second,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	agentClient := agent.New(agent.Config{
+		GatewayBaseURL:       fmt.Sprintf("http://%s", gatewayAddr),
+		AgentToken:           "test-token",
+		AgentID:              "site-agent",
+		HeartbeatInterval:    200 * time.Millisecond,
+		RequestTimeout:       5 * time.Second,
+		PollWait:             1 * time.Second,
+		MaxResponseBodyBytes: 1 << 20,
+		Tunnels:              []protocol.TunnelConfig{{ID: "site", Target: target}},
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = agentClient.Run(ctx) }()
+	if err := waitForTunnelCount(authedClient, fmt.Sprintf("http://%s/api/tunnels", gatewayAddr), 1, 8*time.Second); err != nil {
+		t.Fatalf("tunnel was not registered: %v", err)
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{method: http.MethodGet, path: "/t/site/", status: http.StatusOK, body: "<h1>home</h1>"},
+		{method: http.MethodGet, path: "/t/site/assets/app.js", status: http.StatusOK, body: "console.log('ok')"},
+		{method: http.MethodGet, path: "/t/site/.env", status: http.StatusNotFound},
+		{method: http.MethodGet, path: "/t/site/downloads/", status: http.StatusNotFound},
+		{method: http.MethodPost, path: "/t/site/index.html", status: http.StatusMethodNotAllowed},
+	}
+	for index, tc := range cases {
+		if index > 0 {
+			// Stay under the free plan's per-route request rate.
+			time.Sleep(500 * time.Millisecond)
+		}
+		request, _ := http.NewRequest(tc.method, fmt.Sprintf("http://%s%s", gatewayAddr, tc.path), nil)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tc.method, tc.path, err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		_ = response.Body.Close()
+		if response.StatusCode != tc.status {
+			t.Fatalf("%s %s: expected %d, got %d (%s)", tc.method, tc.path, tc.status, response.StatusCode, body)
+		}
+		if tc.body != "" && string(body) != tc.body {
+			t.Fatalf("%s %s: unexpected body %q", tc.method, tc.path, body)
+		}
+	}
+}
+
+func TestAgentHealthChecksStopDispatchToUnhealthyTarget(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	target := startTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			if !healthy.Load() {
+				http.Error(w, "down", http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = io.WriteString(w, "ok")
+			return
+		}
+		_, _ = io.WriteString(w, "app")
+	}))
+	defer target.Close(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		AgentToken:     "test-token",
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 5 * time.Second,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	agentClient := agent.New(agent.Config{
+		GatewayBaseURL:       fmt.Sprintf("http://%s", gatewayAddr),
+		AgentToken:           "test-token",
+		AgentID:              "health-agent",
+		HeartbeatInterval:    200 * time.Millisecond,
+		RequestTimeout:       5 * time.Second,
+		PollWait:             1 * time.Second,
+		MaxResponseBodyBytes: 1 << 20,
+		Tunnels:              []protocol.TunnelConfig{{ID: "app", Target: target.URL}},
+		HealthChecks:         []agent.HealthCheck{{Target: "app", Kind: agent.HealthCheckHTTP, Path: "/healthz"}},
+		HealthCheckInterval:  100 * time.Millisecond,
+		HealthCheckThreshold: 2,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = agentClient.Run(ctx) }()
+	if err := waitForTunnelCount(authedClient, fmt.Sprintf("http://%s/api/tunnels", gatewayAddr), 1, 8*time.Second); err != nil {
+		t.Fatalf("tunnel was not registered: %v", err)
+	}
+
+	waitForProxyStatus := func(expected int) string {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			response, err := http.Get(fmt.Sprintf("http://%s/t/app/", gatewayAddr))
+			if err != nil {
+				t.Fatalf("proxy request: %v", err)
+			}
+			body, _ := io.ReadAll(response.Body)
+			_ = response.Body.Close()
+			if response.StatusCode == expected {
+				return string(body)
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected status %d, last got %d (%s)", expected, response.StatusCode, body)
+			}
+			// Stay under the free plan's per-route request rate.
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+
+	if body := waitForProxyStatus(http.StatusOK); body != "app" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	healthy.Store(false)
+	if body := waitForProxyStatus(http.StatusServiceUnavailable); !strings.Contains(body, "unhealthy") {
+		t.Fatalf("expected an unhealthy target error, got %q", body)
+	}
+	healthy.Store(true)
+	waitForProxyStatus(http.StatusOK)
+}
+
+func TestAgentForwardsTunnelToUnixSocket(t *testing.T) {
+	// t.TempDir paths can exceed the unix socket path limit.
+	socketDir, err := os.MkdirTemp("", "proxer-sock")
+	if err != nil {
+		t.Fatalf("create socket dir: %v", err)
+	}
+	defer os.RemoveAll(socketDir)
+	socketPath := filepath.Join(socketDir, "app.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	socketServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "socket %s %s", r.Method, r.URL.Path)
+	})}
+	go func() { _ = socketServer.Serve(listener) }()
+	defer socketServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		AgentToken:     "test-token",
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 5 * time.Second,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	agentClient := agent.New(agent.Config{
+		GatewayBaseURL:       fmt.Sprintf("http://%s", gatewayAddr),
+		AgentToken:           "test-token",
+		AgentID:              "socket-agent",
+		HeartbeatInterval:    200 * time.Millisecond,
+		RequestTimeout:       5 * time.Second,
+		PollWait:             1 * time.Second,
+		MaxResponseBodyBytes: 1 << 20,
+		Tunnels:              []protocol.TunnelConfig{{ID: "sock", Target: "unix://" + filepath.ToSlash(socketPath)}},
+		HealthChecks:         []agent.HealthCheck{{Target: "sock", Kind: agent.HealthCheckTCP}},
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = agentClient.Run(ctx) }()
+	if err := waitForTunnelCount(authedClient, fmt.Sprintf("http://%s/api/tunnels", gatewayAddr), 1, 8*time.Second); err != nil {
+		t.Fatalf("tunnel was not registered: %v", err)
+	}
+
+	response, err := http.Get(fmt.Sprintf("http://%s/t/sock/v1.43/version", gatewayAddr))
+	if err != nil {
+		t.Fatalf("proxy request: %v", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusOK || string(body) != "socket GET /v1.43/version" {
+		t.Fatalf("expected the socket server response, got %d %q", response.StatusCode, body)
+	}
+}
+
+func TestAgentTunnelTLSSettingsApplyPerTarget(t *testing.T) {
+	selfSigned := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "secure")
+	}))
+	selfSigned.Config.ErrorLog = log.New(io.Discard, "", 0)
+	selfSigned.StartTLS()
+	defer selfSigned.Close()
+	fingerprint := sha256.Sum256(selfSigned.Certificate().Raw)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		AgentToken:     "test-token",
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 5 * time.Second,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	agentClient := agent.New(agent.Config{
+		GatewayBaseURL:       fmt.Sprintf("http://%s", gatewayAddr),
+		AgentToken:           "test-token",
+		AgentID:              "tls-agent",
+		HeartbeatInterval:    200 * time.Millisecond,
+		RequestTimeout:       5 * time.Second,
+		PollWait:             1 * time.Second,
+		MaxResponseBodyBytes: 1 << 20,
+		Tunnels: []protocol.TunnelConfig{
+			{ID: "pinned", Target: selfSigned.URL, TLS: &protocol.LocalTLS{PinnedSHA256: hex.EncodeToString(fingerprint[:])}},
+			{ID: "wrongpin", Target: selfSigned.URL, TLS: &protocol.LocalTLS{PinnedSHA256: strings.Repeat("00", 32)}},
+			{ID: "verified", Target: selfSigned.URL},
+		},
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = agentClient.Run(ctx) }()
+	if err := waitForTunnelCount(authedClient, fmt.Sprintf("http://%s/api/tunnels", gatewayAddr), 3, 8*time.Second); err != nil {
+		t.Fatalf("tunnels were not registered: %v", err)
+	}
+
+	for _, tc := range []struct {
+		tunnel string
+		status int
+	}{
+		{tunnel: "pinned", status: http.StatusOK},
+		{tunnel: "wrongpin", status: http.StatusBadGateway},
+		{tunnel: "verified", status: http.StatusBadGateway},
+	} {
+		response, err := http.Get(fmt.Sprintf("http://%s/t/%s/", gatewayAddr, tc.tunnel))
+		if err != nil {
+			t.Fatalf("proxy request to %s: %v", tc.tunnel, err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		_ = response.Body.Close()
+		if response.StatusCode != tc.status {
+			t.Fatalf("tunnel %s: expected %d, got %d (%s)", tc.tunnel, tc.status, response.StatusCode, body)
+		}
+	}
+}
+
+// lockedBuffer collects log output written from agent goroutines.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRequestIDReachesLocalTargetAndAgentLogs(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Header.Get("X-Proxer-Request-ID")+" "+r.Header.Get("Traceparent"))
+	}))
+	defer target.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:        "127.0.0.1:0",
+		AgentToken:        "test-token",
+		PublicBaseURL:     "http://localhost:8080",
+		RequestTimeout:    5 * time.Second,
+		InjectTraceparent: true,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	agentLogs := &lockedBuffer{}
+	agentClient := agent.New(agent.Config{
+		GatewayBaseURL:    fmt.Sprintf("http://%s", gatewayAddr),
+		AgentToken:        "test-token",
+		AgentID:           "trace-agent",
+		HeartbeatInterval: 200 * time.Millisecond,
+		RequestTimeout:    5 * time.Second,
+		PollWait:          1 * time.Second,
+		LogLevel:          "debug",
+		Tunnels: []protocol.TunnelConfig{
+			{ID: "app", Target: target.URL},
+			{ID: "down", Target: "http://127.0.0.1:1"},
+		},
+	}, log.New(agentLogs, "", 0))
+	go func() { _ = agentClient.Run(ctx) }()
+	if err := waitForTunnelCount(authedClient, fmt.Sprintf("http://%s/api/tunnels", gatewayAddr), 2, 8*time.Second); err != nil {
+		t.Fatalf("tunnels were not registered: %v", err)
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s/t/app/", gatewayAddr), nil)
+	request.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("proxy request failed: %v", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	requestID := response.Header.Get("X-Proxer-Request-ID")
+	seenID, traceparent, _ := strings.Cut(string(body), " ")
+	if requestID == "" || seenID != requestID {
+		t.Fatalf("expected local target to see request ID %q, got %q", requestID, body)
+	}
+	if !strings.HasPrefix(traceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-") || strings.Contains(traceparent, "00f067aa0ba902b7") {
+		t.Fatalf("expected the caller's trace with a new parent span, got %q", traceparent)
+	}
+
+	response, err = http.Get(fmt.Sprintf("http://%s/t/down/", gatewayAddr))
+	if err != nil {
+		t.Fatalf("proxy request failed: %v", err)
+	}
+	_ = response.Body.Close()
+	failedID := response.Header.Get("X-Proxer-Request-ID")
+	if response.StatusCode != http.StatusBadGateway || failedID == "" {
+		t.Fatalf("expected 502 with a request ID, got %d %q", response.StatusCode, failedID)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		logs := agentLogs.String()
+		if strings.Contains(logs, "request_id="+requestID+" ") && strings.Contains(logs, "request_id="+failedID+" ") && strings.Contains(logs, "error=") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected agent logs for requests %s and %s, got:\n%s", requestID, failedID, logs)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestTLSPassthroughKeepsTLSEndToEnd(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "secure "+r.TLS.ServerName)
+	}))
+	defer target.Close()
+	targetURL, _ := url.Parse(target.URL)
+	port, _ := strconv.Atoi(targetURL.Port())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:           "127.0.0.1:0",
+		TLSListenAddr:        "127.0.0.1:0",
+		AgentToken:           "test-token",
+		PublicBaseURL:        "http://localhost:8080",
+		RequestTimeout:       5 * time.Second,
+		PassthroughHostnames: []string{"example.com"},
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	mustPostJSONStatus(t, authedClient, fmt.Sprintf("http://%s/api/connectors", gatewayAddr), map[string]string{
+		"id":        "secure-conn",
+		"tenant_id": "default",
+	}, http.StatusCreated)
+	pairResp, err := authedClient.Post(fmt.Sprintf("http://%s/api/connectors/secure-conn/pair", gatewayAddr), "application/json", nil)
+	if err != nil {
+		t.Fatalf("pair connector failed: %v", err)
+	}
+	var pairPayload struct {
+		PairToken struct {
+			Token string `json:"token"`
+		} `json:"pair_token"`
+	}
+	err = json.NewDecoder(pairResp.Body).Decode(&pairPayload)
+	_ = pairResp.Body.Close()
+	if err != nil || pairPayload.PairToken.Token == "" {
+		t.Fatalf("decode pair payload: %v", err)
+	}
+	go func() {
+		_ = agent.New(agent.Config{
+			GatewayBaseURL:    fmt.Sprintf("http://%s", gatewayAddr),
+			AgentID:           "secure-agent",
+			HeartbeatInterval: 200 * time.Millisecond,
+			RequestTimeout:    5 * time.Second,
+			PollWait:          1 * time.Second,
+			PairToken:         pairPayload.PairToken.Token,
+		}, log.New(io.Discard, "", 0)).Run(ctx)
+	}()
+
+	// The test server's certificate is for example.com, so a verified
+	// handshake proves the gateway did not terminate TLS.
+	mustPostJSONStatus(t, authedClient, fmt.Sprintf("http://%s/api/tenants/default/routes", gatewayAddr), map[string]any{
+		"id":              "secure",
+		"connector_id":    "secure-conn",
+		"local_port":      port,
+		"tls_passthrough": map[string]any{"hostnames": []string{"Example.com"}},
+	}, http.StatusOK)
+
+	certs := target.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: certs, ServerName: "example.com"},
+	}}
+	var body string
+	deadline := time.Now().Add(8 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := client.Get(fmt.Sprintf("https://%s/", gatewayServer.TLSAddr()))
+		if err == nil {
+			data, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				body = string(data)
+				break
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if body != "secure example.com" {
+		t.Fatalf("expected the local TLS server to answer through passthrough, got %q", body)
+	}
+
+	// Hostnames without a passthrough route are still terminated by the
+	// gateway, which has no certificate for them here.
+	conn, err := tls.Dial("tcp", gatewayServer.TLSAddr(), &tls.Config{ServerName: "other.example.net", InsecureSkipVerify: true})
+	if err == nil {
+		_ = conn.Close()
+		t.Fatalf("expected the gateway to terminate TLS for other hostnames")
+	}
+}
+
+func TestAgentRetriesIdempotentRequestsForConnectorRoutes(t *testing.T) {
+	var calls atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "recovered")
+	}))
+	defer target.Close()
+	targetURL, _ := url.Parse(target.URL)
+	port, _ := strconv.Atoi(targetURL.Port())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		AgentToken:     "test-token",
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 5 * time.Second,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	mustPostJSONStatus(t, authedClient, fmt.Sprintf("http://%s/api/connectors", gatewayAddr), map[string]string{
+		"id":        "retry-conn",
+		"tenant_id": "default",
+	}, http.StatusCreated)
+	pairResp, err := authedClient.Post(fmt.Sprintf("http://%s/api/connectors/retry-conn/pair", gatewayAddr), "application/json", nil)
+	if err != nil {
+		t.Fatalf("pair connector failed: %v", err)
+	}
+	var pairPayload struct {
+		PairToken struct {
+			Token string `json:"token"`
+		} `json:"pair_token"`
+	}
+	err = json.NewDecoder(pairResp.Body).Decode(&pairPayload)
+	_ = pairResp.Body.Close()
+	if err != nil || pairPayload.PairToken.Token == "" {
+		t.Fatalf("decode pair payload: %v", err)
+	}
+	go func() {
+		_ = agent.New(agent.Config{
+			GatewayBaseURL:    fmt.Sprintf("http://%s", gatewayAddr),
+			AgentID:           "retry-agent",
+			HeartbeatInterval: 200 * time.Millisecond,
+			RequestTimeout:    5 * time.Second,
+			PollWait:          1 * time.Second,
+			PairToken:         pairPayload.PairToken.Token,
+		}, log.New(io.Discard, "", 0)).Run(ctx)
+	}()
+
+	mustPostJSONStatus(t, authedClient, fmt.Sprintf("http://%s/api/tenants/default/routes", gatewayAddr), map[string]any{
+		"id":           "retrying",
+		"connector_id": "retry-conn",
+		"local_port":   port,
+		"retry":        map[string]any{"attempts": 3, "backoff_ms": 10},
+	}, http.StatusOK)
+
+	var body string
+	deadline := time.Now().Add(8 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(fmt.Sprintf("http://%s/t/default/retrying/", gatewayAddr))
+		if err == nil {
+			data, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			// Earlier attempts fail before the agent connects; the first
+			// request reaching the target must succeed within its retries.
+			if calls.Load() > 0 {
+				body = string(data)
+				break
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if body != "recovered" || calls.Load() != 3 {
+		t.Fatalf("expected the agent to retry twice before succeeding, got %q after %d calls", body, calls.Load())
+	}
+
+	routesResp, err := authedClient.Get(fmt.Sprintf("http://%s/api/tenants/default/routes", gatewayAddr))
+	if err != nil {
+		t.Fatalf("list routes: %v", err)
+	}
+	var payload struct {
+		Routes []struct {
+			ID      string `json:"id"`
+			Metrics struct {
+				RetryCount int64 `json:"retry_count"`
+			} `json:"metrics"`
+		} `json:"routes"`
+	}
+	err = json.NewDecoder(routesResp.Body).Decode(&payload)
+	_ = routesResp.Body.Close()
+	if err != nil || len(payload.Routes) != 1 || payload.Routes[0].Metrics.RetryCount != 2 {
+		t.Fatalf("expected retry_count 2 in route metrics, got %+v (%v)", payload, err)
+	}
+}
+
+func TestAgentResumesSessionAfterGatewayRestart(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "still here")
+	}))
+	defer target.Close()
+
+	startGateway := func(listenAddr string) (context.CancelFunc, chan struct{}, string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		gatewayServer := gateway.NewServer(gateway.Config{
+			ListenAddr:     listenAddr,
+			AgentToken:     "test-token",
+			PublicBaseURL:  "http://localhost:8080",
+			RequestTimeout: 5 * time.Second,
+		}, log.New(io.Discard, "", 0))
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			_ = gatewayServer.Start(ctx)
+		}()
+		gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+		if err != nil {
+			t.Fatalf("gateway did not publish a listener address: %v", err)
+		}
+		if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+			t.Fatalf("gateway health never became ready: %v", err)
+		}
+		return cancel, stopped, gatewayAddr
+	}
+	stopFirst, firstStopped, gatewayAddr := startGateway("127.0.0.1:0")
+	defer stopFirst()
+
+	agentCtx, cancelAgent := context.WithCancel(context.Background())
+	defer cancelAgent()
+	agentLogs := &lockedBuffer{}
+	go func() {
+		_ = agent.New(agent.Config{
+			GatewayBaseURL:    fmt.Sprintf("http://%s", gatewayAddr),
+			AgentToken:        "test-token",
+			AgentID:           "resume-agent",
+			HeartbeatInterval: 200 * time.Millisecond,
+			RequestTimeout:    5 * time.Second,
+			PollWait:          1 * time.Second,
+			Tunnels:           []protocol.TunnelConfig{{ID: "resume", Target: target.URL}},
+		}, log.New(agentLogs, "", 0)).Run(agentCtx)
+	}()
+	mustProxyBody := func(expected string) {
+		t.Helper()
+		deadline := time.Now().Add(8 * time.Second)
+		body := ""
+		for time.Now().Before(deadline) {
+			resp, err := http.Get(fmt.Sprintf("http://%s/t/resume/", gatewayAddr))
+			if err == nil {
+				data, _ := io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				if body = string(data); resp.StatusCode == http.StatusOK {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if body != expected {
+			t.Fatalf("expected %q through the tunnel, got %q", expected, body)
+		}
+	}
+	mustProxyBody("still here")
+	registered := agentLogs.String()
+	start := strings.Index(registered, "session=")
+	if start < 0 {
+		t.Fatalf("agent did not log its session: %s", registered)
+	}
+	sessionID := strings.Fields(registered[start+len("session="):])[0]
+
+	stopFirst()
+	<-firstStopped
+	stopSecond, secondStopped, _ := startGateway(gatewayAddr)
+	defer func() {
+		stopSecond()
+		<-secondStopped
+	}()
+
+	mustProxyBody("still here")
+	if logs := agentLogs.String(); !strings.Contains(logs, "resumed gateway session: session="+sessionID) {
+		t.Fatalf("expected the agent to resume session %s, logs:\n%s", sessionID, logs)
+	}
+}
+
+func TestAgentReportsConnectionStatsInRuntimeEvents(t *testing.T) {
+	upstream := startTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		AgentToken:     "test-token",
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 5 * time.Second,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	var mu sync.Mutex
+	var latest *agent.ConnectionStats
+	agentClient := agent.New(agent.Config{
+		GatewayBaseURL:       fmt.Sprintf("http://%s", gatewayAddr),
+		AgentToken:           "test-token",
+		AgentID:              "stats-agent",
+		HeartbeatInterval:    100 * time.Millisecond,
+		RequestTimeout:       5 * time.Second,
+		PollWait:             1 * time.Second,
+		MaxResponseBodyBytes: 1 << 20,
+		Tunnels: []protocol.TunnelConfig{
+			{ID: "up", Target: upstream.URL},
+			{ID: "down", Target: "http://127.0.0.1:1"},
+		},
+		EventHook: func(ev agent.RuntimeEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			latest = ev.Connection
+		},
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = agentClient.Run(ctx) }()
+	if err := waitForTunnelCount(authedClient, fmt.Sprintf("http://%s/api/tunnels", gatewayAddr), 2, 8*time.Second); err != nil {
+		t.Fatalf("tunnels were not registered: %v", err)
+	}
+
+	for _, path := range []string{"/t/up/", "/t/up/", "/t/down/"} {
+		response, err := http.Get(fmt.Sprintf("http://%s%s", gatewayAddr, path))
+		if err != nil {
+			t.Fatalf("proxy request %s: %v", path, err)
+		}
+		_ = response.Body.Close()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		stats := latest
+		mu.Unlock()
+		if stats != nil && stats.RequestsServed == 3 && stats.LastHeartbeatAt != nil {
+			if stats.RequestsErrored != 1 || stats.LastRegisteredAt == nil || stats.Reconnects != 0 || stats.BackoffMs != 0 {
+				t.Fatalf("unexpected connection stats: %+v", stats)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("connection stats never reported the requests: %+v", stats)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestAgentQueuesWebhooksWhileLocalTargetIsDown(t *testing.T) {
+	// Reserve a port for the local service, which starts later.
+	reserved, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("reserve port: %v", err)
+	}
+	localAddr := reserved.Addr().String()
+	_ = reserved.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		AgentToken:     "test-token",
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 5 * time.Second,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	agentClient := agent.New(agent.Config{
+		GatewayBaseURL:        fmt.Sprintf("http://%s", gatewayAddr),
+		AgentToken:            "test-token",
+		AgentID:               "queue-agent",
+		HeartbeatInterval:     200 * time.Millisecond,
+		RequestTimeout:        5 * time.Second,
+		PollWait:              1 * time.Second,
+		MaxResponseBodyBytes:  1 << 20,
+		Tunnels:               []protocol.TunnelConfig{{ID: "hooks", Target: "http://" + localAddr}},
+		OfflineQueueTargets:   []string{"hooks"},
+		OfflineQueueDir:       t.TempDir(),
+		OfflineReplayInterval: 100 * time.Millisecond,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = agentClient.Run(ctx) }()
+	if err := waitForTunnelCount(authedClient, fmt.Sprintf("http://%s/api/tunnels", gatewayAddr), 1, 8*time.Second); err != nil {
+		t.Fatalf("tunnel was not registered: %v", err)
+	}
+
+	for _, event := range []string{"first", "second"} {
+		response, err := http.Post(fmt.Sprintf("http://%s/t/hooks/webhook", gatewayAddr), "text/plain", strings.NewReader(event))
+		if err != nil {
+			t.Fatalf("post webhook: %v", err)
+		}
+		_ = response.Body.Close()
+		if response.StatusCode != http.StatusAccepted || response.Header.Get("X-Proxer-Offline-Queued") != "true" {
+			t.Fatalf("expected the webhook to be queued, got %d %v", response.StatusCode, response.Header)
+		}
+	}
+	response, err := http.Get(fmt.Sprintf("http://%s/t/hooks/status", gatewayAddr))
+	if err != nil {
+		t.Fatalf("get status: %v", err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected GET to fail rather than be queued, got %d", response.StatusCode)
+	}
+
+	received := make(chan string, 4)
+	listener, err := net.Listen("tcp", localAddr)
+	if err != nil {
+		t.Skipf("reserved port was taken: %v", err)
+	}
+	localServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r.Method + " " + r.URL.Path + " " + string(body)
+	})}
+	go func() { _ = localServer.Serve(listener) }()
+	defer localServer.Close()
+
+	for _, want := range []string{"POST /webhook first", "POST /webhook second"} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("replayed %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for replay of %q", want)
+		}
+	}
+}
+
+func TestAgentBandwidthLimitPacesLocalResponses(t *testing.T) {
+	payload := strings.Repeat("x", 8192)
+	localService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, payload)
+	}))
+	defer localService.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gatewayServer := gateway.NewServer(gateway.Config{
+		ListenAddr:     "127.0.0.1:0",
+		AgentToken:     "test-token",
+		PublicBaseURL:  "http://localhost:8080",
+		RequestTimeout: 5 * time.Second,
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = gatewayServer.Start(ctx) }()
+	gatewayAddr, err := waitForGatewayAddr(gatewayServer, 5*time.Second)
+	if err != nil {
+		t.Fatalf("gateway did not publish a listener address: %v", err)
+	}
+	if err := waitForHTTP(fmt.Sprintf("http://%s/api/health", gatewayAddr), 5*time.Second); err != nil {
+		t.Fatalf("gateway health never became ready: %v", err)
+	}
+	authedClient := loginAsAdmin(t, gatewayAddr)
+
+	agentClient := agent.New(agent.Config{
+		GatewayBaseURL:       fmt.Sprintf("http://%s", gatewayAddr),
+		AgentToken:           "test-token",
+		AgentID:              "metered-agent",
+		HeartbeatInterval:    200 * time.Millisecond,
+		RequestTimeout:       5 * time.Second,
+		PollWait:             1 * time.Second,
+		MaxResponseBodyBytes: 1 << 20,
+		MaxBytesPerSecond:    4096,
+		Tunnels:              []protocol.TunnelConfig{{ID: "metered", Target: localService.URL}},
+	}, log.New(io.Discard, "", 0))
+	go func() { _ = agentClient.Run(ctx) }()
+	if err := waitForTunnelCount(authedClient, fmt.Sprintf("http://%s/api/tunnels", gatewayAddr), 1, 8*time.Second); err != nil {
+		t.Fatalf("tunnel was not registered: %v", err)
+	}
+
+	// One second of tokens is available up front; the rest of the 8 KiB
+	// body waits for the bucket to refill at 4 KiB/s.
+	start := time.Now()
+	response, err := http.Get(fmt.Sprintf("http://%s/t/metered/", gatewayAddr))
+	if err != nil {
+		t.Fatalf("get through tunnel: %v", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	elapsed := time.Since(start)
+	if response.StatusCode != http.StatusOK || string(body) != payload {
+		t.Fatalf("expected the full body, got %d with %d bytes", response.StatusCode, len(body))
+	}
+	if elapsed < 900*time.Millisecond {
+		t.Fatalf("expected the agent bandwidth limit to slow the response, took %s", elapsed)
+	}
+}
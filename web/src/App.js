@@ -7,6 +7,7 @@ class ApiError extends Error {
         super(message);
         this.name = "ApiError";
         this.status = status;
+        this.code = isRecord(payload) && typeof payload.code === "string" ? payload.code : "";
         this.payload = payload;
     }
 }
@@ -22,11 +23,25 @@ function toErrorMessage(error) {
     }
     return "Request failed";
 }
+// readCSRFToken returns the proxer_csrf cookie the gateway sets with the
+// session; state-changing requests echo it in X-Proxer-CSRF-Token.
+function readCSRFToken() {
+    if (typeof document === "undefined") {
+        return "";
+    }
+    const match = document.cookie.match(/(?:^|;\s*)proxer_csrf=([^;]*)/);
+    return match ? decodeURIComponent(match[1]) : "";
+}
 async function requestJSON(path, init) {
     const headers = new Headers(init?.headers ?? undefined);
     if (init?.body !== undefined && !headers.has("Content-Type")) {
         headers.set("Content-Type", "application/json");
     }
+    const method = (init?.method ?? "GET").toUpperCase();
+    const csrfToken = readCSRFToken();
+    if (method !== "GET" && method !== "HEAD" && csrfToken !== "") {
+        headers.set("X-Proxer-CSRF-Token", csrfToken);
+    }
     const response = await fetch(path, {
         credentials: "include",
         ...init,
@@ -264,7 +279,7 @@ function LandingPage({ me }) {
                                                         trackPublicEvent({ event: "plan_cta_click", plan_id: plan.id, billing });
                                                     }
                                                 }, children: me ? "Use Plan" : "Start Free" })] }, plan.id));
-                                }) })) : null] }), _jsxs("section", { id: "downloads", className: "marketing-panel", "aria-labelledby": "downloads-title", children: [_jsxs("header", { className: "section-head", children: [_jsx("p", { className: "eyebrow", children: "Desktop agent" }), _jsx("h2", { id: "downloads-title", children: "Download binaries for your host machine" })] }), loadingDownloads ? _jsx("p", { role: "status", "aria-live": "polite", children: "Loading downloads..." }) : null, downloadError ? _jsx("p", { className: "status error", children: downloadError }) : null, !loadingDownloads && !downloadError ? (_jsxs(_Fragment, { children: [downloads?.available ? (_jsx("div", { className: "download-grid", children: (downloads.downloads ?? []).map((binary) => (_jsxs("article", { className: "download-card", children: [_jsx("h3", { children: binary.label }), _jsx("p", { className: "code", children: binary.file_name }), _jsx("p", { children: formatBinarySize(binary.size_bytes) }), _jsx("a", { className: "cta-outline", href: binary.url, target: "_blank", rel: "noreferrer", onClick: () => trackPublicEvent({
+                                }) })) : null] }), _jsxs("section", { id: "downloads", className: "marketing-panel", "aria-labelledby": "downloads-title", children: [_jsxs("header", { className: "section-head", children: [_jsx("p", { className: "eyebrow", children: "Desktop agent" }), _jsx("h2", { id: "downloads-title", children: "Download binaries for your host machine" })] }), loadingDownloads ? _jsx("p", { role: "status", "aria-live": "polite", children: "Loading downloads..." }) : null, downloadError ? _jsx("p", { className: "status error", children: downloadError }) : null, !loadingDownloads && !downloadError ? (_jsxs(_Fragment, { children: [downloads?.available ? (_jsx("div", { className: "download-grid", children: (downloads.downloads ?? []).map((binary) => (_jsxs("article", { className: downloads.recommended?.file_name === binary.file_name ? "download-card recommended" : "download-card", children: [downloads.recommended?.file_name === binary.file_name ? _jsx("p", { className: "eyebrow", children: "Recommended for your system" }) : null, _jsx("h3", { children: binary.label }), _jsx("p", { className: "code", children: binary.file_name }), _jsx("p", { children: formatBinarySize(binary.size_bytes) }), binary.sha256 ? (_jsxs("p", { className: "code", title: binary.verified ? "Matches the digest published by GitHub" : undefined, children: ["SHA-256 ", binary.sha256, binary.verified ? " (verified)" : ""] })) : null, binary.signature_url ? (_jsx("a", { href: binary.signature_url, target: "_blank", rel: "noreferrer", children: "Signature" })) : null, _jsx("a", { className: "cta-outline", href: binary.url, target: "_blank", rel: "noreferrer", onClick: () => trackPublicEvent({
                                                         event: "download_click",
                                                         platform: binary.platform,
                                                         file_name: binary.file_name,
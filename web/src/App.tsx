@@ -46,13 +46,22 @@ interface PublicDownloadsPayload {
   release_notes_url?: string;
   checksums_url?: string;
   message?: string;
-  downloads?: Array<{
-    platform: string;
-    label: string;
-    file_name: string;
-    url: string;
-    size_bytes?: number;
-  }>;
+  downloads?: PublicDownloadBinary[];
+  detected_platform?: string;
+  detected_arch?: string;
+  recommended?: PublicDownloadBinary;
+}
+
+interface PublicDownloadBinary {
+  platform: string;
+  arch?: string;
+  label: string;
+  file_name: string;
+  url: string;
+  size_bytes?: number;
+  sha256?: string;
+  signature_url?: string;
+  verified?: boolean;
 }
 
 interface PublicAnalyticsEvent {
@@ -69,12 +78,15 @@ interface PublicAnalyticsEvent {
 class ApiError extends Error {
   status: number;
 
+  code: string;
+
   payload: unknown;
 
   constructor(message: string, status: number, payload: unknown) {
     super(message);
     this.name = "ApiError";
     this.status = status;
+    this.code = isRecord(payload) && typeof payload.code === "string" ? payload.code : "";
     this.payload = payload;
   }
 }
@@ -93,11 +105,26 @@ function toErrorMessage(error: unknown): string {
   return "Request failed";
 }
 
+// readCSRFToken returns the proxer_csrf cookie the gateway sets with the
+// session; state-changing requests echo it in X-Proxer-CSRF-Token.
+function readCSRFToken(): string {
+  if (typeof document === "undefined") {
+    return "";
+  }
+  const match = document.cookie.match(/(?:^|;\s*)proxer_csrf=([^;]*)/);
+  return match ? decodeURIComponent(match[1]) : "";
+}
+
 async function requestJSON<T>(path: string, init?: RequestInit): Promise<T> {
   const headers = new Headers(init?.headers ?? undefined);
   if (init?.body !== undefined && !headers.has("Content-Type")) {
     headers.set("Content-Type", "application/json");
   }
+  const method = (init?.method ?? "GET").toUpperCase();
+  const csrfToken = readCSRFToken();
+  if (method !== "GET" && method !== "HEAD" && csrfToken !== "") {
+    headers.set("X-Proxer-CSRF-Token", csrfToken);
+  }
 
   const response = await fetch(path, {
     credentials: "include",
@@ -622,10 +649,25 @@ function LandingPage({ me }: { me: AuthMeResponse | null }) {
             {downloads?.available ? (
               <div className="download-grid">
                 {(downloads.downloads ?? []).map((binary) => (
-                  <article key={`${binary.platform}:${binary.file_name}`} className="download-card">
+                  <article
+                    key={`${binary.platform}:${binary.file_name}`}
+                    className={downloads.recommended?.file_name === binary.file_name ? "download-card recommended" : "download-card"}
+                  >
+                    {downloads.recommended?.file_name === binary.file_name ? <p className="eyebrow">Recommended for your system</p> : null}
                     <h3>{binary.label}</h3>
                     <p className="code">{binary.file_name}</p>
                     <p>{formatBinarySize(binary.size_bytes)}</p>
+                    {binary.sha256 ? (
+                      <p className="code" title={binary.verified ? "Matches the digest published by GitHub" : undefined}>
+                        SHA-256 {binary.sha256}
+                        {binary.verified ? " (verified)" : ""}
+                      </p>
+                    ) : null}
+                    {binary.signature_url ? (
+                      <a href={binary.signature_url} target="_blank" rel="noreferrer">
+                        Signature
+                      </a>
+                    ) : null}
                     <a
                       className="cta-outline"
                       href={binary.url}
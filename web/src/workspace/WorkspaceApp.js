@@ -59,6 +59,12 @@ const TENANT_NAV = [
     { key: "connectors", label: "Connectors" },
     { key: "tenantConfig", label: "Tenant Config" },
 ];
+function connectorAgentLabel(connector) {
+    if (!connector.agent_id) {
+        return "-";
+    }
+    return connector.agent_version ? `${connector.agent_id} (${connector.agent_version})` : connector.agent_id;
+}
 function isRecord(value) {
     return typeof value === "object" && value !== null;
 }
@@ -164,7 +170,7 @@ function TenantDashboardPage({ api }) {
     }
     const routes = data?.routes ?? [];
     const connectors = data?.connectors ?? [];
-    return (_jsxs(_Fragment, { children: [_jsxs("div", { className: "gauge-row", children: [_jsx(GaugeCard, { title: "Routes", gauge: data?.gauges?.routes, subtitle: "Used / plan limit" }), _jsx(GaugeCard, { title: "Connectors", gauge: data?.gauges?.connectors, subtitle: "Used / plan limit" }), _jsx(GaugeCard, { title: "Traffic (GB)", gauge: data?.gauges?.traffic, subtitle: "Monthly used / cap" })] }), _jsx(Section, { title: "Live Status", actions: _jsx("button", { onClick: () => void load(), children: "Refresh" }), children: _jsxs("div", { className: "kv", children: [_jsxs("p", { children: [_jsx("strong", { children: "Plan" }), _jsx("span", { children: data?.plan?.id ?? "free" })] }), _jsxs("p", { children: [_jsx("strong", { children: "Blocked Requests" }), _jsx("span", { children: data?.status?.blocked_requests_month ?? 0 })] }), _jsxs("p", { children: [_jsx("strong", { children: "Routes Active" }), _jsx("span", { children: data?.status?.routes_active ?? 0 })] }), _jsxs("p", { children: [_jsx("strong", { children: "Connectors Online" }), _jsx("span", { children: data?.status?.connectors_online ?? 0 })] })] }) }), _jsx(Section, { title: "Routes", children: _jsxs("table", { children: [_jsx("thead", { children: _jsxs("tr", { children: [_jsx("th", { children: "Tenant" }), _jsx("th", { children: "Route" }), _jsx("th", { children: "Status" }), _jsx("th", { children: "Public URL" })] }) }), _jsx("tbody", { children: routes.length === 0 ? (_jsx("tr", { children: _jsx("td", { colSpan: 4, children: "No routes yet." }) })) : (routes.map((route) => (_jsxs("tr", { children: [_jsx("td", { children: route.tenant_id }), _jsx("td", { children: route.id }), _jsx("td", { children: _jsx(Badge, { value: route.connected ? "active" : "degraded" }) }), _jsx("td", { className: "code", children: route.public_url ?? "-" })] }, `${route.tenant_id}:${route.id}`)))) })] }) }), _jsx(Section, { title: "Connectors", children: _jsxs("table", { children: [_jsx("thead", { children: _jsxs("tr", { children: [_jsx("th", { children: "ID" }), _jsx("th", { children: "Status" }), _jsx("th", { children: "Agent" }), _jsx("th", { children: "Last Seen" })] }) }), _jsx("tbody", { children: connectors.length === 0 ? (_jsx("tr", { children: _jsx("td", { colSpan: 4, children: "No connectors yet." }) })) : (connectors.map((connector) => (_jsxs("tr", { children: [_jsx("td", { children: connector.id }), _jsx("td", { children: _jsx(Badge, { value: connector.connected ? "online" : "offline" }) }), _jsx("td", { children: connector.agent_id ?? "-" }), _jsx("td", { children: formatDateTime(connector.last_seen) })] }, connector.id)))) })] }) })] }));
+    return (_jsxs(_Fragment, { children: [_jsxs("div", { className: "gauge-row", children: [_jsx(GaugeCard, { title: "Routes", gauge: data?.gauges?.routes, subtitle: "Used / plan limit" }), _jsx(GaugeCard, { title: "Connectors", gauge: data?.gauges?.connectors, subtitle: "Used / plan limit" }), _jsx(GaugeCard, { title: "Traffic (GB)", gauge: data?.gauges?.traffic, subtitle: "Monthly used / cap" })] }), _jsx(Section, { title: "Live Status", actions: _jsx("button", { onClick: () => void load(), children: "Refresh" }), children: _jsxs("div", { className: "kv", children: [_jsxs("p", { children: [_jsx("strong", { children: "Plan" }), _jsx("span", { children: data?.plan?.id ?? "free" })] }), _jsxs("p", { children: [_jsx("strong", { children: "Blocked Requests" }), _jsx("span", { children: data?.status?.blocked_requests_month ?? 0 })] }), _jsxs("p", { children: [_jsx("strong", { children: "Routes Active" }), _jsx("span", { children: data?.status?.routes_active ?? 0 })] }), _jsxs("p", { children: [_jsx("strong", { children: "Connectors Online" }), _jsx("span", { children: data?.status?.connectors_online ?? 0 })] })] }) }), _jsx(Section, { title: "Routes", children: _jsxs("table", { children: [_jsx("thead", { children: _jsxs("tr", { children: [_jsx("th", { children: "Tenant" }), _jsx("th", { children: "Route" }), _jsx("th", { children: "Status" }), _jsx("th", { children: "Public URL" })] }) }), _jsx("tbody", { children: routes.length === 0 ? (_jsx("tr", { children: _jsx("td", { colSpan: 4, children: "No routes yet." }) })) : (routes.map((route) => (_jsxs("tr", { children: [_jsx("td", { children: route.tenant_id }), _jsx("td", { children: route.id }), _jsx("td", { children: _jsx(Badge, { value: route.connected ? "active" : "degraded" }) }), _jsx("td", { className: "code", children: route.public_url ?? "-" })] }, `${route.tenant_id}:${route.id}`)))) })] }) }), _jsx(Section, { title: "Connectors", children: _jsxs("table", { children: [_jsx("thead", { children: _jsxs("tr", { children: [_jsx("th", { children: "ID" }), _jsx("th", { children: "Status" }), _jsx("th", { children: "Agent" }), _jsx("th", { children: "Last Seen" })] }) }), _jsx("tbody", { children: connectors.length === 0 ? (_jsx("tr", { children: _jsx("td", { colSpan: 4, children: "No connectors yet." }) })) : (connectors.map((connector) => (_jsxs("tr", { children: [_jsx("td", { children: connector.id }), _jsx("td", { children: _jsx(Badge, { value: connector.connected ? (connector.outdated ? "outdated" : "online") : "offline" }) }), _jsx("td", { title: connector.outdated_reason, children: connectorAgentLabel(connector) }), _jsx("td", { children: formatDateTime(connector.last_seen) })] }, connector.id)))) })] }) })] }));
 }
 function AdminOverviewPage({ api }) {
     const [stats, setStats] = useState(null);
@@ -540,6 +546,7 @@ function RoutesPage({ api, me }) {
                     target: String(formData.get("target") ?? ""),
                     token: String(formData.get("token") ?? ""),
                     max_rps: Number(formData.get("max_rps") ?? 0),
+                    max_bytes_per_second: Number(formData.get("max_bytes_per_second") ?? 0),
                     connector_id: String(formData.get("connector_id") ?? ""),
                     local_scheme: String(formData.get("local_scheme") ?? "http"),
                     local_host: String(formData.get("local_host") ?? "127.0.0.1"),
@@ -568,7 +575,7 @@ function RoutesPage({ api, me }) {
         }
     }, [api, load]);
     const defaultTenant = me.user.tenant_id || tenants[0]?.id || "default";
-    return (_jsxs(_Fragment, { children: [_jsxs(Section, { title: "Create Route", children: [_jsxs("form", { className: "grid cols-2", onSubmit: submitRoute, children: [_jsxs("label", { children: ["Tenant", _jsx("select", { name: "tenant_id", defaultValue: defaultTenant, disabled: !isSuper, required: isSuper, children: tenants.map((tenant) => (_jsx("option", { value: tenant.id, children: tenant.id }, tenant.id))) })] }), _jsxs("label", { children: ["Route ID", _jsx("input", { name: "id", placeholder: "api", required: true })] }), _jsxs("label", { children: ["Direct Target URL", _jsx("input", { name: "target", placeholder: "http://127.0.0.1:3000" })] }), _jsxs("label", { children: ["Connector", _jsxs("select", { name: "connector_id", defaultValue: "", children: [_jsx("option", { value: "", children: "Direct target" }), connectors.map((connector) => (_jsx("option", { value: connector.id, children: connector.id }, connector.id)))] })] }), _jsxs("label", { children: ["Local Scheme", _jsxs("select", { name: "local_scheme", defaultValue: "http", children: [_jsx("option", { value: "http", children: "http" }), _jsx("option", { value: "https", children: "https" })] })] }), _jsxs("label", { children: ["Local Host", _jsx("input", { name: "local_host", defaultValue: "127.0.0.1" })] }), _jsxs("label", { children: ["Local Port", _jsx("input", { name: "local_port", type: "number", min: 1, max: 65535, placeholder: "3000" })] }), _jsxs("label", { children: ["Local Base Path", _jsx("input", { name: "local_base_path", placeholder: "/" })] }), _jsxs("label", { children: ["Access Token", _jsx("input", { name: "token", placeholder: "optional" })] }), _jsxs("label", { children: ["Route Max RPS", _jsx("input", { name: "max_rps", type: "number", min: 0, step: "0.1", placeholder: "0 = fair share" })] }), _jsx("div", { children: _jsx("button", { type: "submit", children: "Save Route" }) })] }), message ? _jsx("p", { className: "status", children: message }) : null] }), _jsxs(Section, { title: "Routes", actions: _jsx("button", { onClick: () => void load(), children: "Refresh" }), children: [loading ? _jsx("p", { children: "Loading..." }) : null, error ? _jsx("p", { className: "status error", children: error }) : null, !loading && !error ? (_jsxs("table", { children: [_jsx("thead", { children: _jsxs("tr", { children: [_jsx("th", { children: "Tenant" }), _jsx("th", { children: "ID" }), _jsx("th", { children: "Connector" }), _jsx("th", { children: "Max RPS" }), _jsx("th", { children: "Status" }), _jsx("th", { children: "Public URL" }), _jsx("th", { children: "Action" })] }) }), _jsx("tbody", { children: routes.length === 0 ? (_jsx("tr", { children: _jsx("td", { colSpan: 7, children: "No routes." }) })) : (routes.map((route) => (_jsxs("tr", { children: [_jsx("td", { children: route.tenant_id }), _jsx("td", { children: route.id }), _jsx("td", { children: route.connector_id || "-" }), _jsx("td", { children: route.max_rps && route.max_rps > 0 ? route.max_rps : "-" }), _jsx("td", { children: _jsx(Badge, { value: route.connected ? "active" : "offline" }) }), _jsx("td", { className: "code", children: route.public_url ?? "-" }), _jsx("td", { children: _jsx("button", { className: "ghost danger", onClick: () => void deleteRoute(route), children: "Delete" }) })] }, `${route.tenant_id}:${route.id}`)))) })] })) : null] })] }));
+    return (_jsxs(_Fragment, { children: [_jsxs(Section, { title: "Create Route", children: [_jsxs("form", { className: "grid cols-2", onSubmit: submitRoute, children: [_jsxs("label", { children: ["Tenant", _jsx("select", { name: "tenant_id", defaultValue: defaultTenant, disabled: !isSuper, required: isSuper, children: tenants.map((tenant) => (_jsx("option", { value: tenant.id, children: tenant.id }, tenant.id))) })] }), _jsxs("label", { children: ["Route ID", _jsx("input", { name: "id", placeholder: "api", required: true })] }), _jsxs("label", { children: ["Direct Target URL", _jsx("input", { name: "target", placeholder: "http://127.0.0.1:3000" })] }), _jsxs("label", { children: ["Connector", _jsxs("select", { name: "connector_id", defaultValue: "", children: [_jsx("option", { value: "", children: "Direct target" }), connectors.map((connector) => (_jsx("option", { value: connector.id, children: connector.id }, connector.id)))] })] }), _jsxs("label", { children: ["Local Scheme", _jsxs("select", { name: "local_scheme", defaultValue: "http", children: [_jsx("option", { value: "http", children: "http" }), _jsx("option", { value: "https", children: "https" })] })] }), _jsxs("label", { children: ["Local Host", _jsx("input", { name: "local_host", defaultValue: "127.0.0.1" })] }), _jsxs("label", { children: ["Local Port", _jsx("input", { name: "local_port", type: "number", min: 1, max: 65535, placeholder: "3000" })] }), _jsxs("label", { children: ["Local Base Path", _jsx("input", { name: "local_base_path", placeholder: "/" })] }), _jsxs("label", { children: ["Access Token", _jsx("input", { name: "token", placeholder: "optional" })] }), _jsxs("label", { children: ["Route Max RPS", _jsx("input", { name: "max_rps", type: "number", min: 0, step: "0.1", placeholder: "0 = fair share" })] }), _jsxs("label", { children: ["Bandwidth Limit (bytes/s)", _jsx("input", { name: "max_bytes_per_second", type: "number", min: 0, placeholder: "0 = unlimited" })] }), _jsx("div", { children: _jsx("button", { type: "submit", children: "Save Route" }) })] }), message ? _jsx("p", { className: "status", children: message }) : null] }), _jsxs(Section, { title: "Routes", actions: _jsx("button", { onClick: () => void load(), children: "Refresh" }), children: [loading ? _jsx("p", { children: "Loading..." }) : null, error ? _jsx("p", { className: "status error", children: error }) : null, !loading && !error ? (_jsxs("table", { children: [_jsx("thead", { children: _jsxs("tr", { children: [_jsx("th", { children: "Tenant" }), _jsx("th", { children: "ID" }), _jsx("th", { children: "Connector" }), _jsx("th", { children: "Max RPS" }), _jsx("th", { children: "Status" }), _jsx("th", { children: "Public URL" }), _jsx("th", { children: "Action" })] }) }), _jsx("tbody", { children: routes.length === 0 ? (_jsx("tr", { children: _jsx("td", { colSpan: 7, children: "No routes." }) })) : (routes.map((route) => (_jsxs("tr", { children: [_jsx("td", { children: route.tenant_id }), _jsx("td", { children: route.id }), _jsx("td", { children: route.connector_id || "-" }), _jsx("td", { children: route.max_rps && route.max_rps > 0 ? route.max_rps : "-" }), _jsx("td", { children: _jsx(Badge, { value: route.connected ? "active" : "offline" }) }), _jsx("td", { className: "code", children: route.public_url ?? "-" }), _jsx("td", { children: _jsx("button", { className: "ghost danger", onClick: () => void deleteRoute(route), children: "Delete" }) })] }, `${route.tenant_id}:${route.id}`)))) })] })) : null] })] }));
 }
 function ConnectorsPage({ api, me }) {
     const isSuper = me.user.role === "super_admin";
@@ -614,6 +621,7 @@ function ConnectorsPage({ api, me }) {
                     tenant_id: tenantID,
                     id: String(formData.get("id") ?? ""),
                     name: String(formData.get("name") ?? ""),
+                    max_bytes_per_second: Number(formData.get("max_bytes_per_second") ?? 0),
                 }),
             });
             form.reset();
@@ -658,7 +666,7 @@ function ConnectorsPage({ api, me }) {
             setMessage(toErrorMessage(err));
         }
     }, [api, load]);
-    return (_jsxs(_Fragment, { children: [_jsxs(Section, { title: "Create Connector", children: [_jsxs("form", { className: "inline-form", onSubmit: createConnector, children: [isSuper ? (_jsx("select", { name: "tenant_id", defaultValue: me.user.tenant_id || tenants[0]?.id || "default", children: tenants.map((tenant) => (_jsx("option", { value: tenant.id, children: tenant.id }, tenant.id))) })) : null, _jsx("input", { name: "id", placeholder: "connector-id", required: true }), _jsx("input", { name: "name", placeholder: "Friendly name", required: true }), _jsx("button", { type: "submit", children: "Create" })] }), message ? _jsx("p", { className: "status", children: message }) : null, output ? _jsx("p", { className: "code output", children: output }) : null] }), _jsxs(Section, { title: "Connectors", actions: _jsx("button", { onClick: () => void load(), children: "Refresh" }), children: [loading ? _jsx("p", { children: "Loading..." }) : null, error ? _jsx("p", { className: "status error", children: error }) : null, !loading && !error ? (_jsxs("table", { children: [_jsx("thead", { children: _jsxs("tr", { children: [_jsx("th", { children: "ID" }), _jsx("th", { children: "Tenant" }), _jsx("th", { children: "Status" }), _jsx("th", { children: "Agent" }), _jsx("th", { children: "Actions" })] }) }), _jsx("tbody", { children: connectors.length === 0 ? (_jsx("tr", { children: _jsx("td", { colSpan: 5, children: "No connectors." }) })) : (connectors.map((connector) => (_jsxs("tr", { children: [_jsx("td", { children: connector.id }), _jsx("td", { children: connector.tenant_id }), _jsx("td", { children: _jsx(Badge, { value: connector.connected ? "online" : "offline" }) }), _jsx("td", { children: connector.agent_id || "-" }), _jsx("td", { children: _jsxs("div", { className: "actions", children: [_jsx("button", { className: "ghost", onClick: () => void pair(connector.id), children: "Pair" }), _jsx("button", { className: "ghost", onClick: () => void rotate(connector.id), children: "Rotate" }), _jsx("button", { className: "ghost danger", onClick: () => void remove(connector.id), children: "Delete" })] }) })] }, connector.id)))) })] })) : null] })] }));
+    return (_jsxs(_Fragment, { children: [_jsxs(Section, { title: "Create Connector", children: [_jsxs("form", { className: "inline-form", onSubmit: createConnector, children: [isSuper ? (_jsx("select", { name: "tenant_id", defaultValue: me.user.tenant_id || tenants[0]?.id || "default", children: tenants.map((tenant) => (_jsx("option", { value: tenant.id, children: tenant.id }, tenant.id))) })) : null, _jsx("input", { name: "id", placeholder: "connector-id", required: true }), _jsx("input", { name: "name", placeholder: "Friendly name", required: true }), _jsx("input", { name: "max_bytes_per_second", type: "number", min: 0, placeholder: "bytes/s limit (0 = unlimited)" }), _jsx("button", { type: "submit", children: "Create" })] }), message ? _jsx("p", { className: "status", children: message }) : null, output ? _jsx("p", { className: "code output", children: output }) : null] }), _jsxs(Section, { title: "Connectors", actions: _jsx("button", { onClick: () => void load(), children: "Refresh" }), children: [loading ? _jsx("p", { children: "Loading..." }) : null, error ? _jsx("p", { className: "status error", children: error }) : null, !loading && !error ? (_jsxs("table", { children: [_jsx("thead", { children: _jsxs("tr", { children: [_jsx("th", { children: "ID" }), _jsx("th", { children: "Tenant" }), _jsx("th", { children: "Status" }), _jsx("th", { children: "Agent" }), _jsx("th", { children: "Actions" })] }) }), _jsx("tbody", { children: connectors.length === 0 ? (_jsx("tr", { children: _jsx("td", { colSpan: 5, children: "No connectors." }) })) : (connectors.map((connector) => (_jsxs("tr", { children: [_jsx("td", { children: connector.id }), _jsx("td", { children: connector.tenant_id }), _jsx("td", { children: _jsx(Badge, { value: connector.connected ? (connector.outdated ? "outdated" : "online") : "offline" }) }), _jsx("td", { title: connector.outdated_reason, children: connectorAgentLabel(connector) }), _jsx("td", { children: _jsxs("div", { className: "actions", children: [_jsx("button", { className: "ghost", onClick: () => void pair(connector.id), children: "Pair" }), _jsx("button", { className: "ghost", onClick: () => void rotate(connector.id), children: "Rotate" }), _jsx("button", { className: "ghost danger", onClick: () => void remove(connector.id), children: "Delete" })] }) })] }, connector.id)))) })] })) : null] })] }));
 }
 function TenantConfigPage({ api, me }) {
     const isSuper = me.user.role === "super_admin";
@@ -43,6 +43,7 @@ interface RouteView {
   id: string;
   connector_id?: string;
   max_rps?: number;
+  max_bytes_per_second?: number;
   connected?: boolean;
   public_url?: string;
   local_scheme?: string;
@@ -56,9 +57,19 @@ interface ConnectorView {
   name?: string;
   connected?: boolean;
   agent_id?: string;
+  agent_version?: string;
+  outdated?: boolean;
+  outdated_reason?: string;
   last_seen?: string;
 }
 
+function connectorAgentLabel(connector: ConnectorView): string {
+  if (!connector.agent_id) {
+    return "-";
+  }
+  return connector.agent_version ? `${connector.agent_id} (${connector.agent_version})` : connector.agent_id;
+}
+
 interface DashboardPayload {
   plan?: { id?: string; name?: string };
   gauges?: {
@@ -456,9 +467,9 @@ function TenantDashboardPage({ api }: { api: ApiClient }) {
                 <tr key={connector.id}>
                   <td>{connector.id}</td>
                   <td>
-                    <Badge value={connector.connected ? "online" : "offline"} />
+                    <Badge value={connector.connected ? (connector.outdated ? "outdated" : "online") : "offline"} />
                   </td>
-                  <td>{connector.agent_id ?? "-"}</td>
+                  <td title={connector.outdated_reason}>{connectorAgentLabel(connector)}</td>
                   <td>{formatDateTime(connector.last_seen)}</td>
                 </tr>
               ))
@@ -1320,6 +1331,7 @@ function RoutesPage({ api, me }: { api: ApiClient; me: AuthMeResponse }) {
             target: String(formData.get("target") ?? ""),
             token: String(formData.get("token") ?? ""),
             max_rps: Number(formData.get("max_rps") ?? 0),
+            max_bytes_per_second: Number(formData.get("max_bytes_per_second") ?? 0),
             connector_id: String(formData.get("connector_id") ?? ""),
             local_scheme: String(formData.get("local_scheme") ?? "http"),
             local_host: String(formData.get("local_host") ?? "127.0.0.1"),
@@ -1422,6 +1434,10 @@ function RoutesPage({ api, me }: { api: ApiClient; me: AuthMeResponse }) {
             Route Max RPS
             <input name="max_rps" type="number" min={0} step="0.1" placeholder="0 = fair share" />
           </label>
+          <label>
+            Bandwidth Limit (bytes/s)
+            <input name="max_bytes_per_second" type="number" min={0} placeholder="0 = unlimited" />
+          </label>
           <div>
             <button type="submit">Save Route</button>
           </div>
@@ -1523,6 +1539,7 @@ function ConnectorsPage({ api, me }: { api: ApiClient; me: AuthMeResponse }) {
             tenant_id: tenantID,
             id: String(formData.get("id") ?? ""),
             name: String(formData.get("name") ?? ""),
+            max_bytes_per_second: Number(formData.get("max_bytes_per_second") ?? 0),
           }),
         });
         form.reset();
@@ -1596,6 +1613,7 @@ function ConnectorsPage({ api, me }: { api: ApiClient; me: AuthMeResponse }) {
           ) : null}
           <input name="id" placeholder="connector-id" required />
           <input name="name" placeholder="Friendly name" required />
+          <input name="max_bytes_per_second" type="number" min={0} placeholder="bytes/s limit (0 = unlimited)" />
           <button type="submit">Create</button>
         </form>
         {message ? <p className="status">{message}</p> : null}
@@ -1627,9 +1645,9 @@ function ConnectorsPage({ api, me }: { api: ApiClient; me: AuthMeResponse }) {
                     <td>{connector.id}</td>
                     <td>{connector.tenant_id}</td>
                     <td>
-                      <Badge value={connector.connected ? "online" : "offline"} />
+                      <Badge value={connector.connected ? (connector.outdated ? "outdated" : "online") : "offline"} />
                     </td>
-                    <td>{connector.agent_id || "-"}</td>
+                    <td title={connector.outdated_reason}>{connectorAgentLabel(connector)}</td>
                     <td>
                       <div className="actions">
                         <button className="ghost" onClick={() => void pair(connector.id)}>